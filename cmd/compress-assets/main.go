@@ -0,0 +1,86 @@
+// compress-assets compresses the text assets copied to internal/embeds with Brotli before
+// they are embedded, making the binary and with it the update downloads smaller.
+// internal/embeds decompresses them at runtime, or serves them compressed to browsers.
+//
+// Usage: go run ./cmd/compress-assets internal/embeds/static internal/embeds/templates
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressible lists the extensions worth compressing. Images and fonts like PNG and WOFF2
+// are compressed already.
+var compressible = map[string]bool{
+	".css": true, ".html": true, ".ico": true, ".js": true, ".json": true, ".md": true,
+	".svg": true, ".ttf": true, ".txt": true, ".webmanifest": true, ".yml": true, ".yaml": true,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: compress-assets <dir>...")
+		os.Exit(2)
+	}
+
+	var before, after int64
+	for _, dir := range os.Args[1:] {
+		b, a, err := compressDir(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		before += b
+		after += a
+	}
+	fmt.Printf("Compressed assets from %d KB to %d KB\n", before/1024, after/1024)
+}
+
+// compressDir replaces every compressible file below dir with a compressed "name.br" if
+// that is smaller. It returns the size of the compressible files before and after.
+func compressDir(dir string) (before, after int64, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !compressible[strings.ToLower(filepath.Ext(path))] {
+			return err
+		}
+		data, err := os.ReadFile(path) //nolint:gosec // Build tool reading its input
+		if err != nil {
+			return err
+		}
+		compressed, err := brotliBytes(data)
+		if err != nil {
+			return fmt.Errorf("failed to compress %s: %w", path, err)
+		}
+		before += int64(len(data))
+		if len(compressed) >= len(data) {
+			after += int64(len(data))
+			return nil
+		}
+		after += int64(len(compressed))
+		if err := os.WriteFile(path+".br", compressed, 0600); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	})
+	return before, after, err
+}
+
+// brotliBytes compresses data at the highest quality. Brotli streams carry no name or time,
+// so the output is reproducible.
+func brotliBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	bw := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	if _, err := bw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// goCommentWidth is where generated Go comments wrap
+const goCommentWidth = 90
+
+// goTypes generates pkg/client/types_gen.go with a struct per object schema
+func goTypes(s *spec) ([]byte, error) {
+	var body bytes.Buffer
+	for _, e := range s.Components.Schemas {
+		if e.Value.Type != "object" {
+			continue
+		}
+		if e.Value.Description != "" {
+			body.WriteString(comment(e.Value.Description, "// ", goCommentWidth))
+		}
+		fmt.Fprintf(&body, "type %s struct {\n", e.Key)
+		for _, prop := range e.Value.Properties {
+			typ, err := goType(s, prop.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", e.Key, prop.Key, err)
+			}
+			tag := prop.Key
+			if !e.Value.isRequired(prop.Key) {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&body, "%s %s `json:%q`", exportedName(prop.Key), typ, tag)
+			if description := goFieldComment(prop.Value); description != "" {
+				fmt.Fprintf(&body, " // %s", description)
+			}
+			body.WriteString("\n")
+		}
+		body.WriteString("}\n\n")
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// %s\n\npackage client\n\n", header)
+	if strings.Contains(body.String(), "time.Time") {
+		out.WriteString("import \"time\"\n\n")
+	}
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+// goFieldComment returns the comment of a struct field, the values of a string enum
+// unless the spec describes the field
+func goFieldComment(sch *schema) string {
+	if sch.Description != "" || len(sch.Enum) == 0 {
+		return sch.Description
+	}
+	values := strings.Join(sch.Enum[:len(sch.Enum)-1], ", ")
+	return values + " or " + sch.Enum[len(sch.Enum)-1]
+}
+
+// goType returns the Go type of a schema
+func goType(s *spec, sch *schema) (string, error) {
+	if sch.Ref != "" {
+		if _, err := s.resolve(sch); err != nil {
+			return "", err
+		}
+		if s.isEnum(sch) {
+			return "string", nil
+		}
+		return refName(sch.Ref), nil
+	}
+	switch sch.Type {
+	case "string":
+		if sch.Format == "date-time" {
+			if sch.Nullable {
+				return "*time.Time", nil
+			}
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		switch sch.Format {
+		case "int64", "uint64":
+			return sch.Format, nil
+		}
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		if sch.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		items, err := goType(s, sch.Items)
+		return "[]" + items, err
+	case "object":
+		if sch.AdditionalProperties == nil {
+			return "", fmt.Errorf("inline object without additionalProperties")
+		}
+		values, err := goType(s, sch.AdditionalProperties)
+		return "map[string]" + values, err
+	}
+	return "", fmt.Errorf("unsupported type %q", sch.Type)
+}
+
+// goZero returns the zero value of a Go type in a return statement
+func goZero(typ string) string {
+	switch {
+	case typ == "bool":
+		return "false"
+	case typ == "string":
+		return `""`
+	case typ == "int" || typ == "int64" || typ == "uint64" || typ == "float64":
+		return "0"
+	case strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["), strings.HasPrefix(typ, "*"):
+		return "nil"
+	}
+	return typ + "{}"
+}
+
+// goParam is an argument of a generated method
+type goParam struct {
+	name, typ string
+}
+
+// goClient generates pkg/client/client_gen.go with a method per operation
+func goClient(s *spec, ops []*op) ([]byte, error) {
+	var body bytes.Buffer
+	imports := map[string]bool{"context": true, "net/http": true}
+	for _, o := range ops {
+		if err := goMethod(&body, s, o, imports); err != nil {
+			return nil, fmt.Errorf("%s: %w", o.OperationID, err)
+		}
+	}
+
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// %s\n\npackage client\n\nimport (\n", header)
+	for _, path := range paths {
+		fmt.Fprintf(&out, "%q\n", path)
+	}
+	out.WriteString(")\n\n")
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+func goMethod(w *bytes.Buffer, s *spec, o *op, imports map[string]bool) error {
+	var params []goParam
+	for _, p := range o.pathParams {
+		typ, err := goType(s, p.Schema)
+		if err != nil {
+			return err
+		}
+		params = append(params, goParam{paramName(p.Name), typ})
+	}
+	for _, p := range o.queryParams {
+		if p.Schema == nil || p.Schema.Type != "string" {
+			return fmt.Errorf("query parameter %s is not a string", p.Name)
+		}
+		params = append(params, goParam{paramName(p.Name), "string"})
+	}
+
+	// A body schema of the spec is passed as is, an inline one as an argument per property
+	var bodyArg string
+	var bodyFields []string
+	if o.body != nil {
+		if o.body.Ref != "" {
+			params = append(params, goParam{"req", refName(o.body.Ref)})
+			bodyArg = "req"
+		} else {
+			for _, prop := range o.body.Properties {
+				typ, err := goType(s, prop.Value)
+				if err != nil {
+					return err
+				}
+				params = append(params, goParam{paramName(prop.Key), typ})
+				bodyFields = append(bodyFields, fmt.Sprintf("%q: %s,", prop.Key, paramName(prop.Key)))
+			}
+			bodyArg = "body"
+		}
+	}
+
+	name := methodName(o.OperationID)
+	w.WriteString(comment(name+" "+sentence(o.Summary), "// ", goCommentWidth))
+	fmt.Fprintf(w, "func (c *Client) %s(ctx context.Context%s) ", name, goParamList(params))
+
+	var result, resultType, unwrapType string
+	switch {
+	case o.unwrapped != nil:
+		typ, err := goType(s, o.unwrapped)
+		if err != nil {
+			return err
+		}
+		unwrapType, resultType, result = typ, typ, "resp."+exportedName(o.Unwrap)
+		if o.unwrapped.Ref != "" && !s.isEnum(o.unwrapped) {
+			resultType, result = "*"+typ, "&"+result
+		}
+	case o.response != nil:
+		if o.response.Ref == "" {
+			return fmt.Errorf("inline response without x-unwrap")
+		}
+		resultType, result = "*"+refName(o.response.Ref), "&resp"
+	}
+	if resultType != "" {
+		fmt.Fprintf(w, "(%s, error) {\n", resultType)
+	} else {
+		w.WriteString("error {\n")
+	}
+
+	path := goPath(o, imports)
+	if len(o.queryParams) > 0 {
+		imports["net/url"] = true
+		w.WriteString("query := url.Values{}\n")
+		for _, p := range o.queryParams {
+			fmt.Fprintf(w, "query.Set(%q, %s)\n", p.Name, paramName(p.Name))
+		}
+	}
+	if bodyFields != nil {
+		fmt.Fprintf(w, "body := map[string]interface{}{\n%s\n}\n", strings.Join(bodyFields, "\n"))
+	}
+	if bodyArg == "" {
+		bodyArg = "nil"
+	}
+	method := "http.Method" + strings.ToUpper(o.method[:1]) + o.method[1:]
+
+	if resultType == "" {
+		fmt.Fprintf(w, "return c.doJSON(ctx, %s, %s, %s, nil)\n}\n\n", method, path, bodyArg)
+		return nil
+	}
+	if o.unwrapped != nil {
+		fmt.Fprintf(w, "var resp struct {\n%s %s `json:%q`\n}\n", exportedName(o.Unwrap), unwrapType, o.Unwrap)
+	} else {
+		fmt.Fprintf(w, "var resp %s\n", refName(o.response.Ref))
+	}
+	fmt.Fprintf(w, "if err := c.doJSON(ctx, %s, %s, %s, &resp); err != nil {\nreturn %s, err\n}\n", method, path, bodyArg, goZero(resultType))
+	fmt.Fprintf(w, "return %s, nil\n}\n\n", result)
+	return nil
+}
+
+// goParamList joins the arguments of a method, with consecutive ones of a type grouped
+func goParamList(params []goParam) string {
+	var b strings.Builder
+	for i, p := range params {
+		b.WriteString(", " + p.name)
+		if i+1 == len(params) || params[i+1].typ != p.typ {
+			b.WriteString(" " + p.typ)
+		}
+	}
+	return b.String()
+}
+
+// goPath returns the expression of an operation's path with its parameters escaped
+func goPath(o *op, imports map[string]bool) string {
+	params := make(map[string]*parameter)
+	for _, p := range o.pathParams {
+		params[p.Name] = p
+	}
+
+	var parts []string
+	literal := ""
+	for _, segment := range strings.Split(strings.TrimPrefix(o.path, "/"), "/") {
+		literal += "/"
+		p, ok := params[strings.Trim(segment, "{}")]
+		if !ok || !strings.HasPrefix(segment, "{") {
+			literal += segment
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%q", literal))
+		literal = ""
+		switch {
+		case p.Schema.Type == "integer" && p.Schema.Format == "int64":
+			imports["strconv"] = true
+			parts = append(parts, fmt.Sprintf("strconv.FormatInt(%s, 10)", paramName(p.Name)))
+		case p.Schema.Type == "integer":
+			imports["strconv"] = true
+			parts = append(parts, fmt.Sprintf("strconv.Itoa(%s)", paramName(p.Name)))
+		default:
+			imports["net/url"] = true
+			parts = append(parts, fmt.Sprintf("url.PathEscape(%s)", paramName(p.Name)))
+		}
+	}
+	if len(o.queryParams) > 0 {
+		literal += "?"
+	}
+	if literal != "" {
+		parts = append(parts, fmt.Sprintf("%q", literal))
+	}
+	if len(o.queryParams) > 0 {
+		parts = append(parts, "query.Encode()")
+	}
+	return strings.Join(parts, " + ")
+}
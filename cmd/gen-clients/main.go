@@ -0,0 +1,417 @@
+// gen-clients generates the Go client in pkg/client and the TypeScript client in
+// sdk/typescript from the OpenAPI spec in api/openapi.yaml. Operations marked
+// x-client: manual are left to the handwritten parts of the clients.
+//
+// Usage: go run ./cmd/gen-clients [-root dir], run by go generate ./pkg/client
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// specPath is the spec the clients are generated from, relative to the repository root
+const specPath = "api/openapi.yaml"
+
+// header starts every generated file
+const header = "Code generated by gen-clients from " + specPath + ". DO NOT EDIT."
+
+func main() {
+	root := flag.String("root", ".", "Root of the repository")
+	flag.Parse()
+
+	data, err := os.ReadFile(filepath.Join(*root, specPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	files, err := generate(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(*root, name), content, 0o644); err != nil { //nolint:gosec // Source files
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// generate returns the generated files of the spec by their path in the repository
+func generate(data []byte) (map[string][]byte, error) {
+	var s spec
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", specPath, err)
+	}
+	ops, err := s.operations()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	generators := map[string]func() ([]byte, error){
+		"pkg/client/types_gen.go":  func() ([]byte, error) { return goTypes(&s) },
+		"pkg/client/client_gen.go": func() ([]byte, error) { return goClient(&s, ops) },
+		"sdk/typescript/types.ts":  func() ([]byte, error) { return tsTypes(&s) },
+		"sdk/typescript/index.ts":  func() ([]byte, error) { return tsClient(&s, ops) },
+	}
+	for name, gen := range generators {
+		content, err := gen()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// spec is the subset of OpenAPI 3.0 the clients are generated from
+type spec struct {
+	Paths      ordered[pathItem] `yaml:"paths"`
+	Components struct {
+		Parameters map[string]*parameter `yaml:"parameters"`
+		Schemas    ordered[*schema]      `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// ordered is a YAML mapping that keeps the order of its keys, so the generated code
+// follows the spec
+type ordered[T any] []entry[T]
+
+type entry[T any] struct {
+	Key   string
+	Value T
+}
+
+// UnmarshalYAML decodes a mapping node in order
+func (o *ordered[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var value T
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		*o = append(*o, entry[T]{Key: node.Content[i].Value, Value: value})
+	}
+	return nil
+}
+
+// get returns the value of key
+func (o ordered[T]) get(key string) (T, bool) {
+	for _, e := range o {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// pathItem holds the operations of a path by HTTP method, in the order of the spec
+type pathItem struct {
+	Parameters []*parameter
+	Methods    ordered[*operation]
+}
+
+// UnmarshalYAML separates the path parameters from the operations
+func (p *pathItem) UnmarshalYAML(node *yaml.Node) error {
+	var all ordered[yaml.Node]
+	if err := node.Decode(&all); err != nil {
+		return err
+	}
+	for _, e := range all {
+		switch e.Key {
+		case "parameters":
+			if err := e.Value.Decode(&p.Parameters); err != nil {
+				return err
+			}
+		case "get", "put", "post", "patch", "delete":
+			var op operation
+			if err := e.Value.Decode(&op); err != nil {
+				return err
+			}
+			p.Methods = append(p.Methods, entry[*operation]{Key: e.Key, Value: &op})
+		}
+	}
+	return nil
+}
+
+type operation struct {
+	OperationID string       `yaml:"operationId"`
+	Summary     string       `yaml:"summary"`
+	Client      string       `yaml:"x-client"`
+	Unwrap      string       `yaml:"x-unwrap"`
+	Parameters  []*parameter `yaml:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema *schema `yaml:"schema"`
+		} `yaml:"content"`
+	} `yaml:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *schema `yaml:"schema"`
+		} `yaml:"content"`
+	} `yaml:"responses"`
+}
+
+type parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *schema `yaml:"schema"`
+}
+
+type schema struct {
+	Ref                  string           `yaml:"$ref"`
+	Type                 string           `yaml:"type"`
+	Format               string           `yaml:"format"`
+	Description          string           `yaml:"description"`
+	Nullable             bool             `yaml:"nullable"`
+	Enum                 []string         `yaml:"enum"`
+	Required             []string         `yaml:"required"`
+	Properties           ordered[*schema] `yaml:"properties"`
+	Items                *schema          `yaml:"items"`
+	AdditionalProperties *schema          `yaml:"additionalProperties"`
+}
+
+// isRequired reports whether the object schema requires the property name
+func (s *schema) isRequired(name string) bool {
+	for _, required := range s.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// refName returns the name of the component schema a $ref points to
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// resolve follows a $ref to the component schema
+func (s *spec) resolve(sch *schema) (*schema, error) {
+	if sch == nil || sch.Ref == "" {
+		return sch, nil
+	}
+	target, ok := s.Components.Schemas.get(refName(sch.Ref))
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %s", sch.Ref)
+	}
+	return target, nil
+}
+
+// isEnum reports whether a $ref points to a string enum, which Go clients see as string
+func (s *spec) isEnum(sch *schema) bool {
+	target, err := s.resolve(sch)
+	return err == nil && target != nil && len(target.Enum) > 0
+}
+
+// op is an operation the clients implement, with its parameters resolved
+type op struct {
+	method string
+	path   string
+	*operation
+	pathParams  []*parameter // In the order they appear in path
+	queryParams []*parameter // Required query parameters, optional ones are left to callers
+	body        *schema      // JSON request body, nil without one
+	response    *schema      // JSON response, nil when the clients ignore the answer
+	unwrapped   *schema      // Property x-unwrap names, the result of the clients
+}
+
+// operations returns the operations to generate in the order of the spec
+func (s *spec) operations() ([]*op, error) {
+	var ops []*op
+	for _, path := range s.Paths {
+		for _, method := range path.Value.Methods {
+			if method.Value.Client == "manual" {
+				continue
+			}
+			o, err := s.newOp(method.Key, path.Key, path.Value.Parameters, method.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method.Key), path.Key, err)
+			}
+			ops = append(ops, o)
+		}
+	}
+	return ops, nil
+}
+
+func (s *spec) newOp(method, path string, shared []*parameter, operation *operation) (*op, error) {
+	if operation.OperationID == "" {
+		return nil, fmt.Errorf("missing operationId")
+	}
+	o := &op{method: method, path: path, operation: operation}
+
+	params := make(map[string]*parameter)
+	for _, p := range append(append([]*parameter{}, shared...), operation.Parameters...) {
+		if p.Ref != "" {
+			ref, ok := s.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			if !ok {
+				return nil, fmt.Errorf("unknown parameter %s", p.Ref)
+			}
+			p = ref
+		}
+		switch {
+		case p.In == "path":
+			params[p.Name] = p
+		case p.In == "query" && p.Required:
+			o.queryParams = append(o.queryParams, p)
+		}
+	}
+	for _, name := range pathParamNames(path) {
+		p, ok := params[name]
+		if !ok {
+			return nil, fmt.Errorf("path parameter %s is not defined", name)
+		}
+		o.pathParams = append(o.pathParams, p)
+	}
+
+	if operation.RequestBody != nil {
+		content, ok := operation.RequestBody.Content["application/json"]
+		if !ok {
+			return nil, fmt.Errorf("request body is not JSON")
+		}
+		o.body = content.Schema
+	}
+
+	codes := make([]string, 0, len(operation.Responses))
+	for code := range operation.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no 2xx response")
+	}
+	sort.Strings(codes)
+	if content, ok := operation.Responses[codes[0]].Content["application/json"]; ok {
+		o.response = content.Schema
+	}
+
+	if operation.Unwrap != "" {
+		response, err := s.resolve(o.response)
+		if err != nil {
+			return nil, err
+		}
+		if response == nil {
+			return nil, fmt.Errorf("x-unwrap without a JSON response")
+		}
+		property, ok := response.Properties.get(operation.Unwrap)
+		if !ok {
+			return nil, fmt.Errorf("response has no property %s", operation.Unwrap)
+		}
+		o.unwrapped = property
+	}
+	return o, nil
+}
+
+// pathParamNames returns the names of the {parameters} of a path template
+func pathParamNames(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
+
+// initialisms are the words Go names spell in capitals
+var initialisms = map[string]string{
+	"api": "API", "cpu": "CPU", "cpus": "CPUs", "cpuset": "CPUSet", "gpu": "GPU",
+	"id": "ID", "json": "JSON", "url": "URL", "yaml": "YAML",
+}
+
+// words splits a JSON or parameter name at underscores, dashes and case changes
+func words(name string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-':
+			if len(current) > 0 {
+				result = append(result, string(current))
+			}
+			current = nil
+			continue
+		case unicode.IsUpper(r) && len(current) > 0:
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				result = append(result, string(current))
+				current = nil
+			}
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		result = append(result, string(current))
+	}
+	return result
+}
+
+// exportedName returns the Go name of a field, e.g. CPUSet for cpuset
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if initialism, ok := initialisms[strings.ToLower(word)]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+	}
+	return b.String()
+}
+
+// paramName returns the name of an argument of a client method, e.g. composeYAML for
+// compose_yaml
+func paramName(name string) string {
+	parts := words(name)
+	first := strings.ToLower(parts[0])
+	return first + exportedName(strings.Join(parts[1:], "_"))
+}
+
+// methodName returns the Go method name of an operationId
+func methodName(operationID string) string {
+	return strings.ToUpper(operationID[:1]) + operationID[1:]
+}
+
+// sentence makes a summary continue a method name, "Returns the" becomes "returns the"
+func sentence(summary string) string {
+	summary = strings.Join(strings.Fields(summary), " ")
+	if len(summary) > 1 && unicode.IsUpper(rune(summary[0])) && !unicode.IsUpper(rune(summary[1])) {
+		return strings.ToLower(summary[:1]) + summary[1:]
+	}
+	return summary
+}
+
+// comment wraps text into comment lines of prefix, at most width characters long
+func comment(text, prefix string, width int) string {
+	var b strings.Builder
+	line := prefix
+	for _, word := range strings.Fields(text) {
+		if line != prefix && len(line)+1+len(word) > width {
+			b.WriteString(line + "\n")
+			line = prefix
+		}
+		if line != prefix {
+			line += " "
+		}
+		line += word
+	}
+	b.WriteString(line + "\n")
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGeneratedClientsUpToDate(t *testing.T) {
+	root := filepath.Join("..", "..")
+	data, err := os.ReadFile(filepath.Join(root, specPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := generate(data)
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date with %s, run go generate ./pkg/client", name, specPath)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name, exported, param string
+	}{
+		{"compose_yaml", "ComposeYAML", "composeYAML"},
+		{"cpuset", "CPUSet", "cpuset"},
+		{"buildDate", "BuildDate", "buildDate"},
+		{"share_with_agent", "ShareWithAgent", "shareWithAgent"},
+		{"id", "ID", "id"},
+		{"appCPUPinning", "AppCPUPinning", "appCPUPinning"},
+	}
+	for _, tt := range tests {
+		if got := exportedName(tt.name); got != tt.exported {
+			t.Errorf("exportedName(%q) = %q, want %q", tt.name, got, tt.exported)
+		}
+		if got := paramName(tt.name); got != tt.param {
+			t.Errorf("paramName(%q) = %q, want %q", tt.name, got, tt.param)
+		}
+	}
+}
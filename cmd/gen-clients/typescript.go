@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tsCommentWidth is where generated TypeScript comments wrap
+const tsCommentWidth = 100
+
+// tsTypes generates sdk/typescript/types.ts with an interface per object schema and a
+// union type per string enum
+func tsTypes(s *spec) ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// %s\n", header)
+	for _, e := range s.Components.Schemas {
+		out.WriteString("\n")
+		if e.Value.Description != "" {
+			out.WriteString(comment(e.Value.Description, "// ", tsCommentWidth))
+		}
+		if e.Value.Type != "object" {
+			typ, err := tsType(s, e.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e.Key, err)
+			}
+			fmt.Fprintf(&out, "export type %s = %s;\n", e.Key, typ)
+			continue
+		}
+		fmt.Fprintf(&out, "export interface %s {\n", e.Key)
+		for _, prop := range e.Value.Properties {
+			typ, err := tsType(s, prop.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", e.Key, prop.Key, err)
+			}
+			optional := ""
+			if !e.Value.isRequired(prop.Key) {
+				optional = "?"
+			}
+			fmt.Fprintf(&out, "  %s%s: %s;", prop.Key, optional, typ)
+			if prop.Value.Description != "" {
+				fmt.Fprintf(&out, " // %s", prop.Value.Description)
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString("}\n")
+	}
+	return out.Bytes(), nil
+}
+
+// tsType returns the TypeScript type of a schema
+func tsType(s *spec, sch *schema) (string, error) {
+	typ, err := tsBaseType(s, sch)
+	if err == nil && sch.Nullable {
+		typ += " | null"
+	}
+	return typ, err
+}
+
+func tsBaseType(s *spec, sch *schema) (string, error) {
+	if sch.Ref != "" {
+		_, err := s.resolve(sch)
+		return refName(sch.Ref), err
+	}
+	if len(sch.Enum) > 0 {
+		values := make([]string, len(sch.Enum))
+		for i, value := range sch.Enum {
+			values[i] = strconv.Quote(value)
+		}
+		return strings.Join(values, " | "), nil
+	}
+	switch sch.Type {
+	case "string":
+		return "string", nil
+	case "integer", "number":
+		return "number", nil
+	case "boolean":
+		return "boolean", nil
+	case "array":
+		if sch.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		items, err := tsType(s, sch.Items)
+		if strings.Contains(items, " | ") {
+			items = "(" + items + ")"
+		}
+		return items + "[]", err
+	case "object":
+		if sch.AdditionalProperties == nil {
+			return "", fmt.Errorf("inline object without additionalProperties")
+		}
+		values, err := tsType(s, sch.AdditionalProperties)
+		return "Record<string, " + values + ">", err
+	}
+	return "", fmt.Errorf("unsupported type %q", sch.Type)
+}
+
+// tsClient generates sdk/typescript/index.ts with the TreeOSClient class, a method per
+// operation on top of the handwritten BaseClient in base.ts
+func tsClient(s *spec, ops []*op) ([]byte, error) {
+	var body bytes.Buffer
+	used := make(map[string]bool)
+	for _, o := range ops {
+		if err := tsMethod(&body, s, o, used); err != nil {
+			return nil, fmt.Errorf("%s: %w", o.OperationID, err)
+		}
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// %s\n\n", header)
+	out.WriteString("import { BaseClient } from \"./base\";\n")
+	fmt.Fprintf(&out, "import type { %s } from \"./types\";\n\n", strings.Join(names, ", "))
+	out.WriteString("export * from \"./types\";\n")
+	out.WriteString("export { TreeOSError } from \"./base\";\n")
+	out.WriteString("export type { LogQuery } from \"./base\";\n\n")
+	out.WriteString("// TreeOSClient is the typed client of the TreeOS HTTP API.\n")
+	out.WriteString("export class TreeOSClient extends BaseClient {\n")
+	out.WriteString(strings.TrimSuffix(body.String(), "\n"))
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
+func tsMethod(w *bytes.Buffer, s *spec, o *op, used map[string]bool) error {
+	// typeOf returns the type of a schema and records the named types it uses for the import
+	typeOf := func(sch *schema) (string, error) {
+		typ, err := tsType(s, sch)
+		for _, field := range strings.FieldsFunc(typ, func(r rune) bool { return strings.ContainsRune(" |()[]<>,", r) }) {
+			if _, ok := s.Components.Schemas.get(field); ok {
+				used[field] = true
+			}
+		}
+		return typ, err
+	}
+
+	var params []string
+	for _, p := range append(append([]*parameter{}, o.pathParams...), o.queryParams...) {
+		typ, err := typeOf(p.Schema)
+		if err != nil {
+			return err
+		}
+		params = append(params, paramName(p.Name)+": "+typ)
+	}
+
+	bodyArg := ""
+	if o.body != nil {
+		if o.body.Ref != "" {
+			typ, err := typeOf(o.body)
+			if err != nil {
+				return err
+			}
+			params = append(params, "req: "+typ)
+			bodyArg = "req"
+		} else {
+			var fields []string
+			for _, prop := range o.body.Properties {
+				typ, err := typeOf(prop.Value)
+				if err != nil {
+					return err
+				}
+				name := paramName(prop.Key)
+				params = append(params, name+": "+typ)
+				if name == prop.Key {
+					fields = append(fields, name)
+				} else {
+					fields = append(fields, prop.Key+": "+name)
+				}
+			}
+			bodyArg = "{ " + strings.Join(fields, ", ") + " }"
+		}
+	}
+
+	var lines []string
+	if len(o.queryParams) > 0 {
+		names := make([]string, len(o.queryParams))
+		for i, p := range o.queryParams {
+			names[i] = paramName(p.Name)
+		}
+		lines = append(lines, fmt.Sprintf("const query = new URLSearchParams({ %s });", strings.Join(names, ", ")))
+	}
+	args := tsPath(o)
+	if bodyArg != "" {
+		args += ", " + bodyArg
+	}
+	method := strconv.Quote(strings.ToUpper(o.method))
+
+	signature := fmt.Sprintf("%s(%s)", o.OperationID, strings.Join(params, ", "))
+	switch {
+	case o.unwrapped != nil:
+		typ, err := typeOf(o.unwrapped)
+		if err != nil {
+			return err
+		}
+		signature = fmt.Sprintf("async %s: Promise<%s>", signature, typ)
+		lines = append(lines,
+			fmt.Sprintf("const res = await this.request<{ %s: %s }>(%s, %s);", o.Unwrap, typ, method, args),
+			"return res."+o.Unwrap+";")
+	case o.response != nil:
+		typ, err := typeOf(o.response)
+		if err != nil {
+			return err
+		}
+		signature = fmt.Sprintf("%s: Promise<%s>", signature, typ)
+		lines = append(lines, fmt.Sprintf("return this.request(%s, %s);", method, args))
+	default:
+		signature = fmt.Sprintf("async %s: Promise<void>", signature)
+		lines = append(lines, fmt.Sprintf("await this.send(%s, %s);", method, args))
+	}
+
+	w.WriteString(comment(o.OperationID+" "+sentence(o.Summary), "  // ", tsCommentWidth))
+	fmt.Fprintf(w, "  %s {\n", signature)
+	for _, line := range lines {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+	w.WriteString("  }\n\n")
+	return nil
+}
+
+// tsPath returns the expression of an operation's path with its parameters encoded
+func tsPath(o *op) string {
+	params := make(map[string]*parameter)
+	for _, p := range o.pathParams {
+		params[p.Name] = p
+	}
+
+	path := o.path
+	for _, segment := range strings.Split(o.path, "/") {
+		p, ok := params[strings.Trim(segment, "{}")]
+		if !ok || !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		value := "encodeURIComponent(" + paramName(p.Name) + ")"
+		if p.Schema.Type == "integer" {
+			value = paramName(p.Name)
+		}
+		path = strings.Replace(path, segment, "${"+value+"}", 1)
+	}
+	if len(o.queryParams) > 0 {
+		path += "?${query}"
+	}
+	if path == o.path {
+		return strconv.Quote(path)
+	}
+	return "`" + path + "`"
+}
@@ -3,26 +3,36 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/ontree-co/treeos/internal/cli"
 	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/configbundle"
 	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/embeds"
+	"github.com/ontree-co/treeos/internal/installer"
 	"github.com/ontree-co/treeos/internal/logging"
 	"github.com/ontree-co/treeos/internal/migration"
 	"github.com/ontree-co/treeos/internal/ontree"
+	"github.com/ontree-co/treeos/internal/selftest"
 	"github.com/ontree-co/treeos/internal/server"
 	"github.com/ontree-co/treeos/internal/telemetry"
+	"github.com/ontree-co/treeos/internal/templates"
+	"github.com/ontree-co/treeos/internal/userns"
 	"github.com/ontree-co/treeos/internal/version"
 )
 
@@ -43,6 +53,7 @@ func main() {
 
 	// Parse CLI flags before handling subcommands
 	demoMode := false
+	agentMode := embeds.Agent
 	showHelp := false
 	var portOverride string
 
@@ -61,6 +72,8 @@ func main() {
 		switch arg {
 		case "--demo":
 			demoMode = true
+		case "--agent":
+			agentMode = true
 		case "-p", "--port":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "Error: -p/--port requires a port value")
@@ -83,6 +96,11 @@ func main() {
 		os.Setenv("TREEOS_RUN_MODE", "demo") //nolint:errcheck,gosec // Test setup
 	}
 
+	// Agent binaries always run in agent mode
+	if agentMode {
+		os.Setenv("TREEOS_AGENT_MODE", "1") //nolint:errcheck,gosec // Config override
+	}
+
 	if portOverride != "" {
 		addr, err := normalizeListenAddr(portOverride)
 		if err != nil {
@@ -108,12 +126,27 @@ func main() {
 		os.Exit(0)
 	}
 
+	// The self-test only talks to a node over HTTP and needs no local configuration
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(runSelftest(os.Args[2:]))
+	}
+
+	// Template validation only reads the given directories
+	if len(os.Args) > 1 && os.Args[1] == "template" {
+		os.Exit(runTemplate(os.Args[2:]))
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
+	// Starting with an empty database because the old one was not moved would look like a new install
+	if err := config.PrepareDirs(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to prepare directories: %v\n", err)
+		os.Exit(1)
+	}
 
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
@@ -123,6 +156,12 @@ func main() {
 				os.Exit(1)
 			}
 			return
+		case "install-deps":
+			os.Exit(runInstallDeps(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(cfg, os.Args[2:]))
+		case "userns":
+			os.Exit(runUserns(cfg, os.Args[2:]))
 		case "migrate-to-compose":
 			// Initialize database for migration
 			if err := database.Initialize(cfg.DatabasePath); err != nil {
@@ -187,6 +226,21 @@ func main() {
 	// Get version information
 	versionInfo := version.Get()
 
+	// Restore a backup the recovery console scheduled before the database is opened
+	if restored, moved, err := database.ApplyScheduledRestore(cfg.DatabasePath); err != nil {
+		logging.Errorf("Failed to restore scheduled database backup %s: %v", restored, err)
+	} else if restored != "" {
+		logging.Infof("Restored database backup %s, previous database kept at %s", restored, moved)
+	}
+
+	// Boot into recovery mode instead of failing on a headless box when the database is corrupt
+	if err := database.CheckIntegrity(cfg.DatabasePath); err != nil {
+		if err := server.RunRecovery(cfg, err); err != nil {
+			fmt.Fprintf(os.Stderr, "Recovery mode failed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create and start server
 	srv, err := server.New(cfg, versionInfo)
 	if err != nil {
@@ -266,11 +320,321 @@ func runCLI(args []string) int {
 		return 1
 	}
 	defer manager.Close()
+	if err := manager.UseContainerEngine(context.Background()); err != nil {
+		logging.Warnf("Warning: No container engine found: %v", err)
+	}
 
 	cliManager := cli.NewManagerAdapter(manager)
 	return cli.Execute(filtered, cliManager, os.Stdout, os.Stderr)
 }
 
+// runInstallDeps installs the host dependencies for the detected distribution
+func runInstallDeps(args []string) int {
+	flags := flag.NewFlagSet("install-deps", flag.ContinueOnError)
+	runtimeName := flags.String("runtime", installer.RuntimeDocker, "Container runtime to install: docker or podman")
+	withTailscale := flags.Bool("tailscale", false, "Also install Tailscale")
+	force := flags.Bool("force", false, "Reinstall components that are already installed")
+	dryRun := flags.Bool("dry-run", false, "Print the steps without running them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	distro, err := installer.DetectDistro()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	steps, err := installer.Plan(distro, installer.Options{Runtime: *runtimeName, Tailscale: *withTailscale, Force: *force})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(steps) == 0 {
+		fmt.Println("✓ All dependencies are already installed (use --force to reinstall)")
+		return 0
+	}
+
+	if *dryRun {
+		fmt.Printf("Steps for %s:\n", distro.ID)
+		for _, step := range steps {
+			fmt.Printf("  # %s\n  %s\n", step.Description, step)
+		}
+		return 0
+	}
+
+	fmt.Printf("Installing dependencies for %s\n", distro.ID)
+	if err := installer.Run(context.Background(), steps, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println("✓ Dependencies installed, run the system check in TreeOS to verify")
+	return 0
+}
+
+func runUserns(cfg *config.Config, args []string) int {
+	command := "status"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	if command != "status" && command != "migrate" {
+		fmt.Fprintln(os.Stderr, "Usage: treeos userns [status] | treeos userns migrate [--dry-run]")
+		return 2
+	}
+	flags := flag.NewFlagSet("userns "+command, flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Show how many files would change without changing them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	mapping, err := userns.Detect(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !mapping.Enabled() {
+		fmt.Printf("✗ Containers run with the host's user IDs (%s), root in a container is root on the host\n", mapping.Runtime)
+		fmt.Println("  Enable userns-remap in /etc/docker/daemon.json or run Podman rootless, then run: treeos userns migrate")
+		if command == "migrate" {
+			return 1
+		}
+		return 0
+	}
+	fmt.Printf("✓ %s uses %s as %s: container root is host user %d, IDs 1-%d map to %d-%d\n",
+		mapping.Runtime, mapping.Mode, mapping.User, mapping.UIDs.Root,
+		mapping.UIDs.Size, mapping.UIDs.Start, mapping.UIDs.Start+mapping.UIDs.Size-1)
+	if command == "status" {
+		return 0
+	}
+
+	// Only bind-mounted data needs new owners, app definitions stay with TreeOS
+	dirs := []string{config.GetSharedPath()}
+	entries, err := os.ReadDir(cfg.AppsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(cfg.AppsDir, entry.Name(), "volumes"), filepath.Join(cfg.AppsDir, entry.Name(), "mnt"))
+		}
+	}
+
+	if !*dryRun {
+		fmt.Println("Stop all apps before migrating, containers writing during the migration keep the old owners")
+	}
+	failed := false
+	for _, dir := range dirs {
+		result, err := userns.ShiftOwnership(dir, mapping, *dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", dir, err)
+			failed = true
+			continue
+		}
+		if result.Changed+result.Unmapped == 0 {
+			continue
+		}
+		verb := "changed"
+		if *dryRun {
+			verb = "would change"
+		}
+		fmt.Printf("  %s: %s %d, %d already mapped", dir, verb, result.Changed, result.Current)
+		if result.Unmapped > 0 {
+			fmt.Printf(", %d owned by IDs outside the mapped range", result.Unmapped)
+		}
+		fmt.Println()
+	}
+	if failed {
+		return 1
+	}
+	fmt.Println("✓ App data ownership matches the user namespace mapping")
+	return 0
+}
+
+func runConfig(cfg *config.Config, args []string) int {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprintln(os.Stderr, "Usage: treeos config export [-o file] | treeos config import [--dry-run] <file>")
+		return 2
+	}
+	flags := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	output := flags.String("o", "", "Write the bundle to this file instead of stdout")
+	dryRun := flags.Bool("dry-run", false, "Show the changes without applying them")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if err := database.Initialize(cfg.DatabasePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize database: %v\n", err)
+		return 1
+	}
+	defer database.Close() //nolint:errcheck // Cleanup, error not critical
+
+	if args[0] == "export" {
+		bundle, err := configbundle.Export(cfg.AppsDir)
+		if err == nil {
+			var data []byte
+			if data, err = configbundle.Marshal(bundle); err == nil {
+				if *output == "" {
+					_, err = os.Stdout.Write(data)
+				} else {
+					err = os.WriteFile(*output, data, 0600)
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: treeos config import [--dry-run] <file>")
+		return 2
+	}
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	bundle, err := configbundle.Parse(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	result, err := configbundle.Import(cfg.AppsDir, bundle, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, change := range result.Changes {
+		fmt.Printf("  %s\n", change)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Printf("  ! %s\n", warning)
+	}
+	for _, app := range result.MissingApps {
+		fmt.Printf("  ! App %s is not installed, copy its directory into %s\n", app, cfg.AppsDir)
+	}
+	switch {
+	case len(result.Changes) == 0:
+		fmt.Println("✓ Nothing to change")
+	case *dryRun:
+		fmt.Printf("%d changes, run without --dry-run to apply\n", len(result.Changes))
+	default:
+		for username, password := range result.Passwords {
+			fmt.Printf("Temporary password for %s: %s\n", username, password)
+		}
+		fmt.Println("✓ Configuration imported, restart TreeOS to apply it")
+	}
+	return 0
+}
+
+// runSelftest runs the API self-test against a node and prints the pass/fail matrix
+func runSelftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	target := flags.String("target", "http://localhost:3000", "Base URL of the node")
+	token := flags.String("token", os.Getenv("API_TOKEN"), "API token of the node (default $API_TOKEN)")
+	app := flags.String("app", "", "Name of the throwaway app (default selftest-<random>)")
+	timeout := flags.Duration("timeout", 5*time.Minute, "How long to wait for the app to start, including the image pull")
+	jsonOutput := flags.Bool("json", false, "Print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := selftest.Run(ctx, selftest.Options{Target: *target, Token: *token, App: *app, StartTimeout: *timeout})
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Printf("Self-test of %s with app %s\n\n", report.Target, report.App)
+		if err := report.Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if !report.Passed() {
+		if !*jsonOutput {
+			fmt.Println("\n✗ Self-test failed")
+		}
+		return 1
+	}
+	if !*jsonOutput {
+		fmt.Println("\n✓ Self-test passed")
+	}
+	return 0
+}
+
+// runTemplate handles the template subcommands, only validate for now
+func runTemplate(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "Usage: treeos template validate [--json] <dir>...")
+		return 2
+	}
+	flags := flag.NewFlagSet("template validate", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "Print the reports as JSON")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: treeos template validate [--json] <dir>...")
+		return 2
+	}
+
+	reports := make([]*templates.ValidationReport, 0, flags.NArg())
+	valid := true
+	for _, dir := range flags.Args() {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		bundle, err := templates.ReadBundle(os.DirFS(filepath.Dir(abs)), filepath.Base(abs))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		report := templates.ValidateBundle(bundle)
+		valid = valid && report.Valid
+		reports = append(reports, report)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, report := range reports {
+			mark := "✓"
+			if !report.Valid {
+				mark = "✗"
+			}
+			fmt.Printf("%s %s: %d errors, %d warnings\n", mark, report.Template, report.Errors, report.Warnings)
+			for _, issue := range report.Issues {
+				location := issue.File
+				if issue.Field != "" {
+					location += " " + issue.Field
+				}
+				fmt.Printf("  %-7s [%s] %s: %s\n", issue.Severity, issue.Check, location, issue.Message)
+			}
+		}
+	}
+	if !valid {
+		return 1
+	}
+	return 0
+}
+
 func getAppsDir() string {
 	// Load configuration to get the apps directory
 	cfg, err := config.Load()
@@ -305,11 +669,19 @@ func printHelp() {
 	fmt.Println("Commands:")
 	fmt.Println("  setup-dirs            Prepare required directories on the host")
 	fmt.Println("  migrate-to-compose    Convert existing deployments to Docker Compose")
+	fmt.Println("  install-deps          Install Docker (or Podman), Caddy and optionally Tailscale")
+	fmt.Println("  config export         Write settings, users and apps as a YAML bundle (-o file)")
+	fmt.Println("  config import <file>  Apply a YAML bundle (--dry-run to preview)")
+	fmt.Println("  selftest              Test the API of a node (--target URL --token TOKEN)")
+	fmt.Println("  template validate     Check template directories for the catalog (--json for a report)")
+	fmt.Println("  userns [status]       Show how container user IDs map to the host")
+	fmt.Println("  userns migrate        Give app data the owners of the user namespace (--dry-run to preview)")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  --help, -h            Show this help message")
 	fmt.Println("  --version             Show version information")
 	fmt.Println("  --demo                Run using local demo directories")
+	fmt.Println("  --agent               Run as a headless node managed over the API (needs api_token)")
 	fmt.Println("  -p, --port <port>     Override the HTTP listen port (e.g., 4001 or :4001)")
 }
 
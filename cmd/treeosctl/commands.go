@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ontree-co/treeos/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newAppsCommand() *cobra.Command {
+	apps := &cobra.Command{
+		Use:     "apps",
+		Aliases: []string{"app"},
+		Short:   "List and control apps",
+	}
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the apps with their status",
+		Args:    exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			list, err := c.ListApps(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return write(cmd, list, func() [][]string {
+				rows := [][]string{{"NAME", "STATUS", "SERVICES", "ERROR"}}
+				for _, app := range list {
+					rows = append(rows, []string{app.Name, app.Status, orDash(strings.Join(app.Services, ",")), orDash(app.Error)})
+				}
+				return rows
+			})
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status <app>",
+		Short: "Show the status of an app's services",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			status, err := c.AppStatus(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return write(cmd, status, func() [][]string {
+				rows := [][]string{{"SERVICE", "STATUS", "HEALTH", "IMAGE"}}
+				for _, service := range status.Services {
+					rows = append(rows, []string{service.Name, service.Status, orDash(service.Health), service.Image})
+				}
+				return rows
+			})
+		},
+	}
+
+	startCmd := &cobra.Command{
+		Use:   "start <app>",
+		Short: "Start an app",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			resp, err := c.StartApp(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if wait, _ := cmd.Flags().GetBool("wait"); wait {
+				job, err := c.WaitForJob(cmd.Context(), client.JobKindApp, args[0])
+				if err != nil {
+					return err
+				}
+				if job.State != "completed" {
+					return fmt.Errorf("starting %s %s: %s", args[0], job.State, job.Error)
+				}
+				resp.Message = fmt.Sprintf("App '%s' started", args[0])
+			}
+			return writeAction(cmd, resp)
+		},
+	}
+	startCmd.Flags().Bool("wait", false, "wait until the app is up")
+
+	stopCmd := &cobra.Command{
+		Use:   "stop <app>",
+		Short: "Stop an app, keeping its volumes",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			stop := c.StopApp
+			if force, _ := cmd.Flags().GetBool("force"); force {
+				stop = c.ForceStopApp
+			}
+			resp, err := stop(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return writeAction(cmd, resp)
+		},
+	}
+	stopCmd.Flags().Bool("force", false, "stop the app even if running apps depend on it")
+
+	// restart and apply recreate containers of a running app as the app job
+	recreateCmd := func(use, short, done string, run func(*client.Client, context.Context, string) (*client.AppActionResponse, error)) *cobra.Command {
+		cmd := &cobra.Command{
+			Use:   use + " <app>",
+			Short: short,
+			Args:  exactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				c, err := newClient(cmd)
+				if err != nil {
+					return err
+				}
+				resp, err := run(c, cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				if wait, _ := cmd.Flags().GetBool("wait"); wait {
+					job, err := c.WaitForJob(cmd.Context(), client.JobKindApp, args[0])
+					if err != nil {
+						return err
+					}
+					if job.State != "completed" {
+						return fmt.Errorf("%s %s %s: %s", use, args[0], job.State, job.Error)
+					}
+					resp.Message = fmt.Sprintf("App '%s' %s", args[0], done)
+				}
+				return writeAction(cmd, resp)
+			},
+		}
+		cmd.Flags().Bool("wait", false, "wait until the containers are recreated")
+		return cmd
+	}
+	restartCmd := recreateCmd("restart", "Recreate all containers of a running app", "restarted", (*client.Client).RestartApp)
+	applyCmd := recreateCmd("apply", "Recreate the services of a running app whose configuration was edited", "updated", (*client.Client).ApplyApp)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <app>",
+		Short: "Stop an app and delete it with its volumes",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				return &usageError{err: fmt.Errorf("deleting %s removes its volumes, confirm with --yes", args[0])}
+			}
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			resp, err := c.DeleteApp(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return writeAction(cmd, resp)
+		},
+	}
+	deleteCmd.Flags().Bool("yes", false, "confirm that the app and its data are deleted")
+
+	apps.AddCommand(listCmd, statusCmd, startCmd, stopCmd, restartCmd, applyCmd, deleteCmd)
+	return apps
+}
+
+// writeAction prints the result of a change to an app
+func writeAction(cmd *cobra.Command, resp *client.AppActionResponse) error {
+	return write(cmd, resp, func() [][]string {
+		return [][]string{{resp.Message}}
+	})
+}
+
+func newLogsCommand() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:   "logs <app>",
+		Short: "Print the logs of an app",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			service, _ := cmd.Flags().GetString("service")
+			follow, _ := cmd.Flags().GetBool("follow")
+			logs, err := c.AppLogs(cmd.Context(), args[0], service, follow)
+			if err != nil {
+				return err
+			}
+			defer logs.Close() //nolint:errcheck // Cleanup, error not critical
+			// Logs are text in both formats, a followed stream ends with Ctrl+C
+			if _, err := io.Copy(cmd.OutOrStdout(), logs); err != nil && cmd.Context().Err() == nil {
+				return err
+			}
+			return nil
+		},
+	}
+	logsCmd.Flags().String("service", "", "only the logs of this service")
+	logsCmd.Flags().BoolP("follow", "f", false, "keep printing new lines")
+	return logsCmd
+}
+
+func newBackupCommand() *cobra.Command {
+	backup := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the node's database",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			backups, err := c.CreateBackup(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if len(backups) == 0 {
+				return errors.New("the node reported no backups")
+			}
+			return writeBackups(cmd, backups[:1])
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the database backups, newest first",
+		Args:    exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			backups, err := c.Backups(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return writeBackups(cmd, backups)
+		},
+	}
+
+	backup.AddCommand(listCmd)
+	return backup
+}
+
+// writeBackups prints database backups
+func writeBackups(cmd *cobra.Command, backups []client.Backup) error {
+	return write(cmd, backups, func() [][]string {
+		rows := [][]string{{"NAME", "SIZE", "CREATED"}}
+		for _, backup := range backups {
+			rows = append(rows, []string{backup.Name, strconv.FormatInt(backup.Size, 10), formatTime(backup.CreatedAt)})
+		}
+		return rows
+	})
+}
+
+func newUpdateCommand() *cobra.Command {
+	update := &cobra.Command{
+		Use:   "update",
+		Short: "Check for TreeOS updates",
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the running or last update",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			status, err := c.UpdateStatus(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return write(cmd, status, func() [][]string {
+				state := "idle"
+				switch {
+				case status.InProgress:
+					state = fmt.Sprintf("in progress, %s %.0f%%", orDash(status.Stage), status.Percentage)
+				case status.Failed:
+					state = "failed: " + status.Error
+				case status.Success && status.RestartRequired:
+					state = "installed, restart required"
+				case status.Success:
+					state = "installed"
+				}
+				return [][]string{
+					{"STATE", "CURRENT", "AVAILABLE", "UPDATED"},
+					{state, orDash(status.CurrentVersion), orDash(status.AvailableVersion), formatTime(status.UpdatedAt)},
+				}
+			})
+		},
+	}
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Ask the update server for a newer release",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			info, err := c.CheckForUpdate(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return write(cmd, info, func() [][]string {
+				return [][]string{
+					{"CURRENT", "LATEST", "UPDATE AVAILABLE"},
+					{info.CurrentVersion, info.LatestVersion, strconv.FormatBool(info.UpdateAvailable)},
+				}
+			})
+		},
+	}
+
+	update.AddCommand(statusCmd, checkCmd)
+	return update
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Show the version of the node",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			info, err := c.Version(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return write(cmd, info, func() [][]string {
+				return [][]string{
+					{"VERSION", "COMMIT", "BUILT", "PLATFORM"},
+					{info.Version, info.Commit, info.BuildDate, info.Platform},
+				}
+			})
+		},
+	}
+}
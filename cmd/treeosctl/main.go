@@ -0,0 +1,149 @@
+// Package main is treeosctl, a command line client for the HTTP API of a TreeOS node.
+// It authenticates with an API token and prints tables for people or JSON for scripts.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ontree-co/treeos/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes, as for the app and model commands of treeos
+const (
+	exitSuccess      = 0
+	exitRuntimeError = 1
+	exitInvalidUsage = 2
+)
+
+// defaultURL is the node on this machine, at the default port of treeos
+const defaultURL = "http://localhost:3000"
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Getenv, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs treeosctl with args and returns the exit code. getenv reads TREEOS_URL and
+// TREEOS_TOKEN, the defaults of --url and --token.
+func run(ctx context.Context, args []string, getenv func(string) string, out, errOut io.Writer) int {
+	root := newRootCommand(getenv)
+	root.SetArgs(args)
+	root.SetOut(out)
+	root.SetErr(errOut)
+	if err := root.ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(errOut, "Error:", err) //nolint:errcheck // Nothing left to report to
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			return exitInvalidUsage
+		}
+		return exitRuntimeError
+	}
+	return exitSuccess
+}
+
+// usageError is a command used wrongly, as opposed to a failed request
+type usageError struct {
+	err error
+}
+
+func (u *usageError) Error() string {
+	return u.err.Error()
+}
+
+func newRootCommand(getenv func(string) string) *cobra.Command {
+	root := &cobra.Command{
+		Use:           "treeosctl",
+		Short:         "Manage a TreeOS node over its HTTP API",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	url := getenv("TREEOS_URL")
+	if url == "" {
+		url = defaultURL
+	}
+	root.PersistentFlags().String("url", url, "URL of the node, $TREEOS_URL")
+	root.PersistentFlags().String("token", getenv("TREEOS_TOKEN"), "API token, $TREEOS_TOKEN")
+	root.PersistentFlags().StringP("output", "o", "table", "output format: table or json")
+	root.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
+
+	root.AddCommand(newAppsCommand(), newLogsCommand(), newBackupCommand(), newUpdateCommand(), newVersionCommand())
+	return root
+}
+
+// newClient returns a client for the node of the command's flags
+func newClient(cmd *cobra.Command) (*client.Client, error) {
+	url, _ := cmd.Flags().GetString("url")
+	token, _ := cmd.Flags().GetString("token")
+	if token == "" {
+		return nil, &usageError{err: errors.New("an API token is required, set --token or TREEOS_TOKEN")}
+	}
+	c, err := client.New(url)
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+	c.SetToken(token)
+	return c, nil
+}
+
+// exactArgs requires n arguments, reported as a usage error
+func exactArgs(n int) cobra.PositionalArgs {
+	return func(_ *cobra.Command, args []string) error {
+		if len(args) != n {
+			return &usageError{err: fmt.Errorf("requires %d argument(s), got %d", n, len(args))}
+		}
+		return nil
+	}
+}
+
+// write prints v as JSON with -o json, otherwise as the table rows returns. The first
+// row is the header.
+func write(cmd *cobra.Command, v interface{}, rows func() [][]string) error {
+	format, _ := cmd.Flags().GetString("output")
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "table":
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		for _, row := range rows() {
+			if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	default:
+		return &usageError{err: fmt.Errorf("unknown output format %q, use table or json", format)}
+	}
+}
+
+// formatTime prints a time in the local zone, or - for none
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// orDash returns s, or - when it's empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestNode(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/apps", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"apps":[{"name":"wiki","status":"running","services":["db","web"]}]}`)) //nolint:errcheck,gosec // Test response
+	})
+	mux.HandleFunc("POST /api/apps/wiki/stop", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"success":true,"message":"App 'wiki' stopped"}`)) //nolint:errcheck,gosec // Test response
+	})
+	mux.HandleFunc("DELETE /api/apps/wiki", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"success":true,"message":"App 'wiki' deleted"}`)) //nolint:errcheck,gosec // Test response
+	})
+	mux.HandleFunc("POST /api/apps/missing/start", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "App 'missing' not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /api/system/backups", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"backups":[{"name":"treeos-new.db","size":4096,"created_at":"2026-10-16T10:00:00Z"},{"name":"treeos-old.db","size":2048}]}`)) //nolint:errcheck,gosec // Test response
+	})
+	mux.HandleFunc("GET /api/apps/wiki/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("web | ready service=" + r.URL.Query().Get("service") + "\n")) //nolint:errcheck,gosec // Test response
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer treeos_test" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRun(t *testing.T) {
+	srv := newTestNode(t)
+	env := map[string]string{"TREEOS_URL": srv.URL, "TREEOS_TOKEN": "treeos_test"}
+	treeosctl := func(args ...string) (int, string, string) {
+		var out, errOut bytes.Buffer
+		code := run(context.Background(), args, func(key string) string { return env[key] }, &out, &errOut)
+		return code, out.String(), errOut.String()
+	}
+
+	code, out, _ := treeosctl("apps", "list")
+	if code != exitSuccess || !strings.Contains(out, "NAME") || !strings.Contains(out, "wiki  running  db,web") {
+		t.Errorf("apps list = %d %q", code, out)
+	}
+	code, out, _ = treeosctl("apps", "ls", "-o", "json")
+	var apps []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &apps); code != exitSuccess || err != nil || len(apps) != 1 || apps[0]["name"] != "wiki" {
+		t.Errorf("apps ls -o json = %d %q", code, out)
+	}
+
+	if code, out, _ := treeosctl("apps", "stop", "wiki"); code != exitSuccess || out != "App 'wiki' stopped\n" {
+		t.Errorf("apps stop = %d %q", code, out)
+	}
+	if code, _, errOut := treeosctl("apps", "delete", "wiki"); code != exitInvalidUsage || !strings.Contains(errOut, "--yes") {
+		t.Errorf("apps delete without --yes = %d %q", code, errOut)
+	}
+	if code, out, _ := treeosctl("apps", "delete", "wiki", "--yes"); code != exitSuccess || !strings.Contains(out, "deleted") {
+		t.Errorf("apps delete --yes = %d %q", code, out)
+	}
+	if code, _, errOut := treeosctl("apps", "start", "missing"); code != exitRuntimeError || !strings.Contains(errOut, "App 'missing' not found") {
+		t.Errorf("apps start missing = %d %q", code, errOut)
+	}
+
+	if code, out, _ := treeosctl("logs", "wiki", "--service", "web"); code != exitSuccess || out != "web | ready service=web\n" {
+		t.Errorf("logs = %d %q", code, out)
+	}
+	code, out, _ = treeosctl("backup")
+	if code != exitSuccess || !strings.Contains(out, "treeos-new.db") || strings.Contains(out, "treeos-old.db") {
+		t.Errorf("backup = %d %q", code, out)
+	}
+
+	if code, _, _ := treeosctl("apps", "list", "-o", "yaml"); code != exitInvalidUsage {
+		t.Errorf("unknown output format = %d, want %d", code, exitInvalidUsage)
+	}
+	if code, _, _ := treeosctl("apps", "status"); code != exitInvalidUsage {
+		t.Errorf("apps status without an app = %d, want %d", code, exitInvalidUsage)
+	}
+	delete(env, "TREEOS_TOKEN")
+	if code, _, errOut := treeosctl("apps", "list"); code != exitInvalidUsage || !strings.Contains(errOut, "TREEOS_TOKEN") {
+		t.Errorf("apps list without token = %d %q", code, errOut)
+	}
+	if code, _, _ := treeosctl("apps", "list", "--token", "wrong"); code != exitRuntimeError {
+		t.Errorf("apps list with a wrong token = %d, want %d", code, exitRuntimeError)
+	}
+}
@@ -0,0 +1,205 @@
+// Package a11y checks the dashboard templates for elements that screen readers cannot name.
+// The checks read the template source, so they see every branch of a template but not the
+// markup that scripts add at runtime. Attributes inside template actions count as present.
+package a11y
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Rules reported by Audit
+const (
+	RuleImageAlt     = "image-alt"     // <img> without alt
+	RuleControlLabel = "control-label" // Form control without label
+	RuleControlName  = "control-name"  // Button or link without text
+	RuleDocumentLang = "document-lang" // <html> without lang
+)
+
+// Issue is an element of a template that fails a rule
+type Issue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Element string `json:"element"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Audit checks the .html files of fsys, issues are sorted by file and line
+func Audit(fsys fs.FS) ([]Issue, error) {
+	var issues []Issue
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".html" {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, AuditFile(name, content)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues, nil
+}
+
+// element is a start tag with the line it is on
+type element struct {
+	name  string
+	attrs map[string]string
+	line  int
+	text  bool // Button or link has content a screen reader reads
+}
+
+// AuditFile checks one template
+func AuditFile(name string, content []byte) []Issue {
+	var (
+		issues   []Issue
+		controls []element           // Form controls, checked at the end when all labels are known
+		labelFor = map[string]bool{} // Ids named by <label for>
+		labels   int                 // Open <label> elements
+		named    []*element          // Open buttons and links
+	)
+	report := func(e element, rule, message string) {
+		issues = append(issues, Issue{File: name, Line: e.line, Element: e.name, Rule: rule, Message: message})
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	line := 1
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			// io.EOF, the tokenizer reads any input to the end
+			break
+		}
+		start := line
+		line += bytes.Count(tokenizer.Raw(), []byte("\n"))
+		token := tokenizer.Token()
+
+		switch tokenType {
+		case html.TextToken:
+			if strings.TrimSpace(token.Data) != "" {
+				for _, e := range named {
+					e.text = true
+				}
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			e := element{name: token.Data, attrs: map[string]string{}, line: start}
+			for _, attr := range token.Attr {
+				e.attrs[attr.Key] = attr.Val
+			}
+			switch e.name {
+			case "html":
+				if _, ok := e.attrs["lang"]; !ok {
+					report(e, RuleDocumentLang, "The page has no lang attribute, screen readers guess the language")
+				}
+			case "img":
+				if _, ok := e.attrs["alt"]; !ok {
+					report(e, RuleImageAlt, `Image has no alt text, use alt="" for decorative images`)
+				} else if e.attrs["alt"] != "" {
+					for _, open := range named {
+						open.text = true
+					}
+				}
+			case "label":
+				if tokenType == html.StartTagToken {
+					labels++
+				}
+				if id := e.attrs["for"]; id != "" {
+					labelFor[id] = true
+				}
+			case "input", "select", "textarea":
+				if !isLabelled(e) && labels == 0 && needsLabel(e) {
+					controls = append(controls, e)
+				}
+			case "button", "a":
+				if isLabelled(e) {
+					break
+				}
+				if tokenType == html.SelfClosingTagToken {
+					reportUnnamed(report, e)
+					break
+				}
+				named = append(named, &e)
+			}
+		case html.EndTagToken:
+			switch token.Data {
+			case "label":
+				if labels > 0 {
+					labels--
+				}
+			case "button", "a":
+				// Close the innermost open element of that name, unclosed ones inside are dropped
+				for i := len(named) - 1; i >= 0; i-- {
+					if named[i].name != token.Data {
+						continue
+					}
+					if !named[i].text {
+						reportUnnamed(report, *named[i])
+					}
+					named = named[:i]
+					break
+				}
+			}
+		}
+	}
+
+	for _, e := range controls {
+		if labelFor[e.attrs["id"]] && e.attrs["id"] != "" {
+			continue
+		}
+		report(e, RuleControlLabel, "Form control has no label, add a <label for>, aria-label or title")
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// isLabelled reports whether an element is named by an attribute
+func isLabelled(e element) bool {
+	for _, attr := range []string{"aria-label", "aria-labelledby", "title"} {
+		if strings.TrimSpace(e.attrs[attr]) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// needsLabel reports whether a form control is announced by its label. Hidden inputs are not
+// announced and buttons are named by their value.
+func needsLabel(e element) bool {
+	if e.name != "input" {
+		return true
+	}
+	switch strings.ToLower(e.attrs["type"]) {
+	case "hidden", "submit", "reset", "button":
+		return false
+	case "image":
+		return e.attrs["alt"] == ""
+	}
+	return true
+}
+
+// reportUnnamed reports a button or link without text
+func reportUnnamed(report func(element, string, string), e element) {
+	what := "Button"
+	if e.name == "a" {
+		what = "Link"
+	}
+	report(e, RuleControlName, what+" has no text, add an aria-label for icon-only controls")
+}
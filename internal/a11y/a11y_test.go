@@ -0,0 +1,71 @@
+package a11y
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestAuditFile(t *testing.T) {
+	content := `<html>
+<body>
+  <img src="/logo.png">
+  <img src="/divider.png" alt="">
+  <label for="name">Name</label>
+  <input type="text" id="name">
+  <input type="text" id="email">
+  <label>Port <input type="number"></label>
+  <input type="hidden" name="csrf">
+  <input type="search" aria-label="Search">
+  <select id="level"></select>
+  <textarea placeholder="Notes"></textarea>
+  <button><i class="bi bi-x"></i></button>
+  <button aria-label="Close"><i class="bi bi-x"></i></button>
+  <button>{{.Label}}</button>
+  <a href="/"><img src="/home.png" alt="Home"></a>
+  <a href="/settings"><svg></svg></a>
+</body>
+</html>`
+
+	issues := AuditFile("page.html", []byte(content))
+	want := []struct {
+		line int
+		rule string
+	}{
+		{1, RuleDocumentLang},
+		{3, RuleImageAlt},
+		{7, RuleControlLabel},
+		{11, RuleControlLabel},
+		{12, RuleControlLabel},
+		{13, RuleControlName},
+		{17, RuleControlName},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d: %+v", len(issues), len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].Line != w.line || issues[i].Rule != w.rule || issues[i].File != "page.html" {
+			t.Errorf("issue %d = %+v, want line %d rule %s", i, issues[i], w.line, w.rule)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":   {Data: []byte(`<html lang="en"><button></button></html>`)},
+		"dashboard/page.html": {Data: []byte("<p>\n<img src=\"x.png\"></p>")},
+		"static/app.js":       {Data: []byte(`document.body.innerHTML = "<img>"`)},
+	}
+	issues, err := Audit(fsys)
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("got %d issues, want 2: %+v", len(issues), issues)
+	}
+	if issues[0].File != "dashboard/page.html" || issues[0].Line != 2 || issues[0].Rule != RuleImageAlt {
+		t.Errorf("issues[0] = %+v", issues[0])
+	}
+	if issues[1].File != "layouts/base.html" || issues[1].Rule != RuleControlName {
+		t.Errorf("issues[1] = %+v", issues[1])
+	}
+}
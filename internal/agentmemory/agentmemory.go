@@ -0,0 +1,322 @@
+// Package agentmemory exports the operational context the agent has accumulated on a node
+// (the per-app chat histories and the health review reports) as a single JSON file and
+// imports it on another node, so rebuilding or replacing a node doesn't lose it.
+//
+// Pending agent actions are not part of the archive: they were suggested for the state of the
+// old node and have to be suggested again by a review on the new one.
+package agentmemory
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+// Version is the archive format written by Export
+const Version = 1
+
+// Archive is the exported agent memory of a node
+type Archive struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Node       string    `json:"node,omitempty"` // Name of the exporting node
+	Chats      []AppChat `json:"chats"`
+	Reviews    []Review  `json:"reviews"`
+}
+
+// AppChat is the chat history of one app, oldest message first
+type AppChat struct {
+	App      string    `json:"app"`
+	Messages []Message `json:"messages"`
+}
+
+// Message is a chat message without its database ID
+type Message struct {
+	SenderType    string    `json:"sender_type"`
+	SenderName    string    `json:"sender_name"`
+	Message       string    `json:"message"`
+	AgentModel    string    `json:"agent_model,omitempty"`
+	AgentProvider string    `json:"agent_provider,omitempty"`
+	StatusLevel   string    `json:"status_level,omitempty"`
+	Details       string    `json:"details,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Review is a completed health review
+type Review struct {
+	Summary     string                  `json:"summary"`
+	Findings    []database.AgentFinding `json:"findings"`
+	Model       string                  `json:"model,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	CompletedAt *time.Time              `json:"completed_at,omitempty"`
+}
+
+// ImportResult describes what an import added, or would add in a dry run
+type ImportResult struct {
+	DryRun   bool     `json:"dry_run"`
+	Messages int      `json:"messages"`
+	Reviews  int      `json:"reviews"`
+	Changes  []string `json:"changes"`
+	Warnings []string `json:"warnings"`
+}
+
+// Marshal encodes an archive as JSON
+func Marshal(archive *Archive) ([]byte, error) {
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archive: %w", err)
+	}
+	return data, nil
+}
+
+// Parse decodes an archive and checks its version
+func Parse(data []byte) (*Archive, error) {
+	var archive Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("failed to parse archive: %w", err)
+	}
+	if archive.Version != Version {
+		return nil, fmt.Errorf("unsupported archive version %d, expected %d", archive.Version, Version)
+	}
+	for _, chat := range archive.Chats {
+		if chat.App == "" {
+			return nil, errors.New("archive contains a chat without app")
+		}
+		for _, message := range chat.Messages {
+			switch message.SenderType {
+			case database.SenderTypeUser, database.SenderTypeAgent, database.SenderTypeSystem:
+			default:
+				return nil, fmt.Errorf("invalid sender type %q in the chat of %s", message.SenderType, chat.App)
+			}
+		}
+	}
+	return &archive, nil
+}
+
+// Export reads the chat histories and completed reviews from the database
+func Export() (*Archive, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	archive := &Archive{Version: Version, ExportedAt: time.Now().UTC(), Chats: []AppChat{}, Reviews: []Review{}}
+	var nodeName sql.NullString
+	if err := db.QueryRow(`SELECT node_name FROM system_setup WHERE id = 1`).Scan(&nodeName); err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read node name: %w", err)
+	}
+	archive.Node = nodeName.String
+
+	chats, err := readChats(db)
+	if err != nil {
+		return nil, err
+	}
+	archive.Chats = chats
+
+	rows, err := db.Query(`
+		SELECT summary, findings, model, created_at, completed_at
+		FROM agent_reviews
+		WHERE status = ?
+		ORDER BY created_at ASC, id ASC
+	`, database.ReviewStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent reviews: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	for rows.Next() {
+		var review Review
+		var findings string
+		var completedAt sql.NullTime
+		if err := rows.Scan(&review.Summary, &findings, &review.Model, &review.CreatedAt, &completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan agent review: %w", err)
+		}
+		if err := json.Unmarshal([]byte(findings), &review.Findings); err != nil {
+			return nil, fmt.Errorf("failed to decode agent review findings: %w", err)
+		}
+		if completedAt.Valid {
+			review.CompletedAt = &completedAt.Time
+		}
+		archive.Reviews = append(archive.Reviews, review)
+	}
+	return archive, rows.Err()
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func readChats(q queryer) ([]AppChat, error) {
+	rows, err := q.Query(`
+		SELECT app_id, sender_type, sender_name, message, COALESCE(agent_model, ''), COALESCE(agent_provider, ''),
+		       COALESCE(status_level, ''), COALESCE(details, ''), timestamp
+		FROM chat_messages
+		ORDER BY app_id, timestamp ASC, id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat messages: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	chats := []AppChat{}
+	for rows.Next() {
+		var app string
+		var m Message
+		if err := rows.Scan(&app, &m.SenderType, &m.SenderName, &m.Message, &m.AgentModel, &m.AgentProvider,
+			&m.StatusLevel, &m.Details, &m.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		if len(chats) == 0 || chats[len(chats)-1].App != app {
+			chats = append(chats, AppChat{App: app})
+		}
+		chats[len(chats)-1].Messages = append(chats[len(chats)-1].Messages, m)
+	}
+	return chats, rows.Err()
+}
+
+// messageKey identifies a message across nodes, the database IDs differ
+func messageKey(app string, m Message) string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%s", app, m.SenderType, m.Timestamp.Unix(), m.Message)
+}
+
+// Import adds the messages and reviews of an archive that the node doesn't have yet. Nothing
+// is deleted or overwritten, so importing the same archive twice changes nothing. Chats of
+// apps that aren't installed are imported too and show up once an app with that name exists.
+// With dryRun nothing is changed.
+func Import(archive *Archive, installedApps []string, dryRun bool) (*ImportResult, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	result := &ImportResult{DryRun: dryRun, Changes: []string{}, Warnings: []string{}}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start import: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit, discards dry runs
+
+	if err := importChats(tx, archive.Chats, installedApps, result); err != nil {
+		return nil, err
+	}
+	if err := importReviews(tx, archive.Reviews, result); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return result, nil
+}
+
+func importChats(tx *sql.Tx, chats []AppChat, installedApps []string, result *ImportResult) error {
+	existing, err := readChats(tx)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, chat := range existing {
+		for _, m := range chat.Messages {
+			known[messageKey(chat.App, m)] = true
+		}
+	}
+	installed := map[string]bool{}
+	for _, app := range installedApps {
+		installed[app] = true
+	}
+
+	for _, chat := range chats {
+		added := 0
+		for _, m := range chat.Messages {
+			key := messageKey(chat.App, m)
+			if known[key] {
+				continue
+			}
+			known[key] = true
+			_, err := tx.Exec(`
+				INSERT INTO chat_messages (app_id, timestamp, message, sender_type, sender_name, agent_model, agent_provider, status_level, details)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, chat.App, m.Timestamp.UTC(), m.Message, m.SenderType, m.SenderName, nullString(m.AgentModel),
+				nullString(m.AgentProvider), nullString(m.StatusLevel), nullString(m.Details))
+			if err != nil {
+				return fmt.Errorf("failed to import chat message of %s: %w", chat.App, err)
+			}
+			added++
+		}
+		if added == 0 {
+			continue
+		}
+		result.Messages += added
+		result.Changes = append(result.Changes, fmt.Sprintf("Add %d chat messages to %s", added, chat.App))
+		if !installed[chat.App] {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("App %s is not installed, its chat shows up once it is", chat.App))
+		}
+	}
+	return nil
+}
+
+func importReviews(tx *sql.Tx, reviews []Review, result *ImportResult) error {
+	known := map[string]bool{}
+	rows, err := tx.Query(`SELECT summary, created_at FROM agent_reviews`)
+	if err != nil {
+		return fmt.Errorf("failed to query agent reviews: %w", err)
+	}
+	for rows.Next() {
+		var summary string
+		var createdAt time.Time
+		if err := rows.Scan(&summary, &createdAt); err != nil {
+			rows.Close() //nolint:errcheck,gosec // Returning the scan error
+			return fmt.Errorf("failed to scan agent review: %w", err)
+		}
+		known[fmt.Sprintf("%d\x00%s", createdAt.Unix(), summary)] = true
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to read agent reviews: %w", err)
+	}
+
+	added := 0
+	for _, review := range reviews {
+		key := fmt.Sprintf("%d\x00%s", review.CreatedAt.Unix(), review.Summary)
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		findings := review.Findings
+		if findings == nil {
+			findings = []database.AgentFinding{}
+		}
+		data, err := json.Marshal(findings)
+		if err != nil {
+			return fmt.Errorf("failed to encode agent review findings: %w", err)
+		}
+		var completedAt interface{}
+		if review.CompletedAt != nil {
+			completedAt = review.CompletedAt.UTC()
+		}
+		_, err = tx.Exec(`
+			INSERT INTO agent_reviews (status, summary, findings, model, created_at, completed_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, database.ReviewStatusCompleted, review.Summary, string(data), review.Model, review.CreatedAt.UTC(), completedAt)
+		if err != nil {
+			return fmt.Errorf("failed to import agent review: %w", err)
+		}
+		added++
+	}
+	if added > 0 {
+		result.Reviews = added
+		result.Changes = append(result.Changes, fmt.Sprintf("Add %d health review reports", added))
+	}
+	return nil
+}
+
+// nullString stores empty strings as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,112 @@
+package agentmemory
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func setupDatabase(t *testing.T) {
+	t.Helper()
+	if err := database.Initialize(filepath.Join(t.TempDir(), "ontree.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() }) //nolint:errcheck,gosec // Test cleanup
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	setupDatabase(t)
+	for _, m := range []database.ChatMessage{
+		{AppID: "nextcloud", SenderType: database.SenderTypeUser, SenderName: "admin", Message: "Why is cron failing?"},
+		{AppID: "nextcloud", SenderType: database.SenderTypeAgent, SenderName: "Agent", Message: "The database was restarting.", AgentModel: "llama3.1"},
+		{AppID: "web", SenderType: database.SenderTypeUser, SenderName: "admin", Message: "Hi"},
+	} {
+		if _, err := database.AddChatMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reviewID, err := database.CreateAgentReview("llama3.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := []database.AgentFinding{{App: "nextcloud", Severity: database.SeverityWarning, Summary: "Repeated cron failures."}}
+	if err := database.CompleteAgentReview(reviewID, "1 warning", findings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.CreateAgentReview("llama3.1"); err != nil { // Still running, not exported
+		t.Fatal(err)
+	}
+
+	archive, err := Export()
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(archive.Chats) != 2 || archive.Chats[0].App != "nextcloud" || len(archive.Chats[0].Messages) != 2 {
+		t.Fatalf("chats = %+v", archive.Chats)
+	}
+	if len(archive.Reviews) != 1 || archive.Reviews[0].Findings[0].Summary != "Repeated cron failures." {
+		t.Fatalf("reviews = %+v", archive.Reviews)
+	}
+	data, err := Marshal(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Import on a fresh node
+	setupDatabase(t)
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	result, err := Import(parsed, []string{"nextcloud"}, true)
+	if err != nil {
+		t.Fatalf("Import(dry run) error = %v", err)
+	}
+	if result.Messages != 3 || result.Reviews != 1 || len(result.Warnings) != 1 {
+		t.Errorf("dry run result = %+v, want 3 messages, 1 review and a warning for web", result)
+	}
+	if messages, _ := database.GetChatMessages("nextcloud", 10); len(messages) != 0 {
+		t.Errorf("dry run imported %d messages", len(messages))
+	}
+
+	if _, err := Import(parsed, []string{"nextcloud"}, false); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	messages, err := database.GetChatMessages("nextcloud", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Message != "Why is cron failing?" || messages[1].AgentModel != "llama3.1" {
+		t.Errorf("imported messages = %+v", messages)
+	}
+	reviews, err := database.GetAgentReviews(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 1 || reviews[0].Status != database.ReviewStatusCompleted || len(reviews[0].Findings) != 1 {
+		t.Errorf("imported reviews = %+v", reviews)
+	}
+
+	// Importing again adds nothing
+	result, err = Import(parsed, []string{"nextcloud"}, false)
+	if err != nil {
+		t.Fatalf("Import() again error = %v", err)
+	}
+	if len(result.Changes) != 0 {
+		t.Errorf("second import changes = %v, want none", result.Changes)
+	}
+}
+
+func TestParseRejectsInvalidArchives(t *testing.T) {
+	for name, data := range map[string]string{
+		"version":     `{"version": 2}`,
+		"app":         `{"version": 1, "chats": [{"app": "", "messages": []}]}`,
+		"sender type": `{"version": 1, "chats": [{"app": "web", "messages": [{"sender_type": "root", "timestamp": "` + time.Now().Format(time.RFC3339) + `"}]}]}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"time"
 	"github.com/ontree-co/treeos/internal/logging"
+
+	"github.com/ontree-co/treeos/internal/metrics"
 )
 
 // Client represents a client for interacting with Caddy's Admin API
@@ -22,11 +24,28 @@ func NewClient() *Client {
 	return &Client{
 		baseURL: "http://localhost:2019",
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: metricsTransport{next: http.DefaultTransport},
 		},
 	}
 }
 
+// metricsTransport records the latency of Admin API requests
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = metrics.StatusClass(resp.StatusCode)
+	}
+	metrics.CaddyRequestDuration.ObserveSince(start, req.Method, status)
+	return resp, err
+}
+
 // RouteConfig represents the structure of a Caddy route configuration
 type RouteConfig struct {
 	ID       string      `json:"@id"`
@@ -169,6 +188,32 @@ func (c *Client) ensureHTTPApp() error {
 	return nil
 }
 
+// ListRoutes returns the routes of Caddy's HTTP server, empty if the HTTP app doesn't exist yet
+func (c *Client) ListRoutes() ([]RouteConfig, error) {
+	resp, err := c.httpClient.Get(c.baseURL + "/config/apps/http/servers/srv0/routes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Caddy Admin API: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return []RouteConfig{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("caddy returned status %d when listing routes: %s", resp.StatusCode, string(body))
+	}
+
+	var routes []RouteConfig
+	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
+		return nil, fmt.Errorf("failed to decode routes: %w", err)
+	}
+	if routes == nil {
+		routes = []RouteConfig{}
+	}
+	return routes, nil
+}
+
 // DeleteRoute deletes a route from Caddy's configuration by its ID
 func (c *Client) DeleteRoute(routeID string) error {
 	logging.Infof("[Caddy] Deleting route %s", routeID)
@@ -204,8 +249,9 @@ func (c *Client) DeleteRoute(routeID string) error {
 	return nil
 }
 
-// CreateRouteConfig creates a RouteConfig for an application
-func CreateRouteConfig(appID, subdomain string, hostPort int, publicDomain, tailscaleDomain string) *RouteConfig {
+// CreateRouteConfig creates a RouteConfig for an application. customDomain is an optional
+// fully-qualified domain served in addition to the subdomains.
+func CreateRouteConfig(appID, subdomain string, hostPort int, publicDomain, tailscaleDomain, customDomain string) *RouteConfig {
 	routeID := fmt.Sprintf("route-for-%s", appID)
 
 	hosts := []string{}
@@ -215,6 +261,9 @@ func CreateRouteConfig(appID, subdomain string, hostPort int, publicDomain, tail
 	if tailscaleDomain != "" {
 		hosts = append(hosts, fmt.Sprintf("%s.%s", subdomain, tailscaleDomain))
 	}
+	if customDomain != "" {
+		hosts = append(hosts, customDomain)
+	}
 
 	return &RouteConfig{
 		ID: routeID,
@@ -228,7 +277,7 @@ func CreateRouteConfig(appID, subdomain string, hostPort int, publicDomain, tail
 				Handler: "reverse_proxy",
 				Upstreams: []Upstream{
 					{
-						Dial: fmt.Sprintf("localhost:%d", hostPort),
+						Dial: fmt.Sprintf("%s:%d", upstreamHost, hostPort),
 					},
 				},
 			},
@@ -0,0 +1,178 @@
+package caddy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// ErrDNSMismatch is returned when a custom domain doesn't resolve to the node
+var ErrDNSMismatch = errors.New("domain does not point to this node")
+
+// ValidateDomain checks that domain is a fully-qualified host name such as "photos.example.org"
+func ValidateDomain(domain string) error {
+	if len(domain) > 253 {
+		return fmt.Errorf("domain is longer than 253 characters")
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("%q is not a fully-qualified domain", domain)
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("%q has an empty or too long label", domain)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("%q has a label starting or ending with a hyphen", domain)
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return fmt.Errorf("%q may only contain lowercase letters, digits, hyphens and dots", domain)
+			}
+		}
+	}
+	if net.ParseIP(domain) != nil {
+		return fmt.Errorf("%q is an IP address, not a domain", domain)
+	}
+	return nil
+}
+
+// Resolver looks up the addresses of a host, *net.Resolver implements it
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// NodeAddresses returns the addresses clients reach this node at: the addresses of its
+// network interfaces, and those the reference hosts resolve to. Behind NAT only the latter
+// contain the public address, so pass a host of the public base domain.
+func NodeAddresses(ctx context.Context, resolver Resolver, referenceHosts ...string) []string {
+	var addrs []string
+	if ifaceAddrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range ifaceAddrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				addrs = append(addrs, ipNet.IP.String())
+			}
+		}
+	}
+	for _, host := range referenceHosts {
+		if host == "" {
+			continue
+		}
+		resolved, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			logging.Warnf("[Caddy] Failed to resolve %s: %v", host, err)
+			continue
+		}
+		addrs = append(addrs, resolved...)
+	}
+	return addrs
+}
+
+// CheckDNS verifies that domain resolves to at least one of the node's addresses, so Caddy
+// can obtain a certificate for it. A domain that isn't served by the node would fail the
+// ACME challenge and count against the rate limit of Let's Encrypt.
+func CheckDNS(ctx context.Context, resolver Resolver, domain string, nodeAddrs []string) error {
+	resolved, err := resolver.LookupHost(ctx, domain)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", domain, err)
+	}
+	node := make(map[string]bool, len(nodeAddrs))
+	for _, addr := range nodeAddrs {
+		if ip := net.ParseIP(addr); ip != nil {
+			node[ip.String()] = true
+		}
+	}
+	for _, addr := range resolved {
+		if ip := net.ParseIP(addr); ip != nil && node[ip.String()] {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s resolves to %s", ErrDNSMismatch, domain, strings.Join(resolved, ", "))
+}
+
+// CertificatePolicyID returns the config ID of an app's certificate policy for its custom domain
+func CertificatePolicyID(appID string) string {
+	return fmt.Sprintf("tls-for-%s", appID)
+}
+
+// AddOrUpdateCertificatePolicy gives a custom domain its own TLS automation policy, so its
+// certificate is managed independently of the base domain's and removed with the app
+func (c *Client) AddOrUpdateCertificatePolicy(appID, domain string) error {
+	id := CertificatePolicyID(appID)
+	// Replace a policy for an earlier domain
+	if err := c.DeleteRoute(id); err != nil {
+		return err
+	}
+	if err := c.ensureConfigPath("/config/apps/tls", map[string]interface{}{}); err != nil {
+		return fmt.Errorf("failed to ensure TLS app exists: %w", err)
+	}
+	if err := c.ensureConfigPath("/config/apps/tls/automation", map[string]interface{}{}); err != nil {
+		return fmt.Errorf("failed to ensure TLS automation exists: %w", err)
+	}
+	if err := c.ensureConfigPath("/config/apps/tls/automation/policies", []interface{}{}); err != nil {
+		return fmt.Errorf("failed to ensure TLS policies exist: %w", err)
+	}
+
+	policy := map[string]interface{}{
+		"@id":      id,
+		"subjects": []string{domain},
+	}
+	jsonData, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal certificate policy: %w", err)
+	}
+
+	logging.Infof("[Caddy] Adding certificate policy %s for %s", id, domain)
+	resp, err := c.httpClient.Post(c.baseURL+"/config/apps/tls/automation/policies", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to send request to Caddy: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("caddy returned status %d when adding certificate policy: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// ensureConfigPath creates a config path with an empty value if it isn't set yet
+func (c *Client) ensureConfigPath(path string, empty interface{}) error {
+	resp, err := c.httpClient.Get(c.baseURL + path)
+	if err != nil {
+		return err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && strings.TrimSpace(string(body)) != "null" {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(empty)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, c.baseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("caddy returned status %d for %s: %s", resp.StatusCode, path, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package caddy
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := f[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestValidateDomain(t *testing.T) {
+	valid := []string{"photos.example.org", "a-b.c.example.co.uk", "x1.io"}
+	for _, domain := range valid {
+		if err := ValidateDomain(domain); err != nil {
+			t.Errorf("ValidateDomain(%q) error = %v", domain, err)
+		}
+	}
+	invalid := []string{"", "localhost", "photos..example.org", "-photos.example.org", "Photos.example.org", "photos.example.org/", "*.example.org"}
+	for _, domain := range invalid {
+		if err := ValidateDomain(domain); err == nil {
+			t.Errorf("ValidateDomain(%q) should fail", domain)
+		}
+	}
+}
+
+func TestCheckDNS(t *testing.T) {
+	resolver := fakeResolver{
+		"photos.example.org": {"203.0.113.7"},
+		"other.example.org":  {"198.51.100.1"},
+		"photos.node.com":    {"203.0.113.7"},
+	}
+	ctx := context.Background()
+	nodeAddrs := NodeAddresses(ctx, resolver, "photos.node.com", "")
+
+	if err := CheckDNS(ctx, resolver, "photos.example.org", nodeAddrs); err != nil {
+		t.Errorf("CheckDNS() error = %v", err)
+	}
+	if err := CheckDNS(ctx, resolver, "other.example.org", nodeAddrs); !errors.Is(err, ErrDNSMismatch) {
+		t.Errorf("CheckDNS() error = %v, want ErrDNSMismatch", err)
+	}
+	if err := CheckDNS(ctx, resolver, "missing.example.org", nodeAddrs); err == nil {
+		t.Error("CheckDNS() of an unresolvable domain should fail")
+	}
+}
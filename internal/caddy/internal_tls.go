@@ -0,0 +1,85 @@
+package caddy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// internalCertificateTag marks certificates TreeOS loads into Caddy for internal hostnames
+const internalCertificateTag = "treeos-internal"
+
+// NodeInternalRouteID is the config ID of the route serving TreeOS on the internal domain
+const NodeInternalRouteID = "internal-route-treeos"
+
+// InternalRouteID returns the config ID of an app's route on the internal domain
+func InternalRouteID(appID string) string {
+	return fmt.Sprintf("internal-route-for-%s", appID)
+}
+
+// CreateInternalRouteConfig creates a route serving host on the internal domain from a
+// local port. The certificate comes from LoadInternalCertificate, not from ACME.
+func CreateInternalRouteConfig(routeID, host string, hostPort int) *RouteConfig {
+	return &RouteConfig{
+		ID:    routeID,
+		Match: []MatchRule{{Host: []string{host}}},
+		Handle: []Handler{
+			{
+				Handler:   "reverse_proxy",
+				Upstreams: []Upstream{{Dial: fmt.Sprintf("%s:%d", upstreamHost, hostPort)}},
+			},
+		},
+		Terminal: true,
+	}
+}
+
+// LoadInternalCertificate makes Caddy serve a certificate signed by the internal CA.
+// Automatic HTTPS skips hostnames with a loaded certificate, so Caddy doesn't try to get
+// one from Let's Encrypt for names that only resolve in the LAN.
+func (c *Client) LoadInternalCertificate(certPEM, keyPEM []byte) error {
+	if err := c.ensureConfigPath("/config/apps/tls", map[string]interface{}{}); err != nil {
+		return fmt.Errorf("failed to ensure TLS app exists: %w", err)
+	}
+	if err := c.ensureConfigPath("/config/apps/tls/certificates", map[string]interface{}{}); err != nil {
+		return fmt.Errorf("failed to ensure TLS certificates exist: %w", err)
+	}
+	if err := c.ensureConfigPath("/config/apps/tls/certificates/load_pem", []interface{}{}); err != nil {
+		return fmt.Errorf("failed to ensure loaded certificates exist: %w", err)
+	}
+
+	certificates := []map[string]interface{}{
+		{
+			"certificate": string(certPEM),
+			"key":         string(keyPEM),
+			"tags":        []string{internalCertificateTag},
+		},
+	}
+	jsonData, err := json.Marshal(certificates)
+	if err != nil {
+		return fmt.Errorf("failed to marshal certificate: %w", err)
+	}
+
+	logging.Infof("[Caddy] Loading internal certificate")
+	// PATCH replaces the list, TreeOS is the only one loading certificates
+	req, err := http.NewRequest(http.MethodPatch, c.baseURL+"/config/apps/tls/certificates/load_pem", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to Caddy: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("caddy returned status %d when loading internal certificate: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package caddy
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// homebrewPrefixes are where Homebrew installs on Apple silicon and on Intel Macs
+var homebrewPrefixes = []string{"/opt/homebrew", "/usr/local"}
+
+// homebrewServicePlist is the launchd job "brew services start caddy" creates
+const homebrewServicePlist = "Library/LaunchAgents/homebrew.mxcl.caddy.plist"
+
+// Installation describes how Caddy is installed on the host
+type Installation struct {
+	Binary  string // Path of the caddy binary
+	Service bool   // Caddy runs as a Homebrew service and starts with the user session
+}
+
+// PlatformSupported reports whether TreeOS can manage Caddy on this host. Linux installs
+// Caddy with its package manager or install-deps; on macOS it has to come from Homebrew.
+func PlatformSupported() bool {
+	switch runtime.GOOS {
+	case "linux":
+		return true
+	case "darwin":
+		_, ok := DetectHomebrew()
+		return ok
+	default:
+		return false
+	}
+}
+
+// DetectHomebrew looks for a Caddy installed with Homebrew
+func DetectHomebrew() (Installation, bool) {
+	home, _ := os.UserHomeDir()
+	return detectHomebrew(homebrewPrefixes, home)
+}
+
+func detectHomebrew(prefixes []string, home string) (Installation, bool) {
+	for _, prefix := range prefixes {
+		binary := filepath.Join(prefix, "bin", "caddy")
+		if info, err := os.Stat(binary); err != nil || info.IsDir() {
+			continue
+		}
+		inst := Installation{Binary: binary}
+		if home != "" {
+			if _, err := os.Stat(filepath.Join(home, homebrewServicePlist)); err == nil {
+				inst.Service = true
+			}
+		}
+		return inst, true
+	}
+	return Installation{}, false
+}
+
+// upstreamHost is the host routes dial to reach an app. On macOS the container ports are
+// forwarded by Docker Desktop or the Podman machine on IPv4 only, while "localhost" resolves
+// to ::1 first.
+var upstreamHost = upstreamHostFor(runtime.GOOS)
+
+func upstreamHostFor(goos string) string {
+	if goos == "darwin" {
+		return "127.0.0.1"
+	}
+	return "localhost"
+}
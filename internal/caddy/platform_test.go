@@ -0,0 +1,53 @@
+package caddy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectHomebrew(t *testing.T) {
+	intel := t.TempDir()
+	appleSilicon := t.TempDir()
+	home := t.TempDir()
+
+	if _, ok := detectHomebrew([]string{appleSilicon, intel}, home); ok {
+		t.Fatal("detected Caddy without a binary")
+	}
+
+	if err := os.MkdirAll(filepath.Join(intel, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(intel, "bin", "caddy"), nil, 0755); err != nil { //nolint:gosec // Test binary
+		t.Fatal(err)
+	}
+	inst, ok := detectHomebrew([]string{appleSilicon, intel}, home)
+	if !ok || inst.Binary != filepath.Join(intel, "bin", "caddy") || inst.Service {
+		t.Fatalf("detectHomebrew() = %+v, %t", inst, ok)
+	}
+
+	plist := filepath.Join(home, homebrewServicePlist)
+	if err := os.MkdirAll(filepath.Dir(plist), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(plist, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if inst, _ := detectHomebrew([]string{appleSilicon, intel}, home); !inst.Service {
+		t.Error("Homebrew service not detected")
+	}
+}
+
+func TestUpstreamHost(t *testing.T) {
+	if got := upstreamHostFor("darwin"); got != "127.0.0.1" {
+		t.Errorf("upstreamHostFor(darwin) = %q", got)
+	}
+	if got := upstreamHostFor("linux"); got != "localhost" {
+		t.Errorf("upstreamHostFor(linux) = %q", got)
+	}
+
+	route := CreateRouteConfig("app", "sub", 8080, "example.com", "", "")
+	if dial := route.Handle[0].Upstreams[0].Dial; dial != upstreamHost+":8080" {
+		t.Errorf("dial = %q", dial)
+	}
+}
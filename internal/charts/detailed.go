@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"math"
+	"strings"
 	"time"
 )
 
@@ -17,6 +18,12 @@ type DetailedChartData struct {
 	MaxValue  float64
 	StartTime time.Time // Requested start time (for showing full range)
 	EndTime   time.Time // Requested end time
+
+	// Comparison is an optional second series drawn as a dashed line behind Points, e.g.
+	// the same period last week with its timestamps shifted onto StartTime..EndTime
+	Comparison      []DataPoint
+	Label           string // Legend entry of Points, shown with a comparison
+	ComparisonLabel string // Legend entry of Comparison
 }
 
 // DataPoint represents a single data point with timestamp
@@ -27,7 +34,7 @@ type DataPoint struct {
 
 // GenerateDetailedChart creates a detailed SVG chart with axes, labels, and grid lines
 func GenerateDetailedChart(data DetailedChartData, width, height int) template.HTML {
-	if len(data.Points) == 0 {
+	if len(data.Points) == 0 && len(data.Comparison) == 0 {
 		//nolint:gosec // SVG generation, not user input
 		return template.HTML(fmt.Sprintf(`<svg width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
 			<text x="%d" y="%d" text-anchor="middle" fill="#6c757d">No data available</text>
@@ -45,7 +52,7 @@ func GenerateDetailedChart(data DetailedChartData, width, height int) template.H
 
 	// Calculate min/max if not provided
 	if data.MinValue == 0 && data.MaxValue == 0 {
-		data.MinValue, data.MaxValue = findDataPointMinMax(data.Points)
+		data.MinValue, data.MaxValue = findDataPointMinMax(append(append([]DataPoint{}, data.Points...), data.Comparison...))
 	}
 
 	// Add padding to min/max
@@ -87,8 +94,9 @@ func GenerateDetailedChart(data DetailedChartData, width, height int) template.H
 	svg += fmt.Sprintf(`<defs><clipPath id="plotArea"><rect x="%d" y="%d" width="%d" height="%d"/></clipPath></defs>`,
 		marginLeft, marginTop, chartWidth, chartHeight)
 
-	// Data line
+	// Data line, on top of the comparison
 	svg += `<g clip-path="url(#plotArea)">`
+	svg += generateComparisonLine(data.Comparison, marginLeft, marginTop, chartWidth, chartHeight, data.MinValue, data.MaxValue, data.StartTime, data.EndTime)
 	svg += generateDataLine(data.Points, marginLeft, marginTop, chartWidth, chartHeight, data.MinValue, data.MaxValue, data.StartTime, data.EndTime)
 	svg += `</g>`
 
@@ -96,12 +104,33 @@ func GenerateDetailedChart(data DetailedChartData, width, height int) template.H
 	svg += fmt.Sprintf(`<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#dee2e6" stroke-width="1"/>`,
 		marginLeft, marginTop, chartWidth, chartHeight)
 
+	if len(data.Comparison) > 0 {
+		svg += generateLegend(marginLeft+chartWidth, data.Label, data.ComparisonLabel)
+	}
+
 	svg += `</svg>`
 
 	//nolint:gosec // SVG generation, not user input
 	return template.HTML(svg)
 }
 
+// generateLegend creates the legend of a chart with comparison, right-aligned at right
+func generateLegend(right int, label, comparisonLabel string) string {
+	if label == "" {
+		label = "Current"
+	}
+	if comparisonLabel == "" {
+		comparisonLabel = "Comparison"
+	}
+	svg := `<g font-size="12" fill="#6c757d">`
+	svg += fmt.Sprintf(`<line x1="%d" y1="30" x2="%d" y2="30" stroke="#6c757d" stroke-width="2" stroke-dasharray="6 4"/>`, right-120, right-100)
+	svg += fmt.Sprintf(`<text x="%d" y="34">%s</text>`, right-95, template.HTMLEscapeString(comparisonLabel))
+	svg += fmt.Sprintf(`<line x1="%d" y1="14" x2="%d" y2="14" stroke="#198754" stroke-width="2"/>`, right-120, right-100)
+	svg += fmt.Sprintf(`<text x="%d" y="18">%s</text>`, right-95, template.HTMLEscapeString(label))
+	svg += `</g>`
+	return svg
+}
+
 // generateGridLines creates horizontal and vertical grid lines
 func generateGridLines(left, top, width, height int) string {
 	svg := `<g stroke="#f0f0f0" stroke-width="1">`
@@ -319,6 +348,44 @@ func generateDataLine(points []DataPoint, left, top, width, height int, minVal,
 	return svg
 }
 
+// generateComparisonLine creates the dashed line of a comparison series, broken at gaps
+// like the data line but without area and dots
+func generateComparisonLine(points []DataPoint, left, top, width, height int, minVal, maxVal float64, requestedStartTime, requestedEndTime time.Time) string {
+	timeRange := requestedEndTime.Sub(requestedStartTime)
+	if timeRange <= 0 {
+		timeRange = 24 * time.Hour // Default
+	}
+	gapThreshold := 2 * time.Minute
+
+	svg := ""
+	polylinePoints := ""
+	for i, point := range points {
+		if i > 0 && point.Time.Sub(points[i-1].Time) > gapThreshold && polylinePoints != "" {
+			svg += fmt.Sprintf(`<polyline points="%s" fill="none" stroke="#6c757d" stroke-width="1.5" stroke-dasharray="6 4"/>`, polylinePoints)
+			polylinePoints = ""
+		}
+
+		elapsed := point.Time.Sub(requestedStartTime)
+		x := left + int(float64(elapsed)/float64(timeRange)*float64(width))
+		normalized := (point.Value - minVal) / (maxVal - minVal)
+		if normalized < 0 {
+			normalized = 0
+		} else if normalized > 1 {
+			normalized = 1
+		}
+		y := top + height - int(normalized*float64(height))
+
+		if polylinePoints != "" {
+			polylinePoints += " "
+		}
+		polylinePoints += fmt.Sprintf("%d,%d", x, y)
+	}
+	if strings.Contains(polylinePoints, " ") {
+		svg += fmt.Sprintf(`<polyline points="%s" fill="none" stroke="#6c757d" stroke-width="1.5" stroke-dasharray="6 4"/>`, polylinePoints)
+	}
+	return svg
+}
+
 // findDataPointMinMax finds min and max values from DataPoint slice
 func findDataPointMinMax(points []DataPoint) (float64, float64) {
 	if len(points) == 0 {
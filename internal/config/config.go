@@ -6,9 +6,16 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/ontree-co/treeos/internal/engine"
+	"github.com/ontree-co/treeos/internal/lowmem"
+	"github.com/ontree-co/treeos/internal/maintenance"
+	"github.com/ontree-co/treeos/internal/storage"
 )
 
 // RunMode defines whether the application runs in demo or production mode
@@ -32,6 +39,14 @@ type Config struct {
 	// DatabasePath is the path to the SQLite database file
 	DatabasePath string `toml:"database_path"`
 
+	// Directories of TreeOS's own files: ConfigDir holds config.toml, StateDir the database and
+	// everything else a backup needs, CacheDir what can be regenerated, e.g. screenshots and
+	// SBOMs, so it can live on throwaway storage. Unset directories follow DirLayout.
+	DirLayout string `toml:"dir_layout"` // DirLayoutLegacy, DirLayoutFHS or DirLayoutXDG
+	ConfigDir string `toml:"config_dir"`
+	StateDir  string `toml:"state_dir"`
+	CacheDir  string `toml:"cache_dir"`
+
 	// ListenAddr is the address and port for the web server
 	ListenAddr string `toml:"listen_addr"`
 
@@ -42,6 +57,14 @@ type Config struct {
 	// Caddy integration configuration
 	PublicBaseDomain string `toml:"public_base_domain"`
 
+	// Hostname only resolved in the LAN, e.g. mynode.lan. TreeOS serves itself at it and apps
+	// at <subdomain>.<internal domain> over HTTPS with certificates of its own internal CA.
+	InternalDomain string `toml:"internal_domain"`
+
+	// Serve the dashboard over HTTPS and let staff users enroll TLS client certificates from
+	// the internal CA. Once a user has one, their sessions only work with it. Needs InternalDomain.
+	ClientCertAuth bool `toml:"client_cert_auth"`
+
 	// Tailscale integration configuration
 	TailscaleAuthKey string `toml:"tailscale_auth_key"`
 	TailscaleTags    string `toml:"tailscale_tags"` // e.g., "tag:ontree-apps"
@@ -52,6 +75,110 @@ type Config struct {
 	// Auto-update configuration
 	AutoUpdateEnabled bool `toml:"auto_update_enabled"`
 
+	// Time between checks of the registries for newer versions of the images apps use, 0 disables them
+	ImageUpdateInterval time.Duration `toml:"image_update_interval"`
+
+	// Maintenance window for disruptive host operations, e.g. "03:00-05:00" or "sat,sun 22:00-02:00"
+	MaintenanceWindow string `toml:"maintenance_window"`
+
+	// Session lifetime configuration
+	SessionIdleTimeout      time.Duration `toml:"session_idle_timeout"`      // Logout after this long without activity
+	SessionMaxLifetime      time.Duration `toml:"session_max_lifetime"`      // Absolute lifetime of a session
+	SessionRememberLifetime time.Duration `toml:"session_remember_lifetime"` // Absolute lifetime with "remember this device"
+
+	// Tuning for nodes with little memory: lowmem.ModeAuto enables it below 2 GB,
+	// lowmem.ModeOn and lowmem.ModeOff force it
+	LowMemory string `toml:"low_memory"`
+
+	// Mount roots tagged by storage class, used to place app data on fast or bulk devices
+	StorageRoots []storage.Root `toml:"storage_roots"`
+
+	// Largest size of the shared Ollama directory, e.g. "200GB". Downloads of models that
+	// don't fit are refused. Empty for no limit.
+	OllamaQuota string `toml:"ollama_quota"`
+
+	// WebDAV access to app mount directories at /dav/
+	WebDAVEnabled bool `toml:"webdav_enabled"`
+
+	// Invite links admins create so people can set up their own account at /invite/
+	InvitesEnabled bool `toml:"invites_enabled"`
+
+	// Thumbnails of app web UIs on the dashboard, captured with a headless Chrome or Chromium
+	ScreenshotsEnabled bool          `toml:"screenshots_enabled"`
+	ScreenshotInterval time.Duration `toml:"screenshot_interval"` // Time between captures of an app
+
+	// Mandatory access control for app containers: ConfinementAppArmor, ConfinementSELinux or
+	// empty to keep the runtime defaults. Apps with security validation bypassed are not confined.
+	Confinement        string `toml:"confinement"`
+	ConfinementProfile string `toml:"confinement_profile"` // AppArmor profile or SELinux type, empty for Docker's default
+
+	// Read-only root filesystem with tmpfs mounts for services that templates mark compatible.
+	// Apps can override it, and fall back to a writable root when they fail after the change.
+	ReadOnlyRoot bool `toml:"read_only_root"`
+
+	// Container engine apps run on: engine.ModeAuto detects it at startup, engine.ModeDocker
+	// and engine.ModePodman force it. The setting on the settings page takes precedence.
+	ContainerRuntime string `toml:"container_runtime"`
+
+	// Expect containers to run in a user namespace (Docker userns-remap or rootless Podman),
+	// the system check reports hosts where root in a container is root on the host
+	UserNamespaces bool `toml:"user_namespaces"`
+
+	// LDAP directory for logins, e.g. LLDAP, OpenLDAP or Active Directory. Users are created
+	// on their first login and get their roles from group membership on every login.
+	LDAPURL            string `toml:"ldap_url"` // ldap://host:389 or ldaps://host:636, empty disables LDAP
+	LDAPStartTLS       bool   `toml:"ldap_start_tls"`
+	LDAPSkipVerify     bool   `toml:"ldap_skip_verify"`
+	LDAPBindDN         string `toml:"ldap_bind_dn"`
+	LDAPBindPassword   string `toml:"ldap_bind_password"`
+	LDAPBaseDN         string `toml:"ldap_base_dn"`
+	LDAPUserFilter     string `toml:"ldap_user_filter"`     // {username} is replaced with the login name
+	LDAPGroupAttribute string `toml:"ldap_group_attribute"` // Attribute listing the groups of a user
+	LDAPUserGroup      string `toml:"ldap_user_group"`      // Required to log in, empty allows all directory users
+	LDAPStaffGroup     string `toml:"ldap_staff_group"`     // Members can manage apps
+	LDAPAdminGroup     string `toml:"ldap_admin_group"`     // Members are staff and superusers
+
+	// Bearer token for scraping /metrics without a session, e.g. by Prometheus
+	MetricsToken string `toml:"metrics_token"`
+
+	// Who may read the endpoints that need no login: AccessPublic, AccessLAN or AccessToken
+	VersionAccess string `toml:"version_access"` // GET /version
+	MetricsAccess string `toml:"metrics_access"` // GET /metrics
+	HealthAccess  string `toml:"health_access"`  // GET /api/health
+
+	// Largest request body accepted by default, e.g. "1MB". Upload endpoints have their own limits.
+	MaxRequestBodySize string `toml:"max_request_body_size"`
+
+	// Bearer token for the /api/ endpoints without a session, e.g. for treeos selftest in CI.
+	// Requests with it act as the first admin.
+	APIToken string `toml:"api_token"`
+
+	// Agent mode runs a headless node that a primary TreeOS node manages over the API: the
+	// container runtime, the vitals collector and the /api/ endpoints, authenticated with
+	// APIToken only. There is no web UI, no app templates and no Ollama.
+	AgentMode bool `toml:"agent_mode"`
+
+	// Address of the recovery console, e.g. ":4099". It has its own listener so it keeps working
+	// when the web UI doesn't, and only answers Tailscale addresses. Empty disables it.
+	RecoveryConsoleAddr string `toml:"recovery_console_addr"`
+
+	// Read-only status for external dashboards such as Homepage or Glance at /api/widget.
+	// Disabled without a token. Empty WidgetApps means all apps.
+	WidgetToken   string   `toml:"widget_token"`
+	WidgetApps    []string `toml:"widget_apps"`
+	WidgetMetrics []string `toml:"widget_metrics"` // Names from WidgetMetrics
+
+	// Optional offline GeoIP database (DB-IP Lite CSV) used to locate login IPs
+	GeoIPDatabasePath string `toml:"geoip_database_path"`
+
+	// SMTP configuration for email notifications
+	SMTPHost     string `toml:"smtp_host"`
+	SMTPPort     int    `toml:"smtp_port"`
+	SMTPSecurity string `toml:"smtp_security"` // starttls, tls or none
+	SMTPUsername string `toml:"smtp_username"`
+	SMTPPassword string `toml:"smtp_password"`
+	SMTPFrom     string `toml:"smtp_from"`
+
 	// LLM configuration (for future features)
 	AgentLLMAPIKey    string `toml:"agent_llm_api_key"`
 	AgentLLMAPIURL    string `toml:"agent_llm_api_url"`
@@ -148,6 +275,27 @@ func defaultConfig() *Config {
 		PostHogHost:       "https://app.posthog.com",
 		MonitoringEnabled: true, // Enabled by default
 		AutoUpdateEnabled: true,
+		MaintenanceWindow: maintenance.DefaultWindow,
+		LowMemory:         lowmem.ModeAuto,
+		ContainerRuntime:  engine.ModeAuto,
+
+		SessionIdleTimeout:      24 * time.Hour,
+		SessionMaxLifetime:      7 * 24 * time.Hour,
+		SessionRememberLifetime: 30 * 24 * time.Hour,
+
+		MaxRequestBodySize: "1MB",
+
+		ScreenshotInterval: time.Hour,
+
+		ImageUpdateInterval: 12 * time.Hour,
+
+		WidgetMetrics: []string{"cpu", "memory", "disk"},
+
+		VersionAccess: AccessPublic,
+		MetricsAccess: AccessToken,
+		HealthAccess:  AccessPublic,
+
+		SMTPPort: 587,
 	}
 
 	// Set paths using centralized functions
@@ -163,10 +311,7 @@ func Load() (*Config, error) {
 	config := defaultConfig()
 
 	// Try to load from config.toml if it exists
-	configPath := os.Getenv("ONTREE_CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config.toml"
-	}
+	configPath := configFilePath()
 	if _, err := os.Stat(configPath); err == nil {
 		if _, err := toml.DecodeFile(configPath, config); err != nil {
 			return nil, fmt.Errorf("failed to decode config file: %w", err)
@@ -205,6 +350,9 @@ func Load() (*Config, error) {
 	if dbPath := os.Getenv("DATABASE_PATH"); dbPath != "" {
 		config.DatabasePath = dbPath
 	}
+	if err := resolveDirs(config); err != nil {
+		return nil, err
+	}
 
 	if listenAddr := os.Getenv("LISTEN_ADDR"); listenAddr != "" {
 		config.ListenAddr = listenAddr
@@ -222,6 +370,16 @@ func Load() (*Config, error) {
 		config.PublicBaseDomain = publicBaseDomain
 	}
 
+	if internalDomain := os.Getenv("INTERNAL_DOMAIN"); internalDomain != "" {
+		config.InternalDomain = strings.ToLower(internalDomain)
+	}
+	if clientCertAuth := os.Getenv("CLIENT_CERT_AUTH"); clientCertAuth != "" {
+		config.ClientCertAuth = clientCertAuth == "true" || clientCertAuth == "1"
+	}
+	if config.ClientCertAuth && config.InternalDomain == "" {
+		return nil, fmt.Errorf("internal_domain is required when client_cert_auth is set, its CA issues the certificates")
+	}
+
 	if tailscaleAuthKey := os.Getenv("TAILSCALE_AUTH_KEY"); tailscaleAuthKey != "" {
 		config.TailscaleAuthKey = tailscaleAuthKey
 	}
@@ -238,6 +396,201 @@ func Load() (*Config, error) {
 		config.AutoUpdateEnabled = autoUpdateEnabled == "true" || autoUpdateEnabled == "1"
 	}
 
+	if maintenanceWindow := os.Getenv("MAINTENANCE_WINDOW"); maintenanceWindow != "" {
+		config.MaintenanceWindow = maintenanceWindow
+	}
+	if _, err := maintenance.Parse(config.MaintenanceWindow); err != nil {
+		return nil, err
+	}
+
+	if lowMemory := os.Getenv("LOW_MEMORY"); lowMemory != "" {
+		config.LowMemory = lowMemory
+	}
+	if !lowmem.ValidMode(config.LowMemory) {
+		return nil, fmt.Errorf("invalid low_memory %q, expected %s, %s or %s", config.LowMemory, lowmem.ModeAuto, lowmem.ModeOn, lowmem.ModeOff)
+	}
+
+	if containerRuntime := os.Getenv("CONTAINER_RUNTIME"); containerRuntime != "" {
+		config.ContainerRuntime = containerRuntime
+	}
+	if !engine.ValidMode(config.ContainerRuntime) {
+		return nil, fmt.Errorf("invalid container_runtime %q, expected %s, %s or %s", config.ContainerRuntime, engine.ModeAuto, engine.ModeDocker, engine.ModePodman)
+	}
+
+	if err := durationFromEnv("SESSION_IDLE_TIMEOUT", &config.SessionIdleTimeout); err != nil {
+		return nil, err
+	}
+	if err := durationFromEnv("SESSION_MAX_LIFETIME", &config.SessionMaxLifetime); err != nil {
+		return nil, err
+	}
+	if err := durationFromEnv("SESSION_REMEMBER_LIFETIME", &config.SessionRememberLifetime); err != nil {
+		return nil, err
+	}
+
+	if webDAVEnabled := os.Getenv("WEBDAV_ENABLED"); webDAVEnabled != "" {
+		config.WebDAVEnabled = webDAVEnabled == "true" || webDAVEnabled == "1"
+	}
+	if invitesEnabled := os.Getenv("INVITES_ENABLED"); invitesEnabled != "" {
+		config.InvitesEnabled = invitesEnabled == "true" || invitesEnabled == "1"
+	}
+
+	if screenshotsEnabled := os.Getenv("SCREENSHOTS_ENABLED"); screenshotsEnabled != "" {
+		config.ScreenshotsEnabled = screenshotsEnabled == "true" || screenshotsEnabled == "1"
+	}
+	if err := durationFromEnv("SCREENSHOT_INTERVAL", &config.ScreenshotInterval); err != nil {
+		return nil, err
+	}
+	if err := durationFromEnv("IMAGE_UPDATE_INTERVAL", &config.ImageUpdateInterval); err != nil {
+		return nil, err
+	}
+
+	if storageRoots := os.Getenv("STORAGE_ROOTS"); storageRoots != "" {
+		roots, err := storage.ParseRoots(storageRoots)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STORAGE_ROOTS: %w", err)
+		}
+		config.StorageRoots = roots
+	}
+	if err := storage.ValidateRoots(config.StorageRoots); err != nil {
+		return nil, err
+	}
+
+	if confinement := os.Getenv("CONTAINER_CONFINEMENT"); confinement != "" {
+		config.Confinement = confinement
+	}
+	if profile := os.Getenv("CONFINEMENT_PROFILE"); profile != "" {
+		config.ConfinementProfile = profile
+	}
+	if config.Confinement != "" && config.Confinement != ConfinementAppArmor && config.Confinement != ConfinementSELinux {
+		return nil, fmt.Errorf("invalid confinement %q, expected %s or %s", config.Confinement, ConfinementAppArmor, ConfinementSELinux)
+	}
+
+	if readOnlyRoot := os.Getenv("READ_ONLY_ROOT"); readOnlyRoot != "" {
+		config.ReadOnlyRoot = readOnlyRoot == "true" || readOnlyRoot == "1"
+	}
+
+	if userNamespaces := os.Getenv("USER_NAMESPACES"); userNamespaces != "" {
+		config.UserNamespaces = userNamespaces == "true" || userNamespaces == "1"
+	}
+
+	for env, field := range map[string]*string{
+		"LDAP_URL":             &config.LDAPURL,
+		"LDAP_BIND_DN":         &config.LDAPBindDN,
+		"LDAP_BIND_PASSWORD":   &config.LDAPBindPassword,
+		"LDAP_BASE_DN":         &config.LDAPBaseDN,
+		"LDAP_USER_FILTER":     &config.LDAPUserFilter,
+		"LDAP_GROUP_ATTRIBUTE": &config.LDAPGroupAttribute,
+		"LDAP_USER_GROUP":      &config.LDAPUserGroup,
+		"LDAP_STAFF_GROUP":     &config.LDAPStaffGroup,
+		"LDAP_ADMIN_GROUP":     &config.LDAPAdminGroup,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+	if ldapStartTLS := os.Getenv("LDAP_START_TLS"); ldapStartTLS != "" {
+		config.LDAPStartTLS = ldapStartTLS == "true" || ldapStartTLS == "1"
+	}
+	if ldapSkipVerify := os.Getenv("LDAP_SKIP_VERIFY"); ldapSkipVerify != "" {
+		config.LDAPSkipVerify = ldapSkipVerify == "true" || ldapSkipVerify == "1"
+	}
+	if config.LDAPURL != "" && config.LDAPBaseDN == "" {
+		return nil, fmt.Errorf("ldap_base_dn is required when ldap_url is set")
+	}
+
+	if metricsToken := os.Getenv("METRICS_TOKEN"); metricsToken != "" {
+		config.MetricsToken = metricsToken
+	}
+	if apiToken := os.Getenv("API_TOKEN"); apiToken != "" {
+		config.APIToken = apiToken
+	}
+	if agentMode := os.Getenv("TREEOS_AGENT_MODE"); agentMode != "" {
+		config.AgentMode = agentMode == "true" || agentMode == "1"
+	}
+	if config.AgentMode && config.APIToken == "" {
+		return nil, fmt.Errorf("api_token is required in agent mode, the primary node authenticates with it")
+	}
+	if recoveryConsoleAddr := os.Getenv("RECOVERY_CONSOLE_ADDR"); recoveryConsoleAddr != "" {
+		config.RecoveryConsoleAddr = recoveryConsoleAddr
+	}
+	if widgetToken := os.Getenv("WIDGET_TOKEN"); widgetToken != "" {
+		config.WidgetToken = widgetToken
+	}
+	if widgetApps := os.Getenv("WIDGET_APPS"); widgetApps != "" {
+		config.WidgetApps = splitList(widgetApps)
+	}
+	if widgetMetrics := os.Getenv("WIDGET_METRICS"); widgetMetrics != "" {
+		config.WidgetMetrics = splitList(widgetMetrics)
+	}
+	for _, metric := range config.WidgetMetrics {
+		if !slices.Contains(WidgetMetrics, metric) {
+			return nil, fmt.Errorf("invalid widget metric %q, expected one of %s", metric, strings.Join(WidgetMetrics, ", "))
+		}
+	}
+	if maxBody := os.Getenv("MAX_REQUEST_BODY_SIZE"); maxBody != "" {
+		config.MaxRequestBodySize = maxBody
+	}
+	if _, err := storage.ParseSize(config.MaxRequestBodySize); err != nil {
+		return nil, fmt.Errorf("invalid max_request_body_size: %w", err)
+	}
+	if ollamaQuota := os.Getenv("OLLAMA_QUOTA"); ollamaQuota != "" {
+		config.OllamaQuota = ollamaQuota
+	}
+	if config.OllamaQuota != "" {
+		if _, err := storage.ParseSize(config.OllamaQuota); err != nil {
+			return nil, fmt.Errorf("invalid ollama_quota: %w", err)
+		}
+	}
+
+	for _, access := range []struct {
+		env    string
+		target *string
+	}{
+		{"VERSION_ACCESS", &config.VersionAccess},
+		{"METRICS_ACCESS", &config.MetricsAccess},
+		{"HEALTH_ACCESS", &config.HealthAccess},
+	} {
+		if value := os.Getenv(access.env); value != "" {
+			*access.target = value
+		}
+		if !validAccess(*access.target) {
+			return nil, fmt.Errorf("invalid %s %q, expected %s, %s or %s", strings.ToLower(access.env), *access.target, AccessPublic, AccessLAN, AccessToken)
+		}
+	}
+
+	if geoIPPath := os.Getenv("GEOIP_DB_PATH"); geoIPPath != "" {
+		config.GeoIPDatabasePath = geoIPPath
+	}
+
+	// SMTP environment variables
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		config.SMTPHost = smtpHost
+	}
+
+	if smtpPort := os.Getenv("SMTP_PORT"); smtpPort != "" {
+		port, err := strconv.Atoi(smtpPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		config.SMTPPort = port
+	}
+
+	if smtpSecurity := os.Getenv("SMTP_SECURITY"); smtpSecurity != "" {
+		config.SMTPSecurity = smtpSecurity
+	}
+
+	if smtpUsername := os.Getenv("SMTP_USERNAME"); smtpUsername != "" {
+		config.SMTPUsername = smtpUsername
+	}
+
+	if smtpPassword := os.Getenv("SMTP_PASSWORD"); smtpPassword != "" {
+		config.SMTPPassword = smtpPassword
+	}
+
+	if smtpFrom := os.Getenv("SMTP_FROM"); smtpFrom != "" {
+		config.SMTPFrom = smtpFrom
+	}
+
 	// LLM environment variables
 	if agentLLMAPIKey := os.Getenv("AGENT_LLM_API_KEY"); agentLLMAPIKey != "" {
 		config.AgentLLMAPIKey = agentLLMAPIKey
@@ -267,6 +620,35 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+func validAccess(access string) bool {
+	return access == AccessPublic || access == AccessLAN || access == AccessToken
+}
+
+// splitList splits a comma-separated environment variable into its trimmed, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// durationFromEnv parses a duration such as "12h" from an environment variable into target
+func durationFromEnv(name string, target *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	*target = d
+	return nil
+}
+
 // GetAppsDir returns the configured apps directory
 func (c *Config) GetAppsDir() string {
 	return c.AppsDir
@@ -278,6 +660,12 @@ func (c *Config) String() string {
 	parts = append(parts, fmt.Sprintf("RunMode: %s", c.RunMode))
 	parts = append(parts, fmt.Sprintf("AppsDir: %s", c.AppsDir))
 	parts = append(parts, fmt.Sprintf("DatabasePath: %s", c.DatabasePath))
+	if c.StateDir != "" {
+		parts = append(parts, fmt.Sprintf("StateDir: %s", c.StateDir))
+	}
+	if c.CacheDir != "" {
+		parts = append(parts, fmt.Sprintf("CacheDir: %s", c.CacheDir))
+	}
 	parts = append(parts, fmt.Sprintf("ListenAddr: %s", c.ListenAddr))
 	return strings.Join(parts, ", ")
 }
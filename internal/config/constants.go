@@ -8,3 +8,27 @@ const (
 	// TestPort is the port used for E2E tests to avoid conflicts
 	TestPort = ":3001"
 )
+
+// Access levels of the endpoints that need no login
+const (
+	// AccessPublic lets anyone read the endpoint
+	AccessPublic = "public"
+
+	// AccessLAN limits the endpoint to loopback, private and Tailscale addresses
+	AccessLAN = "lan"
+
+	// AccessToken requires the API token (or the metrics token for /metrics) or a login
+	AccessToken = "token"
+)
+
+// Confinement modes for app containers
+const (
+	// ConfinementAppArmor applies an AppArmor profile, docker-default unless configured
+	ConfinementAppArmor = "apparmor"
+
+	// ConfinementSELinux applies an SELinux process type, container_t unless configured
+	ConfinementSELinux = "selinux"
+)
+
+// WidgetMetrics are the metrics the widget endpoint can include
+var WidgetMetrics = []string{"cpu", "memory", "disk", "gpu", "upload", "download"}
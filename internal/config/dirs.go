@@ -0,0 +1,248 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// Directory layouts for the files of TreeOS itself, apps stay in AppsDir with all of them
+const (
+	// DirLayoutLegacy keeps config and state in the base directory and the cache below it
+	DirLayoutLegacy = "legacy"
+
+	// DirLayoutFHS uses /etc/treeos, /var/lib/treeos and /var/cache/treeos
+	DirLayoutFHS = "fhs"
+
+	// DirLayoutXDG uses the XDG base directories of the user, for installs without root
+	DirLayoutXDG = "xdg"
+)
+
+// Files and directories of the base directory, moved when another layout is chosen
+var (
+	legacyStateFiles = []string{"ca", "recovery-token", "session.keys", "secrets.key"}
+	legacyCacheFiles = []string{"screenshots", "sbom"}
+)
+
+// LayoutDirs returns the config, state and cache directories of a layout
+func LayoutDirs(layout string) (configDir, stateDir, cacheDir string, err error) {
+	switch layout {
+	case "", DirLayoutLegacy:
+		base := GetBasePath()
+		return base, base, filepath.Join(base, "cache"), nil
+	case DirLayoutFHS:
+		return "/etc/treeos", "/var/lib/treeos", "/var/cache/treeos", nil
+	case DirLayoutXDG:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", "", fmt.Errorf("xdg layout needs a home directory: %w", err)
+		}
+		xdg := func(env, fallback string) string {
+			// The spec ignores relative paths
+			if dir := os.Getenv(env); filepath.IsAbs(dir) {
+				return filepath.Join(dir, "treeos")
+			}
+			return filepath.Join(home, fallback, "treeos")
+		}
+		return xdg("XDG_CONFIG_HOME", ".config"), xdg("XDG_STATE_HOME", ".local/state"), xdg("XDG_CACHE_HOME", ".cache"), nil
+	default:
+		return "", "", "", fmt.Errorf("invalid dir_layout %q, expected %s, %s or %s", layout, DirLayoutLegacy, DirLayoutFHS, DirLayoutXDG)
+	}
+}
+
+// systemdDir returns the first directory systemd passes for StateDirectory= and friends
+func systemdDir(env string) string {
+	dir, _, _ := strings.Cut(os.Getenv(env), ":")
+	return dir
+}
+
+// resolveDirs fills in the config, state and cache directories that are not set, in this order:
+// TREEOS_*_DIR, the directories systemd grants the unit, then the layout
+func resolveDirs(cfg *Config) error {
+	if layout := os.Getenv("TREEOS_DIR_LAYOUT"); layout != "" {
+		cfg.DirLayout = layout
+	}
+	configDir, stateDir, cacheDir, err := LayoutDirs(cfg.DirLayout)
+	if err != nil {
+		return err
+	}
+	for _, dir := range []struct {
+		target        *string
+		env, systemd  string
+		layoutDefault string
+	}{
+		{&cfg.ConfigDir, "TREEOS_CONFIG_DIR", "CONFIGURATION_DIRECTORY", configDir},
+		{&cfg.StateDir, "TREEOS_STATE_DIR", "STATE_DIRECTORY", stateDir},
+		{&cfg.CacheDir, "TREEOS_CACHE_DIR", "CACHE_DIRECTORY", cacheDir},
+	} {
+		if value := os.Getenv(dir.env); value != "" {
+			*dir.target = value
+		} else if *dir.target == "" {
+			*dir.target = systemdDir(dir.systemd)
+		}
+		if *dir.target == "" {
+			*dir.target = dir.layoutDefault
+		}
+	}
+
+	// The database follows the state directory unless its path was set
+	if cfg.DatabasePath == GetDatabasePath() && filepath.Clean(cfg.StateDir) != filepath.Clean(GetBasePath()) {
+		cfg.DatabasePath = filepath.Join(cfg.StateDir, "ontree.db")
+	}
+	return nil
+}
+
+// configFilePath returns the config file to load: ONTREE_CONFIG_PATH, config.toml in the working
+// directory as before, or config.toml in the config directory
+func configFilePath() string {
+	if path := os.Getenv("ONTREE_CONFIG_PATH"); path != "" {
+		return path
+	}
+	if _, err := os.Stat("config.toml"); err == nil {
+		return "config.toml"
+	}
+	configDir := os.Getenv("TREEOS_CONFIG_DIR")
+	if configDir == "" {
+		configDir = systemdDir("CONFIGURATION_DIRECTORY")
+	}
+	if configDir == "" {
+		// Layout errors are reported once the config is loaded
+		configDir, _, _, _ = LayoutDirs(os.Getenv("TREEOS_DIR_LAYOUT")) //nolint:errcheck // See above
+	}
+	return filepath.Join(configDir, "config.toml")
+}
+
+// StatePath returns the path of a file or directory in the state directory, next to the
+// database when no state directory is set
+func (c *Config) StatePath(name string) string {
+	if c.StateDir != "" {
+		return filepath.Join(c.StateDir, name)
+	}
+	return filepath.Join(filepath.Dir(c.DatabasePath), name)
+}
+
+// CachePath returns the path of a file or directory in the cache directory, next to the
+// database when no cache directory is set
+func (c *Config) CachePath(name string) string {
+	if c.CacheDir != "" {
+		return filepath.Join(c.CacheDir, name)
+	}
+	return filepath.Join(filepath.Dir(c.DatabasePath), name)
+}
+
+// PrepareDirs creates the state and cache directories and moves the files of an install that
+// kept everything in the base directory into them. Files that already exist at the new place
+// are left alone, so it is safe to run on every start.
+func PrepareDirs(cfg *Config) error {
+	for _, dir := range []string{cfg.StateDir, cfg.CacheDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	legacyDB := GetDatabasePath()
+	base := filepath.Dir(legacyDB)
+	var moves [][2]string
+	if cfg.DatabasePath != legacyDB {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			moves = append(moves, [2]string{legacyDB + suffix, cfg.DatabasePath + suffix})
+		}
+		// Backups are kept next to the database
+		moves = append(moves, [2]string{filepath.Join(base, "backups"), filepath.Join(filepath.Dir(cfg.DatabasePath), "backups")})
+	}
+	for _, name := range legacyStateFiles {
+		moves = append(moves, [2]string{filepath.Join(base, name), cfg.StatePath(name)})
+	}
+	for _, name := range legacyCacheFiles {
+		moves = append(moves, [2]string{filepath.Join(base, name), cfg.CachePath(name)})
+	}
+
+	for _, move := range moves {
+		from, to := move[0], move[1]
+		if filepath.Clean(from) == filepath.Clean(to) {
+			continue
+		}
+		if _, err := os.Lstat(from); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if _, err := os.Lstat(to); err == nil {
+			logging.Warnf("Not moving %s, %s already exists", from, to)
+			continue
+		}
+		if err := movePath(from, to); err != nil {
+			return fmt.Errorf("failed to move %s to %s: %w", from, to, err)
+		}
+		logging.Infof("Moved %s to %s", from, to)
+	}
+	return nil
+}
+
+// movePath renames a file or directory, copying it when the target is on another file system
+func movePath(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0750); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+	// Copy to a temporary name first, an interrupted copy must not look like a finished one
+	tmp := to + ".moving"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := copyPath(from, tmp); err != nil {
+		os.RemoveAll(tmp) //nolint:errcheck,gosec // Best-effort cleanup
+		return err
+	}
+	if err := os.Rename(tmp, to); err != nil {
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// copyPath copies a file or directory tree, keeping permissions
+func copyPath(from, to string) error {
+	return filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("cannot move %s, not a regular file", path)
+		}
+		src, err := os.Open(path) //nolint:gosec // Path below the base directory
+		if err != nil {
+			return err
+		}
+		defer src.Close()                                                                      //nolint:errcheck // Read-only
+		dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm()) //nolint:gosec // Path below the new directory
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, src); err != nil {
+			dst.Close() //nolint:errcheck,gosec // Already failing
+			return err
+		}
+		return dst.Close()
+	})
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	t.Setenv("XDG_CACHE_HOME", "relative/cache")
+	t.Setenv("TREEOS_DIR_LAYOUT", "")
+	t.Setenv("TREEOS_CACHE_DIR", "/mnt/scratch/treeos")
+	t.Setenv("STATE_DIRECTORY", "")
+
+	cfg := &Config{DirLayout: DirLayoutXDG, DatabasePath: GetDatabasePath()}
+	if err := resolveDirs(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".config", "treeos"); cfg.ConfigDir != want {
+		t.Errorf("ConfigDir = %s, want %s", cfg.ConfigDir, want)
+	}
+	if want := filepath.Join(home, "state", "treeos"); cfg.StateDir != want {
+		t.Errorf("StateDir = %s, want %s", cfg.StateDir, want)
+	}
+	if cfg.CacheDir != "/mnt/scratch/treeos" {
+		t.Errorf("CacheDir = %s, want the environment to win", cfg.CacheDir)
+	}
+	if want := filepath.Join(home, "state", "treeos", "ontree.db"); cfg.DatabasePath != want {
+		t.Errorf("DatabasePath = %s, want %s", cfg.DatabasePath, want)
+	}
+
+	// Directories granted by systemd win over the layout, a set database path stays
+	t.Setenv("STATE_DIRECTORY", "/var/lib/treeos:/var/lib/other")
+	cfg = &Config{DirLayout: DirLayoutFHS, DatabasePath: "/data/treeos.db"}
+	if err := resolveDirs(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.StateDir != "/var/lib/treeos" || cfg.ConfigDir != "/etc/treeos" || cfg.DatabasePath != "/data/treeos.db" {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	if err := resolveDirs(&Config{DirLayout: "windows"}); err == nil {
+		t.Error("resolveDirs() accepted an unknown layout")
+	}
+}
+
+func TestPrepareDirs(t *testing.T) {
+	t.Setenv("TREEOS_RUN_MODE", "demo")
+	t.Chdir(t.TempDir())
+
+	// An install that kept everything in the base directory
+	for _, name := range []string{"ontree.db", "ontree.db-wal", "recovery-token", "backups/ontree-1.db", "screenshots/web.png", "sbom/web/nginx.spdx.json"} {
+		if err := os.MkdirAll(filepath.Dir(name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{DatabasePath: "state/ontree.db", StateDir: "state", CacheDir: "cache"}
+	if err := PrepareDirs(cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"state/ontree.db", "state/ontree.db-wal", "state/recovery-token", "state/backups/ontree-1.db", "cache/screenshots/web.png", "cache/sbom/web/nginx.spdx.json"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("%s was not moved: %v", name, err)
+		}
+	}
+	for _, name := range []string{"ontree.db", "screenshots", "sbom"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s is still in the base directory", name)
+		}
+	}
+
+	// Running again leaves a newer database alone
+	if err := os.WriteFile("ontree.db", []byte("stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := PrepareDirs(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile("state/ontree.db"); string(data) != "ontree.db" {
+		t.Errorf("state/ontree.db = %q, was overwritten", data)
+	}
+}
+
+func TestCopyPath(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "ca")
+	if err := os.MkdirAll(filepath.Join(from, "certs"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(from, "certs", "ca.key"), []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	to := filepath.Join(dir, "moved")
+	if err := copyPath(from, to); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(to, "certs", "ca.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("ca.key mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,620 @@
+// Package configbundle exports the declarative state of a node (settings, users, apps and
+// their exposure, file access and shares) as a single YAML file and imports it again.
+//
+// The bundle holds no secrets and no app data: passwords, the Tailscale auth key and the LLM
+// API key are left out, and apps are referenced by their compose file instead of embedding it.
+package configbundle
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the bundle format written by Export
+const Version = 1
+
+// Bundle is the exported state of a node
+type Bundle struct {
+	Version    int          `yaml:"version"`
+	ExportedAt time.Time    `yaml:"exported_at"`
+	Node       Node         `yaml:"node"`
+	Users      []User       `yaml:"users"`
+	Apps       []App        `yaml:"apps"`
+	FileAccess []FileAccess `yaml:"file_access,omitempty"`
+	AppAccess  []AppAccess  `yaml:"app_access,omitempty"`
+	Shares     []Share      `yaml:"shares,omitempty"`
+}
+
+// Node holds the node settings without secrets
+type Node struct {
+	Name               string `yaml:"name"`
+	Icon               string `yaml:"icon,omitempty"`
+	Description        string `yaml:"description,omitempty"`
+	PublicBaseDomain   string `yaml:"public_base_domain,omitempty"`
+	TailscaleTags      string `yaml:"tailscale_tags,omitempty"`
+	UpdateChannel      string `yaml:"update_channel,omitempty"`
+	AgentEnabled       bool   `yaml:"agent_enabled"`
+	AgentCheckInterval string `yaml:"agent_check_interval,omitempty"`
+	AgentLLMAPIURL     string `yaml:"agent_llm_api_url,omitempty"`
+	AgentLLMModel      string `yaml:"agent_llm_model,omitempty"`
+	UptimeKumaBaseURL  string `yaml:"uptime_kuma_base_url,omitempty"`
+}
+
+// User is an account without its password
+type User struct {
+	Username    string `yaml:"username"`
+	Email       string `yaml:"email,omitempty"`
+	FirstName   string `yaml:"first_name,omitempty"`
+	LastName    string `yaml:"last_name,omitempty"`
+	IsStaff     bool   `yaml:"is_staff"`
+	IsSuperuser bool   `yaml:"is_superuser"`
+	IsActive    bool   `yaml:"is_active"`
+	Role        string `yaml:"role,omitempty"` // "viewer" for users who can't change apps
+}
+
+// App references an installed app and carries its TreeOS settings
+type App struct {
+	Name          string   `yaml:"name"`
+	ComposeFile   string   `yaml:"compose_file"` // Relative to the apps directory
+	EnvFile       string   `yaml:"env_file,omitempty"`
+	ComposeSHA256 string   `yaml:"compose_sha256"`
+	Emoji         string   `yaml:"emoji,omitempty"`
+	StorageClass  string   `yaml:"storage_class,omitempty"`
+	DiskQuota     string   `yaml:"disk_quota,omitempty"`
+	Exposure      Exposure `yaml:"exposure"`
+}
+
+// Exposure is how an app is reachable from outside the node
+type Exposure struct {
+	Public            bool   `yaml:"public"`
+	Subdomain         string `yaml:"subdomain,omitempty"`
+	HostPort          int    `yaml:"host_port,omitempty"`
+	Tailscale         bool   `yaml:"tailscale"`
+	TailscaleHostname string `yaml:"tailscale_hostname,omitempty"`
+}
+
+// FileAccess grants a user access to an app's files
+type FileAccess struct {
+	Username string `yaml:"username"`
+	App      string `yaml:"app"`
+	Access   string `yaml:"access"`
+}
+
+// AppAccess grants a user view or control access to an app
+type AppAccess struct {
+	Username string `yaml:"username"`
+	App      string `yaml:"app"`
+	Access   string `yaml:"access"`
+}
+
+// Share is a Samba share of an app's data
+type Share struct {
+	Name       string   `yaml:"name"`
+	App        string   `yaml:"app"`
+	SubPath    string   `yaml:"sub_path,omitempty"`
+	Comment    string   `yaml:"comment,omitempty"`
+	ReadOnly   bool     `yaml:"read_only"`
+	ValidUsers []string `yaml:"valid_users,omitempty"`
+}
+
+// ImportResult describes what an import changed, or would change in a dry run
+type ImportResult struct {
+	DryRun      bool              `json:"dry_run"`
+	Changes     []string          `json:"changes"`
+	Warnings    []string          `json:"warnings"`
+	MissingApps []string          `json:"missing_apps"`
+	Passwords   map[string]string `json:"passwords,omitempty"` // Temporary passwords of created users
+	AppsChanged bool              `json:"apps_changed"`
+	// SharesChanged is set when the Samba configuration has to be written again
+	SharesChanged bool `json:"shares_changed"`
+}
+
+// Marshal encodes a bundle as YAML
+func Marshal(bundle *Bundle) ([]byte, error) {
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	return data, nil
+}
+
+// Parse decodes a bundle and checks its version
+func Parse(data []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	if bundle.Version != Version {
+		return nil, fmt.Errorf("unsupported bundle version %d, expected %d", bundle.Version, Version)
+	}
+	for _, user := range bundle.Users {
+		if user.Username == "" {
+			return nil, errors.New("bundle contains a user without username")
+		}
+	}
+	for _, grant := range bundle.FileAccess {
+		if grant.Access != database.FileAccessRead && grant.Access != database.FileAccessWrite {
+			return nil, fmt.Errorf("invalid access level %q for %s on %s", grant.Access, grant.Username, grant.App)
+		}
+	}
+	for _, grant := range bundle.AppAccess {
+		if grant.Access != database.AppAccessView && grant.Access != database.AppAccessControl {
+			return nil, fmt.Errorf("invalid app access %q for %s on %s", grant.Access, grant.Username, grant.App)
+		}
+	}
+	return &bundle, nil
+}
+
+// Export reads the state of the node from the database and the apps directory
+func Export(appsDir string) (*Bundle, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	bundle := &Bundle{Version: Version, ExportedAt: time.Now().UTC()}
+	node, err := readNode(db)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Node = node
+
+	if bundle.Users, err = readUsers(db); err != nil {
+		return nil, err
+	}
+	if bundle.Apps, err = readApps(appsDir); err != nil {
+		return nil, err
+	}
+
+	grants, err := database.GetFileAccessGrants()
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range grants {
+		bundle.FileAccess = append(bundle.FileAccess, FileAccess{Username: grant.Username, App: grant.AppName, Access: grant.Access})
+	}
+
+	permissions, err := database.GetAppPermissions()
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range permissions {
+		bundle.AppAccess = append(bundle.AppAccess, AppAccess{Username: permission.Username, App: permission.AppName, Access: permission.Access})
+	}
+
+	shares, err := database.GetShares()
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		bundle.Shares = append(bundle.Shares, Share{
+			Name:       share.Name,
+			App:        share.AppName,
+			SubPath:    share.SubPath,
+			Comment:    share.Comment,
+			ReadOnly:   share.ReadOnly,
+			ValidUsers: share.ValidUsers,
+		})
+	}
+	return bundle, nil
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func readNode(q queryer) (Node, error) {
+	var (
+		node                                           Node
+		name, icon, description, domain, tags, channel sql.NullString
+		interval, apiURL, model, kumaURL               sql.NullString
+		agentEnabled                                   sql.NullBool
+	)
+	err := q.QueryRow(`
+		SELECT node_name, node_icon, node_description, public_base_domain, tailscale_tags, update_channel,
+		       agent_enabled, agent_check_interval, agent_llm_api_url, agent_llm_model, uptime_kuma_base_url
+		FROM system_setup
+		WHERE id = 1
+	`).Scan(&name, &icon, &description, &domain, &tags, &channel, &agentEnabled, &interval, &apiURL, &model, &kumaURL)
+	if err == sql.ErrNoRows {
+		return node, nil
+	}
+	if err != nil {
+		return node, fmt.Errorf("failed to read node settings: %w", err)
+	}
+
+	node.Name = name.String
+	node.Icon = icon.String
+	node.Description = description.String
+	node.PublicBaseDomain = domain.String
+	node.TailscaleTags = tags.String
+	node.UpdateChannel = channel.String
+	node.AgentEnabled = agentEnabled.Bool
+	node.AgentCheckInterval = interval.String
+	node.AgentLLMAPIURL = apiURL.String
+	node.AgentLLMModel = model.String
+	node.UptimeKumaBaseURL = kumaURL.String
+	return node, nil
+}
+
+func readUsers(q queryer) ([]User, error) {
+	rows, err := q.Query(`
+		SELECT username, email, first_name, last_name, is_staff, is_superuser, is_active,
+		       CASE WHEN role = 'viewer' AND is_staff = 0 THEN 'viewer' ELSE '' END
+		FROM users
+		ORDER BY username
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	users := []User{}
+	for rows.Next() {
+		var (
+			user               User
+			email, first, last sql.NullString
+		)
+		if err := rows.Scan(&user.Username, &email, &first, &last, &user.IsStaff, &user.IsSuperuser, &user.IsActive, &user.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.Email, user.FirstName, user.LastName = email.String, first.String, last.String
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func readApps(appsDir string) ([]App, error) {
+	entries, err := os.ReadDir(appsDir)
+	if os.IsNotExist(err) {
+		return []App{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apps directory: %w", err)
+	}
+
+	apps := []App{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		app, err := readApp(appsDir, entry.Name())
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+func readApp(appsDir, name string) (App, error) {
+	app := App{Name: name, ComposeFile: filepath.Join(name, "docker-compose.yml")}
+	content, err := os.ReadFile(filepath.Join(appsDir, app.ComposeFile))
+	if err != nil {
+		return app, err
+	}
+	sum := sha256.Sum256(content)
+	app.ComposeSHA256 = hex.EncodeToString(sum[:])
+	if _, err := os.Stat(filepath.Join(appsDir, name, ".env")); err == nil {
+		app.EnvFile = filepath.Join(name, ".env")
+	}
+
+	metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(appsDir, name))
+	if err != nil {
+		return app, fmt.Errorf("failed to read metadata of %s: %w", name, err)
+	}
+	app.Emoji = metadata.Emoji
+	app.StorageClass = metadata.StorageClass
+	app.DiskQuota = metadata.DiskQuota
+	app.Exposure = Exposure{
+		Public:            metadata.IsExposed,
+		Subdomain:         metadata.Subdomain,
+		HostPort:          metadata.HostPort,
+		Tailscale:         metadata.TailscaleExposed,
+		TailscaleHostname: metadata.TailscaleHostname,
+	}
+	return app, nil
+}
+
+// Import applies a bundle. Settings, users, file access and shares are created or updated,
+// nothing is deleted. Apps are not installed, their settings are applied to apps present in
+// appsDir and missing ones are reported. With dryRun nothing is changed.
+func Import(appsDir string, bundle *Bundle, dryRun bool) (*ImportResult, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	result := &ImportResult{DryRun: dryRun, Changes: []string{}, Warnings: []string{}, MissingApps: []string{}}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start import: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit, discards dry runs
+
+	if err := importNode(tx, bundle.Node, result); err != nil {
+		return nil, err
+	}
+	if err := importUsers(tx, bundle.Users, result); err != nil {
+		return nil, err
+	}
+	if err := importFileAccess(tx, bundle.FileAccess, result); err != nil {
+		return nil, err
+	}
+	if err := importAppAccess(tx, bundle.AppAccess, result); err != nil {
+		return nil, err
+	}
+	if err := importShares(tx, bundle.Shares, result); err != nil {
+		return nil, err
+	}
+
+	updates, err := planApps(appsDir, bundle.Apps, result)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		result.Passwords = nil
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	for name, metadata := range updates {
+		if err := yamlutil.UpdateComposeMetadata(filepath.Join(appsDir, name), metadata); err != nil {
+			return result, fmt.Errorf("failed to update settings of %s: %w", name, err)
+		}
+	}
+	return result, nil
+}
+
+func importNode(tx *sql.Tx, node Node, result *ImportResult) error {
+	current, err := readNode(tx)
+	if err != nil {
+		return err
+	}
+	if current == node {
+		return nil
+	}
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO system_setup (id, is_setup_complete) VALUES (1, 0)`); err != nil {
+		return fmt.Errorf("failed to ensure system setup row: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE system_setup
+		SET node_name = ?, node_icon = ?, node_description = ?, public_base_domain = ?, tailscale_tags = ?,
+		    update_channel = ?, agent_enabled = ?, agent_check_interval = ?, agent_llm_api_url = ?,
+		    agent_llm_model = ?, uptime_kuma_base_url = ?
+		WHERE id = 1
+	`, node.Name, node.Icon, node.Description, node.PublicBaseDomain, node.TailscaleTags, node.UpdateChannel,
+		node.AgentEnabled, node.AgentCheckInterval, node.AgentLLMAPIURL, node.AgentLLMModel, node.UptimeKumaBaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to update node settings: %w", err)
+	}
+	result.Changes = append(result.Changes, "Update node settings")
+	return nil
+}
+
+func importUsers(tx *sql.Tx, users []User, result *ImportResult) error {
+	existing, err := readUsers(tx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]User, len(existing))
+	for _, user := range existing {
+		byName[user.Username] = user
+	}
+
+	for _, user := range users {
+		current, found := byName[user.Username]
+		switch {
+		case !found:
+			password, err := temporaryPassword()
+			if err != nil {
+				return err
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				return fmt.Errorf("failed to hash password: %w", err)
+			}
+			_, err = tx.Exec(`
+				INSERT INTO users (username, password, email, first_name, last_name, is_staff, is_superuser, is_active, role)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE(NULLIF(?, ''), 'operator'))
+			`, user.Username, string(hash), user.Email, user.FirstName, user.LastName, user.IsStaff, user.IsSuperuser, user.IsActive, user.Role)
+			if err != nil {
+				return fmt.Errorf("failed to create user %s: %w", user.Username, err)
+			}
+			if result.Passwords == nil {
+				result.Passwords = make(map[string]string)
+			}
+			result.Passwords[user.Username] = password
+			result.Changes = append(result.Changes, "Create user "+user.Username)
+		case current != user:
+			_, err := tx.Exec(`
+				UPDATE users
+				SET email = ?, first_name = ?, last_name = ?, is_staff = ?, is_superuser = ?, is_active = ?,
+				    role = COALESCE(NULLIF(?, ''), 'operator')
+				WHERE username = ?
+			`, user.Email, user.FirstName, user.LastName, user.IsStaff, user.IsSuperuser, user.IsActive, user.Role, user.Username)
+			if err != nil {
+				return fmt.Errorf("failed to update user %s: %w", user.Username, err)
+			}
+			result.Changes = append(result.Changes, "Update user "+user.Username)
+		}
+	}
+	return nil
+}
+
+// temporaryPassword is given to imported users, passwords are not part of the bundle
+func temporaryPassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func importFileAccess(tx *sql.Tx, grants []FileAccess, result *ImportResult) error {
+	for _, grant := range grants {
+		var userID int
+		if err := tx.QueryRow(`SELECT id FROM users WHERE username = ?`, grant.Username).Scan(&userID); err != nil {
+			if err == sql.ErrNoRows {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Skipped file access for unknown user %s", grant.Username))
+				continue
+			}
+			return fmt.Errorf("failed to look up user %s: %w", grant.Username, err)
+		}
+
+		var current string
+		err := tx.QueryRow(`SELECT access FROM file_access_grants WHERE user_id = ? AND app_name = ?`, userID, grant.App).Scan(&current)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to read file access: %w", err)
+		}
+		if current == grant.Access {
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT INTO file_access_grants (user_id, app_name, access)
+			VALUES (?, ?, ?)
+			ON CONFLICT(user_id, app_name) DO UPDATE SET access = excluded.access
+		`, userID, grant.App, grant.Access)
+		if err != nil {
+			return fmt.Errorf("failed to set file access: %w", err)
+		}
+		result.Changes = append(result.Changes, fmt.Sprintf("Grant %s %s access to %s", grant.Username, grant.Access, grant.App))
+	}
+	return nil
+}
+
+func importAppAccess(tx *sql.Tx, grants []AppAccess, result *ImportResult) error {
+	for _, grant := range grants {
+		var userID int
+		if err := tx.QueryRow(`SELECT id FROM users WHERE username = ?`, grant.Username).Scan(&userID); err != nil {
+			if err == sql.ErrNoRows {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Skipped app access for unknown user %s", grant.Username))
+				continue
+			}
+			return fmt.Errorf("failed to look up user %s: %w", grant.Username, err)
+		}
+
+		var current string
+		err := tx.QueryRow(`SELECT access FROM app_permissions WHERE user_id = ? AND app_name = ?`, userID, grant.App).Scan(&current)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to read app access: %w", err)
+		}
+		if current == grant.Access {
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT INTO app_permissions (user_id, app_name, access)
+			VALUES (?, ?, ?)
+			ON CONFLICT(user_id, app_name) DO UPDATE SET access = excluded.access
+		`, userID, grant.App, grant.Access)
+		if err != nil {
+			return fmt.Errorf("failed to set app access: %w", err)
+		}
+		result.Changes = append(result.Changes, fmt.Sprintf("Grant %s %s access to app %s", grant.Username, grant.Access, grant.App))
+	}
+	return nil
+}
+
+func importShares(tx *sql.Tx, shares []Share, result *ImportResult) error {
+	for _, share := range shares {
+		var (
+			app, subPath, comment, validUsers string
+			readOnly                          bool
+		)
+		err := tx.QueryRow(`SELECT app_name, sub_path, comment, read_only, valid_users FROM shares WHERE name = ?`, share.Name).
+			Scan(&app, &subPath, &comment, &readOnly, &validUsers)
+		users := strings.Join(share.ValidUsers, " ")
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.Exec(`
+				INSERT INTO shares (name, app_name, sub_path, comment, read_only, valid_users)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, share.Name, share.App, share.SubPath, share.Comment, share.ReadOnly, users)
+			if err != nil {
+				return fmt.Errorf("failed to create share %s: %w", share.Name, err)
+			}
+			result.Changes = append(result.Changes, "Create share "+share.Name)
+		case err != nil:
+			return fmt.Errorf("failed to read share %s: %w", share.Name, err)
+		case app != share.App || subPath != share.SubPath || comment != share.Comment || readOnly != share.ReadOnly || validUsers != users:
+			_, err = tx.Exec(`
+				UPDATE shares SET app_name = ?, sub_path = ?, comment = ?, read_only = ?, valid_users = ?
+				WHERE name = ?
+			`, share.App, share.SubPath, share.Comment, share.ReadOnly, users, share.Name)
+			if err != nil {
+				return fmt.Errorf("failed to update share %s: %w", share.Name, err)
+			}
+			result.Changes = append(result.Changes, "Update share "+share.Name)
+		default:
+			continue
+		}
+		result.SharesChanged = true
+	}
+	return nil
+}
+
+// planApps compares the app settings with the apps on disk and returns the metadata to write
+func planApps(appsDir string, apps []App, result *ImportResult) (map[string]*yamlutil.OnTreeMetadata, error) {
+	updates := make(map[string]*yamlutil.OnTreeMetadata)
+	for _, app := range apps {
+		if app.Name == "" || app.Name != filepath.Base(app.Name) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Skipped app with invalid name %q", app.Name))
+			continue
+		}
+		current, err := readApp(appsDir, app.Name)
+		if os.IsNotExist(err) {
+			result.MissingApps = append(result.MissingApps, app.Name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if app.ComposeSHA256 != "" && current.ComposeSHA256 != app.ComposeSHA256 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("The compose file of %s differs from the exported one", app.Name))
+		}
+
+		current.ComposeSHA256, current.EnvFile, current.ComposeFile = app.ComposeSHA256, app.EnvFile, app.ComposeFile
+		if current == app {
+			continue
+		}
+		metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(appsDir, app.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata of %s: %w", app.Name, err)
+		}
+		metadata.Emoji = app.Emoji
+		metadata.StorageClass = app.StorageClass
+		metadata.DiskQuota = app.DiskQuota
+		metadata.IsExposed = app.Exposure.Public
+		metadata.Subdomain = app.Exposure.Subdomain
+		metadata.HostPort = app.Exposure.HostPort
+		metadata.TailscaleExposed = app.Exposure.Tailscale
+		metadata.TailscaleHostname = app.Exposure.TailscaleHostname
+		updates[app.Name] = metadata
+		result.Changes = append(result.Changes, "Update settings of app "+app.Name)
+		result.AppsChanged = true
+	}
+	sort.Strings(result.MissingApps)
+	return updates, nil
+}
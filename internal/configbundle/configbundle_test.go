@@ -0,0 +1,158 @@
+package configbundle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+const testCompose = `services:
+  web:
+    image: nginx:latest
+x-ontree:
+  emoji: "🌐"
+  subdomain: web
+  host_port: 8080
+  is_exposed: true
+  tailscale_exposed: false
+  bypass_security: false
+`
+
+// setupNode creates a database with an admin, a grant and a share, and one app
+func setupNode(t *testing.T) string {
+	t.Helper()
+	if err := database.Initialize(filepath.Join(t.TempDir(), "ontree.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() }) //nolint:errcheck,gosec // Test cleanup
+
+	db := database.GetDB()
+	for _, query := range []string{
+		`INSERT INTO users (id, username, password, email, is_staff) VALUES (1, 'admin', 'hash', 'admin@example.com', 1)`,
+		`INSERT INTO system_setup (id, is_setup_complete, node_name, tailscale_auth_key) VALUES (1, 1, 'Homelab', 'tskey-secret')`,
+		`INSERT INTO file_access_grants (user_id, app_name, access) VALUES (1, 'web', 'write')`,
+		`INSERT INTO shares (name, app_name, comment, valid_users) VALUES ('media', 'web', 'Media', 'alice bob')`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("Failed to seed database: %v", err)
+		}
+	}
+
+	appsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appsDir, "web"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appsDir, "web", "docker-compose.yml"), []byte(testCompose), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return appsDir
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	appsDir := setupNode(t)
+
+	bundle, err := Export(appsDir)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	data, err := Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if strings.Contains(string(data), "tskey-secret") || strings.Contains(string(data), "hash") {
+		t.Errorf("bundle contains secrets:\n%s", data)
+	}
+
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if parsed.Node.Name != "Homelab" {
+		t.Errorf("node name = %q, want Homelab", parsed.Node.Name)
+	}
+	if len(parsed.Users) != 1 || parsed.Users[0].Username != "admin" || !parsed.Users[0].IsStaff {
+		t.Errorf("users = %+v", parsed.Users)
+	}
+	if len(parsed.Apps) != 1 || !parsed.Apps[0].Exposure.Public || parsed.Apps[0].Exposure.HostPort != 8080 {
+		t.Errorf("apps = %+v", parsed.Apps)
+	}
+	if len(parsed.Shares) != 1 || len(parsed.Shares[0].ValidUsers) != 2 {
+		t.Errorf("shares = %+v", parsed.Shares)
+	}
+
+	// Importing an unchanged export changes nothing
+	result, err := Import(appsDir, parsed, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Changes) != 0 || len(result.Warnings) != 0 {
+		t.Errorf("Import() of unchanged bundle = %+v", result)
+	}
+}
+
+func TestImport(t *testing.T) {
+	appsDir := setupNode(t)
+	bundle, err := Export(appsDir)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	bundle.Node.PublicBaseDomain = "example.com"
+	bundle.Users = append(bundle.Users, User{Username: "alice", IsActive: true})
+	bundle.FileAccess = append(bundle.FileAccess, FileAccess{Username: "alice", App: "web", Access: database.FileAccessRead})
+	bundle.AppAccess = append(bundle.AppAccess, AppAccess{Username: "alice", App: "web", Access: database.AppAccessView})
+	bundle.Apps[0].Emoji = "🚀"
+	bundle.Apps = append(bundle.Apps, App{Name: "missing", ComposeFile: "missing/docker-compose.yml"})
+
+	dryRun, err := Import(appsDir, bundle, true)
+	if err != nil {
+		t.Fatalf("Import(dry run) error = %v", err)
+	}
+	if len(dryRun.Changes) != 5 || len(dryRun.Passwords) != 0 {
+		t.Errorf("dry run result = %+v", dryRun)
+	}
+	var count int
+	if err := database.GetDB().QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil || count != 1 {
+		t.Errorf("dry run created users: count = %d, err = %v", count, err)
+	}
+
+	result, err := Import(appsDir, bundle, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.MissingApps) != 1 || result.MissingApps[0] != "missing" {
+		t.Errorf("missing apps = %v", result.MissingApps)
+	}
+	if result.Passwords["alice"] == "" {
+		t.Error("expected a temporary password for alice")
+	}
+	if !result.AppsChanged || result.SharesChanged {
+		t.Errorf("apps changed = %v, shares changed = %v", result.AppsChanged, result.SharesChanged)
+	}
+
+	access, err := database.GetFileAccessGrants()
+	if err != nil || len(access) != 2 {
+		t.Errorf("file access = %+v, err = %v", access, err)
+	}
+	if permissions, err := database.GetAppPermissions(); err != nil || len(permissions) != 1 || permissions[0].Access != database.AppAccessView {
+		t.Errorf("app access = %+v, err = %v", permissions, err)
+	}
+	metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(appsDir, "web"))
+	if err != nil || metadata.Emoji != "🚀" || !metadata.IsExposed {
+		t.Errorf("metadata = %+v, err = %v", metadata, err)
+	}
+	var domain string
+	if err := database.GetDB().QueryRow(`SELECT public_base_domain FROM system_setup WHERE id = 1`).Scan(&domain); err != nil || domain != "example.com" {
+		t.Errorf("public_base_domain = %q, err = %v", domain, err)
+	}
+}
+
+func TestParseRejectsUnknownVersion(t *testing.T) {
+	if _, err := Parse([]byte("version: 2\n")); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ActivityFilter selects the entries of the activity feed
+type ActivityFilter struct {
+	Category string    // One of the Activity constants, "" for all
+	AppName  string    // "" for all apps and system events
+	Since    time.Time // Zero for no bound
+	Limit    int
+}
+
+// activitySource reads one table into the activity feed. The query selects title, detail,
+// app_name, actor, status, the time of the event and category.
+type activitySource struct {
+	category string
+	query    string
+}
+
+var activitySources = []activitySource{
+	{"", `
+		SELECT title, detail, app_name, actor, status, created_at, category
+		FROM activity_events`},
+	{ActivityAudit, `
+		SELECT CASE action WHEN 'bypass_enabled' THEN 'Security checks bypassed' ELSE 'Security checks enabled' END,
+		       justification, app_name, username, action, created_at, 'audit'
+		FROM security_audit_log`},
+	{ActivityAudit, `
+		SELECT 'Invite ' || action, ip_address, '', username, action, created_at, 'audit'
+		FROM invite_events`},
+	{ActivityAudit, `
+		SELECT 'Login from a new device', ip_address || ' ' || COALESCE(location, ''), '', u.username, '', e.created_at, 'audit'
+		FROM login_events e JOIN users u ON u.id = e.user_id
+		WHERE e.is_new_device = 1`},
+	{ActivityAudit, `
+		SELECT 'Agent action ' || action, COALESCE(NULLIF(result, ''), reason), app_name, decided_by, status, decided_at, 'audit'
+		FROM agent_actions
+		WHERE decided_at IS NOT NULL`},
+	{ActivityJob, `
+		SELECT 'Rebuild', COALESCE(message, ''), app_name, COALESCE(requested_by, ''), status, finished_at, 'job'
+		FROM app_rebuilds
+		WHERE finished_at IS NOT NULL`},
+	{ActivityJob, `
+		SELECT 'Image prefetch of ' || image, COALESCE(message, ''), '', COALESCE(requested_by, ''), status, updated_at, 'job'
+		FROM image_prefetches
+		WHERE status IN ('done', 'failed')`},
+	{ActivityJob, `
+		SELECT 'Host reboot', COALESCE(NULLIF(message, ''), reason, ''), '', COALESCE(requested_by, ''), status, updated_at, 'job'
+		FROM host_reboots
+		WHERE status IN ('completed', 'failed', 'cancelled')`},
+	{ActivityJob, `
+		SELECT 'Model download of ' || name, COALESCE(last_error, ''), '', '', status, updated_at, 'job'
+		FROM ollama_models
+		WHERE status IN ('completed', 'failed')`},
+	{ActivityJob, `
+		SELECT 'Agent review', COALESCE(NULLIF(error, ''), summary), '', '', status, completed_at, 'job'
+		FROM agent_reviews
+		WHERE completed_at IS NOT NULL`},
+	{ActivityUpdate, `
+		SELECT 'Update to ' || version, COALESCE(error_message, ''), '', '', status, completed_at, 'update'
+		FROM update_history
+		WHERE completed_at IS NOT NULL`},
+}
+
+// RecordActivity stores an event that has no history table of its own, such as an alert
+func RecordActivity(event ActivityEvent) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO activity_events (category, title, detail, app_name, actor, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, event.Category, event.Title, event.Detail, event.AppName, event.Actor, event.Status)
+	if err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
+// GetActivity returns the newest events of the activity feed. The sources store times in
+// different formats, so each is read newest first and the results are merged and bounded
+// by Since here.
+func GetActivity(filter ActivityFilter) ([]ActivityEvent, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	events := []ActivityEvent{}
+	for _, source := range activitySources {
+		if filter.Category != "" && source.category != "" && source.category != filter.Category {
+			continue
+		}
+		found, err := readActivity(db, source.query, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range found {
+			if !e.CreatedAt.Before(filter.Since) {
+				events = append(events, e)
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.After(events[j].CreatedAt) })
+	if len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
+
+func readActivity(db *sql.DB, query string, filter ActivityFilter) ([]ActivityEvent, error) {
+	rows, err := db.Query(`
+		WITH source (title, detail, app_name, actor, status, at, category) AS (`+query+`)
+		SELECT title, detail, app_name, actor, status, at, category FROM source
+		WHERE (? = '' OR category = ?) AND (? = '' OR app_name = ?) AND at IS NOT NULL
+		ORDER BY at DESC LIMIT ?
+	`, filter.Category, filter.Category, filter.AppName, filter.AppName, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	var events []ActivityEvent
+	for rows.Next() {
+		var e ActivityEvent
+		var detail, appName, actor, status sql.NullString
+		var at interface{}
+		if err := rows.Scan(&e.Title, &detail, &appName, &actor, &status, &at, &e.Category); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		e.Detail, e.AppName, e.Actor, e.Status = detail.String, appName.String, actor.String, status.String
+		if e.CreatedAt, err = activityTime(at); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// activityTime converts the time of an event, which the driver only parses for columns it
+// knows to be DATETIME
+func activityTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		trimmed := strings.TrimSuffix(v, "Z")
+		for _, format := range sqlite3.SQLiteTimestampFormats {
+			if t, err := time.ParseInLocation(format, trimmed, time.UTC); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid activity time %v", value)
+}
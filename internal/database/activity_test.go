@@ -0,0 +1,66 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetActivity(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := RecordSecurityAudit(SecurityAuditEvent{AppName: "photos", Username: "admin", Action: SecurityBypassEnabled, Justification: "needs host network"}); err != nil {
+		t.Fatal(err)
+	}
+	rebuild := AppRebuild{AppName: "blog", Trigger: "schedule"}
+	if err := StartAppRebuild(&rebuild); err != nil {
+		t.Fatal(err)
+	}
+	if err := FinishAppRebuild(rebuild.ID, RebuildStatusSucceeded, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordActivity(ActivityEvent{Category: ActivityAlert, Title: "Disk almost full", Detail: "92% used"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetDB().Exec(`
+		INSERT INTO update_history (version, channel, status, started_at, completed_at)
+		VALUES ('1.4.0', 'stable', 'success', datetime('now', '-3 days'), datetime('now', '-3 days'))
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	titles := func(events []ActivityEvent) string {
+		var all []string
+		for _, e := range events {
+			all = append(all, e.Title)
+		}
+		return strings.Join(all, ",")
+	}
+
+	events, err := GetActivity(ActivityFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 || events[3].Title != "Update to 1.4.0" || events[3].Category != ActivityUpdate {
+		t.Fatalf("GetActivity() = %s", titles(events))
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].CreatedAt.After(events[i-1].CreatedAt) {
+			t.Errorf("events aren't newest first: %+v", events)
+		}
+	}
+
+	if events, err := GetActivity(ActivityFilter{Category: ActivityAlert, Limit: 10}); err != nil || titles(events) != "Disk almost full" {
+		t.Errorf("alerts = %s, %v", titles(events), err)
+	}
+	if events, err := GetActivity(ActivityFilter{AppName: "photos", Limit: 10}); err != nil || titles(events) != "Security checks bypassed" {
+		t.Errorf("activity of photos = %s, %v", titles(events), err)
+	}
+	if events, err := GetActivity(ActivityFilter{Since: time.Now().Add(-24 * time.Hour), Limit: 10}); err != nil || len(events) != 3 {
+		t.Errorf("activity of the last day = %s, %v", titles(events), err)
+	}
+	if events, err := GetActivity(ActivityFilter{Limit: 2}); err != nil || len(events) != 2 {
+		t.Errorf("limited activity = %s, %v", titles(events), err)
+	}
+}
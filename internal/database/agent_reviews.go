@@ -0,0 +1,269 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CreateAgentReview stores a new running review and returns its ID
+func CreateAgentReview(model string) (int, error) {
+	db := GetDB()
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`INSERT INTO agent_reviews (status, model) VALUES (?, ?)`, ReviewStatusRunning, model)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create agent review: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get agent review id: %w", err)
+	}
+	return int(id), nil
+}
+
+// CompleteAgentReview stores the result of a review
+func CompleteAgentReview(id int, summary string, findings []AgentFinding) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	data, err := json.Marshal(findings)
+	if err != nil {
+		return fmt.Errorf("failed to encode agent review findings: %w", err)
+	}
+	_, err = db.Exec(`
+		UPDATE agent_reviews SET status = ?, summary = ?, findings = ?, completed_at = ?
+		WHERE id = ?
+	`, ReviewStatusCompleted, summary, string(data), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to complete agent review: %w", err)
+	}
+	return nil
+}
+
+// FailAgentReview marks a review as failed
+func FailAgentReview(id int, message string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		UPDATE agent_reviews SET status = ?, error = ?, completed_at = ?
+		WHERE id = ?
+	`, ReviewStatusFailed, message, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update agent review: %w", err)
+	}
+	return nil
+}
+
+// GetAgentReviews returns the most recent reviews, newest first
+func GetAgentReviews(limit int) ([]AgentReview, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, status, summary, findings, model, error, created_at, completed_at
+		FROM agent_reviews
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent reviews: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	reviews := []AgentReview{}
+	for rows.Next() {
+		review, err := scanAgentReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, *review)
+	}
+	return reviews, rows.Err()
+}
+
+// GetAgentReview returns a review, or nil if it doesn't exist
+func GetAgentReview(id int) (*AgentReview, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	review, err := scanAgentReview(db.QueryRow(`
+		SELECT id, status, summary, findings, model, error, created_at, completed_at
+		FROM agent_reviews
+		WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return review, err
+}
+
+func scanAgentReview(row interface{ Scan(...any) error }) (*AgentReview, error) {
+	var r AgentReview
+	var findings string
+	var completedAt sql.NullTime
+	if err := row.Scan(&r.ID, &r.Status, &r.Summary, &findings, &r.Model, &r.Error, &r.CreatedAt, &completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan agent review: %w", err)
+	}
+	if completedAt.Valid {
+		r.CompletedAt = &completedAt.Time
+	}
+	r.Findings = []AgentFinding{}
+	if err := json.Unmarshal([]byte(findings), &r.Findings); err != nil {
+		return nil, fmt.Errorf("failed to decode agent review findings: %w", err)
+	}
+	return &r, nil
+}
+
+// CreateAgentAction stores a suggested action waiting for approval and returns its ID
+func CreateAgentAction(action AgentAction) (int, error) {
+	db := GetDB()
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	var reviewID sql.NullInt64
+	if action.ReviewID != 0 {
+		reviewID = sql.NullInt64{Int64: int64(action.ReviewID), Valid: true}
+	}
+	result, err := db.Exec(`
+		INSERT INTO agent_actions (review_id, app_name, action, reason, status)
+		VALUES (?, ?, ?, ?, ?)
+	`, reviewID, action.AppName, action.Action, action.Reason, ActionStatusPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create agent action: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get agent action id: %w", err)
+	}
+	return int(id), nil
+}
+
+// HasPendingAgentAction reports whether the same action already waits for approval
+func HasPendingAgentAction(appName, action string) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM agent_actions WHERE app_name = ? AND action = ? AND status = ?
+	`, appName, action, ActionStatusPending).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to query agent actions: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetAgentActions returns actions with the given status, or all if status is empty, newest first
+func GetAgentActions(status string, limit int) ([]AgentAction, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, COALESCE(review_id, 0), app_name, action, reason, status, result, decided_by, created_at, decided_at
+		FROM agent_actions
+		WHERE ? = '' OR status = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, status, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent actions: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	actions := []AgentAction{}
+	for rows.Next() {
+		var a AgentAction
+		var decidedAt sql.NullTime
+		if err := rows.Scan(&a.ID, &a.ReviewID, &a.AppName, &a.Action, &a.Reason, &a.Status, &a.Result,
+			&a.DecidedBy, &a.CreatedAt, &decidedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan agent action: %w", err)
+		}
+		if decidedAt.Valid {
+			a.DecidedAt = &decidedAt.Time
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
+// DecideAgentAction moves a pending action to the given status. It returns false if the
+// action is no longer pending, e.g. because another admin decided first.
+func DecideAgentAction(id int, status, decidedBy string) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		UPDATE agent_actions SET status = ?, decided_by = ?, decided_at = ?
+		WHERE id = ? AND status = ?
+	`, status, decidedBy, time.Now(), id, ActionStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to decide agent action: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to decide agent action: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// FinishAgentAction records the outcome of an approved action
+func FinishAgentAction(id int, status, result string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE agent_actions SET status = ?, result = ? WHERE id = ?`, status, result, id); err != nil {
+		return fmt.Errorf("failed to update agent action: %w", err)
+	}
+	return nil
+}
+
+// GetAgentAction returns an action, or nil if it doesn't exist
+func GetAgentAction(id int) (*AgentAction, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var a AgentAction
+	var decidedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT id, COALESCE(review_id, 0), app_name, action, reason, status, result, decided_by, created_at, decided_at
+		FROM agent_actions
+		WHERE id = ?
+	`, id).Scan(&a.ID, &a.ReviewID, &a.AppName, &a.Action, &a.Reason, &a.Status, &a.Result, &a.DecidedBy, &a.CreatedAt, &decidedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent action: %w", err)
+	}
+	if decidedAt.Valid {
+		a.DecidedAt = &decidedAt.Time
+	}
+	return &a, nil
+}
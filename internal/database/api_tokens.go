@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateAPIToken stores a new API token with the hash of its secret, setting its ID
+func CreateAPIToken(token *APIToken, tokenHash string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var expiresAt interface{}
+	if token.ExpiresAt != nil {
+		expiresAt = token.ExpiresAt.UTC()
+	}
+	err := db.QueryRow(`
+		INSERT INTO api_tokens (user_id, name, token_hash, prefix, scope, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at
+	`, token.UserID, token.Name, tokenHash, token.Prefix, token.Scope, expiresAt).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API token: %w", err)
+	}
+	return nil
+}
+
+const apiTokenColumns = `id, user_id, name, prefix, scope, expires_at, last_used_at, created_at, revoked_at`
+
+func scanAPIToken(row interface{ Scan(...any) error }) (*APIToken, error) {
+	var t APIToken
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scope, &expiresAt, &lastUsedAt, &t.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return &t, nil
+}
+
+// GetAPITokens returns the tokens of a user that aren't revoked, newest first
+func GetAPITokens(userID int) ([]APIToken, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// GetAPITokenByHash returns the token with a secret, nil if there is none
+func GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	t, err := scanAPIToken(db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API token: %w", err)
+	}
+	return t, nil
+}
+
+// TouchAPIToken records that a token was used
+func TouchAPIToken(id int, now time.Time) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, now.UTC(), id); err != nil {
+		return fmt.Errorf("failed to update API token: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIToken revokes a token of a user, reporting false if the user has no such token
+// or it is already revoked
+func RevokeAPIToken(id, userID int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API token: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPITokens(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	expiresAt := time.Now().Add(time.Hour)
+	token := APIToken{UserID: 1, Name: "backup", Prefix: "treeos_abcdef", Scope: APITokenScopeRead, ExpiresAt: &expiresAt}
+	if err := CreateAPIToken(&token, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if token.ID == 0 || token.CreatedAt.IsZero() {
+		t.Errorf("CreateAPIToken() = %+v", token)
+	}
+	if err := CreateAPIToken(&APIToken{UserID: 1, Name: "copy", Scope: APITokenScopeFull}, "hash"); err == nil {
+		t.Error("CreateAPIToken() with a duplicate hash succeeded")
+	}
+
+	stored, err := GetAPITokenByHash("hash")
+	if err != nil || stored == nil || stored.Name != "backup" || stored.ExpiresAt == nil || stored.LastUsedAt != nil {
+		t.Fatalf("GetAPITokenByHash() = %+v, %v", stored, err)
+	}
+	if missing, err := GetAPITokenByHash("other"); missing != nil || err != nil {
+		t.Errorf("GetAPITokenByHash(other) = %+v, %v", missing, err)
+	}
+
+	if err := TouchAPIToken(token.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, err := GetAPITokens(1); err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Errorf("GetAPITokens() = %+v, %v", tokens, err)
+	}
+
+	if revoked, err := RevokeAPIToken(token.ID, 2); revoked || err != nil {
+		t.Errorf("RevokeAPIToken() of another user = %v, %v", revoked, err)
+	}
+	if revoked, err := RevokeAPIToken(token.ID, 1); !revoked || err != nil {
+		t.Errorf("RevokeAPIToken() = %v, %v", revoked, err)
+	}
+	if revoked, err := RevokeAPIToken(token.ID, 1); revoked || err != nil {
+		t.Errorf("RevokeAPIToken() twice = %v, %v", revoked, err)
+	}
+	if tokens, err := GetAPITokens(1); err != nil || len(tokens) != 0 {
+		t.Errorf("GetAPITokens() after revoking = %+v, %v", tokens, err)
+	}
+	if stored, err := GetAPITokenByHash("hash"); err != nil || stored.RevokedAt == nil {
+		t.Errorf("revoked token = %+v, %v", stored, err)
+	}
+}
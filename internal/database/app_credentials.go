@@ -0,0 +1,80 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StoreAppCredentials saves the generated secrets of an app, replacing values of the same name
+func StoreAppCredentials(appName string, secrets map[string]string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for _, name := range names {
+		if _, err := tx.Exec(`
+			INSERT INTO app_credentials (app_name, name, value) VALUES (?, ?, ?)
+			ON CONFLICT(app_name, name) DO UPDATE SET value = excluded.value, created_at = CURRENT_TIMESTAMP
+		`, appName, name, secrets[name]); err != nil {
+			tx.Rollback() //nolint:errcheck,gosec // Already failing
+			return fmt.Errorf("failed to store credential %s: %w", name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+	return nil
+}
+
+// GetAppCredentials returns the generated secrets of an app ordered by name
+func GetAppCredentials(appName string) ([]AppCredential, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT app_name, name, value, created_at
+		FROM app_credentials
+		WHERE app_name = ?
+		ORDER BY name
+	`, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query credentials: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	credentials := []AppCredential{}
+	for rows.Next() {
+		var c AppCredential
+		if err := rows.Scan(&c.AppName, &c.Name, &c.Value, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan credential: %w", err)
+		}
+		credentials = append(credentials, c)
+	}
+	return credentials, rows.Err()
+}
+
+// DeleteAppCredentials removes the secrets of a deleted app
+func DeleteAppCredentials(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_credentials WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+	return nil
+}
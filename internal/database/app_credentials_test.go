@@ -0,0 +1,36 @@
+package database
+
+import "testing"
+
+func TestAppCredentials(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := StoreAppCredentials("immich", map[string]string{"DB_PASSWORD": "first", "API_KEY": "abc"}); err != nil {
+		t.Fatalf("StoreAppCredentials() error = %v", err)
+	}
+	if err := StoreAppCredentials("immich", map[string]string{"DB_PASSWORD": "second"}); err != nil {
+		t.Fatalf("StoreAppCredentials() error = %v", err)
+	}
+	if err := StoreAppCredentials("other", map[string]string{"TOKEN": "x"}); err != nil {
+		t.Fatalf("StoreAppCredentials() error = %v", err)
+	}
+
+	credentials, err := GetAppCredentials("immich")
+	if err != nil {
+		t.Fatalf("GetAppCredentials() error = %v", err)
+	}
+	if len(credentials) != 2 || credentials[0].Name != "API_KEY" || credentials[1].Value != "second" {
+		t.Errorf("GetAppCredentials() = %+v", credentials)
+	}
+
+	if err := DeleteAppCredentials("immich"); err != nil {
+		t.Fatalf("DeleteAppCredentials() error = %v", err)
+	}
+	if credentials, _ := GetAppCredentials("immich"); len(credentials) != 0 {
+		t.Errorf("credentials left after delete: %+v", credentials)
+	}
+	if credentials, _ := GetAppCredentials("other"); len(credentials) != 1 {
+		t.Errorf("credentials of another app deleted: %+v", credentials)
+	}
+}
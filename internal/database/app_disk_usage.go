@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Total sums the known parts of the usage
+func (u AppDiskUsage) Total() int64 {
+	return u.Images + u.Containers + u.Volumes + u.BindMounts + max(u.Logs, 0)
+}
+
+// ReplaceAppDiskUsage saves the disk usage of all apps measured at one point in time, in
+// place of the previous measurement
+func ReplaceAppDiskUsage(usages []AppDiskUsage) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	if _, err := tx.Exec(`DELETE FROM app_disk_usage`); err != nil {
+		return fmt.Errorf("failed to replace app disk usage: %w", err)
+	}
+	now := time.Now()
+	for _, u := range usages {
+		if _, err := tx.Exec(`
+			INSERT INTO app_disk_usage (app_name, images, containers, volumes, bind_mounts, logs, measured_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, u.AppName, u.Images, u.Containers, u.Volumes, u.BindMounts, u.Logs, now); err != nil {
+			return fmt.Errorf("failed to store app disk usage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store app disk usage: %w", err)
+	}
+	return nil
+}
+
+// GetAppDiskUsage returns the last measured disk usage of the apps, ordered by name
+func GetAppDiskUsage() ([]AppDiskUsage, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT app_name, images, containers, volumes, bind_mounts, logs, measured_at
+		FROM app_disk_usage
+		ORDER BY app_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app disk usage: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	usages := []AppDiskUsage{}
+	for rows.Next() {
+		var u AppDiskUsage
+		if err := rows.Scan(&u.AppName, &u.Images, &u.Containers, &u.Volumes, &u.BindMounts, &u.Logs, &u.MeasuredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan app disk usage: %w", err)
+		}
+		usages = append(usages, u)
+	}
+	return usages, rows.Err()
+}
+
+// DeleteAppDiskUsage removes the disk usage of an app
+func DeleteAppDiskUsage(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_disk_usage WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete app disk usage: %w", err)
+	}
+	return nil
+}
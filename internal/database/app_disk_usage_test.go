@@ -0,0 +1,40 @@
+package database
+
+import "testing"
+
+func TestAppDiskUsage(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := ReplaceAppDiskUsage([]AppDiskUsage{
+		{AppName: "wiki", Images: 600, Containers: 15, Volumes: 37, BindMounts: 4096, Logs: -1},
+		{AppName: "blog", Images: 200},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	usages, err := GetAppDiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usages) != 2 || usages[0].AppName != "blog" || usages[1].Volumes != 37 || usages[1].Logs != -1 || usages[1].MeasuredAt.IsZero() {
+		t.Fatalf("GetAppDiskUsage() = %+v", usages)
+	}
+
+	if total := usages[1].Total(); total != 4748 {
+		t.Errorf("Total() = %d, want 4748 without the unknown logs", total)
+	}
+
+	// A new measurement replaces the previous one, apps gone included
+	if err := ReplaceAppDiskUsage([]AppDiskUsage{{AppName: "wiki", Images: 700}}); err != nil {
+		t.Fatal(err)
+	}
+	if usages, err := GetAppDiskUsage(); err != nil || len(usages) != 1 || usages[0].Images != 700 {
+		t.Errorf("GetAppDiskUsage() after replace = %+v, %v", usages, err)
+	}
+	if err := DeleteAppDiskUsage("wiki"); err != nil {
+		t.Fatal(err)
+	}
+	if usages, err := GetAppDiskUsage(); err != nil || len(usages) != 0 {
+		t.Errorf("GetAppDiskUsage() after delete = %+v, %v", usages, err)
+	}
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetAppEnvSecret saves the encrypted value of an .env secret of an app, replacing a
+// previous value
+func SetAppEnvSecret(appName, name, sealedValue string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO app_env_secrets (app_name, name, sealed_value) VALUES (?, ?, ?)
+		ON CONFLICT(app_name, name) DO UPDATE SET sealed_value = excluded.sealed_value, updated_at = CURRENT_TIMESTAMP
+	`, appName, name, sealedValue); err != nil {
+		return fmt.Errorf("failed to store secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetAppEnvSecrets returns the encrypted .env secrets of an app by name
+func GetAppEnvSecrets(appName string) (map[string]string, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT name, sealed_value FROM app_env_secrets WHERE app_name = ?`, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query secrets: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	secrets := map[string]string{}
+	for rows.Next() {
+		var name, sealedValue string
+		if err := rows.Scan(&name, &sealedValue); err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+		secrets[name] = sealedValue
+	}
+	return secrets, rows.Err()
+}
+
+// GetAppEnvSecretUpdates returns when the .env secrets of an app were last set
+func GetAppEnvSecretUpdates(appName string) (map[string]time.Time, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT name, updated_at FROM app_env_secrets WHERE app_name = ?`, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query secrets: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	updates := map[string]time.Time{}
+	for rows.Next() {
+		var name string
+		var updatedAt time.Time
+		if err := rows.Scan(&name, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+		updates[name] = updatedAt
+	}
+	return updates, rows.Err()
+}
+
+// DeleteAppEnvSecret removes one .env secret of an app
+func DeleteAppEnvSecret(appName, name string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_env_secrets WHERE app_name = ? AND name = ?`, appName, name); err != nil {
+		return fmt.Errorf("failed to delete secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteAppEnvSecrets removes the .env secrets of a deleted app
+func DeleteAppEnvSecrets(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_env_secrets WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete secrets: %w", err)
+	}
+	return nil
+}
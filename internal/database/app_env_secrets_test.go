@@ -0,0 +1,40 @@
+package database
+
+import "testing"
+
+func TestAppEnvSecrets(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	for _, secret := range []struct{ app, name, value string }{
+		{"wiki", "DB_PASSWORD", "v1:first"},
+		{"wiki", "DB_PASSWORD", "v1:second"},
+		{"wiki", "API_TOKEN", "v1:abc"},
+		{"other", "DB_PASSWORD", "v1:x"},
+	} {
+		if err := SetAppEnvSecret(secret.app, secret.name, secret.value); err != nil {
+			t.Fatalf("SetAppEnvSecret() error = %v", err)
+		}
+	}
+
+	secrets, err := GetAppEnvSecrets("wiki")
+	if err != nil || len(secrets) != 2 || secrets["DB_PASSWORD"] != "v1:second" {
+		t.Errorf("GetAppEnvSecrets() = %v, %v", secrets, err)
+	}
+	if updates, err := GetAppEnvSecretUpdates("wiki"); err != nil || len(updates) != 2 || updates["API_TOKEN"].IsZero() {
+		t.Errorf("GetAppEnvSecretUpdates() = %v, %v", updates, err)
+	}
+
+	if err := DeleteAppEnvSecret("wiki", "API_TOKEN"); err != nil {
+		t.Fatalf("DeleteAppEnvSecret() error = %v", err)
+	}
+	if secrets, _ := GetAppEnvSecrets("wiki"); len(secrets) != 1 {
+		t.Errorf("secrets after deleting one: %v", secrets)
+	}
+	if err := DeleteAppEnvSecrets("wiki"); err != nil {
+		t.Fatalf("DeleteAppEnvSecrets() error = %v", err)
+	}
+	if secrets, _ := GetAppEnvSecrets("other"); len(secrets) != 1 {
+		t.Errorf("secrets of another app deleted: %v", secrets)
+	}
+}
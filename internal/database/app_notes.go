@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AppNote is the runbook of an app, notes people write on how to operate and fix it
+type AppNote struct {
+	AppName        string     `json:"app_name"`
+	Content        string     `json:"content"`
+	ShareWithAgent bool       `json:"share_with_agent"` // Send the notes along with the app's agent chats
+	UpdatedBy      string     `json:"updated_by,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// GetAppNote returns the runbook of an app, an empty one if none was written
+func GetAppNote(appName string) (AppNote, error) {
+	note := AppNote{AppName: appName}
+	db := GetDB()
+	if db == nil {
+		return note, fmt.Errorf("database not initialized")
+	}
+
+	var updatedAt time.Time
+	err := db.QueryRow(`
+		SELECT content, share_with_agent, updated_by, updated_at FROM app_notes WHERE app_name = ?
+	`, appName).Scan(&note.Content, &note.ShareWithAgent, &note.UpdatedBy, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return note, nil
+	}
+	if err != nil {
+		return note, fmt.Errorf("failed to query notes: %w", err)
+	}
+	note.UpdatedAt = &updatedAt
+	return note, nil
+}
+
+// SaveAppNote stores the runbook of an app, replacing the previous one
+func SaveAppNote(note *AppNote) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var updatedAt time.Time
+	err := db.QueryRow(`
+		INSERT INTO app_notes (app_name, content, share_with_agent, updated_by) VALUES (?, ?, ?, ?)
+		ON CONFLICT(app_name) DO UPDATE SET content = excluded.content, share_with_agent = excluded.share_with_agent,
+			updated_by = excluded.updated_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at
+	`, note.AppName, note.Content, note.ShareWithAgent, note.UpdatedBy).Scan(&updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notes: %w", err)
+	}
+	note.UpdatedAt = &updatedAt
+	return nil
+}
+
+// DeleteAppNote removes the runbook of a deleted app
+func DeleteAppNote(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_notes WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete notes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import "testing"
+
+func TestAppNotes(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	note, err := GetAppNote("immich")
+	if err != nil {
+		t.Fatalf("GetAppNote() error = %v", err)
+	}
+	if note.AppName != "immich" || note.Content != "" || note.ShareWithAgent || note.UpdatedAt != nil {
+		t.Errorf("GetAppNote() of an app without notes = %+v", note)
+	}
+
+	note = AppNote{AppName: "immich", Content: "Restart the ML container after upgrades", UpdatedBy: "admin"}
+	if err := SaveAppNote(&note); err != nil {
+		t.Fatalf("SaveAppNote() error = %v", err)
+	}
+	if note.UpdatedAt == nil {
+		t.Error("SaveAppNote() did not set updated_at")
+	}
+	note.ShareWithAgent = true
+	if err := SaveAppNote(&note); err != nil {
+		t.Fatalf("SaveAppNote() error = %v", err)
+	}
+
+	got, err := GetAppNote("immich")
+	if err != nil {
+		t.Fatalf("GetAppNote() error = %v", err)
+	}
+	if got.Content != note.Content || !got.ShareWithAgent || got.UpdatedBy != "admin" || got.UpdatedAt == nil {
+		t.Errorf("GetAppNote() = %+v", got)
+	}
+
+	if err := DeleteAppNote("immich"); err != nil {
+		t.Fatalf("DeleteAppNote() error = %v", err)
+	}
+	if got, _ := GetAppNote("immich"); got.Content != "" || got.UpdatedAt != nil {
+		t.Errorf("notes left after delete: %+v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+)
+
+// SetAppPermission grants a user view or control access to an app, replacing an existing grant
+func SetAppPermission(userID int, appName, access string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if access != AppAccessView && access != AppAccessControl {
+		return fmt.Errorf("invalid access level %q", access)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO app_permissions (user_id, app_name, access)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id, app_name) DO UPDATE SET access = excluded.access
+	`, userID, appName, access)
+	if err != nil {
+		return fmt.Errorf("failed to set app permission: %w", err)
+	}
+	return nil
+}
+
+// DeleteAppPermission removes a grant
+func DeleteAppPermission(id int) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_permissions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete app permission: %w", err)
+	}
+	return nil
+}
+
+// DeleteAppPermissions removes all grants of an app, when it is deleted
+func DeleteAppPermissions(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_permissions WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete app permissions: %w", err)
+	}
+	return nil
+}
+
+// GetAppPermissions returns all grants ordered by user and app
+func GetAppPermissions() ([]AppPermission, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT p.id, p.user_id, u.username, p.app_name, p.access, p.created_at
+		FROM app_permissions p
+		JOIN users u ON u.id = p.user_id
+		ORDER BY u.username, p.app_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app permissions: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	permissions := []AppPermission{}
+	for rows.Next() {
+		var p AppPermission
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Username, &p.AppName, &p.Access, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan app permission: %w", err)
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// GetAppPermissionsForUser returns the access level per app name for a user
+func GetAppPermissionsForUser(userID int) (map[string]string, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT app_name, access FROM app_permissions WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app permissions: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	access := make(map[string]string)
+	for rows.Next() {
+		var appName, level string
+		if err := rows.Scan(&appName, &level); err != nil {
+			return nil, fmt.Errorf("failed to scan app permission: %w", err)
+		}
+		access[appName] = level
+	}
+	return access, rows.Err()
+}
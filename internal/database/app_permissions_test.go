@@ -0,0 +1,44 @@
+package database
+
+import "testing"
+
+func TestAppPermissions(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := SetAppPermission(1, "blog", "admin"); err == nil {
+		t.Error("SetAppPermission() with an invalid level succeeded")
+	}
+	if err := SetAppPermission(1, "blog", AppAccessView); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAppPermission(1, "wiki", AppAccessView); err != nil {
+		t.Fatal(err)
+	}
+	// A second grant for the same app replaces the first
+	if err := SetAppPermission(1, "blog", AppAccessControl); err != nil {
+		t.Fatal(err)
+	}
+
+	access, err := GetAppPermissionsForUser(1)
+	if err != nil || len(access) != 2 || access["blog"] != AppAccessControl || access["wiki"] != AppAccessView {
+		t.Errorf("GetAppPermissionsForUser() = %v, %v", access, err)
+	}
+	permissions, err := GetAppPermissions()
+	if err != nil || len(permissions) != 2 || permissions[0].Username != "admin" || permissions[0].AppName != "blog" {
+		t.Fatalf("GetAppPermissions() = %+v, %v", permissions, err)
+	}
+
+	if err := DeleteAppPermission(permissions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if access, err := GetAppPermissionsForUser(1); err != nil || len(access) != 1 {
+		t.Errorf("GetAppPermissionsForUser() after deleting = %v, %v", access, err)
+	}
+	if err := DeleteAppPermissions("wiki"); err != nil {
+		t.Fatal(err)
+	}
+	if permissions, err := GetAppPermissions(); err != nil || len(permissions) != 0 {
+		t.Errorf("GetAppPermissions() after deleting the app = %+v, %v", permissions, err)
+	}
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StartAppRebuild records a rebuild of an app that is starting and sets its ID
+func StartAppRebuild(rebuild *AppRebuild) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	rebuild.Status = RebuildStatusRunning
+	rebuild.StartedAt = time.Now()
+	result, err := db.Exec(`
+		INSERT INTO app_rebuilds (app_name, trigger, status, requested_by, started_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, rebuild.AppName, rebuild.Trigger, rebuild.Status, rebuild.RequestedBy, rebuild.StartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record app rebuild: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get app rebuild ID: %w", err)
+	}
+	rebuild.ID = int(id)
+	return nil
+}
+
+// FinishAppRebuild records the outcome of a rebuild
+func FinishAppRebuild(id int, status, message string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE app_rebuilds SET status = ?, message = ?, finished_at = ? WHERE id = ?`,
+		status, message, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update app rebuild: %w", err)
+	}
+	return nil
+}
+
+// GetAppRebuilds returns the most recent rebuilds of an app, newest first
+func GetAppRebuilds(appName string, limit int) ([]AppRebuild, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, app_name, trigger, status, message, requested_by, started_at, finished_at
+		FROM app_rebuilds
+		WHERE app_name = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, appName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app rebuilds: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	rebuilds := []AppRebuild{}
+	for rows.Next() {
+		var r AppRebuild
+		var message, requestedBy sql.NullString
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&r.ID, &r.AppName, &r.Trigger, &r.Status, &message, &requestedBy, &r.StartedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan app rebuild: %w", err)
+		}
+		r.Message = message.String
+		r.RequestedBy = requestedBy.String
+		if finishedAt.Valid {
+			r.FinishedAt = &finishedAt.Time
+		}
+		rebuilds = append(rebuilds, r)
+	}
+	return rebuilds, rows.Err()
+}
+
+// GetLatestAppRebuild returns the most recent rebuild of an app, nil if it was never rebuilt
+func GetLatestAppRebuild(appName string) (*AppRebuild, error) {
+	rebuilds, err := GetAppRebuilds(appName, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(rebuilds) == 0 {
+		return nil, nil
+	}
+	return &rebuilds[0], nil
+}
+
+// ResetInterruptedAppRebuilds marks rebuilds that were cut off by a restart as failed
+func ResetInterruptedAppRebuilds() error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE app_rebuilds SET status = ?, message = ?, finished_at = ? WHERE status = ?`,
+		RebuildStatusFailed, "Interrupted by a restart of TreeOS", time.Now(), RebuildStatusRunning); err != nil {
+		return fmt.Errorf("failed to reset app rebuilds: %w", err)
+	}
+	return nil
+}
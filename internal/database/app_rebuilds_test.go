@@ -0,0 +1,45 @@
+package database
+
+import "testing"
+
+func TestAppRebuilds(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if latest, err := GetLatestAppRebuild("web"); err != nil || latest != nil {
+		t.Fatalf("GetLatestAppRebuild() before any rebuild = %v, %v", latest, err)
+	}
+
+	first := AppRebuild{AppName: "web", Trigger: "schedule"}
+	if err := StartAppRebuild(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := FinishAppRebuild(first.ID, RebuildStatusRolledBack, "container web is unhealthy"); err != nil {
+		t.Fatal(err)
+	}
+	second := AppRebuild{AppName: "web", Trigger: "manual", RequestedBy: "admin"}
+	if err := StartAppRebuild(&second); err != nil {
+		t.Fatal(err)
+	}
+	other := AppRebuild{AppName: "other", Trigger: "manual"}
+	if err := StartAppRebuild(&other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResetInterruptedAppRebuilds(); err != nil {
+		t.Fatal(err)
+	}
+	rebuilds, err := GetAppRebuilds("web", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebuilds) != 2 || rebuilds[0].ID != second.ID {
+		t.Fatalf("GetAppRebuilds() = %+v", rebuilds)
+	}
+	if rebuilds[0].Status != RebuildStatusFailed || rebuilds[0].FinishedAt == nil {
+		t.Errorf("interrupted rebuild = %+v", rebuilds[0])
+	}
+	if rebuilds[1].Status != RebuildStatusRolledBack || rebuilds[1].Message != "container web is unhealthy" {
+		t.Errorf("finished rebuild = %+v", rebuilds[1])
+	}
+}
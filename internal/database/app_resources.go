@@ -0,0 +1,93 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// StoreAppResourceLogs saves one sample of the resource usage of app services, all with
+// the same timestamp
+func StoreAppResourceLogs(logs []AppResourceLog) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	now := time.Now()
+	for _, l := range logs {
+		if _, err := tx.Exec(`
+			INSERT INTO app_resource_logs (timestamp, app_name, service, cpu_percent, memory_bytes, memory_limit, net_rx_rate, net_tx_rate)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, now, l.AppName, l.Service, l.CPUPercent, l.MemoryBytes, l.MemoryLimit, l.NetRxRate, l.NetTxRate); err != nil {
+			return fmt.Errorf("failed to store app resource usage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store app resource usage: %w", err)
+	}
+	return nil
+}
+
+// GetAppResourceLogs returns the resource usage samples of an app's services since a
+// point in time, oldest first
+func GetAppResourceLogs(appName string, since time.Time) ([]AppResourceLog, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, timestamp, app_name, service, cpu_percent, memory_bytes,
+		       COALESCE(memory_limit, 0), COALESCE(net_rx_rate, 0), COALESCE(net_tx_rate, 0)
+		FROM app_resource_logs
+		WHERE app_name = ? AND timestamp >= ?
+		ORDER BY timestamp ASC, service
+	`, appName, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app resource usage: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	logs := []AppResourceLog{}
+	for rows.Next() {
+		var l AppResourceLog
+		if err := rows.Scan(&l.ID, &l.Timestamp, &l.AppName, &l.Service, &l.CPUPercent, &l.MemoryBytes,
+			&l.MemoryLimit, &l.NetRxRate, &l.NetTxRate); err != nil {
+			return nil, fmt.Errorf("failed to scan app resource usage: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// CleanupOldAppResourceLogs removes resource usage samples older than the specified duration
+func CleanupOldAppResourceLogs(olderThan time.Duration) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_resource_logs WHERE timestamp < ?`, time.Now().Add(-olderThan)); err != nil {
+		return fmt.Errorf("failed to cleanup old app resource usage: %w", err)
+	}
+	return nil
+}
+
+// DeleteAppResourceLogs removes the resource usage samples of an app
+func DeleteAppResourceLogs(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_resource_logs WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete app resource usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppResourceLogs(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := StoreAppResourceLogs([]AppResourceLog{
+		{AppName: "blog", Service: "web", CPUPercent: 12.5, MemoryBytes: 64 << 20, NetRxRate: 2048, NetTxRate: 512},
+		{AppName: "blog", Service: "db", CPUPercent: 3, MemoryBytes: 256 << 20, MemoryLimit: 1 << 30},
+		{AppName: "wiki", Service: "web", CPUPercent: 1, MemoryBytes: 32 << 20},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := GetAppResourceLogs("blog", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].Service != "db" || logs[0].MemoryLimit != 1<<30 || logs[1].NetRxRate != 2048 || logs[1].Timestamp.IsZero() {
+		t.Fatalf("GetAppResourceLogs() = %+v", logs)
+	}
+	if logs, err := GetAppResourceLogs("blog", time.Now().Add(time.Minute)); err != nil || len(logs) != 0 {
+		t.Errorf("GetAppResourceLogs() in the future = %+v, %v", logs, err)
+	}
+
+	if err := DeleteAppResourceLogs("blog"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CleanupOldAppResourceLogs(-time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	for _, app := range []string{"blog", "wiki"} {
+		if logs, err := GetAppResourceLogs(app, time.Time{}); err != nil || len(logs) != 0 {
+			t.Errorf("GetAppResourceLogs(%q) after cleanup = %+v, %v", app, logs, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetAppSecret saves the material of a compose secret of an app, replacing a previous value
+func SetAppSecret(appName, name, value string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO app_secrets (app_name, name, value) VALUES (?, ?, ?)
+		ON CONFLICT(app_name, name) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
+	`, appName, name, value); err != nil {
+		return fmt.Errorf("failed to store secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetAppSecrets returns the material of an app's compose secrets by secret name
+func GetAppSecrets(appName string) (map[string]string, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT name, value FROM app_secrets WHERE app_name = ?`, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query secrets: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	secrets := map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+		secrets[name] = value
+	}
+	return secrets, rows.Err()
+}
+
+// GetAppSecretUpdates returns when the compose secrets of an app were last set, without
+// their material
+func GetAppSecretUpdates(appName string) (map[string]time.Time, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT name, updated_at FROM app_secrets WHERE app_name = ?`, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query secrets: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	updates := map[string]time.Time{}
+	for rows.Next() {
+		var name string
+		var updatedAt time.Time
+		if err := rows.Scan(&name, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+		updates[name] = updatedAt
+	}
+	return updates, rows.Err()
+}
+
+// DeleteAppSecret removes the material of one compose secret of an app
+func DeleteAppSecret(appName, name string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_secrets WHERE app_name = ? AND name = ?`, appName, name); err != nil {
+		return fmt.Errorf("failed to delete secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteAppSecrets removes the compose secrets of a deleted app
+func DeleteAppSecrets(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM app_secrets WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete secrets: %w", err)
+	}
+	return nil
+}
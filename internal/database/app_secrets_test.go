@@ -0,0 +1,46 @@
+package database
+
+import "testing"
+
+func TestAppSecrets(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	for _, secret := range []struct{ app, name, value string }{
+		{"immich", "db_password", "first"},
+		{"immich", "db_password", "second"},
+		{"immich", "api_key", "abc"},
+		{"other", "db_password", "x"},
+	} {
+		if err := SetAppSecret(secret.app, secret.name, secret.value); err != nil {
+			t.Fatalf("SetAppSecret() error = %v", err)
+		}
+	}
+
+	secrets, err := GetAppSecrets("immich")
+	if err != nil {
+		t.Fatalf("GetAppSecrets() error = %v", err)
+	}
+	if len(secrets) != 2 || secrets["db_password"] != "second" || secrets["api_key"] != "abc" {
+		t.Errorf("GetAppSecrets() = %v", secrets)
+	}
+	if updates, err := GetAppSecretUpdates("immich"); err != nil || len(updates) != 2 || updates["api_key"].IsZero() {
+		t.Errorf("GetAppSecretUpdates() = %v, %v", updates, err)
+	}
+
+	if err := DeleteAppSecret("immich", "api_key"); err != nil {
+		t.Fatalf("DeleteAppSecret() error = %v", err)
+	}
+	if secrets, _ := GetAppSecrets("immich"); len(secrets) != 1 {
+		t.Errorf("secrets after deleting one: %v", secrets)
+	}
+	if err := DeleteAppSecrets("immich"); err != nil {
+		t.Fatalf("DeleteAppSecrets() error = %v", err)
+	}
+	if secrets, _ := GetAppSecrets("immich"); len(secrets) != 0 {
+		t.Errorf("secrets left after delete: %v", secrets)
+	}
+	if secrets, _ := GetAppSecrets("other"); len(secrets) != 1 {
+		t.Errorf("secrets of another app deleted: %v", secrets)
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StartAppUpgrade records an upgrade that is starting with the snapshot it rolls back to,
+// and sets its ID
+func StartAppUpgrade(upgrade *AppUpgrade) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	previous, err := json.Marshal(upgrade.PreviousImages)
+	if err != nil {
+		return fmt.Errorf("failed to encode previous images: %w", err)
+	}
+	upgrade.Status = UpgradeStatusRunning
+	upgrade.StartedAt = time.Now()
+	result, err := db.Exec(`
+		INSERT INTO app_upgrades (app_name, status, previous_images, compose_snapshot, env_snapshot, requested_by, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, upgrade.AppName, upgrade.Status, string(previous), upgrade.ComposeSnapshot, upgrade.EnvSnapshot, upgrade.RequestedBy, upgrade.StartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record app upgrade: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get app upgrade ID: %w", err)
+	}
+	upgrade.ID = int(id)
+	return nil
+}
+
+// FinishAppUpgrade records the outcome of an upgrade
+func FinishAppUpgrade(id int, status, message string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE app_upgrades SET status = ?, message = ?, finished_at = ? WHERE id = ?`,
+		status, message, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update app upgrade: %w", err)
+	}
+	return nil
+}
+
+// GetAppUpgrades returns the most recent upgrades of an app, newest first, without their
+// compose and .env snapshots
+func GetAppUpgrades(appName string, limit int) ([]AppUpgrade, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, app_name, status, message, previous_images, requested_by, started_at, finished_at
+		FROM app_upgrades
+		WHERE app_name = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, appName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app upgrades: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	upgrades := []AppUpgrade{}
+	for rows.Next() {
+		var u AppUpgrade
+		var message, previous, requestedBy sql.NullString
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&u.ID, &u.AppName, &u.Status, &message, &previous, &requestedBy, &u.StartedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan app upgrade: %w", err)
+		}
+		u.Message = message.String
+		u.RequestedBy = requestedBy.String
+		if previous.String != "" {
+			if err := json.Unmarshal([]byte(previous.String), &u.PreviousImages); err != nil {
+				return nil, fmt.Errorf("failed to decode previous images: %w", err)
+			}
+		}
+		if finishedAt.Valid {
+			u.FinishedAt = &finishedAt.Time
+		}
+		upgrades = append(upgrades, u)
+	}
+	return upgrades, rows.Err()
+}
+
+// ResetInterruptedAppUpgrades marks upgrades that were cut off by a restart as failed.
+// Their snapshots stay in the database, so the app can be restored by hand.
+func ResetInterruptedAppUpgrades() error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE app_upgrades SET status = ?, message = ?, finished_at = ? WHERE status = ?`,
+		UpgradeStatusFailed, "Interrupted by a restart of TreeOS", time.Now(), UpgradeStatusRunning); err != nil {
+		return fmt.Errorf("failed to reset app upgrades: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import "testing"
+
+func TestAppUpgrades(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	first := AppUpgrade{
+		AppName:         "blog",
+		PreviousImages:  map[string]string{"postgres:16": "sha256:old"},
+		ComposeSnapshot: "services: {}\n",
+		RequestedBy:     "admin",
+	}
+	if err := StartAppUpgrade(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := FinishAppUpgrade(first.ID, UpgradeStatusRolledBack, "container db is unhealthy"); err != nil {
+		t.Fatal(err)
+	}
+	second := AppUpgrade{AppName: "blog"}
+	if err := StartAppUpgrade(&second); err != nil {
+		t.Fatal(err)
+	}
+	other := AppUpgrade{AppName: "wiki"}
+	if err := StartAppUpgrade(&other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResetInterruptedAppUpgrades(); err != nil {
+		t.Fatal(err)
+	}
+	upgrades, err := GetAppUpgrades("blog", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upgrades) != 2 || upgrades[0].ID != second.ID || upgrades[0].Status != UpgradeStatusFailed || upgrades[0].FinishedAt == nil {
+		t.Fatalf("GetAppUpgrades() = %+v", upgrades)
+	}
+	if u := upgrades[1]; u.Status != UpgradeStatusRolledBack || u.PreviousImages["postgres:16"] != "sha256:old" || u.RequestedBy != "admin" {
+		t.Errorf("rolled back upgrade = %+v", u)
+	}
+}
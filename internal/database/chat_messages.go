@@ -0,0 +1,83 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// AddChatMessage stores a chat message and returns its ID
+func AddChatMessage(message ChatMessage) (int, error) {
+	db := GetDB()
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO chat_messages (app_id, message, sender_type, sender_name, agent_model, agent_provider, status_level, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, message.AppID, message.Message, message.SenderType, message.SenderName,
+		nullString(message.AgentModel), nullString(message.AgentProvider),
+		nullString(message.StatusLevel), nullString(message.Details))
+	if err != nil {
+		return 0, fmt.Errorf("failed to add chat message: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chat message ID: %w", err)
+	}
+	return int(id), nil
+}
+
+// GetChatMessages returns the most recent chat messages of an app, oldest first
+func GetChatMessages(appID string, limit int) ([]ChatMessage, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, app_id, message, sender_type, sender_name, COALESCE(agent_model, ''),
+		       COALESCE(agent_provider, ''), COALESCE(status_level, ''), COALESCE(details, ''), timestamp
+		FROM (
+			SELECT * FROM chat_messages WHERE app_id = ? ORDER BY timestamp DESC, id DESC LIMIT ?
+		)
+		ORDER BY timestamp ASC, id ASC
+	`, appID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat messages: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	messages := []ChatMessage{}
+	for rows.Next() {
+		var m ChatMessage
+		var timestamp sql.NullTime
+		if err := rows.Scan(&m.ID, &m.AppID, &m.Message, &m.SenderType, &m.SenderName, &m.AgentModel,
+			&m.AgentProvider, &m.StatusLevel, &m.Details, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		if timestamp.Valid {
+			m.Timestamp = timestamp.Time
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// DeleteChatMessages removes the chat history of an app
+func DeleteChatMessages(appID string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM chat_messages WHERE app_id = ?`, appID); err != nil {
+		return fmt.Errorf("failed to delete chat messages: %w", err)
+	}
+	return nil
+}
+
+// nullString stores empty strings as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateClientCertificate records a client certificate issued to a user
+func CreateClientCertificate(cert ClientCertificate) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO client_certificates (serial, user_id, name, not_after) VALUES (?, ?, ?, ?)
+	`, cert.Serial, cert.UserID, cert.Name, cert.NotAfter.UTC()); err != nil {
+		return fmt.Errorf("failed to create client certificate: %w", err)
+	}
+	return nil
+}
+
+// GetClientCertificates returns the client certificates of a user, newest first
+func GetClientCertificates(userID int) ([]ClientCertificate, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT serial, user_id, name, not_after, created_at, revoked_at
+		FROM client_certificates
+		WHERE user_id = ?
+		ORDER BY created_at DESC, rowid DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query client certificates: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	certs := []ClientCertificate{}
+	for rows.Next() {
+		var c ClientCertificate
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&c.Serial, &c.UserID, &c.Name, &c.NotAfter, &c.CreatedAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan client certificate: %w", err)
+		}
+		if revokedAt.Valid {
+			c.RevokedAt = &revokedAt.Time
+		}
+		certs = append(certs, c)
+	}
+	return certs, rows.Err()
+}
+
+// HasActiveClientCertificate reports whether a user has a client certificate that is
+// neither revoked nor expired
+func HasActiveClientCertificate(userID int, now time.Time) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	var count int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM client_certificates
+		WHERE user_id = ? AND revoked_at IS NULL AND not_after > ?
+	`, userID, now.UTC()).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to count client certificates: %w", err)
+	}
+	return count > 0, nil
+}
+
+// IsClientCertificateActive reports whether a certificate was issued to a user and is not
+// revoked. The TLS handshake already checked its expiry.
+func IsClientCertificateActive(serial string, userID int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	var count int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM client_certificates
+		WHERE serial = ? AND user_id = ? AND revoked_at IS NULL
+	`, serial, userID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up client certificate: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RevokeClientCertificate revokes a certificate of a user. It returns false if the user
+// has no such certificate or it is already revoked.
+func RevokeClientCertificate(serial string, userID int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		UPDATE client_certificates SET revoked_at = ?
+		WHERE serial = ? AND user_id = ? AND revoked_at IS NULL
+	`, time.Now(), serial, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke client certificate: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke client certificate: %w", err)
+	}
+	return affected == 1, nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientCertificates(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	now := time.Now()
+	if ok, err := HasActiveClientCertificate(1, now); err != nil || ok {
+		t.Fatalf("HasActiveClientCertificate() = %v, %v before any was issued", ok, err)
+	}
+	for _, c := range []ClientCertificate{
+		{Serial: "0a", UserID: 1, Name: "Laptop", NotAfter: now.Add(time.Hour)},
+		{Serial: "0b", UserID: 1, Name: "Old phone", NotAfter: now.Add(-time.Hour)},
+	} {
+		if err := CreateClientCertificate(c); err != nil {
+			t.Fatalf("CreateClientCertificate() error = %v", err)
+		}
+	}
+
+	if ok, err := HasActiveClientCertificate(1, now); err != nil || !ok {
+		t.Errorf("HasActiveClientCertificate() = %v, %v", ok, err)
+	}
+	if ok, _ := HasActiveClientCertificate(1, now.Add(2*time.Hour)); ok {
+		t.Error("expired certificates count as active")
+	}
+	if ok, _ := IsClientCertificateActive("0a", 2); ok {
+		t.Error("certificate is active for another user")
+	}
+
+	if revoked, err := RevokeClientCertificate("0a", 1); err != nil || !revoked {
+		t.Fatalf("RevokeClientCertificate() = %v, %v", revoked, err)
+	}
+	if revoked, _ := RevokeClientCertificate("0a", 1); revoked {
+		t.Error("revoked a certificate twice")
+	}
+	if ok, _ := IsClientCertificateActive("0a", 1); ok {
+		t.Error("revoked certificate is active")
+	}
+	if ok, _ := HasActiveClientCertificate(1, now); ok {
+		t.Error("user still has an active certificate after revoking it")
+	}
+
+	certs, err := GetClientCertificates(1)
+	if err != nil || len(certs) != 2 {
+		t.Fatalf("GetClientCertificates() = %+v, %v", certs, err)
+	}
+	for _, c := range certs {
+		if (c.Serial == "0a") != (c.RevokedAt != nil) {
+			t.Errorf("certificate %s revoked at %v", c.Serial, c.RevokedAt)
+		}
+	}
+}
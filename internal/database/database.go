@@ -30,7 +30,7 @@ func Initialize(dbPath string) error {
 	// Add retry logic for database initialization after updates
 	var retryCount = 3
 	for i := 0; i < retryCount; i++ {
-		db, err = sql.Open("sqlite3", dbPath)
+		db, err = sql.Open(driverName, dbPath)
 		if err != nil {
 			if i < retryCount-1 {
 				logging.Errorf("Attempt %d: Failed to open database, retrying in 1 second: %v", i+1, err)
@@ -118,7 +118,10 @@ func createTables() error {
 			is_superuser INTEGER DEFAULT 0,
 			is_active INTEGER DEFAULT 1,
 			date_joined DATETIME DEFAULT CURRENT_TIMESTAMP,
-			last_login DATETIME
+			last_login DATETIME,
+			auth_source TEXT DEFAULT 'local',
+			role TEXT DEFAULT 'operator',
+			session_version INTEGER DEFAULT 0
 		)`,
 		`CREATE TABLE IF NOT EXISTS system_setup (
 			id INTEGER PRIMARY KEY CHECK (id = 1),
@@ -218,6 +221,296 @@ func createTables() error {
 			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_update_history_started_at ON update_history(started_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS login_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			ip_address TEXT NOT NULL,
+			user_agent TEXT,
+			location TEXT,
+			is_new_device INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_events_user_created ON login_events(user_id, created_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			id_hash TEXT UNIQUE NOT NULL,
+			previous_hash TEXT,
+			created_at DATETIME NOT NULL,
+			rotated_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_previous_hash ON sessions(previous_hash)`,
+		`CREATE TABLE IF NOT EXISTS host_reboots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			status TEXT NOT NULL,
+			reason TEXT,
+			requested_by TEXT,
+			scheduled_for DATETIME NOT NULL,
+			apps TEXT DEFAULT '[]',
+			message TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS image_prefetches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			image TEXT UNIQUE NOT NULL,
+			source TEXT,
+			pull_window TEXT,
+			status TEXT NOT NULL,
+			message TEXT,
+			requested_by TEXT,
+			pulled_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS image_updates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			app_name TEXT NOT NULL,
+			image TEXT NOT NULL,
+			current_digest TEXT,
+			latest_digest TEXT,
+			newer_tag TEXT,
+			update_available BOOLEAN DEFAULT FALSE,
+			error TEXT,
+			checked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(app_name, image)
+		)`,
+		`CREATE TABLE IF NOT EXISTS app_rebuilds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			app_name TEXT NOT NULL,
+			trigger TEXT NOT NULL,
+			status TEXT NOT NULL,
+			message TEXT,
+			requested_by TEXT,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_app_rebuilds_app_started ON app_rebuilds(app_name, started_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS app_upgrades (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			app_name TEXT NOT NULL,
+			status TEXT NOT NULL,
+			message TEXT,
+			previous_images TEXT,
+			compose_snapshot TEXT,
+			env_snapshot TEXT,
+			requested_by TEXT,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_app_upgrades_app_started ON app_upgrades(app_name, started_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS app_resource_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			app_name TEXT NOT NULL,
+			service TEXT NOT NULL,
+			cpu_percent REAL NOT NULL,
+			memory_bytes INTEGER NOT NULL,
+			memory_limit INTEGER DEFAULT 0,
+			net_rx_rate INTEGER DEFAULT 0,
+			net_tx_rate INTEGER DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_app_resource_logs_app_timestamp ON app_resource_logs(app_name, timestamp)`,
+		`CREATE INDEX IF NOT EXISTS idx_app_resource_logs_timestamp ON app_resource_logs(timestamp)`,
+		`CREATE TABLE IF NOT EXISTS app_disk_usage (
+			app_name TEXT PRIMARY KEY,
+			images INTEGER NOT NULL DEFAULT 0,
+			containers INTEGER NOT NULL DEFAULT 0,
+			volumes INTEGER NOT NULL DEFAULT 0,
+			bind_mounts INTEGER NOT NULL DEFAULT 0,
+			logs INTEGER NOT NULL DEFAULT 0,
+			measured_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			last_sent_at DATETIME,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS template_catalogs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			url TEXT NOT NULL,
+			public_key TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			templates INTEGER NOT NULL DEFAULT 0,
+			last_synced_at DATETIME,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS file_access_grants (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			app_name TEXT NOT NULL,
+			access TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, app_name),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS app_permissions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			app_name TEXT NOT NULL,
+			access TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, app_name),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS shares (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			app_name TEXT NOT NULL,
+			sub_path TEXT NOT NULL DEFAULT '',
+			comment TEXT NOT NULL DEFAULT '',
+			read_only BOOLEAN NOT NULL DEFAULT 0,
+			valid_users TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS app_credentials (
+			app_name TEXT NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (app_name, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS app_secrets (
+			app_name TEXT NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (app_name, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS app_env_secrets (
+			app_name TEXT NOT NULL,
+			name TEXT NOT NULL,
+			sealed_value TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (app_name, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS log_filters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			app_name TEXT NOT NULL,
+			name TEXT NOT NULL,
+			service TEXT NOT NULL DEFAULT '',
+			level TEXT NOT NULL DEFAULT '',
+			query TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (app_name, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS app_notes (
+			app_name TEXT PRIMARY KEY,
+			content TEXT NOT NULL DEFAULT '',
+			share_with_agent INTEGER NOT NULL DEFAULT 0,
+			updated_by TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS namespaces (
+			name TEXT PRIMARY KEY,
+			description TEXT NOT NULL DEFAULT '',
+			subdomain_prefix TEXT NOT NULL DEFAULT '',
+			port_min INTEGER NOT NULL DEFAULT 0,
+			port_max INTEGER NOT NULL DEFAULT 0,
+			max_apps INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS namespace_members (
+			namespace TEXT NOT NULL,
+			user_id INTEGER NOT NULL,
+			PRIMARY KEY (namespace, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS security_audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			app_name TEXT NOT NULL,
+			username TEXT NOT NULL,
+			action TEXT NOT NULL,
+			justification TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_security_audit_log_app ON security_audit_log(app_name, created_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS agent_reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			status TEXT NOT NULL,
+			summary TEXT NOT NULL DEFAULT '',
+			findings TEXT NOT NULL DEFAULT '[]',
+			model TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			completed_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS agent_actions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			review_id INTEGER,
+			app_name TEXT NOT NULL,
+			action TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			result TEXT NOT NULL DEFAULT '',
+			decided_by TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			decided_at DATETIME,
+			FOREIGN KEY (review_id) REFERENCES agent_reviews(id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_actions_status ON agent_actions(status, created_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS client_certificates (
+			serial TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			not_after DATETIME NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			revoked_at DATETIME,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS invites (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			token_hash TEXT UNIQUE NOT NULL,
+			role TEXT NOT NULL,
+			namespace TEXT NOT NULL DEFAULT '',
+			max_uses INTEGER NOT NULL,
+			uses INTEGER NOT NULL DEFAULT 0,
+			expires_at DATETIME NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			revoked_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS invite_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			invite_id INTEGER,
+			action TEXT NOT NULL,
+			username TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			prefix TEXT NOT NULL,
+			scope TEXT NOT NULL,
+			expires_at DATETIME,
+			last_used_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			revoked_at DATETIME,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS activity_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			category TEXT NOT NULL,
+			title TEXT NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			app_name TEXT NOT NULL DEFAULT '',
+			actor TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_activity_events_created ON activity_events(created_at DESC)`,
 	}
 
 	for _, query := range queries {
@@ -261,6 +554,22 @@ func migrateColumnsIfNeeded() error {
 		{"system_vital_logs", "download_rate", `ALTER TABLE system_vital_logs ADD COLUMN download_rate INTEGER DEFAULT 0`},
 		{"system_vital_logs", "gpu_load", `ALTER TABLE system_vital_logs ADD COLUMN gpu_load REAL DEFAULT 0`},
 		{"system_setup", "node_icon", `ALTER TABLE system_setup ADD COLUMN node_icon TEXT DEFAULT 'tree1.png'`},
+		{"system_setup", "agent_review_interval", `ALTER TABLE system_setup ADD COLUMN agent_review_interval TEXT DEFAULT '24h'`},
+		{"users", "auth_source", `ALTER TABLE users ADD COLUMN auth_source TEXT DEFAULT 'local'`},
+		{"users", "role", `ALTER TABLE users ADD COLUMN role TEXT DEFAULT 'operator'`},
+		{"users", "session_version", `ALTER TABLE users ADD COLUMN session_version INTEGER DEFAULT 0`},
+		{"system_setup", "timezone", `ALTER TABLE system_setup ADD COLUMN timezone TEXT DEFAULT ''`},
+		{"system_setup", "node_id", `ALTER TABLE system_setup ADD COLUMN node_id TEXT DEFAULT ''`},
+		{"system_setup", "smtp_host", `ALTER TABLE system_setup ADD COLUMN smtp_host TEXT DEFAULT ''`},
+		{"system_setup", "smtp_port", `ALTER TABLE system_setup ADD COLUMN smtp_port INTEGER DEFAULT 0`},
+		{"system_setup", "smtp_security", `ALTER TABLE system_setup ADD COLUMN smtp_security TEXT DEFAULT ''`},
+		{"system_setup", "smtp_username", `ALTER TABLE system_setup ADD COLUMN smtp_username TEXT DEFAULT ''`},
+		{"system_setup", "smtp_password", `ALTER TABLE system_setup ADD COLUMN smtp_password TEXT DEFAULT ''`},
+		{"system_setup", "smtp_from", `ALTER TABLE system_setup ADD COLUMN smtp_from TEXT DEFAULT ''`},
+		{"system_setup", "email_events", `ALTER TABLE system_setup ADD COLUMN email_events TEXT DEFAULT '` + DefaultEmailEvents + `'`},
+		{"system_setup", "container_runtime", `ALTER TABLE system_setup ADD COLUMN container_runtime TEXT DEFAULT ''`},
+		{"system_setup", "prune_schedule", `ALTER TABLE system_setup ADD COLUMN prune_schedule TEXT DEFAULT ''`},
+		{"system_setup", "prune_last_run", `ALTER TABLE system_setup ADD COLUMN prune_last_run DATETIME`},
 	}
 
 	for _, m := range migrations {
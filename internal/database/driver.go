@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/ontree-co/treeos/internal/metrics"
+)
+
+// driverName is the SQLite driver with statement timing, registered on top of go-sqlite3
+const driverName = "sqlite3-metrics"
+
+func init() {
+	sql.Register(driverName, metricsDriver{&sqlite3.SQLiteDriver{}})
+}
+
+// metricsDriver wraps the SQLite driver to record statement durations in
+// metrics.DBQueryDuration
+type metricsDriver struct {
+	driver.Driver
+}
+
+func (d metricsDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+func observeStatement(start time.Time, operation string, err error) {
+	metrics.DBQueryDuration.ObserveSince(start, operation, metrics.Result(err))
+}
+
+type metricsConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *metricsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	observeStatement(start, "exec", err)
+	return result, err
+}
+
+func (c *metricsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.SQLiteConn.QueryContext(ctx, query, args)
+	observeStatement(start, "query", err)
+	return rows, err
+}
+
+func (c *metricsConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *metricsConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsStmt{stmt.(*sqlite3.SQLiteStmt)}, nil
+}
+
+type metricsStmt struct {
+	*sqlite3.SQLiteStmt
+}
+
+func (s *metricsStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := s.SQLiteStmt.ExecContext(ctx, args)
+	observeStatement(start, "exec", err)
+	return result, err
+}
+
+func (s *metricsStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.SQLiteStmt.QueryContext(ctx, args)
+	observeStatement(start, "query", err)
+	return rows, err
+}
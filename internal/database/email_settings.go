@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultEmailEvents are the events emailed to admins until they choose others, the
+// ones that need someone to act
+const DefaultEmailEvents = "container.crashed,container.unhealthy,update.failed,disk.full,backup.failed"
+
+// GetEmailSettings returns the email settings, the defaults if the node isn't set up yet
+func GetEmailSettings() (EmailSettings, error) {
+	settings := EmailSettings{Events: strings.Split(DefaultEmailEvents, ",")}
+	db := GetDB()
+	if db == nil {
+		return settings, fmt.Errorf("database not initialized")
+	}
+
+	var (
+		host, security, username, password, from, events sql.NullString
+		port                                             sql.NullInt64
+	)
+	err := db.QueryRow(`
+		SELECT smtp_host, smtp_port, smtp_security, smtp_username, smtp_password, smtp_from, email_events
+		FROM system_setup WHERE id = 1
+	`).Scan(&host, &port, &security, &username, &password, &from, &events)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to query email settings: %w", err)
+	}
+
+	settings.Host, settings.Port, settings.Security = host.String, int(port.Int64), security.String
+	settings.Username, settings.Password, settings.From = username.String, password.String, from.String
+	settings.Events = []string{}
+	if events.String != "" {
+		settings.Events = strings.Split(events.String, ",")
+	}
+	return settings, nil
+}
+
+// SaveEmailSettings stores the email settings
+func SaveEmailSettings(settings EmailSettings) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`INSERT OR IGNORE INTO system_setup (id, is_setup_complete) VALUES (1, 1)`); err != nil {
+		return fmt.Errorf("failed to create system setup: %w", err)
+	}
+	if _, err := db.Exec(`
+		UPDATE system_setup
+		SET smtp_host = ?, smtp_port = ?, smtp_security = ?, smtp_username = ?, smtp_password = ?, smtp_from = ?, email_events = ?
+		WHERE id = 1
+	`, settings.Host, settings.Port, settings.Security, settings.Username, settings.Password, settings.From,
+		strings.Join(settings.Events, ",")); err != nil {
+		return fmt.Errorf("failed to save email settings: %w", err)
+	}
+	return nil
+}
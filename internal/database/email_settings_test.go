@@ -0,0 +1,47 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmailSettings(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	settings, err := GetEmailSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Host != "" || len(settings.Events) != 5 {
+		t.Errorf("defaults = %+v", settings)
+	}
+
+	want := EmailSettings{
+		Host:     "mail.example.com",
+		Port:     465,
+		Security: "tls",
+		Username: "treeos",
+		Password: "secret",
+		From:     "treeos@example.com",
+		Events:   []string{"update.failed"},
+	}
+	if err := SaveEmailSettings(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetEmailSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("settings = %+v, want %+v", got, want)
+	}
+
+	want.Events = nil
+	if err := SaveEmailSettings(want); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetEmailSettings(); len(got.Events) != 0 {
+		t.Errorf("events = %v, want none", got.Events)
+	}
+}
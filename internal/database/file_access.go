@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+)
+
+// SetFileAccess grants a user read or write access to an app's files, replacing an existing grant
+func SetFileAccess(userID int, appName, access string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if access != FileAccessRead && access != FileAccessWrite {
+		return fmt.Errorf("invalid access level %q", access)
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO file_access_grants (user_id, app_name, access)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id, app_name) DO UPDATE SET access = excluded.access
+	`, userID, appName, access)
+	if err != nil {
+		return fmt.Errorf("failed to set file access: %w", err)
+	}
+	return nil
+}
+
+// DeleteFileAccess removes a grant
+func DeleteFileAccess(id int) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM file_access_grants WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete file access: %w", err)
+	}
+	return nil
+}
+
+// GetFileAccessGrants returns all grants ordered by user and app
+func GetFileAccessGrants() ([]FileAccessGrant, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT g.id, g.user_id, u.username, g.app_name, g.access, g.created_at
+		FROM file_access_grants g
+		JOIN users u ON u.id = g.user_id
+		ORDER BY u.username, g.app_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file access: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	grants := []FileAccessGrant{}
+	for rows.Next() {
+		var g FileAccessGrant
+		if err := rows.Scan(&g.ID, &g.UserID, &g.Username, &g.AppName, &g.Access, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan file access: %w", err)
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// GetFileAccessForUser returns the access level per app name for a user
+func GetFileAccessForUser(userID int) (map[string]string, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT app_name, access FROM file_access_grants WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file access: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	access := make(map[string]string)
+	for rows.Next() {
+		var appName, level string
+		if err := rows.Scan(&appName, &level); err != nil {
+			return nil, fmt.Errorf("failed to scan file access: %w", err)
+		}
+		access[appName] = level
+	}
+	return access, rows.Err()
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CreateHostReboot stores a new scheduled reboot and returns its ID
+func CreateHostReboot(reboot HostReboot) (int, error) {
+	db := GetDB()
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO host_reboots (status, reason, requested_by, scheduled_for)
+		VALUES (?, ?, ?, ?)
+	`, reboot.Status, reboot.Reason, reboot.RequestedBy, reboot.ScheduledFor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create host reboot: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get host reboot id: %w", err)
+	}
+	return int(id), nil
+}
+
+// GetLatestHostReboot returns the most recent reboot, or nil if there is none
+func GetLatestHostReboot() (*HostReboot, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var r HostReboot
+	var reason, requestedBy, apps, message sql.NullString
+	err := db.QueryRow(`
+		SELECT id, status, reason, requested_by, scheduled_for, apps, message, created_at, updated_at
+		FROM host_reboots
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&r.ID, &r.Status, &reason, &requestedBy, &r.ScheduledFor, &apps, &message, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host reboot: %w", err)
+	}
+
+	r.Reason = reason.String
+	r.RequestedBy = requestedBy.String
+	r.Message = message.String
+	r.Apps = []string{}
+	if apps.Valid && apps.String != "" {
+		if err := json.Unmarshal([]byte(apps.String), &r.Apps); err != nil {
+			return nil, fmt.Errorf("failed to decode host reboot apps: %w", err)
+		}
+	}
+	return &r, nil
+}
+
+// UpdateHostRebootStatus changes the status and message of a reboot
+func UpdateHostRebootStatus(id int, status, message string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		UPDATE host_reboots SET status = ?, message = ?, updated_at = ?
+		WHERE id = ?
+	`, status, message, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update host reboot: %w", err)
+	}
+	return nil
+}
+
+// SetHostRebootApps records the apps that were running before the reboot
+func SetHostRebootApps(id int, apps []string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	data, err := json.Marshal(apps)
+	if err != nil {
+		return fmt.Errorf("failed to encode host reboot apps: %w", err)
+	}
+	if _, err := db.Exec(`UPDATE host_reboots SET apps = ?, updated_at = ? WHERE id = ?`, string(data), time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update host reboot apps: %w", err)
+	}
+	return nil
+}
+
+// ClaimHostReboot moves a scheduled reboot to the stopping state. It returns false
+// if the reboot is no longer scheduled, e.g. because it was cancelled or already started.
+func ClaimHostReboot(id int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		UPDATE host_reboots SET status = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, RebootStatusStopping, time.Now(), id, RebootStatusScheduled)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim host reboot: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim host reboot: %w", err)
+	}
+	return affected == 1, nil
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ScheduleImagePrefetch schedules an image to be pulled in a window. An image that is
+// already being pulled keeps its state, otherwise a new request replaces the earlier one.
+func ScheduleImagePrefetch(prefetch ImagePrefetch) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO image_prefetches (image, source, pull_window, status, requested_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(image) DO UPDATE SET
+			source = excluded.source,
+			pull_window = excluded.pull_window,
+			requested_by = excluded.requested_by,
+			status = CASE WHEN status = ? THEN status ELSE excluded.status END,
+			message = CASE WHEN status = ? THEN message ELSE NULL END,
+			updated_at = excluded.updated_at
+	`, prefetch.Image, prefetch.Source, prefetch.Window, PrefetchStatusScheduled, prefetch.RequestedBy, time.Now(), time.Now(),
+		PrefetchStatusPulling, PrefetchStatusPulling)
+	if err != nil {
+		return fmt.Errorf("failed to schedule image prefetch: %w", err)
+	}
+	return nil
+}
+
+// ListImagePrefetches returns all prefetched and scheduled images, ordered by image
+func ListImagePrefetches() ([]ImagePrefetch, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, image, source, pull_window, status, message, requested_by, pulled_at, created_at, updated_at
+		FROM image_prefetches
+		ORDER BY image
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list image prefetches: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	prefetches := []ImagePrefetch{}
+	for rows.Next() {
+		var p ImagePrefetch
+		var source, window, message, requestedBy sql.NullString
+		var pulledAt sql.NullTime
+		if err := rows.Scan(&p.ID, &p.Image, &source, &window, &p.Status, &message, &requestedBy, &pulledAt, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan image prefetch: %w", err)
+		}
+		p.Source = source.String
+		p.Window = window.String
+		p.Message = message.String
+		p.RequestedBy = requestedBy.String
+		if pulledAt.Valid {
+			p.PulledAt = &pulledAt.Time
+		}
+		prefetches = append(prefetches, p)
+	}
+	return prefetches, rows.Err()
+}
+
+// ClaimImagePrefetch moves a scheduled prefetch to the pulling state. It returns false if
+// the prefetch is no longer scheduled.
+func ClaimImagePrefetch(id int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		UPDATE image_prefetches SET status = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, PrefetchStatusPulling, time.Now(), id, PrefetchStatusScheduled)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim image prefetch: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim image prefetch: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// FinishImagePrefetch records the outcome of a pull, pullErr nil marks the image as done
+func FinishImagePrefetch(id int, pullErr error) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	now := time.Now()
+	var err error
+	if pullErr == nil {
+		_, err = db.Exec(`UPDATE image_prefetches SET status = ?, message = NULL, pulled_at = ?, updated_at = ? WHERE id = ?`,
+			PrefetchStatusDone, now, now, id)
+	} else {
+		_, err = db.Exec(`UPDATE image_prefetches SET status = ?, message = ?, updated_at = ? WHERE id = ?`,
+			PrefetchStatusFailed, pullErr.Error(), now, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update image prefetch: %w", err)
+	}
+	return nil
+}
+
+// ResetInterruptedImagePrefetches schedules pulls again that were cut off by a restart
+func ResetInterruptedImagePrefetches() error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE image_prefetches SET status = ?, updated_at = ? WHERE status = ?`,
+		PrefetchStatusScheduled, time.Now(), PrefetchStatusPulling); err != nil {
+		return fmt.Errorf("failed to reset image prefetches: %w", err)
+	}
+	return nil
+}
+
+// DeleteImagePrefetch removes an image from the prefetch list. The image itself stays.
+func DeleteImagePrefetch(id int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`DELETE FROM image_prefetches WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete image prefetch: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete image prefetch: %w", err)
+	}
+	return affected == 1, nil
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReplaceImageUpdates stores the results of checking the images of an app, replacing
+// those of the previous check
+func ReplaceImageUpdates(appName string, updates []ImageUpdate) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	if _, err := tx.Exec(`DELETE FROM image_updates WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to clear image updates: %w", err)
+	}
+	now := time.Now()
+	for _, u := range updates {
+		if _, err := tx.Exec(`
+			INSERT INTO image_updates (app_name, image, current_digest, latest_digest, newer_tag, update_available, error, checked_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, appName, u.Image, u.CurrentDigest, u.LatestDigest, u.NewerTag, u.UpdateAvailable, u.Error, now); err != nil {
+			return fmt.Errorf("failed to store image update: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store image updates: %w", err)
+	}
+	return nil
+}
+
+// GetImageUpdates returns the results of the last check of an app's images, ordered by image
+func GetImageUpdates(appName string) ([]ImageUpdate, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, app_name, image, current_digest, latest_digest, newer_tag, update_available, error, checked_at
+		FROM image_updates
+		WHERE app_name = ?
+		ORDER BY image
+	`, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image updates: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	updates := []ImageUpdate{}
+	for rows.Next() {
+		var u ImageUpdate
+		var currentDigest, latestDigest, newerTag, checkErr sql.NullString
+		if err := rows.Scan(&u.ID, &u.AppName, &u.Image, &currentDigest, &latestDigest, &newerTag, &u.UpdateAvailable, &checkErr, &u.CheckedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan image update: %w", err)
+		}
+		u.CurrentDigest = currentDigest.String
+		u.LatestDigest = latestDigest.String
+		u.NewerTag = newerTag.String
+		u.Error = checkErr.String
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
+
+// GetAppsWithImageUpdates returns the apps with at least one image the registry has a
+// newer version or tag of
+func GetAppsWithImageUpdates() (map[string]bool, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT app_name FROM image_updates
+		WHERE update_available OR (newer_tag IS NOT NULL AND newer_tag != '')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image updates: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	apps := make(map[string]bool)
+	for rows.Next() {
+		var appName string
+		if err := rows.Scan(&appName); err != nil {
+			return nil, fmt.Errorf("failed to scan image update: %w", err)
+		}
+		apps[appName] = true
+	}
+	return apps, rows.Err()
+}
+
+// DeleteImageUpdates removes the check results of an app
+func DeleteImageUpdates(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM image_updates WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete image updates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import "testing"
+
+func TestImageUpdates(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := ReplaceImageUpdates("blog", []ImageUpdate{
+		{Image: "postgres:16", CurrentDigest: "sha256:a", LatestDigest: "sha256:b", UpdateAvailable: true},
+		{Image: "nginx:latest", Error: "registry unreachable"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceImageUpdates("wiki", []ImageUpdate{{Image: "redis:7", NewerTag: "8"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceImageUpdates("notes", []ImageUpdate{{Image: "redis:7", CurrentDigest: "sha256:c", LatestDigest: "sha256:c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := GetImageUpdates("blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 2 || updates[0].Image != "nginx:latest" || updates[0].Error == "" || !updates[1].UpdateAvailable || updates[1].CheckedAt.IsZero() {
+		t.Fatalf("GetImageUpdates() = %+v", updates)
+	}
+	apps, err := GetAppsWithImageUpdates()
+	if err != nil || len(apps) != 2 || !apps["blog"] || !apps["wiki"] {
+		t.Errorf("GetAppsWithImageUpdates() = %v, %v", apps, err)
+	}
+
+	// A new check replaces the results, deleting the app removes them
+	if err := ReplaceImageUpdates("blog", []ImageUpdate{{Image: "postgres:16", CurrentDigest: "sha256:b", LatestDigest: "sha256:b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteImageUpdates("wiki"); err != nil {
+		t.Fatal(err)
+	}
+	if apps, err := GetAppsWithImageUpdates(); err != nil || len(apps) != 0 {
+		t.Errorf("GetAppsWithImageUpdates() after the update = %v, %v", apps, err)
+	}
+	if updates, err := GetImageUpdates("blog"); err != nil || len(updates) != 1 {
+		t.Errorf("GetImageUpdates() after the update = %+v, %v", updates, err)
+	}
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInviteInvalid is returned for invites that expired, are used up or were revoked
+	ErrInviteInvalid = errors.New("invite is no longer valid")
+	// ErrUsernameTaken is returned when an invite is redeemed with an existing username
+	ErrUsernameTaken = errors.New("username is taken")
+)
+
+// CreateInvite stores a new invite with the hash of its token, setting its ID
+func CreateInvite(invite *Invite, tokenHash string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	err := db.QueryRow(`
+		INSERT INTO invites (token_hash, role, namespace, max_uses, expires_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at
+	`, tokenHash, invite.Role, invite.Namespace, invite.MaxUses, invite.ExpiresAt.UTC(), invite.CreatedBy).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invite: %w", err)
+	}
+	return nil
+}
+
+const inviteColumns = `id, role, namespace, max_uses, uses, expires_at, created_by, created_at, revoked_at`
+
+func scanInvite(row interface{ Scan(...any) error }) (*Invite, error) {
+	var inv Invite
+	var revokedAt sql.NullTime
+	if err := row.Scan(&inv.ID, &inv.Role, &inv.Namespace, &inv.MaxUses, &inv.Uses, &inv.ExpiresAt, &inv.CreatedBy, &inv.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		inv.RevokedAt = &revokedAt.Time
+	}
+	return &inv, nil
+}
+
+// GetInvites returns all invites, newest first
+func GetInvites() ([]Invite, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT ` + inviteColumns + ` FROM invites ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invites: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	invites := []Invite{}
+	for rows.Next() {
+		inv, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		invites = append(invites, *inv)
+	}
+	return invites, rows.Err()
+}
+
+// GetInviteByTokenHash returns the invite with a token, nil if there is none
+func GetInviteByTokenHash(tokenHash string) (*Invite, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	inv, err := scanInvite(db.QueryRow(`SELECT `+inviteColumns+` FROM invites WHERE token_hash = ?`, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invite: %w", err)
+	}
+	return inv, nil
+}
+
+// RevokeInvite revokes an invite, reporting false if it doesn't exist or is already revoked
+func RevokeInvite(id int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`UPDATE invites SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now().UTC(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke invite: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// RedeemInvite uses an invite to create a local user with the role of the invite and adds
+// them to its namespace. user holds the username and password hash and is completed with
+// the ID and role. Checking the invite and counting the use happen in one transaction, so
+// concurrent redemptions can't exceed its uses.
+func RedeemInvite(id int, user *User, now time.Time) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	var role, namespace string
+	err = tx.QueryRow(`
+		UPDATE invites SET uses = uses + 1
+		WHERE id = ? AND revoked_at IS NULL AND uses < max_uses AND expires_at > ?
+		RETURNING role, namespace
+	`, id, now.UTC()).Scan(&role, &namespace)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInviteInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("failed to use invite: %w", err)
+	}
+
+	var taken bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)`, user.Username).Scan(&taken); err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+	if taken {
+		return ErrUsernameTaken
+	}
+
+	user.IsSuperuser = role == InviteRoleAdmin
+	user.IsStaff = user.IsSuperuser || role == InviteRoleStaff
+	user.Role = RoleOperator
+	if user.IsStaff {
+		user.Role = RoleAdmin
+	}
+	user.IsActive = true
+	user.DateJoined = now
+	user.AuthSource = AuthSourceLocal
+	result, err := tx.Exec(`
+		INSERT INTO users (username, password, is_staff, is_superuser, is_active, date_joined, auth_source)
+		VALUES (?, ?, ?, ?, 1, ?, ?)
+	`, user.Username, user.Password, user.IsStaff, user.IsSuperuser, now, AuthSourceLocal)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	userID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get user ID: %w", err)
+	}
+	user.ID = int(userID)
+
+	// A namespace deleted after the invite was created is skipped
+	if namespace != "" {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO namespace_members (namespace, user_id)
+			SELECT name, ? FROM namespaces WHERE name = ?
+		`, user.ID, namespace); err != nil {
+			return fmt.Errorf("failed to add user to namespace: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// AddInviteEvent records an entry of the invite audit log
+func AddInviteEvent(event InviteEvent) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var inviteID sql.NullInt64
+	if event.InviteID > 0 {
+		inviteID = sql.NullInt64{Int64: int64(event.InviteID), Valid: true}
+	}
+	if _, err := db.Exec(`
+		INSERT INTO invite_events (invite_id, action, username, ip_address) VALUES (?, ?, ?, ?)
+	`, inviteID, event.Action, event.Username, event.IPAddress); err != nil {
+		return fmt.Errorf("failed to record invite event: %w", err)
+	}
+	return nil
+}
+
+// GetInviteEvents returns the most recent entries of the invite audit log
+func GetInviteEvents(limit int) ([]InviteEvent, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, COALESCE(invite_id, 0), action, username, ip_address, created_at
+		FROM invite_events
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invite events: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	events := []InviteEvent{}
+	for rows.Next() {
+		var e InviteEvent
+		if err := rows.Scan(&e.ID, &e.InviteID, &e.Action, &e.Username, &e.IPAddress, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invite event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
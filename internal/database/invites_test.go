@@ -0,0 +1,89 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedeemInvite(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := SaveNamespace(&Namespace{Name: "family"}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	invite := &Invite{Role: InviteRoleStaff, Namespace: "family", MaxUses: 1, ExpiresAt: now.Add(time.Hour), CreatedBy: "admin"}
+	if err := CreateInvite(invite, "hash"); err != nil {
+		t.Fatalf("CreateInvite() error = %v", err)
+	}
+	if got, err := GetInviteByTokenHash("hash"); err != nil || got == nil || got.ID != invite.ID {
+		t.Fatalf("GetInviteByTokenHash() = %+v, %v", got, err)
+	}
+	if got, _ := GetInviteByTokenHash("other"); got != nil {
+		t.Errorf("GetInviteByTokenHash() found %+v for an unknown token", got)
+	}
+
+	if err := RedeemInvite(invite.ID, &User{Username: "admin", Password: "x"}, now); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("RedeemInvite() with a taken username error = %v", err)
+	}
+	user := &User{Username: "alice", Password: "x"}
+	if err := RedeemInvite(invite.ID, user, now); err != nil {
+		t.Fatalf("RedeemInvite() error = %v", err)
+	}
+	if user.ID == 0 || !user.IsStaff || user.IsSuperuser {
+		t.Errorf("redeemed user = %+v, want staff", user)
+	}
+	if namespaces, _ := GetUserNamespaces(user.ID); len(namespaces) != 1 || namespaces[0] != "family" {
+		t.Errorf("namespaces of the new user = %v", namespaces)
+	}
+	if err := RedeemInvite(invite.ID, &User{Username: "bob", Password: "x"}, now); !errors.Is(err, ErrInviteInvalid) {
+		t.Errorf("RedeemInvite() of a used up invite error = %v", err)
+	}
+
+	expired := &Invite{Role: InviteRoleUser, MaxUses: 5, ExpiresAt: now.Add(-time.Minute), CreatedBy: "admin"}
+	if err := CreateInvite(expired, "expired"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RedeemInvite(expired.ID, &User{Username: "carol", Password: "x"}, now); !errors.Is(err, ErrInviteInvalid) {
+		t.Errorf("RedeemInvite() of an expired invite error = %v", err)
+	}
+
+	revoked := &Invite{Role: InviteRoleUser, MaxUses: 5, ExpiresAt: now.Add(time.Hour), CreatedBy: "admin"}
+	if err := CreateInvite(revoked, "revoked"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := RevokeInvite(revoked.ID); err != nil || !ok {
+		t.Fatalf("RevokeInvite() = %v, %v", ok, err)
+	}
+	if err := RedeemInvite(revoked.ID, &User{Username: "dave", Password: "x"}, now); !errors.Is(err, ErrInviteInvalid) {
+		t.Errorf("RedeemInvite() of a revoked invite error = %v", err)
+	}
+
+	invites, err := GetInvites()
+	if err != nil || len(invites) != 3 || invites[0].ID != revoked.ID || invites[0].RevokedAt == nil || invites[2].Uses != 1 {
+		t.Errorf("GetInvites() = %+v, %v", invites, err)
+	}
+}
+
+func TestInviteEvents(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	for _, e := range []InviteEvent{
+		{InviteID: 1, Action: InviteEventCreated, Username: "admin", IPAddress: "192.168.1.2"},
+		{Action: InviteEventRejected, IPAddress: "203.0.113.9"},
+	} {
+		if err := AddInviteEvent(e); err != nil {
+			t.Fatalf("AddInviteEvent() error = %v", err)
+		}
+	}
+	events, err := GetInviteEvents(10)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("GetInviteEvents() = %+v, %v", events, err)
+	}
+	if events[0].Action != InviteEventRejected || events[0].InviteID != 0 || events[1].InviteID != 1 {
+		t.Errorf("GetInviteEvents() = %+v", events)
+	}
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// LogFilter is a saved filter of an app's log viewer
+type LogFilter struct {
+	ID        int64     `json:"id"`
+	AppName   string    `json:"app_name"`
+	Name      string    `json:"name"`
+	Service   string    `json:"service"`
+	Level     string    `json:"level"`
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SaveLogFilter stores a log filter of an app, replacing a filter with the same name
+func SaveLogFilter(filter *LogFilter) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	err := db.QueryRow(`
+		INSERT INTO log_filters (app_name, name, service, level, query) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(app_name, name) DO UPDATE SET service = excluded.service, level = excluded.level, query = excluded.query
+		RETURNING id, created_at
+	`, filter.AppName, filter.Name, filter.Service, filter.Level, filter.Query).Scan(&filter.ID, &filter.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save log filter %s: %w", filter.Name, err)
+	}
+	return nil
+}
+
+// GetLogFilters returns the saved log filters of an app by name
+func GetLogFilters(appName string) ([]LogFilter, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, app_name, name, service, level, query, created_at
+		FROM log_filters WHERE app_name = ? ORDER BY name
+	`, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log filters: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	filters := []LogFilter{}
+	for rows.Next() {
+		var f LogFilter
+		if err := rows.Scan(&f.ID, &f.AppName, &f.Name, &f.Service, &f.Level, &f.Query, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan log filter: %w", err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, rows.Err()
+}
+
+// DeleteLogFilter removes a saved log filter of an app, reporting whether it existed
+func DeleteLogFilter(appName string, id int64) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`DELETE FROM log_filters WHERE app_name = ? AND id = ?`, appName, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete log filter: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete log filter: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// DeleteLogFilters removes the saved log filters of a deleted app
+func DeleteLogFilters(appName string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM log_filters WHERE app_name = ?`, appName); err != nil {
+		return fmt.Errorf("failed to delete log filters: %w", err)
+	}
+	return nil
+}
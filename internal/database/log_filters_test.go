@@ -0,0 +1,49 @@
+package database
+
+import "testing"
+
+func TestLogFilters(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	errors := &LogFilter{AppName: "immich", Name: "Errors", Level: "error"}
+	if err := SaveLogFilter(errors); err != nil {
+		t.Fatalf("SaveLogFilter() error = %v", err)
+	}
+	if errors.ID == 0 || errors.CreatedAt.IsZero() {
+		t.Errorf("saved filter = %+v, want id and created_at", errors)
+	}
+	for _, f := range []*LogFilter{
+		{AppName: "immich", Name: "Database", Service: "db", Query: "timeout"},
+		{AppName: "immich", Name: "Errors", Level: "warn"},
+		{AppName: "other", Name: "Errors", Level: "error"},
+	} {
+		if err := SaveLogFilter(f); err != nil {
+			t.Fatalf("SaveLogFilter() error = %v", err)
+		}
+	}
+
+	filters, err := GetLogFilters("immich")
+	if err != nil {
+		t.Fatalf("GetLogFilters() error = %v", err)
+	}
+	if len(filters) != 2 || filters[0].Name != "Database" || filters[0].Service != "db" || filters[1].Level != "warn" || filters[1].ID != errors.ID {
+		t.Errorf("GetLogFilters() = %+v", filters)
+	}
+
+	if deleted, err := DeleteLogFilter("other", errors.ID); err != nil || deleted {
+		t.Errorf("DeleteLogFilter() of another app = %v, %v", deleted, err)
+	}
+	if deleted, err := DeleteLogFilter("immich", errors.ID); err != nil || !deleted {
+		t.Errorf("DeleteLogFilter() = %v, %v", deleted, err)
+	}
+	if err := DeleteLogFilters("immich"); err != nil {
+		t.Fatalf("DeleteLogFilters() error = %v", err)
+	}
+	if filters, _ := GetLogFilters("immich"); len(filters) != 0 {
+		t.Errorf("filters left after delete: %+v", filters)
+	}
+	if filters, _ := GetLogFilters("other"); len(filters) != 1 {
+		t.Errorf("filters of another app deleted: %+v", filters)
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// RecordLoginEvent stores a successful login
+func RecordLoginEvent(event LoginEvent) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO login_events (user_id, ip_address, user_agent, location, is_new_device)
+		VALUES (?, ?, ?, ?, ?)
+	`, event.UserID, event.IPAddress, event.UserAgent, event.Location, event.IsNewDevice)
+	if err != nil {
+		return fmt.Errorf("failed to record login event: %w", err)
+	}
+	return nil
+}
+
+// HasLoginFrom reports whether the user has logged in before from the given IP address and user agent
+func HasLoginFrom(userID int, ipAddress, userAgent string) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM login_events
+		WHERE user_id = ? AND ip_address = ? AND user_agent = ?
+	`, userID, ipAddress, userAgent).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to query login events: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CountLoginEvents returns the number of recorded logins for the user
+func CountLoginEvents(userID int) (int, error) {
+	db := GetDB()
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM login_events WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count login events: %w", err)
+	}
+	return count, nil
+}
+
+// GetRecentLoginEvents returns the most recent logins of the user, newest first
+func GetRecentLoginEvents(userID, limit int) ([]LoginEvent, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, user_id, ip_address, COALESCE(user_agent, ''), COALESCE(location, ''),
+		       COALESCE(is_new_device, 0), created_at
+		FROM login_events
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query login events: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	events := []LoginEvent{}
+	for rows.Next() {
+		var e LoginEvent
+		var createdAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.UserID, &e.IPAddress, &e.UserAgent, &e.Location, &e.IsNewDevice, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan login event: %w", err)
+		}
+		if createdAt.Valid {
+			e.CreatedAt = createdAt.Time
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return events, nil
+}
+
+// CleanupOldLoginEvents keeps only the most recent keep logins per user
+func CleanupOldLoginEvents(keep int) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		DELETE FROM login_events
+		WHERE id NOT IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC) AS rn
+				FROM login_events
+			) WHERE rn <= ?
+		)
+	`, keep)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup login events: %w", err)
+	}
+	return nil
+}
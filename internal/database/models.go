@@ -19,8 +19,29 @@ type User struct {
 	IsActive    bool
 	DateJoined  time.Time
 	LastLogin   sql.NullTime
+	AuthSource  string // AuthSourceLocal or AuthSourceLDAP
+	Role        string // RoleAdmin for staff users, RoleOperator or RoleViewer for others
+	// SessionVersion is raised when the password changes, ending sessions started before
+	SessionVersion int
 }
 
+// Roles of users. Staff users are admins, the role column tells operators from viewers.
+const (
+	// RoleAdmin manages users, settings and all apps
+	RoleAdmin = "admin"
+	// RoleOperator starts, stops and changes the apps of their namespaces
+	RoleOperator = "operator"
+	// RoleViewer only looks at the apps of their namespaces
+	RoleViewer = "viewer"
+)
+
+const (
+	// AuthSourceLocal marks users whose password hash is stored by TreeOS.
+	AuthSourceLocal = "local"
+	// AuthSourceLDAP marks users created on their first login against the LDAP directory.
+	AuthSourceLDAP = "ldap"
+)
+
 // SystemSetup tracks the system setup state.
 type SystemSetup struct {
 	ID                 int
@@ -89,6 +110,335 @@ type UpdateHistory struct {
 	CreatedAt    time.Time
 }
 
+// LoginEvent records a successful login and where it came from
+type LoginEvent struct {
+	ID          int
+	UserID      int
+	IPAddress   string
+	UserAgent   string
+	Location    string // Approximate location, empty if unknown
+	IsNewDevice bool   // True if this IP/user agent combination was not seen before
+	CreatedAt   time.Time
+}
+
+// HostReboot tracks a coordinated reboot of the host
+type HostReboot struct {
+	ID           int       `json:"id"`
+	Status       string    `json:"status"`
+	Reason       string    `json:"reason,omitempty"`
+	RequestedBy  string    `json:"requested_by,omitempty"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Apps         []string  `json:"apps"` // Apps that were running before the reboot
+	Message      string    `json:"message,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ImagePrefetch tracks an image pulled ahead of an install or update
+type ImagePrefetch struct {
+	ID          int        `json:"id"`
+	Image       string     `json:"image"`
+	Source      string     `json:"source"`           // template:<id> or app:<name> that needs the image
+	Window      string     `json:"window,omitempty"` // Time window for the pull, empty for as soon as possible
+	Status      string     `json:"status"`
+	Message     string     `json:"message,omitempty"`
+	RequestedBy string     `json:"requested_by,omitempty"`
+	PulledAt    *time.Time `json:"pulled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ImageUpdate is the result of checking the registry for a newer version of an image
+// an app uses
+type ImageUpdate struct {
+	ID              int       `json:"id"`
+	AppName         string    `json:"app_name"`
+	Image           string    `json:"image"`
+	CurrentDigest   string    `json:"current_digest,omitempty"` // Empty if the image isn't pulled
+	LatestDigest    string    `json:"latest_digest,omitempty"`
+	NewerTag        string    `json:"newer_tag,omitempty"` // Higher version tag of the same series, e.g. 17-alpine for 16-alpine
+	UpdateAvailable bool      `json:"update_available"`    // The registry has another image for the tag
+	Error           string    `json:"error,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// AppRebuild is a rebuild of the images of an app that is built from source
+type AppRebuild struct {
+	ID          int        `json:"id"`
+	AppName     string     `json:"app_name"`
+	Trigger     string     `json:"trigger"` // "schedule" or "manual"
+	Status      string     `json:"status"`
+	Message     string     `json:"message,omitempty"`
+	RequestedBy string     `json:"requested_by,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// AppResourceLog is the resource usage of a service of an app at one point in time, the
+// per-app counterpart of SystemVitalLog
+type AppResourceLog struct {
+	ID          int       `json:"-"`
+	Timestamp   time.Time `json:"timestamp"`
+	AppName     string    `json:"-"`
+	Service     string    `json:"service"`
+	CPUPercent  float64   `json:"cpu_percent"`  // Of one core, can exceed 100
+	MemoryBytes uint64    `json:"memory_bytes"` // Without the page cache
+	MemoryLimit uint64    `json:"memory_limit,omitempty"`
+	NetRxRate   uint64    `json:"net_rx_rate"` // bytes per second
+	NetTxRate   uint64    `json:"net_tx_rate"` // bytes per second
+}
+
+// AppDiskUsage is the disk space an app used when it was last measured, in bytes
+type AppDiskUsage struct {
+	AppName    string    `json:"app"`
+	Images     int64     `json:"images"`
+	Containers int64     `json:"containers"` // Writable layers
+	Volumes    int64     `json:"volumes"`
+	BindMounts int64     `json:"bind_mounts"` // The app directory
+	Logs       int64     `json:"logs"`        // -1 when unknown
+	MeasuredAt time.Time `json:"measured_at"`
+}
+
+// AppUpgrade is an upgrade of an app to newer images, with the state it is rolled back to
+// if the app doesn't become healthy
+type AppUpgrade struct {
+	ID              int               `json:"id"`
+	AppName         string            `json:"app_name"`
+	Status          string            `json:"status"`
+	Message         string            `json:"message,omitempty"`
+	PreviousImages  map[string]string `json:"previous_images,omitempty"` // Image to the digest it had before
+	ComposeSnapshot string            `json:"-"`
+	EnvSnapshot     string            `json:"-"` // Empty if the app had no .env file
+	RequestedBy     string            `json:"requested_by,omitempty"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      *time.Time        `json:"finished_at,omitempty"`
+}
+
+// Webhook is a target that is told about events on the node, like an app that failed to
+// start or a full disk
+type Webhook struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	URL        string     `json:"url"`
+	Kind       string     `json:"kind"`   // One of the notify.Webhook kinds
+	Events     []string   `json:"events"` // Event types it subscribes to
+	Enabled    bool       `json:"enabled"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"` // Of the last delivery, "" if it succeeded
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TemplateCatalog is a remote catalog of app templates that is synced into the templates page
+type TemplateCatalog struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`                 // Prefix of the IDs of its templates
+	URL          string     `json:"url"`                  // JSON index or Git repository
+	PublicKey    string     `json:"public_key,omitempty"` // Ed25519 key the index must be signed with, "" for unsigned
+	Enabled      bool       `json:"enabled"`
+	Templates    int        `json:"templates"` // Templates of the last successful sync
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"` // Of the last sync, "" if it succeeded
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// EmailSettings are the mail server set in the settings and the events emailed to admins
+type EmailSettings struct {
+	Host     string
+	Port     int
+	Security string // starttls, tls or none
+	Username string
+	Password string
+	From     string
+	Events   []string // Event types emailed to admins
+}
+
+// APIToken lets scripts call the /api/ endpoints as a user without a browser session
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Start of the token, to recognize it
+	Scope      string     `json:"scope"`  // One of the APITokenScope constants
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Scopes of API tokens
+const (
+	APITokenScopeRead = "read" // GET and HEAD requests
+	APITokenScopeApps = "apps" // Reading, creating and changing apps under /api/apps, nothing else
+	APITokenScopeFull = "full" // Everything the user may do, except managing tokens
+)
+
+// ActivityEvent is an entry of the activity feed of the dashboard, collected from the
+// audit logs and histories of the other tables
+type ActivityEvent struct {
+	Category  string    `json:"category"` // One of the Activity constants
+	Title     string    `json:"title"`
+	Detail    string    `json:"detail,omitempty"`
+	AppName   string    `json:"app_name,omitempty"`
+	Actor     string    `json:"actor,omitempty"` // User who caused it, empty for the system
+	Status    string    `json:"status,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Categories of the activity feed
+const (
+	ActivityAudit  = "audit"  // Security, access and approval changes
+	ActivityJob    = "job"    // Finished app operations, rebuilds, downloads and reboots
+	ActivityAlert  = "alert"  // Notifications sent to the admins
+	ActivityUpdate = "update" // TreeOS updates
+)
+
+// FileAccessGrant gives a user access to an app's mount directory over WebDAV
+type FileAccessGrant struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	AppName   string    `json:"app_name"`
+	Access    string    `json:"access"` // FileAccessRead or FileAccessWrite
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AppPermission grants a user access to an app outside of their namespaces
+type AppPermission struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	AppName   string    `json:"app_name"`
+	Access    string    `json:"access"` // AppAccessView or AppAccessControl
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Share is a Samba share of an app's mount directory or a directory inside it
+type Share struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	AppName    string    `json:"app_name"`
+	SubPath    string    `json:"sub_path"` // Relative to the app's mnt directory, empty for all of it
+	Comment    string    `json:"comment"`
+	ReadOnly   bool      `json:"read_only"`
+	ValidUsers []string  `json:"valid_users"` // Samba users, empty allows all
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SecurityAuditEvent records a change to the security validation of an app
+type SecurityAuditEvent struct {
+	ID            int       `json:"id"`
+	AppName       string    `json:"app_name"`
+	Username      string    `json:"username"`
+	Action        string    `json:"action"` // SecurityBypassEnabled or SecurityBypassDisabled
+	Justification string    `json:"justification"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AppCredential is a secret generated for an app when it was installed from a template
+type AppCredential struct {
+	AppName   string    `json:"app_name"`
+	Name      string    `json:"name"` // Variable the secret was assigned to, e.g. DB_PASSWORD
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClientCertificate is a TLS client certificate issued to a user. Only its serial is kept,
+// the key went to the user in a one-time download.
+type ClientCertificate struct {
+	Serial    string     `json:"serial"` // Hex encoded
+	UserID    int        `json:"user_id"`
+	Name      string     `json:"name"` // Device label, e.g. "Work laptop"
+	NotAfter  time.Time  `json:"not_after"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Invite is a link that lets someone create their own account. Only a hash of its token is
+// stored, the link is shown once when the invite is created.
+type Invite struct {
+	ID        int        `json:"id"`
+	Role      string     `json:"role"`      // InviteRoleUser, InviteRoleStaff or InviteRoleAdmin
+	Namespace string     `json:"namespace"` // New users join it, "" for none
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Roles an invite can grant
+const (
+	InviteRoleUser  = "user"
+	InviteRoleStaff = "staff" // Can manage apps
+	InviteRoleAdmin = "admin" // Staff and superuser
+)
+
+// InviteEvent is an entry of the audit log of invites
+type InviteEvent struct {
+	ID        int       `json:"id"`
+	InviteID  int       `json:"invite_id"` // 0 for attempts with an unknown token
+	Action    string    `json:"action"`    // One of the InviteEvent constants
+	Username  string    `json:"username"`  // Admin who created or revoked it, or the new user
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Actions of the invite audit log
+const (
+	InviteEventCreated  = "created"
+	InviteEventRevoked  = "revoked"
+	InviteEventRedeemed = "redeemed"
+	InviteEventRejected = "rejected" // Unknown, expired, used up or revoked link
+)
+
+// ChatMessage is a message of an app's agent chat
+type ChatMessage struct {
+	ID            int       `json:"id"`
+	AppID         string    `json:"app_id"`
+	Message       string    `json:"message"`
+	SenderType    string    `json:"sender_type"` // SenderTypeUser, SenderTypeAgent or SenderTypeSystem
+	SenderName    string    `json:"sender_name"`
+	AgentModel    string    `json:"agent_model,omitempty"`
+	AgentProvider string    `json:"agent_provider,omitempty"`
+	StatusLevel   string    `json:"status_level,omitempty"`
+	Details       string    `json:"details,omitempty"` // JSON with the tool calls of an agent reply
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// AgentReview is a scheduled health review of all apps by the agent
+type AgentReview struct {
+	ID          int            `json:"id"`
+	Status      string         `json:"status"`
+	Summary     string         `json:"summary"`
+	Findings    []AgentFinding `json:"findings"`
+	Model       string         `json:"model"`
+	Error       string         `json:"error,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+// AgentFinding is the assessment of one app, or of the node, in a review
+type AgentFinding struct {
+	App      string `json:"app"` // Empty for node-wide findings
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+}
+
+// AgentAction is an action suggested by the agent that an admin has to approve
+type AgentAction struct {
+	ID        int        `json:"id"`
+	ReviewID  int        `json:"review_id,omitempty"`
+	AppName   string     `json:"app_name"`
+	Action    string     `json:"action"`
+	Reason    string     `json:"reason"`
+	Status    string     `json:"status"`
+	Result    string     `json:"result,omitempty"`
+	DecidedBy string     `json:"decided_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
 const (
 	// OpTypePullImage indicates a container image pull operation.
 	OpTypePullImage = "pull_image"
@@ -126,6 +476,81 @@ const (
 	// SenderTypeSystem represents a system-generated message
 	SenderTypeSystem = "system"
 
+	// RebootStatusScheduled indicates a reboot waiting for its scheduled time
+	RebootStatusScheduled = "scheduled"
+	// RebootStatusStopping indicates apps are being stopped before a reboot
+	RebootStatusStopping = "stopping"
+	// RebootStatusRebooting indicates the reboot was issued
+	RebootStatusRebooting = "rebooting"
+	// RebootStatusCompleted indicates the host came back with all apps running
+	RebootStatusCompleted = "completed"
+	// RebootStatusFailed indicates the reboot or the verification afterwards failed
+	RebootStatusFailed = "failed"
+	// RebootStatusCancelled indicates a scheduled reboot was cancelled
+	RebootStatusCancelled = "cancelled"
+
+	// PrefetchStatusScheduled indicates an image waiting for its prefetch window
+	PrefetchStatusScheduled = "scheduled"
+	// PrefetchStatusPulling indicates an image that is being pulled
+	PrefetchStatusPulling = "pulling"
+	// PrefetchStatusDone indicates an image that is available locally
+	PrefetchStatusDone = "done"
+	// PrefetchStatusFailed indicates an image whose pull failed
+	PrefetchStatusFailed = "failed"
+
+	// RebuildStatusRunning indicates images being rebuilt and verified
+	RebuildStatusRunning = "running"
+	// RebuildStatusSucceeded indicates the app runs healthy on the rebuilt images
+	RebuildStatusSucceeded = "succeeded"
+	// RebuildStatusFailed indicates a build that failed, the app kept its previous images
+	RebuildStatusFailed = "failed"
+	// RebuildStatusRolledBack indicates rebuilt images that were unhealthy and replaced by the previous ones
+	RebuildStatusRolledBack = "rolled_back"
+
+	// UpgradeStatusRunning indicates an upgrade that is pulling images or waiting for the app to become healthy
+	UpgradeStatusRunning = "running"
+	// UpgradeStatusSucceeded indicates an app that became healthy on its new images
+	UpgradeStatusSucceeded = "succeeded"
+	// UpgradeStatusFailed indicates an upgrade that failed before the app was changed, or whose rollback failed
+	UpgradeStatusFailed = "failed"
+	// UpgradeStatusRolledBack indicates an app that did not become healthy and runs on its previous images again
+	UpgradeStatusRolledBack = "rolled_back"
+
+	// ReviewStatusRunning indicates an agent review is collecting and assessing app data
+	ReviewStatusRunning = "running"
+	// ReviewStatusCompleted indicates an agent review finished
+	ReviewStatusCompleted = "completed"
+	// ReviewStatusFailed indicates an agent review could not be completed
+	ReviewStatusFailed = "failed"
+
+	// SeverityOK indicates nothing needs attention
+	SeverityOK = "ok"
+	// SeverityInfo indicates something worth knowing that needs no action
+	SeverityInfo = "info"
+	// SeverityWarning indicates a problem that should be looked at
+	SeverityWarning = "warning"
+	// SeverityCritical indicates a problem that needs action now
+	SeverityCritical = "critical"
+
+	// ActionRestartApp restarts an app
+	ActionRestartApp = "restart_app"
+
+	// ActionStatusPending indicates an action waiting for approval
+	ActionStatusPending = "pending"
+	// ActionStatusRejected indicates an action an admin rejected
+	ActionStatusRejected = "rejected"
+	// ActionStatusRunning indicates an approved action being executed
+	ActionStatusRunning = "running"
+	// ActionStatusCompleted indicates an approved action that succeeded
+	ActionStatusCompleted = "completed"
+	// ActionStatusFailed indicates an approved action that failed
+	ActionStatusFailed = "failed"
+
+	// FileAccessRead allows listing and downloading files
+	FileAccessRead = "read"
+	// FileAccessWrite additionally allows uploading, changing and deleting files
+	FileAccessWrite = "write"
+
 	// StatusLevelInfo indicates an informational status message
 	StatusLevelInfo = "info"
 	// StatusLevelWarning indicates a warning status message
@@ -144,3 +569,11 @@ const (
 	// ProviderOllama indicates Ollama as the LLM provider
 	ProviderOllama = "ollama"
 )
+
+// Access levels of app permissions
+const (
+	// AppAccessView shows an app, its status and logs
+	AppAccessView = "view"
+	// AppAccessControl additionally allows starting, stopping and changing it
+	AppAccessControl = "control"
+)
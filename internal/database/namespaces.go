@@ -0,0 +1,210 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownUser is returned for namespace members that are not users
+var ErrUnknownUser = errors.New("unknown user")
+
+// Namespace groups apps for the users that are its members, e.g. family, work or kids
+type Namespace struct {
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	SubdomainPrefix string    `json:"subdomain_prefix"` // Subdomains of its apps start with it, "" for any
+	PortMin         int       `json:"port_min"`         // Host ports of its apps, 0 for no range
+	PortMax         int       `json:"port_max"`
+	MaxApps         int       `json:"max_apps"` // 0 for no limit
+	Members         []string  `json:"members"`  // Usernames, sorted
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// GetNamespaces returns all namespaces with their members, ordered by name
+func GetNamespaces() ([]Namespace, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT name, description, subdomain_prefix, port_min, port_max, max_apps, created_at
+		FROM namespaces
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query namespaces: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	namespaces := []Namespace{}
+	for rows.Next() {
+		var ns Namespace
+		if err := rows.Scan(&ns.Name, &ns.Description, &ns.SubdomainPrefix, &ns.PortMin, &ns.PortMax, &ns.MaxApps, &ns.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan namespace: %w", err)
+		}
+		namespaces = append(namespaces, ns)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	members, err := namespaceMembers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range namespaces {
+		namespaces[i].Members = members[namespaces[i].Name]
+		if namespaces[i].Members == nil {
+			namespaces[i].Members = []string{}
+		}
+	}
+	return namespaces, nil
+}
+
+// GetNamespace returns a namespace, nil if it does not exist
+func GetNamespace(name string) (*Namespace, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	ns := Namespace{Name: name}
+	err := db.QueryRow(`
+		SELECT description, subdomain_prefix, port_min, port_max, max_apps, created_at
+		FROM namespaces WHERE name = ?
+	`, name).Scan(&ns.Description, &ns.SubdomainPrefix, &ns.PortMin, &ns.PortMax, &ns.MaxApps, &ns.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query namespace: %w", err)
+	}
+
+	members, err := namespaceMembers()
+	if err != nil {
+		return nil, err
+	}
+	ns.Members = members[name]
+	if ns.Members == nil {
+		ns.Members = []string{}
+	}
+	return &ns, nil
+}
+
+// namespaceMembers returns the usernames of the members of each namespace
+func namespaceMembers() (map[string][]string, error) {
+	rows, err := GetDB().Query(`
+		SELECT m.namespace, u.username
+		FROM namespace_members m JOIN users u ON u.id = m.user_id
+		ORDER BY u.username
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query namespace members: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	members := map[string][]string{}
+	for rows.Next() {
+		var namespace, username string
+		if err := rows.Scan(&namespace, &username); err != nil {
+			return nil, fmt.Errorf("failed to scan namespace member: %w", err)
+		}
+		members[namespace] = append(members[namespace], username)
+	}
+	return members, rows.Err()
+}
+
+// SaveNamespace creates a namespace or updates its settings and members. Members are
+// usernames, unknown ones are an error.
+func SaveNamespace(ns *Namespace) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after commit
+
+	err = tx.QueryRow(`
+		INSERT INTO namespaces (name, description, subdomain_prefix, port_min, port_max, max_apps)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET description = excluded.description,
+			subdomain_prefix = excluded.subdomain_prefix, port_min = excluded.port_min,
+			port_max = excluded.port_max, max_apps = excluded.max_apps
+		RETURNING created_at
+	`, ns.Name, ns.Description, ns.SubdomainPrefix, ns.PortMin, ns.PortMax, ns.MaxApps).Scan(&ns.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save namespace: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM namespace_members WHERE namespace = ?`, ns.Name); err != nil {
+		return fmt.Errorf("failed to clear namespace members: %w", err)
+	}
+	for _, username := range ns.Members {
+		result, err := tx.Exec(`
+			INSERT OR IGNORE INTO namespace_members (namespace, user_id)
+			SELECT ?, id FROM users WHERE username = ?
+		`, ns.Name, username)
+		if err != nil {
+			return fmt.Errorf("failed to add namespace member: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			var exists bool
+			if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)`, username).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to look up user: %w", err)
+			}
+			if !exists {
+				return fmt.Errorf("%w %q", ErrUnknownUser, username)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteNamespace removes a namespace and its memberships, reporting whether it existed
+func DeleteNamespace(name string) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM namespace_members WHERE namespace = ?`, name); err != nil {
+		return false, fmt.Errorf("failed to delete namespace members: %w", err)
+	}
+	result, err := db.Exec(`DELETE FROM namespaces WHERE name = ?`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete namespace: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetUserNamespaces returns the names of the namespaces a user is a member of, sorted
+func GetUserNamespaces(userID int) ([]string, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT namespace FROM namespace_members WHERE user_id = ? ORDER BY namespace`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user namespaces: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan namespace: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
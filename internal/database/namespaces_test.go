@@ -0,0 +1,81 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespaces(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if _, err := GetDB().Exec(`INSERT INTO users (username, password) VALUES ('alice', 'hash'), ('bob', 'hash')`); err != nil {
+		t.Fatalf("Failed to insert users: %v", err)
+	}
+
+	ns := Namespace{Name: "kids", SubdomainPrefix: "kids-", PortMin: 20000, PortMax: 20999, MaxApps: 3, Members: []string{"bob", "alice"}}
+	if err := SaveNamespace(&ns); err != nil {
+		t.Fatalf("SaveNamespace() error = %v", err)
+	}
+	if ns.CreatedAt.IsZero() {
+		t.Error("SaveNamespace() did not set created_at")
+	}
+	if err := SaveNamespace(&Namespace{Name: "work", Members: []string{"alice"}}); err != nil {
+		t.Fatalf("SaveNamespace() error = %v", err)
+	}
+
+	got, err := GetNamespace("kids")
+	if err != nil || got == nil {
+		t.Fatalf("GetNamespace() = %v, %v", got, err)
+	}
+	if got.SubdomainPrefix != "kids-" || got.PortMin != 20000 || got.PortMax != 20999 || got.MaxApps != 3 ||
+		!reflect.DeepEqual(got.Members, []string{"alice", "bob"}) {
+		t.Errorf("GetNamespace() = %+v", got)
+	}
+	if missing, err := GetNamespace("family"); err != nil || missing != nil {
+		t.Errorf("GetNamespace() of a missing namespace = %v, %v", missing, err)
+	}
+
+	// Saving replaces the members
+	ns.Members = []string{"bob"}
+	if err := SaveNamespace(&ns); err != nil {
+		t.Fatalf("SaveNamespace() error = %v", err)
+	}
+	if names, err := GetUserNamespaces(aliceID(t)); err != nil || !reflect.DeepEqual(names, []string{"work"}) {
+		t.Errorf("GetUserNamespaces(alice) = %v, %v", names, err)
+	}
+
+	// Unknown members are refused and leave the namespace as it was
+	ns.Members = []string{"bob", "mallory"}
+	if err := SaveNamespace(&ns); err == nil {
+		t.Error("SaveNamespace() accepted an unknown user")
+	}
+	if got, _ := GetNamespace("kids"); got == nil || !reflect.DeepEqual(got.Members, []string{"bob"}) {
+		t.Errorf("members after a failed save = %+v", got)
+	}
+
+	all, err := GetNamespaces()
+	if err != nil || len(all) != 2 || all[0].Name != "kids" || all[1].Name != "work" {
+		t.Fatalf("GetNamespaces() = %+v, %v", all, err)
+	}
+
+	deleted, err := DeleteNamespace("work")
+	if err != nil || !deleted {
+		t.Fatalf("DeleteNamespace() = %v, %v", deleted, err)
+	}
+	if names, _ := GetUserNamespaces(aliceID(t)); len(names) != 0 {
+		t.Errorf("alice is still in %v after the delete", names)
+	}
+	if deleted, _ := DeleteNamespace("work"); deleted {
+		t.Error("DeleteNamespace() of a missing namespace reported a delete")
+	}
+}
+
+func aliceID(t *testing.T) int {
+	t.Helper()
+	var id int
+	if err := GetDB().QueryRow(`SELECT id FROM users WHERE username = 'alice'`).Scan(&id); err != nil {
+		t.Fatalf("Failed to look up alice: %v", err)
+	}
+	return id
+}
@@ -0,0 +1,387 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// ErrCorrupt is returned when the database fails the SQLite integrity check
+var ErrCorrupt = errors.New("database is corrupt")
+
+// backupTimeFormat names backup files so they sort by age
+const backupTimeFormat = "20060102-150405"
+
+// BackupInfo describes a database backup file
+type BackupInfo struct {
+	Name      string
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// RebuildResult reports how many rows of each table were saved by DumpAndRebuild
+type RebuildResult struct {
+	Tables map[string]int    `json:"tables"`
+	Errors map[string]string `json:"errors,omitempty"` // Tables that could only be read partially
+}
+
+// CheckIntegrity runs the SQLite integrity check on the database file without modifying it.
+// A missing file is fine, it is created on startup.
+func CheckIntegrity(dbPath string) error {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	conn, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer conn.Close() //nolint:errcheck // Read-only check
+
+	rows, err := conn.Query("PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrCorrupt, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// BackupDir returns the directory holding the backups of a database file
+func BackupDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "backups")
+}
+
+// Backup writes a consistent copy of the open database to the backup directory and
+// deletes all but the newest keep backups
+func Backup(dbPath string, keep int) (string, error) {
+	db := GetDB()
+	if db == nil {
+		return "", fmt.Errorf("database not initialized")
+	}
+
+	dir := BackupDir(dbPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	target := filepath.Join(dir, "treeos-"+time.Now().Format(backupTimeFormat)+".db")
+	// VACUUM INTO refuses to overwrite, a backup from the same second is replaced
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to replace backup: %w", err)
+	}
+	if _, err := db.Exec("VACUUM INTO ?", target); err != nil {
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		return target, err
+	}
+	for i := keep; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			logging.Warnf("Failed to remove old database backup %s: %v", backups[i].Path, err)
+		}
+	}
+	return target, nil
+}
+
+// ListBackups returns the backups of a database, newest first
+func ListBackups(dbPath string) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(BackupDir(dbPath))
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".db") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Name:      entry.Name(),
+			Path:      filepath.Join(BackupDir(dbPath), entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// RestoreBackup replaces the database with a backup. The corrupt database is kept next to it.
+func RestoreBackup(dbPath, backupName string) (string, error) {
+	if backupName != filepath.Base(backupName) || !strings.HasSuffix(backupName, ".db") {
+		return "", fmt.Errorf("invalid backup name %q", backupName)
+	}
+	backupPath := filepath.Join(BackupDir(dbPath), backupName)
+	if _, err := os.Stat(backupPath); err != nil {
+		return "", fmt.Errorf("backup %s not found: %w", backupName, err)
+	}
+	if err := CheckIntegrity(backupPath); err != nil {
+		return "", fmt.Errorf("backup %s is not usable: %w", backupName, err)
+	}
+
+	moved, err := moveAside(dbPath)
+	if err != nil {
+		return "", err
+	}
+	if err := copyFile(backupPath, dbPath); err != nil {
+		return moved, fmt.Errorf("failed to restore backup: %w", err)
+	}
+	return moved, nil
+}
+
+// scheduledRestorePath is the file naming the backup ScheduleRestore marked
+func scheduledRestorePath(dbPath string) string {
+	return filepath.Join(BackupDir(dbPath), "restore-scheduled")
+}
+
+// ScheduleRestore marks a backup to be restored on the next start. The database can't be
+// replaced while it is open, so restores of a running TreeOS go through a restart.
+func ScheduleRestore(dbPath, backupName string) error {
+	if backupName != filepath.Base(backupName) || !strings.HasSuffix(backupName, ".db") {
+		return fmt.Errorf("invalid backup name %q", backupName)
+	}
+	if err := CheckIntegrity(filepath.Join(BackupDir(dbPath), backupName)); err != nil {
+		return fmt.Errorf("backup %s is not usable: %w", backupName, err)
+	}
+	if err := os.WriteFile(scheduledRestorePath(dbPath), []byte(backupName+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to schedule restore: %w", err)
+	}
+	return nil
+}
+
+// ApplyScheduledRestore restores the backup ScheduleRestore marked before the database is
+// opened. It returns the restored backup, empty if none was scheduled, and where the replaced
+// database was kept. A failed restore is not tried again.
+func ApplyScheduledRestore(dbPath string) (string, string, error) {
+	data, err := os.ReadFile(scheduledRestorePath(dbPath))
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read scheduled restore: %w", err)
+	}
+	if err := os.Remove(scheduledRestorePath(dbPath)); err != nil {
+		return "", "", fmt.Errorf("failed to clear scheduled restore: %w", err)
+	}
+
+	backupName := strings.TrimSpace(string(data))
+	moved, err := RestoreBackup(dbPath, backupName)
+	return backupName, moved, err
+}
+
+// StartFresh moves the corrupt database aside so an empty one is created on startup.
+// App directories are not touched and are picked up again by the new database.
+func StartFresh(dbPath string) (string, error) {
+	return moveAside(dbPath)
+}
+
+// DumpAndRebuild copies every readable row of the corrupt database into a newly created one.
+// Tables are copied row by row so a damaged page only loses the rows behind it.
+func DumpAndRebuild(dbPath string) (*RebuildResult, string, error) {
+	rebuiltPath := dbPath + ".rebuild"
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(rebuiltPath + suffix) //nolint:errcheck,gosec // Leftovers of an earlier attempt
+	}
+
+	// Create the current schema in the new file
+	if err := Initialize(rebuiltPath); err != nil {
+		return nil, "", fmt.Errorf("failed to create new database: %w", err)
+	}
+	defer Close() //nolint:errcheck // Reopened on normal startup
+
+	old, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open corrupt database: %w", err)
+	}
+	defer old.Close() //nolint:errcheck // Read-only
+
+	tables, err := tableNames(db)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := &RebuildResult{Tables: map[string]int{}, Errors: map[string]string{}}
+	for _, table := range tables {
+		count, err := copyTable(old, db, table)
+		result.Tables[table] = count
+		if err != nil {
+			result.Errors[table] = err.Error()
+		}
+	}
+
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		logging.Warnf("Warning: Could not checkpoint rebuilt database: %v", err)
+	}
+	if err := Close(); err != nil {
+		return result, "", err
+	}
+	db = nil
+
+	moved, err := moveAside(dbPath)
+	if err != nil {
+		return result, "", err
+	}
+	if err := os.Rename(rebuiltPath, dbPath); err != nil {
+		return result, moved, fmt.Errorf("failed to move rebuilt database into place: %w", err)
+	}
+	return result, moved, nil
+}
+
+func tableNames(conn *sql.DB) ([]string, error) {
+	rows, err := conn.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+func columnNames(conn *sql.DB, table string) (map[string]bool, error) {
+	rows, err := conn.Query(fmt.Sprintf("PRAGMA table_info(%q)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// copyTable copies the rows of a table present in both databases, using the columns they share
+func copyTable(from, to *sql.DB, table string) (int, error) {
+	oldColumns, err := columnNames(from, table)
+	if err != nil {
+		return 0, err
+	}
+	if len(oldColumns) == 0 {
+		// Table doesn't exist in the old database
+		return 0, nil
+	}
+	newColumns, err := columnNames(to, table)
+	if err != nil {
+		return 0, err
+	}
+
+	var columns []string
+	for name := range newColumns {
+		if oldColumns[name] {
+			columns = append(columns, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(columns) == 0 {
+		return 0, nil
+	}
+	sort.Strings(columns)
+
+	list := strings.Join(columns, ", ")
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	rows, err := from.Query(fmt.Sprintf("SELECT %s FROM %q", list, table)) //nolint:gosec // Names from sqlite_master
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	insert := fmt.Sprintf("INSERT OR IGNORE INTO %q (%s) VALUES (%s)", table, list, placeholders) //nolint:gosec // Names from sqlite_master
+	count := 0
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		if _, err := to.Exec(insert, values...); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// moveAside renames the database and its WAL files to <db>.corrupt-<time> and returns the new path
+func moveAside(dbPath string) (string, error) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return "", nil
+	}
+	target := dbPath + ".corrupt-" + time.Now().Format(backupTimeFormat)
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, target+suffix); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to move %s aside: %w", dbPath+suffix, err)
+		}
+	}
+	return target, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src) //nolint:gosec // Backup path validated by caller
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck // Read-only
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close() //nolint:errcheck,gosec // Already failing
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestDatabase creates a database with one user and closes it
+func newTestDatabase(t *testing.T) string {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "ontree.db")
+	if err := Initialize(dbPath); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	if _, err := GetDB().Exec(`INSERT INTO users (username, password) VALUES ('admin', 'hash')`); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	return dbPath
+}
+
+func countUsers(t *testing.T, dbPath string) int {
+	t.Helper()
+	if err := Initialize(dbPath); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer Close() //nolint:errcheck // Test cleanup
+
+	var count int
+	if err := GetDB().QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	return count
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	dbPath := newTestDatabase(t)
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckIntegrity(dbPath); err != nil {
+		t.Errorf("CheckIntegrity() on healthy database error = %v", err)
+	}
+	if err := CheckIntegrity(filepath.Join(t.TempDir(), "missing.db")); err != nil {
+		t.Errorf("CheckIntegrity() on missing database error = %v", err)
+	}
+
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	if err := os.WriteFile(garbage, []byte("this is not an SQLite database at all, just some text"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckIntegrity(garbage); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dbPath := newTestDatabase(t)
+	if err := os.MkdirAll(BackupDir(dbPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"treeos-20000101-000000.db", "treeos-20000102-000000.db"} {
+		old := filepath.Join(BackupDir(dbPath), name)
+		if err := os.WriteFile(old, []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Date(2000, 1, i+1, 0, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(old, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target, err := Backup(dbPath, 2)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 2 || backups[0].Path != target || backups[1].Name != "treeos-20000102-000000.db" {
+		t.Fatalf("expected the new and the newest old backup, got %+v", backups)
+	}
+
+	moved, err := RestoreBackup(dbPath, backups[0].Name)
+	if err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	if _, err := os.Stat(moved); err != nil {
+		t.Errorf("expected the old database to be kept at %s: %v", moved, err)
+	}
+	if count := countUsers(t, dbPath); count != 1 {
+		t.Errorf("expected 1 user after restore, got %d", count)
+	}
+
+	if _, err := RestoreBackup(dbPath, backups[1].Name); err == nil {
+		t.Error("expected error restoring a corrupt backup")
+	}
+	if _, err := RestoreBackup(dbPath, "../ontree.db"); err == nil {
+		t.Error("expected error for a backup name outside the backup directory")
+	}
+}
+
+func TestScheduledRestore(t *testing.T) {
+	dbPath := newTestDatabase(t)
+	target, err := Backup(dbPath, 1)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if _, err := GetDB().Exec(`INSERT INTO users (username, password) VALUES ('later', 'hash')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ScheduleRestore(dbPath, "../ontree.db"); err == nil {
+		t.Error("expected error for a backup name outside the backup directory")
+	}
+	if err := ScheduleRestore(dbPath, filepath.Base(target)); err != nil {
+		t.Fatalf("ScheduleRestore() error = %v", err)
+	}
+
+	restored, moved, err := ApplyScheduledRestore(dbPath)
+	if err != nil {
+		t.Fatalf("ApplyScheduledRestore() error = %v", err)
+	}
+	if restored != filepath.Base(target) || moved == "" {
+		t.Errorf("ApplyScheduledRestore() = %q, %q", restored, moved)
+	}
+	if count := countUsers(t, dbPath); count != 1 {
+		t.Errorf("expected 1 user after restore, got %d", count)
+	}
+
+	if restored, _, err := ApplyScheduledRestore(dbPath); err != nil || restored != "" {
+		t.Errorf("expected nothing to restore the second time, got %q, %v", restored, err)
+	}
+}
+
+func TestDumpAndRebuild(t *testing.T) {
+	dbPath := newTestDatabase(t)
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, moved, err := DumpAndRebuild(dbPath)
+	if err != nil {
+		t.Fatalf("DumpAndRebuild() error = %v", err)
+	}
+	if result.Tables["users"] != 1 || len(result.Errors) != 0 {
+		t.Errorf("unexpected rebuild result %+v", result)
+	}
+	if moved == "" {
+		t.Error("expected the old database to be moved aside")
+	}
+	if count := countUsers(t, dbPath); count != 1 {
+		t.Errorf("expected 1 user after rebuild, got %d", count)
+	}
+}
+
+func TestStartFresh(t *testing.T) {
+	dbPath := newTestDatabase(t)
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := StartFresh(dbPath)
+	if err != nil {
+		t.Fatalf("StartFresh() error = %v", err)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("expected database to be moved away, got %v", err)
+	}
+	if _, err := os.Stat(moved); err != nil {
+		t.Errorf("expected the old database at %s: %v", moved, err)
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import "fmt"
+
+// Actions recorded in the security audit log
+const (
+	SecurityBypassEnabled  = "bypass_enabled"
+	SecurityBypassDisabled = "bypass_disabled"
+)
+
+// RecordSecurityAudit stores a change to the security validation of an app
+func RecordSecurityAudit(event SecurityAuditEvent) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO security_audit_log (app_name, username, action, justification)
+		VALUES (?, ?, ?, ?)
+	`, event.AppName, event.Username, event.Action, event.Justification)
+	if err != nil {
+		return fmt.Errorf("failed to record security audit event: %w", err)
+	}
+	return nil
+}
+
+// ListSecurityAudit returns the most recent security changes of an app, newest first
+func ListSecurityAudit(appName string, limit int) ([]SecurityAuditEvent, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, app_name, username, action, justification, created_at
+		FROM security_audit_log
+		WHERE app_name = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, appName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query security audit log: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	events := []SecurityAuditEvent{}
+	for rows.Next() {
+		var e SecurityAuditEvent
+		if err := rows.Scan(&e.ID, &e.AppName, &e.Username, &e.Action, &e.Justification, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan security audit event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,34 @@
+package database
+
+import "testing"
+
+func TestSecurityAudit(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	events := []SecurityAuditEvent{
+		{AppName: "homeassistant", Username: "admin", Action: SecurityBypassEnabled, Justification: "needs host network for discovery"},
+		{AppName: "homeassistant", Username: "admin", Action: SecurityBypassDisabled, Justification: "moved to macvlan"},
+		{AppName: "other", Username: "admin", Action: SecurityBypassEnabled, Justification: "usb access"},
+	}
+	for _, event := range events {
+		if err := RecordSecurityAudit(event); err != nil {
+			t.Fatalf("RecordSecurityAudit() error = %v", err)
+		}
+	}
+
+	got, err := ListSecurityAudit("homeassistant", 10)
+	if err != nil {
+		t.Fatalf("ListSecurityAudit() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ListSecurityAudit() returned %d events, want 2", len(got))
+	}
+	if got[0].Action != SecurityBypassDisabled || got[0].Justification != "moved to macvlan" {
+		t.Errorf("newest event = %+v", got[0])
+	}
+
+	if got, _ := ListSecurityAudit("homeassistant", 1); len(got) != 1 {
+		t.Errorf("limit not applied, got %d events", len(got))
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateSession stores a login session of the user by the hash of its ID
+func CreateSession(idHash string, userID int) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	now := time.Now().UTC()
+	_, err := db.Exec(`
+		INSERT INTO sessions (user_id, id_hash, created_at, rotated_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, idHash, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// SessionActive reports whether the hash belongs to a session of the user. The ID from
+// before the last rotation stays valid for grace, for requests sent with the old cookie.
+func SessionActive(idHash string, userID int, grace time.Duration) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	var current string
+	var rotatedAt time.Time
+	err := db.QueryRow(`
+		SELECT id_hash, rotated_at FROM sessions
+		WHERE user_id = ? AND (id_hash = ? OR previous_hash = ?)
+	`, userID, idHash, idHash).Scan(&current, &rotatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query session: %w", err)
+	}
+	return current == idHash || time.Since(rotatedAt) < grace, nil
+}
+
+// RotateSession replaces the ID of a session, keeping the old one as its previous ID. It
+// returns false if oldHash isn't the current ID of a session.
+func RotateSession(oldHash, newHash string) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		UPDATE sessions SET id_hash = ?, previous_hash = id_hash, rotated_at = ?
+		WHERE id_hash = ?
+	`, newHash, time.Now().UTC(), oldHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// DeleteSession ends the session with the hash as its current or previous ID
+func DeleteSession(idHash string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM sessions WHERE id_hash = ? OR previous_hash = ?`, idHash, idHash); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserSessions ends all sessions of the user
+func DeleteUserSessions(userID int) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteSessionsCreatedBefore removes the sessions that are past their absolute lifetime
+func DeleteSessionsCreatedBefore(cutoff time.Time) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM sessions WHERE created_at < ?`, cutoff.UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	if err := CreateSession("first", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSession("other", 2); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := SessionActive("first", 1, time.Minute); !active || err != nil {
+		t.Errorf("SessionActive() = %v, %v", active, err)
+	}
+	if active, err := SessionActive("first", 2, time.Minute); active || err != nil {
+		t.Errorf("SessionActive() of another user = %v, %v", active, err)
+	}
+
+	if rotated, err := RotateSession("first", "second"); !rotated || err != nil {
+		t.Fatalf("RotateSession() = %v, %v", rotated, err)
+	}
+	if rotated, err := RotateSession("first", "third"); rotated || err != nil {
+		t.Errorf("RotateSession() of the previous ID = %v, %v", rotated, err)
+	}
+	if active, err := SessionActive("second", 1, 0); !active || err != nil {
+		t.Errorf("SessionActive() of the new ID = %v, %v", active, err)
+	}
+	if active, err := SessionActive("first", 1, time.Minute); !active || err != nil {
+		t.Errorf("SessionActive() of the previous ID within the grace = %v, %v", active, err)
+	}
+	if active, err := SessionActive("first", 1, 0); active || err != nil {
+		t.Errorf("SessionActive() of the previous ID after the grace = %v, %v", active, err)
+	}
+
+	if err := DeleteSession("first"); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := SessionActive("second", 1, 0); active || err != nil {
+		t.Errorf("SessionActive() after DeleteSession() = %v, %v", active, err)
+	}
+
+	if err := CreateSession("fourth", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteUserSessions(2); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := SessionActive("fourth", 2, 0); active || err != nil {
+		t.Errorf("SessionActive() after DeleteUserSessions() = %v, %v", active, err)
+	}
+
+	if err := CreateSession("fifth", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteSessionsCreatedBefore(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := SessionActive("fifth", 1, 0); !active || err != nil {
+		t.Errorf("SessionActive() of a recent session after cleanup = %v, %v", active, err)
+	}
+	if err := DeleteSessionsCreatedBefore(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := SessionActive("fifth", 1, 0); active || err != nil {
+		t.Errorf("SessionActive() of an expired session after cleanup = %v, %v", active, err)
+	}
+}
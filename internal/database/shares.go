@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CreateShare stores a new share
+func CreateShare(share *Share) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO shares (name, app_name, sub_path, comment, read_only, valid_users)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, share.Name, share.AppName, share.SubPath, share.Comment, share.ReadOnly, strings.Join(share.ValidUsers, " "))
+	if err != nil {
+		return fmt.Errorf("failed to create share: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get share id: %w", err)
+	}
+	share.ID = int(id)
+	return nil
+}
+
+// DeleteShare removes a share
+func DeleteShare(id int) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`DELETE FROM shares WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+	return nil
+}
+
+// GetShares returns all shares ordered by name
+func GetShares() ([]Share, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, name, app_name, sub_path, comment, read_only, valid_users, created_at
+		FROM shares
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shares: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	shares := []Share{}
+	for rows.Next() {
+		var s Share
+		var validUsers string
+		if err := rows.Scan(&s.ID, &s.Name, &s.AppName, &s.SubPath, &s.Comment, &s.ReadOnly, &validUsers, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan share: %w", err)
+		}
+		s.ValidUsers = strings.Fields(validUsers)
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// templateCatalogColumns are the columns scanned by scanTemplateCatalog
+const templateCatalogColumns = `id, name, url, public_key, enabled, templates, last_synced_at, last_error, created_at`
+
+// scanTemplateCatalog reads a catalog from a row of templateCatalogColumns
+func scanTemplateCatalog(scan func(dest ...interface{}) error) (TemplateCatalog, error) {
+	var (
+		catalog    TemplateCatalog
+		lastSynced sql.NullTime
+	)
+	if err := scan(&catalog.ID, &catalog.Name, &catalog.URL, &catalog.PublicKey, &catalog.Enabled,
+		&catalog.Templates, &lastSynced, &catalog.LastError, &catalog.CreatedAt); err != nil {
+		return catalog, err
+	}
+	if lastSynced.Valid {
+		catalog.LastSyncedAt = &lastSynced.Time
+	}
+	return catalog, nil
+}
+
+// GetTemplateCatalogs returns all template catalogs, oldest first
+func GetTemplateCatalogs() ([]TemplateCatalog, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT ` + templateCatalogColumns + ` FROM template_catalogs ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template catalogs: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	catalogs := []TemplateCatalog{}
+	for rows.Next() {
+		catalog, err := scanTemplateCatalog(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template catalog: %w", err)
+		}
+		catalogs = append(catalogs, catalog)
+	}
+	return catalogs, rows.Err()
+}
+
+// GetTemplateCatalog returns a template catalog, nil if it does not exist
+func GetTemplateCatalog(id int) (*TemplateCatalog, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	catalog, err := scanTemplateCatalog(db.QueryRow(`SELECT `+templateCatalogColumns+` FROM template_catalogs WHERE id = ?`, id).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query template catalog: %w", err)
+	}
+	return &catalog, nil
+}
+
+// CreateTemplateCatalog stores a new template catalog and sets its ID and creation time
+func CreateTemplateCatalog(catalog *TemplateCatalog) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	catalog.CreatedAt = time.Now()
+	result, err := db.Exec(`
+		INSERT INTO template_catalogs (name, url, public_key, enabled, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, catalog.Name, catalog.URL, catalog.PublicKey, catalog.Enabled, catalog.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create template catalog: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get template catalog ID: %w", err)
+	}
+	catalog.ID = int(id)
+	return nil
+}
+
+// UpdateTemplateCatalog changes the URL, key and state of a catalog, reporting whether it
+// exists. The name can't change, it is part of the IDs of the catalog's templates.
+func UpdateTemplateCatalog(catalog *TemplateCatalog) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`UPDATE template_catalogs SET url = ?, public_key = ?, enabled = ? WHERE id = ?`,
+		catalog.URL, catalog.PublicKey, catalog.Enabled, catalog.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update template catalog: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update template catalog: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// DeleteTemplateCatalog removes a template catalog, reporting whether it existed
+func DeleteTemplateCatalog(id int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`DELETE FROM template_catalogs WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete template catalog: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete template catalog: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// RecordTemplateCatalogSync records the outcome of the latest sync of a catalog. A failed
+// sync, syncErr not "", keeps the template count of the last successful one.
+func RecordTemplateCatalogSync(id, templates int, syncErr string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var err error
+	if syncErr == "" {
+		_, err = db.Exec(`UPDATE template_catalogs SET templates = ?, last_synced_at = ?, last_error = '' WHERE id = ?`,
+			templates, time.Now(), id)
+	} else {
+		_, err = db.Exec(`UPDATE template_catalogs SET last_synced_at = ?, last_error = ? WHERE id = ?`,
+			time.Now(), syncErr, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record template catalog sync: %w", err)
+	}
+	return nil
+}
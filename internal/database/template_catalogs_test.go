@@ -0,0 +1,47 @@
+package database
+
+import "testing"
+
+func TestTemplateCatalogs(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	community := TemplateCatalog{Name: "community", URL: "https://apps.example.com/catalog.json", Enabled: true}
+	if err := CreateTemplateCatalog(&community); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateTemplateCatalog(&TemplateCatalog{Name: "community", URL: "https://other.example.com/catalog.json"}); err == nil {
+		t.Error("created two catalogs with the same name")
+	}
+
+	if err := RecordTemplateCatalogSync(community.ID, 12, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordTemplateCatalogSync(community.ID, 0, "catalog returned 404 Not Found"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetTemplateCatalog(community.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetTemplateCatalog = %v, %v", got, err)
+	}
+	if got.Templates != 12 || got.LastSyncedAt == nil || got.LastError != "catalog returned 404 Not Found" {
+		t.Errorf("catalog = %+v, want the count of the last successful sync and the error", got)
+	}
+
+	community.URL = "https://github.com/example/apps.git"
+	community.PublicKey = "key"
+	community.Enabled = false
+	if found, err := UpdateTemplateCatalog(&community); err != nil || !found {
+		t.Fatalf("UpdateTemplateCatalog = %v, %v", found, err)
+	}
+	if catalogs, _ := GetTemplateCatalogs(); len(catalogs) != 1 || catalogs[0].Enabled || catalogs[0].URL != community.URL || catalogs[0].PublicKey != "key" {
+		t.Errorf("catalogs = %+v", catalogs)
+	}
+
+	if deleted, err := DeleteTemplateCatalog(community.ID); err != nil || !deleted {
+		t.Fatalf("DeleteTemplateCatalog = %v, %v", deleted, err)
+	}
+	if missing, _ := GetTemplateCatalog(community.ID); missing != nil {
+		t.Errorf("deleted catalog = %+v", missing)
+	}
+}
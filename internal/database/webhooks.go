@@ -0,0 +1,169 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// webhookColumns are the columns scanned by scanWebhook
+const webhookColumns = `id, name, url, kind, events, enabled, last_sent_at, last_error, created_at`
+
+// scanWebhook reads a webhook from a row of webhookColumns
+func scanWebhook(scan func(dest ...interface{}) error) (Webhook, error) {
+	var (
+		hook     Webhook
+		events   string
+		lastSent sql.NullTime
+	)
+	if err := scan(&hook.ID, &hook.Name, &hook.URL, &hook.Kind, &events, &hook.Enabled, &lastSent, &hook.LastError, &hook.CreatedAt); err != nil {
+		return hook, err
+	}
+	hook.Events = []string{}
+	if events != "" {
+		hook.Events = strings.Split(events, ",")
+	}
+	if lastSent.Valid {
+		hook.LastSentAt = &lastSent.Time
+	}
+	return hook, nil
+}
+
+// GetWebhooks returns all webhooks, oldest first
+func GetWebhooks() ([]Webhook, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// GetWebhook returns a webhook, nil if it does not exist
+func GetWebhook(id int) (*Webhook, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	hook, err := scanWebhook(db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook: %w", err)
+	}
+	return &hook, nil
+}
+
+// GetWebhooksForEvent returns the enabled webhooks subscribed to an event type
+func GetWebhooksForEvent(eventType string) ([]Webhook, error) {
+	hooks, err := GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	subscribed := []Webhook{}
+	for _, hook := range hooks {
+		if !hook.Enabled {
+			continue
+		}
+		for _, event := range hook.Events {
+			if event == eventType {
+				subscribed = append(subscribed, hook)
+				break
+			}
+		}
+	}
+	return subscribed, nil
+}
+
+// CreateWebhook stores a new webhook and sets its ID and creation time
+func CreateWebhook(hook *Webhook) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	hook.CreatedAt = time.Now()
+	result, err := db.Exec(`
+		INSERT INTO webhooks (name, url, kind, events, enabled, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, hook.Name, hook.URL, hook.Kind, strings.Join(hook.Events, ","), hook.Enabled, hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get webhook ID: %w", err)
+	}
+	hook.ID = int(id)
+	return nil
+}
+
+// UpdateWebhook changes the settings of a webhook, reporting whether it exists
+func UpdateWebhook(hook *Webhook) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`UPDATE webhooks SET name = ?, url = ?, kind = ?, events = ?, enabled = ? WHERE id = ?`,
+		hook.Name, hook.URL, hook.Kind, strings.Join(hook.Events, ","), hook.Enabled, hook.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// DeleteWebhook removes a webhook, reporting whether it existed
+func DeleteWebhook(id int) (bool, error) {
+	db := GetDB()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// RecordWebhookDelivery records the outcome of the latest delivery to a webhook, "" for
+// a successful one
+func RecordWebhookDelivery(id int, deliveryErr string) error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec(`UPDATE webhooks SET last_sent_at = ?, last_error = ? WHERE id = ?`,
+		time.Now(), deliveryErr, id); err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
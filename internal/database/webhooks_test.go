@@ -0,0 +1,54 @@
+package database
+
+import "testing"
+
+func TestWebhooks(t *testing.T) {
+	newTestDatabase(t)
+	defer Close() //nolint:errcheck // Test cleanup
+
+	chat := Webhook{Name: "Chat", URL: "https://chat.example.com/hook", Kind: "slack", Events: []string{"app.start_failed", "disk.full"}, Enabled: true}
+	if err := CreateWebhook(&chat); err != nil {
+		t.Fatal(err)
+	}
+	phone := Webhook{Name: "Phone", URL: "https://ntfy.sh/node", Kind: "ntfy", Events: []string{"disk.full"}}
+	if err := CreateWebhook(&phone); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks, err := GetWebhooksForEvent("disk.full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].ID != chat.ID {
+		t.Errorf("disk.full hooks = %+v, want only the enabled one", hooks)
+	}
+
+	phone.Enabled = true
+	phone.Events = nil
+	if found, err := UpdateWebhook(&phone); err != nil || !found {
+		t.Fatalf("UpdateWebhook = %v, %v", found, err)
+	}
+	if err := RecordWebhookDelivery(phone.ID, "webhook returned 404 Not Found"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetWebhook(phone.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetWebhook = %v, %v", got, err)
+	}
+	if !got.Enabled || len(got.Events) != 0 || got.LastSentAt == nil || got.LastError != "webhook returned 404 Not Found" {
+		t.Errorf("webhook = %+v", got)
+	}
+
+	if deleted, err := DeleteWebhook(chat.ID); err != nil || !deleted {
+		t.Fatalf("DeleteWebhook = %v, %v", deleted, err)
+	}
+	if deleted, _ := DeleteWebhook(chat.ID); deleted {
+		t.Error("deleted a webhook twice")
+	}
+	if hooks, _ := GetWebhooks(); len(hooks) != 1 {
+		t.Errorf("webhooks = %+v", hooks)
+	}
+	if missing, _ := GetWebhook(chat.ID); missing != nil {
+		t.Errorf("deleted webhook = %+v", missing)
+	}
+}
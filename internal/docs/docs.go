@@ -0,0 +1,210 @@
+// Package docs serves the user documentation embedded in the binary, so nodes without internet
+// access still have it. Pages are the Markdown files of documentation/docs, rendered to HTML
+// once when they are loaded and searched in memory.
+package docs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Heading is a section of a page that can be linked to
+type Heading struct {
+	Level int    `json:"level"`
+	Title string `json:"title"`
+	ID    string `json:"id"`
+}
+
+// Page is one rendered page of the documentation
+type Page struct {
+	Path     string        // Below /docs, like "features/shares"
+	Title    string        // Title from the front matter or the first heading
+	Position int           // sidebar_position from the front matter
+	HTML     template.HTML // Rendered content, raw HTML of the source is escaped
+	Headings []Heading     // Second-level headings and below, for the table of contents
+	chunks   []*chunk
+}
+
+// URL returns the link to the page
+func (p *Page) URL() string {
+	return "/docs/" + p.Path
+}
+
+// TOC returns the second-level headings of the page
+func (p *Page) TOC() []Heading {
+	var toc []Heading
+	for _, h := range p.Headings {
+		if h.Level == 2 {
+			toc = append(toc, h)
+		}
+	}
+	return toc
+}
+
+// Section groups the pages of a directory, like the sidebar categories of the published docs
+type Section struct {
+	Label    string
+	Position int
+	Pages    []*Page
+}
+
+// Library is the loaded documentation
+type Library struct {
+	Sections []*Section
+	pages    map[string]*Page
+}
+
+// category is the _category_.json of a directory
+type category struct {
+	Label    string `json:"label"`
+	Position int    `json:"position"`
+}
+
+// Load reads and renders the Markdown pages and _category_.json files of a documentation tree
+func Load(fsys fs.FS) (*Library, error) {
+	lib := &Library{pages: make(map[string]*Page)}
+	sections := make(map[string]*Section)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".md" {
+			return err
+		}
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		page := parsePage(strings.TrimSuffix(name, ".md"), string(src))
+		lib.pages[page.Path] = page
+
+		dir := path.Dir(name)
+		section, ok := sections[dir]
+		if !ok {
+			section, err = loadSection(fsys, dir)
+			if err != nil {
+				return err
+			}
+			sections[dir] = section
+			lib.Sections = append(lib.Sections, section)
+		}
+		section.Pages = append(section.Pages, page)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load documentation: %w", err)
+	}
+	if len(lib.pages) == 0 {
+		return nil, errors.New("no documentation pages found")
+	}
+
+	sort.SliceStable(lib.Sections, func(i, j int) bool {
+		a, b := lib.Sections[i], lib.Sections[j]
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.Label < b.Label
+	})
+	for _, section := range lib.Sections {
+		sort.SliceStable(section.Pages, func(i, j int) bool {
+			a, b := section.Pages[i], section.Pages[j]
+			if a.Position != b.Position {
+				return a.Position < b.Position
+			}
+			return a.Title < b.Title
+		})
+	}
+	return lib, nil
+}
+
+// loadSection returns the section of a directory, with the label and position of its
+// _category_.json if there is one. Directories without one are listed after the others.
+func loadSection(fsys fs.FS, dir string) (*Section, error) {
+	if dir == "." {
+		return &Section{}, nil
+	}
+	section := &Section{Label: strings.ReplaceAll(path.Base(dir), "-", " "), Position: 100}
+	section.Label = strings.ToUpper(section.Label[:1]) + section.Label[1:]
+	data, err := fs.ReadFile(fsys, path.Join(dir, "_category_.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return section, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var c category
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid %s/_category_.json: %w", dir, err)
+	}
+	if c.Label != "" {
+		section.Label = c.Label
+	}
+	if c.Position != 0 {
+		section.Position = c.Position
+	}
+	return section, nil
+}
+
+// Render returns the HTML of Markdown that isn't a page of the documentation, e.g. a chat
+// reply. Raw HTML is escaped like in pages.
+func Render(src string) template.HTML {
+	return template.HTML(newRenderer("").render(src)) //nolint:gosec // The renderer escapes the source
+}
+
+// parsePage renders a Markdown page with its front matter
+func parsePage(pagePath, src string) *Page {
+	meta, body := frontMatter(src)
+	r := newRenderer(pagePath)
+	page := &Page{Path: pagePath, HTML: template.HTML(r.render(body))} //nolint:gosec // The renderer escapes the source
+	page.Headings = r.headings
+	page.chunks = r.chunks
+
+	page.Title = meta["title"]
+	if page.Title == "" {
+		page.Title = r.title
+	}
+	if page.Title == "" {
+		page.Title = path.Base(pagePath)
+	}
+	page.chunks[0].heading = Heading{Level: 1, Title: page.Title}
+	page.Position, _ = strconv.Atoi(meta["sidebar_position"]) //nolint:errcheck // Pages without one go first
+	return page
+}
+
+// frontMatter splits the YAML front matter of a page from its content. Only flat key: value
+// pairs are read, that's all the docs use.
+func frontMatter(src string) (map[string]string, string) {
+	meta := make(map[string]string)
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(src, "\ufeff"), "---\n")
+	if !ok {
+		return meta, src
+	}
+	header, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return meta, src
+	}
+	for _, line := range strings.Split(header, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			meta[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return meta, body
+}
+
+// Page returns the page at a path below /docs, nil if there is none
+func (l *Library) Page(pagePath string) *Page {
+	return l.pages[strings.Trim(pagePath, "/")]
+}
+
+// Home returns the page shown at /docs, the first page of the first section
+func (l *Library) Home() *Page {
+	for _, section := range l.Sections {
+		if len(section.Pages) > 0 {
+			return section.Pages[0]
+		}
+	}
+	return nil
+}
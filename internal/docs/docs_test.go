@@ -0,0 +1,159 @@
+package docs
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testLibrary(t *testing.T) *Library {
+	t.Helper()
+	lib, err := Load(fstest.MapFS{
+		"intro.md":                 {Data: []byte("---\nsidebar_position: 1\n---\n\n# Welcome\n\nSee [shares](features/shares.md#quotas) and [settings](/settings).\n")},
+		"features/_category_.json": {Data: []byte(`{"label": "Features", "position": 3}`)},
+		"features/shares.md": {Data: []byte(`---
+sidebar_position: 2
+---
+
+# Network Shares
+
+Shares export storage roots over **SMB**.
+
+## Quotas
+
+- Set with ` + "`quota_gb`" + `
+- Checked hourly
+  1. nested
+
+:::caution Careful
+Removing a share <script>alert(1)</script> keeps the files.
+:::
+
+| Field | Meaning |
+|-------|:-------:|
+| ` + "`a|b`" + ` | pipe in code |
+
+` + "```yaml\nquota_gb: 10 # <not html>\n```\n")},
+		"features/orphans.md": {Data: []byte("---\nsidebar_position: 1\n---\n\n# Orphans\n\nUnused volumes and [quotas](./shares.md#quotas).\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lib
+}
+
+func TestLoad(t *testing.T) {
+	lib := testLibrary(t)
+	if len(lib.Sections) != 2 || lib.Sections[0].Label != "" || lib.Sections[1].Label != "Features" {
+		t.Fatalf("sections = %+v", lib.Sections)
+	}
+	if pages := lib.Sections[1].Pages; pages[0].Title != "Orphans" || pages[1].Title != "Network Shares" {
+		t.Errorf("pages not sorted by sidebar_position: %s, %s", pages[0].Title, pages[1].Title)
+	}
+	if home := lib.Home(); home == nil || home.Path != "intro" {
+		t.Errorf("Home() = %+v, want intro", home)
+	}
+	if lib.Page("/features/shares/") == nil || lib.Page("features/missing") != nil {
+		t.Error("Page() lookup is wrong")
+	}
+}
+
+func TestRender(t *testing.T) {
+	lib := testLibrary(t)
+	html := string(lib.Page("features/shares").HTML)
+	for _, want := range []string{
+		`<h2 id="quotas">Quotas`,
+		`<strong>SMB</strong>`,
+		"<li>Set with <code>quota_gb</code></li>",
+		"<ol>\n<li>nested</li>",
+		`<div class="alert alert-warning docs-admonition"><p class="fw-semibold mb-1">Careful</p>`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		`<th class="text-center">Meaning</th>`,
+		"<td><code>a|b</code></td>",
+		`<code class="language-yaml">quota_gb: 10 # &lt;not html&gt;</code>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered page lacks %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Error("raw HTML was not escaped")
+	}
+
+	for page, want := range map[string]string{
+		"intro":            `<a href="/docs/features/shares#quotas">shares</a> and <a href="/settings">settings</a>`,
+		"features/orphans": `<a href="/docs/features/shares#quotas">quotas</a>`,
+	} {
+		if html := string(lib.Page(page).HTML); !strings.Contains(html, want) {
+			t.Errorf("%s links are not rewritten:\n%s", page, html)
+		}
+	}
+
+	if html := string(Render("**Hi** <b>there</b>, see [the docs](https://example.com)")); !strings.Contains(html, "<strong>Hi</strong> &lt;b&gt;there&lt;/b&gt;") ||
+		!strings.Contains(html, `<a href="https://example.com" target="_blank" rel="noopener">the docs</a>`) {
+		t.Errorf("Render() = %s", html)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	r := newRenderer("features/shares")
+	for target, want := range map[string]string{
+		"../reference/configuration.md#api_token": "/docs/reference/configuration#api_token",
+		"/docs/getting-started/first-app":         "/docs/getting-started/first-app",
+		"#quotas":                                 "#quotas",
+		"https://example.com/x":                   "https://example.com/x",
+		"javascript:alert(1)":                     "#",
+	} {
+		if got, _ := r.resolve(target); got != want {
+			t.Errorf("resolve(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	r := newRenderer("")
+	for _, tt := range []struct{ text, want string }{
+		{"4. Secret Files", "4-secret-files"},
+		{"api_token", "api_token"},
+		{"⚠ Breaking Changes", "-breaking-changes"},
+		{"4. Secret Files!", "4-secret-files-1"},
+	} {
+		if got := r.slug(tt.text); got != tt.want {
+			t.Errorf("slug(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	lib := testLibrary(t)
+	results := lib.Search("QUOTA hourly", 0)
+	if len(results) != 1 {
+		t.Fatalf("Search() = %+v, want the quota section", results)
+	}
+	if r := results[0]; r.URL != "/docs/features/shares#quotas" || r.Heading != "Quotas" || !strings.Contains(r.Snippet, "hourly") {
+		t.Errorf("result = %+v", r)
+	}
+
+	// Title matches rank first
+	results = lib.Search("orphans", 0)
+	if len(results) == 0 || results[0].URL != "/docs/features/orphans" || results[0].Heading != "" {
+		t.Errorf("Search(orphans) = %+v", results)
+	}
+	if len(lib.Search("quotas", 1)) != 1 {
+		t.Error("Search() ignored the limit")
+	}
+	if results := lib.Search("  ", 0); results != nil {
+		t.Errorf("empty query returned %+v", results)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("lorem ipsum ", 30) + "needle " + strings.Repeat("dolor sit ", 30)
+	got := snippet(text, text, []string{"needle"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "needle") {
+		t.Errorf("snippet() = %q", got)
+	}
+	if len(got) > snippetLength+2*len("…") {
+		t.Errorf("snippet() is %d bytes long", len(got))
+	}
+}
@@ -0,0 +1,576 @@
+package docs
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	headingRe     = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	headingIDRe   = regexp.MustCompile(`\s*\{#([\w-]+)\}$`)
+	fenceRe       = regexp.MustCompile("^(\\s*)(```+|~~~+)\\s*([\\w+#.-]*)")
+	listRe        = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+`)
+	ruleRe        = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	tableSepRe    = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	admonitionRe  = regexp.MustCompile(`^\s*:::(\w+)\s*(.*)$`)
+	tagRe         = regexp.MustCompile(`<[^>]*>`)
+	whitespaceRe  = regexp.MustCompile(`\s+`)
+	admonitionCSS = map[string]string{
+		"note": "info", "info": "info", "tip": "success",
+		"caution": "warning", "warning": "warning", "danger": "danger",
+	}
+)
+
+// renderer turns the Markdown of one page into HTML. It covers what the documentation uses:
+// headings, paragraphs, lists, tables, fenced code, block quotes and Docusaurus admonitions.
+// Raw HTML is escaped, so a page can't inject markup into the UI.
+type renderer struct {
+	page     string // Path of the page, relative links are resolved against it
+	title    string // Text of the first top-level heading
+	headings []Heading
+	ids      map[string]int
+	chunks   []*chunk
+}
+
+// chunk is the text below one heading, the unit search results point to
+type chunk struct {
+	heading Heading
+	text    strings.Builder
+}
+
+func newRenderer(page string) *renderer {
+	return &renderer{page: page, ids: make(map[string]int), chunks: []*chunk{{}}}
+}
+
+// render returns the HTML of a Markdown document
+func (r *renderer) render(src string) string {
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\t", "    ")
+	return r.blocks(strings.Split(src, "\n"), false)
+}
+
+// addText adds plain text to the search text of the current heading
+func (r *renderer) addText(text string) {
+	c := r.chunks[len(r.chunks)-1]
+	c.text.WriteString(text)
+	c.text.WriteByte(' ')
+}
+
+// blocks renders a sequence of block-level lines. In tight lists paragraphs are not wrapped
+// in <p>, like the items of a list without blank lines between them.
+func (r *renderer) blocks(lines []string, tight bool) string {
+	var out strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case fenceRe.MatchString(line):
+			i = r.code(lines, i, &out)
+		case admonitionRe.MatchString(line):
+			i = r.admonition(lines, i, &out)
+		case headingRe.MatchString(line):
+			r.heading(line, &out)
+			i++
+		case ruleRe.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(strings.TrimSpace(line), ">"):
+			i = r.blockquote(lines, i, &out)
+		case isTableStart(lines, i):
+			i = r.table(lines, i, &out)
+		case listRe.MatchString(line):
+			i = r.list(lines, i, &out)
+		default:
+			i = r.paragraph(lines, i, tight, &out)
+		}
+	}
+	return out.String()
+}
+
+// startsBlock reports whether a line interrupts a paragraph
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return strings.TrimSpace(line) == "" || fenceRe.MatchString(line) || admonitionRe.MatchString(line) ||
+		headingRe.MatchString(line) || strings.HasPrefix(strings.TrimSpace(line), ">") ||
+		listRe.MatchString(line) || isTableStart(lines, i)
+}
+
+func (r *renderer) paragraph(lines []string, i int, tight bool, out *strings.Builder) int {
+	start := i
+	for i++; i < len(lines) && !startsBlock(lines, i); i++ {
+		// A rule below a paragraph line would be a setext heading, the docs don't use them
+		if ruleRe.MatchString(lines[i]) {
+			break
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines[start:i], "\n"))
+	rendered := r.inline(text)
+	r.addText(plainText(rendered))
+	if tight {
+		out.WriteString(rendered)
+		out.WriteByte('\n')
+	} else {
+		fmt.Fprintf(out, "<p>%s</p>\n", rendered)
+	}
+	return i
+}
+
+func (r *renderer) heading(line string, out *strings.Builder) {
+	m := headingRe.FindStringSubmatch(line)
+	level, text := len(m[1]), m[2]
+	id := ""
+	if idm := headingIDRe.FindStringSubmatch(text); idm != nil {
+		id = idm[1]
+		text = strings.TrimSuffix(text, idm[0])
+	}
+	rendered := r.inline(text)
+	plain := plainText(rendered)
+	if id == "" {
+		id = r.slug(plain)
+	}
+	heading := Heading{Level: level, Title: plain, ID: id}
+
+	if level == 1 {
+		if r.title == "" {
+			r.title = plain
+		}
+		// The top of the page needs no anchor in search results
+		fmt.Fprintf(out, "<h1 id=\"%s\">%s</h1>\n", html.EscapeString(id), rendered)
+		r.addText(plain)
+		return
+	}
+	r.headings = append(r.headings, heading)
+	r.chunks = append(r.chunks, &chunk{heading: heading})
+	fmt.Fprintf(out, "<h%d id=\"%s\">%s <a class=\"docs-anchor\" href=\"#%s\" aria-label=\"Link to this section\">#</a></h%d>\n",
+		level, html.EscapeString(id), rendered, html.EscapeString(id), level)
+}
+
+// slug returns the anchor of a heading the way Docusaurus creates it, so links written for
+// the published docs work here too
+func (r *renderer) slug(text string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-' || c == '_':
+			b.WriteRune(c)
+		case c == ' ':
+			b.WriteByte('-')
+		}
+	}
+	id := b.String()
+	n := r.ids[id]
+	r.ids[id]++
+	if n > 0 {
+		return fmt.Sprintf("%s-%d", id, n)
+	}
+	return id
+}
+
+func (r *renderer) code(lines []string, i int, out *strings.Builder) int {
+	m := fenceRe.FindStringSubmatch(lines[i])
+	indent, marker, lang := len(m[1]), m[2], m[3]
+	var code []string
+	for i++; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, marker) && strings.Trim(trimmed, marker[:1]) == "" {
+			i++
+			break
+		}
+		line := lines[i]
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		code = append(code, line)
+	}
+	text := strings.Join(code, "\n")
+	r.addText(text)
+	if lang != "" {
+		fmt.Fprintf(out, "<pre class=\"docs-code\"><code class=\"language-%s\">%s</code></pre>\n", html.EscapeString(lang), html.EscapeString(text))
+	} else {
+		fmt.Fprintf(out, "<pre class=\"docs-code\"><code>%s</code></pre>\n", html.EscapeString(text))
+	}
+	return i
+}
+
+func (r *renderer) admonition(lines []string, i int, out *strings.Builder) int {
+	m := admonitionRe.FindStringSubmatch(lines[i])
+	kind, title := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+	if title == "" {
+		title = strings.ToUpper(kind[:1]) + kind[1:]
+	}
+	depth := 1
+	var inner []string
+	for i++; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == ":::" {
+			if depth--; depth == 0 {
+				i++
+				break
+			}
+		} else if admonitionRe.MatchString(trimmed) {
+			depth++
+		}
+		inner = append(inner, lines[i])
+	}
+	css, ok := admonitionCSS[kind]
+	if !ok {
+		css = "secondary"
+	}
+	fmt.Fprintf(out, "<div class=\"alert alert-%s docs-admonition\"><p class=\"fw-semibold mb-1\">%s</p>\n%s</div>\n",
+		css, r.inline(title), r.blocks(inner, false))
+	return i
+}
+
+func (r *renderer) blockquote(lines []string, i int, out *strings.Builder) int {
+	var inner []string
+	for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+		line := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+		inner = append(inner, strings.TrimPrefix(line, " "))
+	}
+	fmt.Fprintf(out, "<blockquote class=\"docs-quote\">\n%s</blockquote>\n", r.blocks(inner, false))
+	return i
+}
+
+// isTableStart reports whether a table starts at line i, a row followed by a separator row
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") &&
+		strings.Contains(lines[i+1], "-") && tableSepRe.MatchString(lines[i+1])
+}
+
+func (r *renderer) table(lines []string, i int, out *strings.Builder) int {
+	header := splitRow(lines[i])
+	var align []string
+	for _, sep := range splitRow(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(sep, ":") && strings.HasSuffix(sep, ":"):
+			align = append(align, " class=\"text-center\"")
+		case strings.HasSuffix(sep, ":"):
+			align = append(align, " class=\"text-end\"")
+		default:
+			align = append(align, "")
+		}
+	}
+	cell := func(tag string, n int, text string) {
+		a := ""
+		if n < len(align) {
+			a = align[n]
+		}
+		rendered := r.inline(text)
+		r.addText(plainText(rendered))
+		fmt.Fprintf(out, "<%s%s>%s</%s>", tag, a, rendered, tag)
+	}
+
+	out.WriteString("<div class=\"table-responsive\"><table class=\"table table-sm docs-table\">\n<thead><tr>")
+	for n, text := range header {
+		cell("th", n, text)
+	}
+	out.WriteString("</tr></thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+		out.WriteString("<tr>")
+		row := splitRow(lines[i])
+		for n := range header {
+			text := ""
+			if n < len(row) {
+				text = row[n]
+			}
+			cell("td", n, text)
+		}
+		out.WriteString("</tr>\n")
+	}
+	out.WriteString("</tbody></table></div>\n")
+	return i
+}
+
+// splitRow splits a table row into cells, pipes in code spans and escaped pipes stay
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func (r *renderer) list(lines []string, i int, out *strings.Builder) int {
+	m := listRe.FindStringSubmatch(lines[i])
+	base := len(m[1])
+	ordered := isOrdered(m[2])
+	start := 1
+	if ordered {
+		start, _ = strconv.Atoi(strings.TrimRight(m[2], ".)")) //nolint:errcheck // Digits by the pattern
+	}
+
+	var items [][]string
+	offset := 0 // Indentation of the content of the current item
+	loose, blank := false, false
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if m := listRe.FindStringSubmatch(line); m != nil && indent <= base+1 {
+			if isOrdered(m[2]) != ordered {
+				break
+			}
+			loose = loose || (blank && len(items) > 0)
+			items = append(items, []string{line[len(m[0]):]})
+			offset, blank = len(m[0]), false
+			continue
+		}
+		last := len(items) - 1
+		switch {
+		case indent > base:
+			// Continuation or nested block, relative to the item content
+			if blank {
+				items[last] = append(items[last], "")
+			}
+			for n := 0; n < offset && strings.HasPrefix(line, " "); n++ {
+				line = line[1:]
+			}
+			items[last] = append(items[last], line)
+		case !blank && !startsBlock(lines, i):
+			// Lazy continuation of the last paragraph
+			items[last] = append(items[last], strings.TrimSpace(line))
+		default:
+			return r.writeList(items, ordered, start, loose, out, i)
+		}
+		blank = false
+	}
+	return r.writeList(items, ordered, start, loose, out, i)
+}
+
+func (r *renderer) writeList(items [][]string, ordered bool, start int, loose bool, out *strings.Builder, i int) int {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	if ordered && start != 1 {
+		fmt.Fprintf(out, "<ol start=\"%d\">\n", start)
+	} else {
+		fmt.Fprintf(out, "<%s>\n", tag)
+	}
+	for _, item := range items {
+		fmt.Fprintf(out, "<li>%s</li>\n", strings.TrimSuffix(r.blocks(item, !loose), "\n"))
+	}
+	fmt.Fprintf(out, "</%s>\n", tag)
+	return i
+}
+
+func isOrdered(marker string) bool {
+	return marker[0] >= '0' && marker[0] <= '9'
+}
+
+// inline renders the inline Markdown of a paragraph, heading or table cell: code spans,
+// emphasis, links and images
+func (r *renderer) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_{}[]()#+-.!|<>", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			if n, code, ok := codeSpan(s[i:]); ok {
+				fmt.Fprintf(&b, "<code>%s</code>", html.EscapeString(code))
+				i += n
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**") || strings.HasPrefix(s[i:], "__"):
+			if end := strings.Index(s[i+2:], s[i:i+2]); end > 0 && (c == '*' || boundary(s, i, i+4+end)) {
+				fmt.Fprintf(&b, "<strong>%s</strong>", r.inline(s[i+2:i+2+end]))
+				i += end + 4
+				continue
+			}
+		case c == '*' || c == '_':
+			if end := strings.IndexByte(s[i+1:], c); end > 0 && s[i+1] != ' ' && s[i+end] != ' ' && (c == '*' || boundary(s, i, i+2+end)) {
+				fmt.Fprintf(&b, "<em>%s</em>", r.inline(s[i+1:i+1+end]))
+				i += end + 2
+				continue
+			}
+		case c == '[' || (c == '!' && strings.HasPrefix(s[i:], "![")):
+			if n, link, ok := r.link(s[i:]); ok {
+				b.WriteString(link)
+				i += n
+				continue
+			}
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				if target := s[i+1 : i+end]; strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://") {
+					fmt.Fprintf(&b, "<a href=\"%s\" target=\"_blank\" rel=\"noopener\">%s</a>", html.EscapeString(target), html.EscapeString(target))
+					i += end + 1
+					continue
+				}
+			}
+		case c == '\n':
+			b.WriteByte(' ')
+			i++
+			continue
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// boundary reports whether underscore emphasis from start to end stands on its own, so
+// names like api_token_file stay as they are
+func boundary(s string, start, end int) bool {
+	isWord := func(c byte) bool {
+		return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	return (start == 0 || !isWord(s[start-1])) && (end >= len(s) || !isWord(s[end]))
+}
+
+// codeSpan parses a code span at the start of s and returns its length and content
+func codeSpan(s string) (int, string, bool) {
+	ticks := len(s) - len(strings.TrimLeft(s, "`"))
+	for pos := ticks; pos < len(s); {
+		if s[pos] != '`' {
+			pos++
+			continue
+		}
+		// The closing run of backticks must be as long as the opening one
+		run := len(s[pos:]) - len(strings.TrimLeft(s[pos:], "`"))
+		if run != ticks {
+			pos += run
+			continue
+		}
+		code := strings.ReplaceAll(s[ticks:pos], "\n", " ")
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+			code = code[1 : len(code)-1]
+		}
+		return pos + run, code, true
+	}
+	return 0, "", false
+}
+
+// link parses a link or image at the start of s and returns its length and HTML
+func (r *renderer) link(s string) (int, string, bool) {
+	image := s[0] == '!'
+	open := 0
+	if image {
+		open = 1
+	}
+	// Find the closing bracket, skipping nested ones and code spans
+	depth, closing := 0, -1
+	for i := open; i < len(s) && closing < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			if n, _, ok := codeSpan(s[i:]); ok {
+				i += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(s) || s[closing+1] != '(' {
+		return 0, "", false
+	}
+	depth, end := 0, -1
+	for i := closing + 1; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return 0, "", false
+	}
+
+	text := s[open+1 : closing]
+	target := strings.TrimSpace(s[closing+2 : end])
+	// Drop a title after the target
+	if sp := strings.IndexAny(target, " \t"); sp > 0 {
+		target = target[:sp]
+	}
+	href, external := r.resolve(strings.Trim(target, "<>"))
+	if image {
+		return end + 1, fmt.Sprintf("<img src=\"%s\" alt=\"%s\" class=\"img-fluid\">", html.EscapeString(href), html.EscapeString(text)), true
+	}
+	attrs := ""
+	if external {
+		attrs = " target=\"_blank\" rel=\"noopener\""
+	}
+	return end + 1, fmt.Sprintf("<a href=\"%s\"%s>%s</a>", html.EscapeString(href), attrs, r.inline(text)), true
+}
+
+// resolve turns a link target of the Markdown into a link of the docs handler. Links to other
+// pages like ../reference/configuration.md#api_token point to /docs/reference/configuration.
+func (r *renderer) resolve(target string) (href string, external bool) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "#", false
+	}
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "mailto":
+		return u.String(), true
+	case u.Scheme != "" || u.Host != "":
+		// No javascript: links or links relying on the current scheme
+		return "#", false
+	case u.Path == "":
+		return target, false
+	}
+
+	p := u.Path
+	switch {
+	case p == "/docs" || strings.HasPrefix(p, "/docs/"):
+		p = strings.TrimPrefix(strings.TrimPrefix(p, "/docs"), "/")
+	case strings.HasPrefix(p, "/"):
+		// A page of the UI, like /settings
+		return target, false
+	default:
+		p = path.Join(path.Dir(r.page), p)
+	}
+	p = strings.TrimSuffix(strings.TrimSuffix(p, ".md"), "/")
+	href = "/docs"
+	if p != "" && p != "." {
+		href += "/" + p
+	}
+	if u.Fragment != "" {
+		href += "#" + u.Fragment
+	}
+	return href, false
+}
+
+// plainText strips the markup of rendered inline HTML
+func plainText(rendered string) string {
+	return strings.TrimSpace(whitespaceRe.ReplaceAllString(html.UnescapeString(tagRe.ReplaceAllString(rendered, "")), " "))
+}
@@ -0,0 +1,118 @@
+package docs
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetLength is the number of bytes of text around the first match shown with a result
+const snippetLength = 160
+
+// Result is a section of a page that matches a search
+type Result struct {
+	URL     string `json:"url"`               // Link to the page or the heading, like /docs/features/shares#quotas
+	Title   string `json:"title"`             // Title of the page
+	Heading string `json:"heading,omitempty"` // Heading of the section, empty for the top of the page
+	Snippet string `json:"snippet"`           // Text around the first match
+	score   int
+}
+
+// Search returns the sections of all pages that contain every word of the query, best
+// matches first. Words in the page title count most, then words in the heading.
+func (l *Library) Search(query string, limit int) []Result {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []Result
+	for _, page := range l.pages {
+		title := strings.ToLower(page.Title)
+		for _, c := range page.chunks {
+			text := c.text.String()
+			lower := strings.ToLower(text)
+			heading := strings.ToLower(c.heading.Title)
+			score := 0
+			for _, term := range terms {
+				inTitle, inHeading, count := strings.Contains(title, term), strings.Contains(heading, term), strings.Count(lower, term)
+				if !inTitle && !inHeading && count == 0 {
+					score = 0
+					break
+				}
+				if inTitle {
+					score += 10
+				}
+				if inHeading && c.heading.ID != "" {
+					score += 5
+				}
+				score += min(count, 5)
+			}
+			if score == 0 {
+				continue
+			}
+
+			result := Result{URL: page.URL(), Title: page.Title, Snippet: snippet(text, lower, terms), score: score}
+			if c.heading.ID != "" {
+				result.URL += "#" + c.heading.ID
+				result.Heading = c.heading.Title
+			}
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].URL < results[j].URL
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// snippet returns the text around the first match of any term
+func snippet(text, lower string, terms []string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	lower = strings.Join(strings.Fields(lower), " ")
+	start := 0
+	// Lowercasing can change the length of some characters, then the snippet starts at the top
+	if len(lower) == len(text) {
+		first := -1
+		for _, term := range terms {
+			if i := strings.Index(lower, term); i >= 0 && (first < 0 || i < first) {
+				first = i
+			}
+		}
+		start = max(first-snippetLength/3, 0)
+	}
+	end := min(start+snippetLength, len(text))
+	// Cut at spaces, not within words or characters
+	if start > 0 {
+		if i := strings.IndexByte(text[start:end], ' '); i >= 0 {
+			start += i + 1
+		}
+	}
+	if end < len(text) {
+		if i := strings.LastIndexByte(text[start:end], ' '); i > 0 {
+			end = start + i
+		}
+	}
+	for start < end && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	result := text[start:end]
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(text) {
+		result += "…"
+	}
+	return result
+}
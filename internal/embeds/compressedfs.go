@@ -0,0 +1,197 @@
+package embeds
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotliExt marks files compressed at build time by cmd/compress-assets
+const brotliExt = ".br"
+
+// brotliFS undoes the build-time compression of embedded files. A file "app.css" embedded
+// as "app.css.br" is opened and listed as "app.css" and decompressed when it is opened.
+// Files that were not compressed are passed through, and an uncompressed copy wins over a
+// compressed one, so assets copied in during development override stale ones.
+type brotliFS struct {
+	fsys fs.FS
+}
+
+func (b brotliFS) Open(name string) (fs.File, error) {
+	f, err := b.fsys.Open(name)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return f, err
+	}
+
+	compressed, err := fs.ReadFile(b.fsys, name+brotliExt)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	data, err := unbrotli(compressed)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &memFile{Reader: bytes.NewReader(data), info: fileInfo{name: path.Base(name), size: int64(len(data))}}, nil
+}
+
+// ReadDir lists compressed files under their original names
+func (b brotliFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(b.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	result := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		original, compressed := strings.CutSuffix(entry.Name(), brotliExt)
+		switch {
+		case !compressed || entry.IsDir():
+			result = append(result, entry)
+		case names[original]:
+			// Shadowed by the uncompressed copy
+		default:
+			result = append(result, fs.FileInfoToDirEntry(fileInfo{name: original}))
+		}
+	}
+	return result, nil
+}
+
+func unbrotli(data []byte) ([]byte, error) {
+	data, err := io.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed asset: %w", err)
+	}
+	return data, nil
+}
+
+// memFile is a decompressed file
+type memFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// fileInfo describes a decompressed file. Embedded files have no modification time.
+type fileInfo struct {
+	name string
+	size int64
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return 0444 }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return false }
+func (i fileInfo) Sys() interface{}   { return nil }
+
+// StaticHandler serves the static files. Files compressed at build time are sent as they are
+// to clients that accept Brotli and gzipped to clients that only accept gzip. Other clients
+// get them decompressed.
+func StaticHandler() (http.Handler, error) {
+	rawStatic, err := fs.Sub(raw, "static")
+	if err != nil {
+		return nil, err
+	}
+	return compressedHandler(rawStatic), nil
+}
+
+// compressedHandler serves the files of a file system compressed by cmd/compress-assets.
+// The gzip copy of a file is made on its first request and kept in memory.
+func compressedHandler(rawStatic fs.FS) http.Handler {
+	fallback := http.FileServer(http.FS(brotliFS{rawStatic}))
+	var gzipped sync.Map // name -> []byte
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if _, err := fs.Stat(rawStatic, name); err == nil {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		compressed, err := fs.ReadFile(rawStatic, name+brotliExt)
+		if err != nil {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		acceptEncoding := r.Header.Get("Accept-Encoding")
+		w.Header().Add("Vary", "Accept-Encoding")
+		var encoding string
+		switch {
+		case acceptsEncoding(acceptEncoding, "br"):
+			encoding = "br"
+		case acceptsEncoding(acceptEncoding, "gzip"):
+			encoding = "gzip"
+			if cached, ok := gzipped.Load(name); ok {
+				compressed = cached.([]byte)
+				break
+			}
+			if compressed, err = regzip(compressed); err != nil {
+				http.Error(w, "Failed to compress file", http.StatusInternalServerError)
+				return
+			}
+			gzipped.Store(name, compressed)
+		default:
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Encoding", encoding)
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(compressed))
+	})
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows a content coding.
+// "*" doesn't count, a client that names no coding gets the file decompressed.
+func acceptsEncoding(header, coding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+		return !found || strings.Trim(q, "0.") != ""
+	}
+	return false
+}
+
+// regzip turns a Brotli-compressed file into a gzipped one
+func regzip(compressed []byte) ([]byte, error) {
+	data, err := unbrotli(compressed)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
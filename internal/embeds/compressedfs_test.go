@@ -0,0 +1,147 @@
+package embeds
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	bw := brotli.NewWriter(&buf)
+	if _, err := bw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testFS(t *testing.T) fstest.MapFS {
+	return fstest.MapFS{
+		"css/app.css.br":    {Data: compress(t, "body { color: green; }")},
+		"css/plain.css":     {Data: []byte("p {}")},
+		"css/shadow.css":    {Data: []byte("new")},
+		"css/shadow.css.br": {Data: compress(t, "old")},
+	}
+}
+
+func TestBrotliFS(t *testing.T) {
+	fsys := brotliFS{testFS(t)}
+
+	data, err := fs.ReadFile(fsys, "css/app.css")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "body { color: green; }" {
+		t.Errorf("ReadFile() = %q", data)
+	}
+	if data, _ := fs.ReadFile(fsys, "css/shadow.css"); string(data) != "new" {
+		t.Errorf("uncompressed copy should win, got %q", data)
+	}
+	if _, err := fs.ReadFile(fsys, "css/missing.css"); err == nil {
+		t.Error("ReadFile() of a missing file should fail")
+	}
+
+	entries, err := fs.ReadDir(fsys, "css")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{"app.css", "plain.css", "shadow.css"}
+	if len(names) != len(want) {
+		t.Fatalf("ReadDir() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("ReadDir() = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestCompressedHandler(t *testing.T) {
+	handler := compressedHandler(testFS(t))
+
+	get := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/css/app.css", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("client accepts brotli", func(t *testing.T) {
+		rec := get("gzip, deflate, br")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "br" {
+			t.Fatalf("status = %d, encoding = %q", rec.Code, rec.Header().Get("Content-Encoding"))
+		}
+		data, _ := io.ReadAll(brotli.NewReader(rec.Body))
+		if string(data) != "body { color: green; }" {
+			t.Errorf("body = %q", data)
+		}
+	})
+
+	for _, acceptEncoding := range []string{"gzip, deflate", "gzip, br;q=0"} {
+		t.Run("client accepts "+acceptEncoding, func(t *testing.T) {
+			// The second request is served from the cached gzip copy
+			for i := 0; i < 2; i++ {
+				rec := get(acceptEncoding)
+				if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" {
+					t.Fatalf("status = %d, encoding = %q", rec.Code, rec.Header().Get("Content-Encoding"))
+				}
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(zr)
+				if string(data) != "body { color: green; }" {
+					t.Errorf("body = %q", data)
+				}
+			}
+		})
+	}
+
+	t.Run("client without compression", func(t *testing.T) {
+		rec := get("")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("status = %d, encoding = %q", rec.Code, rec.Header().Get("Content-Encoding"))
+		}
+		if rec.Body.String() != "body { color: green; }" {
+			t.Errorf("body = %q", rec.Body.String())
+		}
+	})
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header, coding string
+		want           bool
+	}{
+		{"gzip, deflate, br", "br", true},
+		{"gzip, deflate", "br", false},
+		{"br;q=0.5, gzip", "br", true},
+		{"br;q=0, gzip", "br", false},
+		{"br; q=0.0", "br", false},
+		{"GZIP", "gzip", true},
+		{"*", "gzip", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.header, tt.coding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", tt.header, tt.coding, got, tt.want)
+		}
+	}
+}
@@ -2,14 +2,13 @@
 package embeds
 
 import (
-	"embed"
 	"html/template"
 	"io/fs"
 	"strings"
 )
 
-//go:embed static templates templates/dashboard/_*.html app-templates
-var content embed.FS
+// content is the embedded files with build-time compression undone, see brotliFS
+var content = brotliFS{raw}
 
 // StaticFS returns the embedded static files
 func StaticFS() (fs.FS, error) {
@@ -26,6 +25,11 @@ func AppTemplateFS() (fs.FS, error) {
 	return fs.Sub(content, "app-templates")
 }
 
+// DocsFS returns the embedded user documentation, the Markdown files of documentation/docs
+func DocsFS() (fs.FS, error) {
+	return fs.Sub(content, "docs")
+}
+
 // ParseTemplate parses templates from the embedded filesystem with custom functions
 func ParseTemplate(patterns ...string) (*template.Template, error) {
 	// Define custom template functions
@@ -0,0 +1,16 @@
+//go:build agent
+
+package embeds
+
+import "embed"
+
+// Slim reports whether the binary was built with the slim build tag. Agent binaries leave
+// out the pattern library like slim ones.
+const Slim = true
+
+// Agent reports whether the binary was built with the agent build tag. Agent binaries only
+// run in agent mode and embed no web UI, page templates or app templates.
+const Agent = true
+
+// raw is empty, agent nodes serve no pages
+var raw embed.FS
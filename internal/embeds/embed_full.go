@@ -0,0 +1,14 @@
+//go:build !slim && !agent
+
+package embeds
+
+import "embed"
+
+// Slim reports whether the binary was built with the slim build tag
+const Slim = false
+
+// Agent reports whether the binary was built with the agent build tag
+const Agent = false
+
+//go:embed static templates templates/dashboard/_* app-templates all:docs
+var raw embed.FS
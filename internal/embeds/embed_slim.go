@@ -0,0 +1,16 @@
+//go:build slim && !agent
+
+package embeds
+
+import "embed"
+
+// Slim reports whether the binary was built with the slim build tag. Slim binaries leave out
+// the pattern library and the sample compose files in templates/compose to make update
+// downloads smaller.
+const Slim = true
+
+// Agent reports whether the binary was built with the agent build tag
+const Agent = false
+
+//go:embed static templates/components templates/dashboard templates/dashboard/_* templates/layouts templates/partials app-templates all:docs
+var raw embed.FS
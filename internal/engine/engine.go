@@ -0,0 +1,198 @@
+// Package engine picks the container engine TreeOS drives, Docker or Podman. Both are used
+// through the Docker API and a docker compatible command line with compose, so apps have the
+// same lifecycle on either. Rootless Podman runs the apps as the user TreeOS runs as.
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Modes of the container_runtime setting
+const (
+	ModeAuto   = "auto" // DOCKER_HOST, then rootless Podman, then Docker, then rootful Podman
+	ModeDocker = "docker"
+	ModePodman = "podman"
+)
+
+// Sockets the engines listen on by default
+const (
+	dockerSocket        = "/var/run/docker.sock"
+	rootfulPodmanSocket = "/run/podman/podman.sock"
+)
+
+// Engine is the container engine of the node
+type Engine struct {
+	Name     string // ModeDocker or ModePodman
+	Host     string // API endpoint, as in DOCKER_HOST
+	CLI      string // Command line tool with compose, docker or podman
+	Rootless bool   // Podman running as a regular user
+}
+
+// Host is what the selection looks at, the real host in Detect
+type Host struct {
+	Getenv func(string) string
+	// Socket returns the path a socket resolves to, podman-docker links the Docker socket
+	// to Podman's. ok is false if there is no socket at path.
+	Socket   func(path string) (resolved string, ok bool)
+	LookPath func(file string) bool
+	UID      int
+}
+
+// ValidMode reports whether mode is a value of the container_runtime setting
+func ValidMode(mode string) bool {
+	return mode == ModeAuto || mode == ModeDocker || mode == ModePodman
+}
+
+// Select returns the engine for a mode on a host. DOCKER_HOST is used as set, in auto mode
+// it also decides the engine. A regular user with the Podman socket enabled gets rootless
+// Podman in auto mode, even if Docker is installed too. An empty mode is auto.
+func Select(mode string, host Host) (Engine, error) {
+	if mode == "" {
+		mode = ModeAuto
+	}
+	if dockerHost := host.Getenv("DOCKER_HOST"); dockerHost != "" {
+		name := ModeDocker
+		if mode == ModePodman || (mode == ModeAuto && isPodmanHost(dockerHost)) {
+			name = ModePodman
+		}
+		return newEngine(name, dockerHost, host), nil
+	}
+
+	rootless := rootlessPodmanSocket(host)
+	if mode == ModeAuto && rootless != "" {
+		if _, ok := host.Socket(rootless); ok {
+			return newEngine(ModePodman, "unix://"+rootless, host), nil
+		}
+	}
+	if mode != ModePodman {
+		sockets := []string{dockerSocket}
+		if dir := host.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			sockets = append(sockets, filepath.Join(dir, "docker.sock")) // Rootless Docker
+		}
+		if home := host.Getenv("HOME"); home != "" {
+			sockets = append(sockets, filepath.Join(home, ".docker", "run", "docker.sock")) // Docker Desktop
+		}
+		for _, socket := range sockets {
+			resolved, ok := host.Socket(socket)
+			if !ok {
+				continue
+			}
+			// With podman-docker the Docker socket is Podman's
+			if mode == ModeAuto && isPodmanHost(resolved) {
+				return newEngine(ModePodman, "unix://"+socket, host), nil
+			}
+			return newEngine(ModeDocker, "unix://"+socket, host), nil
+		}
+		if mode == ModeDocker {
+			return Engine{}, errors.New("no Docker socket found, is the Docker daemon running?")
+		}
+	}
+
+	for _, socket := range []string{rootless, rootfulPodmanSocket} {
+		if socket == "" {
+			continue
+		}
+		if _, ok := host.Socket(socket); ok {
+			return newEngine(ModePodman, "unix://"+socket, host), nil
+		}
+	}
+	if mode == ModePodman {
+		if host.UID != 0 {
+			return Engine{}, errors.New("no Podman socket found, enable it with: systemctl --user enable --now podman.socket")
+		}
+		return Engine{}, errors.New("no Podman socket found, enable it with: systemctl enable --now podman.socket")
+	}
+	return Engine{}, errors.New("no Docker or Podman socket found")
+}
+
+// Detect returns the engine for a mode on this node
+func Detect(mode string) (Engine, error) {
+	return Select(mode, Host{
+		Getenv: os.Getenv,
+		Socket: func(path string) (string, bool) {
+			info, err := os.Stat(path)
+			if err != nil || info.Mode()&os.ModeSocket == 0 {
+				return "", false
+			}
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return path, true
+			}
+			return resolved, true
+		},
+		LookPath: func(file string) bool {
+			_, err := exec.LookPath(file)
+			return err == nil
+		},
+		UID: os.Geteuid(),
+	})
+}
+
+// Apply points the Docker API client, compose and the command line calls of TreeOS at the
+// engine, through DOCKER_HOST and DOCKER_BINARY. DOCKER_BINARY in the environment takes
+// precedence.
+func (e Engine) Apply() error {
+	if err := os.Setenv("DOCKER_HOST", e.Host); err != nil {
+		return err
+	}
+	if os.Getenv("DOCKER_BINARY") != "" {
+		return nil
+	}
+	return os.Setenv("DOCKER_BINARY", e.CLI)
+}
+
+// String describes the engine for the log and the settings page
+func (e Engine) String() string {
+	name := "Docker"
+	if e.Name == ModePodman {
+		name = "Podman"
+		if e.Rootless {
+			name = "rootless Podman"
+		}
+	}
+	return fmt.Sprintf("%s at %s", name, e.Host)
+}
+
+// CLI returns the command line tool of the applied engine, docker if none was applied
+func CLI() string {
+	if binary := os.Getenv("DOCKER_BINARY"); binary != "" {
+		return binary
+	}
+	return "docker"
+}
+
+// newEngine returns the engine of a name at an API endpoint. Podman is driven with its own
+// command line if it is installed, podman-docker provides docker otherwise.
+func newEngine(name, apiHost string, host Host) Engine {
+	e := Engine{Name: name, Host: apiHost, CLI: "docker"}
+	if name == ModePodman {
+		if host.LookPath("podman") {
+			e.CLI = "podman"
+		}
+		e.Rootless = host.UID != 0 && apiHost != "unix://"+rootfulPodmanSocket
+	}
+	return e
+}
+
+// rootlessPodmanSocket returns the socket of the user's Podman service, empty for root
+func rootlessPodmanSocket(host Host) string {
+	if host.UID == 0 {
+		return ""
+	}
+	dir := host.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join("/run/user", strconv.Itoa(host.UID))
+	}
+	return filepath.Join(dir, "podman", "podman.sock")
+}
+
+// isPodmanHost reports whether an API endpoint or socket path is Podman's
+func isPodmanHost(host string) bool {
+	return strings.Contains(host, "podman")
+}
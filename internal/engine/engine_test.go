@@ -0,0 +1,122 @@
+package engine
+
+import "testing"
+
+func TestSelect(t *testing.T) {
+	const userPodman = "/run/user/1000/podman/podman.sock"
+	tests := []struct {
+		name    string
+		mode    string
+		env     map[string]string
+		sockets map[string]string // Socket path to the path it resolves to
+		uid     int
+		want    Engine
+		wantErr bool
+	}{
+		{
+			name:    "docker",
+			mode:    ModeAuto,
+			sockets: map[string]string{dockerSocket: dockerSocket},
+			want:    Engine{Name: ModeDocker, Host: "unix:///var/run/docker.sock", CLI: "docker"},
+		},
+		{
+			name:    "rootless podman before docker",
+			mode:    ModeAuto,
+			sockets: map[string]string{dockerSocket: dockerSocket, userPodman: userPodman},
+			uid:     1000,
+			want:    Engine{Name: ModePodman, Host: "unix://" + userPodman, CLI: "podman", Rootless: true},
+		},
+		{
+			name:    "docker forced",
+			mode:    ModeDocker,
+			sockets: map[string]string{dockerSocket: dockerSocket, userPodman: userPodman},
+			uid:     1000,
+			want:    Engine{Name: ModeDocker, Host: "unix:///var/run/docker.sock", CLI: "docker"},
+		},
+		{
+			name:    "podman-docker",
+			mode:    ModeAuto,
+			sockets: map[string]string{dockerSocket: rootfulPodmanSocket},
+			want:    Engine{Name: ModePodman, Host: "unix:///var/run/docker.sock", CLI: "podman"},
+		},
+		{
+			name:    "rootful podman",
+			mode:    ModePodman,
+			sockets: map[string]string{dockerSocket: dockerSocket, rootfulPodmanSocket: rootfulPodmanSocket},
+			want:    Engine{Name: ModePodman, Host: "unix:///run/podman/podman.sock", CLI: "podman"},
+		},
+		{
+			name:    "XDG_RUNTIME_DIR",
+			mode:    ModePodman,
+			env:     map[string]string{"XDG_RUNTIME_DIR": "/tmp/run"},
+			sockets: map[string]string{"/tmp/run/podman/podman.sock": "/tmp/run/podman/podman.sock"},
+			uid:     1000,
+			want:    Engine{Name: ModePodman, Host: "unix:///tmp/run/podman/podman.sock", CLI: "podman", Rootless: true},
+		},
+		{
+			name: "DOCKER_HOST",
+			mode: ModeAuto,
+			env:  map[string]string{"DOCKER_HOST": "unix://" + userPodman},
+			uid:  1000,
+			want: Engine{Name: ModePodman, Host: "unix://" + userPodman, CLI: "podman", Rootless: true},
+		},
+		{
+			name: "remote docker",
+			mode: ModeAuto,
+			env:  map[string]string{"DOCKER_HOST": "tcp://10.0.0.2:2376"},
+			want: Engine{Name: ModeDocker, Host: "tcp://10.0.0.2:2376", CLI: "docker"},
+		},
+		{
+			name:    "no podman socket",
+			mode:    ModePodman,
+			sockets: map[string]string{dockerSocket: dockerSocket},
+			uid:     1000,
+			wantErr: true,
+		},
+		{
+			name:    "nothing",
+			mode:    ModeAuto,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		host := Host{
+			Getenv: func(key string) string { return tt.env[key] },
+			Socket: func(path string) (string, bool) {
+				resolved, ok := tt.sockets[path]
+				return resolved, ok
+			},
+			LookPath: func(file string) bool { return true },
+			UID:      tt.uid,
+		}
+		got, err := Select(tt.mode, host)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Select error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Select = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectWithoutPodmanCLI(t *testing.T) {
+	host := Host{
+		Getenv: func(string) string { return "" },
+		Socket: func(path string) (string, bool) {
+			return rootfulPodmanSocket, path == dockerSocket
+		},
+		LookPath: func(string) bool { return false },
+	}
+	got, err := Select(ModeAuto, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// podman-docker provides the docker command
+	if got.CLI != "docker" || got.Name != ModePodman {
+		t.Errorf("Select = %+v", got)
+	}
+	if want := "Podman at unix:///var/run/docker.sock"; got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+}
@@ -0,0 +1,134 @@
+// Package geoip provides approximate, offline IP address geolocation.
+//
+// The database is an optional CSV file in the DB-IP Lite format
+// (https://db-ip.com/db/lite.php), either the country variant
+// ("ip_start,ip_end,country") or the city variant
+// ("ip_start,ip_end,continent,country,region,city,latitude,longitude").
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LocalNetwork is returned for private, loopback and link-local addresses
+const LocalNetwork = "Local network"
+
+type ipRange struct {
+	start    netip.Addr
+	end      netip.Addr
+	location string
+}
+
+// DB is an in-memory IP range database
+type DB struct {
+	ranges []ipRange
+}
+
+// Open loads a CSV database from path
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path) //nolint:gosec // Path comes from trusted configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geoip database: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // Read-only file
+
+	return Load(f)
+}
+
+// Load reads a CSV database from r
+func Load(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	db := &DB{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read geoip database line %d: %w", line, err)
+		}
+		if len(record) < 3 {
+			continue
+		}
+
+		start, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+		if err != nil {
+			// Header rows and comments are skipped
+			continue
+		}
+		end, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if err != nil || start.Is4() != end.Is4() {
+			continue
+		}
+
+		db.ranges = append(db.ranges, ipRange{start: start, end: end, location: formatLocation(record)})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].start.Less(db.ranges[j].start)
+	})
+	return db, nil
+}
+
+// formatLocation builds a human readable location from a country or city record
+func formatLocation(record []string) string {
+	if len(record) < 6 {
+		return strings.TrimSpace(record[2])
+	}
+
+	// City variant: continent, country, region, city
+	var parts []string
+	for _, idx := range []int{5, 4, 3} {
+		if part := strings.TrimSpace(record[idx]); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Len returns the number of ranges in the database
+func (db *DB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.ranges)
+}
+
+// Lookup returns the approximate location of ip, or an empty string if unknown.
+// A nil DB still recognizes local network addresses.
+func (db *DB) Lookup(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() {
+		return LocalNetwork
+	}
+	if db == nil {
+		return ""
+	}
+
+	// Find the last range starting at or before addr
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].start)
+	}) - 1
+	if i < 0 {
+		return ""
+	}
+	r := db.ranges[i]
+	if r.start.Is4() != addr.Is4() || r.end.Less(addr) {
+		return ""
+	}
+	return r.location
+}
@@ -0,0 +1,53 @@
+package geoip
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	data := `ip_start,ip_end,country
+1.0.0.0,1.0.0.255,AU
+8.8.8.0,8.8.8.255,"US"
+2001:4860::,2001:4860:ffff:ffff:ffff:ffff:ffff:ffff,US
+5.0.0.0,5.0.0.255,EU,DE,Berlin,Berlin,52.5,13.4
+`
+	db, err := Load(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if db.Len() != 4 {
+		t.Fatalf("expected 4 ranges, got %d", db.Len())
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"1.0.0.1", "AU"},
+		{"8.8.8.8", "US"},
+		{"::ffff:8.8.8.8", "US"},
+		{"2001:4860::8888", "US"},
+		{"5.0.0.10", "Berlin, Berlin, DE"},
+		{"9.9.9.9", ""},
+		{"0.0.0.1", ""},
+		{"192.168.1.20", LocalNetwork},
+		{"127.0.0.1", LocalNetwork},
+		{"not-an-ip", ""},
+	}
+	for _, tt := range tests {
+		if got := db.Lookup(tt.ip); got != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestLookupWithoutDatabase(t *testing.T) {
+	var db *DB
+	if got := db.Lookup("10.0.0.5"); got != LocalNetwork {
+		t.Errorf("expected local network, got %q", got)
+	}
+	if got := db.Lookup("8.8.8.8"); got != "" {
+		t.Errorf("expected unknown location, got %q", got)
+	}
+}
@@ -0,0 +1,260 @@
+// Package gpu detects the GPUs of the host and gives the main service of an app access to
+// one: NVIDIA GPUs through a device reservation the NVIDIA Container Toolkit fulfils, AMD
+// and Intel GPUs through their /dev/dri device nodes.
+package gpu
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// GPU vendors
+const (
+	VendorNVIDIA = "nvidia"
+	VendorAMD    = "amd"
+	VendorIntel  = "intel"
+)
+
+// vendorIDs maps PCI vendor IDs to vendors
+var vendorIDs = map[string]string{
+	"0x10de": VendorNVIDIA,
+	"0x1002": VendorAMD,
+	"0x8086": VendorIntel,
+}
+
+// vendorNames are the display names of the vendors
+var vendorNames = map[string]string{
+	VendorNVIDIA: "NVIDIA",
+	VendorAMD:    "AMD",
+	VendorIntel:  "Intel",
+}
+
+// Host files and commands, replaceable in tests
+var (
+	drmPath      = "/sys/class/drm"
+	devPath      = "/dev"
+	groupPath    = "/etc/group"
+	cdiPaths     = []string{"/etc/cdi", "/var/run/cdi"}
+	runtimeNames = func(ctx context.Context) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		output, err := exec.CommandContext(ctx, "docker", "info", "--format", "{{json .Runtimes}}").Output()
+		return string(output), err
+	}
+)
+
+// GPU is a graphics card of the host
+type GPU struct {
+	Vendor     string `json:"vendor"`
+	Card       string `json:"card"`                  // DRM card, e.g. card0
+	Slot       string `json:"slot,omitempty"`        // PCI address, e.g. 0000:01:00.0
+	RenderNode string `json:"render_node,omitempty"` // e.g. /dev/dri/renderD128
+}
+
+// Name returns the vendor and PCI address of the GPU, e.g. "NVIDIA GPU (0000:01:00.0)"
+func (g GPU) Name() string {
+	if g.Slot == "" {
+		return VendorName(g.Vendor) + " GPU"
+	}
+	return fmt.Sprintf("%s GPU (%s)", VendorName(g.Vendor), g.Slot)
+}
+
+// Host holds what the host offers to pass GPUs to containers
+type Host struct {
+	GPUs          []GPU `json:"gpus"`
+	NVIDIAToolkit bool  `json:"nvidia_toolkit"` // The container runtime has the nvidia runtime or a CDI spec
+	KFD           bool  `json:"kfd"`            // /dev/kfd exists, which ROCm needs for AMD compute
+	RenderGID     int   `json:"render_gid"`     // Group owning the render nodes, -1 if unknown
+}
+
+// VendorName returns the display name of a vendor
+func VendorName(vendor string) string {
+	if name, ok := vendorNames[vendor]; ok {
+		return name
+	}
+	return vendor
+}
+
+// Detect finds the GPUs of the host. A host without GPUs, or one that isn't Linux, is no
+// error and returns an empty Host.
+func Detect(ctx context.Context) Host {
+	host := Host{GPUs: detectGPUs(), RenderGID: -1}
+	for _, g := range host.GPUs {
+		switch g.Vendor {
+		case VendorNVIDIA:
+			host.NVIDIAToolkit = host.NVIDIAToolkit || nvidiaToolkit(ctx)
+		case VendorAMD, VendorIntel:
+			host.RenderGID = renderGID()
+		}
+	}
+	if _, err := os.Stat(filepath.Join(devPath, "kfd")); err == nil {
+		host.KFD = true
+	}
+	return host
+}
+
+// detectGPUs reads the DRM cards of the kernel. NVIDIA GPUs without the nvidia-drm module
+// have no card, the device node of their driver shows them.
+func detectGPUs() []GPU {
+	var gpus []GPU
+	cards, _ := filepath.Glob(filepath.Join(drmPath, "card*"))
+	sort.Strings(cards)
+	for _, card := range cards {
+		name := filepath.Base(card)
+		// Connectors such as card0-HDMI-A-1 are no cards
+		if _, err := strconv.Atoi(strings.TrimPrefix(name, "card")); err != nil {
+			continue
+		}
+		vendorID, err := os.ReadFile(filepath.Join(card, "device", "vendor"))
+		if err != nil {
+			continue
+		}
+		vendor, ok := vendorIDs[strings.TrimSpace(string(vendorID))]
+		if !ok {
+			continue
+		}
+		g := GPU{Vendor: vendor, Card: name}
+		if device, err := filepath.EvalSymlinks(filepath.Join(card, "device")); err == nil {
+			g.Slot = filepath.Base(device)
+		}
+		if nodes, _ := filepath.Glob(filepath.Join(card, "device", "drm", "renderD*")); len(nodes) > 0 {
+			g.RenderNode = filepath.Join(devPath, "dri", filepath.Base(nodes[0]))
+		}
+		gpus = append(gpus, g)
+	}
+
+	if !slices.ContainsFunc(gpus, func(g GPU) bool { return g.Vendor == VendorNVIDIA }) {
+		if _, err := os.Stat(filepath.Join(devPath, "nvidia0")); err == nil {
+			gpus = append(gpus, GPU{Vendor: VendorNVIDIA, Card: "nvidia0"})
+		}
+	}
+	return gpus
+}
+
+// nvidiaToolkit reports whether containers can get NVIDIA GPUs: Docker has the nvidia
+// runtime of the NVIDIA Container Toolkit, or a CDI spec describes the GPUs for Podman
+func nvidiaToolkit(ctx context.Context) bool {
+	if runtimes, err := runtimeNames(ctx); err == nil && strings.Contains(runtimes, `"nvidia"`) {
+		return true
+	}
+	for _, dir := range cdiPaths {
+		if specs, _ := filepath.Glob(filepath.Join(dir, "nvidia*")); len(specs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// renderGID returns the ID of the render group, which owns /dev/dri/renderD* on most
+// distributions, -1 if there is none
+func renderGID() int {
+	file, err := os.Open(groupPath)
+	if err != nil {
+		return -1
+	}
+	defer file.Close() //nolint:errcheck // Read-only file
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) >= 3 && fields[0] == "render" {
+			if gid, err := strconv.Atoi(fields[2]); err == nil {
+				return gid
+			}
+		}
+	}
+	return -1
+}
+
+// Vendors returns the vendors of the GPUs, each once in the order found
+func (h Host) Vendors() []string {
+	var vendors []string
+	for _, g := range h.GPUs {
+		if !slices.Contains(vendors, g.Vendor) {
+			vendors = append(vendors, g.Vendor)
+		}
+	}
+	return vendors
+}
+
+// GPUsOf returns the GPUs of a vendor
+func (h Host) GPUsOf(vendor string) []GPU {
+	var gpus []GPU
+	for _, g := range h.GPUs {
+		if g.Vendor == vendor {
+			gpus = append(gpus, g)
+		}
+	}
+	return gpus
+}
+
+// Check returns why GPUs of a vendor can't be passed to containers, nil if they can
+func (h Host) Check(vendor string) error {
+	if _, ok := vendorNames[vendor]; !ok {
+		return fmt.Errorf("unknown GPU vendor %q", vendor)
+	}
+	gpus := h.GPUsOf(vendor)
+	if len(gpus) == 0 {
+		return fmt.Errorf("this host has no %s GPU", VendorName(vendor))
+	}
+	switch vendor {
+	case VendorNVIDIA:
+		if !h.NVIDIAToolkit {
+			return errors.New("the NVIDIA Container Toolkit is not set up, install it and run `nvidia-ctk runtime configure` to pass NVIDIA GPUs to containers")
+		}
+	default:
+		for _, g := range gpus {
+			if g.RenderNode != "" {
+				return nil
+			}
+		}
+		return fmt.Errorf("the %s GPU has no render node in /dev/dri, is its kernel driver loaded?", VendorName(vendor))
+	}
+	return nil
+}
+
+// nvidiaDevice is the device reservation of all NVIDIA GPUs, with the driver capabilities
+// for compute, e.g. Ollama, and video transcoding, e.g. Jellyfin
+type nvidiaDevice struct {
+	Driver       string   `yaml:"driver"`
+	Count        string   `yaml:"count"`
+	Capabilities []string `yaml:"capabilities"`
+}
+
+// Inject gives a service of a compose file access to the GPUs of a vendor after checking
+// the host supports it. Stanzas the service already has are not added again.
+func (h Host) Inject(content []byte, service, vendor string) ([]byte, error) {
+	if err := h.Check(vendor); err != nil {
+		return nil, err
+	}
+	if vendor == VendorNVIDIA {
+		device := nvidiaDevice{Driver: "nvidia", Count: "all", Capabilities: []string{"gpu", "compute", "utility", "video"}}
+		return yamlutil.AddServiceListItems(content, service, []string{"deploy", "resources", "reservations", "devices"}, device)
+	}
+
+	devices := []interface{}{"/dev/dri:/dev/dri"}
+	if vendor == VendorAMD && h.KFD {
+		devices = append(devices, "/dev/kfd:/dev/kfd")
+	}
+	content, err := yamlutil.AddServiceListItems(content, service, []string{"devices"}, devices...)
+	if err != nil {
+		return nil, err
+	}
+	// Images running as a user need the group of the render nodes
+	if h.RenderGID >= 0 {
+		return yamlutil.AddServiceListItems(content, service, []string{"group_add"}, h.RenderGID)
+	}
+	return content, nil
+}
@@ -0,0 +1,141 @@
+package gpu
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHost creates a sysfs DRM tree with the cards of vendor IDs and a /dev directory
+func fakeHost(t *testing.T, vendorIDs ...string) {
+	t.Helper()
+	root := t.TempDir()
+	drmPath = filepath.Join(root, "sys", "class", "drm")
+	devPath = filepath.Join(root, "dev")
+	groupPath = filepath.Join(root, "group")
+	cdiPaths = []string{filepath.Join(root, "cdi")}
+	runtimeNames = func(context.Context) (string, error) { return "", errors.New("no docker") }
+	for _, dir := range []string{drmPath, devPath} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(groupPath, []byte("video:x:44:\nrender:x:109:ontree\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i, vendorID := range vendorIDs {
+		card := "card" + string(rune('0'+i))
+		device := filepath.Join(root, "sys", "devices", "pci0000:00", "0000:0"+string(rune('1'+i))+":00.0")
+		if err := os.MkdirAll(filepath.Join(device, "drm", "renderD"+string(rune('0'+i))), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(device, "vendor"), []byte(vendorID+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(drmPath, card), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(device, filepath.Join(drmPath, card, "device")); err != nil {
+			t.Fatal(err)
+		}
+		// A connector of the card
+		if err := os.MkdirAll(filepath.Join(drmPath, card+"-HDMI-A-1"), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	fakeHost(t, "0x8086", "0x10de", "0x1af4")
+
+	host := Detect(context.Background())
+	if len(host.GPUs) != 2 || host.GPUs[0].Name() != "Intel GPU (0000:01:00.0)" ||
+		!strings.HasSuffix(host.GPUs[0].RenderNode, "/dev/dri/renderD0") || host.GPUs[1].Vendor != VendorNVIDIA {
+		t.Fatalf("Detect() = %+v", host)
+	}
+	if host.RenderGID != 109 || host.NVIDIAToolkit || host.KFD {
+		t.Errorf("Detect() = %+v", host)
+	}
+	if vendors := host.Vendors(); len(vendors) != 2 || vendors[0] != VendorIntel {
+		t.Errorf("Vendors() = %v", vendors)
+	}
+
+	if err := host.Check(VendorIntel); err != nil {
+		t.Errorf("Check(intel) = %v", err)
+	}
+	if err := host.Check(VendorNVIDIA); err == nil || !strings.Contains(err.Error(), "NVIDIA Container Toolkit") {
+		t.Errorf("Check(nvidia) without the toolkit = %v", err)
+	}
+	if err := host.Check(VendorAMD); err == nil {
+		t.Error("Check(amd) accepted a missing GPU")
+	}
+
+	runtimeNames = func(context.Context) (string, error) {
+		return `{"io.containerd.runc.v2":{"path":"runc"},"nvidia":{"path":"nvidia-container-runtime"}}`, nil
+	}
+	if host := Detect(context.Background()); !host.NVIDIAToolkit || host.Check(VendorNVIDIA) != nil {
+		t.Errorf("Detect() with the nvidia runtime = %+v", host)
+	}
+}
+
+func TestDetectNVIDIAWithoutDRM(t *testing.T) {
+	fakeHost(t)
+	if err := os.WriteFile(filepath.Join(devPath, "nvidia0"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(cdiPaths[0], 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cdiPaths[0], "nvidia.yaml"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	host := Detect(context.Background())
+	if len(host.GPUs) != 1 || host.GPUs[0].Name() != "NVIDIA GPU" || !host.NVIDIAToolkit {
+		t.Errorf("Detect() = %+v", host)
+	}
+}
+
+func TestInject(t *testing.T) {
+	compose := "services:\n  jellyfin:\n    image: jellyfin/jellyfin\n"
+	host := Host{
+		GPUs: []GPU{
+			{Vendor: VendorNVIDIA, Card: "card0"},
+			{Vendor: VendorAMD, Card: "card1", RenderNode: "/dev/dri/renderD129"},
+		},
+		NVIDIAToolkit: true,
+		KFD:           true,
+		RenderGID:     109,
+	}
+
+	got, err := host.Inject([]byte(compose), "jellyfin", VendorNVIDIA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "services:\n  jellyfin:\n    deploy:\n      resources:\n        reservations:\n          devices:\n" +
+		"            - driver: nvidia\n              count: all\n              capabilities:\n" +
+		"                - gpu\n                - compute\n                - utility\n                - video\n" +
+		"    image: jellyfin/jellyfin\n"
+	if string(got) != want {
+		t.Errorf("Inject(nvidia) =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = host.Inject([]byte(compose), "jellyfin", VendorAMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "services:\n  jellyfin:\n    group_add:\n      - 109\n    devices:\n      - /dev/dri:/dev/dri\n      - /dev/kfd:/dev/kfd\n" +
+		"    image: jellyfin/jellyfin\n"
+	if string(got) != want {
+		t.Errorf("Inject(amd) =\n%s\nwant\n%s", got, want)
+	}
+	if again, err := host.Inject(got, "jellyfin", VendorAMD); err != nil || string(again) != string(got) {
+		t.Errorf("Inject(amd) again = %v\n%s", err, again)
+	}
+
+	if _, err := host.Inject([]byte(compose), "jellyfin", VendorIntel); err == nil {
+		t.Error("Inject(intel) accepted a missing GPU")
+	}
+}
@@ -0,0 +1,435 @@
+// Package hostupdates reports pending operating system package updates on the host.
+//
+// Checks are read-only and use the package manager's cached metadata (apt or dnf),
+// they never refresh package lists. Applying security updates requires root.
+package hostupdates
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Package manager identifiers
+const (
+	ManagerApt = "apt"
+	ManagerDnf = "dnf"
+)
+
+const (
+	checkTimeout = 2 * time.Minute
+	applyTimeout = 30 * time.Minute
+)
+
+// Files written by Debian/Ubuntu when a reboot is needed
+var (
+	rebootRequiredFile     = "/var/run/reboot-required"
+	rebootRequiredPkgsFile = "/var/run/reboot-required.pkgs"
+)
+
+// ErrUnsupported is returned on hosts without a supported package manager
+var ErrUnsupported = errors.New("host package updates are only supported on Linux with apt or dnf")
+
+// Package is a single pending package update
+type Package struct {
+	Name           string `json:"name"`
+	CurrentVersion string `json:"current_version,omitempty"`
+	NewVersion     string `json:"new_version"`
+	Source         string `json:"source,omitempty"`
+	Security       bool   `json:"security"`
+}
+
+// Status summarizes the pending updates of the host
+type Status struct {
+	Supported       bool      `json:"supported"`
+	PackageManager  string    `json:"package_manager,omitempty"`
+	PendingUpdates  int       `json:"pending_updates"`
+	SecurityUpdates int       `json:"security_updates"`
+	Packages        []Package `json:"packages"`
+	RebootRequired  bool      `json:"reboot_required"`
+	RebootReasons   []string  `json:"reboot_reasons,omitempty"`
+	RebootPackages  []string  `json:"reboot_packages,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// DetectManager returns the package manager available on this host, or "" if none is supported
+func DetectManager() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	if _, err := exec.LookPath("apt"); err == nil {
+		return ManagerApt
+	}
+	if _, err := exec.LookPath("dnf"); err == nil {
+		return ManagerDnf
+	}
+	return ""
+}
+
+// Check returns the pending updates and reboot status of the host
+func Check(ctx context.Context) (*Status, error) {
+	status := &Status{
+		Packages:  []Package{},
+		CheckedAt: time.Now(),
+	}
+
+	manager := DetectManager()
+	if manager == "" {
+		return status, nil
+	}
+	status.Supported = true
+	status.PackageManager = manager
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var err error
+	switch manager {
+	case ManagerApt:
+		status.Packages, err = checkApt(ctx)
+	case ManagerDnf:
+		status.Packages, err = checkDnf(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.PendingUpdates = len(status.Packages)
+	for _, pkg := range status.Packages {
+		if pkg.Security {
+			status.SecurityUpdates++
+		}
+	}
+
+	status.RebootReasons, status.RebootPackages = rebootReasons(ctx, manager)
+	status.RebootRequired = len(status.RebootReasons) > 0
+	return status, nil
+}
+
+// RebootReasons returns why the host needs a reboot, or nil if it doesn't.
+// It works without a supported package manager, then only kernel and Docker updates are detected.
+func RebootReasons(ctx context.Context) []string {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	reasons, _ := rebootReasons(ctx, DetectManager())
+	return reasons
+}
+
+func rebootReasons(ctx context.Context, manager string) ([]string, []string) {
+	var reasons []string
+	required, packages := rebootRequired(ctx, manager)
+	if required {
+		reasons = append(reasons, "Installed packages require a reboot")
+	}
+	if kernel := pendingKernel(); kernel != "" {
+		reasons = append(reasons, fmt.Sprintf("Kernel %s is installed but not running", kernel))
+	}
+	if dockerVersion := pendingDockerVersion(ctx); dockerVersion != "" {
+		reasons = append(reasons, fmt.Sprintf("Docker %s is installed but an older daemon is running", dockerVersion))
+	}
+	return reasons, packages
+}
+
+// Locations used to detect a newer installed kernel
+var (
+	kernelReleaseFile = "/proc/sys/kernel/osrelease"
+	kernelModulesDir  = "/lib/modules"
+)
+
+// pendingKernel returns the newest installed kernel release if it is not the running one
+func pendingKernel() string {
+	data, err := os.ReadFile(kernelReleaseFile)
+	if err != nil {
+		return ""
+	}
+	running := strings.TrimSpace(string(data))
+
+	entries, err := os.ReadDir(kernelModulesDir)
+	if err != nil {
+		return ""
+	}
+
+	// The most recently installed kernel is the one the next boot will use
+	var newest string
+	var newestTime time.Time
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// Skip leftovers of removed kernels, they have no modules.dep
+		info, err := os.Stat(filepath.Join(kernelModulesDir, entry.Name(), "modules.dep"))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(newestTime) {
+			newest, newestTime = entry.Name(), info.ModTime()
+		}
+	}
+
+	if newest == "" || newest == running {
+		return ""
+	}
+	return newest
+}
+
+// pendingDockerVersion returns the installed Docker version if the running daemon is older
+func pendingDockerVersion(ctx context.Context) string {
+	if _, err := exec.LookPath("dockerd"); err != nil {
+		return ""
+	}
+	installedOutput, err := exec.CommandContext(ctx, "dockerd", "--version").Output()
+	if err != nil {
+		return ""
+	}
+	runningOutput, err := exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}").Output()
+	if err != nil {
+		return ""
+	}
+
+	installed := parseDockerdVersion(string(installedOutput))
+	running := strings.TrimSpace(string(runningOutput))
+	if installed == "" || running == "" || installed == running {
+		return ""
+	}
+	return installed
+}
+
+// parseDockerdVersion extracts the version from "Docker version 27.3.1, build 41ca978"
+func parseDockerdVersion(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) < 3 || fields[0] != "Docker" || fields[1] != "version" {
+		return ""
+	}
+	return strings.TrimSuffix(fields[2], ",")
+}
+
+// ApplySecurityUpdates installs pending security updates and returns the package manager output
+func ApplySecurityUpdates(ctx context.Context) (string, error) {
+	manager := DetectManager()
+	if manager == "" {
+		return "", ErrUnsupported
+	}
+	if os.Geteuid() != 0 {
+		return "", fmt.Errorf("applying host updates requires TreeOS to run as root")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, applyTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch manager {
+	case ManagerApt:
+		packages, err := checkApt(ctx)
+		if err != nil {
+			return "", err
+		}
+		args := []string{"-y", "--only-upgrade", "install"}
+		for _, pkg := range packages {
+			if pkg.Security {
+				args = append(args, pkg.Name)
+			}
+		}
+		if len(args) == 3 {
+			return "No security updates pending", nil
+		}
+		cmd = exec.CommandContext(ctx, "apt-get", args...) //nolint:gosec // Package names come from apt itself
+		cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+	case ManagerDnf:
+		cmd = exec.CommandContext(ctx, "dnf", "-y", "upgrade", "--security")
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%s failed: %w", manager, err)
+	}
+	return string(output), nil
+}
+
+// InstallPackages installs the given packages with the host's package manager and returns its output
+func InstallPackages(ctx context.Context, packages ...string) (string, error) {
+	manager := DetectManager()
+	if manager == "" {
+		return "", ErrUnsupported
+	}
+	if os.Geteuid() != 0 {
+		return "", fmt.Errorf("installing host packages requires TreeOS to run as root")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, applyTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch manager {
+	case ManagerApt:
+		cmd = exec.CommandContext(ctx, "apt-get", append([]string{"-y", "install"}, packages...)...) //nolint:gosec // Fixed package names from callers
+		cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+	case ManagerDnf:
+		cmd = exec.CommandContext(ctx, "dnf", append([]string{"-y", "install"}, packages...)...) //nolint:gosec // Fixed package names from callers
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%s failed: %w", manager, err)
+	}
+	return string(output), nil
+}
+
+func checkApt(ctx context.Context) ([]Package, error) {
+	cmd := exec.CommandContext(ctx, "apt", "list", "--upgradable")
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upgradable packages: %w", err)
+	}
+	return parseAptUpgradable(string(output)), nil
+}
+
+// parseAptUpgradable parses `apt list --upgradable` output, e.g.
+// "openssl/jammy-security 3.0.2-0ubuntu1.15 amd64 [upgradable from: 3.0.2-0ubuntu1.14]"
+func parseAptUpgradable(output string) []Package {
+	packages := []Package{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.Contains(fields[0], "/") {
+			// Skip "Listing..." and warnings
+			continue
+		}
+
+		name, sources, _ := strings.Cut(fields[0], "/")
+		pkg := Package{
+			Name:       name,
+			NewVersion: fields[1],
+			Source:     sources,
+			Security:   strings.Contains(sources, "-security"),
+		}
+		if _, from, ok := strings.Cut(line, "upgradable from: "); ok {
+			pkg.CurrentVersion = strings.TrimSuffix(from, "]")
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+func checkDnf(ctx context.Context) ([]Package, error) {
+	// check-update exits with 100 when updates are available
+	output, err := exec.CommandContext(ctx, "dnf", "-q", "--cacheonly", "check-update").Output()
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 100) {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	packages := parseDnfCheckUpdate(string(output))
+
+	securityOutput, err := exec.CommandContext(ctx, "dnf", "-q", "--cacheonly", "updateinfo", "list", "--security").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security updates: %w", err)
+	}
+	security := parseDnfSecurity(string(securityOutput))
+	for i := range packages {
+		packages[i].Security = security[packages[i].Name]
+	}
+	return packages, nil
+}
+
+// parseDnfCheckUpdate parses `dnf check-update` output, e.g.
+// "openssl.x86_64    1:3.0.7-25.el9    baseos"
+func parseDnfCheckUpdate(output string) []Package {
+	packages := []Package{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !strings.Contains(fields[0], ".") {
+			continue
+		}
+		packages = append(packages, Package{
+			Name:       stripArch(fields[0]),
+			NewVersion: fields[1],
+			Source:     fields[2],
+		})
+	}
+	return packages
+}
+
+// parseDnfSecurity parses `dnf updateinfo list --security` output, e.g.
+// "RHSA-2024:1234 Important/Sec. openssl-1:3.0.7-25.el9.x86_64"
+// and returns the set of affected package names
+func parseDnfSecurity(output string) map[string]bool {
+	names := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if name := nevraName(fields[len(fields)-1]); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// stripArch removes the architecture suffix from "name.arch"
+func stripArch(s string) string {
+	if i := strings.LastIndex(s, "."); i > 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// nevraName extracts the package name from "name-[epoch:]version-release.arch"
+func nevraName(nevra string) string {
+	nevra = stripArch(nevra)
+	// Drop release, then version
+	for range 2 {
+		i := strings.LastIndex(nevra, "-")
+		if i <= 0 {
+			return ""
+		}
+		nevra = nevra[:i]
+	}
+	return nevra
+}
+
+// rebootRequired reports whether the host needs a reboot to finish applying updates
+func rebootRequired(ctx context.Context, manager string) (bool, []string) {
+	switch manager {
+	case ManagerApt:
+		if _, err := os.Stat(rebootRequiredFile); err != nil {
+			return false, nil
+		}
+		var packages []string
+		if data, err := os.ReadFile(rebootRequiredPkgsFile); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					packages = append(packages, line)
+				}
+			}
+		}
+		return true, packages
+	case ManagerDnf:
+		if _, err := exec.LookPath("needs-restarting"); err != nil {
+			return false, nil
+		}
+		// needs-restarting -r exits with 1 when a reboot is required
+		err := exec.CommandContext(ctx, "needs-restarting", "-r").Run()
+		var exitErr *exec.ExitError
+		return errors.As(err, &exitErr) && exitErr.ExitCode() == 1, nil
+	}
+	return false, nil
+}
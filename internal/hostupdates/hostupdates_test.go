@@ -0,0 +1,93 @@
+package hostupdates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseAptUpgradable(t *testing.T) {
+	output := `Listing... Done
+openssl/jammy-updates,jammy-security 3.0.2-0ubuntu1.15 amd64 [upgradable from: 3.0.2-0ubuntu1.14]
+vim/jammy-updates 2:8.2.3995-1ubuntu2.16 amd64 [upgradable from: 2:8.2.3995-1ubuntu2.15]
+`
+	packages := parseAptUpgradable(output)
+	if len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(packages))
+	}
+	if p := packages[0]; p.Name != "openssl" || !p.Security || p.NewVersion != "3.0.2-0ubuntu1.15" || p.CurrentVersion != "3.0.2-0ubuntu1.14" {
+		t.Errorf("unexpected package: %+v", p)
+	}
+	if p := packages[1]; p.Name != "vim" || p.Security {
+		t.Errorf("unexpected package: %+v", p)
+	}
+}
+
+func TestParseDnf(t *testing.T) {
+	checkUpdate := `
+openssl.x86_64          1:3.0.7-25.el9          baseos
+vim-enhanced.x86_64     2:8.2.2637-20.el9       appstream
+Obsoleting Packages
+foo.noarch              1.0-1.el9               appstream
+`
+	packages := parseDnfCheckUpdate(checkUpdate)
+	if len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(packages))
+	}
+	if packages[0].Name != "openssl" || packages[1].Name != "vim-enhanced" {
+		t.Errorf("unexpected packages: %+v", packages)
+	}
+
+	security := parseDnfSecurity("RHSA-2024:1234 Important/Sec. openssl-1:3.0.7-25.el9.x86_64\n")
+	if !security["openssl"] || security["vim-enhanced"] {
+		t.Errorf("unexpected security set: %v", security)
+	}
+}
+
+func TestPendingKernel(t *testing.T) {
+	dir := t.TempDir()
+	release := filepath.Join(dir, "osrelease")
+	modules := filepath.Join(dir, "modules")
+
+	origRelease, origModules := kernelReleaseFile, kernelModulesDir
+	kernelReleaseFile, kernelModulesDir = release, modules
+	defer func() { kernelReleaseFile, kernelModulesDir = origRelease, origModules }()
+
+	addKernel := func(name string, modTime time.Time) {
+		path := filepath.Join(modules, name)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		dep := filepath.Join(path, "modules.dep")
+		if err := os.WriteFile(dep, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dep, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	addKernel("6.8.0-40-generic", now.Add(-48*time.Hour))
+	if err := os.WriteFile(release, []byte("6.8.0-40-generic\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingKernel(); got != "" {
+		t.Errorf("expected no pending kernel, got %q", got)
+	}
+
+	addKernel("6.8.0-45-generic", now)
+	if got := pendingKernel(); got != "6.8.0-45-generic" {
+		t.Errorf("expected pending kernel 6.8.0-45-generic, got %q", got)
+	}
+}
+
+func TestParseDockerdVersion(t *testing.T) {
+	if got := parseDockerdVersion("Docker version 27.3.1, build 41ca978\n"); got != "27.3.1" {
+		t.Errorf("parseDockerdVersion() = %q", got)
+	}
+	if got := parseDockerdVersion("garbage"); got != "" {
+		t.Errorf("parseDockerdVersion() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,322 @@
+// Package installer installs and configures the host dependencies of TreeOS: a container
+// runtime with Docker Compose v2, Caddy and optionally Tailscale.
+package installer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Container runtimes that can be installed
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Distribution families with different package managers and repositories
+const (
+	FamilyDebian = "debian"
+	FamilyFedora = "fedora"
+	FamilyRHEL   = "rhel"
+)
+
+// osReleasePath and lookPath are replaceable in tests
+var (
+	osReleasePath = "/etc/os-release"
+	lookPath      = exec.LookPath
+)
+
+// ErrUnsupported is returned for hosts the installer doesn't know
+var ErrUnsupported = errors.New("install-deps supports Debian, Ubuntu, Fedora and RHEL-based distributions")
+
+// Distro identifies the host's Linux distribution
+type Distro struct {
+	ID        string // e.g. ubuntu, debian, fedora, rocky
+	Codename  string // Debian/Ubuntu release codename, e.g. noble
+	Family    string
+	DockerDir string // Distribution directory on download.docker.com
+}
+
+// Options select what to install
+type Options struct {
+	Runtime   string
+	Tailscale bool
+	Force     bool // Reinstall components that are already present
+}
+
+// Step is a single action of the installation
+type Step struct {
+	Description string
+	Command     []string // Command to run, or
+	URL         string   // file to download to Path, or
+	Content     string   // content to write to Path
+	Path        string
+}
+
+// String describes the step like a shell command for dry runs
+func (s Step) String() string {
+	switch {
+	case len(s.Command) > 0:
+		return strings.Join(s.Command, " ")
+	case s.URL != "":
+		return fmt.Sprintf("curl -fsSL %s -o %s", s.URL, s.Path)
+	default:
+		return fmt.Sprintf("write %s", s.Path)
+	}
+}
+
+// DetectDistro reads /etc/os-release
+func DetectDistro() (Distro, error) {
+	if runtime.GOOS != "linux" {
+		return Distro{}, ErrUnsupported
+	}
+	file, err := os.Open(osReleasePath)
+	if err != nil {
+		return Distro{}, fmt.Errorf("failed to detect distribution: %w", err)
+	}
+	defer file.Close() //nolint:errcheck // Read-only file
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok {
+			fields[key] = strings.Trim(value, `"'`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Distro{}, fmt.Errorf("failed to read %s: %w", osReleasePath, err)
+	}
+	return parseDistro(fields)
+}
+
+func parseDistro(fields map[string]string) (Distro, error) {
+	distro := Distro{ID: fields["ID"], Codename: fields["VERSION_CODENAME"]}
+	like := " " + fields["ID_LIKE"] + " "
+
+	switch {
+	case distro.ID == "debian" || distro.ID == "ubuntu":
+		distro.Family, distro.DockerDir = FamilyDebian, distro.ID
+	case strings.Contains(like, " ubuntu "):
+		// Derivatives like Linux Mint or Pop!_OS use the Ubuntu repositories
+		distro.Family, distro.DockerDir = FamilyDebian, "ubuntu"
+		if codename := fields["UBUNTU_CODENAME"]; codename != "" {
+			distro.Codename = codename
+		}
+	case strings.Contains(like, " debian "):
+		distro.Family, distro.DockerDir = FamilyDebian, "debian"
+		if codename := fields["DEBIAN_CODENAME"]; codename != "" {
+			distro.Codename = codename
+		}
+	case distro.ID == "fedora":
+		distro.Family, distro.DockerDir = FamilyFedora, "fedora"
+	case distro.ID == "rhel":
+		distro.Family, distro.DockerDir = FamilyRHEL, "rhel"
+	case strings.Contains(like, " rhel ") || strings.Contains(like, " centos "):
+		distro.Family, distro.DockerDir = FamilyRHEL, "centos"
+	default:
+		return Distro{}, fmt.Errorf("%w, found %q", ErrUnsupported, distro.ID)
+	}
+
+	if distro.Family == FamilyDebian && distro.Codename == "" {
+		return Distro{}, errors.New("failed to detect the release codename from /etc/os-release")
+	}
+	return distro, nil
+}
+
+// Plan returns the steps to install the selected components. Components that are already
+// installed are skipped unless opts.Force is set.
+func Plan(distro Distro, opts Options) ([]Step, error) {
+	if opts.Runtime == "" {
+		opts.Runtime = RuntimeDocker
+	}
+	if opts.Runtime != RuntimeDocker && opts.Runtime != RuntimePodman {
+		return nil, fmt.Errorf("unknown container runtime %q, use docker or podman", opts.Runtime)
+	}
+
+	var steps []Step
+	if opts.Force || !installed(opts.Runtime) || !composeInstalled() {
+		if distro.Family == FamilyDebian {
+			steps = append(steps, aptPrerequisites()...)
+		}
+		steps = append(steps, dockerRepoSteps(distro)...)
+		if opts.Runtime == RuntimeDocker {
+			steps = append(steps, dockerSteps(distro)...)
+		} else {
+			steps = append(steps, podmanSteps(distro)...)
+		}
+	}
+	if opts.Force || !installed("caddy") {
+		if distro.Family == FamilyDebian && len(steps) == 0 {
+			steps = append(steps, aptPrerequisites()...)
+		}
+		steps = append(steps, caddySteps(distro)...)
+	}
+	if opts.Tailscale && (opts.Force || !installed("tailscale")) {
+		steps = append(steps, tailscaleSteps()...)
+	}
+	return steps, nil
+}
+
+func installed(binary string) bool {
+	_, err := lookPath(binary)
+	return err == nil
+}
+
+func composeInstalled() bool {
+	if !installed("docker") {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, "docker", "compose", "version").Run() == nil
+}
+
+func aptPrerequisites() []Step {
+	return []Step{
+		{Description: "Update package lists", Command: []string{"apt-get", "update"}},
+		{Description: "Install repository prerequisites", Command: []string{"apt-get", "install", "-y", "ca-certificates", "curl"}},
+		{Description: "Create the apt keyring directory", Command: []string{"install", "-m", "0755", "-d", "/etc/apt/keyrings"}},
+	}
+}
+
+// dockerRepoSteps add the Docker repository, which also provides the Compose v2 plugin for Podman
+func dockerRepoSteps(distro Distro) []Step {
+	base := "https://download.docker.com/linux/" + distro.DockerDir
+	if distro.Family == FamilyDebian {
+		return []Step{
+			{Description: "Download the Docker repository key", URL: base + "/gpg", Path: "/etc/apt/keyrings/docker.asc"},
+			{
+				Description: "Add the Docker repository",
+				Path:        "/etc/apt/sources.list.d/docker.list",
+				Content:     fmt.Sprintf("deb [signed-by=/etc/apt/keyrings/docker.asc] %s %s stable\n", base, distro.Codename),
+			},
+			{Description: "Update package lists", Command: []string{"apt-get", "update"}},
+		}
+	}
+	return []Step{
+		{Description: "Add the Docker repository", URL: base + "/docker-ce.repo", Path: "/etc/yum.repos.d/docker-ce.repo"},
+	}
+}
+
+func dockerSteps(distro Distro) []Step {
+	packages := []string{"docker-ce", "docker-ce-cli", "containerd.io", "docker-buildx-plugin", "docker-compose-plugin"}
+	return []Step{
+		{Description: "Install Docker Engine and Compose v2", Command: installCommand(distro, packages...)},
+		{Description: "Start Docker on boot", Command: []string{"systemctl", "enable", "--now", "docker"}},
+	}
+}
+
+func podmanSteps(distro Distro) []Step {
+	// podman-docker provides the docker command, the Compose plugin runs against the Podman socket
+	return []Step{
+		{Description: "Install Podman with Docker compatibility and Compose v2", Command: installCommand(distro, "podman", "podman-docker", "docker-compose-plugin")},
+		{Description: "Start the Podman API socket on boot", Command: []string{"systemctl", "enable", "--now", "podman.socket"}},
+	}
+}
+
+func caddySteps(distro Distro) []Step {
+	var steps []Step
+	if distro.Family == FamilyDebian {
+		steps = []Step{
+			{Description: "Download the Caddy repository key", URL: "https://dl.cloudsmith.io/public/caddy/stable/gpg.key", Path: "/etc/apt/keyrings/caddy-stable.asc"},
+			{
+				Description: "Add the Caddy repository",
+				Path:        "/etc/apt/sources.list.d/caddy-stable.list",
+				Content:     "deb [signed-by=/etc/apt/keyrings/caddy-stable.asc] https://dl.cloudsmith.io/public/caddy/stable/deb/debian any-version main\n",
+			},
+			{Description: "Update package lists", Command: []string{"apt-get", "update"}},
+		}
+	} else {
+		steps = []Step{
+			{Description: "Install the COPR plugin", Command: []string{"dnf", "install", "-y", "dnf-command(copr)"}},
+			{Description: "Add the Caddy repository", Command: []string{"dnf", "copr", "enable", "-y", "@caddy/caddy"}},
+		}
+	}
+	// TreeOS configures Caddy through its admin API, which the packaged unit enables on localhost
+	return append(steps,
+		Step{Description: "Install Caddy", Command: installCommand(distro, "caddy")},
+		Step{Description: "Start Caddy on boot", Command: []string{"systemctl", "enable", "--now", "caddy"}},
+	)
+}
+
+func tailscaleSteps() []Step {
+	script := filepath.Join(os.TempDir(), "tailscale-install.sh")
+	return []Step{
+		{Description: "Download the Tailscale installer", URL: "https://tailscale.com/install.sh", Path: script},
+		{Description: "Install Tailscale", Command: []string{"sh", script}},
+		{Description: "Start Tailscale on boot", Command: []string{"systemctl", "enable", "--now", "tailscaled"}},
+	}
+}
+
+func installCommand(distro Distro, packages ...string) []string {
+	if distro.Family == FamilyDebian {
+		return append([]string{"apt-get", "install", "-y"}, packages...)
+	}
+	return append([]string{"dnf", "install", "-y"}, packages...)
+}
+
+// Run executes the steps in order and stops at the first failure. Command output goes to out.
+func Run(ctx context.Context, steps []Step, out io.Writer) error {
+	if os.Geteuid() != 0 {
+		return errors.New("install-deps must run as root, try: sudo treeos install-deps")
+	}
+
+	for i, step := range steps {
+		fmt.Fprintf(out, "[%d/%d] %s\n", i+1, len(steps), step.Description) //nolint:errcheck // Progress output
+		var err error
+		switch {
+		case len(step.Command) > 0:
+			cmd := exec.CommandContext(ctx, step.Command[0], step.Command[1:]...) //nolint:gosec // Commands from the fixed plan
+			cmd.Stdout, cmd.Stderr = out, out
+			cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+			err = cmd.Run()
+		case step.URL != "":
+			err = download(ctx, step.URL, step.Path)
+		default:
+			err = os.WriteFile(step.Path, []byte(step.Content), 0644) //nolint:gosec // Repository files are world-readable
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", step.Description, err)
+		}
+	}
+	return nil
+}
+
+func download(ctx context.Context, url, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of %s returned status %d", url, resp.StatusCode)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644) //nolint:gosec // Repository keys are world-readable
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close() //nolint:errcheck,gosec // Already failing
+		return err
+	}
+	return file.Close()
+}
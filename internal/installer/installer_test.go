@@ -0,0 +1,127 @@
+package installer
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseDistro(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   Distro
+	}{
+		{
+			name:   "ubuntu",
+			fields: map[string]string{"ID": "ubuntu", "VERSION_CODENAME": "noble"},
+			want:   Distro{ID: "ubuntu", Codename: "noble", Family: FamilyDebian, DockerDir: "ubuntu"},
+		},
+		{
+			name:   "linux mint uses the ubuntu codename",
+			fields: map[string]string{"ID": "linuxmint", "ID_LIKE": "ubuntu debian", "VERSION_CODENAME": "wilma", "UBUNTU_CODENAME": "noble"},
+			want:   Distro{ID: "linuxmint", Codename: "noble", Family: FamilyDebian, DockerDir: "ubuntu"},
+		},
+		{
+			name:   "fedora",
+			fields: map[string]string{"ID": "fedora"},
+			want:   Distro{ID: "fedora", Family: FamilyFedora, DockerDir: "fedora"},
+		},
+		{
+			name:   "rocky uses the centos repository",
+			fields: map[string]string{"ID": "rocky", "ID_LIKE": "rhel centos fedora"},
+			want:   Distro{ID: "rocky", Family: FamilyRHEL, DockerDir: "centos"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDistro(tt.fields)
+			if err != nil {
+				t.Fatalf("parseDistro() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseDistro() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseDistro(map[string]string{"ID": "arch"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for arch, got %v", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	originalLookPath := lookPath
+	defer func() { lookPath = originalLookPath }()
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+
+	ubuntu := Distro{ID: "ubuntu", Codename: "noble", Family: FamilyDebian, DockerDir: "ubuntu"}
+	steps, err := Plan(ubuntu, Options{Tailscale: true})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	plan := planText(steps)
+	for _, want := range []string{
+		"deb [signed-by=/etc/apt/keyrings/docker.asc] https://download.docker.com/linux/ubuntu noble stable",
+		"apt-get install -y docker-ce docker-ce-cli containerd.io docker-buildx-plugin docker-compose-plugin",
+		"systemctl enable --now docker",
+		"apt-get install -y caddy",
+		"systemctl enable --now tailscaled",
+	} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("plan missing %q:\n%s", want, plan)
+		}
+	}
+
+	fedora := Distro{ID: "fedora", Family: FamilyFedora, DockerDir: "fedora"}
+	steps, err = Plan(fedora, Options{Runtime: RuntimePodman})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	plan = planText(steps)
+	for _, want := range []string{
+		"https://download.docker.com/linux/fedora/docker-ce.repo",
+		"dnf install -y podman podman-docker docker-compose-plugin",
+		"systemctl enable --now podman.socket",
+		"dnf copr enable -y @caddy/caddy",
+	} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("plan missing %q:\n%s", want, plan)
+		}
+	}
+	if strings.Contains(plan, "tailscale") {
+		t.Errorf("tailscale installed without being requested:\n%s", plan)
+	}
+
+	if _, err := Plan(fedora, Options{Runtime: "lxc"}); err == nil {
+		t.Error("expected error for unknown runtime")
+	}
+}
+
+func TestPlanSkipsInstalledComponents(t *testing.T) {
+	originalLookPath := lookPath
+	defer func() { lookPath = originalLookPath }()
+	lookPath = func(name string) (string, error) {
+		if name == "caddy" {
+			return "/usr/bin/caddy", nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	steps, err := Plan(Distro{ID: "fedora", Family: FamilyFedora, DockerDir: "fedora"}, Options{})
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if plan := planText(steps); strings.Contains(plan, "caddy") {
+		t.Errorf("caddy is already installed but planned:\n%s", plan)
+	}
+}
+
+func planText(steps []Step) string {
+	lines := make([]string, 0, len(steps))
+	for _, step := range steps {
+		lines = append(lines, step.String()+" "+step.Content)
+	}
+	return strings.Join(lines, "\n")
+}
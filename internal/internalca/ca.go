@@ -0,0 +1,260 @@
+// Package internalca is a small certificate authority for hostnames that only resolve in the
+// LAN. Public CAs cannot issue certificates for them, so TreeOS signs its own and clients
+// trust them after installing the CA certificate once.
+package internalca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File names in the CA directory
+const (
+	certFile = "ca.crt"
+	keyFile  = "ca.key"
+)
+
+// Lifetimes of the CA and the certificates it issues. Apple platforms reject leaf
+// certificates valid for more than 825 days even from user-installed CAs. Client
+// certificates aren't renewed automatically, so they last longer.
+const (
+	caValidity     = 10 * 365 * 24 * time.Hour
+	LeafValidity   = 90 * 24 * time.Hour
+	ClientValidity = 2 * 365 * 24 * time.Hour
+)
+
+// CA signs certificates for internal hostnames
+type CA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+}
+
+// LoadOrCreate loads the CA from dir, creating a new one on first use
+func LoadOrCreate(dir, name string) (*CA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, certFile)) //nolint:gosec // Path from configuration
+	if err == nil {
+		keyPEM, err := os.ReadFile(filepath.Join(dir, keyFile)) //nolint:gosec // Path from configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA key: %w", err)
+		}
+		return parse(certPEM, keyPEM)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	ca, keyPEM, err := create(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create CA directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, keyFile), keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write CA key: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, certFile), ca.certPEM, 0644); err != nil { //nolint:gosec // Public certificate
+		return nil, fmt.Errorf("failed to write CA certificate: %w", err)
+	}
+	return ca, nil
+}
+
+func create(name string) (*CA, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"TreeOS"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode CA key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	ca, err := parse(certPEM, keyPEM)
+	return ca, keyPEM, err
+}
+
+func parse(certPEM, keyPEM []byte) (*CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, errors.New("CA certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("CA key is not PEM encoded")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, errors.New("CA key does not match the certificate")
+	}
+	return &CA{cert: cert, key: key, certPEM: certPEM}, nil
+}
+
+// CertificatePEM returns the CA certificate clients install to trust issued certificates
+func (ca *CA) CertificatePEM() []byte {
+	return ca.certPEM
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the CA certificate, to compare it with
+// the one a client shows before trusting it
+func (ca *CA) Fingerprint() string {
+	sum := sha256.Sum256(ca.cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// Certificate returns the CA certificate, e.g. to include it in client certificate bundles
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// NotAfter returns when the CA certificate expires
+func (ca *CA) NotAfter() time.Time {
+	return ca.cert.NotAfter
+}
+
+// Issue signs a server certificate for hosts, which may include wildcards such as
+// *.home.arpa. It returns the PEM encoded certificate and key.
+func (ca *CA) Issue(hosts []string) (certPEM, keyPEM []byte, err error) {
+	if len(hosts) == 0 {
+		return nil, nil, errors.New("no hosts to issue a certificate for")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(LeafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// IssueClient signs a certificate that authenticates a client as name. The key is returned
+// instead of written anywhere, callers hand it to the client and forget it.
+func (ca *CA) IssueClient(name string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if name == "" {
+		return nil, nil, errors.New("no name to issue a client certificate for")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"TreeOS"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(ClientValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign client certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+	return cert, key, nil
+}
+
+// NeedsRenewal reports whether an issued certificate is missing, expires within a third
+// of its lifetime, or does not cover hosts
+func NeedsRenewal(certPEM []byte, hosts []string, now time.Time) bool {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	if now.Add(LeafValidity / 3).After(cert.NotAfter) {
+		return true
+	}
+	if len(cert.DNSNames) != len(hosts) {
+		return true
+	}
+	for i, host := range hosts {
+		if cert.DNSNames[i] != host {
+			return true
+		}
+	}
+	return false
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
@@ -0,0 +1,96 @@
+package internalca
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadOrCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ca")
+	ca, err := LoadOrCreate(dir, "TreeOS Internal CA")
+	if err != nil {
+		t.Fatalf("LoadOrCreate() error = %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, keyFile)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("CA key mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	reloaded, err := LoadOrCreate(dir, "TreeOS Internal CA")
+	if err != nil {
+		t.Fatalf("LoadOrCreate() reload error = %v", err)
+	}
+	if reloaded.Fingerprint() != ca.Fingerprint() {
+		t.Error("reloading created a new CA")
+	}
+}
+
+func TestIssue(t *testing.T) {
+	ca, err := LoadOrCreate(t.TempDir(), "TreeOS Internal CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := []string{"mynode.lan", "*.mynode.lan"}
+	certPEM, keyPEM, err := ca.Issue(hosts)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if block, _ := pem.Decode(keyPEM); block == nil {
+		t.Fatal("key is not PEM encoded")
+	}
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.CertificatePEM())
+	if _, err := cert.Verify(x509.VerifyOptions{DNSName: "photos.mynode.lan", Roots: roots}); err != nil {
+		t.Errorf("issued certificate does not verify: %v", err)
+	}
+
+	now := time.Now()
+	if NeedsRenewal(certPEM, hosts, now) {
+		t.Error("fresh certificate needs renewal")
+	}
+	if !NeedsRenewal(certPEM, hosts, now.Add(LeafValidity-time.Hour*24*7)) {
+		t.Error("certificate expiring in a week does not need renewal")
+	}
+	if !NeedsRenewal(certPEM, []string{"other.lan"}, now) {
+		t.Error("certificate for other hosts does not need renewal")
+	}
+	if !NeedsRenewal(nil, hosts, now) {
+		t.Error("missing certificate does not need renewal")
+	}
+}
+
+func TestIssueClient(t *testing.T) {
+	ca, err := LoadOrCreate(t.TempDir(), "TreeOS Internal CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, key, err := ca.IssueClient("admin")
+	if err != nil {
+		t.Fatalf("IssueClient() error = %v", err)
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		t.Error("key does not match the certificate")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("client certificate does not verify: %v", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err == nil {
+		t.Error("client certificate is valid for servers")
+	}
+	if _, _, err := ca.IssueClient(""); err == nil {
+		t.Error("IssueClient() without a name succeeded")
+	}
+}
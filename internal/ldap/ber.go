@@ -0,0 +1,178 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER tags used by the LDAP messages TreeOS sends and reads (RFC 4511)
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	tagBindRequest       = 0x60
+	tagBindResponse      = 0x61
+	tagUnbindRequest     = 0x42
+	tagSearchRequest     = 0x63
+	tagSearchEntry       = 0x64
+	tagSearchDone        = 0x65
+	tagSearchReference   = 0x73
+	tagExtendedRequest   = 0x77
+	tagExtendedResponse  = 0x78
+	tagSimpleAuth        = 0x80
+	tagExtendedRequestID = 0x80
+)
+
+// maxMessageSize bounds the messages read from a server
+const maxMessageSize = 4 << 20
+
+// element is a decoded BER type-length-value
+type element struct {
+	tag     byte
+	content []byte
+}
+
+func encode(tag byte, content []byte) []byte {
+	length := len(content)
+	var header []byte
+	switch {
+	case length < 0x80:
+		header = []byte{tag, byte(length)}
+	case length < 0x100:
+		header = []byte{tag, 0x81, byte(length)}
+	case length < 0x10000:
+		header = []byte{tag, 0x82, byte(length >> 8), byte(length)}
+	default:
+		header = []byte{tag, 0x83, byte(length >> 16), byte(length >> 8), byte(length)}
+	}
+	return append(header, content...)
+}
+
+func encodeAll(tag byte, parts ...[]byte) []byte {
+	var content []byte
+	for _, part := range parts {
+		content = append(content, part...)
+	}
+	return encode(tag, content)
+}
+
+func encodeInt(tag byte, n int) []byte {
+	var content []byte
+	for {
+		content = append([]byte{byte(n)}, content...)
+		n >>= 8
+		if (n == 0 && content[0]&0x80 == 0) || (n == -1 && content[0]&0x80 != 0) {
+			break
+		}
+	}
+	return encode(tag, content)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(b bool) []byte {
+	if b {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0x00})
+}
+
+// readElement reads one element, e.g. a whole LDAPMessage, from a connection
+func readElement(r *bufio.Reader) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return element{}, err
+	}
+	if length > maxMessageSize {
+		return element{}, fmt.Errorf("message of %d bytes exceeds the limit", length)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return element{}, err
+	}
+	return element{tag: tag, content: content}, nil
+}
+
+func readLength(r io.ByteReader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+	count := int(first & 0x7f)
+	if count == 0 || count > 4 {
+		return 0, errors.New("unsupported BER length")
+	}
+	length := 0
+	for i := 0; i < count; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
+
+// children decodes the elements contained in a constructed element
+func (e element) children() ([]element, error) {
+	var result []element
+	data := e.content
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errors.New("truncated BER element")
+		}
+		tag := data[0]
+		reader := &byteReader{data: data[1:]}
+		length, err := readLength(reader)
+		if err != nil {
+			return nil, err
+		}
+		start := 1 + reader.pos
+		if length > len(data)-start {
+			return nil, errors.New("truncated BER element")
+		}
+		result = append(result, element{tag: tag, content: data[start : start+length]})
+		data = data[start+length:]
+	}
+	return result, nil
+}
+
+func (e element) int() int {
+	n := 0
+	for i, b := range e.content {
+		if i == 0 && b&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int(b)
+	}
+	return n
+}
+
+type byteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
@@ -0,0 +1,173 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Filter choice tags (RFC 4511 section 4.5.1)
+const (
+	filterAnd        = 0xa0
+	filterOr         = 0xa1
+	filterNot        = 0xa2
+	filterEquality   = 0xa3
+	filterSubstrings = 0xa4
+	filterGreater    = 0xa5
+	filterLess       = 0xa6
+	filterPresent    = 0x87
+)
+
+// EscapeFilter escapes a value for use in a search filter, e.g. a user name
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter encodes a string filter such as (&(objectClass=person)(uid=alice))
+func compileFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	encoded, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter %q: unexpected %q", filter, rest)
+	}
+	return encoded, nil
+}
+
+func parseFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, s, fmt.Errorf("expected ( at %q", s)
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, s, fmt.Errorf("unterminated filter")
+	}
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		s = s[1:]
+		var parts [][]byte
+		for strings.HasPrefix(s, "(") {
+			part, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, rest, err
+			}
+			parts = append(parts, part)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") {
+			return nil, s, fmt.Errorf("expected ) at %q", s)
+		}
+		return encodeAll(tag, parts...), s[1:], nil
+	case '!':
+		part, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, rest, err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, rest, fmt.Errorf("expected ) at %q", rest)
+		}
+		return encode(filterNot, part), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, s, fmt.Errorf("unterminated filter")
+	}
+	item, rest := s[:end], s[end+1:]
+	encoded, err := parseItem(item)
+	return encoded, rest, err
+}
+
+func parseItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("expected attribute=value in %q", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+	tag := byte(filterEquality)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = filterGreater, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLess, attr[:len(attr)-1]
+	case '~', ':':
+		return nil, fmt.Errorf("approximate and extensible matches are not supported")
+	}
+
+	if tag == filterEquality && value == "*" {
+		return encodeString(filterPresent, attr), nil
+	}
+	if tag == filterEquality && strings.Contains(value, "*") {
+		return parseSubstrings(attr, value)
+	}
+	unescaped, err := unescapeFilter(value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeAll(tag, encodeString(tagOctetString, attr), encodeString(tagOctetString, unescaped)), nil
+}
+
+func parseSubstrings(attr, value string) ([]byte, error) {
+	pieces := strings.Split(value, "*")
+	var parts [][]byte
+	for i, piece := range pieces {
+		if piece == "" {
+			continue
+		}
+		unescaped, err := unescapeFilter(piece)
+		if err != nil {
+			return nil, err
+		}
+		tag := byte(0x81) // any
+		switch i {
+		case 0:
+			tag = 0x80 // initial
+		case len(pieces) - 1:
+			tag = 0x82 // final
+		}
+		parts = append(parts, encodeString(tag, unescaped))
+	}
+	return encodeAll(filterSubstrings, encodeString(tagOctetString, attr), encodeAll(tagSequence, parts...)), nil
+}
+
+func unescapeFilter(value string) (string, error) {
+	if !strings.Contains(value, "\\") {
+		return value, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("incomplete escape in %q", value)
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,348 @@
+// Package ldap authenticates users against an LDAP directory such as LLDAP, OpenLDAP or
+// Active Directory. It implements the few LDAPv3 operations a login needs: simple bind,
+// search and StartTLS, without Kerberos or SASL.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCredentials is returned for unknown users and wrong passwords alike
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// DefaultUserFilter finds users by their uid, Active Directory uses sAMAccountName instead
+const DefaultUserFilter = "(&(objectClass=person)(uid={username}))"
+
+// DefaultGroupAttribute lists the groups of a user in LLDAP, OpenLDAP with memberof and AD
+const DefaultGroupAttribute = "memberOf"
+
+// defaultTimeout bounds a login when the context has no deadline
+const defaultTimeout = 10 * time.Second
+
+// resultInvalidCredentials is the LDAP result code of a failed bind
+const resultInvalidCredentials = 49
+
+// startTLSOID names the StartTLS extended operation
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// Config describes how to reach and search the directory
+type Config struct {
+	URL                string // ldap://host:389 or ldaps://host:636
+	StartTLS           bool   // Upgrade ldap:// connections to TLS
+	InsecureSkipVerify bool   // Accept any server certificate, for self-signed test setups
+	BindDN             string // Service account used to search, empty for anonymous search
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // {username} is replaced with the escaped login name
+	GroupAttribute     string
+}
+
+// Entry is the directory entry of an authenticated user
+type Entry struct {
+	DN         string
+	Attributes map[string][]string // Lower-cased attribute names
+}
+
+// Get returns the first value of an attribute
+func (e *Entry) Get(name string) string {
+	if values := e.Attributes[strings.ToLower(name)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values returns all values of an attribute
+func (e *Entry) Values(name string) []string {
+	return e.Attributes[strings.ToLower(name)]
+}
+
+// Authenticate looks up a user with the service account and binds as the user to verify
+// the password
+func (c Config) Authenticate(ctx context.Context, username, password string) (*Entry, error) {
+	// An empty password is an unauthenticated bind, which servers accept for any DN
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	if c.BindDN != "" {
+		if err := conn.bind(c.BindDN, c.BindPassword); err != nil {
+			if errors.Is(err, ErrInvalidCredentials) {
+				return nil, fmt.Errorf("service account bind failed: %w", err)
+			}
+			return nil, err
+		}
+	}
+
+	filter := c.UserFilter
+	if filter == "" {
+		filter = DefaultUserFilter
+	}
+	groupAttribute := c.GroupAttribute
+	if groupAttribute == "" {
+		groupAttribute = DefaultGroupAttribute
+	}
+	entries, err := conn.search(c.BaseDN, strings.ReplaceAll(filter, "{username}", EscapeFilter(username)),
+		[]string{"uid", "mail", "givenName", "sn", "displayName", groupAttribute})
+	if err != nil {
+		return nil, err
+	}
+	switch len(entries) {
+	case 0:
+		return nil, ErrInvalidCredentials
+	case 1:
+	default:
+		return nil, fmt.Errorf("%d directory entries match user %s, make the user filter unique", len(entries), username)
+	}
+
+	if err := conn.bind(entries[0].DN, password); err != nil {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+// MemberOf reports whether an entry belongs to a group, given as DN or as common name
+func (c Config) MemberOf(entry *Entry, group string) bool {
+	if group == "" {
+		return false
+	}
+	groupAttribute := c.GroupAttribute
+	if groupAttribute == "" {
+		groupAttribute = DefaultGroupAttribute
+	}
+	for _, dn := range entry.Values(groupAttribute) {
+		if strings.EqualFold(dn, group) {
+			return true
+		}
+		firstRDN := strings.SplitN(dn, ",", 2)[0]
+		if name, ok := strings.CutPrefix(strings.ToLower(firstRDN), "cn="); ok && name == strings.ToLower(group) {
+			return true
+		}
+	}
+	return false
+}
+
+type conn struct {
+	net    net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+func (c Config) dial(ctx context.Context) (*conn, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+	host := u.Host
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: c.InsecureSkipVerify, MinVersion: tls.VersionTLS12} //nolint:gosec // Opt-in for self-signed directories
+
+	var dialer net.Dialer
+	var netConn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		netConn, err = dialer.DialContext(ctx, "tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		netConn, err = (&tls.Dialer{NetDialer: &dialer, Config: tlsConfig}).DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("unsupported LDAP URL scheme %q, use ldap or ldaps", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	}
+
+	connection := &conn{net: netConn, reader: bufio.NewReader(netConn)}
+	if c.StartTLS && u.Scheme == "ldap" {
+		if err := connection.startTLS(ctx, tlsConfig); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	return connection, nil
+}
+
+func (c *conn) close() {
+	_ = c.send(encode(tagUnbindRequest, nil))
+	_ = c.net.Close()
+}
+
+func (c *conn) send(op []byte) error {
+	c.nextID++
+	_, err := c.net.Write(encodeAll(tagSequence, encodeInt(tagInteger, c.nextID), op))
+	return err
+}
+
+// receive reads the next message and returns its protocol operation
+func (c *conn) receive() (element, error) {
+	message, err := readElement(c.reader)
+	if err != nil {
+		return element{}, fmt.Errorf("failed to read LDAP response: %w", err)
+	}
+	parts, err := message.children()
+	if err != nil || len(parts) < 2 {
+		return element{}, errors.New("malformed LDAP response")
+	}
+	return parts[1], nil
+}
+
+// result checks the LDAPResult of a response
+func result(op element) error {
+	parts, err := op.children()
+	if err != nil || len(parts) < 3 {
+		return errors.New("malformed LDAP result")
+	}
+	switch code := parts[0].int(); code {
+	case 0:
+		return nil
+	case resultInvalidCredentials:
+		return ErrInvalidCredentials
+	default:
+		message := string(parts[2].content)
+		if message == "" {
+			message = "no details"
+		}
+		return fmt.Errorf("LDAP error %d: %s", code, message)
+	}
+}
+
+func (c *conn) bind(dn, password string) error {
+	if err := c.send(encodeAll(tagBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	)); err != nil {
+		return err
+	}
+	op, err := c.receive()
+	if err != nil {
+		return err
+	}
+	if op.tag != tagBindResponse {
+		return fmt.Errorf("unexpected LDAP response 0x%02x to bind", op.tag)
+	}
+	return result(op)
+}
+
+func (c *conn) startTLS(ctx context.Context, config *tls.Config) error {
+	if err := c.send(encode(tagExtendedRequest, encodeString(tagExtendedRequestID, startTLSOID))); err != nil {
+		return err
+	}
+	op, err := c.receive()
+	if err != nil {
+		return err
+	}
+	if op.tag != tagExtendedResponse {
+		return fmt.Errorf("unexpected LDAP response 0x%02x to StartTLS", op.tag)
+	}
+	if err := result(op); err != nil {
+		return fmt.Errorf("StartTLS failed: %w", err)
+	}
+	tlsConn := tls.Client(c.net, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("StartTLS handshake failed: %w", err)
+	}
+	c.net = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+func (c *conn) search(baseDN, filter string, attributes []string) ([]*Entry, error) {
+	encodedFilter, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var attrs [][]byte
+	for _, attribute := range attributes {
+		attrs = append(attrs, encodeString(tagOctetString, attribute))
+	}
+	if err := c.send(encodeAll(tagSearchRequest,
+		encodeString(tagOctetString, baseDN),
+		encodeInt(tagEnumerated, 2), // whole subtree
+		encodeInt(tagEnumerated, 0), // never dereference aliases
+		encodeInt(tagInteger, 2),    // two entries are enough to detect ambiguous filters
+		encodeInt(tagInteger, int(defaultTimeout/time.Second)),
+		encodeBool(false),
+		encodedFilter,
+		encodeAll(tagSequence, attrs...),
+	)); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for {
+		op, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case tagSearchEntry:
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case tagSearchReference:
+			// Referrals to other servers are not followed
+		case tagSearchDone:
+			if err := result(op); err != nil && len(entries) == 0 {
+				return nil, err
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("unexpected LDAP response 0x%02x to search", op.tag)
+		}
+	}
+}
+
+func parseEntry(op element) (*Entry, error) {
+	parts, err := op.children()
+	if err != nil || len(parts) < 2 {
+		return nil, errors.New("malformed search entry")
+	}
+	entry := &Entry{DN: string(parts[0].content), Attributes: map[string][]string{}}
+	attributes, err := parts[1].children()
+	if err != nil {
+		return nil, errors.New("malformed search entry attributes")
+	}
+	for _, attribute := range attributes {
+		fields, err := attribute.children()
+		if err != nil || len(fields) < 2 {
+			return nil, errors.New("malformed search entry attribute")
+		}
+		values, err := fields[1].children()
+		if err != nil {
+			return nil, errors.New("malformed search entry values")
+		}
+		name := strings.ToLower(string(fields[0].content))
+		for _, value := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(value.content))
+		}
+	}
+	return entry, nil
+}
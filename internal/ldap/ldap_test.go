@@ -0,0 +1,158 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeDirectory answers binds and searches like a directory with a single user
+type fakeDirectory struct {
+	passwords map[string]string // DN to password
+	userDN    string
+	uid       string
+	groups    []string
+}
+
+func (d *fakeDirectory) serve(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.handle(conn)
+		}
+	}()
+	return "ldap://" + listener.Addr().String()
+}
+
+func (d *fakeDirectory) handle(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck // Test server
+	reader := bufio.NewReader(conn)
+	for {
+		message, err := readElement(reader)
+		if err != nil {
+			return
+		}
+		parts, _ := message.children()
+		id := parts[0].int()
+		op := parts[1]
+		reply := func(tag byte, body ...[]byte) {
+			_, _ = conn.Write(encodeAll(tagSequence, encodeInt(tagInteger, id), encodeAll(tag, body...)))
+		}
+		ldapResult := func(code int) [][]byte {
+			return [][]byte{encodeInt(tagEnumerated, code), encodeString(tagOctetString, ""), encodeString(tagOctetString, "")}
+		}
+
+		switch op.tag {
+		case tagBindRequest:
+			fields, _ := op.children()
+			dn, password := string(fields[1].content), string(fields[2].content)
+			if expected, ok := d.passwords[dn]; ok && expected == password {
+				reply(tagBindResponse, ldapResult(0)...)
+			} else {
+				reply(tagBindResponse, ldapResult(resultInvalidCredentials)...)
+			}
+		case tagSearchRequest:
+			fields, _ := op.children()
+			if bytes.Contains(fields[6].content, []byte(d.uid)) {
+				var groups [][]byte
+				for _, group := range d.groups {
+					groups = append(groups, encodeString(tagOctetString, group))
+				}
+				reply(tagSearchEntry,
+					encodeString(tagOctetString, d.userDN),
+					encodeAll(tagSequence,
+						encodeAll(tagSequence, encodeString(tagOctetString, "mail"), encodeAll(tagSet, encodeString(tagOctetString, "alice@example.org"))),
+						encodeAll(tagSequence, encodeString(tagOctetString, "memberOf"), encodeAll(tagSet, groups...)),
+					))
+			}
+			reply(tagSearchDone, ldapResult(0)...)
+		case tagUnbindRequest:
+			return
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	directory := &fakeDirectory{
+		passwords: map[string]string{
+			"cn=treeos,ou=people,dc=example,dc=org": "service-secret",
+			"uid=alice,ou=people,dc=example,dc=org": "alice-secret",
+		},
+		userDN: "uid=alice,ou=people,dc=example,dc=org",
+		uid:    "alice",
+		groups: []string{"cn=lldap_admin,ou=groups,dc=example,dc=org", "cn=family,ou=groups,dc=example,dc=org"},
+	}
+	config := Config{
+		URL:          directory.serve(t),
+		BindDN:       "cn=treeos,ou=people,dc=example,dc=org",
+		BindPassword: "service-secret",
+		BaseDN:       "dc=example,dc=org",
+	}
+	ctx := context.Background()
+
+	entry, err := config.Authenticate(ctx, "alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if entry.DN != directory.userDN || entry.Get("mail") != "alice@example.org" {
+		t.Errorf("Authenticate() = %+v", entry)
+	}
+	if !config.MemberOf(entry, "lldap_admin") || !config.MemberOf(entry, "CN=family,ou=groups,dc=example,dc=org") || config.MemberOf(entry, "staff") {
+		t.Errorf("MemberOf() does not match the groups %v", entry.Values("memberof"))
+	}
+
+	for name, tc := range map[string]struct{ username, password string }{
+		"wrong password": {"alice", "wrong"},
+		"unknown user":   {"bob", "alice-secret"},
+		"empty password": {"alice", ""},
+	} {
+		if _, err := config.Authenticate(ctx, tc.username, tc.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: Authenticate() error = %v, want ErrInvalidCredentials", name, err)
+		}
+	}
+
+	config.BindPassword = "wrong"
+	if _, err := config.Authenticate(ctx, "alice", "alice-secret"); err == nil || errors.Is(err, ErrInvalidCredentials) && err.Error() == ErrInvalidCredentials.Error() {
+		t.Errorf("Authenticate() with a wrong service password error = %v, want a service account error", err)
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	valid := []string{
+		DefaultUserFilter,
+		"(|(uid=alice)(mail=alice@example.org))",
+		"(!(userAccountControl=514))",
+		"(cn=Al*ce*)",
+		"objectClass=*",
+		"(uid=" + EscapeFilter("a*(b)\\") + ")",
+	}
+	for _, filter := range valid {
+		if _, err := compileFilter(filter); err != nil {
+			t.Errorf("compileFilter(%q) error = %v", filter, err)
+		}
+	}
+	invalid := []string{"(uid=alice", "(&(uid=alice)", "(uid~=alice)", "(uid=\\zz)", "(uid=alice))"}
+	for _, filter := range invalid {
+		if _, err := compileFilter(filter); err == nil {
+			t.Errorf("compileFilter(%q) succeeded", filter)
+		}
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	if got := EscapeFilter("a*(b)\\"); got != `a\2a\28b\29\5c` {
+		t.Errorf("EscapeFilter() = %q", got)
+	}
+}
@@ -0,0 +1,293 @@
+// Package logparse turns docker compose log output into entries with service, time and level.
+// Containers log in many formats, so detection is best effort: JSON and logfmt fields first,
+// then level words and timestamps at the start of the message. Lines without a recognizable
+// level or time keep them empty.
+package logparse
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Level is the normalized severity of an entry
+type Level string
+
+// Levels from most to least severe
+const (
+	LevelError Level = "error"
+	LevelWarn  Level = "warn"
+	LevelInfo  Level = "info"
+	LevelDebug Level = "debug"
+	LevelNone  Level = ""
+)
+
+// rank orders levels for minimum level filters, entries without level rank lowest
+var rank = map[Level]int{LevelError: 4, LevelWarn: 3, LevelInfo: 2, LevelDebug: 1}
+
+// ParseLevel normalizes a level name like "ERR", "warning" or "fatal"
+func ParseLevel(name string) Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "error", "err", "erro", "fatal", "panic", "crit", "critical", "emerg", "emergency", "alert", "severe":
+		return LevelError
+	case "warn", "warning", "wrn":
+		return LevelWarn
+	case "info", "inf", "notice", "information":
+		return LevelInfo
+	case "debug", "dbg", "trace", "verbose":
+		return LevelDebug
+	}
+	return LevelNone
+}
+
+// AtLeast reports whether l is min or more severe. Every entry passes an empty minimum.
+func (l Level) AtLeast(min Level) bool {
+	return min == LevelNone || rank[l] >= rank[min]
+}
+
+// Entry is one parsed log line
+type Entry struct {
+	Service string     `json:"service"`
+	Time    *time.Time `json:"time,omitempty"`
+	Level   Level      `json:"level,omitempty"`
+	Message string     `json:"message"`
+}
+
+var (
+	// composePrefix is what docker compose puts before each line, "web-1  | "
+	composePrefix = regexp.MustCompile(`^(\S+)\s+\| ?(.*)$`)
+
+	// leadingTime matches a timestamp at the start of a message, optionally in brackets
+	leadingTime = regexp.MustCompile(`^\[?(\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)\]?\s*`)
+
+	// logfmtLevel and logfmtTime match key=value fields
+	logfmtLevel = regexp.MustCompile(`(?:^|\s)(?:level|lvl|severity)="?(\w+)"?`)
+	logfmtTime  = regexp.MustCompile(`(?:^|\s)(?:time|ts|timestamp)="?([0-9T:.\-+Z ]+?)"?(?:\s|$)`)
+
+	// levelWord matches a level in capitals or in brackets near the start of a message,
+	// "ERROR:", "[warn]" or "<E>", but not "no error" in the text
+	levelWord = regexp.MustCompile(`(?:^|[\s\[(<|:])(FATAL|PANIC|CRIT(?:ICAL)?|EMERG|ERROR|ERRO?|WARN(?:ING)?|WRN|INFO|INF|NOTICE|DEBUG|DBG|TRACE)(?:$|[\s\])>|:,])`)
+	bracketed = regexp.MustCompile(`(?i)[\[(<](fatal|panic|crit|critical|emerg|alert|error|err|warn|warning|notice|info|debug|trace)[\])>]`)
+)
+
+// timeLayouts are tried in order for timestamps found in messages
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+}
+
+// levelSearchWindow limits the level word search to the start of a message, where log
+// formats put it. Further in, a capitalized ERROR is usually part of the text.
+const levelSearchWindow = 80
+
+// Parser parses the lines of one app
+type Parser struct {
+	services []string // Longest first, so "web-worker" matches before "web"
+}
+
+// NewParser returns a parser that maps container names to the app's services
+func NewParser(services []string) *Parser {
+	sorted := append([]string(nil), services...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return &Parser{services: sorted}
+}
+
+// service returns the service a container belongs to. Compose names containers
+// <project>-<service>-<n>, containers with a container_name keep their name.
+func (p *Parser) service(container string) string {
+	for _, service := range p.services {
+		if container == service {
+			return service
+		}
+		i := strings.LastIndex(container, "-")
+		if i < 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(container[i+1:]); err != nil {
+			continue
+		}
+		if base := container[:i]; base == service || strings.HasSuffix(base, "-"+service) || strings.HasSuffix(base, "_"+service) {
+			return service
+		}
+	}
+	return container
+}
+
+// Parse parses log output, one entry per non-empty line
+func (p *Parser) Parse(output string) []Entry {
+	lines := strings.Split(output, "\n")
+	entries := make([]Entry, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entries = append(entries, p.ParseLine(line))
+	}
+	return entries
+}
+
+// SplitLine splits the compose prefix and a leading timestamp, the time docker received the
+// line with docker compose logs --timestamps, off one line of output
+func (p *Parser) SplitLine(line string) (service string, received *time.Time, message string) {
+	message = line
+	if match := composePrefix.FindStringSubmatch(line); match != nil {
+		service = p.service(match[1])
+		message = match[2]
+	}
+	received, message = leadingTimestamp(message)
+	return service, received, message
+}
+
+// ParseLine parses one line of docker compose logs output
+func (p *Parser) ParseLine(line string) Entry {
+	// docker compose logs --timestamps puts the time the line was received first, a time
+	// in the message itself is more precise about when it happened but both are fine
+	var entry Entry
+	entry.Service, entry.Time, entry.Message = p.SplitLine(line)
+	if strings.HasPrefix(strings.TrimSpace(entry.Message), "{") && parseJSON(&entry) {
+		return entry
+	}
+	if t, rest := leadingTimestamp(entry.Message); t != nil {
+		entry.Time, entry.Message = t, rest
+	}
+
+	window := entry.Message
+	if len(window) > levelSearchWindow {
+		window = window[:levelSearchWindow]
+	}
+	if match := logfmtLevel.FindStringSubmatch(entry.Message); match != nil {
+		entry.Level = ParseLevel(match[1])
+		if entry.Time == nil {
+			if t := logfmtTime.FindStringSubmatch(entry.Message); t != nil {
+				entry.Time = parseTime(strings.TrimSpace(t[1]))
+			}
+		}
+	}
+	if entry.Level == LevelNone {
+		if match := levelWord.FindStringSubmatch(window); match != nil {
+			entry.Level = ParseLevel(match[1])
+		} else if match := bracketed.FindStringSubmatch(window); match != nil {
+			entry.Level = ParseLevel(match[1])
+		}
+	}
+	if entry.Level == LevelNone && (strings.HasPrefix(entry.Message, "panic:") || strings.HasPrefix(entry.Message, "Traceback (most recent call last)")) {
+		entry.Level = LevelError
+	}
+	return entry
+}
+
+// leadingTimestamp splits a timestamp off the start of a message
+func leadingTimestamp(message string) (*time.Time, string) {
+	match := leadingTime.FindStringSubmatchIndex(message)
+	if match == nil {
+		return nil, message
+	}
+	t := parseTime(message[match[2]:match[3]])
+	if t == nil {
+		return nil, message
+	}
+	return t, message[match[1]:]
+}
+
+// parseTime parses the timestamp formats of timeLayouts, times without zone are local
+func parseTime(value string) *time.Time {
+	value = strings.Replace(value, ",", ".", 1)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// parseJSON reads level, message and time of a JSON log line
+func parseJSON(entry *Entry) bool {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(entry.Message)), &fields); err != nil {
+		return false
+	}
+	for _, key := range []string{"level", "lvl", "severity", "log.level", "levelname"} {
+		switch v := fields[key].(type) {
+		case string:
+			entry.Level = ParseLevel(v)
+		case float64:
+			// Numeric levels of pino and bunyan
+			switch {
+			case v >= 50:
+				entry.Level = LevelError
+			case v >= 40:
+				entry.Level = LevelWarn
+			case v >= 30:
+				entry.Level = LevelInfo
+			default:
+				entry.Level = LevelDebug
+			}
+		}
+		if entry.Level != LevelNone {
+			break
+		}
+	}
+	if entry.Time == nil {
+		for _, key := range []string{"time", "ts", "timestamp", "@timestamp"} {
+			switch v := fields[key].(type) {
+			case string:
+				entry.Time = parseTime(v)
+			case float64:
+				// Unix time in seconds or milliseconds
+				if v > 1e12 {
+					v /= 1000
+				}
+				t := time.Unix(int64(v), int64((v-float64(int64(v)))*1e9))
+				entry.Time = &t
+			}
+			if entry.Time != nil {
+				break
+			}
+		}
+	}
+	for _, key := range []string{"msg", "message"} {
+		if msg, ok := fields[key].(string); ok && msg != "" {
+			entry.Message = msg
+			break
+		}
+	}
+	return true
+}
+
+// Filter selects entries, zero fields match everything
+type Filter struct {
+	Service string `json:"service"`
+	Level   Level  `json:"level"` // Minimum level
+	Query   string `json:"query"` // Case-insensitive text in the message
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(entry Entry) bool {
+	if f.Service != "" && f.Service != entry.Service {
+		return false
+	}
+	if f.Level != LevelNone && (entry.Level == LevelNone || !entry.Level.AtLeast(f.Level)) {
+		return false
+	}
+	return f.Query == "" || strings.Contains(strings.ToLower(entry.Message), strings.ToLower(f.Query))
+}
+
+// Apply returns the entries that pass the filter
+func (f Filter) Apply(entries []Entry) []Entry {
+	matched := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if f.Match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
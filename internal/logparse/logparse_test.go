@@ -0,0 +1,125 @@
+package logparse
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	parser := NewParser([]string{"web", "worker", "web-worker"})
+
+	tests := []struct {
+		name        string
+		line        string
+		wantService string
+		wantLevel   Level
+		wantTime    string
+		wantMessage string
+	}{
+		{name: "docker timestamp and python logging", line: "ontree-app-worker-1  | 2024-05-01T10:00:00.123456789Z ERROR:root:Job failed",
+			wantService: "worker", wantLevel: LevelError, wantTime: "2024-05-01T10:00:00.123456789Z", wantMessage: "ERROR:root:Job failed"},
+		{name: "longest service name wins", line: "ontree-app-web-worker-1  | INFO ready", wantService: "web-worker", wantLevel: LevelInfo, wantMessage: "INFO ready"},
+		{name: "nginx bracketed level", line: "web-1  | 2024/05/01 10:00:00 [warn] 1#1: conflicting server name",
+			wantService: "web", wantLevel: LevelWarn, wantTime: "2024-05-01T10:00:00", wantMessage: "[warn] 1#1: conflicting server name"},
+		{name: "logfmt", line: `web-1  | time="2024-05-01T10:00:00Z" level=error msg="db down"`,
+			wantService: "web", wantLevel: LevelError, wantTime: "2024-05-01T10:00:00Z", wantMessage: `time="2024-05-01T10:00:00Z" level=error msg="db down"`},
+		{name: "json with numeric level", line: `web-1  | {"level":50,"time":1714557600000,"msg":"request failed"}`,
+			wantService: "web", wantLevel: LevelError, wantTime: "2024-05-01T10:00:00Z", wantMessage: "request failed"},
+		{name: "json with level name", line: `web-1  | {"severity":"WARNING","timestamp":"2024-05-01T10:00:00Z","message":"slow query"}`,
+			wantService: "web", wantLevel: LevelWarn, wantTime: "2024-05-01T10:00:00Z", wantMessage: "slow query"},
+		{name: "error in the text is no level", line: "web-1  | Request completed with no error", wantService: "web", wantMessage: "Request completed with no error"},
+		{name: "go panic", line: "worker-1  | panic: runtime error", wantService: "worker", wantLevel: LevelError, wantMessage: "panic: runtime error"},
+		{name: "custom container name", line: "my-db  | LOG: ready", wantService: "my-db", wantMessage: "LOG: ready"},
+		{name: "without compose prefix", line: "WARN disk almost full", wantLevel: LevelWarn, wantMessage: "WARN disk almost full"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := parser.ParseLine(tt.line)
+			if entry.Service != tt.wantService || entry.Level != tt.wantLevel || entry.Message != tt.wantMessage {
+				t.Errorf("ParseLine() = %+v, want service %q level %q message %q", entry, tt.wantService, tt.wantLevel, tt.wantMessage)
+			}
+			if tt.wantTime == "" {
+				if entry.Time != nil {
+					t.Errorf("time = %v, want none", entry.Time)
+				}
+				return
+			}
+			want, err := time.ParseInLocation(time.RFC3339Nano, tt.wantTime, time.Local)
+			if err != nil {
+				want, _ = time.ParseInLocation("2006-01-02T15:04:05", tt.wantTime, time.Local)
+			}
+			if entry.Time == nil || !entry.Time.Equal(want) {
+				t.Errorf("time = %v, want %v", entry.Time, want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entries := []Entry{
+		{Service: "web", Level: LevelError, Message: "Database timeout"},
+		{Service: "web", Level: LevelWarn, Message: "slow request"},
+		{Service: "worker", Level: LevelError, Message: "job failed"},
+		{Service: "worker", Message: "no level"},
+	}
+	tests := []struct {
+		filter Filter
+		want   []int
+	}{
+		{Filter{}, []int{0, 1, 2, 3}},
+		{Filter{Level: LevelWarn}, []int{0, 1, 2}},
+		{Filter{Service: "worker", Level: LevelError}, []int{2}},
+		{Filter{Query: "DATABASE"}, []int{0}},
+	}
+	for _, tt := range tests {
+		var want []Entry
+		for _, i := range tt.want {
+			want = append(want, entries[i])
+		}
+		if got := tt.filter.Apply(entries); !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v.Apply() = %+v, want %+v", tt.filter, got, want)
+		}
+	}
+}
+
+func TestSpikes(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var entries []Entry
+	add := func(minute, count int, service string, level Level) {
+		for i := 0; i < count; i++ {
+			at := start.Add(time.Duration(minute)*time.Minute + time.Duration(i)*time.Second)
+			entries = append(entries, Entry{Service: service, Time: &at, Level: level})
+		}
+	}
+	for minute := 0; minute < 30; minute++ {
+		add(minute, 5, "web", LevelInfo)
+		add(minute, 1, "web", LevelError)
+	}
+	add(12, 20, "worker", LevelError)
+	add(13, 15, "web", LevelError)
+
+	timeline := Timeline(entries, time.Minute)
+	if len(timeline) != 30 || timeline[12].Errors != 21 || timeline[0].Total != 6 {
+		t.Fatalf("Timeline() has %d buckets, bucket 12 = %+v", len(timeline), timeline[12])
+	}
+
+	spikes := Spikes(entries, time.Minute)
+	if len(spikes) != 1 {
+		t.Fatalf("Spikes() = %+v, want one", spikes)
+	}
+	spike := spikes[0]
+	if !spike.Start.Equal(start.Add(12*time.Minute)) || !spike.End.Equal(start.Add(14*time.Minute)) || spike.Errors != 37 {
+		t.Errorf("spike = %+v", spike)
+	}
+	if !reflect.DeepEqual(spike.Services, []string{"web", "worker"}) {
+		t.Errorf("spike services = %v", spike.Services)
+	}
+
+	if got := Interval(entries, 100); got != time.Minute {
+		t.Errorf("Interval() = %v, want 1m", got)
+	}
+	if got := Interval(entries, 10); got != 5*time.Minute {
+		t.Errorf("Interval() = %v, want 5m", got)
+	}
+}
@@ -0,0 +1,144 @@
+package logparse
+
+import (
+	"sort"
+	"time"
+)
+
+// Bucket counts the entries of one interval of the timeline
+type Bucket struct {
+	Start    time.Time `json:"start"`
+	Total    int       `json:"total"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+}
+
+// Spike is a run of buckets with unusually many errors
+type Spike struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Errors   int       `json:"errors"`
+	Services []string  `json:"services"`
+}
+
+// minSpikeErrors is the least number of errors in a bucket that counts as a spike, a few
+// errors per minute are normal for many apps
+const minSpikeErrors = 5
+
+// spikeFactor is how far above the average error rate of the timeline a spike has to be
+const spikeFactor = 3
+
+// maxBuckets bounds the timeline, a bogus timestamp years off would allocate millions of them
+const maxBuckets = 10000
+
+// intervals are the bucket sizes Interval picks from
+var intervals = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// Interval returns the shortest bucket size that covers the entries in at most n buckets
+func Interval(entries []Entry, n int) time.Duration {
+	var first, last time.Time
+	for _, entry := range entries {
+		if entry.Time == nil {
+			continue
+		}
+		if first.IsZero() || entry.Time.Before(first) {
+			first = *entry.Time
+		}
+		if entry.Time.After(last) {
+			last = *entry.Time
+		}
+	}
+	for _, interval := range intervals {
+		if int(last.Sub(first)/interval) < n {
+			return interval
+		}
+	}
+	return intervals[len(intervals)-1]
+}
+
+// Timeline counts entries with a time per interval, oldest first. Intervals without entries
+// are included so that the timeline has no gaps. Timelines of more than maxBuckets are
+// returned empty.
+func Timeline(entries []Entry, interval time.Duration) []Bucket {
+	var first, last time.Time
+	for _, entry := range entries {
+		if entry.Time == nil {
+			continue
+		}
+		t := entry.Time.Truncate(interval)
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	if first.IsZero() || last.Sub(first)/interval >= maxBuckets {
+		return nil
+	}
+
+	buckets := make([]Bucket, int(last.Sub(first)/interval)+1)
+	for i := range buckets {
+		buckets[i].Start = first.Add(time.Duration(i) * interval)
+	}
+	for _, entry := range entries {
+		if entry.Time == nil {
+			continue
+		}
+		b := &buckets[int(entry.Time.Truncate(interval).Sub(first)/interval)]
+		b.Total++
+		switch entry.Level {
+		case LevelError:
+			b.Errors++
+		case LevelWarn:
+			b.Warnings++
+		}
+	}
+	return buckets
+}
+
+// Spikes finds runs of timeline buckets with at least minSpikeErrors errors and spikeFactor
+// times the average errors per bucket. The services are those that logged errors during it.
+func Spikes(entries []Entry, interval time.Duration) []Spike {
+	buckets := Timeline(entries, interval)
+	if len(buckets) == 0 {
+		return nil
+	}
+	total := 0
+	for _, b := range buckets {
+		total += b.Errors
+	}
+	threshold := float64(total) / float64(len(buckets)) * spikeFactor
+	if threshold < minSpikeErrors {
+		threshold = minSpikeErrors
+	}
+
+	var spikes []Spike
+	var current *Spike
+	for _, b := range buckets {
+		if float64(b.Errors) < threshold {
+			current = nil
+			continue
+		}
+		if current == nil {
+			spikes = append(spikes, Spike{Start: b.Start})
+			current = &spikes[len(spikes)-1]
+		}
+		current.End = b.Start.Add(interval)
+		current.Errors += b.Errors
+	}
+
+	for i := range spikes {
+		services := map[string]bool{}
+		for _, entry := range entries {
+			if entry.Level == LevelError && entry.Time != nil && !entry.Time.Before(spikes[i].Start) && entry.Time.Before(spikes[i].End) {
+				services[entry.Service] = true
+			}
+		}
+		for service := range services {
+			spikes[i].Services = append(spikes[i].Services, service)
+		}
+		sort.Strings(spikes[i].Services)
+	}
+	return spikes
+}
@@ -0,0 +1,103 @@
+// Package lowmem picks the intervals and buffer sizes TreeOS runs with. Nodes with little
+// memory, such as a Raspberry Pi 3, get a profile that polls less often, keeps less in memory
+// and runs one compose operation at a time, so the UI stays responsive while apps start.
+package lowmem
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// Modes of the low_memory setting
+const (
+	ModeAuto = "auto" // Low-memory profile below Threshold
+	ModeOn   = "on"
+	ModeOff  = "off"
+)
+
+// Threshold is the total memory below which auto mode uses the low-memory profile. A 2 GB
+// board reports a little less than 2 GiB after the firmware's share.
+const Threshold = 2 << 30
+
+// Profile holds the settings that depend on the memory of the node
+type Profile struct {
+	LowMemory       bool
+	TotalMemory     uint64        // Bytes, 0 if unknown
+	VitalsInterval  time.Duration // Between stored system vitals
+	RealtimeMetrics bool          // Collect CPU and network every second for the live cards
+	UIPollInterval  time.Duration // Shortest refresh interval of dashboard cards
+	CacheTTL        time.Duration // Of rendered sparklines
+	SSEBuffer       int           // Messages queued per server-sent events client
+	ComposeSlots    int           // Concurrent compose up and down operations, 0 for no limit
+	GCPercent       int           // GOGC, 0 keeps the runtime default
+}
+
+// Default is the profile of nodes with enough memory
+var Default = Profile{
+	VitalsInterval:  30 * time.Second,
+	RealtimeMetrics: true,
+	UIPollInterval:  time.Second,
+	CacheTTL:        5 * time.Minute,
+	SSEBuffer:       256,
+}
+
+// Low is the profile of nodes with little memory
+var Low = Profile{
+	LowMemory:      true,
+	VitalsInterval: 2 * time.Minute,
+	UIPollInterval: 10 * time.Second,
+	CacheTTL:       time.Minute,
+	SSEBuffer:      32,
+	ComposeSlots:   1,
+	// Collect garbage before the heap doubles, trading some CPU for a smaller peak
+	GCPercent: 50,
+}
+
+// ValidMode reports whether mode is a value of the low_memory setting
+func ValidMode(mode string) bool {
+	return mode == ModeAuto || mode == ModeOn || mode == ModeOff
+}
+
+// Select returns the profile for a mode and the total memory of the node in bytes. Auto mode
+// with unknown memory keeps the default profile.
+func Select(mode string, totalMemory uint64) Profile {
+	profile := Default
+	if mode == ModeOn || (mode != ModeOff && totalMemory > 0 && totalMemory < Threshold) {
+		profile = Low
+	}
+	profile.TotalMemory = totalMemory
+	return profile
+}
+
+// Detect returns the profile for a mode on this node
+func Detect(mode string) Profile {
+	var total uint64
+	if stat, err := mem.VirtualMemory(); err == nil {
+		total = stat.Total
+	}
+	return Select(mode, total)
+}
+
+// Apply sets the runtime settings of the profile for the process. GOGC in the environment
+// takes precedence.
+func (p Profile) Apply() {
+	if p.GCPercent > 0 && os.Getenv("GOGC") == "" {
+		debug.SetGCPercent(p.GCPercent)
+	}
+}
+
+// String describes the profile for the log
+func (p Profile) String() string {
+	name := "default"
+	if p.LowMemory {
+		name = "low-memory"
+	}
+	if p.TotalMemory == 0 {
+		return name + " profile"
+	}
+	return fmt.Sprintf("%s profile (%.1f GiB memory)", name, float64(p.TotalMemory)/(1<<30))
+}
@@ -0,0 +1,35 @@
+package lowmem
+
+import "testing"
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		mode  string
+		total uint64
+		want  bool
+	}{
+		{ModeAuto, 1 << 30, true},
+		{ModeAuto, 1900 << 20, true},
+		{ModeAuto, 4 << 30, false},
+		{ModeAuto, 0, false}, // Unknown memory
+		{ModeOn, 16 << 30, true},
+		{ModeOff, 512 << 20, false},
+	}
+	for _, tt := range tests {
+		profile := Select(tt.mode, tt.total)
+		if profile.LowMemory != tt.want {
+			t.Errorf("Select(%q, %d).LowMemory = %v, want %v", tt.mode, tt.total, profile.LowMemory, tt.want)
+		}
+		if profile.TotalMemory != tt.total {
+			t.Errorf("Select(%q, %d).TotalMemory = %d", tt.mode, tt.total, profile.TotalMemory)
+		}
+	}
+
+	low := Select(ModeOn, 1<<30)
+	if low.RealtimeMetrics || low.ComposeSlots != 1 || low.UIPollInterval <= Default.UIPollInterval || low.SSEBuffer >= Default.SSEBuffer {
+		t.Errorf("low-memory profile = %+v", low)
+	}
+	if got := low.String(); got != "low-memory profile (1.0 GiB memory)" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+package maintenance
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	// Time zones must load on hosts without a zoneinfo database, e.g. minimal containers
+	_ "time/tzdata"
+)
+
+// zoneTabs list the time zones of the system, the first one found is used
+var zoneTabs = []string{"/usr/share/zoneinfo/zone1970.tab", "/usr/share/zoneinfo/zone.tab"}
+
+// fallbackTimezones are offered when the system has no zone list
+var fallbackTimezones = []string{
+	"Africa/Johannesburg", "America/Chicago", "America/Denver", "America/Los_Angeles",
+	"America/New_York", "America/Sao_Paulo", "Asia/Kolkata", "Asia/Shanghai", "Asia/Singapore",
+	"Asia/Tokyo", "Australia/Sydney", "Europe/Berlin", "Europe/London", "Europe/Paris", "Pacific/Auckland", "UTC",
+}
+
+// Day returns noon of the day offset days after the day of t, in the location of t. Noon
+// exists on every day, unlike midnight in some zones, so it is safe to take the date from.
+func Day(t time.Time, offset int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+offset, 12, 0, 0, 0, t.Location())
+}
+
+// AtClock returns the time the wall clock shows clock on the day of t. time.Date resolves
+// daylight saving changes differently per zone, this resolves them like cron: a clock time
+// skipped when clocks go forward is moved forward by the skipped hour, one that happens
+// twice when clocks go back is the first of the two.
+func AtClock(t time.Time, clock time.Duration) time.Time {
+	loc := t.Location()
+	hour, minute := int(clock/time.Hour), int(clock%time.Hour/time.Minute)
+	wall := time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, time.UTC)
+
+	// The offsets in effect a day before and after cover any transition on this day
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	var first time.Time
+	for _, offset := range []int{before, after} {
+		candidate := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if candidate.Hour() == hour && candidate.Minute() == minute && (first.IsZero() || candidate.Before(first)) {
+			first = candidate
+		}
+	}
+	if !first.IsZero() {
+		return first
+	}
+	// Skipped: read the clock as if it had not moved yet
+	return wall.Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+// NextDaily returns the first time after t the wall clock shows clock
+func NextDaily(t time.Time, clock time.Duration) time.Time {
+	if next := AtClock(t, clock); next.After(t) {
+		return next
+	}
+	return AtClock(Day(t, 1), clock)
+}
+
+// Timezones returns the names of the time zones known to the system, sorted
+func Timezones() []string {
+	for _, path := range zoneTabs {
+		f, err := os.Open(path) //nolint:gosec // Fixed system paths
+		if err != nil {
+			continue
+		}
+		names := []string{"UTC"}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 3 && !strings.HasPrefix(fields[0], "#") {
+				names = append(names, fields[2])
+			}
+		}
+		f.Close() //nolint:errcheck,gosec // Read-only
+		if len(names) > 1 {
+			sort.Strings(names)
+			return names
+		}
+	}
+	return fallbackTimezones
+}
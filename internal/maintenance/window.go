@@ -0,0 +1,176 @@
+// Package maintenance defines the maintenance window in which disruptive host
+// operations such as reboots are allowed to run.
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultWindow is used when no maintenance window is configured
+const DefaultWindow = "03:00-05:00"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a recurring daily time range, optionally limited to some weekdays.
+// A window whose end is before its start spans midnight. Start and end are wall clock
+// times, so "03:00-05:00" opens at 03:00 also on the days daylight saving time changes.
+type Window struct {
+	Days     []time.Weekday // Days the window starts on, empty means every day
+	Start    time.Duration  // Wall clock time after midnight
+	End      time.Duration  // Wall clock time after midnight
+	Location *time.Location // Time zone of the wall clock, nil for the location of the checked time
+}
+
+// Parse parses a window such as "03:00-05:00" or "sat,sun 22:00-02:00"
+func Parse(s string) (Window, error) {
+	var w Window
+	fields := strings.Fields(strings.ToLower(strings.TrimSpace(s)))
+	switch len(fields) {
+	case 1:
+	case 2:
+		for _, day := range strings.Split(fields[0], ",") {
+			wd, err := ParseWeekday(day)
+			if err != nil {
+				return Window{}, fmt.Errorf("%w in maintenance window", err)
+			}
+			w.Days = append(w.Days, wd)
+		}
+		fields = fields[1:]
+	default:
+		return Window{}, fmt.Errorf("invalid maintenance window %q", s)
+	}
+
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid maintenance window %q, expected HH:MM-HH:MM", s)
+	}
+	var err error
+	if w.Start, err = parseClock(start); err != nil {
+		return Window{}, err
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return Window{}, err
+	}
+	if w.Start == w.End {
+		return Window{}, fmt.Errorf("maintenance window %q is empty", s)
+	}
+	return w, nil
+}
+
+// ParseWeekday parses the short name of a weekday such as "sun"
+func ParseWeekday(s string) (time.Weekday, error) {
+	wd, ok := weekdays[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid weekday %q", s)
+	}
+	return wd, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q in maintenance window", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// startsOn reports whether an occurrence of the window starts on the given day
+func (w Window) startsOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// In returns the window with its wall clock times in loc
+func (w Window) In(loc *time.Location) Window {
+	w.Location = loc
+	return w
+}
+
+// local returns t in the location of the window
+func (w Window) local(t time.Time) time.Time {
+	if w.Location == nil {
+		return t
+	}
+	return t.In(w.Location)
+}
+
+// occurrence returns the start and end of the window that starts on the day of t. Both are
+// built from the wall clock, so an occurrence on a day with a daylight saving change is an
+// hour shorter or longer.
+func (w Window) occurrence(t time.Time) (time.Time, time.Time) {
+	start := AtClock(t, w.Start)
+	end := AtClock(t, w.End)
+	if w.End <= w.Start {
+		end = AtClock(t.AddDate(0, 0, 1), w.End)
+	}
+	return start, end
+}
+
+// Contains reports whether t falls inside the window
+func (w Window) Contains(t time.Time) bool {
+	t = w.local(t)
+	// An occurrence that started today or yesterday can contain t
+	for _, offset := range []int{0, -1} {
+		day := Day(t, offset)
+		if !w.startsOn(day.Weekday()) {
+			continue
+		}
+		start, end := w.occurrence(day)
+		if !t.Before(start) && t.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns t if it is inside the window, otherwise the start of the next occurrence
+func (w Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	t = w.local(t)
+	for offset := 0; offset <= 7; offset++ {
+		day := Day(t, offset)
+		if !w.startsOn(day.Weekday()) {
+			continue
+		}
+		if start, _ := w.occurrence(day); start.After(t) {
+			return start
+		}
+	}
+	// Unreachable for a valid window
+	return t
+}
+
+// String formats the window in the format accepted by Parse
+func (w Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	s := clock(w.Start) + "-" + clock(w.End)
+	if len(w.Days) == 0 {
+		return s
+	}
+	names := make([]string, 0, len(w.Days))
+	for _, d := range w.Days {
+		names = append(names, strings.ToLower(d.String()[:3]))
+	}
+	return strings.Join(names, ",") + " " + s
+}
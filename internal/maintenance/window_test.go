@@ -0,0 +1,122 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{"03:00-05:00", "sat,sun 22:00-02:00", "Mon 00:00-23:59"}
+	for _, s := range valid {
+		if _, err := Parse(s); err != nil {
+			t.Errorf("Parse(%q) error = %v", s, err)
+		}
+	}
+
+	invalid := []string{"", "03:00", "25:00-26:00", "funday 03:00-04:00", "03:00-03:00", "a b c"}
+	for _, s := range invalid {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) expected error", s)
+		}
+	}
+
+	w, _ := Parse("sat,sun 22:00-02:00")
+	if got := w.String(); got != "sat,sun 22:00-02:00" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestWindowContainsAndNext(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		// 2026-10-03 is a Saturday
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	daily, _ := Parse("03:00-05:00")
+	if !daily.Contains(at(5, 4, 0)) || daily.Contains(at(5, 5, 0)) || daily.Contains(at(5, 2, 59)) {
+		t.Error("unexpected Contains result for daily window")
+	}
+	if got := daily.Next(at(5, 6, 0)); !got.Equal(at(6, 3, 0)) {
+		t.Errorf("Next() = %v", got)
+	}
+	if got := daily.Next(at(5, 3, 30)); !got.Equal(at(5, 3, 30)) {
+		t.Errorf("Next() inside window = %v", got)
+	}
+
+	weekend, _ := Parse("sat,sun 22:00-02:00")
+	if !weekend.Contains(at(3, 23, 0)) {
+		t.Error("expected Saturday night to be inside the window")
+	}
+	if !weekend.Contains(at(5, 1, 0)) {
+		t.Error("expected Monday 01:00 (Sunday occurrence) to be inside the window")
+	}
+	if weekend.Contains(at(6, 1, 0)) {
+		t.Error("expected Tuesday 01:00 to be outside the window")
+	}
+	if got := weekend.Next(at(5, 12, 0)); !got.Equal(at(10, 22, 0)) {
+		t.Errorf("Next() = %v, want next Saturday", got)
+	}
+}
+
+func TestAtClockDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		day   time.Time
+		clock time.Duration
+		want  string
+	}{
+		{"normal day", time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), 2*time.Hour + 30*time.Minute, "2026-03-28T02:30:00+01:00"},
+		{"skipped in Berlin", time.Date(2026, 3, 29, 12, 0, 0, 0, berlin), 2*time.Hour + 30*time.Minute, "2026-03-29T03:30:00+02:00"},
+		{"skipped in New York", time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), 2*time.Hour + 30*time.Minute, "2026-03-08T03:30:00-04:00"},
+		{"twice in Berlin", time.Date(2026, 10, 25, 12, 0, 0, 0, berlin), 2*time.Hour + 30*time.Minute, "2026-10-25T02:30:00+02:00"},
+		{"twice in New York", time.Date(2026, 11, 1, 12, 0, 0, 0, newYork), 1*time.Hour + 30*time.Minute, "2026-11-01T01:30:00-04:00"},
+	}
+	for _, tt := range tests {
+		if got := AtClock(tt.day, tt.clock).Format(time.RFC3339); got != tt.want {
+			t.Errorf("%s: AtClock() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// The next daily run keeps the wall clock across the change
+	next := NextDaily(time.Date(2026, 10, 24, 4, 0, 0, 0, berlin), 3*time.Hour)
+	if got := next.Format(time.RFC3339); got != "2026-10-25T03:00:00+01:00" {
+		t.Errorf("NextDaily() = %s", got)
+	}
+}
+
+func TestWindowInLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := Parse("03:00-05:00")
+	w = w.In(berlin)
+
+	// 02:30 UTC is 03:30 in Berlin in winter
+	if !w.Contains(time.Date(2026, 1, 10, 2, 30, 0, 0, time.UTC)) {
+		t.Error("expected 03:30 Berlin time to be inside the window")
+	}
+	// On the day clocks go back, 03:00-05:00 lasts two hours of wall clock, from 02:00 UTC
+	next := w.Next(time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC))
+	if got := next.UTC().Format(time.RFC3339); got != "2026-10-25T02:00:00Z" {
+		t.Errorf("Next() = %s", got)
+	}
+	if w.Contains(time.Date(2026, 10, 25, 4, 0, 0, 0, time.UTC)) {
+		t.Error("expected 05:00 Berlin time to be outside the window")
+	}
+	// Spring forward: 01:00-04:00 is only two hours long
+	short, _ := Parse("01:00-04:00")
+	short = short.In(berlin)
+	if short.Contains(time.Date(2026, 3, 29, 2, 0, 0, 0, time.UTC)) {
+		t.Error("expected 04:00 CEST to be outside the window")
+	}
+}
@@ -0,0 +1,258 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Export describes the Prometheus job a node is scraped as. Dashboards and alert rules are
+// generated from the families the node registers, so they only refer to metrics it exposes.
+type Export struct {
+	Node string // Hostname of the node, used in titles, the dashboard UID and the alert labels
+	Job  string // job_name of the scrape config, "treeos" if empty
+}
+
+// latencyThresholds are the p95 durations in seconds above which a histogram alerts. Families
+// without an entry get no latency alert, a generic threshold would be wrong for most of them.
+var latencyThresholds = map[string]float64{
+	"treeos_compose_duration_seconds":   60,
+	"treeos_caddy_api_duration_seconds": 1,
+	"treeos_db_query_duration_seconds":  0.5,
+}
+
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]+`)
+
+func (e Export) job() string {
+	if e.Job == "" {
+		return "treeos"
+	}
+	return e.Job
+}
+
+// selector returns the label matchers of the node's series, with extra matchers appended
+func (e Export) selector(extra ...string) string {
+	matchers := append([]string{fmt.Sprintf("job=%q", e.job()), `instance=~"$instance"`}, extra...)
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// alertSelector is like selector without the Grafana variable, rules run in Prometheus
+func (e Export) alertSelector(extra ...string) string {
+	matchers := append([]string{fmt.Sprintf("job=%q", e.job())}, extra...)
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// slug returns the node name as it is used in UIDs and file names
+func (e Export) slug() string {
+	slug := strings.Trim(nonAlnum.ReplaceAllString(strings.ToLower(e.Node), "-"), "-")
+	if slug == "" {
+		return "node"
+	}
+	return slug
+}
+
+// Filename returns the download name of an export, e.g. treeos-myhost-dashboard.json
+func (e Export) Filename(kind string) string {
+	return "treeos-" + e.slug() + "-" + kind
+}
+
+// shortName turns treeos_caddy_api_duration_seconds into CaddyApi for alert names
+func shortName(name string) string {
+	name = strings.TrimPrefix(name, "treeos_")
+	for _, suffix := range []string{"_duration_seconds", "_seconds", "_total"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+func hasLabel(f Family, label string) bool {
+	for _, l := range f.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// legend returns the Grafana legend format of a family's labels
+func legend(labels []string) string {
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = "{{" + label + "}}"
+	}
+	return strings.Join(parts, " ")
+}
+
+// sumBy returns a "sum by (...)" aggregation, or a plain sum without labels
+func sumBy(labels ...string) string {
+	if len(labels) == 0 {
+		return "sum"
+	}
+	return "sum by (" + strings.Join(labels, ", ") + ")"
+}
+
+type grafanaTarget struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	RefID        string `json:"refId"`
+}
+
+type grafanaPanel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Datasource  map[string]string      `json:"datasource"`
+	GridPos     map[string]int         `json:"gridPos"`
+	FieldConfig map[string]interface{} `json:"fieldConfig"`
+	Targets     []grafanaTarget        `json:"targets"`
+}
+
+// GrafanaDashboard returns a dashboard with one panel per metric family, importable into
+// Grafana with a Prometheus data source chosen at import time
+func (e Export) GrafanaDashboard(families []Family) ([]byte, error) {
+	datasource := map[string]string{"type": "prometheus", "uid": "${DS_PROMETHEUS}"}
+	panel := func(title, description, unit string, targets ...grafanaTarget) grafanaPanel {
+		return grafanaPanel{
+			Type:        "timeseries",
+			Title:       title,
+			Description: description,
+			Datasource:  datasource,
+			FieldConfig: map[string]interface{}{"defaults": map[string]string{"unit": unit}, "overrides": []interface{}{}},
+			Targets:     targets,
+		}
+	}
+
+	up := panel("Up", "Whether Prometheus reaches the node.", "none",
+		grafanaTarget{Expr: "up" + e.selector(), LegendFormat: "{{instance}}", RefID: "A"})
+	up.Type = "stat"
+	panels := []grafanaPanel{up}
+
+	for _, f := range families {
+		switch f.Kind {
+		case KindHistogram:
+			p95 := fmt.Sprintf("histogram_quantile(0.95, %s (rate(%s_bucket%s[5m])))",
+				sumBy(append([]string{"le"}, f.Labels...)...), f.Name, e.selector())
+			rate := fmt.Sprintf("%s (rate(%s_count%s[5m]))", sumBy(f.Labels...), f.Name, e.selector())
+			panels = append(panels,
+				panel(f.Name+" p95", f.Help, "s", grafanaTarget{Expr: p95, LegendFormat: legend(f.Labels), RefID: "A"}),
+				panel(f.Name+" rate", f.Help, "ops", grafanaTarget{Expr: rate, LegendFormat: legend(f.Labels), RefID: "A"}))
+		case KindCounter:
+			expr := fmt.Sprintf("%s (rate(%s%s[5m]))", sumBy(f.Labels...), f.Name, e.selector())
+			panels = append(panels, panel(f.Name, f.Help, "ops", grafanaTarget{Expr: expr, LegendFormat: legend(f.Labels), RefID: "A"}))
+		case KindGauge:
+			expr := fmt.Sprintf("%s (%s%s)", sumBy(f.Labels...), f.Name, e.selector())
+			panels = append(panels, panel(f.Name, f.Help, "short", grafanaTarget{Expr: expr, LegendFormat: legend(f.Labels), RefID: "A"}))
+		}
+	}
+	// Two panels per row, the up panel gets a row of its own
+	for i := range panels {
+		panels[i].ID = i + 1
+		if i == 0 {
+			panels[i].GridPos = map[string]int{"x": 0, "y": 0, "w": 24, "h": 4}
+			continue
+		}
+		panels[i].GridPos = map[string]int{"x": (i - 1) % 2 * 12, "y": 4 + (i-1)/2*8, "w": 12, "h": 8}
+	}
+
+	dashboard := map[string]interface{}{
+		"__inputs": []map[string]string{{
+			"name": "DS_PROMETHEUS", "label": "Prometheus", "type": "datasource", "pluginId": "prometheus", "pluginName": "Prometheus",
+		}},
+		"uid":           "treeos-" + e.slug(),
+		"title":         "TreeOS " + e.Node,
+		"tags":          []string{"treeos"},
+		"editable":      true,
+		"schemaVersion": 39,
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"refresh":       "1m",
+		"templating": map[string]interface{}{"list": []map[string]interface{}{{
+			"name":       "instance",
+			"label":      "Instance",
+			"type":       "query",
+			"datasource": datasource,
+			"query":      fmt.Sprintf("label_values(up{job=%q}, instance)", e.job()),
+			"refresh":    1,
+			"includeAll": true,
+			"current":    map[string]string{"text": "All", "value": "$__all"},
+		}}},
+		"panels": panels,
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
+
+type alertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+type ruleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []alertRule `yaml:"rules"`
+}
+
+// AlertRules returns a Prometheus rule file that alerts when the node is down, when operations
+// fail and when they are slow
+func (e Export) AlertRules(families []Family) ([]byte, error) {
+	rule := func(name, expr, duration, severity, summary string) alertRule {
+		return alertRule{
+			Alert:       "TreeOS" + name,
+			Expr:        expr,
+			For:         duration,
+			Labels:      map[string]string{"severity": severity, "node": e.Node},
+			Annotations: map[string]string{"summary": summary},
+		}
+	}
+
+	rules := []alertRule{rule("Down", "up"+e.alertSelector()+" == 0", "5m", "critical",
+		"TreeOS on {{ $labels.instance }} can't be scraped")}
+	for _, f := range families {
+		name := shortName(f.Name)
+		series := f.Name
+		if f.Kind == KindHistogram {
+			series += "_count"
+		}
+		if f.Kind == KindGauge {
+			continue
+		}
+
+		if hasLabel(f, "status") {
+			expr := fmt.Sprintf("sum by (instance) (rate(%s%s[5m])) / sum by (instance) (rate(%s%s[5m])) > 0.05",
+				series, e.alertSelector(`status="5xx"`), series, e.alertSelector())
+			rules = append(rules, rule(name+"ServerErrors", expr, "10m", "warning",
+				fmt.Sprintf("More than 5%% of %s answer with 5xx on {{ $labels.instance }}", f.Name)))
+		}
+		if hasLabel(f, "result") {
+			expr := fmt.Sprintf("sum by (instance) (increase(%s%s[15m])) > 0", series, e.alertSelector(`result="error"`))
+			rules = append(rules, rule(name+"Failures", expr, "0m", "warning",
+				fmt.Sprintf("%s reports failures on {{ $labels.instance }}", f.Name)))
+		}
+		if threshold, ok := latencyThresholds[f.Name]; ok && f.Kind == KindHistogram {
+			expr := fmt.Sprintf("histogram_quantile(0.95, sum by (le, instance) (rate(%s_bucket%s[10m]))) > %s",
+				f.Name, e.alertSelector(), formatFloat(threshold))
+			rules = append(rules, rule(name+"Slow", expr, "15m", "warning",
+				fmt.Sprintf("p95 of %s is above %ss on {{ $labels.instance }}", f.Name, formatFloat(threshold))))
+		}
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string][]ruleGroup{"groups": {{Name: "treeos-" + e.slug(), Rules: rules}}}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), encoder.Close()
+}
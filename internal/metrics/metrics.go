@@ -0,0 +1,328 @@
+// Package metrics keeps counters, gauges and histograms of internal operations and writes them
+// in the Prometheus text format. It has no dependencies so that low-level packages like the
+// database and the compose wrapper can record into it.
+//
+// All metrics live in one process-wide registry. Label values must have a small, fixed set of
+// values (operation names, result classes), never IDs or paths.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the Prometheus type of a metric family
+type Kind string
+
+// Metric kinds
+const (
+	KindCounter   Kind = "counter"
+	KindGauge     Kind = "gauge"
+	KindHistogram Kind = "histogram"
+)
+
+// DefaultBuckets are histogram bounds in seconds from 1ms to 2 minutes
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]family{}
+)
+
+// family is implemented by all metric vectors
+type family interface {
+	snapshot() Family
+}
+
+func register(name string, f family) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[name]; exists {
+		panic("metrics: duplicate metric " + name)
+	}
+	registry[name] = f
+}
+
+// Family is a point-in-time copy of a metric and all its label combinations
+type Family struct {
+	Name   string
+	Help   string
+	Kind   Kind
+	Labels []string
+	Series []Series
+}
+
+// Series is one label combination of a family. Histograms fill Count, Sum and Buckets,
+// counters and gauges fill Value.
+type Series struct {
+	LabelValues []string
+	Value       float64
+	Count       uint64
+	Sum         float64
+	Buckets     []Bucket
+}
+
+// Bucket is a cumulative histogram bucket
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Mean returns the average observation of a histogram series
+func (s Series) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// Quantile estimates a quantile of a histogram series from its buckets. It returns the upper
+// bound of the bucket the quantile falls into, or +Inf above the largest bucket.
+func (s Series) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	for _, bucket := range s.Buckets {
+		if bucket.Count >= rank {
+			return bucket.UpperBound
+		}
+	}
+	return math.Inf(1)
+}
+
+// labelKey joins label values into a map key
+func labelKey(labels, values []string, name string) string {
+	if len(values) != len(labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", name, len(labels), len(values)))
+	}
+	return strings.Join(values, "\x00")
+}
+
+func splitKey(key string, labels []string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return strings.Split(key, "\x00")
+}
+
+// CounterVec is a counter with labels
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+// NewCounterVec registers a counter
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+	register(name, c)
+	return c
+}
+
+// Inc adds one to the counter of the label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the counter of the label values
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := labelKey(c.labels, labelValues, c.name)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) snapshot() Family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := Family{Name: c.name, Help: c.help, Kind: KindCounter, Labels: c.labels}
+	for key, value := range c.values {
+		f.Series = append(f.Series, Series{LabelValues: splitKey(key, c.labels), Value: value})
+	}
+	return f
+}
+
+// GaugeVec is a gauge with labels
+type GaugeVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+// NewGaugeVec registers a gauge
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+	register(name, g)
+	return g
+}
+
+// Add adds v to the gauge of the label values
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	key := labelKey(g.labels, labelValues, g.name)
+	g.mu.Lock()
+	g.values[key] += v
+	g.mu.Unlock()
+}
+
+// Inc adds one to the gauge of the label values
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts one from the gauge of the label values
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Set sets the gauge of the label values
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	key := labelKey(g.labels, labelValues, g.name)
+	g.mu.Lock()
+	g.values[key] = v
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) snapshot() Family {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f := Family{Name: g.name, Help: g.help, Kind: KindGauge, Labels: g.labels}
+	for key, value := range g.values {
+		f.Series = append(f.Series, Series{LabelValues: splitKey(key, g.labels), Value: value})
+	}
+	return f
+}
+
+// HistogramVec is a histogram with labels
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	values     map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds in ascending order
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: map[string]*histogramValue{}}
+	register(name, h)
+	return h
+}
+
+// Observe records a value for the label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := labelKey(h.labels, labelValues, h.name)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	value, ok := h.values[key]
+	if !ok {
+		value = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = value
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		value.counts[i]++
+	}
+	value.count++
+	value.sum += v
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *HistogramVec) snapshot() Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := Family{Name: h.name, Help: h.help, Kind: KindHistogram, Labels: h.labels}
+	for key, value := range h.values {
+		series := Series{LabelValues: splitKey(key, h.labels), Count: value.count, Sum: value.sum}
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			series.Buckets = append(series.Buckets, Bucket{UpperBound: bound, Count: cumulative})
+		}
+		f.Series = append(f.Series, series)
+	}
+	return f
+}
+
+// Snapshot returns all registered metrics sorted by name and label values
+func Snapshot() []Family {
+	registryMu.Lock()
+	families := make([]Family, 0, len(registry))
+	for _, f := range registry {
+		families = append(families, f.snapshot())
+	}
+	registryMu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	for _, f := range families {
+		sort.Slice(f.Series, func(i, j int) bool {
+			return strings.Join(f.Series[i].LabelValues, "\x00") < strings.Join(f.Series[j].LabelValues, "\x00")
+		})
+	}
+	return families
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format
+func WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	for _, f := range Snapshot() {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.Name, escapeHelp(f.Help), f.Name, f.Kind)
+		for _, series := range f.Series {
+			if f.Kind != KindHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", f.Name, formatLabels(f.Labels, series.LabelValues, "", 0), formatFloat(series.Value))
+				continue
+			}
+			for _, bucket := range series.Buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.Name, formatLabels(f.Labels, series.LabelValues, "le", bucket.UpperBound), bucket.Count)
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.Name, formatLabels(f.Labels, series.LabelValues, "le", math.Inf(1)), series.Count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.Name, formatLabels(f.Labels, series.LabelValues, "", 0), formatFloat(series.Sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.Name, formatLabels(f.Labels, series.LabelValues, "", 0), series.Count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatLabels formats label pairs, with an extra "le" label for histogram buckets
+func formatLabels(labels, values []string, extra string, extraValue float64) string {
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", label, values[i]))
+	}
+	if extra != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra, formatFloat(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	counter := NewCounterVec("test_requests_total", "Test requests.", "status")
+	counter.Inc("2xx")
+	counter.Add(2, "5xx")
+	gauge := NewGaugeVec("test_clients", "Test clients.")
+	gauge.Inc()
+	gauge.Inc()
+	gauge.Dec()
+	histogram := NewHistogramVec("test_duration_seconds", "Test durations.", []float64{0.1, 1}, "result")
+	histogram.Observe(0.05, "ok")
+	histogram.Observe(0.5, "ok")
+	histogram.Observe(5, "ok")
+
+	var b strings.Builder
+	if err := WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE test_requests_total counter",
+		`test_requests_total{status="2xx"} 1`,
+		`test_requests_total{status="5xx"} 2`,
+		"test_clients 1",
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{result="ok",le="0.1"} 1`,
+		`test_duration_seconds_bucket{result="ok",le="1"} 2`,
+		`test_duration_seconds_bucket{result="ok",le="+Inf"} 3`,
+		`test_duration_seconds_sum{result="ok"} 5.55`,
+		`test_duration_seconds_count{result="ok"} 3`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("output lacks %q:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestSeriesQuantile(t *testing.T) {
+	series := Series{Count: 4, Sum: 1, Buckets: []Bucket{{UpperBound: 0.1, Count: 2}, {UpperBound: 1, Count: 3}}}
+	if got := series.Quantile(0.5); got != 0.1 {
+		t.Errorf("p50 = %v, want 0.1", got)
+	}
+	if got := series.Quantile(0.75); got != 1 {
+		t.Errorf("p75 = %v, want 1", got)
+	}
+	if got := series.Quantile(0.95); !math.IsInf(got, 1) {
+		t.Errorf("p95 = %v, want +Inf", got)
+	}
+	if got := series.Mean(); got != 0.25 {
+		t.Errorf("mean = %v, want 0.25", got)
+	}
+}
+
+func TestLabelHelpers(t *testing.T) {
+	if Result(nil) != "ok" || Result(errors.New("x")) != "error" {
+		t.Error("Result() returned wrong labels")
+	}
+	if StatusClass(204) != "2xx" || StatusClass(503) != "5xx" || StatusClass(0) != "error" {
+		t.Error("StatusClass() returned wrong labels")
+	}
+}
+
+func TestExport(t *testing.T) {
+	families := []Family{
+		{Name: "treeos_http_requests_total", Help: "Handled HTTP requests.", Kind: KindCounter, Labels: []string{"status"}},
+		{Name: "treeos_sse_clients", Help: "Connected clients.", Kind: KindGauge, Labels: []string{"stream"}},
+		{Name: "treeos_db_query_duration_seconds", Help: "Duration of SQLite statements.", Kind: KindHistogram, Labels: []string{"operation", "result"}},
+	}
+	export := Export{Node: "Home Server"}
+
+	data, err := export.GrafanaDashboard(families)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dashboard struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			Title   string `json:"title"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		t.Fatal(err)
+	}
+	if dashboard.UID != "treeos-home-server" {
+		t.Errorf("uid = %q, want treeos-home-server", dashboard.UID)
+	}
+	// Up, the counter, the gauge, and p95 and rate of the histogram
+	if len(dashboard.Panels) != 5 {
+		t.Fatalf("got %d panels, want 5", len(dashboard.Panels))
+	}
+	wantExpr := `histogram_quantile(0.95, sum by (le, operation, result) (rate(treeos_db_query_duration_seconds_bucket{job="treeos",instance=~"$instance"}[5m])))`
+	if got := dashboard.Panels[3].Targets[0].Expr; got != wantExpr {
+		t.Errorf("p95 expr = %s, want %s", got, wantExpr)
+	}
+
+	rules, err := Export{Node: "Home Server", Job: "nodes"}.AlertRules(families)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"name: treeos-home-server",
+		"alert: TreeOSDown",
+		`expr: up{job="nodes"} == 0`,
+		"alert: TreeOSHttpRequestsServerErrors",
+		"alert: TreeOSDbQueryFailures",
+		"alert: TreeOSDbQuerySlow",
+		"node: Home Server",
+	} {
+		if !strings.Contains(string(rules), want) {
+			t.Errorf("rules lack %q:\n%s", want, rules)
+		}
+	}
+	if strings.Contains(string(rules), "SseClients") {
+		t.Errorf("gauges need no alerts:\n%s", rules)
+	}
+	if got := export.Filename("alerts.yml"); got != "treeos-home-server-alerts.yml" {
+		t.Errorf("Filename() = %q", got)
+	}
+}
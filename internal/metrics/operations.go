@@ -0,0 +1,40 @@
+package metrics
+
+// Metrics of internal operations. They are defined here rather than next to the code they
+// measure so that the debug page and the docs have one place to look.
+var (
+	// ComposeDuration is the duration of docker compose commands by command and result
+	ComposeDuration = NewHistogramVec("treeos_compose_duration_seconds",
+		"Duration of docker compose commands.", DefaultBuckets, "command", "result")
+
+	// CaddyRequestDuration is the latency of Caddy Admin API calls by method and status class
+	CaddyRequestDuration = NewHistogramVec("treeos_caddy_api_duration_seconds",
+		"Latency of Caddy Admin API requests.", DefaultBuckets, "method", "status")
+
+	// DBQueryDuration is the duration of SQLite statements by operation (exec or query) and
+	// result. Queries are measured until the first row is ready, not until all rows are read.
+	DBQueryDuration = NewHistogramVec("treeos_db_query_duration_seconds",
+		"Duration of SQLite statements.", []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}, "operation", "result")
+
+	// SSEClients is the number of connected Server-Sent Events clients by stream
+	SSEClients = NewGaugeVec("treeos_sse_clients", "Connected Server-Sent Events clients.", "stream")
+
+	// HTTPRequests counts handled HTTP requests by status class
+	HTTPRequests = NewCounterVec("treeos_http_requests_total", "Handled HTTP requests.", "status")
+)
+
+// Result returns the result label value for an error
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// StatusClass returns the status label value for an HTTP status code, e.g. "2xx"
+func StatusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "error"
+	}
+	return string(rune('0'+code/100)) + "xx"
+}
@@ -0,0 +1,354 @@
+// Package mockruntime simulates the container runtime in memory for development and load
+// tests. With TREEOS_MOCK_RUNTIME=1, or in binaries built with the mockruntime build tag,
+// the compose wrapper and the runtime client use it instead of Docker: compose up and down
+// take a configurable latency, containers go through a "starting" phase before they run,
+// and a configurable share of them crash. Seed fills the apps directory with many apps so
+// the UI and API can be measured with hundreds of apps and no Docker daemon.
+//
+// Nothing is persisted: after a restart only the seeded apps run again.
+package mockruntime
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// forceEnabled is set by the mockruntime build tag
+var forceEnabled bool
+
+// Enabled reports whether the mock runtime replaces Docker
+func Enabled() bool {
+	if forceEnabled {
+		return true
+	}
+	value := os.Getenv("TREEOS_MOCK_RUNTIME")
+	return value == "1" || value == "true"
+}
+
+// Config controls the simulation
+type Config struct {
+	Apps        int           // Apps Seed creates, TREEOS_MOCK_APPS
+	Latency     time.Duration // Duration of compose up and down, TREEOS_MOCK_LATENCY
+	StartupTime time.Duration // How long started containers report "starting", TREEOS_MOCK_STARTUP
+	FailureRate float64       // Share of started containers that crash, TREEOS_MOCK_FAILURE_RATE
+}
+
+// DefaultConfig is used for unset environment variables
+var DefaultConfig = Config{Apps: 0, Latency: 500 * time.Millisecond, StartupTime: 5 * time.Second, FailureRate: 0}
+
+// ConfigFromEnv reads the simulation settings from the environment
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig
+	if value := os.Getenv("TREEOS_MOCK_APPS"); value != "" {
+		apps, err := strconv.Atoi(value)
+		if err != nil || apps < 0 {
+			return cfg, fmt.Errorf("invalid TREEOS_MOCK_APPS %q", value)
+		}
+		cfg.Apps = apps
+	}
+	for name, target := range map[string]*time.Duration{
+		"TREEOS_MOCK_LATENCY": &cfg.Latency,
+		"TREEOS_MOCK_STARTUP": &cfg.StartupTime,
+	} {
+		if value := os.Getenv(name); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = duration
+		}
+	}
+	if value := os.Getenv("TREEOS_MOCK_FAILURE_RATE"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return cfg, fmt.Errorf("invalid TREEOS_MOCK_FAILURE_RATE %q, expected 0 to 1", value)
+		}
+		cfg.FailureRate = rate
+	}
+	return cfg, nil
+}
+
+// Service is a compose service the mock runtime starts a container for
+type Service struct {
+	Name  string
+	Image string
+	Ports []string // "HOST:CONTAINER" mappings
+}
+
+// Container is the simulated state of a container
+type Container struct {
+	ID      string
+	Name    string
+	Project string
+	Service string
+	Image   string
+	State   string // "running" or "exited"
+	Status  string // Like docker ps, e.g. "Up 5 minutes (healthy)"
+	Health  string // "starting", "healthy" or "unhealthy"
+	Ports   []string
+}
+
+type container struct {
+	Container
+	startedAt time.Time
+	crashes   bool
+}
+
+// Runtime is an in-memory container runtime
+type Runtime struct {
+	cfg      Config
+	mu       sync.Mutex
+	projects map[string][]*container
+	rand     *rand.Rand
+	nextID   int
+}
+
+// New creates an empty runtime
+func New(cfg Config) *Runtime {
+	return &Runtime{
+		cfg:      cfg,
+		projects: map[string][]*container{},
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec // Simulation, not security
+	}
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultRuntime *Runtime
+)
+
+// Default returns the process-wide runtime configured from the environment
+func Default() *Runtime {
+	defaultOnce.Do(func() {
+		cfg, err := ConfigFromEnv()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mock runtime: %v, using defaults\n", err)
+			cfg = DefaultConfig
+		}
+		defaultRuntime = New(cfg)
+	})
+	return defaultRuntime
+}
+
+// Config returns the simulation settings
+func (r *Runtime) Config() Config {
+	return r.cfg
+}
+
+// wait simulates the latency of a compose command
+func (r *Runtime) wait(ctx context.Context) error {
+	if r.cfg.Latency == 0 {
+		return nil
+	}
+	select {
+	case <-time.After(r.cfg.Latency):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Up creates or restarts the containers of a project after the configured latency
+func (r *Runtime) Up(ctx context.Context, project string, services []Service) error {
+	if err := r.wait(ctx); err != nil {
+		return err
+	}
+	r.start(project, services, time.Now())
+	return nil
+}
+
+// start replaces the containers of a project with new ones started at startedAt
+func (r *Runtime) start(project string, services []Service, startedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	containers := make([]*container, 0, len(services))
+	for _, service := range services {
+		r.nextID++
+		containers = append(containers, &container{
+			Container: Container{
+				ID:      fmt.Sprintf("%012x", r.nextID),
+				Name:    fmt.Sprintf("%s-%s-1", project, service.Name),
+				Project: project,
+				Service: service.Name,
+				Image:   service.Image,
+				Ports:   service.Ports,
+			},
+			startedAt: startedAt,
+			crashes:   r.rand.Float64() < r.cfg.FailureRate,
+		})
+	}
+	r.projects[project] = containers
+}
+
+// Down removes the containers of a project after the configured latency
+func (r *Runtime) Down(ctx context.Context, project string) error {
+	if err := r.wait(ctx); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	delete(r.projects, project)
+	r.mu.Unlock()
+	return nil
+}
+
+// Remove removes a single container, reporting whether it existed
+func (r *Runtime) Remove(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for project, containers := range r.projects {
+		for i, c := range containers {
+			if c.ID != id {
+				continue
+			}
+			r.projects[project] = append(containers[:i:i], containers[i+1:]...)
+			if len(r.projects[project]) == 0 {
+				delete(r.projects, project)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Containers returns the containers of a project, or of all projects if project is empty,
+// sorted by name
+func (r *Runtime) Containers(project string) []Container {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	result := []Container{}
+	for name, containers := range r.projects {
+		if project != "" && name != project {
+			continue
+		}
+		for _, c := range containers {
+			result = append(result, r.state(c, now))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// state derives the current state of a container from its start time
+func (r *Runtime) state(c *container, now time.Time) Container {
+	state := c.Container
+	up := now.Sub(c.startedAt)
+	switch {
+	case up < r.cfg.StartupTime:
+		state.State, state.Health = "running", "starting"
+		state.Status = "Up " + formatUptime(up) + " (health: starting)"
+	case c.crashes:
+		state.State, state.Health = "exited", "unhealthy"
+		state.Status = "Exited (1) " + formatUptime(up-r.cfg.StartupTime) + " ago"
+	default:
+		state.State, state.Health = "running", "healthy"
+		state.Status = "Up " + formatUptime(up) + " (healthy)"
+	}
+	return state
+}
+
+func formatUptime(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%d seconds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+}
+
+// Logs returns simulated log lines of a project's containers, optionally of one service
+func (r *Runtime) Logs(project string, services []string) []string {
+	wanted := map[string]bool{}
+	for _, service := range services {
+		wanted[service] = true
+	}
+	var lines []string
+	for _, c := range r.Containers(project) {
+		if len(wanted) > 0 && !wanted[c.Service] {
+			continue
+		}
+		prefix := c.Name + "  | "
+		lines = append(lines, prefix+"Starting "+c.Image, prefix+"Listening on port 80")
+		switch c.Health {
+		case "starting":
+			lines = append(lines, prefix+"Waiting for dependencies")
+		case "unhealthy":
+			lines = append(lines, prefix+"ERROR simulated crash", prefix+"Exiting with code 1")
+		default:
+			lines = append(lines, prefix+"Ready")
+		}
+	}
+	return lines
+}
+
+// ReadServices reads the services of a compose file
+func ReadServices(composeFile string) ([]Service, error) {
+	data, err := os.ReadFile(composeFile) //nolint:gosec // Compose file of an app directory
+	if err != nil {
+		return nil, err
+	}
+	var compose struct {
+		Services map[string]struct {
+			Image string   `yaml:"image"`
+			Ports []string `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", composeFile, err)
+	}
+	services := make([]Service, 0, len(compose.Services))
+	for name, service := range compose.Services {
+		services = append(services, Service{Name: name, Image: service.Image, Ports: service.Ports})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// Stats is a simulated resource usage sample of a running container
+type Stats struct {
+	CPUPercent  float64
+	MemoryBytes uint64
+	MemoryLimit uint64
+	NetRxBytes  uint64 // Received since the container started
+	NetTxBytes  uint64 // Sent since the container started
+}
+
+// Stats returns a usage sample of a container, false if it doesn't exist or doesn't run.
+// Usage is random within a range per container, traffic grows with the uptime.
+func (r *Runtime) Stats(id string) (Stats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, containers := range r.projects {
+		for i, c := range containers {
+			if c.ID != id {
+				continue
+			}
+			if r.state(c, now).State != "running" {
+				return Stats{}, false
+			}
+			up := uint64(now.Sub(c.startedAt).Seconds())
+			base := uint64(i+1) * 32 << 20
+			return Stats{
+				CPUPercent:  1 + r.rand.Float64()*float64(5*(i+1)),
+				MemoryBytes: base + uint64(r.rand.Int63n(16<<20)),
+				NetRxBytes:  up * 24 << 10,
+				NetTxBytes:  up * 6 << 10,
+			}, true
+		}
+	}
+	return Stats{}, false
+}
@@ -0,0 +1,109 @@
+package mockruntime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContainerStateTransitions(t *testing.T) {
+	r := New(Config{StartupTime: time.Hour})
+	services := []Service{{Name: "web", Image: "nginx:1.27"}, {Name: "db", Image: "postgres:16"}}
+	if err := r.Up(context.Background(), "ontree-blog", services); err != nil {
+		t.Fatal(err)
+	}
+
+	containers := r.Containers("ontree-blog")
+	if len(containers) != 2 || containers[0].Name != "ontree-blog-db-1" {
+		t.Fatalf("containers = %+v", containers)
+	}
+	if containers[0].State != "running" || containers[0].Health != "starting" {
+		t.Errorf("new container state = %s/%s, want running/starting", containers[0].State, containers[0].Health)
+	}
+
+	// A failing container crashes once its startup time is over
+	r = New(Config{FailureRate: 1})
+	r.start("ontree-blog", services, time.Now().Add(-time.Minute))
+	if c := r.Containers("")[0]; c.State != "exited" || c.Health != "unhealthy" {
+		t.Errorf("crashed container state = %s/%s, want exited/unhealthy", c.State, c.Health)
+	}
+
+	if _, ok := r.Stats(r.Containers("")[0].ID); ok {
+		t.Error("crashed container has stats")
+	}
+	running := New(Config{})
+	running.start("ontree-blog", services, time.Now().Add(-time.Minute))
+	if stats, ok := running.Stats(running.Containers("")[0].ID); !ok || stats.MemoryBytes == 0 || stats.NetRxBytes == 0 {
+		t.Errorf("stats of a running container = %+v, %v", stats, ok)
+	}
+
+	if err := r.Down(context.Background(), "ontree-blog"); err != nil {
+		t.Fatal(err)
+	}
+	if containers := r.Containers(""); len(containers) != 0 {
+		t.Errorf("containers after down = %+v", containers)
+	}
+}
+
+func TestUpHonorsLatencyAndCancellation(t *testing.T) {
+	r := New(Config{Latency: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Up(ctx, "ontree-blog", []Service{{Name: "web"}}); err == nil {
+		t.Error("expected an error for a cancelled up")
+	}
+	if len(r.Containers("")) != 0 {
+		t.Error("cancelled up created containers")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	appsDir := t.TempDir()
+	r := New(Config{Apps: 3})
+	if err := r.Seed(appsDir); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+
+	services, err := ReadServices(filepath.Join(appsDir, "mock-app-002", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 3 || services[2].Name != "web" || services[2].Ports[0] != "20002:80" {
+		t.Errorf("services of mock-app-002 = %+v", services)
+	}
+	if _, err := os.Stat(filepath.Join(appsDir, "mock-app-001", ".env")); err != nil {
+		t.Errorf("seeded app has no .env: %v", err)
+	}
+	containers := r.Containers("ontree-mock-app-003")
+	if len(containers) != 1 || containers[0].State != "running" || containers[0].Health != "healthy" {
+		t.Errorf("containers of mock-app-003 = %+v", containers)
+	}
+
+	// Seeding again keeps the apps and restarts them
+	if err := r.Seed(appsDir); err != nil {
+		t.Fatalf("second Seed() error = %v", err)
+	}
+	if got := len(r.Containers("")); got != 6 {
+		t.Errorf("containers after reseeding = %d, want 6", got)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_APPS", "200")
+	t.Setenv("TREEOS_MOCK_LATENCY", "2s")
+	t.Setenv("TREEOS_MOCK_FAILURE_RATE", "0.1")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv() error = %v", err)
+	}
+	if cfg.Apps != 200 || cfg.Latency != 2*time.Second || cfg.StartupTime != DefaultConfig.StartupTime || cfg.FailureRate != 0.1 {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	t.Setenv("TREEOS_MOCK_FAILURE_RATE", "2")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("expected an error for a failure rate above 1")
+	}
+}
@@ -0,0 +1,66 @@
+package mockruntime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/naming"
+)
+
+// seedPrefix names the seeded app directories, e.g. mock-app-007
+const seedPrefix = "mock-app-"
+
+var seedServices = []struct {
+	name, image, emoji string
+}{
+	{"web", "nginx:1.27", "🌐"},
+	{"db", "postgres:16", "🐘"},
+	{"cache", "redis:7", "⚡"},
+}
+
+// seedCompose returns the compose file of the n-th seeded app, which has one to three services
+func seedCompose(n int) string {
+	var b strings.Builder
+	b.WriteString("services:\n")
+	count := n%len(seedServices) + 1
+	for i, service := range seedServices[:count] {
+		fmt.Fprintf(&b, "  %s:\n    image: %s\n    restart: unless-stopped\n", service.name, service.image)
+		if i == 0 {
+			fmt.Fprintf(&b, "    ports:\n      - \"%d:80\"\n", 20000+n)
+		}
+	}
+	fmt.Fprintf(&b, "x-ontree:\n  emoji: %q\n", seedServices[count-1].emoji)
+	return b.String()
+}
+
+// Seed creates cfg.Apps apps in appsDir and marks them as running. Apps left over from an
+// earlier run are kept, so the apps directory can be reused between restarts.
+func (r *Runtime) Seed(appsDir string) error {
+	for n := 1; n <= r.cfg.Apps; n++ {
+		appPath := filepath.Join(appsDir, fmt.Sprintf("%s%03d", seedPrefix, n))
+		composeFile := filepath.Join(appPath, "docker-compose.yml")
+		if _, err := os.Stat(composeFile); os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Join(appPath, "mnt"), 0750); err != nil {
+				return fmt.Errorf("failed to create mock app: %w", err)
+			}
+			if err := os.WriteFile(composeFile, []byte(seedCompose(n)), 0600); err != nil {
+				return fmt.Errorf("failed to write mock app: %w", err)
+			}
+			if err := naming.GenerateEnvFile(appPath); err != nil {
+				return fmt.Errorf("failed to write mock app: %w", err)
+			}
+		}
+
+		services, err := ReadServices(composeFile)
+		if err != nil {
+			return err
+		}
+		// Started a while ago, so only crashed containers differ from running ones
+		startedAt := time.Now().Add(-r.cfg.StartupTime - time.Duration(n)*time.Minute)
+		r.start(naming.GetComposeProjectName(naming.GetAppIdentifier(appPath)), services, startedAt)
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build mockruntime
+
+package mockruntime
+
+func init() {
+	forceEnabled = true
+}
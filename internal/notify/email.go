@@ -0,0 +1,137 @@
+// Package notify delivers notifications to users outside of the web UI.
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Connection security of the mail server
+const (
+	// SMTPSecuritySTARTTLS upgrades the connection with STARTTLS when the server offers it
+	SMTPSecuritySTARTTLS = "starttls"
+	// SMTPSecurityTLS connects with TLS from the start, usually on port 465
+	SMTPSecurityTLS = "tls"
+	// SMTPSecurityNone never encrypts, for relays on the local network
+	SMTPSecurityNone = "none"
+)
+
+// smtpTimeout bounds connecting to the mail server
+const smtpTimeout = 30 * time.Second
+
+// SMTPConfig holds the settings for sending email
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Security string // One of the SMTPSecurity constants, "" for STARTTLS
+	Username string
+	Password string
+	From     string
+}
+
+// Enabled returns true if enough settings are present to send email
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != "" && c.From != ""
+}
+
+// SendEmail sends a plain text email over the connection security of the config
+func SendEmail(cfg SMTPConfig, to []string, subject, body string) error {
+	if !cfg.Enabled() {
+		return fmt.Errorf("email is not configured")
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+		if cfg.Security == SMTPSecurityTLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	msg := buildMessage(cfg.From, to, subject, body)
+
+	switch cfg.Security {
+	case "", SMTPSecuritySTARTTLS:
+		return smtp.SendMail(addr, auth, cfg.From, to, msg)
+	case SMTPSecurityTLS, SMTPSecurityNone:
+	default:
+		return fmt.Errorf("unknown SMTP security %q", cfg.Security)
+	}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if cfg.Security == SMTPSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close() //nolint:errcheck // Cleanup, error not critical
+		return err
+	}
+	defer client.Close() //nolint:errcheck // Cleanup, error not critical
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage assembles an RFC 5322 message
+func buildMessage(from string, to []string, subject, body string) []byte {
+	var msg strings.Builder
+	msg.WriteString("From: " + headerValue(from) + "\r\n")
+	msg.WriteString("To: " + headerValue(strings.Join(to, ", ")) + "\r\n")
+	msg.WriteString("Subject: " + headerValue(subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(msg.String())
+}
+
+// headerValue strips line breaks so values can't inject additional headers
+func headerValue(v string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(v)
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// eventEmailSubject is the subject of all event emails
+var eventEmailSubject = template.Must(template.New("subject").Parse(
+	`[TreeOS{{with .Node}} {{.}}{{end}}] {{.Title}}`))
+
+// eventEmailBodies are the bodies of event emails by event type, with the advice on
+// what to do next. Events without their own body use eventEmailDefault.
+var eventEmailBodies = map[string]*template.Template{
+	EventAppStartFailed: eventEmailTemplate(`App {{.App}} failed to start:
+
+{{.Message}}
+
+Open the app in TreeOS to see its logs and start it again.`),
+	EventAppStopFailed: eventEmailTemplate(`App {{.App}} failed to stop:
+
+{{.Message}}
+
+Its containers may still be running. Open the app in TreeOS to try again.`),
+	EventContainerCrashed: eventEmailTemplate(`{{.Title}}.
+
+{{.Message}}
+
+The app may be unavailable. Open it in TreeOS to see the logs of the container and start it again.`),
+	EventContainerUnhealthy: eventEmailTemplate(`{{.Title}}.
+
+{{.Message}}
+
+The container is running, but its health check fails. Open the app in TreeOS to see its logs.`),
+	EventUpdateFailed: eventEmailTemplate(`{{.Title}}:
+
+{{.Message}}
+
+TreeOS keeps running the current version and tries again at the next check. Install the update from Settings to see the details.`),
+	EventDiskFull: eventEmailTemplate(`{{.Title}}.
+
+{{.Message}}`),
+	EventBackupFailed: eventEmailTemplate(`The daily database backup failed:
+
+{{.Message}}
+
+Recovery mode can only restore older backups until a backup succeeds again. Check the free space and permissions of the state directory.`),
+	EventTest: eventEmailTemplate(`{{.Message}}
+
+Emails for the events you chose in Settings will look like this one.`),
+}
+
+// eventEmailDefault is the body of events without their own
+var eventEmailDefault = eventEmailTemplate(`{{.Title}}{{with .Message}}
+
+{{.}}{{end}}`)
+
+// eventEmailTemplate parses the body of an event email and adds the common footer
+func eventEmailTemplate(body string) *template.Template {
+	return template.Must(template.New("body").Parse(body + `
+
+--
+{{.Time.Format "2006-01-02 15:04:05 MST"}}{{with .Node}} on {{.}}{{end}}{{with .App}}, app {{.}}{{end}}
+Sent by TreeOS because this event is selected under Settings → Email Notifications.
+`))
+}
+
+// RenderEventEmail returns the subject and body of the email for an event
+func RenderEventEmail(event Event) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := eventEmailSubject.Execute(&subject, event); err != nil {
+		return "", "", fmt.Errorf("failed to render email subject: %w", err)
+	}
+	tmpl, ok := eventEmailBodies[event.Type]
+	if !ok {
+		tmpl = eventEmailDefault
+	}
+	if err := tmpl.Execute(&body, event); err != nil {
+		return "", "", fmt.Errorf("failed to render email body: %w", err)
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
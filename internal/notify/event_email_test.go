@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderEventEmail(t *testing.T) {
+	at := time.Date(2026, 5, 4, 9, 12, 0, 0, time.UTC)
+	subject, body, err := RenderEventEmail(Event{
+		Type:    EventAppStartFailed,
+		Title:   "App blog failed to start",
+		Message: "port 8080 is already allocated",
+		App:     "blog",
+		Node:    "home",
+		Time:    at,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "[TreeOS home] App blog failed to start" {
+		t.Errorf("subject = %q", subject)
+	}
+	for _, want := range []string{"App blog failed to start:", "port 8080 is already allocated", "2026-05-04 09:12:00 UTC on home, app blog"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body misses %q:\n%s", want, body)
+		}
+	}
+
+	// Events without their own template get the title and message
+	subject, body, err = RenderEventEmail(Event{Type: EventUpdateApplied, Title: "TreeOS 1.4.0 installed", Time: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "[TreeOS] TreeOS 1.4.0 installed" || !strings.HasPrefix(body, "TreeOS 1.4.0 installed\n\n--") {
+		t.Errorf("subject = %q, body = %q", subject, body)
+	}
+}
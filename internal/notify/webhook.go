@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Kinds of webhook targets, each expects its own payload
+const (
+	// WebhookJSON posts the event as a JSON object
+	WebhookJSON = "json"
+	// WebhookSlack posts to a Slack incoming webhook
+	WebhookSlack = "slack"
+	// WebhookDiscord posts to a Discord channel webhook
+	WebhookDiscord = "discord"
+	// WebhookNtfy publishes to an ntfy topic URL
+	WebhookNtfy = "ntfy"
+)
+
+// WebhookKinds lists the kinds of webhook targets in the order the settings show them
+var WebhookKinds = []string{WebhookJSON, WebhookSlack, WebhookDiscord, WebhookNtfy}
+
+// Events webhooks and email notifications can subscribe to
+const (
+	EventAppStartFailed     = "app.start_failed"
+	EventAppStopFailed      = "app.stop_failed"
+	EventContainerCrashed   = "container.crashed"
+	EventContainerUnhealthy = "container.unhealthy"
+	EventUpdateApplied      = "update.applied"
+	EventUpdateFailed       = "update.failed"
+	EventDiskFull           = "disk.full"
+	EventBackupFailed       = "backup.failed"
+	// EventTest is sent by the test buttons, whatever a channel subscribes to
+	EventTest = "test"
+)
+
+// Events lists the events webhooks and email notifications can subscribe to, with their descriptions, in the
+// order the settings show them
+var Events = []struct {
+	Type        string
+	Description string
+}{
+	{EventAppStartFailed, "An app failed to start"},
+	{EventAppStopFailed, "An app failed to stop"},
+	{EventContainerCrashed, "A container of an app stopped on its own"},
+	{EventContainerUnhealthy, "A container of an app failed its health check"},
+	{EventUpdateApplied, "TreeOS or an app was updated"},
+	{EventUpdateFailed, "An automatic update of TreeOS failed"},
+	{EventDiskFull, "The disk is more than 90% full"},
+	{EventBackupFailed, "The daily database backup failed"},
+}
+
+// IsEvent returns true if notifications can subscribe to the event type
+func IsEvent(eventType string) bool {
+	for _, event := range Events {
+		if event.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is something that happened on the node that notifications are sent for
+type Event struct {
+	Type    string    `json:"event"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	App     string    `json:"app,omitempty"`
+	Node    string    `json:"node,omitempty"`
+	Time    time.Time `json:"timestamp"`
+}
+
+// discordMaxContent is the longest message Discord accepts
+const discordMaxContent = 2000
+
+// webhookTimeout bounds a delivery, a slow target must not hold up the next events
+const webhookTimeout = 10 * time.Second
+
+// ValidateWebhookURL returns an error if url can't be a webhook target
+func ValidateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook URL must be an http or https URL")
+	}
+	return nil
+}
+
+// SendWebhook posts an event to a webhook target in the payload its kind expects.
+// Responses other than 2xx are errors.
+func SendWebhook(ctx context.Context, kind, targetURL string, event Event) error {
+	body, contentType, headers, err := webhookPayload(kind, event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "TreeOS")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup, error not critical
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if text := strings.TrimSpace(string(detail)); text != "" {
+			return fmt.Errorf("webhook returned %s: %s", resp.Status, text)
+		}
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookPayload builds the request body, its content type and extra headers of a kind
+func webhookPayload(kind string, event Event) ([]byte, string, map[string]string, error) {
+	text := event.Title
+	if event.Message != "" {
+		text += "\n" + event.Message
+	}
+
+	var payload interface{}
+	switch kind {
+	case WebhookJSON:
+		payload = event
+	case WebhookSlack:
+		payload = map[string]string{"text": text}
+	case WebhookDiscord:
+		if runes := []rune(text); len(runes) > discordMaxContent {
+			text = string(runes[:discordMaxContent-1]) + "…"
+		}
+		payload = map[string]string{"content": text}
+	case WebhookNtfy:
+		// ntfy takes the message as the body and everything else as headers
+		headers := map[string]string{"Title": headerValue(event.Title), "Tags": "warning"}
+		if event.Type == EventUpdateApplied || event.Type == EventTest {
+			headers["Tags"] = "information_source"
+		} else {
+			headers["Priority"] = "high"
+		}
+		message := event.Message
+		if message == "" {
+			message = event.Title
+		}
+		return []byte(message), "text/plain; charset=utf-8", headers, nil
+	default:
+		return nil, "", nil, fmt.Errorf("unknown webhook kind %q", kind)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	return body, "application/json", nil, nil
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendWebhook(t *testing.T) {
+	var got struct {
+		contentType, title string
+		body               []byte
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.contentType = r.Header.Get("Content-Type")
+		got.title = r.Header.Get("Title")
+		got.body, _ = io.ReadAll(r.Body)
+		if strings.HasSuffix(r.URL.Path, "/broken") {
+			http.Error(w, "no such hook", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	event := Event{Type: EventAppStartFailed, Title: "App blog failed to start", Message: "port 80 is in use", App: "blog", Time: time.Unix(0, 0).UTC()}
+	tests := []struct {
+		kind  string
+		check func() bool
+	}{
+		{WebhookJSON, func() bool {
+			var decoded Event
+			return json.Unmarshal(got.body, &decoded) == nil && decoded == event
+		}},
+		{WebhookSlack, func() bool {
+			return string(got.body) == `{"text":"App blog failed to start\nport 80 is in use"}`
+		}},
+		{WebhookDiscord, func() bool {
+			return string(got.body) == `{"content":"App blog failed to start\nport 80 is in use"}`
+		}},
+		{WebhookNtfy, func() bool {
+			return strings.HasPrefix(got.contentType, "text/plain") && got.title == event.Title && string(got.body) == event.Message
+		}},
+	}
+	for _, tt := range tests {
+		if err := SendWebhook(context.Background(), tt.kind, srv.URL+"/hook", event); err != nil {
+			t.Errorf("%s: %v", tt.kind, err)
+			continue
+		}
+		if !tt.check() {
+			t.Errorf("%s: got %s %q", tt.kind, got.contentType, got.body)
+		}
+	}
+
+	if err := SendWebhook(context.Background(), WebhookJSON, srv.URL+"/broken", event); err == nil || !strings.Contains(err.Error(), "no such hook") {
+		t.Errorf("broken webhook: err = %v", err)
+	}
+	if err := SendWebhook(context.Background(), "pager", srv.URL, event); err == nil {
+		t.Error("unknown kind: expected an error")
+	}
+}
+
+func TestWebhookPayloadDiscordLimit(t *testing.T) {
+	body, _, _, err := webhookPayload(WebhookDiscord, Event{Title: strings.Repeat("x", 3000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if n := len([]rune(payload["content"])); n != discordMaxContent {
+		t.Errorf("content has %d characters", n)
+	}
+}
@@ -0,0 +1,112 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ontree-co/treeos/internal/progress"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+// ErrQuotaExceeded is returned when a download doesn't fit into the model disk quota
+var ErrQuotaExceeded = errors.New("model disk quota exceeded")
+
+// Quota limits the disk space of the shared Ollama directory
+type Quota struct {
+	Dir   string // The shared Ollama directory
+	Limit int64  // Bytes, 0 for no limit
+}
+
+// Used returns the disk space used below the shared Ollama directory, 0 if it doesn't exist yet
+func (q Quota) Used() (int64, error) {
+	used, err := storage.DirUsage(q.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return int64(used), err //nolint:gosec // Disk usage fits into int64
+}
+
+// Check returns an error wrapping ErrQuotaExceeded if need more bytes don't fit into the quota
+func (q Quota) Check(need int64) error {
+	if q.Limit <= 0 {
+		return nil
+	}
+	used, err := q.Used()
+	if err != nil {
+		return fmt.Errorf("failed to measure the models directory: %w", err)
+	}
+	if used+need > q.Limit {
+		//nolint:gosec // Sizes are never negative
+		return fmt.Errorf("%w: %s of %s used and the download needs about %s more, delete models you no longer use or raise ollama_quota",
+			ErrQuotaExceeded, storage.FormatSize(uint64(used)), storage.FormatSize(uint64(q.Limit)), storage.FormatSize(uint64(need)))
+	}
+	return nil
+}
+
+// manifest lists the blobs of a model in an Ollama manifest
+type manifest struct {
+	Config manifestBlob   `json:"config"`
+	Layers []manifestBlob `json:"layers"`
+}
+
+type manifestBlob struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// ModelSizes returns the bytes of the blobs of each model in the shared Ollama directory, by
+// model name. Blobs that several models share count for each of them.
+func ModelSizes(sharedBase string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	for _, manifestsRoot := range uniqueStrings([]string{
+		filepath.Join(sharedBase, "models", "manifests"),
+		filepath.Join(sharedBase, "manifests"),
+	}) {
+		err := filepath.WalkDir(manifestsRoot, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			data, err := os.ReadFile(path) //nolint:gosec // File path from Ollama manifests directory
+			if err != nil {
+				return nil
+			}
+			var m manifest
+			if json.Unmarshal(data, &m) != nil {
+				return nil
+			}
+			name := extractModelNameFromManifest(path, manifestsRoot)
+			if name == "" {
+				return nil
+			}
+			size := m.Config.Size
+			for _, layer := range m.Layers {
+				size += layer.Size
+			}
+			sizes[name] = size
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sizes, nil
+}
+
+// remainingBytes returns the bytes of the layers that still have to be downloaded
+func remainingBytes(layers []progress.ImageProgress) int64 {
+	var remaining int64
+	for _, layer := range layers {
+		remaining += max(layer.Total-layer.Downloaded, 0)
+	}
+	return remaining
+}
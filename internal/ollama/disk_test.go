@@ -0,0 +1,51 @@
+package ollama
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModelSizes(t *testing.T) {
+	dir := t.TempDir()
+	manifestDir := filepath.Join(dir, "models", "manifests", "registry.ollama.ai", "library", "llama3")
+	if err := os.MkdirAll(manifestDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"config":{"digest":"sha256:aa","size":485},"layers":[{"digest":"sha256:bb","size":4661211424},{"digest":"sha256:cc","size":12403}]}`
+	if err := os.WriteFile(filepath.Join(manifestDir, "8b"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sizes, err := ModelSizes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 1 || sizes["llama3:8b"] != 4661224312 {
+		t.Errorf("ModelSizes() = %v", sizes)
+	}
+	if sizes, err := ModelSizes(filepath.Join(dir, "missing")); err != nil || len(sizes) != 0 {
+		t.Errorf("ModelSizes() of a missing directory = %v, %v", sizes, err)
+	}
+}
+
+func TestQuotaCheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "blob"), make([]byte, 8192), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (Quota{Dir: dir}).Check(1 << 40); err != nil {
+		t.Errorf("Check() without a limit = %v", err)
+	}
+	if err := (Quota{Dir: dir, Limit: 1 << 20}).Check(4096); err != nil {
+		t.Errorf("Check() below the limit = %v", err)
+	}
+	if err := (Quota{Dir: dir, Limit: 1 << 20}).Check(1 << 20); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Check() over the limit = %v", err)
+	}
+	if err := (Quota{Dir: filepath.Join(dir, "missing"), Limit: 1 << 20}).Check(4096); err != nil {
+		t.Errorf("Check() of a missing directory = %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package ollama
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/progress"
+)
+
+// layerLineRegex matches the progress line of a layer of `ollama pull`, e.g.
+// "pulling 74701a8c35f6:  45% ▕███      ▏ 2.1 GB/4.7 GB  50 MB/s  52s". Older versions
+// write "pulling 74701a8c35f6... 45%". A finished layer only shows its size.
+var layerLineRegex = regexp.MustCompile(`pulling ([0-9a-f]{12})(?:\.\.\.|:)\s*(\d+)%.*?([\d.]+\s*[KMGT]?B)(?:\s*/\s*([\d.]+\s*[KMGT]?B))?`)
+
+// Layer states of a model download
+const (
+	LayerDownloading = "downloading"
+	LayerComplete    = "complete"
+)
+
+// ParseLayerProgress extracts a layer's digest and its downloaded and total bytes from a
+// line of `ollama pull`. ok is false for other lines.
+func ParseLayerProgress(line string) (digest string, completed, total int64, ok bool) {
+	match := layerLineRegex.FindStringSubmatch(cleanOutput(line))
+	if match == nil {
+		return "", 0, 0, false
+	}
+	percent, _ := strconv.Atoi(match[2])
+	first, ok1 := parseHumanBytes(match[3])
+	if !ok1 {
+		return "", 0, 0, false
+	}
+	if match[4] == "" {
+		// Only the size: the layer is done, or has not started if its percentage is 0
+		if percent == 0 {
+			return match[1], 0, first, true
+		}
+		return match[1], first, first, true
+	}
+	second, ok2 := parseHumanBytes(match[4])
+	if !ok2 {
+		return "", 0, 0, false
+	}
+	return match[1], first, second, true
+}
+
+// parseHumanBytes parses a size as Ollama prints it, e.g. "4.7 GB", with decimal units
+func parseHumanBytes(s string) (int64, bool) {
+	s = strings.ReplaceAll(s, " ", "")
+	unit := strings.TrimLeft(s, "0123456789.")
+	value, err := strconv.ParseFloat(strings.TrimSuffix(s, unit), 64)
+	if err != nil {
+		return 0, false
+	}
+	multiplier := map[string]float64{"B": 1, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12}[unit]
+	if multiplier == 0 {
+		return 0, false
+	}
+	return int64(value * multiplier), true
+}
+
+// layersProgress returns the percentage of all bytes of the layers downloaded so far, so
+// small layers don't count as much as the weights
+func layersProgress(layers []progress.ImageProgress) int {
+	var completed, total int64
+	for _, layer := range layers {
+		completed += layer.Downloaded
+		total += layer.Total
+	}
+	if total == 0 {
+		return 0
+	}
+	return int(completed * 100 / total)
+}
+
+// sortedLayers returns the layers of a download in a stable order
+func sortedLayers(info *progress.AppProgress) []progress.ImageProgress {
+	layers := make([]progress.ImageProgress, 0, len(info.Images))
+	for _, layer := range info.Images {
+		layers = append(layers, *layer)
+	}
+	sort.Slice(layers, func(i, j int) bool { return layers[i].Name < layers[j].Name })
+	return layers
+}
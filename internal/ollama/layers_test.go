@@ -0,0 +1,45 @@
+package ollama
+
+import (
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/progress"
+)
+
+func TestParseLayerProgress(t *testing.T) {
+	tests := []struct {
+		line      string
+		digest    string
+		completed int64
+		total     int64
+		ok        bool
+	}{
+		{line: "\x1b[?25lpulling 74701a8c35f6:  45% ▕███████         ▏ 2.1 GB/4.7 GB   50 MB/s   52s\x1b[K",
+			digest: "74701a8c35f6", completed: 2100000000, total: 4700000000, ok: true},
+		{line: "pulling 966de95ca8a6... 12% ▕██  ▏ 180 B/1.4 KB", digest: "966de95ca8a6", completed: 180, total: 1400, ok: true},
+		{line: "pulling fcc5a6bec9da: 100% ▕████████████████▏ 7.7 KB", digest: "fcc5a6bec9da", completed: 7700, total: 7700, ok: true},
+		{line: "pulling a70ff7e570d9:   0% ▕                ▏    0 B/6.0 KB", digest: "a70ff7e570d9", completed: 0, total: 6000, ok: true},
+		{line: "pulling manifest"},
+		{line: "verifying sha256 digest"},
+	}
+	for _, tt := range tests {
+		digest, completed, total, ok := ParseLayerProgress(tt.line)
+		if digest != tt.digest || completed != tt.completed || total != tt.total || ok != tt.ok {
+			t.Errorf("ParseLayerProgress(%q) = %q, %d, %d, %v", tt.line, digest, completed, total, ok)
+		}
+	}
+}
+
+func TestLayersProgress(t *testing.T) {
+	layers := []progress.ImageProgress{
+		{Name: "weights", Downloaded: 1000, Total: 4000},
+		{Name: "license", Downloaded: 10, Total: 10},
+	}
+	// The finished license barely counts
+	if got := layersProgress(layers); got != 25 {
+		t.Errorf("layersProgress() = %d, want 25", got)
+	}
+	if got := layersProgress(nil); got != 0 {
+		t.Errorf("layersProgress(nil) = %d", got)
+	}
+}
@@ -3,6 +3,8 @@ package ollama
 import (
 	"database/sql"
 	"time"
+
+	"github.com/ontree-co/treeos/internal/progress"
 )
 
 // OllamaModel represents an Ollama model in the system
@@ -12,7 +14,7 @@ type OllamaModel struct { //nolint:revive // intentional naming for clarity
 	SizeEstimate string         `json:"size_estimate"` // Estimated download size
 	Description  string         `json:"description"`
 	Category     string         `json:"category"` // chat, code, vision, etc.
-	Status       string         `json:"status"`   // not_downloaded, queued, downloading, completed, failed
+	Status       string         `json:"status"`   // not_downloaded, queued, downloading, paused, completed, failed
 	Progress     int            `json:"progress"` // 0-100
 	LastError    sql.NullString `json:"last_error,omitempty"`
 	UpdatedAt    time.Time      `json:"updated_at"`
@@ -34,6 +36,8 @@ type ProgressUpdate struct {
 	Status    string `json:"status"`
 	Progress  int    `json:"progress"`
 	Error     string `json:"error,omitempty"`
+	// Progress of each layer, its Name is the digest
+	Layers []progress.ImageProgress `json:"layers,omitempty"`
 }
 
 // ModelStatus constants
@@ -41,6 +45,7 @@ const (
 	StatusNotDownloaded = "not_downloaded"
 	StatusQueued        = "queued"
 	StatusDownloading   = "downloading"
+	StatusPaused        = "paused"
 	StatusCompleted     = "completed"
 	StatusFailed        = "failed"
 )
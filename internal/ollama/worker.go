@@ -3,12 +3,23 @@ package ollama
 import (
 	"bufio"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 	"sync"
 	"time"
 	"github.com/ontree-co/treeos/internal/logging"
+
+	"github.com/ontree-co/treeos/internal/engine"
+	"github.com/ontree-co/treeos/internal/progress"
+)
+
+// Reasons a download stops before it finishes
+const (
+	stopCancelled = "cancelled"
+	stopPaused    = "paused"
+	stopQuota     = "quota"
 )
 
 // Worker manages the background processing of Ollama model downloads
@@ -22,6 +33,10 @@ type Worker struct {
 	// Track active downloads for cancellation
 	activeMu        sync.Mutex
 	activeDownloads map[string]*exec.Cmd
+	stopped         map[string]string // Why a download was stopped, by model
+	// Layer progress of the downloads, by model
+	tracker *progress.Tracker
+	quota   Quota
 }
 
 // NewWorker creates a new worker instance
@@ -38,6 +53,8 @@ func NewWorker(db *sql.DB, containerName string) *Worker {
 		stopCh:          make(chan struct{}),
 		containerName:   containerName,
 		activeDownloads: make(map[string]*exec.Cmd),
+		stopped:         make(map[string]string),
+		tracker:         progress.NewTracker(),
 	}
 }
 
@@ -85,13 +102,107 @@ func (w *Worker) AddJob(job DownloadJob) {
 	}
 }
 
+// SetQuota limits the disk space downloads may fill, call it before Start
+func (w *Worker) SetQuota(quota Quota) {
+	w.quota = quota
+}
+
+// CheckQuota returns an error wrapping ErrQuotaExceeded if the download of a model doesn't
+// fit into the quota. What is already on disk, of a paused download or an installed model
+// that is pulled again, doesn't count.
+func (w *Worker) CheckQuota(modelName string) error {
+	if w.quota.Limit <= 0 {
+		return nil
+	}
+	return w.quota.Check(w.downloadSize(modelName))
+}
+
+// downloadSize estimates the bytes the download of a model still adds to the disk
+func (w *Worker) downloadSize(modelName string) int64 {
+	if layers := w.Layers(modelName); len(layers) > 0 {
+		return remainingBytes(layers)
+	}
+	if sizes, err := ModelSizes(w.quota.Dir); err == nil {
+		if _, installed := sizes[modelName]; installed {
+			return 0
+		}
+	}
+	model, err := GetModel(w.db, modelName)
+	if err != nil || model == nil {
+		return 0
+	}
+	size, _ := parseHumanBytes(model.SizeEstimate)
+	return size
+}
+
 // GetUpdatesChannel returns the channel for progress updates
 func (w *Worker) GetUpdatesChannel() <-chan ProgressUpdate {
 	return w.updates
 }
 
+// Layers returns the progress of each layer of a model's download, nil if there is none
+func (w *Worker) Layers(modelName string) []progress.ImageProgress {
+	info, exists := w.tracker.GetProgress(modelName)
+	if !exists {
+		return nil
+	}
+	return sortedLayers(info)
+}
+
 // CancelDownload cancels an active download for a specific model
 func (w *Worker) CancelDownload(modelName string) error {
+	if err := w.stopDownload(modelName, stopCancelled); err != nil {
+		return err
+	}
+
+	// Try to clean up partial download immediately
+	// Note: This cleanup is also done in the handler, but we do it here too for redundancy
+	cleanupCmd := exec.Command(engine.CLI(), "exec", w.containerName, "ollama", "rm", modelName) //nolint:gosec // containerName and modelName are validated
+	cleanupOutput, cleanupErr := cleanupCmd.CombinedOutput()
+	if cleanupErr == nil {
+		logging.Infof("Worker cleaned up partial download for model %s", modelName)
+	} else if !strings.Contains(string(cleanupOutput), "not found") {
+		logging.Infof("Worker could not clean up partial model %s: %v", modelName, cleanupErr)
+	}
+	w.tracker.RemoveOperation(modelName)
+
+	// Send cancellation update
+	w.sendUpdate(ProgressUpdate{
+		ModelName: modelName,
+		Status:    StatusNotDownloaded,
+		Progress:  0,
+		Error:     "Download cancelled by user",
+	})
+
+	return nil
+}
+
+// PauseDownload stops an active download and keeps what was downloaded. Ollama continues
+// each layer from its partial file when the model is pulled again.
+func (w *Worker) PauseDownload(modelName string) error {
+	if err := w.stopDownload(modelName, stopPaused); err != nil {
+		return err
+	}
+
+	modelProgress := 0
+	if model, err := GetModel(w.db, modelName); err == nil && model != nil {
+		modelProgress = model.Progress
+	}
+	if err := UpdateModelStatus(w.db, modelName, StatusPaused, modelProgress); err != nil {
+		logging.Errorf("Failed to update model status: %v", err)
+	}
+	w.sendUpdate(ProgressUpdate{
+		ModelName: modelName,
+		Status:    StatusPaused,
+		Progress:  modelProgress,
+		Layers:    w.Layers(modelName),
+	})
+	return nil
+}
+
+// stopDownload kills the active download of a model, inside the container and the exec
+// process, and notes why for processDownload
+func (w *Worker) stopDownload(modelName, reason string) error {
 	w.activeMu.Lock()
 	defer w.activeMu.Unlock()
 
@@ -113,7 +224,7 @@ func (w *Worker) CancelDownload(modelName string) error {
 
 	if containerName != "" {
 		//nolint:gosec // Container name validated from discovery, model name from request
-		killInsideCmd := exec.Command("docker", "exec", containerName, "sh", "-c",
+		killInsideCmd := exec.Command(engine.CLI(), "exec", containerName, "sh", "-c",
 			fmt.Sprintf("pkill -f 'ollama pull %s' || true", modelName))
 		if err := killInsideCmd.Run(); err != nil {
 			logging.Warnf("Warning: Failed to kill ollama process inside container: %v", err)
@@ -139,28 +250,18 @@ func (w *Worker) CancelDownload(modelName string) error {
 
 	// Remove from active downloads
 	delete(w.activeDownloads, modelName)
-
-	// Try to clean up partial download immediately
-	// Note: This cleanup is also done in the handler, but we do it here too for redundancy
-	cleanupCmd := exec.Command("docker", "exec", w.containerName, "ollama", "rm", modelName) //nolint:gosec // containerName and modelName are validated
-	cleanupOutput, cleanupErr := cleanupCmd.CombinedOutput()
-	if cleanupErr == nil {
-		logging.Infof("Worker cleaned up partial download for model %s", modelName)
-	} else if !strings.Contains(string(cleanupOutput), "not found") {
-		logging.Infof("Worker could not clean up partial model %s: %v", modelName, cleanupErr)
-	}
-
-	// Send cancellation update
-	w.sendUpdate(ProgressUpdate{
-		ModelName: modelName,
-		Status:    StatusNotDownloaded,
-		Progress:  0,
-		Error:     "Download cancelled by user",
-	})
+	w.stopped[modelName] = reason
 
 	return nil
 }
 
+// isActive reports whether cmd is still the active download of a model, not stopped
+func (w *Worker) isActive(modelName string, cmd *exec.Cmd) bool {
+	w.activeMu.Lock()
+	defer w.activeMu.Unlock()
+	return w.activeDownloads[modelName] == cmd
+}
+
 // processJobs is the main worker loop
 func (w *Worker) processJobs(workerID int) {
 	defer w.wg.Done()
@@ -186,7 +287,7 @@ func (w *Worker) processJobs(workerID int) {
 // discoverOllamaContainer finds the running Ollama container using label-based detection
 func (w *Worker) discoverOllamaContainer() (string, error) {
 	// Look for containers with the ontree.inference=true label
-	cmd := exec.Command("docker", "ps", "--filter", "label=ontree.inference=true", "--format", "{{.Names}}")
+	cmd := exec.Command(engine.CLI(), "ps", "--filter", "label=ontree.inference=true", "--format", "{{.Names}}")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to list containers: %w", err)
@@ -226,6 +327,13 @@ func (w *Worker) processDownload(job DownloadJob) {
 		return
 	}
 
+	if err := w.CheckQuota(job.ModelName); errors.Is(err, ErrQuotaExceeded) {
+		w.handleError(job, err.Error())
+		return
+	} else if err != nil {
+		logging.Warnf("Failed to check the model quota: %v", err)
+	}
+
 	// Update model status to downloading
 	err = UpdateModelStatus(w.db, job.ModelName, StatusDownloading, 0)
 	if err != nil {
@@ -241,12 +349,14 @@ func (w *Worker) processDownload(job DownloadJob) {
 
 	// Execute the ollama pull command
 	//nolint:gosec // Container name validated from discovery, model name from request
-	cmd := exec.Command("docker", "exec", containerName, "ollama", "pull", job.ModelName)
+	cmd := exec.Command(engine.CLI(), "exec", containerName, "ollama", "pull", job.ModelName)
 
 	// Track this command for potential cancellation
 	w.activeMu.Lock()
 	w.activeDownloads[job.ModelName] = cmd
+	delete(w.stopped, job.ModelName)
 	w.activeMu.Unlock()
+	w.tracker.StartOperation(job.ModelName, progress.OperationDownloading, "Pulling manifest")
 
 	// Ensure we clean up when done
 	defer func() {
@@ -270,7 +380,8 @@ func (w *Worker) processDownload(job DownloadJob) {
 
 	// Read and parse output from stderr - using a reader to handle carriage returns
 	reader := bufio.NewReader(stderr)
-	var lastProgress int
+	var lastProgress, lastLayers, checkedLayers int
+	var quotaErr error
 	var buffer []byte
 
 	for {
@@ -287,15 +398,40 @@ func (w *Worker) processDownload(job DownloadJob) {
 			if len(buffer) > 0 {
 				line := string(buffer)
 
-				// Parse progress from the output
-				progress := ParseProgress(line)
-				if progress > 0 {
-					logging.Infof("Parsed progress: %d%%", progress)
-					if progress != lastProgress {
-						lastProgress = progress
+				// Parse progress from the output. Layer lines count by the bytes of all
+				// layers, a layer at 100% isn't the whole model.
+				var modelProgress int
+				var layers []progress.ImageProgress
+				if digest, completed, total, ok := ParseLayerProgress(line); ok {
+					status := LayerDownloading
+					if total > 0 && completed >= total {
+						status = LayerComplete
+					}
+					w.tracker.UpdateImageProgress(job.ModelName, digest, completed, total, status)
+					layers = w.Layers(job.ModelName)
+					modelProgress = min(max(layersProgress(layers), 1), 99)
+
+					// The sizes of the layers are known once they show up, stop when they
+					// don't fit after all, e.g. because the size estimate was off
+					if len(layers) > checkedLayers && quotaErr == nil {
+						checkedLayers = len(layers)
+						if err := w.quota.Check(remainingBytes(layers)); errors.Is(err, ErrQuotaExceeded) {
+							quotaErr = err
+							if err := w.stopDownload(job.ModelName, stopQuota); err != nil {
+								logging.Errorf("Failed to stop download over quota: %v", err)
+							}
+						}
+					}
+				} else {
+					modelProgress = ParseProgress(line)
+				}
+				if modelProgress > 0 {
+					if (modelProgress > lastProgress || len(layers) > lastLayers) && w.isActive(job.ModelName, cmd) {
+						lastProgress = max(modelProgress, lastProgress)
+						lastLayers = max(len(layers), lastLayers)
 
 						// Update database
-						err = UpdateModelStatus(w.db, job.ModelName, StatusDownloading, progress)
+						err = UpdateModelStatus(w.db, job.ModelName, StatusDownloading, lastProgress)
 						if err != nil {
 							logging.Errorf("Failed to update progress: %v", err)
 						}
@@ -304,7 +440,8 @@ func (w *Worker) processDownload(job DownloadJob) {
 						w.sendUpdate(ProgressUpdate{
 							ModelName: job.ModelName,
 							Status:    StatusDownloading,
-							Progress:  progress,
+							Progress:  lastProgress,
+							Layers:    layers,
 						})
 					}
 				} else {
@@ -330,8 +467,22 @@ func (w *Worker) processDownload(job DownloadJob) {
 		w.activeMu.Unlock()
 
 		if !stillActive {
-			// This was cancelled, don't treat as error
-			logging.Infof("Download cancelled for model %s", job.ModelName)
+			// This was cancelled or paused, don't treat as error
+			w.activeMu.Lock()
+			reason := w.stopped[job.ModelName]
+			delete(w.stopped, job.ModelName)
+			w.activeMu.Unlock()
+			if reason == stopQuota {
+				w.handleError(job, quotaErr.Error())
+				return
+			}
+			if reason == "" {
+				reason = stopCancelled
+			}
+			if err := UpdateJobStatus(w.db, job.ID, reason); err != nil {
+				logging.Errorf("Failed to update job status: %v", err)
+			}
+			logging.Infof("Download %s for model %s", reason, job.ModelName)
 			return
 		}
 
@@ -339,6 +490,8 @@ func (w *Worker) processDownload(job DownloadJob) {
 		return
 	}
 
+	w.tracker.RemoveOperation(job.ModelName)
+
 	// Mark as completed
 	err = UpdateModelStatus(w.db, job.ModelName, StatusCompleted, 100)
 	if err != nil {
@@ -376,12 +529,14 @@ func (w *Worker) handleError(job DownloadJob, errorMsg string) {
 		logging.Errorf("Failed to update job failure: %v", err)
 	}
 
-	// Send error update
+	// Send error update, the layers stay for a retry to continue
+	w.tracker.SetError(job.ModelName, errorMsg)
 	w.sendUpdate(ProgressUpdate{
 		ModelName: job.ModelName,
 		Status:    StatusFailed,
 		Progress:  0,
 		Error:     errorMsg,
+		Layers:    w.Layers(job.ModelName),
 	})
 }
 
@@ -434,26 +589,7 @@ func (w *Worker) startCleanupTask() {
 
 // ParseProgress extracts progress percentage from ollama output
 func ParseProgress(line string) int {
-	// First, handle the ANSI escape codes that ollama uses
-	// Remove all ANSI escape sequences
-
-	// Remove the [?2026h, [?2026l, [?25h, [?25l sequences
-	line = strings.ReplaceAll(line, "[?2026h", "")
-	line = strings.ReplaceAll(line, "[?2026l", "")
-	line = strings.ReplaceAll(line, "[?25h", "")
-	line = strings.ReplaceAll(line, "[?25l", "")
-
-	// Remove cursor position sequences like [1G, [K, [A
-	line = strings.ReplaceAll(line, "[1G", "")
-	line = strings.ReplaceAll(line, "[K", "")
-	line = strings.ReplaceAll(line, "[A", "")
-
-	// Remove carriage returns and newlines
-	line = strings.ReplaceAll(line, "\r", "")
-	line = strings.ReplaceAll(line, "\n", "")
-
-	// Trim spaces
-	line = strings.TrimSpace(line)
+	line = cleanOutput(line)
 
 	// Log cleaned line for debugging
 	if line != "" && !strings.Contains(line, "⠋") && !strings.Contains(line, "⠙") &&
@@ -498,3 +634,24 @@ func ParseProgress(line string) int {
 
 	return 0
 }
+
+// cleanOutput removes the terminal control sequences ollama writes around its output
+func cleanOutput(line string) string {
+	// Remove the [?2026h, [?2026l, [?25h, [?25l sequences
+	line = strings.ReplaceAll(line, "[?2026h", "")
+	line = strings.ReplaceAll(line, "[?2026l", "")
+	line = strings.ReplaceAll(line, "[?25h", "")
+	line = strings.ReplaceAll(line, "[?25l", "")
+
+	// Remove cursor position sequences like [1G, [K, [A
+	line = strings.ReplaceAll(line, "[1G", "")
+	line = strings.ReplaceAll(line, "[K", "")
+	line = strings.ReplaceAll(line, "[A", "")
+
+	// Remove carriage returns and newlines
+	line = strings.ReplaceAll(line, "\r", "")
+	line = strings.ReplaceAll(line, "\n", "")
+
+	// Trim spaces
+	return strings.TrimSpace(line)
+}
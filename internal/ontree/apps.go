@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -12,6 +13,11 @@ import (
 	"time"
 
 	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/ports"
+	"github.com/ontree-co/treeos/internal/secrets"
+	"github.com/ontree-co/treeos/internal/storage"
+	"github.com/ontree-co/treeos/internal/templates"
 	"github.com/ontree-co/treeos/internal/yamlutil"
 	"github.com/ontree-co/treeos/pkg/compose"
 	"gopkg.in/yaml.v3"
@@ -36,13 +42,17 @@ func (m *Manager) AppInstall(ctx context.Context, appID, version, envPath string
 		}
 
 		content, err := m.templateSvc.GetTemplateContent(template)
+		if errors.Is(err, templates.ErrUnsupportedArchitecture) {
+			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "unsupported_architecture"}
+			return
+		}
 		if err != nil {
 			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "template_read_failed"}
 			return
 		}
-		content = m.templateSvc.ProcessTemplateContent(content, appID)
 
 		var envContent string
+		env := map[string]string{}
 		if envPath != "" {
 			raw, err := os.ReadFile(envPath)
 			if err != nil {
@@ -50,11 +60,50 @@ func (m *Manager) AppInstall(ctx context.Context, appID, version, envPath string
 				return
 			}
 			envContent = string(raw)
+			if env, err = compose.ReadEnvFile(envPath); err != nil {
+				ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "env_read_failed"}
+				return
+			}
+		}
+
+		// Template variables are taken from the env file, the rest get their defaults
+		values, secrets, err := templates.ResolveVariables(template.Variables, func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		})
+		if err != nil {
+			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "invalid_variables"}
+			return
+		}
+		if content, err = templates.RenderVariables(content, values); err == nil {
+			envContent, err = templates.SetEnvVariables(envContent, template.Variables, values)
 		}
+		if err != nil {
+			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "template_invalid"}
+			return
+		}
+		content = m.templateSvc.ProcessTemplateContent(content, appID)
 
 		envContent = m.injectOpenWebUIAdminEnv(appID, envContent)
 
-		if err := m.createAppScaffoldFromTemplate(appID, content, envContent, ""); err != nil {
+		storageClass, err := storage.ParseClass(template.StorageClass)
+		if err != nil {
+			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "template_invalid"}
+			return
+		}
+
+		// Ports other apps or processes use are moved to free ones
+		composeData, envData, assignments, err := ports.NewRegistry(m.cfg.AppsDir).Assign(appID, []byte(content), []byte(envContent))
+		if err != nil {
+			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "port_conflict"}
+			return
+		}
+		content, envContent = string(composeData), string(envData)
+		for _, assignment := range assignments {
+			ch <- ProgressEvent{Type: "log", Message: fmt.Sprintf("host port %d is taken, service %s publishes %d instead", assignment.From, assignment.Service, assignment.To)}
+		}
+
+		if err := m.createAppScaffoldFromTemplate(appID, content, envContent, "", storageClass, secrets); err != nil {
 			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "app_create_failed"}
 			return
 		}
@@ -202,6 +251,14 @@ func (m *Manager) ensureCompose() error {
 	if err != nil {
 		return err
 	}
+	svc.SetConfinement(compose.NewConfinement(m.cfg.Confinement, m.cfg.ConfinementProfile))
+	svc.SetReadOnlyRoot(m.cfg.ReadOnlyRoot)
+	svc.SetSecretStore(database.GetAppSecrets)
+	box, err := secrets.Load(m.cfg.StatePath(secrets.KeyFile))
+	if err != nil {
+		return err
+	}
+	svc.SetEnvSecretStore(box.Values)
 	m.composeSvc = svc
 	return nil
 }
@@ -241,12 +298,27 @@ func (m *Manager) checkHTTPReady(ctx context.Context, httpURL string) bool {
 	return resp.StatusCode >= 200 && resp.StatusCode < 400
 }
 
-func (m *Manager) createAppScaffoldFromTemplate(appName, composeContent, envContent, emoji string) error {
+func (m *Manager) createAppScaffoldFromTemplate(appName, composeContent, envContent, emoji string, storageClass storage.Class, known templates.Secrets) error {
 	appPath := filepath.Join(m.cfg.AppsDir, appName)
 
-	if err := m.createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji); err != nil {
+	secrets := templates.Secrets{}
+	maps.Copy(secrets, known)
+	envContent, err := templates.ResolveSecrets(envContent, secrets)
+	if err != nil {
+		return fmt.Errorf("invalid secret in .env: %w", err)
+	}
+	if composeContent, err = templates.ResolveSecrets(composeContent, secrets); err != nil {
+		return fmt.Errorf("invalid secret in docker-compose.yml: %w", err)
+	}
+
+	if err := m.createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji, storageClass); err != nil {
 		return err
 	}
+	if len(secrets) > 0 {
+		if err := database.StoreAppCredentials(appName, secrets); err != nil {
+			return fmt.Errorf("failed to store generated credentials: %w", err)
+		}
+	}
 
 	if err := m.generateAppYamlWithFlags(appPath, appName, composeContent, true); err != nil {
 		return err
@@ -255,7 +327,7 @@ func (m *Manager) createAppScaffoldFromTemplate(appName, composeContent, envCont
 	return nil
 }
 
-func (m *Manager) createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji string) error {
+func (m *Manager) createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji string, storageClass storage.Class) error {
 	if err := os.MkdirAll(appPath, 0750); err != nil {
 		return fmt.Errorf("failed to create app directory: %w", err)
 	}
@@ -266,7 +338,7 @@ func (m *Manager) createAppScaffoldInternal(appPath, appName, composeContent, en
 		}
 	}
 
-	if err := os.MkdirAll(filepath.Join(appPath, "mnt"), 0750); err != nil {
+	if err := m.createAppMount(filepath.Join(appPath, "mnt"), appName, storageClass); err != nil {
 		return fmt.Errorf("failed to create mnt directory: %w", err)
 	}
 	if err := os.MkdirAll(filepath.Join(appPath, "volumes"), 0750); err != nil {
@@ -286,8 +358,16 @@ func (m *Manager) createAppScaffoldInternal(appPath, appName, composeContent, en
 	} else {
 		envContent = namingConfig
 	}
+	box, err := secrets.Load(m.cfg.StatePath(secrets.KeyFile))
+	if err != nil {
+		return err
+	}
+	sealed, err := box.SealEnv(appName, []byte(envContent), nil)
+	if err != nil {
+		return fmt.Errorf("failed to store secrets: %w", err)
+	}
 	envPath := filepath.Join(appPath, ".env")
-	if err := os.WriteFile(envPath, []byte(envContent), 0600); err != nil {
+	if err := os.WriteFile(envPath, sealed, 0600); err != nil {
 		return fmt.Errorf("failed to write .env file: %w", err)
 	}
 
@@ -295,14 +375,14 @@ func (m *Manager) createAppScaffoldInternal(appPath, appName, composeContent, en
 	if err != nil {
 		hostPort = 0
 	}
-	if err := m.addMetadata(composePath, appName, emoji, hostPort); err != nil {
+	if err := m.addMetadata(composePath, appName, emoji, hostPort, storageClass); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (m *Manager) addMetadata(composePath, appName, emoji string, hostPort int) error {
+func (m *Manager) addMetadata(composePath, appName, emoji string, hostPort int, storageClass storage.Class) error {
 	yamlData, err := yamlutil.ReadComposeWithMetadata(composePath)
 	if err != nil {
 		return err
@@ -312,6 +392,8 @@ func (m *Manager) addMetadata(composePath, appName, emoji string, hostPort int)
 		HostPort:  hostPort,
 		IsExposed: false,
 		Emoji:     emoji,
+
+		StorageClass: string(storageClass),
 	}
 	yamlutil.SetOnTreeMetadata(yamlData, metadata)
 	if err := yamlutil.WriteComposeWithMetadata(composePath, yamlData); err != nil {
@@ -320,6 +402,15 @@ func (m *Manager) addMetadata(composePath, appName, emoji string, hostPort int)
 	return nil
 }
 
+// createAppMount creates the app's mnt directory, linked to a matching storage root if one is configured
+func (m *Manager) createAppMount(mntPath, appName string, class storage.Class) error {
+	if root, ok := storage.SelectRoot(m.cfg.StorageRoots, class); class != "" && ok {
+		_, err := storage.PlaceAppMount(mntPath, appName, root)
+		return err
+	}
+	return os.MkdirAll(mntPath, 0750)
+}
+
 func (m *Manager) generateAppYamlWithFlags(appPath, appName, composeContent string, fromTemplate bool) error {
 	var composeFile map[string]interface{}
 	if err := yaml.Unmarshal([]byte(composeContent), &composeFile); err != nil {
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/secrets"
 )
 
 func TestAppInstallInjectsOpenWebUIAdminEnv(t *testing.T) {
@@ -50,7 +51,7 @@ func TestAppInstallInjectsOpenWebUIAdminEnv(t *testing.T) {
 
 	required := []string{
 		"WEBUI_ADMIN_EMAIL=admin@example.com",
-		"WEBUI_ADMIN_PASSWORD=password123",
+		"WEBUI_ADMIN_PASSWORD=" + secrets.Placeholder("WEBUI_ADMIN_PASSWORD"),
 		"WEBUI_ADMIN_NAME=TreeOS Admin",
 	}
 	for _, line := range required {
@@ -58,4 +59,13 @@ func TestAppInstallInjectsOpenWebUIAdminEnv(t *testing.T) {
 			t.Fatalf("expected env to contain %q", line)
 		}
 	}
+
+	// The password is kept in the secrets store instead
+	box, err := secrets.Load(cfg.StatePath(secrets.KeyFile))
+	if err != nil {
+		t.Fatalf("secrets.Load() error = %v", err)
+	}
+	if values, err := box.Values("openwebui"); err != nil || values["WEBUI_ADMIN_PASSWORD"] != "password123" {
+		t.Errorf("stored secrets = %v, %v", values, err)
+	}
 }
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/ontree-co/treeos/internal/config"
 	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/engine"
 	"github.com/ontree-co/treeos/internal/logging"
 	"github.com/ontree-co/treeos/internal/templates"
 	"github.com/ontree-co/treeos/pkg/compose"
@@ -63,6 +65,27 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 	return manager, nil
 }
 
+// UseContainerEngine points the app and model commands at the container engine the server
+// uses: CONTAINER_RUNTIME, else the settings page, else the configuration.
+func (m *Manager) UseContainerEngine(ctx context.Context) error {
+	mode := m.cfg.ContainerRuntime
+	if os.Getenv("CONTAINER_RUNTIME") == "" {
+		var setting sql.NullString
+		err := m.db.QueryRowContext(ctx, `SELECT container_runtime FROM system_setup WHERE id = 1`).Scan(&setting)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to read container runtime setting: %w", err)
+		}
+		if setting.String != "" {
+			mode = setting.String
+		}
+	}
+	e, err := engine.Detect(mode)
+	if err != nil {
+		return err
+	}
+	return e.Apply()
+}
+
 // Close releases database resources.
 func (m *Manager) Close() {
 	if err := database.Close(); err != nil {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/ontree-co/treeos/internal/engine"
 )
 
 // ModelInstall pulls a model from the running Ollama container.
@@ -25,7 +27,7 @@ func (m *Manager) ModelInstall(ctx context.Context, model string) <-chan Progres
 			return
 		}
 
-		cmd := m.execCommand(ctx, "docker", "exec", containerName, "ollama", "pull", model)
+		cmd := m.execCommand(ctx, engine.CLI(), "exec", containerName, "ollama", "pull", model)
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
 			ch <- ProgressEvent{Type: "error", Message: err.Error(), Code: "exec_failed"}
@@ -127,7 +129,7 @@ func (m *Manager) listOllamaModels(ctx context.Context) ([]string, error) {
 		return nil, err
 	}
 
-	cmd := m.execCommand(ctx, "docker", "exec", containerName, "ollama", "list")
+	cmd := m.execCommand(ctx, engine.CLI(), "exec", containerName, "ollama", "list")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list models: %w", err)
@@ -149,7 +151,7 @@ func (m *Manager) listOllamaModels(ctx context.Context) ([]string, error) {
 }
 
 func (m *Manager) findOllamaContainer(ctx context.Context) (string, error) {
-	cmd := m.execCommand(ctx, "docker", "ps", "--filter", "label=ontree.inference=true", "--format", "{{.Names}}")
+	cmd := m.execCommand(ctx, engine.CLI(), "ps", "--filter", "label=ontree.inference=true", "--format", "{{.Names}}")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to discover Ollama container: %w", err)
@@ -0,0 +1,321 @@
+// Package pkcs12 writes PKCS#12 (.p12) bundles, the format browsers and operating systems
+// import client certificates from. Bundles use the modern algorithms OpenSSL 3 defaults to:
+// the key is encrypted with AES-256-CBC and a PBKDF2-SHA256 derived key, the bundle is
+// authenticated with HMAC-SHA256.
+package pkcs12
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // Key IDs only, not used for security
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+)
+
+// Iterations of the key derivations, OpenSSL's default. Bundles are protected with
+// generated passwords, so a higher count adds little.
+const iterations = 2048
+
+// saltLength is the length of the random salts of the key encryption and the MAC
+const saltLength = 16
+
+var (
+	oidData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidCertBag         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidShroudedKeyBag  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidX509Certificate = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBES2           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256  = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+const (
+	pfxVersion = 3
+	// macKeyID is the purpose byte of the RFC 7292 key derivation for MAC keys
+	macKeyID = 3
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue
+	Attributes []attribute `asn1:"set,optional"`
+}
+
+type attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data asn1.RawValue
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	PRF        pkix.AlgorithmIdentifier
+}
+
+// Encode returns a bundle of a private key, its certificate and the certificates of the
+// issuing CAs, protected with password. friendlyName is the label clients show for it.
+func Encode(key crypto.PrivateKey, cert *x509.Certificate, caCerts []*x509.Certificate, password, friendlyName string) ([]byte, error) {
+	if cert == nil {
+		return nil, errors.New("no certificate to encode")
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	keyID := sha1.Sum(cert.Raw) //nolint:gosec // Pairs the key with its certificate
+	attrs := []attribute{
+		{ID: oidLocalKeyID, Value: set(mustMarshal(keyID[:]))},
+		{ID: oidFriendlyName, Value: set(mustMarshalRaw(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: bmpString(friendlyName, false)}))},
+	}
+
+	certBags := make([]safeBag, 0, 1+len(caCerts))
+	for i, c := range append([]*x509.Certificate{cert}, caCerts...) {
+		bag, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: explicit(mustMarshal(c.Raw))})
+		if err != nil {
+			return nil, err
+		}
+		sb := safeBag{ID: oidCertBag, Value: explicit(bag)}
+		if i == 0 {
+			sb.Attributes = attrs
+		}
+		certBags = append(certBags, sb)
+	}
+
+	shrouded, err := encryptKey(keyDER, password)
+	if err != nil {
+		return nil, err
+	}
+	keyBags := []safeBag{{ID: oidShroudedKeyBag, Value: explicit(shrouded), Attributes: attrs}}
+
+	var authSafe []contentInfo
+	for _, bags := range [][]safeBag{certBags, keyBags} {
+		contents, err := asn1.Marshal(bags)
+		if err != nil {
+			return nil, err
+		}
+		authSafe = append(authSafe, contentInfo{ContentType: oidData, Content: explicit(mustMarshal(contents))})
+	}
+	authSafeDER, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := randomBytes(saltLength)
+	if err != nil {
+		return nil, err
+	}
+	macKey := kdf(sha256.New, salt, bmpString(password, true), iterations, macKeyID, sha256.Size)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(authSafeDER)
+
+	return asn1.Marshal(pfxPdu{
+		Version:  pfxVersion,
+		AuthSafe: contentInfo{ContentType: oidData, Content: explicit(mustMarshal(authSafeDER))},
+		MacData: macData{
+			Mac:        digestInfo{Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}, Digest: mac.Sum(nil)},
+			MacSalt:    salt,
+			Iterations: iterations,
+		},
+	})
+}
+
+// encryptKey encrypts a PKCS#8 key with PBES2, returning an EncryptedPrivateKeyInfo.
+// PBES2 takes the password as UTF-8, unlike the MAC.
+func encryptKey(keyDER []byte, password string) ([]byte, error) {
+	salt, err := randomBytes(saltLength)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := randomBytes(aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	aesKey, err := pbkdf2.Key(sha256.New, password, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding, a full block when the key is already aligned
+	padding := aes.BlockSize - len(keyDER)%aes.BlockSize
+	data := append([]byte{}, keyDER...)
+	for i := 0; i < padding; i++ {
+		data = append(data, byte(padding))
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:       salt,
+		Iterations: iterations,
+		PRF:        pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: mustMarshal(iv)}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: data,
+	})
+}
+
+// kdf derives size bytes of key material for purpose id from a password and salt, as
+// RFC 7292 appendix B.2 describes. PKCS#12 MACs use it even with SHA-256.
+func kdf(newHash func() hash.Hash, salt, password []byte, rounds int, id byte, size int) []byte {
+	h := newHash()
+	v := h.BlockSize()
+
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	in := append(fill(salt, v), fill(password, v)...)
+
+	var key []byte
+	for len(key) < size {
+		h.Reset()
+		h.Write(d)
+		h.Write(in)
+		a := h.Sum(nil)
+		for r := 1; r < rounds; r++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		key = append(key, a...)
+
+		// Add B+1 to each v byte block of I, modulo 2^(8v)
+		b := fill(a, v)
+		for j := 0; j < len(in); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(in[j+k]) + int(b[k]) + carry
+				in[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return key[:size]
+}
+
+// fill repeats data up to the next multiple of v bytes, nothing for empty data
+func fill(data []byte, v int) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	out := make([]byte, v*((len(data)+v-1)/v))
+	for i := range out {
+		out[i] = data[i%len(data)]
+	}
+	return out
+}
+
+// bmpString encodes s as big-endian UTF-16, with the two zero bytes passwords end with
+func bmpString(s string, terminate bool) []byte {
+	var out []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(r>>8), byte(r))
+	}
+	if terminate {
+		out = append(out, 0, 0)
+	}
+	return out
+}
+
+// explicit wraps DER in the [0] EXPLICIT tag PKCS#12 uses for content. encoding/asn1
+// ignores struct tags on RawValue fields, so the tag is set here.
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// set wraps DER in a SET, attribute values are sets
+func set(der []byte) asn1.RawValue {
+	return asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: der}
+}
+
+// mustMarshal encodes an octet string, which can't fail
+func mustMarshal(b []byte) []byte {
+	der, err := asn1.Marshal(b)
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+// mustMarshalRaw encodes a primitive value with its tag, which can't fail
+func mustMarshalRaw(v asn1.RawValue) []byte {
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,136 @@
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // Test vector of the key derivation
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestKDF(t *testing.T) {
+	// Vector from golang.org/x/crypto/pkcs12, whose I_j blocks get a leading zero byte
+	key := kdf(sha1.New, []byte("\xf3\x7e\x05\xb5\x18\x32\x4b\x4b"), []byte("\x00\x00"), 2048, 1, 24)
+	want := []byte("\x00\xf7\x59\xff\x47\xd1\x4d\xd0\x36\x65\xd5\x94\x3c\xb3\xc4\xa3\x9a\x25\x55\xc0\x2a\xed\x66\xe1")
+	if !bytes.Equal(key, want) {
+		t.Errorf("kdf() = %x, want %x", key, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "admin"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const password = "correct horse"
+	data, err := Encode(key, cert, nil, password, "TreeOS admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pfx pfxPdu
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		t.Fatal(err)
+	}
+	var authSafeDER []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafeDER); err != nil {
+		t.Fatal(err)
+	}
+	macKey := kdf(sha256.New, pfx.MacData.MacSalt, bmpString(password, true), pfx.MacData.Iterations, macKeyID, sha256.Size)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(authSafeDER)
+	if !hmac.Equal(mac.Sum(nil), pfx.MacData.Mac.Digest) {
+		t.Fatal("MAC does not verify")
+	}
+
+	var authSafe []contentInfo
+	if _, err := asn1.Unmarshal(authSafeDER, &authSafe); err != nil || len(authSafe) != 2 {
+		t.Fatalf("authenticated safe = %d entries, %v", len(authSafe), err)
+	}
+	bags := make([][]safeBag, 2)
+	for i, ci := range authSafe {
+		var contents []byte
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &contents); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := asn1.Unmarshal(contents, &bags[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var cb certBag
+	if _, err := asn1.Unmarshal(bags[0][0].Value.Bytes, &cb); err != nil {
+		t.Fatal(err)
+	}
+	var certDER []byte
+	if _, err := asn1.Unmarshal(cb.Data.Bytes, &certDER); err != nil || !bytes.Equal(certDER, cert.Raw) {
+		t.Errorf("certificate bag does not hold the certificate: %v", err)
+	}
+
+	var shrouded encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(bags[1][0].Value.Bytes, &shrouded); err != nil {
+		t.Fatal(err)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(shrouded.Algorithm.Parameters.FullBytes, &params); err != nil {
+		t.Fatal(err)
+	}
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		t.Fatal(err)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		t.Fatal(err)
+	}
+	aesKey, err := pbkdf2.Key(sha256.New, password, kdfParams.Salt, kdfParams.Iterations, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]byte, len(shrouded.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, shrouded.EncryptedData)
+	plain = plain[:len(plain)-int(plain[len(plain)-1])]
+	decoded, err := x509.ParsePKCS8PrivateKey(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(decoded) {
+		t.Error("decrypted key differs")
+	}
+}
+
+func TestBMPString(t *testing.T) {
+	if got := bmpString("ä", true); !bytes.Equal(got, []byte{0x00, 0xe4, 0, 0}) {
+		t.Errorf("bmpString() = %x", got)
+	}
+}
@@ -0,0 +1,380 @@
+// Package ports keeps track of the host ports apps publish, so a new or changed app doesn't
+// take a port another app or another process on the host already uses. Docker only notices
+// such a conflict when the containers start, long after the app was created.
+package ports
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+	"gopkg.in/yaml.v3"
+)
+
+// Protocols of published ports
+const (
+	TCP = "tcp"
+	UDP = "udp"
+)
+
+// ErrNoFreePort is returned when no port above the wanted one is free
+var ErrNoFreePort = errors.New("no free host port")
+
+// portVariable matches a published port set by a single variable, such as ${PORT:-8080}
+var portVariable = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)(?::?-[0-9]*)?\}$`)
+
+// listening reports whether a process listens on a host port, replaceable in tests
+var listening = func(port int, protocol string) bool {
+	address := ":" + strconv.Itoa(port)
+	var err error
+	if protocol == UDP {
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket("udp", address); err == nil {
+			conn.Close() //nolint:errcheck,gosec // Probe only
+		}
+	} else {
+		var listener net.Listener
+		if listener, err = net.Listen("tcp", address); err == nil {
+			listener.Close() //nolint:errcheck,gosec // Probe only
+		}
+	}
+	// Ports below 1024 can't be probed without privileges, compose files are all there is
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// Binding is a host port an app publishes
+type Binding struct {
+	App      string `json:"app"`
+	Service  string `json:"service"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	// published is the host port as written in the compose file, e.g. ${PORT:-8080}
+	published string
+}
+
+// Conflict is a host port an app wants that is already taken
+type Conflict struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service"`       // Service of the app that wants the port
+	App      string `json:"app,omitempty"` // App that publishes the port, "" for another process
+}
+
+func (c Conflict) String() string {
+	if c.App == "" {
+		return fmt.Sprintf("host port %d/%s of service %s is in use by another process", c.Port, c.Protocol, c.Service)
+	}
+	return fmt.Sprintf("host port %d/%s of service %s is published by app %s", c.Port, c.Protocol, c.Service, c.App)
+}
+
+// ConflictError is returned for an app whose host ports are taken
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	messages := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		messages[i] = conflict.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Assignment is a host port of an app that was moved to a free one
+type Assignment struct {
+	Service  string `json:"service"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Variable string `json:"variable,omitempty"` // .env variable the port was set in, "" if the compose file was changed
+}
+
+// Registry finds the host ports of the apps in a directory
+type Registry struct {
+	appsDir string
+}
+
+// NewRegistry returns a registry of the apps in appsDir
+func NewRegistry(appsDir string) *Registry {
+	return &Registry{appsDir: appsDir}
+}
+
+// AppBindings returns the host ports a compose file publishes, with ${VAR} references
+// resolved from env. Ranges are expanded, ports without a host port or with an unset
+// variable are left out.
+func AppBindings(app string, composeContent []byte, env map[string]string) ([]Binding, error) {
+	var file yamlutil.ComposeFile
+	if err := yaml.Unmarshal(composeContent, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	var bindings []Binding
+	for _, port := range yamlutil.PortBindings(&file) {
+		if port.Published == "" {
+			continue
+		}
+		published, ok := compose.ExpandVariables(port.Published, env)
+		if !ok || published == "" {
+			continue
+		}
+		protocol := TCP
+		if _, proto, ok := strings.Cut(port.Target, "/"); ok {
+			protocol = proto
+		}
+		first, last, err := portRange(published)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", port.Service, err)
+		}
+		for p := first; p <= last; p++ {
+			bindings = append(bindings, Binding{App: app, Service: port.Service, Port: p, Protocol: protocol, published: port.Published})
+		}
+	}
+	return bindings, nil
+}
+
+// portRange parses a host port or a range of host ports such as 8000-8010
+func portRange(value string) (int, int, error) {
+	firstValue, lastValue, isRange := strings.Cut(value, "-")
+	first, err := strconv.Atoi(firstValue)
+	if err != nil || first < 1 || first > 65535 {
+		return 0, 0, fmt.Errorf("invalid host port %q", value)
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	last, err := strconv.Atoi(lastValue)
+	if err != nil || last < first || last > 65535 {
+		return 0, 0, fmt.Errorf("invalid host port range %q", value)
+	}
+	return first, last, nil
+}
+
+// appBindings returns the host ports an installed app publishes, nil if it has no compose file
+func (r *Registry) appBindings(app string) ([]Binding, error) {
+	dir := filepath.Join(r.appsDir, app)
+	content, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml")) //nolint:gosec // Apps directory of the node
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	env, err := compose.ReadEnvFile(filepath.Join(dir, ".env"))
+	if err != nil {
+		return nil, err
+	}
+	return AppBindings(app, content, env)
+}
+
+// Bindings returns the host ports the installed apps publish, sorted by port. The app skip
+// is left out, for an app that is changed.
+func (r *Registry) Bindings(skip string) ([]Binding, error) {
+	entries, err := os.ReadDir(r.appsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apps directory: %w", err)
+	}
+	var bindings []Binding
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == skip || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		appBindings, err := r.appBindings(entry.Name())
+		if err != nil {
+			// A broken app doesn't keep others from being created
+			logging.Warnf("Failed to read host ports of app %s: %v", entry.Name(), err)
+			continue
+		}
+		bindings = append(bindings, appBindings...)
+	}
+	sort.SliceStable(bindings, func(i, j int) bool {
+		if bindings[i].Port != bindings[j].Port {
+			return bindings[i].Port < bindings[j].Port
+		}
+		return bindings[i].App < bindings[j].App
+	})
+	return bindings, nil
+}
+
+// taken are the host ports in use, by port and protocol
+type taken map[string]string
+
+func portKey(port int, protocol string) string {
+	return strconv.Itoa(port) + "/" + protocol
+}
+
+// takenPorts returns the ports the apps other than app publish, with the app publishing each
+func (r *Registry) takenPorts(app string) (taken, error) {
+	bindings, err := r.Bindings(app)
+	if err != nil {
+		return nil, err
+	}
+	ports := taken{}
+	for _, binding := range bindings {
+		ports[portKey(binding.Port, binding.Protocol)] = binding.App
+	}
+	return ports, nil
+}
+
+// Conflicts returns the host ports of an app's compose file that other apps publish or
+// another process listens on. Ports the app publishes now aren't probed, its own
+// containers may hold them.
+func (r *Registry) Conflicts(app string, composeContent []byte, env map[string]string) ([]Conflict, error) {
+	wanted, err := AppBindings(app, composeContent, env)
+	if err != nil {
+		return nil, err
+	}
+	ports, err := r.takenPorts(app)
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.appBindings(app)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the current host ports of %s: %w", app, err)
+	}
+	own := map[string]bool{}
+	for _, binding := range current {
+		own[portKey(binding.Port, binding.Protocol)] = true
+	}
+
+	var conflicts []Conflict
+	seen := map[string]bool{}
+	for _, binding := range wanted {
+		key := portKey(binding.Port, binding.Protocol)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if owner, ok := ports[key]; ok {
+			conflicts = append(conflicts, Conflict{Port: binding.Port, Protocol: binding.Protocol, Service: binding.Service, App: owner})
+		} else if !own[key] && listening(binding.Port, binding.Protocol) {
+			conflicts = append(conflicts, Conflict{Port: binding.Port, Protocol: binding.Protocol, Service: binding.Service})
+		}
+	}
+	return conflicts, nil
+}
+
+// Check returns a *ConflictError if host ports of an app's compose file are taken
+func (r *Registry) Check(app string, composeContent, envContent []byte) error {
+	env, err := compose.ParseEnv(envContent)
+	if err != nil {
+		return fmt.Errorf("failed to read .env: %w", err)
+	}
+	conflicts, err := r.Conflicts(app, composeContent, env)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// Free returns the first port from start on that no app other than app publishes and
+// nothing listens on
+func (r *Registry) Free(app string, start int, protocol string) (int, error) {
+	ports, err := r.takenPorts(app)
+	if err != nil {
+		return 0, err
+	}
+	return ports.free(start, protocol)
+}
+
+func (t taken) free(start int, protocol string) (int, error) {
+	if start < 1 {
+		start = 1
+	}
+	for port := start; port <= 65535; port++ {
+		if _, ok := t[portKey(port, protocol)]; !ok && !listening(port, protocol) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("%w from %d on", ErrNoFreePort, start)
+}
+
+// Assign moves the taken host ports of an app to free ones above them. Ports written in the
+// compose file are changed there, ports set by a variable such as ${PORT:-8080} get the
+// free port in .env. The host_port of x-ontree follows its port. Ranges and ports of other
+// expressions can't be moved and are returned in a *ConflictError.
+func (r *Registry) Assign(app string, composeContent, envContent []byte) ([]byte, []byte, []Assignment, error) {
+	env, err := compose.ParseEnv(envContent)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read .env: %w", err)
+	}
+	conflicts, err := r.Conflicts(app, composeContent, env)
+	if err != nil || len(conflicts) == 0 {
+		return composeContent, envContent, nil, err
+	}
+	ports, err := r.takenPorts(app)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	wanted, err := AppBindings(app, composeContent, env)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, binding := range wanted {
+		ports[portKey(binding.Port, binding.Protocol)] = app
+	}
+
+	var assignments []Assignment
+	var unmovable []Conflict
+	for _, conflict := range conflicts {
+		var published string
+		for _, binding := range wanted {
+			if binding.Port == conflict.Port && binding.Protocol == conflict.Protocol {
+				published = binding.published
+				break
+			}
+		}
+		variable := ""
+		if m := portVariable.FindStringSubmatch(published); m != nil {
+			variable = m[1]
+		} else if published != strconv.Itoa(conflict.Port) {
+			unmovable = append(unmovable, conflict)
+			continue
+		}
+
+		port, err := ports.free(conflict.Port+1, conflict.Protocol)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ports[portKey(port, conflict.Protocol)] = app
+		if variable != "" {
+			if envContent, err = yamlutil.SetEnvVar(envContent, variable, strconv.Itoa(port)); err != nil {
+				return nil, nil, nil, err
+			}
+		} else if composeContent, _, err = yamlutil.ReplaceHostPort(composeContent, conflict.Port, port); err != nil {
+			return nil, nil, nil, err
+		}
+		assignments = append(assignments, Assignment{Service: conflict.Service, From: conflict.Port, To: port, Variable: variable})
+	}
+	if len(unmovable) > 0 {
+		return nil, nil, nil, &ConflictError{Conflicts: unmovable}
+	}
+
+	var file yamlutil.ComposeFile
+	if err := yaml.Unmarshal(composeContent, &file); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	hostPort := yamlutil.GetOnTreeMetadata(&file).HostPort
+	for _, assignment := range assignments {
+		if hostPort != 0 && hostPort == assignment.From {
+			if composeContent, err = yamlutil.SetMetadataInt(composeContent, "host_port", assignment.To); err != nil {
+				return nil, nil, nil, err
+			}
+			break
+		}
+	}
+	return composeContent, envContent, assignments, nil
+}
@@ -0,0 +1,122 @@
+package ports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeApp writes the compose and .env files of an app
+func writeApp(t *testing.T, appsDir, app, composeContent, envContent string) {
+	t.Helper()
+	dir := filepath.Join(appsDir, app)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(composeContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if envContent != "" {
+		if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(envContent), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// fakeListening makes ports appear in use by other processes
+func fakeListening(t *testing.T, ports ...int) {
+	t.Helper()
+	original := listening
+	t.Cleanup(func() { listening = original })
+	listening = func(port int, _ string) bool {
+		for _, p := range ports {
+			if p == port {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	appsDir := t.TempDir()
+	writeApp(t, appsDir, "wiki", "services:\n  web:\n    image: nginx\n    ports:\n      - \"${PORT:-8080}:80\"\n", "PORT=8081\n")
+	writeApp(t, appsDir, "dns", "services:\n  dns:\n    image: dns\n    ports:\n      - 5353:53/udp\n", "")
+	fakeListening(t, 9000, 8090)
+	registry := NewRegistry(appsDir)
+
+	bindings, err := registry.Bindings("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings) != 2 || bindings[0].Port != 5353 || bindings[0].Protocol != UDP || bindings[1].Port != 8081 || bindings[1].App != "wiki" {
+		t.Errorf("Bindings = %+v", bindings)
+	}
+
+	content := []byte("services:\n  app:\n    image: app\n    ports:\n      - 8081:80\n      - 8080:81\n      - 5353:53\n      - 9000:9000\n")
+	conflicts, err := registry.Conflicts("blog", content, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Conflict{{Port: 8081, Protocol: TCP, Service: "app", App: "wiki"}, {Port: 9000, Protocol: TCP, Service: "app"}}
+	if len(conflicts) != len(want) || conflicts[0] != want[0] || conflicts[1] != want[1] {
+		t.Errorf("Conflicts = %+v, want %+v", conflicts, want)
+	}
+
+	// An app keeps its own ports, though its containers listen on them
+	fakeListening(t, 8081)
+	if err := registry.Check("wiki", []byte("services:\n  web:\n    image: nginx\n    ports:\n      - 8081:80\n"), nil); err != nil {
+		t.Errorf("Check of an app's own port = %v", err)
+	}
+	var conflictErr *ConflictError
+	if err := registry.Check("blog", content, nil); !errors.As(err, &conflictErr) || !strings.Contains(err.Error(), "published by app wiki") {
+		t.Errorf("Check = %v, want a conflict with wiki", err)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	appsDir := t.TempDir()
+	writeApp(t, appsDir, "wiki", "services:\n  web:\n    image: nginx\n    ports:\n      - 8080:80\n      - 8081:81\n", "")
+	fakeListening(t, 8082)
+	registry := NewRegistry(appsDir)
+
+	content := []byte("services:\n  app:\n    image: app\n    ports:\n      - \"8080:80\" # web\n      - \"${ADMIN_PORT:-8081}:81\"\nx-ontree:\n  host_port: 8080\n")
+	composeContent, envContent, assignments, err := registry.Assign("blog", content, []byte("TITLE=Blog\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCompose := "services:\n  app:\n    image: app\n    ports:\n      - \"8083:80\" # web\n      - \"${ADMIN_PORT:-8081}:81\"\nx-ontree:\n  host_port: 8083\n"
+	if string(composeContent) != wantCompose {
+		t.Errorf("compose =\n%s\nwant\n%s", composeContent, wantCompose)
+	}
+	if string(envContent) != "TITLE=Blog\nADMIN_PORT=8084\n" {
+		t.Errorf(".env = %q", envContent)
+	}
+	if len(assignments) != 2 || assignments[0] != (Assignment{Service: "app", From: 8080, To: 8083}) || assignments[1].Variable != "ADMIN_PORT" {
+		t.Errorf("assignments = %+v", assignments)
+	}
+
+	// Ranges can't be moved
+	var conflictErr *ConflictError
+	if _, _, _, err := registry.Assign("blog", []byte("services:\n  app:\n    image: app\n    ports:\n      - 8079-8080:80\n"), nil); !errors.As(err, &conflictErr) {
+		t.Errorf("Assign of a range = %v, want a conflict", err)
+	}
+}
+
+func TestFree(t *testing.T) {
+	appsDir := t.TempDir()
+	writeApp(t, appsDir, "wiki", "services:\n  web:\n    image: nginx\n    ports:\n      - 8080:80\n", "")
+	fakeListening(t, 8081)
+	registry := NewRegistry(appsDir)
+	if port, err := registry.Free("", 8080, TCP); err != nil || port != 8082 {
+		t.Errorf("Free = %d, %v, want 8082", port, err)
+	}
+	if port, err := registry.Free("wiki", 8080, TCP); err != nil || port != 8080 {
+		t.Errorf("Free for the app itself = %d, %v, want 8080", port, err)
+	}
+	if _, err := registry.Free("", 65535, TCP); err != nil {
+		t.Errorf("Free of the last port = %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+// Package registry looks up the digests and tags of container images in registries that
+// speak the distribution API, such as Docker Hub and ghcr.io.
+//
+// Lookups are anonymous, so only public images can be checked. Manifests are requested
+// with HEAD, which doesn't count against the pull rate limit of Docker Hub.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DockerHub is the registry of images without a registry host
+	DockerHub = "docker.io"
+	// dockerHubAPI is the host that serves the API of Docker Hub
+	dockerHubAPI = "registry-1.docker.io"
+
+	requestTimeout = 30 * time.Second
+	// maxTagPages bounds the pages of a tag list, repositories with many nightly tags
+	// would take long otherwise
+	maxTagPages = 20
+	maxBodySize = 8 << 20
+)
+
+// manifestTypes are accepted for digests. Lists and indexes come first, their digest is
+// the one Docker records for an image pulled by tag.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// ErrNotFound is returned for images or tags the registry doesn't have, or doesn't show
+// without credentials
+var ErrNotFound = errors.New("image not found in registry")
+
+// Reference is a parsed image reference such as "postgres:16" or "ghcr.io/owner/app@sha256:..."
+type Reference struct {
+	Registry   string // Host, DockerHub for images without one
+	Repository string // Path in the registry, "library/<name>" for official Docker Hub images
+	Tag        string // "latest" if the reference has neither tag nor digest
+	Digest     string
+}
+
+// ParseReference parses an image reference the way Docker does
+func ParseReference(image string) (Reference, error) {
+	var ref Reference
+	rest := strings.TrimSpace(image)
+	if rest == "" {
+		return ref, fmt.Errorf("empty image reference")
+	}
+	if name, digest, ok := strings.Cut(rest, "@"); ok {
+		if !strings.Contains(digest, ":") {
+			return ref, fmt.Errorf("invalid digest in %q", image)
+		}
+		rest, ref.Digest = name, digest
+	}
+	// A colon after the last slash separates the tag, one before it is a registry port
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		rest, ref.Tag = rest[:i], rest[i+1:]
+	}
+
+	ref.Registry = DockerHub
+	if first, path, ok := strings.Cut(rest, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, rest = first, path
+	}
+	if ref.Registry == "index.docker.io" {
+		ref.Registry = DockerHub
+	}
+	if ref.Registry == DockerHub && !strings.Contains(rest, "/") {
+		rest = "library/" + rest
+	}
+	if rest == "" || rest != strings.ToLower(rest) {
+		return ref, fmt.Errorf("invalid repository in %q", image)
+	}
+	ref.Repository = rest
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// String returns the reference in its full form, e.g. "docker.io/library/postgres:16"
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Client queries registries. It caches the anonymous tokens it gets, so a client should
+// be reused for the images of one check.
+type Client struct {
+	HTTPClient *http.Client
+	// Insecure talks plain HTTP to registries, for tests
+	Insecure bool
+
+	mu     sync.Mutex
+	tokens map[string]string // Registry and repository to token
+}
+
+// NewClient returns a client with the default timeout
+func NewClient() *Client {
+	return &Client{HTTPClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// Digest returns the digest the registry has for the tag of ref
+func (c *Client) Digest(ctx context.Context, ref Reference) (string, error) {
+	if ref.Tag == "" {
+		return "", fmt.Errorf("%s has no tag", ref)
+	}
+	resp, err := c.get(ctx, http.MethodHead, ref, c.endpoint(ref, "manifests/"+ref.Tag), strings.Join(manifestTypes, ", "))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck // HEAD response without body
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s returned no digest for %s", ref.Registry, ref)
+	}
+	return digest, nil
+}
+
+// Tags returns the tags of the repository of ref
+func (c *Client) Tags(ctx context.Context, ref Reference) ([]string, error) {
+	var tags []string
+	next := c.endpoint(ref, "tags/list?n=1000")
+	for page := 0; next != "" && page < maxTagPages; page++ {
+		resp, err := c.get(ctx, http.MethodGet, ref, next, "application/json")
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&list)
+		resp.Body.Close() //nolint:errcheck,gosec // Body was read
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of %s: %w", ref.Repository, err)
+		}
+		tags = append(tags, list.Tags...)
+
+		next = ""
+		if link := nextLink(resp.Header.Get("Link")); link != "" {
+			base, err := url.Parse(resp.Request.URL.String())
+			if err != nil {
+				return nil, err
+			}
+			u, err := base.Parse(link)
+			if err != nil {
+				return nil, fmt.Errorf("invalid next link %q: %w", link, err)
+			}
+			next = u.String()
+		}
+	}
+	return tags, nil
+}
+
+// endpoint returns the URL of a path below /v2/<repository>/
+func (c *Client) endpoint(ref Reference, path string) string {
+	scheme, host := "https", ref.Registry
+	if c.Insecure {
+		scheme = "http"
+	}
+	if host == DockerHub {
+		host = dockerHubAPI
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, host, ref.Repository, path)
+}
+
+// get sends a request, getting an anonymous token and trying again when the registry
+// asks for one
+func (c *Client) get(ctx context.Context, method string, ref Reference, target, accept string) (*http.Response, error) {
+	key := ref.Registry + "/" + ref.Repository
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", accept)
+		c.mu.Lock()
+		token := c.tokens[key]
+		c.mu.Unlock()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach registry %s: %w", ref.Registry, err)
+		}
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close() //nolint:errcheck,gosec // Error response
+			token, err := c.fetchToken(ctx, challenge, ref)
+			if err != nil {
+				return nil, err
+			}
+			c.mu.Lock()
+			if c.tokens == nil {
+				c.tokens = make(map[string]string)
+			}
+			c.tokens[key] = token
+			c.mu.Unlock()
+			continue
+		}
+		resp.Body.Close() //nolint:errcheck,gosec // Error response
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
+		}
+		return nil, fmt.Errorf("registry %s returned %s for %s", ref.Registry, resp.Status, ref)
+	}
+}
+
+// fetchToken gets an anonymous pull token for the realm of a Bearer challenge
+func (c *Client) fetchToken(ctx context.Context, challenge string, ref Reference) (string, error) {
+	params, ok := parseChallenge(challenge)
+	if !ok || params["realm"] == "" {
+		return "", fmt.Errorf("%w: registry %s needs credentials", ErrNotFound, ref.Registry)
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", params["realm"], err)
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: token for %s refused with %s", ErrNotFound, ref, resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("registry %s returned an empty token", ref.Registry)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// parseChallenge parses the parameters of a WWW-Authenticate Bearer header, e.g.
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseChallenge(header string) (map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimLeft(rest, ", ") {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+			params[key], rest = value[1:end+1], value[end+2:]
+			continue
+		}
+		value, rest, _ = strings.Cut(value, ",")
+		params[key] = strings.TrimSpace(value)
+	}
+	return params, true
+}
+
+// nextLink returns the target of a Link header with rel="next"
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, _ := strings.Cut(link, ";")
+		if strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
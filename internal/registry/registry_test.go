@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	for image, want := range map[string]string{
+		"postgres":                           "docker.io/library/postgres:latest",
+		"postgres:16-alpine":                 "docker.io/library/postgres:16-alpine",
+		"grafana/grafana:10.4.1":             "docker.io/grafana/grafana:10.4.1",
+		"index.docker.io/library/redis:7":    "docker.io/library/redis:7",
+		"ghcr.io/open-webui/open-webui:main": "ghcr.io/open-webui/open-webui:main",
+		"localhost:5000/app":                 "localhost:5000/app:latest",
+		"nginx@sha256:abc":                   "docker.io/library/nginx@sha256:abc",
+		"nginx:1.27@sha256:abc":              "docker.io/library/nginx:1.27@sha256:abc",
+	} {
+		ref, err := ParseReference(image)
+		if err != nil || ref.String() != want {
+			t.Errorf("ParseReference(%q) = %s, %v, want %s", image, ref, err, want)
+		}
+	}
+	for _, image := range []string{"", "Nginx", "nginx@abc"} {
+		if _, err := ParseReference(image); err == nil {
+			t.Errorf("ParseReference(%q) succeeded", image)
+		}
+	}
+}
+
+func TestNewerTag(t *testing.T) {
+	tags := []string{"latest", "15", "16", "17", "16.1", "16.4", "17-alpine", "16-alpine", "18-rc1", "v2.0"}
+	for current, want := range map[string]string{
+		"16":        "17",
+		"16.1":      "16.4",
+		"16-alpine": "17-alpine",
+		"17":        "",
+		"latest":    "",
+		"v1.9":      "v2.0",
+	} {
+		if got := NewerTag(current, tags); got != want {
+			t.Errorf("NewerTag(%q) = %q, want %q", current, got, want)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	params, ok := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if !ok || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/nginx:pull" {
+		t.Errorf("parseChallenge = %v, %v", params, ok)
+	}
+	if _, ok := parseChallenge(`Basic realm="registry"`); ok {
+		t.Error("Basic challenge accepted")
+	}
+}
+
+func TestClient(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:owner/app:pull" {
+				http.Error(w, "bad scope", http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token": "secret"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/owner/app/manifests/1.2":
+			if !strings.Contains(r.Header.Get("Accept"), "manifest.list") {
+				http.Error(w, "no list", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:new")
+		case "/v2/owner/app/tags/list":
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/owner/app/tags/list?n=1000&last=1.2>; rel="next"`)
+				fmt.Fprint(w, `{"name": "owner/app", "tags": ["1.1", "1.2"]}`)
+				return
+			}
+			fmt.Fprint(w, `{"name": "owner/app", "tags": ["1.3"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{HTTPClient: server.Client(), Insecure: true}
+	ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/owner/app:1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if digest, err := client.Digest(ctx, ref); err != nil || digest != "sha256:new" {
+		t.Errorf("Digest = %q, %v", digest, err)
+	}
+	if tags, err := client.Tags(ctx, ref); err != nil || strings.Join(tags, ",") != "1.1,1.2,1.3" {
+		t.Errorf("Tags = %v, %v", tags, err)
+	}
+
+	ref.Tag = "9.9"
+	if _, err := client.Digest(ctx, ref); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Digest of a missing tag: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionTag matches tags such as "16", "v1.2", "1.25.3" or "3.19-alpine"
+var versionTag = regexp.MustCompile(`^(v?)(\d+(?:\.\d+)*)(-[A-Za-z0-9._-]+)?$`)
+
+// tagVersion is a version tag split into its parts
+type tagVersion struct {
+	prefix  string
+	numbers []int
+	suffix  string
+}
+
+func parseTagVersion(tag string) (tagVersion, bool) {
+	m := versionTag.FindStringSubmatch(tag)
+	if m == nil {
+		return tagVersion{}, false
+	}
+	v := tagVersion{prefix: m[1], suffix: m[3]}
+	for _, part := range strings.Split(m[2], ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return tagVersion{}, false
+		}
+		v.numbers = append(v.numbers, n)
+	}
+	return v, true
+}
+
+// sameShape reports whether two versions are tags of the same series, e.g. "16-alpine"
+// and "17-alpine" but not "16" and "16.1" or "16" and "16-alpine"
+func (v tagVersion) sameShape(o tagVersion) bool {
+	return v.prefix == o.prefix && v.suffix == o.suffix && len(v.numbers) == len(o.numbers)
+}
+
+func (v tagVersion) less(o tagVersion) bool {
+	for i := range v.numbers {
+		if v.numbers[i] != o.numbers[i] {
+			return v.numbers[i] < o.numbers[i]
+		}
+	}
+	return false
+}
+
+// NewerTag returns the highest tag of the same shape as current that is a newer version,
+// "" if there is none or current is no version, e.g. "latest"
+func NewerTag(current string, tags []string) string {
+	cur, ok := parseTagVersion(current)
+	if !ok {
+		return ""
+	}
+	newest, best := "", cur
+	for _, tag := range tags {
+		v, ok := parseTagVersion(tag)
+		if !ok || !v.sameShape(cur) || !best.less(v) {
+			continue
+		}
+		newest, best = tag, v
+	}
+	return newest
+}
+
+// IsVersionTag reports whether a tag is a version that NewerTag can find successors of
+func IsVersionTag(tag string) bool {
+	_, ok := parseTagVersion(tag)
+	return ok
+}
@@ -49,6 +49,7 @@ type Compose struct {
 type dockerContainer struct {
 	ID     string
 	Names  []string
+	Image  string
 	State  string
 	Status string
 	Labels map[string]string
@@ -175,6 +176,22 @@ func (c *Client) getContainerStatus(app *App, containers []dockerContainer) stri
 
 // listContainers returns Docker containers relevant to TreeOS apps.
 func (c *Client) listContainers(ctx context.Context) ([]dockerContainer, error) {
+	if c.mock != nil {
+		containers := c.mock.Containers("")
+		result := make([]dockerContainer, 0, len(containers))
+		for _, cnt := range containers {
+			result = append(result, dockerContainer{
+				ID:     cnt.ID,
+				Names:  []string{"/" + cnt.Name},
+				Image:  cnt.Image,
+				State:  cnt.State,
+				Status: cnt.Status,
+				Labels: map[string]string{"com.docker.compose.project": cnt.Project, "com.docker.compose.service": cnt.Service},
+			})
+		}
+		return result, nil
+	}
+
 	if c.dockerClient == nil {
 		return nil, fmt.Errorf("docker client not initialized")
 	}
@@ -189,6 +206,7 @@ func (c *Client) listContainers(ctx context.Context) ([]dockerContainer, error)
 		result = append(result, dockerContainer{
 			ID:     cnt.ID,
 			Names:  cnt.Names,
+			Image:  cnt.Image,
 			State:  cnt.State,
 			Status: cnt.Status,
 			Labels: cnt.Labels,
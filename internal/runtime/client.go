@@ -5,15 +5,21 @@ import (
 	"fmt"
 
 	"github.com/docker/docker/client"
+	"github.com/ontree-co/treeos/internal/mockruntime"
 )
 
 // Client wraps the Docker client
 type Client struct {
 	dockerClient *client.Client
+	mock         *mockruntime.Runtime // Replaces Docker when the mock runtime is enabled
 }
 
 // NewClient creates a new Docker client
 func NewClient() (*Client, error) {
+	if mockruntime.Enabled() {
+		return &Client{mock: mockruntime.Default()}, nil
+	}
+
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
@@ -33,5 +39,8 @@ func NewClient() (*Client, error) {
 
 // Close closes the Docker client connection
 func (c *Client) Close() error {
+	if c.dockerClient == nil {
+		return nil
+	}
 	return c.dockerClient.Close()
 }
@@ -0,0 +1,213 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+// containerLogsMountPath is where the helper measuring logs mounts the daemon's container
+// directory
+const containerLogsMountPath = "/containers"
+
+// AppDiskUsage is the disk space an app uses, in bytes
+type AppDiskUsage struct {
+	App        string `json:"app"`
+	Images     int64  `json:"images"`      // Images of its containers, shared layers included
+	Containers int64  `json:"containers"`  // Writable layers of its containers
+	Volumes    int64  `json:"volumes"`     // Docker volumes of its project or mounted by its containers
+	BindMounts int64  `json:"bind_mounts"` // The app directory with the data mounted into its containers
+	Logs       int64  `json:"logs"`        // Log files of its containers, -1 when they couldn't be measured
+}
+
+// AppDiskUsages measures the disk space of the apps in appsDir, like `docker system df -v`
+// for the Docker objects and du for the app directories. An image used by several apps
+// counts for each of them.
+func (c *Client) AppDiskUsages(ctx context.Context, appsDir string) ([]AppDiskUsage, error) {
+	apps, err := c.ScanApps(appsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage types.DiskUsage
+	if c.mock == nil {
+		if c.dockerClient == nil {
+			return nil, fmt.Errorf("docker client not initialized")
+		}
+		usage, err = c.dockerClient.DiskUsage(ctx, types.DiskUsageOptions{
+			Types: []types.DiskUsageObject{types.ContainerObject, types.ImageObject, types.VolumeObject},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Docker disk usage: %w", err)
+		}
+	}
+
+	result, containers := appDiskUsages(apps, usage)
+	logSizes, logErr := c.containerLogSizes(ctx, containers)
+	if logErr != nil {
+		logging.Warnf("Failed to measure container logs: %v", logErr)
+	}
+	for i := range result {
+		u := &result[i]
+		u.BindMounts = appDirUsage(filepath.Join(appsDir, u.App))
+		if logErr != nil {
+			u.Logs = -1
+			continue
+		}
+		for _, id := range containers[u.App] {
+			u.Logs += logSizes[id]
+		}
+	}
+	return result, nil
+}
+
+// appDiskUsages attributes the Docker objects of a disk usage report to the apps, ordered
+// by name. It returns the IDs of each app's containers as well.
+func appDiskUsages(apps []*App, usage types.DiskUsage) ([]AppDiskUsage, map[string][]string) {
+	imageSizes := map[string]int64{}
+	for _, img := range usage.Images {
+		if img != nil {
+			imageSizes[img.ID] = img.Size
+		}
+	}
+	volumeSizes := map[string]int64{}
+	for _, v := range usage.Volumes {
+		if v != nil && v.UsageData != nil && v.UsageData.Size >= 0 {
+			volumeSizes[v.Name] = v.UsageData.Size
+		}
+	}
+
+	result := make([]AppDiskUsage, 0, len(apps))
+	containers := map[string][]string{}
+	for _, app := range apps {
+		candidates := projectNameCandidates(app)
+		u := AppDiskUsage{App: app.Name}
+		images := map[string]bool{}
+		volumes := map[string]bool{}
+		for _, cnt := range usage.Containers {
+			if cnt == nil || !containerMatchesProject(dockerContainer{Names: cnt.Names, Labels: cnt.Labels}, candidates) {
+				continue
+			}
+			containers[app.Name] = append(containers[app.Name], cnt.ID)
+			u.Containers += cnt.SizeRw
+			images[cnt.ImageID] = true
+			for _, m := range cnt.Mounts {
+				if m.Type == mount.TypeVolume && m.Name != "" {
+					volumes[m.Name] = true
+				}
+			}
+		}
+		for _, v := range usage.Volumes {
+			// Volumes of the project no container mounts, e.g. while the app is stopped
+			if v != nil && containerMatchesProject(dockerContainer{Labels: v.Labels}, candidates) {
+				volumes[v.Name] = true
+			}
+		}
+		for id := range images {
+			u.Images += imageSizes[id]
+		}
+		for name := range volumes {
+			u.Volumes += volumeSizes[name]
+		}
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].App < result[j].App })
+	return result, containers
+}
+
+// appDirUsage measures an app directory like du. Its mnt directory may link to a storage
+// root (see storage.PlaceAppMount), which is measured as well. Files the ontree user
+// can't read are left out.
+func appDirUsage(appDir string) int64 {
+	total, err := storage.DirUsage(appDir)
+	if err != nil {
+		logging.Warnf("Failed to measure %s: %v", appDir, err)
+	}
+	mntPath := filepath.Join(appDir, "mnt")
+	if info, lerr := os.Lstat(mntPath); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+		mnt, err := storage.DirUsage(mntPath)
+		if err != nil {
+			logging.Warnf("Failed to measure %s: %v", mntPath, err)
+		}
+		total += mnt
+	}
+	return int64(total) //nolint:gosec // Disk usage fits into int64
+}
+
+// containerLogSizes measures the log files of containers, by container ID. The files are
+// only readable by root, so a helper container reads them from the daemon's directory.
+func (c *Client) containerLogSizes(ctx context.Context, containers map[string][]string) (map[string]int64, error) {
+	if c.mock != nil {
+		return map[string]int64{}, nil
+	}
+	var dirs []string
+	for _, ids := range containers {
+		for _, id := range ids {
+			dirs = append(dirs, path.Join(containerLogsMountPath, id))
+		}
+	}
+	if len(dirs) == 0 {
+		return map[string]int64{}, nil
+	}
+
+	info, err := c.dockerClient.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Docker info: %w", err)
+	}
+	sort.Strings(dirs)
+	// json-file logs are <id>-json.log with rotated .1, .2..., local logs local-logs/container.log
+	cmd := append([]string{"find"}, dirs...)
+	cmd = append(cmd, "-type", "f", "-name", "*.log*", "-exec", "stat", "-c", "%s|%n", "{}", "+")
+	id, remove, err := c.createHelper(ctx, mount.Mount{
+		Type:     mount.TypeBind,
+		Source:   filepath.Join(info.DockerRootDir, "containers"),
+		Target:   containerLogsMountPath,
+		ReadOnly: true,
+	}, cmd)
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+
+	// A container removed meanwhile fails find but not the others
+	stdout, stderr, exitCode, err := c.runHelper(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 && stdout == "" {
+		return nil, fmt.Errorf("failed to measure logs: %s", strings.TrimSpace(stderr))
+	}
+	return parseLogSizes(stdout), nil
+}
+
+// parseLogSizes sums the `stat -c '%s|%n'` lines of the helper by container ID
+func parseLogSizes(output string) map[string]int64 {
+	sizes := map[string]int64{}
+	for _, line := range strings.Split(output, "\n") {
+		size, name, ok := strings.Cut(line, "|")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			continue
+		}
+		rel := strings.TrimPrefix(name, containerLogsMountPath+"/")
+		id, _, _ := strings.Cut(rel, "/")
+		if id == "" || rel == name {
+			continue
+		}
+		sizes[id] += n
+	}
+	return sizes
+}
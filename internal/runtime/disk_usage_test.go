@@ -0,0 +1,65 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestAppDiskUsages(t *testing.T) {
+	apps := []*App{
+		{Name: "wiki", Path: "/opt/ontree/apps/wiki"},
+		{Name: "blog", Path: "/opt/ontree/apps/blog"},
+	}
+	project := func(name string) map[string]string {
+		return map[string]string{"com.docker.compose.project": "ontree-" + name}
+	}
+	usage := types.DiskUsage{
+		Images: []*image.Summary{
+			{ID: "sha256:nginx", Size: 200},
+			{ID: "sha256:postgres", Size: 400},
+		},
+		Containers: []*container.Summary{
+			{ID: "c1", Names: []string{"/ontree-wiki-web-1"}, Labels: project("wiki"), ImageID: "sha256:nginx", SizeRw: 10,
+				Mounts: []container.MountPoint{{Type: mount.TypeVolume, Name: "shared-cache"}, {Type: mount.TypeBind, Source: "/etc/localtime"}}},
+			{ID: "c2", Names: []string{"/ontree-wiki-db-1"}, Labels: project("wiki"), ImageID: "sha256:postgres", SizeRw: 5},
+			{ID: "c3", Names: []string{"/ontree-blog-web-1"}, Labels: project("blog"), ImageID: "sha256:nginx", SizeRw: 1},
+			{ID: "c4", Names: []string{"/other"}, ImageID: "sha256:postgres", SizeRw: 1000},
+		},
+		Volumes: []*volume.Volume{
+			{Name: "ontree-wiki_db", Labels: project("wiki"), UsageData: &volume.UsageData{Size: 30}},
+			{Name: "shared-cache", UsageData: &volume.UsageData{Size: 7}},
+			{Name: "ontree-blog_unknown", Labels: project("blog"), UsageData: &volume.UsageData{Size: -1}},
+		},
+	}
+
+	result, containers := appDiskUsages(apps, usage)
+	want := []AppDiskUsage{
+		// The nginx image counts for both apps
+		{App: "blog", Images: 200, Containers: 1},
+		{App: "wiki", Images: 600, Containers: 15, Volumes: 37},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("appDiskUsages() = %+v, want %+v", result, want)
+	}
+	if want := map[string][]string{"wiki": {"c1", "c2"}, "blog": {"c3"}}; !reflect.DeepEqual(containers, want) {
+		t.Errorf("containers = %v, want %v", containers, want)
+	}
+}
+
+func TestParseLogSizes(t *testing.T) {
+	output := "1024|/containers/c1/c1-json.log\n" +
+		"4096|/containers/c1/c1-json.log.1\n" +
+		"512|/containers/c2/local-logs/container.log\n" +
+		"find: /containers/c3: No such file or directory\n" +
+		"8|/elsewhere/c4.log\n"
+	want := map[string]int64{"c1": 5120, "c2": 512}
+	if got := parseLogSizes(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLogSizes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/ontree-co/treeos/internal/naming"
+)
+
+// ContainerEvent is a change of a container of an app, as reported by the Docker events API
+type ContainerEvent struct {
+	App       string    `json:"app"`
+	Service   string    `json:"service"`
+	Container string    `json:"container"`
+	Action    string    `json:"action"` // create, start, die, stop, pause, unpause, destroy or health_status
+	State     string    `json:"state"`  // created, running, exited, paused or removed
+	Health    string    `json:"health,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// containerEventActions are the actions that change the state or health of a container
+var containerEventActions = []string{"create", "start", "die", "stop", "pause", "unpause", "destroy", "health_status"}
+
+// WatchEvents calls handle for every change of a container of an app in appsDir until ctx
+// is done or the event stream fails. Containers of other compose projects are left out.
+// The mock runtime has no events, it waits for ctx.
+func (c *Client) WatchEvents(ctx context.Context, appsDir string, handle func(ContainerEvent)) error {
+	if c.mock != nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if c.dockerClient == nil {
+		return errors.New("docker client not initialized")
+	}
+
+	args := filters.NewArgs(filters.Arg("type", string(events.ContainerEventType)), filters.Arg("label", "com.docker.compose.project"))
+	for _, action := range containerEventActions {
+		args.Add("event", action)
+	}
+	messages, errs := c.dockerClient.Events(ctx, events.ListOptions{Filters: args})
+	for {
+		select {
+		case msg := <-messages:
+			event, ok := containerEvent(msg)
+			if !ok {
+				continue
+			}
+			if event.App = appForProject(appsDir, msg.Actor.Attributes["com.docker.compose.project"]); event.App != "" {
+				handle(event)
+			}
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// containerEvent returns the container event of a Docker event message, without the app
+func containerEvent(msg events.Message) (ContainerEvent, bool) {
+	action, health, _ := strings.Cut(string(msg.Action), ": ")
+	event := ContainerEvent{
+		Service:   msg.Actor.Attributes["com.docker.compose.service"],
+		Container: msg.Actor.Attributes["name"],
+		Action:    action,
+		Time:      time.Unix(0, msg.TimeNano),
+	}
+	switch action {
+	case "create":
+		event.State = "created"
+	case "start", "unpause":
+		event.State = "running"
+	case "die", "stop":
+		event.State = "exited"
+	case "pause":
+		event.State = "paused"
+	case "destroy":
+		event.State = "removed"
+	case "health_status":
+		event.State = "running"
+		event.Health = strings.TrimSpace(health)
+	default:
+		return ContainerEvent{}, false
+	}
+	return event, true
+}
+
+// appForProject returns the app in appsDir of a compose project, empty if there is none
+func appForProject(appsDir, project string) string {
+	if project == "" {
+		return ""
+	}
+	entries, err := os.ReadDir(appsDir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if strings.EqualFold(project, naming.GetComposeProjectName(naming.GetAppIdentifier(entry.Name()))) || strings.EqualFold(project, entry.Name()) {
+			return entry.Name()
+		}
+	}
+	return ""
+}
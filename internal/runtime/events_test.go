@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/events"
+)
+
+func TestContainerEvent(t *testing.T) {
+	tests := []struct {
+		action events.Action
+		state  string
+		health string
+		ok     bool
+	}{
+		{"start", "running", "", true},
+		{"die", "exited", "", true},
+		{"destroy", "removed", "", true},
+		{"health_status: unhealthy", "running", "unhealthy", true},
+		{"exec_start: sh -c true", "", "", false},
+	}
+	for _, tt := range tests {
+		msg := events.Message{
+			Type:   events.ContainerEventType,
+			Action: tt.action,
+			Actor: events.Actor{Attributes: map[string]string{
+				"name":                       "ontree-wiki-web-1",
+				"com.docker.compose.service": "web",
+			}},
+		}
+		event, ok := containerEvent(msg)
+		if ok != tt.ok {
+			t.Errorf("containerEvent(%q) ok = %v", tt.action, ok)
+			continue
+		}
+		if ok && (event.State != tt.state || event.Health != tt.health || event.Container != "ontree-wiki-web-1" || event.Service != "web") {
+			t.Errorf("containerEvent(%q) = %+v", tt.action, event)
+		}
+	}
+}
+
+func TestAppForProject(t *testing.T) {
+	appsDir := t.TempDir()
+	for _, name := range []string{"Wiki", "photos"} {
+		if err := os.Mkdir(filepath.Join(appsDir, name), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for project, want := range map[string]string{
+		"ontree-wiki":   "Wiki",
+		"ontree-photos": "photos",
+		"photos":        "photos",
+		"ontree-other":  "",
+		"":              "",
+	} {
+		if got := appForProject(appsDir, project); got != want {
+			t.Errorf("appForProject(%q) = %q, want %q", project, got, want)
+		}
+	}
+}
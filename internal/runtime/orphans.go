@@ -0,0 +1,183 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Orphan kinds, as used by RemoveOrphan
+const (
+	OrphanContainer = "container"
+	OrphanVolume    = "volume"
+	OrphanNetwork   = "network"
+)
+
+// inferenceLabel marks the model containers TreeOS runs outside of the apps directory
+const inferenceLabel = "ontree.inference"
+
+// builtinNetworks are created by Docker itself and can't be removed
+var builtinNetworks = map[string]bool{"bridge": true, "host": true, "none": true}
+
+// Orphan is a Docker resource that no app in the apps directory owns, e.g. the container
+// of a deleted app or a volume left behind by `docker compose down`.
+type Orphan struct {
+	Kind    string `json:"kind"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Project string `json:"project,omitempty"` // Compose project the resource was created for
+	Detail  string `json:"detail,omitempty"`  // Image of a container, driver of a volume or network
+	State   string `json:"state,omitempty"`   // State of a container
+	Status  string `json:"status,omitempty"`  // Like docker ps, e.g. "Exited (0) 3 weeks ago"
+}
+
+// Removable reports whether RemoveOrphan can remove the resource. Running containers
+// have to be stopped first, so a container still in use is never removed by accident.
+func (o Orphan) Removable() bool {
+	return o.Kind != OrphanContainer || o.State != "running"
+}
+
+// FindOrphans lists containers that belong to no app, and unused volumes and networks of
+// compose projects that are not an app (anymore). Unused resources of an app that is
+// stopped are not orphans, they are reused when the app starts again.
+func (c *Client) FindOrphans(ctx context.Context, appsDir string) ([]Orphan, error) {
+	apps, err := c.ScanApps(appsDir)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, app := range apps {
+		candidates = append(candidates, projectNameCandidates(app)...)
+	}
+	owned := func(labels map[string]string, names []string) bool {
+		return containerMatchesProject(dockerContainer{Names: names, Labels: labels}, candidates)
+	}
+
+	containers, err := c.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orphans := []Orphan{}
+	for _, cnt := range containers {
+		if cnt.Labels[inferenceLabel] == "true" || cnt.Labels[volumeHelperLabel] == "true" || owned(cnt.Labels, cnt.Names) {
+			continue
+		}
+		name := cnt.ID
+		if len(cnt.Names) > 0 {
+			name = strings.TrimPrefix(cnt.Names[0], "/")
+		}
+		orphans = append(orphans, Orphan{
+			Kind:    OrphanContainer,
+			ID:      cnt.ID,
+			Name:    name,
+			Project: cnt.Labels["com.docker.compose.project"],
+			Detail:  cnt.Image,
+			State:   cnt.State,
+			Status:  cnt.Status,
+		})
+	}
+
+	// The mock runtime only simulates containers
+	if c.mock != nil {
+		sortOrphans(orphans)
+		return orphans, nil
+	}
+	if c.dockerClient == nil {
+		return nil, fmt.Errorf("docker client not initialized")
+	}
+
+	dangling := filters.NewArgs(filters.Arg("dangling", "true"))
+	volumes, err := c.dockerClient.VolumeList(ctx, volume.ListOptions{Filters: dangling})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker volumes: %w", err)
+	}
+	for _, vol := range volumes.Volumes {
+		if vol == nil || owned(vol.Labels, nil) {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			Kind:    OrphanVolume,
+			ID:      vol.Name,
+			Name:    vol.Name,
+			Project: vol.Labels["com.docker.compose.project"],
+			Detail:  vol.Driver,
+		})
+	}
+
+	networks, err := c.dockerClient.NetworkList(ctx, network.ListOptions{Filters: dangling})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker networks: %w", err)
+	}
+	for _, net := range networks {
+		if builtinNetworks[net.Name] || net.Ingress || owned(net.Labels, nil) {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			Kind:    OrphanNetwork,
+			ID:      net.ID,
+			Name:    net.Name,
+			Project: net.Labels["com.docker.compose.project"],
+			Detail:  net.Driver,
+		})
+	}
+
+	sortOrphans(orphans)
+	return orphans, nil
+}
+
+var orphanKindOrder = map[string]int{OrphanContainer: 0, OrphanVolume: 1, OrphanNetwork: 2}
+
+// sortOrphans sorts by kind, containers first, and then by name
+func sortOrphans(orphans []Orphan) {
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return orphanKindOrder[orphans[i].Kind] < orphanKindOrder[orphans[j].Kind]
+		}
+		return orphans[i].Name < orphans[j].Name
+	})
+}
+
+// RemoveOrphan removes a resource FindOrphans reports. It checks again that the resource
+// is an orphan, so a stale page can't remove something an app has started using since.
+func (c *Client) RemoveOrphan(ctx context.Context, appsDir, kind, id string) error {
+	orphans, err := c.FindOrphans(ctx, appsDir)
+	if err != nil {
+		return err
+	}
+	var orphan *Orphan
+	for i := range orphans {
+		if orphans[i].Kind == kind && orphans[i].ID == id {
+			orphan = &orphans[i]
+			break
+		}
+	}
+	if orphan == nil {
+		return fmt.Errorf("%s %s is not an orphan", kind, id)
+	}
+	if !orphan.Removable() {
+		return fmt.Errorf("container %s is running, stop it first", orphan.Name)
+	}
+
+	if c.mock != nil {
+		c.mock.Remove(id)
+		return nil
+	}
+	switch kind {
+	case OrphanContainer:
+		err = c.dockerClient.ContainerRemove(ctx, id, container.RemoveOptions{})
+	case OrphanVolume:
+		err = c.dockerClient.VolumeRemove(ctx, id, false)
+	case OrphanNetwork:
+		err = c.dockerClient.NetworkRemove(ctx, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove %s %s: %w", kind, orphan.Name, err)
+	}
+	return nil
+}
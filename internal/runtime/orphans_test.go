@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/mockruntime"
+	"github.com/ontree-co/treeos/internal/naming"
+)
+
+func TestFindOrphans(t *testing.T) {
+	appsDir := t.TempDir()
+	appPath := filepath.Join(appsDir, "web")
+	if err := os.MkdirAll(appPath, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appPath, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every container crashes, so the orphan is stopped and can be removed
+	mock := mockruntime.New(mockruntime.Config{FailureRate: 1})
+	ctx := context.Background()
+	services := []mockruntime.Service{{Name: "web", Image: "nginx"}}
+	if err := mock.Up(ctx, naming.GetComposeProjectName(naming.GetAppIdentifier(appPath)), services); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.Up(ctx, "ontree-deleted", services); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{mock: mock}
+
+	orphans, err := c.FindOrphans(ctx, appsDir)
+	if err != nil {
+		t.Fatalf("FindOrphans() error = %v", err)
+	}
+	if len(orphans) != 1 || orphans[0].Kind != OrphanContainer || orphans[0].Project != "ontree-deleted" || orphans[0].Detail != "nginx" {
+		t.Fatalf("FindOrphans() = %+v, want the container of the deleted app", orphans)
+	}
+	if !orphans[0].Removable() {
+		t.Errorf("stopped orphan %+v is not removable", orphans[0])
+	}
+
+	for _, cnt := range mock.Containers("") {
+		if cnt.Project != "ontree-deleted" {
+			if err := c.RemoveOrphan(ctx, appsDir, OrphanContainer, cnt.ID); err == nil {
+				t.Errorf("RemoveOrphan() removed container %s of an app", cnt.Name)
+			}
+		}
+	}
+	if err := c.RemoveOrphan(ctx, appsDir, OrphanContainer, orphans[0].ID); err != nil {
+		t.Fatalf("RemoveOrphan() error = %v", err)
+	}
+	if remaining := mock.Containers(""); len(remaining) != 1 || remaining[0].Project == "ontree-deleted" {
+		t.Errorf("containers after RemoveOrphan() = %+v, want only the app's", remaining)
+	}
+}
+
+func TestOrphanRemovable(t *testing.T) {
+	tests := []struct {
+		orphan Orphan
+		want   bool
+	}{
+		{Orphan{Kind: OrphanContainer, State: "running"}, false},
+		{Orphan{Kind: OrphanContainer, State: "exited"}, true},
+		{Orphan{Kind: OrphanVolume}, true},
+		{Orphan{Kind: OrphanNetwork}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.orphan.Removable(); got != tt.want {
+			t.Errorf("%+v.Removable() = %v, want %v", tt.orphan, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+)
+
+// PruneReport lists what a prune removed or, in a dry run, would remove
+type PruneReport struct {
+	Items     []string `json:"items"`     // Short IDs or names
+	Reclaimed int64    `json:"reclaimed"` // Bytes freed, or reclaimable in a dry run
+}
+
+// shortID shortens an image or cache ID like the docker CLI
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// PruneImages removes dangling images, those without a tag no container uses, like
+// `docker image prune`. Tagged images of stopped apps are kept.
+func (c *Client) PruneImages(ctx context.Context, dryRun bool) (PruneReport, error) {
+	report := PruneReport{Items: []string{}}
+	if c.mock != nil {
+		return report, nil
+	}
+	if c.dockerClient == nil {
+		return report, fmt.Errorf("docker client not initialized")
+	}
+
+	dangling := filters.NewArgs(filters.Arg("dangling", "true"))
+	if dryRun {
+		images, err := c.dockerClient.ImageList(ctx, image.ListOptions{Filters: dangling, ContainerCount: true})
+		if err != nil {
+			return report, fmt.Errorf("failed to list dangling images: %w", err)
+		}
+		for _, img := range images {
+			// An image a container was created from is kept
+			if img.Containers > 0 {
+				continue
+			}
+			report.Items = append(report.Items, shortID(img.ID))
+			report.Reclaimed += img.Size
+		}
+		return report, nil
+	}
+
+	pruned, err := c.dockerClient.ImagesPrune(ctx, dangling)
+	if err != nil {
+		return report, fmt.Errorf("failed to prune images: %w", err)
+	}
+	for _, deleted := range pruned.ImagesDeleted {
+		if deleted.Deleted != "" {
+			report.Items = append(report.Items, shortID(deleted.Deleted))
+		}
+	}
+	report.Reclaimed = int64(pruned.SpaceReclaimed) //nolint:gosec // Freed space fits into int64
+	return report, nil
+}
+
+// PruneBuildCache removes the build cache no build uses, like `docker builder prune`. The
+// dry run reports the cache `docker system df` counts as reclaimable.
+func (c *Client) PruneBuildCache(ctx context.Context, dryRun bool) (PruneReport, error) {
+	report := PruneReport{Items: []string{}}
+	if c.mock != nil {
+		return report, nil
+	}
+	if c.dockerClient == nil {
+		return report, fmt.Errorf("docker client not initialized")
+	}
+
+	if dryRun {
+		usage, err := c.dockerClient.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.BuildCacheObject}})
+		if err != nil {
+			return report, fmt.Errorf("failed to get build cache usage: %w", err)
+		}
+		for _, record := range usage.BuildCache {
+			if record == nil || record.InUse || record.Shared {
+				continue
+			}
+			report.Items = append(report.Items, shortID(record.ID))
+			report.Reclaimed += record.Size
+		}
+		return report, nil
+	}
+
+	pruned, err := c.dockerClient.BuildCachePrune(ctx, build.CachePruneOptions{})
+	if err != nil {
+		return report, fmt.Errorf("failed to prune build cache: %w", err)
+	}
+	for _, id := range pruned.CachesDeleted {
+		report.Items = append(report.Items, shortID(id))
+	}
+	report.Reclaimed = int64(pruned.SpaceReclaimed) //nolint:gosec // Freed space fits into int64
+	return report, nil
+}
+
+// PruneDanglingVolumes removes the volumes no container uses and no app owns, see
+// OrphanVolumes. Volumes of stopped apps are kept.
+func (c *Client) PruneDanglingVolumes(ctx context.Context, appsDir string, dryRun bool) (PruneReport, error) {
+	var volumes []Volume
+	var err error
+	if dryRun {
+		volumes, err = c.OrphanVolumes(ctx, appsDir)
+	} else {
+		volumes, err = c.PruneOrphanVolumes(ctx, appsDir)
+	}
+	report := PruneReport{Items: make([]string, 0, len(volumes))}
+	for _, vol := range volumes {
+		report.Items = append(report.Items, vol.Name)
+		report.Reclaimed += max(vol.Size, 0)
+	}
+	return report, err
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/image"
 	"go.opentelemetry.io/otel/attribute"
@@ -112,6 +113,12 @@ func (s *Service) PullImagesWithProgress(appName string, progressCallback Progre
 
 		progressCallback(0, fmt.Sprintf("Pulling image for service %s: %s", serviceName, imageName))
 
+		if s.client.mock != nil {
+			time.Sleep(s.client.mock.Config().Latency)
+			progressCallback(100, fmt.Sprintf("[%s] Image ready", serviceName))
+			continue
+		}
+
 		// Start pulling the image
 		reader, err := s.client.dockerClient.ImagePull(ctx, imageName, image.PullOptions{})
 		if err != nil {
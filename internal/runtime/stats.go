@@ -0,0 +1,137 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// statsConcurrency bounds the stats requests running at once. Docker takes a second per
+// container to measure the CPU usage.
+const statsConcurrency = 4
+
+// ContainerStats is the resource usage of a container of an app at one point in time
+type ContainerStats struct {
+	App         string
+	Service     string
+	Name        string
+	CPUPercent  float64 // Of one core, like docker stats, so it can exceed 100
+	MemoryBytes uint64  // Without the page cache
+	MemoryLimit uint64
+	NetRxBytes  uint64 // Received since the container started
+	NetTxBytes  uint64 // Sent since the container started
+}
+
+// ContainerStats samples the resource usage of the running containers of the apps in
+// appsDir. Containers that stop while they are sampled are left out.
+func (c *Client) ContainerStats(ctx context.Context, appsDir string) ([]ContainerStats, error) {
+	apps, err := c.ScanApps(appsDir)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := c.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		result []ContainerStats
+		slots  = make(chan struct{}, statsConcurrency)
+	)
+	for _, cnt := range containers {
+		if cnt.State != "running" {
+			continue
+		}
+		app := ""
+		for _, candidate := range apps {
+			if containerMatchesProject(cnt, projectNameCandidates(candidate)) {
+				app = candidate.Name
+				break
+			}
+		}
+		if app == "" {
+			continue
+		}
+		stats := ContainerStats{App: app, Service: cnt.Labels["com.docker.compose.service"], Name: cnt.ID}
+		if len(cnt.Names) > 0 {
+			stats.Name = strings.TrimPrefix(cnt.Names[0], "/")
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if ok := c.sampleStats(ctx, id, &stats); ok {
+				mu.Lock()
+				result = append(result, stats)
+				mu.Unlock()
+			}
+		}(cnt.ID)
+	}
+	wg.Wait()
+	return result, ctx.Err()
+}
+
+// sampleStats fills in the usage of a container, reporting whether it still runs
+func (c *Client) sampleStats(ctx context.Context, id string, stats *ContainerStats) bool {
+	if c.mock != nil {
+		sample, ok := c.mock.Stats(id)
+		stats.CPUPercent, stats.MemoryBytes, stats.MemoryLimit = sample.CPUPercent, sample.MemoryBytes, sample.MemoryLimit
+		stats.NetRxBytes, stats.NetTxBytes = sample.NetRxBytes, sample.NetTxBytes
+		return ok
+	}
+	if c.dockerClient == nil {
+		return false
+	}
+
+	reader, err := c.dockerClient.ContainerStats(ctx, id, false)
+	if err != nil {
+		return false
+	}
+	defer reader.Body.Close() //nolint:errcheck // Cleanup, error not critical
+	var response container.StatsResponse
+	if err := json.NewDecoder(reader.Body).Decode(&response); err != nil {
+		return false
+	}
+	if response.Read.IsZero() {
+		return false
+	}
+	applyStatsResponse(&response, stats)
+	return true
+}
+
+// applyStatsResponse derives the usage from a Docker stats response the way docker stats
+// computes it
+func applyStatsResponse(response *container.StatsResponse, stats *ContainerStats) {
+	cpuDelta := float64(response.CPUStats.CPUUsage.TotalUsage) - float64(response.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(response.CPUStats.SystemUsage) - float64(response.PreCPUStats.SystemUsage)
+	cpus := float64(response.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(response.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// The page cache can be reclaimed, docker stats leaves it out as well.
+	// cgroup v2 reports it as inactive_file, v1 as total_inactive_file.
+	stats.MemoryBytes = response.MemoryStats.Usage
+	for _, key := range []string{"inactive_file", "total_inactive_file"} {
+		if cache, ok := response.MemoryStats.Stats[key]; ok && cache < stats.MemoryBytes {
+			stats.MemoryBytes -= cache
+			break
+		}
+	}
+	stats.MemoryLimit = response.MemoryStats.Limit
+
+	for _, network := range response.Networks {
+		stats.NetRxBytes += network.RxBytes
+		stats.NetTxBytes += network.TxBytes
+	}
+}
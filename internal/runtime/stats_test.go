@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/ontree-co/treeos/internal/mockruntime"
+	"github.com/ontree-co/treeos/internal/naming"
+)
+
+func TestContainerStats(t *testing.T) {
+	appsDir := t.TempDir()
+	appPath := filepath.Join(appsDir, "web")
+	if err := os.MkdirAll(appPath, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appPath, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mock := mockruntime.New(mockruntime.Config{StartupTime: time.Hour})
+	ctx := context.Background()
+	services := []mockruntime.Service{{Name: "web", Image: "nginx"}}
+	if err := mock.Up(ctx, naming.GetComposeProjectName(naming.GetAppIdentifier(appPath)), services); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.Up(ctx, "ontree-deleted", services); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{mock: mock}
+
+	stats, err := c.ContainerStats(ctx, appsDir)
+	if err != nil {
+		t.Fatalf("ContainerStats() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].App != "web" || stats[0].Service != "web" || stats[0].MemoryBytes == 0 {
+		t.Errorf("ContainerStats() = %+v, want the container of the app", stats)
+	}
+}
+
+func TestApplyStatsResponse(t *testing.T) {
+	var response container.StatsResponse
+	response.CPUStats.CPUUsage.TotalUsage = 3_000
+	response.CPUStats.SystemUsage = 20_000
+	response.CPUStats.OnlineCPUs = 4
+	response.PreCPUStats.CPUUsage.TotalUsage = 1_000
+	response.PreCPUStats.SystemUsage = 10_000
+	response.MemoryStats.Usage = 100 << 20
+	response.MemoryStats.Limit = 1 << 30
+	response.MemoryStats.Stats = map[string]uint64{"inactive_file": 40 << 20}
+	response.Networks = map[string]container.NetworkStats{
+		"eth0": {RxBytes: 1000, TxBytes: 100},
+		"eth1": {RxBytes: 500, TxBytes: 50},
+	}
+
+	var stats ContainerStats
+	applyStatsResponse(&response, &stats)
+	if stats.CPUPercent != 80 {
+		t.Errorf("CPUPercent = %v, want 80", stats.CPUPercent)
+	}
+	if stats.MemoryBytes != 60<<20 || stats.MemoryLimit != 1<<30 {
+		t.Errorf("memory = %d of %d, want %d of %d", stats.MemoryBytes, stats.MemoryLimit, 60<<20, 1<<30)
+	}
+	if stats.NetRxBytes != 1500 || stats.NetTxBytes != 150 {
+		t.Errorf("network = %d/%d, want 1500/150", stats.NetRxBytes, stats.NetTxBytes)
+	}
+}
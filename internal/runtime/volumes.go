@@ -0,0 +1,389 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// volumeHelperImage runs the short-lived containers that read and write volumes and
+	// measure container logs, as the directories under /var/lib/docker are only readable
+	// by root
+	volumeHelperImage = "busybox:1.36"
+	// volumeHelperLabel marks the helper containers, so they are no orphans
+	volumeHelperLabel = "ontree.volume-helper"
+	// volumeMountPath is where the helper containers mount the volume
+	volumeMountPath = "/volume"
+	// VolumeFilesLimit bounds the entries ListVolumeFiles returns for a directory
+	VolumeFilesLimit = 1000
+)
+
+// errVolumesMocked is returned for volume operations the mock runtime doesn't simulate
+var errVolumesMocked = errors.New("volumes are not simulated by the mock runtime")
+
+// Volume is a Docker volume an app uses
+type Volume struct {
+	Name      string   `json:"name"`
+	Driver    string   `json:"driver"`
+	CreatedAt string   `json:"created_at,omitempty"`
+	Size      int64    `json:"size"`               // Bytes, -1 when Docker doesn't report it
+	Services  []string `json:"services,omitempty"` // Services of the app mounting it
+	InUse     bool     `json:"in_use"`             // Mounted by a running container
+}
+
+// VolumeEntry is a file or directory in a volume
+type VolumeEntry struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"` // file, dir, symlink or other
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// AppVolumes lists the volumes of an app: those created for its compose project and those
+// its containers mount, with the space they use.
+func (c *Client) AppVolumes(ctx context.Context, appsDir, appName string) ([]Volume, error) {
+	if c.mock != nil {
+		return []Volume{}, nil
+	}
+	if c.dockerClient == nil {
+		return nil, fmt.Errorf("docker client not initialized")
+	}
+	apps, err := c.ScanApps(appsDir)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, app := range apps {
+		if app.Name == appName {
+			candidates = projectNameCandidates(app)
+		}
+	}
+	if candidates == nil {
+		return nil, fmt.Errorf("app %s not found", appName)
+	}
+
+	volumes := map[string]*Volume{}
+	containers, err := c.dockerClient.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker containers: %w", err)
+	}
+	for _, cnt := range containers {
+		if !containerMatchesProject(dockerContainer{Names: cnt.Names, Labels: cnt.Labels}, candidates) {
+			continue
+		}
+		for _, m := range cnt.Mounts {
+			if m.Type != mount.TypeVolume || m.Name == "" {
+				continue
+			}
+			vol, ok := volumes[m.Name]
+			if !ok {
+				vol = &Volume{Name: m.Name, Driver: m.Driver, Size: -1}
+				volumes[m.Name] = vol
+			}
+			if service := cnt.Labels["com.docker.compose.service"]; service != "" && !slices.Contains(vol.Services, service) {
+				vol.Services = append(vol.Services, service)
+			}
+			vol.InUse = vol.InUse || cnt.State == "running"
+		}
+	}
+
+	list, err := c.dockerClient.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker volumes: %w", err)
+	}
+	for _, v := range list.Volumes {
+		if v == nil {
+			continue
+		}
+		vol, ok := volumes[v.Name]
+		if !ok {
+			// Volumes of the project no container mounts, e.g. while the app is stopped
+			if !containerMatchesProject(dockerContainer{Labels: v.Labels}, candidates) {
+				continue
+			}
+			vol = &Volume{Name: v.Name, Size: -1}
+			volumes[v.Name] = vol
+		}
+		vol.Driver = v.Driver
+		vol.CreatedAt = v.CreatedAt
+	}
+
+	sizes := c.volumeSizes(ctx)
+	result := make([]Volume, 0, len(volumes))
+	for _, vol := range volumes {
+		if size, ok := sizes[vol.Name]; ok {
+			vol.Size = size
+		}
+		sort.Strings(vol.Services)
+		result = append(result, *vol)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// volumeSizes returns the space each volume uses, like `docker system df -v`. Docker
+// computes it on request, a failure leaves the sizes unknown.
+func (c *Client) volumeSizes(ctx context.Context) map[string]int64 {
+	sizes := map[string]int64{}
+	usage, err := c.dockerClient.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return sizes
+	}
+	for _, v := range usage.Volumes {
+		if v != nil && v.UsageData != nil && v.UsageData.Size >= 0 {
+			sizes[v.Name] = v.UsageData.Size
+		}
+	}
+	return sizes
+}
+
+// OrphanVolumes returns the volumes FindOrphans reports, e.g. those of deleted apps, with
+// the space they use
+func (c *Client) OrphanVolumes(ctx context.Context, appsDir string) ([]Volume, error) {
+	orphans, err := c.FindOrphans(ctx, appsDir)
+	if err != nil {
+		return nil, err
+	}
+	var sizes map[string]int64
+	if c.dockerClient != nil {
+		sizes = c.volumeSizes(ctx)
+	}
+	volumes := []Volume{}
+	for _, orphan := range orphans {
+		if orphan.Kind != OrphanVolume {
+			continue
+		}
+		size, ok := sizes[orphan.ID]
+		if !ok {
+			size = -1
+		}
+		volumes = append(volumes, Volume{Name: orphan.Name, Driver: orphan.Detail, Size: size})
+	}
+	return volumes, nil
+}
+
+// PruneOrphanVolumes removes the volumes OrphanVolumes reports and returns them with the
+// space they used. Volumes that fail to be removed are skipped and reported in the error.
+func (c *Client) PruneOrphanVolumes(ctx context.Context, appsDir string) ([]Volume, error) {
+	orphans, err := c.OrphanVolumes(ctx, appsDir)
+	if err != nil {
+		return nil, err
+	}
+	removed := []Volume{}
+	var errs []error
+	for _, vol := range orphans {
+		if err := c.dockerClient.VolumeRemove(ctx, vol.Name, false); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove volume %s: %w", vol.Name, err))
+			continue
+		}
+		removed = append(removed, vol)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// volumePath returns the path of a file in the volume, as the helper containers see it.
+// The path can't leave the volume.
+func volumePath(rel string) string {
+	return path.Join(volumeMountPath, path.Clean("/"+rel))
+}
+
+// ensureVolumeHelperImage pulls the helper image unless it is present
+func (c *Client) ensureVolumeHelperImage(ctx context.Context) error {
+	if _, err := c.dockerClient.ImageInspect(ctx, volumeHelperImage); err == nil {
+		return nil
+	}
+	reader, err := c.dockerClient.ImagePull(ctx, volumeHelperImage, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", volumeHelperImage, err)
+	}
+	defer reader.Close() //nolint:errcheck // Cleanup, error not critical
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", volumeHelperImage, err)
+	}
+	return nil
+}
+
+// createVolumeHelper creates a container with the volume mounted and no network. The
+// returned function removes it again.
+func (c *Client) createVolumeHelper(ctx context.Context, volumeName string, readOnly bool, cmd []string) (string, func(), error) {
+	if c.mock != nil {
+		return "", nil, errVolumesMocked
+	}
+	if c.dockerClient == nil {
+		return "", nil, fmt.Errorf("docker client not initialized")
+	}
+	if _, err := c.dockerClient.VolumeInspect(ctx, volumeName); err != nil {
+		return "", nil, fmt.Errorf("volume %s not found: %w", volumeName, err)
+	}
+	return c.createHelper(ctx, mount.Mount{
+		Type:     mount.TypeVolume,
+		Source:   volumeName,
+		Target:   volumeMountPath,
+		ReadOnly: readOnly,
+	}, cmd)
+}
+
+// createHelper creates a helper container with the mount and no network. The returned
+// function removes it again.
+func (c *Client) createHelper(ctx context.Context, m mount.Mount, cmd []string) (string, func(), error) {
+	if err := c.ensureVolumeHelperImage(ctx); err != nil {
+		return "", nil, err
+	}
+	resp, err := c.dockerClient.ContainerCreate(ctx,
+		&container.Config{
+			Image:  volumeHelperImage,
+			Cmd:    cmd,
+			Labels: map[string]string{volumeHelperLabel: "true"},
+		},
+		&container.HostConfig{
+			NetworkMode: "none",
+			Mounts:      []mount.Mount{m},
+		}, nil, nil, "")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create helper container: %w", err)
+	}
+	remove := func() {
+		if err := c.dockerClient.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true}); err != nil {
+			logging.Warnf("Failed to remove volume helper container %s: %v", resp.ID, err)
+		}
+	}
+	return resp.ID, remove, nil
+}
+
+// ListVolumeFiles lists a directory of a volume, directories first, read-only. At most
+// VolumeFilesLimit entries are returned, truncated reports whether there are more.
+func (c *Client) ListVolumeFiles(ctx context.Context, volumeName, dir string) (entries []VolumeEntry, truncated bool, err error) {
+	cmd := []string{"find", volumePath(dir), "-mindepth", "1", "-maxdepth", "1", "-exec", "stat", "-c", "%F|%s|%Y|%n", "{}", "+"}
+	id, remove, err := c.createVolumeHelper(ctx, volumeName, true, cmd)
+	if err != nil {
+		return nil, false, err
+	}
+	defer remove()
+
+	stdout, stderr, exitCode, err := c.runHelper(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if exitCode != 0 {
+		message := strings.TrimSpace(stderr)
+		if strings.Contains(message, "No such file or directory") || strings.Contains(message, "Not a directory") {
+			return nil, false, fmt.Errorf("%s is no directory in volume %s", path.Clean("/"+dir), volumeName)
+		}
+		return nil, false, fmt.Errorf("failed to list %s: %s", path.Clean("/"+dir), message)
+	}
+
+	entries = parseVolumeEntries(stdout)
+	if len(entries) > VolumeFilesLimit {
+		return entries[:VolumeFilesLimit], true, nil
+	}
+	return entries, false, nil
+}
+
+// runHelper starts a helper container and returns its output once it exited
+func (c *Client) runHelper(ctx context.Context, id string) (stdout, stderr string, exitCode int64, err error) {
+	waitCh, errCh := c.dockerClient.ContainerWait(ctx, id, container.WaitConditionNextExit)
+	if err := c.dockerClient.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		return "", "", 0, fmt.Errorf("failed to start helper container: %w", err)
+	}
+	select {
+	case result := <-waitCh:
+		exitCode = result.StatusCode
+	case err := <-errCh:
+		return "", "", 0, fmt.Errorf("failed to wait for helper container: %w", err)
+	}
+
+	logs, err := c.dockerClient.ContainerLogs(ctx, id, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to read helper output: %w", err)
+	}
+	defer logs.Close() //nolint:errcheck // Cleanup, error not critical
+	var out, errOut bytes.Buffer
+	if _, err := stdcopy.StdCopy(&out, &errOut, logs); err != nil {
+		return "", "", 0, fmt.Errorf("failed to read helper output: %w", err)
+	}
+	return out.String(), errOut.String(), exitCode, nil
+}
+
+// parseVolumeEntries parses the `stat -c '%F|%s|%Y|%n'` lines of the helper
+func parseVolumeEntries(output string) []VolumeEntry {
+	entries := []VolumeEntry{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "|", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		mtime, _ := strconv.ParseInt(fields[2], 10, 64)
+		entry := VolumeEntry{Name: path.Base(fields[3]), Size: size, ModTime: time.Unix(mtime, 0).UTC()}
+		switch {
+		case fields[0] == "directory":
+			entry.Type = "dir"
+		case strings.HasPrefix(fields[0], "regular"):
+			entry.Type = "file"
+		case fields[0] == "symbolic link":
+			entry.Type = "symlink"
+		default:
+			entry.Type = "other"
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if (entries[i].Type == "dir") != (entries[j].Type == "dir") {
+			return entries[i].Type == "dir"
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// ExportVolume writes the content of a volume to w as tar archive, read-only
+func (c *Client) ExportVolume(ctx context.Context, volumeName string, w io.Writer) error {
+	id, remove, err := c.createVolumeHelper(ctx, volumeName, true, nil)
+	if err != nil {
+		return err
+	}
+	defer remove()
+
+	// The trailing /. archives the content without the volume directory itself
+	reader, _, err := c.dockerClient.CopyFromContainer(ctx, id, volumeMountPath+"/.")
+	if err != nil {
+		return fmt.Errorf("failed to read volume %s: %w", volumeName, err)
+	}
+	defer reader.Close() //nolint:errcheck // Cleanup, error not critical
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to export volume %s: %w", volumeName, err)
+	}
+	return nil
+}
+
+// ImportVolume extracts a tar archive into a volume. Existing files with the same names
+// are overwritten, others are kept.
+func (c *Client) ImportVolume(ctx context.Context, volumeName string, archive io.Reader) error {
+	id, remove, err := c.createVolumeHelper(ctx, volumeName, false, nil)
+	if err != nil {
+		return err
+	}
+	defer remove()
+
+	if err := c.dockerClient.CopyToContainer(ctx, id, volumeMountPath, archive, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to import into volume %s: %w", volumeName, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVolumePath(t *testing.T) {
+	cases := map[string]string{
+		"":               "/volume",
+		"/":              "/volume",
+		"data/db":        "/volume/data/db",
+		"/data/../logs/": "/volume/logs",
+		"../../etc":      "/volume/etc",
+	}
+	for rel, want := range cases {
+		if got := volumePath(rel); got != want {
+			t.Errorf("volumePath(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
+
+func TestParseVolumeEntries(t *testing.T) {
+	output := "regular file|42|1700000000|/volume/b.txt\n" +
+		"directory|4096|1700000100|/volume/pgdata\n" +
+		"symbolic link|7|1700000200|/volume/current\n" +
+		"regular empty file|0|1700000300|/volume/a|b\n" +
+		"fifo|0|1700000400|/volume/pipe\n"
+	entries := parseVolumeEntries(output)
+	want := []VolumeEntry{
+		{Name: "pgdata", Type: "dir", Size: 4096, ModTime: time.Unix(1700000100, 0).UTC()},
+		{Name: "a|b", Type: "file", Size: 0, ModTime: time.Unix(1700000300, 0).UTC()},
+		{Name: "b.txt", Type: "file", Size: 42, ModTime: time.Unix(1700000000, 0).UTC()},
+		{Name: "current", Type: "symlink", Size: 7, ModTime: time.Unix(1700000200, 0).UTC()},
+		{Name: "pipe", Type: "other", Size: 0, ModTime: time.Unix(1700000400, 0).UTC()},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
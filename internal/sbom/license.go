@@ -0,0 +1,101 @@
+package sbom
+
+import (
+	"sort"
+	"strings"
+)
+
+// copyleftPrefixes are SPDX license IDs whose terms reach into software that is distributed
+// with or links to the package. Weak copyleft like LGPL and MPL is included, because it still
+// obliges whoever redistributes an image.
+var copyleftPrefixes = []string{
+	"AGPL-", "GPL-", "LGPL-", "MPL-", "EPL-", "EUPL-", "CDDL-", "CPL-", "OSL-", "SSPL-", "CC-BY-SA-",
+}
+
+// Summary counts the packages of one or more SBOMs by license
+type Summary struct {
+	Packages int            `json:"packages"`
+	Unknown  int            `json:"unknown"`  // Packages without a license
+	Copyleft int            `json:"copyleft"` // Packages that can only be used under copyleft terms
+	Licenses []LicenseCount `json:"licenses"` // Most used first
+}
+
+// LicenseCount is the number of packages under a license expression
+type LicenseCount struct {
+	License  string `json:"license"`
+	Packages int    `json:"packages"`
+	Copyleft bool   `json:"copyleft"`
+}
+
+// Summarize counts packages by license. Packages without a license are counted as unknown.
+func Summarize(packages []Package) Summary {
+	summary := Summary{Packages: len(packages)}
+	counts := make(map[string]int)
+	for _, pkg := range packages {
+		if license := pkg.License(); license != "" {
+			counts[license]++
+		} else {
+			summary.Unknown++
+		}
+	}
+	summary.setLicenses(counts)
+	return summary
+}
+
+// Merge combines summaries, e.g. of all images of an app or of all apps
+func Merge(summaries ...Summary) Summary {
+	var merged Summary
+	counts := make(map[string]int)
+	for _, summary := range summaries {
+		merged.Packages += summary.Packages
+		merged.Unknown += summary.Unknown
+		for _, count := range summary.Licenses {
+			counts[count.License] += count.Packages
+		}
+	}
+	merged.setLicenses(counts)
+	return merged
+}
+
+func (s *Summary) setLicenses(counts map[string]int) {
+	s.Licenses = make([]LicenseCount, 0, len(counts))
+	for license, n := range counts {
+		copyleft := IsCopyleft(license)
+		if copyleft {
+			s.Copyleft += n
+		}
+		s.Licenses = append(s.Licenses, LicenseCount{License: license, Packages: n, Copyleft: copyleft})
+	}
+	sort.Slice(s.Licenses, func(i, j int) bool {
+		a, b := s.Licenses[i], s.Licenses[j]
+		if a.Packages != b.Packages {
+			return a.Packages > b.Packages
+		}
+		return a.License < b.License
+	})
+}
+
+// IsCopyleft reports whether a license expression leaves only copyleft terms, i.e. every
+// alternative of an OR contains a copyleft license. "MIT OR GPL-2.0-only" is not copyleft,
+// "MIT AND GPL-2.0-only" is. Parentheses are flattened, which is close enough for the
+// expressions found in images.
+func IsCopyleft(expression string) bool {
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+	for _, alternative := range strings.Split(expression, " OR ") {
+		if !containsCopyleft(alternative) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsCopyleft(expression string) bool {
+	for _, id := range strings.Fields(expression) {
+		for _, prefix := range copyleftPrefixes {
+			if strings.HasPrefix(id, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
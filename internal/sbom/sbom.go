@@ -0,0 +1,210 @@
+// Package sbom generates software bills of materials of container images and summarizes
+// their licenses. It runs the syft CLI with SPDX JSON output instead of linking the syft
+// library, which would pull hundreds of dependencies into TreeOS. The SPDX documents are
+// stored as generated, so they can be handed out unchanged.
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// generateTimeout limits how long syft may take for one image
+const generateTimeout = 10 * time.Minute
+
+// spdxSuffix is the file extension of stored SPDX documents
+const spdxSuffix = ".spdx.json"
+
+// ErrNoSyft is returned when syft is not installed
+var ErrNoSyft = errors.New("syft not found")
+
+// FindSyft returns the path of the installed syft
+func FindSyft() (string, error) {
+	path, err := exec.LookPath("syft")
+	if err != nil {
+		return "", ErrNoSyft
+	}
+	return path, nil
+}
+
+// Document is the part of an SPDX 2.3 JSON document the license report needs
+type Document struct {
+	SPDXVersion  string `json:"spdxVersion"`
+	Name         string `json:"name"`
+	CreationInfo struct {
+		Created time.Time `json:"created"`
+	} `json:"creationInfo"`
+	Packages []Package `json:"packages"`
+}
+
+// Package is a package listed in an SPDX document
+type Package struct {
+	Name             string `json:"name"`
+	VersionInfo      string `json:"versionInfo"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	LicenseDeclared  string `json:"licenseDeclared"`
+}
+
+// License returns the SPDX license expression of the package, "" if it is unknown
+func (p Package) License() string {
+	for _, license := range []string{p.LicenseConcluded, p.LicenseDeclared} {
+		if license != "" && license != "NOASSERTION" && license != "NONE" {
+			return license
+		}
+	}
+	return ""
+}
+
+// Generator creates SBOMs with syft and stores them below Dir, one directory per app
+type Generator struct {
+	Syft string
+	Dir  string
+}
+
+// ImageReport is the license summary of one stored SBOM
+type ImageReport struct {
+	Image       string    `json:"image"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Summary     Summary   `json:"summary"`
+}
+
+// Generate creates the SBOMs of an app's images from the local Docker images and replaces
+// the stored ones, which are left as they were if an image fails
+func (g *Generator) Generate(ctx context.Context, appName string, images []string) error {
+	if err := os.MkdirAll(g.Dir, 0750); err != nil {
+		return fmt.Errorf("failed to create SBOM directory: %w", err)
+	}
+	work, err := os.MkdirTemp(g.Dir, ".generate-*")
+	if err != nil {
+		return fmt.Errorf("failed to create SBOM directory: %w", err)
+	}
+	defer os.RemoveAll(work) //nolint:errcheck // Best-effort cleanup
+
+	for _, image := range images {
+		output, err := g.scan(ctx, image)
+		if err != nil {
+			return err
+		}
+		var doc Document
+		if err := json.Unmarshal(output, &doc); err != nil || doc.SPDXVersion == "" {
+			return fmt.Errorf("syft returned no SPDX document for %s", image)
+		}
+		if err := os.WriteFile(filepath.Join(work, url.PathEscape(image)+spdxSuffix), output, 0600); err != nil {
+			return fmt.Errorf("failed to store SBOM of %s: %w", image, err)
+		}
+	}
+
+	if err := g.Remove(appName); err != nil {
+		return err
+	}
+	return os.Rename(work, g.appDir(appName))
+}
+
+func (g *Generator) scan(ctx context.Context, image string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, generateTimeout)
+	defer cancel()
+
+	// docker: reads the image from the local daemon instead of pulling it from a registry
+	//nolint:gosec // syft path comes from FindSyft, images from the app's compose file
+	cmd := exec.CommandContext(ctx, g.Syft, "scan", "docker:"+image, "--output", "spdx-json", "--quiet")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("syft failed for %s: %w: %s", image, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// Reports returns the license summaries of an app's stored SBOMs, sorted by image, none if
+// no SBOMs were generated
+func (g *Generator) Reports(appName string) ([]ImageReport, error) {
+	entries, err := os.ReadDir(g.appDir(appName))
+	if errors.Is(err, os.ErrNotExist) {
+		return []ImageReport{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []ImageReport{}
+	for _, entry := range entries {
+		image, ok := imageOfFile(entry.Name())
+		if !ok {
+			continue
+		}
+		doc, err := g.document(appName, image)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, ImageReport{
+			Image:       image,
+			GeneratedAt: doc.CreationInfo.Created,
+			Summary:     Summarize(doc.Packages),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Image < reports[j].Image })
+	return reports, nil
+}
+
+func (g *Generator) document(appName, image string) (*Document, error) {
+	content, err := g.SPDX(appName, image)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("invalid SBOM of %s: %w", image, err)
+	}
+	return &doc, nil
+}
+
+// SPDX returns the stored SPDX JSON document of an image of an app
+func (g *Generator) SPDX(appName, image string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(g.appDir(appName), url.PathEscape(image)+spdxSuffix))
+}
+
+// Apps returns the apps with stored SBOMs, sorted
+func (g *Generator) Apps() ([]string, error) {
+	entries, err := os.ReadDir(g.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var apps []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			apps = append(apps, entry.Name())
+		}
+	}
+	return apps, nil
+}
+
+// Remove deletes the stored SBOMs of an app
+func (g *Generator) Remove(appName string) error {
+	return os.RemoveAll(g.appDir(appName))
+}
+
+func (g *Generator) appDir(appName string) string {
+	return filepath.Join(g.Dir, appName)
+}
+
+func imageOfFile(name string) (string, bool) {
+	escaped, ok := strings.CutSuffix(name, spdxSuffix)
+	if !ok {
+		return "", false
+	}
+	image, err := url.PathUnescape(escaped)
+	return image, err == nil
+}
@@ -0,0 +1,129 @@
+package sbom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSPDX = `{
+  "spdxVersion": "SPDX-2.3",
+  "name": "IMAGE",
+  "creationInfo": {"created": "2026-10-01T12:00:00Z"},
+  "packages": [
+    {"name": "musl", "versionInfo": "1.2.5", "licenseConcluded": "NOASSERTION", "licenseDeclared": "MIT"},
+    {"name": "busybox", "versionInfo": "1.36.1", "licenseConcluded": "NOASSERTION", "licenseDeclared": "GPL-2.0-only"},
+    {"name": "zlib", "versionInfo": "1.3.1", "licenseDeclared": "Zlib"},
+    {"name": "libfoo", "versionInfo": "0.1", "licenseConcluded": "NOASSERTION", "licenseDeclared": "NOASSERTION"}
+  ]
+}`
+
+// fakeSyft writes a syft that prints testSPDX with the scanned image as the document name,
+// or fails for images named broken
+func fakeSyft(t *testing.T, dir string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "sbom.json"), []byte(testSPDX), 0600); err != nil {
+		t.Fatal(err)
+	}
+	syft := filepath.Join(dir, "syft")
+	script := "#!/bin/sh\ncase $2 in *broken*) echo 'could not fetch image' >&2; exit 1;; esac\n" +
+		"sed \"s|IMAGE|${2#docker:}|\" " + filepath.Join(dir, "sbom.json") + "\n"
+	if err := os.WriteFile(syft, []byte(script), 0755); err != nil { //nolint:gosec // Test executable
+		t.Fatal(err)
+	}
+	return syft
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	g := &Generator{Syft: fakeSyft(t, dir), Dir: filepath.Join(dir, "sbom")}
+
+	images := []string{"nginx:1.27", "ghcr.io/immich-app/immich-server:release"}
+	if err := g.Generate(context.Background(), "immich", images); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	reports, err := g.Reports("immich")
+	if err != nil {
+		t.Fatalf("Reports() error = %v", err)
+	}
+	if len(reports) != 2 || reports[0].Image != "ghcr.io/immich-app/immich-server:release" || reports[1].Image != "nginx:1.27" {
+		t.Fatalf("Reports() = %+v", reports)
+	}
+	if reports[0].GeneratedAt.IsZero() || reports[0].Summary.Packages != 4 {
+		t.Errorf("report = %+v", reports[0])
+	}
+
+	spdx, err := g.SPDX("immich", "nginx:1.27")
+	if err != nil || !strings.Contains(string(spdx), `"name": "nginx:1.27"`) {
+		t.Errorf("SPDX() = %q, %v", spdx, err)
+	}
+	if apps, err := g.Apps(); err != nil || !reflect.DeepEqual(apps, []string{"immich"}) {
+		t.Errorf("Apps() = %v, %v", apps, err)
+	}
+
+	// A failing image keeps the SBOMs that were stored before
+	if err := g.Generate(context.Background(), "immich", []string{"nginx:1.27", "broken:latest"}); err == nil {
+		t.Fatal("Generate() should fail when syft fails")
+	}
+	if reports, _ := g.Reports("immich"); len(reports) != 2 {
+		t.Errorf("stored SBOMs after a failed generate = %+v", reports)
+	}
+	if apps, _ := g.Apps(); len(apps) != 1 {
+		t.Errorf("temporary files left behind: %v", apps)
+	}
+
+	if err := g.Remove("immich"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if reports, err := g.Reports("immich"); err != nil || len(reports) != 0 {
+		t.Errorf("Reports() after Remove() = %+v, %v", reports, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]Package{
+		{Name: "a", LicenseDeclared: "MIT"},
+		{Name: "b", LicenseConcluded: "MIT", LicenseDeclared: "NOASSERTION"},
+		{Name: "c", LicenseDeclared: "GPL-2.0-only"},
+		{Name: "d", LicenseDeclared: "NONE"},
+	})
+	want := Summary{
+		Packages: 4,
+		Unknown:  1,
+		Copyleft: 1,
+		Licenses: []LicenseCount{
+			{License: "MIT", Packages: 2},
+			{License: "GPL-2.0-only", Packages: 1, Copyleft: true},
+		},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("Summarize() = %+v, want %+v", summary, want)
+	}
+
+	merged := Merge(summary, Summarize([]Package{{Name: "c", LicenseDeclared: "GPL-2.0-only"}}))
+	if merged.Packages != 5 || merged.Copyleft != 2 || merged.Licenses[0] != (LicenseCount{License: "GPL-2.0-only", Packages: 2, Copyleft: true}) {
+		t.Errorf("Merge() = %+v", merged)
+	}
+}
+
+func TestIsCopyleft(t *testing.T) {
+	tests := map[string]bool{
+		"MIT":                             false,
+		"GPL-2.0-only":                    true,
+		"LGPL-2.1-or-later":               true,
+		"MIT OR GPL-2.0-only":             false,
+		"MIT AND GPL-2.0-only":            true,
+		"(GPL-2.0-only OR AGPL-3.0-only)": true,
+		"GPL-2.0-only WITH Classpath-exception-2.0": true,
+		"Apache-2.0 AND (MIT OR BSD-3-Clause)":      false,
+	}
+	for expression, want := range tests {
+		if got := IsCopyleft(expression); got != want {
+			t.Errorf("IsCopyleft(%q) = %v, want %v", expression, got, want)
+		}
+	}
+}
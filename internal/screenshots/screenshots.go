@@ -0,0 +1,172 @@
+// Package screenshots captures thumbnails of app web UIs for the dashboard. It runs a
+// headless Chrome or Chromium with --screenshot instead of driving it over the DevTools
+// protocol, which needs no extra dependency and no long-running browser process.
+package screenshots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// Size of the browser window the app is rendered in
+	viewportWidth  = 1280
+	viewportHeight = 800
+
+	// Size of the stored thumbnails
+	thumbnailWidth  = 320
+	thumbnailHeight = 200
+
+	// captureTimeout limits how long the browser may take to load and render a page
+	captureTimeout = 45 * time.Second
+)
+
+// ErrNoBrowser is returned when no Chrome or Chromium is installed
+var ErrNoBrowser = errors.New("no Chrome or Chromium found")
+
+// browserCandidates are the executables tried in order
+var browserCandidates = []string{
+	"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome",
+	"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	"/Applications/Chromium.app/Contents/MacOS/Chromium",
+}
+
+// FindBrowser returns the path of an installed Chrome or Chromium
+func FindBrowser() (string, error) {
+	for _, candidate := range browserCandidates {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNoBrowser
+}
+
+// Capturer takes screenshots with a browser and stores them as thumbnails in a directory
+type Capturer struct {
+	Browser string
+	Dir     string
+}
+
+// Path returns where the thumbnail of an app is stored
+func (c *Capturer) Path(appName string) string {
+	return filepath.Join(c.Dir, appName+".png")
+}
+
+// Capture renders url and stores a thumbnail for the app, replacing an older one
+func (c *Capturer) Capture(ctx context.Context, appName, url string) error {
+	if err := os.MkdirAll(c.Dir, 0750); err != nil {
+		return fmt.Errorf("failed to create screenshot directory: %w", err)
+	}
+	work, err := os.MkdirTemp(c.Dir, ".capture-*")
+	if err != nil {
+		return fmt.Errorf("failed to create screenshot directory: %w", err)
+	}
+	defer os.RemoveAll(work) //nolint:errcheck // Best-effort cleanup
+
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+
+	shot := filepath.Join(work, "screenshot.png")
+	//nolint:gosec // Browser path comes from FindBrowser, the URL from app metadata
+	cmd := exec.CommandContext(ctx, c.Browser, browserArgs(work, shot, url)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("browser failed for %s: %w: %s", url, err, lastLine(output))
+	}
+
+	f, err := os.Open(shot) //nolint:gosec // Path inside our temporary directory
+	if err != nil {
+		return fmt.Errorf("browser wrote no screenshot for %s: %w", url, err)
+	}
+	img, err := png.Decode(f)
+	f.Close() //nolint:errcheck,gosec // Read-only
+	if err != nil {
+		return fmt.Errorf("invalid screenshot of %s: %w", url, err)
+	}
+
+	tmp := filepath.Join(work, "thumbnail.png")
+	out, err := os.Create(tmp) //nolint:gosec // Path inside our temporary directory
+	if err != nil {
+		return err
+	}
+	err = png.Encode(out, Thumbnail(img, thumbnailWidth, thumbnailHeight))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	return os.Rename(tmp, c.Path(appName))
+}
+
+// Remove deletes the thumbnail of an app
+func (c *Capturer) Remove(appName string) error {
+	err := os.Remove(c.Path(appName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func browserArgs(profileDir, output, url string) []string {
+	args := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		"--mute-audio",
+		"--no-first-run",
+		"--disable-extensions",
+		"--ignore-certificate-errors",
+		"--user-data-dir=" + profileDir,
+		fmt.Sprintf("--window-size=%d,%d", viewportWidth, viewportHeight),
+		"--virtual-time-budget=5000", // Let scripts render before the capture
+		"--screenshot=" + output,
+	}
+	// Chromium refuses to run as root with its sandbox, and TreeOS runs as root on Linux
+	if runtime.GOOS == "linux" && os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox")
+	}
+	return append(args, url)
+}
+
+// Thumbnail scales img down to width x height by averaging the source pixels of each
+// target pixel, which keeps text and thin lines readable unlike nearest-neighbour sampling.
+func Thumbnail(img image.Image, width, height int) image.Image {
+	src := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := src.Min.Y + y*src.Dy()/height
+		y1 := max(src.Min.Y+(y+1)*src.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := src.Min.X + x*src.Dx()/width
+			x1 := max(src.Min.X+(x+1)*src.Dx()/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			//nolint:gosec // Averages of 16-bit channels fit into 16 bits
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// lastLine returns the last non-empty line of the browser output, usually the error
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return lines[len(lines)-1]
+}
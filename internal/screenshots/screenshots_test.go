@@ -0,0 +1,109 @@
+package screenshots
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	// Left half black, right half white
+	src := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			if x >= 20 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	thumb := Thumbnail(src, 4, 2)
+	if got := thumb.Bounds(); got.Dx() != 4 || got.Dy() != 2 {
+		t.Fatalf("bounds = %v", got)
+	}
+	if r, _, _, _ := thumb.At(0, 0).RGBA(); r != 0 {
+		t.Errorf("left pixel red = %d, want 0", r)
+	}
+	if r, _, _, _ := thumb.At(3, 1).RGBA(); r != 0xffff {
+		t.Errorf("right pixel red = %d, want 0xffff", r)
+	}
+
+	// Odd sizes average the covered pixels
+	mixed := Thumbnail(src, 1, 1)
+	if r, _, _, _ := mixed.At(0, 0).RGBA(); r < 0x7000 || r > 0x9000 {
+		t.Errorf("averaged red = %#x, want about half", r)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	dir := t.TempDir()
+
+	// A fake browser that copies a prepared page image to the --screenshot path
+	page := filepath.Join(dir, "page.png")
+	f, err := os.Create(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, viewportWidth, viewportHeight))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	browser := filepath.Join(dir, "browser")
+	script := "#!/bin/sh\nfor arg; do case $arg in --screenshot=*) cp " + page + " \"${arg#--screenshot=}\";; esac; done\n"
+	if err := os.WriteFile(browser, []byte(script), 0755); err != nil { //nolint:gosec // Test executable
+		t.Fatal(err)
+	}
+
+	c := &Capturer{Browser: browser, Dir: filepath.Join(dir, "screenshots")}
+	if err := c.Capture(context.Background(), "nextcloud", "http://127.0.0.1:8080/"); err != nil {
+		t.Fatalf("Capture() error = %v", err)
+	}
+
+	out, err := os.Open(c.Path("nextcloud"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	cfg, err := png.DecodeConfig(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != thumbnailWidth || cfg.Height != thumbnailHeight {
+		t.Errorf("thumbnail is %dx%d", cfg.Width, cfg.Height)
+	}
+
+	entries, _ := os.ReadDir(c.Dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	if err := c.Remove("nextcloud"); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+	if err := c.Remove("nextcloud"); err != nil {
+		t.Errorf("Remove() of a missing thumbnail error = %v", err)
+	}
+}
+
+func TestCaptureBrowserFailure(t *testing.T) {
+	dir := t.TempDir()
+	browser := filepath.Join(dir, "browser")
+	if err := os.WriteFile(browser, []byte("#!/bin/sh\necho 'net::ERR_CONNECTION_REFUSED' >&2\nexit 1\n"), 0755); err != nil { //nolint:gosec // Test executable
+		t.Fatal(err)
+	}
+
+	c := &Capturer{Browser: browser, Dir: dir}
+	if err := c.Capture(context.Background(), "app", "http://127.0.0.1:1/"); err == nil {
+		t.Fatal("Capture() should fail when the browser fails")
+	}
+	if _, err := os.Stat(c.Path("app")); err == nil {
+		t.Error("thumbnail written for a failed capture")
+	}
+}
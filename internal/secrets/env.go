@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// placeholder matches {{ secret NAME }}, the value of an .env line kept in the store
+var placeholder = regexp.MustCompile(`\{\{\s*secret\s+([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// sensitiveWords mark variable names whose values are moved into the store
+var sensitiveWords = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "API_KEY", "APIKEY", "PRIVATE_KEY", "CREDENTIAL"}
+
+// Placeholder returns what the .env holds instead of the value of a stored secret
+func Placeholder(name string) string {
+	return "{{ secret " + name + " }}"
+}
+
+// Sensitive reports whether the value of a variable is a secret judging by its name, such
+// as DB_PASSWORD or GITHUB_TOKEN
+func Sensitive(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, word := range sensitiveWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the secrets .env content refers to, in order of appearance
+func Names(content []byte) []string {
+	var names []string
+	for _, m := range placeholder.FindAllSubmatch(content, -1) {
+		if name := string(m[1]); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Render returns .env content with the placeholders replaced by the values of the
+// secrets, quoted where the .env format needs it. A secret without a value is an error.
+func Render(content []byte, values map[string]string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		if !placeholder.MatchString(line) {
+			continue
+		}
+		var missing string
+		key, value, ok := envLine(line)
+		if m := placeholder.FindStringSubmatch(value); ok && m != nil && m[0] == value {
+			// The whole value is the secret, quote it as a whole
+			secret, found := values[m[1]]
+			if !found {
+				return nil, fmt.Errorf("secret %s has no value, set it before starting the app", m[1])
+			}
+			lines[i] = key + "=" + yamlutil.FormatEnvValue(secret) + lineEnd(line)
+			continue
+		}
+		lines[i] = placeholder.ReplaceAllStringFunc(line, func(match string) string {
+			name := placeholder.FindStringSubmatch(match)[1]
+			secret, found := values[name]
+			if !found && missing == "" {
+				missing = name
+			}
+			return secret
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("secret %s has no value, set it before starting the app", missing)
+		}
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// Extract moves the values of the variables for which secret returns true out of .env
+// content. It returns the content with placeholders in their place and the values by
+// name. Empty values, placeholders and values referring to other variables stay.
+func Extract(content []byte, secret func(name string) bool) ([]byte, map[string]string) {
+	values := map[string]string{}
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		key, value, ok := envLine(line)
+		if !ok || !secret(key) || value == "" || strings.Contains(value, "{{") || strings.Contains(value, "${") {
+			continue
+		}
+		values[key] = value
+		lines[i] = key + "=" + Placeholder(key) + lineEnd(line)
+	}
+	return []byte(strings.Join(lines, "")), values
+}
+
+// Mask returns .env content with the values of sensitive variables replaced by
+// placeholders, for showing it. SealEnv stores such values when the masked content is
+// saved back.
+func Mask(content []byte) []byte {
+	masked, _ := Extract(content, Sensitive)
+	return masked
+}
+
+// envValues returns the values of .env content by name
+func envValues(content []byte) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if key, value, ok := envLine(line); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// envLine splits a KEY=VALUE line of an .env file, without the quotes around the value
+func envLine(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(strings.TrimPrefix(trimmed, "export "), "=")
+	key = strings.TrimSpace(key)
+	if !ok || yamlutil.ValidateEnvKey(key) != nil {
+		return "", "", false
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, true
+}
+
+// lineEnd returns the line break a line ends with, if any
+func lineEnd(line string) string {
+	if strings.HasSuffix(line, "\r\n") {
+		return "\r\n"
+	}
+	if strings.HasSuffix(line, "\n") {
+		return "\n"
+	}
+	return ""
+}
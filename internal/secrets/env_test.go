@@ -0,0 +1,54 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSensitive(t *testing.T) {
+	for name, want := range map[string]bool{
+		"DB_PASSWORD":     true,
+		"POSTGRES_PASSWD": true,
+		"JWT_SECRET":      true,
+		"github_token":    true,
+		"OPENAI_API_KEY":  true,
+		"PORT":            false,
+		"TZ":              false,
+		"KEYBOARD_LAYOUT": false,
+	} {
+		if got := Sensitive(name); got != want {
+			t.Errorf("Sensitive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	env := "# Database\nDB_PASSWORD='a b'\nPORT=8080\r\nexport API_TOKEN=abc\nJWT_SECRET={{ secret JWT_SECRET }}"
+	content, values := Extract([]byte(env), Sensitive)
+	want := "# Database\nDB_PASSWORD={{ secret DB_PASSWORD }}\nPORT=8080\r\nAPI_TOKEN={{ secret API_TOKEN }}\nJWT_SECRET={{ secret JWT_SECRET }}"
+	if string(content) != want {
+		t.Errorf("Extract() content = %q, want %q", content, want)
+	}
+	if wantValues := map[string]string{"DB_PASSWORD": "a b", "API_TOKEN": "abc"}; !reflect.DeepEqual(values, wantValues) {
+		t.Errorf("Extract() values = %v, want %v", values, wantValues)
+	}
+	if names := Names(content); !reflect.DeepEqual(names, []string{"DB_PASSWORD", "API_TOKEN", "JWT_SECRET"}) {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestRender(t *testing.T) {
+	env := "DB_PASSWORD={{ secret DB_PASSWORD }}\nDATABASE_URL=postgres://app:{{secret DB_PASSWORD}}@db/app\nPORT=8080\n"
+	got, err := Render([]byte(env), map[string]string{"DB_PASSWORD": "p#ss"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "DB_PASSWORD='p#ss'\nDATABASE_URL=postgres://app:p#ss@db/app\nPORT=8080\n"
+	if string(got) != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	if _, err := Render([]byte(env), nil); err == nil {
+		t.Error("Render() without values succeeded")
+	}
+}
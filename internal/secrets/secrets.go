@@ -0,0 +1,204 @@
+// Package secrets keeps the sensitive values of app .env files encrypted in the database.
+// The .env on disk holds a placeholder such as {{ secret DB_PASSWORD }} instead of the value,
+// compose up gets an .env with the values filled in.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// KeyFile holds the machine secret the encryption key is derived from, in the state directory
+	KeyFile = "secrets.key"
+	// machineSecretSize is the length of the machine secret in bytes
+	machineSecretSize = 32
+	// sealedPrefix marks values sealed with the current scheme, AES-256-GCM
+	sealedPrefix = "v1:"
+	// keyInfo binds the derived key to this use of the machine secret
+	keyInfo = "treeos app env secrets"
+)
+
+// Box encrypts and decrypts secret values with the key of this machine. Values sealed on
+// one machine can't be opened on another one without its key file.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// Load reads the machine secret from path, creating a random one on first use, and
+// returns a box with the key derived from it
+func Load(path string) (*Box, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Path from configuration
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		secret := make([]byte, machineSecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate machine secret: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create state directory: %w", err)
+		}
+		// O_EXCL, so two processes starting at once don't replace each other's secret
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) //nolint:gosec // Path from configuration
+		if errors.Is(err, os.ErrExist) {
+			return Load(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write machine secret: %w", err)
+		}
+		_, writeErr := file.WriteString(hex.EncodeToString(secret) + "\n")
+		if err := errors.Join(writeErr, file.Close()); err != nil {
+			return nil, fmt.Errorf("failed to write machine secret: %w", err)
+		}
+		logging.Infof("Created machine secret for app secrets at %s", path)
+		return New(secret)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read machine secret: %w", err)
+	}
+
+	secret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(secret) != machineSecretSize {
+		return nil, fmt.Errorf("invalid machine secret in %s", path)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+		logging.Warnf("Machine secret at %s was readable by others, restricting it to the owner", path)
+		if err := os.Chmod(path, 0600); err != nil {
+			return nil, fmt.Errorf("failed to restrict machine secret: %w", err)
+		}
+	}
+	return New(secret)
+}
+
+// New returns a box with the key derived from a machine secret
+func New(machineSecret []byte) (*Box, error) {
+	key, err := hkdf.Key(sha256.New, machineSecret, nil, keyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts a value for the database
+func (b *Box) Seal(value string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(value), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed by Seal
+func (b *Box) Open(sealed string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return "", errors.New("unknown secret format")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", errors.New("malformed secret")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	value, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("secret can't be decrypted with the key of this machine")
+	}
+	return string(value), nil
+}
+
+// Set stores a secret of an app, replacing a previous value
+func (b *Box) Set(app, name, value string) error {
+	sealed, err := b.Seal(value)
+	if err != nil {
+		return err
+	}
+	return database.SetAppEnvSecret(app, name, sealed)
+}
+
+// Values returns the stored secrets of an app by name, decrypted. Its signature fits
+// compose.SecretStore.
+func (b *Box) Values(app string) (map[string]string, error) {
+	stored, err := database.GetAppEnvSecrets(app)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(stored))
+	for name, sealed := range stored {
+		value, err := b.Open(sealed)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Updates returns when the secrets of an app were last set, without their values
+func (b *Box) Updates(app string) (map[string]time.Time, error) {
+	return database.GetAppEnvSecretUpdates(app)
+}
+
+// SealEnv moves the sensitive values of an app's .env content into the store and returns
+// the content with placeholders in their place. previous is the .env on disk before the
+// change: a placeholder of a secret that isn't stored yet takes the value from there, so
+// content that was shown masked can be saved back.
+func (b *Box) SealEnv(app string, content, previous []byte) ([]byte, error) {
+	stored, err := database.GetAppEnvSecretUpdates(app)
+	if err != nil {
+		return nil, err
+	}
+	sealed, values := Extract(content, func(name string) bool {
+		_, ok := stored[name]
+		return ok || Sensitive(name)
+	})
+
+	if missing := missingValues(sealed, stored, values); len(missing) > 0 {
+		onDisk := envValues(previous)
+		for _, name := range missing {
+			if value, ok := onDisk[name]; ok && value != "" && !placeholder.MatchString(value) {
+				values[name] = value
+			}
+		}
+	}
+	for name, value := range values {
+		if err := b.Set(app, name, value); err != nil {
+			return nil, fmt.Errorf("failed to store secret %s: %w", name, err)
+		}
+	}
+	return sealed, nil
+}
+
+// missingValues returns the placeholders of content with neither a stored nor a new value
+func missingValues(content []byte, stored map[string]time.Time, values map[string]string) []string {
+	var missing []string
+	for _, name := range Names(content) {
+		_, isStored := stored[name]
+		_, isNew := values[name]
+		if !isStored && !isNew {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,274 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity ranks how much a finding widens the container escape surface
+type Severity string
+
+// Severities from worst to mildest
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+	SeverityNone     Severity = "none"
+)
+
+// severityPoints is what a finding adds to the report score, capped at 100
+var severityPoints = map[Severity]int{
+	SeverityCritical: 40,
+	SeverityHigh:     20,
+	SeverityMedium:   10,
+	SeverityLow:      3,
+}
+
+// severityRank orders findings, worst first
+var severityRank = map[Severity]int{
+	SeverityCritical: 0,
+	SeverityHigh:     1,
+	SeverityMedium:   2,
+	SeverityLow:      3,
+}
+
+// remediationBaseURL points to the security documentation, findings link to a section of it
+const remediationBaseURL = "https://ontree.co/docs/features/security-validation"
+
+// dockerSockets are host sockets that hand out control of the container runtime
+var dockerSockets = []string{
+	"/var/run/docker.sock",
+	"/run/docker.sock",
+	"/run/podman/podman.sock",
+	"/var/run/podman/podman.sock",
+	"/run/containerd/containerd.sock",
+}
+
+// sensitiveHostPaths give a container access to the host system when mounted
+var sensitiveHostPaths = []string{
+	"/",
+	"/boot",
+	"/dev",
+	"/etc",
+	"/home",
+	"/proc",
+	"/root",
+	"/run",
+	"/sys",
+	"/usr",
+	"/var/lib/docker",
+	"/var/run",
+}
+
+// Finding is one risky setting of a service
+type Finding struct {
+	Service     string   `json:"service"`
+	Rule        string   `json:"rule"`
+	Detail      string   `json:"detail"`
+	Severity    Severity `json:"severity"`
+	Remediation string   `json:"remediation"` // Link to the documentation explaining the fix
+}
+
+// Report summarizes the container escape surface of an app
+type Report struct {
+	Findings []Finding `json:"findings"`
+	Score    int       `json:"score"` // 0 for no findings, up to 100
+	Level    Severity  `json:"level"` // Severity of the worst finding
+}
+
+// Report lists the risky settings of a docker-compose.yml. Unlike ValidateCompose it does
+// not stop at the first problem, and it also reports settings the validator allows, so
+// apps with security validation bypassed still show what they expose.
+func (v *Validator) Report(yamlContent []byte) (*Report, error) {
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(yamlContent, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse docker-compose.yml: %w", err)
+	}
+
+	report := &Report{Findings: []Finding{}, Level: SeverityNone}
+	for serviceName, service := range compose.Services {
+		report.Findings = append(report.Findings, v.serviceFindings(serviceName, service)...)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Detail < b.Detail
+	})
+
+	for _, finding := range report.Findings {
+		report.Score += severityPoints[finding.Severity]
+	}
+	if report.Score > 100 {
+		report.Score = 100
+	}
+	if len(report.Findings) > 0 {
+		report.Level = report.Findings[0].Severity
+	}
+	return report, nil
+}
+
+func (v *Validator) serviceFindings(serviceName string, service ServiceConfig) []Finding {
+	var findings []Finding
+	add := func(rule string, severity Severity, anchor, detail string) {
+		findings = append(findings, Finding{
+			Service:     serviceName,
+			Rule:        rule,
+			Detail:      detail,
+			Severity:    severity,
+			Remediation: remediationBaseURL + "#" + anchor,
+		})
+	}
+
+	if service.Privileged {
+		add("privileged mode", SeverityCritical, "1-privileged-mode",
+			"runs privileged with all capabilities and host devices")
+	}
+
+	for _, mode := range []struct{ name, value string }{
+		{"network", service.NetworkMode},
+		{"pid", service.Pid},
+		{"ipc", service.Ipc},
+	} {
+		if mode.value != "host" {
+			continue
+		}
+		severity := SeverityHigh
+		if mode.name == "ipc" {
+			severity = SeverityMedium
+		}
+		add("host namespace", severity, "host-namespaces",
+			fmt.Sprintf("shares the %s namespace of the host", mode.name))
+	}
+
+	for _, capability := range service.CapAdd {
+		normalized := strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+		switch {
+		case normalized == "ALL":
+			add("capabilities", SeverityCritical, "2-dangerous-capabilities", "adds all capabilities")
+		case isDangerousCapability(normalized):
+			add("capabilities", SeverityHigh, "2-dangerous-capabilities",
+				fmt.Sprintf("adds capability %s", normalized))
+		default:
+			add("capabilities", SeverityLow, "2-dangerous-capabilities",
+				fmt.Sprintf("adds capability %s", normalized))
+		}
+	}
+
+	for _, opt := range service.SecurityOpt {
+		normalized := strings.ReplaceAll(strings.ToLower(opt), "=", ":")
+		if strings.HasSuffix(normalized, ":unconfined") || normalized == "label:disable" {
+			add("security profile", SeverityHigh, "security-profiles",
+				fmt.Sprintf("disables confinement with security_opt %s", opt))
+		}
+	}
+
+	for _, device := range service.Devices {
+		if hostDevice, ok := device.(string); ok {
+			add("devices", SeverityMedium, "devices",
+				fmt.Sprintf("has access to host device %s", strings.SplitN(hostDevice, ":", 2)[0]))
+		}
+	}
+
+	for _, source := range bindMountSources(service.Volumes) {
+		switch {
+		case isDockerSocket(source):
+			add("docker socket", SeverityCritical, "docker-socket",
+				fmt.Sprintf("mounts the container runtime socket %s", source))
+		case v.bindMountAllowed(source):
+			// App directories can live below sensitive paths, e.g. /usr/local/ontree on macOS
+		case isSensitiveHostPath(source):
+			add("bind mount", SeverityHigh, "3-bind-mount-restrictions",
+				fmt.Sprintf("mounts the host path %s", source))
+		default:
+			add("bind mount", SeverityMedium, "3-bind-mount-restrictions",
+				fmt.Sprintf("mounts %s, outside the app directories", source))
+		}
+	}
+
+	return findings
+}
+
+// bindMountSources returns the host paths of the bind mounts of a service
+func bindMountSources(volumes []interface{}) []string {
+	var sources []string
+	for _, volume := range volumes {
+		source := ""
+		switch v := volume.(type) {
+		case string:
+			if parts := strings.SplitN(v, ":", 3); len(parts) >= 2 {
+				source = parts[0]
+			}
+		case map[string]interface{}:
+			if volumeType, _ := v["type"].(string); volumeType == "bind" {
+				source, _ = v["source"].(string)
+			}
+		}
+		// Named volumes don't start with / or .
+		if strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") {
+			if source != "/" {
+				source = strings.TrimSuffix(source, "/")
+			}
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// bindMountAllowed reports whether ValidateCompose accepts a bind mount source
+func (v *Validator) bindMountAllowed(source string) bool {
+	var allowed []string
+	if os.Getenv("TREEOS_RUN_MODE") == "demo" {
+		allowed = []string{"./volumes/", "./mnt/", "../../shared/", "./shared/"}
+	} else {
+		allowed = []string{
+			config.GetAppVolumesPath(v.appName) + "/",
+			config.GetAppMntPath(v.appName) + "/",
+			config.GetSharedPath() + "/",
+		}
+	}
+	for _, prefix := range allowed {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDangerousCapability(capability string) bool {
+	for _, dangerous := range DangerousCapabilities {
+		if capability == dangerous {
+			return true
+		}
+	}
+	return false
+}
+
+func isDockerSocket(source string) bool {
+	for _, socket := range dockerSockets {
+		if source == socket {
+			return true
+		}
+	}
+	return false
+}
+
+func isSensitiveHostPath(source string) bool {
+	for _, path := range sensitiveHostPaths {
+		if source == path || (path != "/" && strings.HasPrefix(source, path+"/")) {
+			return true
+		}
+	}
+	return false
+}
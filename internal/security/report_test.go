@@ -0,0 +1,102 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestReport(t *testing.T) {
+	validator := NewValidator("homeassistant")
+
+	yamlContent := `
+services:
+  homeassistant:
+    image: homeassistant/home-assistant:stable
+    privileged: true
+    network_mode: host
+    cap_add:
+      - NET_RAW
+      - CAP_SYS_ADMIN
+    devices:
+      - /dev/ttyUSB0:/dev/ttyUSB0
+    security_opt:
+      - apparmor=unconfined
+    volumes:
+      - /var/run/docker.sock:/var/run/docker.sock
+      - /etc:/host-etc:ro
+      - ` + config.GetAppMntPath("homeassistant") + `/config:/config
+      - /srv/media:/media
+      - ha-data:/data
+  db:
+    image: postgres:16
+    volumes:
+      - db-data:/var/lib/postgresql/data
+volumes:
+  ha-data:
+  db-data:
+`
+
+	report, err := validator.Report([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	want := []struct {
+		severity Severity
+		detail   string
+	}{
+		{SeverityCritical, "mounts the container runtime socket /var/run/docker.sock"},
+		{SeverityCritical, "runs privileged with all capabilities and host devices"},
+		{SeverityHigh, "adds capability SYS_ADMIN"},
+		{SeverityHigh, "disables confinement with security_opt apparmor=unconfined"},
+		{SeverityHigh, "mounts the host path /etc"},
+		{SeverityHigh, "shares the network namespace of the host"},
+		{SeverityMedium, "has access to host device /dev/ttyUSB0"},
+		{SeverityMedium, "mounts /srv/media, outside the app directories"},
+		{SeverityLow, "adds capability NET_RAW"},
+	}
+	if len(report.Findings) != len(want) {
+		t.Fatalf("Report() found %d findings, want %d: %+v", len(report.Findings), len(want), report.Findings)
+	}
+	for i, w := range want {
+		got := report.Findings[i]
+		if got.Severity != w.severity || got.Detail != w.detail || got.Service != "homeassistant" {
+			t.Errorf("finding %d = %+v, want %s %q", i, got, w.severity, w.detail)
+		}
+		if !strings.HasPrefix(got.Remediation, remediationBaseURL+"#") {
+			t.Errorf("finding %d has remediation %q", i, got.Remediation)
+		}
+	}
+
+	if report.Score != 100 {
+		t.Errorf("Score = %d, want capped at 100", report.Score)
+	}
+	if report.Level != SeverityCritical {
+		t.Errorf("Level = %s, want critical", report.Level)
+	}
+}
+
+func TestReport_Clean(t *testing.T) {
+	validator := NewValidator("miniflux")
+
+	report, err := validator.Report([]byte(`
+services:
+  miniflux:
+    image: miniflux/miniflux:latest
+    cap_add: []
+    volumes:
+      - data:/data
+`))
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(report.Findings) != 0 || report.Score != 0 || report.Level != SeverityNone {
+		t.Errorf("Report() = %+v, want no findings", report)
+	}
+
+	if _, err := validator.Report([]byte("services: [")); err == nil {
+		t.Error("Report() accepted invalid YAML")
+	}
+}
@@ -4,6 +4,7 @@ package security
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -27,6 +28,14 @@ var DangerousCapabilities = []string{
 // ComposeConfig represents a minimal docker-compose.yml structure for validation
 type ComposeConfig struct {
 	Services map[string]ServiceConfig `yaml:"services"`
+	Secrets  map[string]SecretConfig  `yaml:"secrets"`
+}
+
+// SecretConfig represents a top-level secret in docker-compose.yml
+type SecretConfig struct {
+	File        string      `yaml:"file"`
+	Environment string      `yaml:"environment"`
+	External    interface{} `yaml:"external"`
 }
 
 // ServiceConfig represents a service configuration in docker-compose.yml
@@ -47,6 +56,11 @@ type ServiceConfig struct {
 	ExtraHosts    []string      `yaml:"extra_hosts"`
 	DependsOn     interface{}   `yaml:"depends_on"`
 	Deploy        interface{}   `yaml:"deploy"`
+	NetworkMode   string        `yaml:"network_mode"`
+	Pid           string        `yaml:"pid"`
+	Ipc           string        `yaml:"ipc"`
+	Devices       []interface{} `yaml:"devices"`
+	SecurityOpt   []string      `yaml:"security_opt"`
 }
 
 // ValidationError represents a security validation error
@@ -99,6 +113,37 @@ func (v *Validator) ValidateCompose(yamlContent []byte) error {
 		}
 	}
 
+	// Check secrets, a secret file is bind mounted like a volume
+	for secretName, secret := range config.Secrets {
+		if err := v.validateSecret(secretName, secret); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSecret checks that a file secret stays inside the app directory, TreeOS writes the
+// material of these files when the app starts
+func (v *Validator) validateSecret(secretName string, secret SecretConfig) error {
+	if secret.External != nil {
+		return ValidationError{
+			Service: secretName,
+			Rule:    "secret source",
+			Detail:  "external secrets need Docker Swarm, use a file in the app directory",
+		}
+	}
+	if secret.File == "" {
+		return nil
+	}
+	file := filepath.Clean(secret.File)
+	if filepath.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+		return ValidationError{
+			Service: secretName,
+			Rule:    "secret file",
+			Detail:  fmt.Sprintf("secret file '%s' must be a relative path inside the app directory, e.g. ./secrets/%s", secret.File, secretName),
+		}
+	}
 	return nil
 }
 
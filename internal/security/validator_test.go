@@ -122,6 +122,38 @@ services:
 	}
 }
 
+func TestValidateSecrets(t *testing.T) {
+	tests := []struct {
+		name       string
+		secrets    string
+		shouldFail bool
+		errorMsg   string
+	}{
+		{name: "file in the app directory", secrets: "    file: ./secrets/db_password\n"},
+		{name: "from the environment", secrets: "    environment: DB_PASSWORD\n"},
+		{name: "absolute host file", secrets: "    file: /etc/shadow\n", shouldFail: true, errorMsg: "must be a relative path"},
+		{name: "outside the app directory", secrets: "    file: ./secrets/../../other/secret\n", shouldFail: true, errorMsg: "must be a relative path"},
+		{name: "external", secrets: "    external: true\n", shouldFail: true, errorMsg: "external secrets need Docker Swarm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlContent := "services:\n  db:\n    image: postgres:16\n    secrets: [db_password]\nsecrets:\n  db_password:\n" + tt.secrets
+			err := NewValidator("test-app").ValidateCompose([]byte(yamlContent))
+
+			if tt.shouldFail {
+				if err == nil {
+					t.Error("Expected validation to fail but it passed")
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing '%s', got: %v", tt.errorMsg, err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected validation to pass but got error: %v", err)
+			}
+		})
+	}
+}
+
 func TestValidateCapabilities(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,269 @@
+// Package selftest checks the public API of a running node end to end. It creates a throwaway
+// app, starts it, waits until it runs, reads its logs, stops and deletes it, and reports which
+// steps passed. It only talks HTTP, so it works against any node the API token is valid for:
+// users validate their install with it and CI runs it against release candidates.
+package selftest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ontree-co/treeos/pkg/client"
+)
+
+// composeYAML is the throwaway app, a single small container that keeps running and logs
+const composeYAML = `services:
+  app:
+    image: busybox:1.36
+    command: ["sh", "-c", "echo treeos selftest ready && sleep 3600"]
+`
+
+// pollInterval is the delay between status checks while waiting for the app
+var pollInterval = 2 * time.Second
+
+// Options configures a run
+type Options struct {
+	Target       string        // Base URL of the node, e.g. http://host:3000
+	Token        string        // API token of the node
+	App          string        // Name of the throwaway app, generated if empty
+	StartTimeout time.Duration // How long to wait for the app to run, including the image pull
+}
+
+// Step results
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Result is the outcome of one step
+type Result struct {
+	Step     string        `json:"step"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration_ns"`
+	Detail   string        `json:"detail,omitempty"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Target  string   `json:"target"`
+	App     string   `json:"app"`
+	Results []Result `json:"results"`
+}
+
+// Passed reports whether no step failed
+func (r *Report) Passed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints the pass/fail matrix
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "STEP\tRESULT\tDURATION\tDETAIL\n") //nolint:errcheck // Checked on flush
+	for _, result := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Step, strings.ToUpper(result.Status), //nolint:errcheck // Checked on flush
+			result.Duration.Round(time.Millisecond), result.Detail)
+	}
+	return tw.Flush()
+}
+
+type runner struct {
+	opts   Options
+	client *client.Client
+	report *Report
+}
+
+// Run executes all steps against the target. Once a step fails the remaining ones are skipped,
+// except for deleting the app, which is always attempted after it was created.
+func Run(ctx context.Context, opts Options) *Report {
+	opts.Target = strings.TrimSuffix(opts.Target, "/")
+	if opts.App == "" {
+		opts.App = generateAppName()
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = 5 * time.Minute
+	}
+
+	r := &runner{opts: opts, report: &Report{Target: opts.Target, App: opts.App}}
+	c, err := client.New(opts.Target)
+	if err != nil {
+		r.report.Results = append(r.report.Results, Result{Step: "version", Status: StatusFail, Detail: err.Error()})
+		return r.report
+	}
+	c.SetToken(opts.Token)
+	r.client = c
+	steps := []struct {
+		name string
+		fn   func(context.Context) (string, error)
+	}{
+		{"version", r.version},
+		{"auth", r.auth},
+		{"create", r.create},
+		{"start", r.start},
+		{"status", r.waitRunning},
+		{"logs", r.logs},
+		{"stop", r.stop},
+	}
+
+	created, failed := false, false
+	for _, step := range steps {
+		if failed {
+			r.skip(step.name)
+			continue
+		}
+		if !r.do(ctx, step.name, step.fn) {
+			failed = true
+		}
+		if step.name == "create" && !failed {
+			created = true
+		}
+	}
+	if created {
+		// The app is removed even if the run was cancelled
+		r.do(context.WithoutCancel(ctx), "delete", r.remove)
+	} else {
+		r.skip("delete")
+	}
+	return r.report
+}
+
+func (r *runner) do(ctx context.Context, step string, fn func(context.Context) (string, error)) bool {
+	start := time.Now()
+	detail, err := fn(ctx)
+	result := Result{Step: step, Status: StatusPass, Duration: time.Since(start), Detail: detail}
+	if err != nil {
+		result.Status, result.Detail = StatusFail, err.Error()
+	}
+	r.report.Results = append(r.report.Results, result)
+	return err == nil
+}
+
+func (r *runner) skip(step string) {
+	r.report.Results = append(r.report.Results, Result{Step: step, Status: StatusSkip})
+}
+
+func (r *runner) version(ctx context.Context) (string, error) {
+	info, err := r.client.Version(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "TreeOS " + info.Version, nil
+}
+
+func (r *runner) auth(ctx context.Context) (string, error) {
+	if r.opts.Token == "" {
+		return "", errors.New("no API token given")
+	}
+	// The app doesn't exist yet, so an accepted token gets a 404
+	_, err := r.client.AppStatus(ctx, r.opts.App)
+	if !isNotFound(err) {
+		if err == nil {
+			return "", fmt.Errorf("app %s already exists", r.opts.App)
+		}
+		return "", err
+	}
+	return "token accepted", nil
+}
+
+func (r *runner) create(ctx context.Context) (string, error) {
+	if _, err := r.client.CreateApp(ctx, client.CreateAppRequest{Name: r.opts.App, ComposeYAML: composeYAML}); err != nil {
+		return "", err
+	}
+	return "created " + r.opts.App, nil
+}
+
+func (r *runner) start(ctx context.Context) (string, error) {
+	resp, err := r.client.StartApp(ctx, r.opts.App)
+	if err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
+
+// waitUntil polls the status until it is want or the timeout expires
+func (r *runner) waitUntil(ctx context.Context, want string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	last := ""
+	for {
+		status, err := r.client.AppStatus(ctx, r.opts.App)
+		if err == nil {
+			if status.Status == want {
+				return "status " + status.Status, nil
+			}
+			last = status.Status
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("status is %q, expected %q after %s", last, want, timeout)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (r *runner) waitRunning(ctx context.Context) (string, error) {
+	return r.waitUntil(ctx, "running", r.opts.StartTimeout)
+}
+
+func (r *runner) logs(ctx context.Context) (string, error) {
+	body, err := r.client.AppLogs(ctx, r.opts.App, "", false)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close() //nolint:errcheck // Cleanup, error not critical
+	data, err := io.ReadAll(io.LimitReader(body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs: %w", err)
+	}
+	// Errors after the headers were sent end up in the log text
+	if _, message, found := strings.Cut(string(data), "Error retrieving logs: "); found {
+		return "", errors.New(strings.TrimSpace(message))
+	}
+	return fmt.Sprintf("%d lines", strings.Count(string(data), "\n")), nil
+}
+
+func (r *runner) stop(ctx context.Context) (string, error) {
+	if _, err := r.client.StopApp(ctx, r.opts.App); err != nil {
+		return "", err
+	}
+	return r.waitUntil(ctx, "stopped", time.Minute)
+}
+
+func (r *runner) remove(ctx context.Context) (string, error) {
+	if _, err := r.client.DeleteApp(ctx, r.opts.App); err != nil {
+		return "", err
+	}
+	if _, err := r.client.AppStatus(ctx, r.opts.App); !isNotFound(err) {
+		return "", fmt.Errorf("app still exists after deleting")
+	}
+	return "", nil
+}
+
+func isNotFound(err error) bool {
+	var apiErr *client.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func generateAppName() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("selftest-%d", time.Now().Unix())
+	}
+	return "selftest-" + hex.EncodeToString(b)
+}
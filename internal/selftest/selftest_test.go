@@ -0,0 +1,148 @@
+package selftest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNode serves the API endpoints the self-test uses for a single app
+type fakeNode struct {
+	mu      sync.Mutex
+	token   string
+	exists  bool
+	running bool
+	failOn  string // Path suffix answered with 500
+	deleted bool
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if r.URL.Path == "/version" {
+		w.Write([]byte(`{"version":"v1.2.3"}`)) //nolint:errcheck,gosec // Test server
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+n.token {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if n.failOn != "" && strings.HasSuffix(r.URL.Path, n.failOn) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/api/apps" && r.Method == http.MethodPost:
+		n.exists = true
+		n.running = true
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"success":true,"message":"created"}`)) //nolint:errcheck,gosec // Test server
+	case !n.exists:
+		http.Error(w, "App not found", http.StatusNotFound)
+	case strings.HasSuffix(r.URL.Path, "/start"):
+		n.running = true
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"success":true,"message":"starting"}`)) //nolint:errcheck,gosec // Test server
+	case strings.HasSuffix(r.URL.Path, "/stop"):
+		n.running = false
+		w.Write([]byte(`{"success":true,"message":"stopped"}`)) //nolint:errcheck,gosec // Test server
+	case strings.HasSuffix(r.URL.Path, "/logs"):
+		w.Write([]byte("app-1  | treeos selftest ready\n")) //nolint:errcheck,gosec // Test server
+	case strings.HasSuffix(r.URL.Path, "/status"):
+		status := "stopped"
+		if n.running {
+			status = "running"
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": status}) //nolint:errcheck,gosec // Test server
+	case r.Method == http.MethodDelete:
+		n.exists = false
+		n.deleted = true
+		w.Write([]byte(`{"success":true,"message":"deleted"}`)) //nolint:errcheck,gosec // Test server
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func statuses(report *Report) string {
+	parts := make([]string, 0, len(report.Results))
+	for _, result := range report.Results {
+		parts = append(parts, result.Step+"="+result.Status)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestRun(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		token       string
+		failOn      string
+		want        string
+		wantDeleted bool
+	}{
+		{
+			name:        "all steps pass",
+			token:       "secret",
+			want:        "version=pass auth=pass create=pass start=pass status=pass logs=pass stop=pass delete=pass",
+			wantDeleted: true,
+		},
+		{
+			name:  "wrong token",
+			token: "wrong",
+			want:  "version=pass auth=fail create=skip start=skip status=skip logs=skip stop=skip delete=skip",
+		},
+		{
+			name:        "failure after create still deletes",
+			token:       "secret",
+			failOn:      "/logs",
+			want:        "version=pass auth=pass create=pass start=pass status=pass logs=fail stop=skip delete=pass",
+			wantDeleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &fakeNode{token: "secret", failOn: tt.failOn}
+			server := httptest.NewServer(node)
+			defer server.Close()
+
+			report := Run(context.Background(), Options{Target: server.URL + "/", Token: tt.token, StartTimeout: time.Second})
+			if got := statuses(report); got != tt.want {
+				t.Errorf("Run() = %s, want %s", got, tt.want)
+			}
+			if report.Passed() != !strings.Contains(tt.want, "fail") {
+				t.Errorf("Passed() = %v", report.Passed())
+			}
+			if node.deleted != tt.wantDeleted {
+				t.Errorf("app deleted = %v, want %v", node.deleted, tt.wantDeleted)
+			}
+			if !strings.HasPrefix(report.App, "selftest-") {
+				t.Errorf("App = %q, want a generated selftest- name", report.App)
+			}
+		})
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	report := &Report{Results: []Result{
+		{Step: "version", Status: StatusPass, Duration: 12 * time.Millisecond, Detail: "TreeOS v1.2.3"},
+		{Step: "auth", Status: StatusFail, Detail: "unexpected status 500"},
+	}}
+	var b strings.Builder
+	if err := report.Write(&b); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{"STEP", "version  PASS", "12ms", "auth     FAIL", "unexpected status 500"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Write() output missing %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ontree-co/treeos/internal/a11y"
+	"github.com/ontree-co/treeos/internal/embeds"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// handleA11yAudit handles GET /api/system/a11y-audit. It lists the elements of the built-in
+// templates that screen readers cannot name, such as icon-only buttons without aria-label.
+func (s *Server) handleA11yAudit(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	templates, err := embeds.TemplateFS()
+	if err != nil {
+		logging.Errorf("Failed to open embedded templates: %v", err)
+		http.Error(w, "Failed to open templates", http.StatusInternalServerError)
+		return
+	}
+	issues, err := a11y.Audit(templates)
+	if err != nil {
+		logging.Errorf("Failed to audit templates: %v", err)
+		http.Error(w, "Failed to audit templates", http.StatusInternalServerError)
+		return
+	}
+	if issues == nil {
+		issues = []a11y.Issue{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"issues": issues, "count": len(issues)}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/a11y"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestHandleA11yAudit(t *testing.T) {
+	s := &Server{}
+	request := func(user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/system/a11y-audit", nil)
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		s.handleA11yAudit(w, req)
+		return w
+	}
+
+	if w := request(&database.User{Username: "user"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("status for non-staff = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w := request(&database.User{Username: "admin", IsStaff: true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var response struct {
+		Issues []a11y.Issue `json:"issues"`
+		Count  int          `json:"count"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// The built-in templates are kept free of issues, a new one fails here
+	for _, issue := range response.Issues {
+		t.Errorf("%s:%d: <%s> %s", issue.File, issue.Line, issue.Element, issue.Message)
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/progress"
+)
+
+const (
+	activityDefaultLimit = 50
+	activityMaxLimit     = 200
+	// activitySinceLastLogin selects the events since the previous login of the user
+	activitySinceLastLogin = "last-login"
+)
+
+// activityPeriods are the periods the feed can be filtered by besides the last login
+var activityPeriods = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// recordActivity adds an event to the activity feed. Without a database, e.g. during
+// setup, the event is only logged.
+func recordActivity(event database.ActivityEvent) {
+	if database.GetDB() == nil {
+		return
+	}
+	if err := database.RecordActivity(event); err != nil {
+		logging.Errorf("Failed to record activity: %v", err)
+	}
+}
+
+// recordAppJobActivity adds a finished app operation, such as a start with image pulls,
+// to the activity feed. They are only tracked in memory otherwise.
+func recordAppJobActivity(info *progress.AppProgress) {
+	event := database.ActivityEvent{
+		Category: database.ActivityJob,
+		Title:    info.Message,
+		AppName:  info.AppName,
+		Status:   jobStateCompleted,
+	}
+	if info.Operation == progress.OperationError {
+		event.Title = "App operation failed"
+		event.Detail = info.Error
+		event.Status = jobStateFailed
+	}
+	recordActivity(event)
+}
+
+// handleAPIActivity handles GET /api/activity, the feed of audit events, finished jobs,
+// alerts and updates, newest first. Query parameters: category (audit, job, alert or
+// update), app, since (24h, 7d, 30d or last-login) and limit.
+func (s *Server) handleAPIActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := database.ActivityFilter{
+		Category: query.Get("category"),
+		AppName:  query.Get("app"),
+		Limit:    activityDefaultLimit,
+	}
+	switch filter.Category {
+	case "", database.ActivityAudit, database.ActivityJob, database.ActivityAlert, database.ActivityUpdate:
+	default:
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+	if filter.AppName != "" && !isValidAppName(filter.AppName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > activityMaxLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	since := query.Get("since")
+	switch period, ok := activityPeriods[since]; {
+	case since == "":
+	case ok:
+		filter.Since = time.Now().Add(-period)
+	case since == activitySinceLastLogin:
+		// The newest login is the current session, the one before it the last visit
+		logins, err := database.GetRecentLoginEvents(user.ID, 2)
+		if err != nil {
+			logging.Errorf("Failed to get logins of user %s: %v", user.Username, err)
+		} else if len(logins) == 2 {
+			filter.Since = logins[1].CreatedAt
+		}
+	default:
+		http.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+
+	events, err := database.GetActivity(filter)
+	if err != nil {
+		logging.Errorf("Failed to get activity: %v", err)
+		http.Error(w, "Failed to get activity", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{"events": events}
+	if !filter.Since.IsZero() {
+		response["since"] = filter.Since
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/progress"
+)
+
+func TestHandleAPIActivity(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{config: &config.Config{}, progressTracker: progress.NewTracker()}
+	s.notifyAdmins("Rebuild of blog failed", "container web is unhealthy")
+	s.progressTracker.StartOperation("photos", progress.OperationPreparing, "Starting...")
+	s.progressTracker.SetError("photos", "pull access denied")
+	s.broadcastAppProgress("photos", "error")
+
+	staff := &database.User{ID: 1, Username: "admin", IsStaff: true}
+	request := func(query string, user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/activity"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIActivity(rec, req)
+		return rec
+	}
+
+	if rec := request("", &database.User{ID: 2, Username: "viewer"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-staff status = %d", rec.Code)
+	}
+	for _, query := range []string{"?category=logins", "?since=1y", "?limit=500", "?app=../etc"} {
+		if rec := request(query, staff); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", query, rec.Code)
+		}
+	}
+
+	decode := func(rec *httptest.ResponseRecorder) []database.ActivityEvent {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var response struct {
+			Events []database.ActivityEvent `json:"events"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Events
+	}
+
+	if events := decode(request("?since=last-login", staff)); len(events) != 2 {
+		t.Errorf("activity = %+v", events)
+	}
+	if events := decode(request("?category=alert&since=24h", staff)); len(events) != 1 || events[0].Title != "Rebuild of blog failed" {
+		t.Errorf("alerts = %+v", events)
+	}
+	events := decode(request("?app=photos", staff))
+	if len(events) != 1 || events[0].Category != database.ActivityJob || events[0].Detail != "pull access denied" {
+		t.Errorf("activity of photos = %+v", events)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/agentmemory"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// maxAgentMemorySize limits uploaded agent memory archives
+const maxAgentMemorySize = 50 << 20
+
+// handleAgentMemoryExport downloads the chat histories and review reports as a JSON archive
+func (s *Server) handleAgentMemoryExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	archive, err := agentmemory.Export()
+	if err != nil {
+		logging.Errorf("Failed to export agent memory: %v", err)
+		http.Error(w, "Failed to export agent memory", http.StatusInternalServerError)
+		return
+	}
+	data, err := agentmemory.Marshal(archive)
+	if err != nil {
+		logging.Errorf("Failed to export agent memory: %v", err)
+		http.Error(w, "Failed to export agent memory", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "treeos-agent-memory-" + time.Now().Format("20060102") + ".json"
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if _, err := w.Write(data); err != nil {
+		logging.Errorf("Failed to write agent memory export: %v", err)
+	}
+}
+
+// handleAgentMemoryImport merges an uploaded archive into the agent memory. With
+// ?dry_run=true it only reports what would be added, otherwise the request must confirm
+// with ?confirm=true.
+func (s *Server) handleAgentMemoryImport(w http.ResponseWriter, r *http.Request, username string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	if !dryRun && r.URL.Query().Get("confirm") != "true" {
+		http.Error(w, "Importing agent memory must be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAgentMemorySize))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w, maxAgentMemorySize)
+			return
+		}
+		http.Error(w, "Failed to read agent memory archive", http.StatusBadRequest)
+		return
+	}
+	archive, err := agentmemory.Parse(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	apps, err := s.reviewableApps()
+	if err != nil {
+		logging.Warnf("Failed to list apps for the agent memory import: %v", err)
+	}
+	result, err := agentmemory.Import(archive, apps, dryRun)
+	if err != nil {
+		logging.Errorf("Failed to import agent memory: %v", err)
+		http.Error(w, "Failed to import agent memory: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !dryRun && len(result.Changes) > 0 {
+		logging.Infof("User %s imported agent memory from %q: %d messages, %d reviews", username, archive.Node, result.Messages, result.Reviews)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logging.Errorf("Failed to encode import result: %v", err)
+	}
+}
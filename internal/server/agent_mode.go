@@ -0,0 +1,136 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/system"
+)
+
+// Agent mode runs a thin node that a primary TreeOS node manages over the API. It keeps the
+// container runtime, the vitals collector and the app, job and status endpoints, and leaves
+// out the web UI, page and app templates, Caddy and Ollama.
+
+// NodeVitals is the latest stored measurement of a node's resources
+type NodeVitals struct {
+	Timestamp     time.Time `json:"timestamp"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemoryPercent float64   `json:"memory_percent"`
+	DiskPercent   float64   `json:"disk_percent"`
+	GPULoad       float64   `json:"gpu_load"`
+	UploadRate    uint64    `json:"upload_rate"`   // Bytes per second
+	DownloadRate  uint64    `json:"download_rate"` // Bytes per second
+}
+
+// NodeInfo describes a node for the primary node that manages it
+type NodeInfo struct {
+	Hostname  string       `json:"hostname"`
+	Version   string       `json:"version"`
+	Platform  string       `json:"platform"`
+	AgentMode bool         `json:"agent_mode"`
+	Apps      int          `json:"apps"`
+	CPUs      []system.CPU `json:"cpus"`
+	Vitals    *NodeVitals  `json:"vitals,omitempty"` // Nil until the first measurement is stored
+}
+
+// startAgentMode starts the background jobs and endpoints of an agent node
+func (s *Server) startAgentMode() error {
+	logging.Infof("Starting in agent mode: API only, managed by a primary node")
+
+	go s.startVitalsCleanup()
+	if s.profile.RealtimeMetrics {
+		go s.startRealtimeMetricsCollection()
+	}
+	go s.startVitalsCollection()
+	go s.startProgressCleanup()
+	go s.startContainerEventWatcher()
+	go s.startAutostartApps()
+
+	return s.serve(s.agentRoutes())
+}
+
+// agentRoutes returns the endpoints of an agent node. All of them need the API token,
+// except the health and version checks, which follow their access settings.
+func (s *Server) agentRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	appAPI := s.TracingMiddleware(s.AgentAuthMiddleware(s.appRoutes(s.appAPIRoutes()).ServeHTTP))
+	mux.HandleFunc("/api/apps", appAPI)
+	mux.HandleFunc("/api/apps/", appAPI)
+	mux.HandleFunc("/api/v1/status/", s.TracingMiddleware(s.AgentAuthMiddleware(s.routeAPIStatus)))
+	mux.HandleFunc("/api/jobs", s.TracingMiddleware(s.AgentAuthMiddleware(s.routeAPIJobs)))
+	mux.HandleFunc("/api/jobs/", s.TracingMiddleware(s.AgentAuthMiddleware(s.routeAPIJobs)))
+	mux.HandleFunc("/api/orphans", s.TracingMiddleware(s.AgentAuthMiddleware(s.handleAPIOrphans)))
+	mux.HandleFunc("/api/node", s.TracingMiddleware(s.AgentAuthMiddleware(s.handleAPINode)))
+
+	mux.HandleFunc("/version", s.TracingMiddleware(s.EndpointAccessMiddleware(s.config.VersionAccess, []string{s.config.APIToken}, s.handleVersion)))
+	mux.HandleFunc("/api/health", s.TracingMiddleware(s.EndpointAccessMiddleware(s.config.HealthAccess, []string{s.config.APIToken}, s.handleHealth)))
+	mux.HandleFunc("/metrics", s.TracingMiddleware(s.EndpointAccessMiddleware(s.config.MetricsAccess, []string{s.config.MetricsToken, s.config.APIToken}, s.handleMetrics)))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "This TreeOS node runs in agent mode and is managed over the API of its primary node", http.StatusNotFound)
+	})
+	return mux
+}
+
+// AgentAuthMiddleware lets requests with the API token through. Agent nodes have no users,
+// requests act as an administrator of the node.
+func (s *Server) AgentAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.config.APIToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.APIToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="treeos"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user := &database.User{Username: "primary", IsStaff: true, IsSuperuser: true, IsActive: true}
+		next(w, r.WithContext(setUserContext(r.Context(), user)))
+	}
+}
+
+// handleAPINode handles GET /api/node: the node's identity, CPUs and latest vitals
+func (s *Server) handleAPINode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	info := NodeInfo{
+		Version:   s.versionInfo.Version,
+		Platform:  s.versionInfo.Platform,
+		AgentMode: s.config.AgentMode,
+		CPUs:      system.CPUTopology(),
+	}
+	info.Hostname, _ = os.Hostname()
+	if entries, err := os.ReadDir(s.config.AppsDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				info.Apps++
+			}
+		}
+	}
+	if latest, err := database.GetLatestMetric(""); err != nil {
+		logging.Errorf("Failed to get latest vitals: %v", err)
+	} else if latest != nil {
+		info.Vitals = &NodeVitals{
+			Timestamp:     latest.Timestamp,
+			CPUPercent:    latest.CPUPercent,
+			MemoryPercent: latest.MemoryPercent,
+			DiskPercent:   latest.DiskUsagePercent,
+			GPULoad:       latest.GPULoad,
+			UploadRate:    latest.UploadRate,
+			DownloadRate:  latest.DownloadRate,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestAgentRoutes(t *testing.T) {
+	appsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appsDir, "web"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir, APIToken: "secret", AgentMode: true}}
+	mux := s.agentRoutes()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "node info", method: http.MethodGet, path: "/api/node", token: "secret", wantStatus: http.StatusOK},
+		{name: "no token", method: http.MethodGet, path: "/api/node", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, path: "/api/apps/web", token: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "no pages", method: http.MethodGet, path: "/", token: "secret", wantStatus: http.StatusNotFound},
+		{name: "no login page", method: http.MethodGet, path: "/login", wantStatus: http.StatusNotFound},
+		{name: "no templates", method: http.MethodPost, path: "/api/apps/propose", token: "secret", wantStatus: http.StatusNotFound},
+		{name: "no chat", method: http.MethodPost, path: "/api/apps/web/chat", token: "secret", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.path != "/api/node" || w.Code != http.StatusOK {
+				return
+			}
+			var info NodeInfo
+			if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+				t.Fatal(err)
+			}
+			if !info.AgentMode || info.Apps != 1 || len(info.CPUs) == 0 {
+				t.Errorf("node info = %+v", info)
+			}
+		})
+	}
+}
@@ -0,0 +1,534 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// agentReviewCheckInterval is how often the monitor checks whether a review is due
+	agentReviewCheckInterval = 15 * time.Minute
+	// defaultAgentReviewInterval is used when no valid interval is configured
+	defaultAgentReviewInterval = 24 * time.Hour
+	// agentReviewAppTimeout limits the assessment of one app
+	agentReviewAppTimeout = 2 * time.Minute
+	agentReviewLogLines   = 80
+	// diskFillWarningDays is the forecast below which a filling disk is reported
+	diskFillWarningDays = 30
+	agentReviewPageSize = 20
+)
+
+// agentReviewIntervals are the intervals offered in Settings
+var agentReviewIntervals = []string{"6h", "12h", "24h", "168h"}
+
+// errAgentReviewRunning is returned when a review is started while another one runs
+var errAgentReviewRunning = errors.New("a review is already running")
+
+// appAssessment is the LLM's answer for one app
+type appAssessment struct {
+	Severity        string `json:"severity"`
+	Summary         string `json:"summary"`
+	SuggestedAction string `json:"suggested_action"`
+	Reason          string `json:"reason"`
+}
+
+// formatReviewInterval formats an interval the way agentReviewIntervals lists it
+func formatReviewInterval(d time.Duration) string {
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+// agentReviewSchedule reads whether scheduled reviews are enabled and how often they run
+func (s *Server) agentReviewSchedule() (bool, time.Duration) {
+	if s.db == nil {
+		return false, defaultAgentReviewInterval
+	}
+	var enabled sql.NullInt64
+	var interval sql.NullString
+	err := s.db.QueryRow(`SELECT agent_enabled, agent_review_interval FROM system_setup WHERE id = 1`).Scan(&enabled, &interval)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logging.Errorf("Failed to read agent review settings: %v", err)
+		}
+		return false, defaultAgentReviewInterval
+	}
+	duration, err := time.ParseDuration(interval.String)
+	if err != nil || duration < time.Hour {
+		duration = defaultAgentReviewInterval
+	}
+	return enabled.Int64 == 1, duration
+}
+
+// startAgentReviewMonitor runs a health review of all apps whenever one is due
+func (s *Server) startAgentReviewMonitor() {
+	go func() {
+		ticker := time.NewTicker(agentReviewCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.checkAgentReviewDue()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Server) checkAgentReviewDue() {
+	enabled, interval := s.agentReviewSchedule()
+	llm, configured := s.agentLLM()
+	if !enabled || !configured {
+		return
+	}
+
+	reviews, err := database.GetAgentReviews(1)
+	if err != nil {
+		logging.Errorf("Failed to get last agent review: %v", err)
+		return
+	}
+	if len(reviews) > 0 && time.Since(reviews[0].CreatedAt) < interval {
+		return
+	}
+
+	if _, err := s.runAgentReview(context.Background(), llm); err != nil && !errors.Is(err, errAgentReviewRunning) {
+		logging.Errorf("Agent review failed: %v", err)
+	}
+}
+
+// runAgentReview assesses every app with the LLM, stores the report and queues suggested
+// actions for approval. Admins are notified when something needs attention.
+func (s *Server) runAgentReview(ctx context.Context, llm llmConfig) (int, error) {
+	if !s.agentReviewMu.TryLock() {
+		return 0, errAgentReviewRunning
+	}
+	defer s.agentReviewMu.Unlock()
+
+	reviewID, err := database.CreateAgentReview(llm.Model)
+	if err != nil {
+		return 0, err
+	}
+
+	apps, err := s.reviewableApps()
+	if err != nil {
+		if failErr := database.FailAgentReview(reviewID, err.Error()); failErr != nil {
+			logging.Errorf("Failed to store agent review error: %v", failErr)
+		}
+		return reviewID, err
+	}
+
+	findings := []database.AgentFinding{}
+	if finding := s.diskFinding(); finding != nil {
+		findings = append(findings, *finding)
+	}
+
+	var queued []database.AgentAction
+	for _, appName := range apps {
+		appCtx, cancel := context.WithTimeout(ctx, agentReviewAppTimeout)
+		assessment, err := reviewApp(appCtx, llm, appName, s.appEvidence(appCtx, appName))
+		cancel()
+		if err != nil {
+			logging.Warnf("Agent review of app %s failed: %v", appName, err)
+			findings = append(findings, database.AgentFinding{App: appName, Severity: database.SeverityInfo, Summary: "Could not be reviewed: " + err.Error()})
+			continue
+		}
+		findings = append(findings, database.AgentFinding{App: appName, Severity: assessment.Severity, Summary: assessment.Summary})
+
+		if assessment.SuggestedAction != database.ActionRestartApp {
+			continue
+		}
+		pending, err := database.HasPendingAgentAction(appName, database.ActionRestartApp)
+		if err != nil || pending {
+			continue
+		}
+		action := database.AgentAction{ReviewID: reviewID, AppName: appName, Action: database.ActionRestartApp, Reason: assessment.Reason}
+		if action.ID, err = database.CreateAgentAction(action); err != nil {
+			logging.Errorf("Failed to queue agent action for app %s: %v", appName, err)
+			continue
+		}
+		queued = append(queued, action)
+	}
+
+	summary := summarizeFindings(len(apps), findings)
+	if err := database.CompleteAgentReview(reviewID, summary, findings); err != nil {
+		return reviewID, err
+	}
+	logging.Infof("Agent review #%d completed: %s", reviewID, summary)
+
+	if body := agentReviewNotification(findings, queued); body != "" {
+		s.notifyAdmins("TreeOS: agent health review", summary+"\n\n"+body)
+	}
+	return reviewID, nil
+}
+
+// reviewableApps returns the names of all apps with a compose file
+func (s *Server) reviewableApps() ([]string, error) {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+	apps := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, entry.Name(), "docker-compose.yml")); err == nil {
+			apps = append(apps, entry.Name())
+		}
+	}
+	return apps, nil
+}
+
+// appEvidence collects what the agent looks at for one app: container status, recent logs
+// and quota usage. Errors are included as text so the LLM can report them.
+func (s *Server) appEvidence(ctx context.Context, appName string) string {
+	tools := s.appAgentTools(appName)
+	var b strings.Builder
+
+	status, err := tools["get_status"].Run(ctx, nil)
+	if err != nil {
+		status = "Error: " + err.Error()
+	}
+	fmt.Fprintf(&b, "## Containers\n%s\n\n", strings.TrimSpace(status))
+
+	logs, err := tools["get_logs"].Run(ctx, json.RawMessage(fmt.Sprintf(`{"lines": %d}`, agentReviewLogLines)))
+	if err != nil {
+		logs = "Error: " + err.Error()
+	}
+	if len(logs) > appAgentMaxToolBytes {
+		logs = logs[len(logs)-appAgentMaxToolBytes:]
+	}
+	fmt.Fprintf(&b, "## Last %d log lines\n%s\n", agentReviewLogLines, strings.TrimSpace(logs))
+
+	if usage := s.appQuotaUsage(appName); usage != nil {
+		fmt.Fprintf(&b, "\n## Disk quota\n%s of %s used\n", usage.UsedLabel(), usage.LimitLabel())
+	}
+	return b.String()
+}
+
+// reviewApp asks the LLM to assess one app
+func reviewApp(ctx context.Context, llm llmConfig, appName, evidence string) (*appAssessment, error) {
+	prompt := fmt.Sprintf(`You review the health of the app %q on a self-hosted TreeOS server.
+Look for crashed or restarting containers, repeated errors, failing jobs and resource problems in the data below.
+Answer with a single JSON object and nothing else:
+{"severity": "ok|info|warning|critical", "summary": "...", "suggested_action": "none|restart_app", "reason": "..."}
+
+- summary: one or two sentences for the admin, e.g. "Nextcloud shows repeated cron failures."
+- Only suggest restart_app when a restart is likely to fix the problem. An admin has to approve it.
+- reason: why the action helps, empty without an action.`, appName)
+
+	reply, err := chatCompletion(ctx, llm, []llmMessage{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: evidence},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var assessment appAssessment
+	if err := decodeJSONReply(reply.Content, &assessment); err != nil {
+		return nil, err
+	}
+	switch assessment.Severity {
+	case database.SeverityOK, database.SeverityInfo, database.SeverityWarning, database.SeverityCritical:
+	default:
+		assessment.Severity = database.SeverityInfo
+	}
+	if assessment.SuggestedAction != database.ActionRestartApp {
+		assessment.SuggestedAction = ""
+	}
+	assessment.Summary = strings.TrimSpace(assessment.Summary)
+	if assessment.Summary == "" {
+		assessment.Summary = "No issues found."
+	}
+	return &assessment, nil
+}
+
+// diskFinding reports when the system disk is forecast to fill up soon
+func (s *Server) diskFinding() *database.AgentFinding {
+	metrics, err := database.GetMetricsForTimeRange(time.Now().Add(-7*24*time.Hour), time.Now())
+	if err != nil {
+		logging.Warnf("Failed to read disk usage history: %v", err)
+		return nil
+	}
+	days, ok := diskFillForecast(metrics)
+	if !ok || days > diskFillWarningDays {
+		return nil
+	}
+	severity := database.SeverityWarning
+	if days < 7 {
+		severity = database.SeverityCritical
+	}
+	return &database.AgentFinding{
+		Severity: severity,
+		Summary:  fmt.Sprintf("The system disk is %.0f%% full and will fill in ~%.0f days at the current rate.", metrics[len(metrics)-1].DiskUsagePercent, days),
+	}
+}
+
+// diskFillForecast fits a line through the disk usage history and returns the days until
+// the disk is full. ok is false with too little history or when usage isn't growing.
+func diskFillForecast(metrics []database.SystemVitalLog) (days float64, ok bool) {
+	if len(metrics) < 10 || metrics[len(metrics)-1].Timestamp.Sub(metrics[0].Timestamp) < 12*time.Hour {
+		return 0, false
+	}
+
+	start := metrics[0].Timestamp
+	var sumX, sumY, sumXY, sumXX float64
+	for _, m := range metrics {
+		x := m.Timestamp.Sub(start).Hours() / 24
+		sumX += x
+		sumY += m.DiskUsagePercent
+		sumXY += x * m.DiskUsagePercent
+		sumXX += x * x
+	}
+	n := float64(len(metrics))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator // Percent per day
+	if slope <= 0.01 {
+		return 0, false
+	}
+	return (100 - metrics[len(metrics)-1].DiskUsagePercent) / slope, true
+}
+
+// summarizeFindings returns a one-line summary of a review
+func summarizeFindings(apps int, findings []database.AgentFinding) string {
+	counts := map[string]int{}
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	summary := fmt.Sprintf("Reviewed %d apps", apps)
+	switch {
+	case counts[database.SeverityCritical] > 0 || counts[database.SeverityWarning] > 0:
+		summary += fmt.Sprintf(": %d critical, %d warnings", counts[database.SeverityCritical], counts[database.SeverityWarning])
+	default:
+		summary += ", nothing needs attention"
+	}
+	return summary + "."
+}
+
+// agentReviewNotification lists what needs attention, or returns "" if nothing does
+func agentReviewNotification(findings []database.AgentFinding, actions []database.AgentAction) string {
+	var b strings.Builder
+	sorted := append([]database.AgentFinding(nil), findings...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Severity == database.SeverityCritical && sorted[j].Severity != database.SeverityCritical
+	})
+	for _, finding := range sorted {
+		if finding.Severity != database.SeverityWarning && finding.Severity != database.SeverityCritical {
+			continue
+		}
+		subject := finding.App
+		if subject == "" {
+			subject = "Node"
+		}
+		fmt.Fprintf(&b, "- [%s] %s: %s\n", finding.Severity, subject, finding.Summary)
+	}
+	if len(actions) > 0 {
+		b.WriteString("\nSuggested actions waiting for approval in Settings:\n")
+		for _, action := range actions {
+			fmt.Fprintf(&b, "- Restart %s: %s\n", action.AppName, action.Reason)
+		}
+	}
+	return b.String()
+}
+
+// executeAgentAction runs an approved action and records the outcome
+func (s *Server) executeAgentAction(action database.AgentAction) {
+	status, result := database.ActionStatusCompleted, ""
+	switch action.Action {
+	case database.ActionRestartApp:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		output, err := s.appAgentTools(action.AppName)["restart_app"].Run(ctx, nil)
+		cancel()
+		result = output
+		if err != nil {
+			status, result = database.ActionStatusFailed, err.Error()
+		}
+	default:
+		status, result = database.ActionStatusFailed, fmt.Sprintf("unknown action %q", action.Action)
+	}
+	if err := database.FinishAgentAction(action.ID, status, result); err != nil {
+		logging.Errorf("Failed to store result of agent action #%d: %v", action.ID, err)
+	}
+}
+
+// routeAPIAgent handles /api/agent/reviews[/{id}], /api/agent/actions[/{id}/{approve|reject}]
+// and /api/agent/memory/{export|import}
+func (s *Server) routeAPIAgent(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/agent/"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "reviews":
+		s.handleAgentReviews(w, r)
+	case len(parts) == 2 && parts[0] == "reviews":
+		s.handleAgentReview(w, r, parts[1])
+	case len(parts) == 1 && parts[0] == "actions":
+		s.handleAgentActions(w, r)
+	case len(parts) == 3 && parts[0] == "actions":
+		s.handleAgentActionDecision(w, r, parts[1], parts[2], user.Username)
+	case len(parts) == 2 && parts[0] == "memory" && parts[1] == "export":
+		s.handleAgentMemoryExport(w, r)
+	case len(parts) == 2 && parts[0] == "memory" && parts[1] == "import":
+		s.handleAgentMemoryImport(w, r, user.Username)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleAgentReviews lists reviews (GET) or starts one in the background (POST)
+func (s *Server) handleAgentReviews(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		reviews, err := database.GetAgentReviews(agentReviewPageSize)
+		if err != nil {
+			logging.Errorf("Failed to get agent reviews: %v", err)
+			http.Error(w, "Failed to get reviews", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"reviews": reviews}); err != nil {
+			logging.Errorf("Failed to encode agent reviews: %v", err)
+		}
+	case http.MethodPost:
+		llm, ok := s.agentLLM()
+		if !ok {
+			http.Error(w, "The agent LLM is not configured. Set it up in Settings first.", http.StatusServiceUnavailable)
+			return
+		}
+		go func() {
+			if _, err := s.runAgentReview(context.Background(), llm); err != nil && !errors.Is(err, errAgentReviewRunning) {
+				logging.Errorf("Agent review failed: %v", err)
+			}
+		}()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Review started"}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAgentReview(w http.ResponseWriter, r *http.Request, idValue string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(idValue)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	review, err := database.GetAgentReview(id)
+	if err != nil {
+		logging.Errorf("Failed to get agent review %d: %v", id, err)
+		http.Error(w, "Failed to get review", http.StatusInternalServerError)
+		return
+	}
+	if review == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		logging.Errorf("Failed to encode agent review: %v", err)
+	}
+}
+
+// handleAgentActions lists actions, filtered with ?status=pending
+func (s *Server) handleAgentActions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actions, err := database.GetAgentActions(r.URL.Query().Get("status"), agentReviewPageSize)
+	if err != nil {
+		logging.Errorf("Failed to get agent actions: %v", err)
+		http.Error(w, "Failed to get actions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"actions": actions}); err != nil {
+		logging.Errorf("Failed to encode agent actions: %v", err)
+	}
+}
+
+// handleAgentActionDecision approves or rejects a pending action. Approved actions run in
+// the background, their outcome is visible in the action list.
+func (s *Server) handleAgentActionDecision(w http.ResponseWriter, r *http.Request, idValue, decision, username string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(idValue)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var status string
+	switch decision {
+	case "approve":
+		status = database.ActionStatusRunning
+	case "reject":
+		status = database.ActionStatusRejected
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	action, err := database.GetAgentAction(id)
+	if err != nil {
+		logging.Errorf("Failed to get agent action %d: %v", id, err)
+		http.Error(w, "Failed to get action", http.StatusInternalServerError)
+		return
+	}
+	if action == nil {
+		http.NotFound(w, r)
+		return
+	}
+	decided, err := database.DecideAgentAction(id, status, username)
+	if err != nil {
+		logging.Errorf("Failed to decide agent action %d: %v", id, err)
+		http.Error(w, "Failed to update action", http.StatusInternalServerError)
+		return
+	}
+	if !decided {
+		http.Error(w, "The action is no longer pending", http.StatusConflict)
+		return
+	}
+
+	logging.Infof("Agent action #%d (%s %s) %sd by %s", id, action.Action, action.AppName, decision, username)
+	if status == database.ActionStatusRunning {
+		go s.executeAgentAction(*action)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "status": status}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestDiskFillForecast(t *testing.T) {
+	start := time.Now().Add(-4 * 24 * time.Hour)
+	metrics := func(percentPerDay float64) []database.SystemVitalLog {
+		var logs []database.SystemVitalLog
+		for hour := 0; hour <= 96; hour += 4 {
+			logs = append(logs, database.SystemVitalLog{
+				Timestamp:        start.Add(time.Duration(hour) * time.Hour),
+				DiskUsagePercent: 60 + percentPerDay*float64(hour)/24,
+			})
+		}
+		return logs
+	}
+
+	days, ok := diskFillForecast(metrics(2))
+	if !ok || math.Abs(days-16) > 0.01 {
+		t.Errorf("forecast = %.2f days (ok=%v), want 16 days from 68%% at 2%%/day", days, ok)
+	}
+	if _, ok := diskFillForecast(metrics(0)); ok {
+		t.Error("expected no forecast for flat disk usage")
+	}
+	if _, ok := diskFillForecast(metrics(2)[:5]); ok {
+		t.Error("expected no forecast with too little history")
+	}
+}
+
+func TestReviewApp(t *testing.T) {
+	url, requests := scriptedLLM(t,
+		`{"role":"assistant","content":"{\"severity\":\"warning\",\"summary\":\"Repeated cron failures.\",\"suggested_action\":\"restart_app\",\"reason\":\"The worker is stuck.\"}"}`,
+		`{"role":"assistant","content":"{\"severity\":\"bad\",\"summary\":\"\",\"suggested_action\":\"delete_app\"}"}`,
+	)
+	llm := llmConfig{APIURL: url, Model: "test"}
+
+	assessment, err := reviewApp(context.Background(), llm, "nextcloud", "logs")
+	if err != nil {
+		t.Fatalf("reviewApp() error = %v", err)
+	}
+	if assessment.Severity != database.SeverityWarning || assessment.SuggestedAction != database.ActionRestartApp {
+		t.Errorf("assessment = %+v", assessment)
+	}
+	if (*requests)[0][1].Content != "logs" {
+		t.Errorf("evidence not sent to the LLM: %+v", (*requests)[0])
+	}
+
+	assessment, err = reviewApp(context.Background(), llm, "nextcloud", "logs")
+	if err != nil {
+		t.Fatalf("reviewApp() error = %v", err)
+	}
+	if assessment.Severity != database.SeverityInfo || assessment.SuggestedAction != "" || assessment.Summary == "" {
+		t.Errorf("unknown values not normalized: %+v", assessment)
+	}
+}
@@ -12,9 +12,12 @@ import (
 	"regexp"
 	"strings"
 	"time"
-	"github.com/ontree-co/treeos/internal/logging"
 
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
 	"github.com/ontree-co/treeos/internal/progress"
+	"github.com/ontree-co/treeos/internal/secrets"
 	"github.com/ontree-co/treeos/internal/security"
 	"github.com/ontree-co/treeos/internal/systemcheck"
 	"github.com/ontree-co/treeos/internal/yamlutil"
@@ -29,12 +32,14 @@ type CreateAppRequest struct {
 	Name        string `json:"name"`
 	ComposeYAML string `json:"compose_yaml"`
 	EnvContent  string `json:"env_content,omitempty"`
+	AssignPorts bool   `json:"assign_ports,omitempty"` // Move taken host ports to free ones instead of failing
 }
 
 // UpdateAppRequest represents the request body for updating an existing app
 type UpdateAppRequest struct {
 	ComposeYAML string `json:"compose_yaml"`
 	EnvContent  string `json:"env_content,omitempty"`
+	AssignPorts bool   `json:"assign_ports,omitempty"` // Move taken host ports to free ones instead of failing
 }
 
 // AppStatusResponse represents the response for app status endpoint
@@ -53,7 +58,9 @@ type ServiceStatusDetail struct {
 	Image         string   `json:"image"`
 	Status        string   `json:"status"`
 	State         string   `json:"state,omitempty"`
+	Health        string   `json:"health,omitempty"` // "healthy", "unhealthy" or "starting", empty without a health check
 	Ports         []string `json:"ports,omitempty"`
+	CPUSet        string   `json:"cpuset,omitempty"` // CPUs the service is pinned to in the compose file
 	Error         string   `json:"error,omitempty"`
 }
 
@@ -74,6 +81,10 @@ func (s *Server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
 	var req CreateAppRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w, s.bodyLimit(r.URL.Path))
+			return
+		}
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
@@ -107,6 +118,18 @@ func (s *Server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.ComposeYAML, err = s.newAppNamespace(getUserFromContext(r.Context()), req.Name, req.ComposeYAML)
+	if err != nil {
+		http.Error(w, err.Error(), namespaceErrorStatus(err))
+		return
+	}
+
+	req.ComposeYAML, req.EnvContent, err = s.claimHostPorts(req.Name, req.ComposeYAML, req.EnvContent, req.AssignPorts)
+	if err != nil {
+		http.Error(w, err.Error(), portErrorStatus(err))
+		return
+	}
+
 	// Create app directory structure
 	appDir := filepath.Join(s.config.AppsDir, req.Name)
 	mountDir := filepath.Join(s.config.AppsDir, "mount", req.Name)
@@ -143,10 +166,18 @@ func (s *Server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Write .env file if provided
+	// Write .env file if provided, sensitive values go to the secrets store
 	if req.EnvContent != "" {
+		envContent, err := s.sealEnvSecrets(req.Name, req.EnvContent)
+		if err != nil {
+			logging.Errorf("Failed to store secrets of app %s: %v", req.Name, err)
+			os.RemoveAll(appDir)   //nolint:errcheck,gosec // Best effort cleanup
+			os.RemoveAll(mountDir) //nolint:errcheck,gosec // Best effort cleanup
+			http.Error(w, "Failed to store secrets", http.StatusInternalServerError)
+			return
+		}
 		envFile := filepath.Join(appDir, ".env")
-		if err := os.WriteFile(envFile, []byte(req.EnvContent), 0600); err != nil { // #nosec G306 - env files need to be readable
+		if err := os.WriteFile(envFile, []byte(envContent), 0600); err != nil { // #nosec G306 - env files need to be readable
 			logging.Errorf("Failed to write .env file: %v", err)
 			// Clean up
 			os.RemoveAll(appDir)   //nolint:errcheck,gosec // Best effort cleanup
@@ -232,8 +263,7 @@ func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -244,6 +274,10 @@ func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
 	var req UpdateAppRequest
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w, s.bodyLimit(r.URL.Path))
+			return
+		}
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
@@ -273,6 +307,12 @@ func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.ComposeYAML, req.EnvContent, err = s.claimHostPorts(appName, req.ComposeYAML, req.EnvContent, req.AssignPorts)
+	if err != nil {
+		http.Error(w, err.Error(), portErrorStatus(err))
+		return
+	}
+
 	// Write docker-compose.yml
 	composeFile := filepath.Join(appDir, "docker-compose.yml")
 	if err := os.WriteFile(composeFile, []byte(req.ComposeYAML), 0600); err != nil { // #nosec G306 - compose files need to be readable
@@ -284,8 +324,14 @@ func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
 	// Handle .env file
 	envFile := filepath.Join(appDir, ".env")
 	if req.EnvContent != "" {
-		// Write or update .env file
-		if err := os.WriteFile(envFile, []byte(req.EnvContent), 0600); err != nil { // #nosec G306 - env files need to be readable
+		// Write or update .env file, sensitive values go to the secrets store
+		envContent, err := s.sealEnvSecrets(appName, req.EnvContent)
+		if err != nil {
+			logging.Errorf("Failed to store secrets of app %s: %v", appName, err)
+			http.Error(w, "Failed to store secrets", http.StatusInternalServerError)
+			return
+		}
+		if err := os.WriteFile(envFile, []byte(envContent), 0600); err != nil { // #nosec G306 - env files need to be readable
 			logging.Errorf("Failed to write .env file: %v", err)
 			http.Error(w, "Failed to write .env file", http.StatusInternalServerError)
 			return
@@ -315,7 +361,8 @@ func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleAPIAppStart handles POST /api/apps/{appName}/start
+// handleAPIAppStart handles POST /api/apps/{appName}/start. Apps the app depends on are
+// started before it and need to be healthy.
 func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -323,8 +370,7 @@ func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/start")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -377,8 +423,26 @@ func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 		logging.Infof("SECURITY: Bypassing security validation for app '%s' (user-configured)", appName)
 	}
 
+	if unavailable := unavailableBindings(yamlContent); len(unavailable) > 0 {
+		http.Error(w, fmt.Sprintf("Ports are bound to addresses this host doesn't have: %s. Choose another interface under Network Interfaces.", strings.Join(unavailable, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	// Apps declared in depends_on of app.yml are started first
+	dependencies, err := dependenciesOf(s.dependencyGraphOrEmpty(), appName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid app dependencies: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	// Initialize progress tracking
-	s.progressTracker.StartOperation(appName, progress.OperationPreparing, "Preparing to start containers...")
+	message := "Preparing to start containers..."
+	if len(dependencies) > 0 {
+		message = fmt.Sprintf("Waiting for %s to be healthy...", strings.Join(dependencies, ", "))
+	}
+	s.progressTracker.StartOperation(appName, progress.OperationPreparing, message)
+	// The containers are created from the saved files, nothing is left to apply
+	s.pendingEdits.clear(appName)
 
 	// Start the app using compose SDK with progress tracking
 	// Use background context with no timeout - user can cancel via UI if needed
@@ -403,18 +467,16 @@ func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 		parser.ParseLine(appName, line)
 
 		// Send SSE update after each progress update
-		if progressInfo, exists := s.progressTracker.GetProgress(appName); exists && s.sseManager != nil {
-			progressData := map[string]interface{}{
-				"type":     "progress",
-				"progress": progressInfo,
-			}
-			s.sseManager.BroadcastMessage("app-progress-"+appName, progressData)
-		}
+		s.broadcastAppProgress(appName, "progress")
 	}
 
 	// Start the compose project with progress tracking
 	startChan := make(chan error, 1)
 	go func() {
+		if err := s.startDependencies(ctx, appName); err != nil {
+			startChan <- err
+			return
+		}
 		startChan <- composeSvc.UpWithProgress(ctx, opts, progressCallback)
 	}()
 
@@ -427,17 +489,12 @@ func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 			s.progressTracker.SetError(appName, err.Error())
 
 			// Send SSE error update
-			if progressInfo, exists := s.progressTracker.GetProgress(appName); exists && s.sseManager != nil {
-				progressData := map[string]interface{}{
-					"type":     "error",
-					"progress": progressInfo,
-				}
-				s.sseManager.BroadcastMessage("app-progress-"+appName, progressData)
-			}
+			s.broadcastAppProgress(appName, "error")
 
 			if isRuntimeUnavailableError(err) {
 				s.markComposeUnhealthy()
 			}
+			s.notifyAppStartFailed(appName, err)
 			http.Error(w, fmt.Sprintf("Failed to start app: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -445,13 +502,7 @@ func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 		s.progressTracker.CompleteOperation(appName, fmt.Sprintf("App '%s' started successfully", appName))
 
 		// Send SSE completion update
-		if progressInfo, exists := s.progressTracker.GetProgress(appName); exists && s.sseManager != nil {
-			progressData := map[string]interface{}{
-				"type":     "complete",
-				"progress": progressInfo,
-			}
-			s.sseManager.BroadcastMessage("app-progress-"+appName, progressData)
-		}
+		s.broadcastAppProgress(appName, "complete")
 	case <-time.After(3 * time.Second):
 		// If it takes more than 3 seconds, return immediately with progress status
 		// The operation continues in the background
@@ -465,29 +516,18 @@ func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 				s.progressTracker.SetError(appName, err.Error())
 
 				// Send SSE error update
-				if progressInfo, exists := s.progressTracker.GetProgress(appName); exists && s.sseManager != nil {
-					progressData := map[string]interface{}{
-						"type":     "error",
-						"progress": progressInfo,
-					}
-					s.sseManager.BroadcastMessage("app-progress-"+appName, progressData)
-				}
+				s.broadcastAppProgress(appName, "error")
 
 				if isRuntimeUnavailableError(err) {
 					s.markComposeUnhealthy()
 				}
+				s.notifyAppStartFailed(appName, err)
 			} else {
 				logging.Infof("Background start completed successfully for app %s", appName)
 				s.progressTracker.CompleteOperation(appName, fmt.Sprintf("App '%s' started successfully", appName))
 
 				// Send SSE completion update
-				if progressInfo, exists := s.progressTracker.GetProgress(appName); exists && s.sseManager != nil {
-					progressData := map[string]interface{}{
-						"type":     "complete",
-						"progress": progressInfo,
-					}
-					s.sseManager.BroadcastMessage("app-progress-"+appName, progressData)
-				}
+				s.broadcastAppProgress(appName, "complete")
 			}
 		}()
 
@@ -523,7 +563,8 @@ func (s *Server) handleAPIAppStart(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleAPIAppStop handles POST /api/apps/{appName}/stop
+// handleAPIAppStop handles POST /api/apps/{appName}/stop. While apps that depend on the app
+// run it answers 409 with the dependents, unless ?force=true is given.
 func (s *Server) handleAPIAppStop(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -531,8 +572,7 @@ func (s *Server) handleAPIAppStop(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/stop")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -559,6 +599,11 @@ func (s *Server) handleAPIAppStop(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := context.Background()
+
+	// Apps depending on this one would break, ?force=true stops it anyway
+	if r.URL.Query().Get("force") != "true" && !s.checkRunningDependents(ctx, w, s.dependencyGraphOrEmpty(), []string{appName}) {
+		return
+	}
 	opts := compose.Options{
 		WorkingDir: appDir,
 	}
@@ -569,9 +614,16 @@ func (s *Server) handleAPIAppStop(w http.ResponseWriter, r *http.Request) {
 		if isRuntimeUnavailableError(err) {
 			s.markComposeUnhealthy()
 		}
+		s.notifyEvent(notify.Event{
+			Type:    notify.EventAppStopFailed,
+			Title:   fmt.Sprintf("App %s failed to stop", appName),
+			Message: err.Error(),
+			App:     appName,
+		})
 		http.Error(w, fmt.Sprintf("Failed to stop app: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.pendingEdits.clear(appName)
 
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
@@ -597,8 +649,7 @@ func (s *Server) handleAPIAppDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -652,6 +703,37 @@ func (s *Server) handleAPIAppDelete(w http.ResponseWriter, r *http.Request) {
 		// Continue, as this is not critical
 	}
 
+	s.removeInternalRoute(appName)
+
+	// Drop generated credentials and stored secrets along with the app
+	if err := database.DeleteAppCredentials(appName); err != nil {
+		logging.Errorf("Failed to delete credentials for %s: %v", appName, err)
+	}
+	if err := database.DeleteAppSecrets(appName); err != nil {
+		logging.Errorf("Failed to delete secrets for %s: %v", appName, err)
+	}
+	if err := database.DeleteAppEnvSecrets(appName); err != nil {
+		logging.Errorf("Failed to delete .env secrets for %s: %v", appName, err)
+	}
+	if err := database.DeleteLogFilters(appName); err != nil {
+		logging.Errorf("Failed to delete log filters for %s: %v", appName, err)
+	}
+	if err := database.DeleteAppNote(appName); err != nil {
+		logging.Errorf("Failed to delete notes for %s: %v", appName, err)
+	}
+	if err := database.DeleteAppPermissions(appName); err != nil {
+		logging.Errorf("Failed to delete permissions for %s: %v", appName, err)
+	}
+	if err := database.DeleteImageUpdates(appName); err != nil {
+		logging.Errorf("Failed to delete image updates for %s: %v", appName, err)
+	}
+	if err := database.DeleteAppResourceLogs(appName); err != nil {
+		logging.Errorf("Failed to delete resource usage for %s: %v", appName, err)
+	}
+	if err := database.DeleteAppDiskUsage(appName); err != nil {
+		logging.Errorf("Failed to delete disk usage for %s: %v", appName, err)
+	}
+
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -664,16 +746,27 @@ func (s *Server) handleAPIAppDelete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleAPIAppSecurityBypass handles POST /api/apps/{appName}/security-bypass
+// maxJustificationLength limits the reason stored in the security audit log
+const maxJustificationLength = 1000
+
+// handleAPIAppSecurityBypass handles POST /api/apps/{appName}/security-bypass.
+// Bypassing disables all container hardening for an app, so only administrators may
+// toggle it, they have to enter their password again, and every change is recorded in
+// the security audit log with a justification.
 func (s *Server) handleAPIAppSecurityBypass(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsSuperuser {
+		http.Error(w, "Only administrators can change security validation", http.StatusForbidden)
+		return
+	}
+
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/security-bypass")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -682,13 +775,32 @@ func (s *Server) handleAPIAppSecurityBypass(w http.ResponseWriter, r *http.Reque
 
 	// Parse request body
 	var request struct {
-		BypassSecurity bool `json:"bypassSecurity"`
+		BypassSecurity bool   `json:"bypassSecurity"`
+		Password       string `json:"password"`
+		Justification  string `json:"justification"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	// Re-authenticate, a stolen session alone must not be enough to disable hardening
+	if request.Password == "" || s.verifyPassword(user, request.Password) != nil {
+		logging.Warnf("SECURITY: Rejected security bypass change for app '%s' by %s: wrong password", appName, user.Username)
+		http.Error(w, "Password is incorrect", http.StatusUnauthorized)
+		return
+	}
+
+	justification := strings.TrimSpace(request.Justification)
+	if justification == "" {
+		http.Error(w, "A justification is required", http.StatusBadRequest)
+		return
+	}
+	if len(justification) > maxJustificationLength {
+		http.Error(w, fmt.Sprintf("Justification must be at most %d characters", maxJustificationLength), http.StatusBadRequest)
+		return
+	}
+
 	// Check if app exists
 	appDir := filepath.Join(s.config.AppsDir, appName)
 	if _, err := os.Stat(appDir); os.IsNotExist(err) {
@@ -704,6 +816,22 @@ func (s *Server) handleAPIAppSecurityBypass(w http.ResponseWriter, r *http.Reque
 		metadata = &yamlutil.OnTreeMetadata{}
 	}
 
+	// Record the change before applying it, an unaudited bypass is not allowed
+	action := database.SecurityBypassDisabled
+	if request.BypassSecurity {
+		action = database.SecurityBypassEnabled
+	}
+	if err := database.RecordSecurityAudit(database.SecurityAuditEvent{
+		AppName:       appName,
+		Username:      user.Username,
+		Action:        action,
+		Justification: justification,
+	}); err != nil {
+		logging.Errorf("Failed to record security audit event for app %s: %v", appName, err)
+		http.Error(w, "Failed to record the change in the audit log", http.StatusInternalServerError)
+		return
+	}
+
 	// Update the bypass security flag
 	metadata.BypassSecurity = request.BypassSecurity
 
@@ -714,11 +842,10 @@ func (s *Server) handleAPIAppSecurityBypass(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Log the security bypass change for audit purposes
 	if request.BypassSecurity {
-		logging.Infof("SECURITY: Security validation BYPASSED for app '%s'", appName)
+		logging.Infof("SECURITY: Security validation BYPASSED for app '%s' by %s: %s", appName, user.Username, justification)
 	} else {
-		logging.Infof("SECURITY: Security validation ENABLED for app '%s'", appName)
+		logging.Infof("SECURITY: Security validation ENABLED for app '%s' by %s: %s", appName, user.Username, justification)
 	}
 
 	// Return success response
@@ -741,8 +868,7 @@ func (s *Server) handleAPIAppStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/status")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -796,6 +922,7 @@ func (s *Server) handleAPIAppStatus(w http.ResponseWriter, r *http.Request) {
 
 	// Process container information into service status
 	services := make([]ServiceStatusDetail, 0)
+	cpusets := s.appCPUSets(appName)
 	for _, container := range containers {
 		// Extract service name from container name
 		// Container names follow the pattern: {appName}-{serviceName}-{index}
@@ -810,11 +937,13 @@ func (s *Server) handleAPIAppStatus(w http.ResponseWriter, r *http.Request) {
 			Image:         container.Image,
 			Status:        status,
 			State:         container.State,
+			Health:        containerHealth(container.Health),
+			CPUSet:        cpusets[container.Service],
 		}
 
 		// Add health status if available
-		if container.Health != "" && container.Health != "none" {
-			service.State = fmt.Sprintf("%s (health: %s)", container.State, container.Health)
+		if service.Health != "" {
+			service.State = fmt.Sprintf("%s (health: %s)", container.State, service.Health)
 		}
 
 		// Add port information
@@ -889,7 +1018,38 @@ func mapContainerState(state string) string {
 	}
 }
 
+// containerHealth returns the result of a container's health check, "" if it has none
+func containerHealth(health string) string {
+	switch health = strings.ToLower(health); health {
+	case "healthy", "unhealthy", "starting":
+		return health
+	default:
+		return ""
+	}
+}
+
+// healthStatus refines the status of an app whose services all run by their health
+// checks: "degraded" if one reports unhealthy, "starting" while one is still in its
+// start period. Other statuses are returned unchanged.
+func healthStatus(status string, services []ServiceStatusDetail) string {
+	if status != "running" {
+		return status
+	}
+	for _, svc := range services {
+		if svc.Health == "unhealthy" {
+			return "degraded"
+		}
+	}
+	for _, svc := range services {
+		if svc.Health == "starting" {
+			return "starting"
+		}
+	}
+	return status
+}
+
 // calculateAggregateStatus calculates the overall app status based on service statuses
+// and their health checks
 func calculateAggregateStatus(services []ServiceStatusDetail) string {
 	if len(services) == 0 {
 		return "stopped"
@@ -906,7 +1066,7 @@ func calculateAggregateStatus(services []ServiceStatusDetail) string {
 
 	// Determine aggregate status
 	if runningCount == totalCount {
-		return "running"
+		return healthStatus("running", services)
 	}
 	if runningCount == 0 {
 		return "stopped"
@@ -922,8 +1082,7 @@ func (s *Server) handleGetApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -964,7 +1123,7 @@ func (s *Server) handleGetApp(w http.ResponseWriter, r *http.Request) {
 		"app": map[string]interface{}{
 			"name":         appName,
 			"compose_yaml": string(composeContent),
-			"env_content":  string(envContent),
+			"env_content":  string(secrets.Mask(envContent)), // Sensitive values stay in the secrets store
 		},
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -980,8 +1139,7 @@ func (s *Server) handleAPIAppLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/logs")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -1094,8 +1252,7 @@ func (s *Server) handleAPIAppProgress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/progress")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
@@ -1158,14 +1315,18 @@ func (s *Server) handleAPIAppProgressSSE(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/apps/")
-	appName := strings.TrimSuffix(path, "/progress/sse")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name is required", http.StatusBadRequest)
 		return
 	}
 
+	if isSSEPoll(r) {
+		s.serveSSEPoll(w, r, "app-progress-"+appName, func() []string { return s.initialProgressEvents(appName) })
+		return
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -1176,8 +1337,8 @@ func (s *Server) handleAPIAppProgressSSE(w http.ResponseWriter, r *http.Request)
 	// Create SSE client with larger buffer for better reliability
 	client := &SSEClient{
 		AppID:    "app-progress-" + appName,
-		Messages: make(chan string, 256), // Increased buffer size
-		Close:    make(chan bool, 1),     // Buffered close channel
+		Messages: make(chan string, s.sseBufferSize()),
+		Close:    make(chan bool, 1), // Buffered close channel
 	}
 
 	// Register client with SSE manager
@@ -1200,44 +1361,11 @@ func (s *Server) handleAPIAppProgressSSE(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Check if container is already running - if so, don't send stale progress data
-	appDir := filepath.Join(s.config.AppsDir, appName)
-	if composeSvc, err := s.getComposeService(); err == nil {
-		opts := compose.Options{WorkingDir: appDir}
-		if containers, err := composeSvc.PS(context.Background(), opts); err == nil {
-			// If any container is running, clear stale progress and don't send initial data
-			isRunning := false
-			for _, container := range containers {
-				if container.State == "running" {
-					isRunning = true
-					break
-				}
-			}
-			if isRunning {
-				// Clear any stale progress data
-				s.progressTracker.RemoveOperation(appName)
-				logging.Infof("Cleared stale progress data for running app: %s", appName)
-			}
-		}
-	}
-
-	// Send initial progress state only if operation is active
-	if progressInfo, exists := s.progressTracker.GetProgress(appName); exists {
-		// Only send if operation is not complete or error
-		if progressInfo.Operation != progress.OperationComplete && progressInfo.Operation != progress.OperationError {
-			initialData := map[string]interface{}{
-				"type":     "progress",
-				"progress": progressInfo,
-			}
-			if jsonData, err := json.Marshal(initialData); err == nil {
-				fmt.Fprintf(w, "event: progress\ndata: %s\n\n", string(jsonData)) //nolint:errcheck // SSE stream
-				flusher.Flush()
-			}
-		} else {
-			// Clear completed/error operations that are stale
-			s.progressTracker.RemoveOperation(appName)
-		}
+	for _, event := range s.initialProgressEvents(appName) {
+		fmt.Fprint(w, event) //nolint:errcheck // SSE stream
 	}
+	// Send the headers now, so that the client sees the stream open before the first update
+	flusher.Flush()
 
 	// Handle client disconnect
 	ctx := r.Context()
@@ -1270,3 +1398,48 @@ func (s *Server) handleAPIAppProgressSSE(w http.ResponseWriter, r *http.Request)
 		}
 	}
 }
+
+// initialProgressEvents returns the progress event a new stream of an app starts with, none
+// if no operation is running. Progress left over from operations of a running app is cleared.
+func (s *Server) initialProgressEvents(appName string) []string {
+	// Check if container is already running - if so, don't send stale progress data
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if composeSvc, err := s.getComposeService(); err == nil {
+		opts := compose.Options{WorkingDir: appDir}
+		if containers, err := composeSvc.PS(context.Background(), opts); err == nil {
+			// If any container is running, clear stale progress and don't send initial data
+			isRunning := false
+			for _, container := range containers {
+				if container.State == "running" {
+					isRunning = true
+					break
+				}
+			}
+			if isRunning {
+				// Clear any stale progress data
+				s.progressTracker.RemoveOperation(appName)
+				logging.Infof("Cleared stale progress data for running app: %s", appName)
+			}
+		}
+	}
+
+	progressInfo, exists := s.progressTracker.GetProgress(appName)
+	if !exists {
+		return nil
+	}
+	// Only send if operation is not complete or error
+	if progressInfo.Operation == progress.OperationComplete || progressInfo.Operation == progress.OperationError {
+		// Clear completed/error operations that are stale
+		s.progressTracker.RemoveOperation(appName)
+		return nil
+	}
+	initialData := map[string]interface{}{
+		"type":     "progress",
+		"progress": progressInfo,
+	}
+	jsonData, err := json.Marshal(initialData)
+	if err != nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("event: progress\ndata: %s\n\n", string(jsonData))}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/configbundle"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// maxConfigBundleSize limits uploaded configuration bundles
+const maxConfigBundleSize = 5 << 20
+
+// handleConfigExport downloads the node configuration as a YAML bundle
+func (s *Server) handleConfigExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bundle, err := configbundle.Export(s.config.AppsDir)
+	if err != nil {
+		logging.Errorf("Failed to export configuration: %v", err)
+		http.Error(w, "Failed to export configuration", http.StatusInternalServerError)
+		return
+	}
+	data, err := configbundle.Marshal(bundle)
+	if err != nil {
+		logging.Errorf("Failed to export configuration: %v", err)
+		http.Error(w, "Failed to export configuration", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "treeos-config-" + time.Now().Format("20060102") + ".yaml"
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if _, err := w.Write(data); err != nil {
+		logging.Errorf("Failed to write configuration export: %v", err)
+	}
+}
+
+// handleConfigImport applies an uploaded YAML bundle. With ?dry_run=true it only reports
+// the changes, otherwise the request must confirm with ?confirm=true.
+func (s *Server) handleConfigImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	if !dryRun && r.URL.Query().Get("confirm") != "true" {
+		http.Error(w, "Importing a configuration must be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigBundleSize))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w, maxConfigBundleSize)
+			return
+		}
+		http.Error(w, "Failed to read configuration bundle", http.StatusBadRequest)
+		return
+	}
+	bundle, err := configbundle.Parse(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := configbundle.Import(s.config.AppsDir, bundle, dryRun)
+	if err != nil {
+		logging.Errorf("Failed to import configuration: %v", err)
+		http.Error(w, "Failed to import configuration: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !dryRun && len(result.Changes) > 0 {
+		logging.Infof("User %s imported a configuration bundle with %d changes", user.Username, len(result.Changes))
+		if err := s.loadConfigFromDatabase(); err != nil {
+			logging.Warnf("Failed to reload settings after import: %v", err)
+		}
+		if result.AppsChanged && s.caddyAvailable {
+			s.syncExposedApps()
+		}
+		if result.SharesChanged {
+			if err := s.applyShares(context.Background()); err != nil {
+				result.Warnings = append(result.Warnings, "Shares were imported but Samba was not updated: "+err.Error())
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logging.Errorf("Failed to encode import result: %v", err)
+	}
+}
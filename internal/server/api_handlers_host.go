@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/hostupdates"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// HostUpdateApplyState tracks the last "apply security updates" run
+type HostUpdateApplyState struct {
+	InProgress bool      `json:"in_progress"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// HostUpdatesResponse is returned by GET /api/system/host-updates
+type HostUpdatesResponse struct {
+	*hostupdates.Status
+	Apply HostUpdateApplyState `json:"apply"`
+}
+
+// handleHostUpdates reports pending OS package updates of the host
+func (s *Server) handleHostUpdates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := hostupdates.Check(r.Context())
+	if err != nil {
+		logging.Errorf("Failed to check host updates: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "Failed to check host updates",
+			"details": err.Error(),
+		}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+		return
+	}
+
+	s.hostUpdateMu.Lock()
+	resp := HostUpdatesResponse{Status: status, Apply: s.hostUpdateApply}
+	s.hostUpdateMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.Errorf("Failed to encode host updates: %v", err)
+	}
+}
+
+// handleHostUpdatesApply installs pending OS security updates in the background.
+// The request must explicitly confirm the action with {"confirm": true}.
+func (s *Server) handleHostUpdatesApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only admins can modify the host
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Confirm bool `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Confirm {
+		http.Error(w, "Applying host updates must be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	if hostupdates.DetectManager() == "" {
+		http.Error(w, hostupdates.ErrUnsupported.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	s.hostUpdateMu.Lock()
+	if s.hostUpdateApply.InProgress {
+		s.hostUpdateMu.Unlock()
+		http.Error(w, "Host updates are already being applied", http.StatusConflict)
+		return
+	}
+	s.hostUpdateApply = HostUpdateApplyState{InProgress: true, StartedAt: time.Now()}
+	s.hostUpdateMu.Unlock()
+
+	logging.Infof("User %s started applying host security updates", user.Username)
+
+	go func() {
+		output, err := hostupdates.ApplySecurityUpdates(context.Background())
+
+		s.hostUpdateMu.Lock()
+		s.hostUpdateApply.InProgress = false
+		s.hostUpdateApply.FinishedAt = time.Now()
+		s.hostUpdateApply.Output = output
+		if err != nil {
+			s.hostUpdateApply.Error = err.Error()
+		}
+		s.hostUpdateMu.Unlock()
+
+		if err != nil {
+			logging.Errorf("Failed to apply host security updates: %v", err)
+		} else {
+			logging.Infof("Host security updates applied")
+		}
+		if s.sseManager != nil {
+			s.sseManager.SendToAll("host-updates", map[string]interface{}{
+				"success": err == nil,
+			})
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Applying security updates",
+	}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
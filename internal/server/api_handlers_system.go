@@ -3,6 +3,7 @@ package server
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/ontree-co/treeos/internal/logging"
 
 	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/notify"
 	"github.com/ontree-co/treeos/internal/update"
 )
 
@@ -23,7 +25,7 @@ func (s *Server) handleSystemUpdateCheck(w http.ResponseWriter, r *http.Request)
 	channel := s.getUpdateChannel()
 
 	// Create update service
-	updateSvc := update.NewService(channel)
+	updateSvc := s.updateService(channel)
 
 	// Check for updates
 	updateInfo, err := updateSvc.CheckForUpdate()
@@ -46,6 +48,47 @@ func (s *Server) handleSystemUpdateCheck(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// ReleaseNotesResponse is returned by GET /api/system/update/release-notes
+type ReleaseNotesResponse struct {
+	Channel        string                `json:"channel"`
+	CurrentVersion string                `json:"current_version"`
+	LatestVersion  string                `json:"latest_version"`
+	Releases       []update.ReleaseNotes `json:"releases"` // Newer than the running version, newest first
+	Breaking       int                   `json:"breaking"` // Breaking changes of all releases
+}
+
+// handleSystemUpdateReleaseNotes returns the release notes of the releases of the channel
+// between the running version and the latest one
+func (s *Server) handleSystemUpdateReleaseNotes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channel := s.getUpdateChannel()
+	updateSvc := s.updateService(channel)
+	latest, releases, err := updateSvc.ReleaseNotes()
+	if err != nil {
+		logging.Errorf("Failed to get release notes: %v", err)
+		http.Error(w, "Failed to get release notes", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := ReleaseNotesResponse{
+		Channel:        string(channel),
+		CurrentVersion: updateSvc.GetCurrentVersion(),
+		LatestVersion:  latest,
+		Releases:       releases,
+	}
+	for _, release := range releases {
+		response.Breaking += len(release.Breaking)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
 // handleSystemUpdateApply applies a system update
 func (s *Server) handleSystemUpdateApply(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -60,10 +103,37 @@ func (s *Server) handleSystemUpdateApply(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The body is optional, {"force": true} updates despite compatibility errors
+	var req struct {
+		Force bool `json:"force"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
 	channel := s.getUpdateChannel()
 
 	// Create update service
-	updateSvc := update.NewService(channel)
+	updateSvc := s.updateService(channel)
+
+	// Check the apps before anything is downloaded, breakage is easier to fix before the restart
+	compatibility, err := s.updateCompatibility(updateSvc)
+	if err != nil {
+		logging.Warnf("Failed to check update compatibility: %v", err)
+	} else if compatibility.Blocking && !req.Force {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":         "The update is not compatible with this node, see the compatibility report",
+			"compatibility": compatibility,
+		}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+		return
+	}
 
 	// Record update attempt in history (if table exists)
 	var historyID int64
@@ -97,6 +167,7 @@ func (s *Server) handleSystemUpdateApply(w http.ResponseWriter, r *http.Request)
 	go func() {
 		s.updateMu.Lock()
 		defer s.updateMu.Unlock()
+		s.backupBeforeMigrations(compatibility)
 		// Apply the update
 		err := updateSvc.ApplyUpdate(func(stage string, percentage float64, message string) {
 			// Log progress
@@ -188,6 +259,11 @@ func (s *Server) handleSystemUpdateApply(w http.ResponseWriter, r *http.Request)
 		})
 
 		logging.Info("Update applied successfully, system will restart...")
+		s.notifyEvent(notify.Event{
+			Type:    notify.EventUpdateApplied,
+			Title:   "TreeOS update installed",
+			Message: fmt.Sprintf("%s installed the update, TreeOS restarts now.", user.Username),
+		})
 
 		// Send success notification
 		if s.sseManager != nil {
@@ -13,6 +13,8 @@ import (
 	"testing"
 
 	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/yamlutil"
 	"github.com/ontree-co/treeos/pkg/compose"
 )
 
@@ -317,7 +319,7 @@ services:
 			}
 
 			// Create request
-			req := httptest.NewRequest("PUT", "/api/apps/"+tt.appName, bytes.NewReader(reqBody))
+			req := newAppRequest("PUT", "/api/apps/"+tt.appName, bytes.NewReader(reqBody))
 			w := httptest.NewRecorder()
 
 			// Handle request
@@ -381,7 +383,7 @@ services:
 		}
 
 		reqBody, _ := json.Marshal(req)
-		httpReq := httptest.NewRequest("PUT", "/api/apps/"+existingAppName, bytes.NewReader(reqBody))
+		httpReq := newAppRequest("PUT", "/api/apps/"+existingAppName, bytes.NewReader(reqBody))
 		w := httptest.NewRecorder()
 
 		s.handleUpdateApp(w, httpReq)
@@ -416,7 +418,7 @@ func TestHTTPMethods(t *testing.T) {
 	})
 
 	t.Run("Update endpoint - wrong method", func(t *testing.T) {
-		req := httptest.NewRequest("POST", "/api/apps/test-app", nil)
+		req := newAppRequest("POST", "/api/apps/test-app", nil)
 		w := httptest.NewRecorder()
 		s.handleUpdateApp(w, req)
 
@@ -586,7 +588,7 @@ services:
 			tt.setupFiles()
 
 			// Create request
-			req := httptest.NewRequest(tt.method, fmt.Sprintf("/api/apps/%s/start", tt.appName), nil)
+			req := newAppRequest(tt.method, fmt.Sprintf("/api/apps/%s/start", tt.appName), nil)
 			w := httptest.NewRecorder()
 
 			// Handle request
@@ -666,7 +668,7 @@ func TestHandleAPIAppStop(t *testing.T) {
 			tt.setupFiles()
 
 			// Create request
-			req := httptest.NewRequest(tt.method, fmt.Sprintf("/api/apps/%s/stop", tt.appName), nil)
+			req := newAppRequest(tt.method, fmt.Sprintf("/api/apps/%s/stop", tt.appName), nil)
 			w := httptest.NewRecorder()
 
 			// Handle request
@@ -748,7 +750,7 @@ func TestHandleAPIAppDelete(t *testing.T) {
 			tt.setupFiles()
 
 			// Create request
-			req := httptest.NewRequest(tt.method, fmt.Sprintf("/api/apps/%s", tt.appName), nil)
+			req := newAppRequest(tt.method, fmt.Sprintf("/api/apps/%s", tt.appName), nil)
 			w := httptest.NewRecorder()
 
 			// Handle request
@@ -829,7 +831,7 @@ func TestHandleAPIAppStatus(t *testing.T) {
 			tt.setupFiles()
 
 			// Create request
-			req := httptest.NewRequest(tt.method, fmt.Sprintf("/api/apps/%s/status", tt.appName), nil)
+			req := newAppRequest(tt.method, fmt.Sprintf("/api/apps/%s/status", tt.appName), nil)
 			req.Header.Set("Accept", "application/json")
 			w := httptest.NewRecorder()
 
@@ -904,6 +906,30 @@ func TestStatusAggregationLogic(t *testing.T) {
 			},
 			expectedStatus: "partial",
 		},
+		{
+			name: "Degraded - one unhealthy",
+			services: []ServiceStatusDetail{
+				{Name: "web", Status: "running", Health: "starting"},
+				{Name: "db", Status: "running", Health: "unhealthy"},
+			},
+			expectedStatus: "degraded",
+		},
+		{
+			name: "Starting - health check in start period",
+			services: []ServiceStatusDetail{
+				{Name: "web", Status: "running", Health: "starting"},
+				{Name: "db", Status: "running"},
+			},
+			expectedStatus: "starting",
+		},
+		{
+			name: "Partial - unhealthy and stopped",
+			services: []ServiceStatusDetail{
+				{Name: "web", Status: "running", Health: "unhealthy"},
+				{Name: "db", Status: "stopped"},
+			},
+			expectedStatus: "partial",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1243,3 +1269,66 @@ func TestExtractServiceNameFromContainers(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleAPIAppSecurityBypass(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	appDir := filepath.Join(tmpDir, "homeassistant")
+	os.MkdirAll(appDir, 0755) //nolint:errcheck,gosec // Test setup
+	composeContent := "services:\n  web:\n    image: homeassistant/home-assistant:stable\n"
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(composeContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &database.User{Username: "admin", Password: hash, IsStaff: true, IsSuperuser: true}
+	staff := &database.User{Username: "staff", Password: hash, IsStaff: true}
+
+	s := &Server{config: &config.Config{AppsDir: tmpDir}}
+
+	tests := []struct {
+		name           string
+		user           *database.User
+		body           string
+		expectedStatus int
+	}{
+		{"Non-admin rejected", staff, `{"bypassSecurity": true, "password": "correct horse", "justification": "host network"}`, http.StatusForbidden},
+		{"Missing password", admin, `{"bypassSecurity": true, "justification": "host network"}`, http.StatusUnauthorized},
+		{"Wrong password", admin, `{"bypassSecurity": true, "password": "wrong", "justification": "host network"}`, http.StatusUnauthorized},
+		{"Missing justification", admin, `{"bypassSecurity": true, "password": "correct horse", "justification": "  "}`, http.StatusBadRequest},
+		{"Bypass enabled", admin, `{"bypassSecurity": true, "password": "correct horse", "justification": "host network"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newAppRequest(http.MethodPost, "/api/apps/homeassistant/security-bypass", strings.NewReader(tt.body))
+			req = req.WithContext(setUserContext(req.Context(), tt.user))
+			w := httptest.NewRecorder()
+
+			s.handleAPIAppSecurityBypass(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	events, err := database.ListSecurityAudit("homeassistant", 10)
+	if err != nil {
+		t.Fatalf("ListSecurityAudit() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Action != database.SecurityBypassEnabled || events[0].Username != "admin" || events[0].Justification != "host network" {
+		t.Errorf("audit log = %+v, want one bypass_enabled entry by admin", events)
+	}
+	metadata, err := yamlutil.ReadComposeMetadata(appDir)
+	if err != nil || !metadata.BypassSecurity {
+		t.Errorf("bypass not stored in metadata: %+v, %v", metadata, err)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	containerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/tuning"
+)
+
+// TuningResponse is returned by GET /api/system/tuning
+type TuningResponse struct {
+	Supported       bool                    `json:"supported"`
+	Recommendations []tuning.Recommendation `json:"recommendations"`
+	Applied         map[string]uint64       `json:"applied"` // Changed settings with their previous values
+}
+
+// appImages returns the images of each app's services
+func appImages(apps ...*containerruntime.App) map[string][]string {
+	images := make(map[string][]string, len(apps))
+	for _, app := range apps {
+		for _, service := range app.Services {
+			images[app.Name] = append(images[app.Name], service.Image)
+		}
+	}
+	return images
+}
+
+// tuningRecommendations checks the kernel settings against all installed apps
+func (s *Server) tuningRecommendations() ([]tuning.Recommendation, error) {
+	apps, err := s.scanApps()
+	if err != nil {
+		return nil, err
+	}
+	return tuning.Check(appImages(apps...))
+}
+
+// tuningWarnings describes kernel settings that are too low for one app
+func tuningWarnings(app *containerruntime.App) []string {
+	recommendations, err := tuning.Check(appImages(app))
+	if err != nil {
+		return nil
+	}
+	warnings := make([]string, 0, len(recommendations))
+	for _, rec := range recommendations {
+		warnings = append(warnings, fmt.Sprintf("Kernel setting %s is %d but this app needs at least %d. Apply the recommended tuning in Settings.",
+			rec.Key, rec.Current, rec.Recommended))
+	}
+	return warnings
+}
+
+// handleTuning reports kernel settings that are too low for the installed apps
+func (s *Server) handleTuning(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeTuningStatus(w)
+}
+
+// writeTuningStatus writes the current recommendations and applied settings
+func (s *Server) writeTuningStatus(w http.ResponseWriter) {
+	resp := TuningResponse{Supported: true, Recommendations: []tuning.Recommendation{}}
+	recommendations, err := s.tuningRecommendations()
+	switch {
+	case errors.Is(err, tuning.ErrUnsupported):
+		resp.Supported = false
+	case err != nil:
+		logging.Errorf("Failed to check kernel tuning: %v", err)
+		http.Error(w, "Failed to check kernel tuning", http.StatusInternalServerError)
+		return
+	default:
+		resp.Recommendations = recommendations
+	}
+
+	if applied, err := tuning.Applied(); err == nil {
+		resp.Applied = applied
+	} else {
+		logging.Warnf("Failed to read applied kernel tuning: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.Errorf("Failed to encode kernel tuning: %v", err)
+	}
+}
+
+// handleTuningApply applies the recommended kernel settings (action "apply") or restores the
+// previous ones (action "rollback"). The request must confirm the action with {"confirm": true}.
+func (s *Server) handleTuningApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only admins can modify the host
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Action  string `json:"action"`
+		Confirm bool   `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Confirm {
+		http.Error(w, "Changing kernel settings must be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Action {
+	case "apply":
+		recommendations, err := s.tuningRecommendations()
+		if err == nil {
+			err = tuning.Apply(recommendations)
+		}
+		if err != nil {
+			logging.Errorf("Failed to apply kernel tuning: %v", err)
+			http.Error(w, "Failed to apply kernel tuning: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, rec := range recommendations {
+			logging.Infof("User %s raised %s from %d to %d for %v", user.Username, rec.Key, rec.Current, rec.Recommended, rec.Apps)
+		}
+	case "rollback":
+		if err := tuning.Rollback(); err != nil {
+			logging.Errorf("Failed to roll back kernel tuning: %v", err)
+			http.Error(w, "Failed to roll back kernel tuning: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s rolled back kernel tuning", user.Username)
+	default:
+		http.Error(w, "Action must be apply or rollback", http.StatusBadRequest)
+		return
+	}
+
+	s.writeTuningStatus(w)
+}
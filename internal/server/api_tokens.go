@@ -0,0 +1,179 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// apiTokenPrefix starts every API token, to tell them from the api_token of the config
+	// file and to make leaked tokens easy to find
+	apiTokenPrefix = "treeos_"
+	// apiTokenPrefixLength is the part of a token shown in the list, including the prefix
+	apiTokenPrefixLength  = len(apiTokenPrefix) + 6
+	apiTokenMaxNameLength = 64
+	apiTokenMaxDays       = 365
+	// apiTokenTouchInterval limits how often the last use of a token is written
+	apiTokenTouchInterval = time.Minute
+)
+
+// apiTokenAllows reports whether a token with the scope may make the request. Apps tokens
+// are limited to the apps for reads as well, they can't read users, settings or backups.
+func apiTokenAllows(scope string, r *http.Request) bool {
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+	switch scope {
+	case database.APITokenScopeFull:
+		return true
+	case database.APITokenScopeApps:
+		apps := r.URL.Path == "/api/apps" || strings.HasPrefix(r.URL.Path, "/api/apps/")
+		if read {
+			return apps || strings.HasPrefix(r.URL.Path, "/api/jobs/app/")
+		}
+		return apps
+	case database.APITokenScopeRead:
+		return read
+	default:
+		return false
+	}
+}
+
+// authenticateAPIToken returns the user a bearer token acts as and its scope. Tokens from
+// /api/tokens act as their owner; the api_token of the config file as the first admin.
+func (s *Server) authenticateAPIToken(secret string, now time.Time) (*database.User, string, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		user, err := s.apiTokenUser(secret)
+		return user, database.APITokenScopeFull, err
+	}
+
+	token, err := database.GetAPITokenByHash(hashToken(secret))
+	if err != nil {
+		return nil, "", err
+	}
+	if token == nil || token.RevokedAt != nil || (token.ExpiresAt != nil && !now.Before(*token.ExpiresAt)) {
+		return nil, "", errInvalidAPIToken
+	}
+	user, err := s.getUserByID(token.UserID)
+	if err != nil {
+		// Tokens of deactivated users stop working with them
+		return nil, "", errInvalidAPIToken
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := database.TouchAPIToken(token.ID, now); err != nil {
+			logging.Errorf("Failed to record use of API token #%d: %v", token.ID, err)
+		}
+	}
+	return user, token.Scope, nil
+}
+
+// handleAPITokens handles /api/tokens for the current user: GET lists their tokens, POST
+// {"name", "scope", "expires_in_days"} creates one and returns its secret once, DELETE ?id=
+// revokes one. Tokens can't be managed with a token, so a leaked one can't mint more.
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if isAPITokenRequest(r.Context()) {
+		http.Error(w, "API tokens can only be managed with a browser session", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := database.GetAPITokens(user.ID)
+		if err != nil {
+			logging.Errorf("Failed to list API tokens: %v", err)
+			http.Error(w, "Failed to list API tokens", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"tokens": tokens}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+
+	case http.MethodPost:
+		var request struct {
+			Name          string `json:"name"`
+			Scope         string `json:"scope"`
+			ExpiresInDays int    `json:"expires_in_days"` // 0 for a token that doesn't expire
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		request.Name = strings.TrimSpace(request.Name)
+		if request.Name == "" || len(request.Name) > apiTokenMaxNameLength {
+			http.Error(w, fmt.Sprintf("name is required and at most %d characters", apiTokenMaxNameLength), http.StatusBadRequest)
+			return
+		}
+		switch request.Scope {
+		case database.APITokenScopeRead, database.APITokenScopeApps, database.APITokenScopeFull:
+		default:
+			http.Error(w, "scope must be read, apps or full", http.StatusBadRequest)
+			return
+		}
+		if request.ExpiresInDays < 0 || request.ExpiresInDays > apiTokenMaxDays {
+			http.Error(w, fmt.Sprintf("expires_in_days must be between 0 and %d", apiTokenMaxDays), http.StatusBadRequest)
+			return
+		}
+
+		secret := apiTokenPrefix + rand.Text()
+		token := &database.APIToken{
+			UserID: user.ID,
+			Name:   request.Name,
+			Prefix: secret[:apiTokenPrefixLength],
+			Scope:  request.Scope,
+		}
+		if request.ExpiresInDays > 0 {
+			expiresAt := time.Now().AddDate(0, 0, request.ExpiresInDays)
+			token.ExpiresAt = &expiresAt
+		}
+		if err := database.CreateAPIToken(token, hashToken(secret)); err != nil {
+			logging.Errorf("Failed to create API token: %v", err)
+			http.Error(w, "Failed to create API token", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s created API token #%d %q with the %s scope", user.Username, token.ID, token.Name, token.Scope)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":  token,
+			"secret": secret,
+		}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid token ID", http.StatusBadRequest)
+			return
+		}
+		revoked, err := database.RevokeAPIToken(id, user.ID)
+		if err != nil {
+			logging.Errorf("Failed to revoke API token: %v", err)
+			http.Error(w, "Failed to revoke API token", http.StatusInternalServerError)
+			return
+		}
+		if !revoked {
+			http.Error(w, "API token not found", http.StatusNotFound)
+			return
+		}
+		logging.Infof("User %s revoked API token #%d", user.Username, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
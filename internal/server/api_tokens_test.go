@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestAPITokens(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if _, err := database.GetDB().Exec(`INSERT INTO users (username, password, is_staff) VALUES ('admin', 'hash', 1)`); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{config: &config.Config{}}
+	admin, err := s.getUserByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manage := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, admin))
+		rec := httptest.NewRecorder()
+		s.handleAPITokens(rec, req)
+		return rec
+	}
+	create := func(scope string) string {
+		t.Helper()
+		rec := manage(http.MethodPost, "/api/tokens", `{"name": "script", "scope": "`+scope+`", "expires_in_days": 30}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+		}
+		var response struct {
+			Token  database.APIToken `json:"token"`
+			Secret string            `json:"secret"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(response.Secret, response.Token.Prefix) || response.Token.ExpiresAt == nil {
+			t.Fatalf("created token = %+v", response)
+		}
+		return response.Secret
+	}
+
+	for _, body := range []string{`{"scope": "read"}`, `{"name": "x", "scope": "admin"}`, `{"name": "x", "scope": "read", "expires_in_days": 400}`} {
+		if rec := manage(http.MethodPost, "/api/tokens", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: status = %d", body, rec.Code)
+		}
+	}
+
+	var reached *http.Request
+	handler := s.AuthRequiredMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		reached = r
+		w.WriteHeader(http.StatusOK)
+	})
+	call := func(method, path, secret string) int {
+		reached = nil
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	read := create(database.APITokenScopeRead)
+	if code := call(http.MethodGet, "/api/apps/status", read); code != http.StatusOK || getUserFromContext(reached.Context()).ID != admin.ID {
+		t.Errorf("GET with read token: status = %d", code)
+	}
+	if code := call(http.MethodPost, "/api/apps/blog/start", read); code != http.StatusForbidden {
+		t.Errorf("POST with read token: status = %d", code)
+	}
+
+	apps := create(database.APITokenScopeApps)
+	if code := call(http.MethodPost, "/api/apps/blog/start", apps); code != http.StatusOK {
+		t.Errorf("app POST with apps token: status = %d", code)
+	}
+	// The path of CreateApp in the SDK, with and without the trailing slash
+	for _, path := range []string{"/api/apps", "/api/apps/"} {
+		if code := call(http.MethodPost, path, apps); code != http.StatusOK {
+			t.Errorf("POST %s with apps token: status = %d", path, code)
+		}
+	}
+	if code := call(http.MethodPost, "/api/invites", apps); code != http.StatusForbidden {
+		t.Errorf("POST outside apps with apps token: status = %d", code)
+	}
+	for _, path := range []string{"/api/apps", "/api/apps/blog/status", "/api/jobs/app/blog"} {
+		if code := call(http.MethodGet, path, apps); code != http.StatusOK {
+			t.Errorf("GET %s with apps token: status = %d", path, code)
+		}
+	}
+	for _, path := range []string{"/api/users", "/api/settings", "/api/system/backups", "/api/jobs/model/llama3"} {
+		if code := call(http.MethodGet, path, apps); code != http.StatusForbidden {
+			t.Errorf("GET %s with apps token: status = %d", path, code)
+		}
+	}
+	if code := call(http.MethodGet, "/api/apps/status", "treeos_unknown"); code != http.StatusUnauthorized {
+		t.Errorf("unknown token: status = %d", code)
+	}
+
+	// Tokens can't manage tokens
+	full := create(database.APITokenScopeFull)
+	req := httptest.NewRequest(http.MethodGet, "/api/tokens", nil)
+	req.Header.Set("Authorization", "Bearer "+full)
+	rec := httptest.NewRecorder()
+	s.AuthRequiredMiddleware(s.handleAPITokens)(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("managing tokens with a token: status = %d", rec.Code)
+	}
+
+	stored, err := database.GetAPITokenByHash(hashToken(read))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("use of the token wasn't recorded")
+	}
+	if _, _, err := s.authenticateAPIToken(read, stored.ExpiresAt.Add(time.Second)); err != errInvalidAPIToken {
+		t.Errorf("expired token: err = %v", err)
+	}
+
+	if rec := manage(http.MethodDelete, "/api/tokens?id=999", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE missing token: status = %d", rec.Code)
+	}
+	if rec := manage(http.MethodDelete, "/api/tokens?id="+strconv.Itoa(stored.ID), ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d", rec.Code)
+	}
+	if code := call(http.MethodGet, "/api/apps/status", read); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d", code)
+	}
+
+	rec = manage(http.MethodGet, "/api/tokens", "")
+	var listed struct {
+		Tokens []database.APIToken `json:"tokens"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || len(listed.Tokens) != 2 {
+		t.Errorf("GET tokens = %+v, %v", listed, err)
+	}
+}
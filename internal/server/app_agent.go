@@ -0,0 +1,488 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// appAgentTimeout limits a whole chat turn including all tool calls
+	appAgentTimeout = 2 * time.Minute
+	// appAgentMaxSteps limits the LLM round trips of a chat turn
+	appAgentMaxSteps = 6
+	// appAgentHistory is the number of earlier messages sent along as context
+	appAgentHistory      = 20
+	appAgentLogLines     = 100
+	appAgentMaxLogLines  = 500
+	appAgentMaxToolBytes = 16 * 1024
+	appChatPageSize      = 100
+)
+
+// llmConfig is the endpoint the agent talks to
+type llmConfig struct {
+	APIKey string
+	APIURL string
+	Model  string
+}
+
+// provider guesses the provider stored with agent messages from the endpoint
+func (c llmConfig) provider() string {
+	switch {
+	case strings.Contains(c.APIURL, "localhost:11434"), strings.Contains(c.APIURL, "127.0.0.1:11434"):
+		return database.ProviderOllama
+	case strings.Contains(c.APIURL, "api.openai.com"):
+		return database.ProviderOpenAI
+	case strings.Contains(c.APIURL, "anthropic.com"):
+		return database.ProviderAnthropic
+	default:
+		return database.ProviderLocal
+	}
+}
+
+// agentLLM returns the LLM configured in the agent settings
+func (s *Server) agentLLM() (llmConfig, bool) {
+	llm := llmConfig{APIKey: s.config.AgentLLMAPIKey, APIURL: s.config.AgentLLMAPIURL, Model: s.config.AgentLLMModel}
+	return llm, llm.APIURL != "" && llm.Model != ""
+}
+
+// appAgentTool is a tool the agent can call. Tools are bound to one app, so the agent can't
+// reach other apps or the host no matter what it is asked.
+type appAgentTool struct {
+	Description string
+	Parameters  map[string]interface{}
+	Run         func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// AppAgentAction records a tool call of a chat turn
+type AppAgentAction struct {
+	Tool      string `json:"tool"`
+	Arguments string `json:"arguments,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// appAgentTools returns the tools for an app
+func (s *Server) appAgentTools(appName string) map[string]appAgentTool {
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	noParameters := map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+
+	return map[string]appAgentTool{
+		"get_compose": {
+			Description: "Read the app's docker-compose.yml",
+			Parameters:  noParameters,
+			Run: func(_ context.Context, _ json.RawMessage) (string, error) {
+				content, err := os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // Path from trusted app directory
+				if err != nil {
+					return "", fmt.Errorf("failed to read docker-compose.yml: %w", err)
+				}
+				return string(content), nil
+			},
+		},
+		"get_status": {
+			Description: "List the app's containers with their state, health and image",
+			Parameters:  noParameters,
+			Run: func(ctx context.Context, _ json.RawMessage) (string, error) {
+				composeSvc, err := s.getComposeService()
+				if err != nil {
+					return "", err
+				}
+				containers, err := composeSvc.PS(ctx, compose.Options{WorkingDir: appDir})
+				if err != nil {
+					return "", fmt.Errorf("failed to get container status: %w", err)
+				}
+				if len(containers) == 0 {
+					return "No containers, the app is stopped.", nil
+				}
+				var b strings.Builder
+				for _, c := range containers {
+					fmt.Fprintf(&b, "service=%s container=%s state=%s status=%q health=%s image=%s\n",
+						c.Service, c.Name, c.State, c.Status, c.Health, c.Image)
+				}
+				return b.String(), nil
+			},
+		},
+		"get_logs": {
+			Description: "Read the most recent log lines of the app, optionally of one service",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"service": map[string]interface{}{"type": "string", "description": "Service name, empty for all services"},
+					"lines":   map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Number of lines, at most %d", appAgentMaxLogLines)},
+				},
+			},
+			Run: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var params struct {
+					Service string `json:"service"`
+					Lines   int    `json:"lines"`
+				}
+				if len(args) > 0 {
+					if err := json.Unmarshal(args, &params); err != nil {
+						return "", fmt.Errorf("invalid arguments: %w", err)
+					}
+				}
+				if params.Lines <= 0 {
+					params.Lines = appAgentLogLines
+				}
+				if params.Lines > appAgentMaxLogLines {
+					params.Lines = appAgentMaxLogLines
+				}
+				composeSvc, err := s.getComposeService()
+				if err != nil {
+					return "", err
+				}
+				var services []string
+				if params.Service != "" && params.Service != "all" {
+					services = []string{params.Service}
+				}
+				tail := &tailBuffer{lines: params.Lines}
+				if err := composeSvc.Logs(ctx, compose.Options{WorkingDir: appDir}, services, false, compose.LogWriter{Out: tail, Err: tail}); err != nil {
+					return "", fmt.Errorf("failed to read logs: %w", err)
+				}
+				return tail.String(), nil
+			},
+		},
+		"restart_app": {
+			Description: "Restart the app by stopping and starting its containers. Volumes are kept.",
+			Parameters:  noParameters,
+			Run: func(ctx context.Context, _ json.RawMessage) (string, error) {
+				composeSvc, err := s.getComposeService()
+				if err != nil {
+					return "", err
+				}
+				if err := composeSvc.Down(ctx, compose.Options{WorkingDir: appDir}, false); err != nil {
+					return "", fmt.Errorf("failed to stop the app: %w", err)
+				}
+				if err := s.startAppAfterReboot(ctx, appName); err != nil {
+					return "", fmt.Errorf("failed to start the app: %w", err)
+				}
+				logging.Infof("App %s was restarted by the agent", appName)
+				return "The app was restarted.", nil
+			},
+		},
+	}
+}
+
+// tailBuffer keeps the last lines written to it
+type tailBuffer struct {
+	mu    sync.Mutex
+	lines int
+	buf   []string
+	part  string
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parts := strings.Split(t.part+string(p), "\n")
+	t.part = parts[len(parts)-1]
+	t.buf = append(t.buf, parts[:len(parts)-1]...)
+	if len(t.buf) > t.lines {
+		t.buf = append([]string(nil), t.buf[len(t.buf)-t.lines:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := t.buf
+	if t.part != "" {
+		lines = append(append([]string(nil), lines...), t.part)
+	}
+	if len(lines) > t.lines {
+		lines = lines[len(lines)-t.lines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// llmMessage is a message of the OpenAI chat completions API
+type llmMessage struct {
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	ToolCalls  []llmToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+}
+
+type llmToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// appAgentSystemPrompt returns the instructions of an app's agent. Notes are the runbook the
+// operators shared with the agent, already redacted.
+func appAgentSystemPrompt(appName, notes string) string {
+	prompt := fmt.Sprintf(`You are the TreeOS assistant for the app %q, a Docker Compose project on a self-hosted server.
+You can only see and act on this app, using the provided tools. If asked about other apps or the host,
+explain that this chat is limited to %q. Look at the status and logs before suggesting a fix.
+Only restart the app when the user asks for it or agrees to it. Answer concisely.`, appName, appName)
+	if notes != "" {
+		prompt += fmt.Sprintf(`
+
+The operators of this node wrote these notes about the app. Prefer their procedures over generic advice
+and mention when you follow them. Lines shown as [redacted] are secret, don't ask for them.
+<notes>
+%s
+</notes>`, notes)
+	}
+	return prompt
+}
+
+// runAppAgent answers a user message, calling tools until the LLM replies with text
+func runAppAgent(ctx context.Context, llm llmConfig, appName, notes string, history []database.ChatMessage, message string, tools map[string]appAgentTool) (string, []AppAgentAction, error) {
+	messages := []llmMessage{{Role: "system", Content: appAgentSystemPrompt(appName, notes)}}
+	for _, m := range history {
+		switch m.SenderType {
+		case database.SenderTypeUser:
+			messages = append(messages, llmMessage{Role: "user", Content: m.Message})
+		case database.SenderTypeAgent:
+			messages = append(messages, llmMessage{Role: "assistant", Content: m.Message})
+		}
+	}
+	messages = append(messages, llmMessage{Role: "user", Content: message})
+
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	toolDefinitions := make([]map[string]interface{}, 0, len(tools))
+	for _, name := range names {
+		tool := tools[name]
+		toolDefinitions = append(toolDefinitions, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        name,
+				"description": tool.Description,
+				"parameters":  tool.Parameters,
+			},
+		})
+	}
+
+	actions := []AppAgentAction{}
+	for step := 0; step < appAgentMaxSteps; step++ {
+		reply, err := chatCompletion(ctx, llm, messages, toolDefinitions)
+		if err != nil {
+			return "", actions, err
+		}
+		if len(reply.ToolCalls) == 0 {
+			return strings.TrimSpace(reply.Content), actions, nil
+		}
+
+		messages = append(messages, reply)
+		for _, call := range reply.ToolCalls {
+			action := AppAgentAction{Tool: call.Function.Name, Arguments: call.Function.Arguments}
+			var result string
+			if tool, ok := tools[call.Function.Name]; ok {
+				output, err := tool.Run(ctx, json.RawMessage(call.Function.Arguments))
+				if err != nil {
+					action.Error = err.Error()
+					result = "Error: " + err.Error()
+				} else {
+					result = output
+				}
+			} else {
+				action.Error = "unknown tool"
+				result = fmt.Sprintf("Error: unknown tool %q", call.Function.Name)
+			}
+			if len(result) > appAgentMaxToolBytes {
+				// Keep the end, which holds the newest log lines
+				result = "[truncated]\n" + result[len(result)-appAgentMaxToolBytes:]
+			}
+			actions = append(actions, action)
+			messages = append(messages, llmMessage{Role: "tool", Content: result, ToolCallID: call.ID})
+		}
+	}
+	return "", actions, fmt.Errorf("the agent did not answer within %d steps", appAgentMaxSteps)
+}
+
+// chatCompletion sends one chat completions request, tools are optional
+func chatCompletion(ctx context.Context, llm llmConfig, messages []llmMessage, tools []map[string]interface{}) (llmMessage, error) {
+	requestBody := map[string]interface{}{
+		"model":    llm.Model,
+		"messages": messages,
+	}
+	if len(tools) > 0 {
+		requestBody["tools"] = tools
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return llmMessage{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, llm.APIURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return llmMessage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if llm.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+llm.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return llmMessage{}, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup, error not critical
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return llmMessage{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errorResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+			return llmMessage{}, fmt.Errorf("API error (%d): %s", resp.StatusCode, errorResp.Error.Message)
+		}
+		return llmMessage{}, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var apiResponse struct {
+		Choices []struct {
+			Message llmMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return llmMessage{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(apiResponse.Choices) == 0 {
+		return llmMessage{}, fmt.Errorf("no response from API")
+	}
+	reply := apiResponse.Choices[0].Message
+	reply.Role = "assistant"
+	return reply, nil
+}
+
+// handleAPIAppChat handles /api/apps/{appName}/chat:
+// GET returns the chat history, POST sends a message to the app's agent, DELETE clears the history
+func (s *Server) handleAPIAppChat(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if appName == "" || strings.Contains(appName, "/") {
+		http.Error(w, "App name is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName)); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		messages, err := database.GetChatMessages(appName, appChatPageSize)
+		if err != nil {
+			logging.Errorf("Failed to get chat of app %s: %v", appName, err)
+			http.Error(w, "Failed to get chat messages", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"messages": messages}); err != nil {
+			logging.Errorf("Failed to encode chat messages: %v", err)
+		}
+	case http.MethodPost:
+		s.handleAPIAppChatMessage(w, r, appName)
+	case http.MethodDelete:
+		if err := database.DeleteChatMessages(appName); err != nil {
+			logging.Errorf("Failed to clear chat of app %s: %v", appName, err)
+			http.Error(w, "Failed to clear chat", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAPIAppChatMessage(w http.ResponseWriter, r *http.Request, appName string) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Message) == "" {
+		http.Error(w, "A message is required", http.StatusBadRequest)
+		return
+	}
+
+	llm, ok := s.agentLLM()
+	if !ok {
+		http.Error(w, "The agent LLM is not configured. Set it up in Settings first.", http.StatusServiceUnavailable)
+		return
+	}
+
+	history, err := database.GetChatMessages(appName, appAgentHistory)
+	if err != nil {
+		logging.Errorf("Failed to get chat of app %s: %v", appName, err)
+		http.Error(w, "Failed to get chat messages", http.StatusInternalServerError)
+		return
+	}
+
+	username := "user"
+	if user := getUserFromContext(r.Context()); user != nil {
+		username = user.Username
+	}
+	userMessage := database.ChatMessage{AppID: appName, Message: strings.TrimSpace(req.Message), SenderType: database.SenderTypeUser, SenderName: username}
+	if userMessage.ID, err = database.AddChatMessage(userMessage); err != nil {
+		logging.Errorf("Failed to store chat message of app %s: %v", appName, err)
+		http.Error(w, "Failed to store message", http.StatusInternalServerError)
+		return
+	}
+
+	// Tool calls and slow local models easily take longer than the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(appAgentTimeout + 10*time.Second)); err != nil {
+		logging.Debugf("Failed to extend write deadline for the chat of app %s: %v", appName, err)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), appAgentTimeout)
+	defer cancel()
+
+	reply, actions, err := runAppAgent(ctx, llm, appName, agentNotes(appName), history, userMessage.Message, s.appAgentTools(appName))
+	agentMessage := database.ChatMessage{
+		AppID:         appName,
+		SenderType:    database.SenderTypeAgent,
+		SenderName:    "Agent",
+		AgentModel:    llm.Model,
+		AgentProvider: llm.provider(),
+		Message:       reply,
+	}
+	if err != nil {
+		logging.Warnf("Agent failed for app %s: %v", appName, err)
+		agentMessage.SenderType = database.SenderTypeSystem
+		agentMessage.SenderName = "System"
+		agentMessage.StatusLevel = database.StatusLevelError
+		agentMessage.Message = "The agent failed: " + err.Error()
+	}
+	if len(actions) > 0 {
+		if details, err := json.Marshal(map[string]interface{}{"actions": actions}); err == nil {
+			agentMessage.Details = string(details)
+		}
+	}
+	if agentMessage.ID, err = database.AddChatMessage(agentMessage); err != nil {
+		logging.Errorf("Failed to store agent reply of app %s: %v", appName, err)
+	}
+	agentMessage.Timestamp = time.Now()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": agentMessage,
+		"actions": actions,
+	}); err != nil {
+		logging.Errorf("Failed to encode chat reply: %v", err)
+	}
+}
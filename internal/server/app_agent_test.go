@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+// scriptedLLM answers chat completions with the given assistant messages in order and records
+// the requests it received
+func scriptedLLM(t *testing.T, replies ...string) (string, *[][]llmMessage) {
+	t.Helper()
+	requests := &[][]llmMessage{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []llmMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*requests = append(*requests, req.Messages)
+		reply := replies[min(len(*requests), len(replies))-1]
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":` + reply + `}]}`)) //nolint:errcheck,gosec // Test server
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, requests
+}
+
+func TestRunAppAgent(t *testing.T) {
+	appsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appsDir, "web"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appsDir, "web", "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx:1.27\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+
+	url, requests := scriptedLLM(t,
+		`{"role":"assistant","content":null,"tool_calls":[`+
+			`{"id":"1","type":"function","function":{"name":"get_compose","arguments":"{}"}},`+
+			`{"id":"2","type":"function","function":{"name":"delete_app","arguments":"{}"}}]}`,
+		`{"role":"assistant","content":"The app runs nginx 1.27."}`,
+	)
+	history := []database.ChatMessage{{SenderType: database.SenderTypeUser, Message: "Hi"}, {SenderType: database.SenderTypeAgent, Message: "Hello"}}
+	reply, actions, err := runAppAgent(context.Background(), llmConfig{APIURL: url, Model: "test"}, "web", "", history, "Which image?", s.appAgentTools("web"))
+	if err != nil {
+		t.Fatalf("runAppAgent() error = %v", err)
+	}
+	if reply != "The app runs nginx 1.27." {
+		t.Errorf("reply = %q", reply)
+	}
+	if len(actions) != 2 || actions[0].Tool != "get_compose" || actions[0].Error != "" || actions[1].Error == "" {
+		t.Errorf("actions = %+v, want get_compose and a rejected delete_app", actions)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("LLM requests = %d, want 2", len(*requests))
+	}
+	first := (*requests)[0]
+	if len(first) != 4 || first[0].Role != "system" || !strings.Contains(first[0].Content, `"web"`) || first[2].Role != "assistant" {
+		t.Errorf("first request messages = %+v", first)
+	}
+	second := (*requests)[1]
+	toolResults := second[len(second)-2:]
+	if toolResults[0].ToolCallID != "1" || !strings.Contains(toolResults[0].Content, "nginx:1.27") {
+		t.Errorf("compose tool result = %+v", toolResults[0])
+	}
+	if toolResults[1].ToolCallID != "2" || !strings.Contains(toolResults[1].Content, "unknown tool") {
+		t.Errorf("unknown tool result = %+v", toolResults[1])
+	}
+}
+
+func TestRunAppAgentStepLimit(t *testing.T) {
+	url, requests := scriptedLLM(t, `{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"get_compose","arguments":"{}"}}]}`)
+	tools := map[string]appAgentTool{"get_compose": {Run: func(context.Context, json.RawMessage) (string, error) { return "services: {}", nil }}}
+
+	if _, _, err := runAppAgent(context.Background(), llmConfig{APIURL: url, Model: "test"}, "web", "", nil, "Loop", tools); err == nil {
+		t.Error("expected an error when the agent never answers")
+	}
+	if len(*requests) != appAgentMaxSteps {
+		t.Errorf("LLM requests = %d, want %d", len(*requests), appAgentMaxSteps)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tail := &tailBuffer{lines: 2}
+	for _, chunk := range []string{"one\ntw", "o\nthree\n", "four"} {
+		if _, err := tail.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := tail.String(); got != "three\nfour" {
+		t.Errorf("tail = %q, want %q", got, "three\nfour")
+	}
+}
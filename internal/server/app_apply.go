@@ -0,0 +1,243 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/progress"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// pendingEdits remembers the files running apps were started with while edits to them
+// are saved, so applying the edits recreates only the services that changed
+type pendingEdits struct {
+	mu    sync.Mutex
+	files map[string]appliedFiles
+}
+
+// appliedFiles are the docker-compose.yml and .env an app runs with
+type appliedFiles struct {
+	compose []byte
+	env     []byte
+}
+
+// record keeps the files an app runs with, unless edits saved before kept them already
+func (p *pendingEdits) record(appName string, files appliedFiles) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.files == nil {
+		p.files = make(map[string]appliedFiles)
+	}
+	if _, ok := p.files[appName]; !ok {
+		p.files[appName] = files
+	}
+}
+
+// get returns the files an app runs with, if edits to it are pending
+func (p *pendingEdits) get(appName string) (appliedFiles, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	files, ok := p.files[appName]
+	return files, ok
+}
+
+// clear forgets the pending edits of an app once its containers use the saved files
+func (p *pendingEdits) clear(appName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.files, appName)
+}
+
+// readAppliedFiles reads the saved docker-compose.yml and .env of an app
+func readAppliedFiles(appDir string) (appliedFiles, error) {
+	var files appliedFiles
+	var err error
+	files.compose, err = os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // Path from trusted app directory
+	if err != nil {
+		return files, err
+	}
+	files.env, err = os.ReadFile(filepath.Join(appDir, ".env")) //nolint:gosec // Path from trusted app directory
+	if err != nil && !os.IsNotExist(err) {
+		return files, err
+	}
+	return files, nil
+}
+
+// rememberRunningFiles records the files of a running app before an edit overwrites them
+func (s *Server) rememberRunningFiles(appName string) {
+	files, err := readAppliedFiles(filepath.Join(s.config.AppsDir, appName))
+	if err != nil {
+		logging.Warnf("Failed to read the files of app %s before saving: %v", appName, err)
+		return
+	}
+	s.pendingEdits.record(appName, files)
+}
+
+// servicesToApply returns the services to recreate to apply the saved files over the
+// applied ones, nil for all. A change of .env can affect any service, `up` recreates only
+// those whose resolved configuration changed.
+func servicesToApply(applied, saved appliedFiles) ([]string, error) {
+	if !bytes.Equal(applied.env, saved.env) {
+		return nil, nil
+	}
+	changes, err := yamlutil.ServiceChanges(applied.compose, saved.compose)
+	if err != nil {
+		return nil, err
+	}
+	services := []string{}
+	for _, change := range changes {
+		if change.Kind != yamlutil.ServiceRemoved {
+			services = append(services, change.Service)
+		}
+	}
+	return services, nil
+}
+
+// applyOptions returns how to bring a running app to its saved files: the changed
+// services are recreated and containers of removed services go. Without pending edits
+// `up` recreates whatever differs from the saved files.
+func (s *Server) applyOptions(appName string) (compose.Options, error) {
+	opts, err := s.appStartOptions(appName)
+	if err != nil {
+		return opts, err
+	}
+	opts.RemoveOrphans = true
+	applied, ok := s.pendingEdits.get(appName)
+	if !ok {
+		return opts, nil
+	}
+	saved, err := readAppliedFiles(opts.WorkingDir)
+	if err != nil {
+		return opts, fmt.Errorf("failed to read app configuration: %w", err)
+	}
+	services, err := servicesToApply(applied, saved)
+	if err != nil {
+		// The applied file may be broken, all services then
+		logging.Warnf("Failed to compare the services of app %s: %v", appName, err)
+		return opts, nil
+	}
+	if len(services) > 0 {
+		opts.Services = services
+		opts.ForceRecreate = true
+	}
+	return opts, nil
+}
+
+// recreateAppTracked runs `up` for an app with the given options, reporting progress as
+// the app job
+func (s *Server) recreateAppTracked(ctx context.Context, composeSvc *compose.Service, appName string, opts compose.Options, done string) error {
+	parser := progress.NewDockerProgressParser(s.progressTracker)
+	err := composeSvc.UpWithProgress(ctx, opts, func(line string) {
+		parser.ParseLine(appName, line)
+		s.broadcastAppProgress(appName, "progress")
+	})
+	if err != nil {
+		logging.Errorf("Failed to recreate app %s: %v", appName, err)
+		s.progressTracker.SetError(appName, err.Error())
+		s.broadcastAppProgress(appName, "error")
+		if isRuntimeUnavailableError(err) {
+			s.markComposeUnhealthy()
+		}
+		s.notifyAppStartFailed(appName, err)
+		return err
+	}
+	s.pendingEdits.clear(appName)
+	s.progressTracker.CompleteOperation(appName, done)
+	s.broadcastAppProgress(appName, "complete")
+	return nil
+}
+
+// applyAppChanges starts applying the saved files to a running app in the background and
+// returns the services it recreates, nil for all
+func (s *Server) applyAppChanges(composeSvc *compose.Service, appName string) ([]string, error) {
+	opts, err := s.applyOptions(appName)
+	if err != nil {
+		return nil, err
+	}
+	message := "Applying changes..."
+	if len(opts.Services) > 0 {
+		message = fmt.Sprintf("Recreating %s...", strings.Join(opts.Services, ", "))
+	}
+	s.progressTracker.StartOperation(appName, progress.OperationPreparing, message)
+	go s.recreateAppTracked(context.Background(), composeSvc, appName, opts, fmt.Sprintf("Changes to app '%s' applied", appName)) //nolint:errcheck // Reported through the progress tracker
+	return opts.Services, nil
+}
+
+// handleAPIAppApply handles POST /api/apps/{name}/apply. It recreates the services of a
+// running app whose configuration changed since it was started, with --force-recreate.
+func (s *Server) handleAPIAppApply(w http.ResponseWriter, r *http.Request) {
+	s.handleRecreateApp(w, r, false)
+}
+
+// handleAPIAppRestart handles POST /api/apps/{name}/restart. It recreates all services of
+// a running app from the saved files.
+func (s *Server) handleAPIAppRestart(w http.ResponseWriter, r *http.Request) {
+	s.handleRecreateApp(w, r, true)
+}
+
+func (s *Server) handleRecreateApp(w http.ResponseWriter, r *http.Request, restart bool) {
+	appName := r.PathValue("name")
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(filepath.Join(appDir, "docker-compose.yml")); !isValidAppName(appName) || err != nil {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		message := "Compose service not available"
+		if !errors.Is(err, errComposeUnavailable) {
+			message = fmt.Sprintf("Compose service error: %v", err)
+		}
+		http.Error(w, message, http.StatusServiceUnavailable)
+		return
+	}
+	if !s.appRunning(r.Context(), composeSvc, appDir) {
+		http.Error(w, fmt.Sprintf("App '%s' is not running, start it instead", appName), http.StatusConflict)
+		return
+	}
+
+	var services []string
+	var message string
+	if restart {
+		opts, err := s.appStartOptions(appName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to restart app: %v", err), http.StatusBadRequest)
+			return
+		}
+		opts.ForceRecreate = true
+		opts.RemoveOrphans = true
+		s.progressTracker.StartOperation(appName, progress.OperationPreparing, "Restarting containers...")
+		go s.recreateAppTracked(context.Background(), composeSvc, appName, opts, fmt.Sprintf("App '%s' restarted successfully", appName)) //nolint:errcheck // Reported through the progress tracker
+		message = fmt.Sprintf("App '%s' is restarting. Check progress at /api/apps/%s/progress", appName, appName)
+	} else {
+		services, err = s.applyAppChanges(composeSvc, appName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to apply changes: %v", err), http.StatusBadRequest)
+			return
+		}
+		message = fmt.Sprintf("Applying changes to app '%s'. Check progress at /api/apps/%s/progress", appName, appName)
+	}
+	logging.Infof("Recreating app %s (restart: %v, services: %v)", appName, restart, services)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	response := map[string]interface{}{
+		"success":  true,
+		"message":  message,
+		"services": services,
+		"job":      jobKindApp + "/" + appName,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
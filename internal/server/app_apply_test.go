@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestServicesToApply(t *testing.T) {
+	applied := appliedFiles{
+		compose: []byte("services:\n  web:\n    image: nginx:1.25\n  db:\n    image: postgres:16\n  cache:\n    image: redis:7\n"),
+		env:     []byte("TZ=UTC\n"),
+	}
+	saved := appliedFiles{
+		compose: []byte("services:\n  web:\n    image: nginx:1.27\n  db:\n    image: postgres:16\n  worker:\n    image: app:latest\n"),
+		env:     applied.env,
+	}
+
+	services, err := servicesToApply(applied, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cache is removed as orphan, db is left running
+	if want := []string{"web", "worker"}; !reflect.DeepEqual(services, want) {
+		t.Errorf("services = %v, want %v", services, want)
+	}
+
+	saved.env = []byte("TZ=Europe/Berlin\n")
+	if services, err := servicesToApply(applied, saved); err != nil || services != nil {
+		t.Errorf("services after .env change = %v, %v, want all", services, err)
+	}
+	if services, err := servicesToApply(applied, applied); err != nil || len(services) != 0 {
+		t.Errorf("services without changes = %v, %v", services, err)
+	}
+}
+
+func TestPendingEdits(t *testing.T) {
+	var pending pendingEdits
+	pending.record("wiki", appliedFiles{compose: []byte("first")})
+	pending.record("wiki", appliedFiles{compose: []byte("second")})
+	// The files the app runs with are those before the first saved edit
+	if files, ok := pending.get("wiki"); !ok || string(files.compose) != "first" {
+		t.Errorf("get = %q, %v", files.compose, ok)
+	}
+	pending.clear("wiki")
+	if _, ok := pending.get("wiki"); ok {
+		t.Error("edits still pending after clear")
+	}
+}
+
+func TestRecreateAppNotFound(t *testing.T) {
+	s := &Server{config: &config.Config{AppsDir: t.TempDir()}}
+	mux := s.appRoutes(s.appAPIRoutes())
+	for _, action := range []string{"apply", "restart"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/apps/missing/"+action, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("POST %s = %d, want 404", action, w.Code)
+		}
+	}
+}
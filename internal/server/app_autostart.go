@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+const (
+	// autostartRuntimeWait is how long the autostart waits for the container runtime, which
+	// may come up after TreeOS when the host boots
+	autostartRuntimeWait  = 5 * time.Minute
+	autostartPollInterval = 5 * time.Second
+	// bulkActionAutostart names the autostart in "bulk" events
+	bulkActionAutostart = "autostart"
+)
+
+// autostartProgress is the progress of the autostart, for dashboards opened while it runs
+type autostartProgress struct {
+	mu      sync.Mutex
+	running bool
+	done    int
+	total   int
+}
+
+// AutostartStatus is the state of the autostart shown on the dashboard
+type AutostartStatus struct {
+	Running bool
+	Done    int
+	Total   int
+}
+
+func (p *autostartProgress) start(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running, p.done, p.total = true, 0, total
+}
+
+func (p *autostartProgress) advance() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+}
+
+func (p *autostartProgress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+}
+
+func (p *autostartProgress) status() AutostartStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return AutostartStatus{Running: p.running, Done: p.done, Total: p.total}
+}
+
+// startAutostartApps brings up the apps with autostart: true in their x-ontree block once
+// the container runtime answers. Apps that are running already, e.g. through their restart
+// policy, are left alone. Apps they depend on are started first, with or without autostart.
+// The apps report progress as their app job and the dashboard follows the "bulk" events of
+// the autostart.
+func (s *Server) startAutostartApps() {
+	apps, err := s.waitForApps()
+	if err != nil {
+		logging.Warnf("Skipping autostart of apps: %v", err)
+		return
+	}
+	var names []string
+	running := map[string]bool{}
+	for _, app := range apps {
+		if app.Status == "running" {
+			running[app.Name] = true
+		} else if appAutostart(app.Path) {
+			names = append(names, app.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	graph := s.dependencyGraphOrEmpty()
+	var stopped []string
+	for _, appName := range withDependencies(graph, names) {
+		if !running[appName] {
+			stopped = append(stopped, appName)
+		}
+	}
+	names = stopped
+	sort.Strings(names)
+
+	// Start all waits until the autostart is done
+	s.bulkMu.Lock()
+	defer s.bulkMu.Unlock()
+	s.autostart.start(len(names))
+	defer s.autostart.finish()
+
+	logging.Infof("Starting %d apps with autostart: %s", len(names), strings.Join(names, ", "))
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	results := s.runBulk(ctx, bulkActionAutostart, names, bulkOrder(graph, bulkActionStart, names), func(ctx context.Context, appName string) error {
+		defer s.autostart.advance()
+		return s.startAppWithDependencies(ctx, appName)
+	})
+
+	var failures []string
+	for _, result := range results {
+		if !result.Success {
+			failures = append(failures, result.App+": "+result.Error)
+		}
+	}
+	event := database.ActivityEvent{
+		Category: database.ActivityJob,
+		Title:    fmt.Sprintf("Started %d apps with autostart", len(names)),
+		Status:   jobStateCompleted,
+	}
+	if len(failures) > 0 {
+		event.Title = fmt.Sprintf("%d of %d apps with autostart failed to start", len(failures), len(names))
+		event.Detail = strings.Join(failures, "\n")
+		event.Status = jobStateFailed
+		logging.Errorf("Autostart of apps failed: %s", strings.Join(failures, "; "))
+	} else {
+		logging.Infof("Started %d apps with autostart", len(names))
+	}
+	recordActivity(event)
+}
+
+// waitForApps returns the installed apps with their status as soon as the container
+// runtime answers, or an error after autostartRuntimeWait
+func (s *Server) waitForApps() ([]*dockerruntime.App, error) {
+	deadline := time.Now().Add(autostartRuntimeWait)
+	for {
+		apps, err := s.scanApps()
+		if err == nil {
+			return apps, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		select {
+		case <-s.stopCh:
+			return nil, errors.New("server is stopping")
+		case <-time.After(autostartPollInterval):
+		}
+	}
+}
+
+// appAutostart reports whether an app has autostart: true in its x-ontree block
+func appAutostart(appDir string) bool {
+	metadata, err := yamlutil.ReadComposeMetadata(appDir)
+	return err == nil && metadata.Autostart
+}
+
+// handleAPIAppAutostart handles /api/apps/{name}/autostart: GET returns {"autostart": bool},
+// PUT with {"autostart": true} makes TreeOS start the app when it starts
+func (s *Server) handleAPIAppAutostart(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(filepath.Join(appDir, "docker-compose.yml")); !isValidAppName(appName) || err != nil {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var request struct {
+			Autostart bool `json:"autostart"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		// Only the key changes, comments and formatting of the file stay
+		composeFile := filepath.Join(appDir, "docker-compose.yml")
+		content, err := os.ReadFile(composeFile) //nolint:gosec // App name is validated
+		if err == nil {
+			content, err = yamlutil.SetMetadataBool(content, "autostart", request.Autostart)
+		}
+		if err == nil {
+			err = os.WriteFile(composeFile, content, 0600)
+		}
+		if err != nil {
+			logging.Errorf("Failed to update autostart of app %s: %v", appName, err)
+			http.Error(w, "Failed to update app configuration", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("Autostart of app %s set to %t by %s", appName, request.Autostart, usernameOf(getUserFromContext(r.Context())))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"app": appName, "autostart": appAutostart(appDir)}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestAppAutostartSetting(t *testing.T) {
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "wiki")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	compose := "services:\n  web:\n    image: nginx\nx-ontree:\n  emoji: 📚\n"
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(compose), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+	mux := s.appRoutes(s.appAPIRoutes())
+
+	request := func(method, path, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response) //nolint:errcheck // Checked through the fields
+		return w.Code, response
+	}
+
+	if code, response := request(http.MethodGet, "/api/apps/wiki/autostart", ""); code != http.StatusOK || response["autostart"] != false {
+		t.Fatalf("GET = %d %v, want autostart false", code, response)
+	}
+	if code, response := request(http.MethodPut, "/api/apps/wiki/autostart", `{"autostart": true}`); code != http.StatusOK || response["autostart"] != true {
+		t.Fatalf("PUT = %d %v, want autostart true", code, response)
+	}
+	if !appAutostart(appDir) {
+		t.Error("autostart not saved to the compose file")
+	}
+	content, err := os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "autostart: true") || !strings.Contains(string(content), "emoji: 📚") {
+		t.Errorf("compose file after PUT:\n%s", content)
+	}
+	if code, _ := request(http.MethodGet, "/api/apps/photos/autostart", ""); code != http.StatusNotFound {
+		t.Errorf("GET of a missing app = %d, want 404", code)
+	}
+}
+
+func TestAutostartProgress(t *testing.T) {
+	var p autostartProgress
+	if p.status().Running {
+		t.Error("running before the start")
+	}
+	p.start(3)
+	p.advance()
+	if got := p.status(); !got.Running || got.Done != 1 || got.Total != 3 {
+		t.Errorf("status = %+v", got)
+	}
+	p.finish()
+	if p.status().Running {
+		t.Error("running after finish")
+	}
+}
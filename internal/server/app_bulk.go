@@ -0,0 +1,372 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
+	"github.com/ontree-co/treeos/internal/progress"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// Actions of POST /api/apps/bulk
+const (
+	bulkActionStart   = "start"
+	bulkActionStop    = "stop"
+	bulkActionRestart = "restart"
+	bulkActionPull    = "pull"
+)
+
+const (
+	// bulkConcurrency is how many apps a bulk operation works on at the same time. On nodes
+	// with little memory the compose service runs fewer up and down operations at once.
+	bulkConcurrency = 4
+	// bulkTimeout bounds a bulk operation, pulls of large images included
+	bulkTimeout = time.Hour
+)
+
+// bulkRequest is the body of POST /api/apps/bulk
+type bulkRequest struct {
+	Action string   `json:"action"`
+	Apps   []string `json:"apps"`
+	All    bool     `json:"all"`   // All apps the user may control, instead of apps
+	Force  bool     `json:"force"` // Stop apps that other running apps depend on
+}
+
+// bulkResult is the outcome of a bulk operation for one app
+type bulkResult struct {
+	App      string  `json:"app"`
+	Success  bool    `json:"success"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// bulkActionFunc returns what a bulk action does to one app, nil for unknown actions
+func (s *Server) bulkActionFunc(action string) func(context.Context, string) error {
+	switch action {
+	case bulkActionStart:
+		return s.startAppWithDependencies
+	case bulkActionStop:
+		return s.stopApp
+	case bulkActionRestart:
+		return func(ctx context.Context, appName string) error {
+			if err := s.stopApp(ctx, appName); err != nil {
+				return err
+			}
+			return s.startAppWithDependencies(ctx, appName)
+		}
+	case bulkActionPull:
+		return s.pullAppImages
+	}
+	return nil
+}
+
+// handleAPIAppsBulk handles POST /api/apps/bulk with {"action": "start", "apps": [...]} or
+// {"action": "start", "all": true}. Actions are start, stop, restart and pull. The apps are
+// worked on concurrently and the response lists the result of each app once all are done.
+// Progress is sent as "bulk" events to the stream of all apps. Apps are started after the
+// apps they depend on, which are started too and must be apps the user controls, and
+// stopped before them. Stopping apps that other running apps depend on needs "force": true.
+func (s *Server) handleAPIAppsBulk(w http.ResponseWriter, r *http.Request) {
+	var req bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	run := s.bulkActionFunc(req.Action)
+	if run == nil {
+		http.Error(w, "action must be start, stop, restart or pull", http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	var apps []string
+	if req.All {
+		var err error
+		if apps, err = s.controllableApps(user); err != nil {
+			logging.Errorf("Failed to list apps for a bulk %s: %v", req.Action, err)
+			http.Error(w, "Failed to list apps", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if len(req.Apps) == 0 {
+			http.Error(w, "Either apps or all is required", http.StatusBadRequest)
+			return
+		}
+		seen := make(map[string]bool, len(req.Apps))
+		for _, appName := range req.Apps {
+			if !isValidAppName(appName) {
+				http.Error(w, fmt.Sprintf("Invalid app name '%s'", appName), http.StatusBadRequest)
+				return
+			}
+			if !s.requireAppAccess(w, r, appName) {
+				return
+			}
+			if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName, "docker-compose.yml")); err != nil {
+				http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+				return
+			}
+			if !seen[appName] {
+				seen[appName] = true
+				apps = append(apps, appName)
+			}
+		}
+	}
+
+	graph := s.dependencyGraphOrEmpty()
+	switch req.Action {
+	case bulkActionStart:
+		// The apps they depend on are started too, so the user has to control them as well
+		apps = withDependencies(graph, apps)
+		for _, appName := range apps {
+			if s.appAccess(user, appName) != database.AppAccessControl {
+				http.Error(w, fmt.Sprintf("Starting these apps also starts '%s', which you can't control", appName), http.StatusForbidden)
+				return
+			}
+		}
+	case bulkActionStop:
+		if !req.Force && !s.checkRunningDependents(r.Context(), w, graph, apps) {
+			return
+		}
+	}
+
+	if !s.bulkMu.TryLock() {
+		http.Error(w, "Another bulk operation is running", http.StatusConflict)
+		return
+	}
+	defer s.bulkMu.Unlock()
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(bulkTimeout + 10*time.Second)); err != nil {
+		logging.Debugf("Failed to extend write deadline for a bulk %s: %v", req.Action, err)
+	}
+	// The apps are worked on to the end even if the client goes away
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+
+	logging.Infof("Bulk %s of %d apps for %s", req.Action, len(apps), usernameOf(user))
+	results := s.runBulk(ctx, req.Action, apps, bulkOrder(graph, req.Action, apps), run)
+
+	failed := 0
+	var failures []string
+	for _, result := range results {
+		if !result.Success {
+			failed++
+			failures = append(failures, result.App+": "+result.Error)
+		}
+	}
+	event := database.ActivityEvent{
+		Category: database.ActivityJob,
+		Title:    fmt.Sprintf("Bulk %s of %d apps", req.Action, len(apps)),
+		Actor:    usernameOf(user),
+		Status:   jobStateCompleted,
+	}
+	if failed > 0 {
+		event.Detail = strings.Join(failures, "\n")
+		event.Status = jobStateFailed
+	}
+	recordActivity(event)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   failed == 0,
+		"action":    req.Action,
+		"results":   results,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+	}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// usernameOf returns the name of a user, empty without one
+func usernameOf(user *database.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Username
+}
+
+// controllableApps returns the installed apps a user may start and stop, by name
+func (s *Server) controllableApps(user *database.User) ([]string, error) {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	apps := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !isValidAppName(entry.Name()) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, entry.Name(), "docker-compose.yml")); err != nil {
+			continue
+		}
+		if s.appAccess(user, entry.Name()) == database.AppAccessControl {
+			apps = append(apps, entry.Name())
+		}
+	}
+	sort.Strings(apps)
+	return apps, nil
+}
+
+// runBulk runs an action on apps, at most bulkConcurrency at a time, and returns the results
+// in the order of apps. An app waits for the apps listed for it in after, see bulkOrder, and
+// fails without running when one of them failed.
+func (s *Server) runBulk(ctx context.Context, action string, apps []string, after map[string][]string, run func(context.Context, string) error) []bulkResult {
+	results := make([]bulkResult, len(apps))
+	index := make(map[string]int, len(apps))
+	finished := make(map[string]chan struct{}, len(apps))
+	for i, appName := range apps {
+		index[appName] = i
+		finished[appName] = make(chan struct{})
+	}
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		done  int
+		slots = make(chan struct{}, bulkConcurrency)
+	)
+	for i, appName := range apps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(finished[appName])
+			var failed []string
+			for _, name := range after[appName] {
+				<-finished[name]
+				if !results[index[name]].Success {
+					failed = append(failed, name)
+				}
+			}
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			s.publishBulkProgress(action, bulkResult{App: appName}, jobStateRunning, 0, len(apps))
+			started := time.Now()
+			var err error
+			if len(failed) > 0 {
+				err = fmt.Errorf("skipped because %s failed", strings.Join(failed, ", "))
+			} else {
+				err = run(ctx, appName)
+			}
+			result := bulkResult{App: appName, Success: err == nil, Duration: time.Since(started).Seconds()}
+			state := jobStateCompleted
+			if err != nil {
+				logging.Errorf("Bulk %s of app %s failed: %v", action, appName, err)
+				result.Error = err.Error()
+				state = jobStateFailed
+			}
+
+			mu.Lock()
+			results[i] = result
+			done++
+			count := done
+			mu.Unlock()
+			s.publishBulkProgress(action, result, state, count, len(apps))
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// publishBulkProgress sends the state of an app in a bulk operation as "bulk" event to the
+// stream of all apps
+func (s *Server) publishBulkProgress(action string, result bulkResult, state string, done, total int) {
+	if s.sseManager == nil {
+		return
+	}
+	s.sseManager.BroadcastMessage(appStatusSSEChannel, map[string]interface{}{
+		"type":   "bulk",
+		"action": action,
+		"app":    result.App,
+		"state":  state,
+		"error":  result.Error,
+		"done":   done,
+		"total":  total,
+	})
+}
+
+// startAppTracked starts an app like the start API, reporting progress as the app job
+func (s *Server) startAppTracked(ctx context.Context, appName string) error {
+	opts, err := s.appStartOptions(appName)
+	if err != nil {
+		return err
+	}
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+
+	s.progressTracker.StartOperation(appName, progress.OperationPreparing, "Preparing to start containers...")
+	s.pendingEdits.clear(appName)
+	parser := progress.NewDockerProgressParser(s.progressTracker)
+	err = composeSvc.UpWithProgress(ctx, opts, func(line string) {
+		parser.ParseLine(appName, line)
+		s.broadcastAppProgress(appName, "progress")
+	})
+	if err != nil {
+		s.progressTracker.SetError(appName, err.Error())
+		s.broadcastAppProgress(appName, "error")
+		if isRuntimeUnavailableError(err) {
+			s.markComposeUnhealthy()
+		}
+		s.notifyAppStartFailed(appName, err)
+		return err
+	}
+	s.progressTracker.CompleteOperation(appName, fmt.Sprintf("App '%s' started successfully", appName))
+	s.broadcastAppProgress(appName, "complete")
+	return nil
+}
+
+// stopApp stops an app like the stop API, its volumes are kept
+func (s *Server) stopApp(ctx context.Context, appName string) error {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+	opts := compose.Options{WorkingDir: filepath.Join(s.config.AppsDir, appName)}
+	if err := composeSvc.Down(ctx, opts, false); err != nil {
+		if isRuntimeUnavailableError(err) {
+			s.markComposeUnhealthy()
+		}
+		s.notifyEvent(notify.Event{
+			Type:    notify.EventAppStopFailed,
+			Title:   fmt.Sprintf("App %s failed to stop", appName),
+			Message: err.Error(),
+			App:     appName,
+		})
+		return err
+	}
+	s.pendingEdits.clear(appName)
+	return nil
+}
+
+// pullAppImages pulls the images of an app's tags, the app uses them when it is recreated
+func (s *Server) pullAppImages(ctx context.Context, appName string) error {
+	images, err := s.appImagesToPrefetch(appName)
+	if err != nil {
+		return err
+	}
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		if err := composeSvc.PullImage(ctx, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
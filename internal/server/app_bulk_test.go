@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunBulk(t *testing.T) {
+	s := &Server{sseManager: NewSSEManager()}
+	client := &SSEClient{AppID: appStatusSSEChannel, Messages: make(chan string, 20), Close: make(chan bool, 1)}
+	s.sseManager.RegisterClient(appStatusSSEChannel, client)
+
+	var (
+		mu            sync.Mutex
+		running, peak int
+	)
+	apps := []string{"a", "b", "c", "d", "e", "f"}
+	results := s.runBulk(context.Background(), bulkActionStart, apps, nil, func(_ context.Context, appName string) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if appName == "c" {
+			return errors.New("port taken")
+		}
+		return nil
+	})
+
+	if peak > bulkConcurrency {
+		t.Errorf("%d apps ran at once, want at most %d", peak, bulkConcurrency)
+	}
+	var got []string
+	for _, result := range results {
+		got = append(got, result.App)
+		if result.Success != (result.App != "c") {
+			t.Errorf("result of %s = %+v", result.App, result)
+		}
+	}
+	if !reflect.DeepEqual(got, apps) {
+		t.Errorf("results in order %v, want %v", got, apps)
+	}
+	if results[2].Error != "port taken" {
+		t.Errorf("error of c = %q", results[2].Error)
+	}
+	// A running and a finished event per app
+	if n := len(client.Messages); n != 2*len(apps) {
+		t.Errorf("%d bulk events, want %d", n, 2*len(apps))
+	}
+}
+
+func TestBulkRequestValidation(t *testing.T) {
+	s, alice, _ := newNamespaceTestServer(t)
+	if err := os.WriteFile(filepath.Join(s.config.AppsDir, "photos", "app.yml"), []byte("depends_on: [homework]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"unknown action", `{"action": "delete", "all": true}`, http.StatusBadRequest},
+		{"no apps", `{"action": "start"}`, http.StatusBadRequest},
+		{"invalid name", `{"action": "stop", "apps": ["../etc"]}`, http.StatusBadRequest},
+		{"app of another namespace", `{"action": "stop", "apps": ["photos", "homework"]}`, http.StatusNotFound},
+		{"dependency of another namespace", `{"action": "start", "apps": ["photos"]}`, http.StatusForbidden},
+		{"dependency of another namespace with all", `{"action": "start", "all": true}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/apps/bulk", strings.NewReader(tt.body))
+		req = req.WithContext(setUserContext(req.Context(), alice))
+		w := httptest.NewRecorder()
+		s.handleAPIAppsBulk(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	apps, err := s.controllableApps(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(apps, []string{"photos"}) {
+		t.Errorf("apps alice controls = %v, want [photos]", apps)
+	}
+}
+
+func TestRunBulkOrder(t *testing.T) {
+	s := &Server{}
+	var (
+		mu      sync.Mutex
+		started []string
+	)
+	apps := []string{"wiki", "postgres", "redis", "chat"}
+	after := map[string][]string{"wiki": {"postgres", "redis"}, "chat": {"wiki"}}
+	results := s.runBulk(context.Background(), bulkActionStart, apps, after, func(_ context.Context, appName string) error {
+		mu.Lock()
+		started = append(started, appName)
+		mu.Unlock()
+		if appName == "wiki" {
+			return errors.New("port taken")
+		}
+		return nil
+	})
+
+	position := map[string]int{}
+	for i, appName := range started {
+		position[appName] = i
+	}
+	if position["wiki"] < position["postgres"] || position["wiki"] < position["redis"] {
+		t.Errorf("wiki started before its dependencies: %v", started)
+	}
+	if _, ok := position["chat"]; ok {
+		t.Errorf("chat started although wiki failed: %v", started)
+	}
+	if results[3].Success || results[3].Error != "skipped because wiki failed" {
+		t.Errorf("result of chat = %+v", results[3])
+	}
+}
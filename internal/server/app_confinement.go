@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// appConfinementTimeout bounds the docker inspect call made while rendering the app page
+const appConfinementTimeout = 5 * time.Second
+
+// appConfinement returns the AppArmor profile and SELinux label the containers of an app
+// actually run with, nil when they cannot be inspected
+func (s *Server) appConfinement(ctx context.Context, appPath string) []compose.ContainerConfinement {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, appConfinementTimeout)
+	defer cancel()
+
+	containers, err := composeSvc.Confinement(ctx, compose.Options{WorkingDir: appPath})
+	if err != nil {
+		logging.Warnf("Failed to inspect confinement of %s: %v", appPath, err)
+		return nil
+	}
+	return containers
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/system"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// CPUPin is the cpuset of a service of an app
+type CPUPin struct {
+	App     string `json:"app"`
+	Service string `json:"service"`
+	CPUSet  string `json:"cpuset"`
+}
+
+// CPUSetRequest represents the request body for pinning a service to CPUs
+type CPUSetRequest struct {
+	Service     string `json:"service"`
+	CPUSet      string `json:"cpuset"`       // Empty removes the pin
+	AllowShared bool   `json:"allow_shared"` // Pin even if other apps use some of the CPUs
+	Restart     bool   `json:"restart"`      // Recreate the service of a running app
+}
+
+// appCPUSets returns the cpuset of each service of an app, empty for unpinned services
+func (s *Server) appCPUSets(appName string) map[string]string {
+	content, err := os.ReadFile(filepath.Join(s.config.AppsDir, appName, "docker-compose.yml")) //nolint:gosec // Path from trusted app directory
+	if err != nil {
+		return nil
+	}
+	cpusets, err := yamlutil.ServiceCPUSets(content)
+	if err != nil {
+		return nil
+	}
+	return cpusets
+}
+
+// otherAppsCPUPins returns the pinned services of all apps except exclude
+func (s *Server) otherAppsCPUPins(exclude string) []CPUPin {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		return nil
+	}
+	var pins []CPUPin
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == exclude {
+			continue
+		}
+		for service, cpuset := range s.appCPUSets(entry.Name()) {
+			if cpuset != "" {
+				pins = append(pins, CPUPin{App: entry.Name(), Service: service, CPUSet: cpuset})
+			}
+		}
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		if pins[i].App != pins[j].App {
+			return pins[i].App < pins[j].App
+		}
+		return pins[i].Service < pins[j].Service
+	})
+	return pins
+}
+
+// cpuPinConflicts returns the pins that share a CPU with ids. Pins that can't be parsed
+// are skipped, Docker rejects them on start anyway.
+func cpuPinConflicts(ids []int, pins []CPUPin) []CPUPin {
+	wanted := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	var conflicts []CPUPin
+	for _, pin := range pins {
+		pinned, err := system.ParseCPUSet(pin.CPUSet)
+		if err != nil {
+			continue
+		}
+		for _, id := range pinned {
+			if wanted[id] {
+				conflicts = append(conflicts, pin)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// validateCPUSet parses a cpuset and checks that its CPUs are online on this host. It
+// returns the cpuset in canonical form, e.g. "0-3" for "3,0,1-2".
+func validateCPUSet(cpuset string, cpus []system.CPU) (string, []int, error) {
+	ids, err := system.ParseCPUSet(cpuset)
+	if err != nil {
+		return "", nil, err
+	}
+	online := make(map[int]bool, len(cpus))
+	for _, cpu := range cpus {
+		online[cpu.ID] = true
+	}
+	for _, id := range ids {
+		if !online[id] {
+			return "", nil, fmt.Errorf("CPU %d is not available on this host", id)
+		}
+	}
+	return system.FormatCPUSet(ids), ids, nil
+}
+
+// handleAPIAppCPUSet handles /api/apps/{appName}/cpuset:
+// GET returns the host's CPUs, the app's pins and the pins of other apps,
+// PUT pins a service to CPUs, rejecting CPUs other apps are pinned to unless allowed
+func (s *Server) handleAPIAppCPUSet(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	composeFile := filepath.Join(appDir, "docker-compose.yml")
+	content, err := os.ReadFile(composeFile) //nolint:gosec // Path from trusted app directory
+	if os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	} else if err != nil {
+		logging.Errorf("Failed to read docker-compose.yml for app %s: %v", appName, err)
+		http.Error(w, "Failed to read app configuration", http.StatusInternalServerError)
+		return
+	}
+
+	cpus := system.CPUTopology()
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		response := map[string]interface{}{
+			"app":      appName,
+			"cpus":     cpus,
+			"services": s.appCPUSets(appName),
+			"pins":     s.otherAppsCPUPins(appName),
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+		return
+	case http.MethodPut:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var request CPUSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	request.CPUSet = strings.TrimSpace(request.CPUSet)
+
+	var conflicts []CPUPin
+	if request.CPUSet == "" {
+		content, err = yamlutil.RemoveServiceKey(content, request.Service, "cpuset")
+	} else {
+		var ids []int
+		request.CPUSet, ids, err = validateCPUSet(request.CPUSet, cpus)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		conflicts = cpuPinConflicts(ids, s.otherAppsCPUPins(appName))
+		if len(conflicts) > 0 && !request.AllowShared {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			response := map[string]interface{}{
+				"error":     "Other apps are pinned to some of these CPUs",
+				"conflicts": conflicts,
+			}
+			if err := json.NewEncoder(w).Encode(response); err != nil {
+				logging.Errorf("Failed to encode response: %v", err)
+			}
+			return
+		}
+		content, err = yamlutil.SetServiceKey(content, request.Service, "cpuset", request.CPUSet)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := os.WriteFile(composeFile, content, 0600); err != nil {
+		logging.Errorf("Failed to write docker-compose.yml: %v", err)
+		http.Error(w, "Failed to write docker-compose.yml", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("User %s pinned service %s of app %s to CPUs %q", user.Username, request.Service, appName, request.CPUSet)
+
+	composeSvc, err := s.getComposeService()
+	restarting := request.Restart && err == nil && s.appRunning(r.Context(), composeSvc, appDir)
+	if restarting {
+		go s.restartPatchedServices(composeSvc, appName, []string{request.Service})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if restarting {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	response := map[string]interface{}{
+		"success":    true,
+		"service":    request.Service,
+		"cpuset":     request.CPUSet,
+		"conflicts":  conflicts,
+		"restarting": restarting,
+	}
+	if restarting {
+		response["job"] = jobKindApp + "/" + appName
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
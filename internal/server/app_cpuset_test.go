@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestHandleAPIAppCPUSet(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	composeSvc, err := compose.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appsDir := t.TempDir()
+	apps := map[string]string{
+		"photos":   "services:\n  web:\n    image: nginx:1.27\n",
+		"database": "services:\n  db:\n    image: postgres:16\n    cpuset: \"0\"\n",
+	}
+	for name := range apps {
+		if err := os.MkdirAll(filepath.Join(appsDir, name), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	composeFile := filepath.Join(appsDir, "photos", "docker-compose.yml")
+	writeFiles := func() {
+		t.Helper()
+		for name, content := range apps {
+			if err := os.WriteFile(filepath.Join(appsDir, name, "docker-compose.yml"), []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}, composeSvc: composeSvc}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		user        *database.User
+		wantStatus  int
+		wantCompose string
+	}{
+		{name: "list", method: http.MethodGet, user: staff, wantStatus: http.StatusOK},
+		{name: "pinned by another app", method: http.MethodPut, user: staff,
+			body: `{"service": "web", "cpuset": "0"}`, wantStatus: http.StatusConflict, wantCompose: apps["photos"]},
+		{name: "shared on purpose", method: http.MethodPut, user: staff,
+			body: `{"service": "web", "cpuset": "0", "allow_shared": true}`, wantStatus: http.StatusOK,
+			wantCompose: "services:\n  web:\n    cpuset: \"0\"\n    image: nginx:1.27\n"},
+		{name: "invalid cpuset", method: http.MethodPut, user: staff, body: `{"service": "web", "cpuset": "0-"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown service", method: http.MethodPut, user: staff, body: `{"service": "api", "cpuset": ""}`, wantStatus: http.StatusBadRequest},
+		{name: "not staff", method: http.MethodPut, user: &database.User{Username: "user"}, body: `{"service": "web", "cpuset": "0"}`, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFiles()
+			req := newAppRequest(tt.method, "/api/apps/photos/cpuset", strings.NewReader(tt.body))
+			req = req.WithContext(setUserContext(req.Context(), tt.user))
+			w := httptest.NewRecorder()
+			s.handleAPIAppCPUSet(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.method == http.MethodGet && !strings.Contains(w.Body.String(), `"app":"database","service":"db","cpuset":"0"`) {
+				t.Errorf("pins of other apps are missing: %s", w.Body.String())
+			}
+			if tt.wantCompose != "" {
+				got, _ := os.ReadFile(composeFile) //nolint:gosec // Test file
+				if string(got) != tt.wantCompose {
+					t.Errorf("docker-compose.yml = %q, want %q", got, tt.wantCompose)
+				}
+			}
+		})
+	}
+}
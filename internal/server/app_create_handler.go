@@ -4,6 +4,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -13,7 +14,11 @@ import (
 
 	"gopkg.in/yaml.v3"
 	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/gpu"
 	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/storage"
+	"github.com/ontree-co/treeos/internal/templates"
 	"github.com/ontree-co/treeos/internal/yamlutil"
 	"github.com/ontree-co/treeos/pkg/compose"
 )
@@ -35,6 +40,7 @@ func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
 		composeContent := r.FormValue("compose_content")
 		envContent := r.FormValue("env_content")
 		emoji := r.FormValue("emoji")
+		gpuHost := gpu.Detect(r.Context())
 
 		// Validate
 		var errors []string
@@ -59,6 +65,14 @@ func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
 			// Validate YAML syntax and structure
 			if err := yamlutil.ValidateComposeFile(composeContent); err != nil {
 				errors = append(errors, err.Error())
+			} else if content, err := s.newAppNamespace(user, appName, composeContent); err != nil {
+				errors = append(errors, err.Error())
+			} else if claimed, env, err := s.claimHostPorts(appName, content, envContent, r.FormValue("assign_ports") == "on"); err != nil {
+				errors = append(errors, err.Error())
+			} else if withGPUs, err := addGPUs(gpuHost, claimed, r.Form["gpu"]); err != nil {
+				errors = append(errors, err.Error())
+			} else {
+				composeContent, envContent = withGPUs, env
 			}
 		}
 
@@ -95,10 +109,13 @@ func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
 			"compose_content": composeContent,
 			"env_content":     envContent,
 			"emoji":           emoji,
+			"assign_ports":    r.FormValue("assign_ports"),
 		}
 		data["CSRFToken"] = ""
 		data["Emojis"] = getRandomEmojis(7)
 		data["SelectedEmoji"] = emoji
+		data["GPUOptions"] = gpuOptions(gpuHost, r.Form["gpu"])
+		_, data["AgentEnabled"] = s.agentLLM()
 
 		tmpl := s.templates["app_create"]
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -116,6 +133,8 @@ func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
 	data["CSRFToken"] = ""
 	data["Emojis"] = getRandomEmojis(7)
 	data["SelectedEmoji"] = ""
+	data["GPUOptions"] = gpuOptions(gpu.Detect(r.Context()), nil)
+	_, data["AgentEnabled"] = s.agentLLM()
 
 	tmpl := s.templates["app_create"]
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -141,10 +160,16 @@ func isValidAppName(appName string) bool {
 func (s *Server) createAppScaffold(appName, composeContent, envContent, emoji string) error {
 	appPath := filepath.Join(s.config.AppsDir, appName)
 
+	composeContent, envContent, secrets, err := resolveAppSecrets(composeContent, envContent, nil)
+	if err != nil {
+		return err
+	}
+
 	// Create the app structure
-	if err := s.createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji); err != nil {
+	if err := s.createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji, ""); err != nil {
 		return err
 	}
+	storeAppSecrets(appName, secrets)
 
 	// Generate app.yml without template flag
 	if err := s.generateAppYaml(appPath, appName, composeContent); err != nil {
@@ -162,8 +187,38 @@ func (s *Server) createAppScaffold(appName, composeContent, envContent, emoji st
 	return nil
 }
 
+// resolveAppSecrets replaces secret placeholders like {{ randomPassword 32 }} in the .env and
+// compose files of a new app with generated values. Values already in secrets are reused
+// for placeholders assigned to the same variable.
+func resolveAppSecrets(composeContent, envContent string, known templates.Secrets) (string, string, templates.Secrets, error) {
+	secrets := templates.Secrets{}
+	maps.Copy(secrets, known)
+	envContent, err := templates.ResolveSecrets(envContent, secrets)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("invalid secret in .env: %w", err)
+	}
+	composeContent, err = templates.ResolveSecrets(composeContent, secrets)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("invalid secret in docker-compose.yml: %w", err)
+	}
+	return composeContent, envContent, secrets, nil
+}
+
+// storeAppSecrets keeps generated secrets in the credentials vault, so admins can look them
+// up after the install. The app works without, the values are in its files.
+func storeAppSecrets(appName string, secrets templates.Secrets) {
+	if len(secrets) == 0 {
+		return
+	}
+	if err := database.StoreAppCredentials(appName, secrets); err != nil {
+		logging.Warnf("Warning: Failed to store generated credentials of %s: %v", appName, err)
+		return
+	}
+	logging.Infof("Generated %d credentials for app %s", len(secrets), appName)
+}
+
 // createAppScaffoldInternal creates the basic app structure without starting containers
-func (s *Server) createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji string) error {
+func (s *Server) createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji string, storageClass storage.Class) error {
 
 	// Create app directory
 	err := os.MkdirAll(appPath, 0750)
@@ -179,9 +234,12 @@ func (s *Server) createAppScaffoldInternal(appPath, appName, composeContent, env
 		}
 	}
 
-	// Create mnt directory
+	// Create mnt directory, on the requested storage device if there is one
+	if storageClass == "" {
+		storageClass = composeStorageClass(composeContent)
+	}
 	mntPath := filepath.Join(appPath, "mnt")
-	err = os.MkdirAll(mntPath, 0750)
+	err = s.createAppMount(mntPath, appName, storageClass)
 	if err != nil {
 		// Clean up on failure
 		if err := os.RemoveAll(appPath); err != nil {
@@ -226,6 +284,11 @@ func (s *Server) createAppScaffoldInternal(appPath, appName, composeContent, env
 		envContent = namingConfig
 	}
 
+	// Sensitive values go to the secrets store, the .env refers to them
+	envContent, err = s.sealEnvSecrets(appName, envContent)
+	if err != nil {
+		return fmt.Errorf("failed to store secrets: %v", err)
+	}
 	envPath := filepath.Join(appPath, ".env")
 	err = os.WriteFile(envPath, []byte(envContent), 0600)
 	if err != nil {
@@ -251,6 +314,8 @@ func (s *Server) createAppScaffoldInternal(appPath, appName, composeContent, env
 			HostPort:  hostPort,
 			IsExposed: false,
 			Emoji:     emoji,
+
+			StorageClass: string(storageClass),
 		}
 		yamlutil.SetOnTreeMetadata(yamlData, metadata)
 
@@ -259,6 +324,8 @@ func (s *Server) createAppScaffoldInternal(appPath, appName, composeContent, env
 		if err != nil {
 			logging.Warnf("Warning: Failed to write compose metadata: %v", err)
 			// Continue anyway - app is created without metadata
+		} else {
+			s.syncInternalRoute(appName, metadata)
 		}
 	}
 
@@ -320,14 +387,21 @@ func (s *Server) startContainersForNewApp(appName, appPath, composeContent strin
 	return nil
 }
 
-// createAppScaffoldFromTemplate creates an app from a template with initial_setup_required flag
-func (s *Server) createAppScaffoldFromTemplate(appName, composeContent, envContent, emoji string) error {
+// createAppScaffoldFromTemplate creates an app from a template with initial_setup_required flag.
+// secrets are the passwords of the template variables, stored with the generated ones.
+func (s *Server) createAppScaffoldFromTemplate(appName, composeContent, envContent, emoji string, storageClass storage.Class, secrets templates.Secrets) error {
 	appPath := filepath.Join(s.config.AppsDir, appName)
 
+	composeContent, envContent, secrets, err := resolveAppSecrets(composeContent, envContent, secrets)
+	if err != nil {
+		return err
+	}
+
 	// Create the app structure normally
-	if err := s.createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji); err != nil {
+	if err := s.createAppScaffoldInternal(appPath, appName, composeContent, envContent, emoji, storageClass); err != nil {
 		return err
 	}
+	storeAppSecrets(appName, secrets)
 
 	// Generate app.yml with initial_setup_required flag
 	if err := s.generateAppYamlWithFlags(appPath, appName, composeContent, true); err != nil {
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// handleAPIAppCredentials handles GET /api/apps/{appName}/credentials.
+// It lists the secrets generated from template placeholders when the app was installed.
+func (s *Server) handleAPIAppCredentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	appName := r.PathValue("name")
+	if appName == "" || !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+
+	credentials, err := database.GetAppCredentials(appName)
+	if err != nil {
+		logging.Errorf("Failed to load credentials for app %s: %v", appName, err)
+		http.Error(w, "Failed to load credentials", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("User %s viewed the credentials of app %s", user.Username, appName)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	response := map[string]interface{}{
+		"app":         appName,
+		"credentials": credentials,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
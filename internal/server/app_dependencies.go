@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// dependencyHealthTimeout is how long a dependency may take to become healthy before
+// starting the apps depending on it fails, databases replaying their log may be slow
+const dependencyHealthTimeout = 5 * time.Minute
+
+// appManifest holds the keys of an app's app.yml that TreeOS acts on
+type appManifest struct {
+	DependsOn []string `yaml:"depends_on"` // Apps that must run before this app starts
+}
+
+// appDependencies returns the apps an app depends on, from depends_on in its app.yml
+func appDependencies(appDir string) ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(appDir, "app.yml")) //nolint:gosec // Path from trusted app directory
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseAppDependencies(filepath.Base(appDir), content)
+}
+
+// parseAppDependencies reads depends_on of the app.yml of an app and checks the app names
+func parseAppDependencies(appName string, content []byte) ([]string, error) {
+	var manifest appManifest
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+	for _, dependency := range manifest.DependsOn {
+		if !isValidAppName(dependency) {
+			return nil, fmt.Errorf("depends_on: invalid app name '%s'", dependency)
+		}
+		if dependency == appName {
+			return nil, errors.New("depends_on: an app can't depend on itself")
+		}
+	}
+	return manifest.DependsOn, nil
+}
+
+// appDependencyGraph maps each installed app to the apps it depends on. Apps with a broken
+// app.yml are logged and taken as having no dependencies.
+func (s *Server) appDependencyGraph() (map[string][]string, error) {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string][]string{}, nil
+		}
+		return nil, err
+	}
+	graph := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || !isValidAppName(entry.Name()) {
+			continue
+		}
+		appDir := filepath.Join(s.config.AppsDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(appDir, "docker-compose.yml")); err != nil {
+			continue
+		}
+		dependencies, err := appDependencies(appDir)
+		if err != nil {
+			logging.Warnf("Ignoring dependencies of app %s: %v", entry.Name(), err)
+		}
+		graph[entry.Name()] = dependencies
+	}
+	return graph, nil
+}
+
+// dependencyGraphOrEmpty returns the dependency graph of the apps, or an empty graph when
+// the apps directory can't be read
+func (s *Server) dependencyGraphOrEmpty() map[string][]string {
+	graph, err := s.appDependencyGraph()
+	if err != nil {
+		logging.Warnf("Failed to read app dependencies: %v", err)
+		return map[string][]string{}
+	}
+	return graph
+}
+
+// dependenciesOf returns the apps an app depends on directly or indirectly, each after the
+// apps it depends on itself. Dependencies that are not installed and cycles are errors.
+func dependenciesOf(graph map[string][]string, appName string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var order, path []string
+	var visit func(string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+		dependencies, installed := graph[name]
+		if !installed && len(path) == 0 {
+			return fmt.Errorf("app %s is not installed", name)
+		}
+		if !installed {
+			return fmt.Errorf("app %s depends on %s, which is not installed", path[len(path)-1], name)
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range dependencies {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+	if err := visit(appName); err != nil {
+		return nil, err
+	}
+	// The app itself comes last
+	return order[:len(order)-1], nil
+}
+
+// dependentsOf returns the apps that depend on an app directly or indirectly, sorted
+func dependentsOf(graph map[string][]string, appName string) []string {
+	dependents := map[string]bool{}
+	queue := []string{appName}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for app, dependencies := range graph {
+			if dependents[app] || app == appName {
+				continue
+			}
+			for _, dependency := range dependencies {
+				if dependency == name {
+					dependents[app] = true
+					queue = append(queue, app)
+					break
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(dependents))
+	for name := range dependents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bulkOrder returns for each app of a bulk operation the apps of the same operation it
+// waits for: its dependencies when starting, the apps depending on it when stopping. Apps
+// with a missing dependency or in a cycle wait for nothing, starting them reports the error.
+func bulkOrder(graph map[string][]string, action string, apps []string) map[string][]string {
+	if action == bulkActionPull {
+		return nil
+	}
+	inBulk := make(map[string]bool, len(apps))
+	for _, appName := range apps {
+		inBulk[appName] = true
+	}
+	after := make(map[string][]string, len(apps))
+	for _, appName := range apps {
+		dependencies, err := dependenciesOf(graph, appName)
+		if err != nil {
+			continue
+		}
+		related := dependencies
+		if action == bulkActionStop {
+			related = nil
+			for _, dependent := range dependentsOf(graph, appName) {
+				// Dependents in a cycle would wait for each other
+				if _, err := dependenciesOf(graph, dependent); err == nil {
+					related = append(related, dependent)
+				}
+			}
+		}
+		for _, name := range related {
+			if inBulk[name] {
+				after[appName] = append(after[appName], name)
+			}
+		}
+	}
+	return after
+}
+
+// startOrder sorts apps by name and then moves each app after the apps it depends on
+func startOrder(graph map[string][]string, apps []string) []string {
+	sorted := append([]string(nil), apps...)
+	sort.Strings(sorted)
+	inApps := make(map[string]bool, len(sorted))
+	for _, appName := range sorted {
+		inApps[appName] = true
+	}
+	seen := make(map[string]bool, len(sorted))
+	ordered := make([]string, 0, len(sorted))
+	for _, appName := range sorted {
+		// Apps with a broken dependency keep their place
+		dependencies, _ := dependenciesOf(graph, appName)
+		for _, name := range append(dependencies, appName) {
+			if inApps[name] && !seen[name] {
+				seen[name] = true
+				ordered = append(ordered, name)
+			}
+		}
+	}
+	return ordered
+}
+
+// withDependencies adds the dependencies of apps to them, for operations that start apps
+func withDependencies(graph map[string][]string, apps []string) []string {
+	seen := make(map[string]bool, len(apps))
+	for _, appName := range apps {
+		seen[appName] = true
+	}
+	all := append([]string(nil), apps...)
+	for _, appName := range apps {
+		dependencies, err := dependenciesOf(graph, appName)
+		if err != nil {
+			continue
+		}
+		for _, dependency := range dependencies {
+			if !seen[dependency] {
+				seen[dependency] = true
+				all = append(all, dependency)
+			}
+		}
+	}
+	return all
+}
+
+// runningDependents returns the running apps that depend on an app, excluding the apps in
+// skip which are stopped along with it
+func (s *Server) runningDependents(ctx context.Context, graph map[string][]string, appName string, skip map[string]bool) ([]string, error) {
+	dependents := dependentsOf(graph, appName)
+	if len(dependents) == 0 {
+		return nil, nil
+	}
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return nil, err
+	}
+	var running []string
+	for _, dependent := range dependents {
+		if !skip[dependent] && s.appRunning(ctx, composeSvc, filepath.Join(s.config.AppsDir, dependent)) {
+			running = append(running, dependent)
+		}
+	}
+	return running, nil
+}
+
+// checkRunningDependents writes a 409 listing the running apps that depend on apps about to
+// be stopped, other than apps, and reports whether there are none
+func (s *Server) checkRunningDependents(ctx context.Context, w http.ResponseWriter, graph map[string][]string, apps []string) bool {
+	stopping := make(map[string]bool, len(apps))
+	for _, appName := range apps {
+		stopping[appName] = true
+	}
+	dependents := map[string][]string{}
+	var problems []string
+	for _, appName := range apps {
+		running, err := s.runningDependents(ctx, graph, appName, stopping)
+		if err != nil {
+			logging.Warnf("Failed to check the apps depending on %s: %v", appName, err)
+			continue
+		}
+		if len(running) > 0 {
+			dependents[appName] = running
+			problems = append(problems, fmt.Sprintf("%s is needed by %s", appName, strings.Join(running, ", ")))
+		}
+	}
+	if len(dependents) == 0 {
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      fmt.Sprintf("Running apps depend on this: %s", strings.Join(problems, "; ")),
+		"dependents": dependents,
+	}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+	return false
+}
+
+// startDependencies starts the apps an app depends on that are not running, in order, and
+// waits until each of them is healthy
+func (s *Server) startDependencies(ctx context.Context, appName string) error {
+	graph, err := s.appDependencyGraph()
+	if err != nil {
+		return err
+	}
+	dependencies, err := dependenciesOf(graph, appName)
+	if err != nil {
+		return err
+	}
+	if len(dependencies) == 0 {
+		return nil
+	}
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+	for _, dependency := range dependencies {
+		if !s.appRunning(ctx, composeSvc, filepath.Join(s.config.AppsDir, dependency)) {
+			logging.Infof("Starting app %s, which app %s depends on", dependency, appName)
+			if err := s.startAppTracked(ctx, dependency); err != nil {
+				return fmt.Errorf("failed to start dependency %s: %w", dependency, err)
+			}
+		}
+		if err := s.waitAppHealthy(ctx, dependency, dependencyHealthTimeout); err != nil {
+			return fmt.Errorf("dependency %s is not healthy: %w", dependency, err)
+		}
+	}
+	return nil
+}
+
+// startAppWithDependencies starts the dependencies of an app and then the app itself
+func (s *Server) startAppWithDependencies(ctx context.Context, appName string) error {
+	if err := s.startDependencies(ctx, appName); err != nil {
+		return err
+	}
+	return s.startAppTracked(ctx, appName)
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestDependenciesOf(t *testing.T) {
+	graph := map[string][]string{
+		"postgres":  nil,
+		"redis":     nil,
+		"nextcloud": {"postgres", "redis"},
+		"collabora": {"nextcloud"},
+		"wiki":      {"postgres"},
+		"broken":    {"mysql"},
+		"a":         {"b"},
+		"b":         {"a"},
+	}
+
+	got, err := dependenciesOf(graph, "collabora")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"postgres", "redis", "nextcloud"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies of collabora = %v, want %v", got, want)
+	}
+	if got, err := dependenciesOf(graph, "postgres"); err != nil || len(got) != 0 {
+		t.Errorf("dependencies of postgres = %v, %v", got, err)
+	}
+	if _, err := dependenciesOf(graph, "broken"); err == nil || !strings.Contains(err.Error(), "mysql, which is not installed") {
+		t.Errorf("missing dependency: %v", err)
+	}
+	if _, err := dependenciesOf(graph, "a"); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("cycle: %v", err)
+	}
+
+	if got, want := dependentsOf(graph, "postgres"), []string{"collabora", "nextcloud", "wiki"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dependents of postgres = %v, want %v", got, want)
+	}
+	if got := dependentsOf(graph, "wiki"); len(got) != 0 {
+		t.Errorf("dependents of wiki = %v", got)
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	graph := map[string][]string{
+		"postgres":  nil,
+		"nextcloud": {"postgres"},
+		"wiki":      {"postgres"},
+		"zabbix":    nil,
+		"a":         {"b"},
+		"b":         {"a"},
+	}
+
+	if got, want := startOrder(graph, []string{"wiki", "zabbix", "postgres", "nextcloud"}), []string{"postgres", "nextcloud", "wiki", "zabbix"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("start order = %v, want %v", got, want)
+	}
+	if got, want := rebootStopOrder(graph, []string{"postgres", "wiki"}), []string{"wiki", "postgres"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stop order = %v, want %v", got, want)
+	}
+	if got, want := withDependencies(graph, []string{"wiki", "zabbix"}), []string{"wiki", "zabbix", "postgres"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with dependencies = %v, want %v", got, want)
+	}
+
+	apps := []string{"postgres", "nextcloud", "wiki", "a", "b"}
+	if got, want := bulkOrder(graph, bulkActionStart, apps), map[string][]string{"nextcloud": {"postgres"}, "wiki": {"postgres"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("start after = %v, want %v", got, want)
+	}
+	if got, want := bulkOrder(graph, bulkActionStop, apps), map[string][]string{"postgres": {"nextcloud", "wiki"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stop after = %v, want %v", got, want)
+	}
+	if got := bulkOrder(graph, bulkActionPull, apps); got != nil {
+		t.Errorf("pull after = %v", got)
+	}
+}
+
+func TestAppDependencyGraph(t *testing.T) {
+	appsDir := t.TempDir()
+	files := map[string]string{
+		"postgres/docker-compose.yml": "services: {}\n",
+		"wiki/docker-compose.yml":     "services: {}\n",
+		"wiki/app.yml":                "id: wiki\ndepends_on:\n  - postgres\n",
+		"notes/docker-compose.yml":    "services: {}\n",
+		"notes/app.yml":               "depends_on: [notes]\n",
+		"backup/app.yml":              "depends_on: [postgres]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(appsDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+
+	graph, err := s.appDependencyGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// notes depends on itself and is taken as having no dependencies, backup has no compose file
+	want := map[string][]string{"postgres": nil, "wiki": {"postgres"}, "notes": nil}
+	if !reflect.DeepEqual(graph, want) {
+		t.Errorf("graph = %v, want %v", graph, want)
+	}
+
+	if _, err := parseAppDependencies("wiki", []byte("depends_on: [\"../etc\"]\n")); err == nil {
+		t.Error("invalid app name accepted")
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// appDiskUsageInterval is how often the disk usage of the apps is measured. Walking
+	// large app directories takes a while, so it runs less often than the vitals.
+	appDiskUsageInterval = time.Hour
+	// appDiskUsageTimeout bounds one measurement
+	appDiskUsageTimeout = 10 * time.Minute
+)
+
+// appDiskUsageEntry is the disk usage of an app with its total
+type appDiskUsageEntry struct {
+	database.AppDiskUsage
+	Total int64 `json:"total"`
+}
+
+// startAppDiskUsageCollection measures the disk usage of every app at startup and then
+// every appDiskUsageInterval
+func (s *Server) startAppDiskUsageCollection() {
+	if s.db == nil {
+		return
+	}
+	logging.Infof("App disk usage collection started (every %s)", appDiskUsageInterval)
+
+	ticker := time.NewTicker(appDiskUsageInterval)
+	defer ticker.Stop()
+
+	s.storeAppDiskUsage()
+	for range ticker.C {
+		s.storeAppDiskUsage()
+	}
+}
+
+// storeAppDiskUsage measures the disk usage of the apps, unless a measurement runs already
+func (s *Server) storeAppDiskUsage() {
+	if !s.diskUsageMu.TryLock() {
+		return
+	}
+	defer s.diskUsageMu.Unlock()
+	s.measureAppDiskUsage()
+}
+
+// measureAppDiskUsage measures the disk usage of the apps and stores it in place of the
+// previous measurement. The caller holds diskUsageMu.
+func (s *Server) measureAppDiskUsage() {
+	client, err := s.getRuntimeClient()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), appDiskUsageTimeout)
+	defer cancel()
+	start := time.Now()
+	usages, err := client.AppDiskUsages(ctx, s.config.AppsDir)
+	if err != nil {
+		logging.Warnf("Failed to measure app disk usage: %v", err)
+		return
+	}
+
+	rows := make([]database.AppDiskUsage, 0, len(usages))
+	for _, u := range usages {
+		rows = append(rows, database.AppDiskUsage{
+			AppName:    u.App,
+			Images:     u.Images,
+			Containers: u.Containers,
+			Volumes:    u.Volumes,
+			BindMounts: u.BindMounts,
+			Logs:       u.Logs,
+		})
+	}
+	if err := database.ReplaceAppDiskUsage(rows); err != nil {
+		logging.Errorf("Failed to store app disk usage: %v", err)
+		return
+	}
+	logging.Infof("Measured the disk usage of %d app(s) in %s", len(rows), time.Since(start).Round(time.Second))
+}
+
+// appDiskUsageEntries returns the stored disk usage of the apps, largest first
+func appDiskUsageEntries(usages []database.AppDiskUsage) []appDiskUsageEntry {
+	entries := make([]appDiskUsageEntry, 0, len(usages))
+	for _, u := range usages {
+		entries = append(entries, appDiskUsageEntry{
+			AppDiskUsage: u,
+			Total:        u.Total(),
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Total > entries[j].Total })
+	return entries
+}
+
+// handleAPIDiskUsage handles /api/disk-usage. GET returns the disk usage of each app as
+// last measured, largest first, POST starts a new measurement in the background.
+func (s *Server) handleAPIDiskUsage(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		usages, err := database.GetAppDiskUsage()
+		if err != nil {
+			logging.Errorf("Failed to get app disk usage: %v", err)
+			http.Error(w, "Failed to get disk usage", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		response := map[string]interface{}{
+			"apps":      appDiskUsageEntries(usages),
+			"measuring": s.diskUsageMeasuring(),
+		}
+		if len(usages) > 0 {
+			response["measured_at"] = usages[0].MeasuredAt
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	case http.MethodPost:
+		// Held from here, so the measurement shows as running right away
+		if s.diskUsageMu.TryLock() {
+			go func() {
+				defer s.diskUsageMu.Unlock()
+				s.measureAppDiskUsage()
+			}()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"measuring": true}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// diskUsageMeasuring reports whether the disk usage of the apps is being measured
+func (s *Server) diskUsageMeasuring() bool {
+	if !s.diskUsageMu.TryLock() {
+		return true
+	}
+	s.diskUsageMu.Unlock()
+	return false
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestHandleAPIDiskUsage(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	appsDir := t.TempDir()
+	for appName, dataSize := range map[string]int{"wiki": 1 << 10, "photos": 1 << 20} {
+		if err := os.MkdirAll(filepath.Join(appsDir, appName, "mnt"), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(appsDir, appName, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(appsDir, appName, "mnt", "data"), []byte(strings.Repeat("x", dataSize)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+	s.storeAppDiskUsage()
+
+	request := func(user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/disk-usage", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIDiskUsage(rec, req)
+		return rec
+	}
+	if rec := request(&database.User{ID: 2, Username: "viewer"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-staff status = %d", rec.Code)
+	}
+
+	rec := request(&database.User{ID: 1, Username: "admin", IsStaff: true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var response struct {
+		Apps      []appDiskUsageEntry `json:"apps"`
+		Measuring bool                `json:"measuring"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	// Largest first
+	if len(response.Apps) != 2 || response.Apps[0].AppName != "photos" || response.Apps[0].BindMounts < 1<<20 ||
+		response.Apps[0].Total != response.Apps[0].BindMounts || response.Measuring {
+		t.Errorf("disk usage = %+v", response)
+	}
+}
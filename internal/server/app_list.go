@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+// appSummary is an app in the list of /api/apps
+type appSummary struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	Emoji    string   `json:"emoji,omitempty"`
+	Services []string `json:"services"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// summarizeApps returns the apps for the list, by name. The environment and the
+// paths of the services stay out of it.
+func summarizeApps(apps []*dockerruntime.App) []appSummary {
+	summaries := make([]appSummary, 0, len(apps))
+	for _, app := range apps {
+		services := make([]string, 0, len(app.Services))
+		for name := range app.Services {
+			services = append(services, name)
+		}
+		sort.Strings(services)
+		summaries = append(summaries, appSummary{
+			Name:     app.Name,
+			Status:   app.Status,
+			Emoji:    app.Emoji,
+			Services: services,
+			Error:    app.Error,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries
+}
+
+// handleAPIApps handles GET /api/apps: the apps the user may see with their status, or the
+// stream of their container changes for clients that accept server-sent events
+func (s *Server) handleAPIApps(w http.ResponseWriter, r *http.Request) {
+	if wantsEventStream(r) {
+		s.handleAPIAppsEvents(w, r)
+		return
+	}
+	apps, err := s.scanApps()
+	if err != nil {
+		if errors.Is(err, errRuntimeUnavailable) {
+			http.Error(w, "Container runtime not available", http.StatusServiceUnavailable)
+			return
+		}
+		logging.Errorf("Error scanning apps: %v", err)
+		http.Error(w, "Failed to list apps", http.StatusInternalServerError)
+		return
+	}
+	apps = s.filterAppsForUser(getUserFromContext(r.Context()), apps)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"apps": summarizeApps(apps)}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
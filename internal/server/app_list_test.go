@@ -0,0 +1,25 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+func TestSummarizeApps(t *testing.T) {
+	apps := []*dockerruntime.App{
+		{Name: "wiki", Path: "/opt/ontree/apps/wiki", Status: "running", Services: map[string]dockerruntime.ComposeService{
+			"web": {Image: "wiki", Environment: []string{"DB_PASSWORD=hunter2"}},
+			"db":  {Image: "postgres"},
+		}},
+		{Name: "blog", Status: "exited", Error: "port taken"},
+	}
+	want := []appSummary{
+		{Name: "blog", Status: "exited", Services: []string{}, Error: "port taken"},
+		{Name: "wiki", Status: "running", Services: []string{"db", "web"}},
+	}
+	if got := summarizeApps(apps); !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeApps() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/logparse"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// logStreamTail is how many lines a new log stream starts with unless the request asks
+	// for another number
+	logStreamTail = 100
+	// logStreamRetry is the reconnect delay sent to clients, and how long a follower waits
+	// before running docker compose logs again after it ended
+	logStreamRetry = 3 * time.Second
+	// logStreamMaxLine splits longer lines, so a container writing without newlines can't
+	// grow the buffer
+	logStreamMaxLine = 16 * 1024
+	// logStreamIDLayout formats event IDs. They are fixed-width UTC times, so they compare
+	// as strings and can be passed to docker compose logs --since after a reconnect.
+	logStreamIDLayout = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// logStreamEvent is one log line sent as "log" event
+type logStreamEvent struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Service   string    `json:"service"`
+	Stream    string    `json:"stream"` // stdout for container output, stderr for messages of docker compose
+	Line      string    `json:"line"`
+}
+
+// newLogStreamEvent parses a line of docker compose logs --timestamps. Lines without a
+// timestamp, such as those of the simulated runtime, get the time they were read.
+func newLogStreamEvent(parser *logparse.Parser, stream, line string, now time.Time) logStreamEvent {
+	service, received, message := parser.SplitLine(line)
+	event := logStreamEvent{Timestamp: now, Service: service, Stream: stream, Line: message}
+	if received != nil {
+		event.Timestamp = *received
+	}
+	event.ID = event.Timestamp.UTC().Format(logStreamIDLayout)
+	return event
+}
+
+// sse formats the event for an event stream
+func (e logStreamEvent) sse() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		logging.Errorf("Failed to marshal log event: %v", err)
+		return ""
+	}
+	return fmt.Sprintf("id: %s\nevent: log\ndata: %s\n\n", e.ID, data)
+}
+
+// sseEventID returns the ID of a formatted SSE message, "" if it has none
+func sseEventID(message string) string {
+	id, ok := strings.CutPrefix(message, "id: ")
+	if !ok {
+		return ""
+	}
+	id, _, _ = strings.Cut(id, "\n")
+	return id
+}
+
+// logLineWriter calls emit with each complete line written to it
+type logLineWriter struct {
+	buf  []byte
+	emit func(line string)
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			if len(w.buf) < logStreamMaxLine {
+				return len(p), nil
+			}
+			i = logStreamMaxLine
+			w.emit(string(w.buf[:i]))
+			w.buf = append(w.buf[:0], w.buf[i:]...)
+			continue
+		}
+		w.emit(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = append(w.buf[:0], w.buf[i+1:]...)
+	}
+}
+
+// Flush emits a last line without newline
+func (w *logLineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = w.buf[:0]
+	}
+}
+
+// streamAppLogs runs docker compose logs --timestamps for an app and calls emit with each
+// line newer than the event ID after. It returns when the output ends, with Follow when
+// ctx is done or the containers stopped.
+func (s *Server) streamAppLogs(ctx context.Context, appName string, services []string, logOpts compose.LogOptions, after string, emit func(logStreamEvent)) error {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	names, err := appServices(appDir)
+	if err != nil {
+		logging.Warnf("Failed to read services of app %s: %v", appName, err)
+	}
+	parser := logparse.NewParser(names)
+
+	// Out and Err are copied concurrently
+	var mu sync.Mutex
+	writer := func(stream string) *logLineWriter {
+		return &logLineWriter{emit: func(line string) {
+			if strings.TrimSpace(line) == "" {
+				return
+			}
+			event := newLogStreamEvent(parser, stream, line, time.Now())
+			if event.ID <= after {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			emit(event)
+		}}
+	}
+	out, errOut := writer("stdout"), writer("stderr")
+
+	logOpts.Services = services
+	logOpts.Timestamps = true
+	err = composeSvc.LogsWithOptions(ctx, compose.Options{WorkingDir: appDir}, logOpts, compose.LogWriter{Out: out, Err: errOut})
+	out.Flush()
+	errOut.Flush()
+	if err != nil && isRuntimeUnavailableError(err) {
+		s.markComposeUnhealthy()
+	}
+	return err
+}
+
+// logFollowers runs one docker compose logs --follow per stream channel, shared by all
+// clients of the channel
+type logFollowers struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// appLogChannel returns the SSE channel of the logs of an app, or of one of its services
+func appLogChannel(appName, service string) string {
+	if service == "" {
+		return "app-logs-" + appName
+	}
+	return "app-logs-" + appName + "/" + service
+}
+
+// joinLogStream registers a client for the logs of an app, starting to follow them if it
+// is the first
+func (s *Server) joinLogStream(channel, appName string, services []string, client *SSEClient) {
+	s.logFollowers.mu.Lock()
+	defer s.logFollowers.mu.Unlock()
+
+	s.sseManager.RegisterClient(channel, client)
+	if s.logFollowers.cancels[channel] != nil {
+		return
+	}
+	if s.logFollowers.cancels == nil {
+		s.logFollowers.cancels = make(map[string]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.logFollowers.cancels[channel] = cancel
+	go s.followAppLogs(ctx, channel, appName, services)
+}
+
+// leaveLogStream unregisters a client, no longer following the logs if it was the last
+func (s *Server) leaveLogStream(channel string, client *SSEClient) {
+	s.logFollowers.mu.Lock()
+	defer s.logFollowers.mu.Unlock()
+
+	s.sseManager.UnregisterClient(channel, client)
+	if s.sseManager.ClientCount(channel) > 0 {
+		return
+	}
+	if cancel := s.logFollowers.cancels[channel]; cancel != nil {
+		cancel()
+		delete(s.logFollowers.cancels, channel)
+	}
+}
+
+// followAppLogs publishes new log lines of an app to channel until ctx is done. docker
+// compose logs --follow ends when the containers stop, so it is run again until then and
+// picks up where it left off once they are started.
+func (s *Server) followAppLogs(ctx context.Context, channel, appName string, services []string) {
+	last := time.Now().UTC().Format(logStreamIDLayout)
+	for {
+		err := s.streamAppLogs(ctx, appName, services, compose.LogOptions{Follow: true, Since: last}, last, func(event logStreamEvent) {
+			last = max(last, event.ID)
+			s.sseManager.Publish(channel, event.sse())
+		})
+		if err != nil && ctx.Err() == nil {
+			logging.Warnf("Following logs of app %s failed: %v", appName, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(logStreamRetry):
+		}
+	}
+}
+
+// handleAPIAppLogStream handles GET /api/apps/{appName}/logs/stream, the live logs of an
+// app as Server-Sent Events. Each line is a "log" event with a JSON object of id,
+// timestamp, service, stream and line. Query parameters: service (all if empty) and tail,
+// the lines to start with. Reconnecting clients send the ID of the last event they got
+// as Last-Event-ID header, or last_event_id parameter, and get the lines they missed
+// instead. A client too slow for the logs gets a "dropped" event with the number of lines
+// it missed.
+func (s *Server) handleAPIAppLogStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	var services []string
+	service := query.Get("service")
+	if service == "all" {
+		service = ""
+	}
+	if service != "" {
+		names, err := appServices(appDir)
+		if err != nil || !slices.Contains(names, service) {
+			http.Error(w, fmt.Sprintf("Service '%s' not found", service), http.StatusNotFound)
+			return
+		}
+		services = []string{service}
+	}
+
+	tail := logStreamTail
+	if value := query.Get("tail"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > logViewerMaxTail {
+			http.Error(w, fmt.Sprintf("tail must be between 0 and %d", logViewerMaxTail), http.StatusBadRequest)
+			return
+		}
+		tail = n
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = query.Get("last_event_id")
+	}
+	if lastID != "" {
+		if _, err := time.Parse(logStreamIDLayout, lastID); err != nil {
+			http.Error(w, "Invalid last event ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if s.sseManager == nil {
+		http.Error(w, "SSE not available", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// The stream stays open for as long as the client watches, past the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logging.Debugf("Failed to clear write deadline of the log stream of %s: %v", appName, err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	// Join before reading the backlog, so that no line falls between the two. Lines in
+	// both are skipped by their ID below.
+	channel := appLogChannel(appName, service)
+	client := &SSEClient{
+		AppID:    channel,
+		Messages: make(chan string, s.sseBufferSize()),
+		Close:    make(chan bool, 1),
+	}
+	s.joinLogStream(channel, appName, services, client)
+	defer s.leaveLogStream(channel, client)
+
+	ctx := r.Context()
+	fmt.Fprintf(w, "retry: %d\n\n", logStreamRetry.Milliseconds()) //nolint:errcheck // SSE stream
+
+	// A new stream starts with the last lines, a reconnected one with the lines it missed
+	backlog := compose.LogOptions{Tail: tail}
+	if lastID != "" {
+		backlog = compose.LogOptions{Since: lastID, Tail: logViewerMaxTail}
+	}
+	last := lastID
+	if lastID != "" || tail > 0 {
+		// Containers are read concurrently, so the newest line isn't always the last
+		err := s.streamAppLogs(ctx, appName, services, backlog, lastID, func(event logStreamEvent) {
+			last = max(last, event.ID)
+			fmt.Fprint(w, event.sse()) //nolint:errcheck // SSE stream
+		})
+		if err != nil && ctx.Err() == nil {
+			logging.Errorf("Failed to get logs for app %s: %v", appName, err)
+			data, _ := json.Marshal(map[string]string{"message": err.Error()}) //nolint:errcheck // Marshaling a string map can't fail
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)                 //nolint:errcheck // SSE stream
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case message := <-client.Messages:
+			if id := sseEventID(message); id != "" && id <= last {
+				continue
+			}
+			if _, err := fmt.Fprint(w, message); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		case <-client.Close:
+			return
+		}
+	}
+}
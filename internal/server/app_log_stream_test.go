@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/logparse"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestLogLineWriter(t *testing.T) {
+	var lines []string
+	w := &logLineWriter{emit: func(line string) { lines = append(lines, line) }}
+	w.Write([]byte("web-1  | first\r\nweb-1  | sec"))                   //nolint:errcheck // Never fails
+	w.Write([]byte("ond\n" + strings.Repeat("x", logStreamMaxLine+10))) //nolint:errcheck // Never fails
+	w.Flush()
+
+	if len(lines) != 4 || lines[0] != "web-1  | first" || lines[1] != "web-1  | second" ||
+		len(lines[2]) != logStreamMaxLine || lines[3] != "xxxxxxxxxx" {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestNewLogStreamEvent(t *testing.T) {
+	parser := logparse.NewParser([]string{"web", "db"})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	event := newLogStreamEvent(parser, "stdout", "blog-web-1  | 2026-03-01T11:59:58.5Z GET / 200", now)
+	if event.Service != "web" || event.Line != "GET / 200" || event.ID != "2026-03-01T11:59:58.500000000Z" {
+		t.Errorf("event = %+v", event)
+	}
+	if event := newLogStreamEvent(parser, "stderr", "no such service: cache", now); event.Service != "" || !event.Timestamp.Equal(now) {
+		t.Errorf("event without prefix = %+v", event)
+	}
+
+	message := event.sse()
+	if id := sseEventID(message); id != event.ID || !strings.Contains(message, "\nevent: log\ndata: {") {
+		t.Errorf("sse() = %q", message)
+	}
+	if id := sseEventID("event: heartbeat\ndata: ping\n\n"); id != "" {
+		t.Errorf("sseEventID(heartbeat) = %q", id)
+	}
+}
+
+func TestSSEManagerPublish(t *testing.T) {
+	m := NewSSEManager()
+	slow := &SSEClient{Messages: make(chan string, 2), Close: make(chan bool, 1)}
+	fast := &SSEClient{Messages: make(chan string, 10), Close: make(chan bool, 1)}
+	m.RegisterClient("app-logs-blog", slow)
+	m.RegisterClient("app-logs-blog", fast)
+	defer m.UnregisterClient("app-logs-blog", slow)
+	defer m.UnregisterClient("app-logs-blog", fast)
+
+	for _, line := range []string{"a", "b", "c", "d"} {
+		m.Publish("app-logs-blog", line)
+	}
+	if len(fast.Messages) != 4 {
+		t.Errorf("fast client got %d messages", len(fast.Messages))
+	}
+
+	// The slow client misses what doesn't fit and is told how much once there is room
+	if first, second := <-slow.Messages, <-slow.Messages; first != "a" || second != "b" {
+		t.Errorf("slow client got %q, %q", first, second)
+	}
+	m.Publish("app-logs-blog", "e")
+	if notice, next := <-slow.Messages, <-slow.Messages; notice != "event: dropped\ndata: {\"count\": 2}\n\n" || next != "e" {
+		t.Errorf("slow client got %q, %q", notice, next)
+	}
+}
+
+func TestHandleAPIAppLogStream(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "blog")
+	if err := os.MkdirAll(appDir, 0755); err != nil { //nolint:gosec // Test directory permissions
+		t.Fatal(err)
+	}
+	composeYAML := "services:\n  web:\n    image: nginx:latest\n  db:\n    image: postgres:16\n"
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(composeYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	composeSvc, err := compose.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := composeSvc.Up(context.Background(), compose.Options{WorkingDir: appDir}); err != nil {
+		t.Fatal(err)
+	}
+	defer composeSvc.Down(context.Background(), compose.Options{WorkingDir: appDir}, false) //nolint:errcheck // Test cleanup
+
+	s := &Server{
+		config:         &config.Config{AppsDir: appsDir},
+		composeSvc:     composeSvc,
+		composeHealthy: true,
+		sseManager:     NewSSEManager(),
+	}
+
+	for query, want := range map[string]int{
+		"?service=cache":           http.StatusNotFound,
+		"?tail=-1":                 http.StatusBadRequest,
+		"?last_event_id=yesterday": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		s.handleAPIAppLogStream(rec, newAppRequest(http.MethodGet, "/api/apps/blog/logs/stream"+query, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d", query, rec.Code)
+		}
+	}
+
+	server := httptest.NewUnstartedServer(s.appRoutes(s.appAPIRoutes()))
+	// Shorter than the stream, which must outlive it
+	server.Config.WriteTimeout = time.Nanosecond
+	server.Start()
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/apps/blog/logs/stream?service=web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // Test cleanup
+
+	var events []logStreamEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for len(events) < 3 && scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event logStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 3 || events[0].Service != "web" || events[0].Stream != "stdout" || events[1].Line != "Listening on port 80" {
+		t.Errorf("events = %+v", events)
+	}
+	if n := s.sseManager.ClientCount(appLogChannel("blog", "web")); n != 1 {
+		t.Errorf("clients = %d", n)
+	}
+
+	// The follower stops with its last client
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.logFollowers.mu.Lock()
+		following := len(s.logFollowers.cancels)
+		s.logFollowers.mu.Unlock()
+		if following == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("log follower still running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
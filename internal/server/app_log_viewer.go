@@ -0,0 +1,286 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/logparse"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// logViewerTail is how many lines the log viewer parses unless the request asks for more
+	logViewerTail = 1000
+	// logViewerMaxTail bounds the lines parsed per request
+	logViewerMaxTail = 10000
+	// logViewerBuckets is the most timeline bars the viewer shows
+	logViewerBuckets = 120
+)
+
+// parsedLogs is the response of the log viewer
+type parsedLogs struct {
+	App      string                 `json:"app"`
+	Services []string               `json:"services"`
+	Entries  []logparse.Entry       `json:"entries"` // Matching the filter, oldest first
+	Total    int                    `json:"total"`   // Lines read before the level and text filter
+	Counts   map[logparse.Level]int `json:"counts"`  // Lines per level before the level and text filter, "" for none
+	Interval int                    `json:"interval"`
+	Timeline []logparse.Bucket      `json:"timeline"`
+	Spikes   []logparse.Spike       `json:"spikes"`
+	Filter   logparse.Filter        `json:"filter"`
+	Since    *time.Time             `json:"since,omitempty"`
+	Until    *time.Time             `json:"until,omitempty"`
+}
+
+// logFilterFromQuery reads a log filter from service, level and q query parameters
+func logFilterFromQuery(r *http.Request) (logparse.Filter, error) {
+	query := r.URL.Query()
+	filter := logparse.Filter{Service: query.Get("service"), Query: query.Get("q")}
+	if filter.Service == "all" {
+		filter.Service = ""
+	}
+	if level := query.Get("level"); level != "" && level != "all" {
+		filter.Level = logparse.ParseLevel(level)
+		if filter.Level == logparse.LevelNone {
+			return filter, fmt.Errorf("invalid level %q, use error, warn, info or debug", level)
+		}
+	}
+	return filter, nil
+}
+
+// appServices returns the service names of an app's compose file, sorted
+func appServices(appDir string) ([]string, error) {
+	composeFile, err := yamlutil.ReadComposeWithMetadata(filepath.Join(appDir, "docker-compose.yml"))
+	if err != nil {
+		return nil, err
+	}
+	services := make([]string, 0, len(composeFile.Services))
+	for name := range composeFile.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// handleAPIAppParsedLogs handles GET /api/apps/{appName}/logs/parsed?service=&level=&q=&tail=&since=&until=.
+// It reads the last lines of the app's logs, detects time and level of each line and returns
+// the lines that match the filter. The timeline and its error spikes cover all lines of the
+// selected service, so a spike stays visible while the list is narrowed to it with since and until.
+func (s *Server) handleAPIAppParsedLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	filter, err := logFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tail := logViewerTail
+	if value := r.URL.Query().Get("tail"); value != "" {
+		tail, err = strconv.Atoi(value)
+		if err != nil || tail <= 0 {
+			http.Error(w, "tail must be a positive number", http.StatusBadRequest)
+			return
+		}
+		if tail > logViewerMaxTail {
+			tail = logViewerMaxTail
+		}
+	}
+	var since, until *time.Time
+	for name, target := range map[string]**time.Time{"since": &since, "until": &until} {
+		if value := r.URL.Query().Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, name+" must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*target = &t
+		}
+	}
+
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	services, err := appServices(appDir)
+	if err != nil {
+		if _, statErr := os.Stat(appDir); os.IsNotExist(statErr) {
+			http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+			return
+		}
+		logging.Errorf("Failed to read docker-compose.yml for app %s: %v", appName, err)
+		http.Error(w, "Failed to read app configuration", http.StatusInternalServerError)
+		return
+	}
+
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		message := "Compose service not available"
+		if !errors.Is(err, errComposeUnavailable) {
+			message = fmt.Sprintf("Compose service error: %v", err)
+		}
+		http.Error(w, message, http.StatusServiceUnavailable)
+		return
+	}
+
+	logOpts := compose.LogOptions{Timestamps: true, Tail: tail}
+	if filter.Service != "" {
+		logOpts.Services = []string{filter.Service}
+	}
+	// --tail is per container, the buffer keeps the last lines of all of them together
+	output := &tailBuffer{lines: tail}
+	if err := composeSvc.LogsWithOptions(r.Context(), compose.Options{WorkingDir: appDir}, logOpts, compose.LogWriter{Out: output, Err: output}); err != nil {
+		if isRuntimeUnavailableError(err) {
+			s.markComposeUnhealthy()
+		}
+		logging.Errorf("Failed to get logs for app %s: %v", appName, err)
+		http.Error(w, "Failed to read logs", http.StatusBadGateway)
+		return
+	}
+
+	entries := logparse.NewParser(services).Parse(output.String())
+	// Lines of several containers arrive grouped by container, order them by time. Lines
+	// without time keep their place relative to each other.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time != nil && entries[j].Time != nil && entries[i].Time.Before(*entries[j].Time)
+	})
+
+	response := parsedLogs{
+		App:      appName,
+		Services: services,
+		Total:    len(entries),
+		Counts:   map[logparse.Level]int{},
+		Filter:   filter,
+		Since:    since,
+		Until:    until,
+	}
+	for _, entry := range entries {
+		response.Counts[entry.Level]++
+	}
+	interval := logparse.Interval(entries, logViewerBuckets)
+	response.Interval = int(interval / time.Second)
+	response.Timeline = logparse.Timeline(entries, interval)
+	response.Spikes = logparse.Spikes(entries, interval)
+
+	response.Entries = make([]logparse.Entry, 0, len(entries))
+	for _, entry := range filter.Apply(entries) {
+		if entry.Time != nil && ((since != nil && entry.Time.Before(*since)) || (until != nil && !entry.Time.Before(*until))) {
+			continue
+		}
+		response.Entries = append(response.Entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleAPIAppLogFilters handles /api/apps/{appName}/log-filters:
+// GET lists the saved filters of the log viewer, POST saves one replacing a filter with the
+// same name, DELETE /log-filters/{id} removes one.
+func (s *Server) handleAPIAppLogFilters(w http.ResponseWriter, r *http.Request) {
+	appName, filterID := r.PathValue("name"), r.PathValue("id")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName)); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	if filterID != "" {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(filterID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid filter id", http.StatusBadRequest)
+			return
+		}
+		deleted, err := database.DeleteLogFilter(appName, id)
+		if err != nil {
+			logging.Errorf("Failed to delete log filter %d of app %s: %v", id, appName, err)
+			http.Error(w, "Failed to delete log filter", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Log filter not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		filters, err := database.GetLogFilters(appName)
+		if err != nil {
+			logging.Errorf("Failed to load log filters of app %s: %v", appName, err)
+			http.Error(w, "Failed to load log filters", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"app": appName, "filters": filters}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	case http.MethodPost:
+		var request struct {
+			Name    string `json:"name"`
+			Service string `json:"service"`
+			Level   string `json:"level"`
+			Query   string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		filter := &database.LogFilter{
+			AppName: appName,
+			Name:    strings.TrimSpace(request.Name),
+			Service: request.Service,
+			Query:   request.Query,
+		}
+		if filter.Name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		if request.Level != "" {
+			level := logparse.ParseLevel(request.Level)
+			if level == logparse.LevelNone {
+				http.Error(w, fmt.Sprintf("invalid level %q, use error, warn, info or debug", request.Level), http.StatusBadRequest)
+				return
+			}
+			filter.Level = string(level)
+		}
+		if err := database.SaveLogFilter(filter); err != nil {
+			logging.Errorf("Failed to save log filter of app %s: %v", appName, err)
+			http.Error(w, "Failed to save log filter", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(filter); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
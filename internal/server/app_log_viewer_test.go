@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestHandleAPIAppLogFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "apps", "web"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: filepath.Join(tmpDir, "apps")}}
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := newAppRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.handleAPIAppLogFilters(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "unknown app", method: http.MethodGet, path: "/api/apps/missing/log-filters", wantStatus: http.StatusNotFound},
+		{name: "missing name", method: http.MethodPost, path: "/api/apps/web/log-filters", body: `{"level": "error"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid level", method: http.MethodPost, path: "/api/apps/web/log-filters", body: `{"name": "x", "level": "loud"}`, wantStatus: http.StatusBadRequest},
+		{name: "save", method: http.MethodPost, path: "/api/apps/web/log-filters", body: `{"name": "Errors", "level": "ERROR", "query": "timeout"}`, wantStatus: http.StatusCreated},
+		{name: "invalid id", method: http.MethodDelete, path: "/api/apps/web/log-filters/abc", wantStatus: http.StatusBadRequest},
+		{name: "unknown id", method: http.MethodDelete, path: "/api/apps/web/log-filters/999", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request(tt.method, tt.path, tt.body); w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	var listing struct {
+		Filters []database.LogFilter `json:"filters"`
+	}
+	if err := json.NewDecoder(request(http.MethodGet, "/api/apps/web/log-filters", "").Body).Decode(&listing); err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Filters) != 1 || listing.Filters[0].Level != "error" || listing.Filters[0].Query != "timeout" {
+		t.Fatalf("filters = %+v", listing.Filters)
+	}
+	if w := request(http.MethodDelete, fmt.Sprintf("/api/apps/web/log-filters/%d", listing.Filters[0].ID), ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleAPIAppParsedLogsValidation(t *testing.T) {
+	s := &Server{config: &config.Config{AppsDir: t.TempDir()}}
+	for _, path := range []string{
+		"/api/apps/web/logs/parsed?level=loud",
+		"/api/apps/web/logs/parsed?tail=-1",
+		"/api/apps/web/logs/parsed?since=yesterday",
+		"/api/apps/../logs/parsed",
+	} {
+		w := httptest.NewRecorder()
+		s.handleAPIAppParsedLogs(w, newAppRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", path, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.handleAPIAppParsedLogs(w, newAppRequest(http.MethodGet, "/api/apps/missing/logs/parsed", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown app: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// maxAppNotesSize limits the runbook of an app, it is sent along with every agent chat turn
+const maxAppNotesSize = 16 * 1024
+
+// secretMarker marks a runbook line that must not leave the node, "[secret]" anywhere in it
+var secretMarker = regexp.MustCompile(`(?i)\[secret\]`)
+
+// redactNotes replaces the lines of a runbook that are marked secret
+func redactNotes(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if secretMarker.MatchString(line) {
+			lines[i] = "[redacted]"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// agentNotes returns the runbook of an app redacted for the agent, empty if the notes are
+// not shared
+func agentNotes(appName string) string {
+	note, err := database.GetAppNote(appName)
+	if err != nil {
+		logging.Warnf("Failed to load notes of app %s for the agent: %v", appName, err)
+		return ""
+	}
+	if !note.ShareWithAgent || strings.TrimSpace(note.Content) == "" {
+		return ""
+	}
+	return redactNotes(note.Content)
+}
+
+// handleAPIAppNotes handles /api/apps/{appName}/notes:
+// GET returns the runbook of the app, PUT replaces it and whether it is shared with the agent.
+func (s *Server) handleAPIAppNotes(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName)); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	var note database.AppNote
+	switch r.Method {
+	case http.MethodGet:
+		var err error
+		if note, err = database.GetAppNote(appName); err != nil {
+			logging.Errorf("Failed to load notes of app %s: %v", appName, err)
+			http.Error(w, "Failed to load notes", http.StatusInternalServerError)
+			return
+		}
+	case http.MethodPut:
+		// Sharing decides what leaves the node, only staff may change it
+		user := getUserFromContext(r.Context())
+		if user == nil || !user.IsStaff {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var request struct {
+			Content        string `json:"content"`
+			ShareWithAgent bool   `json:"share_with_agent"`
+		}
+		// JSON escaping may double the size of the notes
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 2*maxAppNotesSize)).Decode(&request); err != nil {
+			if isBodyTooLarge(err) {
+				writeBodyTooLarge(w, 2*maxAppNotesSize)
+				return
+			}
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if len(request.Content) > maxAppNotesSize {
+			http.Error(w, fmt.Sprintf("Notes are limited to %d KB", maxAppNotesSize/1024), http.StatusRequestEntityTooLarge)
+			return
+		}
+		note = database.AppNote{AppName: appName, Content: request.Content, ShareWithAgent: request.ShareWithAgent, UpdatedBy: user.Username}
+		if err := database.SaveAppNote(&note); err != nil {
+			logging.Errorf("Failed to save notes of app %s: %v", appName, err)
+			http.Error(w, "Failed to save notes", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s updated the notes of app %s, shared with the agent: %t", user.Username, appName, note.ShareWithAgent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(note); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
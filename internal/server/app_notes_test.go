@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestRedactNotes(t *testing.T) {
+	notes := "Admin login is in the vault\nRoot password: hunter2 [secret]\nRestart ml after upgrades\nAPI key [SECRET] abc"
+	want := "Admin login is in the vault\n[redacted]\nRestart ml after upgrades\n[redacted]"
+	if got := redactNotes(notes); got != want {
+		t.Errorf("redactNotes() = %q, want %q", got, want)
+	}
+}
+
+func TestHandleAPIAppNotes(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "apps", "immich"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: filepath.Join(tmpDir, "apps")}}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	request := func(method, path, body string, user *database.User) *httptest.ResponseRecorder {
+		req := newAppRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		s.handleAPIAppNotes(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		user       *database.User
+		wantStatus int
+	}{
+		{name: "unknown app", method: http.MethodGet, path: "/api/apps/missing/notes", user: staff, wantStatus: http.StatusNotFound},
+		{name: "not staff", method: http.MethodPut, path: "/api/apps/immich/notes", body: `{"content": "x"}`, user: &database.User{Username: "user"}, wantStatus: http.StatusUnauthorized},
+		{name: "too long", method: http.MethodPut, path: "/api/apps/immich/notes", body: `{"content": "` + strings.Repeat("x", maxAppNotesSize+1) + `"}`, user: staff, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "empty", method: http.MethodGet, path: "/api/apps/immich/notes", user: &database.User{Username: "user"}, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request(tt.method, tt.path, tt.body, tt.user); w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	body := `{"content": "Restart ml after upgrades\nDB password hunter2 [secret]", "share_with_agent": false}`
+	if w := request(http.MethodPut, "/api/apps/immich/notes", body, staff); w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	if notes := agentNotes("immich"); notes != "" {
+		t.Errorf("agentNotes() of unshared notes = %q, want empty", notes)
+	}
+
+	body = strings.Replace(body, `"share_with_agent": false`, `"share_with_agent": true`, 1)
+	if w := request(http.MethodPut, "/api/apps/immich/notes", body, staff); w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	if notes := agentNotes("immich"); notes != "Restart ml after upgrades\n[redacted]" {
+		t.Errorf("agentNotes() = %q", notes)
+	}
+
+	w := request(http.MethodGet, "/api/apps/immich/notes", "", &database.User{Username: "user"})
+	var note database.AppNote
+	if err := json.NewDecoder(w.Body).Decode(&note); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// The owners see their notes unredacted
+	if !note.ShareWithAgent || !strings.Contains(note.Content, "hunter2") || note.UpdatedBy != "admin" {
+		t.Errorf("GET notes = %+v", note)
+	}
+	if prompt := appAgentSystemPrompt("immich", agentNotes("immich")); !strings.Contains(prompt, "Restart ml after upgrades") || strings.Contains(prompt, "hunter2") {
+		t.Errorf("system prompt = %q", prompt)
+	}
+}
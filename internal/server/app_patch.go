@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/progress"
+	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// Patch operations
+const (
+	patchSetEnv   = "set-env"   // Set Key=Value in .env, or in the environment of Service
+	patchSetImage = "set-image" // Set the Image of Service, or only its Tag
+)
+
+// PatchOperation is one targeted edit of PATCH /api/apps/{name}
+type PatchOperation struct {
+	Op      string `json:"op"`
+	Service string `json:"service,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Image   string `json:"image,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+}
+
+// PatchAppRequest represents the request body for editing single values of an app
+type PatchAppRequest struct {
+	Operations []PatchOperation `json:"operations"`
+	Restart    bool             `json:"restart"` // Recreate the affected services of a running app
+}
+
+// appPatch holds the files of an app while operations are applied to them
+type appPatch struct {
+	compose, env []byte
+	envChanged   bool
+	services     map[string]bool // Services whose definition changed
+}
+
+// apply edits the files in memory. An error leaves the app's files untouched, as
+// nothing is written before all operations succeeded.
+func (p *appPatch) apply(op PatchOperation) error {
+	var err error
+	switch op.Op {
+	case patchSetEnv:
+		if op.Service == "" {
+			p.env, err = yamlutil.SetEnvVar(p.env, op.Key, op.Value)
+			p.envChanged = true
+			return err
+		}
+		p.compose, err = yamlutil.SetServiceEnv(p.compose, op.Service, op.Key, op.Value)
+	case patchSetImage:
+		if op.Service == "" {
+			return fmt.Errorf("service is required")
+		}
+		image := op.Image
+		switch {
+		case image != "" && op.Tag != "":
+			return fmt.Errorf("set either image or tag")
+		case op.Tag != "":
+			current, err := yamlutil.ServiceImage(p.compose, op.Service)
+			if err != nil {
+				return err
+			}
+			if current == "" {
+				return fmt.Errorf("service %s has no image to change the tag of", op.Service)
+			}
+			if image, err = yamlutil.ReplaceImageTag(current, op.Tag); err != nil {
+				return err
+			}
+		case image == "":
+			return fmt.Errorf("image or tag is required")
+		}
+		p.compose, err = yamlutil.SetServiceImage(p.compose, op.Service, image)
+	default:
+		return fmt.Errorf("unknown operation %q, expected %s or %s", op.Op, patchSetEnv, patchSetImage)
+	}
+	if err != nil {
+		return err
+	}
+	p.services[op.Service] = true
+	return nil
+}
+
+// affectedServices returns the services to recreate, nil for all. A change of .env can
+// affect any service, `up` recreates only those whose resolved configuration changed.
+func (p *appPatch) affectedServices() []string {
+	if p.envChanged {
+		return nil
+	}
+	services := make([]string, 0, len(p.services))
+	for service := range p.services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// handlePatchApp handles PATCH /api/apps/{appName}: targeted edits of single values that
+// keep the rest of the files as they are, for small automated changes without uploading
+// the whole compose file
+func (s *Server) handlePatchApp(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	composeFile := filepath.Join(appDir, "docker-compose.yml")
+	envFile := filepath.Join(appDir, ".env")
+
+	var request PatchAppRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w, s.bodyLimit(r.URL.Path))
+			return
+		}
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(request.Operations) == 0 {
+		http.Error(w, "At least one operation is required", http.StatusBadRequest)
+		return
+	}
+
+	patch := &appPatch{services: map[string]bool{}}
+	var err error
+	patch.compose, err = os.ReadFile(composeFile) //nolint:gosec // Path from trusted app directory
+	if os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	} else if err != nil {
+		logging.Errorf("Failed to read docker-compose.yml for app %s: %v", appName, err)
+		http.Error(w, "Failed to read app configuration", http.StatusInternalServerError)
+		return
+	}
+	patch.env, err = os.ReadFile(envFile) //nolint:gosec // Path from trusted app directory
+	if err != nil && !os.IsNotExist(err) {
+		logging.Errorf("Failed to read .env for app %s: %v", appName, err)
+		http.Error(w, "Failed to read app configuration", http.StatusInternalServerError)
+		return
+	}
+	originalCompose, originalEnv := patch.compose, patch.env
+
+	for i, op := range request.Operations {
+		if err := patch.apply(op); err != nil {
+			http.Error(w, fmt.Sprintf("Operation %d (%s): %v", i+1, op.Op, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// A restart validates the result like a start would, before anything is written
+	composeSvc, err := s.getComposeService()
+	restarting := false
+	if request.Restart && err == nil && s.appRunning(r.Context(), composeSvc, appDir) {
+		metadata, err := yamlutil.ReadComposeMetadata(appDir)
+		if err != nil {
+			metadata = &yamlutil.OnTreeMetadata{}
+		}
+		if !metadata.BypassSecurity {
+			if err := security.NewValidator(appName).ValidateCompose(patch.compose); err != nil {
+				http.Error(w, fmt.Sprintf("Security validation failed: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		restarting = true
+	}
+
+	changed := []string{}
+	if !bytes.Equal(patch.compose, originalCompose) {
+		if err := os.WriteFile(composeFile, patch.compose, 0600); err != nil {
+			logging.Errorf("Failed to write docker-compose.yml: %v", err)
+			http.Error(w, "Failed to write docker-compose.yml", http.StatusInternalServerError)
+			return
+		}
+		changed = append(changed, "docker-compose.yml")
+	}
+	if !bytes.Equal(patch.env, originalEnv) {
+		if err := os.WriteFile(envFile, patch.env, 0600); err != nil {
+			logging.Errorf("Failed to write .env file: %v", err)
+			http.Error(w, "Failed to write .env file", http.StatusInternalServerError)
+			return
+		}
+		changed = append(changed, ".env")
+	}
+	restarting = restarting && len(changed) > 0
+
+	services := patch.affectedServices()
+	logging.Infof("Patched app %s: %d operation(s), changed %v", appName, len(request.Operations), changed)
+	if restarting {
+		go s.restartPatchedServices(composeSvc, appName, services)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if restarting {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	response := map[string]interface{}{
+		"success":    true,
+		"changed":    changed,
+		"services":   services,
+		"restarting": restarting,
+	}
+	if restarting {
+		response["job"] = jobKindApp + "/" + appName
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// restartPatchedServices recreates the services a patch changed, all for nil, reporting
+// progress as the app job
+func (s *Server) restartPatchedServices(composeSvc *compose.Service, appName string, services []string) {
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	opts := compose.Options{WorkingDir: appDir, Services: services}
+	if _, err := os.Stat(filepath.Join(appDir, ".env")); err == nil {
+		opts.EnvFile = ".env"
+	}
+
+	s.progressTracker.StartOperation(appName, progress.OperationPreparing, "Applying changes...")
+	parser := progress.NewDockerProgressParser(s.progressTracker)
+	err := composeSvc.UpWithProgress(context.Background(), opts, func(line string) {
+		parser.ParseLine(appName, line)
+		s.broadcastAppProgress(appName, "progress")
+	})
+	if err != nil {
+		logging.Errorf("Failed to apply changes to app %s: %v", appName, err)
+		s.progressTracker.SetError(appName, err.Error())
+		s.broadcastAppProgress(appName, "error")
+		if isRuntimeUnavailableError(err) {
+			s.markComposeUnhealthy()
+		}
+		return
+	}
+	s.progressTracker.CompleteOperation(appName, fmt.Sprintf("Changes to app '%s' applied", appName))
+	s.broadcastAppProgress(appName, "complete")
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestHandlePatchApp(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	composeSvc, err := compose.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "photos")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	composeContent := "services:\n  web:\n    image: ghcr.io/example/photos:1.2 # pinned\n    environment:\n      TZ: UTC\n"
+	writeFiles := func() {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(composeContent), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(appDir, ".env"), []byte("# Settings\nTAG=1\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}, composeSvc: composeSvc}
+
+	tests := []struct {
+		name        string
+		app         string
+		body        string
+		wantStatus  int
+		wantCompose string
+		wantEnv     string
+	}{
+		{
+			name:        "tag and env",
+			app:         "photos",
+			body:        `{"operations": [{"op": "set-image", "service": "web", "tag": "1.3"}, {"op": "set-env", "service": "web", "key": "TZ", "value": "Europe/Berlin"}, {"op": "set-env", "key": "TAG", "value": "2"}], "restart": true}`,
+			wantStatus:  http.StatusOK,
+			wantCompose: "services:\n  web:\n    image: ghcr.io/example/photos:1.3 # pinned\n    environment:\n      TZ: Europe/Berlin\n",
+			wantEnv:     "# Settings\nTAG=2\n",
+		},
+		{
+			name:        "failing operation writes nothing",
+			app:         "photos",
+			body:        `{"operations": [{"op": "set-env", "key": "TAG", "value": "2"}, {"op": "set-image", "service": "db", "image": "postgres:16"}]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCompose: composeContent,
+			wantEnv:     "# Settings\nTAG=1\n",
+		},
+		{name: "unknown operation", app: "photos", body: `{"operations": [{"op": "delete"}]}`, wantStatus: http.StatusBadRequest},
+		{name: "no operations", app: "photos", body: `{"operations": []}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown app", app: "missing", body: `{"operations": [{"op": "set-env", "key": "A", "value": "1"}]}`, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFiles()
+			req := newAppRequest(http.MethodPatch, "/api/apps/"+tt.app, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			s.handlePatchApp(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCompose != "" {
+				got, _ := os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // Test file
+				if string(got) != tt.wantCompose {
+					t.Errorf("docker-compose.yml = %q, want %q", got, tt.wantCompose)
+				}
+				got, _ = os.ReadFile(filepath.Join(appDir, ".env")) //nolint:gosec // Test file
+				if string(got) != tt.wantEnv {
+					t.Errorf(".env = %q, want %q", got, tt.wantEnv)
+				}
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				Changed    []string `json:"changed"`
+				Services   []string `json:"services"`
+				Restarting bool     `json:"restarting"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			// The .env change affects all services, and a stopped app isn't started
+			if len(response.Changed) != 2 || response.Services != nil || response.Restarting {
+				t.Errorf("response = %+v", response)
+			}
+		})
+	}
+}
+
+func TestAppPatchAffectedServices(t *testing.T) {
+	patch := &appPatch{
+		compose:  []byte("services:\n  web:\n    image: nginx\n  db:\n    image: postgres:16\n  cache:\n    image: redis\n"),
+		services: map[string]bool{},
+	}
+	for _, op := range []PatchOperation{
+		{Op: patchSetImage, Service: "web", Tag: "1.27"},
+		{Op: patchSetEnv, Service: "db", Key: "POSTGRES_DB", Value: "app"},
+	} {
+		if err := patch.apply(op); err != nil {
+			t.Fatalf("apply(%+v) error = %v", op, err)
+		}
+	}
+	if got := patch.affectedServices(); strings.Join(got, ",") != "db,web" {
+		t.Errorf("affectedServices() = %v, want db and web", got)
+	}
+}
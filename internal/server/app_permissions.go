@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// handleAPIAppPermissions handles /api/app-permissions for admins: GET lists the grants,
+// POST {"user_id", "app_name", "access"} creates or changes a grant and DELETE (?id=)
+// removes one. Every answer lists the grants.
+func (s *Server) handleAPIAppPermissions(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			UserID  int    `json:"user_id"`
+			AppName string `json:"app_name"`
+			Access  string `json:"access"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.AppName = strings.TrimSpace(req.AppName)
+		if !isValidAppName(req.AppName) {
+			http.Error(w, "Invalid app name", http.StatusBadRequest)
+			return
+		}
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, req.AppName)); os.IsNotExist(err) {
+			http.Error(w, "App '"+req.AppName+"' not found", http.StatusNotFound)
+			return
+		}
+		grantee, err := s.getUserByID(req.UserID)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if req.Access != database.AppAccessView && req.Access != database.AppAccessControl {
+			http.Error(w, "Access must be view or control", http.StatusBadRequest)
+			return
+		}
+		if err := database.SetAppPermission(req.UserID, req.AppName, req.Access); err != nil {
+			logging.Errorf("Failed to set app permission: %v", err)
+			http.Error(w, "Failed to set app permission", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s granted %s access to app %s to user %s", user.Username, req.Access, req.AppName, grantee.Username)
+		recordActivity(database.ActivityEvent{
+			Category: database.ActivityAudit,
+			Title:    "Access to " + req.AppName + " granted to " + grantee.Username,
+			Detail:   "Access " + req.Access,
+			Actor:    user.Username,
+		})
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid permission id", http.StatusBadRequest)
+			return
+		}
+		if err := database.DeleteAppPermission(id); err != nil {
+			logging.Errorf("Failed to delete app permission: %v", err)
+			http.Error(w, "Failed to delete app permission", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s removed app permission #%d", user.Username, id)
+		recordActivity(database.ActivityEvent{
+			Category: database.ActivityAudit,
+			Title:    "App permission removed",
+			Detail:   "Permission #" + strconv.Itoa(id),
+			Actor:    user.Username,
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	permissions, err := database.GetAppPermissions()
+	if err != nil {
+		logging.Errorf("Failed to get app permissions: %v", err)
+		http.Error(w, "Failed to get app permissions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"permissions": permissions}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
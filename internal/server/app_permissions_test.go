@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/database"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+func TestAppPermissionAccess(t *testing.T) {
+	s, alice, bob := newNamespaceTestServer(t)
+	for _, grant := range []struct {
+		user   *database.User
+		app    string
+		access string
+	}{
+		{alice, "wiki", database.AppAccessControl},
+		{alice, "photos", database.AppAccessView},
+		{bob, "homework", database.AppAccessView},
+	} {
+		if err := database.SetAppPermission(grant.user.ID, grant.app, grant.access); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		user *database.User
+		app  string
+		want string
+	}{
+		{alice, "wiki", database.AppAccessControl},
+		// A grant also sets the access to apps of the user's namespaces
+		{alice, "photos", database.AppAccessView},
+		{alice, "homework", ""},
+		// Users in no namespace with grants see only the granted apps
+		{bob, "homework", database.AppAccessView},
+		{bob, "wiki", ""},
+	}
+	for _, tt := range tests {
+		if got := s.appAccess(tt.user, tt.app); got != tt.want {
+			t.Errorf("appAccess(%s, %s) = %q, want %q", tt.user.Username, tt.app, got, tt.want)
+		}
+	}
+
+	var apps []*dockerruntime.App
+	for _, name := range []string{"homework", "photos", "wiki"} {
+		apps = append(apps, &dockerruntime.App{Name: name})
+	}
+	if visible := s.filterAppsForUser(bob, apps); len(visible) != 1 || visible[0].Name != "homework" {
+		t.Errorf("filterAppsForUser(bob) = %v", visible)
+	}
+	if visible := s.filterAppsForUser(alice, apps); len(visible) != 2 {
+		t.Errorf("filterAppsForUser(alice) = %v", visible)
+	}
+
+	for _, tt := range []struct {
+		method string
+		app    string
+		want   int
+	}{
+		{http.MethodGet, "homework", http.StatusOK},
+		{http.MethodPost, "homework", http.StatusForbidden},
+		{http.MethodPost, "wiki", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(tt.method, "/api/apps/"+tt.app+"/start", nil)
+		req = req.WithContext(setUserContext(req.Context(), bob))
+		w := httptest.NewRecorder()
+		if ok := s.requireAppAccess(w, req, tt.app); ok != (tt.want == http.StatusOK) || w.Code != tt.want {
+			t.Errorf("%s %s = %v, %d, want %d", tt.method, tt.app, ok, w.Code, tt.want)
+		}
+	}
+}
+
+func TestHandleAPIAppPermissions(t *testing.T) {
+	s, alice, bob := newNamespaceTestServer(t)
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	api := func(method, target, body string, user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		s.handleAPIAppPermissions(w, req)
+		return w
+	}
+
+	if w := api(http.MethodGet, "/api/app-permissions", "", alice); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET by a non-admin = %d, want 401", w.Code)
+	}
+	for body, want := range map[string]int{
+		fmt.Sprintf(`{"user_id": %d, "app_name": "../etc", "access": "view"}`, bob.ID): http.StatusBadRequest,
+		fmt.Sprintf(`{"user_id": %d, "app_name": "blog", "access": "view"}`, bob.ID):   http.StatusNotFound,
+		`{"user_id": 999, "app_name": "wiki", "access": "view"}`:                       http.StatusNotFound,
+		fmt.Sprintf(`{"user_id": %d, "app_name": "wiki", "access": "admin"}`, bob.ID):  http.StatusBadRequest,
+	} {
+		if w := api(http.MethodPost, "/api/app-permissions", body, staff); w.Code != want {
+			t.Errorf("POST %s = %d, want %d", body, w.Code, want)
+		}
+	}
+
+	w := api(http.MethodPost, "/api/app-permissions", fmt.Sprintf(`{"user_id": %d, "app_name": "homework", "access": "control"}`, bob.ID), staff)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST = %d %s", w.Code, w.Body.String())
+	}
+	var listed struct {
+		Permissions []database.AppPermission `json:"permissions"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil || len(listed.Permissions) != 1 || listed.Permissions[0].Username != "bob" {
+		t.Fatalf("POST response = %+v, %v", listed, err)
+	}
+	if !s.canAccessApp(bob, "homework") {
+		t.Error("bob can't access homework after the grant")
+	}
+
+	w = api(http.MethodDelete, fmt.Sprintf("/api/app-permissions?id=%d", listed.Permissions[0].ID), "", staff)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "homework") {
+		t.Errorf("DELETE = %d %s", w.Code, w.Body.String())
+	}
+	if s.canAccessApp(bob, "homework") {
+		t.Error("bob can still access homework after removing the grant")
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/system"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"gopkg.in/yaml.v3"
+)
+
+// AppPortBinding is a published port of an app and whether the host has its address
+type AppPortBinding struct {
+	yamlutil.PortBinding
+	Available bool `json:"available"`
+}
+
+// PortBindingRequest represents the request body for binding published ports to an address
+type PortBindingRequest struct {
+	Service string `json:"service"` // Empty for all services that publish ports
+	HostIP  string `json:"host_ip"` // Empty for all interfaces
+	Restart bool   `json:"restart"` // Recreate the services of a running app
+}
+
+// bindingAvailable reports whether the host can bind a port to an address. Addresses that
+// aren't literal IPs, such as ${BIND_IP}, are left to Docker.
+func bindingAvailable(hostIP string) bool {
+	ip := net.ParseIP(hostIP)
+	return ip == nil || ip.IsUnspecified() || system.HostHasAddress(ip)
+}
+
+// appPortBindings returns the published ports of a compose file with their availability
+func appPortBindings(content []byte) ([]AppPortBinding, error) {
+	var compose yamlutil.ComposeFile
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	var bindings []AppPortBinding
+	for _, binding := range yamlutil.PortBindings(&compose) {
+		bindings = append(bindings, AppPortBinding{PortBinding: binding, Available: bindingAvailable(binding.HostIP)})
+	}
+	return bindings, nil
+}
+
+// unavailableBindings describes the ports of a compose file bound to addresses the host
+// doesn't have, e.g. after the address of a VLAN changed. Docker would fail to start them
+// with "cannot assign requested address".
+func unavailableBindings(content []byte) []string {
+	bindings, err := appPortBindings(content)
+	if err != nil {
+		// Parse errors are reported by the compose validation
+		return nil
+	}
+	var unavailable []string
+	for _, binding := range bindings {
+		if !binding.Available {
+			unavailable = append(unavailable, fmt.Sprintf("%s of service %s on %s", binding.Target, binding.Service, binding.HostIP))
+		}
+	}
+	return unavailable
+}
+
+// normalizeHostIP validates an address to bind ports to and returns it in canonical form,
+// empty for all interfaces
+func normalizeHostIP(hostIP string) (string, error) {
+	hostIP = strings.Trim(strings.TrimSpace(hostIP), "[]")
+	if hostIP == "" {
+		return "", nil
+	}
+	ip := net.ParseIP(hostIP)
+	switch {
+	case ip == nil:
+		return "", fmt.Errorf("invalid address %q", hostIP)
+	case ip.IsUnspecified():
+		return "", nil
+	case !system.HostHasAddress(ip):
+		return "", fmt.Errorf("%s is not an address of this host", ip)
+	}
+	return ip.String(), nil
+}
+
+// handleAPIAppPortBindings handles /api/apps/{appName}/port-bindings:
+// GET returns the host's interfaces and the published ports of the app,
+// PUT binds the ports of a service, or of all services, to one of the host's addresses
+func (s *Server) handleAPIAppPortBindings(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	composeFile := filepath.Join(appDir, "docker-compose.yml")
+	content, err := os.ReadFile(composeFile) //nolint:gosec // Path from trusted app directory
+	if os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	} else if err != nil {
+		logging.Errorf("Failed to read docker-compose.yml for app %s: %v", appName, err)
+		http.Error(w, "Failed to read app configuration", http.StatusInternalServerError)
+		return
+	}
+	bindings, err := appPortBindings(content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		interfaces, err := system.HostInterfaces()
+		if err != nil {
+			logging.Errorf("Failed to list network interfaces: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		response := map[string]interface{}{
+			"app":        appName,
+			"interfaces": interfaces,
+			"bindings":   bindings,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+		return
+	case http.MethodPut:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var request PortBindingRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.HostIP, err = normalizeHostIP(request.HostIP); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	services := []string{request.Service}
+	if request.Service == "" {
+		seen := map[string]bool{}
+		services = nil
+		for _, binding := range bindings {
+			if !seen[binding.Service] {
+				seen[binding.Service] = true
+				services = append(services, binding.Service)
+			}
+		}
+		sort.Strings(services)
+		if len(services) == 0 {
+			http.Error(w, "The app publishes no ports", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, service := range services {
+		if content, err = yamlutil.SetServicePortsHostIP(content, service, request.HostIP); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := os.WriteFile(composeFile, content, 0600); err != nil {
+		logging.Errorf("Failed to write docker-compose.yml: %v", err)
+		http.Error(w, "Failed to write docker-compose.yml", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("User %s bound the ports of %s of app %s to %q", user.Username, strings.Join(services, ", "), appName, request.HostIP)
+
+	// Routes dial the app on localhost, they can't reach ports bound to another address
+	var warnings []string
+	if ip := net.ParseIP(request.HostIP); ip != nil && !ip.IsLoopback() {
+		if metadata, err := yamlutil.ReadComposeMetadata(appDir); err == nil && metadata.IsExposed {
+			warnings = append(warnings, fmt.Sprintf("The app is exposed through Caddy, which reaches it on localhost. Its domain stops working while the ports only listen on %s.", request.HostIP))
+		}
+	}
+
+	composeSvc, err := s.getComposeService()
+	restarting := request.Restart && err == nil && s.appRunning(r.Context(), composeSvc, appDir)
+	if restarting {
+		go s.restartPatchedServices(composeSvc, appName, services)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if restarting {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	response := map[string]interface{}{
+		"success":    true,
+		"services":   services,
+		"host_ip":    request.HostIP,
+		"warnings":   warnings,
+		"restarting": restarting,
+	}
+	if restarting {
+		response["job"] = jobKindApp + "/" + appName
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestHandleAPIAppPortBindings(t *testing.T) {
+	appsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appsDir, "photos"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	composeFile := filepath.Join(appsDir, "photos", "docker-compose.yml")
+	content := "services:\n  web:\n    image: nginx\n    ports:\n      - \"8080:80\"\n  db:\n    image: postgres\n    ports:\n      - 192.0.2.1:5432:5432\n"
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		user        *database.User
+		wantStatus  int
+		wantCompose string
+	}{
+		{name: "list", method: http.MethodGet, user: staff, wantStatus: http.StatusOK},
+		{name: "loopback", method: http.MethodPut, user: staff, body: `{"service": "web", "host_ip": "127.0.0.1"}`, wantStatus: http.StatusOK,
+			wantCompose: strings.Replace(content, `"8080:80"`, `"127.0.0.1:8080:80"`, 1)},
+		{name: "all services on all interfaces", method: http.MethodPut, user: staff, body: `{"host_ip": "0.0.0.0"}`, wantStatus: http.StatusOK,
+			wantCompose: strings.Replace(content, "192.0.2.1:5432:5432", "5432:5432", 1)},
+		{name: "address of another host", method: http.MethodPut, user: staff, body: `{"service": "web", "host_ip": "192.0.2.1"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid address", method: http.MethodPut, user: staff, body: `{"service": "web", "host_ip": "iot"}`, wantStatus: http.StatusBadRequest},
+		{name: "not staff", method: http.MethodPut, user: &database.User{Username: "user"}, body: `{"service": "web"}`, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(composeFile, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			req := newAppRequest(tt.method, "/api/apps/photos/port-bindings", strings.NewReader(tt.body))
+			req = req.WithContext(setUserContext(req.Context(), tt.user))
+			w := httptest.NewRecorder()
+			s.handleAPIAppPortBindings(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.method == http.MethodGet && !strings.Contains(w.Body.String(), `"service":"db","host_ip":"192.0.2.1","published":"5432","target":"5432","available":false`) {
+				t.Errorf("unavailable binding is not marked: %s", w.Body.String())
+			}
+			if tt.wantCompose != "" {
+				got, _ := os.ReadFile(composeFile) //nolint:gosec // Test file
+				if string(got) != tt.wantCompose {
+					t.Errorf("docker-compose.yml = %q, want %q", got, tt.wantCompose)
+				}
+			}
+		})
+	}
+
+	if got := unavailableBindings([]byte(content)); len(got) != 1 || got[0] != "5432 of service db on 192.0.2.1" {
+		t.Errorf("unavailableBindings() = %q", got)
+	}
+}
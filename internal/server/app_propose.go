@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/templates"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// appProposalTimeout leaves room for slow local models within the extended write deadline
+const appProposalTimeout = 90 * time.Second
+
+var invalidAppNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// AppProposal is a compose file proposed by the LLM for a described app. It is only a
+// suggestion: the user reviews it in the create form, which validates it again.
+type AppProposal struct {
+	AppName          string   `json:"app_name"`
+	Emoji            string   `json:"emoji"`
+	TemplateID       string   `json:"template_id,omitempty"` // Catalog template the compose file is taken from
+	Compose          string   `json:"compose"`
+	Env              string   `json:"env"`
+	Explanation      string   `json:"explanation"`
+	ValidationErrors []string `json:"validation_errors"`
+	SecurityIssues   []string `json:"security_issues"`
+}
+
+func appProposalPrompt(catalog []templates.Template) string {
+	var b strings.Builder
+	b.WriteString(`You help users of TreeOS, a self-hosting platform, set up apps with Docker Compose.
+The user describes the app they want. Answer with a single JSON object and nothing else:
+{"app_name": "...", "emoji": "...", "template_id": "...", "compose": "...", "env": "...", "explanation": "..."}
+
+- If an app from the catalog below fits, set template_id to its id and leave compose empty.
+- Otherwise leave template_id empty and write the docker-compose.yml in compose.
+- app_name: lowercase letters, numbers and hyphens.
+- Use pinned image versions from the official images, and restart: unless-stopped.
+- Publish the web UI with a single "HOST:CONTAINER" port mapping.
+- Bind mounts must start with {{APP_MNT_PATH}}/ for user data or {{APP_VOLUMES_PATH}}/ for app state.
+- Never use privileged mode, cap_add, host networking or the Docker socket.
+- Put secrets in env as KEY=value lines with placeholder values, and reference them as ${KEY}.
+- explanation: a few sentences on what the setup does and what the user has to change.
+
+Catalog:
+`)
+	for _, t := range catalog {
+		if t.IsSystemService {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %s. %s\n", t.ID, t.Name, t.Description)
+	}
+	return b.String()
+}
+
+// decodeJSONReply decodes the JSON object of an LLM reply, which may be wrapped in prose or a code block
+func decodeJSONReply(reply string, v interface{}) error {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("the LLM did not answer with JSON")
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), v); err != nil {
+		return fmt.Errorf("the LLM answered with invalid JSON: %w", err)
+	}
+	return nil
+}
+
+func parseAppProposal(reply string) (*AppProposal, error) {
+	var proposal AppProposal
+	if err := decodeJSONReply(reply, &proposal); err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// proposeApp asks the LLM for an app matching the description and lints the result
+func (s *Server) proposeApp(ctx context.Context, llm llmConfig, description string) (*AppProposal, error) {
+	available, err := s.templateSvc.GetAvailableTemplates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+	// Only offer templates that run on this node
+	catalog := make([]templates.Template, 0, len(available))
+	for _, template := range available {
+		if template.Supports(s.templateSvc.Architecture()) {
+			catalog = append(catalog, template)
+		}
+	}
+
+	reply, err := chatCompletion(ctx, llm, []llmMessage{
+		{Role: "system", Content: appProposalPrompt(catalog)},
+		{Role: "user", Content: description},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := parseAppProposal(reply.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal.AppName = s.uniqueAppName(proposal.AppName)
+	if proposal.TemplateID != "" {
+		template, err := s.templateSvc.GetTemplateByID(proposal.TemplateID)
+		if err == nil && !template.Supports(s.templateSvc.Architecture()) {
+			err = fmt.Errorf("no images for %s", s.templateSvc.Architecture())
+		}
+		if err != nil {
+			// Fall back to the LLM's own compose file
+			logging.Warnf("LLM proposed unusable template %q: %v", proposal.TemplateID, err)
+			proposal.TemplateID = ""
+		} else {
+			if proposal.Compose, err = s.templateSvc.GetTemplateContent(template); err != nil {
+				return nil, fmt.Errorf("failed to read template %s: %w", template.ID, err)
+			}
+			if env, err := s.templateSvc.GetTemplateEnvExample(template.ID); err == nil && env != "" {
+				proposal.Env = env
+			}
+			if proposal.Compose, proposal.Env, err = renderDefaultVariables(template, proposal.Compose, proposal.Env); err != nil {
+				return nil, fmt.Errorf("failed to render template %s: %w", template.ID, err)
+			}
+			if proposal.Emoji == "" {
+				proposal.Emoji = template.Icon
+			}
+		}
+	}
+	proposal.Compose = s.templateSvc.ProcessTemplateContent(strings.TrimSpace(proposal.Compose)+"\n", proposal.AppName)
+
+	proposal.ValidationErrors = []string{}
+	proposal.SecurityIssues = []string{}
+	if err := yamlutil.ValidateComposeFile(proposal.Compose); err != nil {
+		proposal.ValidationErrors = append(proposal.ValidationErrors, err.Error())
+	}
+	if err := security.NewValidator(proposal.AppName).ValidateCompose([]byte(proposal.Compose)); err != nil {
+		proposal.SecurityIssues = append(proposal.SecurityIssues, err.Error())
+	}
+	return proposal, nil
+}
+
+// uniqueAppName turns a proposed name into a valid name that no app uses yet
+func (s *Server) uniqueAppName(name string) string {
+	name = strings.Trim(invalidAppNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-_")
+	if name == "" {
+		name = "app"
+	}
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	candidate := name
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+// handleAPIAppPropose handles POST /api/apps/propose with {"description": "..."}
+func (s *Server) handleAPIAppPropose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Description) == "" {
+		http.Error(w, "A description is required", http.StatusBadRequest)
+		return
+	}
+
+	llm, ok := s.agentLLM()
+	if !ok {
+		http.Error(w, "The agent LLM is not configured. Set it up in Settings first.", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(appProposalTimeout + 10*time.Second)); err != nil {
+		logging.Debugf("Failed to extend write deadline for an app proposal: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), appProposalTimeout)
+	defer cancel()
+
+	proposal, err := s.proposeApp(ctx, llm, strings.TrimSpace(req.Description))
+	if err != nil {
+		logging.Warnf("Failed to propose an app: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(proposal); err != nil {
+		logging.Errorf("Failed to encode app proposal: %v", err)
+	}
+}
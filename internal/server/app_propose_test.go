@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/templates"
+)
+
+// proposalReply wraps a proposal the way models often answer, in a code block
+func proposalReply(t *testing.T, proposal map[string]string) string {
+	t.Helper()
+	content, err := json.Marshal(proposal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := json.Marshal(map[string]string{"role": "assistant", "content": "```json\n" + string(content) + "\n```"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(message)
+}
+
+func TestProposeAppFromTemplate(t *testing.T) {
+	t.Setenv("TREEOS_RUN_MODE", "demo")
+	appsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appsDir, "miniflux"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}, templateSvc: templates.NewService(".")}
+
+	url, requests := scriptedLLM(t, proposalReply(t, map[string]string{
+		"app_name":    "Miniflux",
+		"template_id": "miniflux",
+		"explanation": "Miniflux is a minimalist feed reader.",
+	}))
+	proposal, err := s.proposeApp(context.Background(), llmConfig{APIURL: url, Model: "test"}, "An RSS reader")
+	if err != nil {
+		t.Fatalf("proposeApp() error = %v", err)
+	}
+	if proposal.AppName != "miniflux-2" {
+		t.Errorf("app name = %q, want miniflux-2 since miniflux exists", proposal.AppName)
+	}
+	if proposal.TemplateID != "miniflux" || !strings.Contains(proposal.Compose, "miniflux") || strings.Contains(proposal.Compose, "{{APP_MNT_PATH}}") {
+		t.Errorf("compose not taken from the template:\n%s", proposal.Compose)
+	}
+	if len(proposal.ValidationErrors) != 0 || len(proposal.SecurityIssues) != 0 {
+		t.Errorf("template proposal has issues: %v %v", proposal.ValidationErrors, proposal.SecurityIssues)
+	}
+	if !strings.Contains((*requests)[0][0].Content, "- miniflux:") {
+		t.Error("system prompt does not list the template catalog")
+	}
+}
+
+func TestProposeAppCustomCompose(t *testing.T) {
+	t.Setenv("TREEOS_RUN_MODE", "demo")
+	s := &Server{config: &config.Config{AppsDir: t.TempDir()}, templateSvc: templates.NewService(".")}
+
+	url, _ := scriptedLLM(t, proposalReply(t, map[string]string{
+		"app_name": "My Tool!",
+		"compose":  "services:\n  tool:\n    image: example/tool:1.0\n    privileged: true\n    volumes:\n      - {{APP_MNT_PATH}}/data:/data\n",
+	}))
+	proposal, err := s.proposeApp(context.Background(), llmConfig{APIURL: url, Model: "test"}, "A tool")
+	if err != nil {
+		t.Fatalf("proposeApp() error = %v", err)
+	}
+	if proposal.AppName != "my-tool" {
+		t.Errorf("app name = %q, want my-tool", proposal.AppName)
+	}
+	if !strings.Contains(proposal.Compose, "./mnt/data:/data") {
+		t.Errorf("placeholders not replaced:\n%s", proposal.Compose)
+	}
+	if len(proposal.SecurityIssues) != 1 || !strings.Contains(proposal.SecurityIssues[0], "privileged") {
+		t.Errorf("security issues = %v, want privileged mode", proposal.SecurityIssues)
+	}
+}
+
+func TestParseAppProposalRejectsProse(t *testing.T) {
+	if _, err := parseAppProposal("I can't help with that."); err == nil {
+		t.Error("expected an error for a reply without JSON")
+	}
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/storage"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// quotaCheckInterval is how often app mount directories are measured
+	quotaCheckInterval = 5 * time.Minute
+	// quotaWarnPercent is the usage at which admins are warned
+	quotaWarnPercent = 90
+)
+
+// Quota levels, from fine to over the limit
+const (
+	quotaLevelOK       = "ok"
+	quotaLevelWarning  = "warning"
+	quotaLevelExceeded = "exceeded"
+)
+
+// AppQuotaUsage is the disk usage of an app's mnt directory measured against its quota
+type AppQuotaUsage struct {
+	Limit     uint64    `json:"limit"`
+	Used      uint64    `json:"used"`
+	Percent   float64   `json:"percent"`
+	Level     string    `json:"level"`
+	Enforced  bool      `json:"enforced"` // Limit is enforced by a filesystem project quota
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// LimitLabel returns the quota in human readable form
+func (u AppQuotaUsage) LimitLabel() string {
+	return storage.FormatSize(u.Limit)
+}
+
+// UsedLabel returns the usage in human readable form
+func (u AppQuotaUsage) UsedLabel() string {
+	return storage.FormatSize(u.Used)
+}
+
+// BarPercent returns the usage capped at 100 for progress bars
+func (u AppQuotaUsage) BarPercent() int {
+	if u.Percent > 100 {
+		return 100
+	}
+	return int(u.Percent)
+}
+
+// appQuotaState tracks the last measurement and alert of an app
+type appQuotaState struct {
+	usage   AppQuotaUsage
+	alerted string // Level that was last alerted, to alert once per level change
+}
+
+// startQuotaMonitor periodically measures app mount directories that have a quota
+func (s *Server) startQuotaMonitor() {
+	go func() {
+		s.checkAppQuotas()
+
+		ticker := time.NewTicker(quotaCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.checkAppQuotas()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// checkAppQuotas measures all apps with a quota and drops state of apps without one
+func (s *Server) checkAppQuotas() {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Errorf("Failed to list apps for quota check: %v", err)
+		}
+		return
+	}
+
+	withQuota := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		appDir := filepath.Join(s.config.AppsDir, entry.Name())
+		metadata, err := yamlutil.ReadComposeMetadata(appDir)
+		if err != nil || metadata.DiskQuota == "" {
+			continue
+		}
+		withQuota[entry.Name()] = true
+		s.checkAppQuota(entry.Name(), metadata.DiskQuota)
+	}
+
+	s.quotaMu.Lock()
+	for appName := range s.appQuotas {
+		if !withQuota[appName] {
+			delete(s.appQuotas, appName)
+		}
+	}
+	s.quotaMu.Unlock()
+}
+
+// checkAppQuota measures one app, alerts on level changes and stops apps that exceed a
+// quota the filesystem can't enforce
+func (s *Server) checkAppQuota(appName, quota string) {
+	limit, err := storage.ParseSize(quota)
+	if err != nil {
+		logging.Errorf("Invalid disk quota for app %s: %v", appName, err)
+		return
+	}
+	mntPath := filepath.Join(s.config.AppsDir, appName, "mnt")
+
+	// Work on a copy, the monitor and the API may check the same app concurrently
+	var state appQuotaState
+	s.quotaMu.Lock()
+	existing := s.appQuotas[appName]
+	if existing != nil {
+		state = *existing
+	}
+	s.quotaMu.Unlock()
+
+	if existing == nil || state.usage.Limit != limit {
+		state = appQuotaState{usage: AppQuotaUsage{Limit: limit}}
+		state.usage.Enforced = s.applyProjectQuota(appName, mntPath, limit)
+	}
+
+	used, err := storage.DirUsage(mntPath)
+	if err != nil {
+		logging.Errorf("Failed to measure disk usage of app %s: %v", appName, err)
+		return
+	}
+	state.usage.Used = used
+	state.usage.Percent = float64(used) * 100 / float64(limit)
+	state.usage.CheckedAt = time.Now()
+	switch {
+	case state.usage.Percent >= 100:
+		state.usage.Level = quotaLevelExceeded
+	case state.usage.Percent >= quotaWarnPercent:
+		state.usage.Level = quotaLevelWarning
+	default:
+		state.usage.Level = quotaLevelOK
+	}
+
+	level := state.usage.Level
+	alert := level != quotaLevelOK && level != state.alerted
+	state.alerted = level
+
+	s.quotaMu.Lock()
+	if s.appQuotas == nil {
+		s.appQuotas = make(map[string]*appQuotaState)
+	}
+	s.appQuotas[appName] = &state
+	s.quotaMu.Unlock()
+
+	if s.sseManager != nil {
+		s.sseManager.SendToAll("app-quota", map[string]interface{}{
+			"app":   appName,
+			"usage": state.usage,
+		})
+	}
+
+	if !alert {
+		return
+	}
+	usage := state.usage
+	message := fmt.Sprintf("App %s uses %s of its %s disk quota (%.0f%%).", appName, usage.UsedLabel(), usage.LimitLabel(), usage.Percent)
+	if level == quotaLevelExceeded && !usage.Enforced {
+		if err := s.stopAppOverQuota(appName); err != nil {
+			logging.Errorf("Failed to stop app %s over its disk quota: %v", appName, err)
+			message += " Stopping the app failed: " + err.Error()
+		} else {
+			message += " The app was stopped to protect the disk. Free up space or raise the quota, then start it again."
+		}
+	}
+	logging.Warnf("Disk quota: %s", message)
+	s.notifyAdmins(fmt.Sprintf("TreeOS: disk quota %s for %s", level, appName), message+"\n")
+}
+
+// applyProjectQuota sets a filesystem project quota if possible and reports whether it is enforced
+func (s *Server) applyProjectQuota(appName, mntPath string, limit uint64) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := storage.SetProjectQuota(ctx, mntPath, storage.ProjectID(appName), limit)
+	switch {
+	case err == nil:
+		logging.Infof("Project quota of %s set for app %s", storage.FormatSize(limit), appName)
+		return true
+	case errors.Is(err, storage.ErrQuotaUnsupported):
+		logging.Debugf("Disk quota of app %s is monitored only: %v", appName, err)
+	default:
+		logging.Warnf("Failed to set project quota for app %s, quota is monitored only: %v", appName, err)
+	}
+	return false
+}
+
+// stopAppOverQuota stops an app that exceeded a quota that isn't enforced by the filesystem
+func (s *Server) stopAppOverQuota(appName string) error {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+	opts := compose.Options{WorkingDir: filepath.Join(s.config.AppsDir, appName)}
+	return composeSvc.Down(context.Background(), opts, false)
+}
+
+// appQuotaUsage returns the last measured quota usage of an app, or nil if it has no quota
+func (s *Server) appQuotaUsage(appName string) *AppQuotaUsage {
+	s.quotaMu.Lock()
+	defer s.quotaMu.Unlock()
+	state, ok := s.appQuotas[appName]
+	if !ok {
+		return nil
+	}
+	usage := state.usage
+	return &usage
+}
+
+// handleAPIAppQuota handles /api/apps/{appName}/quota:
+// GET returns the quota and usage, PUT sets the quota (an empty limit removes it)
+func (s *Server) handleAPIAppQuota(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if appName == "" {
+		http.Error(w, "App name is required", http.StatusBadRequest)
+		return
+	}
+
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	metadata, err := yamlutil.ReadComposeMetadata(appDir)
+	if err != nil {
+		logging.Errorf("Failed to read metadata for app %s: %v", appName, err)
+		metadata = &yamlutil.OnTreeMetadata{}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		user := getUserFromContext(r.Context())
+		if user == nil || !user.IsStaff {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			Limit string `json:"limit"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		request.Limit = strings.TrimSpace(request.Limit)
+		if request.Limit != "" {
+			if _, err := storage.ParseSize(request.Limit); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		metadata.DiskQuota = request.Limit
+		if err := yamlutil.UpdateComposeMetadata(appDir, metadata); err != nil {
+			logging.Errorf("Failed to update metadata for app %s: %v", appName, err)
+			http.Error(w, "Failed to update disk quota", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s set disk quota of app %s to %q", user.Username, appName, request.Limit)
+
+		if request.Limit == "" {
+			s.removeAppQuota(appName)
+		} else {
+			s.checkAppQuota(appName, request.Limit)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"success": true,
+		"app":     appName,
+		"limit":   metadata.DiskQuota,
+		"usage":   s.appQuotaUsage(appName),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// removeAppQuota forgets the quota state of an app and lifts an enforced project quota
+func (s *Server) removeAppQuota(appName string) {
+	s.quotaMu.Lock()
+	state, ok := s.appQuotas[appName]
+	delete(s.appQuotas, appName)
+	s.quotaMu.Unlock()
+
+	if ok && state.usage.Enforced {
+		s.applyProjectQuota(appName, filepath.Join(s.config.AppsDir, appName, "mnt"), 0)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// How long an app has to stay up after switching to a read-only root filesystem.
+// Services that write outside their volumes and tmpfs mounts usually crash within seconds.
+const (
+	readOnlySettleTime    = 60 * time.Second
+	readOnlyPollInterval  = 5 * time.Second
+	readOnlyApplyTimeout  = 10 * time.Minute
+	readOnlyRollbackLimit = 5 * time.Minute
+)
+
+// handleAPIAppReadOnly handles PUT /api/apps/{appName}/read-only with {"mode": "on"|"off"|""}.
+// An empty mode follows the global read_only_root setting. When the change makes a running
+// app read-only, it is restarted and watched in the background, and the previous setting is
+// restored if it fails its health checks.
+func (s *Server) handleAPIAppReadOnly(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	appName := r.PathValue("name")
+	if appName == "" {
+		http.Error(w, "App name is required", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Mode != "" && request.Mode != compose.ReadOnlyRootOn && request.Mode != compose.ReadOnlyRootOff {
+		http.Error(w, fmt.Sprintf("Invalid mode %q, expected %s, %s or empty", request.Mode, compose.ReadOnlyRootOn, compose.ReadOnlyRootOff), http.StatusBadRequest)
+		return
+	}
+
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		http.Error(w, "Compose service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	metadata, err := yamlutil.ReadComposeMetadata(appDir)
+	if err != nil {
+		metadata = &yamlutil.OnTreeMetadata{}
+	}
+	previous := metadata.ReadOnlyRoot
+	metadata.ReadOnlyRoot = request.Mode
+	if err := yamlutil.UpdateComposeMetadata(appDir, metadata); err != nil {
+		logging.Errorf("Failed to update metadata for app %s: %v", appName, err)
+		http.Error(w, "Failed to update read-only root setting", http.StatusInternalServerError)
+		return
+	}
+
+	wasReadOnly := composeSvc.ReadOnlyRootApplies(previous)
+	readOnly := composeSvc.ReadOnlyRootApplies(request.Mode)
+	logging.Infof("SECURITY: Read-only root filesystem for app '%s' set to %q by %s", appName, request.Mode, user.Username)
+
+	applying := wasReadOnly != readOnly && s.appRunning(r.Context(), composeSvc, appDir)
+	if applying {
+		go s.applyReadOnlyRoot(appName, previous, readOnly)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if applying {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	response := map[string]interface{}{
+		"success":  true,
+		"mode":     request.Mode,
+		"readOnly": readOnly,
+		"applying": applying,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// appRunning reports whether any container of an app is running
+func (s *Server) appRunning(ctx context.Context, composeSvc *compose.Service, appDir string) bool {
+	containers, err := composeSvc.PS(ctx, compose.Options{WorkingDir: appDir})
+	if err != nil {
+		return false
+	}
+	for _, container := range containers {
+		if container.State == "running" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyReadOnlyRoot recreates the containers of a running app with the new setting. When the
+// app turned read-only and does not stay healthy, the previous setting is restored.
+func (s *Server) applyReadOnlyRoot(appName, previous string, readOnly bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), readOnlyApplyTimeout)
+	defer cancel()
+
+	err := s.startAppAfterReboot(ctx, appName)
+	if err == nil && readOnly {
+		err = s.watchAppHealth(ctx, appName, readOnlySettleTime)
+	}
+	if err == nil {
+		logging.Infof("Applied read-only root filesystem setting to app %s", appName)
+		return
+	}
+	if !readOnly {
+		logging.Errorf("Failed to restart app %s with a writable root filesystem: %v", appName, err)
+		return
+	}
+
+	logging.Warnf("SECURITY: App %s failed with a read-only root filesystem, rolling back: %v", appName, err)
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	metadata, readErr := yamlutil.ReadComposeMetadata(appDir)
+	if readErr != nil {
+		metadata = &yamlutil.OnTreeMetadata{}
+	}
+	metadata.ReadOnlyRoot = previous
+	if writeErr := yamlutil.UpdateComposeMetadata(appDir, metadata); writeErr != nil {
+		logging.Errorf("Failed to restore read-only root setting of app %s: %v", appName, writeErr)
+		return
+	}
+
+	rollbackCtx, rollbackCancel := context.WithTimeout(context.Background(), readOnlyRollbackLimit)
+	defer rollbackCancel()
+	if startErr := s.startAppAfterReboot(rollbackCtx, appName); startErr != nil {
+		logging.Errorf("Failed to restart app %s after rolling back the read-only root filesystem: %v", appName, startErr)
+	}
+	s.notifyAdmins(
+		fmt.Sprintf("TreeOS rolled back the read-only root filesystem of %s", appName),
+		fmt.Sprintf("App %s did not stay healthy with a read-only root filesystem and runs with a writable root again.\n\n%v\n", appName, err),
+	)
+}
+
+// watchAppHealth polls the containers of an app for the settle time and fails as soon as one
+// stops, restarts or reports unhealthy
+func (s *Server) watchAppHealth(ctx context.Context, appName string, settle time.Duration) error {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+	opts := compose.Options{WorkingDir: filepath.Join(s.config.AppsDir, appName)}
+
+	deadline := time.Now().Add(settle)
+	for {
+		containers, err := composeSvc.PS(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		if err := containersHealthy(containers); err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(readOnlyPollInterval):
+		}
+	}
+}
+
+// containersHealthy returns an error naming the first container that is not running or
+// reports unhealthy. Containers still in their health check start period pass.
+func containersHealthy(containers []compose.ContainerSummary) error {
+	if len(containers) == 0 {
+		return fmt.Errorf("no containers are running")
+	}
+	for _, container := range containers {
+		name := container.Service
+		if name == "" {
+			name = container.Name
+		}
+		if container.State != "running" {
+			return fmt.Errorf("container %s is %s: %s", name, container.State, container.Status)
+		}
+		if container.Health == "unhealthy" {
+			return fmt.Errorf("container %s is unhealthy", name)
+		}
+	}
+	return nil
+}
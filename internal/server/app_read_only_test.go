@@ -0,0 +1,44 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestContainersHealthy(t *testing.T) {
+	cases := []struct {
+		name       string
+		containers []compose.ContainerSummary
+		wantErr    string
+	}{
+		{"no containers", nil, "no containers"},
+		{"running", []compose.ContainerSummary{
+			{Service: "web", State: "running", Health: "healthy"},
+			{Service: "db", State: "running", Health: "starting"},
+		}, ""},
+		{"restarting", []compose.ContainerSummary{
+			{Service: "web", State: "running"},
+			{Service: "db", State: "restarting", Status: "Restarting (1) 2 seconds ago"},
+		}, "container db is restarting"},
+		{"unhealthy", []compose.ContainerSummary{
+			{Name: "ontree-miniflux-web-1", State: "running", Health: "unhealthy"},
+		}, "container ontree-miniflux-web-1 is unhealthy"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := containersHealthy(tc.containers)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("containersHealthy() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("containersHealthy() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
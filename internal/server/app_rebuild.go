@@ -0,0 +1,377 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// rebuildCheckInterval is how often the rebuilder looks for apps that are due
+	rebuildCheckInterval = 15 * time.Minute
+	// rebuildTimeout bounds a rebuild including the health check and a rollback
+	rebuildTimeout = time.Hour
+	// rebuildSettleTime is how long a recreated app has to stay healthy on its new images
+	rebuildSettleTime = 2 * time.Minute
+	// rebuildSlack lets a rebuild run that early in the window, so a weekly rebuild that
+	// started late in last week's window doesn't skip this week's
+	rebuildSlack = 12 * time.Hour
+	// rebuildHistoryLimit is the number of past rebuilds shown for an app
+	rebuildHistoryLimit = 10
+
+	rebuildTriggerSchedule = "schedule"
+	rebuildTriggerManual   = "manual"
+)
+
+var errRebuildRunning = errors.New("another rebuild is running")
+
+// rebuildIntervals are the rebuild schedules an app can have
+var rebuildIntervals = map[string]time.Duration{
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+// AppRebuildResponse is returned by GET /api/apps/{appName}/rebuild
+type AppRebuildResponse struct {
+	Schedule string                 `json:"schedule"`
+	Services []compose.BuildService `json:"services"`
+	NextRun  *time.Time             `json:"next_run,omitempty"`
+	History  []database.AppRebuild  `json:"history"`
+}
+
+// rebuildDue returns when an app with a schedule should be rebuilt next, ignoring the
+// maintenance window. Apps that were never rebuilt are due right away.
+func rebuildDue(schedule string, last *database.AppRebuild, now time.Time) time.Time {
+	if last == nil {
+		return now
+	}
+	return last.StartedAt.Add(rebuildIntervals[schedule] - rebuildSlack)
+}
+
+// appBuildServices returns the services of an app that are built from source
+func (s *Server) appBuildServices(appName string) ([]compose.BuildService, error) {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return nil, err
+	}
+	return composeSvc.BuildServices(compose.Options{WorkingDir: filepath.Join(s.config.AppsDir, appName)})
+}
+
+// appRebuildSchedule returns the rebuild schedule of an app, empty if it has none
+func (s *Server) appRebuildSchedule(appName string) string {
+	metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(s.config.AppsDir, appName))
+	if err != nil || metadata == nil {
+		return ""
+	}
+	if _, ok := rebuildIntervals[metadata.RebuildSchedule]; !ok {
+		return ""
+	}
+	return metadata.RebuildSchedule
+}
+
+// nextAppRebuild returns when an app with a schedule is rebuilt next, nil without one
+func (s *Server) nextAppRebuild(appName string, now time.Time) *time.Time {
+	schedule := s.appRebuildSchedule(appName)
+	if schedule == "" {
+		return nil
+	}
+	last, err := database.GetLatestAppRebuild(appName)
+	if err != nil {
+		logging.Errorf("Failed to get last rebuild of app %s: %v", appName, err)
+		return nil
+	}
+	due := rebuildDue(schedule, last, now)
+	if due.Before(now) {
+		due = now
+	}
+	next := s.maintenanceWindow().In(s.location()).Next(due)
+	return &next
+}
+
+// startAppRebuilder rebuilds apps with a rebuild schedule in the maintenance window
+func (s *Server) startAppRebuilder() {
+	if s.db == nil {
+		return
+	}
+	if err := database.ResetInterruptedAppRebuilds(); err != nil {
+		logging.Errorf("Failed to reset app rebuilds: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(rebuildCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.runDueRebuilds()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// runDueRebuilds rebuilds the apps that are due one after another while the maintenance
+// window is open
+func (s *Server) runDueRebuilds() {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		now := time.Now()
+		if !entry.IsDir() || !s.maintenanceWindow().In(s.location()).Contains(now) {
+			continue
+		}
+		schedule := s.appRebuildSchedule(entry.Name())
+		if schedule == "" {
+			continue
+		}
+		last, err := database.GetLatestAppRebuild(entry.Name())
+		if err != nil {
+			logging.Errorf("Failed to get last rebuild of app %s: %v", entry.Name(), err)
+			continue
+		}
+		if rebuildDue(schedule, last, now).After(now) {
+			continue
+		}
+		rebuild := &database.AppRebuild{AppName: entry.Name(), Trigger: rebuildTriggerSchedule}
+		if err := s.rebuildApp(rebuild); err != nil && !errors.Is(err, errRebuildRunning) {
+			logging.Errorf("Scheduled rebuild of app %s failed: %v", entry.Name(), err)
+		}
+	}
+}
+
+// rebuildApp rebuilds the images of an app from the latest base images and git sources.
+// A running app is recreated and watched, if it doesn't stay healthy the previous images
+// are tagged again and the app is recreated on them. Only one rebuild runs at a time.
+func (s *Server) rebuildApp(rebuild *database.AppRebuild) error {
+	if !s.rebuildMu.TryLock() {
+		return errRebuildRunning
+	}
+	defer s.rebuildMu.Unlock()
+
+	if err := database.StartAppRebuild(rebuild); err != nil {
+		return err
+	}
+	logging.Infof("Rebuilding app %s (%s)", rebuild.AppName, rebuild.Trigger)
+
+	summary, rolledBack, err := s.runAppRebuild(rebuild.AppName)
+	status, message := database.RebuildStatusSucceeded, summary
+	switch {
+	case err == nil:
+		logging.Infof("Rebuilt app %s: %s", rebuild.AppName, summary)
+	case rolledBack:
+		status, message = database.RebuildStatusRolledBack, err.Error()
+		logging.Warnf("Rolled back the rebuild of app %s: %v", rebuild.AppName, err)
+		s.notifyAdmins(
+			fmt.Sprintf("TreeOS rolled back the rebuild of %s", rebuild.AppName),
+			fmt.Sprintf("App %s did not stay healthy on its rebuilt images and runs on the previous ones again.\n\n%v\n", rebuild.AppName, err),
+		)
+	default:
+		status, message = database.RebuildStatusFailed, err.Error()
+		if rebuild.Trigger == rebuildTriggerSchedule {
+			s.notifyAdmins(
+				fmt.Sprintf("TreeOS could not rebuild %s", rebuild.AppName),
+				fmt.Sprintf("The scheduled rebuild of app %s failed, it keeps running on its previous images.\n\n%v\n", rebuild.AppName, err),
+			)
+		}
+	}
+	if recordErr := database.FinishAppRebuild(rebuild.ID, status, message); recordErr != nil {
+		logging.Errorf("Failed to record rebuild of app %s: %v", rebuild.AppName, recordErr)
+	}
+	return err
+}
+
+// runAppRebuild does the work of rebuildApp and returns a summary. rolledBack reports
+// whether the app is running on its previous images after failing the health check.
+func (s *Server) runAppRebuild(appName string) (summary string, rolledBack bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rebuildTimeout)
+	defer cancel()
+
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return "", false, err
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	opts := compose.Options{WorkingDir: appDir}
+	services, err := composeSvc.BuildServices(opts)
+	if err != nil {
+		return "", false, err
+	}
+	if len(services) == 0 {
+		return "", false, fmt.Errorf("no service of the app is built from source")
+	}
+
+	for _, service := range services {
+		contextDir := filepath.Join(appDir, service.Context)
+		// Checkouts outside the app directory may be shared, they are updated by their owner
+		if service.LocalContext() && strings.HasPrefix(contextDir+string(filepath.Separator), appDir+string(filepath.Separator)) {
+			if err := pullGitContext(ctx, contextDir); err != nil {
+				return "", false, fmt.Errorf("service %s: %w", service.Name, err)
+			}
+		}
+	}
+
+	// Keep the current images, a service that was never built has nothing to roll back to
+	var kept []compose.BuildService
+	for _, service := range services {
+		if err := composeSvc.TagImage(ctx, service.Image, compose.RollbackImage(service.Image)); err == nil {
+			kept = append(kept, service)
+		}
+	}
+
+	if err := composeSvc.Build(ctx, opts); err != nil {
+		return "", false, err
+	}
+	if !s.appRunning(ctx, composeSvc, appDir) {
+		return "Built, the app uses the new images when it starts", false, nil
+	}
+
+	err = s.startAppAfterReboot(ctx, appName)
+	if err == nil {
+		err = s.watchAppHealth(ctx, appName, rebuildSettleTime)
+	}
+	if err == nil {
+		return "Built and recreated, the app is healthy", false, nil
+	}
+
+	// A timed out context must not keep the rollback from running
+	rollbackCtx, rollbackCancel := context.WithTimeout(context.Background(), readOnlyRollbackLimit)
+	defer rollbackCancel()
+	for _, service := range kept {
+		if tagErr := composeSvc.TagImage(rollbackCtx, compose.RollbackImage(service.Image), service.Image); tagErr != nil {
+			return "", false, fmt.Errorf("%v, and restoring the previous image failed: %w", err, tagErr)
+		}
+	}
+	if startErr := s.startAppAfterReboot(rollbackCtx, appName); startErr != nil {
+		return "", false, fmt.Errorf("%v, and restarting on the previous images failed: %w", err, startErr)
+	}
+	return "", true, err
+}
+
+// pullGitContext fast-forwards a build context that is a git checkout to its upstream
+// branch. Directories that aren't checkouts are left alone.
+func pullGitContext(ctx context.Context, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return nil
+	}
+	// #nosec G204 -- dir is a build context inside the app directory
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "pull", "--ff-only")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update git checkout: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// handleAPIAppRebuild handles /api/apps/{appName}/rebuild: GET returns the build services,
+// schedule and past rebuilds, PUT sets the schedule with {"schedule": "weekly"} and POST
+// starts a rebuild in the background
+func (s *Server) handleAPIAppRebuild(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	appName := r.PathValue("name")
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+	services, err := s.appBuildServices(appName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read build services: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Schedule string `json:"schedule"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if _, ok := rebuildIntervals[req.Schedule]; !ok && req.Schedule != "" {
+			http.Error(w, "Schedule must be daily, weekly, monthly or empty", http.StatusBadRequest)
+			return
+		}
+		if req.Schedule != "" && len(services) == 0 {
+			http.Error(w, "No service of the app is built from source", http.StatusConflict)
+			return
+		}
+		metadata, err := yamlutil.ReadComposeMetadata(appDir)
+		if err != nil {
+			metadata = &yamlutil.OnTreeMetadata{}
+		}
+		metadata.RebuildSchedule = req.Schedule
+		if err := yamlutil.UpdateComposeMetadata(appDir, metadata); err != nil {
+			logging.Errorf("Failed to update metadata for app %s: %v", appName, err)
+			http.Error(w, "Failed to update rebuild schedule", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("Rebuild schedule of app %s set to %q by %s", appName, req.Schedule, user.Username)
+	case http.MethodPost:
+		if len(services) == 0 {
+			http.Error(w, "No service of the app is built from source", http.StatusConflict)
+			return
+		}
+		if !s.rebuildMu.TryLock() {
+			http.Error(w, "Another rebuild is running", http.StatusConflict)
+			return
+		}
+		s.rebuildMu.Unlock()
+		go func() {
+			rebuild := &database.AppRebuild{AppName: appName, Trigger: rebuildTriggerManual, RequestedBy: user.Username}
+			if err := s.rebuildApp(rebuild); err != nil {
+				logging.Errorf("Rebuild of app %s failed: %v", appName, err)
+			}
+		}()
+		w.WriteHeader(http.StatusAccepted)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	history, err := database.GetAppRebuilds(appName, rebuildHistoryLimit)
+	if err != nil {
+		logging.Errorf("Failed to get rebuilds of app %s: %v", appName, err)
+		http.Error(w, "Failed to get rebuilds", http.StatusInternalServerError)
+		return
+	}
+	if services == nil {
+		services = []compose.BuildService{}
+	}
+	response := AppRebuildResponse{
+		Schedule: s.appRebuildSchedule(appName),
+		Services: services,
+		NextRun:  s.nextAppRebuild(appName, time.Now()),
+		History:  history,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestRebuildDue(t *testing.T) {
+	now := time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC)
+	if due := rebuildDue("weekly", nil, now); !due.Equal(now) {
+		t.Errorf("never rebuilt app is due at %v", due)
+	}
+	// Started late in last week's window, due again early in this week's
+	last := &database.AppRebuild{StartedAt: now.Add(-7*24*time.Hour + 2*time.Hour)}
+	if due := rebuildDue("weekly", last, now); due.After(now) {
+		t.Errorf("weekly rebuild is due at %v, after this week's window at %v", due, now)
+	}
+	last.StartedAt = now.Add(-24 * time.Hour)
+	if due := rebuildDue("weekly", last, now); !due.After(now) {
+		t.Errorf("weekly rebuild from yesterday is already due at %v", due)
+	}
+}
+
+func TestAppRebuild(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	composeSvc, err := compose.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	appsDir := t.TempDir()
+	for name, content := range map[string]string{
+		"blog":   "services:\n  web:\n    build: ./src\n  db:\n    image: postgres:16\n",
+		"photos": "services:\n  web:\n    image: nginx:1.27\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(appsDir, name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(appsDir, name, "docker-compose.yml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}, composeSvc: composeSvc}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	request := func(method, appName, body string) *httptest.ResponseRecorder {
+		req := newAppRequest(method, "/api/apps/"+appName+"/rebuild", bytes.NewReader([]byte(body)))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, staff))
+		rec := httptest.NewRecorder()
+		s.handleAPIAppRebuild(rec, req)
+		return rec
+	}
+
+	if rec := request(http.MethodPut, "photos", `{"schedule": "weekly"}`); rec.Code != http.StatusConflict {
+		t.Errorf("schedule for an app without build services: status = %d", rec.Code)
+	}
+	if rec := request(http.MethodPut, "blog", `{"schedule": "hourly"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown schedule: status = %d", rec.Code)
+	}
+
+	rec := request(http.MethodPut, "blog", `{"schedule": "weekly"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body)
+	}
+	var response AppRebuildResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Schedule != "weekly" || len(response.Services) != 1 || response.Services[0].Image != "blog-web" || response.NextRun == nil {
+		t.Errorf("response = %+v", response)
+	}
+
+	// The app is stopped, so it is only built
+	if err := s.rebuildApp(&database.AppRebuild{AppName: "blog", Trigger: rebuildTriggerManual}); err != nil {
+		t.Fatalf("rebuildApp() error = %v", err)
+	}
+	history, err := database.GetAppRebuilds("blog", rebuildHistoryLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Status != database.RebuildStatusSucceeded {
+		t.Errorf("history = %+v", history)
+	}
+
+	if err := s.rebuildApp(&database.AppRebuild{AppName: "photos", Trigger: rebuildTriggerManual}); err == nil {
+		t.Error("rebuilt an app without build services")
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// resolvedConfigTimeout bounds `docker compose config`, which only reads files
+const resolvedConfigTimeout = 30 * time.Second
+
+// handleAPIAppResolvedConfig handles /api/apps/{appName}/resolved-config. GET renders the
+// saved files, POST renders unsaved {"compose_yaml", "env_content"} from the editor. The
+// result contains the values of all variables, so only staff may see it.
+func (s *Server) handleAPIAppResolvedConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	var draft *compose.Draft
+	if r.Method == http.MethodPost {
+		var request struct {
+			ComposeYAML string `json:"compose_yaml"`
+			EnvContent  string `json:"env_content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(request.ComposeYAML) == "" {
+			http.Error(w, "compose_yaml is required", http.StatusBadRequest)
+			return
+		}
+		draft = &compose.Draft{Compose: request.ComposeYAML, Env: request.EnvContent}
+	}
+
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		http.Error(w, "Compose service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), resolvedConfigTimeout)
+	defer cancel()
+	rendered, err := composeSvc.RenderConfig(ctx, compose.Options{WorkingDir: appDir}, draft)
+	if err != nil {
+		logging.Errorf("Failed to render config of app %s: %v", appName, err)
+		http.Error(w, fmt.Sprintf("Failed to render config: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rendered); err != nil {
+		logging.Errorf("Failed to encode resolved config: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestHandleAPIAppResolvedConfig(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	composeSvc, err := compose.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "web")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx:${TAG}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, ".env"), []byte("TAG=1.27\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}, composeSvc: composeSvc}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	tests := []struct {
+		name       string
+		method     string
+		app        string
+		body       string
+		user       *database.User
+		wantStatus int
+		wantConfig string
+		wantWarns  int
+	}{
+		{name: "saved files", method: http.MethodGet, app: "web", user: staff, wantStatus: http.StatusOK, wantConfig: "image: nginx:1.27"},
+		{name: "draft with a typo", method: http.MethodPost, app: "web", user: staff,
+			body:       `{"compose_yaml": "services:\n  web:\n    image: nginx:${TGA}\n", "env_content": "TAG=1.27\n"}`,
+			wantStatus: http.StatusOK, wantConfig: "image: nginx:\n", wantWarns: 1},
+		{name: "empty draft", method: http.MethodPost, app: "web", user: staff, body: `{"compose_yaml": ""}`, wantStatus: http.StatusBadRequest},
+		{name: "not staff", method: http.MethodGet, app: "web", user: &database.User{Username: "user"}, wantStatus: http.StatusForbidden},
+		{name: "unknown app", method: http.MethodGet, app: "missing", user: staff, wantStatus: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodDelete, app: "web", user: staff, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newAppRequest(tt.method, "/api/apps/"+tt.app+"/resolved-config", strings.NewReader(tt.body))
+			req = req.WithContext(setUserContext(req.Context(), tt.user))
+			w := httptest.NewRecorder()
+			s.handleAPIAppResolvedConfig(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var rendered compose.RenderedConfig
+			if err := json.NewDecoder(w.Body).Decode(&rendered); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(rendered.Content, tt.wantConfig) {
+				t.Errorf("config = %q, want it to contain %q", rendered.Content, tt.wantConfig)
+			}
+			if len(rendered.Warnings) != tt.wantWarns {
+				t.Errorf("warnings = %q, want %d", rendered.Warnings, tt.wantWarns)
+			}
+		})
+	}
+}
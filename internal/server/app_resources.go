@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/charts"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+const (
+	// appResourceRetention is how long the resource usage of app services is kept
+	appResourceRetention = 48 * time.Hour
+	// appResourceMaxHours bounds the history returned by the resources API
+	appResourceMaxHours = 48
+)
+
+// AppResourcesResponse is returned by GET /api/apps/{appName}/resources
+type AppResourcesResponse struct {
+	Samples []database.AppResourceLog `json:"samples"`
+}
+
+// appResourceCounters are the network counters of a container at its last sample, to turn
+// the next sample into rates
+type appResourceCounters struct {
+	rx, tx uint64
+	at     time.Time
+}
+
+// startAppResourceCollection samples the CPU, memory and network usage of every running
+// app container at the vitals interval and stores it per service
+func (s *Server) startAppResourceCollection() {
+	if s.db == nil {
+		return
+	}
+	logging.Infof("App resource collection started (every %s)", s.profile.VitalsInterval)
+
+	ticker := time.NewTicker(s.profile.VitalsInterval)
+	defer ticker.Stop()
+
+	previous := make(map[string]appResourceCounters)
+	for range ticker.C {
+		previous = s.storeAppResources(previous)
+	}
+}
+
+// storeAppResources stores one sample of all app containers. It returns the network
+// counters to compute the rates of the next sample from.
+func (s *Server) storeAppResources(previous map[string]appResourceCounters) map[string]appResourceCounters {
+	client, err := s.getRuntimeClient()
+	if err != nil {
+		return previous
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.profile.VitalsInterval)
+	defer cancel()
+	stats, err := client.ContainerStats(ctx, s.config.AppsDir)
+	if err != nil {
+		logging.Warnf("Failed to sample app resource usage: %v", err)
+		return previous
+	}
+
+	logs, counters := appResourceLogs(stats, previous, time.Now())
+	if len(logs) == 0 {
+		return counters
+	}
+	if err := database.StoreAppResourceLogs(logs); err != nil {
+		logging.Errorf("Failed to store app resource usage: %v", err)
+	}
+	return counters
+}
+
+// appResourceLogs turns container samples into logs, with the network rates since the
+// previous sample of the same container. Containers sampled for the first time, or
+// whose counters went back after a restart, get no rates.
+func appResourceLogs(stats []dockerruntime.ContainerStats, previous map[string]appResourceCounters, now time.Time) ([]database.AppResourceLog, map[string]appResourceCounters) {
+	logs := make([]database.AppResourceLog, 0, len(stats))
+	counters := make(map[string]appResourceCounters, len(stats))
+	for _, st := range stats {
+		service := st.Service
+		if service == "" {
+			service = st.Name
+		}
+		log := database.AppResourceLog{
+			AppName:     st.App,
+			Service:     service,
+			CPUPercent:  st.CPUPercent,
+			MemoryBytes: st.MemoryBytes,
+			MemoryLimit: st.MemoryLimit,
+		}
+		if prev, ok := previous[st.Name]; ok && st.NetRxBytes >= prev.rx && st.NetTxBytes >= prev.tx {
+			if seconds := now.Sub(prev.at).Seconds(); seconds > 0 {
+				log.NetRxRate = uint64(float64(st.NetRxBytes-prev.rx) / seconds)
+				log.NetTxRate = uint64(float64(st.NetTxBytes-prev.tx) / seconds)
+			}
+		}
+		counters[st.Name] = appResourceCounters{rx: st.NetRxBytes, tx: st.NetTxBytes, at: now}
+		logs = append(logs, log)
+	}
+	return logs, counters
+}
+
+// appResourceView is the resource usage of a service of an app over the last 24 hours
+type appResourceView struct {
+	Service         string
+	CPULabel        string // Of the latest sample
+	MemoryLabel     string
+	NetworkLabel    string
+	CPUSparkline    template.HTML
+	MemorySparkline template.HTML
+	NetSparkline    template.HTML // Received and sent together
+}
+
+// appResourceViews groups the samples of an app by service, ordered by service name
+func appResourceViews(logs []database.AppResourceLog) []appResourceView {
+	byService := make(map[string][]database.AppResourceLog)
+	for _, l := range logs {
+		byService[l.Service] = append(byService[l.Service], l)
+	}
+
+	views := make([]appResourceView, 0, len(byService))
+	for service, samples := range byService {
+		cpu := make([]float64, len(samples))
+		memory := make([]float64, len(samples))
+		network := make([]float64, len(samples))
+		for i, sample := range samples {
+			cpu[i] = sample.CPUPercent
+			memory[i] = float64(sample.MemoryBytes)
+			network[i] = float64(sample.NetRxRate + sample.NetTxRate)
+		}
+		latest := samples[len(samples)-1]
+		view := appResourceView{
+			Service:         service,
+			CPULabel:        fmt.Sprintf("%.1f%%", latest.CPUPercent),
+			MemoryLabel:     storage.FormatSize(latest.MemoryBytes),
+			NetworkLabel:    fmt.Sprintf("↓ %s/s ↑ %s/s", storage.FormatSize(latest.NetRxRate), storage.FormatSize(latest.NetTxRate)),
+			CPUSparkline:    charts.GenerateSparklineSVGWithStyle(cpu, 120, 30, "#0d6efd", 2),
+			MemorySparkline: charts.GenerateSparklineSVGWithStyle(memory, 120, 30, "#6f42c1", 2),
+			NetSparkline:    charts.GenerateSparklineSVGWithStyle(normalizeNetworkRates(network), 120, 30, "#198754", 2),
+		}
+		if latest.MemoryLimit > 0 {
+			view.MemoryLabel += " / " + storage.FormatSize(latest.MemoryLimit)
+		}
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Service < views[j].Service })
+	return views
+}
+
+// handleAPIAppResources handles GET /api/apps/{appName}/resources, the resource usage
+// samples of an app's services over the last 24 hours, or ?hours=N up to 48
+func (s *Server) handleAPIAppResources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName, "docker-compose.yml")); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	hours := 24
+	if value := r.URL.Query().Get("hours"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > appResourceMaxHours {
+			http.Error(w, fmt.Sprintf("hours must be between 1 and %d", appResourceMaxHours), http.StatusBadRequest)
+			return
+		}
+		hours = n
+	}
+
+	samples, err := database.GetAppResourceLogs(appName, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		logging.Errorf("Failed to get resource usage of app %s: %v", appName, err)
+		http.Error(w, "Failed to get resource usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(AppResourcesResponse{Samples: samples}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+func TestAppResourceLogs(t *testing.T) {
+	start := time.Now()
+	stats := []dockerruntime.ContainerStats{
+		{App: "blog", Service: "web", Name: "blog-web-1", CPUPercent: 10, MemoryBytes: 64 << 20, NetRxBytes: 1000, NetTxBytes: 100},
+		{App: "blog", Service: "db", Name: "blog-db-1", MemoryBytes: 128 << 20, NetRxBytes: 5000},
+	}
+	logs, counters := appResourceLogs(stats, nil, start)
+	if len(logs) != 2 || logs[0].NetRxRate != 0 || logs[0].CPUPercent != 10 {
+		t.Fatalf("first sample = %+v", logs)
+	}
+
+	// Rates from the counters of the previous sample, none after a restart reset them
+	stats[0].NetRxBytes, stats[0].NetTxBytes = 21000, 2100
+	stats[1].NetRxBytes = 100
+	logs, _ = appResourceLogs(stats, counters, start.Add(10*time.Second))
+	if logs[0].NetRxRate != 2000 || logs[0].NetTxRate != 200 {
+		t.Errorf("web rates = %d/%d, want 2000/200", logs[0].NetRxRate, logs[0].NetTxRate)
+	}
+	if logs[1].NetRxRate != 0 {
+		t.Errorf("db rate after a restart = %d", logs[1].NetRxRate)
+	}
+
+	views := appResourceViews([]database.AppResourceLog{
+		{Service: "web", CPUPercent: 5, MemoryBytes: 1 << 20},
+		{Service: "db", CPUPercent: 1, MemoryBytes: 1 << 20, MemoryLimit: 1 << 30},
+		{Service: "web", CPUPercent: 7.25, MemoryBytes: 2 << 20, NetRxRate: 1 << 10},
+	})
+	if len(views) != 2 || views[0].Service != "db" || views[1].CPULabel != "7.2%" || views[1].CPUSparkline == "" || views[0].CPUSparkline != "" {
+		t.Errorf("views = %+v", views)
+	}
+	if !strings.Contains(views[0].MemoryLabel, " / ") {
+		t.Errorf("memory label with a limit = %q", views[0].MemoryLabel)
+	}
+}
+
+func TestHandleAPIAppResources(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	appsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appsDir, "blog"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appsDir, "blog", "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.StoreAppResourceLogs([]database.AppResourceLog{{AppName: "blog", Service: "web", CPUPercent: 3, MemoryBytes: 1 << 20}}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+
+	request := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleAPIAppResources(rec, newAppRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	rec := request("/api/apps/blog/resources?hours=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var response AppResourcesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Samples) != 1 || response.Samples[0].Service != "web" || response.Samples[0].CPUPercent != 3 {
+		t.Errorf("samples = %+v", response.Samples)
+	}
+	if rec := request("/api/apps/blog/resources?hours=100"); rec.Code != http.StatusBadRequest {
+		t.Errorf("too many hours: status = %d", rec.Code)
+	}
+	if rec := request("/api/apps/wiki/resources"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown app: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// appSecret is a file secret of an app's compose file. The material is never returned.
+type appSecret struct {
+	Name      string     `json:"name"`
+	File      string     `json:"file"`
+	Stored    bool       `json:"stored"`  // TreeOS writes the file at start and removes it at stop
+	OnDisk    bool       `json:"on_disk"` // The file exists, for unstored secrets it was put there by hand
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// handleAPIAppSecrets handles /api/apps/{appName}/secrets:
+// GET lists the file secrets of the compose file and whether TreeOS stores them,
+// PUT /secrets/{name} stores the material of a secret, DELETE /secrets/{name} forgets it.
+// Stored secrets are written to their files when the app starts and removed when it stops.
+func (s *Server) handleAPIAppSecrets(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	appName, name := r.PathValue("name"), r.PathValue("secret")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	content, err := os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // Path from trusted app directory
+	if os.IsNotExist(err) {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.Errorf("Failed to read docker-compose.yml for app %s: %v", appName, err)
+		http.Error(w, "Failed to read app configuration", http.StatusInternalServerError)
+		return
+	}
+	declared, err := compose.FileSecrets(content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if name == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeAppSecrets(w, appName, appDir, declared)
+		return
+	}
+
+	found := false
+	for _, secret := range declared {
+		found = found || secret.Name == name
+	}
+	if !found {
+		http.Error(w, "The compose file declares no file secret "+name, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var request struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if request.Value == "" {
+			http.Error(w, "value is required", http.StatusBadRequest)
+			return
+		}
+		if err := database.SetAppSecret(appName, name, request.Value); err != nil {
+			logging.Errorf("Failed to store secret %s of app %s: %v", name, appName, err)
+			http.Error(w, "Failed to store secret", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s set secret %s of app %s", user.Username, name, appName)
+	case http.MethodDelete:
+		if err := database.DeleteAppSecret(appName, name); err != nil {
+			logging.Errorf("Failed to delete secret %s of app %s: %v", name, appName, err)
+			http.Error(w, "Failed to delete secret", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s deleted secret %s of app %s", user.Username, name, appName)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeAppSecrets(w, appName, appDir, declared)
+}
+
+// writeAppSecrets answers with the declared secrets of an app and their state
+func (s *Server) writeAppSecrets(w http.ResponseWriter, appName, appDir string, declared []compose.FileSecret) {
+	updates, err := database.GetAppSecretUpdates(appName)
+	if err != nil {
+		logging.Errorf("Failed to load secrets of app %s: %v", appName, err)
+		http.Error(w, "Failed to load secrets", http.StatusInternalServerError)
+		return
+	}
+
+	secrets := make([]appSecret, 0, len(declared))
+	for _, secret := range declared {
+		entry := appSecret{Name: secret.Name, File: secret.File}
+		if updatedAt, ok := updates[secret.Name]; ok {
+			entry.Stored = true
+			entry.UpdatedAt = &updatedAt
+		}
+		_, statErr := os.Stat(filepath.Join(appDir, secret.File))
+		entry.OnDisk = statErr == nil
+		secrets = append(secrets, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"app": appName, "secrets": secrets}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
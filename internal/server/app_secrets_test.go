@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestHandleAPIAppSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	appDir := filepath.Join(tmpDir, "apps", "db")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	composeContent := "services:\n  db:\n    image: postgres:16\n    secrets: [db_password]\nsecrets:\n  db_password:\n    file: ./secrets/db_password\n"
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(composeContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: filepath.Join(tmpDir, "apps")}}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	request := func(method, path, body string, user *database.User) *httptest.ResponseRecorder {
+		req := newAppRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		s.handleAPIAppSecrets(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		user       *database.User
+		wantStatus int
+	}{
+		{name: "not staff", method: http.MethodGet, path: "/api/apps/db/secrets", user: &database.User{Username: "user"}, wantStatus: http.StatusUnauthorized},
+		{name: "unknown app", method: http.MethodGet, path: "/api/apps/missing/secrets", user: staff, wantStatus: http.StatusNotFound},
+		{name: "undeclared secret", method: http.MethodPut, path: "/api/apps/db/secrets/other", body: `{"value": "x"}`, user: staff, wantStatus: http.StatusNotFound},
+		{name: "empty value", method: http.MethodPut, path: "/api/apps/db/secrets/db_password", body: `{"value": ""}`, user: staff, wantStatus: http.StatusBadRequest},
+		{name: "set", method: http.MethodPut, path: "/api/apps/db/secrets/db_password", body: `{"value": "s3cret"}`, user: staff, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request(tt.method, tt.path, tt.body, tt.user); w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	if secrets, _ := database.GetAppSecrets("db"); secrets["db_password"] != "s3cret" {
+		t.Errorf("stored secrets = %v", secrets)
+	}
+	w := request(http.MethodGet, "/api/apps/db/secrets", "", staff)
+	if strings.Contains(w.Body.String(), "s3cret") {
+		t.Fatalf("listing contains the secret material: %s", w.Body.String())
+	}
+	var listing struct {
+		Secrets []appSecret `json:"secrets"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&listing); err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Secrets) != 1 || !listing.Secrets[0].Stored || listing.Secrets[0].OnDisk || listing.Secrets[0].File != "secrets/db_password" {
+		t.Errorf("secrets = %+v", listing.Secrets)
+	}
+
+	if w := request(http.MethodDelete, "/api/apps/db/secrets/db_password", "", staff); w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", w.Code, w.Body.String())
+	}
+	if secrets, _ := database.GetAppSecrets("db"); len(secrets) != 0 {
+		t.Errorf("secrets after delete = %v", secrets)
+	}
+}
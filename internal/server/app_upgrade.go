@@ -0,0 +1,422 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
+	"github.com/ontree-co/treeos/internal/registry"
+	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// upgradeHealthTimeout is how long an upgraded app has to become healthy by default
+	upgradeHealthTimeout = 5 * time.Minute
+	// upgradeMaxHealthTimeout bounds the timeout a request can ask for
+	upgradeMaxHealthTimeout = 30 * time.Minute
+	// upgradePullTimeout bounds pulling the images of an app
+	upgradePullTimeout = time.Hour
+	// upgradeHistoryLimit is the number of past upgrades returned for an app
+	upgradeHistoryLimit = 10
+)
+
+// upgradeSettleTime is how long an upgraded app has to stay healthy once it is, so a
+// container that crashes shortly after its first health check is caught. A variable for tests.
+var upgradeSettleTime = 30 * time.Second
+
+var errUpgradeRunning = errors.New("another upgrade is running")
+
+// AppUpgradeRequest is the optional body of POST /api/apps/{appName}/upgrade
+type AppUpgradeRequest struct {
+	Operations []PatchOperation `json:"operations"` // Changes applied with the upgrade, as for PATCH /api/apps/{name}
+	Timeout    string           `json:"timeout"`    // How long the app has to become healthy, e.g. "10m"
+}
+
+// AppUpgradeResponse is returned by /api/apps/{appName}/upgrade
+type AppUpgradeResponse struct {
+	Upgrade  *database.AppUpgrade  `json:"upgrade,omitempty"` // The upgrade that was started by POST
+	Upgrades []database.AppUpgrade `json:"upgrades"`
+}
+
+// appUpgradeFiles are the compose and .env files of an app before and after an upgrade
+type appUpgradeFiles struct {
+	compose, env       []byte // With the operations of the request applied
+	oldCompose, oldEnv []byte
+	envExisted, hasEnv bool // Whether .env existed before and after the operations
+}
+
+// startAppUpgrade snapshots an app, records the upgrade and runs it in the background.
+// Only one upgrade runs at a time.
+func (s *Server) startAppUpgrade(appName, username string, files *appUpgradeFiles, timeout time.Duration) (*database.AppUpgrade, error) {
+	if !s.upgradeMu.TryLock() {
+		return nil, errUpgradeRunning
+	}
+	upgrade, kept, err := s.snapshotAppUpgrade(appName, username, files)
+	if err != nil {
+		s.upgradeMu.Unlock()
+		return nil, err
+	}
+	logging.Infof("Upgrading app %s for %s", appName, username)
+	recordActivity(database.ActivityEvent{
+		Category: database.ActivityAudit,
+		Title:    "Started an upgrade",
+		AppName:  appName,
+		Actor:    username,
+	})
+
+	go func() {
+		defer s.upgradeMu.Unlock()
+		s.finishAppUpgrade(upgrade, s.runAppUpgrade(appName, files, kept, timeout))
+	}()
+	return upgrade, nil
+}
+
+// snapshotAppUpgrade records the digests of the app's images and keeps the images under
+// their rollback tag. It returns the images that have one.
+func (s *Server) snapshotAppUpgrade(appName, username string, files *appUpgradeFiles) (*database.AppUpgrade, []string, error) {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return nil, nil, err
+	}
+	images, err := s.registryImages(appName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), imageUpdateCheckTimeout)
+	defer cancel()
+	upgrade := &database.AppUpgrade{
+		AppName:         appName,
+		PreviousImages:  make(map[string]string, len(images)),
+		ComposeSnapshot: string(files.oldCompose),
+		EnvSnapshot:     string(files.oldEnv),
+		RequestedBy:     username,
+	}
+	var kept []string
+	for _, image := range images {
+		digests, err := composeSvc.ImageDigests(ctx, image)
+		if err != nil {
+			logging.Warnf("Failed to get the digest of image %s: %v", image, err)
+		}
+		upgrade.PreviousImages[image] = ""
+		if len(digests) > 0 {
+			_, upgrade.PreviousImages[image], _ = strings.Cut(digests[0], "@")
+		}
+		// Images that were never pulled have nothing to roll back to
+		if err := composeSvc.TagImage(ctx, image, compose.RollbackImage(image)); err == nil {
+			kept = append(kept, image)
+		}
+	}
+	if err := database.StartAppUpgrade(upgrade); err != nil {
+		return nil, nil, err
+	}
+	return upgrade, kept, nil
+}
+
+// appUpgradeResult is the outcome of runAppUpgrade
+type appUpgradeResult struct {
+	summary    string
+	rolledBack bool // The app runs on its previous images and files again
+	err        error
+}
+
+// runAppUpgrade writes the changed files, pulls the images and recreates a running app.
+// If it doesn't become healthy within timeout, the previous files and images are restored
+// and the app is recreated on them.
+func (s *Server) runAppUpgrade(appName string, files *appUpgradeFiles, kept []string, timeout time.Duration) appUpgradeResult {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return appUpgradeResult{err: err}
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	running := s.appRunning(context.Background(), composeSvc, appDir)
+
+	if err := files.write(appDir, files.compose, files.env, files.hasEnv); err != nil {
+		return appUpgradeResult{err: err}
+	}
+	pullCtx, cancel := context.WithTimeout(context.Background(), upgradePullTimeout)
+	defer cancel()
+	images, err := s.registryImages(appName)
+	if err == nil {
+		for _, image := range images {
+			if err = composeSvc.PullImage(pullCtx, image); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		// Nothing runs on the new images yet, the previous files are enough
+		if restoreErr := files.write(appDir, files.oldCompose, files.oldEnv, files.envExisted); restoreErr != nil {
+			return appUpgradeResult{err: fmt.Errorf("%v, and restoring the previous files failed: %w", err, restoreErr)}
+		}
+		return appUpgradeResult{err: err}
+	}
+	if !running {
+		return appUpgradeResult{summary: "Pulled, the app uses the new images when it starts"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+upgradeSettleTime+upgradePullTimeout)
+	defer cancel()
+	err = s.startAppAfterReboot(ctx, appName)
+	if err == nil {
+		err = s.waitAppHealthy(ctx, appName, timeout)
+	}
+	if err == nil {
+		err = s.watchAppHealth(ctx, appName, upgradeSettleTime)
+	}
+	if err == nil {
+		return appUpgradeResult{summary: "Upgraded, the app is healthy"}
+	}
+
+	// A timed out context must not keep the rollback from running
+	rollbackCtx, rollbackCancel := context.WithTimeout(context.Background(), readOnlyRollbackLimit)
+	defer rollbackCancel()
+	if restoreErr := files.write(appDir, files.oldCompose, files.oldEnv, files.envExisted); restoreErr != nil {
+		return appUpgradeResult{err: fmt.Errorf("%v, and restoring the previous files failed: %w", err, restoreErr)}
+	}
+	for _, image := range kept {
+		if tagErr := composeSvc.TagImage(rollbackCtx, compose.RollbackImage(image), image); tagErr != nil {
+			return appUpgradeResult{err: fmt.Errorf("%v, and restoring the previous image failed: %w", err, tagErr)}
+		}
+	}
+	if startErr := s.startAppAfterReboot(rollbackCtx, appName); startErr != nil {
+		return appUpgradeResult{err: fmt.Errorf("%v, and restarting on the previous images failed: %w", err, startErr)}
+	}
+	return appUpgradeResult{rolledBack: true, err: err}
+}
+
+// finishAppUpgrade records the outcome of an upgrade and tells the admins about a rollback
+func (s *Server) finishAppUpgrade(upgrade *database.AppUpgrade, result appUpgradeResult) {
+	status, message := database.UpgradeStatusSucceeded, result.summary
+	switch {
+	case result.err == nil:
+		logging.Infof("Upgraded app %s: %s", upgrade.AppName, result.summary)
+		s.notifyEvent(notify.Event{
+			Type:    notify.EventUpdateApplied,
+			Title:   fmt.Sprintf("App %s upgraded", upgrade.AppName),
+			Message: result.summary,
+			App:     upgrade.AppName,
+		})
+	case result.rolledBack:
+		status, message = database.UpgradeStatusRolledBack, result.err.Error()
+		logging.Warnf("Rolled back the upgrade of app %s: %v", upgrade.AppName, result.err)
+		s.notifyAdmins(
+			fmt.Sprintf("TreeOS rolled back the upgrade of %s", upgrade.AppName),
+			fmt.Sprintf("App %s did not become healthy on its new images and runs on the previous ones again.\n\n%v\n", upgrade.AppName, result.err),
+		)
+	default:
+		status, message = database.UpgradeStatusFailed, result.err.Error()
+		logging.Errorf("Upgrade of app %s failed: %v", upgrade.AppName, result.err)
+	}
+	if err := database.FinishAppUpgrade(upgrade.ID, status, message); err != nil {
+		logging.Errorf("Failed to record upgrade of app %s: %v", upgrade.AppName, err)
+	}
+
+	event := database.ActivityEvent{Category: database.ActivityJob, Title: "Upgraded the app", AppName: upgrade.AppName, Status: jobStateCompleted}
+	if status != database.UpgradeStatusSucceeded {
+		event.Title, event.Detail, event.Status = "Upgrade "+strings.ReplaceAll(status, "_", " "), message, jobStateFailed
+	}
+	recordActivity(event)
+
+	// The badge of the image update check goes away after a successful upgrade
+	if status == database.UpgradeStatusSucceeded {
+		if _, err := s.checkAppImageUpdates(registry.NewClient(), upgrade.AppName); err != nil {
+			logging.Warnf("Failed to check image updates of app %s: %v", upgrade.AppName, err)
+		}
+	}
+}
+
+// write writes the compose and .env files of an app, removing .env if envExists is false
+func (f *appUpgradeFiles) write(appDir string, composeContent, env []byte, envExists bool) error {
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), composeContent, 0600); err != nil {
+		return fmt.Errorf("failed to write docker-compose.yml: %w", err)
+	}
+	envFile := filepath.Join(appDir, ".env")
+	if !envExists {
+		if err := os.Remove(envFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove .env: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(envFile, env, 0600); err != nil {
+		return fmt.Errorf("failed to write .env: %w", err)
+	}
+	return nil
+}
+
+// waitAppHealthy waits until all containers of an app run and those with a health check
+// report healthy. Containers that exit fail right away, ones still starting at the
+// timeout fail then.
+func (s *Server) waitAppHealthy(ctx context.Context, appName string, timeout time.Duration) error {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+	opts := compose.Options{WorkingDir: filepath.Join(s.config.AppsDir, appName)}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		containers, err := composeSvc.PS(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		err = containersHealthy(containers)
+		for _, container := range containers {
+			switch {
+			case container.State == "exited" || container.State == "dead":
+				return err
+			case err == nil && container.Health == "starting":
+				err = fmt.Errorf("container %s is still starting", container.Service)
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not healthy after %s: %w", timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(upgradePollInterval(timeout)):
+		}
+	}
+}
+
+// upgradePollInterval checks short timeouts more often than readOnlyPollInterval
+func upgradePollInterval(timeout time.Duration) time.Duration {
+	return min(readOnlyPollInterval, max(timeout/10, 100*time.Millisecond))
+}
+
+// handleAPIAppUpgrade handles /api/apps/{appName}/upgrade: GET returns the past upgrades,
+// POST upgrades the app to the latest images for its tags in the background, with the
+// changes of the optional AppUpgradeRequest. A running app that doesn't become healthy
+// is rolled back to its previous images and files.
+func (s *Server) handleAPIAppUpgrade(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(filepath.Join(appDir, "docker-compose.yml")); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	var response AppUpgradeResponse
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var request AppUpgradeRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			if isBodyTooLarge(err) {
+				writeBodyTooLarge(w, s.bodyLimit(r.URL.Path))
+				return
+			}
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		timeout := upgradeHealthTimeout
+		if request.Timeout != "" {
+			d, err := time.ParseDuration(request.Timeout)
+			if err != nil || d <= 0 || d > upgradeMaxHealthTimeout {
+				http.Error(w, fmt.Sprintf("Timeout must be a duration up to %s", upgradeMaxHealthTimeout), http.StatusBadRequest)
+				return
+			}
+			timeout = d
+		}
+
+		files, err := s.prepareAppUpgradeFiles(appName, request.Operations)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		upgrade, err := s.startAppUpgrade(appName, user.Username, files, timeout)
+		if errors.Is(err, errUpgradeRunning) {
+			http.Error(w, "Another upgrade is running", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logging.Errorf("Failed to start upgrade of app %s: %v", appName, err)
+			http.Error(w, fmt.Sprintf("Failed to start upgrade: %v", err), http.StatusInternalServerError)
+			return
+		}
+		response.Upgrade = upgrade
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	upgrades, err := database.GetAppUpgrades(appName, upgradeHistoryLimit)
+	if err != nil {
+		logging.Errorf("Failed to get upgrades of app %s: %v", appName, err)
+	}
+	response.Upgrades = upgrades
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// prepareAppUpgradeFiles reads the files of an app and applies the operations of an
+// upgrade to them in memory. The result is validated like a start would.
+func (s *Server) prepareAppUpgradeFiles(appName string, operations []PatchOperation) (*appUpgradeFiles, error) {
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	files := &appUpgradeFiles{}
+	var err error
+	files.oldCompose, err = os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // Path from trusted app directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker-compose.yml: %w", err)
+	}
+	files.oldEnv, err = os.ReadFile(filepath.Join(appDir, ".env")) //nolint:gosec // Path from trusted app directory
+	switch {
+	case err == nil:
+		files.envExisted = true
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read .env: %w", err)
+	}
+
+	patch := &appPatch{compose: files.oldCompose, env: files.oldEnv, services: map[string]bool{}}
+	for i, op := range operations {
+		if err := patch.apply(op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i+1, op.Op, err)
+		}
+	}
+	files.compose, files.env = patch.compose, patch.env
+	files.hasEnv = files.envExisted || patch.envChanged
+
+	if !bytes.Equal(files.compose, files.oldCompose) {
+		metadata, err := yamlutil.ReadComposeMetadata(appDir)
+		if err != nil {
+			metadata = &yamlutil.OnTreeMetadata{}
+		}
+		if !metadata.BypassSecurity {
+			if err := security.NewValidator(appName).ValidateCompose(files.compose); err != nil {
+				return nil, fmt.Errorf("security validation failed: %w", err)
+			}
+		}
+	}
+	return files, nil
+}
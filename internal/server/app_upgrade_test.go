@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestAppUpgrade(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	composeSvc, err := compose.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	settle := upgradeSettleTime
+	upgradeSettleTime = 0
+	defer func() { upgradeSettleTime = settle }()
+
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "shop")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx:1.27\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}, composeSvc: composeSvc}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	request := func(method, body string) *httptest.ResponseRecorder {
+		req := newAppRequest(method, "/api/apps/shop/upgrade", bytes.NewReader([]byte(body)))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, staff))
+		rec := httptest.NewRecorder()
+		s.handleAPIAppUpgrade(rec, req)
+		return rec
+	}
+	// upgrade starts an upgrade and returns the history once it finished
+	upgrade := func(body string) []database.AppUpgrade {
+		t.Helper()
+		if rec := request(http.MethodPost, body); rec.Code != http.StatusAccepted {
+			t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+		}
+		s.upgradeMu.Lock()
+		s.upgradeMu.Unlock() //nolint:staticcheck // Waits for the upgrade to finish
+		rec := request(http.MethodGet, "")
+		var response AppUpgradeResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Upgrades
+	}
+
+	if rec := request(http.MethodPost, `{"timeout": "1y"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid timeout: status = %d", rec.Code)
+	}
+	if rec := request(http.MethodPost, `{"operations": [{"op": "set-image", "service": "cache", "image": "redis"}]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown service: status = %d", rec.Code)
+	}
+
+	// A stopped app only gets its files changed and its images pulled
+	upgrades := upgrade(`{"operations": [{"op": "set-env", "key": "MODE", "value": "blue"}]}`)
+	if len(upgrades) != 1 || upgrades[0].Status != database.UpgradeStatusSucceeded || upgrades[0].RequestedBy != "admin" {
+		t.Fatalf("upgrades = %+v", upgrades)
+	}
+	if env, _ := os.ReadFile(filepath.Join(appDir, ".env")); !strings.Contains(string(env), "MODE=blue") {
+		t.Errorf(".env = %q", env)
+	}
+
+	// A running app still starting at the timeout is rolled back to its previous files
+	if err := composeSvc.Up(context.Background(), compose.Options{WorkingDir: appDir}); err != nil {
+		t.Fatal(err)
+	}
+	defer composeSvc.Down(context.Background(), compose.Options{WorkingDir: appDir}, false) //nolint:errcheck // Test cleanup
+	upgrades = upgrade(`{"operations": [{"op": "set-image", "service": "web", "tag": "1.28"}, {"op": "set-env", "key": "MODE", "value": "green"}], "timeout": "100ms"}`)
+	if len(upgrades) != 2 || upgrades[0].Status != database.UpgradeStatusRolledBack || !strings.Contains(upgrades[0].Message, "starting") {
+		t.Fatalf("upgrades = %+v", upgrades)
+	}
+	if content, _ := os.ReadFile(filepath.Join(appDir, "docker-compose.yml")); !strings.Contains(string(content), "nginx:1.27") {
+		t.Errorf("docker-compose.yml not restored: %s", content)
+	}
+	if env, _ := os.ReadFile(filepath.Join(appDir, ".env")); !strings.Contains(string(env), "MODE=blue") {
+		t.Errorf(".env not restored: %q", env)
+	}
+	if _, ok := upgrades[0].PreviousImages["nginx:1.27"]; !ok {
+		t.Errorf("previous images = %v", upgrades[0].PreviousImages)
+	}
+}
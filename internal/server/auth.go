@@ -1,14 +1,19 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
-	"github.com/ontree-co/treeos/internal/database"
 
+	"github.com/ontree-co/treeos/internal/database"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// userRoleColumn selects the role of a user. Staff users are admins, whatever the role
+// column says, so that LDAP group changes and older databases need no migration.
+const userRoleColumn = `CASE WHEN is_staff = 1 THEN 'admin' WHEN role = 'viewer' THEN 'viewer' ELSE 'operator' END`
+
 // hashPassword hashes a plain text password
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -20,31 +25,38 @@ func checkPassword(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-// authenticateUser verifies username and password
+// authenticateUser verifies username and password. Local users are checked first, so a
+// directory user can't take over a local account with the same name.
 func (s *Server) authenticateUser(username, password string) (*database.User, error) {
 	db := database.GetDB()
 
 	user := &database.User{}
 	err := db.QueryRow(`
 		SELECT id, username, password, email, first_name, last_name, 
-		       is_staff, is_superuser, is_active, date_joined, last_login
-		FROM users WHERE username = ? AND is_active = 1
+		       is_staff, is_superuser, is_active, date_joined, last_login,
+		       COALESCE(auth_source, 'local'), `+userRoleColumn+`, COALESCE(session_version, 0)
+		FROM users WHERE username = ?
 	`, username).Scan(
 		&user.ID, &user.Username, &user.Password, &user.Email,
 		&user.FirstName, &user.LastName, &user.IsStaff, &user.IsSuperuser,
-		&user.IsActive, &user.DateJoined, &user.LastLogin,
+		&user.IsActive, &user.DateJoined, &user.LastLogin, &user.AuthSource, &user.Role, &user.SessionVersion,
 	)
 
-	if err != nil {
-		if err == sql.ErrNoRows {
+	switch {
+	case err == nil && user.AuthSource != database.AuthSourceLDAP:
+		// Check password
+		if !user.IsActive || checkPassword(password, user.Password) != nil {
 			return nil, fmt.Errorf("invalid username or password")
 		}
-		return nil, err
-	}
-
-	// Check password
-	if err := checkPassword(password, user.Password); err != nil {
+	case (err == nil || err == sql.ErrNoRows) && s.ldapEnabled():
+		user, err = s.authenticateLDAP(username, password)
+		if err != nil {
+			return nil, err
+		}
+	case err == nil || err == sql.ErrNoRows:
 		return nil, fmt.Errorf("invalid username or password")
+	default:
+		return nil, err
 	}
 
 	// Update last login
@@ -59,6 +71,21 @@ func (s *Server) authenticateUser(username, password string) (*database.User, er
 	return user, nil
 }
 
+// verifyPassword checks the password of a logged-in user again, against the directory for
+// LDAP users
+func (s *Server) verifyPassword(user *database.User, password string) error {
+	if user.AuthSource == database.AuthSourceLDAP {
+		if !s.ldapEnabled() {
+			return fmt.Errorf("LDAP logins are disabled")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ldapTimeout)
+		defer cancel()
+		_, err := s.ldapConfig().Authenticate(ctx, user.Username, password)
+		return err
+	}
+	return checkPassword(password, user.Password)
+}
+
 // getUserByID retrieves a user by ID
 func (s *Server) getUserByID(id int) (*database.User, error) {
 	db := database.GetDB()
@@ -66,12 +93,13 @@ func (s *Server) getUserByID(id int) (*database.User, error) {
 	user := &database.User{}
 	err := db.QueryRow(`
 		SELECT id, username, password, email, first_name, last_name, 
-		       is_staff, is_superuser, is_active, date_joined, last_login
+		       is_staff, is_superuser, is_active, date_joined, last_login,
+		       COALESCE(auth_source, 'local'), `+userRoleColumn+`, COALESCE(session_version, 0)
 		FROM users WHERE id = ? AND is_active = 1
 	`, id).Scan(
 		&user.ID, &user.Username, &user.Password, &user.Email,
 		&user.FirstName, &user.LastName, &user.IsStaff, &user.IsSuperuser,
-		&user.IsActive, &user.DateJoined, &user.LastLogin,
+		&user.IsActive, &user.DateJoined, &user.LastLogin, &user.AuthSource, &user.Role, &user.SessionVersion,
 	)
 
 	if err != nil {
@@ -105,6 +133,10 @@ func (s *Server) createUser(username, password, email string, isStaff, isSuperus
 		return nil, fmt.Errorf("failed to get user ID: %w", err)
 	}
 
+	role := database.RoleOperator
+	if isStaff {
+		role = database.RoleAdmin
+	}
 	return &database.User{
 		ID:          int(id),
 		Username:    username,
@@ -114,5 +146,55 @@ func (s *Server) createUser(username, password, email string, isStaff, isSuperus
 		IsSuperuser: isSuperuser,
 		IsActive:    true,
 		DateJoined:  now,
+		AuthSource:  database.AuthSourceLocal,
+		Role:        role,
 	}, nil
 }
+
+// setUserPassword changes the password of a local user and ends all their sessions. It
+// returns the new session version, for a session of the user that should go on.
+func setUserPassword(id int, password string) (int, error) {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var version int
+	err = database.GetDB().QueryRow(`
+		UPDATE users SET password = ?, session_version = COALESCE(session_version, 0) + 1
+		WHERE id = ? AND COALESCE(auth_source, 'local') = 'local'
+		RETURNING session_version
+	`, hashedPassword, id).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set password: %w", err)
+	}
+	if err := database.DeleteUserSessions(id); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// listUsers returns all active users ordered by username, without password hashes
+func (s *Server) listUsers() ([]database.User, error) {
+	db := database.GetDB()
+
+	rows, err := db.Query(`
+		SELECT id, username, is_staff, ` + userRoleColumn + `
+		FROM users WHERE is_active = 1
+		ORDER BY username
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	var users []database.User
+	for rows.Next() {
+		var user database.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.IsStaff, &user.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
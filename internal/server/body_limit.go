@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+// defaultMaxRequestBody is used when the configured limit can't be parsed
+const defaultMaxRequestBody = 1 << 20
+
+// bodyLimits overrides the global request body limit for endpoints that take uploads, by path
+// prefix. A limit of 0 means the handler streams the body to disk and needs no limit.
+var bodyLimits = []struct {
+	prefix string
+	limit  int64
+}{
+	{"/api/agent/memory/import", maxAgentMemorySize},
+	{"/api/system/config/import", maxConfigBundleSize},
+	{webDAVPrefix + "/", 0},
+}
+
+// bodyLimit returns the request body limit for a path
+func (s *Server) bodyLimit(path string) int64 {
+	for _, l := range bodyLimits {
+		if strings.HasPrefix(path, l.prefix) {
+			return l.limit
+		}
+	}
+	// Volume imports stream the archive to Docker
+	if strings.HasPrefix(path, "/api/apps/") && strings.Contains(path, "/volumes/") && strings.HasSuffix(path, "/import") {
+		return 0
+	}
+	limit, err := storage.ParseSize(s.config.MaxRequestBodySize)
+	if err != nil {
+		return defaultMaxRequestBody
+	}
+	return int64(limit) //nolint:gosec // Sizes are far below the int64 range
+}
+
+// BodyLimitMiddleware limits the size of request bodies so a large upload can't exhaust the
+// memory of the node. Requests that announce a larger body are rejected before it is read,
+// reading past the limit otherwise fails with *http.MaxBytesError (see isBodyTooLarge).
+func (s *Server) BodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := s.bodyLimit(r.URL.Path)
+		if limit > 0 {
+			if r.ContentLength > limit {
+				logging.Warnf("Rejected %s %s with a body of %d bytes, the limit is %d", r.Method, r.URL.Path, r.ContentLength, limit)
+				writeBodyTooLarge(w, limit)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isBodyTooLarge reports whether reading the request body failed because of its size
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	http.Error(w, fmt.Sprintf("Request body too large, the limit is %s", storage.FormatSize(uint64(limit))), http.StatusRequestEntityTooLarge) //nolint:gosec // Limit is positive
+}
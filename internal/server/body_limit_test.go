@@ -0,0 +1,54 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	s := &Server{config: &config.Config{MaxRequestBodySize: "1KB"}}
+	handler := s.BodyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			if isBodyTooLarge(err) {
+				writeBodyTooLarge(w, s.bodyLimit(r.URL.Path))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool // No Content-Length, the limit applies while reading
+		want    int
+	}{
+		{"small body", "/api/apps", 500, false, http.StatusOK},
+		{"announced body over the limit", "/api/apps", 2000, false, http.StatusRequestEntityTooLarge},
+		{"streamed body over the limit", "/api/apps", 2000, true, http.StatusRequestEntityTooLarge},
+		{"upload endpoint has its own limit", "/api/system/config/import", 2000, false, http.StatusOK},
+		{"webdav is not limited", webDAVPrefix + "/app/file.bin", 1 << 20, true, http.StatusOK},
+		{"volume imports are not limited", "/api/apps/wiki/volumes/wiki_data/import", 1 << 20, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(strings.Repeat("x", tt.size))
+			if tt.chunked {
+				body = io.MultiReader(body) // Hides the length from NewRequest
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
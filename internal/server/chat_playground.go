@@ -0,0 +1,297 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/docs"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// chatPlaygroundTimeout limits one reply, slow local models take a while for long answers
+	chatPlaygroundTimeout = 5 * time.Minute
+	chatPlaygroundPrompt  = "You are a helpful assistant on a self-hosted TreeOS server. Format your answers in Markdown."
+)
+
+// chatPlaygroundID returns the app ID the playground messages of a user are stored with in
+// chat_messages. App names can't contain an underscore, so they never mix with an app's chat.
+func chatPlaygroundID(username string) string {
+	return "_playground:" + username
+}
+
+// playgroundMessage is a stored playground message with its Markdown rendered
+type playgroundMessage struct {
+	database.ChatMessage
+	HTML template.HTML `json:"html"`
+}
+
+func newPlaygroundMessage(m database.ChatMessage) playgroundMessage {
+	return playgroundMessage{ChatMessage: m, HTML: docs.Render(m.Message)}
+}
+
+// playgroundEvent is a line of the NDJSON stream of a playground reply
+type playgroundEvent struct {
+	Type    string             `json:"type"` // delta, done or error
+	Content string             `json:"content,omitempty"`
+	Message *playgroundMessage `json:"message,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// handleChatPlayground renders the chat playground page
+func (s *Server) handleChatPlayground(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	data := s.baseTemplateData(user)
+	llm, configured := s.agentLLM()
+	data["LLMConfigured"] = configured
+	data["LLMModel"] = llm.Model
+
+	tmpl, ok := s.templates["chat"]
+	if !ok {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		logging.Errorf("Error rendering template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
+
+// handleAPIChat handles /api/chat, the chat playground of the current user:
+// GET returns the history, POST streams the reply to a message, DELETE clears the history
+func (s *Server) handleAPIChat(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID := chatPlaygroundID(user.Username)
+
+	switch r.Method {
+	case http.MethodGet:
+		messages, err := database.GetChatMessages(chatID, appChatPageSize)
+		if err != nil {
+			logging.Errorf("Failed to get playground chat of %s: %v", user.Username, err)
+			http.Error(w, "Failed to get chat messages", http.StatusInternalServerError)
+			return
+		}
+		rendered := make([]playgroundMessage, 0, len(messages))
+		for _, m := range messages {
+			rendered = append(rendered, newPlaygroundMessage(m))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"messages": rendered}); err != nil {
+			logging.Errorf("Failed to encode chat messages: %v", err)
+		}
+	case http.MethodPost:
+		s.handleAPIChatMessage(w, r, chatID, user.Username)
+	case http.MethodDelete:
+		if err := database.DeleteChatMessages(chatID); err != nil {
+			logging.Errorf("Failed to clear playground chat of %s: %v", user.Username, err)
+			http.Error(w, "Failed to clear chat", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIChatMessage stores a message and streams the reply of the LLM as NDJSON events.
+// Closing the request stops the generation, the reply so far is kept.
+func (s *Server) handleAPIChatMessage(w http.ResponseWriter, r *http.Request, chatID, username string) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Message) == "" {
+		http.Error(w, "A message is required", http.StatusBadRequest)
+		return
+	}
+
+	llm, ok := s.agentLLM()
+	if !ok {
+		http.Error(w, "The LLM is not configured. Set it up in Settings first.", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := database.GetChatMessages(chatID, appAgentHistory)
+	if err != nil {
+		logging.Errorf("Failed to get playground chat of %s: %v", username, err)
+		http.Error(w, "Failed to get chat messages", http.StatusInternalServerError)
+		return
+	}
+	userMessage := database.ChatMessage{AppID: chatID, Message: strings.TrimSpace(req.Message), SenderType: database.SenderTypeUser, SenderName: username}
+	if userMessage.ID, err = database.AddChatMessage(userMessage); err != nil {
+		logging.Errorf("Failed to store playground message of %s: %v", username, err)
+		http.Error(w, "Failed to store message", http.StatusInternalServerError)
+		return
+	}
+
+	messages := []llmMessage{{Role: "system", Content: chatPlaygroundPrompt}}
+	for _, m := range history {
+		switch m.SenderType {
+		case database.SenderTypeUser:
+			messages = append(messages, llmMessage{Role: "user", Content: m.Message})
+		case database.SenderTypeAgent:
+			messages = append(messages, llmMessage{Role: "assistant", Content: m.Message})
+		}
+	}
+	messages = append(messages, llmMessage{Role: "user", Content: userMessage.Message})
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(chatPlaygroundTimeout + 10*time.Second)); err != nil {
+		logging.Debugf("Failed to extend write deadline for the playground chat of %s: %v", username, err)
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	encoder := json.NewEncoder(w)
+	send := func(event playgroundEvent) {
+		if err := encoder.Encode(event); err == nil {
+			flusher.Flush()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), chatPlaygroundTimeout)
+	defer cancel()
+	reply, err := streamChatCompletion(ctx, llm, messages, func(delta string) {
+		send(playgroundEvent{Type: "delta", Content: delta})
+	})
+	stopped := errors.Is(r.Context().Err(), context.Canceled)
+
+	agentMessage := database.ChatMessage{
+		AppID:         chatID,
+		SenderType:    database.SenderTypeAgent,
+		SenderName:    "Assistant",
+		AgentModel:    llm.Model,
+		AgentProvider: llm.provider(),
+		Message:       strings.TrimSpace(reply),
+	}
+	switch {
+	case stopped:
+		// Keep what was generated until the user stopped it
+		agentMessage.Details = `{"stopped":true}`
+		if agentMessage.Message == "" {
+			return
+		}
+	case err != nil:
+		logging.Warnf("Playground chat of %s failed: %v", username, err)
+		if agentMessage.Message == "" {
+			agentMessage.SenderType = database.SenderTypeSystem
+			agentMessage.SenderName = "System"
+			agentMessage.StatusLevel = database.StatusLevelError
+			agentMessage.Message = "The LLM failed: " + err.Error()
+		}
+	}
+	if agentMessage.ID, err = database.AddChatMessage(agentMessage); err != nil {
+		logging.Errorf("Failed to store playground reply of %s: %v", username, err)
+	}
+	agentMessage.Timestamp = time.Now()
+
+	message := newPlaygroundMessage(agentMessage)
+	if agentMessage.SenderType == database.SenderTypeSystem {
+		send(playgroundEvent{Type: "error", Error: agentMessage.Message, Message: &message})
+		return
+	}
+	send(playgroundEvent{Type: "done", Message: &message})
+}
+
+// streamChatCompletion streams a chat completion, calling onDelta with each piece of content.
+// It returns the content so far also when the stream breaks off.
+func streamChatCompletion(ctx context.Context, llm llmConfig, messages []llmMessage, onDelta func(string)) (string, error) {
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model":    llm.Model,
+		"messages": messages,
+		"stream":   true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, llm.APIURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if llm.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+llm.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup, error not critical
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck // Best effort error details
+		var errorResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+			return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, errorResp.Error.Message)
+		}
+		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return content.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+	if content.Len() == 0 {
+		return "", fmt.Errorf("the endpoint returned no streamed content")
+	}
+	return content.String(), nil
+}
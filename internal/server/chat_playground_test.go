@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestHandleAPIChat(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+
+	s := &Server{config: &config.Config{AgentLLMAPIKey: "test-key", AgentLLMAPIURL: streamingLLM(t, 3, false), AgentLLMModel: "test"}}
+	request := func(user *database.User, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/chat", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIChat(rec, req)
+		return rec
+	}
+	alice := &database.User{ID: 1, Username: "alice"}
+	bob := &database.User{ID: 2, Username: "bob"}
+
+	rec := request(alice, http.MethodPost, `{"message":"Count to 3"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("POST = %d: %s", rec.Code, rec.Body)
+	}
+	var deltas []string
+	var done *playgroundMessage
+	for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		var event playgroundEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		switch event.Type {
+		case "delta":
+			deltas = append(deltas, event.Content)
+		case "done":
+			done = event.Message
+		}
+	}
+	if strings.Join(deltas, "") != "1 2 3 " || done == nil || done.Message != "1 2 3" || done.AgentModel != "test" {
+		t.Errorf("deltas = %q, done = %+v", deltas, done)
+	}
+
+	var history struct {
+		Messages []playgroundMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(request(alice, http.MethodGet, "").Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Messages) != 2 || history.Messages[0].Message != "Count to 3" || !strings.Contains(string(history.Messages[1].HTML), "<p>1 2 3</p>") {
+		t.Errorf("history = %+v", history.Messages)
+	}
+	// Each user has their own history, separate from the chats of apps
+	if err := json.NewDecoder(request(bob, http.MethodGet, "").Body).Decode(&history); err != nil || len(history.Messages) != 0 {
+		t.Errorf("history of another user = %+v, %v", history.Messages, err)
+	}
+
+	if rec := request(alice, http.MethodPost, `{"message":" "}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST without a message = %d", rec.Code)
+	}
+	if rec := request(alice, http.MethodDelete, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d", rec.Code)
+	}
+	if messages, err := database.GetChatMessages(chatPlaygroundID("alice"), 10); err != nil || len(messages) != 0 {
+		t.Errorf("messages after clearing = %v, %v", messages, err)
+	}
+
+	s.config.AgentLLMAPIURL = ""
+	if rec := request(alice, http.MethodPost, `{"message":"Hi"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST without an LLM = %d", rec.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/internalca"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/pkcs12"
+)
+
+// maxClientCertNameLength caps the device label of a client certificate
+const maxClientCertNameLength = 64
+
+var errClientCertRequired = errors.New("client certificate required")
+
+// clientCertView is shown in the settings so users can enroll and revoke certificates
+type clientCertView struct {
+	Certificates []database.ClientCertificate
+	Presented    string // Serial of the certificate the browser presented, empty for none
+}
+
+// ClientCertBundle is the one-time download of a new client certificate. The key only
+// exists in it, TreeOS doesn't keep a copy.
+type ClientCertBundle struct {
+	Certificate database.ClientCertificate `json:"certificate"`
+	Filename    string                     `json:"filename"`
+	Password    string                     `json:"password"` // Protects the bundle, shown once
+	Bundle      []byte                     `json:"bundle"`   // PKCS#12, base64 encoded in JSON
+}
+
+// dashboardTLSConfig returns the TLS configuration of the dashboard listener. Client
+// certificates are requested but not required, so browsers without one can still reach
+// the login page and users without a certificate can enroll.
+func (s *Server) dashboardTLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.internalCA.Certificate())
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		ClientAuth:     tls.VerifyClientCertIfGiven,
+		ClientCAs:      pool,
+		GetCertificate: s.dashboardCertificate,
+	}
+}
+
+// dashboardCertificate returns the server certificate of the dashboard listener, issuing
+// a new one from the internal CA when it is about to expire
+func (s *Server) dashboardCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.dashboardCertMu.Lock()
+	defer s.dashboardCertMu.Unlock()
+
+	hosts := s.internalHosts()
+	if s.dashboardCert != nil && !internalca.NeedsRenewal(s.dashboardCertPEM, hosts, time.Now()) {
+		return s.dashboardCert, nil
+	}
+	certPEM, keyPEM, err := s.internalCA.Issue(hosts)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	s.dashboardCert, s.dashboardCertPEM = &cert, certPEM
+	logging.Infof("Issued dashboard certificate for %s", strings.Join(hosts, ", "))
+	return s.dashboardCert, nil
+}
+
+// presentedClientSerial returns the serial of the client certificate a request was made
+// with, empty when the browser presented none. The handshake verified it against the CA.
+func presentedClientSerial(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].SerialNumber.Text(16)
+}
+
+// clientCertSatisfied reports whether a request of user passes client certificate auth.
+// Staff users who enrolled a certificate must present one of theirs; everyone else passes.
+func (s *Server) clientCertSatisfied(r *http.Request, user *database.User) bool {
+	if !s.config.ClientCertAuth || user == nil || !user.IsStaff {
+		return true
+	}
+	if serial := presentedClientSerial(r); serial != "" {
+		active, err := database.IsClientCertificateActive(serial, user.ID)
+		if err != nil {
+			logging.Errorf("Failed to check client certificate of %s: %v", user.Username, err)
+			return false
+		}
+		if active {
+			return true
+		}
+	}
+	required, err := database.HasActiveClientCertificate(user.ID, time.Now())
+	if err != nil {
+		logging.Errorf("Failed to check client certificates of %s: %v", user.Username, err)
+		return false
+	}
+	return !required
+}
+
+// clientCertificates returns the settings view of a user's certificates, nil when client
+// certificate auth is off
+func (s *Server) clientCertificates(r *http.Request, user *database.User) *clientCertView {
+	if !s.config.ClientCertAuth || s.internalCA == nil || user == nil || !user.IsStaff {
+		return nil
+	}
+	certs, err := database.GetClientCertificates(user.ID)
+	if err != nil {
+		logging.Errorf("Failed to get client certificates: %v", err)
+	}
+	return &clientCertView{Certificates: certs, Presented: presentedClientSerial(r)}
+}
+
+// issueClientCertificate signs a certificate for user and packs it with its key into a
+// password protected bundle
+func (s *Server) issueClientCertificate(user *database.User, name string) (*ClientCertBundle, error) {
+	cert, key, err := s.internalCA.IssueClient(user.Username)
+	if err != nil {
+		return nil, err
+	}
+	password := rand.Text()
+	data, err := pkcs12.Encode(key, cert, []*x509.Certificate{s.internalCA.Certificate()}, password, "TreeOS "+user.Username+" ("+name+")")
+	if err != nil {
+		return nil, err
+	}
+
+	record := database.ClientCertificate{
+		Serial:    cert.SerialNumber.Text(16),
+		UserID:    user.ID,
+		Name:      name,
+		NotAfter:  cert.NotAfter,
+		CreatedAt: time.Now(),
+	}
+	if err := database.CreateClientCertificate(record); err != nil {
+		return nil, err
+	}
+	return &ClientCertBundle{
+		Certificate: record,
+		Filename:    "treeos-" + user.Username + "-" + s.config.InternalDomain + ".p12",
+		Password:    password,
+		Bundle:      data,
+	}, nil
+}
+
+// handleAPIClientCertificates handles /api/client-certificates: GET lists the certificates
+// of the current user, POST issues a new one and returns it once, DELETE ?serial= revokes one
+func (s *Server) handleAPIClientCertificates(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !s.config.ClientCertAuth || s.internalCA == nil {
+		http.Error(w, "Client certificate auth is not enabled", http.StatusConflict)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > maxClientCertNameLength {
+			http.Error(w, "Name the device the certificate is for, at most 64 characters", http.StatusBadRequest)
+			return
+		}
+
+		bundle, err := s.issueClientCertificate(user, req.Name)
+		if err != nil {
+			logging.Errorf("Failed to issue client certificate: %v", err)
+			http.Error(w, "Failed to issue client certificate", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s enrolled client certificate %s for %q", user.Username, bundle.Certificate.Serial, req.Name)
+
+		// Never cache the only copy of the key
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(bundle); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+		return
+	case http.MethodDelete:
+		serial := strings.ToLower(r.URL.Query().Get("serial"))
+		revoked, err := database.RevokeClientCertificate(serial, user.ID)
+		if err != nil {
+			logging.Errorf("Failed to revoke client certificate: %v", err)
+			http.Error(w, "Failed to revoke client certificate", http.StatusInternalServerError)
+			return
+		}
+		if !revoked {
+			http.Error(w, "Certificate not found", http.StatusNotFound)
+			return
+		}
+		logging.Infof("User %s revoked client certificate %s", user.Username, serial)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	certs, err := database.GetClientCertificates(user.ID)
+	if err != nil {
+		logging.Errorf("Failed to get client certificates: %v", err)
+		http.Error(w, "Failed to get client certificates", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"certificates": certs,
+		"presented":    presentedClientSerial(r),
+	}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/cache"
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/internalca"
+	"golang.org/x/net/webdav"
+)
+
+func TestClientCertificates(t *testing.T) {
+	tmp := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmp, "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	ca, err := internalca.LoadOrCreate(filepath.Join(tmp, "ca"), "TreeOS Internal CA mynode.lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		config:     &config.Config{InternalDomain: "mynode.lan", ClientCertAuth: true},
+		internalCA: ca,
+	}
+	admin := &database.User{ID: 1, Username: "admin", IsStaff: true}
+	other := &database.User{ID: 2, Username: "other", IsStaff: true}
+	viewer := &database.User{ID: 3, Username: "viewer"}
+
+	request := func(method, url string, body []byte, user *database.User, cert *x509.Certificate) *http.Request {
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca.Certificate()}}}
+		}
+		return req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+	}
+
+	// Without an enrolled certificate the password is enough
+	if !s.clientCertSatisfied(request(http.MethodGet, "/", nil, admin, nil), admin) {
+		t.Error("admin without a certificate was rejected")
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleAPIClientCertificates(rec, request(http.MethodPost, "/api/client-certificates", []byte(`{"name": "Laptop"}`), admin, nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+	}
+	var bundle ClientCertBundle
+	if err := json.NewDecoder(rec.Body).Decode(&bundle); err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Bundle) == 0 || bundle.Password == "" || bundle.Filename != "treeos-admin-mynode.lan.p12" {
+		t.Errorf("bundle = %s, password %q", bundle.Filename, bundle.Password)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("bundle may be cached")
+	}
+
+	// The handshake verifies certificates, the test issues one the same way to present it
+	cert, _, err := ca.IssueClient("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCert, _, err := ca.IssueClient("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []database.ClientCertificate{
+		{Serial: cert.SerialNumber.Text(16), UserID: admin.ID, Name: "Test", NotAfter: cert.NotAfter},
+		{Serial: otherCert.SerialNumber.Text(16), UserID: other.ID, Name: "Test", NotAfter: otherCert.NotAfter},
+	} {
+		if err := database.CreateClientCertificate(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		user *database.User
+		cert *x509.Certificate
+		want bool
+	}{
+		{"enrolled admin with own certificate", admin, cert, true},
+		{"enrolled admin without certificate", admin, nil, false},
+		{"enrolled admin with another user's certificate", admin, otherCert, false},
+		{"non-staff user", viewer, nil, true},
+	} {
+		if got := s.clientCertSatisfied(request(http.MethodGet, "/", nil, tt.user, tt.cert), tt.user); got != tt.want {
+			t.Errorf("%s: clientCertSatisfied() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleAPIClientCertificates(rec, request(http.MethodDelete, "/api/client-certificates?serial="+cert.SerialNumber.Text(16), nil, admin, cert))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d: %s", rec.Code, rec.Body)
+	}
+	if s.clientCertSatisfied(request(http.MethodGet, "/", nil, admin, cert), admin) {
+		t.Error("revoked certificate is accepted while the admin has another one")
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleAPIClientCertificates(rec, request(http.MethodDelete, "/api/client-certificates?serial="+otherCert.SerialNumber.Text(16), nil, admin, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("revoking another user's certificate: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleAPIClientCertificates(rec, request(http.MethodGet, "/api/client-certificates", nil, viewer, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-staff GET status = %d", rec.Code)
+	}
+
+	s.config.ClientCertAuth = false
+	if !s.clientCertSatisfied(request(http.MethodGet, "/", nil, admin, nil), admin) {
+		t.Error("certificates are enforced with client_cert_auth off")
+	}
+}
+
+func TestDashboardCertificate(t *testing.T) {
+	ca, err := internalca.LoadOrCreate(t.TempDir(), "TreeOS Internal CA mynode.lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{InternalDomain: "mynode.lan"}, internalCA: ca}
+
+	first, err := s.dashboardCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := s.dashboardCertificate(nil); second != first {
+		t.Error("certificate was issued again before it needs renewal")
+	}
+	if cfg := s.dashboardTLSConfig(); cfg.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Errorf("ClientAuth = %v, want VerifyClientCertIfGiven", cfg.ClientAuth)
+	}
+}
+
+func TestClientCertificateTokensAndWebDAV(t *testing.T) {
+	tmp := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmp, "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+
+	ca, err := internalca.LoadOrCreate(filepath.Join(tmp, "ca"), "TreeOS Internal CA mynode.lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var adminID int
+	if err := database.GetDB().QueryRow(`INSERT INTO users (username, password, is_staff, is_active) VALUES ('admin', ?, 1, 1) RETURNING id`, hash).Scan(&adminID); err != nil {
+		t.Fatal(err)
+	}
+	cert, _, err := ca.IssueClient("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateClientCertificate(database.ClientCertificate{Serial: cert.SerialNumber.Text(16), UserID: adminID, Name: "Test", NotAfter: cert.NotAfter}); err != nil {
+		t.Fatal(err)
+	}
+	secret := apiTokenPrefix + "certtest"
+	if err := database.CreateAPIToken(&database.APIToken{UserID: adminID, Name: "script", Prefix: secret[:apiTokenPrefixLength], Scope: database.APITokenScopeFull}, hashToken(secret)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		config:          &config.Config{InternalDomain: "mynode.lan", ClientCertAuth: true, APIToken: "config-token", WebDAVEnabled: true, AppsDir: filepath.Join(tmp, "apps")},
+		internalCA:      ca,
+		webDAVAuthCache: cache.New(webDAVAuthCacheTTL),
+		webDAVLocks:     webdav.NewMemLS(),
+	}
+	withCert := func(req *http.Request, present bool) *http.Request {
+		if present {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca.Certificate()}}}
+		}
+		return req
+	}
+	handler := s.AuthRequiredMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	call := func(token string, present bool) int {
+		req := withCert(httptest.NewRequest(http.MethodGet, "/api/apps/status", nil), present)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+	propfind := func(present bool) int {
+		req := withCert(httptest.NewRequest("PROPFIND", "/dav/", nil), present)
+		req.Header.Set("Depth", "0")
+		req.SetBasicAuth("admin", "correct horse")
+		rec := httptest.NewRecorder()
+		s.handleWebDAV(rec, req)
+		return rec.Code
+	}
+
+	for _, tt := range []struct {
+		name string
+		got  int
+		want int
+	}{
+		{"API token without certificate", call(secret, false), http.StatusForbidden},
+		{"API token with certificate", call(secret, true), http.StatusOK},
+		{"config api_token without certificate", call("config-token", false), http.StatusOK},
+		{"WebDAV without certificate", propfind(false), http.StatusForbidden},
+		{"WebDAV with certificate", propfind(true), http.StatusMultiStatus},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
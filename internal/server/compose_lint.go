@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/secrets"
+	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+	"gopkg.in/yaml.v3"
+)
+
+// Checks of the compose linter
+const (
+	lintCheckSchema   = "schema"
+	lintCheckSecurity = "security"
+	lintCheckPorts    = "ports"
+	lintCheckAppYml   = "app_yml"
+)
+
+// composeEdit holds the files of an app as edited on the edit page, not saved yet
+type composeEdit struct {
+	Compose string `json:"compose_yaml"`
+	Env     string `json:"env_content"`
+	AppYml  string `json:"app_yml_content"`
+}
+
+// ComposeLintIssue is a problem found in an edit
+type ComposeLintIssue struct {
+	Check   string `json:"check"` // schema, security, ports or app_yml
+	Service string `json:"service,omitempty"`
+	Message string `json:"message"`
+}
+
+// ComposeLintReport is the outcome of checking an edit before it is saved
+type ComposeLintReport struct {
+	Errors   []ComposeLintIssue       `json:"errors"` // Block the save
+	Warnings []ComposeLintIssue       `json:"warnings"`
+	Changes  []yamlutil.ServiceChange `json:"changes"` // Services added, removed or changed
+	Diffs    map[string]string        `json:"diffs"`   // Unified diff of each changed file
+}
+
+func (r *ComposeLintReport) addError(check, service, message string) {
+	r.Errors = append(r.Errors, ComposeLintIssue{Check: check, Service: service, Message: message})
+}
+
+func (r *ComposeLintReport) addWarning(check, service, message string) {
+	r.Warnings = append(r.Warnings, ComposeLintIssue{Check: check, Service: service, Message: message})
+}
+
+// errorSummary joins the errors of a report for the edit form
+func (r *ComposeLintReport) errorSummary() string {
+	messages := make([]string, 0, len(r.Errors))
+	for _, issue := range r.Errors {
+		if issue.Service != "" {
+			messages = append(messages, fmt.Sprintf("service %s %s", issue.Service, issue.Message))
+		} else {
+			messages = append(messages, issue.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// lintComposeEdit checks an edit of an app's files the way saving and starting the app
+// would: the compose structure and its references, `docker compose config` when the
+// runtime is up, the security rules unless the app bypasses them, host ports and app.yml.
+// It also compares the edit with the saved files.
+func (s *Server) lintComposeEdit(ctx context.Context, appName string, edit composeEdit) *ComposeLintReport {
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	report := &ComposeLintReport{
+		Errors:   []ComposeLintIssue{},
+		Warnings: []ComposeLintIssue{},
+		Changes:  []yamlutil.ServiceChange{},
+		Diffs:    map[string]string{},
+	}
+
+	if edit.AppYml != "" {
+		var appYml map[string]interface{}
+		err := yaml.Unmarshal([]byte(edit.AppYml), &appYml)
+		if err == nil {
+			_, err = parseAppDependencies(appName, []byte(edit.AppYml))
+		}
+		if err != nil {
+			report.addError(lintCheckAppYml, "", fmt.Sprintf("Invalid app.yml: %v", err))
+		}
+	}
+
+	s.diffComposeEdit(appDir, edit, report)
+
+	if err := yamlutil.ValidateComposeFile(edit.Compose); err != nil {
+		// The other checks need a well-formed file
+		report.addError(lintCheckSchema, "", fmt.Sprintf("Invalid docker-compose.yml: %v", err))
+		return report
+	}
+	findings, err := yamlutil.LintCompose([]byte(edit.Compose))
+	if err != nil {
+		report.addError(lintCheckSchema, "", err.Error())
+	}
+	for _, finding := range findings {
+		report.addError(lintCheckSchema, finding.Service, finding.Message)
+	}
+	if composeSvc, err := s.getComposeService(); err == nil {
+		renderCtx, cancel := context.WithTimeout(ctx, resolvedConfigTimeout)
+		rendered, err := composeSvc.RenderConfig(renderCtx, compose.Options{WorkingDir: appDir}, &compose.Draft{Compose: edit.Compose, Env: edit.Env})
+		cancel()
+		switch {
+		case err != nil:
+			logging.Warnf("Failed to render config of app %s for the linter: %v", appName, err)
+		case rendered.Error != "":
+			report.addError(lintCheckSchema, "", rendered.Error)
+		default:
+			for _, warning := range rendered.Warnings {
+				report.addWarning(lintCheckSchema, "", warning)
+			}
+		}
+	}
+
+	// An edit can't switch the validation off, the saved setting counts
+	metadata, err := yamlutil.ReadComposeMetadata(appDir)
+	if err != nil {
+		metadata = &yamlutil.OnTreeMetadata{}
+	}
+	validator := security.NewValidator(appName)
+	if !metadata.BypassSecurity {
+		if err := validator.ValidateCompose([]byte(edit.Compose)); err != nil {
+			report.addError(lintCheckSecurity, "", err.Error())
+		}
+	} else if securityReport, err := validator.Report([]byte(edit.Compose)); err == nil {
+		for _, finding := range securityReport.Findings {
+			report.addWarning(lintCheckSecurity, finding.Service, fmt.Sprintf("%s (security validation is bypassed for this app)", finding.Detail))
+		}
+	}
+
+	if _, _, err := s.claimHostPorts(appName, edit.Compose, edit.Env, false); err != nil {
+		report.addError(lintCheckPorts, "", fmt.Sprintf("Host ports are taken: %v", err))
+	}
+	for _, binding := range unavailableBindings([]byte(edit.Compose)) {
+		report.addError(lintCheckPorts, "", fmt.Sprintf("Port %s is bound to an address this host doesn't have", binding))
+	}
+	return report
+}
+
+// diffComposeEdit adds the differences between the saved files and an edit to a report.
+// The .env is compared masked, as the edit page shows it.
+func (s *Server) diffComposeEdit(appDir string, edit composeEdit, report *ComposeLintReport) {
+	saved := func(name string) []byte {
+		content, err := os.ReadFile(filepath.Join(appDir, name)) //nolint:gosec // Path from trusted app directory
+		if err != nil {
+			return nil
+		}
+		return content
+	}
+	savedCompose := saved("docker-compose.yml")
+	files := []struct {
+		name          string
+		before, after []byte
+	}{
+		{".env", secrets.Mask(saved(".env")), []byte(edit.Env)},
+		{"docker-compose.yml", savedCompose, []byte(edit.Compose)},
+		{"app.yml", saved("app.yml"), []byte(edit.AppYml)},
+	}
+	for _, file := range files {
+		// An empty app.yml field keeps the saved file
+		if file.name == "app.yml" && edit.AppYml == "" {
+			continue
+		}
+		if diff := yamlutil.LineDiff(file.before, file.after); diff != "" {
+			report.Diffs[file.name] = diff
+		}
+	}
+	if changes, err := yamlutil.ServiceChanges(savedCompose, []byte(edit.Compose)); err == nil && changes != nil {
+		report.Changes = changes
+	}
+}
+
+// handleAPIAppLint handles POST /api/apps/{name}/lint with the edited files
+// {"compose_yaml", "env_content", "app_yml_content"}. It returns the problems that would
+// block saving them, warnings, the services that change and a diff of each changed file;
+// nothing is saved.
+func (s *Server) handleAPIAppLint(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName, "docker-compose.yml")); !isValidAppName(appName) || err != nil {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+	var edit composeEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(edit.Compose) == "" {
+		http.Error(w, "compose_yaml is required", http.StatusBadRequest)
+		return
+	}
+
+	report := s.lintComposeEdit(r.Context(), appName, edit)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+func TestAppLint(t *testing.T) {
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "wiki")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	saved := "version: '3.8'\nservices:\n  web:\n    image: nginx:1.25\n"
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(saved), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+	mux := s.appRoutes(s.appAPIRoutes())
+
+	lint := func(edit composeEdit) (int, ComposeLintReport) {
+		body, err := json.Marshal(edit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/apps/wiki/lint", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var report ComposeLintReport
+		_ = json.Unmarshal(w.Body.Bytes(), &report) //nolint:errcheck // Checked through the fields
+		return w.Code, report
+	}
+
+	edited := "version: '3.8'\nservices:\n  web:\n    image: nginx:1.27\n  worker:\n    image: busybox\n    privileged: true\n    volumes:\n      - data:/data\n"
+	code, report := lint(composeEdit{Compose: edited, AppYml: "depends_on: [wiki]\n"})
+	if code != http.StatusOK {
+		t.Fatalf("POST = %d", code)
+	}
+	checks := map[string]bool{}
+	for _, issue := range report.Errors {
+		checks[issue.Check] = true
+	}
+	for _, check := range []string{lintCheckSchema, lintCheckSecurity, lintCheckAppYml} {
+		if !checks[check] {
+			t.Errorf("no %s error in %+v", check, report.Errors)
+		}
+	}
+	if len(report.Changes) != 2 || report.Changes[0].ImageAfter != "nginx:1.27" || report.Changes[1].Kind != yamlutil.ServiceAdded {
+		t.Errorf("changes = %+v", report.Changes)
+	}
+	if !strings.Contains(report.Diffs["docker-compose.yml"], "+    image: nginx:1.27") {
+		t.Errorf("diffs = %v", report.Diffs)
+	}
+	if _, ok := report.Diffs["app.yml"]; !ok {
+		t.Errorf("no diff of the new app.yml in %v", report.Diffs)
+	}
+
+	if code, report := lint(composeEdit{Compose: saved}); code != http.StatusOK || len(report.Changes) != 0 || len(report.Diffs) != 0 {
+		t.Errorf("unchanged edit = %d %+v", code, report)
+	}
+	if code, _ := lint(composeEdit{}); code != http.StatusBadRequest {
+		t.Errorf("empty edit = %d, want 400", code)
+	}
+}
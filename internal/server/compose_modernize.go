@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// ComposeModernizeResponse lists the legacy constructs of a compose file with the
+// rewritten file and a diff to review before applying it
+type ComposeModernizeResponse struct {
+	Findings    []yamlutil.LegacyFinding `json:"findings"`
+	ComposeYAML string                   `json:"compose_yaml"`
+	Diff        string                   `json:"diff"`
+}
+
+// handleAPIComposeModernize handles POST /api/compose/modernize. It checks an unsaved
+// {"compose_yaml"} from the create or edit form for docker-compose v1 constructs and
+// returns the suggested rewrite; nothing is saved.
+func (s *Server) handleAPIComposeModernize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		ComposeYAML string `json:"compose_yaml"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(request.ComposeYAML) == "" {
+		http.Error(w, "compose_yaml is required", http.StatusBadRequest)
+		return
+	}
+
+	rewritten, findings, err := yamlutil.ModernizeCompose([]byte(request.ComposeYAML))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := ComposeModernizeResponse{
+		Findings:    findings,
+		ComposeYAML: string(rewritten),
+		Diff:        yamlutil.LineDiff([]byte(request.ComposeYAML), rewritten),
+	}
+	if response.Findings == nil {
+		response.Findings = []yamlutil.LegacyFinding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleAPIComposeModernize(t *testing.T) {
+	s := &Server{}
+	request := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/compose/modernize", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleAPIComposeModernize(rec, req)
+		return rec
+	}
+
+	if rec := request(http.MethodGet, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d", rec.Code)
+	}
+	if rec := request(http.MethodPost, `{"compose_yaml": "services: [unclosed"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid YAML status = %d", rec.Code)
+	}
+
+	body, _ := json.Marshal(map[string]string{"compose_yaml": "version: \"2\"\nservices:\n  web:\n    image: nginx\n    net: host\n"})
+	rec := request(http.MethodPost, string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var response ComposeModernizeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Findings) != 2 || response.ComposeYAML != "services:\n  web:\n    image: nginx\n    network_mode: host\n" {
+		t.Errorf("response = %+v", response)
+	}
+	if !strings.Contains(response.Diff, "-version: \"2\"\n") || !strings.Contains(response.Diff, "+    network_mode: host\n") {
+		t.Errorf("diff = %s", response.Diff)
+	}
+}
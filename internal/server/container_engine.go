@@ -0,0 +1,105 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/engine"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// containerRuntimeSetting returns the container runtime chosen on the settings page, empty
+// to follow the configuration
+func (s *Server) containerRuntimeSetting() string {
+	if s.db == nil {
+		return ""
+	}
+	var mode sql.NullString
+	err := s.db.QueryRow(`SELECT container_runtime FROM system_setup WHERE id = 1`).Scan(&mode)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logging.Errorf("Failed to read container runtime setting: %v", err)
+	}
+	return mode.String
+}
+
+// containerRuntimeMode returns the container_runtime mode the node starts with. CONTAINER_RUNTIME
+// takes precedence over the settings page, the settings page over the configuration file.
+func (s *Server) containerRuntimeMode() string {
+	mode := s.config.ContainerRuntime
+	if setting := s.containerRuntimeSetting(); setting != "" && os.Getenv("CONTAINER_RUNTIME") == "" {
+		mode = setting
+	}
+	if mode == "" {
+		return engine.ModeAuto
+	}
+	return mode
+}
+
+// detectEngine points the container runtime clients at the engine of the node. It runs
+// before they are created, they read DOCKER_HOST and DOCKER_BINARY once.
+func (s *Server) detectEngine() {
+	mode := s.containerRuntimeMode()
+	e, err := engine.Detect(mode)
+	if err == nil {
+		err = e.Apply()
+	}
+	if err != nil {
+		s.engineErr = err
+		logging.Warnf("Warning: No container engine for container_runtime %s: %v", mode, err)
+		return
+	}
+	s.engine = e
+	logging.Infof("Using %s as container engine", e)
+}
+
+// engineStatus describes the engine of the node for the settings page
+func (s *Server) engineStatus() string {
+	if s.engineErr != nil {
+		return "Not found: " + s.engineErr.Error()
+	}
+	if s.engine.Name == "" {
+		return "Mock runtime"
+	}
+	return s.engine.String()
+}
+
+// saveContainerRuntime handles the update_container_runtime action of the settings page. The
+// engine has to be reachable in the chosen mode, it is used after TreeOS restarts.
+func (s *Server) saveContainerRuntime(w http.ResponseWriter, r *http.Request) {
+	mode := strings.TrimSpace(r.FormValue("container_runtime"))
+	var err error
+	switch {
+	case !engine.ValidMode(mode):
+		err = fmt.Errorf("unknown container runtime %q", mode)
+	default:
+		var e engine.Engine
+		if e, err = engine.Detect(mode); err == nil {
+			if _, err = s.db.Exec(`UPDATE system_setup SET container_runtime = ? WHERE id = 1`, mode); err != nil {
+				logging.Errorf("Failed to update container runtime: %v", err)
+				err = errors.New("failed to save container runtime")
+			} else {
+				logging.Infof("Container runtime set to %s, found %s", mode, e)
+			}
+		}
+	}
+
+	session, sessionErr := s.sessionStore.Get(r, "ontree-session")
+	if sessionErr != nil {
+		logging.Errorf("Failed to get session: %v", sessionErr)
+	} else {
+		if err != nil {
+			session.AddFlash(err.Error(), "error")
+		} else {
+			session.AddFlash("Container runtime saved, it is used after TreeOS restarts", "success")
+		}
+		if saveErr := session.Save(r, w); saveErr != nil {
+			logging.Errorf("Failed to save session: %v", saveErr)
+		}
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusFound)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/engine"
+)
+
+func TestContainerRuntimeMode(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() }) //nolint:errcheck,gosec // Test cleanup
+	db := database.GetDB()
+	if _, err := db.Exec(`INSERT INTO system_setup (id) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONTAINER_RUNTIME", "")
+
+	s := &Server{db: db, config: &config.Config{ContainerRuntime: engine.ModeDocker}}
+	if got := s.containerRuntimeMode(); got != engine.ModeDocker {
+		t.Errorf("mode from the configuration = %q", got)
+	}
+	if _, err := db.Exec(`UPDATE system_setup SET container_runtime = 'podman' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.containerRuntimeMode(); got != engine.ModePodman {
+		t.Errorf("mode from the settings page = %q", got)
+	}
+	// The environment wins, config.Load put it into the configuration
+	t.Setenv("CONTAINER_RUNTIME", engine.ModeDocker)
+	if got := s.containerRuntimeMode(); got != engine.ModeDocker {
+		t.Errorf("mode from the environment = %q", got)
+	}
+
+	// Unknown modes are not saved
+	s.sessionStore = sessions.NewCookieStore([]byte("test-secret"))
+	form := url.Values{"container_runtime": {"lxc"}}
+	req := httptest.NewRequest(http.MethodPost, "/settings", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	s.saveContainerRuntime(w, req)
+	if w.Code != http.StatusFound {
+		t.Errorf("save = %d", w.Code)
+	}
+	if got := s.containerRuntimeSetting(); got != engine.ModePodman {
+		t.Errorf("setting after saving an unknown mode = %q", got)
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+const (
+	// appStatusSSEChannel carries the container changes of all apps
+	appStatusSSEChannel = "app-status"
+	// containerEventsRetryMax bounds the wait before the event stream is opened again
+	containerEventsRetryMax = 30 * time.Second
+	// appStatusConnectedEvent starts the streams of container changes
+	appStatusConnectedEvent = "event: connected\ndata: {\"message\": \"Connected to app status updates\"}\n\n"
+)
+
+// appStatusChannel returns the SSE channel of the container changes of one app
+func appStatusChannel(appName string) string {
+	return "app-status-" + appName
+}
+
+// wantsEventStream reports whether a request asked for server-sent events, as EventSource and
+// the long-polling transport of TreeOSEventSource do
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// startContainerEventWatcher follows the Docker events of app containers and pushes them to
+// the dashboard and the app pages, so they don't have to poll. The stream is opened again
+// after the runtime restarts.
+func (s *Server) startContainerEventWatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stopCh
+		cancel()
+	}()
+
+	delay := time.Second
+	for {
+		started := time.Now()
+		err := s.watchContainerEvents(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			delay = time.Second
+		}
+		// Only the first failure in a row is a warning, the runtime may be down for a while
+		if delay == time.Second {
+			logging.Warnf("Container event stream ended, reconnecting: %v", err)
+		} else {
+			logging.Debugf("Container event stream ended, reconnecting in %s: %v", delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, containerEventsRetryMax)
+	}
+}
+
+// watchContainerEvents publishes container events until ctx is done or the stream fails
+func (s *Server) watchContainerEvents(ctx context.Context) error {
+	client, err := s.getRuntimeClient()
+	if err != nil {
+		return err
+	}
+	err = client.WatchEvents(ctx, s.config.AppsDir, s.publishContainerEvent)
+	if err != nil && isRuntimeUnavailableError(err) {
+		s.markRuntimeUnhealthy()
+	}
+	return err
+}
+
+// publishContainerEvent sends a container change as "app-status" event to the stream of all
+// apps and to the stream of its app
+func (s *Server) publishContainerEvent(event dockerruntime.ContainerEvent) {
+	if s.sseManager == nil {
+		return
+	}
+	for _, channel := range []string{appStatusSSEChannel, appStatusChannel(event.App)} {
+		s.sseManager.BroadcastMessage(channel, map[string]interface{}{
+			"type":      "app-status",
+			"app":       event.App,
+			"service":   event.Service,
+			"container": event.Container,
+			"action":    event.Action,
+			"state":     event.State,
+			"health":    event.Health,
+			"time":      event.Time,
+		})
+	}
+}
+
+// handleAPIAppsEvents streams the container changes of all apps as "app-status" events. Users
+// limited to some apps follow the stream of each app instead.
+func (s *Server) handleAPIAppsEvents(w http.ResponseWriter, r *http.Request) {
+	if _, _, all, err := userAppAccess(getUserFromContext(r.Context())); !all || err != nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	s.serveSSEChannel(w, r, appStatusSSEChannel, appStatusConnectedEvent)
+}
+
+// handleAPIAppStatusEvents streams the container changes of the app of the route as
+// "app-status" events
+func (s *Server) handleAPIAppStatusEvents(w http.ResponseWriter, r *http.Request) {
+	s.serveSSEChannel(w, r, appStatusChannel(r.PathValue("name")), appStatusConnectedEvent)
+}
+
+// serveSSEChannel streams the events of a channel, or answers a poll of the long-polling
+// transport. The stream starts with connectedEvent.
+func (s *Server) serveSSEChannel(w http.ResponseWriter, r *http.Request, channel, connectedEvent string) {
+	if isSSEPoll(r) {
+		s.serveSSEPoll(w, r, channel, func() []string { return []string{connectedEvent} })
+		return
+	}
+	if s.sseManager == nil {
+		http.Error(w, "SSE not available", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	client := &SSEClient{
+		AppID:    channel,
+		Messages: make(chan string, s.sseBufferSize()),
+		Close:    make(chan bool, 1),
+	}
+	s.sseManager.RegisterClient(channel, client)
+	defer s.sseManager.UnregisterClient(channel, client)
+
+	fmt.Fprint(w, connectedEvent) //nolint:errcheck // SSE stream
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case message := <-client.Messages:
+			fmt.Fprint(w, message) //nolint:errcheck // SSE stream
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprintf(w, "event: heartbeat\ndata: ping\n\n") //nolint:errcheck // SSE stream
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-client.Close:
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+func TestPublishContainerEvent(t *testing.T) {
+	s := &Server{sseManager: NewSSEManager()}
+	clients := map[string]*SSEClient{}
+	for _, channel := range []string{appStatusSSEChannel, appStatusChannel("wiki"), appStatusChannel("photos")} {
+		client := &SSEClient{AppID: channel, Messages: make(chan string, 1), Close: make(chan bool, 1)}
+		s.sseManager.RegisterClient(channel, client)
+		clients[channel] = client
+	}
+
+	s.publishContainerEvent(dockerruntime.ContainerEvent{App: "wiki", Service: "web", Action: "die", State: "exited"})
+
+	for _, channel := range []string{appStatusSSEChannel, appStatusChannel("wiki")} {
+		select {
+		case message := <-clients[channel].Messages:
+			if !strings.HasPrefix(message, "event: app-status\n") || !strings.Contains(message, `"state":"exited"`) {
+				t.Errorf("message on %s = %q", channel, message)
+			}
+		default:
+			t.Errorf("no message on %s", channel)
+		}
+	}
+	select {
+	case message := <-clients[appStatusChannel("photos")].Messages:
+		t.Errorf("message of another app: %q", message)
+	default:
+	}
+}
+
+func TestAppsEventsAccess(t *testing.T) {
+	s, alice, _ := newNamespaceTestServer(t)
+
+	// Users limited to some apps can't follow all of them
+	req := httptest.NewRequest(http.MethodGet, "/api/apps", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req = req.WithContext(setUserContext(req.Context(), alice))
+	w := httptest.NewRecorder()
+	s.handleAPIApps(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("all apps stream of a namespace member = %d, want 403", w.Code)
+	}
+}
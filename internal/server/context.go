@@ -7,7 +7,10 @@ import (
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey     contextKey = "user"
+	apiTokenContextKey contextKey = "api_token"
+)
 
 // setUserContext adds user to context
 func setUserContext(ctx context.Context, user *database.User) context.Context {
@@ -26,3 +29,10 @@ func getUserFromContext(ctx context.Context) *database.User {
 	}
 	return user
 }
+
+// isAPITokenRequest reports whether the request was authenticated with a bearer token
+// rather than a session
+func isAPITokenRequest(ctx context.Context) bool {
+	authenticated, _ := ctx.Value(apiTokenContextKey).(bool)
+	return authenticated
+}
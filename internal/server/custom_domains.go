@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/caddy"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// customDomainDNSTimeout limits the DNS lookups of a custom domain check
+const customDomainDNSTimeout = 10 * time.Second
+
+// dnsResolver resolves custom domains, replaced in tests
+var dnsResolver caddy.Resolver = net.DefaultResolver
+
+// checkCustomDomain validates a custom domain for an app and verifies that its DNS points
+// to this node. The app's subdomain of the public base domain is the reference for the
+// node's public address.
+func (s *Server) checkCustomDomain(appName, domain, subdomain string) error {
+	if err := caddy.ValidateDomain(domain); err != nil {
+		return err
+	}
+
+	base := s.config.PublicBaseDomain
+	if base != "" && (domain == base || strings.HasSuffix(domain, "."+base)) {
+		return fmt.Errorf("%s is part of the public base domain, use the subdomain instead", domain)
+	}
+	if owner := s.customDomainOwner(domain); owner != "" && owner != appName {
+		return fmt.Errorf("%s is already used by %s", domain, owner)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), customDomainDNSTimeout)
+	defer cancel()
+	reference := ""
+	if base != "" {
+		reference = subdomain + "." + base
+	}
+	return caddy.CheckDNS(ctx, dnsResolver, domain, caddy.NodeAddresses(ctx, dnsResolver, reference))
+}
+
+// customDomainOwner returns the app that uses a custom domain, or ""
+func (s *Server) customDomainOwner(domain string) string {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(s.config.AppsDir, entry.Name()))
+		if err == nil && metadata.CustomDomain == domain {
+			return entry.Name()
+		}
+	}
+	return ""
+}
+
+// syncCertificatePolicy adds the certificate policy of an app's custom domain, or removes a
+// stale one when the app has none
+func (s *Server) syncCertificatePolicy(appID, customDomain string) error {
+	if customDomain == "" {
+		return s.caddyClient.DeleteRoute(caddy.CertificatePolicyID(appID))
+	}
+	return s.caddyClient.AddOrUpdateCertificatePolicy(appID, customDomain)
+}
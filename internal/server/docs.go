@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/docs"
+	"github.com/ontree-co/treeos/internal/embeds"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// docsSearchLimit caps the number of search results
+const docsSearchLimit = 30
+
+// docsLibrary returns the embedded documentation, rendered on first use
+func (s *Server) docsLibrary() (*docs.Library, error) {
+	s.docsOnce.Do(func() {
+		if s.docsLib != nil {
+			return
+		}
+		fsys, err := embeds.DocsFS()
+		if err == nil {
+			s.docsLib, err = docs.Load(fsys)
+		}
+		if err != nil {
+			logging.Errorf("Failed to load documentation: %v", err)
+			s.docsErr = err
+		}
+	})
+	return s.docsLib, s.docsErr
+}
+
+// handleDocs serves the documentation at /docs/{page}, and search results for /docs?q=
+func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	data := s.baseTemplateData(user)
+
+	lib, err := s.docsLibrary()
+	if err != nil {
+		http.Error(w, "Documentation is not included in this build", http.StatusNotFound)
+		return
+	}
+	data["Sections"] = lib.Sections
+
+	status := http.StatusOK
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	pagePath := strings.Trim(strings.TrimPrefix(r.URL.Path, "/docs"), "/")
+	switch {
+	case query != "":
+		data["Query"] = query
+		data["Results"] = lib.Search(query, docsSearchLimit)
+	case pagePath == "":
+		data["Page"] = lib.Home()
+	default:
+		page := lib.Page(pagePath)
+		if page == nil {
+			status = http.StatusNotFound
+			data["NotFound"] = pagePath
+			break
+		}
+		data["Page"] = page
+	}
+
+	tmpl, ok := s.templates["docs"]
+	if !ok {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		logging.Errorf("Error rendering template: %v", err)
+	}
+}
+
+// handleAPIDocsSearch handles GET /api/docs/search?q=, searching the documentation
+func (s *Server) handleAPIDocsSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lib, err := s.docsLibrary()
+	if err != nil {
+		http.Error(w, "Documentation is not included in this build", http.StatusNotFound)
+		return
+	}
+
+	results := lib.Search(r.URL.Query().Get("q"), docsSearchLimit)
+	if results == nil {
+		results = []docs.Result{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"results": results}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/docs"
+	"github.com/ontree-co/treeos/internal/embeds"
+)
+
+func TestHandleDocs(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	lib, err := docs.Load(fstest.MapFS{
+		"intro.md":                        {Data: []byte("# Welcome\n\nStart here.\n")},
+		"features/security-validation.md": {Data: []byte("# Security Validation\n\n## Bypassing Validation for an App\n\nAdmins can bypass validation.\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := embeds.ParseTemplate(filepath.Join("templates", "layouts", "base.html"), filepath.Join("templates", "dashboard", "docs.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		config:    &config.Config{},
+		db:        database.GetDB(),
+		templates: map[string]*template.Template{"docs": tmpl},
+		docsLib:   lib,
+	}
+	user := &database.User{Username: "viewer"}
+
+	for _, tt := range []struct {
+		url    string
+		status int
+		want   string
+	}{
+		{"/docs", http.StatusOK, "Start here."},
+		{"/docs/features/security-validation", http.StatusOK, `<h2 id="bypassing-validation-for-an-app">`},
+		{"/docs?q=bypass", http.StatusOK, `href="/docs/features/security-validation#bypassing-validation-for-an-app"`},
+		{"/docs/features/missing", http.StatusNotFound, "Page Not Found"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleDocs(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.url, rec.Code, tt.status)
+		}
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: body lacks %q", tt.url, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/docs/search?q=bypass", nil)
+	rec := httptest.NewRecorder()
+	s.handleAPIDocsSearch(rec, req)
+	var resp struct {
+		Results []docs.Result `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Heading != "Bypassing Validation for an App" {
+		t.Errorf("search results = %+v", resp.Results)
+	}
+}
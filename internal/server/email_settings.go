@@ -0,0 +1,190 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
+)
+
+// smtpSecurityOptions are the connection security choices of the settings
+var smtpSecurityOptions = []string{notify.SMTPSecuritySTARTTLS, notify.SMTPSecurityTLS, notify.SMTPSecurityNone}
+
+// emailSettingsView is the Email Notifications card of the settings
+type emailSettingsView struct {
+	Settings        database.EmailSettings // Without the password
+	HasPassword     bool
+	FromEnvironment bool   // The mail server is set by SMTP_HOST and can't be changed here
+	ConfigFileHost  string // Mail server of the configuration file, used while none is set here
+	Enabled         bool
+	Recipients      []string
+	Events          []emailEventView
+}
+
+// emailEventView is an event that can be emailed to admins
+type emailEventView struct {
+	Type        string
+	Description string
+	Selected    bool
+}
+
+// emailSettingsView loads the Email Notifications card
+func (s *Server) emailSettingsView() emailSettingsView {
+	settings, err := database.GetEmailSettings()
+	if err != nil {
+		logging.Errorf("Failed to get email settings: %v", err)
+	}
+	view := emailSettingsView{
+		HasPassword:     settings.Password != "",
+		FromEnvironment: smtpFromEnvironment(),
+		Enabled:         s.smtpConfig().Enabled(),
+	}
+	settings.Password = ""
+	if settings.Security == "" {
+		settings.Security = notify.SMTPSecuritySTARTTLS
+	}
+	view.Settings = settings
+	if settings.Host == "" {
+		view.ConfigFileHost = s.config.SMTPHost
+	}
+	if recipients, err := s.adminEmails(); err == nil {
+		view.Recipients = recipients
+	}
+	for _, event := range notify.Events {
+		view.Events = append(view.Events, emailEventView{
+			Type:        event.Type,
+			Description: event.Description,
+			Selected:    slices.Contains(settings.Events, event.Type),
+		})
+	}
+	return view
+}
+
+// saveEmailSettings saves the Email Notifications card of the settings form. A blank
+// password keeps the saved one. With SMTP_HOST in the environment only the events change.
+func (s *Server) saveEmailSettings(w http.ResponseWriter, r *http.Request) {
+	flash := func(message, kind string) {
+		session, err := s.sessionStore.Get(r, "ontree-session")
+		if err != nil {
+			logging.Errorf("Failed to get session: %v", err)
+			return
+		}
+		session.AddFlash(message, kind)
+		if err := session.Save(r, w); err != nil {
+			logging.Errorf("Failed to save session: %v", err)
+		}
+	}
+	defer http.Redirect(w, r, "/settings", http.StatusFound)
+
+	settings, err := database.GetEmailSettings()
+	if err != nil {
+		logging.Errorf("Failed to get email settings: %v", err)
+		flash("Failed to save email settings", "error")
+		return
+	}
+
+	settings.Events = []string{}
+	for _, event := range r.Form["email_events"] {
+		if notify.IsEvent(event) && !slices.Contains(settings.Events, event) {
+			settings.Events = append(settings.Events, event)
+		}
+	}
+
+	if !smtpFromEnvironment() {
+		changed, err := emailServerFromForm(r, settings)
+		if err != nil {
+			flash(err.Error(), "error")
+			return
+		}
+		settings = changed
+	}
+
+	if err := database.SaveEmailSettings(settings); err != nil {
+		logging.Errorf("Failed to save email settings: %v", err)
+		flash("Failed to save email settings", "error")
+		return
+	}
+	logging.Infof("Email settings changed by %s: server %q, events %v", getUserFromContext(r.Context()).Username, settings.Host, settings.Events)
+	flash("Email settings updated successfully", "success")
+}
+
+// emailServerFromForm returns the settings with the mail server of the settings form
+func emailServerFromForm(r *http.Request, settings database.EmailSettings) (database.EmailSettings, error) {
+	settings.Host = strings.TrimSpace(r.FormValue("smtp_host"))
+	settings.Security = r.FormValue("smtp_security")
+	settings.Username = strings.TrimSpace(r.FormValue("smtp_username"))
+	settings.From = strings.TrimSpace(r.FormValue("smtp_from"))
+	if password := r.FormValue("smtp_password"); password != "" {
+		settings.Password = password
+	}
+	if settings.Username == "" {
+		settings.Password = ""
+	}
+
+	settings.Port = 0
+	if value := strings.TrimSpace(r.FormValue("smtp_port")); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return settings, fmt.Errorf("SMTP port must be between 1 and 65535")
+		}
+		settings.Port = port
+	}
+	if !slices.Contains(smtpSecurityOptions, settings.Security) {
+		return settings, fmt.Errorf("SMTP connection security %q is unknown", settings.Security)
+	}
+	if settings.Host != "" && !strings.Contains(settings.From, "@") {
+		return settings, fmt.Errorf("SMTP sender address is missing")
+	}
+	return settings, nil
+}
+
+// handleAPIEmailTest handles POST /api/system/email/test, which emails a test message
+// to the admin with the saved mail server
+func (s *Server) handleAPIEmailTest(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !user.Email.Valid || user.Email.String == "" {
+		http.Error(w, "Add an email address to your account first", http.StatusBadRequest)
+		return
+	}
+	smtpConfig := s.smtpConfig()
+	if !smtpConfig.Enabled() {
+		http.Error(w, "Save a mail server and sender address first", http.StatusBadRequest)
+		return
+	}
+
+	event := notify.Event{
+		Type:    notify.EventTest,
+		Title:   "Test email",
+		Message: fmt.Sprintf("This is a test email sent by %s from the TreeOS settings.", user.Username),
+		Time:    time.Now(),
+	}
+	event.Node, _ = os.Hostname() //nolint:errcheck // The email is useful without it
+	subject, body, err := notify.RenderEventEmail(event)
+	if err != nil {
+		logging.Errorf("Failed to render test email: %v", err)
+		http.Error(w, "Failed to render test email", http.StatusInternalServerError)
+		return
+	}
+	if err := notify.SendEmail(smtpConfig, []string{user.Email.String}, subject, body); err != nil {
+		logging.Warnf("Test email to %s failed: %v", user.Email.String, err)
+		http.Error(w, fmt.Sprintf("Failed to send the test email: %v", err), http.StatusBadGateway)
+		return
+	}
+	logging.Infof("Test email sent to %s", user.Email.String)
+	w.WriteHeader(http.StatusNoContent)
+}
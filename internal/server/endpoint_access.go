@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+// tailscaleRange is the CGNAT range Tailscale assigns node addresses from
+var tailscaleRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isLANAddress reports whether ip is a loopback, private, link-local or Tailscale address
+func isLANAddress(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || tailscaleRange.Contains(addr)
+}
+
+// hasBearerToken reports whether the request carries one of the non-empty tokens
+func hasBearerToken(r *http.Request, tokens ...string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && tokenMatches(token, tokens...)
+}
+
+// tokenMatches compares token in constant time with the non-empty tokens
+func tokenMatches(token string, tokens ...string) bool {
+	for _, want := range tokens {
+		if want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionUser returns the logged-in user of the request without redirecting or renewing the
+// session, or nil
+func (s *Server) sessionUser(r *http.Request) *database.User {
+	session, err := s.sessionStore.Get(r, "ontree-session")
+	if err != nil {
+		return nil
+	}
+	userID, ok := session.Values["user_id"].(int)
+	if !ok || userID == 0 {
+		return nil
+	}
+	if expired, _ := s.touchSession(session, time.Now()); expired {
+		return nil
+	}
+	user, err := s.getUserByID(userID)
+	if err != nil || !sessionCurrent(session, user) {
+		return nil
+	}
+	return user
+}
+
+// EndpointAccessMiddleware limits an endpoint that needs no login to the configured access
+// level. With config.AccessToken the request needs one of the tokens or a session; handlers
+// that want an admin check the user in the context.
+func (s *Server) EndpointAccessMiddleware(access string, tokens []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch access {
+		case config.AccessPublic:
+		case config.AccessLAN:
+			if !isLANAddress(clientIP(r)) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		default:
+			if !hasBearerToken(r, tokens...) {
+				user := s.sessionUser(r)
+				if user == nil {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				r = r.WithContext(setUserContext(r.Context(), user))
+			}
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestEndpointAccessMiddleware(t *testing.T) {
+	s := &Server{config: &config.Config{}, sessionStore: sessions.NewCookieStore([]byte("test-secret-key-with-32-bytes!!"))}
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		name       string
+		access     string
+		remoteAddr string
+		token      string
+		want       int
+	}{
+		{"public from the internet", config.AccessPublic, "203.0.113.5:4000", "", http.StatusOK},
+		{"lan from a private address", config.AccessLAN, "192.168.1.20:4000", "", http.StatusOK},
+		{"lan from tailscale", config.AccessLAN, "100.101.102.103:4000", "", http.StatusOK},
+		{"lan from the internet", config.AccessLAN, "203.0.113.5:4000", "", http.StatusForbidden},
+		{"token with the right token", config.AccessToken, "203.0.113.5:4000", "secret", http.StatusOK},
+		{"token with a wrong token", config.AccessToken, "203.0.113.5:4000", "wrong", http.StatusUnauthorized},
+		{"token without token or session", config.AccessToken, "192.168.1.20:4000", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/version", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			s.EndpointAccessMiddleware(tt.access, []string{"", "secret"}, ok)(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
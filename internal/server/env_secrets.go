@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/secrets"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// appEnvSecret is a secret of an app's .env. The value is never returned.
+type appEnvSecret struct {
+	Name       string     `json:"name"`
+	Stored     bool       `json:"stored"`     // A value is kept encrypted in the database
+	Referenced bool       `json:"referenced"` // The .env refers to it with a placeholder
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// sealEnvSecrets moves the sensitive values of the .env content of an app into the secrets
+// store, returning the content with placeholders in their place. Without a store the
+// content is kept as it is.
+func (s *Server) sealEnvSecrets(appName, envContent string) (string, error) {
+	if s.secretBox == nil {
+		return envContent, nil
+	}
+	previous, err := os.ReadFile(filepath.Join(s.config.AppsDir, appName, ".env")) //nolint:gosec // Path from trusted app directory
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read .env: %w", err)
+	}
+	sealed, err := s.secretBox.SealEnv(appName, []byte(envContent), previous)
+	if err != nil {
+		return "", err
+	}
+	return string(sealed), nil
+}
+
+// handleAPIAppEnvSecrets handles /api/apps/{appName}/env-secrets:
+// GET lists the secrets of the app's .env, PUT /env-secrets/{name} stores a value and makes
+// the .env refer to it, DELETE /env-secrets/{name} forgets the value. Values are filled in
+// when the app starts and never returned.
+func (s *Server) handleAPIAppEnvSecrets(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.secretBox == nil {
+		http.Error(w, "Secrets are not available on this node", http.StatusServiceUnavailable)
+		return
+	}
+
+	appName, name := r.PathValue("name"), r.PathValue("secret")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	if _, err := os.Stat(filepath.Join(appDir, "docker-compose.yml")); os.IsNotExist(err) {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	if name == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeAppEnvSecrets(w, appName, appDir)
+		return
+	}
+
+	if err := yamlutil.ValidateEnvKey(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		var request struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if request.Value == "" || strings.ContainsAny(request.Value, "\r\n") {
+			http.Error(w, "value is required and must be a single line", http.StatusBadRequest)
+			return
+		}
+		if err := s.secretBox.Set(appName, name, request.Value); err != nil {
+			logging.Errorf("Failed to store .env secret %s of app %s: %v", name, appName, err)
+			http.Error(w, "Failed to store secret", http.StatusInternalServerError)
+			return
+		}
+		if err := referenceEnvSecret(appDir, name); err != nil {
+			logging.Errorf("Failed to refer to secret %s in the .env of app %s: %v", name, appName, err)
+			http.Error(w, "Failed to update .env", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s set .env secret %s of app %s", user.Username, name, appName)
+	case http.MethodDelete:
+		if err := database.DeleteAppEnvSecret(appName, name); err != nil {
+			logging.Errorf("Failed to delete .env secret %s of app %s: %v", name, appName, err)
+			http.Error(w, "Failed to delete secret", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s deleted .env secret %s of app %s", user.Username, name, appName)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeAppEnvSecrets(w, appName, appDir)
+}
+
+// referenceEnvSecret makes the .env of an app take a variable from the secrets store,
+// replacing a plain value or adding the variable
+func referenceEnvSecret(appDir, name string) error {
+	envPath := filepath.Join(appDir, ".env")
+	content, err := os.ReadFile(envPath) //nolint:gosec // Path from trusted app directory
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	updated, _ := secrets.Extract(content, func(key string) bool { return key == name })
+	if !slices.Contains(secrets.Names(updated), name) {
+		if len(updated) > 0 && !strings.HasSuffix(string(updated), "\n") {
+			updated = append(updated, '\n')
+		}
+		updated = append(updated, name+"="+secrets.Placeholder(name)+"\n"...)
+	}
+	if string(updated) == string(content) {
+		return nil
+	}
+	return os.WriteFile(envPath, updated, 0600)
+}
+
+// writeAppEnvSecrets answers with the stored and referenced .env secrets of an app
+func (s *Server) writeAppEnvSecrets(w http.ResponseWriter, appName, appDir string) {
+	updates, err := s.secretBox.Updates(appName)
+	if err != nil {
+		logging.Errorf("Failed to load .env secrets of app %s: %v", appName, err)
+		http.Error(w, "Failed to load secrets", http.StatusInternalServerError)
+		return
+	}
+	envContent, err := os.ReadFile(filepath.Join(appDir, ".env")) //nolint:gosec // Path from trusted app directory
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logging.Errorf("Failed to read .env of app %s: %v", appName, err)
+	}
+
+	byName := map[string]*appEnvSecret{}
+	for name, updatedAt := range updates {
+		byName[name] = &appEnvSecret{Name: name, Stored: true, UpdatedAt: &updatedAt}
+	}
+	for _, name := range secrets.Names(envContent) {
+		if byName[name] == nil {
+			byName[name] = &appEnvSecret{Name: name}
+		}
+		byName[name].Referenced = true
+	}
+	list := make([]appEnvSecret, 0, len(byName))
+	for _, secret := range byName {
+		list = append(list, *secret)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"app": appName, "secrets": list}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
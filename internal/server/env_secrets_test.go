@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/secrets"
+)
+
+func TestHandleAPIAppEnvSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	appDir := filepath.Join(tmpDir, "apps", "wiki")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx:1.27\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	envPath := filepath.Join(appDir, ".env")
+	if err := os.WriteFile(envPath, []byte("PORT=8080\nSMTP_URL=smtp://user:pw@mail\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	box, err := secrets.Load(filepath.Join(tmpDir, secrets.KeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: filepath.Join(tmpDir, "apps")}, secretBox: box}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	request := func(method, path, body string, user *database.User) *httptest.ResponseRecorder {
+		req := newAppRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		s.handleAPIAppEnvSecrets(w, req)
+		return w
+	}
+
+	for _, tt := range []struct {
+		name, method, path, body string
+		user                     *database.User
+		wantStatus               int
+	}{
+		{"not staff", http.MethodGet, "/api/apps/wiki/env-secrets", "", &database.User{Username: "user"}, http.StatusUnauthorized},
+		{"unknown app", http.MethodGet, "/api/apps/missing/env-secrets", "", staff, http.StatusNotFound},
+		{"invalid name", http.MethodPut, "/api/apps/wiki/env-secrets/1X", `{"value": "x"}`, staff, http.StatusBadRequest},
+		{"multi-line value", http.MethodPut, "/api/apps/wiki/env-secrets/SMTP_URL", `{"value": "a\nb"}`, staff, http.StatusBadRequest},
+	} {
+		if w := request(tt.method, tt.path, tt.body, tt.user); w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+		}
+	}
+
+	// Storing a value replaces the plain one in the .env, adding a variable appends it
+	for _, name := range []string{"SMTP_URL", "LICENSE"} {
+		if w := request(http.MethodPut, "/api/apps/wiki/env-secrets/"+name, `{"value": "v-`+name+`"}`, staff); w.Code != http.StatusOK {
+			t.Fatalf("PUT %s status = %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+	env, _ := os.ReadFile(envPath)
+	if want := "PORT=8080\nSMTP_URL={{ secret SMTP_URL }}\nLICENSE={{ secret LICENSE }}\n"; string(env) != want {
+		t.Errorf(".env = %q, want %q", env, want)
+	}
+
+	if w := request(http.MethodDelete, "/api/apps/wiki/env-secrets/LICENSE", "", staff); w.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d", w.Code)
+	}
+	w := request(http.MethodGet, "/api/apps/wiki/env-secrets", "", staff)
+	if strings.Contains(w.Body.String(), "v-SMTP_URL") {
+		t.Errorf("GET returned a value: %s", w.Body.String())
+	}
+	var response struct {
+		Secrets []appEnvSecret `json:"secrets"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Secrets) != 2 ||
+		response.Secrets[0].Name != "LICENSE" || response.Secrets[0].Stored || !response.Secrets[0].Referenced ||
+		response.Secrets[1].Name != "SMTP_URL" || !response.Secrets[1].Stored || !response.Secrets[1].Referenced {
+		t.Errorf("GET secrets = %+v", response.Secrets)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ontree-co/treeos/internal/gpu"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// gpuOption is a GPU vendor of the host offered in the app creation form
+type gpuOption struct {
+	Vendor  string
+	Name    string // Vendor name, e.g. NVIDIA
+	GPUs    string // The GPUs of the vendor
+	Error   string // Why its GPUs can't be passed to containers, empty if they can
+	Checked bool
+}
+
+// gpuOptions returns a checkbox for each GPU vendor of the host
+func gpuOptions(host gpu.Host, checked []string) []gpuOption {
+	var options []gpuOption
+	for _, vendor := range host.Vendors() {
+		option := gpuOption{Vendor: vendor, Name: gpu.VendorName(vendor)}
+		var names []string
+		for _, g := range host.GPUsOf(vendor) {
+			names = append(names, g.Name())
+		}
+		option.GPUs = strings.Join(names, ", ")
+		if err := host.Check(vendor); err != nil {
+			option.Error = err.Error()
+		}
+		for _, v := range checked {
+			option.Checked = option.Checked || v == vendor
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// addGPUs gives the main service of a compose file access to the GPUs of vendors
+func addGPUs(host gpu.Host, composeContent string, vendors []string) (string, error) {
+	if len(vendors) == 0 {
+		return composeContent, nil
+	}
+	var compose yamlutil.ComposeFile
+	if err := yaml.Unmarshal([]byte(composeContent), &compose); err != nil {
+		return "", fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	service := yamlutil.GetMainServiceName(&compose)
+	if service == "" {
+		return "", fmt.Errorf("compose file has no service to pass the GPU to")
+	}
+	content := []byte(composeContent)
+	for _, vendor := range vendors {
+		var err error
+		if content, err = host.Inject(content, service, vendor); err != nil {
+			return "", fmt.Errorf("can't pass the %s GPU to service %s: %w", gpu.VendorName(vendor), service, err)
+		}
+	}
+	return string(content), nil
+}
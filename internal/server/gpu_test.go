@@ -0,0 +1,35 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/gpu"
+)
+
+func TestAddGPUs(t *testing.T) {
+	host := gpu.Host{
+		GPUs:      []gpu.GPU{{Vendor: gpu.VendorIntel, Card: "card0", RenderNode: "/dev/dri/renderD128"}, {Vendor: gpu.VendorNVIDIA, Card: "card1"}},
+		RenderGID: -1,
+	}
+	compose := "services:\n  db:\n    image: postgres:16\n  web:\n    image: jellyfin/jellyfin\n"
+
+	got, err := addGPUs(host, compose, []string{gpu.VendorIntel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "  web:\n    devices:\n      - /dev/dri:/dev/dri\n    image: jellyfin/jellyfin\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("addGPUs() =\n%s", got)
+	}
+	if got, err := addGPUs(host, compose, nil); err != nil || got != compose {
+		t.Errorf("addGPUs() without vendors = %v\n%s", err, got)
+	}
+	if _, err := addGPUs(host, compose, []string{gpu.VendorNVIDIA}); err == nil || !strings.Contains(err.Error(), "service web") {
+		t.Errorf("addGPUs() without the NVIDIA toolkit = %v", err)
+	}
+
+	options := gpuOptions(host, []string{gpu.VendorIntel})
+	if len(options) != 2 || !options[0].Checked || options[0].Error != "" || options[1].Checked || options[1].Error == "" {
+		t.Errorf("gpuOptions() = %+v", options)
+	}
+}
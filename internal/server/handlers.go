@@ -13,13 +13,20 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/ontree-co/treeos/internal/caddy"
 	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/maintenance"
+	"github.com/ontree-co/treeos/internal/notify"
 	"github.com/ontree-co/treeos/internal/ollama"
 	containerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/secrets"
+	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/storage"
 	"github.com/ontree-co/treeos/internal/yamlutil"
 	"github.com/ontree-co/treeos/pkg/compose"
 
@@ -48,6 +55,16 @@ type appDetailView struct {
 	PublicAccess   publicAccessView
 	Tailscale      tailscaleView
 	Security       securityView
+	Quota          quotaView
+	Namespace      namespaceView
+	Rebuild        rebuildView
+	Autostart      bool                   // Started when TreeOS starts
+	DependsOn      []string               // Apps from depends_on in app.yml
+	RequiredBy     []string               // Apps depending on this app
+	ImageUpdates   []database.ImageUpdate // Last registry check of the app's images
+	Upgrades       []database.AppUpgrade  // Recent upgrades, newest first
+	Resources      []appResourceView      // CPU, memory and network usage per service
+	AgentEnabled   bool                   // Whether an LLM is configured for the app chat
 	Actions        actionsView
 	Warnings       []string
 }
@@ -60,7 +77,9 @@ type serviceView struct {
 	StatusLabel   string
 	StatusClass   string
 	State         string
+	Health        string // Result of the container's health check, "" without one
 	Ports         []string
+	CPUSet        string
 }
 
 func (s *Server) getAppDetailsForRequest(w http.ResponseWriter, r *http.Request, appName string) (*containerruntime.App, bool) {
@@ -86,18 +105,22 @@ type metadataView struct {
 	HostPort          int
 	IsExposed         bool
 	PublicURL         string
+	CustomDomain      string
+	CustomURL         string
 	TailscaleExposed  bool
 	TailscaleHostname string
 	TailscaleURL      string
 }
 
 type publicAccessView struct {
-	FormEnabled bool
-	Exposed     bool
-	Subdomain   string
-	PublicURL   string
-	BaseDomain  string
-	Alert       *alertView
+	FormEnabled  bool
+	Exposed      bool
+	Subdomain    string
+	PublicURL    string
+	BaseDomain   string
+	CustomDomain string
+	CustomURL    string
+	Alert        *alertView
 }
 
 type tailscaleView struct {
@@ -109,12 +132,40 @@ type tailscaleView struct {
 }
 
 type securityView struct {
-	BypassEnabled bool
+	BypassEnabled    bool
+	CanChange        bool                           // Only administrators may toggle the bypass
+	LastChange       *database.SecurityAuditEvent   // Most recent audit log entry, nil if never changed
+	Report           *security.Report               // Escape surface of the compose file, nil if unreadable
+	Confinement      []compose.ContainerConfinement // AppArmor profile and SELinux label of each container
+	ConfineMode      string                         // Configured confinement, empty when off
+	ReadOnlyMode     string                         // Per-app read_only_root setting, empty follows the default
+	ReadOnly         bool                           // Whether compatible services run with a read-only root
+	ReadOnlyServices []string                       // Services the template marks compatible
+	CanHarden        bool                           // Staff may change the read-only root setting
+}
+
+type quotaView struct {
+	Limit string
+	Usage *AppQuotaUsage
+}
+
+type namespaceView struct {
+	Name      string
+	Options   []string // Namespaces the app can be moved to
+	CanChange bool
+}
+
+type rebuildView struct {
+	Services []compose.BuildService // Services built from source, the card is hidden without
+	Schedule string
+	NextRun  *time.Time
+	History  []database.AppRebuild
 }
 
 type actionsView struct {
 	CanStart bool
 	CanStop  bool
+	CanApply bool // Saved edits the running containers don't use yet
 }
 
 type alertView struct {
@@ -136,8 +187,10 @@ func statusBadgeClass(status string) string {
 	switch status {
 	case "running":
 		return "bg-success"
-	case "partial":
+	case "partial", "degraded":
 		return "bg-warning"
+	case "starting":
+		return "bg-info"
 	case "stopped", "exited", "not_created":
 		return "bg-secondary"
 	case "error":
@@ -360,7 +413,9 @@ func (s *Server) handleSetupSystemCheck(w http.ResponseWriter, r *http.Request)
 		}
 
 		// Log the user in
-		session.Values["user_id"] = user.ID
+		if err := s.startSession(session, user, false); err != nil {
+			logging.Errorf("Failed to start session: %v", err)
+		}
 		// Clear setup data from session
 		delete(session.Values, "setup_username")
 		delete(session.Values, "setup_password")
@@ -416,6 +471,11 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 		// Authenticate user
 		user, err := s.authenticateUser(username, password)
+		if err == nil && !s.clientCertSatisfied(r, user) {
+			// Fails like a wrong password, so the password can't be tested without the certificate
+			logging.Warnf("Login of %s rejected, no client certificate presented", username)
+			err = errClientCertRequired
+		}
 		if err != nil {
 			// Render with error
 			data := s.baseTemplateData(nil) // nil for user since not logged in
@@ -432,12 +492,18 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Set session
-		session.Values["user_id"] = user.ID
+		remember := r.FormValue("remember") == "on"
+		if err := s.startSession(session, user, remember); err != nil {
+			logging.Errorf("Failed to start session of %s: %v", username, err)
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+			return
+		}
 		if err := session.Save(r, w); err != nil {
 			logging.Errorf("Failed to save session: %v", err)
 		}
 
 		logging.Infof("User %s logged in successfully with user_id=%d", username, user.ID)
+		s.recordLogin(r, user)
 
 		// Redirect to next URL or dashboard
 		next := session.Values["next"]
@@ -489,7 +555,7 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clear session
-	session.Values["user_id"] = nil
+	clearSession(session)
 	session.Options.MaxAge = -1
 	if err := session.Save(r, w); err != nil {
 		logging.Errorf("Failed to save session: %v", err)
@@ -503,13 +569,7 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 // handleAppDetail handles the application detail page
 func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 	// Extract app name from URL path
-	path := r.URL.Path
-	if !strings.HasPrefix(path, "/apps/") {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := strings.TrimPrefix(path, "/apps/")
+	appName := r.PathValue("name")
 	if appName == "" {
 		http.NotFound(w, r)
 		return
@@ -528,6 +588,7 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 	// Read docker-compose.yml content
 	composePath := filepath.Join(app.Path, "docker-compose.yml")
 	composeContent, err := os.ReadFile(composePath) //nolint:gosec // Path from trusted app directory
+	composeRead := err == nil
 	if err != nil {
 		logging.Errorf("Failed to read docker-compose.yml: %v", err)
 		composeContent = []byte("Failed to read docker-compose.yml")
@@ -571,6 +632,7 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 				App:      appName,
 				Services: []ServiceStatusDetail{},
 			}
+			cpusets := s.appCPUSets(appName)
 
 			// Process containers to get service information
 			for _, container := range containers {
@@ -587,6 +649,8 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 					Image:         container.Image,
 					Status:        strings.ToLower(container.State),
 					State:         container.Status,
+					Health:        containerHealth(container.Health),
+					CPUSet:        cpusets[container.Service],
 				}
 
 				// Add port information
@@ -628,6 +692,7 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 			} else {
 				appStatus.Status = "not_created"
 			}
+			appStatus.Status = healthStatus(appStatus.Status, appStatus.Services)
 
 			// Override app status with multi-service status
 			app.Status = appStatus.Status
@@ -674,7 +739,7 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 		StatusLabel:    capitalizeFirst(app.Status),
 		StatusClass:    statusBadgeClass(app.Status),
 		ComposeContent: string(composeContent),
-		EnvContent:     string(envContent),
+		EnvContent:     string(secrets.Mask(envContent)),
 		AppYmlContent:  string(appYmlContent),
 		ComposePath:    composePath,
 		EnvPath:        envPath,
@@ -682,7 +747,34 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 		AppPath:        app.Path,
 		TailscaleDNS:   strings.TrimSuffix(getTailscaleDNS(), "."),
 		RequestHost:    requestHost,
-		Security:       securityView{BypassEnabled: app.BypassSecurity},
+		Security: securityView{
+			BypassEnabled: app.BypassSecurity,
+			CanChange:     user != nil && user.IsSuperuser,
+		},
+	}
+	if events, err := database.ListSecurityAudit(appName, 1); err != nil {
+		logging.Errorf("Failed to load security audit log for app %s: %v", appName, err)
+	} else if len(events) > 0 {
+		view.Security.LastChange = &events[0]
+	}
+	view.Security.ConfineMode = s.config.Confinement
+	if appStatus != nil && len(appStatus.Services) > 0 {
+		view.Security.Confinement = s.appConfinement(r.Context(), app.Path)
+	}
+	view.Security.CanHarden = user != nil && user.IsStaff
+	if composeRead {
+		if services, err := compose.ReadOnlyServices(composeContent); err == nil {
+			view.Security.ReadOnlyServices = services
+		}
+		if hasMetadata && metadata != nil {
+			view.Security.ReadOnlyMode = metadata.ReadOnlyRoot
+		}
+		view.Security.ReadOnly = compose.ReadOnlyRootEnabled(view.Security.ReadOnlyMode, s.config.ReadOnlyRoot)
+		if report, err := security.NewValidator(appName).Report(composeContent); err != nil {
+			logging.Warnf("Failed to build security report for app %s: %v", appName, err)
+		} else {
+			view.Security.Report = report
+		}
 	}
 
 	if view.Emoji == "" && hasMetadata && metadata != nil && metadata.Emoji != "" {
@@ -701,7 +793,9 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 				StatusLabel:   capitalizeFirst(svc.Status),
 				StatusClass:   statusBadgeClass(svc.Status),
 				State:         svc.State,
+				Health:        svc.Health,
 				Ports:         svc.Ports,
+				CPUSet:        svc.CPUSet,
 			}
 			view.Services = append(view.Services, service)
 			if svc.Name != "" {
@@ -715,8 +809,9 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 	// Determine available actions
 	actions := actionsView{}
 	switch app.Status {
-	case "running", "partial":
+	case "running", "partial", "degraded", "starting":
 		actions.CanStop = true
+		_, actions.CanApply = s.pendingEdits.get(app.Name)
 	default:
 		actions.CanStart = true
 	}
@@ -737,6 +832,10 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 		if metadata.IsExposed && metadata.Subdomain != "" && s.config.PublicBaseDomain != "" {
 			metadataSummary.PublicURL = fmt.Sprintf("https://%s.%s", metadata.Subdomain, s.config.PublicBaseDomain)
 		}
+		metadataSummary.CustomDomain = metadata.CustomDomain
+		if metadata.IsExposed && metadata.CustomDomain != "" {
+			metadataSummary.CustomURL = fmt.Sprintf("https://%s", metadata.CustomDomain)
+		}
 	}
 	view.Metadata = metadataSummary
 
@@ -746,17 +845,22 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 		defaultSubdomain = strings.ToLower(app.Name)
 	}
 	publicAccess := publicAccessView{
-		FormEnabled: runtime.GOOS == "linux" && s.caddyClient != nil && s.config.PublicBaseDomain != "",
-		Exposed:     metadataSummary.IsExposed,
-		Subdomain:   defaultSubdomain,
-		PublicURL:   metadataSummary.PublicURL,
-		BaseDomain:  s.config.PublicBaseDomain,
+		FormEnabled:  s.platformSupportsCaddy && s.caddyClient != nil && s.config.PublicBaseDomain != "",
+		Exposed:      metadataSummary.IsExposed,
+		Subdomain:    defaultSubdomain,
+		PublicURL:    metadataSummary.PublicURL,
+		BaseDomain:   s.config.PublicBaseDomain,
+		CustomDomain: metadataSummary.CustomDomain,
+		CustomURL:    metadataSummary.CustomURL,
 	}
 	if !publicAccess.FormEnabled {
 		var alertMsg, alertType string
 		switch {
-		case runtime.GOOS != "linux":
-			alertMsg = "Domain exposure is only available on Linux servers."
+		case !s.platformSupportsCaddy && runtime.GOOS == "darwin":
+			alertMsg = "Domain exposure on macOS needs Caddy from Homebrew: brew install caddy && brew services start caddy"
+			alertType = "info"
+		case !s.platformSupportsCaddy:
+			alertMsg = "Domain exposure is only available on Linux and macOS servers."
 			alertType = "info"
 		case s.caddyClient == nil:
 			alertMsg = "Caddy service not available. Configure Caddy to enable public exposure."
@@ -783,12 +887,92 @@ func (s *Server) handleAppDetail(w http.ResponseWriter, r *http.Request) {
 	}
 	view.Tailscale = tailscale
 
+	// Disk quota of the mnt directory
+	view.Quota.Usage = s.appQuotaUsage(app.Name)
+	if hasMetadata && metadata != nil {
+		view.Quota.Limit = metadata.DiskQuota
+	}
+
+	// Apps this app depends on and apps depending on it
+	dependencyGraph := s.dependencyGraphOrEmpty()
+	view.DependsOn = dependencyGraph[app.Name]
+	view.RequiredBy = dependentsOf(dependencyGraph, app.Name)
+
+	// Namespace, staff can move the app to another one
+	if hasMetadata && metadata != nil {
+		view.Namespace.Name = metadata.Namespace
+		view.Autostart = metadata.Autostart
+	}
+	if user != nil && user.IsStaff {
+		namespaces, err := database.GetNamespaces()
+		if err != nil {
+			logging.Errorf("Failed to get namespaces: %v", err)
+		}
+		for _, ns := range namespaces {
+			view.Namespace.Options = append(view.Namespace.Options, ns.Name)
+		}
+		view.Namespace.CanChange = len(namespaces) > 0 || view.Namespace.Name != ""
+	}
+
+	// Scheduled rebuilds of apps built from source, managed by staff
+	if user != nil && user.IsStaff {
+		if services, err := s.appBuildServices(app.Name); err == nil && len(services) > 0 {
+			view.Rebuild.Services = services
+			view.Rebuild.Schedule = s.appRebuildSchedule(app.Name)
+			view.Rebuild.NextRun = s.nextAppRebuild(app.Name, time.Now())
+			history, err := database.GetAppRebuilds(app.Name, rebuildHistoryLimit)
+			if err != nil {
+				logging.Errorf("Failed to get rebuilds of app %s: %v", app.Name, err)
+			}
+			view.Rebuild.History = history
+		}
+	}
+
+	// Newer images in the registries, staff can pull them and recreate the app
+	if user != nil && user.IsStaff {
+		updates, err := database.GetImageUpdates(app.Name)
+		if err != nil {
+			logging.Errorf("Failed to get image updates of app %s: %v", app.Name, err)
+		}
+		view.ImageUpdates = updates
+
+		upgrades, err := database.GetAppUpgrades(app.Name, upgradeHistoryLimit)
+		if err != nil {
+			logging.Errorf("Failed to get upgrades of app %s: %v", app.Name, err)
+		}
+		view.Upgrades = upgrades
+	}
+
+	// Resource usage of the last 24 hours
+	if database.GetDB() != nil {
+		logs, err := database.GetAppResourceLogs(app.Name, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			logging.Errorf("Failed to get resource usage of app %s: %v", app.Name, err)
+		}
+		view.Resources = appResourceViews(logs)
+	}
+
+	_, view.AgentEnabled = s.agentLLM()
+
+	// Warn when a media-heavy app keeps its data on the system disk
+	if hasMetadata && metadata != nil {
+		if class, err := storage.ParseClass(metadata.StorageClass); err == nil {
+			if warning := storage.PlacementWarning(class, filepath.Join(app.Path, "mnt")); warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+
+	// Warn about kernel settings the app needs before it fails with obscure errors
+	warnings = append(warnings, tuningWarnings(app)...)
+
 	// Attach warnings collected during processing
 	view.Warnings = warnings
 
 	// Prepare template data
 	data := s.baseTemplateData(user)
 	data["View"] = view
+	data["MaintenanceWindow"] = s.maintenanceWindow().String()
 	data["Messages"] = messages
 	data["CSRFToken"] = ""
 
@@ -820,14 +1004,7 @@ func (s *Server) handleAppComposeEdit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 || parts[1] != "apps" || parts[3] != "edit" {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := parts[2]
+	appName := r.PathValue("name")
 	user := getUserFromContext(r.Context())
 
 	appDetails, ok := s.getAppDetailsForRequest(w, r, appName)
@@ -864,7 +1041,7 @@ func (s *Server) handleAppComposeEdit(w http.ResponseWriter, r *http.Request) {
 	data["App"] = appDetails
 	data["Content"] = string(composeContent) // Keep for backward compatibility
 	data["ComposeContent"] = string(composeContent)
-	data["EnvContent"] = string(envContent)
+	data["EnvContent"] = string(secrets.Mask(envContent)) // Saving placeholders back keeps the stored values
 	data["AppYmlContent"] = string(appYmlContent)
 
 	// Render the template
@@ -884,14 +1061,7 @@ func (s *Server) handleAppComposeUpdate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Extract app name from URL
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 || parts[1] != "apps" || parts[3] != "edit" {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := parts[2]
+	appName := r.PathValue("name")
 	user := getUserFromContext(r.Context())
 
 	// Parse form
@@ -945,7 +1115,11 @@ func (s *Server) handleAppComposeUpdate(w http.ResponseWriter, r *http.Request)
 	// Validate app.yml YAML syntax if provided
 	if appYmlContent != "" {
 		var appYml map[string]interface{}
-		if err := yaml.Unmarshal([]byte(appYmlContent), &appYml); err != nil {
+		err := yaml.Unmarshal([]byte(appYmlContent), &appYml)
+		if err == nil {
+			_, err = parseAppDependencies(appName, []byte(appYmlContent))
+		}
+		if err != nil {
 			// Show error in edit form
 			appDetails, detailErr := s.getAppDetails(appName)
 			if detailErr != nil {
@@ -978,6 +1152,40 @@ func (s *Server) handleAppComposeUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Security rules, references and host ports would only fail when the app starts
+	if report := s.lintComposeEdit(r.Context(), appName, composeEdit{Compose: composeContent, Env: envContent, AppYml: appYmlContent}); len(report.Errors) > 0 {
+		data := s.baseTemplateData(user)
+		data["App"] = appDetails
+		data["ComposeContent"] = composeContent
+		data["EnvContent"] = envContent
+		data["AppYmlContent"] = appYmlContent
+		data["Error"] = fmt.Sprintf("The configuration can't be saved: %s", report.errorSummary())
+
+		tmpl := s.templates["app_compose_edit"]
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+			logging.Errorf("Failed to render template: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Sensitive values of the .env go to the secrets store
+	envContent, err := s.sealEnvSecrets(appName, envContent)
+	if err != nil {
+		logging.Errorf("Failed to store secrets of app %s: %v", appName, err)
+		http.Error(w, "Failed to store secrets", http.StatusInternalServerError)
+		return
+	}
+
+	// The files a running app uses are kept, applying the edit recreates what it changes
+	containerRunning := false
+	switch appDetails.Status {
+	case "running", "partial", "degraded", "starting":
+		containerRunning = true
+		s.rememberRunningFiles(appName)
+	}
+
 	// Write docker-compose.yml
 	composePath := filepath.Join(appDetails.Path, "docker-compose.yml")
 	// Use 0644 for docker-compose.yml files as they need to be readable by docker daemon
@@ -1003,18 +1211,27 @@ func (s *Server) handleAppComposeUpdate(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// Check if container is running
-	containerRunning := appDetails.Status == "running"
-
 	// Get session for flash message
 	session, err := s.sessionStore.Get(r, "ontree-session")
 	if err != nil {
 		logging.Errorf("Failed to get session: %v", err)
 	}
 
-	if containerRunning {
-		session.AddFlash("Configuration saved. Please restart the container to apply changes.", "warning")
-	} else {
+	switch {
+	case containerRunning && r.FormValue("apply") == "true":
+		composeSvc, err := s.getComposeService()
+		if err == nil {
+			_, err = s.applyAppChanges(composeSvc, appName)
+		}
+		if err != nil {
+			logging.Errorf("Failed to apply changes to app %s: %v", appName, err)
+			session.AddFlash(fmt.Sprintf("Configuration saved, but the changes could not be applied: %v", err), "error")
+		} else {
+			session.AddFlash("Configuration saved. Applying the changes...", "success")
+		}
+	case containerRunning:
+		session.AddFlash("Configuration saved. Apply the changes to recreate the services they affect.", "warning")
+	default:
 		session.AddFlash("Configuration saved successfully.", "success")
 	}
 
@@ -1034,14 +1251,7 @@ func (s *Server) handleAppExpose(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 || parts[1] != "apps" || parts[3] != "expose" {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := parts[2]
+	appName := r.PathValue("name")
 
 	// Check if Caddy is available
 	if !s.caddyAvailable || s.caddyClient == nil {
@@ -1090,9 +1300,18 @@ func (s *Server) handleAppExpose(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get subdomain from form
-	subdomain := r.FormValue("subdomain")
-	if subdomain == "" {
-		subdomain = appName // Default to app name
+	subdomain, err := s.namespaceSubdomain(metadata.Namespace, appName, r.FormValue("subdomain"))
+	if err != nil {
+		session, sessErr := s.sessionStore.Get(r, "ontree-session")
+		if sessErr != nil {
+			logging.Errorf("Failed to get session: %v", sessErr)
+		}
+		session.AddFlash(fmt.Sprintf("Cannot expose app: %v", err), "error")
+		if err := session.Save(r, w); err != nil {
+			logging.Errorf("Failed to save session: %v", err)
+		}
+		http.Redirect(w, r, fmt.Sprintf("/apps/%s", appName), http.StatusFound)
+		return
 	}
 
 	// Update metadata with subdomain from form
@@ -1138,12 +1357,30 @@ func (s *Server) handleAppExpose(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Optional custom domain, which has to point to this node before Caddy requests a certificate
+	metadata.CustomDomain = strings.ToLower(strings.TrimSpace(r.FormValue("custom_domain")))
+	if metadata.CustomDomain != "" {
+		if err := s.checkCustomDomain(appName, metadata.CustomDomain, metadata.Subdomain); err != nil {
+			logging.Warnf("[Expose] Custom domain %s rejected for app %s: %v", metadata.CustomDomain, appName, err)
+			session, sessErr := s.sessionStore.Get(r, "ontree-session")
+			if sessErr != nil {
+				logging.Errorf("Failed to get session: %v", sessErr)
+			}
+			session.AddFlash(fmt.Sprintf("Cannot use custom domain: %v", err), "error")
+			if err := session.Save(r, w); err != nil {
+				logging.Errorf("Failed to save session: %v", err)
+			}
+			http.Redirect(w, r, fmt.Sprintf("/apps/%s", appName), http.StatusFound)
+			return
+		}
+	}
+
 	// Use lowercase app name as ID for route
 	appID := strings.ToLower(appName)
 	logging.Infof("[Expose] Exposing app %s with subdomain %s on port %d", appName, metadata.Subdomain, metadata.HostPort)
 
 	// Create route config (only for public domain, Tailscale handled separately)
-	routeConfig := caddy.CreateRouteConfig(appID, metadata.Subdomain, metadata.HostPort, s.config.PublicBaseDomain, "")
+	routeConfig := caddy.CreateRouteConfig(appID, metadata.Subdomain, metadata.HostPort, s.config.PublicBaseDomain, "", metadata.CustomDomain)
 
 	// Add route to Caddy
 	logging.Infof("[Expose] Sending route config to Caddy for app %s", appName)
@@ -1162,6 +1399,21 @@ func (s *Server) handleAppExpose(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.syncCertificatePolicy(appID, metadata.CustomDomain); err != nil {
+		logging.Errorf("[Expose] Failed to add certificate policy to Caddy: %v", err)
+		_ = s.caddyClient.DeleteRoute(fmt.Sprintf("route-for-%s", appID))
+		session, sessErr := s.sessionStore.Get(r, "ontree-session")
+		if sessErr != nil {
+			logging.Errorf("Failed to get session: %v", sessErr)
+		}
+		session.AddFlash(fmt.Sprintf("Failed to expose app: %v", err), "error")
+		if err := session.Save(r, w); err != nil {
+			logging.Errorf("Failed to save session: %v", err)
+		}
+		http.Redirect(w, r, fmt.Sprintf("/apps/%s", appName), http.StatusFound)
+		return
+	}
+
 	// Update compose file metadata
 	metadata.IsExposed = true
 	err = yamlutil.UpdateComposeMetadata(appDetails.Path, metadata)
@@ -1169,6 +1421,7 @@ func (s *Server) handleAppExpose(w http.ResponseWriter, r *http.Request) {
 		logging.Errorf("Failed to update compose metadata: %v", err)
 		// Try to rollback Caddy change
 		_ = s.caddyClient.DeleteRoute(fmt.Sprintf("route-for-%s", appID))
+		_ = s.caddyClient.DeleteRoute(caddy.CertificatePolicyID(appID))
 		session, err := s.sessionStore.Get(r, "ontree-session")
 		if err != nil {
 			logging.Errorf("Failed to get session: %v", err)
@@ -1188,6 +1441,9 @@ func (s *Server) handleAppExpose(w http.ResponseWriter, r *http.Request) {
 	}
 
 	publicURL := fmt.Sprintf("https://%s.%s", metadata.Subdomain, s.config.PublicBaseDomain)
+	if metadata.CustomDomain != "" {
+		publicURL += fmt.Sprintf(" and https://%s", metadata.CustomDomain)
+	}
 	session.AddFlash(fmt.Sprintf("App exposed successfully at: %s", publicURL), "success")
 	if err := session.Save(r, w); err != nil {
 		logging.Errorf("Failed to save session: %v", err)
@@ -1204,14 +1460,7 @@ func (s *Server) handleAppUnexpose(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract app name from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 || parts[1] != "apps" || parts[3] != "unexpose" {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := parts[2]
+	appName := r.PathValue("name")
 
 	// Get app details from container runtime
 	appDetails, err := s.getAppDetails(appName)
@@ -1272,6 +1521,11 @@ func (s *Server) handleAppUnexpose(w http.ResponseWriter, r *http.Request) {
 			logging.Errorf("Failed to delete route from Caddy: %v", err)
 			// Continue anyway - we'll update the metadata
 		}
+		if metadata.CustomDomain != "" {
+			if err := s.caddyClient.DeleteRoute(caddy.CertificatePolicyID(appID)); err != nil {
+				logging.Errorf("Failed to delete certificate policy from Caddy: %v", err)
+			}
+		}
 	}
 
 	// Update compose file metadata
@@ -1389,6 +1643,9 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 	if setup.AgentCheckInterval.Valid {
 		data["AgentCheckInterval"] = setup.AgentCheckInterval.String
 	}
+	_, reviewInterval := s.agentReviewSchedule()
+	data["AgentReviewInterval"] = formatReviewInterval(reviewInterval)
+	data["AgentReviewIntervals"] = agentReviewIntervals
 	if setup.AgentLLMAPIKey.Valid {
 		data["AgentLLMAPIKey"] = setup.AgentLLMAPIKey.String
 	}
@@ -1439,6 +1696,144 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 		data["NodeName"] = "TreeOS" // Default name
 	}
 
+	data["MaintenanceWindow"] = s.maintenanceWindow().String()
+	if user != nil && user.IsStaff {
+		now := time.Now()
+		loc := s.location()
+		data["Timezone"] = s.timezoneSetting()
+		data["Timezones"] = maintenance.Timezones()
+		data["SystemTimezone"] = time.Local.String()
+		data["NodeTime"] = now.In(loc)
+		data["ScheduledTasks"] = s.scheduledTasks(now)
+		data["ContainerRuntime"] = s.containerRuntimeMode()
+		data["ContainerRuntimeFromEnv"] = os.Getenv("CONTAINER_RUNTIME") != ""
+		data["ContainerEngine"] = s.engineStatus()
+	}
+
+	// Add login history of the current user
+	if user != nil {
+		loginEvents, err := database.GetRecentLoginEvents(user.ID, loginHistoryLimit)
+		if err != nil {
+			logging.Errorf("Failed to get login history: %v", err)
+		}
+		data["LoginEvents"] = loginEvents
+	}
+
+	// TLS client certificates of the current user
+	data["ClientCerts"] = s.clientCertificates(r, user)
+
+	// API tokens of the current user
+	if user != nil {
+		tokens, err := database.GetAPITokens(user.ID)
+		if err != nil {
+			logging.Errorf("Failed to get API tokens: %v", err)
+		}
+		data["APITokens"] = tokens
+	}
+
+	// WebDAV file access, managed by admins
+	data["WebDAVEnabled"] = s.config.WebDAVEnabled
+	if user != nil && user.IsStaff {
+		grants, err := database.GetFileAccessGrants()
+		if err != nil {
+			logging.Errorf("Failed to get file access grants: %v", err)
+		}
+		data["FileAccessGrants"] = grants
+
+		users, err := s.listUsers()
+		if err != nil {
+			logging.Errorf("Failed to list users: %v", err)
+		}
+		data["FileAccessUsers"] = users
+
+		if access, err := s.fileAccessFor(user); err == nil {
+			appNames := make([]string, 0, len(access))
+			for appName := range access {
+				appNames = append(appNames, appName)
+			}
+			sort.Strings(appNames)
+			data["FileAccessApps"] = appNames
+		}
+	}
+
+	// Invite links and their audit log, managed by admins
+	data["InvitesEnabled"] = s.config.InvitesEnabled
+	if user != nil && user.IsStaff && s.config.InvitesEnabled {
+		invites, err := database.GetInvites()
+		if err != nil {
+			logging.Errorf("Failed to get invites: %v", err)
+		}
+		data["Invites"] = invites
+
+		events, err := database.GetInviteEvents(inviteEventLimit)
+		if err != nil {
+			logging.Errorf("Failed to get invite events: %v", err)
+		}
+		data["InviteEvents"] = events
+	}
+
+	// Users and their roles, managed by admins
+	if user != nil && user.IsStaff {
+		users, err := s.listAllUsers()
+		if err != nil {
+			logging.Errorf("Failed to list users: %v", err)
+		}
+		data["Users"] = users
+	}
+
+	// Namespaces of apps, managed by admins
+	if user != nil && user.IsStaff {
+		namespaces, err := database.GetNamespaces()
+		if err != nil {
+			logging.Errorf("Failed to get namespaces: %v", err)
+		}
+		views := make([]namespaceSettingsView, 0, len(namespaces))
+		for _, ns := range namespaces {
+			views = append(views, namespaceSettingsView{Namespace: ns, Apps: s.namespaceApps(ns.Name)})
+		}
+		data["Namespaces"] = views
+	}
+
+	// Webhooks for events on the node, managed by admins
+	if user != nil && user.IsStaff {
+		webhooks, err := database.GetWebhooks()
+		if err != nil {
+			logging.Errorf("Failed to get webhooks: %v", err)
+		}
+		data["Webhooks"] = webhooks
+		data["WebhookEvents"] = notify.Events
+		data["Email"] = s.emailSettingsView()
+		data["SMTPSecurityOptions"] = smtpSecurityOptions
+	}
+
+	// Remote template catalogs, managed by admins
+	if user != nil && user.IsStaff && s.templateSvc != nil {
+		catalogs, err := database.GetTemplateCatalogs()
+		if err != nil {
+			logging.Errorf("Failed to get template catalogs: %v", err)
+		}
+		data["TemplateCatalogs"] = catalogs
+	}
+
+	// Access of users to single apps, managed by admins
+	if user != nil && user.IsStaff {
+		permissions, err := database.GetAppPermissions()
+		if err != nil {
+			logging.Errorf("Failed to get app permissions: %v", err)
+		}
+		data["AppPermissions"] = permissions
+
+		var appNames []string
+		if entries, err := os.ReadDir(s.config.AppsDir); err == nil {
+			for _, entry := range entries {
+				if _, err := os.Stat(filepath.Join(s.config.AppsDir, entry.Name(), "docker-compose.yml")); entry.IsDir() && err == nil {
+					appNames = append(appNames, entry.Name())
+				}
+			}
+		}
+		data["AppPermissionApps"] = appNames
+	}
+
 	// Render template
 	tmpl, ok := s.templates["settings"]
 	if !ok {
@@ -1552,6 +1947,70 @@ func (s *Server) handleSettingsUpdate(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		http.Redirect(w, r, "/settings", http.StatusFound)
+		return
+	case "update_timezone":
+		timezone, tzErr := parseTimezone(strings.TrimSpace(r.FormValue("timezone")))
+		if tzErr == nil {
+			_, err = s.db.Exec(`UPDATE system_setup SET timezone = ? WHERE id = 1`, timezone)
+		}
+
+		session, sessionErr := s.sessionStore.Get(r, "ontree-session")
+		if sessionErr != nil {
+			logging.Errorf("Failed to get session: %v", sessionErr)
+		} else {
+			switch {
+			case tzErr != nil:
+				session.AddFlash(tzErr.Error(), "error")
+			case err != nil:
+				logging.Errorf("Failed to update time zone: %v", err)
+				session.AddFlash("Failed to save time zone", "error")
+			default:
+				session.AddFlash("Time zone updated successfully", "success")
+			}
+			if saveErr := session.Save(r, w); saveErr != nil {
+				logging.Errorf("Failed to save session: %v", saveErr)
+			}
+		}
+
+		http.Redirect(w, r, "/settings", http.StatusFound)
+		return
+	case "update_email":
+		s.saveEmailSettings(w, r)
+		return
+	case "update_container_runtime":
+		s.saveContainerRuntime(w, r)
+		return
+	case "update_agent_reviews":
+		// Handle scheduled agent review settings
+		enabled := 0
+		if r.FormValue("agent_enabled") == "on" {
+			enabled = 1
+		}
+		interval := r.FormValue("agent_review_interval")
+		if !slices.Contains(agentReviewIntervals, interval) {
+			interval = formatReviewInterval(defaultAgentReviewInterval)
+		}
+
+		_, err = s.db.Exec(`
+			UPDATE system_setup SET agent_enabled = ?, agent_review_interval = ? WHERE id = 1
+		`, enabled, interval)
+
+		session, sessionErr := s.sessionStore.Get(r, "ontree-session")
+		if sessionErr != nil {
+			logging.Errorf("Failed to get session: %v", sessionErr)
+		} else {
+			if err != nil {
+				logging.Errorf("Failed to update agent review settings: %v", err)
+				session.AddFlash("Failed to save agent review settings", "error")
+			} else {
+				session.AddFlash("Agent review settings updated successfully", "success")
+			}
+			if saveErr := session.Save(r, w); saveErr != nil {
+				logging.Errorf("Failed to save session: %v", saveErr)
+			}
+		}
+
 		http.Redirect(w, r, "/settings", http.StatusFound)
 		return
 	}
@@ -1680,14 +2139,7 @@ func (s *Server) handleSettingsUpdate(w http.ResponseWriter, r *http.Request) {
 // handleAppStatusCheck handles checking the status of an exposed application's subdomains
 func (s *Server) handleAppStatusCheck(w http.ResponseWriter, r *http.Request) {
 	// Extract app name from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 5 || parts[1] != "api" || parts[2] != "apps" || parts[4] != "status" {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := parts[3]
+	appName := r.PathValue("name")
 
 	// Get app details from container runtime
 	appDetails, err := s.getAppDetails(appName)
@@ -1843,8 +2295,7 @@ func (s *Server) handleAppStatusCheck(w http.ResponseWriter, r *http.Request) {
 // handleAppContainers returns the running containers for an app
 func (s *Server) handleAppContainers(w http.ResponseWriter, r *http.Request) {
 	// Extract app name from URL
-	path := strings.TrimPrefix(r.URL.Path, "/apps/")
-	appName := strings.TrimSuffix(path, "/containers")
+	appName := r.PathValue("name")
 
 	if appName == "" {
 		http.Error(w, "App name required", http.StatusBadRequest)
@@ -1902,14 +2353,7 @@ func (s *Server) handleAppExposeTailscale(w http.ResponseWriter, r *http.Request
 	}
 
 	// Extract app name from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 || parts[1] != "apps" || parts[3] != "expose-tailscale" {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := parts[2]
+	appName := r.PathValue("name")
 
 	// Check if Tailscale auth key is configured
 	if s.config.TailscaleAuthKey == "" {
@@ -2054,14 +2498,7 @@ func (s *Server) handleAppUnexposeTailscale(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Extract app name from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 || parts[1] != "apps" || parts[3] != "unexpose-tailscale" {
-		http.NotFound(w, r)
-		return
-	}
-
-	appName := parts[2]
+	appName := r.PathValue("name")
 
 	// Get app details from container runtime
 	appDetails, err := s.getAppDetails(appName)
@@ -2187,9 +2624,10 @@ func (s *Server) handleTestLLMConnection(w http.ResponseWriter, r *http.Request)
 
 	// Parse request body
 	var req struct {
-		APIKey string `json:"api_key"`
-		APIURL string `json:"api_url"`
-		Model  string `json:"model"`
+		APIKey    string `json:"api_key"`
+		APIURL    string `json:"api_url"`
+		Model     string `json:"model"`
+		Benchmark bool   `json:"benchmark"` // Also measure latency and tokens/sec with a streamed completion
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -2219,10 +2657,19 @@ func (s *Server) handleTestLLMConnection(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+	result := map[string]interface{}{
 		"success":  true,
 		"response": response,
-	}); err != nil {
+	}
+	if req.Benchmark {
+		if benchmark, err := benchmarkLLM(r.Context(), req.APIKey, req.APIURL, req.Model); err != nil {
+			result["benchmark_error"] = err.Error()
+		} else {
+			result["benchmark"] = benchmark
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
 		logging.Errorf("Error encoding response: %v", err)
 	}
 }
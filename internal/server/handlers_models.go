@@ -12,6 +12,7 @@ import (
 	"github.com/ontree-co/treeos/internal/logging"
 
 	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/engine"
 	"github.com/ontree-co/treeos/internal/ollama"
 )
 
@@ -45,6 +46,8 @@ func (s *Server) handleModelTemplates(w http.ResponseWriter, r *http.Request) {
 
 	// Group models by category
 	var chatModels, codeModels, visionModels, customModels []interface{}
+	quota := s.modelQuota()
+	sizes := modelSizes(quota)
 
 	for _, model := range models {
 		// Add status text and color for template
@@ -59,6 +62,7 @@ func (s *Server) handleModelTemplates(w http.ResponseWriter, r *http.Request) {
 			"LastError":    model.LastError,
 			"StatusText":   formatStatusText(model.Status),
 			"StatusColor":  getStatusColorClass(model.Status),
+			"DiskUsage":    sizes[model.Name],
 		}
 
 		switch model.Category {
@@ -84,6 +88,7 @@ func (s *Server) handleModelTemplates(w http.ResponseWriter, r *http.Request) {
 	data["CodeModels"] = codeModels
 	data["VisionModels"] = visionModels
 	data["CustomModels"] = customModels
+	data["DiskUsage"] = modelDiskSummary(quota)
 
 	// Render the template
 	tmpl := s.templates["model_templates"]
@@ -119,11 +124,29 @@ func (s *Server) routeAPIModels(w http.ResponseWriter, r *http.Request) {
 		modelName := strings.TrimPrefix(path, "/api/models/")
 		modelName = strings.TrimSuffix(modelName, "/cancel")
 		s.handleAPIModelCancel(w, r, modelName)
+	case strings.HasSuffix(path, "/pause") && r.Method == http.MethodPost:
+		modelName := strings.TrimPrefix(path, "/api/models/")
+		modelName = strings.TrimSuffix(modelName, "/pause")
+		s.handleAPIModelPause(w, r, modelName)
+	case strings.HasSuffix(path, "/resume") && r.Method == http.MethodPost:
+		modelName := strings.TrimPrefix(path, "/api/models/")
+		modelName = strings.TrimSuffix(modelName, "/resume")
+		s.handleAPIModelResume(w, r, modelName)
+	case strings.HasSuffix(path, "/progress") && r.Method == http.MethodGet:
+		modelName := strings.TrimPrefix(path, "/api/models/")
+		modelName = strings.TrimSuffix(modelName, "/progress")
+		s.handleAPIModelProgress(w, r, modelName)
 	case strings.HasSuffix(path, "/delete") && r.Method == http.MethodPost:
 		// Extract model name from path
 		modelName := strings.TrimPrefix(path, "/api/models/")
 		modelName = strings.TrimSuffix(modelName, "/delete")
 		s.handleAPIModelDelete(w, r, modelName)
+	case strings.HasSuffix(path, "/repull") && r.Method == http.MethodPost:
+		modelName := strings.TrimPrefix(path, "/api/models/")
+		modelName = strings.TrimSuffix(modelName, "/repull")
+		s.handleAPIModelRepull(w, r, modelName)
+	case strings.HasPrefix(path, "/api/models/") && r.Method == http.MethodDelete:
+		s.handleAPIModelDelete(w, r, strings.TrimPrefix(path, "/api/models/"))
 	default:
 		http.NotFound(w, r)
 	}
@@ -172,6 +195,7 @@ func (s *Server) handleAPIModelsGet(w http.ResponseWriter, r *http.Request) {
 			// Show installed models and models that are currently downloading/queued
 			if model.Status == ollama.StatusCompleted ||
 				model.Status == ollama.StatusDownloading ||
+				model.Status == ollama.StatusPaused ||
 				model.Status == ollama.StatusQueued {
 				filteredModels = append(filteredModels, model)
 			}
@@ -253,6 +277,10 @@ func (s *Server) handleAPIModelPull(w http.ResponseWriter, _ *http.Request, mode
 		}
 	}
 
+	if s.refuseOverQuota(w, modelName) {
+		return
+	}
+
 	// Create a new download job
 	job, err := ollama.CreateDownloadJob(s.db, modelName)
 	if err != nil {
@@ -300,6 +328,10 @@ func (s *Server) handleAPIModelRetry(w http.ResponseWriter, _ *http.Request, mod
 		return
 	}
 
+	if s.refuseOverQuota(w, modelName) {
+		return
+	}
+
 	// Clear error state
 	err = ollama.ClearModelError(s.db, modelName)
 	if err != nil {
@@ -334,8 +366,15 @@ func (s *Server) handleAPIModelRetry(w http.ResponseWriter, _ *http.Request, mod
 	})
 }
 
+// modelsConnectedEvent starts the stream of model updates
+const modelsConnectedEvent = "event: connected\ndata: {\"message\": \"Connected to model updates\"}\n\n"
+
 // handleAPIModelsSSE handles SSE connections for real-time model updates
 func (s *Server) handleAPIModelsSSE(w http.ResponseWriter, r *http.Request) {
+	if isSSEPoll(r) {
+		s.serveSSEPoll(w, r, "models", func() []string { return []string{modelsConnectedEvent} })
+		return
+	}
 	logging.Infof("SSE client connecting from %s", r.RemoteAddr)
 
 	// Set SSE headers
@@ -348,7 +387,7 @@ func (s *Server) handleAPIModelsSSE(w http.ResponseWriter, r *http.Request) {
 	// Create SSE client
 	client := &SSEClient{
 		AppID:    "models", // Use "models" as the app ID for model updates
-		Messages: make(chan string, 100),
+		Messages: make(chan string, s.sseBufferSize()),
 		Close:    make(chan bool),
 	}
 
@@ -368,7 +407,7 @@ func (s *Server) handleAPIModelsSSE(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send initial connection message
-	fmt.Fprintf(w, "event: connected\ndata: {\"message\": \"Connected to model updates\"}\n\n") //nolint:errcheck // SSE stream
+	fmt.Fprint(w, modelsConnectedEvent) //nolint:errcheck // SSE stream
 	flusher.Flush()
 
 	// Handle client disconnect
@@ -413,7 +452,7 @@ type OllamaContainer struct {
 // discoverOllamaContainer finds Ollama containers using label-based detection
 func (s *Server) discoverOllamaContainer() *OllamaContainer {
 	// Look for containers with the ontree.inference=true label
-	cmd := exec.Command("docker", "ps", "--filter", "label=ontree.inference=true", "--format", "{{.Names}}\t{{.Ports}}")
+	cmd := exec.Command(engine.CLI(), "ps", "--filter", "label=ontree.inference=true", "--format", "{{.Names}}\t{{.Ports}}")
 	output, err := cmd.Output()
 	if err != nil {
 		logging.Errorf("Failed to discover Ollama container: %v", err)
@@ -474,7 +513,7 @@ func (s *Server) getInstalledModels() []string {
 		return nil
 	}
 
-	cmd := exec.Command("docker", "exec", container.Name, "ollama", "list") //nolint:gosec // container.Name is from Docker API
+	cmd := exec.Command(engine.CLI(), "exec", container.Name, "ollama", "list") //nolint:gosec // container.Name is from Docker API
 	output, err := cmd.Output()
 	if err != nil {
 		logging.Errorf("Failed to list Ollama models: %v", err)
@@ -521,6 +560,8 @@ func isInstalled(modelName string, installedModels []string) bool {
 func (s *Server) renderModelsHTML(w http.ResponseWriter, _ *http.Request, models []ollama.OllamaModel, hasOllama bool) {
 	// Group models by category
 	var chatModels, codeModels, visionModels, customModels []interface{}
+	quota := s.modelQuota()
+	sizes := modelSizes(quota)
 
 	for _, model := range models {
 		// Add status text and color for template
@@ -535,6 +576,7 @@ func (s *Server) renderModelsHTML(w http.ResponseWriter, _ *http.Request, models
 			"LastError":    model.LastError,
 			"StatusText":   formatStatusText(model.Status),
 			"StatusColor":  getStatusColorClass(model.Status),
+			"DiskUsage":    sizes[model.Name],
 		}
 
 		switch model.Category {
@@ -563,6 +605,7 @@ func (s *Server) renderModelsHTML(w http.ResponseWriter, _ *http.Request, models
 		"CustomModels": customModels,
 		"TotalCount":   len(models),
 		"ModelsDir":    modelsDir,
+		"DiskUsage":    modelDiskSummary(quota),
 	}
 
 	// Use the pre-loaded template
@@ -588,6 +631,8 @@ func formatStatusText(status string) string {
 		return "Queued"
 	case ollama.StatusDownloading:
 		return "Downloading"
+	case ollama.StatusPaused:
+		return "Paused"
 	case ollama.StatusCompleted:
 		return "Installed"
 	case ollama.StatusFailed:
@@ -606,6 +651,8 @@ func getStatusColorClass(status string) string {
 		return "info"
 	case ollama.StatusDownloading:
 		return "primary"
+	case ollama.StatusPaused:
+		return "warning"
 	case ollama.StatusCompleted:
 		return "success"
 	case ollama.StatusFailed:
@@ -642,6 +689,7 @@ func (s *Server) startOllamaWorker() {
 		containerName = container.Name
 	}
 	s.ollamaWorker = ollama.NewWorker(s.db, containerName)
+	s.ollamaWorker.SetQuota(s.modelQuota())
 	s.ollamaWorker.Start(3) // Start with 3 workers
 
 	// Listen for updates and broadcast via SSE
@@ -659,9 +707,14 @@ func (s *Server) startOllamaWorker() {
 				"status":    update.Status,
 				"progress":  update.Progress,
 				"error":     update.Error,
+				"layers":    update.Layers,
 				"timestamp": time.Now().Unix(),
 			})
 
+			if model, err := ollama.GetModel(s.db, update.ModelName); err == nil && model != nil {
+				s.publishJob(jobFromModel(model))
+			}
+
 			// Note: The broadcast only happens if clients are connected
 			logging.Infof("Attempted to broadcast update for model %s (clients may not be connected)", update.ModelName)
 		}
@@ -685,6 +738,11 @@ func (s *Server) handleAPIModelDelete(w http.ResponseWriter, _ *http.Request, mo
 		return
 	}
 
+	if model.Status == ollama.StatusQueued || model.Status == ollama.StatusDownloading {
+		http.Error(w, "Model is being downloaded, cancel the download first", http.StatusConflict)
+		return
+	}
+
 	// Check if model is actually installed
 	container := s.discoverOllamaContainer()
 	if container == nil {
@@ -693,7 +751,7 @@ func (s *Server) handleAPIModelDelete(w http.ResponseWriter, _ *http.Request, mo
 	}
 
 	// Delete the model from Ollama
-	cmd := exec.Command("docker", "exec", container.Name, "ollama", "rm", modelName) //nolint:gosec // container.Name and modelName are validated
+	cmd := exec.Command(engine.CLI(), "exec", container.Name, "ollama", "rm", modelName) //nolint:gosec // container.Name and modelName are validated
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// Check if model doesn't exist in Ollama (already deleted)
@@ -735,14 +793,14 @@ func (s *Server) handleAPIModelCancel(w http.ResponseWriter, _ *http.Request, mo
 		return
 	}
 
-	// Only allow cancel for downloading models
-	if model.Status != ollama.StatusDownloading {
+	// Only allow cancel for downloading and paused models
+	if model.Status != ollama.StatusDownloading && model.Status != ollama.StatusPaused {
 		http.Error(w, "Model is not currently downloading", http.StatusBadRequest)
 		return
 	}
 
-	// Cancel the download through the worker
-	if s.ollamaWorker != nil {
+	// Cancel the download through the worker, a paused one has nothing running
+	if s.ollamaWorker != nil && model.Status == ollama.StatusDownloading {
 		err = s.ollamaWorker.CancelDownload(modelName)
 		if err != nil {
 			logging.Errorf("Failed to cancel download: %v", err)
@@ -755,7 +813,7 @@ func (s *Server) handleAPIModelCancel(w http.ResponseWriter, _ *http.Request, mo
 	container := s.discoverOllamaContainer()
 	if container != nil {
 		// Try to remove the partial model - this will fail if model doesn't exist, which is fine
-		cmd := exec.Command("docker", "exec", container.Name, "ollama", "rm", modelName) //nolint:gosec // container.Name and modelName are validated
+		cmd := exec.Command(engine.CLI(), "exec", container.Name, "ollama", "rm", modelName) //nolint:gosec // container.Name and modelName are validated
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			// Only log if it's not a "model not found" error
@@ -782,6 +840,122 @@ func (s *Server) handleAPIModelCancel(w http.ResponseWriter, _ *http.Request, mo
 	})
 }
 
+// handleAPIModelPause stops a running download and keeps the downloaded layers, so
+// POST /api/models/{name}/resume continues where it stopped
+func (s *Server) handleAPIModelPause(w http.ResponseWriter, _ *http.Request, modelName string) {
+	model, err := ollama.GetModel(s.db, modelName)
+	if err != nil {
+		logging.Errorf("Failed to get model: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if model == nil {
+		http.Error(w, "Model not found", http.StatusNotFound)
+		return
+	}
+	if model.Status != ollama.StatusDownloading {
+		http.Error(w, "Model is not currently downloading", http.StatusBadRequest)
+		return
+	}
+	if s.ollamaWorker == nil {
+		http.Error(w, "Download service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := s.ollamaWorker.PauseDownload(modelName); err != nil {
+		logging.Errorf("Failed to pause download: %v", err)
+		http.Error(w, "Failed to pause download", http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Download paused",
+		"model":   modelName,
+	})
+}
+
+// handleAPIModelResume queues a paused download again. Ollama continues the layers from
+// the parts it downloaded before.
+func (s *Server) handleAPIModelResume(w http.ResponseWriter, _ *http.Request, modelName string) {
+	model, err := ollama.GetModel(s.db, modelName)
+	if err != nil {
+		logging.Errorf("Failed to get model: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if model == nil {
+		http.Error(w, "Model not found", http.StatusNotFound)
+		return
+	}
+	if model.Status != ollama.StatusPaused {
+		http.Error(w, "Model download is not paused", http.StatusBadRequest)
+		return
+	}
+	if s.ollamaWorker == nil {
+		http.Error(w, "Download service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if s.refuseOverQuota(w, modelName) {
+		return
+	}
+
+	job, err := ollama.CreateDownloadJob(s.db, modelName)
+	if err != nil {
+		logging.Errorf("Failed to create resume job: %v", err)
+		http.Error(w, "Failed to queue download", http.StatusInternalServerError)
+		return
+	}
+	s.ollamaWorker.AddJob(*job)
+
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Download resumed",
+		"job_id":  job.ID,
+		"model":   modelName,
+	})
+}
+
+// handleAPIModelRepull downloads an installed model again, e.g. to get a newer version of its
+// tag. Ollama only downloads the layers that changed and the model stays usable meanwhile.
+func (s *Server) handleAPIModelRepull(w http.ResponseWriter, _ *http.Request, modelName string) {
+	model, err := ollama.GetModel(s.db, modelName)
+	if err != nil {
+		logging.Errorf("Failed to get model: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if model == nil {
+		http.Error(w, "Model not found", http.StatusNotFound)
+		return
+	}
+	if model.Status != ollama.StatusCompleted {
+		http.Error(w, "Model is not installed", http.StatusBadRequest)
+		return
+	}
+	if s.ollamaWorker == nil {
+		http.Error(w, "Download service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if s.refuseOverQuota(w, modelName) {
+		return
+	}
+
+	job, err := ollama.CreateDownloadJob(s.db, modelName)
+	if err != nil {
+		logging.Errorf("Failed to create re-pull job: %v", err)
+		http.Error(w, "Failed to queue download", http.StatusInternalServerError)
+		return
+	}
+	s.ollamaWorker.AddJob(*job)
+
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Re-pull queued",
+		"job_id":  job.ID,
+		"model":   modelName,
+	})
+}
+
 // handleModelDetail handles the model detail page
 func (s *Server) handleModelDetail(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r.Context())
@@ -875,6 +1049,10 @@ func (s *Server) handleModelDetail(w http.ResponseWriter, r *http.Request) {
 	data["IsInstalled"] = isModelInstalled
 	data["RegistryPath"] = registryPath
 	data["BlobPath"] = blobPath
+	data["DiskUsage"] = modelSizes(s.modelQuota())[modelName]
+	if s.ollamaWorker != nil {
+		data["Layers"] = s.ollamaWorker.Layers(modelName)
+	}
 
 	// Render the template
 	tmpl, ok := s.templates["model_detail"]
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/ollama"
+)
+
+func TestAPIModelPauseResume(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+	db := database.GetDB()
+	if err := ollama.CreateModel(db, &ollama.OllamaModel{Name: "llama3:8b", DisplayName: "Llama 3", Status: ollama.StatusQueued}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{db: db}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.routeAPIModels(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec
+	}
+	if rec := post("/api/models/llama3:8b/pause"); rec.Code != http.StatusBadRequest {
+		t.Errorf("pause of a queued model = %d", rec.Code)
+	}
+	if rec := post("/api/models/mistral:7b/pause"); rec.Code != http.StatusNotFound {
+		t.Errorf("pause of an unknown model = %d", rec.Code)
+	}
+	if rec := post("/api/models/llama3:8b/resume"); rec.Code != http.StatusBadRequest {
+		t.Errorf("resume of a queued model = %d", rec.Code)
+	}
+
+	if err := ollama.UpdateModelStatus(db, "llama3:8b", ollama.StatusPaused, 40); err != nil {
+		t.Fatal(err)
+	}
+	if rec := post("/api/models/llama3:8b/resume"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("resume without worker = %d", rec.Code)
+	}
+
+	// Cancelling a paused download resets the model
+	if rec := post("/api/models/llama3:8b/cancel"); rec.Code != http.StatusOK {
+		t.Errorf("cancel of a paused model = %d: %s", rec.Code, rec.Body)
+	}
+	if model, err := ollama.GetModel(db, "llama3:8b"); err != nil || model.Status != ollama.StatusNotDownloaded {
+		t.Errorf("cancelled model = %+v, %v", model, err)
+	}
+}
+
+func TestAPIModelQuotaAndLifecycle(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+	db := database.GetDB()
+	if err := ollama.CreateModel(db, &ollama.OllamaModel{Name: "llama3:8b", DisplayName: "Llama 3", Status: ollama.StatusQueued}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "blob"), make([]byte, 8192), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	worker := ollama.NewWorker(db, "ollama")
+	worker.SetQuota(ollama.Quota{Dir: dir, Limit: 4096})
+	s := &Server{db: db, ollamaWorker: worker}
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.routeAPIModels(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	if rec := do(http.MethodPost, "/api/models/tinyllama:1b/pull"); rec.Code != http.StatusInsufficientStorage || !strings.Contains(rec.Body.String(), "quota") {
+		t.Errorf("pull over quota = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/models/llama3:8b/repull"); rec.Code != http.StatusBadRequest {
+		t.Errorf("re-pull of a model that isn't installed = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/models/llama3:8b"); rec.Code != http.StatusConflict {
+		t.Errorf("delete of a queued model = %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/models/mistral:7b"); rec.Code != http.StatusNotFound {
+		t.Errorf("delete of an unknown model = %d", rec.Code)
+	}
+}
@@ -180,6 +180,30 @@ func TestHandleSettingsUpdate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Update email settings",
+			formData: url.Values{
+				"action":        {"update_email"},
+				"smtp_host":     {"mail.example.com"},
+				"smtp_port":     {"465"},
+				"smtp_security": {"tls"},
+				"smtp_username": {"treeos"},
+				"smtp_password": {"secret"},
+				"smtp_from":     {"treeos@example.com"},
+				"email_events":  {"update.failed", "container.unhealthy", "app.deleted"},
+			},
+			expectedStatus: http.StatusFound,
+			checkConfig: func(t *testing.T, s *Server) {
+				smtp := s.smtpConfig()
+				if smtp.Host != "mail.example.com" || smtp.Port != 465 || smtp.Security != "tls" || smtp.Password != "secret" {
+					t.Errorf("Expected the saved mail server, got %+v", smtp)
+				}
+				settings, _ := database.GetEmailSettings()
+				if strings.Join(settings.Events, ",") != "update.failed,container.unhealthy" {
+					t.Errorf("Expected only known email events, got %v", settings.Events)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	// "io"       // Commented out - used by handleAppCheckUpdate
 	"net/http"
-	"strings"
 	// "time"     // Commented out - used by handleAppCheckUpdate
 	// Deprecated imports removed - functionality replaced with Docker
 )
@@ -207,25 +206,36 @@ func (s *Server) checkImageUpdate(imageName string) (*ImageUpdateInfo, error) {
 }
 */
 
-// handleAppUpdate handles the actual update of an app's container image
+// handleAppUpdate handles POST /apps/{appName}/update, which pulls the images of an app
+// and recreates it on them in the background, like POST /api/apps/{appName}/upgrade
 func (s *Server) handleAppUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 
 	// Extract app name from path
-	appName := strings.TrimPrefix(r.URL.Path, "/apps/")
-	appName = strings.TrimSuffix(appName, "/update")
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
 
-	logging.Infof("Update requested for app: %s", appName)
+	flash, flashType := "Pulling the latest images. A running app is recreated on them and rolled back if it doesn't become healthy.", "info"
+	if err := s.startAppImageUpdate(appName, user.Username); err != nil {
+		flash, flashType = fmt.Sprintf("Failed to start the update: %v", err), "error"
+	}
 
-	// For now, just redirect back with a message that the user needs to recreate manually
 	session, err := s.sessionStore.Get(r, "ontree-session")
 	if err != nil {
 		logging.Errorf("Failed to get session: %v", err)
 	} else {
-		session.AddFlash("Please use the 'Recreate' button to update the container with the latest image", "info")
+		session.AddFlash(flash, flashType)
 		if err := session.Save(r, w); err != nil {
 			logging.Errorf("Failed to save session: %v", err)
 		}
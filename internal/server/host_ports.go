@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/ports"
+)
+
+// portRegistry returns the registry of the host ports the apps publish
+func (s *Server) portRegistry() *ports.Registry {
+	return ports.NewRegistry(s.config.AppsDir)
+}
+
+// claimHostPorts checks the host ports of a new or changed app against the other apps and
+// the host. With assign, taken ports are moved to free ones and the changed files are
+// returned, otherwise taken ports fail with a *ports.ConflictError.
+func (s *Server) claimHostPorts(appName, composeContent, envContent string, assign bool) (string, string, error) {
+	registry := s.portRegistry()
+	if !assign {
+		return composeContent, envContent, registry.Check(appName, []byte(composeContent), []byte(envContent))
+	}
+	composeData, envData, assignments, err := registry.Assign(appName, []byte(composeContent), []byte(envContent))
+	if err != nil {
+		return "", "", err
+	}
+	for _, assignment := range assignments {
+		logging.Infof("Host port %d of app %s was taken, service %s publishes %d instead", assignment.From, appName, assignment.Service, assignment.To)
+	}
+	return string(composeData), string(envData), nil
+}
+
+// portErrorStatus returns the HTTP status of an error of claimHostPorts
+func portErrorStatus(err error) int {
+	var conflict *ports.ConflictError
+	if errors.As(err, &conflict) || errors.Is(err, ports.ErrNoFreePort) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// handleAPIPorts handles GET /api/ports, the host ports the apps publish, for staff, and GET
+// /api/ports/free?from=8080&protocol=tcp, the first free port from a port on
+func (s *Server) handleAPIPorts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	registry := s.portRegistry()
+
+	if r.URL.Path == "/api/ports/free" {
+		from := 8080
+		if value := r.URL.Query().Get("from"); value != "" {
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				http.Error(w, "from must be a port between 1 and 65535", http.StatusBadRequest)
+				return
+			}
+			from = port
+		}
+		protocol := r.URL.Query().Get("protocol")
+		switch protocol {
+		case "":
+			protocol = ports.TCP
+		case ports.TCP, ports.UDP:
+		default:
+			http.Error(w, "protocol must be tcp or udp", http.StatusBadRequest)
+			return
+		}
+		port, err := registry.Free("", from, protocol)
+		if err != nil {
+			http.Error(w, err.Error(), portErrorStatus(err))
+			return
+		}
+		writePortsJSON(w, map[string]interface{}{"port": port, "protocol": protocol})
+		return
+	}
+
+	if !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	bindings, err := registry.Bindings("")
+	if err != nil {
+		logging.Errorf("Failed to read host ports of the apps: %v", err)
+		http.Error(w, "Failed to read host ports", http.StatusInternalServerError)
+		return
+	}
+	if bindings == nil {
+		bindings = []ports.Binding{}
+	}
+	writePortsJSON(w, map[string]interface{}{"ports": bindings})
+}
+
+// writePortsJSON writes a JSON response
+func writePortsJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
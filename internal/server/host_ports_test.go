@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/ports"
+)
+
+func TestClaimHostPorts(t *testing.T) {
+	appsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appsDir, "wiki"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	wiki := "services:\n  web:\n    image: nginx\n    ports:\n      - \"47080:80\"\n"
+	if err := os.WriteFile(filepath.Join(appsDir, "wiki", "docker-compose.yml"), []byte(wiki), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+
+	blog := "services:\n  web:\n    image: ghost\n    ports:\n      - \"47080:2368\"\nx-ontree:\n  host_port: 47080\n"
+	_, _, err := s.claimHostPorts("blog", blog, "", false)
+	var conflict *ports.ConflictError
+	if !errors.As(err, &conflict) || conflict.Conflicts[0].App != "wiki" || portErrorStatus(err) != http.StatusConflict {
+		t.Fatalf("claimHostPorts = %v, want a conflict with wiki", err)
+	}
+	if _, _, err := s.claimHostPorts("wiki", wiki, "", false); err != nil {
+		t.Errorf("claimHostPorts of the app itself = %v", err)
+	}
+
+	composeContent, _, err := s.claimHostPorts("blog", blog, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(composeContent, "47080") || !strings.Contains(composeContent, "host_port: 4708") {
+		t.Errorf("assigned compose file =\n%s", composeContent)
+	}
+
+	request := func(user *database.User, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIPorts(rec, req)
+		return rec
+	}
+	if rec := request(&database.User{Username: "bob"}, "/api/ports"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("ports for non-staff: status = %d", rec.Code)
+	}
+	rec := request(&database.User{Username: "admin", IsStaff: true}, "/api/ports")
+	var list struct {
+		Ports []ports.Binding `json:"ports"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Ports) != 1 || list.Ports[0].App != "wiki" {
+		t.Errorf("GET /api/ports = %s", rec.Body)
+	}
+	rec = request(&database.User{Username: "bob"}, "/api/ports/free?from=47080")
+	var free struct {
+		Port int `json:"port"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &free); err != nil || free.Port <= 47080 {
+		t.Errorf("GET /api/ports/free = %s", rec.Body)
+	}
+	if rec := request(&database.User{Username: "bob"}, "/api/ports/free?protocol=sctp"); rec.Code != http.StatusBadRequest {
+		t.Errorf("free port of an unknown protocol: status = %d", rec.Code)
+	}
+}
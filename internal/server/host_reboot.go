@@ -0,0 +1,487 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/hostupdates"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/maintenance"
+	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// rebootCheckInterval is how often the scheduler looks for due reboots
+	rebootCheckInterval = time.Minute
+	// rebootVerifyTimeout is how long to wait for Docker and apps after boot
+	rebootVerifyTimeout = 10 * time.Minute
+)
+
+// HostRebootResponse is returned by GET /api/system/reboot
+type HostRebootResponse struct {
+	Supported         bool                 `json:"supported"`
+	RebootRequired    bool                 `json:"reboot_required"`
+	RebootReasons     []string             `json:"reboot_reasons,omitempty"`
+	MaintenanceWindow string               `json:"maintenance_window"`
+	NextWindow        time.Time            `json:"next_window"`
+	Reboot            *database.HostReboot `json:"reboot,omitempty"`
+}
+
+// maintenanceWindow returns the configured maintenance window in the node's time zone
+func (s *Server) maintenanceWindow() maintenance.Window {
+	w, err := maintenance.Parse(s.config.MaintenanceWindow)
+	if err != nil {
+		// Validated when loading the config, only reachable with a hand-built config
+		w, _ = maintenance.Parse(maintenance.DefaultWindow) //nolint:errcheck // Default is valid
+	}
+	return w.In(s.location())
+}
+
+// startRebootScheduler verifies the outcome of a reboot that happened before this
+// start and runs scheduled reboots once their maintenance window opens.
+func (s *Server) startRebootScheduler() {
+	if runtime.GOOS != "linux" || s.db == nil {
+		return
+	}
+
+	go func() {
+		s.verifyHostReboot()
+
+		ticker := time.NewTicker(rebootCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.runDueReboot()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// runDueReboot executes the scheduled reboot if its time has come and the window is open
+func (s *Server) runDueReboot() {
+	reboot, err := database.GetLatestHostReboot()
+	if err != nil {
+		logging.Errorf("Failed to get scheduled reboot: %v", err)
+		return
+	}
+	if reboot == nil || reboot.Status != database.RebootStatusScheduled {
+		return
+	}
+
+	now := time.Now()
+	if now.Before(reboot.ScheduledFor) {
+		return
+	}
+	// A reboot that was scheduled for a window but missed it (e.g. TreeOS was down)
+	// waits for the next window. Immediate reboots run right away.
+	if reboot.Reason != rebootReasonImmediate && !s.maintenanceWindow().Contains(now) {
+		return
+	}
+
+	s.executeHostReboot(reboot)
+}
+
+// rebootReasonImmediate marks reboots that should not wait for the maintenance window
+const rebootReasonImmediate = "immediate"
+
+// executeHostReboot stops all running apps and reboots the host
+func (s *Server) executeHostReboot(reboot *database.HostReboot) {
+	claimed, err := database.ClaimHostReboot(reboot.ID)
+	if err != nil {
+		logging.Errorf("Failed to start host reboot: %v", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	ctx := context.Background()
+	logging.Infof("Starting coordinated host reboot #%d", reboot.ID)
+
+	apps, err := s.runningApps()
+	if err != nil {
+		s.failHostReboot(reboot.ID, fmt.Sprintf("Failed to list running apps: %v", err))
+		return
+	}
+	if err := database.SetHostRebootApps(reboot.ID, apps); err != nil {
+		s.failHostReboot(reboot.ID, err.Error())
+		return
+	}
+	s.setHostRebootStatus(reboot.ID, database.RebootStatusStopping, fmt.Sprintf("Stopping %d apps", len(apps)))
+
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		s.failHostReboot(reboot.ID, fmt.Sprintf("Compose service not available: %v", err))
+		return
+	}
+
+	var stopped []string
+	for _, appName := range rebootStopOrder(s.dependencyGraphOrEmpty(), apps) {
+		opts := compose.Options{WorkingDir: filepath.Join(s.config.AppsDir, appName)}
+		if err := composeSvc.Down(ctx, opts, false); err != nil {
+			s.startApps(ctx, stopped)
+			s.failHostReboot(reboot.ID, fmt.Sprintf("Failed to stop app %s: %v", appName, err))
+			return
+		}
+		stopped = append(stopped, appName)
+	}
+
+	s.setHostRebootStatus(reboot.ID, database.RebootStatusRebooting, "Rebooting host")
+	if err := rebootHost(ctx); err != nil {
+		s.startApps(ctx, stopped)
+		s.failHostReboot(reboot.ID, fmt.Sprintf("Failed to reboot host: %v", err))
+		return
+	}
+}
+
+// verifyHostReboot checks that all apps that were running before a reboot are running again
+func (s *Server) verifyHostReboot() {
+	reboot, err := database.GetLatestHostReboot()
+	if err != nil {
+		logging.Errorf("Failed to get last reboot: %v", err)
+		return
+	}
+	if reboot == nil || reboot.Status != database.RebootStatusRebooting {
+		return
+	}
+
+	// TreeOS may have been restarted on its own while the reboot was pending
+	if bootTime, err := hostBootTime(); err == nil && bootTime.Before(reboot.UpdatedAt) {
+		s.failHostReboot(reboot.ID, "TreeOS restarted but the host did not reboot")
+		return
+	}
+
+	logging.Infof("Verifying apps after host reboot #%d", reboot.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), rebootVerifyTimeout)
+	defer cancel()
+
+	// Apps with a restart policy come back on their own, start the rest
+	var failed []string
+	for _, appName := range reverse(rebootStopOrder(s.dependencyGraphOrEmpty(), reboot.Apps)) {
+		if s.waitForApp(ctx, appName, time.Minute) {
+			continue
+		}
+		if err := s.startAppAfterReboot(ctx, appName); err != nil {
+			logging.Errorf("Failed to start app %s after reboot: %v", appName, err)
+			failed = append(failed, appName)
+			continue
+		}
+		if !s.waitForApp(ctx, appName, 2*time.Minute) {
+			failed = append(failed, appName)
+		}
+	}
+
+	if len(failed) > 0 {
+		s.failHostReboot(reboot.ID, fmt.Sprintf("Host rebooted but these apps are not running: %s", strings.Join(failed, ", ")))
+		return
+	}
+
+	message := fmt.Sprintf("Host rebooted and all %d apps are running", len(reboot.Apps))
+	s.setHostRebootStatus(reboot.ID, database.RebootStatusCompleted, message)
+	s.notifyAdmins("TreeOS host reboot completed", message+".\n")
+}
+
+// runningApps returns the names of apps with running containers
+func (s *Server) runningApps() ([]string, error) {
+	if s.runtimeSvc == nil {
+		return nil, errRuntimeUnavailable
+	}
+	apps, err := s.runtimeSvc.ScanApps()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, app := range apps {
+		if app.Status == "running" || app.Status == "partial" {
+			names = append(names, app.Name)
+		}
+	}
+	return names, nil
+}
+
+// waitForApp polls until the app is running or the timeout expires
+func (s *Server) waitForApp(ctx context.Context, appName string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if s.runtimeSvc != nil {
+			if app, err := s.runtimeSvc.GetAppDetails(appName); err == nil && app.Status == "running" {
+				return true
+			}
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// startApps starts apps again after an aborted reboot, errors are only logged
+func (s *Server) startApps(ctx context.Context, apps []string) {
+	for _, appName := range reverse(apps) {
+		if err := s.startAppAfterReboot(ctx, appName); err != nil {
+			logging.Errorf("Failed to restart app %s: %v", appName, err)
+		}
+	}
+}
+
+// startAppAfterReboot starts an app the same way the start API does, without progress tracking
+func (s *Server) startAppAfterReboot(ctx context.Context, appName string) error {
+	opts, err := s.appStartOptions(appName)
+	if err != nil {
+		return err
+	}
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return err
+	}
+	return composeSvc.Up(ctx, opts)
+}
+
+// appStartOptions returns the compose options to start an app with, after the checks of the
+// start API: the security rules unless the app bypasses them, and the addresses of its ports
+func (s *Server) appStartOptions(appName string) (compose.Options, error) {
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	yamlContent, err := os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // Path from trusted app directory
+	if err != nil {
+		return compose.Options{}, fmt.Errorf("failed to read docker-compose.yml: %w", err)
+	}
+
+	metadata, err := yamlutil.ReadComposeMetadata(appDir)
+	if err != nil {
+		metadata = &yamlutil.OnTreeMetadata{}
+	}
+	if !metadata.BypassSecurity {
+		if err := security.NewValidator(appName).ValidateCompose(yamlContent); err != nil {
+			return compose.Options{}, fmt.Errorf("security validation failed: %w", err)
+		}
+	}
+	if unavailable := unavailableBindings(yamlContent); len(unavailable) > 0 {
+		return compose.Options{}, fmt.Errorf("ports are bound to addresses this host doesn't have: %s", strings.Join(unavailable, ", "))
+	}
+
+	opts := compose.Options{WorkingDir: appDir}
+	if _, err := os.Stat(filepath.Join(appDir, ".env")); err == nil {
+		opts.EnvFile = ".env"
+	}
+	return opts, nil
+}
+
+func (s *Server) setHostRebootStatus(id int, status, message string) {
+	if err := database.UpdateHostRebootStatus(id, status, message); err != nil {
+		logging.Errorf("Failed to update reboot status: %v", err)
+	}
+	if s.sseManager != nil {
+		s.sseManager.SendToAll("host-reboot", map[string]interface{}{
+			"id":      id,
+			"status":  status,
+			"message": message,
+		})
+	}
+}
+
+func (s *Server) failHostReboot(id int, message string) {
+	logging.Errorf("Host reboot #%d failed: %s", id, message)
+	s.setHostRebootStatus(id, database.RebootStatusFailed, message)
+	s.notifyAdmins("TreeOS host reboot failed", message+"\n")
+}
+
+// rebootStopOrder returns the order in which apps are stopped before a reboot.
+// Apps are stopped before the apps they depend on, otherwise in name order, and
+// are started in the reverse order.
+func rebootStopOrder(graph map[string][]string, apps []string) []string {
+	return reverse(startOrder(graph, apps))
+}
+
+func reverse(items []string) []string {
+	reversed := make([]string, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		reversed = append(reversed, items[i])
+	}
+	return reversed
+}
+
+// rebootHost asks systemd-logind to reboot the machine
+func rebootHost(ctx context.Context) error {
+	if _, err := exec.LookPath("busctl"); err == nil {
+		output, err := exec.CommandContext(ctx, "busctl", "call",
+			"org.freedesktop.login1", "/org/freedesktop/login1", "org.freedesktop.login1.Manager",
+			"Reboot", "b", "false").CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		logging.Warnf("logind reboot via busctl failed, falling back to systemctl: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	output, err := exec.CommandContext(ctx, "systemctl", "reboot").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// hostBootTime returns when the host was booted, read from /proc/stat
+func hostBootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("boot time not found")
+}
+
+// handleHostReboot handles /api/system/reboot:
+// GET returns the reboot status, POST schedules a reboot and DELETE cancels a scheduled one
+func (s *Server) handleHostReboot(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGetHostReboot(w, r)
+	case http.MethodPost:
+		s.handleScheduleHostReboot(w, r)
+	case http.MethodDelete:
+		s.handleCancelHostReboot(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleGetHostReboot(w http.ResponseWriter, r *http.Request) {
+	window := s.maintenanceWindow()
+	resp := HostRebootResponse{
+		Supported:         runtime.GOOS == "linux",
+		MaintenanceWindow: window.String(),
+		NextWindow:        window.Next(time.Now()),
+	}
+	resp.RebootReasons = hostupdates.RebootReasons(r.Context())
+	resp.RebootRequired = len(resp.RebootReasons) > 0
+
+	reboot, err := database.GetLatestHostReboot()
+	if err != nil {
+		logging.Errorf("Failed to get last reboot: %v", err)
+	}
+	resp.Reboot = reboot
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.Errorf("Failed to encode reboot status: %v", err)
+	}
+}
+
+func (s *Server) handleScheduleHostReboot(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if runtime.GOOS != "linux" {
+		http.Error(w, "Host reboots are only supported on Linux", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Confirm   bool `json:"confirm"`
+		Immediate bool `json:"immediate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Confirm {
+		http.Error(w, "Rebooting the host must be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	latest, err := database.GetLatestHostReboot()
+	if err != nil {
+		logging.Errorf("Failed to get last reboot: %v", err)
+		http.Error(w, "Failed to schedule reboot", http.StatusInternalServerError)
+		return
+	}
+	if latest != nil && (latest.Status == database.RebootStatusScheduled ||
+		latest.Status == database.RebootStatusStopping || latest.Status == database.RebootStatusRebooting) {
+		http.Error(w, "A reboot is already scheduled", http.StatusConflict)
+		return
+	}
+
+	reboot := database.HostReboot{
+		Status:       database.RebootStatusScheduled,
+		RequestedBy:  user.Username,
+		ScheduledFor: s.maintenanceWindow().Next(time.Now()),
+	}
+	if req.Immediate {
+		reboot.Reason = rebootReasonImmediate
+		reboot.ScheduledFor = time.Now()
+	}
+	id, err := database.CreateHostReboot(reboot)
+	if err != nil {
+		logging.Errorf("Failed to schedule reboot: %v", err)
+		http.Error(w, "Failed to schedule reboot", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("User %s scheduled host reboot #%d for %s", user.Username, id, reboot.ScheduledFor.Format(time.RFC3339))
+
+	if req.Immediate {
+		reboot.ID = id
+		go s.executeHostReboot(&reboot)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"id":            id,
+		"scheduled_for": reboot.ScheduledFor,
+	}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+func (s *Server) handleCancelHostReboot(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	latest, err := database.GetLatestHostReboot()
+	if err != nil {
+		logging.Errorf("Failed to get last reboot: %v", err)
+		http.Error(w, "Failed to cancel reboot", http.StatusInternalServerError)
+		return
+	}
+	if latest == nil || latest.Status != database.RebootStatusScheduled {
+		http.Error(w, "No reboot is scheduled", http.StatusNotFound)
+		return
+	}
+
+	s.setHostRebootStatus(latest.ID, database.RebootStatusCancelled, "Cancelled by "+user.Username)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
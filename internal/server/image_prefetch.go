@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/maintenance"
+	"github.com/ontree-co/treeos/internal/templates"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// prefetchCheckInterval is how often the prefetcher looks for images whose window opened
+	prefetchCheckInterval = time.Minute
+	// prefetchPullTimeout bounds the pull of a single image
+	prefetchPullTimeout = 30 * time.Minute
+	// prefetchWindowMaintenance pulls in the configured maintenance window
+	prefetchWindowMaintenance = "maintenance"
+)
+
+// ImagePrefetchResponse is returned by GET /api/images/prefetch
+type ImagePrefetchResponse struct {
+	MaintenanceWindow string                   `json:"maintenance_window"`
+	Prefetches        []database.ImagePrefetch `json:"prefetches"`
+}
+
+// parsePrefetchWindow validates the window of a prefetch request: empty for as soon as
+// possible, "maintenance" or a window such as "01:00-05:00"
+func parsePrefetchWindow(window string) (string, error) {
+	window = strings.TrimSpace(window)
+	if window == "" || window == prefetchWindowMaintenance {
+		return window, nil
+	}
+	w, err := maintenance.Parse(window)
+	if err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+// prefetchWindowOpen reports whether images with the given window may be pulled now
+func (s *Server) prefetchWindowOpen(window string, now time.Time) bool {
+	switch window {
+	case "":
+		return true
+	case prefetchWindowMaintenance:
+		return s.maintenanceWindow().Contains(now)
+	}
+	w, err := maintenance.Parse(window)
+	return err == nil && w.In(s.location()).Contains(now)
+}
+
+// startImagePrefetcher pulls scheduled images once their window opens
+func (s *Server) startImagePrefetcher() {
+	if s.db == nil {
+		return
+	}
+	if err := database.ResetInterruptedImagePrefetches(); err != nil {
+		logging.Errorf("Failed to reset image prefetches: %v", err)
+	}
+
+	go func() {
+		s.runDuePrefetches()
+
+		ticker := time.NewTicker(prefetchCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.runDuePrefetches()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// runDuePrefetches pulls scheduled images one after another while their window is open
+func (s *Server) runDuePrefetches() {
+	if !s.prefetchMu.TryLock() {
+		return // Already pulling
+	}
+	defer s.prefetchMu.Unlock()
+
+	prefetches, err := database.ListImagePrefetches()
+	if err != nil {
+		logging.Errorf("Failed to list image prefetches: %v", err)
+		return
+	}
+	for _, prefetch := range prefetches {
+		if prefetch.Status != database.PrefetchStatusScheduled || !s.prefetchWindowOpen(prefetch.Window, time.Now()) {
+			continue
+		}
+		claimed, err := database.ClaimImagePrefetch(prefetch.ID)
+		if err != nil {
+			logging.Errorf("Failed to start prefetch of %s: %v", prefetch.Image, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		pullErr := s.pullImage(prefetch.Image)
+		if pullErr != nil {
+			logging.Errorf("Prefetch of %s failed: %v", prefetch.Image, pullErr)
+		} else {
+			logging.Infof("Prefetched image %s for %s", prefetch.Image, prefetch.Source)
+		}
+		if err := database.FinishImagePrefetch(prefetch.ID, pullErr); err != nil {
+			logging.Errorf("Failed to record prefetch of %s: %v", prefetch.Image, err)
+		}
+	}
+}
+
+func (s *Server) pullImage(image string) error {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return fmt.Errorf("compose service not available: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), prefetchPullTimeout)
+	defer cancel()
+	return composeSvc.PullImage(ctx, image)
+}
+
+// templateImages returns the images a template installs with its default settings
+func (s *Server) templateImages(templateID string) ([]string, error) {
+	template, err := s.templateSvc.GetTemplateByID(templateID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.templateSvc.GetTemplateContent(template)
+	if err != nil {
+		return nil, err
+	}
+	if content, _, err = renderDefaultVariables(template, content, ""); err != nil {
+		return nil, err
+	}
+	return compose.Images([]byte(content), nil)
+}
+
+// appImagesToPrefetch returns the images an installed app uses, so newer versions of its
+// tags can be pulled before the app is recreated
+func (s *Server) appImagesToPrefetch(appName string) ([]string, error) {
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	content, err := os.ReadFile(filepath.Join(appDir, "docker-compose.yml")) //nolint:gosec // App name is validated
+	if err != nil {
+		return nil, err
+	}
+	env, err := compose.ReadEnvFile(filepath.Join(appDir, ".env"))
+	if err != nil {
+		return nil, err
+	}
+	return compose.Images(content, env)
+}
+
+// templatePrefetchStates summarizes the prefetch state of each template's images for the
+// templates page: done when all are pulled, otherwise the state of the first pending image
+func (s *Server) templatePrefetchStates(templateIDs []string) map[string]string {
+	prefetches, err := database.ListImagePrefetches()
+	if err != nil {
+		logging.Errorf("Failed to list image prefetches: %v", err)
+		return nil
+	}
+	status := make(map[string]string, len(prefetches))
+	for _, prefetch := range prefetches {
+		status[prefetch.Image] = prefetch.Status
+	}
+
+	states := make(map[string]string, len(templateIDs))
+	for _, id := range templateIDs {
+		images, err := s.templateImages(id)
+		if err != nil || len(images) == 0 {
+			continue
+		}
+		state := database.PrefetchStatusDone
+		for _, image := range images {
+			if status[image] != database.PrefetchStatusDone {
+				state = status[image]
+				break
+			}
+		}
+		if state != "" {
+			states[id] = state
+		}
+	}
+	return states
+}
+
+// handleImagePrefetch handles /api/images/prefetch:
+// GET lists prefetched images and POST schedules the images of templates and apps
+func (s *Server) handleImagePrefetch(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		prefetches, err := database.ListImagePrefetches()
+		if err != nil {
+			logging.Errorf("Failed to list image prefetches: %v", err)
+			http.Error(w, "Failed to list image prefetches", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ImagePrefetchResponse{
+			MaintenanceWindow: s.maintenanceWindow().String(),
+			Prefetches:        prefetches,
+		}); err != nil {
+			logging.Errorf("Failed to encode image prefetches: %v", err)
+		}
+	case http.MethodPost:
+		s.handleScheduleImagePrefetch(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleScheduleImagePrefetch(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Templates []string `json:"templates"`
+		Apps      []string `json:"apps"`
+		Window    string   `json:"window"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Templates) == 0 && len(req.Apps) == 0 {
+		http.Error(w, "Select at least one template or app", http.StatusBadRequest)
+		return
+	}
+	window, err := parsePrefetchWindow(req.Window)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid window: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sources := make(map[string][]string) // Source to images
+	for _, id := range req.Templates {
+		images, err := s.templateImages(id)
+		if errors.Is(err, templates.ErrUnsupportedArchitecture) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Template %s not found", id), http.StatusNotFound)
+			return
+		}
+		sources["template:"+id] = images
+	}
+	for _, appName := range req.Apps {
+		if !isValidAppName(appName) {
+			http.Error(w, "Invalid app name", http.StatusBadRequest)
+			return
+		}
+		images, err := s.appImagesToPrefetch(appName)
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, fmt.Sprintf("App %s not found", appName), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read images of app %s: %v", appName, err), http.StatusBadRequest)
+			return
+		}
+		sources["app:"+appName] = images
+	}
+
+	var scheduled []string
+	for source, images := range sources {
+		for _, image := range images {
+			if err := database.ScheduleImagePrefetch(database.ImagePrefetch{
+				Image:       image,
+				Source:      source,
+				Window:      window,
+				RequestedBy: user.Username,
+			}); err != nil {
+				logging.Errorf("Failed to schedule prefetch of %s: %v", image, err)
+				http.Error(w, "Failed to schedule image prefetch", http.StatusInternalServerError)
+				return
+			}
+			scheduled = append(scheduled, image)
+		}
+	}
+	logging.Infof("User %s scheduled prefetch of %d images (window %q)", user.Username, len(scheduled), window)
+
+	if s.prefetchWindowOpen(window, time.Now()) {
+		go s.runDuePrefetches()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"images":  scheduled,
+	}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleImagePrefetchItem handles DELETE /api/images/prefetch/{id}, which removes an image
+// from the prefetch list or cancels its scheduled pull
+func (s *Server) handleImagePrefetchItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/images/prefetch/"))
+	if err != nil {
+		http.Error(w, "Invalid prefetch ID", http.StatusBadRequest)
+		return
+	}
+	deleted, err := database.DeleteImagePrefetch(id)
+	if err != nil {
+		logging.Errorf("Failed to delete image prefetch %d: %v", id, err)
+		http.Error(w, "Failed to delete image prefetch", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Prefetch not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
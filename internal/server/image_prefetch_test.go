@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/templates"
+)
+
+func TestParsePrefetchWindow(t *testing.T) {
+	for input, want := range map[string]string{
+		"":            "",
+		"maintenance": "maintenance",
+		" 1:00-5:00 ": "01:00-05:00",
+	} {
+		got, err := parsePrefetchWindow(input)
+		if err != nil || got != want {
+			t.Errorf("parsePrefetchWindow(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := parsePrefetchWindow("tonight"); err == nil {
+		t.Error("parsePrefetchWindow() accepted an invalid window")
+	}
+
+	s := &Server{config: &config.Config{MaintenanceWindow: "03:00-05:00"}}
+	at := time.Date(2026, 3, 2, 4, 0, 0, 0, time.Local)
+	if !s.prefetchWindowOpen("", at) || !s.prefetchWindowOpen("maintenance", at) || s.prefetchWindowOpen("22:00-23:00", at) {
+		t.Error("prefetchWindowOpen() does not follow the windows")
+	}
+}
+
+func TestScheduleImagePrefetch(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(tmpDir + "/test.db"); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	appDir := filepath.Join(tmpDir, "apps", "blog")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	compose := "services:\n  web:\n    image: ghost:${GHOST_VERSION:-5}\n"
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(compose), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, ".env"), []byte("GHOST_VERSION=5.80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		config:      &config.Config{AppsDir: filepath.Join(tmpDir, "apps")},
+		templateSvc: templates.NewService("."),
+	}
+	templateImages, err := s.templateImages("nginx-test")
+	if err != nil || len(templateImages) == 0 {
+		t.Fatalf("templateImages() = %v, %v", templateImages, err)
+	}
+
+	// A window that is closed for the next hours, so nothing is pulled during the test
+	start := time.Now().Add(2 * time.Hour)
+	window := fmt.Sprintf("%02d:00-%02d:00", start.Hour(), start.Add(time.Hour).Hour())
+	body := fmt.Sprintf(`{"templates":["nginx-test"],"apps":["blog"],"window":%q}`, window)
+	req := httptest.NewRequest(http.MethodPost, "/api/images/prefetch", strings.NewReader(body))
+	req = req.WithContext(setUserContext(req.Context(), &database.User{Username: "admin", IsStaff: true}))
+	rec := httptest.NewRecorder()
+	s.handleImagePrefetch(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("POST /api/images/prefetch = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	prefetches, err := database.ListImagePrefetches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefetches) != len(templateImages)+1 {
+		t.Fatalf("prefetches = %+v, want the template images and ghost:5.80", prefetches)
+	}
+	var ghost database.ImagePrefetch
+	for _, prefetch := range prefetches {
+		if prefetch.Status != database.PrefetchStatusScheduled || prefetch.Window != window || prefetch.RequestedBy != "admin" {
+			t.Errorf("prefetch = %+v, want scheduled for %s by admin", prefetch, window)
+		}
+		if prefetch.Image == "ghost:5.80" {
+			ghost = prefetch
+		}
+	}
+	if ghost.Source != "app:blog" {
+		t.Fatalf("prefetch of the app image = %+v", ghost)
+	}
+
+	// Pulling, then rescheduled: the running pull is kept
+	if claimed, err := database.ClaimImagePrefetch(ghost.ID); err != nil || !claimed {
+		t.Fatalf("ClaimImagePrefetch() = %v, %v", claimed, err)
+	}
+	if claimed, _ := database.ClaimImagePrefetch(ghost.ID); claimed {
+		t.Error("ClaimImagePrefetch() claimed a prefetch twice")
+	}
+	if err := database.ScheduleImagePrefetch(database.ImagePrefetch{Image: "ghost:5.80", Source: "app:blog"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.FinishImagePrefetch(ghost.ID, errors.New("registry unreachable")); err != nil {
+		t.Fatal(err)
+	}
+	if states := s.templatePrefetchStates([]string{"nginx-test"}); states["nginx-test"] != database.PrefetchStatusScheduled {
+		t.Errorf("templatePrefetchStates() = %v, want scheduled", states)
+	}
+
+	prefetches, _ = database.ListImagePrefetches()
+	for _, prefetch := range prefetches {
+		if prefetch.Image == "ghost:5.80" && (prefetch.Status != database.PrefetchStatusFailed || prefetch.Message != "registry unreachable") {
+			t.Errorf("failed prefetch = %+v", prefetch)
+		}
+		if prefetch.Image != "ghost:5.80" {
+			if err := database.FinishImagePrefetch(prefetch.ID, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if states := s.templatePrefetchStates([]string{"nginx-test"}); states["nginx-test"] != database.PrefetchStatusDone {
+		t.Errorf("templatePrefetchStates() = %v, want done", states)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/images/prefetch", nil)
+	rec = httptest.NewRecorder()
+	s.handleImagePrefetch(rec, req)
+	var resp ImagePrefetchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Prefetches) != len(prefetches) {
+		t.Errorf("GET /api/images/prefetch = %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/images/prefetch/%d", ghost.ID), nil)
+	req = req.WithContext(setUserContext(req.Context(), &database.User{Username: "admin", IsStaff: true}))
+	rec = httptest.NewRecorder()
+	s.handleImagePrefetchItem(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("DELETE /api/images/prefetch/%d = %d", ghost.ID, rec.Code)
+	}
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/registry"
+)
+
+const (
+	// imageUpdateStartDelay lets the server settle before the first check after a start
+	imageUpdateStartDelay = 5 * time.Minute
+	// imageUpdateCheckTimeout bounds the registry lookups for the images of one app
+	imageUpdateCheckTimeout = 2 * time.Minute
+)
+
+// ImageUpdatesResponse is returned by GET /api/apps/{appName}/image-updates
+type ImageUpdatesResponse struct {
+	Updates []database.ImageUpdate `json:"updates"`
+}
+
+// startImageUpdateChecker checks the registries for newer images of all apps every
+// ImageUpdateInterval
+func (s *Server) startImageUpdateChecker() {
+	if s.db == nil {
+		return
+	}
+	if err := database.ResetInterruptedAppUpgrades(); err != nil {
+		logging.Errorf("Failed to reset app upgrades: %v", err)
+	}
+	if s.config.ImageUpdateInterval <= 0 {
+		return
+	}
+
+	go func() {
+		select {
+		case <-time.After(imageUpdateStartDelay):
+			s.checkAllImageUpdates()
+		case <-s.stopCh:
+			return
+		}
+
+		ticker := time.NewTicker(s.config.ImageUpdateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.checkAllImageUpdates()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// checkAllImageUpdates checks the images of every app one after another
+func (s *Server) checkAllImageUpdates() {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		return
+	}
+	client := registry.NewClient()
+	for _, entry := range entries {
+		if !entry.IsDir() || !isValidAppName(entry.Name()) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, entry.Name(), "docker-compose.yml")); err != nil {
+			continue
+		}
+		if _, err := s.checkAppImageUpdates(client, entry.Name()); err != nil {
+			logging.Warnf("Failed to check image updates of app %s: %v", entry.Name(), err)
+		}
+	}
+}
+
+// registryImages returns the images of an app that come from a registry. Images of
+// services built from source only exist locally and are left out.
+func (s *Server) registryImages(appName string) ([]string, error) {
+	images, err := s.appImagesToPrefetch(appName)
+	if err != nil {
+		return nil, err
+	}
+	built := make(map[string]bool)
+	services, err := s.appBuildServices(appName)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services {
+		built[service.Image] = true
+	}
+	var pulled []string
+	for _, image := range images {
+		if !built[image] {
+			pulled = append(pulled, image)
+		}
+	}
+	return pulled, nil
+}
+
+// checkAppImageUpdates looks up the images of an app in their registries and stores the
+// results. Images pinned to a digest are skipped, they never change.
+func (s *Server) checkAppImageUpdates(client *registry.Client, appName string) ([]database.ImageUpdate, error) {
+	s.imageUpdateMu.Lock()
+	defer s.imageUpdateMu.Unlock()
+
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return nil, err
+	}
+	images, err := s.registryImages(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), imageUpdateCheckTimeout)
+	defer cancel()
+	updates := []database.ImageUpdate{}
+	for _, image := range images {
+		ref, err := registry.ParseReference(image)
+		if err != nil {
+			updates = append(updates, database.ImageUpdate{AppName: appName, Image: image, Error: err.Error()})
+			continue
+		}
+		if ref.Digest != "" {
+			continue
+		}
+		local, err := composeSvc.ImageDigests(ctx, image)
+		if err != nil {
+			logging.Warnf("Failed to get the digest of image %s: %v", image, err)
+		}
+		update := checkImageUpdate(ctx, client, ref, image, local)
+		update.AppName = appName
+		updates = append(updates, update)
+	}
+
+	if err := database.ReplaceImageUpdates(appName, updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// checkImageUpdate compares the local digests of an image, as listed by
+// compose.Service.ImageDigests, with the registry and looks for higher version tags
+func checkImageUpdate(ctx context.Context, client *registry.Client, ref registry.Reference, image string, local []string) database.ImageUpdate {
+	update := database.ImageUpdate{Image: image}
+	for _, repoDigest := range local {
+		if _, digest, ok := strings.Cut(repoDigest, "@"); ok {
+			update.CurrentDigest = digest
+			break
+		}
+	}
+
+	var errs []string
+	latest, err := client.Digest(ctx, ref)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	update.LatestDigest = latest
+	if latest != "" && update.CurrentDigest != "" {
+		// An image can be known under several digests, e.g. after a pull through a mirror
+		update.UpdateAvailable = true
+		for _, repoDigest := range local {
+			if strings.HasSuffix(repoDigest, "@"+latest) {
+				update.UpdateAvailable = false
+			}
+		}
+	}
+
+	if registry.IsVersionTag(ref.Tag) {
+		tags, err := client.Tags(ctx, ref)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		update.NewerTag = registry.NewerTag(ref.Tag, tags)
+	}
+	update.Error = strings.Join(errs, "; ")
+	return update
+}
+
+// appsWithImageUpdates returns the apps the registries have newer images for
+func (s *Server) appsWithImageUpdates() map[string]bool {
+	if database.GetDB() == nil {
+		return nil
+	}
+	apps, err := database.GetAppsWithImageUpdates()
+	if err != nil {
+		logging.Errorf("Failed to get image updates: %v", err)
+	}
+	return apps
+}
+
+// startAppImageUpdate upgrades an app to the latest images for its tags, with the
+// health check and rollback of POST /api/apps/{appName}/upgrade
+func (s *Server) startAppImageUpdate(appName, username string) error {
+	files, err := s.prepareAppUpgradeFiles(appName, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.startAppUpgrade(appName, username, files, upgradeHealthTimeout)
+	return err
+}
+
+// handleAPIAppImageUpdates handles /api/apps/{appName}/image-updates: GET returns the
+// results of the last registry check, POST pulls the images and recreates a running app
+// in the background like POST /api/apps/{appName}/upgrade. POST
+// /api/apps/{appName}/image-updates/check checks right away.
+func (s *Server) handleAPIAppImageUpdates(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	appName := r.PathValue("name")
+	check := strings.HasSuffix(r.URL.Path, "/image-updates/check")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName, "docker-compose.yml")); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	var updates []database.ImageUpdate
+	var err error
+	switch {
+	case r.Method == http.MethodGet && !check:
+		updates, err = database.GetImageUpdates(appName)
+	case r.Method == http.MethodPost && check:
+		updates, err = s.checkAppImageUpdates(registry.NewClient(), appName)
+		if errors.Is(err, errComposeUnavailable) {
+			http.Error(w, "Container runtime not available", http.StatusServiceUnavailable)
+			return
+		}
+	case r.Method == http.MethodPost:
+		err := s.startAppImageUpdate(appName, user.Username)
+		if errors.Is(err, errUpgradeRunning) {
+			http.Error(w, "Another upgrade is running", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logging.Errorf("Failed to start image update of app %s: %v", appName, err)
+			http.Error(w, fmt.Sprintf("Failed to start image update: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		logging.Errorf("Failed to get image updates of app %s: %v", appName, err)
+		http.Error(w, "Failed to get image updates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ImageUpdatesResponse{Updates: updates}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/registry"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// newTestRegistry serves the digest sha256:new for every tag of owner/app and the tags
+// 1.2 and 1.3
+func newTestRegistry(t *testing.T) (*httptest.Server, *registry.Client) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v2/owner/app/manifests/"):
+			w.Header().Set("Docker-Content-Digest", "sha256:new")
+		case r.URL.Path == "/v2/owner/app/tags/list":
+			fmt.Fprint(w, `{"tags": ["1.2", "1.3", "latest"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &registry.Client{HTTPClient: server.Client(), Insecure: true}
+}
+
+func TestCheckImageUpdate(t *testing.T) {
+	server, client := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	ctx := context.Background()
+
+	check := func(image string, local []string) database.ImageUpdate {
+		ref, err := registry.ParseReference(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return checkImageUpdate(ctx, client, ref, image, local)
+	}
+
+	if u := check(host+"/owner/app:1.2", []string{host + "/owner/app@sha256:old"}); !u.UpdateAvailable || u.CurrentDigest != "sha256:old" || u.LatestDigest != "sha256:new" || u.NewerTag != "1.3" || u.Error != "" {
+		t.Errorf("outdated image = %+v", u)
+	}
+	if u := check(host+"/owner/app:latest", []string{"mirror/app@sha256:old", host + "/owner/app@sha256:new"}); u.UpdateAvailable || u.NewerTag != "" {
+		t.Errorf("current image = %+v", u)
+	}
+	if u := check(host+"/owner/app:1.3", nil); u.UpdateAvailable || u.CurrentDigest != "" || u.NewerTag != "" {
+		t.Errorf("image that isn't pulled = %+v", u)
+	}
+	if u := check(host+"/other/app:1.0", nil); u.Error == "" || u.LatestDigest != "" {
+		t.Errorf("missing image = %+v", u)
+	}
+}
+
+func TestHandleAPIAppImageUpdates(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	server, client := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	appsDir := filepath.Join(tmpDir, "apps")
+	appDir := filepath.Join(appsDir, "blog")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	composeYAML := fmt.Sprintf(`services:
+  web:
+    image: %s/owner/app:1.2
+  worker:
+    build: .
+    image: blog-worker:latest
+  proxy:
+    image: nginx@sha256:abc
+`, host)
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte(composeYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	composeSvc, err := compose.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}, composeSvc: composeSvc, composeHealthy: true}
+
+	// Built and pinned images are skipped, the simulated runtime has no digests to compare
+	updates, err := s.checkAppImageUpdates(client, "blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].Image != host+"/owner/app:1.2" || updates[0].NewerTag != "1.3" || updates[0].UpdateAvailable {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if apps := s.appsWithImageUpdates(); !apps["blog"] {
+		t.Errorf("apps with updates = %v", apps)
+	}
+
+	admin := &database.User{Username: "admin", IsStaff: true}
+	api := func(method, target string, user *database.User) *httptest.ResponseRecorder {
+		req := newAppRequest(method, target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIAppImageUpdates(rec, req)
+		return rec
+	}
+	if rec := api(http.MethodGet, "/api/apps/blog/image-updates", &database.User{Username: "viewer"}); rec.Code != http.StatusForbidden {
+		t.Errorf("non-staff GET: status = %d", rec.Code)
+	}
+	if rec := api(http.MethodGet, "/api/apps/missing/image-updates", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("missing app: status = %d", rec.Code)
+	}
+	if rec := api(http.MethodGet, "/api/apps/blog/image-updates/check", admin); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET check: status = %d", rec.Code)
+	}
+
+	rec := api(http.MethodGet, "/api/apps/blog/image-updates", admin)
+	var response ImageUpdatesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET: status = %d, %v", rec.Code, err)
+	}
+	if len(response.Updates) != 1 || response.Updates[0].NewerTag != "1.3" {
+		t.Errorf("GET updates = %+v", response.Updates)
+	}
+}
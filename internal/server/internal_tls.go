@@ -0,0 +1,211 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/caddy"
+	"github.com/ontree-co/treeos/internal/internalca"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// internalTLSCheckInterval is how often the internal certificate is checked for renewal
+const internalTLSCheckInterval = 12 * time.Hour
+
+// internalTLSView is shown on the dashboard so users can install the CA
+type internalTLSView struct {
+	Domain      string
+	Fingerprint string
+}
+
+// initInternalCA loads or creates the internal CA when an internal domain is configured
+func (s *Server) initInternalCA() {
+	domain := s.config.InternalDomain
+	if domain == "" {
+		return
+	}
+	if err := caddy.ValidateDomain(domain); err != nil {
+		logging.Errorf("Internal TLS disabled, invalid internal domain: %v", err)
+		return
+	}
+	ca, err := internalca.LoadOrCreate(s.config.StatePath("ca"), "TreeOS Internal CA "+domain)
+	if err != nil {
+		logging.Errorf("Internal TLS disabled: %v", err)
+		return
+	}
+	s.internalCA = ca
+	logging.Infof("Internal CA for %s loaded, fingerprint %s", domain, ca.Fingerprint())
+}
+
+// internalTLS returns the dashboard view of the internal CA, nil when it is disabled
+func (s *Server) internalTLS() *internalTLSView {
+	if s.internalCA == nil {
+		return nil
+	}
+	return &internalTLSView{Domain: s.config.InternalDomain, Fingerprint: s.internalCA.Fingerprint()}
+}
+
+// internalHosts are the names the internal certificate covers: TreeOS at the internal
+// domain and apps below it
+func (s *Server) internalHosts() []string {
+	return []string{s.config.InternalDomain, "*." + s.config.InternalDomain}
+}
+
+// syncInternalTLS renews the internal certificate when needed and routes TreeOS and all
+// apps on the internal domain. Apps get a route whether they are exposed or not.
+func (s *Server) syncInternalTLS() {
+	if s.internalCA == nil || !s.caddyAvailable || s.caddyClient == nil {
+		return
+	}
+	if err := s.renewInternalCertificate(true); err != nil {
+		logging.Errorf("Failed to load internal certificate into Caddy: %v", err)
+		return
+	}
+
+	if s.config.ClientCertAuth {
+		// TreeOS serves HTTPS itself, Caddy can't pass client certificates on
+		if err := s.caddyClient.DeleteRoute(caddy.NodeInternalRouteID); err != nil {
+			logging.Errorf("Failed to remove the route of %s to TreeOS: %v", s.config.InternalDomain, err)
+		}
+	} else if port := listenPort(s.config.ListenAddr); port > 0 {
+		route := caddy.CreateInternalRouteConfig(caddy.NodeInternalRouteID, s.config.InternalDomain, port)
+		if err := s.replaceRoute(route); err != nil {
+			logging.Errorf("Failed to route %s to TreeOS: %v", s.config.InternalDomain, err)
+		}
+	}
+
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		logging.Errorf("Failed to list apps for internal routes: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(s.config.AppsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		s.syncInternalRoute(entry.Name(), metadata)
+	}
+}
+
+// renewInternalCertificate issues a new internal certificate when the current one is about
+// to expire and loads it into Caddy. force loads the current one again, e.g. after Caddy
+// restarted with an empty config.
+func (s *Server) renewInternalCertificate(force bool) error {
+	s.internalTLSMu.Lock()
+	defer s.internalTLSMu.Unlock()
+
+	hosts := s.internalHosts()
+	if !internalca.NeedsRenewal(s.internalCertPEM, hosts, time.Now()) && !force {
+		return nil
+	}
+	if internalca.NeedsRenewal(s.internalCertPEM, hosts, time.Now()) {
+		certPEM, keyPEM, err := s.internalCA.Issue(hosts)
+		if err != nil {
+			return err
+		}
+		s.internalCertPEM, s.internalKeyPEM = certPEM, keyPEM
+		logging.Infof("Issued internal certificate for %s", strings.Join(hosts, ", "))
+	}
+	return s.caddyClient.LoadInternalCertificate(s.internalCertPEM, s.internalKeyPEM)
+}
+
+// syncInternalRoute routes <subdomain>.<internal domain> to an app
+func (s *Server) syncInternalRoute(appName string, metadata *yamlutil.OnTreeMetadata) {
+	if s.internalCA == nil || !s.caddyAvailable || s.caddyClient == nil || metadata == nil || metadata.HostPort == 0 {
+		return
+	}
+	appID := strings.ToLower(appName)
+	subdomain := metadata.Subdomain
+	if subdomain == "" {
+		subdomain = appID
+	}
+	host := subdomain + "." + s.config.InternalDomain
+	if err := s.replaceRoute(caddy.CreateInternalRouteConfig(caddy.InternalRouteID(appID), host, metadata.HostPort)); err != nil {
+		logging.Errorf("Failed to route %s to app %s: %v", host, appName, err)
+	}
+}
+
+// removeInternalRoute removes the internal route of a deleted app
+func (s *Server) removeInternalRoute(appName string) {
+	if s.internalCA == nil || !s.caddyAvailable || s.caddyClient == nil {
+		return
+	}
+	if err := s.caddyClient.DeleteRoute(caddy.InternalRouteID(strings.ToLower(appName))); err != nil {
+		logging.Errorf("Failed to remove internal route of app %s: %v", appName, err)
+	}
+}
+
+// replaceRoute adds a route, removing an earlier one with the same ID so syncing twice
+// doesn't duplicate it
+func (s *Server) replaceRoute(route *caddy.RouteConfig) error {
+	if err := s.caddyClient.DeleteRoute(route.ID); err != nil {
+		return err
+	}
+	return s.caddyClient.AddOrUpdateRoute(route)
+}
+
+// startInternalTLSMonitor renews the internal certificate before it expires
+func (s *Server) startInternalTLSMonitor() {
+	if s.internalCA == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(internalTLSCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !s.caddyAvailable || s.caddyClient == nil {
+					continue
+				}
+				if err := s.renewInternalCertificate(false); err != nil {
+					logging.Errorf("Failed to renew internal certificate: %v", err)
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// handleCACertificate serves the internal CA certificate. It is public, clients need it
+// before they can trust the node.
+func (s *Server) handleCACertificate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.internalCA == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "treeos-ca-"+s.config.InternalDomain+".crt"))
+	if _, err := w.Write(s.internalCA.CertificatePEM()); err != nil {
+		logging.Errorf("Failed to write CA certificate: %v", err)
+	}
+}
+
+// listenPort returns the port of a listen address such as :3000 or 127.0.0.1:3000
+func listenPort(addr string) int {
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return 0
+	}
+	return port
+}
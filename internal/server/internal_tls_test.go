@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/internalca"
+)
+
+func TestHandleCACertificate(t *testing.T) {
+	s := &Server{config: &config.Config{InternalDomain: "mynode.lan"}}
+
+	rec := httptest.NewRecorder()
+	s.handleCACertificate(rec, httptest.NewRequest(http.MethodGet, "/ca.crt", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without internal CA status = %d, want 404", rec.Code)
+	}
+
+	ca, err := internalca.LoadOrCreate(t.TempDir(), "TreeOS Internal CA mynode.lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.internalCA = ca
+
+	rec = httptest.NewRecorder()
+	s.handleCACertificate(rec, httptest.NewRequest(http.MethodGet, "/ca.crt", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "-----BEGIN CERTIFICATE-----") {
+		t.Errorf("status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "treeos-ca-mynode.lan.crt") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+}
+
+func TestListenPort(t *testing.T) {
+	cases := map[string]int{":3000": 3000, "127.0.0.1:4000": 4000, "[::1]:8080": 8080, "3000": 0}
+	for addr, want := range cases {
+		if got := listenPort(addr); got != want {
+			t.Errorf("listenPort(%q) = %d, want %d", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// Lifetimes and uses admins can choose for an invite
+	defaultInviteLifetime = 7 * 24 * time.Hour
+	maxInviteLifetime     = 30 * 24 * time.Hour
+	maxInviteUses         = 50
+
+	// Requests to /invite/ a client IP may make per window, so tokens can't be guessed
+	inviteRateLimit  = 10
+	inviteRateWindow = 15 * time.Minute
+
+	// inviteEventLimit is the number of audit log entries shown in the settings
+	inviteEventLimit = 50
+)
+
+// usernamePattern is what invited users may choose as username
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,31}$`)
+
+// attemptWindow counts the attempts of a client IP since start
+type attemptWindow struct {
+	start time.Time
+	count int
+}
+
+// countAttempt counts an attempt of ip in attempts and reports whether it is within limit
+// attempts per window. The caller holds the lock guarding attempts.
+func countAttempt(attempts map[string]*attemptWindow, ip string, now time.Time, limit int, window time.Duration) bool {
+	for key, w := range attempts {
+		if now.Sub(w.start) >= window {
+			delete(attempts, key)
+		}
+	}
+	w := attempts[ip]
+	if w == nil {
+		w = &attemptWindow{start: now}
+		attempts[ip] = w
+	}
+	w.count++
+	return w.count <= limit
+}
+
+// inviteAllowed counts a request of ip to /invite/ and reports whether it is within the
+// rate limit
+func (s *Server) inviteAllowed(ip string, now time.Time) bool {
+	s.inviteMu.Lock()
+	defer s.inviteMu.Unlock()
+
+	if s.inviteAttempts == nil {
+		s.inviteAttempts = make(map[string]*attemptWindow)
+	}
+	return countAttempt(s.inviteAttempts, ip, now, inviteRateLimit, inviteRateWindow)
+}
+
+// hashToken returns what is stored of an invite or API token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// inviteValid reports whether an invite can still be redeemed
+func inviteValid(invite *database.Invite, now time.Time) bool {
+	return invite != nil && invite.RevokedAt == nil && invite.Uses < invite.MaxUses && now.Before(invite.ExpiresAt)
+}
+
+// recordInviteEvent adds an entry to the invite audit log
+func recordInviteEvent(r *http.Request, inviteID int, action, username string) {
+	if err := database.AddInviteEvent(database.InviteEvent{
+		InviteID:  inviteID,
+		Action:    action,
+		Username:  username,
+		IPAddress: clientIP(r),
+	}); err != nil {
+		logging.Errorf("Failed to record invite event: %v", err)
+	}
+}
+
+// handleAPIInvites handles /api/invites: GET lists invites and the audit log, POST creates
+// an invite and returns its link once, DELETE ?id= revokes one
+func (s *Server) handleAPIInvites(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !s.config.InvitesEnabled {
+		http.Error(w, "Invites are disabled", http.StatusConflict)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Role           string `json:"role"`
+			Namespace      string `json:"namespace"`
+			MaxUses        int    `json:"max_uses"`
+			ExpiresInHours int    `json:"expires_in_hours"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		invite := database.Invite{Role: req.Role, Namespace: strings.TrimSpace(req.Namespace), MaxUses: req.MaxUses, CreatedBy: user.Username}
+		switch invite.Role {
+		case "":
+			invite.Role = database.InviteRoleUser
+		case database.InviteRoleUser:
+		case database.InviteRoleStaff, database.InviteRoleAdmin:
+			if !user.IsSuperuser {
+				http.Error(w, "Only superusers can invite admins", http.StatusForbidden)
+				return
+			}
+		default:
+			http.Error(w, "Role must be user, staff or admin", http.StatusBadRequest)
+			return
+		}
+		if invite.MaxUses == 0 {
+			invite.MaxUses = 1
+		}
+		if invite.MaxUses < 1 || invite.MaxUses > maxInviteUses {
+			http.Error(w, "Uses must be between 1 and "+strconv.Itoa(maxInviteUses), http.StatusBadRequest)
+			return
+		}
+		lifetime := time.Duration(req.ExpiresInHours) * time.Hour
+		if lifetime == 0 {
+			lifetime = defaultInviteLifetime
+		}
+		if lifetime < time.Hour || lifetime > maxInviteLifetime {
+			http.Error(w, "Invites expire after 1 hour to 30 days", http.StatusBadRequest)
+			return
+		}
+		invite.ExpiresAt = time.Now().Add(lifetime)
+		if invite.Namespace != "" {
+			ns, err := database.GetNamespace(invite.Namespace)
+			if err != nil {
+				logging.Errorf("Failed to get namespace: %v", err)
+				http.Error(w, "Failed to create invite", http.StatusInternalServerError)
+				return
+			}
+			if ns == nil {
+				http.Error(w, "Namespace '"+invite.Namespace+"' not found", http.StatusNotFound)
+				return
+			}
+		}
+
+		token := rand.Text()
+		if err := database.CreateInvite(&invite, hashToken(token)); err != nil {
+			logging.Errorf("Failed to create invite: %v", err)
+			http.Error(w, "Failed to create invite", http.StatusInternalServerError)
+			return
+		}
+		recordInviteEvent(r, invite.ID, database.InviteEventCreated, user.Username)
+		logging.Infof("User %s created invite #%d for the %s role, %d uses", user.Username, invite.ID, invite.Role, invite.MaxUses)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"invite": invite,
+			"path":   "/invite/" + token,
+		}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+		return
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid invite id", http.StatusBadRequest)
+			return
+		}
+		revoked, err := database.RevokeInvite(id)
+		if err != nil {
+			logging.Errorf("Failed to revoke invite: %v", err)
+			http.Error(w, "Failed to revoke invite", http.StatusInternalServerError)
+			return
+		}
+		if !revoked {
+			http.Error(w, "Invite not found", http.StatusNotFound)
+			return
+		}
+		recordInviteEvent(r, id, database.InviteEventRevoked, user.Username)
+		logging.Infof("User %s revoked invite #%d", user.Username, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	invites, err := database.GetInvites()
+	if err != nil {
+		logging.Errorf("Failed to get invites: %v", err)
+		http.Error(w, "Failed to get invites", http.StatusInternalServerError)
+		return
+	}
+	events, err := database.GetInviteEvents(inviteEventLimit)
+	if err != nil {
+		logging.Errorf("Failed to get invite events: %v", err)
+		http.Error(w, "Failed to get invites", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"invites": invites, "events": events}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleInvite serves /invite/{token}, where invited people create their account
+func (s *Server) handleInvite(w http.ResponseWriter, r *http.Request) {
+	if !s.config.InvitesEnabled {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.inviteAllowed(clientIP(r), time.Now()) {
+		logging.Warnf("Invite requests from %s are rate limited", clientIP(r))
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	data := s.baseTemplateData(nil)
+	invite, err := database.GetInviteByTokenHash(hashToken(strings.TrimPrefix(r.URL.Path, "/invite/")))
+	if err != nil {
+		logging.Errorf("Failed to get invite: %v", err)
+		http.Error(w, "Failed to get invite", http.StatusInternalServerError)
+		return
+	}
+	if !inviteValid(invite, time.Now()) {
+		inviteID := 0
+		if invite != nil {
+			inviteID = invite.ID
+		}
+		recordInviteEvent(r, inviteID, database.InviteEventRejected, "")
+		data["Invalid"] = true
+		s.renderInvite(w, http.StatusNotFound, data)
+		return
+	}
+	data["Invite"] = invite
+
+	if r.Method != http.MethodPost {
+		s.renderInvite(w, http.StatusOK, data)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+	data["Username"] = username
+
+	var errs []string
+	if !usernamePattern.MatchString(username) {
+		errs = append(errs, "Usernames are up to 32 letters, digits, dots, dashes and underscores")
+	}
+	if len(password) < 8 {
+		errs = append(errs, "Password must be at least 8 characters long")
+	}
+	if password != r.FormValue("password2") {
+		errs = append(errs, "Passwords do not match")
+	}
+	if len(errs) > 0 {
+		data["Errors"] = errs
+		s.renderInvite(w, http.StatusOK, data)
+		return
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		logging.Errorf("Failed to hash password: %v", err)
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+	user := &database.User{Username: username, Password: hashedPassword}
+	err = database.RedeemInvite(invite.ID, user, time.Now())
+	switch {
+	case errors.Is(err, database.ErrUsernameTaken):
+		data["Errors"] = []string{"The username " + username + " is taken"}
+		s.renderInvite(w, http.StatusOK, data)
+		return
+	case errors.Is(err, database.ErrInviteInvalid):
+		recordInviteEvent(r, invite.ID, database.InviteEventRejected, username)
+		delete(data, "Invite")
+		data["Invalid"] = true
+		s.renderInvite(w, http.StatusNotFound, data)
+		return
+	case err != nil:
+		logging.Errorf("Failed to redeem invite #%d: %v", invite.ID, err)
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+	recordInviteEvent(r, invite.ID, database.InviteEventRedeemed, username)
+	logging.Infof("User %s joined with invite #%d of %s", username, invite.ID, invite.CreatedBy)
+
+	session, err := s.sessionStore.Get(r, "ontree-session")
+	if err != nil {
+		logging.Errorf("Failed to get session: %v", err)
+	}
+	if err := s.startSession(session, user, false); err != nil {
+		logging.Errorf("Failed to start session of %s: %v", username, err)
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	if err := session.Save(r, w); err != nil {
+		logging.Errorf("Failed to save session: %v", err)
+	}
+	s.recordLogin(r, user)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (s *Server) renderInvite(w http.ResponseWriter, status int, data map[string]interface{}) {
+	tmpl, ok := s.templates["invite"]
+	if !ok {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		logging.Errorf("Error rendering invite template: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestInvites(t *testing.T) {
+	tmp := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmp, "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{
+		config:       &config.Config{InvitesEnabled: true},
+		sessionStore: sessions.NewCookieStore([]byte("test-session-key-0123456789abcdef")),
+		templates:    make(map[string]*template.Template),
+	}
+	if err := s.loadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	staff := &database.User{ID: 1, Username: "staff", IsStaff: true}
+	viewer := &database.User{ID: 2, Username: "viewer"}
+
+	api := func(method, target string, body string, user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIInvites(rec, req)
+		return rec
+	}
+
+	if rec := api(http.MethodPost, "/api/invites", `{"role": "admin"}`, staff); rec.Code != http.StatusForbidden {
+		t.Errorf("staff inviting an admin: status = %d", rec.Code)
+	}
+	if rec := api(http.MethodPost, "/api/invites", `{"namespace": "missing"}`, staff); rec.Code != http.StatusNotFound {
+		t.Errorf("invite to missing namespace: status = %d", rec.Code)
+	}
+	if rec := api(http.MethodGet, "/api/invites", "", viewer); rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-staff GET status = %d", rec.Code)
+	}
+
+	rec := api(http.MethodPost, "/api/invites", `{"max_uses": 1}`, staff)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		Invite database.Invite `json:"invite"`
+		Path   string          `json:"path"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Path, "/invite/") || created.Invite.Role != database.InviteRoleUser {
+		t.Fatalf("created = %+v", created)
+	}
+
+	join := func(method, path string, form url.Values, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		s.handleInvite(rec, req)
+		return rec
+	}
+
+	if rec := join(http.MethodGet, "/invite/wrong", nil, "192.0.2.1"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown token: status = %d", rec.Code)
+	}
+	if rec := join(http.MethodGet, created.Path, nil, "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Errorf("GET invite: status = %d", rec.Code)
+	}
+	form := url.Values{"username": {"alice"}, "password": {"correct horse"}, "password2": {"correct horse"}}
+	rec = join(http.MethodPost, created.Path, form, "192.0.2.1")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("POST invite: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec.Result().Cookies() == nil {
+		t.Error("no session was started")
+	}
+	if rec := join(http.MethodPost, created.Path, url.Values{"username": {"bob"}, "password": {"correct horse"}, "password2": {"correct horse"}}, "192.0.2.1"); rec.Code != http.StatusNotFound {
+		t.Errorf("used up invite: status = %d", rec.Code)
+	}
+
+	rec = api(http.MethodGet, "/api/invites", "", staff)
+	var listed struct {
+		Invites []database.Invite      `json:"invites"`
+		Events  []database.InviteEvent `json:"events"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range listed.Events {
+		actions = append(actions, e.Action)
+	}
+	// Newest first: the used up invite, the redemption, the unknown token and the creation
+	want := "rejected,redeemed,rejected,created"
+	if got := strings.Join(actions, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+
+	s.config.InvitesEnabled = false
+	if rec := join(http.MethodGet, created.Path, nil, "192.0.2.1"); rec.Code != http.StatusNotFound {
+		t.Errorf("disabled invites: status = %d", rec.Code)
+	}
+	if rec := api(http.MethodGet, "/api/invites", "", staff); rec.Code != http.StatusConflict {
+		t.Errorf("disabled invites API: status = %d", rec.Code)
+	}
+}
+
+func TestInviteAllowed(t *testing.T) {
+	s := &Server{}
+	now := time.Now()
+	for i := 0; i < inviteRateLimit; i++ {
+		if !s.inviteAllowed("192.0.2.1", now) {
+			t.Fatalf("request %d was rate limited", i+1)
+		}
+	}
+	if s.inviteAllowed("192.0.2.1", now) {
+		t.Error("request over the limit was allowed")
+	}
+	if !s.inviteAllowed("192.0.2.2", now) {
+		t.Error("another client was rate limited")
+	}
+	if !s.inviteAllowed("192.0.2.1", now.Add(inviteRateWindow)) {
+		t.Error("client is still limited after the window")
+	}
+}
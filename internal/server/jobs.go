@@ -0,0 +1,317 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/ollama"
+	"github.com/ontree-co/treeos/internal/progress"
+)
+
+// Job kinds
+const (
+	jobKindApp   = "app"   // Starting an app, including image pulls of a template install
+	jobKindModel = "model" // Downloading an Ollama model
+)
+
+// Job states
+const (
+	jobStateIdle      = "idle"
+	jobStateQueued    = "queued"
+	jobStateRunning   = "running"
+	jobStatePaused    = "paused"
+	jobStateCompleted = "completed"
+	jobStateFailed    = "failed"
+)
+
+const (
+	// jobWaitMax limits how long GET /api/jobs/{kind}/{name}?wait= blocks
+	jobWaitMax = 10 * time.Minute
+	// jobWaitFallback is used when the write deadline can't be extended for a long wait
+	jobWaitFallback = 25 * time.Second
+	jobPollInterval = 500 * time.Millisecond
+	// jobsSSEChannel is the SSE channel that receives updates of all jobs
+	jobsSSEChannel = "jobs"
+)
+
+// Job is the common progress view of app operations and model downloads, so scripts can
+// wait for either the same way
+type Job struct {
+	ID        string    `json:"id"` // <kind>/<name>
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Progress  float64   `json:"progress"` // 0-100
+	Message   string    `json:"message,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Done      bool      `json:"done"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newJob(kind, name, state string) Job {
+	return Job{
+		ID:    kind + "/" + name,
+		Kind:  kind,
+		Name:  name,
+		State: state,
+		Done:  state == jobStateIdle || state == jobStatePaused || state == jobStateCompleted || state == jobStateFailed,
+	}
+}
+
+func jobFromAppProgress(info *progress.AppProgress) Job {
+	state := jobStateRunning
+	switch info.Operation {
+	case progress.OperationComplete:
+		state = jobStateCompleted
+	case progress.OperationError:
+		state = jobStateFailed
+	}
+	job := newJob(jobKindApp, info.AppName, state)
+	job.Progress = info.OverallProgress
+	job.Message = info.Message
+	job.Error = info.Error
+	job.UpdatedAt = info.LastUpdate
+	return job
+}
+
+func jobFromModel(model *ollama.OllamaModel) Job {
+	state := jobStateIdle
+	switch model.Status {
+	case ollama.StatusQueued:
+		state = jobStateQueued
+	case ollama.StatusDownloading:
+		state = jobStateRunning
+	case ollama.StatusPaused:
+		state = jobStatePaused
+	case ollama.StatusCompleted:
+		state = jobStateCompleted
+	case ollama.StatusFailed:
+		state = jobStateFailed
+	}
+	job := newJob(jobKindModel, model.Name, state)
+	job.Progress = float64(model.Progress)
+	job.Message = formatStatusText(model.Status)
+	job.Error = model.LastError.String
+	job.UpdatedAt = model.UpdatedAt
+	return job
+}
+
+// lookupJob returns the current job of an app or model. Apps without a tracked operation and
+// models that were never downloaded are idle. found is false for unknown models.
+func (s *Server) lookupJob(kind, name string) (job Job, found bool, err error) {
+	switch kind {
+	case jobKindApp:
+		if s.progressTracker != nil {
+			if info, exists := s.progressTracker.GetProgress(name); exists {
+				return jobFromAppProgress(info), true, nil
+			}
+		}
+		return newJob(jobKindApp, name, jobStateIdle), true, nil
+	case jobKindModel:
+		if s.db == nil {
+			return Job{}, false, fmt.Errorf("database not initialized")
+		}
+		model, err := ollama.GetModel(s.db, name)
+		if err != nil || model == nil {
+			return Job{}, false, err
+		}
+		return jobFromModel(model), true, nil
+	default:
+		return Job{}, false, nil
+	}
+}
+
+// listJobs returns tracked app operations and model downloads that are queued or running
+func (s *Server) listJobs() ([]Job, error) {
+	jobs := []Job{}
+	if s.progressTracker != nil {
+		for _, info := range s.progressTracker.ListActiveOperations() {
+			jobs = append(jobs, jobFromAppProgress(info))
+		}
+	}
+	if s.db != nil {
+		models, err := ollama.GetAllModels(s.db)
+		if err != nil {
+			return nil, err
+		}
+		for i := range models {
+			if models[i].Status == ollama.StatusQueued || models[i].Status == ollama.StatusDownloading {
+				jobs = append(jobs, jobFromModel(&models[i]))
+			}
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+// publishJob sends a job update to the clients of /api/jobs/events
+func (s *Server) publishJob(job Job) {
+	if s.sseManager == nil {
+		return
+	}
+	s.sseManager.BroadcastMessage(jobsSSEChannel, map[string]interface{}{
+		"type": "job",
+		"job":  job,
+	})
+}
+
+// broadcastAppProgress sends an app's progress to its progress stream and the jobs stream.
+// Finished operations are added to the activity feed.
+func (s *Server) broadcastAppProgress(appName, eventType string) {
+	if s.progressTracker == nil {
+		return
+	}
+	progressInfo, exists := s.progressTracker.GetProgress(appName)
+	if !exists {
+		return
+	}
+	if eventType == "complete" || eventType == "error" {
+		recordAppJobActivity(progressInfo)
+	}
+	if s.sseManager == nil {
+		return
+	}
+	s.sseManager.BroadcastMessage("app-progress-"+appName, map[string]interface{}{
+		"type":     eventType,
+		"progress": progressInfo,
+	})
+	s.publishJob(jobFromAppProgress(progressInfo))
+}
+
+// routeAPIJobs handles /api/jobs, /api/jobs/events and /api/jobs/{kind}/{name}
+func (s *Server) routeAPIJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs"), "/")
+	switch {
+	case path == "":
+		jobs, err := s.listJobs()
+		if err != nil {
+			logging.Errorf("Failed to list jobs: %v", err)
+			http.Error(w, "Failed to list jobs", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"jobs": jobs}); err != nil {
+			logging.Errorf("Failed to encode jobs: %v", err)
+		}
+	case path == "events":
+		s.handleAPIJobsSSE(w, r)
+	default:
+		kind, name, ok := strings.Cut(path, "/")
+		if !ok || name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		s.handleAPIJob(w, r, kind, name)
+	}
+}
+
+// handleAPIJob returns a job. With ?wait=<duration> it blocks until the job is done or the
+// duration has passed, so scripts don't have to poll.
+func (s *Server) handleAPIJob(w http.ResponseWriter, r *http.Request, kind, name string) {
+	var wait time.Duration
+	if value := r.URL.Query().Get("wait"); value != "" {
+		var err error
+		if wait, err = time.ParseDuration(value); err != nil || wait < 0 {
+			http.Error(w, "wait must be a duration like 30s or 5m", http.StatusBadRequest)
+			return
+		}
+		if wait > jobWaitMax {
+			wait = jobWaitMax
+		}
+		// The server's write timeout would cut long waits short
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 10*time.Second)); err != nil && wait > jobWaitFallback {
+			wait = jobWaitFallback
+		}
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		job, found, err := s.lookupJob(kind, name)
+		if err != nil {
+			logging.Errorf("Failed to look up job %s/%s: %v", kind, name, err)
+			http.Error(w, "Failed to look up job", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		if job.Done || !time.Now().Before(deadline) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(job); err != nil {
+				logging.Errorf("Failed to encode job: %v", err)
+			}
+			return
+		}
+
+		select {
+		case <-time.After(jobPollInterval):
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleAPIModelProgress handles GET /api/models/{name}/progress
+func (s *Server) handleAPIModelProgress(w http.ResponseWriter, r *http.Request, modelName string) {
+	s.handleAPIJob(w, r, jobKindModel, modelName)
+}
+
+// jobsConnectedEvent starts the stream of job updates
+const jobsConnectedEvent = "event: connected\ndata: {\"message\": \"Connected to job updates\"}\n\n"
+
+// handleAPIJobsSSE streams updates of all jobs as "job" events
+func (s *Server) handleAPIJobsSSE(w http.ResponseWriter, r *http.Request) {
+	if isSSEPoll(r) {
+		s.serveSSEPoll(w, r, jobsSSEChannel, func() []string { return []string{jobsConnectedEvent} })
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	client := &SSEClient{
+		AppID:    jobsSSEChannel,
+		Messages: make(chan string, s.sseBufferSize()),
+		Close:    make(chan bool, 1),
+	}
+	s.sseManager.RegisterClient(jobsSSEChannel, client)
+	defer s.sseManager.UnregisterClient(jobsSSEChannel, client)
+
+	fmt.Fprint(w, jobsConnectedEvent) //nolint:errcheck // SSE stream
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case message := <-client.Messages:
+			fmt.Fprint(w, message) //nolint:errcheck // SSE stream
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprintf(w, "event: heartbeat\ndata: ping\n\n") //nolint:errcheck // SSE stream
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-client.Close:
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/ollama"
+	"github.com/ontree-co/treeos/internal/progress"
+)
+
+func getJob(t *testing.T, s *Server, url string) (int, Job) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.routeAPIJobs(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+	var job Job
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("Failed to decode job: %v", err)
+		}
+	}
+	return rec.Code, job
+}
+
+func TestAPIJobApp(t *testing.T) {
+	s := &Server{progressTracker: progress.NewTracker()}
+
+	code, job := getJob(t, s, "/api/jobs/app/nextcloud")
+	if code != http.StatusOK || job.State != jobStateIdle || !job.Done {
+		t.Errorf("idle app job = %d %+v", code, job)
+	}
+
+	s.progressTracker.StartOperation("nextcloud", progress.OperationDownloading, "Pulling images")
+	code, job = getJob(t, s, "/api/jobs/app/nextcloud")
+	if code != http.StatusOK || job.State != jobStateRunning || job.Done {
+		t.Errorf("running app job = %d %+v", code, job)
+	}
+
+	// A wait returns as soon as the operation completes
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s.progressTracker.CompleteOperation("nextcloud", "Started")
+	}()
+	start := time.Now()
+	code, job = getJob(t, s, "/api/jobs/app/nextcloud?wait=10s")
+	if code != http.StatusOK || job.State != jobStateCompleted || !job.Done || job.Progress != 100 {
+		t.Errorf("completed app job = %d %+v", code, job)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("wait took %v, expected it to return on completion", time.Since(start))
+	}
+
+	// A wait gives up after the duration and returns the running job
+	s.progressTracker.StartOperation("immich", progress.OperationStarting, "Starting")
+	code, job = getJob(t, s, "/api/jobs/app/immich?wait=600ms")
+	if code != http.StatusOK || job.Done {
+		t.Errorf("timed out wait = %d %+v", code, job)
+	}
+}
+
+func TestAPIJobErrors(t *testing.T) {
+	s := &Server{progressTracker: progress.NewTracker()}
+
+	if code, _ := getJob(t, s, "/api/jobs/app/nextcloud?wait=soon"); code != http.StatusBadRequest {
+		t.Errorf("invalid wait status = %d, want 400", code)
+	}
+	if code, _ := getJob(t, s, "/api/jobs/unknown/thing"); code != http.StatusNotFound {
+		t.Errorf("unknown kind status = %d, want 404", code)
+	}
+	if code, _ := getJob(t, s, "/api/jobs/app"); code != http.StatusNotFound {
+		t.Errorf("missing name status = %d, want 404", code)
+	}
+}
+
+func TestJobFromPausedModel(t *testing.T) {
+	job := jobFromModel(&ollama.OllamaModel{Name: "llama3:8b", Status: ollama.StatusPaused, Progress: 40})
+	if job.State != jobStatePaused || !job.Done || job.Progress != 40 || job.Message != "Paused" {
+		t.Errorf("paused model job = %+v", job)
+	}
+}
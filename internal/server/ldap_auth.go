@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/ldap"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// ldapTimeout bounds a login against the directory
+const ldapTimeout = 10 * time.Second
+
+// ldapPasswordHash is stored for directory users. It is no bcrypt hash, so local password
+// checks always fail for them.
+const ldapPasswordHash = "!ldap"
+
+// ldapEnabled reports whether logins are checked against an LDAP directory
+func (s *Server) ldapEnabled() bool {
+	return s.config.LDAPURL != ""
+}
+
+func (s *Server) ldapConfig() ldap.Config {
+	return ldap.Config{
+		URL:                s.config.LDAPURL,
+		StartTLS:           s.config.LDAPStartTLS,
+		InsecureSkipVerify: s.config.LDAPSkipVerify,
+		BindDN:             s.config.LDAPBindDN,
+		BindPassword:       s.config.LDAPBindPassword,
+		BaseDN:             s.config.LDAPBaseDN,
+		UserFilter:         s.config.LDAPUserFilter,
+		GroupAttribute:     s.config.LDAPGroupAttribute,
+	}
+}
+
+// authenticateLDAP checks the password against the directory and creates or updates the
+// local user. Roles follow the configured groups on every login.
+func (s *Server) authenticateLDAP(username, password string) (*database.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ldapTimeout)
+	defer cancel()
+
+	config := s.ldapConfig()
+	entry, err := config.Authenticate(ctx, username, password)
+	if err != nil {
+		if !errors.Is(err, ldap.ErrInvalidCredentials) {
+			logging.Errorf("LDAP login of %s failed: %v", username, err)
+		}
+		return nil, fmt.Errorf("invalid username or password")
+	}
+	if s.config.LDAPUserGroup != "" && !config.MemberOf(entry, s.config.LDAPUserGroup) {
+		logging.Warnf("LDAP user %s is not a member of %s, login denied", username, s.config.LDAPUserGroup)
+		return nil, fmt.Errorf("invalid username or password")
+	}
+
+	isSuperuser := config.MemberOf(entry, s.config.LDAPAdminGroup)
+	user := &database.User{
+		Username:    strings.ToLower(username), // Directories match names case-insensitively
+		Password:    ldapPasswordHash,
+		Email:       nullString(entry.Get("mail")),
+		FirstName:   nullString(entry.Get("givenName")),
+		LastName:    nullString(entry.Get("sn")),
+		IsStaff:     isSuperuser || config.MemberOf(entry, s.config.LDAPStaffGroup),
+		IsSuperuser: isSuperuser,
+		IsActive:    true,
+		AuthSource:  database.AuthSourceLDAP,
+	}
+	if err := syncLDAPUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// syncLDAPUser stores a directory user, creating it on the first login. Deactivated users
+// stay deactivated.
+func syncLDAPUser(user *database.User) error {
+	db := database.GetDB()
+
+	var isActive bool
+	err := db.QueryRow(`SELECT id, is_active, date_joined FROM users WHERE username = ? AND auth_source = ?`,
+		user.Username, database.AuthSourceLDAP).Scan(&user.ID, &isActive, &user.DateJoined)
+	switch {
+	case err == sql.ErrNoRows:
+		user.DateJoined = time.Now()
+		result, err := db.Exec(`
+			INSERT INTO users (username, password, email, first_name, last_name, is_staff, is_superuser, is_active, date_joined, auth_source)
+			VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+		`, user.Username, user.Password, user.Email, user.FirstName, user.LastName,
+			user.IsStaff, user.IsSuperuser, user.DateJoined, user.AuthSource)
+		if err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get user ID: %w", err)
+		}
+		user.ID = int(id)
+		logging.Infof("Created user %s from LDAP directory", user.Username)
+		return nil
+	case err != nil:
+		return err
+	case !isActive:
+		return fmt.Errorf("invalid username or password")
+	}
+
+	_, err = db.Exec(`
+		UPDATE users SET email = ?, first_name = ?, last_name = ?, is_staff = ?, is_superuser = ?
+		WHERE id = ?
+	`, user.Email, user.FirstName, user.LastName, user.IsStaff, user.IsSuperuser, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// nullString stores empty directory attributes as NULL
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
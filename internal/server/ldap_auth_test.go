@@ -0,0 +1,85 @@
+package server
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestAuthenticateUserPrefersLocalUsers(t *testing.T) {
+	if err := database.Initialize(t.TempDir() + "/test.db"); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	// Nothing listens on the directory port, local logins must work without it
+	s := &Server{config: &config.Config{LDAPURL: "ldap://127.0.0.1:1", LDAPBaseDN: "dc=example,dc=org"}}
+	local, err := s.createUser("admin", "local-secret", "", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := s.authenticateUser("admin", "local-secret")
+	if err != nil || user.ID != local.ID || user.AuthSource != database.AuthSourceLocal {
+		t.Fatalf("authenticateUser() = %+v, %v", user, err)
+	}
+	if err := s.verifyPassword(user, "local-secret"); err != nil {
+		t.Errorf("verifyPassword() error = %v", err)
+	}
+	if _, err := s.authenticateUser("admin", "wrong"); err == nil {
+		t.Error("authenticateUser() accepted a wrong password")
+	}
+	if _, err := s.authenticateUser("alice", "secret"); err == nil {
+		t.Error("authenticateUser() accepted a user the directory could not confirm")
+	}
+}
+
+func TestSyncLDAPUser(t *testing.T) {
+	if err := database.Initialize(t.TempDir() + "/test.db"); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	user := &database.User{
+		Username:   "alice",
+		Password:   ldapPasswordHash,
+		Email:      nullString("alice@example.org"),
+		IsStaff:    true,
+		IsActive:   true,
+		AuthSource: database.AuthSourceLDAP,
+	}
+	if err := syncLDAPUser(user); err != nil {
+		t.Fatalf("syncLDAPUser() error = %v", err)
+	}
+	firstID := user.ID
+
+	// Removed from the staff group in the directory
+	user = &database.User{Username: "alice", Password: ldapPasswordHash, IsActive: true, AuthSource: database.AuthSourceLDAP}
+	if err := syncLDAPUser(user); err != nil {
+		t.Fatalf("syncLDAPUser() error = %v", err)
+	}
+	if user.ID != firstID {
+		t.Errorf("syncLDAPUser() created a second user %d, want %d", user.ID, firstID)
+	}
+
+	s := &Server{config: &config.Config{}}
+	stored, err := s.getUserByID(firstID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.IsStaff || stored.Email != (sql.NullString{}) || stored.AuthSource != database.AuthSourceLDAP {
+		t.Errorf("stored user = %+v, want roles and attributes from the last login", stored)
+	}
+	if checkPassword("", stored.Password) == nil || checkPassword(ldapPasswordHash, stored.Password) == nil {
+		t.Error("directory users must not be able to log in with a local password")
+	}
+
+	if _, err := database.GetDB().Exec(`UPDATE users SET is_active = 0 WHERE id = ?`, firstID); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncLDAPUser(&database.User{Username: "alice", AuthSource: database.AuthSourceLDAP}); err == nil {
+		t.Error("syncLDAPUser() reactivated a deactivated user")
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// llmBenchmarkTimeout keeps the test and the benchmark within the server's write timeout
+	llmBenchmarkTimeout = 18 * time.Second
+	llmBenchmarkPrompt  = "Count from 1 to 50, separated by spaces. Respond with the numbers only."
+)
+
+// LLMBenchmark is the result of a short streaming completion against an LLM endpoint
+type LLMBenchmark struct {
+	FirstTokenMs    int64   `json:"first_token_ms"` // Time until the first content arrived
+	TotalMs         int64   `json:"total_ms"`
+	Tokens          int     `json:"tokens"`
+	TokensEstimated bool    `json:"tokens_estimated"` // True when the endpoint reported no usage and chunks were counted
+	TokensPerSecond float64 `json:"tokens_per_second"`
+}
+
+// benchmarkLLM streams a short completion and measures first-token latency and generation speed
+func benchmarkLLM(ctx context.Context, apiKey, apiURL, model string) (*LLMBenchmark, error) {
+	ctx, cancel := context.WithTimeout(ctx, llmBenchmarkTimeout)
+	defer cancel()
+
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "user", "content": llmBenchmarkPrompt},
+		},
+		"max_completion_tokens": 300,
+		"stream":                true,
+		"stream_options":        map[string]bool{"include_usage": true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup, error not critical
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck // Best effort error details
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var firstToken time.Time
+	var chunks, usageTokens int
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" && choice.Delta.ReasoningContent == "" {
+				continue
+			}
+			if firstToken.IsZero() {
+				firstToken = time.Now()
+			}
+			chunks++
+		}
+		if chunk.Usage != nil && chunk.Usage.CompletionTokens > 0 {
+			usageTokens = chunk.Usage.CompletionTokens
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	end := time.Now()
+
+	if firstToken.IsZero() {
+		return nil, fmt.Errorf("the endpoint returned no streamed content")
+	}
+
+	result := &LLMBenchmark{
+		FirstTokenMs: firstToken.Sub(start).Milliseconds(),
+		TotalMs:      end.Sub(start).Milliseconds(),
+		Tokens:       usageTokens,
+	}
+	if result.Tokens == 0 {
+		// Most servers send one token per chunk
+		result.Tokens = chunks
+		result.TokensEstimated = true
+	}
+	// The rate excludes the first token latency, which is dominated by prompt processing
+	if generation := end.Sub(firstToken).Seconds(); generation > 0 && result.Tokens > 1 {
+		result.TokensPerSecond = float64(result.Tokens-1) / generation
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// streamingLLM serves an OpenAI compatible stream of count chunks, optionally followed by usage
+func streamingLLM(t *testing.T, count int, usage bool) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, `{"error":{"message":"invalid key"}}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 1; i <= count; i++ {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"%d \"}}]}\n\n", i) //nolint:errcheck // Test server
+			w.(http.Flusher).Flush()
+		}
+		if usage {
+			fmt.Fprintf(w, "data: {\"choices\":[],\"usage\":{\"completion_tokens\":%d}}\n\n", count*2) //nolint:errcheck // Test server
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n") //nolint:errcheck // Test server
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestBenchmarkLLM(t *testing.T) {
+	result, err := benchmarkLLM(context.Background(), "test-key", streamingLLM(t, 50, true), "test")
+	if err != nil {
+		t.Fatalf("benchmarkLLM() error = %v", err)
+	}
+	if result.Tokens != 100 || result.TokensEstimated {
+		t.Errorf("tokens = %d (estimated %v), want 100 from usage", result.Tokens, result.TokensEstimated)
+	}
+	if result.FirstTokenMs > result.TotalMs {
+		t.Errorf("first token %dms after total %dms", result.FirstTokenMs, result.TotalMs)
+	}
+
+	result, err = benchmarkLLM(context.Background(), "test-key", streamingLLM(t, 20, false), "test")
+	if err != nil {
+		t.Fatalf("benchmarkLLM() without usage error = %v", err)
+	}
+	if result.Tokens != 20 || !result.TokensEstimated {
+		t.Errorf("tokens = %d (estimated %v), want 20 counted chunks", result.Tokens, result.TokensEstimated)
+	}
+}
+
+func TestBenchmarkLLMErrors(t *testing.T) {
+	if _, err := benchmarkLLM(context.Background(), "wrong-key", streamingLLM(t, 5, false), "test"); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+	if _, err := benchmarkLLM(context.Background(), "test-key", streamingLLM(t, 0, false), "test"); err == nil {
+		t.Error("expected an error for an empty stream")
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
+)
+
+const (
+	// loginHistoryLimit is how many logins are shown in settings
+	loginHistoryLimit = 20
+	// loginHistoryRetention is how many logins are kept per user
+	loginHistoryRetention = 200
+)
+
+// clientIP returns the IP address of the client. Forwarding headers are only
+// trusted when the request comes from a local reverse proxy such as Caddy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// The last entry was added by the proxy closest to us
+			parts := strings.Split(forwarded, ",")
+			if candidate := strings.TrimSpace(parts[len(parts)-1]); net.ParseIP(candidate) != nil {
+				return candidate
+			}
+		}
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+			return realIP
+		}
+	}
+
+	return host
+}
+
+// recordLogin stores the login in the login history and sends an alert if it
+// comes from a device or IP address that has not been seen for this user before.
+func (s *Server) recordLogin(r *http.Request, user *database.User) {
+	event := database.LoginEvent{
+		UserID:    user.ID,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		CreatedAt: time.Now(),
+	}
+	event.Location = s.geoIPDB.Lookup(event.IPAddress)
+
+	known, err := database.HasLoginFrom(user.ID, event.IPAddress, event.UserAgent)
+	if err != nil {
+		logging.Errorf("Failed to check login history: %v", err)
+		return
+	}
+	previous, err := database.CountLoginEvents(user.ID)
+	if err != nil {
+		logging.Errorf("Failed to check login history: %v", err)
+		return
+	}
+	// The very first login is not an unexpected device
+	event.IsNewDevice = !known && previous > 0
+
+	if err := database.RecordLoginEvent(event); err != nil {
+		logging.Errorf("Failed to record login: %v", err)
+		return
+	}
+	if err := database.CleanupOldLoginEvents(loginHistoryRetention); err != nil {
+		logging.Warnf("Failed to cleanup login history: %v", err)
+	}
+
+	if event.IsNewDevice {
+		s.notifyNewDeviceLogin(user, event)
+	}
+}
+
+// notifyNewDeviceLogin alerts the user about a login from an unknown device
+func (s *Server) notifyNewDeviceLogin(user *database.User, event database.LoginEvent) {
+	location := event.Location
+	if location == "" {
+		location = "unknown location"
+	}
+	logging.Warnf("New device login for user %s from %s (%s), user agent: %s",
+		user.Username, event.IPAddress, location, event.UserAgent)
+
+	smtpConfig := s.smtpConfig()
+	if !smtpConfig.Enabled() || !user.Email.Valid || user.Email.String == "" {
+		return
+	}
+
+	subject := "New login to your TreeOS node"
+	body := fmt.Sprintf(`Hello %s,
+
+your TreeOS account was just used to log in from a device we haven't seen before.
+
+Time:       %s
+IP address: %s
+Location:   %s
+Browser:    %s
+
+If this was you, no action is needed. If you don't recognize this login,
+change your password immediately.
+`, user.Username, event.CreatedAt.Format(time.RFC1123), event.IPAddress, location, event.UserAgent)
+
+	// Don't hold up the login on a slow mail server
+	go func() {
+		if err := notify.SendEmail(smtpConfig, []string{user.Email.String}, subject, body); err != nil {
+			logging.Errorf("Failed to send new device login email: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct connection", "203.0.113.7:51234", "", "203.0.113.7"},
+		{"forwarded header ignored from remote peer", "203.0.113.7:51234", "198.51.100.1", "203.0.113.7"},
+		{"forwarded header trusted from local proxy", "127.0.0.1:40000", "198.51.100.1", "198.51.100.1"},
+		{"last forwarded entry is used", "127.0.0.1:40000", "10.0.0.1, 198.51.100.1", "198.51.100.1"},
+		{"invalid forwarded entry falls back to peer", "[::1]:40000", "garbage", "::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
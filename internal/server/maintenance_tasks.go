@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/maintenance"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+// Prune tasks, in the order they run
+const (
+	pruneTaskImages     = "images"
+	pruneTaskBuildCache = "build-cache"
+	pruneTaskVolumes    = "volumes"
+)
+
+const (
+	// pruneCheckInterval is how often the scheduler looks for a due weekly cleanup
+	pruneCheckInterval = 10 * time.Minute
+	// pruneTimeout bounds one maintenance run
+	pruneTimeout = 30 * time.Minute
+)
+
+// MaintenanceTask is a cleanup the maintenance page offers
+type MaintenanceTask struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// maintenanceTasks are the cleanups a maintenance run can do
+var maintenanceTasks = []MaintenanceTask{
+	{ID: pruneTaskImages, Name: "Dangling images", Description: "Untagged images no container uses, e.g. left behind by updates (docker image prune)"},
+	{ID: pruneTaskBuildCache, Name: "Build cache", Description: "Cache of image builds no build uses (docker builder prune)"},
+	{ID: pruneTaskVolumes, Name: "Dangling volumes", Description: "Volumes no container uses and no app owns, e.g. of deleted apps"},
+}
+
+// PruneResult is the outcome of one task of a maintenance run
+type PruneResult struct {
+	Task   string `json:"task"`
+	DryRun bool   `json:"dry_run"`
+	dockerruntime.PruneReport
+	Error string `json:"error,omitempty"`
+}
+
+// MaintenanceScheduleResponse is the weekly cleanup as returned by the maintenance API
+type MaintenanceScheduleResponse struct {
+	Day     string     `json:"day"` // Short weekday name, "" when off
+	Window  string     `json:"window"`
+	LastRun *time.Time `json:"last_run,omitempty"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+// errMaintenanceRunning is returned while another maintenance run is in progress
+var errMaintenanceRunning = errors.New("a maintenance run is already in progress")
+
+// pruneSchedule returns the weekday of the weekly cleanup, "" when it is off, and when it
+// last ran
+func (s *Server) pruneSchedule() (string, time.Time) {
+	if s.db == nil {
+		return "", time.Time{}
+	}
+	var day sql.NullString
+	var lastRun sql.NullTime
+	err := s.db.QueryRow(`SELECT prune_schedule, prune_last_run FROM system_setup WHERE id = 1`).Scan(&day, &lastRun)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logging.Errorf("Failed to read cleanup schedule: %v", err)
+	}
+	return day.String, lastRun.Time
+}
+
+// pruneWindow returns the maintenance window on the weekday of the weekly cleanup
+func (s *Server) pruneWindow(day string) (maintenance.Window, error) {
+	weekday, err := maintenance.ParseWeekday(day)
+	if err != nil {
+		return maintenance.Window{}, err
+	}
+	w := s.maintenanceWindow()
+	w.Days = []time.Weekday{weekday}
+	return w, nil
+}
+
+// nextPrune returns when the weekly cleanup runs next. A run in the current window
+// happened already, the next one is a week later.
+func nextPrune(w maintenance.Window, lastRun, now time.Time) time.Time {
+	from := now
+	if !lastRun.IsZero() && now.Sub(lastRun) < 24*time.Hour {
+		from = lastRun.Add(24 * time.Hour)
+	}
+	return w.Next(from)
+}
+
+// maintenanceSchedule returns the weekly cleanup in the node's time zone
+func (s *Server) maintenanceSchedule(now time.Time) MaintenanceScheduleResponse {
+	day, lastRun := s.pruneSchedule()
+	loc := s.location()
+	response := MaintenanceScheduleResponse{Day: day, Window: s.maintenanceWindow().String()}
+	if !lastRun.IsZero() {
+		t := lastRun.In(loc)
+		response.LastRun = &t
+	}
+	if w, err := s.pruneWindow(day); err == nil {
+		next := nextPrune(w, lastRun, now).In(loc)
+		response.NextRun = &next
+	}
+	return response
+}
+
+// startMaintenanceScheduler runs the weekly cleanup once its window opens
+func (s *Server) startMaintenanceScheduler() {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(pruneCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.runDuePrune(time.Now())
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// runDuePrune runs all cleanup tasks if the weekly cleanup is due
+func (s *Server) runDuePrune(now time.Time) {
+	day, lastRun := s.pruneSchedule()
+	w, err := s.pruneWindow(day)
+	if err != nil || !w.Contains(now) || nextPrune(w, lastRun, now).After(now) {
+		return
+	}
+	// Recorded first, so a run that fails isn't repeated every check
+	if _, err := s.db.Exec(`UPDATE system_setup SET prune_last_run = ? WHERE id = 1`, now); err != nil {
+		logging.Errorf("Failed to record cleanup run: %v", err)
+		return
+	}
+
+	logging.Infof("Running the weekly cleanup")
+	if _, err := s.runMaintenance(context.Background(), nil, false, ""); err != nil {
+		logging.Errorf("Weekly cleanup failed: %v", err)
+	}
+}
+
+// runMaintenance runs cleanup tasks, all when none are given, and records what a real run
+// removed in the activity feed. An empty actor stands for the weekly schedule.
+func (s *Server) runMaintenance(ctx context.Context, tasks []string, dryRun bool, actor string) ([]PruneResult, error) {
+	if !s.maintenanceMu.TryLock() {
+		return nil, errMaintenanceRunning
+	}
+	defer s.maintenanceMu.Unlock()
+
+	runtimeClient, err := s.getRuntimeClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, pruneTimeout)
+	defer cancel()
+
+	results := []PruneResult{}
+	for _, task := range maintenanceTasks {
+		if len(tasks) > 0 && !slices.Contains(tasks, task.ID) {
+			continue
+		}
+		var report dockerruntime.PruneReport
+		switch task.ID {
+		case pruneTaskImages:
+			report, err = runtimeClient.PruneImages(ctx, dryRun)
+		case pruneTaskBuildCache:
+			report, err = runtimeClient.PruneBuildCache(ctx, dryRun)
+		case pruneTaskVolumes:
+			report, err = runtimeClient.PruneDanglingVolumes(ctx, s.config.AppsDir, dryRun)
+		}
+		result := PruneResult{Task: task.ID, DryRun: dryRun, PruneReport: report}
+		if err != nil {
+			logging.Errorf("Cleanup of %s failed: %v", task.ID, err)
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	if !dryRun {
+		recordActivity(pruneActivity(results, actor))
+	}
+	return results, nil
+}
+
+// pruneActivity describes a maintenance run for the activity feed
+func pruneActivity(results []PruneResult, actor string) database.ActivityEvent {
+	var reclaimed int64
+	var details []string
+	status := jobStateCompleted
+	for _, result := range results {
+		reclaimed += result.Reclaimed
+		detail := fmt.Sprintf("%s: %d removed", result.Task, len(result.Items))
+		if result.Error != "" {
+			detail += " (" + result.Error + ")"
+			status = jobStateFailed
+		}
+		details = append(details, detail)
+	}
+
+	freed := storage.FormatSize(uint64(reclaimed)) //nolint:gosec // Sum of sizes is not negative
+	title := fmt.Sprintf("Cleanup freed %s", freed)
+	if actor == "" {
+		title = fmt.Sprintf("Weekly cleanup freed %s", freed)
+	}
+	return database.ActivityEvent{
+		Category: database.ActivityAudit,
+		Title:    title,
+		Detail:   strings.Join(details, ", "),
+		Actor:    actor,
+		Status:   status,
+	}
+}
+
+// handleAPIMaintenance handles GET /api/maintenance, the cleanup tasks and the weekly
+// schedule
+func (s *Server) handleAPIMaintenance(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"tasks":    maintenanceTasks,
+		"schedule": s.maintenanceSchedule(time.Now()),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleAPIMaintenancePrune handles POST /api/maintenance/prune with a JSON body
+// {"tasks": ["images", ...], "dry_run": true}. No tasks runs all of them. A dry run only
+// reports what would be removed and the space it would free.
+func (s *Server) handleAPIMaintenancePrune(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Tasks  []string `json:"tasks"`
+		DryRun bool     `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, task := range request.Tasks {
+		if !slices.ContainsFunc(maintenanceTasks, func(t MaintenanceTask) bool { return t.ID == task }) {
+			http.Error(w, fmt.Sprintf("Unknown task '%s'", task), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := s.runMaintenance(r.Context(), request.Tasks, request.DryRun, user.Username)
+	switch {
+	case errors.Is(err, errMaintenanceRunning):
+		http.Error(w, "A maintenance run is already in progress", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf("Container runtime not available: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	if !request.DryRun {
+		logging.Infof("User %s ran the cleanup tasks %v", user.Username, request.Tasks)
+	}
+
+	var reclaimed int64
+	for _, result := range results {
+		reclaimed += result.Reclaimed
+	}
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{"results": results, "reclaimed": reclaimed, "dry_run": request.DryRun}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleAPIMaintenanceSchedule handles PUT /api/maintenance/schedule with a JSON body
+// {"day": "sun"}. The cleanup then runs all tasks weekly on that day in the maintenance
+// window, an empty day turns it off.
+func (s *Server) handleAPIMaintenanceSchedule(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request struct {
+		Day string `json:"day"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	day := strings.ToLower(strings.TrimSpace(request.Day))
+	if day != "" {
+		if _, err := maintenance.ParseWeekday(day); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := s.db.Exec(`UPDATE system_setup SET prune_schedule = ? WHERE id = 1`, day); err != nil {
+		logging.Errorf("Failed to save cleanup schedule: %v", err)
+		http.Error(w, "Failed to save schedule", http.StatusInternalServerError)
+		return
+	}
+
+	title := "Weekly cleanup turned off"
+	if day != "" {
+		title = fmt.Sprintf("Weekly cleanup scheduled on %s", day)
+	}
+	logging.Infof("User %s: %s", user.Username, title)
+	recordActivity(database.ActivityEvent{Category: database.ActivityAudit, Title: title, Actor: user.Username})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.maintenanceSchedule(time.Now())); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+func TestNextPrune(t *testing.T) {
+	s := &Server{config: &config.Config{MaintenanceWindow: "03:00-05:00"}}
+	w, err := s.pruneWindow("sun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = w.In(time.UTC)
+	if _, err := s.pruneWindow("someday"); err == nil {
+		t.Error("pruneWindow() accepted an invalid weekday")
+	}
+
+	// Friday, the window opens on Sunday
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	sunday := time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)
+	if got := nextPrune(w, time.Time{}, friday); !got.Equal(sunday) {
+		t.Errorf("nextPrune() = %s, want %s", got, sunday)
+	}
+	// Due inside the window until it ran, then a week later
+	now := sunday.Add(30 * time.Minute)
+	if got := nextPrune(w, sunday.AddDate(0, 0, -7), now); !got.Equal(now) {
+		t.Errorf("nextPrune() in the window = %s, want now", got)
+	}
+	if got := nextPrune(w, now, now.Add(time.Minute)); !got.Equal(sunday.AddDate(0, 0, 7)) {
+		t.Errorf("nextPrune() after the run = %s", got)
+	}
+}
+
+func TestPruneActivity(t *testing.T) {
+	results := []PruneResult{
+		{Task: pruneTaskImages, PruneReport: dockerruntime.PruneReport{Items: []string{"a1", "b2"}, Reclaimed: 2000000}},
+		{Task: pruneTaskVolumes, PruneReport: dockerruntime.PruneReport{Items: []string{}}, Error: "volume in use"},
+	}
+	event := pruneActivity(results, "admin")
+	if event.Category != database.ActivityAudit || event.Title != "Cleanup freed 2MB" || event.Status != jobStateFailed ||
+		event.Detail != "images: 2 removed, volumes: 0 removed (volume in use)" {
+		t.Errorf("pruneActivity() = %+v", event)
+	}
+	if event := pruneActivity(results[:1], ""); event.Title != "Weekly cleanup freed 2MB" || event.Status != jobStateCompleted {
+		t.Errorf("pruneActivity() of the schedule = %+v", event)
+	}
+}
+
+func TestHandleAPIMaintenance(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+	db := database.GetDB()
+	if _, err := db.Exec(`INSERT INTO system_setup (id) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{db: db, config: &config.Config{AppsDir: t.TempDir(), MaintenanceWindow: "03:00-05:00"}}
+
+	staff := &database.User{ID: 1, Username: "admin", IsStaff: true}
+	request := func(handler http.HandlerFunc, method, body string, user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/maintenance", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := request(s.handleAPIMaintenancePrune, http.MethodPost, `{}`, &database.User{ID: 2, Username: "viewer"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-staff status = %d", rec.Code)
+	}
+	if rec := request(s.handleAPIMaintenancePrune, http.MethodPost, `{"tasks": ["system"]}`, staff); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown task status = %d", rec.Code)
+	}
+
+	rec := request(s.handleAPIMaintenancePrune, http.MethodPost, `{"dry_run": true}`, staff)
+	var response struct {
+		Results []PruneResult `json:"results"`
+		DryRun  bool          `json:"dry_run"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("status = %d: %v", rec.Code, err)
+	}
+	if len(response.Results) != 3 || !response.DryRun || response.Results[0].Task != pruneTaskImages {
+		t.Errorf("dry run = %+v", response)
+	}
+	events, err := database.GetActivity(database.ActivityFilter{Category: database.ActivityAudit, Limit: 10})
+	if err != nil || len(events) != 0 {
+		t.Errorf("dry run recorded %+v, %v", events, err)
+	}
+
+	if rec := request(s.handleAPIMaintenancePrune, http.MethodPost, `{"tasks": ["build-cache"]}`, staff); rec.Code != http.StatusOK {
+		t.Errorf("prune status = %d: %s", rec.Code, rec.Body)
+	}
+	events, err = database.GetActivity(database.ActivityFilter{Category: database.ActivityAudit, Limit: 10})
+	if err != nil || len(events) != 1 || events[0].Actor != "admin" || events[0].Detail != "build-cache: 0 removed" {
+		t.Errorf("prune recorded %+v, %v", events, err)
+	}
+
+	if rec := request(s.handleAPIMaintenanceSchedule, http.MethodPut, `{"day": "funday"}`, staff); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid day status = %d", rec.Code)
+	}
+	rec = request(s.handleAPIMaintenanceSchedule, http.MethodPut, `{"day": "Sun"}`, staff)
+	var schedule MaintenanceScheduleResponse
+	if err := json.NewDecoder(rec.Body).Decode(&schedule); err != nil {
+		t.Fatalf("status = %d: %v", rec.Code, err)
+	}
+	if schedule.Day != "sun" || schedule.NextRun == nil || schedule.NextRun.Weekday() != time.Sunday {
+		t.Errorf("schedule = %+v", schedule)
+	}
+}
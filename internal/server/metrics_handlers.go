@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/metrics"
+)
+
+// handleMetrics serves the internal metrics in the Prometheus text format. Access is limited by
+// EndpointAccessMiddleware: scrapers authenticate with the metrics token as bearer token, users
+// with their session, and only admins see the metrics.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if user := getUserFromContext(r.Context()); user != nil && !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.writeMetrics(w)
+}
+
+func (s *Server) writeMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.WritePrometheus(w); err != nil {
+		logging.Errorf("Failed to write metrics: %v", err)
+	}
+}
+
+// handleMetricsExport serves a Grafana dashboard or Prometheus alert rules for the node, built
+// from the metrics it registers. ?format= is grafana or prometheus, ?job= the job_name of the
+// scrape config.
+func (s *Server) handleMetricsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if user := getUserFromContext(r.Context()); user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	node, _ := os.Hostname() //nolint:errcheck // An empty name falls back to "node"
+	export := metrics.Export{Node: node, Job: r.URL.Query().Get("job")}
+
+	var (
+		data        []byte
+		err         error
+		filename    string
+		contentType string
+	)
+	switch r.URL.Query().Get("format") {
+	case "grafana":
+		data, err = export.GrafanaDashboard(metrics.Snapshot())
+		filename, contentType = export.Filename("dashboard.json"), "application/json"
+	case "prometheus":
+		data, err = export.AlertRules(metrics.Snapshot())
+		filename, contentType = export.Filename("alerts.yml"), "application/yaml"
+	default:
+		http.Error(w, "format must be grafana or prometheus", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.Errorf("Failed to export metrics definitions: %v", err)
+		http.Error(w, "Failed to export metrics definitions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if _, err := w.Write(data); err != nil {
+		logging.Errorf("Failed to write metrics export: %v", err)
+	}
+}
+
+// metricRow is one label combination of a metric on the debug page
+type metricRow struct {
+	Labels string
+	Value  string // Counters and gauges
+	Count  uint64 // Histograms
+	Mean   string
+	P50    string
+	P95    string
+}
+
+// metricView is a metric family on the debug page
+type metricView struct {
+	Name      string
+	Help      string
+	Histogram bool
+	Rows      []metricRow
+}
+
+func metricViews(families []metrics.Family) []metricView {
+	views := make([]metricView, 0, len(families))
+	for _, f := range families {
+		view := metricView{Name: f.Name, Help: f.Help, Histogram: f.Kind == metrics.KindHistogram}
+		for _, series := range f.Series {
+			pairs := make([]string, len(f.Labels))
+			for i, label := range f.Labels {
+				pairs[i] = label + "=" + series.LabelValues[i]
+			}
+			row := metricRow{Labels: strings.Join(pairs, " ")}
+			if view.Histogram {
+				row.Count = series.Count
+				row.Mean = formatSeconds(series.Mean())
+				row.P50 = formatSeconds(series.Quantile(0.5))
+				row.P95 = formatSeconds(series.Quantile(0.95))
+			} else {
+				row.Value = fmt.Sprintf("%g", series.Value)
+			}
+			view.Rows = append(view.Rows, row)
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// formatSeconds formats a duration in seconds for the debug page
+func formatSeconds(seconds float64) string {
+	switch {
+	case math.IsInf(seconds, 1):
+		return "> max bucket"
+	case seconds < 1:
+		return fmt.Sprintf("%.1f ms", seconds*1000)
+	default:
+		return fmt.Sprintf("%.2f s", seconds)
+	}
+}
+
+// handleDebugMetrics shows the internal metrics to admins
+func (s *Server) handleDebugMetrics(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data := s.baseTemplateData(user)
+	data["Metrics"] = metricViews(metrics.Snapshot())
+	data["MetricsTokenSet"] = s.config.MetricsToken != ""
+
+	tmpl, ok := s.templates["debug_metrics"]
+	if !ok {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		logging.Errorf("Error rendering template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
@@ -1,11 +1,18 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
+
 	"github.com/ontree-co/treeos/internal/database"
 	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/metrics"
 	"github.com/ontree-co/treeos/internal/telemetry"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -13,6 +20,8 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+var errInvalidAPIToken = errors.New("invalid API token")
+
 // SetupRequiredMiddleware checks if initial setup is complete
 func (s *Server) SetupRequiredMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -87,6 +96,31 @@ func (s *Server) AuthRequiredMiddleware(next http.HandlerFunc) http.HandlerFunc
 		}
 
 		if !pathPublic {
+			// API clients without a browser session authenticate with an API token
+			if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(r.URL.Path, "/api/") {
+				user, scope, err := s.authenticateAPIToken(token, time.Now())
+				if err != nil {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				// Tokens of a user need their certificate like the user's sessions. The
+				// api_token of the config file belongs to whoever can read the config.
+				if strings.HasPrefix(token, apiTokenPrefix) && !s.clientCertSatisfied(r, user) {
+					http.Error(w, "This account requires its client certificate", http.StatusForbidden)
+					return
+				}
+				if !apiTokenAllows(scope, r) {
+					http.Error(w, fmt.Sprintf("The %s scope of this API token doesn't allow this request", scope), http.StatusForbidden)
+					return
+				}
+				if !roleAllows(user, r) {
+					http.Error(w, "Viewers can't make changes", http.StatusForbidden)
+					return
+				}
+				ctx := context.WithValue(setUserContext(r.Context(), user), apiTokenContextKey, true)
+				next(w, r.WithContext(ctx))
+				return
+			}
 
 			// Check if user is authenticated
 			session, err := s.sessionStore.Get(r, "ontree-session")
@@ -108,11 +142,25 @@ func (s *Server) AuthRequiredMiddleware(next http.HandlerFunc) http.HandlerFunc
 				return
 			}
 
-			// Load user data and add to context
+			// Enforce idle timeout and absolute lifetime, renewing the session on activity
+			now := time.Now()
+			expired, changed := s.touchSession(session, now)
+			if expired {
+				clearSession(session)
+				session.Values["next"] = r.URL.Path
+				if err := session.Save(r, w); err != nil {
+					logging.Errorf("Error saving session: %v", err)
+				}
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			s.applySessionLifetime(session)
+
+			// Load user data and add to context. Sessions from before a password change end.
 			user, err := s.getUserByID(userID)
-			if err != nil {
+			if err != nil || !sessionCurrent(session, user) {
 				// Invalid session, clear it
-				delete(session.Values, "user_id")
+				clearSession(session)
 				if err := session.Save(r, w); err != nil {
 					logging.Errorf("Error saving session: %v", err)
 				}
@@ -120,6 +168,35 @@ func (s *Server) AuthRequiredMiddleware(next http.HandlerFunc) http.HandlerFunc
 				return
 			}
 
+			// Rotate the session ID on the renewal interval. A request that was sent with the
+			// cookie from before a rotation keeps the newer cookie in the browser.
+			switch rotated, err := rotateSessionID(session, now); {
+			case errors.Is(err, errSessionSuperseded):
+				changed = false
+			case err != nil:
+				logging.Errorf("Failed to rotate session ID: %v", err)
+			case rotated:
+				changed = true
+			}
+
+			if changed {
+				if err := session.Save(r, w); err != nil {
+					logging.Errorf("Error saving session: %v", err)
+				}
+			}
+
+			// Staff users with a client certificate need it for every request, not just the login
+			if !s.clientCertSatisfied(r, user) {
+				http.Error(w, "This account requires its client certificate", http.StatusForbidden)
+				return
+			}
+
+			// Viewers can look at their apps but not change them
+			if !roleAllows(user, r) {
+				http.Error(w, "Viewers can't make changes", http.StatusForbidden)
+				return
+			}
+
 			// Store user in request context
 			r = r.WithContext(setUserContext(r.Context(), user))
 		}
@@ -128,6 +205,22 @@ func (s *Server) AuthRequiredMiddleware(next http.HandlerFunc) http.HandlerFunc
 	}
 }
 
+// apiTokenUser returns the user API token requests act as, the first active admin
+func (s *Server) apiTokenUser(token string) (*database.User, error) {
+	if s.config.APIToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.APIToken)) != 1 {
+		return nil, errInvalidAPIToken
+	}
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	var userID int
+	if err := db.QueryRow(`SELECT id FROM users WHERE is_staff = 1 AND is_active = 1 ORDER BY id LIMIT 1`).Scan(&userID); err != nil {
+		return nil, fmt.Errorf("failed to find an admin for the API token: %w", err)
+	}
+	return s.getUserByID(userID)
+}
+
 // TracingMiddleware adds OpenTelemetry tracing to HTTP requests
 func (s *Server) TracingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -149,6 +242,7 @@ func (s *Server) TracingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 		// Pass the request with the new context
 		next(rw, r.WithContext(ctx))
+		metrics.HTTPRequests.Inc(metrics.StatusClass(rw.statusCode))
 
 		// Set response attributes
 		span.SetAttributes(
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/ollama"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+// modelQuota returns the disk quota of the shared Ollama directory
+func (s *Server) modelQuota() ollama.Quota {
+	quota := ollama.Quota{Dir: config.GetSharedOllamaPath()}
+	if s.config != nil && s.config.OllamaQuota != "" {
+		// Validated when the config was loaded
+		if limit, err := storage.ParseSize(s.config.OllamaQuota); err == nil {
+			quota.Limit = int64(limit) //nolint:gosec // Quotas fit into int64
+		}
+	}
+	return quota
+}
+
+// modelDiskUsage is the disk space models take, shown above the models list
+type modelDiskUsage struct {
+	Used    string
+	Limit   string // Empty without a quota
+	Percent int
+}
+
+// modelDiskSummary returns the disk space of the shared Ollama directory and its quota
+func modelDiskSummary(quota ollama.Quota) modelDiskUsage {
+	used, err := quota.Used()
+	if err != nil {
+		logging.Warnf("Failed to measure the models directory: %v", err)
+	}
+	summary := modelDiskUsage{Used: storage.FormatSize(uint64(used))} //nolint:gosec // Disk usage is never negative
+	if quota.Limit > 0 {
+		summary.Limit = storage.FormatSize(uint64(quota.Limit))
+		summary.Percent = int(min(used*100/quota.Limit, 100))
+	}
+	return summary
+}
+
+// modelSizes returns the formatted disk usage of each installed model, by name
+func modelSizes(quota ollama.Quota) map[string]string {
+	sizes, err := ollama.ModelSizes(quota.Dir)
+	if err != nil {
+		logging.Warnf("Failed to read model manifests: %v", err)
+	}
+	formatted := make(map[string]string, len(sizes))
+	for name, size := range sizes {
+		formatted[name] = storage.FormatSize(uint64(size)) //nolint:gosec // Sizes are never negative
+	}
+	return formatted
+}
+
+// refuseOverQuota answers 507 Insufficient Storage and returns true if the download of a
+// model doesn't fit into the quota
+func (s *Server) refuseOverQuota(w http.ResponseWriter, modelName string) bool {
+	if s.ollamaWorker == nil {
+		return false
+	}
+	err := s.ollamaWorker.CheckQuota(modelName)
+	if errors.Is(err, ollama.ErrQuotaExceeded) {
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+		return true
+	}
+	if err != nil {
+		// The worker checks again before the download starts
+		logging.Warnf("Failed to check the model quota: %v", err)
+	}
+	return false
+}
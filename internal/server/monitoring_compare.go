@@ -0,0 +1,131 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/charts"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// comparedMetrics are the metrics of the comparison view, with their title
+var comparedMetrics = []struct {
+	name  string
+	title string
+	value func(database.SystemVitalLog) float64
+}{
+	{"cpu", "CPU Usage", func(m database.SystemVitalLog) float64 { return m.CPUPercent }},
+	{"memory", "Memory Usage", func(m database.SystemVitalLog) float64 { return m.MemoryPercent }},
+	{"disk", "Disk Usage (/)", func(m database.SystemVitalLog) float64 { return m.DiskUsagePercent }},
+}
+
+// comparisonWindows returns the start and end of today and the start of the same weekday
+// last week. Days are local calendar days, so a DST change keeps midnight aligned.
+func comparisonWindows(now time.Time) (dayStart, dayEnd, lastWeekStart time.Time) {
+	dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return dayStart, dayStart.AddDate(0, 0, 1), dayStart.AddDate(0, 0, -7)
+}
+
+// metricComparison holds one metric of today and of last week, shifted onto today
+type metricComparison struct {
+	today, lastWeek []charts.DataPoint
+	// Averages up to the current time of day, so a half day isn't held against a full one
+	todayAvg, lastWeekAvg float64
+	hasLastWeek           bool
+}
+
+// compareMetric builds the comparison of one metric. lastWeek is shifted by shift onto
+// today; its average only covers the part of the day that has passed today.
+func compareMetric(today, lastWeek []database.SystemVitalLog, shift time.Duration, now time.Time, value func(database.SystemVitalLog) float64) metricComparison {
+	var c metricComparison
+	var sum float64
+	for _, m := range today {
+		c.today = append(c.today, charts.DataPoint{Time: m.Timestamp, Value: value(m)})
+		sum += value(m)
+	}
+	if len(today) > 0 {
+		c.todayAvg = sum / float64(len(today))
+	}
+
+	sum = 0
+	count := 0
+	for _, m := range lastWeek {
+		t := m.Timestamp.Add(shift)
+		c.lastWeek = append(c.lastWeek, charts.DataPoint{Time: t, Value: value(m)})
+		if !t.After(now) {
+			sum += value(m)
+			count++
+		}
+	}
+	if count > 0 {
+		c.lastWeekAvg = sum / float64(count)
+		c.hasLastWeek = true
+	}
+	return c
+}
+
+// handleMonitoringCompare returns charts that overlay today's CPU, memory and disk usage
+// with the same weekday last week, to see whether e.g. a new app changed the baseline load
+func (s *Server) handleMonitoringCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	dayStart, dayEnd, lastWeekStart := comparisonWindows(now)
+	lastWeekEnd := lastWeekStart.AddDate(0, 0, 1)
+
+	today, err := database.GetMetricsBatch(dayStart, now)
+	if err != nil {
+		logging.Errorf("Failed to get metrics of today: %v", err)
+		today = &database.MetricsBatch{}
+	}
+	lastWeek, err := database.GetMetricsBatch(lastWeekStart, lastWeekEnd)
+	if err != nil {
+		logging.Errorf("Failed to get metrics of last week: %v", err)
+		lastWeek = &database.MetricsBatch{}
+	}
+
+	lastWeekLabel := lastWeekStart.Format("Mon Jan 2")
+	var b strings.Builder
+	b.WriteString(`<div class="row g-3 mt-1">`)
+	for _, metric := range comparedMetrics {
+		c := compareMetric(today.Metrics, lastWeek.Metrics, dayStart.Sub(lastWeekStart), now, metric.value)
+		chart := charts.GenerateDetailedChart(charts.DetailedChartData{
+			Points:          c.today,
+			Comparison:      c.lastWeek,
+			Title:           metric.title,
+			YAxisUnit:       "%",
+			MinValue:        0,
+			MaxValue:        100,
+			StartTime:       dayStart,
+			EndTime:         dayEnd,
+			Label:           "Today",
+			ComparisonLabel: lastWeekLabel,
+		}, 700, 400)
+
+		summary := "No data of " + lastWeekLabel + " to compare with"
+		switch {
+		case len(c.today) == 0:
+			summary = "No data of today yet"
+		case c.hasLastWeek:
+			summary = fmt.Sprintf("Average so far: %.1f%% today, %.1f%% on %s (%+.1f points)",
+				c.todayAvg, c.lastWeekAvg, lastWeekLabel, c.todayAvg-c.lastWeekAvg)
+		}
+		fmt.Fprintf(&b, `<div class="col-12 col-xl-4" id="compare-%s"><div class="card"><div class="card-body">
+	<div class="chart-container">%s</div>
+	<small class="text-muted">%s</small>
+</div></div></div>`, metric.name, chart, template.HTMLEscapeString(summary))
+	}
+	b.WriteString(`</div>`)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(b.String())); err != nil {
+		logging.Errorf("Failed to write response: %v", err)
+	}
+}
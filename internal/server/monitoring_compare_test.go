@@ -0,0 +1,61 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestComparisonWindows(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	// The week before spans the end of daylight saving time on Oct 25
+	now := time.Date(2026, 10, 28, 14, 30, 0, 0, berlin)
+	dayStart, dayEnd, lastWeekStart := comparisonWindows(now)
+
+	if want := time.Date(2026, 10, 28, 0, 0, 0, 0, berlin); !dayStart.Equal(want) {
+		t.Errorf("dayStart = %v, want %v", dayStart, want)
+	}
+	if want := time.Date(2026, 10, 29, 0, 0, 0, 0, berlin); !dayEnd.Equal(want) {
+		t.Errorf("dayEnd = %v, want %v", dayEnd, want)
+	}
+	if want := time.Date(2026, 10, 21, 0, 0, 0, 0, berlin); !lastWeekStart.Equal(want) {
+		t.Errorf("lastWeekStart = %v, want %v", lastWeekStart, want)
+	}
+	if lastWeekStart.Weekday() != now.Weekday() {
+		t.Errorf("lastWeekStart is a %v, want %v", lastWeekStart.Weekday(), now.Weekday())
+	}
+}
+
+func TestCompareMetric(t *testing.T) {
+	dayStart := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	lastWeekStart := dayStart.AddDate(0, 0, -7)
+	now := dayStart.Add(12 * time.Hour)
+
+	today := []database.SystemVitalLog{
+		{Timestamp: dayStart.Add(1 * time.Hour), CPUPercent: 40},
+		{Timestamp: dayStart.Add(11 * time.Hour), CPUPercent: 60},
+	}
+	lastWeek := []database.SystemVitalLog{
+		{Timestamp: lastWeekStart.Add(1 * time.Hour), CPUPercent: 10},
+		{Timestamp: lastWeekStart.Add(11 * time.Hour), CPUPercent: 30},
+		// Later than the current time of day, drawn but not averaged
+		{Timestamp: lastWeekStart.Add(20 * time.Hour), CPUPercent: 90},
+	}
+	cpu := func(m database.SystemVitalLog) float64 { return m.CPUPercent }
+
+	c := compareMetric(today, lastWeek, dayStart.Sub(lastWeekStart), now, cpu)
+	if c.todayAvg != 50 || c.lastWeekAvg != 20 || !c.hasLastWeek {
+		t.Errorf("averages = %v and %v (%v), want 50 and 20", c.todayAvg, c.lastWeekAvg, c.hasLastWeek)
+	}
+	if len(c.lastWeek) != 3 || !c.lastWeek[2].Time.Equal(dayStart.Add(20*time.Hour)) {
+		t.Errorf("last week's points weren't shifted onto today: %+v", c.lastWeek)
+	}
+
+	if c := compareMetric(today, nil, dayStart.Sub(lastWeekStart), now, cpu); c.hasLastWeek {
+		t.Error("comparison without data of last week has an average of last week")
+	}
+}
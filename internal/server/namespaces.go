@@ -0,0 +1,465 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"gopkg.in/yaml.v3"
+)
+
+// Namespaces split the apps of a node between groups of users, e.g. family, work and kids.
+// Members of a namespace see and control only its apps, users in no namespace see the apps
+// outside of all namespaces, and staff see everything. An app's namespace is stored in the
+// x-ontree block of its compose file. App permissions grant users view or control access to
+// single apps on top of that.
+
+var (
+	// errNamespaceDenied is returned when a user may not put an app in a namespace
+	errNamespaceDenied = errors.New("namespace not allowed")
+	// errNamespacePolicy is returned when an app breaks the rules of its namespace
+	errNamespacePolicy = errors.New("namespace policy violated")
+)
+
+// namespaceSettingsView is a namespace on the settings page
+type namespaceSettingsView struct {
+	database.Namespace
+	Apps []string
+}
+
+// userNamespaces returns the namespaces whose apps a user sees. all is true for staff, who
+// see every app.
+func userNamespaces(user *database.User) (names []string, all bool, err error) {
+	if user == nil || user.IsStaff {
+		return nil, true, nil
+	}
+	names, err = database.GetUserNamespaces(user.ID)
+	return names, false, err
+}
+
+// namespaceVisible reports whether an app in namespace is visible to a user in names. Users
+// in no namespace see the apps outside of all namespaces.
+func namespaceVisible(names []string, namespace string) bool {
+	if len(names) == 0 {
+		return namespace == ""
+	}
+	return slices.Contains(names, namespace)
+}
+
+// appNamespace returns the namespace of an app, "" for none or if it can't be read
+func (s *Server) appNamespace(appName string) string {
+	metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(s.config.AppsDir, appName))
+	if err != nil {
+		return ""
+	}
+	return metadata.Namespace
+}
+
+// appAccessLevel returns the access of a user in the namespaces names and with the app
+// permissions grants to an app in namespace: the level of a grant, control for apps in the
+// user's namespaces, or "" for none. A grant also sets the access to an app in the user's
+// namespaces, and users with grants but in no namespace see only the apps granted to them.
+func appAccessLevel(names []string, grants map[string]string, appName, namespace string) string {
+	if level := grants[appName]; level != "" {
+		return level
+	}
+	if len(names) == 0 && len(grants) > 0 {
+		return ""
+	}
+	if namespaceVisible(names, namespace) {
+		return database.AppAccessControl
+	}
+	return ""
+}
+
+// userAppAccess loads the namespaces and app permissions of a user. all is true for staff,
+// who see and control every app.
+func userAppAccess(user *database.User) (names []string, grants map[string]string, all bool, err error) {
+	names, all, err = userNamespaces(user)
+	if all || err != nil {
+		return names, nil, all, err
+	}
+	grants, err = database.GetAppPermissionsForUser(user.ID)
+	return names, grants, false, err
+}
+
+// appAccess returns the access a user has to an app, AppAccessView, AppAccessControl or ""
+// for none
+func (s *Server) appAccess(user *database.User, appName string) string {
+	names, grants, all, err := userAppAccess(user)
+	if err != nil {
+		logging.Errorf("Failed to load app access of user %s: %v", user.Username, err)
+		return ""
+	}
+	if all {
+		return database.AppAccessControl
+	}
+	return appAccessLevel(names, grants, appName, s.appNamespace(appName))
+}
+
+// canAccessApp reports whether a user may see an app
+func (s *Server) canAccessApp(user *database.User, appName string) bool {
+	return s.appAccess(user, appName) != ""
+}
+
+// requireAppAccess answers 404 for apps the user may not access, as if they did not exist,
+// and 403 for changes to apps the user may only view. Invalid names, e.g. with an escaped
+// slash, are not apps and answer 404 too. It reports whether the request may go on.
+func (s *Server) requireAppAccess(w http.ResponseWriter, r *http.Request, appName string) bool {
+	if !isValidAppName(appName) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return false
+	}
+	switch s.appAccess(getUserFromContext(r.Context()), appName) {
+	case database.AppAccessControl:
+		return true
+	case database.AppAccessView:
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return true
+		}
+		http.Error(w, fmt.Sprintf("You can only view app '%s'", appName), http.StatusForbidden)
+		return false
+	}
+	http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+	return false
+}
+
+// filterAppsForUser returns the apps a user may see
+func (s *Server) filterAppsForUser(user *database.User, apps []*dockerruntime.App) []*dockerruntime.App {
+	names, grants, all, err := userAppAccess(user)
+	if all {
+		return apps
+	}
+	if err != nil {
+		logging.Errorf("Failed to load app access of user %s: %v", user.Username, err)
+		return nil
+	}
+	visible := make([]*dockerruntime.App, 0, len(apps))
+	for _, app := range apps {
+		if appAccessLevel(names, grants, app.Name, s.appNamespace(app.Name)) != "" {
+			visible = append(visible, app)
+		}
+	}
+	return visible
+}
+
+// namespaceApps returns the apps in a namespace
+func (s *Server) namespaceApps(namespace string) []string {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		return nil
+	}
+	var apps []string
+	for _, entry := range entries {
+		if entry.IsDir() && s.appNamespace(entry.Name()) == namespace {
+			apps = append(apps, entry.Name())
+		}
+	}
+	return apps
+}
+
+// namespaceViolations returns the rules of a namespace an app breaks: host ports outside of
+// its range, a subdomain without its prefix and apps beyond its limit
+func (s *Server) namespaceViolations(ns *database.Namespace, appName string, compose *yamlutil.ComposeFile) []string {
+	var violations []string
+	if ns.PortMin > 0 || ns.PortMax > 0 {
+		ports, err := yamlutil.HostPorts(compose)
+		if err != nil {
+			violations = append(violations, err.Error())
+		}
+		for _, port := range ports {
+			if port < ns.PortMin || (ns.PortMax > 0 && port > ns.PortMax) {
+				violations = append(violations, fmt.Sprintf("host port %d is outside of %s's ports %d-%d", port, ns.Name, ns.PortMin, ns.PortMax))
+			}
+		}
+	}
+	if metadata := yamlutil.GetOnTreeMetadata(compose); metadata.Subdomain != "" && !strings.HasPrefix(metadata.Subdomain, ns.SubdomainPrefix) {
+		violations = append(violations, fmt.Sprintf("subdomain %s must start with %s", metadata.Subdomain, ns.SubdomainPrefix))
+	}
+	if ns.MaxApps > 0 {
+		apps := s.namespaceApps(ns.Name)
+		if !slices.Contains(apps, appName) && len(apps) >= ns.MaxApps {
+			violations = append(violations, fmt.Sprintf("%s already has %d of %d apps", ns.Name, len(apps), ns.MaxApps))
+		}
+	}
+	return violations
+}
+
+// namespaceSubdomain returns the subdomain an app in a namespace is exposed at. Without a
+// requested subdomain it is the app name behind the namespace's prefix, a requested one must
+// start with the prefix.
+func (s *Server) namespaceSubdomain(namespace, appName, requested string) (string, error) {
+	prefix := ""
+	if namespace != "" {
+		ns, err := database.GetNamespace(namespace)
+		if err != nil {
+			return "", fmt.Errorf("failed to load namespace: %w", err)
+		}
+		if ns != nil {
+			prefix = ns.SubdomainPrefix
+		}
+	}
+	if requested == "" {
+		if strings.HasPrefix(appName, prefix) {
+			return appName, nil
+		}
+		return prefix + appName, nil
+	}
+	if !strings.HasPrefix(requested, prefix) {
+		return "", fmt.Errorf("subdomains in namespace %s must start with %s", namespace, prefix)
+	}
+	return requested, nil
+}
+
+// newAppNamespace checks the namespace of a new app against the user and the namespace's
+// rules. Apps of users in a single namespace go there, the compose content is returned with
+// the namespace set.
+func (s *Server) newAppNamespace(user *database.User, appName, composeContent string) (string, error) {
+	var compose yamlutil.ComposeFile
+	if err := yaml.Unmarshal([]byte(composeContent), &compose); err != nil {
+		// Invalid files are reported by the compose validation
+		return composeContent, nil
+	}
+	namespace := yamlutil.GetOnTreeMetadata(&compose).Namespace
+	assigned := false
+
+	names, all, err := userNamespaces(user)
+	if err != nil {
+		return "", fmt.Errorf("failed to load namespaces: %w", err)
+	}
+	if !all {
+		switch {
+		case namespace != "" && !slices.Contains(names, namespace):
+			return "", fmt.Errorf("%w: you are not a member of namespace %s", errNamespaceDenied, namespace)
+		case namespace == "" && len(names) == 1:
+			namespace, assigned = names[0], true
+		case namespace == "" && len(names) > 1:
+			return "", fmt.Errorf("%w: set x-ontree namespace to one of %s", errNamespaceDenied, strings.Join(names, ", "))
+		}
+	}
+	if namespace == "" {
+		return composeContent, nil
+	}
+
+	ns, err := database.GetNamespace(namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to load namespace: %w", err)
+	}
+	if ns == nil {
+		return "", fmt.Errorf("%w: namespace %s does not exist", errNamespacePolicy, namespace)
+	}
+	if violations := s.namespaceViolations(ns, appName, &compose); len(violations) > 0 {
+		return "", fmt.Errorf("%w: %s", errNamespacePolicy, strings.Join(violations, "; "))
+	}
+	if assigned {
+		content, err := yamlutil.SetMetadataKey([]byte(composeContent), "namespace", namespace)
+		if err != nil {
+			return "", fmt.Errorf("failed to set the namespace: %w", err)
+		}
+		composeContent = string(content)
+	}
+	return composeContent, nil
+}
+
+// namespaceErrorStatus returns the HTTP status of an error of newAppNamespace
+func namespaceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNamespaceDenied):
+		return http.StatusForbidden
+	case errors.Is(err, errNamespacePolicy):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// handleAPINamespaces handles /api/namespaces: GET lists the namespaces, all for staff and
+// the user's own for others, POST creates or updates one, DELETE /api/namespaces/{name}
+// removes an empty one. Changes are for staff.
+func (s *Server) handleAPINamespaces(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/namespaces"), "/")
+
+	if r.Method == http.MethodGet && name == "" {
+		namespaces, err := database.GetNamespaces()
+		if err != nil {
+			logging.Errorf("Failed to load namespaces: %v", err)
+			http.Error(w, "Failed to load namespaces", http.StatusInternalServerError)
+			return
+		}
+		names, all, err := userNamespaces(user)
+		if err != nil {
+			logging.Errorf("Failed to load namespaces of user %s: %v", user.Username, err)
+			http.Error(w, "Failed to load namespaces", http.StatusInternalServerError)
+			return
+		}
+		visible := []database.Namespace{}
+		for _, ns := range namespaces {
+			if all || slices.Contains(names, ns.Name) {
+				visible = append(visible, ns)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"namespaces": visible}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+		return
+	}
+
+	if !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && name == "":
+		var ns database.Namespace
+		if err := json.NewDecoder(r.Body).Decode(&ns); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if !appNameRegex.MatchString(ns.Name) {
+			http.Error(w, "Invalid namespace name. Only lowercase letters, numbers, and hyphens are allowed", http.StatusBadRequest)
+			return
+		}
+		if ns.PortMin < 0 || ns.PortMax < 0 || ns.PortMin > 65535 || ns.PortMax > 65535 || (ns.PortMax > 0 && ns.PortMax < ns.PortMin) {
+			http.Error(w, "Invalid port range", http.StatusBadRequest)
+			return
+		}
+		if ns.MaxApps < 0 {
+			http.Error(w, "max_apps must not be negative", http.StatusBadRequest)
+			return
+		}
+		if ns.Members == nil {
+			ns.Members = []string{}
+		}
+		if err := database.SaveNamespace(&ns); err != nil {
+			if errors.Is(err, database.ErrUnknownUser) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logging.Errorf("Failed to save namespace %s: %v", ns.Name, err)
+			http.Error(w, "Failed to save namespace", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("Namespace %s saved by %s with members %v", ns.Name, user.Username, ns.Members)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ns); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	case r.Method == http.MethodDelete && name != "":
+		if apps := s.namespaceApps(name); len(apps) > 0 {
+			http.Error(w, fmt.Sprintf("Namespace %s still has the apps %s, move them first", name, strings.Join(apps, ", ")), http.StatusConflict)
+			return
+		}
+		deleted, err := database.DeleteNamespace(name)
+		if err != nil {
+			logging.Errorf("Failed to delete namespace %s: %v", name, err)
+			http.Error(w, "Failed to delete namespace", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Namespace not found", http.StatusNotFound)
+			return
+		}
+		logging.Infof("Namespace %s deleted by %s", name, user.Username)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIAppNamespace handles /api/apps/{appName}/namespace: GET returns the app's
+// namespace, PUT moves the app to another namespace, "" for none. Moving apps is for staff.
+func (s *Server) handleAPIAppNamespace(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	appDir := filepath.Join(s.config.AppsDir, appName)
+	composeFile := filepath.Join(appDir, "docker-compose.yml")
+	if _, err := os.Stat(composeFile); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"app": appName, "namespace": s.appNamespace(appName)}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	case http.MethodPut:
+		user := getUserFromContext(r.Context())
+		if user == nil || !user.IsStaff {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var request struct {
+			Namespace string `json:"namespace"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		compose, err := yamlutil.ReadComposeWithMetadata(composeFile)
+		if err != nil {
+			logging.Errorf("Failed to read docker-compose.yml for app %s: %v", appName, err)
+			http.Error(w, "Failed to read app configuration", http.StatusInternalServerError)
+			return
+		}
+		if request.Namespace != "" {
+			ns, err := database.GetNamespace(request.Namespace)
+			if err != nil {
+				logging.Errorf("Failed to load namespace %s: %v", request.Namespace, err)
+				http.Error(w, "Failed to load namespace", http.StatusInternalServerError)
+				return
+			}
+			if ns == nil {
+				http.Error(w, fmt.Sprintf("Namespace %s does not exist", request.Namespace), http.StatusNotFound)
+				return
+			}
+			if violations := s.namespaceViolations(ns, appName, compose); len(violations) > 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				if err := json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":      fmt.Sprintf("App %s can't be moved to %s", appName, ns.Name),
+					"violations": violations,
+				}); err != nil {
+					logging.Errorf("Failed to encode response: %v", err)
+				}
+				return
+			}
+		}
+
+		metadata := yamlutil.GetOnTreeMetadata(compose)
+		metadata.Namespace = request.Namespace
+		if err := yamlutil.UpdateComposeMetadata(appDir, metadata); err != nil {
+			logging.Errorf("Failed to update namespace of app %s: %v", appName, err)
+			http.Error(w, "Failed to update app configuration", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("App %s moved to namespace %q by %s", appName, request.Namespace, user.Username)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"app": appName, "namespace": request.Namespace}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// newNamespaceTestServer returns a server with the apps photos in family, homework in kids and
+// wiki in no namespace, and the users alice in family and bob in no namespace
+func newNamespaceTestServer(t *testing.T) (*Server, *database.User, *database.User) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() }) //nolint:errcheck,gosec // Test cleanup
+
+	if _, err := database.GetDB().Exec(`INSERT INTO users (username, password) VALUES ('alice', 'hash'), ('bob', 'hash')`); err != nil {
+		t.Fatalf("Failed to insert users: %v", err)
+	}
+	for _, ns := range []database.Namespace{
+		{Name: "family", SubdomainPrefix: "family-", PortMin: 20000, PortMax: 20999, MaxApps: 2, Members: []string{"alice"}},
+		{Name: "kids"},
+	} {
+		if err := database.SaveNamespace(&ns); err != nil {
+			t.Fatalf("Failed to save namespace: %v", err)
+		}
+	}
+
+	appsDir := filepath.Join(tmpDir, "apps")
+	for app, namespace := range map[string]string{"photos": "family", "homework": "kids", "wiki": ""} {
+		content := "services:\n  web:\n    image: nginx\n"
+		if namespace != "" {
+			content += "x-ontree:\n  namespace: " + namespace + "\n"
+		}
+		if err := os.MkdirAll(filepath.Join(appsDir, app), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(appsDir, app, "docker-compose.yml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+	alice := &database.User{Username: "alice"}
+	bob := &database.User{Username: "bob"}
+	for _, user := range []*database.User{alice, bob} {
+		if err := database.GetDB().QueryRow(`SELECT id FROM users WHERE username = ?`, user.Username).Scan(&user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, alice, bob
+}
+
+func TestNamespaceAccess(t *testing.T) {
+	s, alice, bob := newNamespaceTestServer(t)
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	tests := []struct {
+		user *database.User
+		app  string
+		want bool
+	}{
+		{staff, "photos", true},
+		{staff, "homework", true},
+		{alice, "photos", true},
+		{alice, "homework", false},
+		{alice, "wiki", false},
+		{bob, "wiki", true},
+		{bob, "photos", false},
+	}
+	for _, tt := range tests {
+		if got := s.canAccessApp(tt.user, tt.app); got != tt.want {
+			t.Errorf("canAccessApp(%s, %s) = %v, want %v", tt.user.Username, tt.app, got, tt.want)
+		}
+	}
+
+	// Apps of other namespaces are answered as missing
+	req := httptest.NewRequest(http.MethodGet, "/api/apps/homework/notes", nil)
+	req = req.WithContext(setUserContext(req.Context(), alice))
+	w := httptest.NewRecorder()
+	s.appRoutes(s.appAPIRoutes()).ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("alice reading homework = %d, want 404", w.Code)
+	}
+	// An escaped slash must not get past the check with a name the handlers would trim
+	for _, target := range []string{"/api/apps/homework%2F", "/api/apps/homework%2F/start", "/apps/homework%2F"} {
+		method := http.MethodGet
+		if strings.HasSuffix(target, "/start") {
+			method = http.MethodPost
+		}
+		req := httptest.NewRequest(method, target, nil)
+		req = req.WithContext(setUserContext(req.Context(), alice))
+		w := httptest.NewRecorder()
+		s.appRoutes(append(s.appPageRoutes(), s.appAPIRoutes()...)).ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("alice %s %s = %d, want 404", method, target, w.Code)
+		}
+	}
+}
+
+func TestNewAppNamespace(t *testing.T) {
+	s, alice, bob := newNamespaceTestServer(t)
+	compose := "services:\n  web:\n    image: nginx\n    ports:\n      - \"20080:80\"\n"
+
+	// Members of a single namespace create apps there
+	content, err := s.newAppNamespace(alice, "recipes", compose)
+	if err != nil {
+		t.Fatalf("newAppNamespace() error = %v", err)
+	}
+	if !strings.HasSuffix(content, "x-ontree:\n  namespace: family\n") {
+		t.Errorf("newAppNamespace() did not set the namespace:\n%s", content)
+	}
+
+	// The namespace's ports, subdomain prefix and app limit apply
+	badPort := strings.Replace(compose, "20080", "8080", 1)
+	if _, err := s.newAppNamespace(alice, "recipes", badPort); !errors.Is(err, errNamespacePolicy) || !strings.Contains(err.Error(), "host port 8080") {
+		t.Errorf("newAppNamespace() with a port outside the range = %v", err)
+	}
+	badSubdomain := compose + "x-ontree:\n  subdomain: recipes\n"
+	if _, err := s.newAppNamespace(alice, "recipes", badSubdomain); !errors.Is(err, errNamespacePolicy) {
+		t.Errorf("newAppNamespace() with a subdomain without the prefix = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.config.AppsDir, "recipes"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.config.AppsDir, "recipes", "docker-compose.yml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.newAppNamespace(alice, "chores", compose); !errors.Is(err, errNamespacePolicy) || !strings.Contains(err.Error(), "2 of 2 apps") {
+		t.Errorf("newAppNamespace() beyond the app limit = %v", err)
+	}
+
+	// Users may not put apps in namespaces they are not members of
+	if _, err := s.newAppNamespace(bob, "games", compose+"x-ontree:\n  namespace: kids\n"); !errors.Is(err, errNamespaceDenied) {
+		t.Errorf("newAppNamespace() of a non-member = %v", err)
+	}
+	if content, err := s.newAppNamespace(bob, "games", compose); err != nil || content != compose {
+		t.Errorf("newAppNamespace() of a user in no namespace = %q, %v", content, err)
+	}
+}
+
+func TestHandleAPIAppNamespace(t *testing.T) {
+	s, alice, _ := newNamespaceTestServer(t)
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	request := func(body string, user *database.User) *httptest.ResponseRecorder {
+		req := newAppRequest(http.MethodPut, "/api/apps/wiki/namespace", strings.NewReader(body))
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		s.handleAPIAppNamespace(w, req)
+		return w
+	}
+
+	if w := request(`{"namespace": "kids"}`, alice); w.Code != http.StatusUnauthorized {
+		t.Errorf("move by a member = %d, want 401", w.Code)
+	}
+	if w := request(`{"namespace": "work"}`, staff); w.Code != http.StatusNotFound {
+		t.Errorf("move to a missing namespace = %d, want 404", w.Code)
+	}
+	if w := request(`{"namespace": "kids"}`, staff); w.Code != http.StatusOK {
+		t.Fatalf("move = %d %s", w.Code, w.Body.String())
+	}
+	metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(s.config.AppsDir, "wiki"))
+	if err != nil || metadata.Namespace != "kids" {
+		t.Errorf("namespace after the move = %+v, %v", metadata, err)
+	}
+}
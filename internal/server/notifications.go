@@ -0,0 +1,158 @@
+package server
+
+import (
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
+)
+
+// notifyAdmins emails a message to all active staff users with an email address and
+// adds it to the activity feed. Without SMTP it is only added to the feed.
+func (s *Server) notifyAdmins(subject, body string) {
+	recordActivity(database.ActivityEvent{Category: database.ActivityAlert, Title: subject, Detail: body})
+
+	smtpConfig := s.smtpConfig()
+	if !smtpConfig.Enabled() || s.db == nil {
+		return
+	}
+	recipients, err := s.adminEmails()
+	if err != nil {
+		logging.Errorf("Failed to get admin emails: %v", err)
+		return
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	go func() {
+		if err := notify.SendEmail(smtpConfig, recipients, subject, body); err != nil {
+			logging.Errorf("Failed to send notification email: %v", err)
+		}
+	}()
+}
+
+// notifyEvent sends an event to the webhooks subscribed to it, and emails it to the
+// admins if it is one of the email events chosen in the settings
+func (s *Server) notifyEvent(event notify.Event) {
+	if database.GetDB() == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Node == "" {
+		event.Node, _ = os.Hostname() //nolint:errcheck // The event is useful without it
+	}
+
+	s.notifyWebhooks(event)
+
+	settings, err := database.GetEmailSettings()
+	if err != nil {
+		logging.Errorf("Failed to get email settings: %v", err)
+		return
+	}
+	if !slices.Contains(settings.Events, event.Type) {
+		return
+	}
+	smtpConfig := s.smtpConfig()
+	if !smtpConfig.Enabled() {
+		return
+	}
+	recipients, err := s.adminEmails()
+	if err != nil {
+		logging.Errorf("Failed to get admin emails: %v", err)
+		return
+	}
+	if len(recipients) == 0 {
+		return
+	}
+	subject, body, err := notify.RenderEventEmail(event)
+	if err != nil {
+		logging.Errorf("Failed to render email for %s: %v", event.Type, err)
+		return
+	}
+	go func() {
+		if err := notify.SendEmail(smtpConfig, recipients, subject, body); err != nil {
+			logging.Errorf("Failed to email %s: %v", event.Type, err)
+		}
+	}()
+}
+
+// eventSubscribed returns true if a webhook or the email notifications subscribe to one
+// of the event types, to skip checks nobody would hear about
+func (s *Server) eventSubscribed(eventTypes ...string) bool {
+	if database.GetDB() == nil {
+		return false
+	}
+	settings, err := database.GetEmailSettings()
+	emailEnabled := err == nil && s.smtpConfig().Enabled()
+	for _, eventType := range eventTypes {
+		if emailEnabled && slices.Contains(settings.Events, eventType) {
+			return true
+		}
+		if hooks, err := database.GetWebhooksForEvent(eventType); err == nil && len(hooks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// adminEmails returns the email addresses of all active staff users that have one
+func (s *Server) adminEmails() ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT email FROM users
+		WHERE is_staff = 1 AND is_active = 1 AND email IS NOT NULL AND email != ''
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	var recipients []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, email)
+	}
+	return recipients, rows.Err()
+}
+
+// smtpConfig returns the email settings. A mail server set in the settings takes the
+// place of the one in the configuration file, unless SMTP_HOST is set in the environment.
+func (s *Server) smtpConfig() notify.SMTPConfig {
+	cfg := notify.SMTPConfig{
+		Host:     s.config.SMTPHost,
+		Port:     s.config.SMTPPort,
+		Security: s.config.SMTPSecurity,
+		Username: s.config.SMTPUsername,
+		Password: s.config.SMTPPassword,
+		From:     s.config.SMTPFrom,
+	}
+	if smtpFromEnvironment() || database.GetDB() == nil {
+		return cfg
+	}
+	settings, err := database.GetEmailSettings()
+	if err != nil || settings.Host == "" {
+		return cfg
+	}
+	return notify.SMTPConfig{
+		Host:     settings.Host,
+		Port:     settings.Port,
+		Security: settings.Security,
+		Username: settings.Username,
+		Password: settings.Password,
+		From:     settings.From,
+	}
+}
+
+// smtpFromEnvironment returns true if the mail server is set in the environment, which
+// the settings can't change
+func smtpFromEnvironment() bool {
+	return os.Getenv("SMTP_HOST") != ""
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/caddy"
+	"github.com/ontree-co/treeos/internal/logging"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+// orphanRoute is the kind of Caddy routes in the orphan list, next to the Docker kinds
+const orphanRoute = "route"
+
+// upstreamDialTimeout bounds the check whether anything listens behind a route
+const upstreamDialTimeout = time.Second
+
+// appRoutePrefixes are the config IDs of routes TreeOS creates for apps, see
+// caddy.CreateRouteConfig and caddy.InternalRouteID
+var appRoutePrefixes = []string{"route-for-", "internal-route-for-"}
+
+// upstreamReachable reports whether something accepts connections at a dial address
+func upstreamReachable(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, upstreamDialTimeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// deadRoutes returns the routes none of whose upstreams accept connections. Routes of
+// existing apps are left out, they are dead while the app is stopped and come back
+// when it starts. Routes without an ID weren't created by TreeOS and are left alone.
+func deadRoutes(routes []caddy.RouteConfig, appIDs map[string]bool, reachable func(string) bool) []dockerruntime.Orphan {
+	orphans := []dockerruntime.Orphan{}
+	for _, route := range routes {
+		if route.ID == "" {
+			continue
+		}
+		ownedByApp := false
+		for _, prefix := range appRoutePrefixes {
+			if appID, ok := strings.CutPrefix(route.ID, prefix); ok && appIDs[appID] {
+				ownedByApp = true
+			}
+		}
+		if ownedByApp {
+			continue
+		}
+
+		var upstreams []string
+		alive := false
+		for _, handler := range route.Handle {
+			for _, upstream := range handler.Upstreams {
+				upstreams = append(upstreams, upstream.Dial)
+				alive = alive || reachable(upstream.Dial)
+			}
+		}
+		if alive || len(upstreams) == 0 {
+			continue
+		}
+
+		var hosts []string
+		for _, match := range route.Match {
+			hosts = append(hosts, match.Host...)
+		}
+		orphans = append(orphans, dockerruntime.Orphan{
+			Kind:   orphanRoute,
+			ID:     route.ID,
+			Name:   strings.Join(hosts, ", "),
+			Detail: strings.Join(upstreams, ", "),
+		})
+	}
+	return orphans
+}
+
+// findOrphans lists the Docker resources no app owns and the Caddy routes pointing at dead
+// ports. Caddy being unavailable is logged, the Docker resources are listed anyway.
+func (s *Server) findOrphans(ctx context.Context) ([]dockerruntime.Orphan, error) {
+	runtimeClient, err := s.getRuntimeClient()
+	if err != nil {
+		return nil, err
+	}
+	orphans, err := runtimeClient.FindOrphans(ctx, s.config.AppsDir)
+	if err != nil {
+		return nil, err
+	}
+	if s.caddyClient == nil {
+		return orphans, nil
+	}
+
+	routes, err := s.caddyClient.ListRoutes()
+	if err != nil {
+		logging.Warnf("Failed to list Caddy routes for orphan detection: %v", err)
+		return orphans, nil
+	}
+	apps, err := s.scanApps()
+	if err != nil {
+		return nil, err
+	}
+	appIDs := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		appIDs[strings.ToLower(app.Name)] = true
+	}
+	return append(orphans, deadRoutes(routes, appIDs, upstreamReachable)...), nil
+}
+
+// removeOrphan removes one entry of the orphan list after checking it is still an orphan
+func (s *Server) removeOrphan(ctx context.Context, kind, id string) error {
+	if kind != orphanRoute {
+		runtimeClient, err := s.getRuntimeClient()
+		if err != nil {
+			return err
+		}
+		return runtimeClient.RemoveOrphan(ctx, s.config.AppsDir, kind, id)
+	}
+
+	if s.caddyClient == nil {
+		return fmt.Errorf("caddy is not available")
+	}
+	orphans, err := s.findOrphans(ctx)
+	if err != nil {
+		return err
+	}
+	for _, orphan := range orphans {
+		if orphan.Kind == orphanRoute && orphan.ID == id {
+			return s.caddyClient.DeleteRoute(id)
+		}
+	}
+	return fmt.Errorf("route %s is not an orphan", id)
+}
+
+// handleOrphans renders the page listing resources left behind by deleted apps
+func (s *Server) handleOrphans(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data := s.baseTemplateData(user)
+	orphans, err := s.findOrphans(r.Context())
+	if err != nil {
+		logging.Errorf("Failed to find orphaned resources: %v", err)
+		data["OrphansError"] = err.Error()
+	}
+	data["Orphans"] = orphans
+	data["CaddyAvailable"] = s.caddyClient != nil
+	orphanVolumes := 0
+	for _, orphan := range orphans {
+		if orphan.Kind == dockerruntime.OrphanVolume {
+			orphanVolumes++
+		}
+	}
+	data["OrphanVolumes"] = orphanVolumes
+
+	tmpl, ok := s.templates["orphans"]
+	if !ok {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		logging.Errorf("Error rendering template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
+
+// handleAPIOrphans handles /api/orphans. GET lists orphaned resources, DELETE with
+// ?kind=...&id=... removes one of them.
+func (s *Server) handleAPIOrphans(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		orphans, err := s.findOrphans(r.Context())
+		if err != nil {
+			logging.Errorf("Failed to find orphaned resources: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to find orphaned resources: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"orphans": orphans}); err != nil {
+			logging.Errorf("Failed to encode orphans: %v", err)
+		}
+
+	case http.MethodDelete:
+		kind, id := r.URL.Query().Get("kind"), r.URL.Query().Get("id")
+		switch kind {
+		case dockerruntime.OrphanContainer, dockerruntime.OrphanVolume, dockerruntime.OrphanNetwork, orphanRoute:
+		default:
+			http.Error(w, "kind must be container, volume, network or route", http.StatusBadRequest)
+			return
+		}
+		if id == "" {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		if err := s.removeOrphan(r.Context(), kind, id); err != nil {
+			logging.Errorf("Failed to remove orphaned %s %s: %v", kind, id, err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		logging.Infof("User %s removed orphaned %s %s", user.Username, kind, id)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/caddy"
+)
+
+func TestDeadRoutes(t *testing.T) {
+	routes := []caddy.RouteConfig{
+		*caddy.CreateRouteConfig("web", "web", 8080, "example.com", "", ""),
+		*caddy.CreateRouteConfig("deleted", "deleted", 8081, "example.com", "", ""),
+		*caddy.CreateRouteConfig("moved", "moved", 8082, "example.com", "", ""),
+		*caddy.CreateInternalRouteConfig(caddy.InternalRouteID("gone"), "gone.home.arpa", 8083),
+		{Handle: []caddy.Handler{{Handler: "reverse_proxy", Upstreams: []caddy.Upstream{{Dial: "localhost:8084"}}}}},
+		{ID: "static", Handle: []caddy.Handler{{Handler: "static_response"}}},
+	}
+	appIDs := map[string]bool{"web": true}
+	listening := func(addr string) bool { return addr == "localhost:8082" || addr == "127.0.0.1:8082" }
+
+	orphans := deadRoutes(routes, appIDs, listening)
+	if len(orphans) != 2 {
+		t.Fatalf("deadRoutes() = %+v, want the routes of deleted and gone", orphans)
+	}
+	if orphans[0].ID != "route-for-deleted" || orphans[0].Kind != orphanRoute || orphans[0].Name != "deleted.example.com" {
+		t.Errorf("deadRoutes()[0] = %+v", orphans[0])
+	}
+	if orphans[1].ID != caddy.InternalRouteID("gone") || orphans[1].Name != "gone.home.arpa" {
+		t.Errorf("deadRoutes()[1] = %+v", orphans[1])
+	}
+}
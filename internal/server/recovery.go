@@ -0,0 +1,315 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/embeds"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
+)
+
+const (
+	// databaseBackupInterval is how often the database is backed up
+	databaseBackupInterval = 24 * time.Hour
+	// databaseBackupsKept is how many daily backups are kept
+	databaseBackupsKept = 7
+)
+
+// recoveryServer serves the recovery UI while the database is corrupt
+type recoveryServer struct {
+	cfg       *config.Config
+	cause     error
+	token     string
+	tokenPath string
+	tmpl      *template.Template
+	done      chan struct{}
+	doneOnce  sync.Once
+	mu        sync.Mutex
+}
+
+// RunRecovery serves a minimal recovery UI instead of the dashboard when the database failed
+// its integrity check. It returns once the database was repaired so normal startup can continue.
+//
+// There are no user accounts without a database, so actions need a one-time token that is
+// written to the log and to a file next to the database that only root can read.
+func RunRecovery(cfg *config.Config, cause error) error {
+	tmpl, err := embeds.ParseTemplate(filepath.Join("templates", "dashboard", "recovery.html"))
+	if err != nil {
+		return fmt.Errorf("failed to parse recovery template: %w", err)
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return fmt.Errorf("failed to generate recovery token: %w", err)
+	}
+	rs := &recoveryServer{
+		cfg:       cfg,
+		cause:     cause,
+		token:     hex.EncodeToString(tokenBytes),
+		tokenPath: cfg.StatePath("recovery-token"),
+		tmpl:      tmpl,
+		done:      make(chan struct{}),
+	}
+	if err := os.WriteFile(rs.tokenPath, []byte(rs.token+"\n"), 0600); err != nil {
+		logging.Warnf("Failed to write recovery token file: %v", err)
+	}
+	defer os.Remove(rs.tokenPath) //nolint:errcheck // Token is useless after recovery
+
+	staticHandler, err := embeds.StaticHandler()
+	if err != nil {
+		return fmt.Errorf("failed to get static filesystem: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/static/", http.StripPrefix("/static/", staticHandler))
+	mux.HandleFunc("/recovery", rs.handleAction)
+	mux.HandleFunc("/", rs.handlePage)
+
+	srv := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logging.Errorf("Database %s is corrupt: %v", cfg.DatabasePath, cause)
+	logging.Errorf("Starting in RECOVERY MODE on %s. Recovery token: %s (also in %s)", cfg.ListenAddr, rs.token, rs.tokenPath)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("recovery server failed: %w", err)
+	case <-rs.done:
+		// Give the browser time to receive the success page
+		time.Sleep(time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logging.Warnf("Failed to stop recovery server: %v", err)
+		}
+		logging.Infof("Database recovered, continuing normal startup")
+		return nil
+	}
+}
+
+// recoveryPageData is shown on the recovery page
+type recoveryPageData struct {
+	Cause        string
+	DatabasePath string
+	AppsDir      string
+	AppCount     int
+	Backups      []database.BackupInfo
+	Error        string
+	Success      string
+	Details      []string
+}
+
+func (rs *recoveryServer) pageData() recoveryPageData {
+	data := recoveryPageData{
+		Cause:        rs.cause.Error(),
+		DatabasePath: rs.cfg.DatabasePath,
+		AppsDir:      rs.cfg.AppsDir,
+	}
+	if entries, err := os.ReadDir(rs.cfg.AppsDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				data.AppCount++
+			}
+		}
+	}
+	backups, err := database.ListBackups(rs.cfg.DatabasePath)
+	if err != nil {
+		logging.Warnf("Failed to list database backups: %v", err)
+	}
+	data.Backups = backups
+	return data
+}
+
+func (rs *recoveryServer) render(w http.ResponseWriter, status int, data recoveryPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := rs.tmpl.ExecuteTemplate(w, "recovery", data); err != nil {
+		logging.Errorf("Error rendering recovery template: %v", err)
+	}
+}
+
+func (rs *recoveryServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	// Every page leads to recovery, the API isn't available without a database
+	status := http.StatusServiceUnavailable
+	if r.URL.Path == "/" {
+		status = http.StatusOK
+	}
+	rs.render(w, status, rs.pageData())
+}
+
+// handleAction runs one of the recovery options: restore, rebuild or fresh
+func (rs *recoveryServer) handleAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	data := rs.pageData()
+	if subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(rs.token)) != 1 {
+		logging.Warnf("Recovery attempt with a wrong token from %s", clientIP(r))
+		data.Error = "The recovery token is not correct. It is printed in the TreeOS log and stored in " + rs.tokenPath + "."
+		rs.render(w, http.StatusForbidden, data)
+		return
+	}
+
+	// One action at a time, they all replace the database file
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	action := r.FormValue("action")
+	var (
+		moved string
+		err   error
+	)
+	switch action {
+	case "restore":
+		moved, err = database.RestoreBackup(rs.cfg.DatabasePath, r.FormValue("backup"))
+		data.Success = "The database was restored from " + r.FormValue("backup") + "."
+	case "rebuild":
+		var result *database.RebuildResult
+		result, moved, err = database.DumpAndRebuild(rs.cfg.DatabasePath)
+		data.Success = "The database was rebuilt from the readable data."
+		if result != nil {
+			for table, count := range result.Tables {
+				if message, failed := result.Errors[table]; failed {
+					data.Details = append(data.Details, fmt.Sprintf("%s: %d rows saved, rest unreadable (%s)", table, count, message))
+				} else if count > 0 {
+					data.Details = append(data.Details, fmt.Sprintf("%s: %d rows", table, count))
+				}
+			}
+		}
+	case "fresh":
+		moved, err = database.StartFresh(rs.cfg.DatabasePath)
+		data.Success = "A new database will be created. Your apps are kept and show up again on the dashboard. You will have to create an admin account again."
+	default:
+		err = errors.New("unknown recovery action")
+	}
+	if err == nil {
+		err = database.CheckIntegrity(rs.cfg.DatabasePath)
+	}
+	if err != nil {
+		logging.Errorf("Recovery action %s failed: %v", action, err)
+		data.Success = ""
+		data.Error = "Recovery failed: " + err.Error()
+		rs.render(w, http.StatusInternalServerError, data)
+		return
+	}
+
+	logging.Infof("Recovery action %s succeeded, corrupt database kept at %s", action, moved)
+	if moved != "" {
+		data.Details = append(data.Details, "The corrupt database was kept at "+moved+".")
+	}
+	rs.render(w, http.StatusOK, data)
+	rs.doneOnce.Do(func() { close(rs.done) })
+}
+
+// startDatabaseBackups backs up the database daily so recovery mode has something to restore
+func (s *Server) startDatabaseBackups() {
+	go func() {
+		backup := func() {
+			if path, err := database.Backup(s.config.DatabasePath, databaseBackupsKept); err != nil {
+				logging.Errorf("Failed to back up database: %v", err)
+				s.notifyEvent(notify.Event{
+					Type:    notify.EventBackupFailed,
+					Title:   "Database backup failed",
+					Message: err.Error(),
+				})
+			} else {
+				logging.Debugf("Backed up database to %s", path)
+			}
+		}
+
+		// Skip the startup backup if a recent one exists, restarts shouldn't push out older backups
+		if backups, err := database.ListBackups(s.config.DatabasePath); err != nil || len(backups) == 0 ||
+			time.Since(backups[0].CreatedAt) > databaseBackupInterval {
+			backup()
+		}
+
+		ticker := time.NewTicker(databaseBackupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				backup()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// databaseBackup is a backup in the answers of /api/system/backups
+type databaseBackup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleAPIBackups handles /api/system/backups: GET lists the database backups, newest
+// first, POST backs up the database now. Like the daily backups, only the newest ones
+// are kept.
+func (s *Server) handleAPIBackups(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		path, err := database.Backup(s.config.DatabasePath, databaseBackupsKept)
+		if err != nil {
+			logging.Errorf("Failed to back up database: %v", err)
+			http.Error(w, "Failed to back up database", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s backed up the database to %s", user.Username, path)
+		status = http.StatusCreated
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	backups, err := database.ListBackups(s.config.DatabasePath)
+	if err != nil {
+		logging.Errorf("Failed to list database backups: %v", err)
+		http.Error(w, "Failed to list backups", http.StatusInternalServerError)
+		return
+	}
+	list := make([]databaseBackup, 0, len(backups))
+	for _, backup := range backups {
+		list = append(list, databaseBackup{Name: backup.Name, Size: backup.Size, CreatedAt: backup.CreatedAt})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"backups": list}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
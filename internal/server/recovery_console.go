@@ -0,0 +1,282 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/embeds"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+const (
+	// recoveryConsoleActionTimeout bounds a runtime restart or cleanup started from the console
+	recoveryConsoleActionTimeout = 5 * time.Minute
+
+	// Actions a client IP may submit per window, so passwords can't be guessed
+	recoveryConsoleRateLimit  = 10
+	recoveryConsoleRateWindow = 15 * time.Minute
+)
+
+// recoveryConsoleActions are the actions the console offers
+var recoveryConsoleActions = map[string]bool{
+	"restart-runtime": true,
+	"free-disk":       true,
+	"restore-backup":  true,
+	"restart-treeos":  true,
+}
+
+// tailscaleIPv6Range is the range Tailscale assigns IPv6 node addresses from
+var tailscaleIPv6Range = &net.IPNet{IP: net.ParseIP("fd7a:115c:a1e0::"), Mask: net.CIDRMask(48, 128)}
+
+// runRecoveryCommand runs a host command of a console action, replaceable in tests
+var runRecoveryCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput() //nolint:gosec // Fixed commands of the console actions
+}
+
+// isTailnetAddress reports whether ip is a Tailscale node address
+func isTailnetAddress(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && (tailscaleRange.Contains(addr) || tailscaleIPv6Range.Contains(addr))
+}
+
+// recoveryConsolePageData is shown on the recovery console
+type recoveryConsolePageData struct {
+	Version       string
+	Runtime       string
+	DiskPath      string
+	DiskFree      string
+	DiskUsed      int
+	Backups       []database.BackupInfo
+	Username      string
+	Error         string
+	Success       string
+	Output        string
+	RestartIssued bool
+}
+
+// startRecoveryConsole serves the recovery console on its own listener, so it keeps answering
+// when the web UI is broken or hangs. It offers a few fixed actions instead of a shell, and
+// every action needs the password of an admin again.
+func (s *Server) startRecoveryConsole() {
+	if s.config.RecoveryConsoleAddr == "" {
+		return
+	}
+	tmpl, err := embeds.ParseTemplate(filepath.Join("templates", "dashboard", "recovery_console.html"))
+	if err != nil {
+		logging.Errorf("Failed to parse recovery console template: %v", err)
+		return
+	}
+
+	s.recoveryConsole = &http.Server{
+		Addr:              s.config.RecoveryConsoleAddr,
+		Handler:           s.recoveryConsoleHandler(tmpl),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logging.Infof("Recovery console listening on %s for Tailscale addresses", s.config.RecoveryConsoleAddr)
+		if err := s.recoveryConsole.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Errorf("Recovery console failed: %v", err)
+		}
+	}()
+}
+
+// recoveryConsoleHandler serves the console page and runs its actions. Requests are checked
+// by their connection address, forwarded headers don't count: a proxy in front of the console
+// would make it reachable from outside the tailnet.
+func (s *Server) recoveryConsoleHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil || !isTailnetAddress(host) {
+			http.Error(w, "The recovery console is only reachable over Tailscale", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		data := s.recoveryConsoleData()
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			status = s.runRecoveryConsoleAction(r, host, &data)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if err := tmpl.ExecuteTemplate(w, "recovery_console", data); err != nil {
+			logging.Errorf("Error rendering recovery console template: %v", err)
+		}
+	}
+}
+
+func (s *Server) recoveryConsoleData() recoveryConsolePageData {
+	data := recoveryConsolePageData{
+		Version:  s.versionInfo.Version,
+		Runtime:  recoveryRuntimeName(),
+		DiskPath: s.config.AppsDir,
+	}
+	if usage := storage.Usages([]storage.Root{{Path: s.config.AppsDir}})[0]; usage.Available {
+		data.DiskFree = usage.FreeLabel()
+		data.DiskUsed = usage.UsedPercent()
+	}
+	backups, err := database.ListBackups(s.config.DatabasePath)
+	if err != nil {
+		logging.Warnf("Failed to list database backups: %v", err)
+	}
+	data.Backups = backups
+	return data
+}
+
+// runRecoveryConsoleAction checks the admin's credentials and runs the requested action of
+// the client at host
+func (s *Server) runRecoveryConsoleAction(r *http.Request, host string, data *recoveryConsolePageData) int {
+	if err := r.ParseForm(); err != nil {
+		data.Error = "Failed to parse form"
+		return http.StatusBadRequest
+	}
+	data.Username = r.FormValue("username")
+	action := r.FormValue("action")
+	if !recoveryConsoleActions[action] {
+		data.Error = "Unknown recovery action."
+		return http.StatusBadRequest
+	}
+	if !s.recoveryConsoleAllowed(host, time.Now()) {
+		logging.Warnf("Recovery console actions from %s are rate limited", host)
+		data.Error = "Too many attempts, try again later."
+		return http.StatusTooManyRequests
+	}
+	if err := s.recoveryConsoleAuthenticate(data.Username, r.FormValue("password")); err != nil {
+		logging.Warnf("Recovery console login of %q from %s failed: %v", data.Username, r.RemoteAddr, err)
+		data.Error = "Only admins can use the recovery console. Check the username and password."
+		return http.StatusForbidden
+	}
+
+	// One action at a time, a cleanup during a runtime restart fails in confusing ways
+	if !s.recoveryConsoleMu.TryLock() {
+		data.Error = "Another recovery action is still running."
+		return http.StatusConflict
+	}
+	defer s.recoveryConsoleMu.Unlock()
+
+	logging.Warnf("Recovery console action %s by %s from %s", action, data.Username, r.RemoteAddr)
+
+	ctx, cancel := context.WithTimeout(r.Context(), recoveryConsoleActionTimeout)
+	defer cancel()
+
+	var (
+		output []byte
+		err    error
+	)
+	switch action {
+	case "restart-runtime":
+		output, err = restartContainerRuntime(ctx)
+		data.Success = "The container runtime was restarted. Apps with a restart policy come back on their own."
+	case "free-disk":
+		output, err = freeRuntimeDiskSpace(ctx)
+		data.Success = "Unused images and build cache were removed."
+	case "restore-backup":
+		err = database.ScheduleRestore(s.config.DatabasePath, r.FormValue("backup"))
+		data.Success = "TreeOS restarts and restores the database from " + r.FormValue("backup") + ". Changes since the backup are lost."
+		data.RestartIssued = err == nil
+	case "restart-treeos":
+		data.Success = "TreeOS restarts. The web UI is back in a few seconds."
+		data.RestartIssued = true
+	}
+	data.Output = strings.TrimSpace(string(output))
+	if err != nil {
+		logging.Errorf("Recovery console action %s failed: %v", action, err)
+		data.Success = ""
+		data.RestartIssued = false
+		data.Error = "The action failed: " + err.Error()
+		return http.StatusInternalServerError
+	}
+
+	if data.RestartIssued {
+		// The service manager starts TreeOS again, a scheduled restore is applied on startup
+		go func() {
+			time.Sleep(time.Second)
+			s.Shutdown()
+		}()
+	}
+	fresh := s.recoveryConsoleData()
+	data.DiskFree, data.DiskUsed, data.Backups = fresh.DiskFree, fresh.DiskUsed, fresh.Backups
+	return http.StatusOK
+}
+
+// recoveryConsoleAuthenticate checks that the credentials belong to an active admin
+func (s *Server) recoveryConsoleAuthenticate(username, password string) error {
+	if username == "" || password == "" {
+		return errors.New("missing credentials")
+	}
+	if database.GetDB() == nil {
+		return errors.New("database not available")
+	}
+	user, err := s.authenticateUser(username, password)
+	if err != nil {
+		return err
+	}
+	if user.Role != database.RoleAdmin {
+		return errors.New("not an admin")
+	}
+	return nil
+}
+
+// recoveryConsoleAllowed counts an action of ip and reports whether it is within the rate limit
+func (s *Server) recoveryConsoleAllowed(ip string, now time.Time) bool {
+	s.recoveryAttemptsMu.Lock()
+	defer s.recoveryAttemptsMu.Unlock()
+
+	if s.recoveryAttempts == nil {
+		s.recoveryAttempts = make(map[string]*attemptWindow)
+	}
+	return countAttempt(s.recoveryAttempts, ip, now, recoveryConsoleRateLimit, recoveryConsoleRateWindow)
+}
+
+// recoveryRuntimeName returns the container runtime of the host, Podman if it is installed
+func recoveryRuntimeName() string {
+	if _, err := exec.LookPath("podman"); err == nil {
+		return "podman"
+	}
+	return "docker"
+}
+
+// restartContainerRuntime restarts the Docker daemon or the Podman API socket
+func restartContainerRuntime(ctx context.Context) ([]byte, error) {
+	if recoveryRuntimeName() == "podman" {
+		if os.Geteuid() != 0 {
+			return runRecoveryCommand(ctx, "systemctl", "--user", "restart", "podman.socket")
+		}
+		return runRecoveryCommand(ctx, "systemctl", "restart", "podman.socket")
+	}
+	return runRecoveryCommand(ctx, "systemctl", "restart", "docker")
+}
+
+// freeRuntimeDiskSpace removes dangling images and the build cache. Images of stopped apps are
+// kept, removing them would make the next start depend on the registry.
+func freeRuntimeDiskSpace(ctx context.Context) ([]byte, error) {
+	name := recoveryRuntimeName()
+	var output []byte
+	for _, args := range [][]string{
+		{"image", "prune", "--force"},
+		{"builder", "prune", "--force"},
+	} {
+		out, err := runRecoveryCommand(ctx, name, args...)
+		output = append(output, out...)
+		if err != nil {
+			return output, fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+		}
+	}
+	return output, nil
+}
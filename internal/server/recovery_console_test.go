@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/embeds"
+)
+
+func TestRecoveryConsole(t *testing.T) {
+	dir := t.TempDir()
+	if err := database.Initialize(filepath.Join(dir, "ontree.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck,gosec // Test cleanup
+
+	s := &Server{config: &config.Config{DatabasePath: filepath.Join(dir, "ontree.db"), AppsDir: dir}}
+	if _, err := s.createUser("admin", "admin-secret", "", true, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.createUser("staff", "staff-secret", "", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.createUser("operator", "operator-secret", "", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var commands []string
+	origRunRecoveryCommand := runRecoveryCommand
+	defer func() { runRecoveryCommand = origRunRecoveryCommand }()
+	runRecoveryCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return []byte("Total reclaimed space: 0B"), nil
+	}
+
+	tmpl, err := embeds.ParseTemplate(filepath.Join("templates", "dashboard", "recovery_console.html"))
+	if err != nil {
+		t.Fatalf("failed to parse recovery console template: %v", err)
+	}
+	handler := s.recoveryConsoleHandler(tmpl)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		form       url.Values
+		wantStatus int
+	}{
+		{name: "page over tailnet", remoteAddr: "100.101.102.103:50000", wantStatus: http.StatusOK},
+		{name: "tailnet IPv6", remoteAddr: "[fd7a:115c:a1e0::1]:50000", wantStatus: http.StatusOK},
+		{name: "LAN", remoteAddr: "192.168.1.20:50000", wantStatus: http.StatusForbidden},
+		{name: "loopback", remoteAddr: "127.0.0.1:50000", wantStatus: http.StatusForbidden},
+		{
+			name:       "wrong password",
+			remoteAddr: "100.101.102.103:50000",
+			form:       url.Values{"username": {"admin"}, "password": {"wrong"}, "action": {"free-disk"}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "not an admin",
+			remoteAddr: "100.101.102.103:50000",
+			form:       url.Values{"username": {"operator"}, "password": {"operator-secret"}, "action": {"free-disk"}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "admin without superuser",
+			remoteAddr: "100.101.102.103:50000",
+			form:       url.Values{"username": {"staff"}, "password": {"staff-secret"}, "action": {"free-disk"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown action",
+			remoteAddr: "100.101.102.103:50000",
+			form:       url.Values{"username": {"admin"}, "password": {"admin-secret"}, "action": {"shell"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing backup",
+			remoteAddr: "100.101.102.103:50000",
+			form:       url.Values{"username": {"admin"}, "password": {"admin-secret"}, "action": {"restore-backup"}, "backup": {"missing.db"}},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "free disk",
+			remoteAddr: "100.101.102.103:50000",
+			form:       url.Values{"username": {"admin"}, "password": {"admin-secret"}, "action": {"free-disk"}},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.form != nil {
+				req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			req.RemoteAddr = tt.remoteAddr
+			// Forwarded headers must not open the console to other networks
+			req.Header.Set("X-Forwarded-For", "100.64.0.1")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	// Two free-disk actions with two commands each
+	if len(commands) != 4 || !strings.HasSuffix(commands[0], "image prune --force") {
+		t.Errorf("expected the prune commands only after a valid login, got %v", commands)
+	}
+
+	// Guessing passwords is rate limited, the right password doesn't help afterwards
+	post := func(password string) int {
+		form := url.Values{"username": {"admin"}, "password": {password}, "action": {"free-disk"}}
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = "100.101.102.104:50000"
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+	for i := 0; i < recoveryConsoleRateLimit; i++ {
+		if code := post("guess"); code != http.StatusForbidden {
+			t.Fatalf("guess %d: status = %d, want %d", i+1, code, http.StatusForbidden)
+		}
+	}
+	if code := post("admin-secret"); code != http.StatusTooManyRequests {
+		t.Errorf("status over the limit = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if len(commands) != 4 {
+		t.Errorf("rate limited request ran commands: %v", commands)
+	}
+}
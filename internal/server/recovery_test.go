@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/embeds"
+)
+
+func TestRecoveryActions(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "ontree.db")
+	if err := os.WriteFile(dbPath, []byte("not a database"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cause := database.CheckIntegrity(dbPath)
+	if !errors.Is(cause, database.ErrCorrupt) {
+		t.Fatalf("expected corrupt database, got %v", cause)
+	}
+
+	tmpl, err := embeds.ParseTemplate(filepath.Join("templates", "dashboard", "recovery.html"))
+	if err != nil {
+		t.Fatalf("failed to parse recovery template: %v", err)
+	}
+	rs := &recoveryServer{
+		cfg:   &config.Config{DatabasePath: dbPath, AppsDir: filepath.Join(dir, "apps")},
+		cause: cause,
+		token: "secret",
+		tmpl:  tmpl,
+		done:  make(chan struct{}),
+	}
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/recovery", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		rs.handleAction(rec, req)
+		return rec
+	}
+
+	if rec := post(url.Values{"token": {"wrong"}, "action": {"fresh"}}); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a wrong token, got %d", rec.Code)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Fatalf("database must not change without a valid token: %v", err)
+	}
+
+	if rec := post(url.Values{"token": {"secret"}, "action": {"restore"}, "backup": {"missing.db"}}); rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 restoring a missing backup, got %d", rec.Code)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Fatalf("a failed restore must keep the database in place: %v", err)
+	}
+
+	rec := post(url.Values{"token": {"secret"}, "action": {"fresh"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 starting fresh, got %d: %s", rec.Code, rec.Body.String())
+	}
+	select {
+	case <-rs.done:
+	default:
+		t.Error("expected recovery to finish after a successful action")
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("expected the corrupt database to be moved aside, got %v", err)
+	}
+}
+
+func TestHandleAPIBackups(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "treeos.db")
+	if err := database.Initialize(dbPath); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() }) //nolint:errcheck,gosec // Test cleanup
+	s := &Server{config: &config.Config{DatabasePath: dbPath}}
+
+	request := func(method string, user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/system/backups", nil)
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		s.handleAPIBackups(w, req)
+		return w
+	}
+
+	if w := request(http.MethodPost, &database.User{Username: "bob"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST by a non-admin = %d, want 401", w.Code)
+	}
+	admin := &database.User{Username: "admin", IsStaff: true}
+	if w := request(http.MethodGet, admin); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"backups":[]`) {
+		t.Errorf("GET without backups = %d %s", w.Code, w.Body.String())
+	}
+	w := request(http.MethodPost, admin)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"name":"treeos-`) {
+		t.Fatalf("POST = %d %s", w.Code, w.Body.String())
+	}
+	if backups, _ := database.ListBackups(dbPath); len(backups) != 1 {
+		t.Errorf("backups = %v, want one", backups)
+	}
+}
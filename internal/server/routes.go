@@ -0,0 +1,147 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// appRoute is an endpoint of the app pages or the app API. The mux matches the method and
+// the path, so an app may be named like an action and other methods get a 405 with Allow.
+// The routes get a mux of their own, the catch-all of the main mux would take any method.
+type appRoute struct {
+	methods     string // Space-separated, empty for any method
+	path        string // ServeMux pattern, {name} is the app
+	handler     http.HandlerFunc
+	primaryOnly bool // Needs app templates or an LLM, not served on agent nodes
+}
+
+// appPageRoutes returns the routes of the /apps/ pages
+func (s *Server) appPageRoutes() []appRoute {
+	return []appRoute{
+		{methods: "GET POST", path: "/apps/create", handler: s.handleAppCreate},
+		{methods: "GET", path: "/apps/{name}", handler: s.handleAppDetail},
+		{methods: "GET", path: "/apps/{name}/edit", handler: s.handleAppComposeEdit},
+		{methods: "POST", path: "/apps/{name}/edit", handler: s.handleAppComposeUpdate},
+		{methods: "POST", path: "/apps/{name}/expose", handler: s.handleAppExpose},
+		{methods: "POST", path: "/apps/{name}/unexpose", handler: s.handleAppUnexpose},
+		{methods: "POST", path: "/apps/{name}/expose-tailscale", handler: s.handleAppExposeTailscale},
+		{methods: "POST", path: "/apps/{name}/unexpose-tailscale", handler: s.handleAppUnexposeTailscale},
+		{path: "/apps/{name}/containers", handler: s.handleAppContainers},
+		{methods: "GET", path: "/apps/{name}/thumbnail.png", handler: s.handleAppThumbnail},
+		{methods: "POST", path: "/apps/{name}/update", handler: s.handleAppUpdate},
+	}
+}
+
+// appAPIRoutes returns the routes of the /api/apps/ API
+func (s *Server) appAPIRoutes() []appRoute {
+	return []appRoute{
+		{methods: "GET", path: "/api/apps", handler: s.handleAPIApps},
+		{methods: "POST", path: "/api/apps", handler: s.handleCreateApp},
+		{methods: "GET", path: "/api/apps/{$}", handler: s.handleAPIApps},
+		{methods: "POST", path: "/api/apps/{$}", handler: s.handleCreateApp},
+		{methods: "POST", path: "/api/apps/propose", handler: s.handleAPIAppPropose, primaryOnly: true},
+		{methods: "POST", path: "/api/apps/bulk", handler: s.handleAPIAppsBulk},
+
+		{methods: "GET", path: "/api/apps/{name}", handler: s.handleGetApp},
+		{methods: "PUT", path: "/api/apps/{name}", handler: s.handleUpdateApp},
+		{methods: "PATCH", path: "/api/apps/{name}", handler: s.handlePatchApp},
+		{methods: "DELETE", path: "/api/apps/{name}", handler: s.handleAPIAppDelete},
+		{methods: "GET", path: "/api/apps/{name}/{$}", handler: s.handleGetApp},
+		{methods: "PUT", path: "/api/apps/{name}/{$}", handler: s.handleUpdateApp},
+		{methods: "PATCH", path: "/api/apps/{name}/{$}", handler: s.handlePatchApp},
+		{methods: "DELETE", path: "/api/apps/{name}/{$}", handler: s.handleAPIAppDelete},
+
+		{path: "/api/apps/{name}/status", handler: s.handleAppStatusByAccept},
+		{methods: "POST", path: "/api/apps/{name}/start", handler: s.handleAPIAppStart},
+		{methods: "POST", path: "/api/apps/{name}/stop", handler: s.handleAPIAppStop},
+		{methods: "GET", path: "/api/apps/{name}/logs", handler: s.handleAPIAppLogs},
+		{methods: "GET", path: "/api/apps/{name}/logs/parsed", handler: s.handleAPIAppParsedLogs},
+		{methods: "GET", path: "/api/apps/{name}/logs/stream", handler: s.handleAPIAppLogStream},
+		{methods: "GET POST", path: "/api/apps/{name}/log-filters", handler: s.handleAPIAppLogFilters},
+		{methods: "DELETE", path: "/api/apps/{name}/log-filters/{id}", handler: s.handleAPIAppLogFilters},
+		{methods: "GET", path: "/api/apps/{name}/progress", handler: s.handleAPIAppProgress},
+		{methods: "GET", path: "/api/apps/{name}/progress/sse", handler: s.handleAPIAppProgressSSE},
+
+		{methods: "GET", path: "/api/apps/{name}/secrets", handler: s.handleAPIAppSecrets},
+		{methods: "PUT DELETE", path: "/api/apps/{name}/secrets/{secret}", handler: s.handleAPIAppSecrets},
+		{methods: "GET", path: "/api/apps/{name}/env-secrets", handler: s.handleAPIAppEnvSecrets},
+		{methods: "PUT DELETE", path: "/api/apps/{name}/env-secrets/{secret}", handler: s.handleAPIAppEnvSecrets},
+		{methods: "GET", path: "/api/apps/{name}/credentials", handler: s.handleAPIAppCredentials},
+		{methods: "PUT", path: "/api/apps/{name}/read-only", handler: s.handleAPIAppReadOnly},
+		{methods: "POST", path: "/api/apps/{name}/security-bypass", handler: s.handleAPIAppSecurityBypass},
+
+		{methods: "GET PUT", path: "/api/apps/{name}/quota", handler: s.handleAPIAppQuota},
+		{methods: "GET PUT", path: "/api/apps/{name}/cpuset", handler: s.handleAPIAppCPUSet},
+		{methods: "GET PUT", path: "/api/apps/{name}/port-bindings", handler: s.handleAPIAppPortBindings},
+		{methods: "GET PUT", path: "/api/apps/{name}/namespace", handler: s.handleAPIAppNamespace},
+		{methods: "GET PUT", path: "/api/apps/{name}/autostart", handler: s.handleAPIAppAutostart},
+		{methods: "GET PUT", path: "/api/apps/{name}/notes", handler: s.handleAPIAppNotes},
+		{methods: "GET", path: "/api/apps/{name}/resources", handler: s.handleAPIAppResources},
+		{methods: "GET", path: "/api/apps/{name}/volumes", handler: s.handleAPIAppVolumes},
+		{methods: "GET", path: "/api/apps/{name}/volumes/{volume}/files", handler: s.handleAPIAppVolumeFiles},
+		{methods: "GET", path: "/api/apps/{name}/volumes/{volume}/export", handler: s.handleAPIAppVolumeExport},
+		{methods: "POST", path: "/api/apps/{name}/volumes/{volume}/import", handler: s.handleAPIAppVolumeImport},
+		{methods: "GET POST", path: "/api/apps/{name}/resolved-config", handler: s.handleAPIAppResolvedConfig},
+		{methods: "POST", path: "/api/apps/{name}/lint", handler: s.handleAPIAppLint},
+		{methods: "POST", path: "/api/apps/{name}/apply", handler: s.handleAPIAppApply},
+		{methods: "POST", path: "/api/apps/{name}/restart", handler: s.handleAPIAppRestart},
+
+		{methods: "GET POST", path: "/api/apps/{name}/sbom", handler: s.handleAPIAppSBOM},
+		{methods: "GET", path: "/api/apps/{name}/sbom/spdx", handler: s.handleAPIAppSBOMSPDX},
+		{methods: "GET PUT POST", path: "/api/apps/{name}/rebuild", handler: s.handleAPIAppRebuild},
+		{methods: "GET POST", path: "/api/apps/{name}/image-updates", handler: s.handleAPIAppImageUpdates},
+		{methods: "POST", path: "/api/apps/{name}/image-updates/check", handler: s.handleAPIAppImageUpdates},
+		{methods: "GET POST", path: "/api/apps/{name}/upgrade", handler: s.handleAPIAppUpgrade},
+
+		{methods: "GET POST DELETE", path: "/api/apps/{name}/chat", handler: s.handleAPIAppChat, primaryOnly: true},
+	}
+}
+
+// appRoutes returns a mux of routes, each wrapped in the app access check when it names an
+// app. On agent nodes the primary only routes answer 404.
+func (s *Server) appRoutes(routes []appRoute) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		handler := route.handler
+		if route.primaryOnly && s.config.AgentMode {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Not available in agent mode", http.StatusNotFound)
+			}
+		} else if strings.Contains(route.path, "{name}") {
+			handler = s.AppAccessMiddleware(handler)
+		}
+		if route.methods == "" {
+			mux.HandleFunc(route.path, handler)
+			continue
+		}
+		for _, method := range strings.Fields(route.methods) {
+			mux.HandleFunc(method+" "+route.path, handler)
+		}
+	}
+	return mux
+}
+
+// AppAccessMiddleware lets requests through to the app of the route, {name}, when the user
+// may access it
+func (s *Server) AppAccessMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.requireAppAccess(w, r, r.PathValue("name")) {
+			next(w, r)
+		}
+	}
+}
+
+// handleAppStatusByAccept handles /api/apps/{name}/status: server-sent events of container
+// changes for event streams, JSON for GET and API clients, an HTML fragment for the subdomain
+// checks of the dashboard
+func (s *Server) handleAppStatusByAccept(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && wantsEventStream(r) {
+		s.handleAPIAppStatusEvents(w, r)
+		return
+	}
+	if r.Header.Get("Accept") == "application/json" || r.Method == http.MethodGet {
+		s.handleAPIAppStatus(w, r)
+		return
+	}
+	s.handleAppStatusCheck(w, r)
+}
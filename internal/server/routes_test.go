@@ -0,0 +1,94 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestAppRoutes(t *testing.T) {
+	s, _, _ := newNamespaceTestServer(t)
+	// An app named like an action
+	if err := os.MkdirAll(filepath.Join(s.config.AppsDir, "stop"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.config.AppsDir, "stop", "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	staff := &database.User{Username: "admin", IsStaff: true}
+
+	newMux := func() *http.ServeMux {
+		// Mounted like in Start, next to the catch-all of the dashboard
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+		mux.Handle("/apps/", s.appRoutes(s.appPageRoutes()))
+		mux.Handle("/api/apps/", s.appRoutes(s.appAPIRoutes()))
+		return mux
+	}
+	mux := newMux()
+	request := func(mux *http.ServeMux, method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req = req.WithContext(setUserContext(req.Context(), staff))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request(mux, http.MethodGet, "/api/apps/stop"); w.Code != http.StatusOK {
+		t.Errorf("GET app named stop = %d %s", w.Code, w.Body.String())
+	}
+	for target, allow := range map[string]string{
+		"/api/apps/wiki/start":               "POST",
+		"/api/apps/wiki/secrets/start":       "DELETE, PUT",
+		"/apps/wiki/thumbnail.png":           "GET, HEAD",
+		"/api/apps/stop/image-updates/check": "POST",
+	} {
+		method := http.MethodGet
+		if allow == "GET, HEAD" {
+			method = http.MethodPost
+		}
+		w := request(mux, method, target)
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != allow {
+			t.Errorf("%s %s = %d, Allow %q, want 405, Allow %q", method, target, w.Code, w.Header().Get("Allow"), allow)
+		}
+	}
+
+	// Agent nodes leave out the endpoints that need templates or an LLM
+	s.config.AgentMode = true
+	agent := newMux()
+	if w := request(agent, http.MethodPost, "/api/apps/wiki/chat"); w.Code != http.StatusNotFound {
+		t.Errorf("POST chat on an agent node = %d, want 404", w.Code)
+	}
+	if w := request(agent, http.MethodPost, "/api/apps/propose"); w.Code != http.StatusNotFound {
+		t.Errorf("POST propose on an agent node = %d, want 404", w.Code)
+	}
+}
+
+// newAppRequest returns a test request with the path values of the app route it matches, for
+// tests that call an app handler directly instead of through the mux
+func newAppRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	var s *Server
+	mux := http.NewServeMux()
+	seen := make(map[string]bool)
+	for _, route := range append(s.appPageRoutes(), s.appAPIRoutes()...) {
+		if seen[route.path] {
+			continue
+		}
+		seen[route.path] = true
+		mux.HandleFunc(route.path, func(_ http.ResponseWriter, r *http.Request) {
+			for _, name := range []string{"name", "secret", "id", "volume"} {
+				req.SetPathValue(name, r.PathValue(name))
+			}
+		})
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), req.Clone(req.Context()))
+	return req
+}
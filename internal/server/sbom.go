@@ -0,0 +1,334 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/sbom"
+)
+
+// sbomRuns tracks SBOM generations, which take too long for a request. Only one syft runs
+// at a time, scanning an image reads all of its layers.
+type sbomRuns struct {
+	mu      sync.Mutex
+	running map[string]bool
+	errors  map[string]string // Failure of the last generation of an app
+	scan    sync.Mutex        // Held while syft runs
+}
+
+// start marks the apps as generating and returns those that weren't already
+func (r *sbomRuns) start(apps []string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running == nil {
+		r.running = make(map[string]bool)
+		r.errors = make(map[string]string)
+	}
+	var started []string
+	for _, app := range apps {
+		if !r.running[app] {
+			r.running[app] = true
+			delete(r.errors, app)
+			started = append(started, app)
+		}
+	}
+	return started
+}
+
+func (r *sbomRuns) finish(app string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, app)
+	if err != nil {
+		r.errors[app] = err.Error()
+	}
+}
+
+func (r *sbomRuns) state(app string) (running bool, lastErr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running[app], r.errors[app]
+}
+
+// SBOMAppReport is the license report of an app's images
+type SBOMAppReport struct {
+	App        string             `json:"app"`
+	Generating bool               `json:"generating"`
+	Error      string             `json:"error,omitempty"` // Why the last generation failed
+	Images     []sbom.ImageReport `json:"images"`
+	Summary    sbom.Summary       `json:"summary"`
+}
+
+// SBOMReport is the node-wide license report returned by GET /api/sbom
+type SBOMReport struct {
+	SyftInstalled bool            `json:"syft_installed"`
+	Apps          []SBOMAppReport `json:"apps"`
+	Summary       sbom.Summary    `json:"summary"`
+}
+
+// sbomGenerator returns the SBOM store next to the database. syft is looked up each time,
+// so installing it takes effect without a restart.
+func (s *Server) sbomGenerator() *sbom.Generator {
+	syft, _ := sbom.FindSyft()
+	return &sbom.Generator{Syft: syft, Dir: s.config.CachePath("sbom")}
+}
+
+// appSBOMReport returns the license report of the stored SBOMs of an app
+func (s *Server) appSBOMReport(g *sbom.Generator, appName string) (SBOMAppReport, error) {
+	report := SBOMAppReport{App: appName}
+	report.Generating, report.Error = s.sbomRuns.state(appName)
+	images, err := g.Reports(appName)
+	if err != nil {
+		return report, err
+	}
+	report.Images = images
+	summaries := make([]sbom.Summary, len(images))
+	for i, image := range images {
+		summaries[i] = image.Summary
+	}
+	report.Summary = sbom.Merge(summaries...)
+	return report, nil
+}
+
+// generateSBOMs generates the SBOMs of apps one after another in the background
+func (s *Server) generateSBOMs(g *sbom.Generator, apps []string) {
+	apps = s.sbomRuns.start(apps)
+	if len(apps) == 0 {
+		return
+	}
+	go func() {
+		for _, appName := range apps {
+			err := s.generateAppSBOM(g, appName)
+			if err != nil {
+				logging.Warnf("Failed to generate SBOM of %s: %v", appName, err)
+			}
+			s.sbomRuns.finish(appName, err)
+		}
+	}()
+}
+
+func (s *Server) generateAppSBOM(g *sbom.Generator, appName string) error {
+	images, err := s.appImagesToPrefetch(appName)
+	if err != nil {
+		return fmt.Errorf("failed to read images: %w", err)
+	}
+	s.sbomRuns.scan.Lock()
+	defer s.sbomRuns.scan.Unlock()
+	return g.Generate(context.Background(), appName, images)
+}
+
+// handleAPIAppSBOM handles /api/apps/{appName}/sbom: GET returns the license report of the
+// app, POST (staff) generates its SBOMs in the background
+func (s *Server) handleAPIAppSBOM(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if !isValidAppName(appName) {
+		http.Error(w, "Invalid app name", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName)); os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("App '%s' not found", appName), http.StatusNotFound)
+		return
+	}
+
+	g := s.sbomGenerator()
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		user := getUserFromContext(r.Context())
+		if user == nil || !user.IsStaff {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if g.Syft == "" {
+			http.Error(w, "syft is not installed", http.StatusServiceUnavailable)
+			return
+		}
+		s.generateSBOMs(g, []string{appName})
+		status = http.StatusAccepted
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := s.appSBOMReport(g, appName)
+	if err != nil {
+		logging.Errorf("Failed to read SBOMs of %s: %v", appName, err)
+		http.Error(w, "Failed to read SBOMs", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleAPIAppSBOMSPDX handles GET /api/apps/{appName}/sbom/spdx?image=...
+// and returns the stored SPDX JSON document of an image as a download
+func (s *Server) handleAPIAppSBOMSPDX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	appName := r.PathValue("name")
+	image := r.URL.Query().Get("image")
+	if !isValidAppName(appName) || image == "" {
+		http.Error(w, "Invalid app name or image", http.StatusBadRequest)
+		return
+	}
+
+	content, err := s.sbomGenerator().SPDX(appName, image)
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, fmt.Sprintf("No SBOM of %s in app '%s'", image, appName), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.Errorf("Failed to read SBOM of %s in %s: %v", image, appName, err)
+		http.Error(w, "Failed to read SBOM", http.StatusInternalServerError)
+		return
+	}
+
+	filename := strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image) + ".spdx.json"
+	w.Header().Set("Content-Type", "application/spdx+json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s"`, appName, filename))
+	if _, err := w.Write(content); err != nil {
+		logging.Errorf("Failed to write SBOM: %v", err)
+	}
+}
+
+// sbomReport builds the license report of all apps the user can see. SBOMs of deleted apps
+// are removed on the way.
+func (s *Server) sbomReport(r *http.Request) (*SBOMReport, error) {
+	g := s.sbomGenerator()
+	report := &SBOMReport{SyftInstalled: g.Syft != "", Apps: []SBOMAppReport{}}
+
+	stored, err := g.Apps()
+	if err != nil {
+		return nil, err
+	}
+	for _, appName := range stored {
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, appName)); os.IsNotExist(err) {
+			if err := g.Remove(appName); err != nil {
+				logging.Warnf("Failed to remove SBOMs of deleted app %s: %v", appName, err)
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	user := getUserFromContext(r.Context())
+	var summaries []sbom.Summary
+	for _, entry := range entries {
+		if !entry.IsDir() || !s.canAccessApp(user, entry.Name()) {
+			continue
+		}
+		app, err := s.appSBOMReport(g, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		report.Apps = append(report.Apps, app)
+		summaries = append(summaries, app.Summary)
+	}
+	report.Summary = sbom.Merge(summaries...)
+	return report, nil
+}
+
+// handleAPISBOM handles /api/sbom: GET returns the node-wide license report, POST (staff)
+// generates the SBOMs of all apps in the background
+func (s *Server) handleAPISBOM(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !user.IsStaff {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		g := s.sbomGenerator()
+		if g.Syft == "" {
+			http.Error(w, "syft is not installed", http.StatusServiceUnavailable)
+			return
+		}
+		entries, err := os.ReadDir(s.config.AppsDir)
+		if err != nil && !os.IsNotExist(err) {
+			logging.Errorf("Failed to list apps: %v", err)
+			http.Error(w, "Failed to list apps", http.StatusInternalServerError)
+			return
+		}
+		var apps []string
+		for _, entry := range entries {
+			if entry.IsDir() && isValidAppName(entry.Name()) {
+				apps = append(apps, entry.Name())
+			}
+		}
+		s.generateSBOMs(g, apps)
+		status = http.StatusAccepted
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := s.sbomReport(r)
+	if err != nil {
+		logging.Errorf("Failed to build license report: %v", err)
+		http.Error(w, "Failed to build license report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleLicenses renders the node-wide license report
+func (s *Server) handleLicenses(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data := s.baseTemplateData(user)
+	report, err := s.sbomReport(r)
+	if err != nil {
+		logging.Errorf("Failed to build license report: %v", err)
+		data["ReportError"] = err.Error()
+		report = &SBOMReport{}
+	}
+	data["Report"] = report
+	generating := false
+	for _, app := range report.Apps {
+		generating = generating || app.Generating
+	}
+	data["Generating"] = generating
+
+	tmpl, ok := s.templates["licenses"]
+	if !ok {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		logging.Errorf("Error rendering template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+const testAppSPDX = `{"spdxVersion": "SPDX-2.3", "name": "nginx:1.27", "creationInfo": {"created": "2026-10-01T12:00:00Z"},
+"packages": [{"name": "musl", "licenseDeclared": "MIT"}, {"name": "busybox", "licenseDeclared": "GPL-2.0-only"}]}`
+
+func TestHandleAPISBOM(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "treeos.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+
+	appsDir := filepath.Join(tmpDir, "apps")
+	if err := os.MkdirAll(filepath.Join(appsDir, "web"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir, DatabasePath: filepath.Join(tmpDir, "treeos.db")}}
+
+	// SBOMs as syft leaves them, one of an app that was deleted since
+	for _, app := range []string{"web", "deleted"} {
+		dir := filepath.Join(tmpDir, "sbom", app)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, url.PathEscape("nginx:1.27")+".spdx.json"), []byte(testAppSPDX), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := &database.User{Username: "alice"}
+	mux := s.appRoutes(s.appAPIRoutes())
+	request := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req = req.WithContext(setUserContext(req.Context(), user))
+		w := httptest.NewRecorder()
+		if strings.HasPrefix(target, "/api/apps/") {
+			mux.ServeHTTP(w, req)
+		} else {
+			s.handleAPISBOM(w, req)
+		}
+		return w
+	}
+
+	w := request(http.MethodGet, "/api/apps/web/sbom")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET app SBOM = %d %s", w.Code, w.Body.String())
+	}
+	var app SBOMAppReport
+	if err := json.NewDecoder(w.Body).Decode(&app); err != nil {
+		t.Fatal(err)
+	}
+	if len(app.Images) != 1 || app.Images[0].Image != "nginx:1.27" || app.Summary.Packages != 2 || app.Summary.Copyleft != 1 {
+		t.Errorf("app report = %+v", app)
+	}
+
+	if w := request(http.MethodPost, "/api/apps/web/sbom"); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST by a user = %d, want 401", w.Code)
+	}
+
+	w = request(http.MethodGet, "/api/apps/web/sbom/spdx?image=nginx:1.27")
+	if w.Code != http.StatusOK || w.Body.String() != testAppSPDX {
+		t.Fatalf("GET SPDX = %d %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="web-nginx_1.27.spdx.json"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if w := request(http.MethodGet, "/api/apps/web/sbom/spdx?image=redis:7"); w.Code != http.StatusNotFound {
+		t.Errorf("GET SPDX of an unknown image = %d, want 404", w.Code)
+	}
+
+	w = request(http.MethodGet, "/api/sbom")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET node report = %d %s", w.Code, w.Body.String())
+	}
+	var report SBOMReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Apps) != 1 || report.Apps[0].App != "web" || report.Summary.Packages != 2 {
+		t.Errorf("node report = %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "sbom", "deleted")); !os.IsNotExist(err) {
+		t.Error("SBOMs of the deleted app were kept")
+	}
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/maintenance"
+)
+
+const (
+	// autoUpdateClock is the wall clock time automatic updates run at
+	autoUpdateClock = 3 * time.Hour
+	// scheduleRecheckInterval bounds how long a scheduler sleeps, so a changed time zone
+	// takes effect within it
+	scheduleRecheckInterval = time.Hour
+)
+
+// ScheduledTask is a recurring job of the node and when it runs next
+type ScheduledTask struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	Enabled  bool       `json:"enabled"`
+	NextRun  *time.Time `json:"next_run,omitempty"` // In the node's time zone, nil if unknown
+	Idle     string     `json:"idle,omitempty"`     // Shown instead of the next run while disabled
+}
+
+// SchedulesResponse is returned by GET /api/system/schedules
+type SchedulesResponse struct {
+	Timezone string          `json:"timezone"` // Configured zone, "" for the system's
+	Location string          `json:"location"` // Zone in effect
+	Now      time.Time       `json:"now"`
+	Tasks    []ScheduledTask `json:"tasks"`
+}
+
+// timezoneSetting returns the time zone chosen in the settings, "" for the system's
+func (s *Server) timezoneSetting() string {
+	if s.db == nil {
+		return ""
+	}
+	var timezone sql.NullString
+	err := s.db.QueryRow(`SELECT timezone FROM system_setup WHERE id = 1`).Scan(&timezone)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logging.Errorf("Failed to read time zone setting: %v", err)
+	}
+	return timezone.String
+}
+
+// location returns the time zone schedules and maintenance windows are in
+func (s *Server) location() *time.Location {
+	name := s.timezoneSetting()
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		logging.Warnf("Unknown time zone %q, using the system time zone: %v", name, err)
+		return time.Local
+	}
+	return loc
+}
+
+// parseTimezone validates a time zone name from the settings, "" for the system's
+func parseTimezone(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	// "Local" would silently follow the system zone, which is what "" is for
+	if name == "Local" {
+		return "", fmt.Errorf("unknown time zone %q", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", fmt.Errorf("unknown time zone %q", name)
+	}
+	return name, nil
+}
+
+// nextAutoUpdate returns when automatic updates run next
+func nextAutoUpdate(now time.Time, loc *time.Location) time.Time {
+	return maintenance.NextDaily(now.In(loc), autoUpdateClock)
+}
+
+// scheduledTasks lists the recurring jobs of the node with their next run in its time zone
+func (s *Server) scheduledTasks(now time.Time) []ScheduledTask {
+	loc := s.location()
+	at := func(t time.Time) *time.Time {
+		t = t.In(loc)
+		return &t
+	}
+
+	autoUpdates := s.config.AutoUpdateEnabled && !s.config.IsDemo() && os.Getenv("DEBUG") != "true"
+	tasks := []ScheduledTask{{
+		Name:     "Automatic updates",
+		Schedule: fmt.Sprintf("Daily at %02d:00", int(autoUpdateClock.Hours())),
+		Enabled:  autoUpdates,
+		Idle:     "Off",
+	}}
+	if autoUpdates {
+		tasks[0].NextRun = at(nextAutoUpdate(now, loc))
+	}
+
+	window := s.maintenanceWindow()
+	tasks = append(tasks, ScheduledTask{
+		Name:     "Maintenance window",
+		Schedule: window.String(),
+		Enabled:  true,
+		NextRun:  at(window.Next(now)),
+	})
+
+	reboot := ScheduledTask{Name: "Host reboot", Schedule: "In the maintenance window", Idle: "Nothing scheduled"}
+	if s.db != nil {
+		if latest, err := database.GetLatestHostReboot(); err != nil {
+			logging.Errorf("Failed to get scheduled reboot: %v", err)
+		} else if latest != nil && latest.Status == database.RebootStatusScheduled {
+			reboot.Enabled = true
+			reboot.NextRun = at(latest.ScheduledFor)
+		}
+	}
+	tasks = append(tasks, reboot)
+
+	prefetch := ScheduledTask{Name: "Image prefetch", Schedule: "When the chosen window opens", Idle: "Nothing scheduled"}
+	if s.db != nil {
+		prefetches, err := database.ListImagePrefetches()
+		if err != nil {
+			logging.Errorf("Failed to list image prefetches: %v", err)
+		}
+		for _, p := range prefetches {
+			if p.Status != database.PrefetchStatusScheduled {
+				continue
+			}
+			next := now
+			if w, err := maintenance.Parse(p.Window); err == nil {
+				next = w.In(loc).Next(now)
+			} else if p.Window == prefetchWindowMaintenance {
+				next = window.Next(now)
+			}
+			if !prefetch.Enabled || next.Before(*prefetch.NextRun) {
+				prefetch.NextRun = at(next)
+			}
+			prefetch.Enabled = true
+		}
+	}
+	tasks = append(tasks, prefetch)
+
+	rebuilds := ScheduledTask{Name: "App rebuilds", Schedule: "In the maintenance window", Idle: "Nothing scheduled"}
+	if s.db != nil {
+		if entries, err := os.ReadDir(s.config.AppsDir); err == nil {
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					continue
+				}
+				next := s.nextAppRebuild(entry.Name(), now)
+				if next != nil && (!rebuilds.Enabled || next.Before(*rebuilds.NextRun)) {
+					rebuilds.NextRun = at(*next)
+					rebuilds.Enabled = true
+				}
+			}
+		}
+	}
+	tasks = append(tasks, rebuilds)
+
+	cleanup := ScheduledTask{Name: "Weekly cleanup", Schedule: "Weekly in the maintenance window", Idle: "Off"}
+	if day, lastRun := s.pruneSchedule(); day != "" {
+		if w, err := s.pruneWindow(day); err == nil {
+			cleanup.Schedule = w.String()
+			cleanup.Enabled = true
+			cleanup.NextRun = at(nextPrune(w, lastRun, now))
+		}
+	}
+	tasks = append(tasks, cleanup)
+
+	backups := ScheduledTask{Name: "Database backup", Schedule: "Every 24h", Enabled: s.db != nil, Idle: "Off"}
+	if backups.Enabled {
+		next := now
+		if list, err := database.ListBackups(s.config.DatabasePath); err == nil && len(list) > 0 {
+			next = list[0].CreatedAt.Add(databaseBackupInterval)
+		}
+		backups.NextRun = at(next)
+	}
+	tasks = append(tasks, backups)
+
+	enabled, interval := s.agentReviewSchedule()
+	_, configured := s.agentLLM()
+	reviews := ScheduledTask{
+		Name:     "Agent health review",
+		Schedule: "Every " + formatReviewInterval(interval),
+		Enabled:  enabled && configured,
+		Idle:     "Off",
+	}
+	if reviews.Enabled {
+		next := now
+		if last, err := database.GetAgentReviews(1); err == nil && len(last) > 0 {
+			next = last[0].CreatedAt.Add(interval)
+		}
+		reviews.NextRun = at(next)
+	}
+	tasks = append(tasks, reviews)
+
+	screenshots := ScheduledTask{
+		Name:     "App screenshots",
+		Schedule: "Every " + s.config.ScreenshotInterval.String(),
+		Enabled:  s.screenshots != nil,
+		Idle:     "Off",
+	}
+	if s.screenshots != nil {
+		next := now
+		if entries, err := os.ReadDir(s.screenshots.Dir); err == nil {
+			var latest time.Time
+			for _, entry := range entries {
+				if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+					latest = info.ModTime()
+				}
+			}
+			if !latest.IsZero() {
+				next = latest.Add(s.config.ScreenshotInterval)
+			}
+		}
+		screenshots.NextRun = at(next)
+	}
+	tasks = append(tasks, screenshots)
+
+	for i := range tasks {
+		// Overdue jobs run at their next check
+		if tasks[i].NextRun != nil && tasks[i].NextRun.Before(now) {
+			tasks[i].NextRun = at(now)
+		}
+	}
+	return tasks
+}
+
+// handleAPISchedules handles GET /api/system/schedules and lists the scheduled tasks with
+// their next run in the node's time zone
+func (s *Server) handleAPISchedules(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	loc := s.location()
+	now := time.Now()
+	response := SchedulesResponse{
+		Timezone: s.timezoneSetting(),
+		Location: loc.String(),
+		Now:      now.In(loc),
+		Tasks:    s.scheduledTasks(now),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
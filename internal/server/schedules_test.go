@@ -0,0 +1,81 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestNextAutoUpdate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The day before clocks go back, the next run is still at 03:00 wall clock
+	now := time.Date(2026, 10, 24, 3, 0, 0, 0, berlin)
+	if got := nextAutoUpdate(now, berlin).Format(time.RFC3339); got != "2026-10-25T03:00:00+01:00" {
+		t.Errorf("nextAutoUpdate() = %s", got)
+	}
+	// 01:30 UTC is 03:30 in Berlin in summer, past today's run
+	now = time.Date(2026, 7, 1, 1, 30, 0, 0, time.UTC)
+	if got := nextAutoUpdate(now, berlin).Format(time.RFC3339); got != "2026-07-02T03:00:00+02:00" {
+		t.Errorf("nextAutoUpdate() = %s", got)
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	for _, name := range []string{"", "UTC", "Europe/Berlin", "America/New_York"} {
+		if got, err := parseTimezone(name); err != nil || got != name {
+			t.Errorf("parseTimezone(%q) = %q, %v", name, got, err)
+		}
+	}
+	for _, name := range []string{"Local", "Mars/Olympus_Mons", "../etc/passwd"} {
+		if _, err := parseTimezone(name); err == nil {
+			t.Errorf("parseTimezone(%q) accepted an unknown zone", name)
+		}
+	}
+}
+
+func TestScheduledTasksTimezone(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	if err := database.Initialize(dbPath); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+
+	db := database.GetDB()
+	if _, err := db.Exec(`INSERT INTO system_setup (id, timezone) VALUES (1, 'Asia/Tokyo')`); err != nil {
+		t.Fatalf("Failed to set time zone: %v", err)
+	}
+	s := &Server{
+		db:     db,
+		config: &config.Config{AutoUpdateEnabled: true, MaintenanceWindow: "03:00-05:00", DatabasePath: dbPath},
+	}
+	if got := s.location().String(); got != "Asia/Tokyo" {
+		t.Fatalf("location() = %s", got)
+	}
+
+	// 20:00 UTC is 05:00 in Tokyo, just after the window closed
+	now := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	tasks := s.scheduledTasks(now)
+	next := map[string]string{}
+	for _, task := range tasks {
+		if task.NextRun != nil {
+			next[task.Name] = task.NextRun.Format(time.RFC3339)
+		}
+	}
+	if next["Maintenance window"] != "2026-10-18T03:00:00+09:00" {
+		t.Errorf("next maintenance window = %s", next["Maintenance window"])
+	}
+	if next["Database backup"] != "2026-10-17T05:00:00+09:00" {
+		t.Errorf("next database backup = %s, want now as none exists", next["Database backup"])
+	}
+	if _, ok := next["Host reboot"]; ok {
+		t.Error("host reboot has a next run without a scheduled reboot")
+	}
+}
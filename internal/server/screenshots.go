@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/screenshots"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+const (
+	// screenshotStartDelay gives apps time to come up after a restart of TreeOS
+	screenshotStartDelay = time.Minute
+	// screenshotDialTimeout is how long to wait for an app's port before skipping it
+	screenshotDialTimeout = 2 * time.Second
+)
+
+// startScreenshotWorker periodically captures thumbnails of the web UIs of apps that have a
+// host port, when enabled and a browser is installed
+func (s *Server) startScreenshotWorker() {
+	if !s.config.ScreenshotsEnabled {
+		return
+	}
+	browser, err := screenshots.FindBrowser()
+	if err != nil {
+		logging.Warnf("App screenshots disabled: %v. Install chromium to enable them.", err)
+		return
+	}
+	s.screenshots = &screenshots.Capturer{
+		Browser: browser,
+		Dir:     s.config.CachePath("screenshots"),
+	}
+	logging.Infof("Capturing app screenshots every %s with %s", s.config.ScreenshotInterval, browser)
+
+	go func() {
+		timer := time.NewTimer(screenshotStartDelay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				s.captureAppScreenshots()
+				timer.Reset(s.config.ScreenshotInterval)
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// captureAppScreenshots captures every app whose web UI answers, one at a time so only one
+// browser runs, and removes thumbnails of deleted apps
+func (s *Server) captureAppScreenshots() {
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Errorf("Failed to list apps for screenshots: %v", err)
+		}
+		return
+	}
+
+	apps := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		apps[entry.Name()] = true
+		metadata, err := yamlutil.ReadComposeMetadata(filepath.Join(s.config.AppsDir, entry.Name()))
+		if err != nil || metadata.HostPort == 0 {
+			continue
+		}
+
+		addr := fmt.Sprintf("127.0.0.1:%d", metadata.HostPort)
+		conn, err := net.DialTimeout("tcp", addr, screenshotDialTimeout)
+		if err != nil {
+			// Stopped, the browser would only capture its error page
+			continue
+		}
+		conn.Close() //nolint:errcheck,gosec // Only probing
+
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+		if err := s.screenshots.Capture(context.Background(), entry.Name(), "http://"+addr+"/"); err != nil {
+			logging.Warnf("Failed to capture screenshot of %s: %v", entry.Name(), err)
+		}
+	}
+
+	thumbnails, err := os.ReadDir(s.screenshots.Dir)
+	if err != nil {
+		return
+	}
+	for _, thumbnail := range thumbnails {
+		appName, ok := strings.CutSuffix(thumbnail.Name(), ".png")
+		if ok && !apps[appName] {
+			if err := s.screenshots.Remove(appName); err != nil {
+				logging.Warnf("Failed to remove screenshot of deleted app %s: %v", appName, err)
+			}
+		}
+	}
+}
+
+// appThumbnailURL returns the dashboard URL of an app's thumbnail, or "" if there is none.
+// The modification time busts the browser cache when the thumbnail is replaced.
+func (s *Server) appThumbnailURL(appName string) string {
+	if s.screenshots == nil {
+		return ""
+	}
+	info, err := os.Stat(s.screenshots.Path(appName))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("/apps/%s/thumbnail.png?v=%d", appName, info.ModTime().Unix())
+}
+
+// handleAppThumbnail serves the thumbnail of an app
+func (s *Server) handleAppThumbnail(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if s.screenshots == nil || !isValidAppName(appName) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeFile(w, r, s.screenshots.Path(appName))
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -18,25 +19,36 @@ import (
 	"strings"
 	"sync"
 	"time"
-	"github.com/ontree-co/treeos/internal/logging"
 
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"github.com/ontree-co/treeos/internal/cache"
 	"github.com/ontree-co/treeos/internal/caddy"
 	"github.com/ontree-co/treeos/internal/charts"
 	"github.com/ontree-co/treeos/internal/config"
 	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/docs"
 	"github.com/ontree-co/treeos/internal/embeds"
+	"github.com/ontree-co/treeos/internal/engine"
+	"github.com/ontree-co/treeos/internal/geoip"
+	"github.com/ontree-co/treeos/internal/internalca"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/lowmem"
+	"github.com/ontree-co/treeos/internal/mockruntime"
+	"github.com/ontree-co/treeos/internal/notify"
 	"github.com/ontree-co/treeos/internal/ollama"
 	"github.com/ontree-co/treeos/internal/progress"
 	"github.com/ontree-co/treeos/internal/realtime"
 	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/screenshots"
+	"github.com/ontree-co/treeos/internal/secrets"
 	"github.com/ontree-co/treeos/internal/system"
 	"github.com/ontree-co/treeos/internal/templates"
 	"github.com/ontree-co/treeos/internal/update"
 	"github.com/ontree-co/treeos/internal/version"
 	"github.com/ontree-co/treeos/internal/yamlutil"
 	"github.com/ontree-co/treeos/pkg/compose"
+	"golang.org/x/net/webdav"
 )
 
 // Server represents the HTTP server
@@ -44,6 +56,8 @@ type Server struct {
 	config                *config.Config
 	templates             map[string]*template.Template
 	sessionStore          *sessions.CookieStore
+	sessionKeys           *sessionKeyring // Signing keys of sessionStore
+	secretBox             *secrets.Box    // Encrypts the secrets of app .env files
 	runtimeClient         *dockerruntime.Client
 	runtimeSvc            *dockerruntime.Service
 	runtimeMu             sync.Mutex
@@ -54,11 +68,30 @@ type Server struct {
 	versionInfo           version.Info
 	caddyAvailable        bool
 	caddyClient           *caddy.Client
+	internalCA            *internalca.CA
+	internalTLSMu         sync.Mutex
+	internalCertPEM       []byte
+	internalKeyPEM        []byte
+	dashboardCertMu       sync.Mutex
+	dashboardCert         *tls.Certificate // Served by the dashboard listener with client certificate auth
+	dashboardCertPEM      []byte
+	prefetchMu            sync.Mutex // Held while scheduled images are pulled
+	rebuildMu             sync.Mutex // Held while an app is rebuilt from source
+	imageUpdateMu         sync.Mutex // Held while the images of an app are checked
+	upgradeMu             sync.Mutex // Held while an app is upgraded
+	catalogSyncMu         sync.Mutex // Held while a template catalog is synced
+	bulkMu                sync.Mutex // Held while a bulk operation runs on apps
+	autostart             autostartProgress
+	diskFull              bool // disk.full fired, only used by the vitals collection
 	platformSupportsCaddy bool
+	profile               lowmem.Profile // Intervals and buffer sizes for the node's memory
+	engine                engine.Engine  // Container engine the runtime clients use, zero with the mock runtime
+	engineErr             error          // Why no container engine was found
 	sparklineCache        *cache.Cache
 	realtimeMetrics       *realtime.Metrics
 	composeSvc            *compose.Service
 	sseManager            *SSEManager
+	logFollowers          logFollowers // docker compose logs --follow per log stream channel
 	ollamaWorker          *ollama.Worker
 	progressTracker       *progress.Tracker
 	stopCh                chan struct{}
@@ -66,6 +99,30 @@ type Server struct {
 	updateMu              sync.Mutex
 	composeHealthy        bool
 	httpServer            *http.Server
+	geoIPDB               *geoip.DB
+	hostUpdateMu          sync.Mutex
+	hostUpdateApply       HostUpdateApplyState
+	quotaMu               sync.Mutex
+	appQuotas             map[string]*appQuotaState
+	timeSyncMu            sync.Mutex
+	timeSyncDrifted       bool
+	agentReviewMu         sync.Mutex
+	webDAVLocks           webdav.LockSystem
+	webDAVAuthCache       *cache.Cache
+	inviteMu              sync.Mutex
+	inviteAttempts        map[string]*attemptWindow // Requests to /invite/ per client IP
+	screenshots           *screenshots.Capturer
+	recoveryConsole       *http.Server
+	recoveryConsoleMu     sync.Mutex // Held while a recovery console action runs
+	recoveryAttemptsMu    sync.Mutex
+	recoveryAttempts      map[string]*attemptWindow // Recovery console actions per client IP
+	sbomRuns              sbomRuns
+	pendingEdits          pendingEdits // Files of running apps with saved, not applied edits
+	diskUsageMu           sync.Mutex   // Held while the disk usage of the apps is measured
+	maintenanceMu         sync.Mutex   // Held while cleanup tasks run
+	docsOnce              sync.Once
+	docsLib               *docs.Library // Embedded documentation, loaded on first use
+	docsErr               error
 }
 
 var (
@@ -75,34 +132,69 @@ var (
 
 // New creates a new server instance
 func New(cfg *config.Config, versionInfo version.Info) (*Server, error) {
-	// Create session store with secure key
-	// In production, this should be loaded from environment or config
-	sessionKey := []byte("your-32-byte-session-key-here!!") // TODO: Load from config
+	// Session cookies are signed with a random key of this node, created on first boot
+	sessionKeys, err := loadSessionKeyring(cfg.StatePath(sessionKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session keys: %w", err)
+	}
+	// Secrets of app .env files are encrypted with a key derived from a machine secret
+	secretBox, err := secrets.Load(cfg.StatePath(secrets.KeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load machine secret: %w", err)
+	}
+
+	profile := lowmem.Detect(cfg.LowMemory)
+	profile.Apply()
+	logging.Infof("Running with the %s", profile)
 
 	s := &Server{
 		config:                cfg,
 		templates:             make(map[string]*template.Template),
-		sessionStore:          sessions.NewCookieStore(sessionKey),
+		sessionStore:          &sessions.CookieStore{Codecs: []securecookie.Codec{sessionKeys}},
+		sessionKeys:           sessionKeys,
+		secretBox:             secretBox,
 		versionInfo:           versionInfo,
-		platformSupportsCaddy: runtime.GOOS == "linux",
-		sparklineCache:        cache.New(5 * time.Minute), // 5-minute cache for sparklines
+		platformSupportsCaddy: caddy.PlatformSupported(),
+		profile:               profile,
+		sparklineCache:        cache.New(profile.CacheTTL),
 		realtimeMetrics:       realtime.NewMetrics(),
 		progressTracker:       progress.NewTracker(),
 		stopCh:                make(chan struct{}),
+		webDAVLocks:           webdav.NewMemLS(),
+		webDAVAuthCache:       cache.New(webDAVAuthCacheTTL),
 	}
 
 	// Configure session store
 	s.sessionStore.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   86400 * 7, // 7 days
 		HttpOnly: true,
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode,
 	}
+	// Cookies must stay decodable for the longest lifetime (remembered devices),
+	// the per-session lifetime is enforced by AuthRequiredMiddleware
+	s.sessionKeys.MaxAge(int(max(cfg.SessionRememberLifetime, cfg.SessionMaxLifetime).Seconds()))
+	s.sessionStore.Options.MaxAge = int(cfg.SessionMaxLifetime.Seconds())
+
+	// Load the optional GeoIP database used for login history
+	if cfg.GeoIPDatabasePath != "" {
+		geoDB, err := geoip.Open(cfg.GeoIPDatabasePath)
+		if err != nil {
+			logging.Warnf("Warning: Failed to load GeoIP database: %v", err)
+		} else {
+			s.geoIPDB = geoDB
+			logging.Infof("Loaded GeoIP database with %d ranges", geoDB.Len())
+		}
+	}
 
-	// Load templates
-	if err := s.loadTemplates(); err != nil {
-		return nil, fmt.Errorf("failed to load templates: %w", err)
+	// Load templates, agent nodes serve no pages
+	if !cfg.AgentMode {
+		if err := s.loadTemplates(); err != nil {
+			return nil, fmt.Errorf("failed to load templates: %w", err)
+		}
+	} else {
+		// Agent nodes don't configure Caddy, the primary node decides how apps are reached
+		s.platformSupportsCaddy = false
 	}
 
 	// Initialize database with migration verification
@@ -120,6 +212,18 @@ func New(cfg *config.Config, versionInfo version.Info) (*Server, error) {
 	}
 	logging.Infof("Database initialized and migrations verified")
 
+	if mockruntime.Enabled() {
+		mock := mockruntime.Default()
+		sim := mock.Config()
+		logging.Warnf("Using the mock container runtime: %d seeded apps, %s latency, %s startup, %.0f%% failures",
+			sim.Apps, sim.Latency, sim.StartupTime, sim.FailureRate*100)
+		if err := mock.Seed(cfg.AppsDir); err != nil {
+			logging.Errorf("Failed to seed mock apps: %v", err)
+		}
+	} else {
+		s.detectEngine()
+	}
+
 	// Initialize container runtime client
 	runtimeClient, err := dockerruntime.NewClient()
 	if err != nil {
@@ -141,7 +245,7 @@ func New(cfg *config.Config, versionInfo version.Info) (*Server, error) {
 	}
 
 	// Initialize Compose service
-	composeSvc, err := compose.NewService()
+	composeSvc, err := s.newComposeService()
 	if err != nil {
 		logging.Warnf("Warning: Failed to initialize Compose service: %v", err)
 		// Continue without Compose support
@@ -155,22 +259,39 @@ func New(cfg *config.Config, versionInfo version.Info) (*Server, error) {
 		logging.Warnf("Warning: Failed to load config from database: %v", err)
 	}
 
-	// Initialize Caddy client only on Linux
+	// The internal CA has to exist before Caddy is synced
+	if !cfg.AgentMode {
+		s.initInternalCA()
+	}
+
+	// Initialize Caddy client on Linux and on macOS with Caddy from Homebrew
 	if s.platformSupportsCaddy {
+		if inst, ok := caddy.DetectHomebrew(); ok && runtime.GOOS == "darwin" {
+			logging.Infof("Found Caddy from Homebrew at %s (service: %t)", inst.Binary, inst.Service)
+		}
 		s.caddyClient = caddy.NewClient()
 		// Check Caddy availability
 		s.checkCaddyHealth()
 	} else {
-		logging.Infof("Caddy integration is not supported on %s platform", runtime.GOOS)
+		if cfg.AgentMode {
+			logging.Infof("Agent mode: Caddy integration is off")
+		} else if runtime.GOOS == "darwin" {
+			logging.Infof("Caddy integration on macOS needs Caddy from Homebrew: brew install caddy && brew services start caddy")
+		} else {
+			logging.Infof("Caddy integration is not supported on %s platform", runtime.GOOS)
+		}
 		s.caddyAvailable = false
 	}
 
 	// Initialize SSE manager
 	s.sseManager = NewSSEManager()
 
-	// Initialize template service
-	templatesPath := "." // Path within the embedded app templates directory
-	s.templateSvc = templates.NewService(templatesPath)
+	// Initialize template service, agent nodes get apps from the primary node
+	if !cfg.AgentMode {
+		templatesPath := "." // Path within the embedded app templates directory
+		s.templateSvc = templates.NewService(templatesPath)
+		s.templateSvc.SetCatalogDir(cfg.CachePath("catalogs"))
+	}
 
 	// Agent will be initialized in Start() if enabled
 
@@ -189,6 +310,11 @@ func (s *Server) Shutdown() {
 			logging.Errorf("HTTP server shutdown error: %v", err)
 		}
 	}
+	if s.recoveryConsole != nil {
+		if err := s.recoveryConsole.Close(); err != nil {
+			logging.Errorf("Recovery console shutdown error: %v", err)
+		}
+	}
 
 	s.stopOnce.Do(func() {
 		if s.stopCh != nil {
@@ -258,6 +384,14 @@ func (s *Server) loadTemplates() error {
 	}
 	s.templates["login"] = tmpl
 
+	// Load invite template
+	inviteTemplate := filepath.Join("templates", "dashboard", "invite.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, inviteTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse invite template: %w", err)
+	}
+	s.templates["invite"] = tmpl
+
 	// Load settings template with system-check partial
 	settingsTemplate := filepath.Join("templates", "dashboard", "settings.html")
 	systemCheckPartial := filepath.Join("templates", "partials", "system_check.html")
@@ -267,6 +401,54 @@ func (s *Server) loadTemplates() error {
 	}
 	s.templates["settings"] = tmpl
 
+	// Load storage template
+	storageTemplate := filepath.Join("templates", "dashboard", "storage.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, storageTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse storage template: %w", err)
+	}
+	s.templates["storage"] = tmpl
+
+	// Load orphaned resources template
+	orphansTemplate := filepath.Join("templates", "dashboard", "orphans.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, orphansTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse orphans template: %w", err)
+	}
+	s.templates["orphans"] = tmpl
+
+	// Load license report template
+	licensesTemplate := filepath.Join("templates", "dashboard", "licenses.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, licensesTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse licenses template: %w", err)
+	}
+	s.templates["licenses"] = tmpl
+
+	// Load documentation template
+	docsTemplate := filepath.Join("templates", "dashboard", "docs.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, docsTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse docs template: %w", err)
+	}
+	s.templates["docs"] = tmpl
+
+	// Load chat playground template
+	chatTemplate := filepath.Join("templates", "dashboard", "chat.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, chatTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse chat template: %w", err)
+	}
+	s.templates["chat"] = tmpl
+
+	// Load internal metrics debug template
+	debugMetricsTemplate := filepath.Join("templates", "dashboard", "debug_metrics.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, debugMetricsTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse debug metrics template: %w", err)
+	}
+	s.templates["debug_metrics"] = tmpl
+
 	// Load app detail template
 	appDetailTemplate := filepath.Join("templates", "dashboard", "app_detail.html")
 	tmpl, err = embeds.ParseTemplate(baseTemplate, appDetailTemplate)
@@ -275,10 +457,11 @@ func (s *Server) loadTemplates() error {
 	}
 	s.templates["app_detail"] = tmpl
 
-	// Load app create template with emoji picker component
+	// Load app create template with emoji picker and compose syntax check components
 	appCreateTemplate := filepath.Join("templates", "dashboard", "app_create.html")
 	emojiPickerTemplate := filepath.Join("templates", "components", "emoji-picker.html")
-	tmpl, err = embeds.ParseTemplate(baseTemplate, appCreateTemplate, emojiPickerTemplate)
+	composeModernizeTemplate := filepath.Join("templates", "components", "compose-modernize.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, appCreateTemplate, emojiPickerTemplate, composeModernizeTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse app create template: %w", err)
 	}
@@ -292,9 +475,9 @@ func (s *Server) loadTemplates() error {
 	}
 	s.templates["app_templates"] = tmpl
 
-	// Load model templates list template
+	// Load model templates list template with the disk usage of the models list partial
 	modelTemplatesTemplate := filepath.Join("templates", "dashboard", "model_templates.html")
-	tmpl, err = embeds.ParseTemplate(baseTemplate, modelTemplatesTemplate)
+	tmpl, err = embeds.ParseTemplate(baseTemplate, modelTemplatesTemplate, filepath.Join("templates", "partials", "models_list.html"))
 	if err != nil {
 		return fmt.Errorf("failed to parse model templates template: %w", err)
 	}
@@ -308,9 +491,9 @@ func (s *Server) loadTemplates() error {
 	}
 	s.templates["app_create_from_template"] = tmpl
 
-	// Load app compose edit template
+	// Load app compose edit template with the compose syntax check component
 	appComposeEditTemplate := filepath.Join("templates", "dashboard", "app_compose_edit.html")
-	tmpl, err = embeds.ParseTemplate(baseTemplate, appComposeEditTemplate)
+	tmpl, err = embeds.ParseTemplate(baseTemplate, appComposeEditTemplate, composeModernizeTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse app compose edit template: %w", err)
 	}
@@ -373,10 +556,37 @@ func (s *Server) loadTemplates() error {
 	}
 	s.templates["_upload_card"] = uploadTmpl
 
-	// Load pattern library templates
+	// Slim builds leave out the pattern library
+	if !embeds.Slim {
+		if err := s.loadPatternTemplates(baseTemplate); err != nil {
+			return err
+		}
+	}
+
+	// Load models list partial template
+	modelsListTemplate := filepath.Join("templates", "partials", "models_list.html")
+	modelsTmpl, err := embeds.ParseTemplate(modelsListTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse models list template: %w", err)
+	}
+	s.templates["models_list"] = modelsTmpl
+
+	// Load model detail template
+	modelDetailTemplate := filepath.Join("templates", "dashboard", "model_detail.html")
+	tmpl, err = embeds.ParseTemplate(baseTemplate, modelDetailTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse model detail template: %w", err)
+	}
+	s.templates["model_detail"] = tmpl
+
+	return nil
+}
+
+// loadPatternTemplates parses the pattern library pages
+func (s *Server) loadPatternTemplates(baseTemplate string) error {
 	// Pattern library index
 	patternsIndexTemplate := filepath.Join("templates", "pattern_library", "index.html")
-	tmpl, err = embeds.ParseTemplate(baseTemplate, patternsIndexTemplate)
+	tmpl, err := embeds.ParseTemplate(baseTemplate, patternsIndexTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse patterns index template: %w", err)
 	}
@@ -430,22 +640,6 @@ func (s *Server) loadTemplates() error {
 	}
 	s.templates["patterns_style_guide"] = tmpl
 
-	// Load models list partial template
-	modelsListTemplate := filepath.Join("templates", "partials", "models_list.html")
-	modelsTmpl, err := embeds.ParseTemplate(modelsListTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse models list template: %w", err)
-	}
-	s.templates["models_list"] = modelsTmpl
-
-	// Load model detail template
-	modelDetailTemplate := filepath.Join("templates", "dashboard", "model_detail.html")
-	tmpl, err = embeds.ParseTemplate(baseTemplate, modelDetailTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse model detail template: %w", err)
-	}
-	s.templates["model_detail"] = tmpl
-
 	return nil
 }
 
@@ -473,10 +667,25 @@ func (s *Server) getUpdateChannel() update.UpdateChannel {
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
+	if s.config.AgentMode {
+		return s.startAgentMode()
+	}
+
+	// Last-resort console over Tailscale, first so it runs even if later setup hangs
+	s.startRecoveryConsole()
+
 	// Start background jobs
 	go s.startVitalsCleanup()
-	go s.startRealtimeMetricsCollection()
+	if s.profile.RealtimeMetrics {
+		go s.startRealtimeMetricsCollection()
+	}
 	go s.startVitalsCollection()
+	go s.startAppResourceCollection()
+	go s.startAppDiskUsageCollection()
+	go s.startContainerMonitor()
+	go s.startContainerEventWatcher()
+	go s.startAutostartApps()
+	go s.startCatalogSync()
 	go s.startProgressCleanup()
 
 	// Start Ollama worker if database is available
@@ -491,15 +700,49 @@ func (s *Server) Start() error {
 	// Automatic update scheduler
 	s.startAutoUpdateScheduler()
 
+	// Coordinated host reboots
+	s.startRebootScheduler()
+
+	// Weekly cleanup of unused images, build cache and volumes
+	s.startMaintenanceScheduler()
+
+	// Images pulled ahead of installs and updates
+	s.startImagePrefetcher()
+
+	// Apps built from source, rebuilt on their schedule
+	s.startAppRebuilder()
+
+	// Newer images of apps in their registries
+	s.startImageUpdateChecker()
+
+	// Disk quotas of app mount directories
+	s.startQuotaMonitor()
+
+	// Renewal of the certificate for the internal domain
+	s.startInternalTLSMonitor()
+
+	// Clock drift breaks TLS and two-factor logins in apps
+	s.startTimeSyncMonitor()
+
+	// Scheduled health reviews of all apps by the agent
+	s.startAgentReviewMonitor()
+
+	// Dashboard thumbnails of app web UIs
+	s.startScreenshotWorker()
+
+	// Daily database backups for recovery mode
+	if s.db != nil {
+		s.startDatabaseBackups()
+	}
+
 	// Set up routes
 	mux := http.NewServeMux()
 
 	// Static file serving using embedded files
-	staticFS, err := embeds.StaticFS()
+	staticHandler, err := embeds.StaticHandler()
 	if err != nil {
 		return fmt.Errorf("failed to get static filesystem: %w", err)
 	}
-	staticHandler := http.FileServer(http.FS(staticFS))
 	mux.Handle("/static/", http.StripPrefix("/static/", staticHandler))
 	mux.Handle("/favicon.ico", staticHandler)
 	mux.Handle("/favicon.svg", staticHandler)
@@ -512,23 +755,29 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/setup", s.TracingMiddleware(s.SetupRequiredMiddleware(s.handleSetup)))
 	mux.HandleFunc("/systemcheck", s.TracingMiddleware(s.SetupRequiredMiddleware(s.handleSetupSystemCheck)))
 	mux.HandleFunc("/login", s.TracingMiddleware(s.SetupRequiredMiddleware(s.handleLogin)))
+	mux.HandleFunc("/invite/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.handleInvite)))
 	mux.HandleFunc("/logout", s.TracingMiddleware(s.handleLogout))
 
 	// Protected routes (auth required)
 	mux.HandleFunc("/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleDashboard))))
-	mux.HandleFunc("/apps/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeApps))))
+	mux.HandleFunc("/apps/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.appRoutes(s.appPageRoutes()).ServeHTTP))))
 	mux.HandleFunc("/templates", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleTemplates))))
 	mux.HandleFunc("/templates/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeTemplates))))
 
 	// API routes
-	mux.HandleFunc("/api/apps/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeAPIApps))))
+	appAPI := s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.appRoutes(s.appAPIRoutes()).ServeHTTP)))
+	mux.HandleFunc("/api/apps", appAPI)
+	mux.HandleFunc("/api/apps/", appAPI)
 	mux.HandleFunc("/api/v1/status/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeAPIStatus))))
 	mux.HandleFunc("/api/models", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeAPIModels))))
 	mux.HandleFunc("/api/models/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeAPIModels))))
+	mux.HandleFunc("/api/jobs", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeAPIJobs))))
+	mux.HandleFunc("/api/jobs/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeAPIJobs))))
 	mux.HandleFunc("/models", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleModelTemplates))))
 	mux.HandleFunc("/models/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleModelDetail))))
 
 	// Test endpoint for checking LLM API connection
+	mux.HandleFunc("/api/agent/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.routeAPIAgent))))
 	mux.HandleFunc("/api/test-llm", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleTestLLMConnection))))
 
 	// Dashboard partial routes (for monitoring cards on dashboard)
@@ -541,7 +790,23 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/partials/upload", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleMonitoringUploadPartial))))
 
 	// Version endpoint (no auth required for automation/monitoring)
-	mux.HandleFunc("/version", s.TracingMiddleware(s.handleVersion))
+	mux.HandleFunc("/version", s.TracingMiddleware(s.EndpointAccessMiddleware(s.config.VersionAccess, []string{s.config.APIToken}, s.handleVersion)))
+	mux.HandleFunc("/api/health", s.TracingMiddleware(s.EndpointAccessMiddleware(s.config.HealthAccess, []string{s.config.APIToken}, s.handleHealth)))
+	mux.HandleFunc("/api/widget", s.TracingMiddleware(s.handleWidget))
+	mux.HandleFunc("/ca.crt", s.TracingMiddleware(s.handleCACertificate))
+	mux.HandleFunc("/api/client-certificates", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIClientCertificates)))
+	mux.HandleFunc("/api/invites", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIInvites)))
+	mux.HandleFunc("/api/tokens", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPITokens)))
+	mux.HandleFunc("/api/users", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIUsers)))
+	mux.HandleFunc("/api/users/", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIUsers)))
+	mux.HandleFunc("/api/app-permissions", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIAppPermissions)))
+	mux.HandleFunc("/api/account/password", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIAccountPassword)))
+	mux.HandleFunc("/api/system/session-key/rotate", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleSessionKeyRotate)))
+	mux.HandleFunc("/api/system/email/test", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIEmailTest)))
+
+	// Audit events, finished jobs, alerts and updates for the dashboard's activity feed
+	mux.HandleFunc("/api/activity", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIActivity)))
+	mux.HandleFunc("/api/disk-usage", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIDiskUsage)))
 
 	// Logging endpoints
 	mux.HandleFunc("/api/log", s.TracingMiddleware(s.handleBrowserLog))
@@ -555,14 +820,85 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/api/system/update/channel", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleSystemUpdateChannel)))
 	mux.HandleFunc("/api/system/update/history", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleSystemUpdateHistory)))
 	mux.HandleFunc("/api/system/update/restart", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleSystemUpdateRestart)))
-
-	// Pattern library routes (no auth required - public access)
-	mux.HandleFunc("/patterns", s.TracingMiddleware(s.routePatterns))
-	mux.HandleFunc("/patterns/", s.TracingMiddleware(s.routePatterns))
+	mux.HandleFunc("/api/system/update/release-notes", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleSystemUpdateReleaseNotes)))
+	mux.HandleFunc("/api/system/update/compatibility", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleSystemUpdateCompatibility)))
+	mux.HandleFunc("/api/system/host-updates", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleHostUpdates)))
+	mux.HandleFunc("/api/system/host-updates/apply", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleHostUpdatesApply)))
+	mux.HandleFunc("/api/system/tuning", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleTuning)))
+	mux.HandleFunc("/api/system/tuning/apply", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleTuningApply)))
+	mux.HandleFunc("/api/system/config/export", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleConfigExport)))
+	mux.HandleFunc("/api/system/config/import", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleConfigImport)))
+	mux.HandleFunc("/api/system/timesync", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleTimeSync)))
+	mux.HandleFunc("/api/system/timesync/repair", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleTimeSyncRepair)))
+	mux.HandleFunc("/api/system/reboot", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleHostReboot)))
+	mux.HandleFunc("/api/system/schedules", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPISchedules)))
+	mux.HandleFunc("/api/system/backups", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIBackups)))
+	mux.HandleFunc("/api/system/a11y-audit", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleA11yAudit)))
+	mux.HandleFunc("/api/webdav/access", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleFileAccess)))
+	mux.HandleFunc("/api/images/prefetch", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleImagePrefetch)))
+	mux.HandleFunc("/api/images/prefetch/", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleImagePrefetchItem)))
+	mux.HandleFunc("/api/shares", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleShares)))
+	mux.HandleFunc("/api/shares/users", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleShareUsers)))
+	mux.HandleFunc("/api/namespaces", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPINamespaces)))
+	mux.HandleFunc("/api/namespaces/", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPINamespaces)))
+	mux.HandleFunc("/api/webhooks", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIWebhooks)))
+	mux.HandleFunc("/api/webhooks/", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIWebhooks)))
+	mux.HandleFunc("/api/shares/install", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleSharesInstall)))
+
+	// WebDAV access to app mount directories, authenticated with HTTP basic auth
+	mux.HandleFunc(webDAVPrefix+"/", s.TracingMiddleware(s.handleWebDAV))
+
+	// Pattern library routes (no auth required - public access), not part of slim builds
+	if !embeds.Slim {
+		mux.HandleFunc("/patterns", s.TracingMiddleware(s.routePatterns))
+		mux.HandleFunc("/patterns/", s.TracingMiddleware(s.routePatterns))
+	}
 
 	// Component routes (no auth required - public access for HTMX components)
 	mux.HandleFunc("/components/", s.TracingMiddleware(s.routeComponents))
 
+	// Storage page
+	mux.HandleFunc("/storage", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleStorage))))
+
+	// Orphaned resources: containers, volumes, networks and routes no app owns
+	mux.HandleFunc("/orphans", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleOrphans))))
+	mux.HandleFunc("/api/orphans", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIOrphans)))
+	mux.HandleFunc("/api/orphans/volumes", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIOrphanVolumes)))
+	mux.HandleFunc("/api/maintenance", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIMaintenance)))
+	mux.HandleFunc("/api/maintenance/prune", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIMaintenancePrune)))
+	mux.HandleFunc("/api/maintenance/schedule", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIMaintenanceSchedule)))
+
+	// License report of the images of all apps
+	mux.HandleFunc("/licenses", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleLicenses))))
+	mux.HandleFunc("/docs", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleDocs))))
+	mux.HandleFunc("/docs/", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleDocs))))
+	mux.HandleFunc("/api/sbom", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPISBOM)))
+	mux.HandleFunc("/api/docs/search", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIDocsSearch)))
+
+	// Chat playground against the configured LLM
+	mux.HandleFunc("/chat", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleChatPlayground))))
+	mux.HandleFunc("/api/chat", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIChat)))
+
+	// Remote template catalogs, and checks of a template bundle before it is contributed to one
+	mux.HandleFunc("/api/templates/catalogs", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPITemplateCatalogs)))
+	mux.HandleFunc("/api/templates/catalogs/", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPITemplateCatalogs)))
+	mux.HandleFunc("/api/templates/validate", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPITemplateValidate)))
+
+	// Host ports the apps publish, and free ones for new apps
+	mux.HandleFunc("/api/ports", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIPorts)))
+	mux.HandleFunc("/api/ports/free", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIPorts)))
+
+	// Suggests rewrites of docker-compose v1 constructs in a compose file being imported or edited
+	mux.HandleFunc("/api/compose/modernize", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPIComposeModernize)))
+
+	// Identity, CPUs and vitals of the node, as agent nodes report them
+	mux.HandleFunc("/api/node", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleAPINode)))
+
+	// Internal metrics: Prometheus format for scrapers and a debug page for admins
+	mux.HandleFunc("/metrics", s.TracingMiddleware(s.EndpointAccessMiddleware(s.config.MetricsAccess, []string{s.config.MetricsToken, s.config.APIToken}, s.handleMetrics)))
+	mux.HandleFunc("/api/metrics/export", s.TracingMiddleware(s.AuthRequiredMiddleware(s.handleMetricsExport)))
+	mux.HandleFunc("/debug/metrics", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleDebugMetrics))))
+
 	// Settings routes
 	mux.HandleFunc("/settings", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
@@ -579,11 +915,17 @@ func (s *Server) Start() error {
 	})
 	// Handle monitoring dashboard updates
 	mux.HandleFunc("/monitoring/dashboard/all", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleDashboardMonitoringUpdate))))
+	// Today's metrics against the same weekday last week
+	mux.HandleFunc("/monitoring/compare", s.TracingMiddleware(s.SetupRequiredMiddleware(s.AuthRequiredMiddleware(s.handleMonitoringCompare))))
 	mux.HandleFunc("/monitoring/", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/", http.StatusMovedPermanently)
 	})
 
-	// Start server
+	return s.serve(mux)
+}
+
+// serve listens on the configured address until the server is shut down
+func (s *Server) serve(mux *http.ServeMux) error {
 	addr := s.config.ListenAddr
 	if addr == "" {
 		addr = config.DefaultPort
@@ -594,12 +936,23 @@ func (s *Server) Start() error {
 	// Create server with proper timeouts
 	s.httpServer = &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      s.BodyLimitMiddleware(mux),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// With client certificate auth the dashboard terminates TLS itself, a proxy in front
+	// couldn't pass the client certificate on
+	if s.config.ClientCertAuth {
+		if s.internalCA == nil {
+			logging.Errorf("Client certificate auth needs the internal CA, serving plain HTTP. Staff users with a client certificate can't log in.")
+		} else {
+			s.httpServer.TLSConfig = s.dashboardTLSConfig()
+			logging.Infof("Serving HTTPS for %s with client certificate auth", s.config.InternalDomain)
+			return s.httpServer.ListenAndServeTLS("", "")
+		}
+	}
 	return s.httpServer.ListenAndServe()
 }
 
@@ -619,14 +972,15 @@ func (s *Server) startVitalsCleanup() {
 	}
 }
 
-// cleanupOldVitals removes system vital logs older than 7 days
+// cleanupOldVitals removes system vital logs older than 8 days
 func (s *Server) cleanupOldVitals() {
 	db := database.GetDB()
 
-	// Delete records older than 7 days
+	// Delete records older than 8 days. The comparison with the same weekday last week
+	// reaches back to midnight 7 days ago, up to 8 days.
 	query := `
 		DELETE FROM system_vital_logs 
-		WHERE timestamp < datetime('now', '-7 days')
+		WHERE timestamp < datetime('now', '-8 days')
 	`
 
 	result, err := db.Exec(query)
@@ -644,6 +998,10 @@ func (s *Server) cleanupOldVitals() {
 	if rowsAffected > 0 {
 		logging.Infof("Cleaned up %d old vital log records", rowsAffected)
 	}
+
+	if err := database.CleanupOldAppResourceLogs(appResourceRetention); err != nil {
+		logging.Errorf("Failed to cleanup old app resource usage: %v", err)
+	}
 }
 
 // startProgressCleanup runs a background job to clean up old progress tracking operations
@@ -662,10 +1020,9 @@ func (s *Server) startProgressCleanup() {
 
 // startVitalsCollection periodically collects and stores system vitals to the database
 func (s *Server) startVitalsCollection() {
-	logging.Infof("System vitals collection started (storing to database every 30 seconds)")
+	logging.Infof("System vitals collection started (storing to database every %s)", s.profile.VitalsInterval)
 
-	// Collect and store vitals every 30 seconds
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(s.profile.VitalsInterval)
 	defer ticker.Stop()
 
 	// Store initial vitals on startup
@@ -697,11 +1054,14 @@ func (s *Server) autoUpdateLoop() {
 	s.runAutoUpdate("startup")
 
 	for {
-		next := durationUntilNextUpdate(time.Now())
-		timer := time.NewTimer(next)
+		// Sleep at most scheduleRecheckInterval, the time zone may change in the meantime
+		next := nextAutoUpdate(time.Now(), s.location())
+		timer := time.NewTimer(min(time.Until(next), scheduleRecheckInterval))
 		select {
 		case <-timer.C:
-			s.runAutoUpdate("scheduled")
+			if !time.Now().Before(next) {
+				s.runAutoUpdate("scheduled")
+			}
 		case <-s.stopCh:
 			timer.Stop()
 			logging.Infof("Automatic update scheduler stopping")
@@ -710,14 +1070,6 @@ func (s *Server) autoUpdateLoop() {
 	}
 }
 
-func durationUntilNextUpdate(now time.Time) time.Duration {
-	next := time.Date(now.Year(), now.Month(), now.Day(), 3, 0, 0, 0, now.Location())
-	if !next.After(now) {
-		next = next.Add(24 * time.Hour)
-	}
-	return next.Sub(now)
-}
-
 func (s *Server) runAutoUpdate(trigger string) {
 	if !s.config.AutoUpdateEnabled {
 		return
@@ -727,7 +1079,7 @@ func (s *Server) runAutoUpdate(trigger string) {
 	defer s.updateMu.Unlock()
 
 	channel := s.getUpdateChannel()
-	updateSvc := update.NewService(channel)
+	updateSvc := s.updateService(channel)
 
 	info, err := updateSvc.CheckForUpdate()
 	if err != nil {
@@ -742,6 +1094,10 @@ func (s *Server) runAutoUpdate(trigger string) {
 	}
 
 	if !info.UpdateAvailable {
+		if info.Rollout != nil && !info.Rollout.Included {
+			status.Message = fmt.Sprintf("Update %s is rolling out to %d%% of beta nodes, this node gets it later",
+				info.LatestVersion, info.Rollout.Percentage)
+		}
 		SetUpdateStatus(status)
 		return
 	}
@@ -754,6 +1110,25 @@ func (s *Server) runAutoUpdate(trigger string) {
 
 	logging.Infof("Automatic update found: %s -> %s (trigger=%s)", info.CurrentVersion, info.LatestVersion, trigger)
 
+	// Leave updates that would break apps to an admin, who can fix them or force the update
+	compatibility, err := s.updateCompatibility(updateSvc)
+	if err != nil {
+		logging.Warnf("Failed to check update compatibility: %v", err)
+	} else if compatibility.Blocking {
+		summary := compatibilitySummary(compatibility)
+		logging.Warnf("Automatic update to %s skipped, it is not compatible with this node:\n%s", info.LatestVersion, summary)
+		status.Message = fmt.Sprintf("Update %s needs attention before it can be installed, see the compatibility report", info.LatestVersion)
+		SetUpdateStatus(status)
+		// Admins hear about each skipped version once, not at every check
+		if current.Message != status.Message {
+			s.notifyAdmins("TreeOS: update "+info.LatestVersion+" needs attention",
+				"The automatic update to "+info.LatestVersion+" was skipped because it is not compatible with this node:\n\n"+
+					summary+"\n\nFix the apps or install the update from Settings.")
+		}
+		return
+	}
+	s.backupBeforeMigrations(compatibility)
+
 	started := time.Now()
 	SetUpdateStatus(UpdateStatus{
 		InProgress:       true,
@@ -786,10 +1161,20 @@ func (s *Server) runAutoUpdate(trigger string) {
 			CurrentVersion:   info.CurrentVersion,
 			AvailableVersion: info.LatestVersion,
 		})
+		s.notifyEvent(notify.Event{
+			Type:    notify.EventUpdateFailed,
+			Title:   fmt.Sprintf("Automatic update to TreeOS %s failed", info.LatestVersion),
+			Message: err.Error(),
+		})
 		return
 	}
 
 	logging.Infof("Automatic update to %s applied. Restart required.", info.LatestVersion)
+	s.notifyEvent(notify.Event{
+		Type:    notify.EventUpdateApplied,
+		Title:   fmt.Sprintf("TreeOS %s installed", info.LatestVersion),
+		Message: fmt.Sprintf("The automatic update from %s to %s was installed and takes effect at the next restart.", info.CurrentVersion, info.LatestVersion),
+	})
 	SetUpdateStatus(UpdateStatus{
 		Success:          true,
 		RestartRequired:  true,
@@ -828,6 +1213,7 @@ func (s *Server) storeVitals() {
 		logging.Errorf("Failed to store system vitals: %v", err)
 		return
 	}
+	s.checkDiskFull(vitals.DiskPercent)
 
 	// Log successful storage for debugging (can be removed in production)
 	logging.Infof("Stored system vitals: CPU=%.1f%%, Mem=%.1f%%, Disk=%.1f%%, GPU=%.1f%%, Upload=%d B/s, Download=%d B/s",
@@ -880,6 +1266,22 @@ func (s *Server) getRuntimeClient() (*dockerruntime.Client, error) {
 	return s.runtimeClient, nil
 }
 
+// newComposeService creates a compose service that hardens app containers as configured
+func (s *Server) newComposeService() (*compose.Service, error) {
+	svc, err := compose.NewService()
+	if err != nil {
+		return nil, err
+	}
+	svc.SetConfinement(compose.NewConfinement(s.config.Confinement, s.config.ConfinementProfile))
+	svc.SetReadOnlyRoot(s.config.ReadOnlyRoot)
+	svc.SetSecretStore(database.GetAppSecrets)
+	if s.secretBox != nil {
+		svc.SetEnvSecretStore(s.secretBox.Values)
+	}
+	svc.SetMaxConcurrent(s.profile.ComposeSlots)
+	return svc, nil
+}
+
 func (s *Server) getComposeService() (*compose.Service, error) {
 	s.runtimeMu.Lock()
 	defer s.runtimeMu.Unlock()
@@ -893,7 +1295,7 @@ func (s *Server) getComposeService() (*compose.Service, error) {
 		if s.composeSvc != nil {
 			_ = s.composeSvc.Close()
 		}
-		svc, err := compose.NewService()
+		svc, err := s.newComposeService()
 		if err != nil {
 			s.composeHealthy = false
 			return nil, fmt.Errorf("%w: %v", errComposeUnavailable, err)
@@ -989,6 +1391,8 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	// Scan for applications
 	var apps []interface{}
 	runtimeApps, err := s.scanApps()
+	runtimeApps = s.filterAppsForUser(user, runtimeApps)
+	imageUpdates := s.appsWithImageUpdates()
 	if err != nil {
 		if errors.Is(err, errRuntimeUnavailable) {
 			logging.Infof("Container runtime not available: %v", err)
@@ -1002,16 +1406,23 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 				Name   string
 				Status string
 				State  string
+				Health string
 				Uptime string
 			}
 
 			// Create an enriched app struct with additional status
 			enrichedApp := struct {
 				*dockerruntime.App
-				ServiceCount int
-				Containers   []ContainerInfo
+				ServiceCount    int
+				Containers      []ContainerInfo
+				Quota           *AppQuotaUsage
+				Thumbnail       string
+				UpdateAvailable bool
 			}{
-				App: app,
+				App:             app,
+				Quota:           s.appQuotaUsage(app.Name),
+				Thumbnail:       s.appThumbnailURL(app.Name),
+				UpdateAvailable: imageUpdates[app.Name],
 			}
 
 			composeSvc, composeErr := s.getComposeService()
@@ -1046,6 +1457,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 								Name:   serviceName,
 								Status: status,
 								State:  container.State,
+								Health: containerHealth(container.Health),
 								Uptime: uptime,
 							})
 						}
@@ -1065,7 +1477,11 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 							}
 						}
 						if runningCount == len(containerInfos) {
-							enrichedApp.Status = "running"
+							services := make([]ServiceStatusDetail, 0, len(containerInfos))
+							for _, c := range containerInfos {
+								services = append(services, ServiceStatusDetail{Health: c.Health})
+							}
+							enrichedApp.Status = healthStatus("running", services)
 						} else if exitedCount == len(containerInfos) {
 							enrichedApp.Status = "exited"
 						} else if runningCount > 0 {
@@ -1182,12 +1598,14 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	data := s.baseTemplateData(user)
 	data["Apps"] = apps
 	data["AppsDir"] = s.config.AppsDir
+	data["Autostart"] = s.autostart.status()
 	data["Messages"] = nil
 	data["CSRFToken"] = ""      // No CSRF yet
 	data["Hostname"] = nodeName // Using node name instead of system hostname
 	data["LocalIP"] = localIP
 	data["TailscaleIP"] = tailscaleIP
 	data["MonitoringData"] = monitoringData
+	data["InternalTLS"] = s.internalTLS()
 
 	// Render template
 	tmpl, ok := s.templates["dashboard"]
@@ -1238,6 +1656,9 @@ func (s *Server) baseTemplateData(user *database.User) map[string]interface{} {
 
 	// Monitoring availability
 	data["MonitoringEnabled"] = s.config.MonitoringEnabled
+	if s.profile.LowMemory {
+		data["MinPollInterval"] = s.profile.UIPollInterval.Milliseconds()
+	}
 
 	// Get node icon and name from database
 	db := database.GetDB()
@@ -1271,7 +1692,7 @@ func (s *Server) baseTemplateData(user *database.User) map[string]interface{} {
 
 // checkCaddyHealth checks if Caddy is available and running
 func (s *Server) checkCaddyHealth() {
-	// Skip Caddy checks on non-Linux platforms
+	// Skip Caddy checks on platforms without Caddy integration
 	if !s.platformSupportsCaddy {
 		s.caddyAvailable = false
 		return
@@ -1284,7 +1705,11 @@ func (s *Server) checkCaddyHealth() {
 
 	err := s.caddyClient.HealthCheck()
 	if err != nil {
-		logging.Errorf("Cannot connect to Caddy Admin API at localhost:2019. Please ensure Caddy is installed and running. Error: %v", err)
+		hint := "Please ensure Caddy is installed and running."
+		if runtime.GOOS == "darwin" {
+			hint = "Start it with: brew services start caddy."
+		}
+		logging.Errorf("Cannot connect to Caddy Admin API at localhost:2019. %s Error: %v", hint, err)
 		s.caddyAvailable = false
 		return
 	}
@@ -1296,6 +1721,7 @@ func (s *Server) checkCaddyHealth() {
 	if s.db != nil && s.caddyAvailable {
 		s.syncExposedApps()
 	}
+	s.syncInternalTLS()
 }
 
 // loadConfigFromDatabase loads configuration from database if not set by environment
@@ -1476,10 +1902,13 @@ func (s *Server) syncExposedApps() {
 		appID := strings.ToLower(app.Name)
 
 		// Create route config (only for public domain now, Tailscale handled separately)
-		routeConfig := caddy.CreateRouteConfig(appID, metadata.Subdomain, metadata.HostPort, publicDomain, "")
+		routeConfig := caddy.CreateRouteConfig(appID, metadata.Subdomain, metadata.HostPort, publicDomain, "", metadata.CustomDomain)
 
 		// Add route to Caddy
 		err = s.caddyClient.AddOrUpdateRoute(routeConfig)
+		if err == nil {
+			err = s.syncCertificatePolicy(appID, metadata.CustomDomain)
+		}
 		if err != nil {
 			logging.Errorf("Failed to sync app %s to Caddy: %v", app.Name, err)
 		} else {
@@ -1488,92 +1917,6 @@ func (s *Server) syncExposedApps() {
 	}
 }
 
-// routeApps routes all /apps/* requests to the appropriate handler
-func (s *Server) routeApps(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-
-	// Debug logging
-	if strings.Contains(path, "expose") {
-		logging.Infof("[routeApps] Request: method=%s path=%s", r.Method, path)
-	}
-
-	// Route based on the path pattern
-	if path == "/apps/create" {
-		s.handleAppCreate(w, r)
-	} else if strings.HasSuffix(path, "/expose-tailscale") {
-		s.handleAppExposeTailscale(w, r)
-	} else if strings.HasSuffix(path, "/unexpose-tailscale") {
-		s.handleAppUnexposeTailscale(w, r)
-	} else if strings.HasSuffix(path, "/expose") {
-		s.handleAppExpose(w, r)
-	} else if strings.HasSuffix(path, "/unexpose") {
-		s.handleAppUnexpose(w, r)
-	} else if strings.HasSuffix(path, "/edit") {
-		if r.Method == "POST" {
-			s.handleAppComposeUpdate(w, r)
-		} else {
-			s.handleAppComposeEdit(w, r)
-		}
-	} else if strings.HasSuffix(path, "/containers") {
-		s.handleAppContainers(w, r)
-		// Check-update functionality removed - using Visit in Browser instead
-		// } else if strings.HasSuffix(path, "/check-update") {
-		//	s.handleAppCheckUpdate(w, r)
-	} else if strings.HasSuffix(path, "/update") {
-		s.handleAppUpdate(w, r)
-	} else {
-		// Default to app detail page
-		s.handleAppDetail(w, r)
-	}
-}
-
-// routeAPIApps routes /api/apps/* requests
-func (s *Server) routeAPIApps(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-
-	// Route based on the path pattern
-	if path == "/api/apps" || path == "/api/apps/" {
-		// Handle app creation
-		s.handleCreateApp(w, r)
-	} else if strings.HasSuffix(path, "/status") {
-		// Route to different handlers based on content type
-		if r.Header.Get("Accept") == "application/json" || r.Method == http.MethodGet {
-			// Use the new API status handler for JSON responses
-			s.handleAPIAppStatus(w, r)
-		} else {
-			// Keep the old handler for HTML responses (subdomain checks)
-			s.handleAppStatusCheck(w, r)
-		}
-	} else if strings.HasSuffix(path, "/start") {
-		s.handleAPIAppStart(w, r)
-	} else if strings.HasSuffix(path, "/stop") {
-		s.handleAPIAppStop(w, r)
-	} else if strings.HasSuffix(path, "/logs") {
-		s.handleAPIAppLogs(w, r)
-	} else if strings.HasSuffix(path, "/progress/sse") {
-		s.handleAPIAppProgressSSE(w, r)
-	} else if strings.HasSuffix(path, "/progress") {
-		s.handleAPIAppProgress(w, r)
-	} else if strings.HasSuffix(path, "/security-bypass") {
-		// Toggle security bypass for an app
-		s.handleAPIAppSecurityBypass(w, r)
-	} else if strings.HasPrefix(path, "/api/apps/") {
-		// Check if it's a DELETE request for app deletion
-		switch r.Method {
-		case http.MethodDelete:
-			s.handleAPIAppDelete(w, r)
-		case http.MethodGet:
-			// Handle GET request to fetch app configuration
-			s.handleGetApp(w, r)
-		default:
-			// Handle app updates - extract app name and route
-			s.handleUpdateApp(w, r)
-		}
-	} else {
-		http.NotFound(w, r)
-	}
-}
-
 // routeAPIStatus routes /api/v1/status/* requests
 func (s *Server) routeAPIStatus(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
@@ -1610,6 +1953,21 @@ func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// handleHealth reports whether the node can serve requests, for load balancers and uptime checks
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	status, code := "ok", http.StatusOK
+	if db := database.GetDB(); db == nil || db.Ping() != nil {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": status, "database": status}); err != nil {
+		logging.Errorf("Error encoding health response: %v", err)
+	}
+}
+
 // getLocalIP returns the primary local IP address
 func getLocalIP() string {
 	addrs, err := net.InterfaceAddrs()
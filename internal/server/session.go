@@ -0,0 +1,167 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// sessionActivityInterval throttles how often activity refreshes the session cookie
+	sessionActivityInterval = time.Minute
+	// sessionRotationInterval is how often the session ID is rotated on activity
+	sessionRotationInterval = 15 * time.Minute
+	// sessionRotationGrace is how long the ID before a rotation stays valid, for requests
+	// that were already sent with the old cookie
+	sessionRotationGrace = 30 * time.Second
+)
+
+// errSessionSuperseded means another request already rotated the session ID of the cookie
+var errSessionSuperseded = errors.New("session ID already rotated")
+
+// newSessionID returns a random identifier for a login session
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand failing is not recoverable in a meaningful way
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// startSession marks the session as authenticated for user with a new session ID and resets
+// its timestamps. The server only stores the hash of the ID, the cookie carries the ID.
+func (s *Server) startSession(session *sessions.Session, user *database.User, remember bool) error {
+	if sid, ok := session.Values["sid"].(string); ok {
+		if err := database.DeleteSession(hashToken(sid)); err != nil {
+			return err
+		}
+	}
+	if err := database.DeleteSessionsCreatedBefore(time.Now().Add(-s.longestSessionLifetime())); err != nil {
+		logging.Errorf("Failed to delete expired sessions: %v", err)
+	}
+
+	sid := newSessionID()
+	if err := database.CreateSession(hashToken(sid), user.ID); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	session.Values["user_id"] = user.ID
+	session.Values["session_version"] = user.SessionVersion
+	session.Values["sid"] = sid
+	session.Values["created_at"] = now
+	session.Values["last_seen"] = now
+	session.Values["rotated_at"] = now
+	session.Values["remember"] = remember
+	s.applySessionLifetime(session)
+	return nil
+}
+
+// longestSessionLifetime returns the absolute lifetime of the longest lasting sessions
+func (s *Server) longestSessionLifetime() time.Duration {
+	return max(s.config.SessionMaxLifetime, s.config.SessionRememberLifetime)
+}
+
+// applySessionLifetime sets the cookie lifetime matching the session's remember flag.
+// Cookie options are not stored in the cookie itself, so this must run before every save.
+func (s *Server) applySessionLifetime(session *sessions.Session) {
+	if session.Options == nil {
+		return
+	}
+	session.Options.MaxAge = int(s.sessionLifetime(session).Seconds())
+}
+
+// sessionLifetime returns the absolute lifetime of the session
+func (s *Server) sessionLifetime(session *sessions.Session) time.Duration {
+	if remember, _ := session.Values["remember"].(bool); remember {
+		return s.config.SessionRememberLifetime
+	}
+	return s.config.SessionMaxLifetime
+}
+
+// touchSession validates the idle timeout and absolute lifetime of an authenticated session
+// and records activity. It returns expired=true if the session must be discarded and
+// changed=true if the session values were updated and need to be saved.
+func (s *Server) touchSession(session *sessions.Session, now time.Time) (expired, changed bool) {
+	createdAt, hasCreated := session.Values["created_at"].(int64)
+	lastSeen, hasLastSeen := session.Values["last_seen"].(int64)
+	if !hasCreated || !hasLastSeen {
+		// Sessions from before lifetime tracking can't be validated
+		return true, false
+	}
+
+	if lifetime := s.sessionLifetime(session); lifetime > 0 && now.Sub(time.Unix(createdAt, 0)) > lifetime {
+		return true, false
+	}
+
+	// Remembered devices are only bound by their absolute lifetime
+	remember, _ := session.Values["remember"].(bool)
+	if !remember && s.config.SessionIdleTimeout > 0 && now.Sub(time.Unix(lastSeen, 0)) > s.config.SessionIdleTimeout {
+		return true, false
+	}
+
+	if now.Sub(time.Unix(lastSeen, 0)) >= sessionActivityInterval {
+		session.Values["last_seen"] = now.Unix()
+		changed = true
+	}
+
+	return false, changed
+}
+
+// rotateSessionID gives the session a new ID once sessionRotationInterval has passed since
+// the last rotation. It returns errSessionSuperseded if a concurrent request already rotated
+// the ID, the session must not be saved then so the cookie with the new ID stays.
+func rotateSessionID(session *sessions.Session, now time.Time) (bool, error) {
+	rotatedAt, _ := session.Values["rotated_at"].(int64)
+	if now.Sub(time.Unix(rotatedAt, 0)) < sessionRotationInterval {
+		return false, nil
+	}
+	sid, _ := session.Values["sid"].(string)
+	newSID := newSessionID()
+	rotated, err := database.RotateSession(hashToken(sid), hashToken(newSID))
+	if err != nil {
+		return false, err
+	}
+	if !rotated {
+		return false, errSessionSuperseded
+	}
+	session.Values["sid"] = newSID
+	session.Values["rotated_at"] = now.Unix()
+	return true, nil
+}
+
+// sessionCurrent reports whether the session ID is one the server knows for the user and the
+// session was started after the last password change of the user
+func sessionCurrent(session *sessions.Session, user *database.User) bool {
+	version, _ := session.Values["session_version"].(int)
+	if version != user.SessionVersion {
+		return false
+	}
+	sid, ok := session.Values["sid"].(string)
+	if !ok {
+		return false
+	}
+	active, err := database.SessionActive(hashToken(sid), user.ID, sessionRotationGrace)
+	if err != nil {
+		logging.Errorf("Failed to check session: %v", err)
+		return false
+	}
+	return active
+}
+
+// clearSession ends the session on the server and removes authentication data from it
+func clearSession(session *sessions.Session) {
+	if sid, ok := session.Values["sid"].(string); ok {
+		if err := database.DeleteSession(hashToken(sid)); err != nil {
+			logging.Errorf("Failed to delete session: %v", err)
+		}
+	}
+	for _, key := range []string{"user_id", "session_version", "sid", "created_at", "last_seen", "rotated_at", "remember"} {
+		delete(session.Values, key)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/securecookie"
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// sessionKeyFile holds the keys that sign session cookies, in the state directory
+	sessionKeyFile = "session.keys"
+	// sessionKeySize is the length of a signing key in bytes
+	sessionKeySize = 64
+	// sessionKeysKept is how many keys are accepted: the current one and the one before it
+	sessionKeysKept = 2
+)
+
+// sessionKeyring signs session cookies with the current key and still accepts cookies
+// signed with the previous one, so rotating the key doesn't log everyone out at once.
+// Sessions are signed again with the new key on their next save. The keyring is the only
+// codec of the session store, so keys can change while the server runs.
+type sessionKeyring struct {
+	mu     sync.RWMutex
+	path   string
+	keys   [][]byte // Newest first
+	codecs []securecookie.Codec
+	maxAge int
+}
+
+// loadSessionKeyring reads the keys from path, creating a random key on first use
+func loadSessionKeyring(path string) (*sessionKeyring, error) {
+	k := &sessionKeyring{path: path}
+
+	data, err := os.ReadFile(path) //nolint:gosec // Path from configuration
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		key, err := newSessionKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := k.setKeys([][]byte{key}); err != nil {
+			return nil, err
+		}
+		logging.Infof("Created session key at %s", path)
+		return k, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read session keys: %w", err)
+	}
+
+	var keys [][]byte
+	for _, line := range strings.Fields(string(data)) {
+		key, err := hex.DecodeString(line)
+		if err != nil || len(key) != sessionKeySize {
+			return nil, fmt.Errorf("invalid session key in %s", path)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no session key in %s", path)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+		logging.Warnf("Session keys at %s were readable by others, restricting them to the owner", path)
+		if err := os.Chmod(path, 0600); err != nil {
+			return nil, fmt.Errorf("failed to restrict session keys: %w", err)
+		}
+	}
+	k.use(keys)
+	return k, nil
+}
+
+// newSessionKey returns a random signing key
+func newSessionKey() ([]byte, error) {
+	key := make([]byte, sessionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	return key, nil
+}
+
+// Rotate signs new cookies with a new key, keeping the current one to check existing
+// cookies. Sessions signed with the key before are no longer accepted.
+func (k *sessionKeyring) Rotate() error {
+	key, err := newSessionKey()
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := append([][]byte{key}, k.keys...)
+	return k.setKeysLocked(keys[:min(len(keys), sessionKeysKept)])
+}
+
+// setKeys writes keys to the key file and uses them
+func (k *sessionKeyring) setKeys(keys [][]byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.setKeysLocked(keys)
+}
+
+func (k *sessionKeyring) setKeysLocked(keys [][]byte) error {
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = hex.EncodeToString(key)
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := k.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write session keys: %w", err)
+	}
+	if err := os.Rename(tmp, k.path); err != nil {
+		return fmt.Errorf("failed to write session keys: %w", err)
+	}
+	k.useLocked(keys)
+	return nil
+}
+
+func (k *sessionKeyring) use(keys [][]byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.useLocked(keys)
+}
+
+func (k *sessionKeyring) useLocked(keys [][]byte) {
+	k.keys = keys
+	k.codecs = make([]securecookie.Codec, len(keys))
+	for i, key := range keys {
+		codec := securecookie.New(key, nil)
+		if k.maxAge > 0 {
+			codec.MaxAge(k.maxAge)
+		}
+		k.codecs[i] = codec
+	}
+}
+
+// MaxAge sets how long cookies stay decodable, in seconds, like CookieStore.MaxAge does
+// for its own codecs
+func (k *sessionKeyring) MaxAge(age int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.maxAge = age
+	k.useLocked(k.keys)
+}
+
+// Encode signs a cookie value with the current key
+func (k *sessionKeyring) Encode(name string, value interface{}) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.codecs[0].Encode(name, value)
+}
+
+// Decode checks a cookie value against all kept keys
+func (k *sessionKeyring) Decode(name, value string, dst interface{}) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return securecookie.DecodeMulti(name, value, dst, k.codecs...)
+}
+
+// handleSessionKeyRotate handles POST /api/system/session-key/rotate for admins. Sessions
+// signed with the previous key keep working and move to the new key with their next
+// request; sessions idle since before the previous rotation end.
+func (s *Server) handleSessionKeyRotate(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.sessionKeys == nil {
+		http.Error(w, "Session keys not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := s.sessionKeys.Rotate(); err != nil {
+		logging.Errorf("Failed to rotate session key: %v", err)
+		http.Error(w, "Failed to rotate session key", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("User %s rotated the session key", user.Username)
+	recordActivity(database.ActivityEvent{
+		Category: database.ActivityAudit,
+		Title:    "Session key rotated",
+		Actor:    user.Username,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
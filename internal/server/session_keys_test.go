@@ -0,0 +1,73 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", sessionKeyFile)
+	keys, err := loadSessionKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file = %v, %v", info, err)
+	}
+	first, err := keys.Encode("ontree-session", map[string]int{"user_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key survives a restart
+	keys, err = loadSessionKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values map[string]int
+	if err := keys.Decode("ontree-session", first, &values); err != nil || values["user_id"] != 1 {
+		t.Fatalf("Decode() after restart = %v, %v", values, err)
+	}
+
+	// After a rotation cookies of the previous key still work, after two they don't
+	if err := keys.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Decode("ontree-session", first, &values); err != nil {
+		t.Errorf("Decode() after one rotation: %v", err)
+	}
+	second, err := keys.Encode("ontree-session", map[string]int{"user_id": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Decode("ontree-session", first, &values); err == nil {
+		t.Error("Decode() of a cookie two rotations old succeeded")
+	}
+	if err := keys.Decode("ontree-session", second, &values); err != nil || values["user_id"] != 2 {
+		t.Errorf("Decode() of the previous key = %v, %v", values, err)
+	}
+	if data, err := os.ReadFile(path); err != nil || len(strings.Fields(string(data))) != sessionKeysKept {
+		t.Errorf("key file = %q, %v", data, err)
+	}
+
+	// Other installs don't accept the cookies
+	other, err := loadSessionKeyring(filepath.Join(t.TempDir(), sessionKeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Decode("ontree-session", second, &values); err == nil {
+		t.Error("Decode() with the keys of another install succeeded")
+	}
+
+	if err := os.WriteFile(path, []byte("not-hex\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSessionKeyring(path); err == nil {
+		t.Error("loadSessionKeyring() with an invalid key succeeded")
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestTouchSession(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{
+		config: &config.Config{
+			SessionIdleTimeout:      time.Hour,
+			SessionMaxLifetime:      24 * time.Hour,
+			SessionRememberLifetime: 30 * 24 * time.Hour,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		remember    bool
+		age         time.Duration // time since login
+		idle        time.Duration // time since last activity
+		wantExpired bool
+		wantChanged bool
+	}{
+		{"recent activity is unchanged", false, 10 * time.Second, 10 * time.Second, false, false},
+		{"activity refreshes last seen", false, 10 * time.Minute, 5 * time.Minute, false, true},
+		{"idle timeout expires session", false, 2 * time.Hour, 2 * time.Hour, true, false},
+		{"remembered session ignores idle timeout", true, 2 * time.Hour, 2 * time.Hour, false, true},
+		{"absolute lifetime expires session", false, 25 * time.Hour, time.Minute, true, false},
+		{"remembered session outlives normal lifetime", true, 48 * time.Hour, time.Minute, false, true},
+		{"remembered session expires after remember lifetime", true, 31 * 24 * time.Hour, time.Minute, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			session := sessions.NewSession(nil, "ontree-session")
+			session.Options = &sessions.Options{}
+			if err := s.startSession(session, &database.User{ID: 1}, tt.remember); err != nil {
+				t.Fatal(err)
+			}
+			session.Values["created_at"] = now.Add(-tt.age).Unix()
+			session.Values["last_seen"] = now.Add(-tt.idle).Unix()
+
+			expired, changed := s.touchSession(session, now)
+			if expired != tt.wantExpired {
+				t.Errorf("expired = %v, want %v", expired, tt.wantExpired)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestTouchSessionRecordsActivity(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{config: &config.Config{SessionIdleTimeout: time.Hour, SessionMaxLifetime: 24 * time.Hour}}
+	session := sessions.NewSession(nil, "ontree-session")
+	session.Options = &sessions.Options{}
+	if err := s.startSession(session, &database.User{ID: 1}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	session.Values["last_seen"] = now.Add(-20 * time.Minute).Unix()
+
+	expired, changed := s.touchSession(session, now)
+	if expired || !changed {
+		t.Fatalf("expected active changed session, got expired=%v changed=%v", expired, changed)
+	}
+	if session.Values["last_seen"] != now.Unix() {
+		t.Error("expected activity to be recorded")
+	}
+	if session.Options.MaxAge != int((24 * time.Hour).Seconds()) {
+		t.Errorf("unexpected cookie max age %d", session.Options.MaxAge)
+	}
+}
+
+func TestTouchSessionRejectsLegacySession(t *testing.T) {
+	s := &Server{config: &config.Config{SessionIdleTimeout: time.Hour, SessionMaxLifetime: 24 * time.Hour}}
+	session := sessions.NewSession(nil, "ontree-session")
+	session.Values["user_id"] = 1
+
+	if expired, _ := s.touchSession(session, time.Now()); !expired {
+		t.Error("expected session without timestamps to be expired")
+	}
+}
+
+func TestSessionCurrent(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{config: &config.Config{SessionMaxLifetime: 24 * time.Hour}}
+	session := sessions.NewSession(nil, "ontree-session")
+	session.Options = &sessions.Options{}
+	user := &database.User{ID: 1, SessionVersion: 2}
+	if err := s.startSession(session, user, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if !sessionCurrent(session, user) {
+		t.Error("new session is not current")
+	}
+	if sessionCurrent(session, &database.User{ID: 2, SessionVersion: 2}) {
+		t.Error("session is current for another user")
+	}
+	user.SessionVersion++
+	if sessionCurrent(session, user) {
+		t.Error("session from before the password change is still current")
+	}
+
+	// A copied cookie ends with the session on the server
+	user.SessionVersion--
+	copied := sessions.NewSession(nil, "ontree-session")
+	for key, value := range session.Values {
+		copied.Values[key] = value
+	}
+	clearSession(session)
+	if sessionCurrent(copied, user) {
+		t.Error("copy of a cleared session is still current")
+	}
+
+	// Cookies from before server-side sessions have no session ID
+	legacy := sessions.NewSession(nil, "ontree-session")
+	legacy.Values["user_id"] = 1
+	if sessionCurrent(legacy, &database.User{ID: 1}) {
+		t.Error("session without ID is current")
+	}
+}
+
+func TestRotateSessionID(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{config: &config.Config{SessionMaxLifetime: 24 * time.Hour}}
+	session := sessions.NewSession(nil, "ontree-session")
+	session.Options = &sessions.Options{}
+	user := &database.User{ID: 1}
+	if err := s.startSession(session, user, false); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if rotated, err := rotateSessionID(session, now); rotated || err != nil {
+		t.Errorf("rotateSessionID() right after the login = %v, %v", rotated, err)
+	}
+
+	// A login gets a new ID, the one from before it ends
+	before := sessions.NewSession(nil, "ontree-session")
+	for key, value := range session.Values {
+		before.Values[key] = value
+	}
+	if err := s.startSession(session, user, false); err != nil {
+		t.Fatal(err)
+	}
+	if session.Values["sid"] == before.Values["sid"] || sessionCurrent(before, user) {
+		t.Error("login kept the session ID")
+	}
+
+	old := sessions.NewSession(nil, "ontree-session")
+	session.Values["rotated_at"] = now.Add(-sessionRotationInterval).Unix()
+	for key, value := range session.Values {
+		old.Values[key] = value
+	}
+	if rotated, err := rotateSessionID(session, now); !rotated || err != nil {
+		t.Fatalf("rotateSessionID() after the interval = %v, %v", rotated, err)
+	}
+	if session.Values["sid"] == old.Values["sid"] || session.Values["rotated_at"] != now.Unix() {
+		t.Errorf("session after the rotation = %v", session.Values)
+	}
+	if !sessionCurrent(session, user) {
+		t.Error("rotated session is not current")
+	}
+
+	// The old cookie works during the grace but doesn't rotate again
+	if !sessionCurrent(old, user) {
+		t.Error("cookie from before the rotation is not current during the grace")
+	}
+	if _, err := rotateSessionID(old, now); !errors.Is(err, errSessionSuperseded) {
+		t.Errorf("rotateSessionID() of the old cookie = %v, want errSessionSuperseded", err)
+	}
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/shares"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+// appQuotaEntry is an app's quota usage for the storage page
+type appQuotaEntry struct {
+	AppName string
+	Usage   AppQuotaUsage
+}
+
+// handleStorage renders the storage page with storage roots, app quotas and Samba shares
+func (s *Server) handleStorage(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data := s.baseTemplateData(user)
+	data["StorageRoots"] = storage.Usages(s.config.StorageRoots)
+
+	s.quotaMu.Lock()
+	quotas := make([]appQuotaEntry, 0, len(s.appQuotas))
+	for appName, state := range s.appQuotas {
+		quotas = append(quotas, appQuotaEntry{AppName: appName, Usage: state.usage})
+	}
+	s.quotaMu.Unlock()
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].AppName < quotas[j].AppName })
+	data["AppQuotas"] = quotas
+
+	data["Samba"] = shares.GetStatus(r.Context())
+	appShares, err := database.GetShares()
+	if err != nil {
+		logging.Errorf("Failed to get shares: %v", err)
+	}
+	data["Shares"] = appShares
+
+	if access, err := s.fileAccessFor(user); err == nil {
+		appNames := make([]string, 0, len(access))
+		for appName := range access {
+			appNames = append(appNames, appName)
+		}
+		sort.Strings(appNames)
+		data["ShareApps"] = appNames
+	}
+
+	tmpl, ok := s.templates["storage"]
+	if !ok {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		logging.Errorf("Error rendering template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
+
+// handleShares handles /api/shares:
+// GET lists shares, POST creates a share and DELETE (?id=) removes one
+func (s *Server) handleShares(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var share database.Share
+		if err := json.NewDecoder(r.Body).Decode(&share); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		share.Name = strings.TrimSpace(share.Name)
+		share.Comment = strings.TrimSpace(share.Comment)
+		share.SubPath = strings.Trim(strings.TrimSpace(share.SubPath), "/")
+		if err := s.validateShare(&share); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.CreateShare(&share); err != nil {
+			logging.Errorf("Failed to create share: %v", err)
+			http.Error(w, "Failed to create share, is the name already used?", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s created share %s for app %s", user.Username, share.Name, share.AppName)
+		if err := s.applyShares(r.Context()); err != nil {
+			logging.Errorf("Failed to apply shares: %v", err)
+			http.Error(w, "Share saved but Samba could not be updated: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid share id", http.StatusBadRequest)
+			return
+		}
+		if err := database.DeleteShare(id); err != nil {
+			logging.Errorf("Failed to delete share: %v", err)
+			http.Error(w, "Failed to delete share", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s removed share #%d", user.Username, id)
+		if err := s.applyShares(r.Context()); err != nil {
+			logging.Errorf("Failed to apply shares: %v", err)
+			http.Error(w, "Share removed but Samba could not be updated: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	appShares, err := database.GetShares()
+	if err != nil {
+		logging.Errorf("Failed to get shares: %v", err)
+		http.Error(w, "Failed to get shares", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"shares": appShares,
+		"status": shares.GetStatus(r.Context()),
+	}); err != nil {
+		logging.Errorf("Failed to encode shares: %v", err)
+	}
+}
+
+// validateShare checks a new share and that its directory exists inside the app's mount directory
+func (s *Server) validateShare(share *database.Share) error {
+	if err := shares.ValidateShareName(share.Name); err != nil {
+		return err
+	}
+	if share.AppName == "" || strings.ContainsAny(share.AppName, `/\`) || strings.HasPrefix(share.AppName, ".") {
+		return errors.New("invalid app name")
+	}
+	if share.SubPath != "" && !filepath.IsLocal(share.SubPath) {
+		return errors.New("the directory must be inside the app's mnt directory")
+	}
+	if strings.ContainsAny(share.SubPath+share.Comment, "\n\r") {
+		return errors.New("directory and comment must not contain line breaks")
+	}
+	for _, name := range share.ValidUsers {
+		if err := shares.ValidateUserName(name); err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Stat(s.sharePath(*share))
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("directory %q not found in the mnt directory of app %s", share.SubPath, share.AppName)
+	}
+	return nil
+}
+
+// sharePath returns the host directory of a share
+func (s *Server) sharePath(share database.Share) string {
+	return filepath.Join(s.config.AppsDir, share.AppName, "mnt", filepath.FromSlash(share.SubPath))
+}
+
+// applyShares writes all shares to the Samba config fragment and reloads Samba
+func (s *Server) applyShares(ctx context.Context) error {
+	appShares, err := database.GetShares()
+	if err != nil {
+		return err
+	}
+
+	config := make([]shares.Share, 0, len(appShares))
+	for _, share := range appShares {
+		config = append(config, shares.Share{
+			Name:       share.Name,
+			Path:       s.sharePath(share),
+			Comment:    share.Comment,
+			ReadOnly:   share.ReadOnly,
+			ValidUsers: share.ValidUsers,
+		})
+	}
+	return shares.Apply(ctx, config)
+}
+
+// handleShareUsers handles /api/shares/users:
+// POST creates a Samba user or changes its password, DELETE (?name=) removes one
+func (s *Server) handleShareUsers(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			Name     string `json:"name"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := shares.ValidateUserName(req.Name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := shares.SetUserPassword(r.Context(), req.Name, req.Password); err != nil {
+			logging.Errorf("Failed to set Samba password for %s: %v", req.Name, err)
+			http.Error(w, "Failed to set password: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s set the Samba password of %s", user.Username, req.Name)
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		if err := shares.DeleteUser(r.Context(), name); err != nil {
+			logging.Errorf("Failed to delete Samba user %s: %v", name, err)
+			http.Error(w, "Failed to delete user: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s removed Samba user %s", user.Username, name)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(shares.GetStatus(r.Context())); err != nil {
+		logging.Errorf("Failed to encode Samba status: %v", err)
+	}
+}
+
+// handleSharesInstall installs Samba on the host. The request must be confirmed.
+func (s *Server) handleSharesInstall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only admins can modify the host
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Confirm bool `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Confirm {
+		http.Error(w, "Installing Samba must be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	logging.Infof("User %s started installing Samba", user.Username)
+	output, err := shares.Install(r.Context())
+	if err != nil {
+		logging.Errorf("Failed to install Samba: %v", err)
+		http.Error(w, "Failed to install Samba: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Existing shares only become active once smbd is installed
+	if err := s.applyShares(r.Context()); err != nil {
+		logging.Warnf("Failed to apply shares after installing Samba: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"output": output,
+		"status": shares.GetStatus(r.Context()),
+	}); err != nil {
+		logging.Errorf("Failed to encode Samba status: %v", err)
+	}
+}
@@ -3,9 +3,14 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
 	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/lowmem"
+	"github.com/ontree-co/treeos/internal/metrics"
 )
 
 // SSEClient represents a connected SSE client
@@ -13,12 +18,22 @@ type SSEClient struct {
 	AppID    string
 	Messages chan string
 	Close    chan bool
+	dropped  atomic.Int64 // Messages Publish couldn't queue since the last "dropped" event
+}
+
+// sseBufferSize returns how many messages are queued per SSE client before they are dropped
+func (s *Server) sseBufferSize() int {
+	if s.profile.SSEBuffer > 0 {
+		return s.profile.SSEBuffer
+	}
+	return lowmem.Default.SSEBuffer
 }
 
 // SSEManager manages Server-Sent Event connections
 type SSEManager struct {
 	clients map[string]map[*SSEClient]bool // appID -> clients
 	mu      sync.RWMutex
+	polls   ssePolls // Clients of the long-polling transport
 }
 
 // NewSSEManager creates a new SSE manager
@@ -36,15 +51,35 @@ func (m *SSEManager) RegisterClient(appID string, client *SSEClient) {
 	if m.clients[appID] == nil {
 		m.clients[appID] = make(map[*SSEClient]bool)
 	}
+	if !m.clients[appID][client] {
+		metrics.SSEClients.Inc(sseStream(appID))
+	}
 	m.clients[appID][client] = true
 }
 
+// sseStream returns the metrics label of a channel, without the app name of per-app channels
+func sseStream(appID string) string {
+	if strings.HasPrefix(appID, "app-progress-") {
+		return "app-progress"
+	}
+	if strings.HasPrefix(appID, "app-logs-") {
+		return "app-logs"
+	}
+	if strings.HasPrefix(appID, "app-status-") {
+		return "app-status"
+	}
+	return appID
+}
+
 // UnregisterClient removes an SSE client
 func (m *SSEManager) UnregisterClient(appID string, client *SSEClient) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if clients, ok := m.clients[appID]; ok {
+		if clients[client] {
+			metrics.SSEClients.Dec(sseStream(appID))
+		}
 		delete(clients, client)
 		if len(clients) == 0 {
 			delete(m.clients, appID)
@@ -130,6 +165,39 @@ func (m *SSEManager) BroadcastMessage(appID string, messageData interface{}) {
 	}
 }
 
+// ClientCount returns the number of clients connected to a channel
+func (m *SSEManager) ClientCount(appID string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.clients[appID])
+}
+
+// Publish queues a formatted SSE message for all clients of a channel without waiting.
+// Unlike BroadcastMessage it is meant for high-volume streams such as logs: a client whose
+// buffer is full misses the message instead of slowing down the others or being dropped,
+// and gets a "dropped" event with the number of missed messages once it catches up.
+func (m *SSEManager) Publish(appID string, message string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for client := range m.clients[appID] {
+		if missed := client.dropped.Load(); missed > 0 {
+			select {
+			case client.Messages <- fmt.Sprintf("event: dropped\ndata: {\"count\": %d}\n\n", missed):
+				client.dropped.Add(-missed)
+			default:
+				client.dropped.Add(1)
+				continue
+			}
+		}
+		select {
+		case client.Messages <- message:
+		default:
+			client.dropped.Add(1)
+		}
+	}
+}
+
 // SendHeartbeat sends a heartbeat to keep connections alive
 func (m *SSEManager) SendHeartbeat(appID string) {
 	m.mu.RLock()
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+const (
+	// ssePollWait is how long a poll waits for an event, below the server's write timeout
+	ssePollWait = 25 * time.Second
+	// ssePollGather is how long a poll waits for more events after the first, so that a burst
+	// of progress updates arrives in one response
+	ssePollGather = 100 * time.Millisecond
+	// ssePollIdle is how long a session queues events without being polled
+	ssePollIdle = time.Minute
+	// ssePollMaxSessions bounds the open sessions, the least recently polled is dropped
+	ssePollMaxSessions = 256
+	// ssePollSessionHeader returns the session id to the client
+	ssePollSessionHeader = "X-Poll-Session"
+)
+
+// ssePollSession queues the events of a channel between the polls of one client
+type ssePollSession struct {
+	channel  string
+	client   *SSEClient
+	lastSeen time.Time
+}
+
+// ssePolls holds the sessions of the long-polling transport
+type ssePolls struct {
+	mu       sync.Mutex
+	sessions map[string]*ssePollSession
+}
+
+// isSSEPoll reports whether a request to an event stream asked for the long-polling transport.
+// Clients switch to it when a proxy drops or buffers the stream.
+func isSSEPoll(r *http.Request) bool {
+	return r.URL.Query().Get("transport") == "poll"
+}
+
+// open returns the session with id on channel, or a new one registered with the manager.
+// Sessions that have not been polled for ssePollIdle are removed first.
+func (p *ssePolls) open(m *SSEManager, id, channel string, buffer int) (string, *ssePollSession, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var oldest string
+	for key, session := range p.sessions {
+		if now.Sub(session.lastSeen) > ssePollIdle {
+			p.remove(m, key)
+			continue
+		}
+		if oldest == "" || session.lastSeen.Before(p.sessions[oldest].lastSeen) {
+			oldest = key
+		}
+	}
+
+	if session, ok := p.sessions[id]; ok && session.channel == channel {
+		select {
+		case <-session.client.Close:
+			// The manager dropped the client when its queue was full, start over
+			p.remove(m, id)
+		default:
+			session.lastSeen = now
+			return id, session, false
+		}
+	}
+
+	if len(p.sessions) >= ssePollMaxSessions && oldest != "" {
+		p.remove(m, oldest)
+	}
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		logging.Errorf("Failed to create poll session id: %v", err)
+	}
+	id = hex.EncodeToString(idBytes)
+	session := &ssePollSession{
+		channel: channel,
+		client: &SSEClient{
+			AppID:    channel,
+			Messages: make(chan string, buffer),
+			Close:    make(chan bool, 1),
+		},
+		lastSeen: now,
+	}
+	if p.sessions == nil {
+		p.sessions = make(map[string]*ssePollSession)
+	}
+	p.sessions[id] = session
+	m.RegisterClient(channel, session.client)
+	return id, session, true
+}
+
+// remove unregisters a session, p.mu must be held
+func (p *ssePolls) remove(m *SSEManager, id string) {
+	if session, ok := p.sessions[id]; ok {
+		m.UnregisterClient(session.channel, session.client)
+		delete(p.sessions, id)
+	}
+}
+
+// close removes a session
+func (p *ssePolls) close(m *SSEManager, id string) {
+	p.mu.Lock()
+	p.remove(m, id)
+	p.mu.Unlock()
+}
+
+// touch keeps a session open after a poll that waited for events
+func (p *ssePolls) touch(session *ssePollSession) {
+	p.mu.Lock()
+	session.lastSeen = time.Now()
+	p.mu.Unlock()
+}
+
+// serveSSEPoll answers one poll of the long-polling transport of an event stream. The response
+// has the framing of the stream and holds the events since the last poll, waiting up to
+// ssePollWait for one. The first poll opens a session, its id is returned in the X-Poll-Session
+// header and passed back in the session query parameter. initial returns the events a new
+// stream starts with.
+func (s *Server) serveSSEPoll(w http.ResponseWriter, r *http.Request, channel string, initial func() []string) {
+	if s.sseManager == nil {
+		http.Error(w, "SSE not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id, session, created := s.sseManager.polls.open(s.sseManager, r.URL.Query().Get("session"), channel, s.sseBufferSize())
+	var events []string
+	if created && initial != nil {
+		events = initial()
+	}
+
+	if len(events) == 0 {
+		timeout := time.NewTimer(ssePollWait)
+		defer timeout.Stop()
+		select {
+		case message := <-session.client.Messages:
+			events = append(events, message)
+		case <-session.client.Close:
+			// The manager dropped the client, the next poll opens a new session
+			s.sseManager.polls.close(s.sseManager, id)
+		case <-timeout.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if len(events) > 0 {
+		gather := time.NewTimer(ssePollGather)
+		defer gather.Stop()
+	collect:
+		for {
+			select {
+			case message := <-session.client.Messages:
+				events = append(events, message)
+			case <-gather.C:
+				break collect
+			case <-r.Context().Done():
+				// Try to deliver what was taken from the queue
+				break collect
+			}
+		}
+	}
+	s.sseManager.polls.touch(session)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(ssePollSessionHeader, id)
+	if len(events) == 0 {
+		// An empty poll is a keepalive, the client polls again
+		fmt.Fprint(w, ": keepalive\n\n") //nolint:errcheck // SSE stream
+		return
+	}
+	for _, event := range events {
+		if _, err := fmt.Fprint(w, event); err != nil {
+			logging.Errorf("Failed to write poll response for %s: %v", channel, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func pollJobs(t *testing.T, s *Server, session string) (string, string) {
+	t.Helper()
+	url := "/api/jobs/events?transport=poll"
+	if session != "" {
+		url += "&session=" + session
+	}
+	rec := httptest.NewRecorder()
+	s.routeAPIJobs(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("poll = %d %s", rec.Code, rec.Body.String())
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	return rec.Header().Get(ssePollSessionHeader), rec.Body.String()
+}
+
+func TestSSEPoll(t *testing.T) {
+	s := &Server{sseManager: NewSSEManager()}
+
+	// The first poll opens a session and returns the events a stream starts with
+	session, body := pollJobs(t, s, "")
+	if session == "" || body != jobsConnectedEvent {
+		t.Fatalf("first poll = %q %q", session, body)
+	}
+
+	// Events broadcast between polls are queued and returned with the stream's framing
+	s.sseManager.BroadcastMessage(jobsSSEChannel, map[string]interface{}{"event": "job", "name": "nextcloud"})
+	s.sseManager.BroadcastMessage(jobsSSEChannel, map[string]interface{}{"event": "job", "name": "immich"})
+	next, body := pollJobs(t, s, session)
+	if next != session {
+		t.Errorf("session = %q, want %q", next, session)
+	}
+	want := "event: job\ndata: {\"name\":\"nextcloud\"}\n\nevent: job\ndata: {\"name\":\"immich\"}\n\n"
+	if body != want {
+		t.Errorf("second poll = %q, want %q", body, want)
+	}
+
+	// An unknown session starts over
+	other, body := pollJobs(t, s, "expired")
+	if other == "" || other == session || !strings.HasPrefix(body, "event: connected") {
+		t.Errorf("poll with unknown session = %q %q", other, body)
+	}
+
+	// Sessions of another channel are not reused
+	rec := httptest.NewRecorder()
+	s.handleAPIModelsSSE(rec, httptest.NewRequest(http.MethodGet, "/api/models/events?transport=poll&session="+session, nil))
+	if got := rec.Header().Get(ssePollSessionHeader); got == session || rec.Body.String() != modelsConnectedEvent {
+		t.Errorf("models poll = %q %q", got, rec.Body.String())
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"os"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/storage"
+	"github.com/ontree-co/treeos/internal/yamlutil"
+	"gopkg.in/yaml.v3"
+)
+
+// createAppMount creates the app's mnt directory. If the app requests a storage class and a
+// matching storage root is configured, mnt is linked to a directory on that device instead.
+func (s *Server) createAppMount(mntPath, appName string, class storage.Class) error {
+	if class != "" {
+		if root, ok := storage.SelectRoot(s.config.StorageRoots, class); ok {
+			target, err := storage.PlaceAppMount(mntPath, appName, root)
+			if err != nil {
+				return err
+			}
+			logging.Infof("Placed data of app %s on %s storage at %s", appName, class, target)
+			return nil
+		}
+		logging.Warnf("No %s storage root available for app %s, using the apps directory", class, appName)
+	}
+
+	if err := os.MkdirAll(mntPath, 0750); err != nil {
+		return err
+	}
+	if warning := storage.PlacementWarning(class, mntPath); warning != "" {
+		logging.Warnf("App %s: %s", appName, warning)
+	}
+	return nil
+}
+
+// plannedStorageWarning warns before creating an app when its data would land on the system disk
+func (s *Server) plannedStorageWarning(class storage.Class) string {
+	dataPath := s.config.AppsDir
+	if root, ok := storage.SelectRoot(s.config.StorageRoots, class); ok {
+		dataPath = root.Path
+	}
+	return storage.PlacementWarning(class, dataPath)
+}
+
+// composeStorageClass returns the storage class declared in the x-ontree metadata of a compose file
+func composeStorageClass(composeContent string) storage.Class {
+	var compose yamlutil.ComposeFile
+	if err := yaml.Unmarshal([]byte(composeContent), &compose); err != nil || compose.XOnTree == nil {
+		return ""
+	}
+	class, err := storage.ParseClass(compose.XOnTree.StorageClass)
+	if err != nil {
+		logging.Warnf("Ignoring storage class in compose file: %v", err)
+		return ""
+	}
+	return class
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/templates"
+)
+
+// catalogSyncInterval is how often the enabled template catalogs are synced
+const catalogSyncInterval = 6 * time.Hour
+
+// catalogSyncTimeout bounds a sync, including the clone of a Git catalog
+const catalogSyncTimeout = 5 * time.Minute
+
+// TemplateCatalogRequest is the body of POST /api/templates/catalogs and PUT /api/templates/catalogs/{id}
+type TemplateCatalogRequest struct {
+	Name      string `json:"name"` // Ignored by PUT, the name is part of the template IDs
+	URL       string `json:"url"`
+	PublicKey string `json:"public_key"`
+	Enabled   *bool  `json:"enabled,omitempty"` // Defaults to true
+}
+
+// loadTemplateCatalogs hands the enabled catalogs to the template service, so their synced
+// templates are offered, and returns all catalogs
+func (s *Server) loadTemplateCatalogs() []database.TemplateCatalog {
+	catalogs, err := database.GetTemplateCatalogs()
+	if err != nil {
+		logging.Errorf("Failed to get template catalogs: %v", err)
+		return nil
+	}
+	sources := []templates.CatalogSource{}
+	for _, catalog := range catalogs {
+		if catalog.Enabled {
+			sources = append(sources, catalogSource(catalog))
+		}
+	}
+	s.templateSvc.SetCatalogs(sources)
+	return catalogs
+}
+
+// catalogSource returns what the template service needs to know of a catalog
+func catalogSource(catalog database.TemplateCatalog) templates.CatalogSource {
+	return templates.CatalogSource{Name: catalog.Name, URL: catalog.URL, PublicKey: catalog.PublicKey}
+}
+
+// syncTemplateCatalog syncs a catalog and records the outcome for the settings
+func (s *Server) syncTemplateCatalog(catalog database.TemplateCatalog) (*templates.CatalogSyncResult, error) {
+	s.catalogSyncMu.Lock()
+	defer s.catalogSyncMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), catalogSyncTimeout)
+	defer cancel()
+	result, err := s.templateSvc.SyncCatalog(ctx, catalogSource(catalog))
+	if err != nil {
+		logging.Warnf("Failed to sync template catalog %s: %v", catalog.Name, err)
+		if recordErr := database.RecordTemplateCatalogSync(catalog.ID, 0, err.Error()); recordErr != nil {
+			logging.Errorf("Failed to record sync of template catalog %s: %v", catalog.Name, recordErr)
+		}
+		return nil, err
+	}
+	for _, skipped := range result.Skipped {
+		logging.Warnf("Template catalog %s: left out %s", catalog.Name, skipped)
+	}
+	logging.Infof("Synced template catalog %s: %d templates", catalog.Name, result.Templates)
+	if err := database.RecordTemplateCatalogSync(catalog.ID, result.Templates, ""); err != nil {
+		logging.Errorf("Failed to record sync of template catalog %s: %v", catalog.Name, err)
+	}
+	return result, nil
+}
+
+// startCatalogSync syncs the enabled template catalogs at start and then periodically
+func (s *Server) startCatalogSync() {
+	if s.templateSvc == nil || s.db == nil {
+		return
+	}
+	syncAll := func() {
+		for _, catalog := range s.loadTemplateCatalogs() {
+			if catalog.Enabled {
+				s.syncTemplateCatalog(catalog) //nolint:errcheck // Recorded for the settings
+			}
+		}
+	}
+	syncAll()
+
+	ticker := time.NewTicker(catalogSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			syncAll()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// handleAPITemplateCatalogs handles /api/templates/catalogs: GET lists the catalogs, POST
+// adds one, PUT and DELETE /api/templates/catalogs/{id} change or remove one and POST
+// /api/templates/catalogs/{id}/sync syncs it now. Catalogs are for staff.
+func (s *Server) handleAPITemplateCatalogs(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.templateSvc == nil {
+		http.Error(w, "Templates are not available on this node", http.StatusNotFound)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/templates/catalogs"), "/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			catalogs, err := database.GetTemplateCatalogs()
+			if err != nil {
+				logging.Errorf("Failed to load template catalogs: %v", err)
+				http.Error(w, "Failed to load template catalogs", http.StatusInternalServerError)
+				return
+			}
+			writeCatalogJSON(w, http.StatusOK, map[string]interface{}{"catalogs": catalogs})
+		case http.MethodPost:
+			catalog, ok := decodeTemplateCatalogRequest(w, r, "")
+			if !ok {
+				return
+			}
+			catalogs, err := database.GetTemplateCatalogs()
+			if err != nil {
+				logging.Errorf("Failed to load template catalogs: %v", err)
+				http.Error(w, "Failed to load template catalogs", http.StatusInternalServerError)
+				return
+			}
+			for _, existing := range catalogs {
+				if existing.Name == catalog.Name {
+					http.Error(w, fmt.Sprintf("A catalog named %s already exists", catalog.Name), http.StatusConflict)
+					return
+				}
+			}
+			if err := database.CreateTemplateCatalog(catalog); err != nil {
+				logging.Errorf("Failed to create template catalog %s: %v", catalog.Name, err)
+				http.Error(w, "Failed to create template catalog", http.StatusInternalServerError)
+				return
+			}
+			logging.Infof("Template catalog %s added by %s: %s", catalog.Name, user.Username, catalog.URL)
+			s.loadTemplateCatalogs()
+			if catalog.Enabled {
+				go s.syncTemplateCatalog(*catalog) //nolint:errcheck // Recorded for the settings
+			}
+			writeCatalogJSON(w, http.StatusCreated, catalog)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	idText, action, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(idText)
+	if err != nil || (action != "" && action != "sync") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	catalog, err := database.GetTemplateCatalog(id)
+	if err != nil {
+		logging.Errorf("Failed to load template catalog %d: %v", id, err)
+		http.Error(w, "Failed to load template catalog", http.StatusInternalServerError)
+		return
+	}
+	if catalog == nil {
+		http.Error(w, "Template catalog not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "sync" && r.Method == http.MethodPost:
+		result, err := s.syncTemplateCatalog(*catalog)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Sync failed: %v", err), http.StatusBadGateway)
+			return
+		}
+		writeCatalogJSON(w, http.StatusOK, result)
+	case action == "" && r.Method == http.MethodPut:
+		changed, ok := decodeTemplateCatalogRequest(w, r, catalog.Name)
+		if !ok {
+			return
+		}
+		changed.ID, changed.CreatedAt = catalog.ID, catalog.CreatedAt
+		if _, err := database.UpdateTemplateCatalog(changed); err != nil {
+			logging.Errorf("Failed to update template catalog %d: %v", id, err)
+			http.Error(w, "Failed to update template catalog", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("Template catalog %s changed by %s", changed.Name, user.Username)
+		s.loadTemplateCatalogs()
+		if changed.Enabled && (changed.URL != catalog.URL || changed.PublicKey != catalog.PublicKey || !catalog.Enabled) {
+			go s.syncTemplateCatalog(*changed) //nolint:errcheck // Recorded for the settings
+		}
+		writeCatalogJSON(w, http.StatusOK, changed)
+	case action == "" && r.Method == http.MethodDelete:
+		if _, err := database.DeleteTemplateCatalog(id); err != nil {
+			logging.Errorf("Failed to delete template catalog %d: %v", id, err)
+			http.Error(w, "Failed to delete template catalog", http.StatusInternalServerError)
+			return
+		}
+		s.loadTemplateCatalogs()
+		if err := s.templateSvc.RemoveCatalog(catalog.Name); err != nil {
+			logging.Warnf("Failed to remove synced templates of catalog %s: %v", catalog.Name, err)
+		}
+		logging.Infof("Template catalog %s deleted by %s", catalog.Name, user.Username)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeTemplateCatalogRequest reads and validates a catalog from the request body,
+// writing the error response if it is invalid. name is the name of a catalog that is
+// changed, "" for a new one.
+func decodeTemplateCatalogRequest(w http.ResponseWriter, r *http.Request, name string) (*database.TemplateCatalog, bool) {
+	var req TemplateCatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return nil, false
+	}
+	if name == "" {
+		name = strings.TrimSpace(req.Name)
+	}
+	catalog := &database.TemplateCatalog{
+		Name:      name,
+		URL:       strings.TrimSpace(req.URL),
+		PublicKey: strings.TrimSpace(req.PublicKey),
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if err := templates.ValidateCatalogSource(catalogSource(*catalog)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return catalog, true
+}
+
+// writeCatalogJSON writes a JSON response with a status
+func writeCatalogJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/templates"
+)
+
+func TestHandleAPITemplateCatalogs(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	files := map[string]string{
+		"notes/template.json":      `{"id": "notes", "name": "Notes", "description": "Shared notes", "category_tags": ["Productivity"], "icon": "bi-journal", "port": "8095"}`,
+		"notes/docker-compose.yml": "services:\n  web:\n    image: nginx:1.27\n    ports:\n      - \"8095:80\"\n",
+	}
+	entry := templates.CatalogEntry{Dir: "notes", Files: map[string]string{}}
+	for name, content := range files {
+		sum := sha256.Sum256([]byte(content))
+		entry.Files[strings.TrimPrefix(name, "notes/")] = hex.EncodeToString(sum[:])
+	}
+	index, err := json.Marshal(templates.CatalogIndex{Templates: []templates.CatalogEntry{entry}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["catalog.json"] = string(index)
+	catalogServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer catalogServer.Close()
+
+	catalogDir := t.TempDir()
+	s := &Server{templateSvc: templates.NewService(".")}
+	s.templateSvc.SetCatalogDir(catalogDir)
+	staff := &database.User{Username: "admin", IsStaff: true}
+	request := func(user *database.User, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		s.handleAPITemplateCatalogs(rec, req)
+		return rec
+	}
+
+	if rec := request(&database.User{Username: "bob"}, http.MethodGet, "/api/templates/catalogs", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-staff: status = %d", rec.Code)
+	}
+	for _, body := range []string{
+		`{"name": "Community", "url": "https://example.com/catalog.json"}`,
+		`{"name": "community", "url": "file:///srv/apps.git"}`,
+		`{"name": "community", "url": "https://example.com/catalog.json", "public_key": "short"}`,
+	} {
+		if rec := request(staff, http.MethodPost, "/api/templates/catalogs", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", body, rec.Code)
+		}
+	}
+
+	body := `{"name": "community", "url": "` + catalogServer.URL + `/catalog.json", "enabled": false}`
+	rec := request(staff, http.MethodPost, "/api/templates/catalogs", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+	}
+	var catalog database.TemplateCatalog
+	if err := json.Unmarshal(rec.Body.Bytes(), &catalog); err != nil {
+		t.Fatal(err)
+	}
+	if rec := request(staff, http.MethodPost, "/api/templates/catalogs", body); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate name: status = %d", rec.Code)
+	}
+
+	path := "/api/templates/catalogs/" + strconv.Itoa(catalog.ID)
+	rec = request(staff, http.MethodPost, path+"/sync", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sync status = %d: %s", rec.Code, rec.Body)
+	}
+	if got, _ := database.GetTemplateCatalog(catalog.ID); got == nil || got.Templates != 1 || got.LastError != "" {
+		t.Errorf("sync not recorded: %+v", got)
+	}
+	if _, err := s.templateSvc.GetTemplateByID("community/notes"); err == nil {
+		t.Error("template of a disabled catalog is offered")
+	}
+
+	// Enabling syncs in the background and offers the templates
+	enabledAt := time.Now()
+	rec = request(staff, http.MethodPut, path, `{"url": "`+catalogServer.URL+`/catalog.json"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if got, _ := database.GetTemplateCatalog(catalog.ID); got != nil && got.LastSyncedAt != nil && !got.LastSyncedAt.Before(enabledAt) {
+			break
+		}
+	}
+	if template, err := s.templateSvc.GetTemplateByID("community/notes"); err != nil || template.Catalog != "community" {
+		t.Errorf("GetTemplateByID = %+v, %v", template, err)
+	}
+
+	if rec := request(staff, http.MethodDelete, path, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d", rec.Code)
+	}
+	if _, err := s.templateSvc.GetTemplateByID("community/notes"); err == nil {
+		t.Error("template of a deleted catalog is offered")
+	}
+	if _, err := os.Stat(filepath.Join(catalogDir, "community")); !os.IsNotExist(err) {
+		t.Errorf("synced catalog not removed: %v", err)
+	}
+	if rec := request(staff, http.MethodDelete, path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d", rec.Code)
+	}
+}
@@ -2,16 +2,20 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 	"github.com/ontree-co/treeos/internal/logging"
 
+	"github.com/ontree-co/treeos/internal/storage"
+	"github.com/ontree-co/treeos/internal/templates"
 	"gopkg.in/yaml.v3"
 )
 
@@ -111,6 +115,14 @@ func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Catalogs the templates come from, to filter by
+	catalogs := []string{}
+	for _, template := range templates {
+		if template.Catalog != "" && !slices.Contains(catalogs, template.Catalog) {
+			catalogs = append(catalogs, template.Catalog)
+		}
+	}
+
 	// Build a deterministic category order (alphabetical)
 	categoryOrder := make([]string, 0, len(categorySet))
 	for tag := range categorySet {
@@ -122,6 +134,14 @@ func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
 	data := s.baseTemplateData(user)
 	data["CategorizedTemplates"] = categorizedTemplates
 	data["CategoryOrder"] = categoryOrder
+	data["Catalogs"] = catalogs
+	data["HostArchitecture"] = s.templateSvc.Architecture()
+	templateIDs := make([]string, 0, len(templates))
+	for _, template := range templates {
+		templateIDs = append(templateIDs, template.ID)
+	}
+	data["PrefetchStates"] = s.templatePrefetchStates(templateIDs)
+	data["MaintenanceWindow"] = s.maintenanceWindow().String()
 	data["Messages"] = nil
 	data["CSRFToken"] = "" // No CSRF yet
 
@@ -144,10 +164,10 @@ func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
 func (s *Server) routeTemplates(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 
-	// Parse template ID from path like /templates/openwebui/create
-	parts := strings.Split(strings.TrimPrefix(path, "/templates/"), "/")
-	if len(parts) >= 2 && parts[1] == "create" {
-		templateID := parts[0]
+	// Parse template ID from path like /templates/openwebui/create, templates of remote
+	// catalogs have the catalog as prefix: /templates/community/wiki/create
+	templateID, ok := strings.CutSuffix(strings.TrimPrefix(path, "/templates/"), "/create")
+	if ok && templateID != "" {
 		s.handleCreateFromTemplate(w, r, templateID)
 	} else {
 		http.NotFound(w, r)
@@ -171,10 +191,18 @@ func (s *Server) handleCreateFromTemplate(w http.ResponseWriter, r *http.Request
 		// Show the form
 		data := s.baseTemplateData(user)
 		data["Template"] = template
+		data["HostArchitecture"] = s.templateSvc.Architecture()
 		data["Messages"] = nil
 		data["CSRFToken"] = "" // No CSRF yet
 		data["Emojis"] = getRandomEmojis(7)
 		data["SelectedEmoji"] = ""
+		if class, err := storage.ParseClass(template.StorageClass); err == nil {
+			data["StorageWarning"] = s.plannedStorageWarning(class)
+		}
+		data["Variables"] = templateVariableViews(template.Variables)
+		data["HasPortVariable"] = slices.ContainsFunc(template.Variables, func(v templates.Variable) bool {
+			return v.Kind() == templates.VariablePort
+		})
 
 		tmpl, ok := s.templates["app_create_from_template"]
 		if !ok {
@@ -206,14 +234,43 @@ func (s *Server) handleCreateFromTemplate(w http.ResponseWriter, r *http.Request
 			return
 		}
 
-		// Get template content
+		// Variables come as var_NAME, the last value wins so a checkbox overrides its hidden "false"
+		values, secrets, err := templates.ResolveVariables(template.Variables, func(name string) (string, bool) {
+			formValues := r.PostForm["var_"+name]
+			if len(formValues) == 0 {
+				return "", false
+			}
+			return formValues[len(formValues)-1], true
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Get template content, with the images of this node's architecture
 		content, err := s.templateSvc.GetTemplateContent(template)
+		if errors.Is(err, templates.ErrUnsupportedArchitecture) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if errors.Is(err, templates.ErrChecksumMismatch) || errors.Is(err, templates.ErrBadSignature) {
+			logging.Warnf("Template %s failed verification: %v", templateID, err)
+			http.Error(w, fmt.Sprintf("Template failed verification, sync its catalog again: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			logging.Errorf("Error getting template content: %v", err)
 			http.Error(w, "Failed to read template", http.StatusInternalServerError)
 			return
 		}
 
+		content, err = templates.RenderVariables(content, values)
+		if err != nil {
+			logging.Errorf("Error rendering variables of template %s: %v", templateID, err)
+			http.Error(w, "Failed to render template variables", http.StatusInternalServerError)
+			return
+		}
+
 		// Process template content first (replace placeholders)
 		processedContent := s.templateSvc.ProcessTemplateContent(content, appName)
 
@@ -229,6 +286,11 @@ func (s *Server) handleCreateFromTemplate(w http.ResponseWriter, r *http.Request
 
 		// Get .env.example content if it exists for this template
 		envContent, err := s.templateSvc.GetTemplateEnvExample(templateID)
+		if errors.Is(err, templates.ErrChecksumMismatch) || errors.Is(err, templates.ErrBadSignature) {
+			logging.Warnf("Template %s failed verification: %v", templateID, err)
+			http.Error(w, fmt.Sprintf("Template failed verification, sync its catalog again: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			logging.Errorf("Error reading .env.example for template %s: %v", templateID, err)
 			http.Error(w, "Failed to read template environment file", http.StatusInternalServerError)
@@ -237,9 +299,40 @@ func (s *Server) handleCreateFromTemplate(w http.ResponseWriter, r *http.Request
 		if envContent != "" {
 			logging.Infof("Found .env.example for template %s, will use default environment variables", templateID)
 		}
+		envContent, err = templates.RenderVariables(envContent, values)
+		if err == nil {
+			envContent, err = templates.SetEnvVariables(envContent, template.Variables, values)
+		}
+		if err != nil {
+			logging.Errorf("Error rendering variables of template %s: %v", templateID, err)
+			http.Error(w, "Failed to render template variables", http.StatusInternalServerError)
+			return
+		}
+
+		storageClass, err := storage.ParseClass(template.StorageClass)
+		if err != nil {
+			logging.Errorf("Invalid storage class in template %s: %v", templateID, err)
+			http.Error(w, "Invalid template storage class", http.StatusInternalServerError)
+			return
+		}
+
+		processedContent, err = s.newAppNamespace(user, appName, processedContent)
+		if err != nil {
+			logging.Errorf("Namespace of app %s from template %s refused: %v", appName, templateID, err)
+			http.Error(w, err.Error(), namespaceErrorStatus(err))
+			return
+		}
+
+		// Ports other apps or processes use are moved to free ones
+		processedContent, envContent, err = s.claimHostPorts(appName, processedContent, envContent, true)
+		if err != nil {
+			logging.Errorf("Host ports of app %s from template %s are taken: %v", appName, templateID, err)
+			http.Error(w, err.Error(), portErrorStatus(err))
+			return
+		}
 
 		// Create the app using scaffold logic with template flag
-		if err := s.createAppScaffoldFromTemplate(appName, processedContent, envContent, emoji); err != nil {
+		if err := s.createAppScaffoldFromTemplate(appName, processedContent, envContent, emoji, storageClass, secrets); err != nil {
 			logging.Errorf("Error creating app from template: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to create application: %v", err), http.StatusInternalServerError)
 			return
@@ -303,6 +396,56 @@ endpoints:
 	}
 }
 
+// renderDefaultVariables renders the variables of a template into its compose and .env
+// content with their defaults, for uses without the create form
+func renderDefaultVariables(template *templates.Template, composeContent, envContent string) (string, string, error) {
+	if len(template.Variables) == 0 {
+		return composeContent, envContent, nil
+	}
+	values, _, err := templates.ResolveVariables(template.Variables, func(string) (string, bool) { return "", false })
+	if err != nil {
+		return "", "", err
+	}
+	if composeContent, err = templates.RenderVariables(composeContent, values); err != nil {
+		return "", "", err
+	}
+	if envContent, err = templates.RenderVariables(envContent, values); err != nil {
+		return "", "", err
+	}
+	envContent, err = templates.SetEnvVariables(envContent, template.Variables, values)
+	return composeContent, envContent, err
+}
+
+// templateVariableView is a template variable as the create form shows it
+type templateVariableView struct {
+	templates.Variable
+	InputType string // HTML input type, "select" and "checkbox" get their own markup
+	Value     string
+	Checked   bool
+}
+
+// templateVariableViews prepares the variables of a template for the create form
+func templateVariableViews(vars []templates.Variable) []templateVariableView {
+	inputTypes := map[string]string{
+		templates.VariablePort:     "number",
+		templates.VariableNumber:   "number",
+		templates.VariablePassword: "password",
+		templates.VariableEmail:    "email",
+		templates.VariableBoolean:  "checkbox",
+		templates.VariableSelect:   "select",
+	}
+	views := make([]templateVariableView, 0, len(vars))
+	for _, v := range vars {
+		inputType, ok := inputTypes[v.Kind()]
+		if !ok {
+			inputType = "text"
+		}
+		enabled, _ := strconv.ParseBool(v.Default)
+		views = append(views, templateVariableView{Variable: v, InputType: inputType, Value: v.Default, Checked: enabled})
+	}
+	return views
+}
+
 // replacePortsInYAML replaces all host ports in the docker-compose YAML with the custom port
 func (s *Server) replacePortsInYAML(content string, customPort string) (string, error) {
 	// Validate port number
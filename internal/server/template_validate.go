@@ -0,0 +1,35 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/templates"
+)
+
+// handleAPITemplateValidate checks a contributed template bundle against the catalog's schema,
+// security rules and architectures. An invalid bundle is still answered with 200, the report
+// says why it fails.
+func (s *Server) handleAPITemplateValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var bundle templates.Bundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if len(bundle.Files) == 0 {
+		http.Error(w, "files is required", http.StatusBadRequest)
+		return
+	}
+
+	report := templates.ValidateBundle(&bundle)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logging.Errorf("Failed to encode template validation report: %v", err)
+	}
+}
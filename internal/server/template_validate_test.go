@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/templates"
+)
+
+func TestHandleAPITemplateValidate(t *testing.T) {
+	s := &Server{}
+	body := `{"dir": "whoami", "files": {
+		"template.json": "{\"id\": \"whoami\", \"name\": \"Whoami\", \"description\": \"Echo server\", \"filename\": \"docker-compose.yml\", \"port\": \"8080\"}",
+		"docker-compose.yml": "services:\n  whoami:\n    image: traefik/whoami:v1.10\n    privileged: true\n    ports:\n      - \"8080:80\"\n"
+	}}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/templates/validate", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.handleAPITemplateValidate(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	var report templates.ValidationReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.Valid || report.Template != "whoami" {
+		t.Errorf("report = %+v, want an invalid report for whoami", report)
+	}
+	found := false
+	for _, issue := range report.Issues {
+		found = found || (issue.Check == templates.CheckSecurity && issue.Severity == templates.IssueError)
+	}
+	if !found {
+		t.Errorf("issues = %+v, want a security error for the privileged service", report.Issues)
+	}
+
+	for _, tc := range []struct {
+		method, body string
+		want         int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "not json", http.StatusBadRequest},
+		{http.MethodPost, `{"dir": "whoami"}`, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		s.handleAPITemplateValidate(rec, httptest.NewRequest(tc.method, "/api/templates/validate", strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %q: status = %d, want %d", tc.method, tc.body, rec.Code, tc.want)
+		}
+	}
+}
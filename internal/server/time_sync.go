@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/timesync"
+)
+
+// timeSyncCheckInterval is how often the clock offset is measured
+const timeSyncCheckInterval = time.Hour
+
+// TimeSyncResponse is returned by the time synchronization endpoints
+type TimeSyncResponse struct {
+	timesync.Status
+	Drifted bool   `json:"drifted"`
+	Output  string `json:"output,omitempty"` // Output of the repair commands
+}
+
+// startTimeSyncMonitor measures the clock offset periodically and notifies admins when the
+// clock drifts, which breaks TLS and two-factor logins in apps without any visible error
+func (s *Server) startTimeSyncMonitor() {
+	go func() {
+		s.checkTimeSync()
+
+		ticker := time.NewTicker(timeSyncCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.checkTimeSync()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Server) checkTimeSync() {
+	status := timesync.Check(context.Background())
+	if !status.OffsetKnown {
+		logging.Debugf("Failed to measure clock offset: %s", status.OffsetError)
+		return
+	}
+
+	drifted := status.Drifted()
+	s.timeSyncMu.Lock()
+	alert := drifted && !s.timeSyncDrifted
+	recovered := !drifted && s.timeSyncDrifted
+	s.timeSyncDrifted = drifted
+	s.timeSyncMu.Unlock()
+
+	switch {
+	case alert:
+		message := fmt.Sprintf("The clock of this node is off by %s compared to %s. TLS connections and two-factor logins in apps may fail.",
+			status.Offset.Round(time.Second), status.Server)
+		if status.Supported && !status.Enabled {
+			message += " Time synchronization is disabled, enable it in Settings under Host Packages."
+		}
+		logging.Warnf("Clock drift: %s", message)
+		s.notifyAdmins("TreeOS: clock drift detected", message+"\n")
+	case recovered:
+		logging.Infof("Clock is synchronized again, offset %s", status.Offset.Round(time.Millisecond))
+	}
+}
+
+// handleTimeSync reports the time synchronization status and clock offset
+func (s *Server) handleTimeSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeTimeSync(r.Context(), w, "")
+}
+
+// handleTimeSyncRepair enables time synchronization on the host. The request must confirm
+// the action with {"confirm": true}.
+func (s *Server) handleTimeSyncRepair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only admins can modify the host
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Confirm bool `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Confirm {
+		http.Error(w, "Enabling time synchronization must be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	output, err := timesync.Repair(r.Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, timesync.ErrUnsupported) {
+			status = http.StatusNotImplemented
+		}
+		logging.Errorf("Failed to enable time synchronization: %v", err)
+		http.Error(w, "Failed to enable time synchronization: "+err.Error(), status)
+		return
+	}
+	logging.Infof("User %s enabled time synchronization", user.Username)
+
+	s.timeSyncMu.Lock()
+	s.timeSyncDrifted = false
+	s.timeSyncMu.Unlock()
+	writeTimeSync(r.Context(), w, output)
+}
+
+func writeTimeSync(ctx context.Context, w http.ResponseWriter, output string) {
+	status := timesync.Check(ctx)
+	resp := TimeSyncResponse{Status: status, Drifted: status.Drifted(), Output: output}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.Errorf("Failed to encode time synchronization status: %v", err)
+	}
+}
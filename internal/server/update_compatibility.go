@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/update"
+)
+
+// appComposeFiles returns the compose files of all apps for the update compatibility check
+func (s *Server) appComposeFiles() ([]update.AppCompose, error) {
+	apps, err := s.reviewableApps()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]update.AppCompose, 0, len(apps))
+	for _, app := range apps {
+		data, err := os.ReadFile(filepath.Join(s.config.AppsDir, app, "docker-compose.yml")) //nolint:gosec // Path from app directory
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compose file of %s: %w", app, err)
+		}
+		files = append(files, update.AppCompose{App: app, Compose: data})
+	}
+	return files, nil
+}
+
+// updateCompatibility checks the node and its apps against the latest release of the channel
+func (s *Server) updateCompatibility(updateSvc *update.Service) (*update.CompatibilityReport, error) {
+	apps, err := s.appComposeFiles()
+	if err != nil {
+		return nil, err
+	}
+	return updateSvc.CheckCompatibility(apps)
+}
+
+// compatibilitySummary lists the errors of a report in one line each, for logs and emails
+func compatibilitySummary(report *update.CompatibilityReport) string {
+	var lines []string
+	for _, f := range report.Node {
+		if f.Severity == update.FindingError {
+			lines = append(lines, "- "+f.Message)
+		}
+	}
+	for _, app := range report.Apps {
+		for _, f := range app.Findings {
+			if f.Severity == update.FindingError {
+				lines = append(lines, "- "+app.App+": "+f.Message)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// backupBeforeMigrations backs up the database if the release announces migrations, so the
+// previous version can be restored with its database
+func (s *Server) backupBeforeMigrations(report *update.CompatibilityReport) {
+	if report == nil || report.Migrations == 0 || s.config.DatabasePath == "" {
+		return
+	}
+	if path, err := database.Backup(s.config.DatabasePath, databaseBackupsKept); err != nil {
+		logging.Errorf("Failed to back up database before update: %v", err)
+	} else {
+		logging.Infof("Backed up database to %s before updating to %s", path, report.Version)
+	}
+}
+
+// handleSystemUpdateCompatibility handles GET /api/system/update/compatibility and reports,
+// per app, what the latest release of the channel breaks or deprecates
+func (s *Server) handleSystemUpdateCompatibility(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := s.updateCompatibility(s.updateService(s.getUpdateChannel()))
+	if err != nil {
+		logging.Errorf("Failed to check update compatibility: %v", err)
+		http.Error(w, "Failed to check update compatibility", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/update"
+)
+
+func TestAppComposeFilesCompatibility(t *testing.T) {
+	appsDir := t.TempDir()
+	composes := map[string]string{
+		"web":  "services:\n  web:\n    image: nginx\n    links: [db]\n",
+		"wiki": "services:\n  wiki:\n    image: wiki\n",
+	}
+	for app, compose := range composes {
+		if err := os.MkdirAll(filepath.Join(appsDir, app), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(appsDir, app, "docker-compose.yml"), []byte(compose), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory without a compose file is not an app
+	if err := os.MkdirAll(filepath.Join(appsDir, ".backup"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+	apps, err := s.appComposeFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 {
+		t.Fatalf("appComposeFiles() = %d apps, want 2", len(apps))
+	}
+
+	c := &update.Compatibility{DeprecatedFields: []update.DeprecatedField{{Field: "services.*.links", Removed: true}}}
+	report := update.CheckCompatibility("0.10.0", "0.11.0", c, apps)
+	if got, want := compatibilitySummary(report), "- web: services.web.links is no longer supported"; got != want {
+		t.Errorf("compatibilitySummary() = %q, want %q", got, want)
+	}
+}
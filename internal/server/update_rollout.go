@@ -0,0 +1,44 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/update"
+)
+
+// nodeID returns the random identifier of this node, created on first use. It only places the
+// node in staged rollouts and is never sent anywhere.
+func (s *Server) nodeID() string {
+	if s.db != nil {
+		var id sql.NullString
+		err := s.db.QueryRow(`SELECT node_id FROM system_setup WHERE id = 1`).Scan(&id)
+		switch {
+		case err == nil && id.String != "":
+			return id.String
+		case err == nil:
+			newID := uuid.New().String()
+			if _, err := s.db.Exec(`UPDATE system_setup SET node_id = ? WHERE id = 1 AND (node_id IS NULL OR node_id = '')`, newID); err != nil {
+				logging.Errorf("Failed to store node ID: %v", err)
+			} else if err := s.db.QueryRow(`SELECT node_id FROM system_setup WHERE id = 1`).Scan(&id); err == nil {
+				// Another request may have stored one first
+				return id.String
+			}
+		case !errors.Is(err, sql.ErrNoRows):
+			logging.Errorf("Failed to read node ID: %v", err)
+		}
+	}
+	// Before setup there is nowhere to store it, the host name keeps the node in the same bucket
+	hostname, _ := os.Hostname() //nolint:errcheck // Empty host name still gives a bucket
+	return hostname
+}
+
+// updateService returns the self-update service for a channel, set up for this node
+func (s *Server) updateService(channel update.UpdateChannel) *update.Service {
+	updateSvc := update.NewService(channel)
+	updateSvc.SetNodeID(s.nodeID())
+	return updateSvc
+}
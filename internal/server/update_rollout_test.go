@@ -0,0 +1,30 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestNodeID(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+
+	db := database.GetDB()
+	if _, err := db.Exec(`INSERT INTO system_setup (id) VALUES (1)`); err != nil {
+		t.Fatalf("Failed to create setup row: %v", err)
+	}
+	s := &Server{db: db}
+
+	id := s.nodeID()
+	if len(id) != 36 {
+		t.Fatalf("nodeID() = %q, want a UUID", id)
+	}
+	if again := s.nodeID(); again != id {
+		t.Errorf("nodeID() = %q, then %q", id, again)
+	}
+}
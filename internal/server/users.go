@@ -0,0 +1,383 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+)
+
+// userMinPasswordLength is the shortest password of users created by admins, as for invites
+const userMinPasswordLength = 8
+
+// userView is a user as listed by /api/users, without the password hash
+type userView struct {
+	ID         int        `json:"id"`
+	Username   string     `json:"username"`
+	Email      string     `json:"email,omitempty"`
+	Role       string     `json:"role"`
+	Superuser  bool       `json:"superuser"`
+	Active     bool       `json:"active"`
+	AuthSource string     `json:"auth_source"`
+	DateJoined time.Time  `json:"date_joined"`
+	LastLogin  *time.Time `json:"last_login,omitempty"`
+}
+
+// isValidRole reports whether role is one of the user roles
+func isValidRole(role string) bool {
+	switch role {
+	case database.RoleAdmin, database.RoleOperator, database.RoleViewer:
+		return true
+	}
+	return false
+}
+
+// roleAllows reports whether the role of a user permits a request. Viewers can only read,
+// except for managing their own API tokens and password. What admins alone may do is
+// checked by the handlers.
+func roleAllows(user *database.User, r *http.Request) bool {
+	if user.IsStaff || user.Role != database.RoleViewer {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return r.URL.Path == "/api/tokens" || r.URL.Path == "/api/account/password"
+}
+
+// listAllUsers returns all users including disabled ones, ordered by username
+func (s *Server) listAllUsers() ([]userView, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT id, username, COALESCE(email, ''), ` + userRoleColumn + `, is_superuser, is_active,
+		       COALESCE(auth_source, 'local'), date_joined, last_login
+		FROM users ORDER BY username
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // Cleanup, error not critical
+
+	users := []userView{}
+	for rows.Next() {
+		var user userView
+		var lastLogin sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.Superuser, &user.Active,
+			&user.AuthSource, &user.DateJoined, &lastLogin); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		if lastLogin.Valid {
+			user.LastLogin = &lastLogin.Time
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// setUserRole changes the role of a user. Admins are staff users; taking the role away
+// also takes away superuser rights.
+func setUserRole(id int, role string) error {
+	_, err := database.GetDB().Exec(`
+		UPDATE users SET role = ?, is_staff = ?, is_superuser = CASE WHEN ? THEN is_superuser ELSE 0 END
+		WHERE id = ?
+	`, role, role == database.RoleAdmin, role == database.RoleAdmin, id)
+	if err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	return nil
+}
+
+// handleAPIUsers handles /api/users for admins: GET lists all users, POST {"username",
+// "password", "email", "role"} creates one, PATCH /api/users/{id} {"role", "active",
+// "password"} changes the role of a user, disables them or sets their password. Disabled
+// users can't log in, and their sessions and API tokens stop working. A new password ends
+// the user's sessions.
+func (s *Server) handleAPIUsers(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	idPart := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/users"), "/")
+	switch {
+	case idPart == "" && r.Method == http.MethodGet:
+		users, err := s.listAllUsers()
+		if err != nil {
+			logging.Errorf("Failed to list users: %v", err)
+			http.Error(w, "Failed to list users", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"users": users}); err != nil {
+			logging.Errorf("Failed to encode response: %v", err)
+		}
+
+	case idPart == "" && r.Method == http.MethodPost:
+		s.createUserFromAPI(w, r, user)
+
+	case idPart != "" && r.Method == http.MethodPatch:
+		id, err := strconv.Atoi(idPart)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		s.updateUserFromAPI(w, r, user, id)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createUserFromAPI creates a local user with a role
+func (s *Server) createUserFromAPI(w http.ResponseWriter, r *http.Request, admin *database.User) {
+	var request struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Email    string `json:"email"`
+		Role     string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	request.Username = strings.TrimSpace(request.Username)
+	if !usernamePattern.MatchString(request.Username) {
+		http.Error(w, "Usernames are up to 32 letters, digits, dots, dashes and underscores", http.StatusBadRequest)
+		return
+	}
+	if len(request.Password) < userMinPasswordLength {
+		http.Error(w, fmt.Sprintf("The password needs at least %d characters", userMinPasswordLength), http.StatusBadRequest)
+		return
+	}
+	if request.Role == "" {
+		request.Role = database.RoleOperator
+	}
+	if !isValidRole(request.Role) {
+		http.Error(w, "role must be admin, operator or viewer", http.StatusBadRequest)
+		return
+	}
+
+	var taken bool
+	if err := database.GetDB().QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)`, request.Username).Scan(&taken); err != nil {
+		logging.Errorf("Failed to look up user: %v", err)
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		http.Error(w, fmt.Sprintf("The username %s is taken", request.Username), http.StatusConflict)
+		return
+	}
+
+	created, err := s.createUser(request.Username, request.Password, strings.TrimSpace(request.Email), request.Role == database.RoleAdmin, false)
+	if err == nil && request.Role == database.RoleViewer {
+		err = setUserRole(created.ID, request.Role)
+		created.Role = request.Role
+	}
+	if err != nil {
+		logging.Errorf("Failed to create user: %v", err)
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("User %s created user %s with the %s role", admin.Username, created.Username, created.Role)
+	recordActivity(database.ActivityEvent{
+		Category: database.ActivityAudit,
+		Title:    "User " + created.Username + " created",
+		Detail:   "Role " + created.Role,
+		Actor:    admin.Username,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(userView{
+		ID:         created.ID,
+		Username:   created.Username,
+		Email:      created.Email.String,
+		Role:       created.Role,
+		Active:     true,
+		AuthSource: created.AuthSource,
+		DateJoined: created.DateJoined,
+	}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// updateUserFromAPI changes the role of a user, disables them or sets their password.
+// Admins can't change themselves, so there is always an admin left, and only superusers
+// can change superusers.
+func (s *Server) updateUserFromAPI(w http.ResponseWriter, r *http.Request, admin *database.User, id int) {
+	var request struct {
+		Role     *string `json:"role"`
+		Active   *bool   `json:"active"`
+		Password *string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Role != nil && !isValidRole(*request.Role) {
+		http.Error(w, "role must be admin, operator or viewer", http.StatusBadRequest)
+		return
+	}
+	if request.Password != nil && len(*request.Password) < userMinPasswordLength {
+		http.Error(w, fmt.Sprintf("The password needs at least %d characters", userMinPasswordLength), http.StatusBadRequest)
+		return
+	}
+	if id == admin.ID {
+		http.Error(w, "You can't change your own role or disable yourself", http.StatusForbidden)
+		return
+	}
+
+	var username, authSource string
+	var superuser bool
+	err := database.GetDB().QueryRow(`SELECT username, is_superuser, COALESCE(auth_source, 'local') FROM users WHERE id = ?`, id).
+		Scan(&username, &superuser, &authSource)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.Errorf("Failed to look up user: %v", err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	if superuser && !admin.IsSuperuser {
+		http.Error(w, "Only superusers can change a superuser", http.StatusForbidden)
+		return
+	}
+	if request.Password != nil && authSource == database.AuthSourceLDAP {
+		http.Error(w, "Passwords of directory users are changed in the directory", http.StatusBadRequest)
+		return
+	}
+
+	if request.Role != nil {
+		if err := setUserRole(id, *request.Role); err != nil {
+			logging.Errorf("Failed to update user %s: %v", username, err)
+			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s gave user %s the %s role", admin.Username, username, *request.Role)
+		recordActivity(database.ActivityEvent{
+			Category: database.ActivityAudit,
+			Title:    "Role of " + username + " changed",
+			Detail:   "Role " + *request.Role,
+			Actor:    admin.Username,
+		})
+	}
+	if request.Active != nil {
+		if _, err := database.GetDB().Exec(`UPDATE users SET is_active = ? WHERE id = ?`, *request.Active, id); err != nil {
+			logging.Errorf("Failed to update user %s: %v", username, err)
+			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+			return
+		}
+		if !*request.Active {
+			if err := database.DeleteUserSessions(id); err != nil {
+				logging.Errorf("Failed to end the sessions of %s: %v", username, err)
+			}
+		}
+		title := "User " + username + " disabled"
+		if *request.Active {
+			title = "User " + username + " enabled"
+		}
+		logging.Infof("User %s: %s", admin.Username, title)
+		recordActivity(database.ActivityEvent{Category: database.ActivityAudit, Title: title, Actor: admin.Username})
+	}
+	if request.Password != nil {
+		if _, err := setUserPassword(id, *request.Password); err != nil {
+			logging.Errorf("Failed to update user %s: %v", username, err)
+			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s set the password of user %s", admin.Username, username)
+		recordActivity(database.ActivityEvent{
+			Category: database.ActivityAudit,
+			Title:    "Password of " + username + " reset",
+			Detail:   "Their sessions were ended",
+			Actor:    admin.Username,
+		})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAPIAccountPassword handles POST /api/account/password {"current_password",
+// "new_password"}, users changing their own password. It ends their other sessions, the
+// one making the request goes on. Directory users change their password in the directory.
+func (s *Server) handleAPIAccountPassword(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if isAPITokenRequest(r.Context()) {
+		http.Error(w, "Passwords can only be changed with a browser session", http.StatusForbidden)
+		return
+	}
+	if user.AuthSource == database.AuthSourceLDAP {
+		http.Error(w, "Passwords of directory users are changed in the directory", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if s.verifyPassword(user, request.CurrentPassword) != nil {
+		logging.Warnf("SECURITY: Rejected password change of %s: wrong current password", user.Username)
+		http.Error(w, "The current password is wrong", http.StatusForbidden)
+		return
+	}
+	if len(request.NewPassword) < userMinPasswordLength {
+		http.Error(w, fmt.Sprintf("The password needs at least %d characters", userMinPasswordLength), http.StatusBadRequest)
+		return
+	}
+
+	version, err := setUserPassword(user.ID, request.NewPassword)
+	if err != nil {
+		logging.Errorf("Failed to change password of %s: %v", user.Username, err)
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("User %s changed their password", user.Username)
+	recordActivity(database.ActivityEvent{
+		Category: database.ActivityAudit,
+		Title:    "Password of " + user.Username + " changed",
+		Detail:   "Their other sessions were ended",
+		Actor:    user.Username,
+	})
+
+	// Keep the session of this request, as a new login
+	session, err := s.sessionStore.Get(r, "ontree-session")
+	if err != nil {
+		logging.Errorf("Failed to get session: %v", err)
+	}
+	remember, _ := session.Values["remember"].(bool)
+	user.SessionVersion = version
+	if err := s.startSession(session, user, remember); err != nil {
+		logging.Errorf("Failed to start session of %s: %v", user.Username, err)
+		http.Error(w, "Failed to keep the session", http.StatusInternalServerError)
+		return
+	}
+	if err := session.Save(r, w); err != nil {
+		logging.Errorf("Failed to save session: %v", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
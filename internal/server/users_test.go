@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestHandleAPIUsers(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{config: &config.Config{}}
+	root, err := s.createUser("root", "password", "", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := s.createUser("admin", "password", "", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := func(method, target, body string, user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIUsers(rec, req)
+		return rec
+	}
+
+	for body, want := range map[string]int{
+		`{"username": "bad name", "password": "password"}`:                http.StatusBadRequest,
+		`{"username": "alice", "password": "short"}`:                      http.StatusBadRequest,
+		`{"username": "alice", "password": "password", "role": "owner"}`:  http.StatusBadRequest,
+		`{"username": "admin", "password": "password", "role": "viewer"}`: http.StatusConflict,
+	} {
+		if rec := api(http.MethodPost, "/api/users", body, admin); rec.Code != want {
+			t.Errorf("POST %s: status = %d", body, rec.Code)
+		}
+	}
+
+	rec := api(http.MethodPost, "/api/users", `{"username": "alice", "password": "password", "role": "viewer"}`, admin)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+	}
+	var created userView
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	alice, err := s.getUserByID(created.ID)
+	if err != nil || alice.Role != database.RoleViewer || alice.IsStaff {
+		t.Fatalf("created user = %+v, %v", alice, err)
+	}
+	if rec := api(http.MethodGet, "/api/users", "", alice); rec.Code != http.StatusUnauthorized {
+		t.Errorf("viewer GET status = %d", rec.Code)
+	}
+
+	patch := func(id int, body string, user *database.User) int {
+		return api(http.MethodPatch, "/api/users/"+strconv.Itoa(id), body, user).Code
+	}
+	if code := patch(admin.ID, `{"role": "viewer"}`, admin); code != http.StatusForbidden {
+		t.Errorf("changing yourself: status = %d", code)
+	}
+	if code := patch(root.ID, `{"active": false}`, admin); code != http.StatusForbidden {
+		t.Errorf("admin disabling a superuser: status = %d", code)
+	}
+	if code := patch(999, `{"active": false}`, admin); code != http.StatusNotFound {
+		t.Errorf("missing user: status = %d", code)
+	}
+	if code := patch(created.ID, `{"role": "admin"}`, admin); code != http.StatusNoContent {
+		t.Errorf("promoting: status = %d", code)
+	}
+	if alice, err := s.getUserByID(created.ID); err != nil || alice.Role != database.RoleAdmin || !alice.IsStaff {
+		t.Errorf("promoted user = %+v, %v", alice, err)
+	}
+	if code := patch(admin.ID, `{"role": "operator"}`, root); code != http.StatusNoContent {
+		t.Errorf("demoting: status = %d", code)
+	}
+	if admin, err := s.getUserByID(admin.ID); err != nil || admin.Role != database.RoleOperator || admin.IsStaff {
+		t.Errorf("demoted user = %+v, %v", admin, err)
+	}
+	if code := patch(created.ID, `{"password": "short"}`, root); code != http.StatusBadRequest {
+		t.Errorf("short password: status = %d", code)
+	}
+	if code := patch(created.ID, `{"password": "new-password"}`, root); code != http.StatusNoContent {
+		t.Errorf("setting the password: status = %d", code)
+	}
+	if alice, err := s.getUserByID(created.ID); err != nil || alice.SessionVersion != 1 || checkPassword("new-password", alice.Password) != nil {
+		t.Errorf("user after setting the password = %+v, %v", alice, err)
+	}
+	if code := patch(created.ID, `{"active": false}`, root); code != http.StatusNoContent {
+		t.Errorf("disabling: status = %d", code)
+	}
+	if _, err := s.getUserByID(created.ID); err == nil {
+		t.Error("disabled user can still log in")
+	}
+
+	rec = api(http.MethodGet, "/api/users", "", root)
+	var listed struct {
+		Users []userView `json:"users"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || len(listed.Users) != 3 || listed.Users[1].Username != "alice" || listed.Users[1].Active {
+		t.Errorf("GET users = %+v, %v", listed, err)
+	}
+}
+
+func TestHandleAPIAccountPassword(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	s := &Server{
+		config:       &config.Config{SessionMaxLifetime: time.Hour},
+		sessionStore: sessions.NewCookieStore([]byte("test-session-key-0123456789abcdef")),
+	}
+	alice, err := s.createUser("alice", "password", "", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change := func(body string, user *database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/account/password", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		rec := httptest.NewRecorder()
+		s.handleAPIAccountPassword(rec, req)
+		return rec
+	}
+
+	for body, want := range map[string]int{
+		`{"current_password": "wrong", "new_password": "new-password"}`:    http.StatusForbidden,
+		`{"current_password": "password", "new_password": "short"}`:        http.StatusBadRequest,
+		`{"current_password": "password", "new_password": "new-password"}`: http.StatusNoContent,
+	} {
+		if rec := change(body, alice); rec.Code != want {
+			t.Errorf("POST %s: status = %d", body, rec.Code)
+		}
+	}
+	changed, err := s.getUserByID(alice.ID)
+	if err != nil || changed.SessionVersion != 1 || checkPassword("new-password", changed.Password) != nil {
+		t.Fatalf("user after the change = %+v, %v", changed, err)
+	}
+
+	// Sessions from before the change end, the one that changed it goes on
+	old := sessions.NewSession(s.sessionStore, "ontree-session")
+	old.Options = &sessions.Options{}
+	if err := s.startSession(old, &database.User{ID: alice.ID}, false); err != nil {
+		t.Fatal(err)
+	}
+	if sessionCurrent(old, changed) {
+		t.Error("session from before the change is still current")
+	}
+	rec := change(`{"current_password": "new-password", "new_password": "password"}`, changed)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	session, err := s.sessionStore.Get(req, "ontree-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := s.getUserByID(alice.ID); err != nil || !sessionCurrent(session, changed) {
+		t.Errorf("session of the change is not current: %v, %v", session.Values, err)
+	}
+
+	ldapUser := &database.User{ID: alice.ID, Username: "alice", AuthSource: database.AuthSourceLDAP}
+	if rec := change(`{"current_password": "password", "new_password": "new-password"}`, ldapUser); rec.Code != http.StatusBadRequest {
+		t.Errorf("directory user: status = %d", rec.Code)
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	viewer := &database.User{Role: database.RoleViewer}
+	operator := &database.User{Role: database.RoleOperator}
+	for _, tt := range []struct {
+		user   *database.User
+		method string
+		path   string
+		want   bool
+	}{
+		{viewer, http.MethodGet, "/api/apps/blog/status", true},
+		{viewer, http.MethodPost, "/api/apps/blog/start", false},
+		{viewer, http.MethodDelete, "/api/apps/blog", false},
+		{viewer, http.MethodPost, "/api/tokens", true},
+		{viewer, http.MethodPost, "/api/account/password", true},
+		{operator, http.MethodPost, "/api/apps/blog/start", true},
+		{&database.User{}, http.MethodPost, "/apps/blog/edit", true},
+	} {
+		if got := roleAllows(tt.user, httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+			t.Errorf("roleAllows(%q, %s %s) = %v", tt.user.Role, tt.method, tt.path, got)
+		}
+	}
+}
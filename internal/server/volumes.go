@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+	"github.com/ontree-co/treeos/internal/storage"
+)
+
+// appVolume returns the volume of an app named in the request path. It answers the
+// request itself and returns nil when the volume isn't one of the app's.
+func (s *Server) appVolume(w http.ResponseWriter, r *http.Request) (*dockerruntime.Client, *dockerruntime.Volume) {
+	appName, volumeName := r.PathValue("name"), r.PathValue("volume")
+	runtimeClient, err := s.getRuntimeClient()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Container runtime not available: %v", err), http.StatusServiceUnavailable)
+		return nil, nil
+	}
+	volumes, err := runtimeClient.AppVolumes(r.Context(), s.config.AppsDir, appName)
+	if err != nil {
+		logging.Errorf("Failed to list volumes of app %s: %v", appName, err)
+		http.Error(w, fmt.Sprintf("Failed to list volumes: %v", err), http.StatusInternalServerError)
+		return nil, nil
+	}
+	for i := range volumes {
+		if volumes[i].Name == volumeName {
+			return runtimeClient, &volumes[i]
+		}
+	}
+	http.Error(w, fmt.Sprintf("App '%s' has no volume '%s'", appName, volumeName), http.StatusNotFound)
+	return nil, nil
+}
+
+// handleAPIAppVolumes handles GET /api/apps/{name}/volumes, the Docker volumes of an app
+// with their size and the services mounting them
+func (s *Server) handleAPIAppVolumes(w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	runtimeClient, err := s.getRuntimeClient()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Container runtime not available: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	volumes, err := runtimeClient.AppVolumes(r.Context(), s.config.AppsDir, appName)
+	if err != nil {
+		logging.Errorf("Failed to list volumes of app %s: %v", appName, err)
+		http.Error(w, fmt.Sprintf("Failed to list volumes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"volumes": volumes}); err != nil {
+		logging.Errorf("Failed to encode volumes: %v", err)
+	}
+}
+
+// handleAPIAppVolumeFiles handles GET /api/apps/{name}/volumes/{volume}/files?path=/dir,
+// a read-only listing of one directory of the volume
+func (s *Server) handleAPIAppVolumeFiles(w http.ResponseWriter, r *http.Request) {
+	runtimeClient, vol := s.appVolume(w, r)
+	if vol == nil {
+		return
+	}
+	dir := r.URL.Query().Get("path")
+	entries, truncated, err := runtimeClient.ListVolumeFiles(r.Context(), vol.Name, dir)
+	if err != nil {
+		logging.Warnf("Failed to list %s in volume %s: %v", dir, vol.Name, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"volume":    vol.Name,
+		"path":      path.Clean("/" + dir),
+		"entries":   entries,
+		"truncated": truncated,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode volume files: %v", err)
+	}
+}
+
+// clearDeadlines lifts the read and write timeouts of the server for a request that streams
+// a body of any size, like a volume archive. A client that goes away still ends it.
+func clearDeadlines(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		logging.Debugf("Failed to clear read deadline of %s %s: %v", r.Method, r.URL.Path, err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		logging.Debugf("Failed to clear write deadline of %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+// tarAttachment sends the headers of a tar download with the first bytes of the archive,
+// so a failure before answers as plain text
+type tarAttachment struct {
+	w       http.ResponseWriter
+	name    string
+	started bool
+}
+
+func (a *tarAttachment) Write(p []byte) (int, error) {
+	if !a.started {
+		a.started = true
+		a.w.Header().Set("Content-Type", "application/x-tar")
+		a.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", a.name))
+	}
+	return a.w.Write(p)
+}
+
+// handleAPIAppVolumeExport handles GET /api/apps/{name}/volumes/{volume}/export, the
+// content of the volume as tar archive. A running app can change files while they are
+// read, stop it for a consistent copy.
+func (s *Server) handleAPIAppVolumeExport(w http.ResponseWriter, r *http.Request) {
+	runtimeClient, vol := s.appVolume(w, r)
+	if vol == nil {
+		return
+	}
+	clearDeadlines(w, r)
+	archive := &tarAttachment{w: w, name: vol.Name + ".tar"}
+	if err := runtimeClient.ExportVolume(r.Context(), vol.Name, archive); err != nil {
+		logging.Errorf("Failed to export volume %s: %v", vol.Name, err)
+		// Once the archive is streaming the status can't change anymore
+		if !archive.started {
+			http.Error(w, fmt.Sprintf("Failed to export volume: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	logging.Infof("Exported volume %s of app %s", vol.Name, r.PathValue("name"))
+}
+
+// handleAPIAppVolumeImport handles POST /api/apps/{name}/volumes/{volume}/import with a
+// tar archive as body. The files are extracted into the volume, overwriting those with the
+// same names. The app has to be stopped.
+func (s *Server) handleAPIAppVolumeImport(w http.ResponseWriter, r *http.Request) {
+	runtimeClient, vol := s.appVolume(w, r)
+	if vol == nil {
+		return
+	}
+	appName := r.PathValue("name")
+	if vol.InUse {
+		http.Error(w, fmt.Sprintf("Volume '%s' is used by a running container, stop app '%s' first", vol.Name, appName), http.StatusConflict)
+		return
+	}
+	clearDeadlines(w, r)
+	if err := runtimeClient.ImportVolume(r.Context(), vol.Name, r.Body); err != nil {
+		logging.Errorf("Failed to import into volume %s: %v", vol.Name, err)
+		http.Error(w, fmt.Sprintf("Failed to import archive: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	actor := ""
+	if user := getUserFromContext(r.Context()); user != nil {
+		actor = user.Username
+	}
+	logging.Infof("User %s imported an archive into volume %s of app %s", actor, vol.Name, appName)
+	recordActivity(database.ActivityEvent{
+		Category: database.ActivityAudit,
+		Title:    fmt.Sprintf("Archive imported into volume %s", vol.Name),
+		AppName:  appName,
+		Actor:    actor,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true}); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// handleAPIOrphanVolumes handles DELETE /api/orphans/volumes, which removes all orphaned
+// volumes, e.g. those left behind by deleted apps
+func (s *Server) handleAPIOrphanVolumes(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	runtimeClient, err := s.getRuntimeClient()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Container runtime not available: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	removed, err := s.pruneOrphanVolumes(r.Context(), runtimeClient, user.Username)
+	response := map[string]interface{}{"removed": removed, "reclaimed": reclaimedSpace(removed)}
+	if err != nil {
+		logging.Errorf("Failed to prune orphaned volumes: %v", err)
+		response["error"] = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+// pruneOrphanVolumes removes the orphaned volumes and records it in the activity feed
+func (s *Server) pruneOrphanVolumes(ctx context.Context, runtimeClient *dockerruntime.Client, actor string) ([]dockerruntime.Volume, error) {
+	removed, err := runtimeClient.PruneOrphanVolumes(ctx, s.config.AppsDir)
+	if len(removed) == 0 {
+		return removed, err
+	}
+	names := make([]string, 0, len(removed))
+	for _, vol := range removed {
+		names = append(names, vol.Name)
+	}
+	logging.Infof("User %s removed %d orphaned volume(s): %s", actor, len(removed), strings.Join(names, ", "))
+	recordActivity(database.ActivityEvent{
+		Category: database.ActivityAudit,
+		Title:    fmt.Sprintf("%d orphaned volume(s) removed, %s freed", len(removed), storage.FormatSize(uint64(reclaimedSpace(removed)))), //nolint:gosec // Sum of sizes is not negative
+		Detail:   strings.Join(names, ", "),
+		Actor:    actor,
+	})
+	return removed, err
+}
+
+// reclaimedSpace sums the known sizes of volumes
+func reclaimedSpace(volumes []dockerruntime.Volume) int64 {
+	var total int64
+	for _, vol := range volumes {
+		if vol.Size > 0 {
+			total += vol.Size
+		}
+	}
+	return total
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/config"
+	dockerruntime "github.com/ontree-co/treeos/internal/runtime"
+)
+
+func TestAppVolumes(t *testing.T) {
+	t.Setenv("TREEOS_MOCK_RUNTIME", "1")
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "wiki")
+	if err := os.MkdirAll(appDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &config.Config{AppsDir: appsDir}}
+	mux := s.appRoutes(s.appAPIRoutes())
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/apps/wiki/volumes", nil))
+	var response struct {
+		Volumes []dockerruntime.Volume `json:"volumes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); w.Code != http.StatusOK || err != nil || response.Volumes == nil {
+		t.Fatalf("GET volumes = %d %s", w.Code, w.Body.String())
+	}
+
+	// Only volumes of the app can be read or written
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/apps/wiki/volumes/other_data/files?path=/", nil),
+		httptest.NewRequest(http.MethodGet, "/api/apps/wiki/volumes/other_data/export", nil),
+		httptest.NewRequest(http.MethodPost, "/api/apps/wiki/volumes/other_data/import", nil),
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s = %d, want 404", req.Method, req.URL.Path, w.Code)
+		}
+	}
+}
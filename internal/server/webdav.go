@@ -0,0 +1,201 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	// webDAVPrefix is the URL prefix of the WebDAV endpoint
+	webDAVPrefix = "/dav"
+	// webDAVAuthCacheTTL is how long verified credentials skip the password hash check.
+	// File managers send credentials with every request.
+	webDAVAuthCacheTTL = 5 * time.Minute
+)
+
+// webDAVAuth is a verified login in the WebDAV auth cache. It holds as long as the session
+// version of the user is the same, a password change raises it.
+type webDAVAuth struct {
+	userID         int
+	sessionVersion int
+}
+
+// handleWebDAV serves the mount directories of the apps the user may access over WebDAV.
+// File managers don't keep session cookies, so requests use HTTP basic authentication.
+func (s *Server) handleWebDAV(w http.ResponseWriter, r *http.Request) {
+	if !s.config.WebDAVEnabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	user := s.webDAVUser(r)
+	if user == nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="TreeOS files", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// The password alone doesn't open the files of an admin with a client certificate
+	if !s.clientCertSatisfied(r, user) {
+		http.Error(w, "This account requires its client certificate", http.StatusForbidden)
+		return
+	}
+
+	access, err := s.fileAccessFor(user)
+	if err != nil {
+		logging.Errorf("Failed to get file access for %s: %v", user.Username, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Media files take longer to up- or download than the server's timeouts allow
+	clearDeadlines(w, r)
+	handler := &webdav.Handler{
+		Prefix:     webDAVPrefix,
+		FileSystem: &appMountFS{appsDir: s.config.AppsDir, access: access},
+		LockSystem: s.webDAVLocks,
+		Logger: func(r *http.Request, err error) {
+			if err != nil && !os.IsNotExist(err) {
+				logging.Debugf("WebDAV %s %s by %s: %v", r.Method, r.URL.Path, user.Username, err)
+			}
+		},
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// webDAVUser authenticates a request with HTTP basic authentication
+func (s *Server) webDAVUser(r *http.Request) *database.User {
+	username, password, ok := r.BasicAuth()
+	if !ok || username == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(username + "\x00" + password))
+	key := "webdav-auth:" + hex.EncodeToString(sum[:])
+	if cached, ok := s.webDAVAuthCache.Get(key); ok {
+		// Deactivated users aren't found, a changed password ends the login like a session
+		auth := cached.(webDAVAuth)
+		if user, err := s.getUserByID(auth.userID); err == nil && user.SessionVersion == auth.sessionVersion {
+			return user
+		}
+		s.webDAVAuthCache.Delete(key)
+	}
+
+	user, err := s.authenticateUser(username, password)
+	if err != nil {
+		logging.Warnf("WebDAV login failed for %q from %s", username, clientIP(r))
+		return nil
+	}
+	logging.Infof("WebDAV login by %s from %s", user.Username, clientIP(r))
+	s.webDAVAuthCache.SetWithTTL(key, webDAVAuth{userID: user.ID, sessionVersion: user.SessionVersion}, webDAVAuthCacheTTL)
+	return user
+}
+
+// fileAccessFor returns the apps a user may access over WebDAV. Admins can write to all apps.
+func (s *Server) fileAccessFor(user *database.User) (map[string]string, error) {
+	if !user.IsStaff {
+		return database.GetFileAccessForUser(user.ID)
+	}
+
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	access := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, entry.Name(), "mnt")); err == nil {
+			access[entry.Name()] = database.FileAccessWrite
+		}
+	}
+	return access, nil
+}
+
+// handleFileAccess handles /api/webdav/access:
+// GET lists grants, POST creates or changes a grant and DELETE (?id=) removes one
+func (s *Server) handleFileAccess(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			UserID  int    `json:"user_id"`
+			AppName string `json:"app_name"`
+			Access  string `json:"access"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.AppName = strings.TrimSpace(req.AppName)
+		if req.AppName == "" || strings.ContainsAny(req.AppName, `/\`) || strings.HasPrefix(req.AppName, ".") {
+			http.Error(w, "Invalid app name", http.StatusBadRequest)
+			return
+		}
+		if _, err := os.Stat(filepath.Join(s.config.AppsDir, req.AppName)); os.IsNotExist(err) {
+			http.Error(w, "App '"+req.AppName+"' not found", http.StatusNotFound)
+			return
+		}
+		if _, err := s.getUserByID(req.UserID); err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if req.Access != database.FileAccessRead && req.Access != database.FileAccessWrite {
+			http.Error(w, "Access must be read or write", http.StatusBadRequest)
+			return
+		}
+		if err := database.SetFileAccess(req.UserID, req.AppName, req.Access); err != nil {
+			logging.Errorf("Failed to set file access: %v", err)
+			http.Error(w, "Failed to set file access", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s granted %s file access for app %s to user #%d", user.Username, req.Access, req.AppName, req.UserID)
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid grant id", http.StatusBadRequest)
+			return
+		}
+		if err := database.DeleteFileAccess(id); err != nil {
+			logging.Errorf("Failed to delete file access: %v", err)
+			http.Error(w, "Failed to delete file access", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("User %s removed file access grant #%d", user.Username, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	grants, err := database.GetFileAccessGrants()
+	if err != nil {
+		logging.Errorf("Failed to get file access: %v", err)
+		http.Error(w, "Failed to get file access", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"grants": grants}); err != nil {
+		logging.Errorf("Failed to encode file access: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"golang.org/x/net/webdav"
+)
+
+// appMountFS exposes the mnt directories of the apps a user may access as one WebDAV tree.
+// "/<app>/..." maps to "<apps dir>/<app>/mnt/...", the root lists the accessible apps.
+type appMountFS struct {
+	appsDir string
+	access  map[string]string // App name -> database.FileAccessRead or database.FileAccessWrite
+}
+
+// split returns the app name and the slash separated path inside its mount directory
+func (m *appMountFS) split(name string) (string, string) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	appName, rest, _ := strings.Cut(name, "/")
+	return appName, rest
+}
+
+// resolve returns the host path for name after checking access. Paths that leave the
+// app's mount directory through symlinks are refused.
+func (m *appMountFS) resolve(name string, write bool) (string, error) {
+	appName, rest := m.split(name)
+	level, ok := m.access[appName]
+	if appName == "" || !ok {
+		return "", os.ErrNotExist
+	}
+	if write && level != database.FileAccessWrite {
+		return "", os.ErrPermission
+	}
+
+	root, err := filepath.EvalSymlinks(filepath.Join(m.appsDir, appName, "mnt"))
+	if err != nil {
+		return "", err
+	}
+	full := filepath.Join(root, filepath.FromSlash(rest))
+
+	check := full
+	if _, err := os.Lstat(full); errors.Is(err, os.ErrNotExist) {
+		check = filepath.Dir(full)
+	}
+	resolved, err := filepath.EvalSymlinks(check)
+	if err != nil {
+		return "", err
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return "", os.ErrPermission
+	}
+	return full, nil
+}
+
+func (m *appMountFS) Mkdir(_ context.Context, name string, perm os.FileMode) error {
+	if _, rest := m.split(name); rest == "" {
+		return os.ErrPermission
+	}
+	full, err := m.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return os.Mkdir(full, perm)
+}
+
+func (m *appMountFS) OpenFile(_ context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+	appName, rest := m.split(name)
+	if appName == "" {
+		if write {
+			return nil, os.ErrPermission
+		}
+		return &appListDir{fs: m}, nil
+	}
+
+	full, err := m.resolve(name, write)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(full, flag, perm) //nolint:gosec // Path checked by resolve
+	if err != nil {
+		return nil, err
+	}
+	if rest == "" {
+		// Show the app name instead of "mnt" for the app's top directory
+		return &renamedFile{File: f, name: appName}, nil
+	}
+	return f, nil
+}
+
+func (m *appMountFS) RemoveAll(_ context.Context, name string) error {
+	if _, rest := m.split(name); rest == "" {
+		return os.ErrPermission
+	}
+	full, err := m.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(full)
+}
+
+func (m *appMountFS) Rename(_ context.Context, oldName, newName string) error {
+	_, oldRest := m.split(oldName)
+	_, newRest := m.split(newName)
+	if oldRest == "" || newRest == "" {
+		return os.ErrPermission
+	}
+	oldPath, err := m.resolve(oldName, true)
+	if err != nil {
+		return err
+	}
+	newPath, err := m.resolve(newName, true)
+	if err != nil {
+		return err
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+func (m *appMountFS) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	appName, rest := m.split(name)
+	if appName == "" {
+		return rootInfo{}, nil
+	}
+	full, err := m.resolve(name, false)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	if rest == "" {
+		return renamedInfo{FileInfo: info, name: appName}, nil
+	}
+	return info, nil
+}
+
+// appListDir is the read-only root directory listing the accessible apps
+type appListDir struct {
+	fs   *appMountFS
+	read bool
+}
+
+func (d *appListDir) Close() error                   { return nil }
+func (d *appListDir) Read([]byte) (int, error)       { return 0, os.ErrInvalid }
+func (d *appListDir) Seek(int64, int) (int64, error) { return 0, nil }
+func (d *appListDir) Write([]byte) (int, error)      { return 0, os.ErrPermission }
+func (d *appListDir) Stat() (os.FileInfo, error)     { return rootInfo{}, nil }
+func (d *appListDir) Readdir(count int) ([]fs.FileInfo, error) {
+	if d.read && count > 0 {
+		return nil, io.EOF
+	}
+	d.read = true
+
+	names := make([]string, 0, len(d.fs.access))
+	for appName := range d.fs.access {
+		names = append(names, appName)
+	}
+	sort.Strings(names)
+
+	infos := make([]fs.FileInfo, 0, len(names))
+	for _, appName := range names {
+		info, err := d.fs.Stat(context.Background(), "/"+appName)
+		if err != nil {
+			// Apps without a mount directory have nothing to share
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// rootInfo describes the virtual root directory
+type rootInfo struct{}
+
+func (rootInfo) Name() string       { return "/" }
+func (rootInfo) Size() int64        { return 0 }
+func (rootInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (rootInfo) ModTime() time.Time { return time.Time{} }
+func (rootInfo) IsDir() bool        { return true }
+func (rootInfo) Sys() interface{}   { return nil }
+
+// renamedInfo reports a different name for a file
+type renamedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i renamedInfo) Name() string { return i.name }
+
+// renamedFile reports a different name for an open directory
+type renamedFile struct {
+	*os.File
+	name string
+}
+
+func (f *renamedFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: f.name}, nil
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/database"
+)
+
+func TestAppMountFS(t *testing.T) {
+	appsDir := t.TempDir()
+	outside := t.TempDir()
+	for _, app := range []string{"photos", "notes", "private"} {
+		if err := os.MkdirAll(filepath.Join(appsDir, app, "mnt"), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(appsDir, "photos", "mnt", "a.jpg"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(appsDir, "photos", "mnt", "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &appMountFS{
+		appsDir: appsDir,
+		access: map[string]string{
+			"photos": database.FileAccessWrite,
+			"notes":  database.FileAccessRead,
+		},
+	}
+	ctx := context.Background()
+
+	// The root lists only accessible apps
+	root, err := fs.OpenFile(ctx, "/", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(/) error = %v", err)
+	}
+	infos, err := root.Readdir(0)
+	if err != nil {
+		t.Fatalf("Readdir() error = %v", err)
+	}
+	if len(infos) != 2 || infos[0].Name() != "notes" || infos[1].Name() != "photos" {
+		t.Errorf("unexpected root listing: %v", infos)
+	}
+
+	if _, err := fs.Stat(ctx, "/photos/a.jpg"); err != nil {
+		t.Errorf("Stat() error = %v", err)
+	}
+	if _, err := fs.Stat(ctx, "/private"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected app without grant to be hidden, got %v", err)
+	}
+
+	// Read-only access refuses writes
+	if err := fs.Mkdir(ctx, "/notes/new", 0750); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("expected permission error on read-only app, got %v", err)
+	}
+	if err := fs.Mkdir(ctx, "/photos/new", 0750); err != nil {
+		t.Errorf("Mkdir() error = %v", err)
+	}
+
+	// App directories themselves can't be removed or replaced
+	if err := fs.RemoveAll(ctx, "/photos"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("expected permission error removing app root, got %v", err)
+	}
+
+	// Symlinks must not lead out of the mount directory
+	if _, err := fs.OpenFile(ctx, "/photos/escape/secret", os.O_CREATE|os.O_WRONLY, 0600); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("expected permission error through symlink, got %v", err)
+	}
+	if _, err := fs.Stat(ctx, "/photos/../private"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected path traversal to stay inside the tree, got %v", err)
+	}
+}
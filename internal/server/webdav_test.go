@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/cache"
+	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/database"
+	"golang.org/x/net/webdav"
+)
+
+func TestWebDAVAuthCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := database.Initialize(filepath.Join(tmpDir, "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close() //nolint:errcheck // Test cleanup
+
+	hash, err := hashPassword("old password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userID int
+	if err := database.GetDB().QueryRow(`INSERT INTO users (username, password, is_active) VALUES ('alice', ?, 1) RETURNING id`, hash).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		config:          &config.Config{AppsDir: filepath.Join(tmpDir, "apps"), WebDAVEnabled: true},
+		webDAVAuthCache: cache.New(webDAVAuthCacheTTL),
+		webDAVLocks:     webdav.NewMemLS(),
+	}
+	propfind := func(password string) int {
+		req := httptest.NewRequest("PROPFIND", "/dav/", nil)
+		req.Header.Set("Depth", "0")
+		req.SetBasicAuth("alice", password)
+		w := httptest.NewRecorder()
+		s.handleWebDAV(w, req)
+		return w.Code
+	}
+
+	if code := propfind("old password"); code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND = %d, want 207", code)
+	}
+	// The cached login ends with a password change
+	if _, err := setUserPassword(userID, "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if code := propfind("old password"); code != http.StatusUnauthorized {
+		t.Errorf("PROPFIND with the old password = %d, want 401", code)
+	}
+	if code := propfind("new password"); code != http.StatusMultiStatus {
+		t.Errorf("PROPFIND with the new password = %d, want 207", code)
+	}
+	// and when the user is deactivated
+	if _, err := database.GetDB().Exec(`UPDATE users SET is_active = 0 WHERE id = ?`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if code := propfind("new password"); code != http.StatusUnauthorized {
+		t.Errorf("PROPFIND of a deactivated user = %d, want 401", code)
+	}
+}
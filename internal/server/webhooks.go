@@ -0,0 +1,350 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/internal/notify"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+const (
+	// containerMonitorInterval is how often the containers of apps are checked for crashes
+	// and failing health checks
+	containerMonitorInterval = time.Minute
+	// diskFullPercent is the disk usage that fires disk.full
+	diskFullPercent = 90
+	// diskFullClearPercent is the usage the disk has to drop below before disk.full fires again
+	diskFullClearPercent = 85
+)
+
+// WebhookRequest is the body of POST /api/webhooks and PUT /api/webhooks/{id}
+type WebhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Kind    string   `json:"kind"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled,omitempty"` // Defaults to true
+}
+
+// notifyWebhooks sends an event to the enabled webhooks subscribed to it. Deliveries
+// run in the background, their outcome is shown in the settings.
+func (s *Server) notifyWebhooks(event notify.Event) {
+	hooks, err := database.GetWebhooksForEvent(event.Type)
+	if err != nil {
+		logging.Errorf("Failed to get webhooks for %s: %v", event.Type, err)
+		return
+	}
+	for _, hook := range hooks {
+		go deliverWebhook(hook, event) //nolint:errcheck // Failures are recorded on the webhook
+	}
+}
+
+// deliverWebhook sends an event to a webhook and records the outcome on it
+func deliverWebhook(hook database.Webhook, event notify.Event) error {
+	err := notify.SendWebhook(context.Background(), hook.Kind, hook.URL, event)
+	deliveryErr := ""
+	if err != nil {
+		logging.Warnf("Failed to deliver %s to webhook %s: %v", event.Type, hook.Name, err)
+		deliveryErr = err.Error()
+	}
+	if recordErr := database.RecordWebhookDelivery(hook.ID, deliveryErr); recordErr != nil {
+		logging.Errorf("Failed to record delivery to webhook %s: %v", hook.Name, recordErr)
+	}
+	return err
+}
+
+// notifyAppStartFailed fires app.start_failed
+func (s *Server) notifyAppStartFailed(appName string, err error) {
+	s.notifyEvent(notify.Event{
+		Type:    notify.EventAppStartFailed,
+		Title:   fmt.Sprintf("App %s failed to start", appName),
+		Message: err.Error(),
+		App:     appName,
+	})
+}
+
+// checkDiskFull fires disk.full when the disk usage crosses diskFullPercent. It fires
+// again only after the usage dropped below diskFullClearPercent, not at every sample.
+func (s *Server) checkDiskFull(percent float64) {
+	switch {
+	case percent >= diskFullPercent && !s.diskFull:
+		s.diskFull = true
+		s.notifyEvent(notify.Event{
+			Type:    notify.EventDiskFull,
+			Title:   fmt.Sprintf("Disk is %.0f%% full", percent),
+			Message: "Free up space, e.g. by removing unused images, models or backups, before apps fail to write.",
+		})
+	case percent < diskFullClearPercent && s.diskFull:
+		s.diskFull = false
+	}
+}
+
+// containerState is the state of a container at the last check of the container monitor
+type containerState struct {
+	state  string
+	health string
+}
+
+// startContainerMonitor watches the containers of all apps for ones that stop on their
+// own or fail their health check. It only polls while a notification subscribes to them.
+func (s *Server) startContainerMonitor() {
+	ticker := time.NewTicker(containerMonitorInterval)
+	defer ticker.Stop()
+
+	states := make(map[string]containerState)
+	for {
+		select {
+		case <-ticker.C:
+			if !s.eventSubscribed(notify.EventContainerCrashed, notify.EventContainerUnhealthy) {
+				states = make(map[string]containerState)
+				continue
+			}
+			states = s.checkContainers(states)
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// checkContainers compares the containers of all apps with the states of the previous
+// check, fires container.crashed and container.unhealthy and returns the new states
+func (s *Server) checkContainers(previous map[string]containerState) map[string]containerState {
+	composeSvc, err := s.getComposeService()
+	if err != nil {
+		return previous
+	}
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil {
+		return previous
+	}
+
+	states := make(map[string]containerState)
+	failed := false
+	for _, entry := range entries {
+		appDir := filepath.Join(s.config.AppsDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(appDir, "docker-compose.yml")); !entry.IsDir() || err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		containers, err := composeSvc.PS(ctx, compose.Options{WorkingDir: appDir})
+		cancel()
+		if err != nil {
+			failed = true
+			continue
+		}
+		crashed, unhealthy := containerProblems(previous, containers)
+		for _, c := range crashed {
+			logging.Warnf("Container %s of app %s crashed: %s", c.Name, entry.Name(), c.Status)
+			s.notifyEvent(notify.Event{
+				Type:    notify.EventContainerCrashed,
+				Title:   fmt.Sprintf("Container %s of app %s crashed", c.Name, entry.Name()),
+				Message: c.Status,
+				App:     entry.Name(),
+			})
+		}
+		for _, c := range unhealthy {
+			logging.Warnf("Container %s of app %s is unhealthy: %s", c.Name, entry.Name(), c.Status)
+			s.notifyEvent(notify.Event{
+				Type:    notify.EventContainerUnhealthy,
+				Title:   fmt.Sprintf("Container %s of app %s is unhealthy", c.Name, entry.Name()),
+				Message: c.Status,
+				App:     entry.Name(),
+			})
+		}
+		for _, c := range containers {
+			states[c.ID] = containerState{state: strings.ToLower(c.State), health: containerHealth(c.Health)}
+		}
+	}
+	// Apps that couldn't be checked keep their states for the next check
+	if failed {
+		for id, state := range previous {
+			if _, ok := states[id]; !ok {
+				states[id] = state
+			}
+		}
+	}
+	return states
+}
+
+// containerProblems returns the containers that ran at the previous check and have since
+// exited, died or are restarting, and the running ones whose health check started to
+// fail. Containers that were removed in between were stopped through TreeOS or
+// recreated, they are not crashes.
+func containerProblems(previous map[string]containerState, containers []compose.ContainerSummary) (crashed, unhealthy []compose.ContainerSummary) {
+	for _, c := range containers {
+		prev, seen := previous[c.ID]
+		if !seen {
+			continue
+		}
+		switch strings.ToLower(c.State) {
+		case "exited", "dead", "restarting":
+			if prev.state == "running" {
+				crashed = append(crashed, c)
+			}
+		case "running":
+			if containerHealth(c.Health) == "unhealthy" && prev.health != "unhealthy" {
+				unhealthy = append(unhealthy, c)
+			}
+		}
+	}
+	return crashed, unhealthy
+}
+
+// handleAPIWebhooks handles /api/webhooks: GET lists the webhooks, POST adds one,
+// PUT and DELETE /api/webhooks/{id} change or remove one and POST /api/webhooks/{id}/test
+// sends a test event. Webhooks are for staff.
+func (s *Server) handleAPIWebhooks(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || !user.IsStaff {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks"), "/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			hooks, err := database.GetWebhooks()
+			if err != nil {
+				logging.Errorf("Failed to load webhooks: %v", err)
+				http.Error(w, "Failed to load webhooks", http.StatusInternalServerError)
+				return
+			}
+			writeWebhookJSON(w, http.StatusOK, map[string]interface{}{"webhooks": hooks})
+		case http.MethodPost:
+			hook, ok := decodeWebhookRequest(w, r)
+			if !ok {
+				return
+			}
+			if err := database.CreateWebhook(hook); err != nil {
+				logging.Errorf("Failed to create webhook %s: %v", hook.Name, err)
+				http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+				return
+			}
+			logging.Infof("Webhook %s added by %s for %v", hook.Name, user.Username, hook.Events)
+			writeWebhookJSON(w, http.StatusCreated, hook)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	idText, action, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(idText)
+	if err != nil || (action != "" && action != "test") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	hook, err := database.GetWebhook(id)
+	if err != nil {
+		logging.Errorf("Failed to load webhook %d: %v", id, err)
+		http.Error(w, "Failed to load webhook", http.StatusInternalServerError)
+		return
+	}
+	if hook == nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "test" && r.Method == http.MethodPost:
+		event := notify.Event{
+			Type:    notify.EventTest,
+			Title:   "TreeOS test notification",
+			Message: fmt.Sprintf("Webhook %s works, sent by %s.", hook.Name, user.Username),
+			Time:    time.Now(),
+		}
+		event.Node, _ = os.Hostname() //nolint:errcheck // The event is useful without it
+		if err := deliverWebhook(*hook, event); err != nil {
+			http.Error(w, fmt.Sprintf("Test failed: %v", err), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case action == "" && r.Method == http.MethodPut:
+		changed, ok := decodeWebhookRequest(w, r)
+		if !ok {
+			return
+		}
+		changed.ID, changed.CreatedAt = hook.ID, hook.CreatedAt
+		if _, err := database.UpdateWebhook(changed); err != nil {
+			logging.Errorf("Failed to update webhook %d: %v", id, err)
+			http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("Webhook %s changed by %s", changed.Name, user.Username)
+		writeWebhookJSON(w, http.StatusOK, changed)
+	case action == "" && r.Method == http.MethodDelete:
+		if _, err := database.DeleteWebhook(id); err != nil {
+			logging.Errorf("Failed to delete webhook %d: %v", id, err)
+			http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("Webhook %s deleted by %s", hook.Name, user.Username)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeWebhookRequest reads and validates a webhook from the request body, writing the
+// error response if it is invalid
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request) (*database.Webhook, bool) {
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return nil, false
+	}
+	hook := &database.Webhook{
+		Name:    strings.TrimSpace(req.Name),
+		URL:     strings.TrimSpace(req.URL),
+		Kind:    req.Kind,
+		Events:  []string{},
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if hook.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return nil, false
+	}
+	if err := notify.ValidateWebhookURL(hook.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if hook.Kind == "" {
+		hook.Kind = notify.WebhookJSON
+	}
+	validKind := false
+	for _, kind := range notify.WebhookKinds {
+		validKind = validKind || kind == hook.Kind
+	}
+	if !validKind {
+		http.Error(w, fmt.Sprintf("Unknown kind %q, use one of %s", hook.Kind, strings.Join(notify.WebhookKinds, ", ")), http.StatusBadRequest)
+		return nil, false
+	}
+	for _, event := range req.Events {
+		if !notify.IsEvent(event) {
+			http.Error(w, fmt.Sprintf("Unknown event %q", event), http.StatusBadRequest)
+			return nil, false
+		}
+		hook.Events = append(hook.Events, event)
+	}
+	return hook, true
+}
+
+// writeWebhookJSON writes a JSON response with a status
+func writeWebhookJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/notify"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+func TestHandleAPIWebhooks(t *testing.T) {
+	if err := database.Initialize(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	var received []notify.Event
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			received = append(received, event)
+		}
+	}))
+	defer target.Close()
+
+	s := &Server{}
+	staff := &database.User{Username: "admin", IsStaff: true}
+	request := func(user *database.User, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		s.handleAPIWebhooks(rec, req)
+		return rec
+	}
+
+	if rec := request(&database.User{Username: "bob"}, http.MethodGet, "/api/webhooks", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("non-staff: status = %d", rec.Code)
+	}
+	for _, body := range []string{
+		`{"name": "Chat", "url": "ftp://example.com", "kind": "slack"}`,
+		`{"name": "Chat", "url": "https://example.com", "kind": "pager"}`,
+		`{"name": "Chat", "url": "https://example.com", "events": ["app.deleted"]}`,
+		`{"url": "https://example.com"}`,
+	} {
+		if rec := request(staff, http.MethodPost, "/api/webhooks", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", body, rec.Code)
+		}
+	}
+
+	rec := request(staff, http.MethodPost, "/api/webhooks", `{"name": "Scripts", "url": "`+target.URL+`", "events": ["disk.full"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+	}
+	var hook database.Webhook
+	if err := json.Unmarshal(rec.Body.Bytes(), &hook); err != nil {
+		t.Fatal(err)
+	}
+	if hook.Kind != notify.WebhookJSON || !hook.Enabled {
+		t.Errorf("webhook = %+v, want an enabled json webhook", hook)
+	}
+
+	path := "/api/webhooks/" + strconv.Itoa(hook.ID)
+	if rec := request(staff, http.MethodPost, path+"/test", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("test status = %d: %s", rec.Code, rec.Body)
+	}
+	if len(received) != 1 || received[0].Type != notify.EventTest {
+		t.Errorf("received = %+v", received)
+	}
+	if got, _ := database.GetWebhook(hook.ID); got == nil || got.LastSentAt == nil || got.LastError != "" {
+		t.Errorf("delivery not recorded: %+v", got)
+	}
+
+	rec = request(staff, http.MethodPut, path, `{"name": "Scripts", "url": "`+target.URL+`", "events": ["disk.full", "backup.failed"], "enabled": false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body)
+	}
+	if hooks, _ := database.GetWebhooksForEvent(notify.EventDiskFull); len(hooks) != 0 {
+		t.Errorf("disabled webhook is subscribed: %+v", hooks)
+	}
+
+	if rec := request(staff, http.MethodDelete, path, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d", rec.Code)
+	}
+	if rec := request(staff, http.MethodDelete, path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d", rec.Code)
+	}
+}
+
+func TestContainerProblems(t *testing.T) {
+	running, healthy := containerState{state: "running"}, containerState{state: "running", health: "healthy"}
+	previous := map[string]containerState{
+		"a": running, "b": running, "c": {state: "exited"}, "d": running,
+		"f": healthy, "g": {state: "running", health: "unhealthy"},
+	}
+	containers := []compose.ContainerSummary{
+		{ID: "a", Name: "blog-web-1", State: "exited"},
+		{ID: "b", Name: "blog-db-1", State: "running"},
+		{ID: "c", Name: "blog-worker-1", State: "exited"},
+		{ID: "d", Name: "blog-cache-1", State: "restarting"},
+		{ID: "e", Name: "blog-web-1", State: "exited"}, // Recreated, never seen running
+		{ID: "f", Name: "shop-web-1", State: "running", Health: "unhealthy"},
+		{ID: "g", Name: "shop-db-1", State: "running", Health: "unhealthy"}, // Already reported
+		{ID: "h", Name: "shop-cache-1", State: "running", Health: "unhealthy"},
+	}
+	names := func(containers []compose.ContainerSummary) string {
+		var names []string
+		for _, c := range containers {
+			names = append(names, c.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	crashed, unhealthy := containerProblems(previous, containers)
+	if got := names(crashed); got != "blog-web-1,blog-cache-1" {
+		t.Errorf("crashed = %s", got)
+	}
+	if got := names(unhealthy); got != "shop-web-1" {
+		t.Errorf("unhealthy = %s", got)
+	}
+}
+
+func TestCheckDiskFull(t *testing.T) {
+	s := &Server{}
+	for _, step := range []struct {
+		percent float64
+		full    bool
+	}{{80, false}, {91, true}, {88, true}, {84, false}, {95, true}} {
+		s.checkDiskFull(step.percent)
+		if s.diskFull != step.full {
+			t.Errorf("at %.0f%%: diskFull = %v, want %v", step.percent, s.diskFull, step.full)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/database"
+	"github.com/ontree-co/treeos/internal/logging"
+	"github.com/ontree-co/treeos/pkg/compose"
+)
+
+// widgetCacheTTL limits how often widget requests query the container runtime. Dashboards
+// poll every few seconds, and listing the containers of every app is not free on a small board.
+const widgetCacheTTL = 30 * time.Second
+
+// WidgetResponse is the read-only summary served to external dashboards at /api/widget
+type WidgetResponse struct {
+	Node      string             `json:"node"`
+	Timestamp time.Time          `json:"timestamp"`
+	Running   int                `json:"running"` // Apps with all services running
+	Total     int                `json:"total"`
+	Apps      []WidgetApp        `json:"apps"`
+	Metrics   map[string]float64 `json:"metrics"` // Percentages, upload and download in bytes per second
+}
+
+// WidgetApp is the status of one app in the widget response
+type WidgetApp struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // running, partial, stopped or unknown
+	Running  int    `json:"running"`
+	Services int    `json:"services"`
+}
+
+// handleWidget handles GET /api/widget. It needs the widget token as bearer token or as
+// "token" query parameter, since some dashboards can't set headers, and answers any origin
+// so browser-side widgets can fetch it.
+func (s *Server) handleWidget(w http.ResponseWriter, r *http.Request) {
+	if s.config.WidgetToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !hasBearerToken(r, s.config.WidgetToken) && !tokenMatches(r.URL.Query().Get("token"), s.config.WidgetToken) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var response *WidgetResponse
+	if cached, found := s.sparklineCache.Get("widget"); found {
+		response, _ = cached.(*WidgetResponse)
+	}
+	if response == nil {
+		response = s.buildWidgetResponse()
+		s.sparklineCache.SetWithTTL("widget", response, widgetCacheTTL)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Errorf("Failed to encode widget response: %v", err)
+	}
+}
+
+func (s *Server) buildWidgetResponse() *WidgetResponse {
+	response := &WidgetResponse{
+		Node:      "TreeOS",
+		Timestamp: time.Now().UTC(),
+		Apps:      []WidgetApp{},
+		Metrics:   map[string]float64{},
+	}
+	if db := database.GetDB(); db != nil {
+		var nodeName string
+		if err := db.QueryRow("SELECT node_name FROM system_setup WHERE id = 1").Scan(&nodeName); err == nil && nodeName != "" {
+			response.Node = nodeName
+		}
+	}
+
+	entries, err := os.ReadDir(s.config.AppsDir)
+	if err != nil && !os.IsNotExist(err) {
+		logging.Errorf("Failed to list apps for widget: %v", err)
+	}
+	composeSvc, composeErr := s.getComposeService()
+	for _, entry := range entries {
+		if !entry.IsDir() || (len(s.config.WidgetApps) > 0 && !slices.Contains(s.config.WidgetApps, entry.Name())) {
+			continue
+		}
+		app := WidgetApp{Name: entry.Name(), Status: "unknown"}
+		if composeErr == nil {
+			containers, err := composeSvc.PS(context.Background(), compose.Options{WorkingDir: filepath.Join(s.config.AppsDir, entry.Name())})
+			if err == nil {
+				services := make([]ServiceStatusDetail, 0, len(containers))
+				for _, container := range containers {
+					services = append(services, ServiceStatusDetail{Status: mapContainerState(container.State)})
+				}
+				app.Status = calculateAggregateStatus(services)
+				app.Services = len(services)
+				for _, svc := range services {
+					if svc.Status == "running" {
+						app.Running++
+					}
+				}
+			}
+		}
+		if app.Status == "running" {
+			response.Running++
+		}
+		response.Apps = append(response.Apps, app)
+	}
+	response.Total = len(response.Apps)
+
+	latest, err := database.GetLatestMetric("")
+	if err != nil {
+		logging.Errorf("Failed to get latest metric for widget: %v", err)
+	}
+	if latest != nil {
+		values := map[string]float64{
+			"cpu":      latest.CPUPercent,
+			"memory":   latest.MemoryPercent,
+			"disk":     latest.DiskUsagePercent,
+			"gpu":      latest.GPULoad,
+			"upload":   float64(latest.UploadRate),
+			"download": float64(latest.DownloadRate),
+		}
+		for _, metric := range s.config.WidgetMetrics {
+			response.Metrics[metric] = values[metric]
+		}
+	}
+	return response
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/cache"
+	"github.com/ontree-co/treeos/internal/config"
+)
+
+func TestHandleWidget(t *testing.T) {
+	appsDir := t.TempDir()
+	for _, app := range []string{"jellyfin", "nextcloud", "private"} {
+		if err := os.Mkdir(filepath.Join(appsDir, app), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{
+		config: &config.Config{
+			AppsDir:       appsDir,
+			WidgetToken:   "widget-secret",
+			WidgetApps:    []string{"jellyfin", "nextcloud"},
+			WidgetMetrics: []string{"cpu"},
+		},
+		sparklineCache: cache.New(widgetCacheTTL),
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   int
+	}{
+		{"without token", "/api/widget", "", http.StatusUnauthorized},
+		{"wrong token", "/api/widget?token=wrong", "", http.StatusUnauthorized},
+		{"bearer token", "/api/widget", "Bearer widget-secret", http.StatusOK},
+		{"query token", "/api/widget?token=widget-secret", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.handleWidget(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+				t.Error("missing CORS header")
+			}
+
+			var response WidgetResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Total != 2 || len(response.Apps) != 2 {
+				t.Errorf("apps = %+v, want only the selected apps", response.Apps)
+			}
+			for _, app := range response.Apps {
+				if app.Name == "private" {
+					t.Error("app outside widget_apps included")
+				}
+			}
+		})
+	}
+}
+
+func TestHandleWidgetDisabled(t *testing.T) {
+	s := &Server{config: &config.Config{}, sparklineCache: cache.New(widgetCacheTTL)}
+	rec := httptest.NewRecorder()
+	s.handleWidget(rec, httptest.NewRequest(http.MethodGet, "/api/widget?token=", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 without a widget token", rec.Code)
+	}
+}
@@ -0,0 +1,296 @@
+// Package shares manages Samba shares for app data directories.
+//
+// TreeOS never rewrites the admin's smb.conf. Its shares live in a separate config
+// fragment that smb.conf includes, so an existing Samba setup keeps working.
+package shares
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/hostupdates"
+)
+
+const commandTimeout = 30 * time.Second
+
+// Config file locations, replaceable in tests
+var (
+	smbConfPath  = "/etc/samba/smb.conf"
+	fragmentPath = "/etc/samba/treeos-shares.conf"
+)
+
+var (
+	shareNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,79}$`)
+	userNameRegex  = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+	// Section names with a special meaning in smb.conf
+	reservedShareNames = map[string]bool{"global": true, "homes": true, "printers": true, "print$": true, "ipc$": true}
+)
+
+// ErrUnsupported is returned on hosts where Samba can't be managed
+var ErrUnsupported = errors.New("managing Samba shares is only supported on Linux")
+
+// Share is a Samba share of a directory
+type Share struct {
+	Name       string
+	Path       string
+	Comment    string
+	ReadOnly   bool
+	ValidUsers []string // Empty allows all Samba users
+}
+
+// Status describes the Samba installation and its active connections
+type Status struct {
+	Supported   bool           `json:"supported"`
+	Installed   bool           `json:"installed"`
+	Running     bool           `json:"running"`
+	Version     string         `json:"version,omitempty"`
+	Connections map[string]int `json:"connections"` // Active connections per share
+	Users       []string       `json:"users"`
+}
+
+// ValidateShareName checks that name can be used as a share section in smb.conf
+func ValidateShareName(name string) error {
+	if !shareNameRegex.MatchString(name) || reservedShareNames[strings.ToLower(name)] {
+		return fmt.Errorf("invalid share name %q: use letters, numbers, '-' and '_'", name)
+	}
+	return nil
+}
+
+// ValidateUserName checks that name can be used as a Samba and system user name
+func ValidateUserName(name string) error {
+	if !userNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid user name %q: use lowercase letters, numbers, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Render returns the config fragment for the given shares
+func Render(shares []Share) string {
+	var b strings.Builder
+	b.WriteString("# Managed by TreeOS, changes to this file are overwritten.\n")
+	for _, share := range shares {
+		fmt.Fprintf(&b, "\n[%s]\n", share.Name)
+		fmt.Fprintf(&b, "   path = %s\n", share.Path)
+		if share.Comment != "" {
+			fmt.Fprintf(&b, "   comment = %s\n", share.Comment)
+		}
+		b.WriteString("   browseable = yes\n")
+		if share.ReadOnly {
+			b.WriteString("   read only = yes\n")
+		} else {
+			b.WriteString("   read only = no\n")
+		}
+		if len(share.ValidUsers) > 0 {
+			fmt.Fprintf(&b, "   valid users = %s\n", strings.Join(share.ValidUsers, " "))
+		}
+		b.WriteString("   create mask = 0664\n")
+		b.WriteString("   directory mask = 0775\n")
+	}
+	return b.String()
+}
+
+// Apply writes the shares fragment, makes sure smb.conf includes it and reloads Samba
+func Apply(ctx context.Context, shares []Share) error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupported
+	}
+	for _, share := range shares {
+		if err := ValidateShareName(share.Name); err != nil {
+			return err
+		}
+		if strings.ContainsAny(share.Path+share.Comment, "\n\r") {
+			return fmt.Errorf("share %s contains a line break", share.Name)
+		}
+	}
+
+	if err := os.WriteFile(fragmentPath, []byte(Render(shares)), 0644); err != nil { //nolint:gosec // smb.conf fragments are world-readable like smb.conf
+		return fmt.Errorf("failed to write %s: %w", fragmentPath, err)
+	}
+	if err := ensureInclude(); err != nil {
+		return err
+	}
+	return reload(ctx)
+}
+
+// ensureInclude appends an include of the fragment to smb.conf if it is missing
+func ensureInclude() error {
+	data, err := os.ReadFile(smbConfPath) //nolint:gosec // Fixed Samba config path
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", smbConfPath, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "include") && strings.TrimSpace(value) == fragmentPath {
+			return nil
+		}
+	}
+
+	content := string(data)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += "\n# Shares managed by TreeOS\ninclude = " + fragmentPath + "\n"
+	if err := os.WriteFile(smbConfPath, []byte(content), 0644); err != nil { //nolint:gosec // smb.conf is world-readable
+		return fmt.Errorf("failed to update %s: %w", smbConfPath, err)
+	}
+	return nil
+}
+
+// reload tells a running smbd to reread its config
+func reload(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	if _, err := exec.LookPath("smbcontrol"); err == nil {
+		if err := run(ctx, nil, "smbcontrol", "smbd", "reload-config"); err == nil {
+			return nil
+		}
+	}
+	// Debian/Ubuntu call the service smbd, Fedora/RHEL smb
+	for _, service := range []string{"smbd", "smb"} {
+		if err := run(ctx, nil, "systemctl", "reload-or-restart", service); err == nil {
+			return nil
+		}
+	}
+	return errors.New("failed to reload Samba, is smbd running?")
+}
+
+// GetStatus reports whether Samba is installed and running, its users and connections
+func GetStatus(ctx context.Context) Status {
+	status := Status{Supported: runtime.GOOS == "linux", Connections: map[string]int{}, Users: []string{}}
+	if !status.Supported {
+		return status
+	}
+	if _, err := exec.LookPath("smbd"); err != nil {
+		return status
+	}
+	status.Installed = true
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	if output, err := exec.CommandContext(ctx, "smbd", "--version").Output(); err == nil {
+		status.Version = strings.TrimPrefix(strings.TrimSpace(string(output)), "Version ")
+	}
+	for _, service := range []string{"smbd", "smb"} {
+		if exec.CommandContext(ctx, "systemctl", "is-active", "--quiet", service).Run() == nil {
+			status.Running = true
+			break
+		}
+	}
+	if output, err := exec.CommandContext(ctx, "smbstatus", "--shares").Output(); err == nil {
+		status.Connections = parseSmbstatusShares(string(output))
+	}
+	if output, err := exec.CommandContext(ctx, "pdbedit", "-L").Output(); err == nil {
+		status.Users = parsePdbeditUsers(string(output))
+	}
+	return status
+}
+
+// Install installs Samba with the host's package manager
+func Install(ctx context.Context) (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", ErrUnsupported
+	}
+	output, err := hostupdates.InstallPackages(ctx, "samba")
+	if err != nil {
+		return output, err
+	}
+	// Fedora/RHEL don't start the service after installing it
+	if hostupdates.DetectManager() == hostupdates.ManagerDnf {
+		if err := run(ctx, nil, "systemctl", "enable", "--now", "smb"); err != nil {
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+// SetUserPassword creates or updates a Samba user. Samba users need a system account,
+// which is created without a home directory or login shell if it doesn't exist.
+func SetUserPassword(ctx context.Context, name, password string) error {
+	if err := ValidateUserName(name); err != nil {
+		return err
+	}
+	if password == "" || strings.ContainsAny(password, "\n\r") {
+		return errors.New("password must not be empty or contain line breaks")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	if _, err := user.Lookup(name); err != nil {
+		if err := run(ctx, nil, "useradd", "--system", "--no-create-home", "--shell", "/usr/sbin/nologin", name); err != nil {
+			return err
+		}
+	}
+	return run(ctx, strings.NewReader(password+"\n"+password+"\n"), "smbpasswd", "-a", "-s", name)
+}
+
+// DeleteUser removes a Samba user. The system account is kept, it may own files.
+func DeleteUser(ctx context.Context, name string) error {
+	if err := ValidateUserName(name); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	return run(ctx, nil, "smbpasswd", "-x", name)
+}
+
+// parseSmbstatusShares counts connections per share from `smbstatus --shares`
+func parseSmbstatusShares(output string) map[string]int {
+	connections := map[string]int{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	inTable := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "---") {
+			inTable = true
+			continue
+		}
+		if !inTable || line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if strings.EqualFold(fields[0], "IPC$") {
+			continue
+		}
+		connections[fields[0]]++
+	}
+	return connections
+}
+
+// parsePdbeditUsers returns user names from `pdbedit -L` (name:uid:full name)
+func parsePdbeditUsers(output string) []string {
+	users := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		name, _, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && name != "" {
+			users = append(users, name)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+func run(ctx context.Context, stdin *strings.Reader, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...) //nolint:gosec // Fixed Samba tools, arguments validated by callers
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
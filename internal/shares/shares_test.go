@@ -0,0 +1,91 @@
+package shares
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	got := Render([]Share{
+		{Name: "photos", Path: "/opt/ontree/apps/immich/mnt", Comment: "Immich library", ReadOnly: true, ValidUsers: []string{"alice", "bob"}},
+		{Name: "inbox", Path: "/opt/ontree/apps/paperless/mnt/consume"},
+	})
+
+	for _, want := range []string{
+		"[photos]\n   path = /opt/ontree/apps/immich/mnt\n   comment = Immich library\n",
+		"   read only = yes\n   valid users = alice bob\n",
+		"[inbox]\n   path = /opt/ontree/apps/paperless/mnt/consume\n   browseable = yes\n   read only = no\n   create mask",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered config missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestValidateShareName(t *testing.T) {
+	for _, valid := range []string{"photos", "Media_2", "paperless-inbox"} {
+		if err := ValidateShareName(valid); err != nil {
+			t.Errorf("ValidateShareName(%q) error = %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "global", "Homes", "has space", "x]\n[global", "-leading"} {
+		if err := ValidateShareName(invalid); err == nil {
+			t.Errorf("ValidateShareName(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestEnsureInclude(t *testing.T) {
+	dir := t.TempDir()
+	originalConf, originalFragment := smbConfPath, fragmentPath
+	smbConfPath = filepath.Join(dir, "smb.conf")
+	fragmentPath = filepath.Join(dir, "treeos-shares.conf")
+	defer func() { smbConfPath, fragmentPath = originalConf, originalFragment }()
+
+	if err := os.WriteFile(smbConfPath, []byte("[global]\n   workgroup = WORKGROUP"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := ensureInclude(); err != nil {
+			t.Fatalf("ensureInclude() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(smbConfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "include = "+fragmentPath) != 1 {
+		t.Errorf("expected exactly one include:\n%s", data)
+	}
+	if !strings.HasPrefix(string(data), "[global]\n   workgroup = WORKGROUP\n") {
+		t.Errorf("existing config was changed:\n%s", data)
+	}
+}
+
+func TestParseSmbstatusShares(t *testing.T) {
+	output := `
+Service      pid     Machine       Connected at                     Encryption   Signing
+---------------------------------------------------------------------------------------------
+photos       1201    192.168.1.20  Mon Jun  2 10:00:00 2025 CEST    -            -
+photos       1305    192.168.1.31  Mon Jun  2 11:00:00 2025 CEST    -            -
+IPC$         1201    192.168.1.20  Mon Jun  2 10:00:00 2025 CEST    -            -
+inbox        1410    192.168.1.40  Mon Jun  2 12:00:00 2025 CEST    -            -
+`
+	got := parseSmbstatusShares(output)
+	want := map[string]int{"photos": 2, "inbox": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSmbstatusShares() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePdbeditUsers(t *testing.T) {
+	got := parsePdbeditUsers("bob:1002:\nalice:1001:Alice Example\n")
+	want := []string{"alice", "bob"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePdbeditUsers() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/docker/go-units"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// ErrQuotaUnsupported is returned when the filesystem can't enforce project quotas
+var ErrQuotaUnsupported = errors.New("project quotas are not supported on this filesystem")
+
+// ParseSize parses a human readable size like "50GB" or "1.5TB" into bytes
+func ParseSize(size string) (uint64, error) {
+	bytes, err := units.FromHumanSize(strings.TrimSpace(size))
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+	if bytes <= 0 {
+		return 0, fmt.Errorf("size must be positive: %q", size)
+	}
+	return uint64(bytes), nil
+}
+
+// FormatSize formats bytes the same way sizes are entered, e.g. "52.4GB"
+func FormatSize(bytes uint64) string {
+	return units.HumanSize(float64(bytes))
+}
+
+// DirUsage returns the disk space used by the files below dir, like du.
+// A symlinked dir (see PlaceAppMount) is followed.
+func DirUsage(dir string) (uint64, error) {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var total uint64
+	err = filepath.WalkDir(resolved, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may disappear while walking, e.g. temporary files of a running app
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			total += uint64(stat.Blocks) * 512 //nolint:gosec // Block counts are never negative
+		} else {
+			total += uint64(info.Size()) //nolint:gosec // File sizes are never negative
+		}
+		return nil
+	})
+	return total, err
+}
+
+// ProjectID returns a stable quota project ID for an app. IDs start at 100000 to stay clear
+// of projects an admin configured by hand.
+func ProjectID(appName string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(appName)) //nolint:errcheck,gosec // hash.Hash never returns an error
+	return 100000 + h.Sum32()%1000000000
+}
+
+// SetProjectQuota limits the space used below dir with a filesystem project quota on
+// xfs or ext4. A limit of 0 removes the limit. The filesystem must be mounted with
+// project quotas enabled (prjquota) and TreeOS must run as root.
+func SetProjectQuota(ctx context.Context, dir string, projectID uint32, limit uint64) error {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	mountpoint, fstype, err := mountOf(resolved)
+	if err != nil {
+		return err
+	}
+
+	id := strconv.FormatUint(uint64(projectID), 10)
+	switch fstype {
+	case "xfs":
+		if err := run(ctx, "xfs_quota", "-x", "-c", fmt.Sprintf("project -s -p %s %s", resolved, id), mountpoint); err != nil {
+			return err
+		}
+		return run(ctx, "xfs_quota", "-x", "-c", fmt.Sprintf("limit -p bhard=%d %s", limit, id), mountpoint)
+	case "ext4":
+		if err := run(ctx, "chattr", "-R", "+P", "-p", id, resolved); err != nil {
+			return err
+		}
+		// setquota takes block limits in KiB
+		return run(ctx, "setquota", "-P", id, "0", strconv.FormatUint(limit/1024, 10), "0", "0", mountpoint)
+	default:
+		return fmt.Errorf("%w (%s)", ErrQuotaUnsupported, fstype)
+	}
+}
+
+// mountOf returns the mount point and filesystem type of the mount containing path
+func mountOf(path string) (string, string, error) {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return "", "", err
+	}
+	var mountpoint, fstype string
+	for _, p := range partitions {
+		if !isWithin(path, p.Mountpoint) || len(p.Mountpoint) < len(mountpoint) {
+			continue
+		}
+		mountpoint, fstype = p.Mountpoint, p.Fstype
+	}
+	if mountpoint == "" {
+		return "", "", fmt.Errorf("no mount found for %s", path)
+	}
+	return mountpoint, fstype, nil
+}
+
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+func run(ctx context.Context, name string, args ...string) error {
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput() //nolint:gosec // Fixed quota tools
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]uint64{
+		"50GB":  50_000_000_000,
+		"1.5TB": 1_500_000_000_000,
+		"512MB": 512_000_000,
+		" 10g ": 10_000_000_000,
+	}
+	for input, want := range tests {
+		got, err := ParseSize(input)
+		if err != nil {
+			t.Errorf("ParseSize(%q) error = %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSize(%q) = %d, want %d", input, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "lots", "0", "-5GB"} {
+		if _, err := ParseSize(invalid); err == nil {
+			t.Errorf("ParseSize(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestDirUsageFollowsMountLink(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "hdd", "app")
+	if err := os.MkdirAll(filepath.Join(data, "library"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data, "library", "photo.jpg"), make([]byte, 64*1024), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mnt := filepath.Join(dir, "mnt")
+	if err := os.Symlink(data, mnt); err != nil {
+		t.Fatal(err)
+	}
+
+	used, err := DirUsage(mnt)
+	if err != nil {
+		t.Fatalf("DirUsage() error = %v", err)
+	}
+	if used < 64*1024 {
+		t.Errorf("DirUsage() = %d, want at least %d", used, 64*1024)
+	}
+}
+
+func TestProjectID(t *testing.T) {
+	if ProjectID("immich") != ProjectID("immich") {
+		t.Error("project ID must be stable")
+	}
+	if ProjectID("immich") == ProjectID("photoprism") {
+		t.Error("expected different project IDs")
+	}
+	if ProjectID("immich") < 100000 {
+		t.Error("project ID must not collide with low IDs")
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/mnt/hdd/apps", "/mnt/hdd", true},
+		{"/mnt/hdd", "/mnt/hdd", true},
+		{"/mnt/hdd2/apps", "/mnt/hdd", false},
+		{"/mnt/hdd/apps", "/", true},
+	}
+	for _, tt := range tests {
+		if got := isWithin(tt.path, tt.dir); got != tt.want {
+			t.Errorf("isWithin(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package storage places app data on storage devices by class (fast SSD vs bulk HDD)
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// Class describes the kind of device an app wants its data on
+type Class string
+
+const (
+	// ClassFast is for small, latency sensitive data such as databases
+	ClassFast Class = "fast"
+	// ClassBulk is for large media libraries that need capacity rather than speed
+	ClassBulk Class = "bulk"
+)
+
+// ParseClass validates a storage class name. An empty name means no preference.
+func ParseClass(name string) (Class, error) {
+	switch c := Class(strings.ToLower(strings.TrimSpace(name))); c {
+	case "", ClassFast, ClassBulk:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown storage class %q (expected %q or %q)", name, ClassFast, ClassBulk)
+	}
+}
+
+// Root is a mount root tagged with the storage class of the device behind it
+type Root struct {
+	Path  string `toml:"path"`
+	Class Class  `toml:"class"`
+}
+
+// ParseRoots parses roots from a comma separated list like "fast=/mnt/ssd,bulk=/mnt/hdd"
+func ParseRoots(value string) ([]Root, error) {
+	var roots []Root
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid storage root %q (expected class=path)", entry)
+		}
+		root := Root{Path: strings.TrimSpace(path)}
+		class, err := ParseClass(name)
+		if err != nil {
+			return nil, err
+		}
+		root.Class = class
+		roots = append(roots, root)
+	}
+	return roots, ValidateRoots(roots)
+}
+
+// ValidateRoots checks that every root has a class and an absolute path
+func ValidateRoots(roots []Root) error {
+	for _, root := range roots {
+		if root.Class == "" {
+			return fmt.Errorf("storage root %q has no class", root.Path)
+		}
+		if _, err := ParseClass(string(root.Class)); err != nil {
+			return err
+		}
+		if !filepath.IsAbs(root.Path) {
+			return fmt.Errorf("storage root path must be absolute: %q", root.Path)
+		}
+	}
+	return nil
+}
+
+// freeSpace is replaceable in tests
+var freeSpace = func(path string) (uint64, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, err
+	}
+	return usage.Free, nil
+}
+
+// SelectRoot returns the root of the given class with the most free space.
+// Roots that can't be read (e.g. an unplugged disk) are skipped.
+func SelectRoot(roots []Root, class Class) (Root, bool) {
+	var (
+		best     Root
+		bestFree uint64
+		found    bool
+	)
+	for _, root := range roots {
+		if root.Class != class {
+			continue
+		}
+		free, err := freeSpace(root.Path)
+		if err != nil {
+			continue
+		}
+		if !found || free > bestFree {
+			best, bestFree, found = root, free, true
+		}
+	}
+	return best, found
+}
+
+// RootUsage is the space on the device of a storage root
+type RootUsage struct {
+	Root
+	Total     uint64
+	Free      uint64
+	Available bool // False if the root can't be read, e.g. an unplugged disk
+}
+
+// FreeLabel returns the free space in human readable form
+func (u RootUsage) FreeLabel() string {
+	return formatBytes(u.Free)
+}
+
+// TotalLabel returns the device size in human readable form
+func (u RootUsage) TotalLabel() string {
+	return formatBytes(u.Total)
+}
+
+// UsedPercent returns how full the device is
+func (u RootUsage) UsedPercent() int {
+	if u.Total == 0 {
+		return 0
+	}
+	return int((u.Total - u.Free) * 100 / u.Total)
+}
+
+// Usages returns the space on the device of each root
+func Usages(roots []Root) []RootUsage {
+	usages := make([]RootUsage, 0, len(roots))
+	for _, root := range roots {
+		usage := RootUsage{Root: root}
+		if stat, err := disk.Usage(root.Path); err == nil {
+			usage.Total, usage.Free, usage.Available = stat.Total, stat.Free, true
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// systemRoot is the mount point of the system disk, replaceable in tests
+var systemRoot = "/"
+
+// OnSystemDisk reports whether path lives on the same device as the root filesystem
+func OnSystemDisk(path string) (bool, error) {
+	var pathStat, rootStat syscall.Stat_t
+	if err := syscall.Stat(path, &pathStat); err != nil {
+		return false, err
+	}
+	if err := syscall.Stat(systemRoot, &rootStat); err != nil {
+		return false, err
+	}
+	return pathStat.Dev == rootStat.Dev, nil
+}
+
+// PlaceAppMount points the app's mnt directory at a directory for the app below root.
+// The mnt path is replaced by a symlink so compose files keep using ./mnt paths.
+// It returns the directory that now holds the app's data.
+func PlaceAppMount(mntPath, appName string, root Root) (string, error) {
+	target := filepath.Join(root.Path, "treeos", appName)
+	if err := os.MkdirAll(target, 0750); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", target, err)
+	}
+
+	info, err := os.Lstat(mntPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return "", err
+	case info.Mode()&os.ModeSymlink != 0:
+		if current, err := os.Readlink(mntPath); err == nil && current == target {
+			return target, nil
+		}
+		return "", fmt.Errorf("%s already points to another location", mntPath)
+	case info.IsDir():
+		empty, err := isEmptyDir(mntPath)
+		if err != nil {
+			return "", err
+		}
+		if !empty {
+			return "", fmt.Errorf("%s already contains data", mntPath)
+		}
+		if err := os.Remove(mntPath); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%s is not a directory", mntPath)
+	}
+
+	if err := os.Symlink(target, mntPath); err != nil {
+		return "", fmt.Errorf("failed to link %s to %s: %w", mntPath, target, err)
+	}
+	return target, nil
+}
+
+func isEmptyDir(path string) (bool, error) {
+	dir, err := os.Open(path) //nolint:gosec // Path from app directory
+	if err != nil {
+		return false, err
+	}
+	defer dir.Close() //nolint:errcheck // Read-only handle
+
+	_, err = dir.Readdirnames(1)
+	if errors.Is(err, io.EOF) {
+		return true, nil
+	}
+	return false, err
+}
+
+// PlacementWarning returns a warning when an app requesting bulk storage would keep its
+// data on the system disk, or an empty string if the placement is fine.
+func PlacementWarning(class Class, dataPath string) string {
+	if class != ClassBulk {
+		return ""
+	}
+	onSystem, err := OnSystemDisk(dataPath)
+	if err != nil || !onSystem {
+		return ""
+	}
+
+	message := "This app stores large media files but its data directory is on the system disk"
+	if free, err := freeSpace(dataPath); err == nil {
+		message += fmt.Sprintf(" (%s free)", formatBytes(free))
+	}
+	return message + ". Configure a bulk storage root to keep media on a larger disk."
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
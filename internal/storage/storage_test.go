@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRoots(t *testing.T) {
+	roots, err := ParseRoots("fast=/mnt/ssd, bulk=/mnt/hdd,BULK=/mnt/usb")
+	if err != nil {
+		t.Fatalf("ParseRoots() error = %v", err)
+	}
+	want := []Root{{"/mnt/ssd", ClassFast}, {"/mnt/hdd", ClassBulk}, {"/mnt/usb", ClassBulk}}
+	if len(roots) != len(want) {
+		t.Fatalf("got %d roots, want %d", len(roots), len(want))
+	}
+	for i := range want {
+		if roots[i] != want[i] {
+			t.Errorf("root %d = %+v, want %+v", i, roots[i], want[i])
+		}
+	}
+
+	for _, invalid := range []string{"/mnt/hdd", "slow=/mnt/hdd", "bulk=relative/path", "=/mnt/hdd"} {
+		if _, err := ParseRoots(invalid); err == nil {
+			t.Errorf("ParseRoots(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestSelectRoot(t *testing.T) {
+	free := map[string]uint64{"/mnt/a": 100, "/mnt/b": 500, "/mnt/ssd": 1000}
+	original := freeSpace
+	freeSpace = func(path string) (uint64, error) {
+		if f, ok := free[path]; ok {
+			return f, nil
+		}
+		return 0, errors.New("not mounted")
+	}
+	defer func() { freeSpace = original }()
+
+	roots := []Root{
+		{"/mnt/a", ClassBulk},
+		{"/mnt/gone", ClassBulk},
+		{"/mnt/b", ClassBulk},
+		{"/mnt/ssd", ClassFast},
+	}
+
+	root, ok := SelectRoot(roots, ClassBulk)
+	if !ok || root.Path != "/mnt/b" {
+		t.Errorf("SelectRoot(bulk) = %+v, %v; want /mnt/b", root, ok)
+	}
+	if _, ok := SelectRoot(roots[:2], ClassFast); ok {
+		t.Error("expected no fast root")
+	}
+}
+
+func TestPlaceAppMount(t *testing.T) {
+	dir := t.TempDir()
+	root := Root{Path: filepath.Join(dir, "hdd"), Class: ClassBulk}
+	mntPath := filepath.Join(dir, "apps", "photos", "mnt")
+	if err := os.MkdirAll(mntPath, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := PlaceAppMount(mntPath, "photos", root)
+	if err != nil {
+		t.Fatalf("PlaceAppMount() error = %v", err)
+	}
+	if link, err := os.Readlink(mntPath); err != nil || link != target {
+		t.Fatalf("mnt link = %q, %v; want %q", link, err, target)
+	}
+
+	// Placing again is a no-op
+	if _, err := PlaceAppMount(mntPath, "photos", root); err != nil {
+		t.Errorf("second PlaceAppMount() error = %v", err)
+	}
+
+	// Existing data is never moved out of the way
+	otherMnt := filepath.Join(dir, "apps", "other", "mnt")
+	if err := os.MkdirAll(otherMnt, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(otherMnt, "data"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PlaceAppMount(otherMnt, "other", root); err == nil {
+		t.Error("expected error for non-empty mnt directory")
+	}
+}
+
+func TestPlacementWarning(t *testing.T) {
+	dir := t.TempDir()
+	original := systemRoot
+	systemRoot = dir
+	defer func() { systemRoot = original }()
+
+	if PlacementWarning(ClassBulk, dir) == "" {
+		t.Error("expected warning for bulk app on the system disk")
+	}
+	if msg := PlacementWarning(ClassFast, dir); msg != "" {
+		t.Errorf("unexpected warning for fast app: %q", msg)
+	}
+	if msg := PlacementWarning("", dir); msg != "" {
+		t.Errorf("unexpected warning without class: %q", msg)
+	}
+}
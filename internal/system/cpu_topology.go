@@ -0,0 +1,144 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CPU describes one online logical CPU of the host, for pinning containers to cores
+type CPU struct {
+	ID      int `json:"id"`
+	Package int `json:"package"` // Physical socket
+	Core    int `json:"core"`    // Core within the package, shared by hyper-threads
+	Node    int `json:"node"`    // NUMA node, 0 on hosts without NUMA
+	// Capacity is the relative performance on big.LITTLE hosts, 1024 for the fastest
+	// cores. It is 0 where the kernel doesn't report it.
+	Capacity int `json:"capacity,omitempty"`
+}
+
+// CPUTopology returns the online CPUs of the host with their socket, core and NUMA node.
+// Without sysfs, e.g. on macOS, it returns runtime.NumCPU() CPUs on a single node.
+func CPUTopology() []CPU {
+	cpus, err := readCPUTopology("/sys/devices/system/cpu")
+	if err != nil || len(cpus) == 0 {
+		cpus = make([]CPU, runtime.NumCPU())
+		for i := range cpus {
+			cpus[i] = CPU{ID: i, Core: i}
+		}
+	}
+	return cpus
+}
+
+// readCPUTopology reads the CPUs from a sysfs cpu directory
+func readCPUTopology(dir string) ([]CPU, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cpus []CPU
+	for _, entry := range entries {
+		id, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "cpu"))
+		if err != nil || !strings.HasPrefix(entry.Name(), "cpu") {
+			continue
+		}
+		cpuDir := filepath.Join(dir, entry.Name())
+		// cpu0 usually has no online file, as it can't be taken offline
+		if online, err := readSysfsInt(filepath.Join(cpuDir, "online")); err == nil && online == 0 {
+			continue
+		}
+
+		cpu := CPU{ID: id, Core: id}
+		if v, err := readSysfsInt(filepath.Join(cpuDir, "topology", "physical_package_id")); err == nil && v >= 0 {
+			cpu.Package = v
+		}
+		if v, err := readSysfsInt(filepath.Join(cpuDir, "topology", "core_id")); err == nil {
+			cpu.Core = v
+		}
+		if v, err := readSysfsInt(filepath.Join(cpuDir, "cpu_capacity")); err == nil {
+			cpu.Capacity = v
+		}
+		// The NUMA node shows as a nodeN link in the CPU's directory
+		if nodes, err := filepath.Glob(filepath.Join(cpuDir, "node[0-9]*")); err == nil && len(nodes) > 0 {
+			if v, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(nodes[0]), "node")); err == nil {
+				cpu.Node = v
+			}
+		}
+		cpus = append(cpus, cpu)
+	}
+
+	sort.Slice(cpus, func(i, j int) bool { return cpus[i].ID < cpus[j].ID })
+	return cpus, nil
+}
+
+func readSysfsInt(path string) (int, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Path within sysfs
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// maxCPUID bounds cpuset ranges, the kernel supports at most 8192 CPUs
+const maxCPUID = 8191
+
+// ParseCPUSet parses a cpuset list such as "0-3,6" into sorted, distinct CPU ids
+func ParseCPUSet(set string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(set, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid cpuset %q: empty entry", set)
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid cpuset %q: %q is not a CPU number", set, first)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return nil, fmt.Errorf("invalid cpuset %q: invalid range %q", set, part)
+			}
+		}
+		if end > maxCPUID {
+			return nil, fmt.Errorf("invalid cpuset %q: CPU %d is out of range", set, end)
+		}
+		for id := start; id <= end; id++ {
+			seen[id] = true
+		}
+	}
+
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// FormatCPUSet writes CPU ids as a cpuset list, joining consecutive ids to ranges
+func FormatCPUSet(ids []int) string {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[j] == sorted[i] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
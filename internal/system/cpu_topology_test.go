@@ -0,0 +1,75 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUSet(t *testing.T) {
+	tests := []struct {
+		set     string
+		want    []int
+		wantErr bool
+	}{
+		{set: "0", want: []int{0}},
+		{set: "0-3", want: []int{0, 1, 2, 3}},
+		{set: "6, 0-1,1", want: []int{0, 1, 6}},
+		{set: "", wantErr: true},
+		{set: "3-1", wantErr: true},
+		{set: "a", wantErr: true},
+		{set: "0-100000", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCPUSet(tt.set)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCPUSet(%q) error = %v, wantErr %v", tt.set, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCPUSet(%q) = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+
+	if got := FormatCPUSet([]int{6, 0, 1, 2, 4}); got != "0-2,4,6" {
+		t.Errorf("FormatCPUSet() = %q, want 0-2,4,6", got)
+	}
+}
+
+func TestReadCPUTopology(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two sockets on two NUMA nodes, cpu2 is offline
+	write("cpu0/topology/physical_package_id", "0")
+	write("cpu0/topology/core_id", "0")
+	write("cpu0/node0/cpulist", "0")
+	write("cpu1/topology/physical_package_id", "1")
+	write("cpu1/topology/core_id", "0")
+	write("cpu1/cpu_capacity", "512")
+	write("cpu1/node1/cpulist", "1")
+	write("cpu1/online", "1")
+	write("cpu2/online", "0")
+	write("cpufreq/policy0", "")
+
+	got, err := readCPUTopology(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CPU{
+		{ID: 0, Package: 0, Core: 0, Node: 0},
+		{ID: 1, Package: 1, Core: 0, Node: 1, Capacity: 512},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readCPUTopology() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package system
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// vlanConfig lists the 802.1Q interfaces of a Linux host with their VLAN ID and parent
+const vlanConfig = "/proc/net/vlan/config"
+
+// runtimeInterfacePrefixes are the bridges and veth pairs container runtimes create. Ports
+// can't usefully be bound to them, so they aren't offered.
+var runtimeInterfacePrefixes = []string{"docker", "br-", "veth", "cni", "podman", "virbr", "flannel", "cali"}
+
+// NetworkInterface is a network interface of the host that published ports can bind to
+type NetworkInterface struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
+	Loopback  bool     `json:"loopback,omitempty"`
+	VLAN      int      `json:"vlan,omitempty"`   // 802.1Q VLAN ID, 0 for untagged interfaces
+	Parent    string   `json:"parent,omitempty"` // Interface the VLAN runs on
+}
+
+// vlan is an entry of /proc/net/vlan/config
+type vlan struct {
+	id     int
+	parent string
+}
+
+// HostInterfaces returns the interfaces of the host that are up and have an address, with
+// the VLAN each one belongs to. Interfaces of the container runtime are left out.
+func HostInterfaces() ([]NetworkInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	vlans := readVLANs(vlanConfig)
+
+	var result []NetworkInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || isRuntimeInterface(iface.Name) {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		ni := NetworkInterface{Name: iface.Name, Loopback: iface.Flags&net.FlagLoopback != 0}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			// Link-local IPv6 addresses need a zone, compose can't bind to them
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			ni.Addresses = append(ni.Addresses, ipNet.IP.String())
+		}
+		if len(ni.Addresses) == 0 {
+			continue
+		}
+		if v, ok := vlans[iface.Name]; ok {
+			ni.VLAN, ni.Parent = v.id, v.parent
+		}
+		result = append(result, ni)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		// Loopback first, it's the usual choice for apps only reached through Caddy
+		return result[i].Loopback && !result[j].Loopback
+	})
+	return result, nil
+}
+
+// HostHasAddress reports whether an address is assigned to one of the host's interfaces
+func HostHasAddress(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRuntimeInterface reports whether an interface belongs to the container runtime
+func isRuntimeInterface(name string) bool {
+	for _, prefix := range runtimeInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readVLANs reads the VLAN interfaces from /proc/net/vlan/config, which looks like:
+//
+//	VLAN Dev name	 | VLAN ID
+//	Name-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD
+//	eth0.20        | 20  | eth0
+//
+// Hosts without the 8021q module or Linux have no VLANs.
+func readVLANs(path string) map[string]vlan {
+	vlans := make(map[string]vlan)
+	f, err := os.Open(path) //nolint:gosec // Fixed path below /proc
+	if err != nil {
+		return vlans
+	}
+	defer f.Close() //nolint:errcheck // Read-only
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) != 3 {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			continue
+		}
+		vlans[strings.TrimSpace(fields[0])] = vlan{id: id, parent: strings.TrimSpace(fields[2])}
+	}
+	return vlans
+}
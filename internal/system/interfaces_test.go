@@ -0,0 +1,44 @@
+package system
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadVLANs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := "VLAN Dev name\t | VLAN ID\nName-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD\neth0.20        | 20  | eth0\niot            | 30  | enp3s0\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]vlan{"eth0.20": {id: 20, parent: "eth0"}, "iot": {id: 30, parent: "enp3s0"}}
+	if got := readVLANs(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("readVLANs() = %v, want %v", got, want)
+	}
+	if got := readVLANs(filepath.Join(t.TempDir(), "missing")); len(got) != 0 {
+		t.Errorf("readVLANs() without the file = %v", got)
+	}
+}
+
+func TestHostInterfaces(t *testing.T) {
+	ifaces, err := HostInterfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, iface := range ifaces {
+		if isRuntimeInterface(iface.Name) {
+			t.Errorf("HostInterfaces() offers %s of the container runtime", iface.Name)
+		}
+		for _, addr := range iface.Addresses {
+			if !HostHasAddress(net.ParseIP(addr)) {
+				t.Errorf("HostHasAddress(%s) = false for an address of %s", addr, iface.Name)
+			}
+		}
+	}
+	if HostHasAddress(net.ParseIP("192.0.2.1")) {
+		t.Error("HostHasAddress() accepted a documentation address")
+	}
+}
@@ -12,6 +12,10 @@ import (
 	"time"
 
 	"github.com/ontree-co/treeos/internal/config"
+	"github.com/ontree-co/treeos/internal/engine"
+	"github.com/ontree-co/treeos/internal/timesync"
+	"github.com/ontree-co/treeos/internal/userns"
+	"github.com/ontree-co/treeos/pkg/compose"
 )
 
 // Status represents the health status of a system check.
@@ -47,12 +51,20 @@ func NewRunner(cfg *config.Config) *Runner {
 
 // Run executes all system checks and returns the results.
 func (r *Runner) Run(ctx context.Context) []CheckResult {
-	return []CheckResult{
+	results := []CheckResult{
 		r.checkDirectories(),
 		r.checkDocker(ctx),
 		r.checkDockerCompose(ctx),
 		r.checkCaddy(ctx),
+		r.checkTimeSync(ctx),
 	}
+	if r.cfg.Confinement != "" {
+		results = append(results, r.checkConfinement(ctx))
+	}
+	if r.cfg.UserNamespaces {
+		results = append(results, r.checkUserNamespaces(ctx))
+	}
+	return results
 }
 
 func (r *Runner) checkDirectories() CheckResult {
@@ -100,6 +112,9 @@ func (r *Runner) checkDirectories() CheckResult {
 }
 
 func (r *Runner) checkDocker(ctx context.Context) CheckResult {
+	if engine.CLI() == "podman" {
+		return r.checkPodman(ctx)
+	}
 	version, err := commandVersion(ctx, "docker", "--version")
 	if err != nil {
 		return CheckResult{
@@ -133,9 +148,44 @@ func (r *Runner) checkDocker(ctx context.Context) CheckResult {
 	}
 }
 
+// checkPodman checks Podman in place of Docker, under the same ID
+func (r *Runner) checkPodman(ctx context.Context) CheckResult {
+	version, err := commandVersion(ctx, "podman", "--version")
+	if err != nil {
+		return CheckResult{
+			ID:          "docker",
+			Name:        "Podman",
+			Status:      StatusError,
+			Message:     "Podman not available",
+			Details:     err.Error(),
+			Remediation: podmanRemediation(),
+		}
+	}
+
+	if _, err := commandOutput(ctx, "podman", "info"); err != nil {
+		return CheckResult{
+			ID:          "docker",
+			Name:        "Podman",
+			Status:      StatusError,
+			Message:     "Podman not working",
+			Details:     err.Error(),
+			Remediation: podmanRemediation(),
+		}
+	}
+
+	return CheckResult{
+		ID:      "docker",
+		Name:    "Podman",
+		Status:  StatusOK,
+		Message: "Podman detected and running",
+		Version: version,
+	}
+}
+
 func (r *Runner) checkDockerCompose(ctx context.Context) CheckResult {
-	// Only check for docker compose v2 (plugin version)
-	version, err := commandVersion(ctx, "docker", "compose", "version")
+	cli := engine.CLI()
+	// Only check for docker compose v2 (plugin version), Podman runs it as compose provider
+	version, err := commandVersion(ctx, cli, "compose", "version")
 	if err == nil {
 		return CheckResult{
 			ID:      "docker_compose",
@@ -191,6 +241,138 @@ func (r *Runner) checkCaddy(ctx context.Context) CheckResult {
 	}
 }
 
+func (r *Runner) checkTimeSync(ctx context.Context) CheckResult {
+	status := timesync.Check(ctx)
+	result := CheckResult{ID: "time_sync", Name: "Time synchronization", Status: StatusError}
+	if status.Service != "" {
+		result.Version = status.Service
+	}
+	if status.OffsetKnown {
+		result.Details = fmt.Sprintf("Clock offset to %s: %s", status.Server, status.Offset.Round(time.Millisecond))
+	} else {
+		result.Details = "Could not measure the clock offset: " + status.OffsetError
+	}
+
+	switch {
+	case status.Drifted():
+		result.Message = fmt.Sprintf("Clock is off by %s", status.Offset.Round(time.Second))
+		result.Remediation = timeSyncRemediation(status.Supported)
+	case status.Supported && !status.Enabled:
+		result.Message = "Time synchronization is disabled"
+		result.Remediation = timeSyncRemediation(true)
+	case status.Supported && !status.Synchronized:
+		result.Message = "Clock is not synchronized yet"
+		result.Remediation = timeSyncRemediation(true)
+	default:
+		result.Status = StatusOK
+		result.Message = "Clock is synchronized"
+	}
+	return result
+}
+
+// Kernel interfaces reporting the state of the security modules
+var (
+	appArmorEnabledPath  = "/sys/module/apparmor/parameters/enabled"
+	appArmorProfilesPath = "/sys/kernel/security/apparmor/profiles"
+	seLinuxEnforcePath   = "/sys/fs/selinux/enforce"
+)
+
+// dockerSecurityOptions returns the security options the Docker daemon runs with
+var dockerSecurityOptions = func(ctx context.Context) (string, error) {
+	return commandOutput(ctx, "docker", "info", "--format", "{{json .SecurityOptions}}")
+}
+
+// checkConfinement verifies that the kernel enforces the configured security module and
+// that Docker uses it, otherwise the profile applied to app containers has no effect.
+func (r *Runner) checkConfinement(ctx context.Context) CheckResult {
+	mode := r.cfg.Confinement
+	confinement := compose.NewConfinement(mode, r.cfg.ConfinementProfile)
+	profile := confinement.AppArmorProfile + confinement.SELinuxType
+	result := CheckResult{ID: "confinement", Name: "Container confinement", Status: StatusError, Version: mode}
+
+	enforced := false
+	switch mode {
+	case config.ConfinementAppArmor:
+		enforced = readTrimmed(appArmorEnabledPath) == "Y"
+	case config.ConfinementSELinux:
+		enforced = readTrimmed(seLinuxEnforcePath) == "1"
+	}
+	if !enforced {
+		result.Message = fmt.Sprintf("%s is not enforcing on this host", confinementName(mode))
+		result.Remediation = confinementRemediation(mode)
+		return result
+	}
+
+	options, err := dockerSecurityOptions(ctx)
+	if err != nil {
+		result.Message = "Could not read the Docker security options"
+		result.Details = err.Error()
+		result.Remediation = dockerDaemonRemediation()
+		return result
+	}
+	if !strings.Contains(options, "name="+mode) {
+		result.Message = fmt.Sprintf("Docker does not use %s", confinementName(mode))
+		result.Details = "Docker security options: " + options
+		result.Remediation = confinementRemediation(mode)
+		return result
+	}
+
+	// The profile list is only readable by root, skip the check when it is not available
+	if mode == config.ConfinementAppArmor {
+		if profiles, err := os.ReadFile(appArmorProfilesPath); err == nil && !strings.Contains(string(profiles), profile+" (enforce)") {
+			result.Message = fmt.Sprintf("AppArmor profile %s is not loaded in enforce mode", profile)
+			result.Remediation = []string{
+				fmt.Sprintf("Load the profile: sudo apparmor_parser -r /etc/apparmor.d/%s", profile),
+				"Or unset CONFINEMENT_PROFILE to use " + compose.DefaultAppArmorProfile,
+			}
+			return result
+		}
+	}
+
+	result.Status = StatusOK
+	result.Message = fmt.Sprintf("App containers are confined by %s (%s)", confinementName(mode), profile)
+	return result
+}
+
+// checkUserNamespaces verifies that root in an app container is not root on the host
+func (r *Runner) checkUserNamespaces(ctx context.Context) CheckResult {
+	result := CheckResult{ID: "userns", Name: "User namespaces", Status: StatusError}
+
+	mapping, err := userns.Detect(ctx)
+	result.Version = mapping.Mode
+	if err != nil {
+		result.Message = "Could not determine the user namespace mapping"
+		result.Details = err.Error()
+		result.Remediation = userNamespacesRemediation(mapping.Runtime)
+		return result
+	}
+	if !mapping.Enabled() {
+		result.Message = fmt.Sprintf("Containers run with the host's user IDs, root in a container is root on the host (%s)", mapping.Runtime)
+		result.Remediation = userNamespacesRemediation(mapping.Runtime)
+		return result
+	}
+
+	result.Status = StatusOK
+	result.Message = fmt.Sprintf("Container root is host user %d (%s, %s)", mapping.UIDs.Root, mapping.Mode, mapping.User)
+	result.Details = fmt.Sprintf("Container IDs 1-%d map to host IDs %d-%d", mapping.UIDs.Size, mapping.UIDs.Start, mapping.UIDs.Start+mapping.UIDs.Size-1)
+	return result
+}
+
+func confinementName(mode string) string {
+	if mode == config.ConfinementSELinux {
+		return "SELinux"
+	}
+	return "AppArmor"
+}
+
+func readTrimmed(path string) string {
+	content, err := os.ReadFile(path) //nolint:gosec // Fixed kernel interface paths
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
 func sharedPath(_ *config.Config) string {
 	return config.GetSharedPath()
 }
@@ -220,6 +402,9 @@ func commandOutput(ctx context.Context, name string, args ...string) (string, er
 	return strings.TrimSpace(string(output)), nil
 }
 
+// installDepsRemediation installs missing dependencies with the repositories for the host's distribution
+const installDepsRemediation = "Install automatically: sudo treeos install-deps"
+
 func directoryRemediation(path string) []string {
 	return []string{
 		fmt.Sprintf("Create the directory: sudo mkdir -p %s", path),
@@ -237,7 +422,8 @@ func dockerRemediation() []string {
 		}
 	case "linux":
 		return []string{
-			"Install Docker: curl -fsSL https://get.docker.com -o get-docker.sh && sh get-docker.sh",
+			installDepsRemediation,
+			"Or install Docker: curl -fsSL https://get.docker.com -o get-docker.sh && sh get-docker.sh",
 			"Add user to docker group: sudo usermod -aG docker $USER",
 			"Start Docker service: sudo systemctl start docker",
 			"Enable Docker service: sudo systemctl enable docker",
@@ -250,6 +436,22 @@ func dockerRemediation() []string {
 	}
 }
 
+func podmanRemediation() []string {
+	if os.Geteuid() != 0 {
+		return []string{
+			"Install automatically: sudo treeos install-deps --runtime podman",
+			"Enable the API socket of your user: systemctl --user enable --now podman.socket",
+			"Keep it running after logout: sudo loginctl enable-linger $USER",
+			"Check the setup: podman info",
+		}
+	}
+	return []string{
+		"Install automatically: sudo treeos install-deps --runtime podman",
+		"Enable the API socket: sudo systemctl enable --now podman.socket",
+		"Check the setup: podman info",
+	}
+}
+
 func dockerDaemonRemediation() []string {
 	switch runtime.GOOS {
 	case "darwin":
@@ -284,6 +486,7 @@ func dockerComposeRemediation() []string {
 	case "linux":
 		return []string{
 			"Docker Compose v2 is required (not the standalone docker-compose v1)",
+			installDepsRemediation,
 			"Ubuntu/Debian: sudo apt update && sudo apt install docker-compose-v2",
 			"Or via Docker repos: sudo apt update && sudo apt install docker-compose-plugin",
 			"Other distros: https://docs.docker.com/compose/install/linux/",
@@ -298,11 +501,66 @@ func dockerComposeRemediation() []string {
 	}
 }
 
-func caddyRemediation() []string {
+func timeSyncRemediation(supported bool) []string {
+	if !supported {
+		return []string{
+			"Enable setting the time automatically in the system settings",
+		}
+	}
 	return []string{
-		"Install Caddy: sudo apt install -y debian-keyring debian-archive-keyring apt-transport-https curl",
-		"Add Caddy repo: curl -1sLf 'https://dl.cloudsmith.io/public/caddy/stable/gpg.key' | sudo gpg --dearmor -o /usr/share/keyrings/caddy-stable-archive-keyring.gpg",
-		"Install: sudo apt update && sudo apt install caddy",
-		"Or download from: https://caddyserver.com/download",
+		"Enable it in Settings under Host Packages, or run: sudo timedatectl set-ntp true",
+		"Install an NTP client if none is present: sudo apt install chrony (or sudo dnf install chrony)",
+		"Check the status: timedatectl status",
+	}
+}
+
+func confinementRemediation(mode string) []string {
+	if mode == config.ConfinementSELinux {
+		return []string{
+			"Set SELINUX=enforcing in /etc/selinux/config and reboot",
+			`Enable SELinux in Docker: add "selinux-enabled": true to /etc/docker/daemon.json and restart Docker`,
+			"Or unset CONTAINER_CONFINEMENT on hosts without SELinux",
+		}
+	}
+	return []string{
+		"Enable AppArmor: sudo systemctl enable --now apparmor",
+		"Check the status: sudo aa-status",
+		"Or unset CONTAINER_CONFINEMENT on hosts without AppArmor",
+	}
+}
+
+func userNamespacesRemediation(runtimeName string) []string {
+	if runtimeName == "podman" {
+		return []string{
+			"Run TreeOS as a regular user so Podman runs rootless",
+			"Give the user subordinate IDs: sudo usermod --add-subuids 100000-165535 --add-subgids 100000-165535 <user>",
+			"Fix the ownership of existing app data: treeos userns migrate",
+		}
+	}
+	return []string{
+		`Add "userns-remap": "default" to /etc/docker/daemon.json and restart Docker: sudo systemctl restart docker`,
+		"Fix the ownership of existing app data: sudo treeos userns migrate",
+		"Named volumes are not carried over, copy them from /var/lib/docker/volumes to the remapped data root",
+		"Or unset USER_NAMESPACES",
+	}
+}
+
+func caddyRemediation() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"Install via Homebrew: brew install caddy",
+			"Run it as a service: brew services start caddy",
+			"TreeOS only manages a Caddy installed with Homebrew on macOS",
+		}
+	case "linux":
+		return []string{
+			installDepsRemediation,
+			"Or follow the instructions for your distribution: https://caddyserver.com/docs/install",
+		}
+	default:
+		return []string{
+			"Download from: https://caddyserver.com/download",
+		}
 	}
 }
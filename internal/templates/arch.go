@@ -0,0 +1,63 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// ErrUnsupportedArchitecture is returned for templates that have no images for the host
+var ErrUnsupportedArchitecture = errors.New("unsupported architecture")
+
+// HostArchitecture returns the architecture of this node as templates name it. TreeOS
+// builds its 32-bit ARM binaries for ARMv7.
+func HostArchitecture() string {
+	if runtime.GOARCH == "arm" {
+		return "armv7"
+	}
+	return runtime.GOARCH
+}
+
+// platformArchitecture returns the template architecture of a compose platform such as
+// linux/arm/v7, and the OS of the platform
+func platformArchitecture(platform string) (osName, arch string) {
+	osName, arch, _ = strings.Cut(platform, "/")
+	arch, variant, _ := strings.Cut(arch, "/")
+	if arch == "arm" && (variant == "" || variant == "v7") {
+		arch = "armv7"
+	}
+	return osName, arch
+}
+
+// Supports reports whether the template runs on an architecture. Templates without
+// declared architectures are assumed to run everywhere.
+func (t Template) Supports(arch string) bool {
+	return len(t.Architectures) == 0 || slices.Contains(t.Architectures, arch)
+}
+
+// ImagesFor returns the service images the template replaces on an architecture, nil if the
+// compose file is used as it is
+func (t Template) ImagesFor(arch string) (map[string]string, error) {
+	if !t.Supports(arch) {
+		return nil, fmt.Errorf("%w: %s has no images for %s, it runs on %s",
+			ErrUnsupportedArchitecture, t.Name, arch, strings.Join(t.Architectures, ", "))
+	}
+	return t.Variants[arch], nil
+}
+
+// ApplyVariant sets the images of the services in a compose file, keeping its formatting
+func ApplyVariant(content string, images map[string]string) (string, error) {
+	data := []byte(content)
+	for _, service := range slices.Sorted(maps.Keys(images)) {
+		var err error
+		if data, err = yamlutil.SetServiceImage(data, service, images[service]); err != nil {
+			return "", fmt.Errorf("failed to set the image of service %s: %w", service, err)
+		}
+	}
+	return string(data), nil
+}
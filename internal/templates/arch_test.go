@@ -0,0 +1,62 @@
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImagesFor(t *testing.T) {
+	tmpl := &Template{
+		Name:          "Web",
+		Architectures: []string{"amd64", "arm64", "armv7"},
+		Variants:      map[string]map[string]string{"armv7": {"web": "nginx:1.27-armv7"}},
+	}
+	if images, err := tmpl.ImagesFor("amd64"); err != nil || images != nil {
+		t.Errorf("ImagesFor(amd64) = %v, %v, want no replacements", images, err)
+	}
+	if images, err := tmpl.ImagesFor("armv7"); err != nil || images["web"] != "nginx:1.27-armv7" {
+		t.Errorf("ImagesFor(armv7) = %v, %v", images, err)
+	}
+
+	tmpl.Architectures = []string{"amd64"}
+	_, err := tmpl.ImagesFor("arm64")
+	if !errors.Is(err, ErrUnsupportedArchitecture) || !strings.Contains(err.Error(), "Web has no images for arm64, it runs on amd64") {
+		t.Errorf("ImagesFor(arm64) error = %v", err)
+	}
+
+	if _, err := (&Template{}).ImagesFor("riscv64"); err != nil {
+		t.Errorf("ImagesFor() of a template without architectures error = %v", err)
+	}
+}
+
+func TestApplyVariant(t *testing.T) {
+	content := `services:
+  web:
+    image: nginx:1.27 # Pinned
+    ports:
+      - "8080:80"
+  worker:
+    build: .
+`
+	got, err := ApplyVariant(content, map[string]string{"web": "nginx:1.27-armv7", "worker": "example/worker:1-armv7"})
+	if err != nil {
+		t.Fatalf("ApplyVariant() error = %v", err)
+	}
+	if !strings.Contains(got, "image: nginx:1.27-armv7 # Pinned") || !strings.Contains(got, "image: example/worker:1-armv7") || !strings.Contains(got, `- "8080:80"`) {
+		t.Errorf("ApplyVariant() = %s", got)
+	}
+
+	if _, err := ApplyVariant(content, map[string]string{"db": "postgres:16"}); err == nil {
+		t.Error("expected an error for an unknown service")
+	}
+}
+
+func TestPlatformArchitecture(t *testing.T) {
+	tests := map[string]string{"linux/amd64": "amd64", "linux/arm64/v8": "arm64", "linux/arm/v7": "armv7", "linux/arm": "armv7", "linux/arm/v6": "arm"}
+	for platform, want := range tests {
+		if osName, arch := platformArchitecture(platform); osName != "linux" || arch != want {
+			t.Errorf("platformArchitecture(%q) = %s, %s, want linux, %s", platform, osName, arch, want)
+		}
+	}
+}
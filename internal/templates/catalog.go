@@ -0,0 +1,423 @@
+package templates
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// catalogIndexName is the index file of Git catalogs, and the name synced indexes are kept as
+const catalogIndexName = "catalog.json"
+
+// Limits of catalog downloads, templates are a few small text files
+const (
+	maxCatalogIndexSize = 4 << 20
+	maxCatalogFileSize  = 1 << 20
+)
+
+// catalogHTTPTimeout bounds each download of a JSON catalog
+const catalogHTTPTimeout = 30 * time.Second
+
+var (
+	// ErrChecksumMismatch is returned when a file of a catalog template doesn't match the
+	// checksum of its catalog index
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrBadSignature is returned when a catalog index isn't signed by the catalog's key
+	ErrBadSignature = errors.New("catalog signature is invalid")
+)
+
+// CatalogSource is a remote catalog templates are synced from
+type CatalogSource struct {
+	Name      string // Prefix of the IDs of its templates
+	URL       string // JSON index, or Git repository with a catalog.json at its root
+	PublicKey string // Base64 Ed25519 public key the index must be signed with, "" for unsigned
+}
+
+// CatalogIndex is the catalog.json of a remote catalog. The template directories are laid
+// out like the shipped templates, next to the index.
+type CatalogIndex struct {
+	Templates []CatalogEntry `json:"templates"`
+}
+
+// CatalogEntry is a template directory of a catalog index
+type CatalogEntry struct {
+	Dir   string            `json:"dir"`   // Directory relative to the index, also the template ID
+	Files map[string]string `json:"files"` // Hex SHA-256 checksum of each file by name
+}
+
+// CatalogSyncResult is the outcome of syncing a catalog
+type CatalogSyncResult struct {
+	Templates int      `json:"templates"`         // Templates now offered by the catalog
+	Skipped   []string `json:"skipped,omitempty"` // Templates left out, with the reason
+}
+
+// IsGitCatalog returns true if a catalog URL is a Git repository rather than a JSON index
+func IsGitCatalog(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "git@") || strings.HasSuffix(strings.TrimSuffix(rawURL, "/"), ".git")
+}
+
+// ValidateCatalogSource returns an error if a catalog can't be synced as configured
+func ValidateCatalogSource(source CatalogSource) error {
+	if !templateIDPattern.MatchString(source.Name) {
+		return fmt.Errorf("catalog name must be lowercase letters, digits and dashes")
+	}
+	if strings.HasPrefix(source.URL, "git@") {
+		if host, repo, ok := strings.Cut(strings.TrimPrefix(source.URL, "git@"), ":"); !ok || host == "" || repo == "" {
+			return fmt.Errorf("catalog URL must look like git@host:owner/repo.git")
+		}
+	} else {
+		u, err := url.Parse(source.URL)
+		schemes := []string{"http", "https"}
+		if IsGitCatalog(source.URL) {
+			schemes = append(schemes, "ssh")
+		}
+		if err != nil || !slices.Contains(schemes, u.Scheme) || u.Host == "" {
+			return fmt.Errorf("catalog URL must be an http or https URL, or a Git repository")
+		}
+	}
+	if source.PublicKey != "" {
+		if _, err := parseCatalogKey(source.PublicKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCatalogKey decodes a base64 Ed25519 public key
+func parseCatalogKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("catalog public key must be a base64 Ed25519 key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// verifyCatalogSignature checks the base64 Ed25519 signature of an index. Catalogs without
+// a public key are not signed.
+func verifyCatalogSignature(index, signature []byte, publicKey string) error {
+	if publicKey == "" {
+		return nil
+	}
+	key, err := parseCatalogKey(publicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(key, index, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// verifyChecksum compares a file with its hex SHA-256 checksum
+func verifyChecksum(name string, data []byte, checksum string) error {
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), checksum) {
+		return fmt.Errorf("%s: %w", name, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// catalogReader reads the files of a remote catalog
+type catalogReader interface {
+	// read returns a file by its path relative to the index
+	read(name string, limit int64) ([]byte, error)
+}
+
+// httpCatalog reads a JSON catalog, files are resolved against the index URL
+type httpCatalog struct {
+	ctx   context.Context
+	index *url.URL
+}
+
+func (c httpCatalog) read(name string, limit int64) ([]byte, error) {
+	target := c.index.ResolveReference(&url.URL{Path: name})
+	ctx, cancel := context.WithTimeout(c.ctx, catalogHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "TreeOS")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup, error not critical
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return readLimited(name, resp.Body, limit)
+}
+
+// dirCatalog reads a catalog checked out from Git
+type dirCatalog struct {
+	fsys fs.FS
+}
+
+func (c dirCatalog) read(name string, limit int64) ([]byte, error) {
+	file, err := c.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck // Cleanup, error not critical
+	return readLimited(name, file, limit)
+}
+
+// readLimited reads a catalog file, failing if it is larger than limit
+func readLimited(name string, r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, limit)
+	}
+	return data, nil
+}
+
+// openCatalogSource returns a reader of a remote catalog and the name of its index. Git
+// catalogs are cloned into workDir.
+func openCatalogSource(ctx context.Context, rawURL, workDir string) (catalogReader, string, error) {
+	if !IsGitCatalog(rawURL) {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid catalog URL: %w", err)
+		}
+		return httpCatalog{ctx: ctx, index: u}, path.Base(u.Path), nil
+	}
+
+	checkout := filepath.Join(workDir, "checkout")
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--quiet", "--", rawURL, checkout) //nolint:gosec // URL is validated
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if text := strings.TrimSpace(string(output)); text != "" {
+			return nil, "", fmt.Errorf("git clone failed: %s", text)
+		}
+		return nil, "", fmt.Errorf("git clone failed: %w", err)
+	}
+	return dirCatalog{fsys: os.DirFS(checkout)}, catalogIndexName, nil
+}
+
+// SyncCatalog downloads a catalog, checks the signature of its index and the checksums of
+// its files, and validates each template like a contribution to the shipped ones. Templates
+// with errors are left out and listed in the result. The templates of the last sync stay
+// available until a sync succeeds.
+func (s *Service) SyncCatalog(ctx context.Context, source CatalogSource) (*CatalogSyncResult, error) {
+	if s.catalogDir == "" {
+		return nil, fmt.Errorf("template catalogs are not enabled")
+	}
+	if err := ValidateCatalogSource(source); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.catalogDir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+	workDir, err := os.MkdirTemp(s.catalogDir, "."+source.Name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+	defer os.RemoveAll(workDir) //nolint:errcheck // Cleanup, error not critical
+
+	reader, indexName, err := openCatalogSource(ctx, source.URL, workDir)
+	if err != nil {
+		return nil, err
+	}
+	index, err := reader.read(indexName, maxCatalogIndexSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog index: %w", err)
+	}
+	var signature []byte
+	if source.PublicKey != "" {
+		if signature, err = reader.read(indexName+".sig", 1024); err != nil {
+			return nil, fmt.Errorf("failed to read catalog signature: %w", err)
+		}
+		if err := verifyCatalogSignature(index, signature, source.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+	var catalog CatalogIndex
+	if err := json.Unmarshal(index, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid catalog index: %w", err)
+	}
+
+	synced := filepath.Join(workDir, "synced")
+	if err := writeCatalogFile(synced, catalogIndexName, index); err != nil {
+		return nil, err
+	}
+	if signature != nil {
+		if err := writeCatalogFile(synced, catalogIndexName+".sig", signature); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &CatalogSyncResult{Skipped: []string{}}
+	seen := map[string]bool{}
+	for _, entry := range catalog.Templates {
+		if seen[entry.Dir] {
+			result.Skipped = append(result.Skipped, entry.Dir+": listed twice")
+			continue
+		}
+		seen[entry.Dir] = true
+		bundle, err := fetchCatalogBundle(reader, entry)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", entry.Dir, err))
+			continue
+		}
+		if report := ValidateBundle(bundle); !report.Valid {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %s", entry.Dir, report.Issues[0].Message))
+			continue
+		}
+		for name, content := range bundle.Files {
+			if err := writeCatalogFile(filepath.Join(synced, entry.Dir), name, []byte(content)); err != nil {
+				return nil, err
+			}
+		}
+		result.Templates++
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	target := filepath.Join(s.catalogDir, source.Name)
+	if err := os.RemoveAll(target); err != nil {
+		return nil, fmt.Errorf("failed to replace catalog: %w", err)
+	}
+	if err := os.Rename(synced, target); err != nil {
+		return nil, fmt.Errorf("failed to replace catalog: %w", err)
+	}
+	return result, nil
+}
+
+// fetchCatalogBundle downloads the files of a catalog entry and checks their checksums
+func fetchCatalogBundle(reader catalogReader, entry CatalogEntry) (*Bundle, error) {
+	if !templateIDPattern.MatchString(entry.Dir) {
+		return nil, fmt.Errorf("invalid directory name")
+	}
+	if _, ok := entry.Files["template.json"]; !ok {
+		return nil, fmt.Errorf("template.json is not listed")
+	}
+
+	bundle := &Bundle{Dir: entry.Dir, Files: map[string]string{}}
+	fetch := func(name string) error {
+		data, err := reader.read(entry.Dir+"/"+name, maxCatalogFileSize)
+		if err != nil {
+			return err
+		}
+		if err := verifyChecksum(name, data, entry.Files[name]); err != nil {
+			return err
+		}
+		bundle.Files[name] = string(data)
+		return nil
+	}
+	if err := fetch("template.json"); err != nil {
+		return nil, err
+	}
+	allowed := append(slices.Clone(bundleFiles), bundle.composeFilename())
+	for _, name := range slices.Sorted(maps.Keys(entry.Files)) {
+		if name == "template.json" {
+			continue
+		}
+		if !slices.Contains(allowed, name) || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("unexpected file %s", name)
+		}
+		if err := fetch(name); err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
+
+// writeCatalogFile writes a file of a synced catalog
+func writeCatalogFile(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return fmt.Errorf("failed to write catalog file %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemoveCatalog deletes the synced templates of a catalog
+func (s *Service) RemoveCatalog(name string) error {
+	if s.catalogDir == "" || !templateIDPattern.MatchString(name) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.RemoveAll(filepath.Join(s.catalogDir, name))
+}
+
+// syncedCatalog is a catalog as kept by the last successful sync
+type syncedCatalog struct {
+	name  string
+	fsys  fs.FS
+	index CatalogIndex
+}
+
+// openSyncedCatalog reads the synced index of a catalog and checks its signature again, a
+// changed key makes the templates unavailable until the next sync. Callers hold s.mu.
+func (s *Service) openSyncedCatalog(source CatalogSource) (*syncedCatalog, error) {
+	dir := filepath.Join(s.catalogDir, source.Name)
+	index, err := os.ReadFile(filepath.Join(dir, catalogIndexName)) //nolint:gosec // Catalog names are validated
+	if err != nil {
+		return nil, err
+	}
+	if source.PublicKey != "" {
+		signature, err := os.ReadFile(filepath.Join(dir, catalogIndexName+".sig")) //nolint:gosec // Catalog names are validated
+		if err != nil {
+			return nil, ErrBadSignature
+		}
+		if err := verifyCatalogSignature(index, signature, source.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+	catalog := &syncedCatalog{name: source.Name, fsys: os.DirFS(dir)}
+	if err := json.Unmarshal(index, &catalog.index); err != nil {
+		return nil, fmt.Errorf("invalid catalog index: %w", err)
+	}
+	return catalog, nil
+}
+
+// readFile reads a file of a template of the catalog and checks it against the index
+func (c *syncedCatalog) readFile(dir, name string) ([]byte, error) {
+	for _, entry := range c.index.Templates {
+		if entry.Dir != dir {
+			continue
+		}
+		checksum, ok := entry.Files[name]
+		if !ok {
+			break
+		}
+		data, err := fs.ReadFile(c.fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyChecksum(name, data, checksum); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s/%s: %w", dir, name, fs.ErrNotExist)
+}
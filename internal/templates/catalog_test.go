@@ -0,0 +1,138 @@
+package templates
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCatalog serves a JSON catalog with a valid template and one with a wrong checksum
+func testCatalog(t *testing.T, key ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	files := map[string]string{
+		"wiki/template.json":        `{"id": "wiki", "name": "Wiki", "description": "A wiki", "category_tags": ["Knowledge"], "icon": "bi-book", "port": "8090"}`,
+		"wiki/docker-compose.yml":   "services:\n  web:\n    image: nginx:1.27\n    ports:\n      - \"8090:80\"\n",
+		"wiki/.env.example":         "TITLE=Wiki\n",
+		"broken/template.json":      `{"id": "broken", "name": "Broken", "description": "Broken", "icon": "bi-bug"}`,
+		"broken/docker-compose.yml": "services:\n  web:\n    image: nginx:1.27\n",
+	}
+	sum := func(content string) string {
+		s := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(s[:])
+	}
+	index := CatalogIndex{Templates: []CatalogEntry{
+		{Dir: "wiki", Files: map[string]string{}},
+		{Dir: "broken", Files: map[string]string{"template.json": sum(files["broken/template.json"]), "docker-compose.yml": sum("something else")}},
+	}}
+	for _, name := range []string{"template.json", "docker-compose.yml", ".env.example"} {
+		index.Templates[0].Files[name] = sum(files["wiki/"+name])
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["catalog.json"] = string(data)
+	files["catalog.json.sig"] = base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/apps/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+}
+
+func TestSyncCatalog(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := testCatalog(t, privateKey)
+	defer server.Close()
+
+	svc := NewService(".")
+	svc.SetCatalogDir(t.TempDir())
+	source := CatalogSource{Name: "community", URL: server.URL + "/apps/catalog.json", PublicKey: base64.StdEncoding.EncodeToString(publicKey)}
+	svc.SetCatalogs([]CatalogSource{source})
+
+	result, err := svc.SyncCatalog(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Templates != 1 || len(result.Skipped) != 1 || !strings.Contains(result.Skipped[0], "checksum mismatch") {
+		t.Fatalf("result = %+v, want wiki synced and broken skipped", result)
+	}
+
+	template, err := svc.GetTemplateByID("community/wiki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.Catalog != "community" || template.Name != "Wiki" {
+		t.Errorf("template = %+v", template)
+	}
+	if content, err := svc.GetTemplateContent(template); err != nil || !strings.Contains(content, "nginx:1.27") {
+		t.Errorf("GetTemplateContent = %q, %v", content, err)
+	}
+	if env, err := svc.GetTemplateEnvExample(template.ID); err != nil || env != "TITLE=Wiki\n" {
+		t.Errorf("GetTemplateEnvExample = %q, %v", env, err)
+	}
+
+	// Files changed after the sync are refused before install
+	composePath := filepath.Join(svc.catalogDir, "community", "wiki", "docker-compose.yml")
+	if err := os.WriteFile(composePath, []byte("services:\n  web:\n    image: evil\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetTemplateContent(template); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("GetTemplateContent of a changed file = %v, want a checksum mismatch", err)
+	}
+
+	// Another key refuses the index, and the synced templates disappear
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	source.PublicKey = base64.StdEncoding.EncodeToString(otherKey)
+	if _, err := svc.SyncCatalog(context.Background(), source); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("SyncCatalog with another key = %v, want a bad signature", err)
+	}
+	svc.SetCatalogs([]CatalogSource{source})
+	if _, err := svc.GetTemplateByID("community/wiki"); err == nil {
+		t.Error("template of a catalog with another key is still offered")
+	}
+
+	if err := svc.RemoveCatalog("community"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(svc.catalogDir, "community")); !os.IsNotExist(err) {
+		t.Errorf("catalog not removed: %v", err)
+	}
+}
+
+func TestValidateCatalogSource(t *testing.T) {
+	for _, tc := range []struct {
+		source CatalogSource
+		valid  bool
+	}{
+		{CatalogSource{Name: "community", URL: "https://apps.example.com/catalog.json"}, true},
+		{CatalogSource{Name: "community", URL: "https://github.com/example/apps.git"}, true},
+		{CatalogSource{Name: "community", URL: "git@github.com:example/apps.git"}, true},
+		{CatalogSource{Name: "community", URL: "ssh://git@example.com/apps.git"}, true},
+		{CatalogSource{Name: "community", URL: "ssh://example.com/catalog.json"}, false},
+		{CatalogSource{Name: "community", URL: "file:///srv/apps.git"}, false},
+		{CatalogSource{Name: "Community Apps", URL: "https://apps.example.com/catalog.json"}, false},
+		{CatalogSource{Name: "community", URL: "https://apps.example.com/catalog.json", PublicKey: "not a key"}, false},
+	} {
+		if err := ValidateCatalogSource(tc.source); (err == nil) != tc.valid {
+			t.Errorf("ValidateCatalogSource(%+v) = %v, want valid %v", tc.source, err, tc.valid)
+		}
+	}
+}
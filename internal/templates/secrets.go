@@ -0,0 +1,101 @@
+package templates
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxSecretLength limits generated secrets to a sane size
+const maxSecretLength = 256
+
+// passwordAlphabet avoids characters that need quoting in YAML, .env files or URLs
+const passwordAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+var (
+	// secretPlaceholder matches {{ randomPassword 32 }} and {{ randomHex 16 }}
+	secretPlaceholder = regexp.MustCompile(`\{\{\s*(randomPassword|randomHex)\s+(\d+)\s*\}\}`)
+	// secretName finds the upper-case variable a placeholder is assigned to, in .env lines ("KEY=...")
+	// and compose environments ("- KEY=..." or "KEY: ...")
+	secretName = regexp.MustCompile(`^\s*(?:-\s*)?([A-Z_][A-Z0-9_]*)\s*[=:]`)
+)
+
+// Secrets are the values generated for an app, by variable name
+type Secrets map[string]string
+
+// ResolveSecrets replaces the secret placeholders of a template file with random values:
+//
+//	{{ randomPassword 32 }}  32 letters and digits
+//	{{ randomHex 16 }}       16 random bytes as 32 hex digits, like openssl rand -hex 16
+//
+// Values are recorded in secrets under the variable they are assigned to. A variable that
+// already has a value reuses it, so a password in .env.example and the default of the
+// same variable in docker-compose.yml match. Placeholders outside an assignment are named
+// SECRET_1, SECRET_2 and so on.
+func ResolveSecrets(content string, secrets Secrets) (string, error) {
+	if !secretPlaceholder.MatchString(content) {
+		return content, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		var resolveErr error
+		name := ""
+		if m := secretName.FindStringSubmatch(line); m != nil {
+			name = m[1]
+		}
+		lines[i] = secretPlaceholder.ReplaceAllStringFunc(line, func(placeholder string) string {
+			if name != "" {
+				if value, ok := secrets[name]; ok {
+					return value
+				}
+			}
+			m := secretPlaceholder.FindStringSubmatch(placeholder)
+			value, err := generateSecret(m[1], m[2])
+			if err != nil {
+				resolveErr = err
+				return placeholder
+			}
+			key := name
+			if key == "" {
+				key = fmt.Sprintf("SECRET_%d", len(secrets)+1)
+			}
+			secrets[key] = value
+			return value
+		})
+		if resolveErr != nil {
+			return "", fmt.Errorf("line %d: %w", i+1, resolveErr)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func generateSecret(kind, length string) (string, error) {
+	n, err := strconv.Atoi(length)
+	if err != nil || n < 1 || n > maxSecretLength {
+		return "", fmt.Errorf("%s length must be between 1 and %d", kind, maxSecretLength)
+	}
+
+	if kind == "randomHex" {
+		buf := make([]byte, n)
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to generate secret: %w", err)
+		}
+		return hex.EncodeToString(buf), nil
+	}
+
+	var sb strings.Builder
+	limit := big.NewInt(int64(len(passwordAlphabet)))
+	for range n {
+		idx, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate secret: %w", err)
+		}
+		sb.WriteByte(passwordAlphabet[idx.Int64()])
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	secrets := Secrets{}
+
+	env, err := ResolveSecrets("DB_PASSWORD={{ randomPassword 24 }}\nAPI_KEY={{randomHex 16}}\nPLAIN=value\n", secrets)
+	if err != nil {
+		t.Fatalf("ResolveSecrets() error = %v", err)
+	}
+	if strings.Contains(env, "{{") {
+		t.Fatalf("placeholders left in %q", env)
+	}
+	if !regexp.MustCompile(`^[A-Za-z0-9]{24}$`).MatchString(secrets["DB_PASSWORD"]) {
+		t.Errorf("DB_PASSWORD = %q", secrets["DB_PASSWORD"])
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(secrets["API_KEY"]) {
+		t.Errorf("API_KEY = %q", secrets["API_KEY"])
+	}
+	if !strings.Contains(env, "PLAIN=value") {
+		t.Errorf("unrelated lines changed: %q", env)
+	}
+
+	// The compose file reuses the values of the same variables
+	compose, err := ResolveSecrets("    environment:\n      - POSTGRES_PASSWORD=x\n      - DB_PASSWORD=${DB_PASSWORD:-{{ randomPassword 24 }}}\n      SESSION: \"{{ randomHex 8 }}\"\n    command: --key {{ randomHex 4 }}\n", secrets)
+	if err != nil {
+		t.Fatalf("ResolveSecrets() error = %v", err)
+	}
+	if !strings.Contains(compose, "${DB_PASSWORD:-"+secrets["DB_PASSWORD"]+"}") {
+		t.Errorf("DB_PASSWORD not reused: %q", compose)
+	}
+	if len(secrets["SESSION"]) != 16 {
+		t.Errorf("SESSION = %q", secrets["SESSION"])
+	}
+	if len(secrets["SECRET_4"]) != 8 {
+		t.Errorf("unnamed secret not recorded: %v", secrets)
+	}
+}
+
+func TestResolveSecretsInvalidLength(t *testing.T) {
+	for _, content := range []string{"A={{ randomPassword 0 }}", "A={{ randomHex 1000 }}"} {
+		if _, err := ResolveSecrets(content, Secrets{}); err == nil {
+			t.Errorf("ResolveSecrets(%q) should fail", content)
+		}
+	}
+}
+
+func TestResolveSecretsUnique(t *testing.T) {
+	a, _ := ResolveSecrets("A={{ randomPassword 32 }}", Secrets{})
+	b, _ := ResolveSecrets("A={{ randomPassword 32 }}", Secrets{})
+	if a == b {
+		t.Error("two installs generated the same password")
+	}
+}
@@ -3,11 +3,13 @@ package templates
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
+	"sync"
 
 	"github.com/ontree-co/treeos/internal/config"
 	"github.com/ontree-co/treeos/internal/embeds"
@@ -26,32 +28,100 @@ type Template struct {
 	Port             string   `json:"port"`
 	DocumentationURL string   `json:"documentation_url"`
 	IsSystemService  bool     `json:"is_system_service,omitempty"`
+	StorageClass     string   `json:"storage_class,omitempty"` // "fast" or "bulk" storage for the app's mnt data
+	Catalog          string   `json:"catalog,omitempty"`       // Remote catalog of the template, "" for shipped ones
+	Architectures    []string `json:"architectures,omitempty"` // CPU architectures the images exist for, see Architectures
+	// Variants replaces service images on an architecture, for apps that publish an image per
+	// architecture instead of a multi-arch one: architecture, then service, then image
+	Variants map[string]map[string]string `json:"variants,omitempty"`
+	// Variables are asked for when an app is created, see Variable
+	Variables []Variable `json:"variables,omitempty"`
 }
 
 // Service provides template management functionality
 type Service struct {
 	templatesPath string
+	arch          string // Architecture template content is selected for
+	catalogDir    string // Synced remote catalogs, "" without catalogs
+
+	mu       sync.RWMutex    // Held while catalogs are read or replaced
+	catalogs []CatalogSource // Enabled catalogs, in the order their templates are listed
 }
 
 // NewService creates a new template service instance
 func NewService(templatesPath string) *Service {
 	return &Service{
 		templatesPath: templatesPath,
+		arch:          HostArchitecture(),
 	}
 }
 
-// GetAvailableTemplates returns all available application templates
+// Architecture returns the architecture the service selects template images for
+func (s *Service) Architecture() string {
+	return s.arch
+}
+
+// SetCatalogDir enables remote catalogs, which are synced into dir
+func (s *Service) SetCatalogDir(dir string) {
+	s.catalogDir = dir
+}
+
+// SetCatalogs sets the enabled remote catalogs whose synced templates are offered
+func (s *Service) SetCatalogs(sources []CatalogSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catalogs = sources
+}
+
+// GetAvailableTemplates returns all available application templates, the shipped ones
+// first. Templates of remote catalogs have the catalog name as prefix of their ID.
 func (s *Service) GetAvailableTemplates() ([]Template, error) {
 	templateFS, err := embeds.AppTemplateFS()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get template filesystem: %w", err)
 	}
 
-	entries, err := fs.ReadDir(templateFS, s.templatesPath)
+	templates, err := readTemplates(templateFS, s.templatesPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list templates directory: %w", err)
 	}
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, source := range s.catalogs {
+		if s.catalogDir == "" {
+			break
+		}
+		catalog, err := s.openSyncedCatalog(source)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Not synced yet
+		}
+		if err != nil {
+			logging.Warnf("Skipping templates of catalog %s: %v", source.Name, err)
+			continue
+		}
+		catalogTemplates, err := readTemplates(catalog.fsys, ".")
+		if err != nil {
+			logging.Warnf("Skipping templates of catalog %s: %v", source.Name, err)
+			continue
+		}
+		for _, tmpl := range catalogTemplates {
+			tmpl.ID = source.Name + "/" + tmpl.ID
+			tmpl.Catalog = source.Name
+			templates = append(templates, tmpl)
+		}
+	}
+
+	return templates, nil
+}
+
+// readTemplates reads the template.json of each template directory below root
+func readTemplates(fsys fs.FS, root string) ([]Template, error) {
+	entries, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
 	templates := make([]Template, 0, len(entries))
 	for _, entry := range entries {
 		if !entry.IsDir() {
@@ -59,10 +129,10 @@ func (s *Service) GetAvailableTemplates() ([]Template, error) {
 		}
 
 		dirName := entry.Name()
-		jsonPath := filepath.Join(s.templatesPath, dirName, "template.json")
+		jsonPath := path.Join(root, dirName, "template.json")
 		logging.Debugf("Looking for template metadata at: %s", jsonPath)
 
-		data, err := fs.ReadFile(templateFS, jsonPath)
+		data, err := fs.ReadFile(fsys, jsonPath)
 		if err != nil {
 			// Skip directories without template.json
 			logging.Debugf("Skipping %s (no template.json)", dirName)
@@ -85,6 +155,7 @@ func (s *Service) GetAvailableTemplates() ([]Template, error) {
 		if len(tmpl.CategoryTags) == 0 && tmpl.Category != "" {
 			tmpl.CategoryTags = []string{tmpl.Category}
 		}
+		tmpl.Catalog = ""
 
 		templates = append(templates, tmpl)
 	}
@@ -108,41 +179,66 @@ func (s *Service) GetTemplateByID(id string) (*Template, error) {
 	return nil, fmt.Errorf("template with id %s not found", id)
 }
 
-// GetTemplateContent reads the docker-compose.yml content for a template
+// GetTemplateContent reads the docker-compose.yml content for a template with the images of
+// the host's architecture. Templates without images for it fail with ErrUnsupportedArchitecture
+// before anything is pulled.
 func (s *Service) GetTemplateContent(template *Template) (string, error) {
-	templateFS, err := embeds.AppTemplateFS()
+	images, err := template.ImagesFor(s.arch)
 	if err != nil {
-		return "", fmt.Errorf("failed to get template filesystem: %w", err)
+		return "", err
 	}
 
-	yamlPath := filepath.Join(s.templatesPath, template.ID, template.Filename)
-	content, err := fs.ReadFile(templateFS, yamlPath)
+	content, err := s.readTemplateFile(template.ID, template.Filename)
 	if err != nil {
 		return "", fmt.Errorf("failed to read template file %s: %w", template.Filename, err)
 	}
 
-	return string(content), nil
+	if len(images) == 0 {
+		return string(content), nil
+	}
+	return ApplyVariant(string(content), images)
 }
 
 // GetTemplateEnvExample reads the .env.example file for a template if it exists
 // Returns empty string (not an error) if the .env.example file doesn't exist
 func (s *Service) GetTemplateEnvExample(templateID string) (string, error) {
-	templateFS, err := embeds.AppTemplateFS()
+	content, err := s.readTemplateFile(templateID, ".env.example")
+	if errors.Is(err, fs.ErrNotExist) {
+		// File doesn't exist - this is normal for templates without .env.example
+		return "", nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get template filesystem: %w", err)
+		return "", err
 	}
 
-	// .env.example lives inside the template directory
-	envExamplePath := filepath.Join(s.templatesPath, templateID, ".env.example")
+	return string(content), nil
+}
 
-	// Try to read the file - if it doesn't exist, return empty string (not an error)
-	content, err := fs.ReadFile(templateFS, envExamplePath)
-	if err != nil {
-		// File doesn't exist - this is normal for templates without .env.example
-		return "", nil
+// readTemplateFile reads a file of a template. Files of catalog templates are checked
+// against the signed checksums of their catalog before they are used.
+func (s *Service) readTemplateFile(templateID, name string) ([]byte, error) {
+	catalogName, dir, fromCatalog := strings.Cut(templateID, "/")
+	if !fromCatalog {
+		templateFS, err := embeds.AppTemplateFS()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get template filesystem: %w", err)
+		}
+		return fs.ReadFile(templateFS, path.Join(s.templatesPath, templateID, name))
 	}
 
-	return string(content), nil
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, source := range s.catalogs {
+		if source.Name != catalogName || s.catalogDir == "" {
+			continue
+		}
+		catalog, err := s.openSyncedCatalog(source)
+		if err != nil {
+			return nil, err
+		}
+		return catalog.readFile(dir, name)
+	}
+	return nil, fmt.Errorf("catalog %s: %w", catalogName, fs.ErrNotExist)
 }
 
 // ProcessTemplateContent replaces template variables with actual values
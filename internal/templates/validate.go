@@ -0,0 +1,439 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/security"
+	"github.com/ontree-co/treeos/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Checks a validation issue belongs to
+const (
+	CheckSchema   = "schema"
+	CheckSecurity = "security"
+	CheckArch     = "arch"
+)
+
+// Issue severities. Errors make a bundle invalid, warnings don't.
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+)
+
+// Architectures are the CPU architectures templates can declare, as Docker names them
+var Architectures = []string{"amd64", "arm64", "armv7"}
+
+// bundleFiles are the files of a template directory the validator reads besides the compose file
+var bundleFiles = []string{"template.json", ".env.example", "app.yml.example"}
+
+var (
+	// templateIDPattern matches template IDs, which become directory and app names
+	templateIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	// placeholderPattern matches the placeholders ProcessTemplateContent replaces
+	placeholderPattern = regexp.MustCompile(`\{\{([A-Za-z_]+)\}\}`)
+	// composeVariablePattern matches ${VAR}, ${VAR:-default} and friends
+	composeVariablePattern = regexp.MustCompile(`(\$?)\$\{([A-Za-z_][A-Za-z0-9_]*)(:?[-?+][^}]*)?\}`)
+)
+
+// knownPlaceholders are replaced by ProcessTemplateContent
+var knownPlaceholders = []string{"APP_VOLUMES_PATH", "APP_MNT_PATH", "SHARED_OLLAMA_PATH", "SHARED_PATH", "APP_NAME"}
+
+// Bundle is a template directory as contributed to the catalog
+type Bundle struct {
+	Dir   string            `json:"dir"`   // Directory name the template ID has to match, empty skips the check
+	Files map[string]string `json:"files"` // File contents by name, e.g. template.json and docker-compose.yml
+}
+
+// Issue is one problem the validator found in a bundle
+type Issue struct {
+	Check    string `json:"check"`    // CheckSchema, CheckSecurity or CheckArch
+	Severity string `json:"severity"` // IssueError or IssueWarning
+	File     string `json:"file"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the machine-readable result of validating a bundle
+type ValidationReport struct {
+	Template string           `json:"template"`
+	Valid    bool             `json:"valid"` // No errors, warnings are allowed
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Issues   []Issue          `json:"issues"`
+	Security *security.Report `json:"security,omitempty"` // Findings of the compose file, also the allowed ones
+}
+
+// ReadBundle reads a template directory, with the compose file its template.json names
+func ReadBundle(fsys fs.FS, dir string) (*Bundle, error) {
+	bundle := &Bundle{Dir: path.Base(dir), Files: map[string]string{}}
+	for _, name := range bundleFiles {
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err == nil {
+			bundle.Files[name] = string(data)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+
+	composeFile := bundle.composeFilename()
+	data, err := fs.ReadFile(fsys, path.Join(dir, composeFile))
+	if err == nil {
+		bundle.Files[composeFile] = string(data)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", composeFile, err)
+	}
+	return bundle, nil
+}
+
+// composeFilename returns the compose file template.json names, docker-compose.yml by default
+func (b *Bundle) composeFilename() string {
+	var tmpl Template
+	if err := json.Unmarshal([]byte(b.Files["template.json"]), &tmpl); err == nil && tmpl.Filename != "" {
+		return tmpl.Filename
+	}
+	return "docker-compose.yml"
+}
+
+// bundleValidation collects the issues of one bundle
+type bundleValidation struct {
+	report *ValidationReport
+}
+
+func (v *bundleValidation) add(check, severity, file, field, message string) {
+	v.report.Issues = append(v.report.Issues, Issue{Check: check, Severity: severity, File: file, Field: field, Message: message})
+}
+
+// ValidateBundle checks a template bundle against the template schema, the security rules
+// apps are created with and its declared architectures. It runs the same code the server
+// uses, so catalog contributions can be checked before they are merged.
+func ValidateBundle(bundle *Bundle) *ValidationReport {
+	v := &bundleValidation{report: &ValidationReport{Template: bundle.Dir, Issues: []Issue{}}}
+
+	tmpl, ok := v.validateMetadata(bundle)
+	if ok {
+		v.report.Template = tmpl.ID
+		v.validateCompose(bundle, tmpl)
+	}
+
+	sort.SliceStable(v.report.Issues, func(i, j int) bool {
+		return v.report.Issues[i].Severity == IssueError && v.report.Issues[j].Severity != IssueError
+	})
+	for _, issue := range v.report.Issues {
+		if issue.Severity == IssueError {
+			v.report.Errors++
+		} else {
+			v.report.Warnings++
+		}
+	}
+	v.report.Valid = v.report.Errors == 0
+	return v.report
+}
+
+// validateMetadata checks template.json. It reports false if the rest can't be checked.
+func (v *bundleValidation) validateMetadata(bundle *Bundle) (*Template, bool) {
+	const file = "template.json"
+	data, ok := bundle.Files[file]
+	if !ok {
+		v.add(CheckSchema, IssueError, file, "", "template.json is missing")
+		return nil, false
+	}
+	var tmpl Template
+	if err := json.Unmarshal([]byte(data), &tmpl); err != nil {
+		v.add(CheckSchema, IssueError, file, "", "invalid JSON: "+err.Error())
+		return nil, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &fields); err == nil {
+		known := templateFields()
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			if !known[name] {
+				v.add(CheckSchema, IssueWarning, file, name, "unknown field, TreeOS ignores it")
+			}
+		}
+	}
+	if tmpl.Filename == "" {
+		tmpl.Filename = "docker-compose.yml"
+	}
+
+	switch {
+	case tmpl.ID == "":
+		v.add(CheckSchema, IssueError, file, "id", "id is required")
+	case !templateIDPattern.MatchString(tmpl.ID):
+		v.add(CheckSchema, IssueError, file, "id", "id may only contain lowercase letters, digits and dashes")
+	case bundle.Dir != "" && bundle.Dir != tmpl.ID:
+		v.add(CheckSchema, IssueError, file, "id", fmt.Sprintf("id %q does not match the directory %q", tmpl.ID, bundle.Dir))
+	}
+	if strings.TrimSpace(tmpl.Name) == "" {
+		v.add(CheckSchema, IssueError, file, "name", "name is required")
+	}
+	if strings.TrimSpace(tmpl.Description) == "" {
+		v.add(CheckSchema, IssueError, file, "description", "description is required")
+	}
+	if port, err := strconv.Atoi(tmpl.Port); tmpl.Port != "" && (err != nil || port < 1 || port > 65535) {
+		v.add(CheckSchema, IssueError, file, "port", fmt.Sprintf("port %q is not a port number", tmpl.Port))
+	}
+	if _, err := storage.ParseClass(tmpl.StorageClass); err != nil {
+		v.add(CheckSchema, IssueError, file, "storage_class", err.Error())
+	}
+	if tmpl.Filename != path.Base(tmpl.Filename) {
+		v.add(CheckSchema, IssueError, file, "filename", "filename must name a file in the template directory")
+		return &tmpl, false
+	}
+
+	if !strings.HasPrefix(tmpl.Icon, "bi-") {
+		v.add(CheckSchema, IssueWarning, file, "icon", "icon should be a Bootstrap icon class such as bi-box")
+	}
+	if len(tmpl.CategoryTags) == 0 && tmpl.Category == "" {
+		v.add(CheckSchema, IssueWarning, file, "category_tags", "templates without category tags are listed under Others")
+	}
+	if u, err := url.Parse(tmpl.DocumentationURL); tmpl.DocumentationURL == "" || err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		v.add(CheckSchema, IssueWarning, file, "documentation_url", "documentation_url should link to the app's documentation")
+	}
+
+	if len(tmpl.Architectures) == 0 {
+		v.add(CheckArch, IssueWarning, file, "architectures", "architectures is not declared, the template is offered on every node")
+	}
+	for _, arch := range tmpl.Architectures {
+		if !slices.Contains(Architectures, arch) {
+			v.add(CheckArch, IssueError, file, "architectures", fmt.Sprintf("unknown architecture %q, expected one of %s", arch, strings.Join(Architectures, ", ")))
+		}
+	}
+	v.validateVariableDefinitions(tmpl.Variables)
+	return &tmpl, true
+}
+
+// validateVariableDefinitions checks the variables a template asks for
+func (v *bundleValidation) validateVariableDefinitions(vars []Variable) {
+	const file = "template.json"
+	seen := map[string]bool{}
+	for i, variable := range vars {
+		field := fmt.Sprintf("variables[%d]", i)
+		switch {
+		case !variableNamePattern.MatchString(variable.Name):
+			v.add(CheckSchema, IssueError, file, field+".name", fmt.Sprintf("variable name %q must be upper case letters, digits and underscores", variable.Name))
+		case seen[variable.Name]:
+			v.add(CheckSchema, IssueError, file, field+".name", fmt.Sprintf("variable %s is declared twice", variable.Name))
+		}
+		seen[variable.Name] = true
+
+		if !slices.Contains(VariableTypes, variable.Kind()) {
+			v.add(CheckSchema, IssueError, file, field+".type", fmt.Sprintf("unknown type %q, expected one of %s", variable.Type, strings.Join(VariableTypes, ", ")))
+			continue
+		}
+		if variable.Kind() == VariableSelect && len(variable.Options) == 0 {
+			v.add(CheckSchema, IssueError, file, field+".options", "a select needs options")
+		}
+		if variable.Length < 0 || variable.Length > maxSecretLength {
+			v.add(CheckSchema, IssueError, file, field+".length", fmt.Sprintf("length must be at most %d", maxSecretLength))
+		}
+		if _, err := regexp.Compile(variable.Pattern); err != nil {
+			v.add(CheckSchema, IssueError, file, field+".pattern", "invalid pattern: "+err.Error())
+		} else if err := variable.Check(variable.Default); err != nil {
+			v.add(CheckSchema, IssueError, file, field+".default", "invalid default: "+err.Error())
+		}
+	}
+}
+
+// sampleVariableValues returns a valid value for each variable, to check the template files
+// as they are after rendering
+func sampleVariableValues(vars []Variable) map[string]string {
+	samples := map[string]string{
+		VariableString:   "value",
+		VariablePort:     "8080",
+		VariablePassword: "password",
+		VariablePath:     "/data",
+		VariableDomain:   "app.example.com",
+		VariableEmail:    "admin@example.com",
+		VariableNumber:   "1",
+		VariableBoolean:  "true",
+	}
+	values := map[string]string{}
+	for _, variable := range vars {
+		switch {
+		case variable.Default != "":
+			values[variable.Name] = variable.Default
+		case variable.Kind() == VariableSelect && len(variable.Options) > 0:
+			values[variable.Name] = variable.Options[0]
+		default:
+			values[variable.Name] = samples[variable.Kind()]
+		}
+	}
+	return values
+}
+
+// composeService is the part of a service the bundle validator looks at
+type composeService struct {
+	Image    string        `yaml:"image"`
+	Build    interface{}   `yaml:"build"`
+	Platform string        `yaml:"platform"`
+	Ports    []interface{} `yaml:"ports"`
+}
+
+// validateCompose checks the compose file of the bundle
+func (v *bundleValidation) validateCompose(bundle *Bundle, tmpl *Template) {
+	file := tmpl.Filename
+	content, ok := bundle.Files[file]
+	if !ok {
+		v.add(CheckSchema, IssueError, file, "", file+" is missing")
+		return
+	}
+
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		if !slices.Contains(knownPlaceholders, match[1]) {
+			v.add(CheckSchema, IssueError, file, "", fmt.Sprintf("unknown placeholder %s, expected one of %s", match[0], strings.Join(knownPlaceholders, ", ")))
+		}
+	}
+
+	values := sampleVariableValues(tmpl.Variables)
+	for _, name := range []string{file, ".env.example"} {
+		if _, err := RenderVariables(bundle.Files[name], values); err != nil {
+			v.add(CheckSchema, IssueError, name, "", err.Error())
+		}
+	}
+
+	// Placeholders are replaced first, they aren't valid YAML everywhere
+	rendered, _ := RenderVariables(content, values)
+	processed := NewService(".").ProcessTemplateContent(rendered, tmpl.ID)
+	var compose struct {
+		Services map[string]composeService `yaml:"services"`
+	}
+	if err := yaml.Unmarshal([]byte(processed), &compose); err != nil {
+		v.add(CheckSchema, IssueError, file, "", "invalid YAML: "+err.Error())
+		return
+	}
+	if len(compose.Services) == 0 {
+		v.add(CheckSchema, IssueError, file, "services", "no services defined")
+		return
+	}
+
+	portPublished := tmpl.Port == ""
+	for _, name := range slices.Sorted(maps.Keys(compose.Services)) {
+		service := compose.Services[name]
+		field := "services." + name
+		if service.Image == "" && service.Build == nil {
+			v.add(CheckSchema, IssueError, file, field, "service needs an image or a build")
+		}
+		if service.Image != "" && !strings.Contains(service.Image, "$") && !strings.Contains(path.Base(service.Image), ":") && !strings.Contains(service.Image, "@") {
+			v.add(CheckSchema, IssueWarning, file, field+".image", fmt.Sprintf("image %s has no tag and follows latest", service.Image))
+		}
+		for _, port := range service.Ports {
+			portPublished = portPublished || publishesPort(port, tmpl.Port)
+		}
+		v.validatePlatform(file, field, service.Platform, tmpl.Architectures)
+	}
+	v.validateVariants(file, tmpl, compose.Services)
+	if !portPublished {
+		v.add(CheckSchema, IssueWarning, file, "ports", fmt.Sprintf("no service publishes the port %s of template.json", tmpl.Port))
+	}
+
+	v.validateVariables(bundle, tmpl, file, content)
+
+	validator := security.NewValidator(tmpl.ID)
+	if err := validator.ValidateCompose([]byte(processed)); err != nil {
+		v.add(CheckSecurity, IssueError, file, "", err.Error())
+	}
+	if report, err := validator.Report([]byte(processed)); err == nil {
+		v.report.Security = report
+	}
+}
+
+// publishesPort reports whether a compose port entry publishes port on the host, directly
+// or as the default of a variable such as "${PORT:-8080}:80"
+func publishesPort(entry interface{}, port string) bool {
+	switch entry := entry.(type) {
+	case string:
+		i := strings.LastIndex(entry, ":")
+		if i < 0 {
+			return false
+		}
+		host := entry[:i]
+		return host == port || strings.HasSuffix(host, ":"+port) || strings.HasSuffix(host, "-"+port+"}")
+	case map[string]interface{}:
+		return fmt.Sprint(entry["published"]) == port
+	}
+	return false
+}
+
+// templateFields returns the template.json fields TreeOS reads
+func templateFields() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(Template{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	return fields
+}
+
+// validatePlatform checks that a service pinned to a platform runs on the declared architectures
+func (v *bundleValidation) validatePlatform(file, field, platform string, architectures []string) {
+	if platform == "" {
+		return
+	}
+	osName, arch := platformArchitecture(platform)
+	switch {
+	case osName != "linux" || !slices.Contains(Architectures, arch):
+		v.add(CheckArch, IssueError, file, field+".platform", fmt.Sprintf("unsupported platform %q", platform))
+	case len(architectures) == 0:
+		v.add(CheckArch, IssueWarning, file, field+".platform", fmt.Sprintf("service is pinned to %s, declare it in architectures", platform))
+	case !slices.Contains(architectures, arch):
+		v.add(CheckArch, IssueError, file, field+".platform", fmt.Sprintf("service is pinned to %s, which is not one of the declared architectures", platform))
+	}
+}
+
+// validateVariants checks that the image variants of a template replace images of existing
+// services on declared architectures
+func (v *bundleValidation) validateVariants(file string, tmpl *Template, services map[string]composeService) {
+	for _, arch := range slices.Sorted(maps.Keys(tmpl.Variants)) {
+		field := "variants." + arch
+		if !slices.Contains(tmpl.Architectures, arch) {
+			v.add(CheckArch, IssueError, "template.json", field, fmt.Sprintf("variant for %s, which is not one of the declared architectures", arch))
+		}
+		images := tmpl.Variants[arch]
+		for _, name := range slices.Sorted(maps.Keys(images)) {
+			if _, ok := services[name]; !ok {
+				v.add(CheckArch, IssueError, "template.json", field+"."+name, fmt.Sprintf("%s has no service %s", file, name))
+			} else if image := images[name]; image == "" || strings.ContainsAny(image, " \t\r\n") {
+				v.add(CheckArch, IssueError, "template.json", field+"."+name, fmt.Sprintf("invalid image %q", image))
+			}
+		}
+	}
+}
+
+// validateVariables warns about compose variables that have neither a default nor a value in
+// .env.example or a template variable, they are empty after install
+func (v *bundleValidation) validateVariables(bundle *Bundle, tmpl *Template, file, content string) {
+	// Compose sets the project name itself
+	defined := map[string]bool{"COMPOSE_PROJECT_NAME": true}
+	for _, variable := range tmpl.Variables {
+		defined[variable.Name] = true
+	}
+	for _, line := range strings.Split(bundle.Files[".env.example"], "\n") {
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), "="); ok && !strings.HasPrefix(name, "#") {
+			defined[strings.TrimSpace(name)] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, match := range composeVariablePattern.FindAllStringSubmatch(content, -1) {
+		escaped, name, modifier := match[1] != "", match[2], match[3]
+		if escaped || modifier != "" || defined[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		v.add(CheckSchema, IssueWarning, file, "", fmt.Sprintf("${%s} has no default and is not set in .env.example", name))
+	}
+}
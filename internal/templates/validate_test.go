@@ -0,0 +1,175 @@
+package templates
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/embeds"
+)
+
+// knownInvalid lists shipped templates that break a rule, with the reason
+var knownInvalid = map[string]string{
+	"librechat": "mounts the config TreeOS writes for it from ./shared/config, outside the app's volumes and mnt directories",
+}
+
+func TestValidateBundle_ShippedTemplates(t *testing.T) {
+	templateFS, err := embeds.AppTemplateFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := fs.ReadDir(templateFS, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			bundle, err := ReadBundle(templateFS, entry.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := ValidateBundle(bundle)
+			if _, known := knownInvalid[entry.Name()]; !report.Valid && !known {
+				t.Errorf("shipped template is invalid: %+v", report.Issues)
+			}
+		})
+	}
+}
+
+func TestValidateBundle(t *testing.T) {
+	const metadata = `{"id": "web", "name": "Web", "description": "A web server", "icon": "bi-globe",
+		"port": "8080", "category_tags": ["Others"], "documentation_url": "https://example.com",
+		"architectures": ["amd64", "arm64"]}`
+	const compose = `services:
+  web:
+    image: nginx:1.27
+    ports:
+      - "${PORT:-8080}:80"
+    volumes:
+      - {{APP_VOLUMES_PATH}}/html:/usr/share/nginx/html
+`
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantValid bool
+		wantIssue string // Substring of an issue message, empty for none
+	}{
+		{name: "valid", files: map[string]string{"template.json": metadata, "docker-compose.yml": compose}, wantValid: true},
+		{name: "missing metadata", files: map[string]string{"docker-compose.yml": compose}, wantIssue: "template.json is missing"},
+		{
+			name:      "directory mismatch",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `"web"`, `"site"`, 1), "docker-compose.yml": compose},
+			wantIssue: "does not match the directory",
+		},
+		{name: "missing compose", files: map[string]string{"template.json": metadata}, wantIssue: "docker-compose.yml is missing"},
+		{
+			name:      "privileged",
+			files:     map[string]string{"template.json": metadata, "docker-compose.yml": compose + "    privileged: true\n"},
+			wantIssue: "privileged mode",
+		},
+		{
+			name:      "unknown placeholder",
+			files:     map[string]string{"template.json": metadata, "docker-compose.yml": strings.Replace(compose, "APP_VOLUMES_PATH", "APP_DATA", 1)},
+			wantIssue: "unknown placeholder {{APP_DATA}}",
+		},
+		{
+			name:      "unknown architecture",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `"arm64"`, `"riscv64"`, 1), "docker-compose.yml": compose},
+			wantIssue: `unknown architecture "riscv64"`,
+		},
+		{
+			name:      "platform outside architectures",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `, "arm64"`, "", 1), "docker-compose.yml": compose + "    platform: linux/arm64\n"},
+			wantIssue: "not one of the declared architectures",
+		},
+		{
+			name:      "armv7 platform",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `"arm64"`, `"armv7"`, 1), "docker-compose.yml": compose + "    platform: linux/arm/v7\n"},
+			wantValid: true,
+		},
+		{
+			name:      "variant",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `]}`, `], "variants": {"arm64": {"web": "nginx:1.27-arm64"}}}`, 1), "docker-compose.yml": compose},
+			wantValid: true,
+		},
+		{
+			name:      "variant outside architectures",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `]}`, `], "variants": {"armv7": {"web": "nginx:1.27-armv7"}}}`, 1), "docker-compose.yml": compose},
+			wantIssue: "variant for armv7, which is not one of the declared architectures",
+		},
+		{
+			name:      "variant of unknown service",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `]}`, `], "variants": {"arm64": {"db": "postgres:16"}}}`, 1), "docker-compose.yml": compose},
+			wantIssue: "docker-compose.yml has no service db",
+		},
+		{
+			name:      "undefined variable",
+			files:     map[string]string{"template.json": metadata, "docker-compose.yml": compose + "    environment:\n      - TOKEN=${TOKEN}\n"},
+			wantValid: true,
+			wantIssue: "${TOKEN} has no default",
+		},
+		{
+			name: "variable from env example",
+			files: map[string]string{
+				"template.json":      metadata,
+				"docker-compose.yml": compose + "    environment:\n      - TOKEN=${TOKEN}\n",
+				".env.example":       "# Token of the API\nTOKEN=change-me\n",
+			},
+			wantValid: true,
+		},
+		{
+			name: "template variables",
+			files: map[string]string{
+				"template.json":      strings.Replace(metadata, `]}`, `], "variables": [{"name": "PORT", "type": "port", "default": "8080"}, {"name": "TOKEN", "type": "password"}]}`, 1),
+				"docker-compose.yml": strings.Replace(compose, "${PORT:-8080}", "{{ var PORT }}", 1) + "    environment:\n      - TOKEN=${TOKEN}\n",
+			},
+			wantValid: true,
+		},
+		{
+			name:      "undeclared template variable",
+			files:     map[string]string{"template.json": metadata, "docker-compose.yml": strings.Replace(compose, "${PORT:-8080}", "{{ var PORT }}", 1)},
+			wantIssue: "undeclared template variables: PORT",
+		},
+		{
+			name:      "invalid variable default",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `]}`, `], "variables": [{"name": "PORT", "type": "port", "default": "80800"}]}`, 1), "docker-compose.yml": compose},
+			wantIssue: "invalid default",
+		},
+		{
+			name:      "select without options",
+			files:     map[string]string{"template.json": strings.Replace(metadata, `]}`, `], "variables": [{"name": "MODE", "type": "select"}]}`, 1), "docker-compose.yml": compose},
+			wantIssue: "a select needs options",
+		},
+		{
+			name:      "port not published",
+			files:     map[string]string{"template.json": strings.Replace(metadata, "8080", "9090", 1), "docker-compose.yml": compose},
+			wantValid: true,
+			wantIssue: "no service publishes the port 9090",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ValidateBundle(&Bundle{Dir: "web", Files: tt.files})
+			if report.Valid != tt.wantValid {
+				t.Errorf("Valid = %v, want %v: %+v", report.Valid, tt.wantValid, report.Issues)
+			}
+			if tt.wantIssue == "" {
+				if len(report.Issues) > 0 {
+					t.Errorf("expected no issues, got %+v", report.Issues)
+				}
+				return
+			}
+			found := false
+			for _, issue := range report.Issues {
+				found = found || strings.Contains(issue.Message, tt.wantIssue)
+			}
+			if !found {
+				t.Errorf("expected an issue containing %q, got %+v", tt.wantIssue, report.Issues)
+			}
+		})
+	}
+}
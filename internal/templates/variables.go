@@ -0,0 +1,215 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/yamlutil"
+)
+
+// Variable types a template can declare
+const (
+	VariableString   = "string"
+	VariablePort     = "port"
+	VariablePassword = "password"
+	VariablePath     = "path"
+	VariableDomain   = "domain"
+	VariableEmail    = "email"
+	VariableNumber   = "number"
+	VariableBoolean  = "boolean"
+	VariableSelect   = "select"
+)
+
+// VariableTypes are the known variable types, VariableString is the default
+var VariableTypes = []string{VariableString, VariablePort, VariablePassword, VariablePath, VariableDomain, VariableEmail, VariableNumber, VariableBoolean, VariableSelect}
+
+// defaultPasswordLength is the length of generated passwords of variables without a length
+const defaultPasswordLength = 32
+
+var (
+	// variableNamePattern matches variable names, which are also .env keys
+	variableNamePattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+	// variablePlaceholder matches {{ var NAME }} in template files
+	variablePlaceholder = regexp.MustCompile(`\{\{\s*var\s+([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	// domainPattern matches host names such as wiki.example.com
+	domainPattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+)
+
+// Variable is a value a template asks for when an app is created from it. The value is
+// written to the app's .env under Name and replaces {{ var NAME }} in the template files.
+type Variable struct {
+	Name        string   `json:"name"`
+	Label       string   `json:"label,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"` // One of VariableTypes, VariableString by default
+	Default     string   `json:"default,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Options     []string `json:"options,omitempty"` // Choices of a select
+	Pattern     string   `json:"pattern,omitempty"` // Regular expression the whole value has to match
+	Length      int      `json:"length,omitempty"`  // Length of a generated password
+}
+
+// Kind returns the type of the variable, VariableString if none is declared
+func (v Variable) Kind() string {
+	if v.Type == "" {
+		return VariableString
+	}
+	return v.Type
+}
+
+// Title returns the label of the variable, its name if it has none
+func (v Variable) Title() string {
+	if v.Label == "" {
+		return v.Name
+	}
+	return v.Label
+}
+
+// Check returns why value is not valid for the variable, nil if it is. An empty value is
+// valid, Required is checked by ResolveVariables.
+func (v Variable) Check(value string) error {
+	if value == "" {
+		return nil
+	}
+	// The value ends up in YAML and .env files, quoted by the template or not
+	if strings.ContainsAny(value, "\r\n\"'$\\") {
+		return fmt.Errorf("%s may not contain line breaks, quotes, $ or \\", v.Title())
+	}
+
+	switch v.Kind() {
+	case VariablePort:
+		if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("%s must be a port between 1 and 65535", v.Title())
+		}
+	case VariableNumber:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be a whole number", v.Title())
+		}
+	case VariableBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false", v.Title())
+		}
+	case VariableDomain:
+		if len(value) > 253 || !domainPattern.MatchString(value) {
+			return fmt.Errorf("%s must be a domain name such as app.example.com", v.Title())
+		}
+	case VariableEmail:
+		if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+			return fmt.Errorf("%s must be an email address", v.Title())
+		}
+	case VariablePath:
+		if !path.IsAbs(value) || slices.Contains(strings.Split(value, "/"), "..") {
+			return fmt.Errorf("%s must be an absolute path without ..", v.Title())
+		}
+	case VariableSelect:
+		if !slices.Contains(v.Options, value) {
+			return fmt.Errorf("%s must be one of %s", v.Title(), strings.Join(v.Options, ", "))
+		}
+	}
+
+	if v.Pattern != "" {
+		pattern, err := regexp.Compile(`^(?:` + v.Pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("%s has an invalid pattern: %w", v.Title(), err)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("%s does not match %s", v.Title(), v.Pattern)
+		}
+	}
+	return nil
+}
+
+// ResolveVariables returns the values of a template's variables. lookup returns what the
+// user entered; variables left empty get their default, passwords without one are
+// generated. Passwords are also returned as secrets, for the credentials vault. All
+// invalid values are reported together.
+func ResolveVariables(vars []Variable, lookup func(name string) (string, bool)) (map[string]string, Secrets, error) {
+	values := map[string]string{}
+	secrets := Secrets{}
+	var errs []error
+	for _, v := range vars {
+		value, _ := lookup(v.Name)
+		value = strings.TrimSpace(value)
+		if value == "" {
+			value = v.Default
+		}
+		if value == "" && v.Kind() == VariablePassword {
+			length := v.Length
+			if length == 0 {
+				length = defaultPasswordLength
+			}
+			generated, err := generateSecret("randomPassword", strconv.Itoa(length))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", v.Title(), err))
+				continue
+			}
+			value = generated
+		}
+
+		if value == "" && v.Required {
+			errs = append(errs, fmt.Errorf("%s is required", v.Title()))
+			continue
+		}
+		if err := v.Check(value); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if v.Kind() == VariableBoolean && value != "" {
+			enabled, _ := strconv.ParseBool(value)
+			value = strconv.FormatBool(enabled)
+		}
+
+		values[v.Name] = value
+		if v.Kind() == VariablePassword {
+			secrets[v.Name] = value
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return values, secrets, nil
+}
+
+// RenderVariables replaces the {{ var NAME }} placeholders of a template file with the
+// values of the variables. A placeholder of an undeclared variable is an error.
+func RenderVariables(content string, values map[string]string) (string, error) {
+	var unknown []string
+	rendered := variablePlaceholder.ReplaceAllStringFunc(content, func(placeholder string) string {
+		name := variablePlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := values[name]
+		if !ok {
+			if !slices.Contains(unknown, name) {
+				unknown = append(unknown, name)
+			}
+			return placeholder
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("undeclared template variables: %s", strings.Join(unknown, ", "))
+	}
+	return rendered, nil
+}
+
+// SetEnvVariables writes the values of the variables to the content of an .env file, where
+// docker compose picks them up as ${NAME}. Existing lines are replaced where they are.
+func SetEnvVariables(envContent string, vars []Variable, values map[string]string) (string, error) {
+	content := []byte(envContent)
+	for _, v := range vars {
+		value, ok := values[v.Name]
+		if !ok {
+			continue
+		}
+		var err error
+		if content, err = yamlutil.SetEnvVar(content, v.Name, value); err != nil {
+			return "", err
+		}
+	}
+	return string(content), nil
+}
@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariableCheck(t *testing.T) {
+	for _, tc := range []struct {
+		variable Variable
+		value    string
+		valid    bool
+	}{
+		{Variable{Name: "PORT", Type: VariablePort}, "8080", true},
+		{Variable{Name: "PORT", Type: VariablePort}, "70000", false},
+		{Variable{Name: "DOMAIN", Type: VariableDomain}, "wiki.example.com", true},
+		{Variable{Name: "DOMAIN", Type: VariableDomain}, "https://wiki.example.com", false},
+		{Variable{Name: "EMAIL", Type: VariableEmail}, "admin@example.com", true},
+		{Variable{Name: "EMAIL", Type: VariableEmail}, "Admin <admin@example.com>", false},
+		{Variable{Name: "DATA", Type: VariablePath}, "/srv/data", true},
+		{Variable{Name: "DATA", Type: VariablePath}, "/srv/../etc", false},
+		{Variable{Name: "DATA", Type: VariablePath}, "data", false},
+		{Variable{Name: "WORKERS", Type: VariableNumber}, "4", true},
+		{Variable{Name: "WORKERS", Type: VariableNumber}, "four", false},
+		{Variable{Name: "MODE", Type: VariableSelect, Options: []string{"fast", "safe"}}, "safe", true},
+		{Variable{Name: "MODE", Type: VariableSelect, Options: []string{"fast", "safe"}}, "slow", false},
+		{Variable{Name: "TITLE", Pattern: "[a-z]+"}, "wiki", true},
+		{Variable{Name: "TITLE", Pattern: "[a-z]+"}, "wiki2", false},
+		{Variable{Name: "TITLE"}, "My Wiki", true},
+		{Variable{Name: "TITLE"}, "line\nbreak", false},
+		{Variable{Name: "TITLE"}, `say "hi"`, false},
+		{Variable{Name: "TITLE"}, "", true},
+	} {
+		if err := tc.variable.Check(tc.value); (err == nil) != tc.valid {
+			t.Errorf("%s %q: Check = %v, want valid %v", tc.variable.Kind(), tc.value, err, tc.valid)
+		}
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+	vars := []Variable{
+		{Name: "PORT", Type: VariablePort, Default: "8080"},
+		{Name: "ADMIN_PASSWORD", Type: VariablePassword, Length: 20},
+		{Name: "DB_PASSWORD", Type: VariablePassword},
+		{Name: "SIGNUPS", Type: VariableBoolean},
+		{Name: "DOMAIN", Type: VariableDomain},
+	}
+	input := map[string]string{"DB_PASSWORD": "chosen-by-me", "SIGNUPS": "on", "DOMAIN": " wiki.example.com "}
+	lookup := func(name string) (string, bool) {
+		value, ok := input[name]
+		return value, ok
+	}
+
+	// "on" is no boolean
+	if _, _, err := ResolveVariables(vars, lookup); err == nil || !strings.Contains(err.Error(), "SIGNUPS must be true or false") {
+		t.Fatalf("ResolveVariables = %v, want the boolean refused", err)
+	}
+	input["SIGNUPS"] = "1"
+	values, secrets, err := ResolveVariables(vars, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["PORT"] != "8080" || values["SIGNUPS"] != "true" || values["DOMAIN"] != "wiki.example.com" || values["DB_PASSWORD"] != "chosen-by-me" {
+		t.Errorf("values = %v", values)
+	}
+	if len(values["ADMIN_PASSWORD"]) != 20 || secrets["ADMIN_PASSWORD"] != values["ADMIN_PASSWORD"] || secrets["DB_PASSWORD"] != "chosen-by-me" || len(secrets) != 2 {
+		t.Errorf("secrets = %v, values = %v", secrets, values)
+	}
+
+	// All problems are reported at once
+	vars = append(vars, Variable{Name: "EMAIL", Type: VariableEmail, Required: true})
+	input["PORT"] = "0"
+	_, _, err = ResolveVariables(vars, lookup)
+	if err == nil || !strings.Contains(err.Error(), "PORT must be a port") || !strings.Contains(err.Error(), "EMAIL is required") {
+		t.Errorf("ResolveVariables = %v, want the port and the missing email reported", err)
+	}
+}
+
+func TestRenderVariables(t *testing.T) {
+	values := map[string]string{"PORT": "8081", "DOMAIN": "wiki.example.com"}
+	content := "ports:\n  - \"{{ var PORT }}:80\"\nenvironment:\n  - URL=https://{{var DOMAIN}}\n  - DATA={{APP_VOLUMES_PATH}}\n"
+	rendered, err := RenderVariables(content, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ports:\n  - \"8081:80\"\nenvironment:\n  - URL=https://wiki.example.com\n  - DATA={{APP_VOLUMES_PATH}}\n"
+	if rendered != want {
+		t.Errorf("RenderVariables = %q, want %q", rendered, want)
+	}
+	if _, err := RenderVariables("image: app:{{ var TAG }}", values); err == nil || !strings.Contains(err.Error(), "TAG") {
+		t.Errorf("RenderVariables of an undeclared variable = %v", err)
+	}
+}
+
+func TestSetEnvVariables(t *testing.T) {
+	vars := []Variable{{Name: "PORT", Type: VariablePort}, {Name: "TITLE"}, {Name: "UNSET"}}
+	env, err := SetEnvVariables("# Port\nPORT=8080\nOTHER=1\n", vars, map[string]string{"PORT": "8081", "TITLE": "My Wiki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Port\nPORT=8081\nOTHER=1\nTITLE='My Wiki'\n"; env != want {
+		t.Errorf("SetEnvVariables = %q, want %q", env, want)
+	}
+}
@@ -0,0 +1,220 @@
+// Package timesync checks that the host clock is kept in sync and measures its drift against
+// an NTP server. A wrong clock silently breaks TLS certificate validation and TOTP codes in apps.
+//
+// The service status is read with timedatectl, so it is only available on Linux with systemd.
+// Measuring the drift works everywhere.
+package timesync
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/hostupdates"
+)
+
+const (
+	// DefaultServer is queried to measure the clock offset
+	DefaultServer = "pool.ntp.org"
+	// MaxDrift is the offset above which the clock is reported as drifted. TOTP codes are
+	// valid for 30 seconds, so larger offsets make two-factor logins fail.
+	MaxDrift = 10 * time.Second
+
+	queryTimeout = 3 * time.Second
+	// ntpEpochOffset is the number of seconds between 1900 (NTP) and 1970 (Unix)
+	ntpEpochOffset = 2208988800
+)
+
+// lookPath and timesyncdPaths are replaceable in tests
+var (
+	lookPath       = exec.LookPath
+	timesyncdPaths = []string{"/lib/systemd/systemd-timesyncd", "/usr/lib/systemd/systemd-timesyncd"}
+)
+
+// ErrUnsupported is returned when time synchronization can't be managed on this host
+var ErrUnsupported = errors.New("managing time synchronization is only supported on Linux with systemd")
+
+// services are checked in order, the first active one is reported
+var services = []string{"chronyd", "chrony", "systemd-timesyncd", "ntpd", "ntp"}
+
+// Status describes the time synchronization of the host
+type Status struct {
+	Supported    bool          `json:"supported"` // Whether the service status could be read
+	Service      string        `json:"service,omitempty"`
+	Enabled      bool          `json:"enabled"`
+	Synchronized bool          `json:"synchronized"`
+	Offset       time.Duration `json:"offset"` // Local clock minus server time
+	OffsetKnown  bool          `json:"offset_known"`
+	Server       string        `json:"server"`
+	OffsetError  string        `json:"offset_error,omitempty"`
+	CheckedAt    time.Time     `json:"checked_at"`
+}
+
+// Drifted reports whether the measured offset is larger than MaxDrift
+func (s Status) Drifted() bool {
+	return s.OffsetKnown && (s.Offset > MaxDrift || s.Offset < -MaxDrift)
+}
+
+// Check reads the synchronization status and measures the offset against DefaultServer
+func Check(ctx context.Context) Status {
+	status := Status{Server: DefaultServer, CheckedAt: time.Now()}
+
+	if runtime.GOOS == "linux" {
+		if output, err := command(ctx, "timedatectl", "show", "--property=NTP", "--property=NTPSynchronized"); err == nil {
+			status.Supported = true
+			status.Enabled, status.Synchronized = parseTimedatectl(output)
+			status.Service = activeService(ctx)
+		}
+	}
+
+	offset, err := MeasureOffset(ctx, DefaultServer)
+	if err != nil {
+		status.OffsetError = err.Error()
+	} else {
+		status.Offset, status.OffsetKnown = offset, true
+	}
+	return status
+}
+
+func parseTimedatectl(output string) (enabled, synchronized bool) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		switch key {
+		case "NTP":
+			enabled = value == "yes"
+		case "NTPSynchronized":
+			synchronized = value == "yes"
+		}
+	}
+	return enabled, synchronized
+}
+
+func activeService(ctx context.Context) string {
+	for _, service := range services {
+		if output, err := command(ctx, "systemctl", "is-active", service); err == nil && output == "active" {
+			return service
+		}
+	}
+	return ""
+}
+
+// MeasureOffset queries an NTP server (host or host:port) with SNTP and returns how far the
+// local clock is ahead of it
+func MeasureOffset(ctx context.Context, server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach %s: %w", server, err)
+	}
+	defer conn.Close() //nolint:errcheck // Cleanup
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+
+	request := make([]byte, 48)
+	request[0] = 0x1B // Version 3, client mode
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", server, err)
+	}
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		return 0, fmt.Errorf("no answer from %s: %w", server, err)
+	}
+	if n < 48 || response[0]&0x07 != 4 || response[1] == 0 {
+		return 0, fmt.Errorf("invalid answer from %s", server)
+	}
+
+	serverReceived := ntpTime(response[32:40])
+	serverSent := ntpTime(response[40:48])
+	return (sent.Sub(serverReceived) + received.Sub(serverSent)) / 2, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(seconds, fraction*1e9>>32)
+}
+
+// Repair enables time synchronization. If no NTP service is installed, chrony is installed
+// first. The clock is stepped right away when chrony is used. Requires root.
+func Repair(ctx context.Context) (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", ErrUnsupported
+	}
+	if _, err := lookPath("timedatectl"); err != nil {
+		return "", ErrUnsupported
+	}
+	if os.Geteuid() != 0 {
+		return "", errors.New("enabling time synchronization requires TreeOS to run as root")
+	}
+
+	var log strings.Builder
+	if !serviceInstalled() {
+		output, err := hostupdates.InstallPackages(ctx, "chrony")
+		log.WriteString(output)
+		if err != nil {
+			return log.String(), fmt.Errorf("failed to install chrony: %w", err)
+		}
+	}
+
+	output, err := command(ctx, "timedatectl", "set-ntp", "true")
+	log.WriteString(output)
+	if err != nil {
+		return log.String(), fmt.Errorf("failed to enable time synchronization: %w", err)
+	}
+
+	if _, err := lookPath("chronyc"); err == nil {
+		// Large offsets are otherwise corrected slowly, which can take hours
+		output, err := command(ctx, "chronyc", "makestep")
+		log.WriteString(output)
+		if err != nil {
+			return log.String(), fmt.Errorf("failed to correct the clock: %w", err)
+		}
+	}
+	return log.String(), nil
+}
+
+func serviceInstalled() bool {
+	if _, err := lookPath("chronyd"); err == nil {
+		return true
+	}
+	if _, err := lookPath("ntpd"); err == nil {
+		return true
+	}
+	for _, path := range timesyncdPaths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func command(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...) //nolint:gosec // Fixed commands
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
@@ -0,0 +1,102 @@
+package timesync
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTimedatectl(t *testing.T) {
+	tests := []struct {
+		output          string
+		enabled, inSync bool
+	}{
+		{"NTP=yes\nNTPSynchronized=yes\n", true, true},
+		{"NTP=yes\nNTPSynchronized=no\n", true, false},
+		{"NTP=no\nNTPSynchronized=no\n", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		enabled, inSync := parseTimedatectl(tt.output)
+		if enabled != tt.enabled || inSync != tt.inSync {
+			t.Errorf("parseTimedatectl(%q) = %v, %v, want %v, %v", tt.output, enabled, inSync, tt.enabled, tt.inSync)
+		}
+	}
+}
+
+// fakeNTPServer answers SNTP requests with a clock that is skew ahead of the local one
+func fakeNTPServer(t *testing.T, skew time.Duration) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() }) //nolint:errcheck,gosec // Test cleanup
+
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			_, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			response := make([]byte, 48)
+			response[0] = 0x1C // Version 3, server mode
+			response[1] = 2    // Stratum
+			now := time.Now().Add(skew)
+			seconds := uint32(now.Unix() + ntpEpochOffset)
+			fraction := uint32((int64(now.Nanosecond()) << 32) / 1e9)
+			for _, offset := range []int{32, 40} {
+				binary.BigEndian.PutUint32(response[offset:], seconds)
+				binary.BigEndian.PutUint32(response[offset+4:], fraction)
+			}
+			conn.WriteTo(response, addr) //nolint:errcheck,gosec // Test server
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestMeasureOffset(t *testing.T) {
+	server := fakeNTPServer(t, -time.Minute)
+	offset, err := MeasureOffset(context.Background(), server)
+	if err != nil {
+		t.Fatalf("MeasureOffset() error = %v", err)
+	}
+	if offset < 59*time.Second || offset > 61*time.Second {
+		t.Errorf("offset = %v, want about 1m (local clock ahead)", offset)
+	}
+
+	status := Status{Offset: offset, OffsetKnown: true}
+	if !status.Drifted() {
+		t.Error("expected a one minute offset to be reported as drifted")
+	}
+	if (Status{Offset: time.Second, OffsetKnown: true}).Drifted() {
+		t.Error("a one second offset is not drift")
+	}
+}
+
+func TestServiceInstalled(t *testing.T) {
+	origLookPath, origPaths := lookPath, timesyncdPaths
+	t.Cleanup(func() { lookPath, timesyncdPaths = origLookPath, origPaths })
+
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	timesyncdPaths = []string{filepath.Join(t.TempDir(), "systemd-timesyncd")}
+	if serviceInstalled() {
+		t.Error("expected no service without chronyd, ntpd or timesyncd")
+	}
+
+	lookPath = func(name string) (string, error) {
+		if name == "chronyd" {
+			return "/usr/sbin/chronyd", nil
+		}
+		return "", errors.New("not found")
+	}
+	if !serviceInstalled() {
+		t.Error("expected chronyd to count as installed")
+	}
+}
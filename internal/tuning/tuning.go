@@ -0,0 +1,301 @@
+// Package tuning detects kernel settings that are too low for the installed apps and raises them.
+//
+// Changes are written to a sysctl.d file so they survive reboots. The previous values are kept
+// in a backup file so they can be rolled back.
+package tuning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// File locations, replaceable in tests
+var (
+	procSysDir = "/proc/sys"
+	confPath   = "/etc/sysctl.d/90-treeos.conf"
+	// sysctl.d only reads *.conf files, so the backup next to it is ignored
+	backupPath = "/etc/sysctl.d/.90-treeos.backup.json"
+)
+
+// ErrUnsupported is returned on hosts without sysctl
+var ErrUnsupported = errors.New("kernel tuning is only supported on Linux")
+
+// requirement is a minimum value of a kernel setting needed by apps using a matching image
+type requirement struct {
+	Key    string
+	Min    uint64
+	Images []string // Substrings of image names that need this setting
+	Reason string
+}
+
+// requirements are the documented minimums of popular self-hosted apps
+var requirements = []requirement{
+	{
+		Key:    "vm.max_map_count",
+		Min:    262144,
+		Images: []string{"elasticsearch", "opensearch", "immich-machine-learning", "sonarqube"},
+		Reason: "Elasticsearch, OpenSearch and ML models map many files into memory and fail to start below this limit",
+	},
+	{
+		Key:    "fs.inotify.max_user_watches",
+		Min:    524288,
+		Images: []string{"syncthing", "nextcloud", "immich-server", "jellyfin", "photoprism", "paperless"},
+		Reason: "Apps that watch directories for changes stop noticing new files when watches run out",
+	},
+	{
+		Key:    "fs.inotify.max_user_instances",
+		Min:    512,
+		Images: []string{"syncthing", "nextcloud", "immich-server", "jellyfin", "photoprism", "paperless"},
+		Reason: "Every container watching files uses inotify instances, the default of 128 is shared by all of them",
+	},
+	{
+		Key:    "fs.file-max",
+		Min:    1048576,
+		Images: []string{"elasticsearch", "opensearch", "nextcloud", "postgres", "mariadb", "mysql"},
+		Reason: "Databases and search engines keep many files open and fail with \"too many open files\"",
+	},
+}
+
+// Recommendation is a kernel setting that is lower than installed apps need
+type Recommendation struct {
+	Key         string   `json:"key"`
+	Current     uint64   `json:"current"`
+	Recommended uint64   `json:"recommended"`
+	Apps        []string `json:"apps"`
+	Reason      string   `json:"reason"`
+}
+
+// Check returns the settings that are too low for the given apps, keyed by app name with
+// the images of their services
+func Check(appImages map[string][]string) ([]Recommendation, error) {
+	if runtime.GOOS != "linux" {
+		return nil, ErrUnsupported
+	}
+
+	recommendations := []Recommendation{}
+	for _, req := range requirements {
+		apps := appsNeeding(req, appImages)
+		if len(apps) == 0 {
+			continue
+		}
+		current, err := readSysctl(req.Key)
+		if err != nil {
+			// Settings missing in this kernel or namespace can't be tuned
+			continue
+		}
+		if current >= req.Min {
+			continue
+		}
+		recommendations = append(recommendations, Recommendation{
+			Key:         req.Key,
+			Current:     current,
+			Recommended: req.Min,
+			Apps:        apps,
+			Reason:      req.Reason,
+		})
+	}
+	return recommendations, nil
+}
+
+// appsNeeding returns the sorted names of apps with an image matching the requirement
+func appsNeeding(req requirement, appImages map[string][]string) []string {
+	var apps []string
+	for appName, images := range appImages {
+	images:
+		for _, image := range images {
+			image = strings.ToLower(image)
+			for _, match := range req.Images {
+				if strings.Contains(image, match) {
+					apps = append(apps, appName)
+					break images
+				}
+			}
+		}
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+// Apply raises the recommended settings now and persists them in the sysctl.d file.
+// Values before the first change are kept for Rollback.
+func Apply(recommendations []Recommendation) error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupported
+	}
+	if len(recommendations) == 0 {
+		return nil
+	}
+
+	backup, err := readBackup()
+	if err != nil {
+		return err
+	}
+	applied, err := readConf()
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range recommendations {
+		if _, known := requirementFor(rec.Key); !known {
+			return fmt.Errorf("unknown setting %s", rec.Key)
+		}
+		current, err := readSysctl(rec.Key)
+		if err != nil {
+			return err
+		}
+		if current >= rec.Recommended {
+			continue
+		}
+		if _, ok := backup[rec.Key]; !ok {
+			backup[rec.Key] = current
+		}
+		if err := writeSysctl(rec.Key, rec.Recommended); err != nil {
+			return err
+		}
+		applied[rec.Key] = rec.Recommended
+	}
+
+	if err := writeBackup(backup); err != nil {
+		return err
+	}
+	return writeConf(applied)
+}
+
+// Applied returns the settings TreeOS changed with their previous values
+func Applied() (map[string]uint64, error) {
+	return readBackup()
+}
+
+// Rollback restores the values from before Apply and removes the sysctl.d file
+func Rollback() error {
+	if runtime.GOOS != "linux" {
+		return ErrUnsupported
+	}
+
+	backup, err := readBackup()
+	if err != nil {
+		return err
+	}
+	for key, value := range backup {
+		if err := writeSysctl(key, value); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range []string{confPath, backupPath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func requirementFor(key string) (requirement, bool) {
+	for _, req := range requirements {
+		if req.Key == key {
+			return req, true
+		}
+	}
+	return requirement{}, false
+}
+
+func sysctlPath(key string) string {
+	return filepath.Join(procSysDir, strings.ReplaceAll(key, ".", "/"))
+}
+
+func readSysctl(key string) (uint64, error) {
+	data, err := os.ReadFile(sysctlPath(key))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func writeSysctl(key string, value uint64) error {
+	if err := os.WriteFile(sysctlPath(key), []byte(strconv.FormatUint(value, 10)), 0644); err != nil { //nolint:gosec // Kernel settings file
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	return nil
+}
+
+// readConf returns the settings in the sysctl.d file
+func readConf() (map[string]uint64, error) {
+	settings := make(map[string]uint64)
+	data, err := os.ReadFile(confPath)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", confPath, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+			settings[strings.TrimSpace(key)] = v
+		}
+	}
+	return settings, nil
+}
+
+func writeConf(settings map[string]uint64) error {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("# Managed by TreeOS, roll back from the TreeOS settings page.\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s = %d\n", key, settings[key])
+	}
+	if err := os.WriteFile(confPath, []byte(b.String()), 0644); err != nil { //nolint:gosec // sysctl.d files are world-readable
+		return fmt.Errorf("failed to write %s: %w", confPath, err)
+	}
+	return nil
+}
+
+func readBackup() (map[string]uint64, error) {
+	backup := make(map[string]uint64)
+	data, err := os.ReadFile(backupPath)
+	if os.IsNotExist(err) {
+		return backup, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", backupPath, err)
+	}
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", backupPath, err)
+	}
+	return backup, nil
+}
+
+func writeBackup(backup map[string]uint64) error {
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(backupPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", backupPath, err)
+	}
+	return nil
+}
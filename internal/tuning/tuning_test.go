@@ -0,0 +1,102 @@
+package tuning
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupFakeSysctl points the package at a temporary /proc/sys and sysctl.d
+func setupFakeSysctl(t *testing.T, values map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	originalProc, originalConf, originalBackup := procSysDir, confPath, backupPath
+	procSysDir = filepath.Join(dir, "proc")
+	confPath = filepath.Join(dir, "90-treeos.conf")
+	backupPath = filepath.Join(dir, ".90-treeos.backup.json")
+	t.Cleanup(func() { procSysDir, confPath, backupPath = originalProc, originalConf, originalBackup })
+
+	for key, value := range values {
+		path := sysctlPath(key)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	setupFakeSysctl(t, map[string]string{
+		"vm.max_map_count":              "65530",
+		"fs.inotify.max_user_watches":   "1048576",
+		"fs.inotify.max_user_instances": "128",
+		"fs.file-max":                   "9223372036854775807",
+	})
+
+	recommendations, err := Check(map[string][]string{
+		"search": {"docker.elastic.co/elasticsearch/elasticsearch:8.13.0"},
+		"sync":   {"syncthing/syncthing:latest"},
+		"blog":   {"ghost:5"},
+	})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	got := map[string]Recommendation{}
+	for _, rec := range recommendations {
+		got[rec.Key] = rec
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 recommendations, got %+v", recommendations)
+	}
+	if rec := got["vm.max_map_count"]; rec.Current != 65530 || rec.Recommended != 262144 || strings.Join(rec.Apps, ",") != "search" {
+		t.Errorf("unexpected max_map_count recommendation: %+v", rec)
+	}
+	if rec := got["fs.inotify.max_user_instances"]; strings.Join(rec.Apps, ",") != "sync" {
+		t.Errorf("unexpected inotify recommendation: %+v", rec)
+	}
+}
+
+func TestApplyAndRollback(t *testing.T) {
+	setupFakeSysctl(t, map[string]string{"vm.max_map_count": "65530"})
+
+	rec := Recommendation{Key: "vm.max_map_count", Current: 65530, Recommended: 262144}
+	if err := Apply([]Recommendation{rec}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if value, _ := readSysctl("vm.max_map_count"); value != 262144 {
+		t.Errorf("expected live value 262144, got %d", value)
+	}
+	conf, err := os.ReadFile(confPath)
+	if err != nil || !strings.Contains(string(conf), "vm.max_map_count = 262144\n") {
+		t.Errorf("unexpected sysctl.d file: %q, %v", conf, err)
+	}
+
+	// Applying again keeps the original value for rollback
+	if err := Apply([]Recommendation{rec}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if applied, _ := Applied(); applied["vm.max_map_count"] != 65530 {
+		t.Errorf("expected backup of 65530, got %v", applied)
+	}
+
+	if err := Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if value, _ := readSysctl("vm.max_map_count"); value != 65530 {
+		t.Errorf("expected restored value 65530, got %d", value)
+	}
+	if _, err := os.Stat(confPath); !os.IsNotExist(err) {
+		t.Errorf("expected sysctl.d file to be removed, got %v", err)
+	}
+}
+
+func TestApplyRejectsUnknownSettings(t *testing.T) {
+	setupFakeSysctl(t, map[string]string{"kernel.panic": "0"})
+	if err := Apply([]Recommendation{{Key: "kernel.panic", Recommended: 10}}); err == nil {
+		t.Error("expected error for a setting TreeOS doesn't manage")
+	}
+}
@@ -0,0 +1,220 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// compatibilityAsset is the release asset describing what an update changes for existing installs
+const compatibilityAsset = "compatibility.json"
+
+// Finding severities. Errors block an update unless it is forced, warnings don't.
+const (
+	FindingError   = "error"
+	FindingWarning = "warning"
+	FindingInfo    = "info"
+)
+
+// Compatibility is published with a release as compatibility.json and describes the changes
+// that may break existing installs
+type Compatibility struct {
+	MinVersion        string            `json:"min_version,omitempty"`         // Oldest version that can update directly
+	MinComposeVersion string            `json:"min_compose_version,omitempty"` // Oldest compose file format the release accepts
+	Migrations        []Migration       `json:"migrations,omitempty"`
+	DeprecatedFields  []DeprecatedField `json:"deprecated_fields,omitempty"`
+}
+
+// Migration is a database migration the release runs at startup
+type Migration struct {
+	Description  string `json:"description"`
+	Irreversible bool   `json:"irreversible,omitempty"` // The previous version can't read the database afterwards
+}
+
+// DeprecatedField is a compose file field the release no longer supports as it used to
+type DeprecatedField struct {
+	Field       string `json:"field"`                 // Dotted path, "*" matches any key, e.g. "services.*.links"
+	Removed     bool   `json:"removed,omitempty"`     // Ignored by the release, not just discouraged
+	Replacement string `json:"replacement,omitempty"` // What to use instead
+}
+
+// AppCompose is the compose file of an installed app
+type AppCompose struct {
+	App     string
+	Compose []byte
+}
+
+// Finding is one result of the compatibility check
+type Finding struct {
+	Severity string `json:"severity"` // FindingError, FindingWarning or FindingInfo
+	Message  string `json:"message"`
+}
+
+// AppCompatibility lists the findings of one app
+type AppCompatibility struct {
+	App      string    `json:"app"`
+	Findings []Finding `json:"findings"`
+}
+
+// CompatibilityReport is the result of checking the node and its apps against a release
+type CompatibilityReport struct {
+	CurrentVersion string             `json:"current_version"`
+	Version        string             `json:"version"`
+	Published      bool               `json:"published"` // The release has compatibility.json, otherwise only the apps were parsed
+	Blocking       bool               `json:"blocking"`  // There are errors
+	Migrations     int                `json:"migrations"`
+	Node           []Finding          `json:"node"`
+	Apps           []AppCompatibility `json:"apps"` // Apps with findings
+}
+
+// CheckCompatibility checks the running version and the compose files of the apps against the
+// compatibility information of a release, which may be nil
+func CheckCompatibility(currentVersion, version string, c *Compatibility, apps []AppCompose) *CompatibilityReport {
+	report := &CompatibilityReport{
+		CurrentVersion: currentVersion,
+		Version:        version,
+		Published:      c != nil,
+		Node:           []Finding{},
+		Apps:           []AppCompatibility{},
+	}
+	if c == nil {
+		c = &Compatibility{}
+	}
+	report.Migrations = len(c.Migrations)
+
+	current := strings.TrimPrefix(currentVersion, "v")
+	if c.MinVersion != "" && current != "dev" && current != "unknown" &&
+		compareVersions(current, strings.TrimPrefix(c.MinVersion, "v")) < 0 {
+		report.Node = append(report.Node, Finding{FindingError,
+			fmt.Sprintf("Version %s can't update to %s directly, update to %s first", currentVersion, version, c.MinVersion)})
+	}
+	for _, m := range c.Migrations {
+		if m.Irreversible {
+			report.Node = append(report.Node, Finding{FindingWarning,
+				"Database migration: " + m.Description + ". Going back to " + currentVersion + " afterwards needs the database backup taken before the update"})
+		} else {
+			report.Node = append(report.Node, Finding{FindingInfo, "Database migration: " + m.Description})
+		}
+	}
+
+	for _, app := range apps {
+		if findings := checkApp(c, app.Compose); len(findings) > 0 {
+			report.Apps = append(report.Apps, AppCompatibility{App: app.App, Findings: findings})
+		}
+	}
+	sort.Slice(report.Apps, func(i, j int) bool { return report.Apps[i].App < report.Apps[j].App })
+
+	for _, f := range report.Node {
+		report.Blocking = report.Blocking || f.Severity == FindingError
+	}
+	for _, app := range report.Apps {
+		for _, f := range app.Findings {
+			report.Blocking = report.Blocking || f.Severity == FindingError
+		}
+	}
+	return report
+}
+
+// checkApp checks one compose file
+func checkApp(c *Compatibility, compose []byte) []Finding {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(compose, &doc); err != nil {
+		return []Finding{{FindingError, fmt.Sprintf("docker-compose.yml can't be parsed: %v", err)}}
+	}
+
+	var findings []Finding
+	if version, ok := doc["version"]; ok && c.MinComposeVersion != "" {
+		v := fmt.Sprint(version)
+		if compareVersionParts(v, c.MinComposeVersion) < 0 {
+			findings = append(findings, Finding{FindingError,
+				fmt.Sprintf("Compose file format %s is no longer supported, the oldest supported is %s", v, c.MinComposeVersion)})
+		}
+	}
+	for _, field := range c.DeprecatedFields {
+		for _, path := range matchField(doc, strings.Split(field.Field, "."), "") {
+			severity, message := FindingWarning, path+" is deprecated"
+			if field.Removed {
+				severity, message = FindingError, path+" is no longer supported"
+			}
+			if field.Replacement != "" {
+				message += ", use " + field.Replacement + " instead"
+			}
+			findings = append(findings, Finding{severity, message})
+		}
+	}
+	return findings
+}
+
+// matchField returns the paths in node matching the dotted field parts, sorted
+func matchField(node interface{}, parts []string, prefix string) []string {
+	if len(parts) == 0 {
+		return []string{prefix}
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	if parts[0] != "*" {
+		child, ok := m[parts[0]]
+		if !ok {
+			return nil
+		}
+		return matchField(child, parts[1:], join(parts[0]))
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var paths []string
+	for _, key := range keys {
+		paths = append(paths, matchField(m[key], parts[1:], join(key))...)
+	}
+	return paths
+}
+
+// downloadJSON downloads a JSON asset of a release, such as compatibility.json, into v
+func (s *GitHubUpdateSource) downloadJSON(url, name string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", "TreeOS-Updater")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: status %d", name, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// CheckCompatibility fetches the latest release of the channel and checks the node and the
+// compose files of its apps against it
+func (s *Service) CheckCompatibility(apps []AppCompose) (*CompatibilityReport, error) {
+	manifest, err := s.source.FetchManifest(s.updateChannel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch update manifest: %w", err)
+	}
+	return CheckCompatibility(s.currentVersion, manifest.Version, manifest.Compatibility, apps), nil
+}
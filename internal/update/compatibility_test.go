@@ -0,0 +1,62 @@
+package update
+
+import (
+	"testing"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	c := &Compatibility{
+		MinVersion:        "0.9.0",
+		MinComposeVersion: "3",
+		Migrations: []Migration{
+			{Description: "Add app tags"},
+			{Description: "Move sessions to their own table", Irreversible: true},
+		},
+		DeprecatedFields: []DeprecatedField{
+			{Field: "x-ontree.is_exposed", Replacement: "x-ontree.exposure"},
+			{Field: "services.*.links", Removed: true, Replacement: "networks"},
+		},
+	}
+	apps := []AppCompose{
+		{App: "web", Compose: []byte("services:\n  web:\n    image: nginx\n")},
+		{App: "wiki", Compose: []byte("version: '2.4'\nservices:\n  db:\n    image: postgres\n  app:\n    image: wiki\n    links: [db]\nx-ontree:\n  is_exposed: true\n")},
+		{App: "broken", Compose: []byte("services: [\n")},
+	}
+
+	report := CheckCompatibility("0.10.0", "0.11.0", c, apps)
+	if !report.Published || !report.Blocking {
+		t.Errorf("report = %+v, want published and blocking", report)
+	}
+	if len(report.Node) != 2 || report.Node[0].Severity != FindingInfo || report.Node[1].Severity != FindingWarning {
+		t.Errorf("node findings = %+v", report.Node)
+	}
+	if len(report.Apps) != 2 || report.Apps[0].App != "broken" || report.Apps[1].App != "wiki" {
+		t.Fatalf("apps = %+v, want broken and wiki", report.Apps)
+	}
+	want := []Finding{
+		{FindingError, "Compose file format 2.4 is no longer supported, the oldest supported is 3"},
+		{FindingWarning, "x-ontree.is_exposed is deprecated, use x-ontree.exposure instead"},
+		{FindingError, "services.app.links is no longer supported, use networks instead"},
+	}
+	got := report.Apps[1].Findings
+	if len(got) != len(want) {
+		t.Fatalf("wiki findings = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Too old to update directly
+	report = CheckCompatibility("0.8.2", "0.11.0", c, nil)
+	if !report.Blocking || report.Node[0].Severity != FindingError {
+		t.Errorf("node findings = %+v, want an error for the minimum version", report.Node)
+	}
+
+	// Releases without compatibility.json only check that apps parse
+	report = CheckCompatibility("0.10.0", "0.11.0", nil, apps[:2])
+	if report.Published || report.Blocking || len(report.Apps) != 0 {
+		t.Errorf("report = %+v, want no findings", report)
+	}
+}
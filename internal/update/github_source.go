@@ -11,6 +11,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/ontree-co/treeos/internal/logging"
 )
 
 // GitHubUpdateSource fetches updates from GitHub releases
@@ -36,6 +38,7 @@ type GitHubRelease struct {
 	TagName     string        `json:"tag_name"`
 	Name        string        `json:"name"`
 	Body        string        `json:"body"`
+	HTMLURL     string        `json:"html_url"`
 	Prerelease  bool          `json:"prerelease"`
 	PublishedAt time.Time     `json:"published_at"`
 	Assets      []GitHubAsset `json:"assets"`
@@ -130,6 +133,27 @@ func (s *GitHubUpdateSource) FetchManifest(channel UpdateChannel) (*UpdateManife
 		}
 	}
 
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case compatibilityAsset:
+			// Without it the update is only checked for apps that can't be parsed
+			var c Compatibility
+			if err := s.downloadJSON(asset.BrowserDownloadURL, asset.Name, &c); err != nil {
+				logging.Warnf("Failed to get compatibility information of %s: %v", manifest.Version, err)
+			} else {
+				manifest.Compatibility = &c
+			}
+		case rolloutAsset:
+			// Without it the release goes to all nodes of the channel
+			var rollout Rollout
+			if err := s.downloadJSON(asset.BrowserDownloadURL, asset.Name, &rollout); err != nil {
+				logging.Warnf("Failed to get rollout of %s: %v", manifest.Version, err)
+			} else {
+				manifest.Rollout = &rollout
+			}
+		}
+	}
+
 	// Map assets to platforms
 	for _, asset := range release.Assets {
 		if strings.HasSuffix(asset.Name, ".tar.gz") {
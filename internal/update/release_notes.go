@@ -0,0 +1,207 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// headingPattern matches Markdown headings
+	headingPattern = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	// listItemPattern matches bullet and numbered list items
+	listItemPattern = regexp.MustCompile(`^([-*+]|\d+[.)])\s+(.*)$`)
+	// breakingItemPattern matches items marked breaking, "BREAKING: ..." or conventional
+	// commits like "feat(api)!: ..."
+	breakingItemPattern = regexp.MustCompile(`(?i)^(\*\*)?(breaking( change)?:|[a-z]+(\([^)]*\))?!:)`)
+	// breakingSectionPattern matches headings of sections that list breaking changes
+	breakingSectionPattern = regexp.MustCompile(`(?i)breaking`)
+)
+
+// ReleaseNotes are the release notes of one release, split into sections
+type ReleaseNotes struct {
+	Version    string           `json:"version"`
+	Name       string           `json:"name"`
+	Date       time.Time        `json:"date"`
+	URL        string           `json:"url,omitempty"`
+	Prerelease bool             `json:"prerelease"`
+	Sections   []ChangelogEntry `json:"sections"`
+	Breaking   []string         `json:"breaking"` // Breaking changes of all sections
+}
+
+// ChangelogEntry is a section of release notes below a heading
+type ChangelogEntry struct {
+	Title      string          `json:"title,omitempty"` // Empty for text before the first heading
+	Breaking   bool            `json:"breaking"`
+	Paragraphs []string        `json:"paragraphs,omitempty"`
+	Items      []ChangelogItem `json:"items,omitempty"`
+}
+
+// ChangelogItem is a list item of a changelog section
+type ChangelogItem struct {
+	Text     string `json:"text"`
+	Breaking bool   `json:"breaking"`
+}
+
+// ParseReleaseNotes splits the Markdown body of a release into sections and marks breaking
+// changes: all items of a section whose heading mentions breaking changes, and items starting
+// with "BREAKING:" or a conventional commit type with "!"
+func ParseReleaseNotes(body string) ([]ChangelogEntry, []string) {
+	sections := []ChangelogEntry{}
+	current := func() *ChangelogEntry {
+		if len(sections) == 0 {
+			sections = append(sections, ChangelogEntry{})
+		}
+		return &sections[len(sections)-1]
+	}
+
+	inComment, inFence, continued := false, false, false
+	for _, raw := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		switch {
+		case inComment:
+			inComment = !strings.Contains(line, "-->")
+			continue
+		case strings.HasPrefix(line, "<!--"):
+			inComment = !strings.Contains(line, "-->")
+			continue
+		case strings.HasPrefix(line, "```"):
+			inFence = !inFence
+			continued = false
+			continue
+		}
+
+		if inFence {
+			if line != "" {
+				s := current()
+				s.Paragraphs = append(s.Paragraphs, line)
+			}
+			continue
+		}
+		if line == "" {
+			continued = false
+			continue
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			sections = append(sections, ChangelogEntry{Title: m[1], Breaking: breakingSectionPattern.MatchString(m[1])})
+			continued = false
+			continue
+		}
+
+		s := current()
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			s.Items = append(s.Items, ChangelogItem{Text: m[2], Breaking: s.Breaking || breakingItemPattern.MatchString(m[2])})
+			continued = true
+			continue
+		}
+		// Indented lines and lazy continuations belong to the list item above
+		if continued && len(s.Items) > 0 {
+			item := &s.Items[len(s.Items)-1]
+			item.Text += " " + line
+			continue
+		}
+		s.Paragraphs = append(s.Paragraphs, line)
+	}
+
+	var breaking []string
+	kept := sections[:0]
+	for _, s := range sections {
+		for _, item := range s.Items {
+			if item.Breaking {
+				breaking = append(breaking, item.Text)
+			}
+		}
+		// Paragraphs of a breaking section without a list describe the change
+		if s.Breaking && len(s.Items) == 0 {
+			breaking = append(breaking, s.Paragraphs...)
+		}
+		if s.Title != "" || len(s.Paragraphs) > 0 || len(s.Items) > 0 {
+			kept = append(kept, s)
+		}
+	}
+	if breaking == nil {
+		breaking = []string{}
+	}
+	return kept, breaking
+}
+
+// FetchReleases fetches the most recent releases, newest first
+func (s *GitHubUpdateSource) FetchReleases() ([]GitHubRelease, error) {
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases?per_page=50", s.Owner, s.Repo)
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "TreeOS-Updater")
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var releases []GitHubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to decode releases: %w", err)
+	}
+	return releases, nil
+}
+
+// releaseNotesBetween returns the notes of the releases newer than current up to latest,
+// newest first. The stable channel skips pre-releases.
+func releaseNotesBetween(releases []GitHubRelease, channel UpdateChannel, current, latest string) []ReleaseNotes {
+	current = strings.TrimPrefix(current, "v")
+	notes := []ReleaseNotes{}
+	for _, release := range releases {
+		version := strings.TrimPrefix(release.TagName, "v")
+		prerelease := release.Prerelease || strings.Contains(strings.ToLower(release.TagName), "beta")
+		if channel == ChannelStable && prerelease {
+			continue
+		}
+		if compareVersions(version, latest) > 0 {
+			continue
+		}
+		// Development builds only get the notes of the release they update to
+		if current == "dev" || current == "unknown" {
+			if version != latest {
+				continue
+			}
+		} else if compareVersions(version, current) <= 0 {
+			continue
+		}
+		sections, breaking := ParseReleaseNotes(release.Body)
+		notes = append(notes, ReleaseNotes{
+			Version:    version,
+			Name:       release.Name,
+			Date:       release.PublishedAt,
+			URL:        release.HTMLURL,
+			Prerelease: prerelease,
+			Sections:   sections,
+			Breaking:   breaking,
+		})
+	}
+	return notes
+}
+
+// ReleaseNotes returns the notes of all releases of the channel between the running version
+// and the latest one, newest first, so nothing between two updates goes unnoticed
+func (s *Service) ReleaseNotes() (string, []ReleaseNotes, error) {
+	manifest, err := s.source.FetchManifest(s.updateChannel)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch update manifest: %w", err)
+	}
+	releases, err := s.source.FetchReleases()
+	if err != nil {
+		return "", nil, err
+	}
+	return manifest.Version, releaseNotesBetween(releases, s.updateChannel, s.currentVersion, manifest.Version), nil
+}
@@ -0,0 +1,73 @@
+package update
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testReleaseNotes = `<!-- Release notes generated by release-please -->
+Small maintenance release.
+
+## ⚠ BREAKING CHANGES
+
+* Apps must use compose file format 3
+  or newer
+
+## Features
+
+- feat(api)!: rename ` + "`/api/apps/{name}/status`" + `
+- Add app tags
+1. Faster dashboard
+
+### Notes
+` + "```" + `
+sudo systemctl restart treeos
+` + "```"
+
+func TestParseReleaseNotes(t *testing.T) {
+	sections, breaking := ParseReleaseNotes(testReleaseNotes)
+	if len(sections) != 4 {
+		t.Fatalf("ParseReleaseNotes() = %d sections, want 4: %+v", len(sections), sections)
+	}
+	if sections[0].Title != "" || !reflect.DeepEqual(sections[0].Paragraphs, []string{"Small maintenance release."}) {
+		t.Errorf("intro = %+v", sections[0])
+	}
+	if !sections[1].Breaking || len(sections[1].Items) != 1 || sections[1].Items[0].Text != "Apps must use compose file format 3 or newer" {
+		t.Errorf("breaking section = %+v", sections[1])
+	}
+	features := sections[2]
+	if features.Breaking || len(features.Items) != 3 || !features.Items[0].Breaking || features.Items[1].Breaking {
+		t.Errorf("features = %+v", features)
+	}
+	if !reflect.DeepEqual(sections[3].Paragraphs, []string{"sudo systemctl restart treeos"}) {
+		t.Errorf("notes = %+v", sections[3])
+	}
+	want := []string{"Apps must use compose file format 3 or newer", "feat(api)!: rename `/api/apps/{name}/status`"}
+	if !reflect.DeepEqual(breaking, want) {
+		t.Errorf("breaking = %q, want %q", breaking, want)
+	}
+}
+
+func TestReleaseNotesBetween(t *testing.T) {
+	releases := []GitHubRelease{
+		{TagName: "v0.12.0-beta.1", Prerelease: true, Body: "- BREAKING: drop arm/v7"},
+		{TagName: "v0.11.0", Body: "- Add tags"},
+		{TagName: "v0.10.1", Body: "- Fix login"},
+		{TagName: "v0.10.0", Body: "- Old"},
+	}
+
+	notes := releaseNotesBetween(releases, ChannelStable, "v0.10.0", "0.11.0")
+	if len(notes) != 2 || notes[0].Version != "0.11.0" || notes[1].Version != "0.10.1" {
+		t.Errorf("stable notes = %+v, want 0.11.0 and 0.10.1", notes)
+	}
+
+	notes = releaseNotesBetween(releases, ChannelBeta, "0.11.0", "0.12.0-beta.1")
+	if len(notes) != 1 || !notes[0].Prerelease || len(notes[0].Breaking) != 1 {
+		t.Errorf("beta notes = %+v, want 0.12.0-beta.1 with a breaking change", notes)
+	}
+
+	notes = releaseNotesBetween(releases, ChannelStable, "dev", "0.11.0")
+	if len(notes) != 1 || notes[0].Version != "0.11.0" {
+		t.Errorf("dev notes = %+v, want only 0.11.0", notes)
+	}
+}
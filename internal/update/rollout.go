@@ -0,0 +1,42 @@
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// rolloutAsset is the release asset that limits a beta release to part of the nodes
+const rolloutAsset = "rollout.json"
+
+// Rollout is published with a beta release as rollout.json to reach a share of the nodes first.
+// Raising the percentage later adds nodes, the ones that already got the release stay in.
+type Rollout struct {
+	Percentage int `json:"percentage"` // 0 to 100
+}
+
+// RolloutStatus tells whether this node is part of the rollout of a release
+type RolloutStatus struct {
+	Percentage int  `json:"percentage"`
+	Bucket     int  `json:"bucket"`   // 0 to 99, the node gets the release once Percentage exceeds it
+	Included   bool `json:"included"` // The node gets the release now
+}
+
+// RolloutBucket places a node in one of 100 buckets for a release. The same node gets the same
+// bucket for a release on every check, but different buckets for different releases, so the
+// same nodes aren't always the first to get a beta.
+func RolloutBucket(nodeID, version string) int {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s", version, nodeID)))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+// rolloutStatus returns whether a node is part of the rollout of a release, nil if the
+// release has no rollout on the channel
+func rolloutStatus(channel UpdateChannel, rollout *Rollout, nodeID, version string) *RolloutStatus {
+	// Stable releases have been through the beta channel and go to all nodes
+	if channel != ChannelBeta || rollout == nil || rollout.Percentage >= 100 {
+		return nil
+	}
+	bucket := RolloutBucket(nodeID, version)
+	return &RolloutStatus{Percentage: rollout.Percentage, Bucket: bucket, Included: bucket < rollout.Percentage}
+}
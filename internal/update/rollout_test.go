@@ -0,0 +1,53 @@
+package update
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRolloutBucket(t *testing.T) {
+	if RolloutBucket("node-a", "0.12.0-beta.1") != RolloutBucket("node-a", "0.12.0-beta.1") {
+		t.Error("RolloutBucket() is not deterministic")
+	}
+
+	// About a tenth of the nodes land below 10, and not the same ones for every release
+	included, again := 0, 0
+	for i := 0; i < 1000; i++ {
+		node := fmt.Sprintf("node-%d", i)
+		bucket := RolloutBucket(node, "0.12.0-beta.1")
+		if bucket < 0 || bucket > 99 {
+			t.Fatalf("RolloutBucket(%q) = %d, want 0 to 99", node, bucket)
+		}
+		if bucket < 10 {
+			included++
+			if RolloutBucket(node, "0.12.0-beta.2") < 10 {
+				again++
+			}
+		}
+	}
+	if included < 60 || included > 140 {
+		t.Errorf("%d of 1000 nodes in a 10%% rollout", included)
+	}
+	if again > included/2 {
+		t.Errorf("%d of %d nodes are first again for the next release", again, included)
+	}
+}
+
+func TestRolloutStatus(t *testing.T) {
+	rollout := &Rollout{Percentage: 25}
+	if status := rolloutStatus(ChannelStable, rollout, "node-a", "0.12.0"); status != nil {
+		t.Errorf("stable rollout = %+v, want nil", status)
+	}
+	if status := rolloutStatus(ChannelBeta, &Rollout{Percentage: 100}, "node-a", "0.12.0-beta.1"); status != nil {
+		t.Errorf("full rollout = %+v, want nil", status)
+	}
+
+	status := rolloutStatus(ChannelBeta, rollout, "node-a", "0.12.0-beta.1")
+	if status == nil || status.Included != (status.Bucket < 25) {
+		t.Fatalf("beta rollout = %+v", status)
+	}
+	// Raising the percentage keeps the nodes that are already in
+	if status.Included && !rolloutStatus(ChannelBeta, &Rollout{Percentage: 50}, "node-a", "0.12.0-beta.1").Included {
+		t.Error("node dropped out when the rollout grew")
+	}
+}
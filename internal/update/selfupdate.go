@@ -20,6 +20,7 @@ type Service struct {
 	currentVersion string
 	updateChannel  UpdateChannel
 	source         *GitHubUpdateSource
+	nodeID         string // Places the node in staged rollouts of beta releases
 }
 
 // NewService creates a new update service
@@ -39,6 +40,11 @@ func (s *Service) SetChannel(channel UpdateChannel) {
 	logging.Infof("Update channel changed to: %s", channel)
 }
 
+// SetNodeID sets the identifier of the node, which decides when it gets staged beta releases
+func (s *Service) SetNodeID(nodeID string) {
+	s.nodeID = nodeID
+}
+
 // GetChannel returns the current update channel
 func (s *Service) GetChannel() UpdateChannel {
 	return s.updateChannel
@@ -69,6 +75,12 @@ func (s *Service) CheckForUpdate() (*UpdateInfo, error) {
 		DownloadSize:    asset.Size,
 		SHA256:          asset.SHA256,
 	}
+	if info.UpdateAvailable {
+		// Nodes outside a staged rollout get the release once the rollout reaches them
+		if info.Rollout = rolloutStatus(s.updateChannel, manifest.Rollout, s.nodeID, manifest.Version); info.Rollout != nil {
+			info.UpdateAvailable = info.Rollout.Included
+		}
+	}
 
 	logging.Infof("Update check complete. Current: %s, Latest: %s, Available: %v",
 		info.CurrentVersion, info.LatestVersion, info.UpdateAvailable)
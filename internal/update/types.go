@@ -28,6 +28,8 @@ type UpdateInfo struct {
 	DownloadURL     string    `json:"download_url,omitempty"`
 	DownloadSize    int64     `json:"download_size,omitempty"`
 	SHA256          string    `json:"sha256,omitempty"`
+	// Rollout is set while a beta release only reaches part of the nodes
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
 }
 
 // UpdateManifest represents the JSON structure from the update server
@@ -38,6 +40,10 @@ type UpdateManifest struct {
 	ReleaseDate  time.Time `json:"release_date"`
 	ReleaseNotes string    `json:"release_notes"`
 	Assets       Assets    `json:"assets"`
+	// Compatibility is nil when the release publishes no compatibility.json
+	Compatibility *Compatibility `json:"compatibility,omitempty"`
+	// Rollout is nil when the release goes to all nodes of the channel at once
+	Rollout *Rollout `json:"rollout,omitempty"`
 }
 
 // Assets contains platform-specific download information
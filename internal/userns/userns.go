@@ -0,0 +1,276 @@
+// Package userns detects whether containers run in a user namespace, so root in a container
+// is not root on the host, and shifts the ownership of bind-mounted app data when a host
+// switches to Docker's userns-remap or rootless Podman.
+package userns
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Ways containers can be kept from running as real root
+const (
+	ModeRemap    = "userns-remap" // Docker daemon remaps container IDs to a subordinate range
+	ModeRootless = "rootless"     // Runtime runs as a regular user, container root is that user
+)
+
+// defaultRemapUser is the user Docker creates for "userns-remap": "default"
+const defaultRemapUser = "dockremap"
+
+// Host files and commands, replaceable in tests
+var (
+	daemonConfigPath = "/etc/docker/daemon.json"
+	subUIDPath       = "/etc/subuid"
+	subGIDPath       = "/etc/subgid"
+	runtimeInfo      = func(ctx context.Context) (runtimeName, securityOptions string, err error) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if _, err := exec.LookPath("podman"); err == nil {
+			output, err := exec.CommandContext(ctx, "podman", "info", "--format", "{{.Host.Security.Rootless}}").Output()
+			if err == nil && strings.TrimSpace(string(output)) == "true" {
+				return "podman", "name=rootless", nil
+			}
+			return "podman", "", err
+		}
+		output, err := exec.CommandContext(ctx, "docker", "info", "--format", "{{json .SecurityOptions}}").Output()
+		return "docker", strings.TrimSpace(string(output)), err
+	}
+	currentUser = user.Current
+)
+
+// IDMap maps user or group IDs of a container to the host
+type IDMap struct {
+	Root  int // Host ID of root in the container
+	Start int // Host ID of ID 1 in the container
+	Size  int // Number of IDs mapped after root
+}
+
+// HostID returns the host ID of a container ID, -1 if it is not mapped
+func (m IDMap) HostID(id int) int {
+	switch {
+	case id == 0:
+		return m.Root
+	case id > 0 && id <= m.Size:
+		return m.Start + id - 1
+	default:
+		return -1
+	}
+}
+
+// mapped reports whether a host ID is one a container can own, i.e. was already shifted.
+// With rootless runtimes this includes the user's own ID, which container root maps to.
+func (m IDMap) mapped(hostID int) bool {
+	return hostID == m.Root || (hostID >= m.Start && hostID < m.Start+m.Size)
+}
+
+// Mapping describes how the container runtime maps IDs, Mode is empty when containers
+// run with the host's IDs
+type Mapping struct {
+	Runtime string
+	Mode    string
+	User    string // User owning the subordinate ID ranges
+	UIDs    IDMap
+	GIDs    IDMap
+}
+
+// Enabled reports whether root in a container is not root on the host
+func (m Mapping) Enabled() bool {
+	return m.Mode != ""
+}
+
+// Detect asks the container runtime whether it uses a user namespace and reads the
+// subordinate ID ranges it maps to
+func Detect(ctx context.Context) (Mapping, error) {
+	runtimeName, options, err := runtimeInfo(ctx)
+	if err != nil {
+		return Mapping{Runtime: runtimeName}, fmt.Errorf("failed to query %s: %w", runtimeName, err)
+	}
+	mapping := Mapping{Runtime: runtimeName}
+
+	switch {
+	case strings.Contains(options, "name=userns"):
+		mapping.Mode = ModeRemap
+		mapping.User, err = remapUser()
+		if err != nil {
+			return mapping, err
+		}
+		uids, err := subordinateRange(subUIDPath, mapping.User)
+		if err != nil {
+			return mapping, err
+		}
+		gids, err := subordinateRange(subGIDPath, mapping.User)
+		if err != nil {
+			return mapping, err
+		}
+		mapping.UIDs = IDMap{Root: uids.Start, Start: uids.Start + 1, Size: uids.Size - 1}
+		mapping.GIDs = IDMap{Root: gids.Start, Start: gids.Start + 1, Size: gids.Size - 1}
+	case strings.Contains(options, "name=rootless"):
+		mapping.Mode = ModeRootless
+		current, err := currentUser()
+		if err != nil {
+			return mapping, fmt.Errorf("failed to look up the current user: %w", err)
+		}
+		mapping.User = current.Username
+		uid, _ := strconv.Atoi(current.Uid) //nolint:errcheck // Numeric on Linux
+		gid, _ := strconv.Atoi(current.Gid) //nolint:errcheck // Numeric on Linux
+		uids, err := subordinateRange(subUIDPath, current.Username)
+		if err != nil {
+			return mapping, err
+		}
+		gids, err := subordinateRange(subGIDPath, current.Username)
+		if err != nil {
+			return mapping, err
+		}
+		mapping.UIDs = IDMap{Root: uid, Start: uids.Start, Size: uids.Size}
+		mapping.GIDs = IDMap{Root: gid, Start: gids.Start, Size: gids.Size}
+	}
+	return mapping, nil
+}
+
+// remapUser returns the user named by userns-remap in the Docker daemon configuration
+func remapUser() (string, error) {
+	content, err := os.ReadFile(daemonConfigPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", daemonConfigPath, err)
+	}
+	var daemonConfig struct {
+		UsernsRemap string `json:"userns-remap"`
+	}
+	if err := json.Unmarshal(content, &daemonConfig); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", daemonConfigPath, err)
+	}
+	name := strings.SplitN(daemonConfig.UsernsRemap, ":", 2)[0]
+	switch name {
+	case "":
+		return "", fmt.Errorf("%s does not set userns-remap", daemonConfigPath)
+	case "default":
+		return defaultRemapUser, nil
+	default:
+		return name, nil
+	}
+}
+
+// subordinate is one line of /etc/subuid or /etc/subgid
+type subordinate struct {
+	Start int
+	Size  int
+}
+
+// subordinateRange returns the first range of a user in /etc/subuid or /etc/subgid
+func subordinateRange(path, name string) (subordinate, error) {
+	file, err := os.Open(path) //nolint:gosec // Fixed system file
+	if err != nil {
+		return subordinate{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close() //nolint:errcheck // Read-only
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ":")
+		if len(fields) != 3 || fields[0] != name {
+			continue
+		}
+		start, startErr := strconv.Atoi(fields[1])
+		size, sizeErr := strconv.Atoi(fields[2])
+		if startErr != nil || sizeErr != nil || size < 2 {
+			return subordinate{}, fmt.Errorf("invalid range for %s in %s: %s", name, path, scanner.Text())
+		}
+		return subordinate{Start: start, Size: size}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return subordinate{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return subordinate{}, fmt.Errorf("no range for %s in %s", name, path)
+}
+
+// Result counts the files visited by ShiftOwnership
+type Result struct {
+	Changed  int // Files given the mapped owner
+	Current  int // Files already owned by mapped IDs
+	Unmapped int // Files owned by IDs outside the mapped range, left alone
+}
+
+// ShiftOwnership gives the files below root the host IDs their owner has inside a container
+// of the mapping, so data created by containers running with host IDs stays accessible.
+// Files that already belong to the subordinate range are skipped, running it twice is safe.
+// With dryRun nothing is changed.
+func ShiftOwnership(root string, m Mapping, dryRun bool) (Result, error) {
+	var result Result
+	if !m.Enabled() {
+		return result, errors.New("containers do not run in a user namespace")
+	}
+
+	err := filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("cannot read the owner of %s", path)
+		}
+
+		uid, gid, state := shiftIDs(int(stat.Uid), int(stat.Gid), m)
+		switch state {
+		case shiftCurrent:
+			result.Current++
+			return nil
+		case shiftUnmapped:
+			result.Unmapped++
+			return nil
+		}
+		result.Changed++
+		if dryRun {
+			return nil
+		}
+		if err := os.Lchown(path, uid, gid); err != nil {
+			return fmt.Errorf("failed to change the owner of %s: %w", path, err)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	return result, err
+}
+
+type shiftState int
+
+const (
+	shiftChange shiftState = iota
+	shiftCurrent
+	shiftUnmapped
+)
+
+// shiftIDs returns the host owner of a file after the switch to a mapping
+func shiftIDs(uid, gid int, m Mapping) (int, int, shiftState) {
+	if m.UIDs.mapped(uid) && m.GIDs.mapped(gid) {
+		return uid, gid, shiftCurrent
+	}
+	newUID, newGID := uid, gid
+	if !m.UIDs.mapped(uid) {
+		newUID = m.UIDs.HostID(uid)
+	}
+	if !m.GIDs.mapped(gid) {
+		newGID = m.GIDs.HostID(gid)
+	}
+	if newUID < 0 || newGID < 0 {
+		return uid, gid, shiftUnmapped
+	}
+	return newUID, newGID, shiftChange
+}
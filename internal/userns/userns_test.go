@@ -0,0 +1,130 @@
+package userns
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	daemonConfigPath = writeFile(t, dir, "daemon.json", `{"userns-remap": "default"}`)
+	subUIDPath = writeFile(t, dir, "subuid", "alice:100000:65536\ndockremap:165536:65536\n")
+	subGIDPath = writeFile(t, dir, "subgid", "alice:100000:65536\ndockremap:231072:65536\n")
+	currentUser = func() (*user.User, error) {
+		return &user.User{Username: "alice", Uid: "1000", Gid: "1000"}, nil
+	}
+
+	t.Run("userns-remap", func(t *testing.T) {
+		runtimeInfo = func(context.Context) (string, string, error) {
+			return "docker", `["name=apparmor","name=seccomp,profile=builtin","name=userns"]`, nil
+		}
+		m, err := Detect(context.Background())
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if m.Mode != ModeRemap || m.User != "dockremap" {
+			t.Fatalf("Detect() = %+v", m)
+		}
+		if got := m.UIDs.HostID(0); got != 165536 {
+			t.Errorf("container root = host uid %d, want 165536", got)
+		}
+		if got := m.GIDs.HostID(1000); got != 232072 {
+			t.Errorf("container gid 1000 = host gid %d, want 232072", got)
+		}
+	})
+
+	t.Run("rootless", func(t *testing.T) {
+		runtimeInfo = func(context.Context) (string, string, error) {
+			return "podman", "name=rootless", nil
+		}
+		m, err := Detect(context.Background())
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if m.Mode != ModeRootless || m.UIDs.HostID(0) != 1000 || m.UIDs.HostID(1) != 100000 {
+			t.Errorf("Detect() = %+v", m)
+		}
+	})
+
+	t.Run("host IDs", func(t *testing.T) {
+		runtimeInfo = func(context.Context) (string, string, error) {
+			return "docker", `["name=apparmor","name=seccomp,profile=builtin"]`, nil
+		}
+		m, err := Detect(context.Background())
+		if err != nil || m.Enabled() {
+			t.Errorf("Detect() = %+v, %v; want no mapping", m, err)
+		}
+	})
+}
+
+func TestShiftIDs(t *testing.T) {
+	m := Mapping{
+		Mode: ModeRemap,
+		UIDs: IDMap{Root: 100000, Start: 100001, Size: 65535},
+		GIDs: IDMap{Root: 100000, Start: 100001, Size: 65535},
+	}
+
+	cases := []struct {
+		uid, gid         int
+		wantUID, wantGID int
+		want             shiftState
+	}{
+		{0, 0, 100000, 100000, shiftChange},
+		{999, 999, 100999, 100999, shiftChange},
+		{100999, 100999, 100999, 100999, shiftCurrent},
+		{100999, 0, 100999, 100000, shiftChange},
+		{70000, 0, 70000, 0, shiftUnmapped},
+	}
+	for _, tc := range cases {
+		uid, gid, state := shiftIDs(tc.uid, tc.gid, m)
+		if uid != tc.wantUID || gid != tc.wantGID || state != tc.want {
+			t.Errorf("shiftIDs(%d, %d) = %d, %d, %v; want %d, %d, %v", tc.uid, tc.gid, uid, gid, state, tc.wantUID, tc.wantGID, tc.want)
+		}
+	}
+}
+
+func TestShiftOwnershipDryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "data.db", "")
+	uid, gid := os.Getuid(), os.Getgid()
+	m := Mapping{
+		Mode: ModeRemap,
+		UIDs: IDMap{Root: 200000, Start: 200001, Size: uid + gid + 1000},
+		GIDs: IDMap{Root: 200000, Start: 200001, Size: uid + gid + 1000},
+	}
+
+	result, err := ShiftOwnership(dir, m, true)
+	if err != nil {
+		t.Fatalf("ShiftOwnership() error = %v", err)
+	}
+	if result.Changed != 2 {
+		t.Errorf("ShiftOwnership() = %+v, want the directory and file changed", result)
+	}
+	info, err := os.Stat(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat := info.Sys().(*syscall.Stat_t); int(stat.Uid) != uid {
+		t.Errorf("dry run changed the owner to %d", stat.Uid)
+	}
+
+	if result, err := ShiftOwnership(filepath.Join(dir, "missing"), m, true); err != nil || result.Changed != 0 {
+		t.Errorf("ShiftOwnership() of a missing directory = %+v, %v", result, err)
+	}
+	if _, err := ShiftOwnership(dir, Mapping{}, true); err == nil {
+		t.Error("ShiftOwnership() without a mapping succeeded")
+	}
+}
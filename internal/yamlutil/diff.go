@@ -0,0 +1,107 @@
+package yamlutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around a change
+	diffContext = 3
+	// maxDiffLines bounds the table of the line matching, longer files are shown as
+	// replaced as a whole
+	maxDiffLines = 2000
+)
+
+// LineDiff returns a unified diff of two versions of a file, empty if they are equal
+func LineDiff(before, after []byte) string {
+	if string(before) == string(after) {
+		return ""
+	}
+	a := strings.Split(strings.TrimSuffix(string(before), "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(string(after), "\n"), "\n")
+
+	// Longest common subsequence of lines; without the table all lines count as changed
+	var lcs [][]int
+	if len(a) <= maxDiffLines && len(b) <= maxDiffLines {
+		lcs = make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		text string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case lcs != nil && i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs == nil || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		// A hunk runs until diffContext*2 unchanged lines separate it from the next change
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			} else if k-end >= 2*diffContext {
+				break
+			}
+		}
+		from, to := max(start-diffContext, 0), min(end+diffContext, len(ops))
+
+		lineA, lineB := 1, 1
+		for _, o := range ops[:from] {
+			if o.kind != '+' {
+				lineA++
+			}
+			if o.kind != '-' {
+				lineB++
+			}
+		}
+		countA, countB := 0, 0
+		for _, o := range ops[from:to] {
+			if o.kind != '+' {
+				countA++
+			}
+			if o.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, o := range ops[from:to] {
+			out.WriteByte(o.kind)
+			out.WriteString(o.text)
+			out.WriteByte('\n')
+		}
+		start = to
+	}
+	return out.String()
+}
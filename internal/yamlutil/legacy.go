@@ -0,0 +1,324 @@
+package yamlutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LegacyFinding is a construct of the docker-compose v1 era in a compose file, which
+// Compose v2 rejects, ignores or reads differently
+type LegacyFinding struct {
+	Line    int    `json:"line"`
+	Service string `json:"service,omitempty"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"` // The rewrite ModernizeCompose applies, empty if it needs a manual change
+}
+
+// legacyEdit rewrites the lines of one finding. Edits only touch their own line, or remove
+// it, so they are applied from the bottom up.
+type legacyEdit struct {
+	finding int
+	line    int
+	apply   func(lines []string) ([]string, error)
+}
+
+// legacyCheck is the state of a check of one compose file
+type legacyCheck struct {
+	lines    []string
+	findings []LegacyFinding
+	edits    []legacyEdit
+	wrap     bool // Services are at the top level and move under services:
+}
+
+// LegacyFindings returns the legacy constructs of a compose file, ordered by line
+func LegacyFindings(content []byte) ([]LegacyFinding, error) {
+	c, err := checkLegacy(content)
+	if err != nil {
+		return nil, err
+	}
+	return c.findings, nil
+}
+
+// ModernizeCompose rewrites the legacy constructs of a compose file that have a safe
+// equivalent, in the text itself like the edits in patch.go. The findings tell what was
+// rewritten and what is left to change by hand.
+func ModernizeCompose(content []byte) ([]byte, []LegacyFinding, error) {
+	c, err := checkLegacy(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines := c.lines
+	sort.SliceStable(c.edits, func(i, j int) bool { return c.edits[i].line > c.edits[j].line })
+	for _, edit := range c.edits {
+		edited, err := edit.apply(append([]string(nil), lines...))
+		if err != nil {
+			c.findings[edit.finding].Fix = ""
+			continue
+		}
+		lines = edited
+	}
+	if c.wrap {
+		for i, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				lines[i] = "  " + line
+			}
+		}
+		lines = append([]string{"services:\n"}, lines...)
+	}
+
+	result := []byte(strings.Join(lines, ""))
+	var check map[string]interface{}
+	if err := yaml.Unmarshal(result, &check); err != nil {
+		return nil, nil, fmt.Errorf("rewritten compose file doesn't parse: %w", err)
+	}
+	return result, c.findings, nil
+}
+
+func checkLegacy(content []byte) (*legacyCheck, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("compose file is not a mapping")
+	}
+	root := doc.Content[0]
+	c := &legacyCheck{lines: splitLines(content)}
+
+	if key, value := mappingPair(root, "version"); value != nil {
+		f := c.add(LegacyFinding{
+			Line:    key.Line,
+			Key:     "version",
+			Message: "The version key is obsolete, Compose v2 ignores it and warns about it on every command",
+		})
+		if value.Kind == yaml.ScalarNode && value.Line == key.Line && key.Column == 1 {
+			c.findings[f].Fix = "Remove the version line"
+			c.edits = append(c.edits, legacyEdit{finding: f, line: key.Line, apply: removeLine(key.Line)})
+		}
+	}
+
+	_, services := mappingPair(root, "services")
+	if services == nil {
+		services = v1Services(root)
+		if services != nil {
+			f := c.add(LegacyFinding{
+				Line:    root.Content[0].Line,
+				Key:     "services",
+				Message: "Services are defined at the top level like in the v1 file format, Compose v2 doesn't find them there",
+			})
+			if root.Style&yaml.FlowStyle == 0 && !hasDocumentMarkers(c.lines) {
+				c.findings[f].Fix = "Move the services under a services key"
+				c.wrap = true
+			}
+		}
+	}
+	if services != nil && services.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			if svc := services.Content[i+1]; svc.Kind == yaml.MappingNode {
+				c.checkService(services.Content[i].Value, svc, services)
+			}
+		}
+	}
+
+	for _, section := range []string{"networks", "volumes"} {
+		_, top := mappingPair(root, section)
+		if top == nil || top.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(top.Content); i += 2 {
+			if _, external := mappingPair(top.Content[i+1], "external"); external != nil && external.Kind == yaml.MappingNode {
+				if nameKey, _ := mappingPair(external, "name"); nameKey != nil {
+					c.add(LegacyFinding{
+						Line:    nameKey.Line,
+						Key:     section + "." + top.Content[i].Value + ".external.name",
+						Message: "external.name is deprecated, set external: true and the name next to it",
+					})
+				}
+			}
+		}
+	}
+
+	c.sortFindings()
+	return c, nil
+}
+
+// add records a finding and returns its index
+func (c *legacyCheck) add(f LegacyFinding) int {
+	c.findings = append(c.findings, f)
+	return len(c.findings) - 1
+}
+
+// sortFindings orders the findings by line and updates the indexes the edits refer to
+func (c *legacyCheck) sortFindings() {
+	index := make([]int, len(c.findings))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool { return c.findings[index[i]].Line < c.findings[index[j]].Line })
+	findings := make([]LegacyFinding, len(c.findings))
+	position := make([]int, len(c.findings))
+	for to, from := range index {
+		findings[to] = c.findings[from]
+		position[from] = to
+	}
+	for i := range c.edits {
+		c.edits[i].finding = position[c.edits[i].finding]
+	}
+	c.findings = findings
+}
+
+// checkService checks the keys of one service
+func (c *legacyCheck) checkService(name string, svc, services *yaml.Node) {
+	if key, value := mappingPair(svc, "net"); value != nil {
+		f := c.add(LegacyFinding{
+			Line:    key.Line,
+			Service: name,
+			Key:     "net",
+			Message: "net was replaced by network_mode",
+		})
+		if key.Style == 0 {
+			c.findings[f].Fix = "Rename net to network_mode"
+			c.edits = append(c.edits, legacyEdit{finding: f, line: key.Line, apply: renameKey(key, "network_mode")})
+		}
+	}
+
+	if key, value := mappingPair(svc, "links"); value != nil {
+		f := c.add(LegacyFinding{
+			Line:    key.Line,
+			Service: name,
+			Key:     "links",
+			Message: "links is a legacy feature, services on the same network reach each other by their service name",
+		})
+		depends, _ := mappingPair(svc, "depends_on")
+		if depends == nil && key.Style == 0 && plainLinks(value) {
+			c.findings[f].Fix = "Rename links to depends_on, which keeps the start order"
+			c.edits = append(c.edits, legacyEdit{finding: f, line: key.Line, apply: renameKey(key, "depends_on")})
+		} else {
+			c.findings[f].Message += "; use the service name as host name or set aliases on the network"
+		}
+	}
+
+	if key, _ := mappingPair(svc, "external_links"); key != nil {
+		c.add(LegacyFinding{
+			Line:    key.Line,
+			Service: name,
+			Key:     "external_links",
+			Message: "external_links only works with the default bridge network; connect the containers through a shared external network instead",
+		})
+	}
+
+	if key, _ := mappingPair(svc, "dockerfile"); key != nil {
+		c.add(LegacyFinding{
+			Line:    key.Line,
+			Service: name,
+			Key:     "dockerfile",
+			Message: "dockerfile belongs into the build section, next to its context",
+		})
+	}
+
+	for _, legacy := range []string{"log_driver", "log_opt"} {
+		if key, _ := mappingPair(svc, legacy); key != nil {
+			c.add(LegacyFinding{
+				Line:    key.Line,
+				Service: name,
+				Key:     legacy,
+				Message: legacy + " was replaced by the driver and options of a logging section",
+			})
+		}
+	}
+
+	// v1 took bare container names in volumes_from, v2 only reads them as service names
+	if _, from := mappingPair(svc, "volumes_from"); from != nil && from.Kind == yaml.SequenceNode {
+		for _, item := range from.Content {
+			source, _, _ := strings.Cut(item.Value, ":")
+			if item.Kind != yaml.ScalarNode || source == "container" || source == "service" {
+				continue
+			}
+			if _, known := mappingPair(services, source); known != nil {
+				continue
+			}
+			f := c.add(LegacyFinding{
+				Line:    item.Line,
+				Service: name,
+				Key:     "volumes_from",
+				Message: fmt.Sprintf("%s is no service of this file, Compose v2 only finds containers with a container: prefix", source),
+			})
+			value := "container:" + item.Value
+			c.findings[f].Fix = "Use " + value
+			node := item
+			c.edits = append(c.edits, legacyEdit{finding: f, line: item.Line, apply: func(lines []string) ([]string, error) {
+				return lines, replaceScalar(lines, node, value)
+			}})
+		}
+	}
+}
+
+// v1Services returns the top-level mapping if it holds services like a v1 file, i.e. no
+// services key and only entries with an image or a build
+func v1Services(root *yaml.Node) *yaml.Node {
+	if len(root.Content) == 0 {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value == "version" || strings.HasPrefix(key.Value, "x-") {
+			return nil
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil
+		}
+		if _, image := mappingPair(value, "image"); image == nil {
+			if _, build := mappingPair(value, "build"); build == nil {
+				return nil
+			}
+		}
+	}
+	return root
+}
+
+// plainLinks reports whether links only names services, without aliases
+func plainLinks(links *yaml.Node) bool {
+	if links.Kind != yaml.SequenceNode || len(links.Content) == 0 {
+		return false
+	}
+	for _, item := range links.Content {
+		if item.Kind != yaml.ScalarNode || strings.Contains(item.Value, ":") {
+			return false
+		}
+	}
+	return true
+}
+
+func hasDocumentMarkers(lines []string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "...") {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLine returns an edit that removes the 1-based line
+func removeLine(line int) func([]string) ([]string, error) {
+	return func(lines []string) ([]string, error) {
+		return append(lines[:line-1], lines[line:]...), nil
+	}
+}
+
+// renameKey returns an edit that renames a plain mapping key in place
+func renameKey(key *yaml.Node, name string) func([]string) ([]string, error) {
+	return func(lines []string) ([]string, error) {
+		line := lines[key.Line-1]
+		start := byteOffset(line, key.Column)
+		if !strings.HasPrefix(line[start:], key.Value) {
+			return nil, fmt.Errorf("line %d: key %s not found", key.Line, key.Value)
+		}
+		lines[key.Line-1] = line[:start] + name + line[start+len(key.Value):]
+		return lines, nil
+	}
+}
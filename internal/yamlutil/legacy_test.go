@@ -0,0 +1,111 @@
+package yamlutil
+
+import (
+	"strings"
+	"testing"
+)
+
+const legacyCompose = `version: '3.8'
+
+services:
+  web:
+    image: wordpress:6 # pinned
+    links:
+      - db
+    net: "bridge"
+    volumes_from:
+      - db
+      - backup_data
+  db:
+    image: mariadb:11
+    external_links:
+      - redis_1:redis
+    log_driver: syslog
+networks:
+  proxy:
+    external:
+      name: proxy_net
+`
+
+func TestModernizeCompose(t *testing.T) {
+	got, findings, err := ModernizeCompose([]byte(legacyCompose))
+	if err != nil {
+		t.Fatalf("ModernizeCompose() error = %v", err)
+	}
+	want := `
+services:
+  web:
+    image: wordpress:6 # pinned
+    depends_on:
+      - db
+    network_mode: "bridge"
+    volumes_from:
+      - db
+      - container:backup_data
+  db:
+    image: mariadb:11
+    external_links:
+      - redis_1:redis
+    log_driver: syslog
+networks:
+  proxy:
+    external:
+      name: proxy_net
+`
+	if string(got) != want {
+		t.Errorf("ModernizeCompose() =\n%s\nwant\n%s", got, want)
+	}
+
+	var keys []string
+	fixes := 0
+	for _, f := range findings {
+		keys = append(keys, f.Key)
+		if f.Fix != "" {
+			fixes++
+		}
+	}
+	wantKeys := "version,links,net,volumes_from,external_links,log_driver,networks.proxy.external.name"
+	if strings.Join(keys, ",") != wantKeys {
+		t.Errorf("finding keys = %s, want %s", strings.Join(keys, ","), wantKeys)
+	}
+	if fixes != 4 {
+		t.Errorf("%d findings have a fix, want 4: %+v", fixes, findings)
+	}
+}
+
+func TestModernizeComposeV1Format(t *testing.T) {
+	content := "# Blog from 2016\nweb:\n  build: .\n  links:\n    - db:database\ndb:\n  image: postgres:9.6\n"
+	got, findings, err := ModernizeCompose([]byte(content))
+	if err != nil {
+		t.Fatalf("ModernizeCompose() error = %v", err)
+	}
+	want := "services:\n  # Blog from 2016\n  web:\n    build: .\n    links:\n      - db:database\n  db:\n    image: postgres:9.6\n"
+	if string(got) != want {
+		t.Errorf("ModernizeCompose() =\n%s\nwant\n%s", got, want)
+	}
+	if len(findings) != 2 || findings[0].Key != "services" || findings[1].Fix != "" {
+		t.Errorf("findings = %+v", findings)
+	}
+}
+
+func TestLegacyFindingsModernFile(t *testing.T) {
+	findings, err := LegacyFindings([]byte(patchCompose))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("findings in a v2 file = %+v", findings)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	after := "b\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\n"
+	want := "@@ -1,4 +1,3 @@\n-a\n b\n c\n d\n@@ -9,4 +8,4 @@\n i\n j\n k\n-l\n+L\n"
+	if got := LineDiff([]byte(before), []byte(after)); got != want {
+		t.Errorf("LineDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := LineDiff([]byte(before), []byte(before)); got != "" {
+		t.Errorf("LineDiff() of equal files = %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package yamlutil
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintFinding is a reference in a compose file that Docker Compose would reject
+type LintFinding struct {
+	Service string `json:"service,omitempty"`
+	Message string `json:"message"`
+}
+
+// ServiceChange is how a service differs between two versions of a compose file
+type ServiceChange struct {
+	Service     string   `json:"service"`
+	Kind        string   `json:"kind"` // added, removed or changed
+	ImageBefore string   `json:"image_before,omitempty"`
+	ImageAfter  string   `json:"image_after,omitempty"`
+	Keys        []string `json:"keys,omitempty"` // Settings that changed, for changed services
+}
+
+// Kinds of ServiceChange
+const (
+	ServiceAdded   = "added"
+	ServiceRemoved = "removed"
+	ServiceChanged = "changed"
+)
+
+// LintCompose checks that the services, volumes and networks a compose file refers to are
+// defined in it. ValidateComposeFile checks the structure of the file.
+func LintCompose(content []byte) ([]LintFinding, error) {
+	var compose map[string]interface{}
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		return nil, fmt.Errorf("invalid YAML syntax: %w", err)
+	}
+	services, _ := compose["services"].(map[string]interface{})
+	volumes, _ := compose["volumes"].(map[string]interface{})
+	networks, _ := compose["networks"].(map[string]interface{})
+
+	var findings []LintFinding
+	for _, name := range sortedKeys(services) {
+		service, ok := services[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, dependency := range keysOrItems(service["depends_on"]) {
+			if _, defined := services[dependency]; !defined {
+				findings = append(findings, LintFinding{Service: name, Message: fmt.Sprintf("depends on service '%s', which is not defined", dependency)})
+			}
+		}
+		for _, volume := range namedVolumes(service["volumes"]) {
+			if _, defined := volumes[volume]; !defined {
+				findings = append(findings, LintFinding{Service: name, Message: fmt.Sprintf("uses volume '%s', which is not declared under volumes", volume)})
+			}
+		}
+		for _, network := range keysOrItems(service["networks"]) {
+			if _, defined := networks[network]; !defined && network != "default" {
+				findings = append(findings, LintFinding{Service: name, Message: fmt.Sprintf("uses network '%s', which is not declared under networks", network)})
+			}
+		}
+		if mode, _ := service["network_mode"].(string); strings.HasPrefix(mode, "service:") {
+			if _, defined := services[strings.TrimPrefix(mode, "service:")]; !defined {
+				findings = append(findings, LintFinding{Service: name, Message: fmt.Sprintf("network_mode %s names a service that is not defined", mode)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// ServiceChanges lists the services added, removed or changed from before to after, by
+// service name
+func ServiceChanges(before, after []byte) ([]ServiceChange, error) {
+	var a, b map[string]interface{}
+	if err := yaml.Unmarshal(before, &a); err != nil {
+		return nil, fmt.Errorf("invalid YAML syntax: %w", err)
+	}
+	if err := yaml.Unmarshal(after, &b); err != nil {
+		return nil, fmt.Errorf("invalid YAML syntax: %w", err)
+	}
+	servicesA, _ := a["services"].(map[string]interface{})
+	servicesB, _ := b["services"].(map[string]interface{})
+
+	names := sortedKeys(servicesA)
+	for _, name := range sortedKeys(servicesB) {
+		if _, ok := servicesA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []ServiceChange
+	for _, name := range names {
+		serviceA, inA := servicesA[name].(map[string]interface{})
+		serviceB, inB := servicesB[name].(map[string]interface{})
+		imageA, _ := serviceA["image"].(string)
+		imageB, _ := serviceB["image"].(string)
+		switch {
+		case !inA:
+			changes = append(changes, ServiceChange{Service: name, Kind: ServiceAdded, ImageAfter: imageB})
+		case !inB:
+			changes = append(changes, ServiceChange{Service: name, Kind: ServiceRemoved, ImageBefore: imageA})
+		default:
+			var keys []string
+			for _, key := range sortedKeys(serviceA) {
+				if value, ok := serviceB[key]; !ok || !reflect.DeepEqual(serviceA[key], value) {
+					keys = append(keys, key)
+				}
+			}
+			for _, key := range sortedKeys(serviceB) {
+				if _, ok := serviceA[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			if len(keys) == 0 {
+				continue
+			}
+			sort.Strings(keys)
+			change := ServiceChange{Service: name, Kind: ServiceChanged, Keys: keys}
+			if imageA != imageB {
+				change.ImageBefore, change.ImageAfter = imageA, imageB
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keysOrItems returns the names of a list or map setting such as depends_on or networks
+func keysOrItems(value interface{}) []string {
+	var names []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+	case map[string]interface{}:
+		names = sortedKeys(v)
+	}
+	return names
+}
+
+// namedVolumes returns the named volumes a service mounts, bind mounts and anonymous
+// volumes are left out
+func namedVolumes(value interface{}) []string {
+	items, _ := value.([]interface{})
+	var names []string
+	for _, item := range items {
+		var source string
+		switch v := item.(type) {
+		case string:
+			if parts := strings.SplitN(v, ":", 2); len(parts) == 2 {
+				source = parts[0]
+			}
+		case map[string]interface{}:
+			if kind, _ := v["type"].(string); kind == "volume" {
+				source, _ = v["source"].(string)
+			}
+		}
+		if source == "" || strings.ContainsAny(source[:1], "/.~$") {
+			continue
+		}
+		names = append(names, source)
+	}
+	return names
+}
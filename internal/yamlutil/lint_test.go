@@ -0,0 +1,88 @@
+package yamlutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintCompose(t *testing.T) {
+	compose := `services:
+  web:
+    image: nginx
+    depends_on: [db, cache]
+    networks: [default, proxy, backend]
+    volumes:
+      - ./html:/usr/share/nginx/html
+      - static:/static
+      - uploads:/uploads
+      - /cache
+      - type: volume
+        source: logs
+        target: /logs
+  db:
+    image: postgres
+    depends_on:
+      web:
+        condition: service_started
+    network_mode: "service:vpn"
+volumes:
+  static:
+networks:
+  proxy:
+`
+	findings, err := LintCompose([]byte(compose))
+	if err != nil {
+		t.Fatalf("LintCompose() error = %v", err)
+	}
+	want := []LintFinding{
+		{Service: "db", Message: "network_mode service:vpn names a service that is not defined"},
+		{Service: "web", Message: "depends on service 'cache', which is not defined"},
+		{Service: "web", Message: "uses volume 'uploads', which is not declared under volumes"},
+		{Service: "web", Message: "uses volume 'logs', which is not declared under volumes"},
+		{Service: "web", Message: "uses network 'backend', which is not declared under networks"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("LintCompose() =\n%+v\nwant\n%+v", findings, want)
+	}
+
+	if _, err := LintCompose([]byte("services: [")); err == nil {
+		t.Error("LintCompose() of invalid YAML returned no error")
+	}
+}
+
+func TestServiceChanges(t *testing.T) {
+	before := `services:
+  web:
+    image: nginx:1.25
+    ports: ["8080:80"]
+  db:
+    image: postgres:16
+  cache:
+    image: redis:7
+`
+	after := `services:
+  web:
+    image: nginx:1.27
+    ports: ["8081:80"]
+    restart: unless-stopped
+  db:
+    image: postgres:16 # unchanged
+  worker:
+    image: app:latest
+`
+	changes, err := ServiceChanges([]byte(before), []byte(after))
+	if err != nil {
+		t.Fatalf("ServiceChanges() error = %v", err)
+	}
+	want := []ServiceChange{
+		{Service: "cache", Kind: ServiceRemoved, ImageBefore: "redis:7"},
+		{Service: "web", Kind: ServiceChanged, ImageBefore: "nginx:1.25", ImageAfter: "nginx:1.27", Keys: []string{"image", "ports", "restart"}},
+		{Service: "worker", Kind: ServiceAdded, ImageAfter: "app:latest"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("ServiceChanges() =\n%+v\nwant\n%+v", changes, want)
+	}
+	if changes, _ := ServiceChanges([]byte(before), []byte(before)); len(changes) != 0 {
+		t.Errorf("ServiceChanges() of equal files = %+v", changes)
+	}
+}
@@ -0,0 +1,713 @@
+package yamlutil
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The edits in this file change single values of a compose or .env file in the text itself,
+// so comments, key order, quoting and indentation of the rest of the file stay untouched.
+
+var (
+	envKeyPattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	imageTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+)
+
+// ValidateEnvKey checks that key is a valid environment variable name
+func ValidateEnvKey(key string) error {
+	if !envKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid environment variable name %q", key)
+	}
+	return nil
+}
+
+// ReplaceImageTag returns image with its tag, or digest, replaced by tag
+func ReplaceImageTag(image, tag string) (string, error) {
+	if !imageTagPattern.MatchString(tag) {
+		return "", fmt.Errorf("invalid image tag %q", tag)
+	}
+	repository, _, _ := strings.Cut(image, "@")
+	if colon := strings.LastIndex(repository, ":"); colon > strings.LastIndex(repository, "/") {
+		repository = repository[:colon]
+	}
+	if repository == "" {
+		return "", fmt.Errorf("image %q has no repository", image)
+	}
+	return repository + ":" + tag, nil
+}
+
+// ServiceImage returns the image of a compose service, empty if it only has a build section
+func ServiceImage(content []byte, service string) (string, error) {
+	_, svc, err := findService(content, service)
+	if err != nil {
+		return "", err
+	}
+	if _, image := mappingPair(svc, "image"); image != nil {
+		return image.Value, nil
+	}
+	return "", nil
+}
+
+// SetServiceImage sets the image of a compose service
+func SetServiceImage(content []byte, service, image string) ([]byte, error) {
+	if image == "" || strings.ContainsAny(image, " \t\r\n") {
+		return nil, fmt.Errorf("invalid image %q", image)
+	}
+	return SetServiceKey(content, service, "image", image)
+}
+
+// SetServiceKey sets a single-line string value of a compose service, such as its image
+// or cpuset. A missing key is added as the first key of the service.
+func SetServiceKey(content []byte, service, key, value string) ([]byte, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return nil, fmt.Errorf("value of %s must be a single line", key)
+	}
+	svcKey, svc, err := findService(content, service)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(content)
+	if _, node := mappingPair(svc, key); node != nil {
+		if err := replaceScalar(lines, node, value); err != nil {
+			return nil, fmt.Errorf("failed to set %s of service %s: %w", key, service, err)
+		}
+		return []byte(strings.Join(lines, "")), nil
+	}
+	indent, _, err := childIndent(lines, svcKey, svc)
+	if err != nil {
+		return nil, err
+	}
+	return insertLines(lines, svc.Content[0].Line, indent+key+": "+formatScalar(value, 0)+"\n"), nil
+}
+
+// SetMetadataKey sets a single-line string value of the x-ontree block of a compose file,
+// such as the namespace of an app. A missing block is added at the end of the file.
+func SetMetadataKey(content []byte, key, value string) ([]byte, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return nil, fmt.Errorf("value of %s must be a single line", key)
+	}
+	return setMetadata(content, key, formatScalar(value, 0), func(node *yaml.Node) string {
+		return formatScalar(value, node.Style)
+	})
+}
+
+// SetMetadataInt sets a number of the x-ontree block of a compose file, such as the
+// host_port of an app
+func SetMetadataInt(content []byte, key string, value int) ([]byte, error) {
+	text := strconv.Itoa(value)
+	return setMetadata(content, key, text, func(*yaml.Node) string { return text })
+}
+
+// SetMetadataBool sets a boolean of the x-ontree block of a compose file, such as the
+// autostart of an app
+func SetMetadataBool(content []byte, key string, value bool) ([]byte, error) {
+	text := strconv.FormatBool(value)
+	return setMetadata(content, key, text, func(*yaml.Node) string { return text })
+}
+
+// setMetadata sets a key of the x-ontree block to text, or to the text replace returns for
+// the node of an existing value
+func setMetadata(content []byte, key, text string, replace func(node *yaml.Node) string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("compose file is not a mapping")
+	}
+	lines := splitLines(content)
+	metaKey, meta := mappingPair(doc.Content[0], "x-ontree")
+	if meta == nil || (meta.Kind == yaml.ScalarNode && meta.Tag == "!!null") {
+		result := string(content)
+		if meta != nil {
+			// An empty x-ontree: line, replaced by the block
+			lines = append(lines[:metaKey.Line-1], lines[metaKey.Line:]...)
+			result = strings.Join(lines, "")
+		}
+		if result != "" && !strings.HasSuffix(result, "\n") {
+			result += "\n"
+		}
+		return []byte(result + "x-ontree:\n  " + key + ": " + text + "\n"), nil
+	}
+	if meta.Kind != yaml.MappingNode || meta.Style&yaml.FlowStyle != 0 || len(meta.Content) == 0 {
+		return nil, fmt.Errorf("x-ontree must be a block mapping to edit it")
+	}
+	if _, node := mappingPair(meta, key); node != nil {
+		if err := replaceScalarText(lines, node, replace(node)); err != nil {
+			return nil, fmt.Errorf("failed to set %s of x-ontree: %w", key, err)
+		}
+		return []byte(strings.Join(lines, "")), nil
+	}
+	indent, _, err := childIndent(lines, metaKey, meta)
+	if err != nil {
+		return nil, err
+	}
+	return insertLines(lines, meta.Content[0].Line, indent+key+": "+text+"\n"), nil
+}
+
+// RemoveServiceKey removes a key with a single-line value from a compose service. A
+// missing key is no error.
+func RemoveServiceKey(content []byte, service, key string) ([]byte, error) {
+	_, svc, err := findService(content, service)
+	if err != nil {
+		return nil, err
+	}
+	keyNode, node := mappingPair(svc, key)
+	if node == nil {
+		return content, nil
+	}
+	if node.Kind != yaml.ScalarNode || node.Line != keyNode.Line || len(svc.Content) == 2 {
+		return nil, fmt.Errorf("%s of service %s can't be removed in place", key, service)
+	}
+	lines := splitLines(content)
+	if _, err := linePrefix(lines, keyNode); err != nil {
+		return nil, fmt.Errorf("%s of service %s can't be removed in place", key, service)
+	}
+	lines = append(lines[:keyNode.Line-1], lines[keyNode.Line:]...)
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// SetServicePortsHostIP binds all published ports of a compose service to a host address,
+// or to all interfaces if hostIP is empty. Short mappings get the address as their first
+// field, long ones a host_ip key.
+func SetServicePortsHostIP(content []byte, service, hostIP string) ([]byte, error) {
+	_, svc, err := findService(content, service)
+	if err != nil {
+		return nil, err
+	}
+	_, ports := mappingPair(svc, "ports")
+	if ports == nil || len(ports.Content) == 0 {
+		return nil, fmt.Errorf("service %s publishes no ports", service)
+	}
+	if ports.Kind != yaml.SequenceNode || ports.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("ports of service %s must be a block list to edit them", service)
+	}
+
+	lines := splitLines(content)
+	// Go from the last entry up, added and removed lines don't move the ones above
+	for i := len(ports.Content) - 1; i >= 0; i-- {
+		item := ports.Content[i]
+		switch item.Kind {
+		case yaml.ScalarNode:
+			_, published, target := splitPortMapping(item.Value)
+			if err := replaceScalar(lines, item, joinPortMapping(hostIP, published, target)); err != nil {
+				return nil, fmt.Errorf("failed to bind ports of service %s: %w", service, err)
+			}
+		case yaml.MappingNode:
+			if item.Style&yaml.FlowStyle != 0 || len(item.Content) == 0 {
+				return nil, fmt.Errorf("ports of service %s must be block mappings to edit them", service)
+			}
+			keyNode, value := mappingPair(item, "host_ip")
+			switch {
+			case value != nil && hostIP != "":
+				if err := replaceScalar(lines, value, hostIP); err != nil {
+					return nil, fmt.Errorf("failed to bind ports of service %s: %w", service, err)
+				}
+			case value != nil:
+				if value.Line != keyNode.Line || len(item.Content) == 2 {
+					return nil, fmt.Errorf("host_ip of service %s can't be removed in place", service)
+				}
+				if _, err := linePrefix(lines, keyNode); err != nil {
+					return nil, fmt.Errorf("host_ip of service %s can't be removed in place", service)
+				}
+				lines = append(lines[:keyNode.Line-1], lines[keyNode.Line:]...)
+			case hostIP != "":
+				// Add the key below the first one, which may share its line with the dash
+				first, firstValue := item.Content[0], item.Content[1]
+				if firstValue.Line != first.Line || firstValue.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("ports of service %s must start with a single-line key to edit them", service)
+				}
+				indent := strings.Repeat(" ", first.Column-1)
+				if !strings.HasSuffix(lines[first.Line-1], "\n") {
+					lines[first.Line-1] += "\n"
+				}
+				lines = splitLines(insertLines(lines, first.Line+1, indent+"host_ip: "+formatScalar(hostIP, 0)+"\n"))
+			}
+		default:
+			return nil, fmt.Errorf("ports of service %s must be strings or mappings", service)
+		}
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// ReplaceHostPort changes the host port from to to in the published ports of all services of
+// a compose file, keeping the address and container port. Ranges and ports set by a variable
+// are left alone. It returns how many ports were changed.
+func ReplaceHostPort(content []byte, from, to int) ([]byte, int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, 0, fmt.Errorf("compose file is not a mapping")
+	}
+	_, services := mappingPair(doc.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return content, 0, nil
+	}
+
+	lines := splitLines(content)
+	oldPort, newPort := strconv.Itoa(from), strconv.Itoa(to)
+	changed := 0
+	for i := 1; i < len(services.Content); i += 2 {
+		name := services.Content[i-1].Value
+		_, ports := mappingPair(services.Content[i], "ports")
+		if ports == nil || ports.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, item := range ports.Content {
+			var err error
+			switch item.Kind {
+			case yaml.ScalarNode:
+				hostIP, published, target := splitPortMapping(item.Value)
+				if published != oldPort {
+					continue
+				}
+				err = replaceScalar(lines, item, joinPortMapping(hostIP, newPort, target))
+			case yaml.MappingNode:
+				_, value := mappingPair(item, "published")
+				if value == nil || value.Value != oldPort {
+					continue
+				}
+				text := newPort
+				if value.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+					text = formatScalar(newPort, value.Style)
+				}
+				err = replaceScalarText(lines, value, text)
+			default:
+				continue
+			}
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to change port %d of service %s: %w", from, name, err)
+			}
+			changed++
+		}
+	}
+	return []byte(strings.Join(lines, "")), changed, nil
+}
+
+// ServiceCPUSets returns the cpuset of each service of a compose file, empty for services
+// that aren't pinned
+func ServiceCPUSets(content []byte) (map[string]string, error) {
+	var compose struct {
+		Services map[string]struct {
+			CPUSet string `yaml:"cpuset"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	cpusets := make(map[string]string)
+	for name, service := range compose.Services {
+		cpusets[name] = service.CPUSet
+	}
+	return cpusets, nil
+}
+
+// SetServiceEnv sets an environment variable in the environment section of a compose
+// service, which can be a mapping or a list of KEY=VALUE entries. A new variable is added
+// as the first entry; a service without environment section gets one.
+func SetServiceEnv(content []byte, service, key, value string) ([]byte, error) {
+	if err := ValidateEnvKey(key); err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return nil, fmt.Errorf("value of %s must be a single line", key)
+	}
+	svcKey, svc, err := findService(content, service)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(content)
+
+	_, env := mappingPair(svc, "environment")
+	if env == nil {
+		indent, step, err := childIndent(lines, svcKey, svc)
+		if err != nil {
+			return nil, err
+		}
+		block := indent + "environment:\n" + indent + step + key + ": " + formatScalar(value, 0) + "\n"
+		return insertLines(lines, svc.Content[0].Line, block), nil
+	}
+	if env.Style&yaml.FlowStyle != 0 || len(env.Content) == 0 {
+		return nil, fmt.Errorf("environment of service %s must be a block mapping or list to edit it", service)
+	}
+
+	switch env.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(env.Content); i += 2 {
+			if env.Content[i].Value == key {
+				if err := replaceScalar(lines, env.Content[i+1], value); err != nil {
+					return nil, fmt.Errorf("failed to set %s of service %s: %w", key, service, err)
+				}
+				return []byte(strings.Join(lines, "")), nil
+			}
+		}
+		first := env.Content[0]
+		prefix, err := linePrefix(lines, first)
+		if err != nil {
+			return nil, err
+		}
+		return insertLines(lines, first.Line, prefix+key+": "+formatScalar(value, 0)+"\n"), nil
+	case yaml.SequenceNode:
+		entry := key + "=" + value
+		for _, item := range env.Content {
+			if item.Value == key || strings.HasPrefix(item.Value, key+"=") {
+				if err := replaceScalar(lines, item, entry); err != nil {
+					return nil, fmt.Errorf("failed to set %s of service %s: %w", key, service, err)
+				}
+				return []byte(strings.Join(lines, "")), nil
+			}
+		}
+		first := env.Content[0]
+		prefix, err := linePrefix(lines, first)
+		if err != nil {
+			return nil, err
+		}
+		return insertLines(lines, first.Line, prefix+formatScalar(entry, 0)+"\n"), nil
+	default:
+		return nil, fmt.Errorf("environment of service %s must be a mapping or a list", service)
+	}
+}
+
+// AddServiceListItems adds items to a list of a compose service at a path of keys, such as
+// devices or deploy.resources.reservations.devices. Missing keys of the path are added as
+// the first keys of their parent, items the list already holds are skipped. Items are
+// values yaml.Marshal can encode, such as strings or structs.
+func AddServiceListItems(content []byte, service string, path []string, items ...interface{}) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("path of the list is empty")
+	}
+	parentKey, parent, err := findService(content, service)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(content)
+
+	depth := 0
+	var list *yaml.Node
+	for ; depth < len(path); depth++ {
+		key, node := mappingPair(parent, path[depth])
+		if node == nil {
+			break
+		}
+		name := strings.Join(path[:depth+1], ".")
+		if node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+			return nil, fmt.Errorf("%s of service %s must be a block to add to it", name, service)
+		}
+		if depth == len(path)-1 {
+			if node.Kind != yaml.SequenceNode {
+				return nil, fmt.Errorf("%s of service %s must be a list", name, service)
+			}
+			list = node
+			break
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s of service %s must be a mapping", name, service)
+		}
+		parentKey, parent = key, node
+	}
+
+	var missing []interface{}
+	for _, item := range items {
+		value, err := normalizeYAML(item)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		if list != nil {
+			for _, entry := range list.Content {
+				var existing interface{}
+				if err := entry.Decode(&existing); err == nil && reflect.DeepEqual(existing, value) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			missing = append(missing, item)
+		}
+	}
+	if len(missing) == 0 {
+		return content, nil
+	}
+
+	if list != nil {
+		first := list.Content[0]
+		prefix, err := linePrefix(lines, first)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasSuffix(prefix, "- ") {
+			return nil, fmt.Errorf("%s of service %s must be a list of \"- \" entries", strings.Join(path, "."), service)
+		}
+		text, err := listItemsText(prefix, missing)
+		if err != nil {
+			return nil, err
+		}
+		return insertLines(lines, first.Line, text), nil
+	}
+
+	indent, step, err := childIndent(lines, parentKey, parent)
+	if err != nil {
+		return nil, err
+	}
+	var block strings.Builder
+	for _, key := range path[depth:] {
+		block.WriteString(indent + key + ":\n")
+		indent += step
+	}
+	text, err := listItemsText(indent+"- ", missing)
+	if err != nil {
+		return nil, err
+	}
+	block.WriteString(text)
+	return insertLines(lines, parent.Content[0].Line, block.String()), nil
+}
+
+// normalizeYAML returns a value as a YAML decoder returns it, to compare it with a node
+func normalizeYAML(value interface{}) (interface{}, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %v: %w", value, err)
+	}
+	var normalized interface{}
+	if err := yaml.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %w", value, err)
+	}
+	return normalized, nil
+}
+
+// listItemsText renders list entries, the first line of each after prefix, e.g. "    - ",
+// and the rest of them aligned with it
+func listItemsText(prefix string, items []interface{}) (string, error) {
+	var text strings.Builder
+	for _, item := range items {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(item); err != nil {
+			return "", fmt.Errorf("failed to encode %v: %w", item, err)
+		}
+		for i, line := range strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if i == 0 {
+				text.WriteString(prefix)
+			} else {
+				text.WriteString(strings.Repeat(" ", len(prefix)))
+			}
+			text.WriteString(line)
+		}
+		text.WriteString("\n")
+	}
+	return text.String(), nil
+}
+
+// SetEnvVar sets KEY=VALUE in the content of an .env file. An existing line is replaced
+// where it is, keeping an `export ` prefix; a new variable is appended.
+func SetEnvVar(content []byte, key, value string) ([]byte, error) {
+	if err := ValidateEnvKey(key); err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return nil, fmt.Errorf("value of %s must be a single line", key)
+	}
+	formatted := FormatEnvValue(value)
+
+	lines := splitLines(content)
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		prefix := line[:len(line)-len(trimmed)]
+		if rest, ok := strings.CutPrefix(trimmed, "export "); ok {
+			prefix += "export "
+			trimmed = rest
+		}
+		name, _, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+		newline := ""
+		if strings.HasSuffix(line, "\n") {
+			newline = "\n"
+		}
+		lines[i] = prefix + key + "=" + formatted + newline
+		return []byte(strings.Join(lines, "")), nil
+	}
+
+	result := string(content)
+	if result != "" && !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	return []byte(result + key + "=" + formatted + "\n"), nil
+}
+
+// FormatEnvValue quotes a .env value when it would otherwise be cut at a comment or
+// whitespace. Single quotes keep it literal, including $.
+func FormatEnvValue(value string) string {
+	if !strings.ContainsAny(value, " \t#'\"") {
+		return value
+	}
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// findService returns the key and the mapping of a service
+func findService(content []byte, service string) (*yaml.Node, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("compose file is not a mapping")
+	}
+	_, services := mappingPair(doc.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("compose file has no services")
+	}
+	key, svc := mappingPair(services, service)
+	if svc == nil {
+		return nil, nil, fmt.Errorf("service %q not found", service)
+	}
+	if svc.Kind != yaml.MappingNode || svc.Style&yaml.FlowStyle != 0 || len(svc.Content) == 0 {
+		return nil, nil, fmt.Errorf("service %s must be a block mapping to edit it", service)
+	}
+	return key, svc, nil
+}
+
+// mappingPair returns the key and value nodes of key in a mapping
+func mappingPair(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func splitLines(content []byte) []string {
+	return strings.SplitAfter(string(content), "\n")
+}
+
+// insertLines inserts text before the 1-based line
+func insertLines(lines []string, line int, text string) []byte {
+	result := make([]string, 0, len(lines)+1)
+	result = append(result, lines[:line-1]...)
+	result = append(result, text)
+	result = append(result, lines[line-1:]...)
+	return []byte(strings.Join(result, ""))
+}
+
+// byteOffset converts the 1-based column of a node to a byte offset in its line
+func byteOffset(line string, column int) int {
+	offset := 0
+	for i := 1; i < column && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// linePrefix returns the text before a node on its line, e.g. the indentation or "    - "
+func linePrefix(lines []string, node *yaml.Node) (string, error) {
+	prefix := lines[node.Line-1][:byteOffset(lines[node.Line-1], node.Column)]
+	if strings.TrimLeft(prefix, " -") != "" {
+		return "", fmt.Errorf("line %d must hold a single entry to add one next to it", node.Line)
+	}
+	return prefix, nil
+}
+
+// childIndent returns the indentation of the keys of a service and the indentation step
+// from the service name to its keys, to add a key or a nested block
+func childIndent(lines []string, key, svc *yaml.Node) (string, string, error) {
+	first := svc.Content[0]
+	indent, err := linePrefix(lines, first)
+	if err != nil || strings.Contains(indent, "-") {
+		return "", "", fmt.Errorf("service keys must be indented blocks to add one")
+	}
+	step := first.Column - key.Column
+	if step <= 0 {
+		step = 2
+	}
+	return indent, strings.Repeat(" ", step), nil
+}
+
+// replaceScalar replaces the text of a single-line scalar, keeping its quoting style
+func replaceScalar(lines []string, node *yaml.Node, value string) error {
+	return replaceScalarText(lines, node, formatScalar(value, node.Style))
+}
+
+// replaceScalarText replaces a single-line scalar, quotes included, with text
+func replaceScalarText(lines []string, node *yaml.Node, text string) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: value is not a scalar", node.Line)
+	}
+	line := lines[node.Line-1]
+	start := byteOffset(line, node.Column)
+	rest := line[start:]
+
+	var end int
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		end = closingQuote(rest, '"')
+	case yaml.SingleQuotedStyle:
+		end = closingQuote(rest, '\'')
+	case 0:
+		end = len(strings.TrimRight(rest, "\r\n"))
+		if comment := strings.Index(rest, " #"); comment >= 0 && comment < end {
+			end = comment
+		}
+		end = len(strings.TrimRight(rest[:end], " \t"))
+		if rest[:end] != node.Value {
+			end = -1
+		}
+	default:
+		return fmt.Errorf("line %d: block scalars can't be edited in place", node.Line)
+	}
+	if end < 0 {
+		return fmt.Errorf("line %d: only single-line values can be edited in place", node.Line)
+	}
+	lines[node.Line-1] = line[:start] + text + rest[end:]
+	return nil
+}
+
+// closingQuote returns the offset after the quote closing the string rest starts with,
+// -1 if it isn't closed on this line
+func closingQuote(rest string, quote byte) int {
+	for i := 1; i < len(rest); i++ {
+		switch {
+		case quote == '"' && rest[i] == '\\':
+			i++
+		case rest[i] == quote && quote == '\'' && i+1 < len(rest) && rest[i+1] == '\'':
+			i++
+		case rest[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// formatScalar writes a string in the given quoting style. Plain values that YAML would
+// read as something else, e.g. numbers or values with ": ", are double quoted.
+func formatScalar(value string, style yaml.Style) string {
+	switch style {
+	case yaml.SingleQuotedStyle:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case yaml.DoubleQuotedStyle:
+		return strconv.Quote(value)
+	}
+	if value != "" {
+		var parsed map[string]interface{}
+		if err := yaml.Unmarshal([]byte("v: "+value), &parsed); err == nil && parsed["v"] == value {
+			return value
+		}
+	}
+	return strconv.Quote(value)
+}
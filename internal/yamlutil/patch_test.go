@@ -0,0 +1,389 @@
+package yamlutil
+
+import (
+	"strings"
+	"testing"
+)
+
+const patchCompose = `# Photo library
+services:
+  web:
+    image: "ghcr.io/example/photos:1.2" # pinned
+    environment:
+      - TZ=UTC
+      - 'GREETING=hello world'
+    ports:
+      - "8080:80"
+  db:
+    image: postgres:16
+    environment:
+      POSTGRES_USER: photos  # owner
+      POSTGRES_DB: photos
+  worker:
+    build: .
+`
+
+func TestSetServiceImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		image   string
+		want    string
+		wantErr bool
+	}{
+		{name: "quoted with comment", service: "web", image: "ghcr.io/example/photos:1.3",
+			want: `    image: "ghcr.io/example/photos:1.3" # pinned` + "\n"},
+		{name: "plain", service: "db", image: "postgres:17", want: "    image: postgres:17\n"},
+		{name: "build only", service: "worker", image: "example/worker:2", want: "    image: example/worker:2\n    build: .\n"},
+		{name: "unknown service", service: "cache", image: "redis:7", wantErr: true},
+		{name: "invalid image", service: "db", image: "postgres 17", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetServiceImage([]byte(patchCompose), tt.service, tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetServiceImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			assertOneChange(t, string(got), tt.want)
+			if image, err := ServiceImage(got, tt.service); err != nil || image != tt.image {
+				t.Errorf("ServiceImage() after edit = %q, %v, want %q", image, err, tt.image)
+			}
+		})
+	}
+}
+
+func TestSetServiceEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		key     string
+		value   string
+		want    string
+	}{
+		{name: "list entry", service: "web", key: "TZ", value: "Europe/Berlin", want: "      - TZ=Europe/Berlin\n"},
+		{name: "quoted list entry", service: "web", key: "GREETING", value: "it's me", want: "      - 'GREETING=it''s me'\n"},
+		{name: "new list entry", service: "web", key: "DEBUG", value: "1", want: "      - DEBUG=1\n      - TZ=UTC\n"},
+		{name: "mapping entry with comment", service: "db", key: "POSTGRES_USER", value: "admin", want: "      POSTGRES_USER: admin  # owner\n"},
+		{name: "number in mapping", service: "db", key: "POSTGRES_PORT", value: "5433", want: "      POSTGRES_PORT: \"5433\"\n      POSTGRES_USER"},
+		{name: "no environment", service: "worker", key: "QUEUE", value: "photos", want: "    environment:\n      QUEUE: photos\n    build: .\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetServiceEnv([]byte(patchCompose), tt.service, tt.key, tt.value)
+			if err != nil {
+				t.Fatalf("SetServiceEnv() error = %v", err)
+			}
+			assertOneChange(t, string(got), tt.want)
+			if _, _, err := findService(got, tt.service); err != nil {
+				t.Errorf("edited compose file is invalid: %v\n%s", err, got)
+			}
+		})
+	}
+
+	if _, err := SetServiceEnv([]byte(patchCompose), "db", "BAD-NAME", "x"); err == nil {
+		t.Error("SetServiceEnv() accepted an invalid variable name")
+	}
+	if _, err := SetServiceEnv([]byte(patchCompose), "db", "MULTI", "a\nb"); err == nil {
+		t.Error("SetServiceEnv() accepted a multi-line value")
+	}
+}
+
+func TestAddServiceListItems(t *testing.T) {
+	type request struct {
+		Driver       string   `yaml:"driver"`
+		Capabilities []string `yaml:"capabilities"`
+	}
+	tests := []struct {
+		name    string
+		service string
+		path    []string
+		items   []interface{}
+		want    string
+	}{
+		{name: "existing list", service: "web", path: []string{"ports"}, items: []interface{}{"9090:90", "8080:80"},
+			want: "    ports:\n      - 9090:90\n      - \"8080:80\"\n"},
+		{name: "new list", service: "db", path: []string{"devices"}, items: []interface{}{"/dev/dri:/dev/dri"},
+			want: "    devices:\n      - /dev/dri:/dev/dri\n    image: postgres:16\n"},
+		{name: "nested mapping", service: "worker", path: []string{"deploy", "resources", "reservations", "devices"},
+			items: []interface{}{request{Driver: "nvidia", Capabilities: []string{"gpu"}}},
+			want: "    deploy:\n      resources:\n        reservations:\n          devices:\n" +
+				"            - driver: nvidia\n              capabilities:\n                - gpu\n    build: .\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AddServiceListItems([]byte(patchCompose), tt.service, tt.path, tt.items...)
+			if err != nil {
+				t.Fatalf("AddServiceListItems() error = %v", err)
+			}
+			assertOneChange(t, string(got), tt.want)
+			// Adding the same items again changes nothing
+			again, err := AddServiceListItems(got, tt.service, tt.path, tt.items...)
+			if err != nil || string(again) != string(got) {
+				t.Errorf("adding again = %v:\n%s", err, again)
+			}
+		})
+	}
+
+	if _, err := AddServiceListItems([]byte(patchCompose), "web", []string{"environment", "TZ"}, "x"); err == nil {
+		t.Error("AddServiceListItems() added below a list")
+	}
+	if _, err := AddServiceListItems([]byte(patchCompose), "db", []string{"environment"}, "x"); err == nil {
+		t.Error("AddServiceListItems() added to a mapping")
+	}
+}
+
+// assertOneChange checks that want appears in got and that everything else was kept
+func assertOneChange(t *testing.T, got, want string) {
+	t.Helper()
+	if !strings.Contains(got, want) {
+		t.Fatalf("edited file does not contain %q:\n%s", want, got)
+	}
+	if !strings.Contains(got, "# Photo library\n") || !strings.Contains(got, "      - \"8080:80\"\n") {
+		t.Errorf("edit changed unrelated lines:\n%s", got)
+	}
+}
+
+func TestSetEnvVar(t *testing.T) {
+	content := "# Generated\nexport TAG=1.2\nPASSWORD=secret\n"
+	tests := []struct {
+		key, value, want string
+	}{
+		{"TAG", "1.3", "# Generated\nexport TAG=1.3\nPASSWORD=secret\n"},
+		{"PASSWORD", "two words", "# Generated\nexport TAG=1.2\nPASSWORD='two words'\n"},
+		{"PASSWORD", `it's #1`, "# Generated\nexport TAG=1.2\nPASSWORD=\"it's #1\"\n"},
+		{"NEW", "x", content + "NEW=x\n"},
+	}
+	for _, tt := range tests {
+		got, err := SetEnvVar([]byte(content), tt.key, tt.value)
+		if err != nil {
+			t.Fatalf("SetEnvVar(%s) error = %v", tt.key, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("SetEnvVar(%s, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+	if got, _ := SetEnvVar([]byte("A=1"), "B", "2"); string(got) != "A=1\nB=2\n" {
+		t.Errorf("SetEnvVar() without trailing newline = %q", got)
+	}
+}
+
+func TestReplaceImageTag(t *testing.T) {
+	tests := []struct {
+		image, tag, want string
+	}{
+		{"nginx", "1.27", "nginx:1.27"},
+		{"nginx:1.25", "1.27", "nginx:1.27"},
+		{"localhost:5000/team/app:v1", "v2", "localhost:5000/team/app:v2"},
+		{"ghcr.io/example/app@sha256:abc", "v2", "ghcr.io/example/app:v2"},
+	}
+	for _, tt := range tests {
+		got, err := ReplaceImageTag(tt.image, tt.tag)
+		if err != nil || got != tt.want {
+			t.Errorf("ReplaceImageTag(%q, %q) = %q, %v, want %q", tt.image, tt.tag, got, err, tt.want)
+		}
+	}
+	if _, err := ReplaceImageTag("nginx", "bad tag"); err == nil {
+		t.Error("ReplaceImageTag() accepted an invalid tag")
+	}
+}
+
+func TestSetAndRemoveServiceKey(t *testing.T) {
+	got, err := SetServiceKey([]byte(patchCompose), "db", "cpuset", "0-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertOneChange(t, string(got), "  db:\n    cpuset: 0-3\n    image: postgres:16\n")
+	if got, err = SetServiceKey(got, "db", "cpuset", "4"); err != nil {
+		t.Fatal(err)
+	}
+	assertOneChange(t, string(got), "    cpuset: \"4\"\n")
+	if cpusets, err := ServiceCPUSets(got); err != nil || cpusets["db"] != "4" || cpusets["web"] != "" {
+		t.Errorf("ServiceCPUSets() = %v, %v", cpusets, err)
+	}
+
+	if got, err = RemoveServiceKey(got, "db", "cpuset"); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != patchCompose {
+		t.Errorf("RemoveServiceKey() did not restore the file:\n%s", got)
+	}
+	if _, err := RemoveServiceKey([]byte(patchCompose), "worker", "build"); err == nil {
+		t.Error("RemoveServiceKey() removed the only key of a service")
+	}
+}
+
+func TestSetMetadataKey(t *testing.T) {
+	// A file without x-ontree gets the block at the end
+	got, err := SetMetadataKey([]byte(patchCompose), "namespace", "kids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != patchCompose+"x-ontree:\n  namespace: kids\n" {
+		t.Errorf("SetMetadataKey() without x-ontree =\n%s", got)
+	}
+
+	content := "services:\n  web:\n    image: nginx\nx-ontree:\n    emoji: \"🌳\"\n    subdomain: web\n"
+	if got, err = SetMetadataKey([]byte(content), "namespace", "work"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "x-ontree:\n    namespace: work\n    emoji: \"🌳\"\n"; !strings.Contains(string(got), want) {
+		t.Errorf("SetMetadataKey() added the key as\n%s", got)
+	}
+	if got, err = SetMetadataKey(got, "namespace", "family"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "    namespace: family\n") || strings.Contains(string(got), "work") {
+		t.Errorf("SetMetadataKey() replaced the key as\n%s", got)
+	}
+}
+
+func TestSetMetadataBool(t *testing.T) {
+	content := "services:\n  web:\n    image: nginx\nx-ontree:\n  emoji: \"🌳\"\n"
+	got, err := SetMetadataBool([]byte(content), "autostart", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x-ontree:\n  autostart: true\n  emoji: \"🌳\"\n"; !strings.HasSuffix(string(got), want) {
+		t.Errorf("SetMetadataBool() added the key as\n%s", got)
+	}
+	if got, err = SetMetadataBool(got, "autostart", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "  autostart: false\n") {
+		t.Errorf("SetMetadataBool() replaced the key as\n%s", got)
+	}
+}
+
+func TestSetServicePortsHostIP(t *testing.T) {
+	content := `services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80" # web
+      - 127.0.0.1:8443:443/tcp
+      - 9000
+      - target: 53
+        published: 5353
+        protocol: udp
+      - target: 81
+        host_ip: 127.0.0.1
+        published: 8081
+`
+	got, err := SetServicePortsHostIP([]byte(content), "web", "192.168.20.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `services:
+  web:
+    image: nginx
+    ports:
+      - "192.168.20.5:8080:80" # web
+      - 192.168.20.5:8443:443/tcp
+      - 192.168.20.5::9000
+      - target: 53
+        host_ip: 192.168.20.5
+        published: 5353
+        protocol: udp
+      - target: 81
+        host_ip: 192.168.20.5
+        published: 8081
+`
+	if string(got) != want {
+		t.Errorf("SetServicePortsHostIP() =\n%s\nwant\n%s", got, want)
+	}
+
+	v6, err := SetServicePortsHostIP(got, "web", "fd00::5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(v6), `- "[fd00::5]:8080:80" # web`) || !strings.Contains(string(v6), "host_ip: fd00::5") {
+		t.Errorf("IPv6 binding =\n%s", v6)
+	}
+
+	// An empty address goes back to all interfaces
+	if got, err = SetServicePortsHostIP(got, "web", ""); err != nil {
+		t.Fatal(err)
+	}
+	want = `services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80" # web
+      - 8443:443/tcp
+      - "9000"
+      - target: 53
+        published: 5353
+        protocol: udp
+      - target: 81
+        published: 8081
+`
+	if string(got) != want {
+		t.Errorf("SetServicePortsHostIP() to all interfaces =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := SetServicePortsHostIP([]byte(patchCompose), "db", "127.0.0.1"); err == nil {
+		t.Error("SetServicePortsHostIP() accepted a service without ports")
+	}
+	if _, err := SetServicePortsHostIP([]byte("services:\n  web:\n    image: nginx\n    ports: [\"80:80\"]\n"), "web", "127.0.0.1"); err == nil {
+		t.Error("SetServicePortsHostIP() edited a flow list")
+	}
+}
+
+func TestReplaceHostPort(t *testing.T) {
+	content := `services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80" # web
+      - 127.0.0.1:8080:443/tcp
+      - "${PORT:-8080}:8080"
+      - 8000-8010:8000-8010
+  api:
+    image: api
+    ports:
+      - target: 3000
+        published: 8080
+      - target: 3001
+        published: "8081"
+x-ontree:
+  host_port: 8080
+`
+	got, changed, err := ReplaceHostPort([]byte(content), 8080, 8090)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `services:
+  web:
+    image: nginx
+    ports:
+      - "8090:80" # web
+      - 127.0.0.1:8090:443/tcp
+      - "${PORT:-8080}:8080"
+      - 8000-8010:8000-8010
+  api:
+    image: api
+    ports:
+      - target: 3000
+        published: 8090
+      - target: 3001
+        published: "8081"
+x-ontree:
+  host_port: 8080
+`
+	if string(got) != want || changed != 3 {
+		t.Errorf("ReplaceHostPort() changed %d ports:\n%s\nwant\n%s", changed, got, want)
+	}
+
+	if got, err = SetMetadataInt(got, "host_port", 8090); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(got), "x-ontree:\n  host_port: 8090\n") {
+		t.Errorf("SetMetadataInt() =\n%s", got)
+	}
+	if got, _, err = ReplaceHostPort(got, 8081, 9000); err != nil || !strings.Contains(string(got), `published: "9000"`) {
+		t.Errorf("ReplaceHostPort() of a quoted port = %v\n%s", err, got)
+	}
+}
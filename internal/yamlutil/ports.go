@@ -0,0 +1,206 @@
+package yamlutil
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HostPorts returns the host ports the services of a compose file publish, sorted and without
+// duplicates. Ports published without a host port get a random one and are left out.
+func HostPorts(compose *ComposeFile) ([]int, error) {
+	seen := map[int]bool{}
+	for name, service := range compose.Services {
+		serviceMap, ok := service.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ports, ok := serviceMap["ports"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, port := range ports {
+			var published string
+			switch v := port.(type) {
+			case string:
+				published = hostPart(v)
+			case int:
+				// A bare container port
+			case map[string]interface{}:
+				if value, ok := v["published"]; ok {
+					published = fmt.Sprint(value)
+				}
+			}
+			if published == "" {
+				continue
+			}
+			first, last, err := portRange(published)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %w", name, err)
+			}
+			for p := first; p <= last; p++ {
+				seen[p] = true
+			}
+		}
+	}
+
+	result := make([]int, 0, len(seen))
+	for p := range seen {
+		result = append(result, p)
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+// hostPart returns the host port of a short port mapping such as 127.0.0.1:8080:80/tcp, ""
+// if the mapping has none
+func hostPart(mapping string) string {
+	mapping, _, _ = strings.Cut(mapping, "/")
+	// IPv6 addresses are in brackets, their colons do not separate fields
+	if strings.HasPrefix(mapping, "[") {
+		if end := strings.Index(mapping, "]"); end != -1 {
+			mapping = mapping[end+1:]
+			mapping = strings.TrimPrefix(mapping, ":")
+		}
+	}
+	parts := splitPortFields(mapping)
+	switch len(parts) {
+	case 2:
+		return parts[0]
+	case 3:
+		return parts[1]
+	}
+	return ""
+}
+
+// portRange parses a port or a range of ports such as 8000-8010
+func portRange(value string) (int, int, error) {
+	firstValue, lastValue, isRange := strings.Cut(value, "-")
+	first, err := strconv.Atoi(firstValue)
+	if err != nil || first < 1 || first > 65535 {
+		return 0, 0, fmt.Errorf("invalid host port %q", value)
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	last, err := strconv.Atoi(lastValue)
+	if err != nil || last < first || last > 65535 {
+		return 0, 0, fmt.Errorf("invalid host port range %q", value)
+	}
+	return first, last, nil
+}
+
+// PortBinding is a port a compose service publishes, with the host address it binds to
+type PortBinding struct {
+	Service   string `json:"service"`
+	HostIP    string `json:"host_ip"`   // Empty for all interfaces
+	Published string `json:"published"` // Host port or range, empty for a random port
+	Target    string `json:"target"`    // Container port, with the protocol if it isn't TCP
+}
+
+// PortBindings returns the published ports of the services of a compose file, sorted by
+// service and in file order within a service
+func PortBindings(compose *ComposeFile) []PortBinding {
+	var bindings []PortBinding
+	for name, service := range compose.Services {
+		serviceMap, ok := service.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ports, ok := serviceMap["ports"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, port := range ports {
+			binding := PortBinding{Service: name}
+			switch v := port.(type) {
+			case string:
+				binding.HostIP, binding.Published, binding.Target = splitPortMapping(v)
+			case int:
+				binding.Target = strconv.Itoa(v)
+			case map[string]interface{}:
+				if value, ok := v["host_ip"]; ok {
+					binding.HostIP = fmt.Sprint(value)
+				}
+				if value, ok := v["published"]; ok {
+					binding.Published = fmt.Sprint(value)
+				}
+				binding.Target = fmt.Sprint(v["target"])
+				if protocol, ok := v["protocol"].(string); ok && protocol != "tcp" {
+					binding.Target += "/" + protocol
+				}
+			default:
+				continue
+			}
+			bindings = append(bindings, binding)
+		}
+	}
+	sort.SliceStable(bindings, func(i, j int) bool { return bindings[i].Service < bindings[j].Service })
+	return bindings
+}
+
+// splitPortMapping splits a short port mapping such as [::1]:8080:80/udp into the host
+// address without brackets, the host port and the container port with its protocol
+func splitPortMapping(mapping string) (hostIP, published, target string) {
+	ports, protocol, hasProtocol := strings.Cut(mapping, "/")
+	if strings.HasPrefix(ports, "[") {
+		if end := strings.Index(ports, "]"); end != -1 {
+			hostIP = ports[1:end]
+			ports = strings.TrimPrefix(ports[end+1:], ":")
+			published, target, _ = strings.Cut(ports, ":")
+			return hostIP, published, withProtocol(target, protocol, hasProtocol)
+		}
+	}
+	parts := splitPortFields(ports)
+	switch len(parts) {
+	case 1:
+		target = parts[0]
+	case 2:
+		published, target = parts[0], parts[1]
+	default:
+		hostIP, published, target = strings.Join(parts[:len(parts)-2], ":"), parts[len(parts)-2], parts[len(parts)-1]
+	}
+	return hostIP, published, withProtocol(target, protocol, hasProtocol)
+}
+
+// splitPortFields splits a port mapping at its colons, except those of variables such as
+// ${PORT:-8080}
+func splitPortFields(mapping string) []string {
+	var fields []string
+	depth, start := 0, 0
+	for i := 0; i < len(mapping); i++ {
+		switch {
+		case mapping[i] == '{' && i > 0 && mapping[i-1] == '$':
+			depth++
+		case mapping[i] == '}' && depth > 0:
+			depth--
+		case mapping[i] == ':' && depth == 0:
+			fields = append(fields, mapping[start:i])
+			start = i + 1
+		}
+	}
+	return append(fields, mapping[start:])
+}
+
+func withProtocol(target, protocol string, hasProtocol bool) string {
+	if hasProtocol {
+		return target + "/" + protocol
+	}
+	return target
+}
+
+// joinPortMapping is the reverse of splitPortMapping. A port without a host port that is
+// bound to an address keeps a random host port, as in 127.0.0.1::80.
+func joinPortMapping(hostIP, published, target string) string {
+	if hostIP == "" {
+		if published == "" {
+			return target
+		}
+		return published + ":" + target
+	}
+	if strings.Contains(hostIP, ":") {
+		hostIP = "[" + hostIP + "]"
+	}
+	return hostIP + ":" + published + ":" + target
+}
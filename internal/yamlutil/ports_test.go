@@ -0,0 +1,90 @@
+package yamlutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestHostPorts(t *testing.T) {
+	content := `
+services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80"
+      - "127.0.0.1:8443:443/tcp"
+      - "[::1]:9000:9000"
+      - "5000-5002:5000-5002"
+      - "3000"
+      - 4000
+  db:
+    image: postgres
+    ports:
+      - target: 5432
+        published: 15432
+      - target: 6379
+      - "8080:81"
+`
+	var compose ComposeFile
+	if err := yaml.Unmarshal([]byte(content), &compose); err != nil {
+		t.Fatalf("Failed to parse compose: %v", err)
+	}
+	ports, err := HostPorts(&compose)
+	if err != nil {
+		t.Fatalf("HostPorts: %v", err)
+	}
+	want := []int{5000, 5001, 5002, 8080, 8443, 9000, 15432}
+	if !reflect.DeepEqual(ports, want) {
+		t.Errorf("HostPorts = %v, want %v", ports, want)
+	}
+
+	compose.Services = map[string]interface{}{"web": map[string]interface{}{"ports": []interface{}{"http:80"}}}
+	if _, err := HostPorts(&compose); err == nil {
+		t.Error("HostPorts accepted a port that is not a number")
+	}
+}
+
+func TestPortBindings(t *testing.T) {
+	content := `
+services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80"
+      - "10.0.20.5:8443:443/udp"
+      - "[fd00::5]::9000"
+      - 4000
+      - "${ADMIN_PORT:-8081}:${ADMIN_PORT:-8081}"
+      - target: 5432
+        host_ip: 127.0.0.1
+        published: 15432
+        protocol: tcp
+`
+	var compose ComposeFile
+	if err := yaml.Unmarshal([]byte(content), &compose); err != nil {
+		t.Fatalf("Failed to parse compose: %v", err)
+	}
+	want := []PortBinding{
+		{Service: "web", Published: "8080", Target: "80"},
+		{Service: "web", HostIP: "10.0.20.5", Published: "8443", Target: "443/udp"},
+		{Service: "web", HostIP: "fd00::5", Target: "9000"},
+		{Service: "web", Target: "4000"},
+		{Service: "web", Published: "${ADMIN_PORT:-8081}", Target: "${ADMIN_PORT:-8081}"},
+		{Service: "web", HostIP: "127.0.0.1", Published: "15432", Target: "5432"},
+	}
+	if got := PortBindings(&compose); !reflect.DeepEqual(got, want) {
+		t.Errorf("PortBindings = %+v, want %+v", got, want)
+	}
+
+	for _, b := range want {
+		if b.Service == "web" && b.Target == "5432" {
+			continue
+		}
+		if got := joinPortMapping(b.HostIP, b.Published, b.Target); !strings.Contains(content, got) {
+			t.Errorf("joinPortMapping(%+v) = %q, not in the compose file", b, got)
+		}
+	}
+}
@@ -84,10 +84,17 @@ type OnTreeMetadata struct {
 	Subdomain         string `yaml:"subdomain,omitempty"`          // For Caddy/public exposure
 	HostPort          int    `yaml:"host_port,omitempty"`          // For Caddy/public exposure
 	IsExposed         bool   `yaml:"is_exposed"`                   // For Caddy/public exposure
+	CustomDomain      string `yaml:"custom_domain,omitempty"`      // Domain served besides the subdomain, e.g. "photos.example.org"
 	TailscaleHostname string `yaml:"tailscale_hostname,omitempty"` // e.g., "jellyfin"
 	TailscaleExposed  bool   `yaml:"tailscale_exposed"`            // Separate from public exposure
 	Emoji             string `yaml:"emoji,omitempty"`
-	BypassSecurity    bool   `yaml:"bypass_security"` // Skip security validation for this app
+	BypassSecurity    bool   `yaml:"bypass_security"`            // Skip security validation for this app
+	StorageClass      string `yaml:"storage_class,omitempty"`    // "fast" or "bulk", where the app's mnt data lives
+	DiskQuota         string `yaml:"disk_quota,omitempty"`       // Size limit for the app's mnt data, e.g. "50GB"
+	ReadOnlyRoot      string `yaml:"read_only_root,omitempty"`   // "on" or "off", empty follows the global setting
+	Namespace         string `yaml:"namespace,omitempty"`        // Namespace whose members see and control the app
+	RebuildSchedule   string `yaml:"rebuild_schedule,omitempty"` // "daily", "weekly" or "monthly" for apps built from source
+	Autostart         bool   `yaml:"autostart,omitempty"`        // Started when TreeOS starts
 }
 
 // ComposeFile represents a docker-compose.yml file structure
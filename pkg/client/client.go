@@ -0,0 +1,309 @@
+// Package client provides a typed Go client for the TreeOS HTTP API.
+//
+// The types and most methods are generated from api/openapi.yaml into types_gen.go and
+// client_gen.go, this file holds the requests the spec marks x-client: manual.
+package client
+
+//go:generate go run ../../cmd/gen-clients -root ../..
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to a TreeOS node over HTTP.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// APIError is returned when the node answers with a non-2xx status code.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("treeos api error (%d): %s", e.StatusCode, e.Message)
+}
+
+// New creates a client for the node at baseURL (e.g. "http://localhost:3000").
+func New(baseURL string) (*Client, error) {
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Jar:     jar,
+			Timeout: 60 * time.Second,
+			// The web UI answers unauthenticated requests with a redirect to /login,
+			// surface that as an error instead of following it.
+			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// Login authenticates the client with a username and password.
+// The session cookie is kept in the client's cookie jar.
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	form := url.Values{}
+	form.Set("username", username)
+	form.Set("password", password)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup, error not critical
+
+	// A successful login redirects away from the login page
+	if resp.StatusCode != http.StatusFound || strings.HasPrefix(resp.Header.Get("Location"), "/login") {
+		return &APIError{StatusCode: http.StatusUnauthorized, Message: "invalid username or password"}
+	}
+	return nil
+}
+
+// SetToken authenticates the client with the node's API token instead of a
+// login. The token is sent as bearer token with every request.
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// ForceStopApp stops an app like StopApp, also when running apps depend on it.
+// StopApp fails with a 409 APIError listing them instead.
+func (c *Client) ForceStopApp(ctx context.Context, name string) (*AppActionResponse, error) {
+	var resp AppActionResponse
+	if err := c.doJSON(ctx, http.MethodPost, appPath(name, "stop")+"?force=true", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Job kinds accepted by Job and WaitForJob.
+const (
+	JobKindApp   = "app"
+	JobKindModel = "model"
+)
+
+// jobWaitStep is how long a single WaitForJob request is held by the server,
+// below the client's request timeout.
+const jobWaitStep = 30 * time.Second
+
+// Job returns the state of an app operation or a model download. With wait
+// set, the server holds the request until the job is done or wait has passed.
+func (c *Client) Job(ctx context.Context, kind, name string, wait time.Duration) (*Job, error) {
+	path := "/api/jobs/" + url.PathEscape(kind) + "/" + url.PathEscape(name)
+	if wait > 0 {
+		path += "?wait=" + url.QueryEscape(wait.String())
+	}
+
+	var resp Job
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// WaitForJob blocks until the job is done or the context is cancelled. Check
+// the returned job's State to tell completed from failed jobs.
+func (c *Client) WaitForJob(ctx context.Context, kind, name string) (*Job, error) {
+	for {
+		job, err := c.Job(ctx, kind, name, jobWaitStep)
+		if err != nil {
+			return nil, err
+		}
+		if job.Done {
+			return job, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return job, err
+		}
+	}
+}
+
+// AppLogs returns the plain text logs of an app. An empty service returns
+// logs for all services. With follow set, the stream stays open until the
+// context is cancelled; the caller must close the returned reader.
+func (c *Client) AppLogs(ctx context.Context, name, service string, follow bool) (io.ReadCloser, error) {
+	query := url.Values{}
+	if service != "" {
+		query.Set("service", service)
+	}
+	if follow {
+		query.Set("follow", "true")
+	}
+
+	path := appPath(name, "logs")
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Followed logs are long-lived, rely on the context for cancellation
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close() //nolint:errcheck,gosec // Error already being returned
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// LogQuery selects the lines of ParsedAppLogs. Zero fields select everything.
+type LogQuery struct {
+	Service string
+	Level   string // Minimum level: error, warn, info or debug
+	Query   string // Case-insensitive text in the message
+	Tail    int    // Lines to parse, 1000 if 0
+	Since   *time.Time
+	Until   *time.Time
+}
+
+// ParsedAppLogs returns the last log lines of an app with detected time and level,
+// filtered by the query, and a timeline of all lines with its error spikes.
+func (c *Client) ParsedAppLogs(ctx context.Context, name string, q LogQuery) (*ParsedLogs, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{"service": q.Service, "level": q.Level, "q": q.Query} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if q.Tail > 0 {
+		query.Set("tail", strconv.Itoa(q.Tail))
+	}
+	if q.Since != nil {
+		query.Set("since", q.Since.Format(time.RFC3339))
+	}
+	if q.Until != nil {
+		query.Set("until", q.Until.Format(time.RFC3339))
+	}
+
+	path := appPath(name, "logs/parsed")
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var resp ParsedLogs
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func appPath(name, action string) string {
+	path := "/api/apps/" + url.PathEscape(name)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // Cleanup, error not critical
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusFound {
+		return &APIError{StatusCode: http.StatusUnauthorized, Message: "not authenticated"}
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck // Best effort error message
+	message := strings.TrimSpace(string(data))
+
+	// Some endpoints answer with a JSON error object
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &payload) == nil {
+		if payload.Error != "" {
+			message = payload.Error
+		} else if payload.Message != "" {
+			message = payload.Message
+		}
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: message}
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientAppLifecycle(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "ontree-session", Value: "ok", Path: "/"})
+		http.Redirect(w, r, "/?login=success", http.StatusFound)
+	})
+	mux.HandleFunc("/api/apps", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("ontree-session"); err != nil {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		var req CreateAppRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{ //nolint:errcheck,gosec // Test response
+			"success": true,
+			"message": "created",
+			"app":     map[string]string{"name": req.Name},
+		})
+	})
+	mux.HandleFunc("/api/apps/demo/status", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(AppStatus{ //nolint:errcheck,gosec // Test response
+			Success:  true,
+			App:      "demo",
+			Status:   "running",
+			Services: []ServiceStatus{{Name: "web", Status: "running"}},
+		})
+	})
+	mux.HandleFunc("/api/apps/missing/stop", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "App 'missing' not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/api/apps/demo/logs", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "service="+r.URL.Query().Get("service")) //nolint:errcheck,gosec // Test response
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	// Requests without a session are reported as unauthenticated
+	_, err = c.CreateApp(ctx, CreateAppRequest{Name: "demo"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized APIError, got %v", err)
+	}
+
+	if err := c.Login(ctx, "admin", "wrong"); err == nil {
+		t.Fatal("expected login with wrong password to fail")
+	}
+	if err := c.Login(ctx, "admin", "secret"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	created, err := c.CreateApp(ctx, CreateAppRequest{Name: "demo", ComposeYAML: "services: {}"})
+	if err != nil {
+		t.Fatalf("CreateApp() error = %v", err)
+	}
+	if !created.Success || created.App["name"] != "demo" {
+		t.Errorf("unexpected create response: %+v", created)
+	}
+
+	status, err := c.AppStatus(ctx, "demo")
+	if err != nil {
+		t.Fatalf("AppStatus() error = %v", err)
+	}
+	if status.Status != "running" || len(status.Services) != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	_, err = c.StopApp(ctx, "missing")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found APIError, got %v", err)
+	}
+	if apiErr.Message != "App 'missing' not found" {
+		t.Errorf("unexpected error message: %q", apiErr.Message)
+	}
+
+	logs, err := c.AppLogs(ctx, "demo", "web", false)
+	if err != nil {
+		t.Fatalf("AppLogs() error = %v", err)
+	}
+	defer logs.Close()          //nolint:errcheck // Test cleanup
+	body, _ := io.ReadAll(logs) //nolint:errcheck // Test read
+	if string(body) != "service=web" {
+		t.Errorf("unexpected logs body: %q", body)
+	}
+}
+
+func TestNewRejectsInvalidURL(t *testing.T) {
+	if _, err := New("not a url"); err == nil {
+		t.Fatal("expected error for invalid base URL")
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/jobs/model/llama3:8b" || r.URL.Query().Get("wait") != "30s" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		job := Job{Kind: JobKindModel, Name: "llama3:8b", State: "running", Progress: 40}
+		if calls > 1 {
+			job.State, job.Progress, job.Done = "completed", 100, true
+		}
+		json.NewEncoder(w).Encode(job) //nolint:errcheck,gosec // Test response
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	job, err := c.WaitForJob(context.Background(), JobKindModel, "llama3:8b")
+	if err != nil {
+		t.Fatalf("WaitForJob() error = %v", err)
+	}
+	if !job.Done || job.State != "completed" || calls != 2 {
+		t.Errorf("unexpected job after %d calls: %+v", calls, job)
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.Write([]byte(`{"success":true,"app":"demo","status":"running"}`)) //nolint:errcheck,gosec // Test response
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var apiErr *APIError
+	if _, err := c.AppStatus(context.Background(), "demo"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("AppStatus() without token error = %v, want 401", err)
+	}
+
+	c.SetToken("secret")
+	status, err := c.AppStatus(context.Background(), "demo")
+	if err != nil {
+		t.Fatalf("AppStatus() error = %v", err)
+	}
+	if status.Status != "running" {
+		t.Errorf("Status = %q, want running", status.Status)
+	}
+}
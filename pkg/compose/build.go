@@ -0,0 +1,172 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ontree-co/treeos/internal/metrics"
+)
+
+// rollbackTag is the tag the previous build of an image is kept under during a rebuild
+const rollbackTag = "treeos-rollback"
+
+// BuildService is a service of a compose file that is built from source
+type BuildService struct {
+	Name    string `json:"name"`
+	Context string `json:"context"` // Directory relative to the app, or a git URL
+	Image   string `json:"image"`   // Image the build is tagged as
+}
+
+// LocalContext reports whether the build context is a directory rather than a remote URL
+func (b BuildService) LocalContext() bool {
+	return !strings.Contains(b.Context, "://") && !strings.HasPrefix(b.Context, "git@")
+}
+
+// RollbackImage returns the tag the previous build of an image is kept under while a
+// rebuild is verified, e.g. "myapp-web:treeos-rollback" for "myapp-web:latest"
+func RollbackImage(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + rollbackTag
+}
+
+// buildServices returns the services of a compose file with a build section, sorted by
+// name. Services without an image are tagged <project>-<service> like Docker Compose does.
+func buildServices(content []byte, env map[string]string, project string) ([]BuildService, error) {
+	var file struct {
+		Services map[string]struct {
+			Image string    `yaml:"image"`
+			Build yaml.Node `yaml:"build"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			value, _ := expandVariable(name, env)
+			return value
+		})
+	}
+
+	var services []BuildService
+	for name, service := range file.Services {
+		var buildContext string
+		switch service.Build.Kind {
+		case 0:
+			continue
+		case yaml.ScalarNode:
+			buildContext = service.Build.Value
+		case yaml.MappingNode:
+			var build struct {
+				Context string `yaml:"context"`
+			}
+			if err := service.Build.Decode(&build); err != nil {
+				return nil, fmt.Errorf("invalid build section of service %s: %w", name, err)
+			}
+			buildContext = build.Context
+		default:
+			return nil, fmt.Errorf("invalid build section of service %s", name)
+		}
+		if buildContext == "" {
+			buildContext = "."
+		}
+
+		image := expand(service.Image)
+		if image == "" {
+			image = project + "-" + name
+		}
+		services = append(services, BuildService{Name: name, Context: expand(buildContext), Image: image})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// BuildServices returns the services of a project that are built from source
+func (s *Service) BuildServices(opts Options) ([]BuildService, error) {
+	absPath, project, err := resolveProject(opts)
+	if err != nil {
+		return nil, err
+	}
+	composeFile, err := locateComposeFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(composeFile) //nolint:gosec // Path from compose directory
+	if err != nil {
+		return nil, err
+	}
+	envFile := ".env"
+	if opts.EnvFile != "" {
+		envFile = opts.EnvFile
+	}
+	env, err := ReadEnvFile(filepath.Join(absPath, envFile))
+	if err != nil {
+		return nil, err
+	}
+	return buildServices(content, env, project)
+}
+
+// Build builds the images of a project, pulling newer versions of their base images
+// (equivalent to `docker compose build --pull`)
+func (s *Service) Build(ctx context.Context, opts Options) (err error) {
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	defer func() { metrics.ComposeDuration.ObserveSince(start, "build", metrics.Result(err)) }()
+	if s.mock != nil {
+		select {
+		case <-time.After(s.mock.Config().Latency):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	cmd, err := s.newComposeCmd(ctx, opts, append([]string{"build", "--pull"}, opts.Services...)...)
+	if err != nil {
+		return err
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to build images: %w (output: %s)", err, lastLines(string(output), 20))
+	}
+	return nil
+}
+
+// TagImage tags an existing image as target (equivalent to `docker tag`)
+func (s *Service) TagImage(ctx context.Context, source, target string) error {
+	if s.mock != nil {
+		return nil
+	}
+	// #nosec G204 -- image names come from compose files of installed apps
+	cmd := exec.CommandContext(ctx, s.dockerBinary, "tag", source, target)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to tag %s as %s: %w (output: %s)", source, target, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// lastLines returns the last n lines of s, where build errors are reported
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
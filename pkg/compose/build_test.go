@@ -0,0 +1,58 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildServices(t *testing.T) {
+	content := []byte(`
+services:
+  web:
+    build: ./web
+  worker:
+    image: ${REGISTRY:-local}/worker:dev
+    build:
+      context: https://github.com/example/worker.git#main
+      dockerfile: Dockerfile.prod
+  api:
+    build:
+      dockerfile: api.Dockerfile
+  db:
+    image: postgres:16-alpine
+`)
+
+	services, err := buildServices(content, map[string]string{"REGISTRY": "ghcr.io/example"}, "myapp")
+	if err != nil {
+		t.Fatalf("buildServices() error = %v", err)
+	}
+	want := []BuildService{
+		{Name: "api", Context: ".", Image: "myapp-api"},
+		{Name: "web", Context: "./web", Image: "myapp-web"},
+		{Name: "worker", Context: "https://github.com/example/worker.git#main", Image: "ghcr.io/example/worker:dev"},
+	}
+	if !reflect.DeepEqual(services, want) {
+		t.Errorf("buildServices() = %+v, want %+v", services, want)
+	}
+	if !services[1].LocalContext() || services[2].LocalContext() {
+		t.Error("LocalContext() doesn't tell directories from git URLs")
+	}
+
+	if _, err := buildServices([]byte("services:\n  web:\n    build: [1]\n"), nil, "myapp"); err == nil {
+		t.Error("buildServices() accepted a list as build section")
+	}
+}
+
+func TestRollbackImage(t *testing.T) {
+	for image, want := range map[string]string{
+		"myapp-web":                         "myapp-web:treeos-rollback",
+		"myapp-web:latest":                  "myapp-web:treeos-rollback",
+		"registry.lan:5000/team/web":        "registry.lan:5000/team/web:treeos-rollback",
+		"registry.lan:5000/team/web:v2":     "registry.lan:5000/team/web:treeos-rollback",
+		"ghcr.io/example/web@sha256:abc123": "ghcr.io/example/web:treeos-rollback",
+	} {
+		if got := RollbackImage(image); got != want {
+			t.Errorf("RollbackImage(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
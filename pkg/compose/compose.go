@@ -12,16 +12,31 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/ontree-co/treeos/internal/metrics"
+	"github.com/ontree-co/treeos/internal/mockruntime"
 )
 
 // Service wraps access to docker compose operations.
 type Service struct {
 	dockerBinary string
+	mock         *mockruntime.Runtime // Replaces the docker CLI when the mock runtime is enabled
+	confinement  Confinement          // Applied by Up, see SetConfinement
+	readOnlyRoot bool                 // Default for apps without read_only_root, see SetReadOnlyRoot
+	secretStore  SecretStore          // Material of file secrets written by Up, see SetSecretStore
+	envSecrets   SecretStore          // Values of .env placeholders filled in by Up, see SetEnvSecretStore
+	slots        chan struct{}        // Limits concurrent Up and Down, see SetMaxConcurrent
 }
 
 // NewService creates a new compose service instance.
 func NewService() (*Service, error) {
+	if mockruntime.Enabled() {
+		return &Service{mock: mockruntime.Default()}, nil
+	}
+
 	dockerBin := os.Getenv("DOCKER_BINARY")
 	if dockerBin == "" {
 		dockerBin = "docker"
@@ -48,7 +63,11 @@ func (s *Service) Close() error { return nil }
 // Options represents options for compose operations.
 type Options struct {
 	WorkingDir string
-	EnvFile    string
+	EnvFile    string   // Relative to WorkingDir or absolute, .env by default
+	Services   []string // Limits Up to these services, all services if empty
+
+	ForceRecreate bool // Up recreates the containers even if their configuration is unchanged
+	RemoveOrphans bool // Up removes containers of services no longer in the compose file
 }
 
 // ContainerSummary captures container state returned by docker.
@@ -80,11 +99,26 @@ func (s *Service) Up(ctx context.Context, opts Options) error {
 }
 
 // UpWithProgress starts a compose project with progress monitoring.
-func (s *Service) UpWithProgress(ctx context.Context, opts Options, progressCallback ProgressCallback) error {
-	cmd, err := s.newComposeCmd(ctx, opts, "up", "-d")
+func (s *Service) UpWithProgress(ctx context.Context, opts Options, progressCallback ProgressCallback) (err error) {
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	defer func() { metrics.ComposeDuration.ObserveSince(start, "up", metrics.Result(err)) }()
+	if s.mock != nil {
+		return s.mockUp(ctx, opts, progressCallback)
+	}
+
+	if err := s.materializeSecrets(opts); err != nil {
+		return err
+	}
+	cmd, cleanup, err := s.newUpCmd(ctx, opts)
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
 	if progressCallback == nil {
 		// Fallback to simple execution if no progress callback
@@ -175,7 +209,18 @@ func (s *Service) UpWithProgress(ctx context.Context, opts Options, progressCall
 }
 
 // Down stops a compose project (equivalent to `docker compose down`).
-func (s *Service) Down(ctx context.Context, opts Options, removeVolumes bool) error {
+func (s *Service) Down(ctx context.Context, opts Options, removeVolumes bool) (err error) {
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+	defer func() { metrics.ComposeDuration.ObserveSince(start, "down", metrics.Result(err)) }()
+	if s.mock != nil {
+		return s.mockDown(ctx, opts)
+	}
+
 	args := []string{"down"}
 	if removeVolumes {
 		args = append(args, "--volumes")
@@ -190,6 +235,9 @@ func (s *Service) Down(ctx context.Context, opts Options, removeVolumes bool) er
 	if err != nil {
 		return fmt.Errorf("failed to stop containers: %w (output: %s)", err, strings.TrimSpace(string(output)))
 	}
+	if err := s.removeSecrets(opts); err != nil {
+		return fmt.Errorf("containers stopped, but %w", err)
+	}
 	return nil
 }
 
@@ -221,14 +269,42 @@ type LogWriter struct {
 	Err io.Writer
 }
 
+// LogOptions selects the log lines of LogsWithOptions
+type LogOptions struct {
+	Services   []string // All services if empty
+	Follow     bool
+	Timestamps bool   // Prefix each line with the time docker received it
+	Tail       int    // Only the last lines per container, all if 0
+	Since      string // Only lines after a time in RFC 3339 or a duration such as "10m"
+}
+
 // Logs streams logs from the compose project using the docker compose CLI.
 func (s *Service) Logs(ctx context.Context, opts Options, services []string, follow bool, writer LogWriter) error {
+	return s.LogsWithOptions(ctx, opts, LogOptions{Services: services, Follow: follow}, writer)
+}
+
+// LogsWithOptions streams logs like Logs with timestamps, tail and since. The simulated
+// runtime ignores them.
+func (s *Service) LogsWithOptions(ctx context.Context, opts Options, logOpts LogOptions, writer LogWriter) error {
+	if s.mock != nil {
+		return s.mockLogs(ctx, opts, logOpts.Services, logOpts.Follow, writer)
+	}
+
 	args := []string{"logs"}
-	if follow {
+	if logOpts.Follow {
 		args = append(args, "--follow")
 	}
-	if len(services) > 0 {
-		args = append(args, services...)
+	if logOpts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	if logOpts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(logOpts.Tail))
+	}
+	if logOpts.Since != "" {
+		args = append(args, "--since", logOpts.Since)
+	}
+	if len(logOpts.Services) > 0 {
+		args = append(args, logOpts.Services...)
 	}
 
 	cmd, err := s.newComposeCmd(ctx, opts, args...)
@@ -444,7 +520,66 @@ func commandAvailable(bin string, args ...string) error {
 	return nil
 }
 
+// newUpCmd builds `docker compose up -d` with the hardening override when one applies.
+// Call cleanup once the command has finished.
+func (s *Service) newUpCmd(ctx context.Context, opts Options) (*exec.Cmd, func(), error) {
+	noop := func() {}
+	absPath, _, err := resolveProject(opts)
+	if err != nil {
+		return nil, noop, err
+	}
+	composeFile, err := locateComposeFile(absPath)
+	if err != nil {
+		return nil, noop, err
+	}
+	override, cleanupOverride, err := s.writeOverride(composeFile)
+	if err != nil {
+		return nil, noop, err
+	}
+	envFile, cleanupEnv, err := s.writeEnvSecrets(opts)
+	if err != nil {
+		cleanupOverride()
+		return nil, noop, err
+	}
+	cleanup := func() {
+		cleanupEnv()
+		cleanupOverride()
+	}
+	if envFile != "" {
+		opts.EnvFile = envFile
+	}
+
+	var files []string
+	if override != "" {
+		files = append(files, override)
+	}
+	cmd, err := s.newComposeCmdWithFiles(ctx, opts, files, upArgs(opts)...)
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	return cmd, cleanup, nil
+}
+
+// upArgs returns the arguments of `docker compose up` for the options
+func upArgs(opts Options) []string {
+	args := []string{"up", "-d"}
+	if opts.ForceRecreate {
+		args = append(args, "--force-recreate")
+	}
+	if opts.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+	return append(args, opts.Services...)
+}
+
 func (s *Service) newComposeCmd(ctx context.Context, opts Options, extra ...string) (*exec.Cmd, error) {
+	return s.newComposeCmdWithFiles(ctx, opts, nil, extra...)
+}
+
+// newComposeCmdWithFiles builds a compose command with override files merged over the
+// project's compose file, in order.
+func (s *Service) newComposeCmdWithFiles(ctx context.Context, opts Options, overrides []string, extra ...string) (*exec.Cmd, error) {
 	absPath, _, err := resolveProject(opts)
 	if err != nil {
 		return nil, err
@@ -457,11 +592,16 @@ func (s *Service) newComposeCmd(ctx context.Context, opts Options, extra ...stri
 
 	// Always check for .env file in the project directory
 	envFile := filepath.Join(absPath, ".env")
-	if opts.EnvFile != "" {
+	if filepath.IsAbs(opts.EnvFile) {
+		envFile = opts.EnvFile
+	} else if opts.EnvFile != "" {
 		envFile = filepath.Join(absPath, opts.EnvFile)
 	}
 
 	args := []string{"compose", "-f", composeFile}
+	for _, override := range overrides {
+		args = append(args, "-f", override)
+	}
 
 	// Always pass env file if it exists
 	// The .env file should contain COMPOSE_PROJECT_NAME
@@ -477,6 +617,10 @@ func (s *Service) newComposeCmd(ctx context.Context, opts Options, extra ...stri
 }
 
 func (s *Service) listContainersForProject(ctx context.Context, project string) ([]ContainerSummary, error) {
+	if s.mock != nil {
+		return mockContainerSummaries(s.mock.Containers(project)), nil
+	}
+
 	// Use Docker compose labels
 	filters := []string{
 		fmt.Sprintf("label=com.docker.compose.project=%s", project),
@@ -3,6 +3,7 @@ package compose
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -77,3 +78,19 @@ func TestSortContainerSummaries(t *testing.T) {
 		t.Fatalf("unexpected order after sort: %+v", containers)
 	}
 }
+
+func TestUpArgs(t *testing.T) {
+	cases := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{}, "up -d"},
+		{Options{Services: []string{"web", "db"}}, "up -d web db"},
+		{Options{Services: []string{"web"}, ForceRecreate: true, RemoveOrphans: true}, "up -d --force-recreate --remove-orphans web"},
+	}
+	for _, c := range cases {
+		if got := strings.Join(upArgs(c.opts), " "); got != c.expected {
+			t.Errorf("upArgs(%+v) = %q, want %q", c.opts, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package compose
+
+import "context"
+
+// SetMaxConcurrent limits how many Up and Down operations run at the same time, further
+// ones wait for a slot. Pulling and starting several apps at once can exhaust the memory
+// of small nodes. 0 removes the limit.
+func (s *Service) SetMaxConcurrent(n int) {
+	if n <= 0 {
+		s.slots = nil
+		return
+	}
+	s.slots = make(chan struct{}, n)
+}
+
+// acquire waits for a slot of SetMaxConcurrent and returns the function releasing it
+func (s *Service) acquire(ctx context.Context) (func(), error) {
+	if s.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
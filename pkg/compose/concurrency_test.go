@@ -0,0 +1,37 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetMaxConcurrent(t *testing.T) {
+	s := &Service{}
+	s.SetMaxConcurrent(1)
+
+	release, err := s.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire() with all slots taken = %v, want deadline exceeded", err)
+	}
+
+	release()
+	release, err = s.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() after release error = %v", err)
+	}
+	release()
+
+	s.SetMaxConcurrent(0)
+	for i := 0; i < 3; i++ {
+		if _, err := s.acquire(context.Background()); err != nil {
+			t.Fatalf("acquire() without limit error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RenderedConfig is an app's compose file as Docker Compose deploys it: variables
+// interpolated, extends and profiles applied and the TreeOS hardening override merged
+type RenderedConfig struct {
+	Content  string   `json:"config"`
+	Warnings []string `json:"warnings"`        // e.g. variables that are not set
+	Error    string   `json:"error,omitempty"` // Why Docker Compose rejected the files
+}
+
+// Draft holds unsaved compose and .env content to render instead of the app's files
+type Draft struct {
+	Compose string
+	Env     string
+}
+
+// RenderConfig renders an app's configuration with `docker compose config`. With a draft
+// it renders the draft in the app's directory, so relative paths resolve as after saving.
+// A configuration Docker Compose rejects is reported in the Error field, not as error.
+func (s *Service) RenderConfig(ctx context.Context, opts Options, draft *Draft) (*RenderedConfig, error) {
+	absPath, _, err := resolveProject(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var composeContent, envContent []byte
+	if draft != nil {
+		composeContent, envContent = []byte(draft.Compose), []byte(draft.Env)
+	} else {
+		composeFile, err := locateComposeFile(absPath)
+		if err != nil {
+			return nil, err
+		}
+		if composeContent, err = os.ReadFile(composeFile); err != nil { //nolint:gosec // Path from the app directory
+			return nil, fmt.Errorf("failed to read compose file: %w", err)
+		}
+		envFile := filepath.Join(absPath, ".env")
+		if opts.EnvFile != "" {
+			envFile = filepath.Join(absPath, opts.EnvFile)
+		}
+		if envContent, err = os.ReadFile(envFile); err != nil && !os.IsNotExist(err) { //nolint:gosec // Path from the app directory
+			return nil, fmt.Errorf("failed to read env file: %w", err)
+		}
+	}
+
+	if s.mock != nil {
+		return mockRenderConfig(composeContent, envContent)
+	}
+	return s.renderConfig(ctx, absPath, composeContent, envContent)
+}
+
+func (s *Service) renderConfig(ctx context.Context, absPath string, composeContent, envContent []byte) (*RenderedConfig, error) {
+	// The override is built from the content so a draft renders with its own x-ontree settings
+	override, cleanup, err := s.writeOverrideFor(composeContent)
+	if err != nil {
+		// An unparsable draft is reported by compose itself below
+		override, cleanup = "", func() {}
+	}
+	defer cleanup()
+
+	envFile, err := os.CreateTemp("", "treeos-env-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create env file: %w", err)
+	}
+	defer os.Remove(envFile.Name()) //nolint:errcheck // Best effort cleanup
+	if _, err := envFile.Write(envContent); err != nil {
+		envFile.Close() //nolint:errcheck,gosec // Already failing
+		return nil, fmt.Errorf("failed to write env file: %w", err)
+	}
+	if err := envFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write env file: %w", err)
+	}
+
+	// The compose file is read from stdin, relative paths resolve against the project directory
+	args := []string{"compose", "-f", "-"}
+	if override != "" {
+		args = append(args, "-f", override)
+	}
+	args = append(args, "--project-directory", absPath, "--env-file", envFile.Name(), "config")
+
+	// #nosec G204 -- arguments are generated internally for docker interaction
+	cmd := exec.CommandContext(ctx, s.dockerBinary, args...)
+	cmd.Dir = absPath
+	cmd.Stdin = bytes.NewReader(composeContent)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, fmt.Errorf("failed to run docker compose config: %w", runErr)
+	}
+
+	rendered := &RenderedConfig{Warnings: []string{}}
+	var problems []string
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if message, level := composeMessage(line); message != "" {
+			if level == "warning" {
+				rendered.Warnings = append(rendered.Warnings, message)
+			} else {
+				problems = append(problems, message)
+			}
+		}
+	}
+	if runErr != nil {
+		rendered.Error = strings.Join(problems, "\n")
+		if rendered.Error == "" {
+			rendered.Error = runErr.Error()
+		}
+		return rendered, nil
+	}
+	rendered.Content = stdout.String()
+	return rendered, nil
+}
+
+// composeMessage extracts the message and level of a line Docker Compose logged, e.g.
+// `time="..." level=warning msg="The \"TAG\" variable is not set."` or `WARN[0000] ...`
+func composeMessage(line string) (message, level string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", ""
+	}
+	if strings.HasPrefix(line, "WARN[") {
+		if _, rest, ok := strings.Cut(line, "] "); ok {
+			return strings.TrimSpace(rest), "warning"
+		}
+	}
+	if strings.Contains(line, "level=") && strings.Contains(line, "msg=") {
+		_, levelPart, _ := strings.Cut(line, "level=")
+		level, _, _ = strings.Cut(levelPart, " ")
+		_, msgPart, _ := strings.Cut(line, "msg=")
+		if unquoted, err := strconv.Unquote(msgPart); err == nil {
+			msgPart = unquoted
+		}
+		return msgPart, level
+	}
+	return line, "error"
+}
+
+// mockRenderConfig interpolates variables the way compose does, without the merging
+// and validation only Docker Compose implements
+func mockRenderConfig(composeContent, envContent []byte) (*RenderedConfig, error) {
+	env, err := parseEnv(bytes.NewReader(envContent))
+	if err != nil {
+		return nil, err
+	}
+	rendered := &RenderedConfig{Warnings: []string{}}
+	warned := make(map[string]bool)
+	rendered.Content = os.Expand(string(composeContent), func(expr string) string {
+		if expr == "$" {
+			return "$" // $$ escapes a dollar sign
+		}
+		value, ok := expandVariable(expr, env)
+		if !ok && !warned[expr] {
+			warned[expr] = true
+			rendered.Warnings = append(rendered.Warnings, fmt.Sprintf("The %q variable is not set. Defaulting to a blank string.", expr))
+		}
+		return value
+	})
+	return rendered, nil
+}
@@ -0,0 +1,104 @@
+package compose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/mockruntime"
+)
+
+// fakeDocker writes a docker replacement that echoes the compose file from stdin and logs
+// like Docker Compose does
+func fakeDocker(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700); err != nil { //nolint:gosec // Test executable
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRenderConfig(t *testing.T) {
+	dir := t.TempDir()
+	compose := "services:\n  web:\n    image: nginx:${TAG}\n    x-ontree:\n      read_only: true\n"
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(compose), 0600); err != nil {
+		t.Fatal(err)
+	}
+	argsFile := filepath.Join(t.TempDir(), "args")
+	s := &Service{
+		dockerBinary: fakeDocker(t, `echo "$@" > `+argsFile+`
+cat
+echo 'time="2026-01-01T00:00:00Z" level=warning msg="The \"TAG\" variable is not set. Defaulting to a blank string."' >&2
+`),
+		readOnlyRoot: true,
+	}
+
+	rendered, err := s.RenderConfig(context.Background(), Options{WorkingDir: dir}, nil)
+	if err != nil {
+		t.Fatalf("RenderConfig() error = %v", err)
+	}
+	if rendered.Content != compose || rendered.Error != "" {
+		t.Errorf("RenderConfig() = %+v, want the compose file from stdin", rendered)
+	}
+	want := []string{`The "TAG" variable is not set. Defaulting to a blank string.`}
+	if !reflect.DeepEqual(rendered.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", rendered.Warnings, want)
+	}
+	args, err := os.ReadFile(argsFile) //nolint:gosec // Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The read-only service gets the override, like `up` would apply it
+	if !strings.HasPrefix(string(args), "compose -f - -f ") || !strings.Contains(string(args), "--project-directory "+dir) ||
+		!strings.HasSuffix(strings.TrimSpace(string(args)), " config") {
+		t.Errorf("docker called with %q", args)
+	}
+
+	s.dockerBinary = fakeDocker(t, "cat > /dev/null\necho 'services.web.ports must be a list' >&2\nexit 15\n")
+	rendered, err = s.RenderConfig(context.Background(), Options{WorkingDir: dir}, &Draft{Compose: "services:\n  web:\n    ports: 80\n"})
+	if err != nil {
+		t.Fatalf("RenderConfig() of an invalid draft error = %v", err)
+	}
+	if rendered.Error != "services.web.ports must be a list" || rendered.Content != "" {
+		t.Errorf("RenderConfig() of an invalid draft = %+v", rendered)
+	}
+}
+
+func TestMockRenderConfig(t *testing.T) {
+	s := &Service{mock: mockruntime.New(mockruntime.Config{})}
+	draft := &Draft{
+		Compose: "services:\n  web:\n    image: nginx:${TAG:-1.27}\n    command: echo $$HOME ${GREETING} ${TYPO}\n",
+		Env:     "GREETING=hello\n",
+	}
+	rendered, err := s.RenderConfig(context.Background(), Options{WorkingDir: t.TempDir()}, draft)
+	if err != nil {
+		t.Fatalf("RenderConfig() error = %v", err)
+	}
+	if !strings.Contains(rendered.Content, "nginx:1.27") || !strings.Contains(rendered.Content, "echo $HOME hello \n") {
+		t.Errorf("RenderConfig() content = %q", rendered.Content)
+	}
+	if len(rendered.Warnings) != 1 || !strings.Contains(rendered.Warnings[0], `"TYPO"`) {
+		t.Errorf("RenderConfig() warnings = %q, want one for TYPO", rendered.Warnings)
+	}
+}
+
+func TestComposeMessage(t *testing.T) {
+	tests := []struct {
+		line, message, level string
+	}{
+		{"", "", ""},
+		{`WARN[0000] /srv/app/docker-compose.yml: the attribute version is obsolete`, "/srv/app/docker-compose.yml: the attribute version is obsolete", "warning"},
+		{`time="x" level=warning msg="The \"A\" variable is not set."`, `The "A" variable is not set.`, "warning"},
+		{`invalid interpolation format for services.web.image`, "invalid interpolation format for services.web.image", "error"},
+	}
+	for _, tt := range tests {
+		message, level := composeMessage(tt.line)
+		if message != tt.message || level != tt.level {
+			t.Errorf("composeMessage(%q) = %q, %q, want %q, %q", tt.line, message, level, tt.message, tt.level)
+		}
+	}
+}
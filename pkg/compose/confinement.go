@@ -0,0 +1,125 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Confinement modes accepted by NewConfinement
+const (
+	ConfinementAppArmor = "apparmor"
+	ConfinementSELinux  = "selinux"
+)
+
+// Default profiles, both ship with Docker on hosts that enable the modules
+const (
+	DefaultAppArmorProfile = "docker-default"
+	DefaultSELinuxType     = "container_t"
+)
+
+// Confinement is the mandatory access control applied to containers started by Up.
+// The zero value leaves the runtime defaults alone.
+type Confinement struct {
+	AppArmorProfile string // Profile loaded on the host, e.g. docker-default
+	SELinuxType     string // SELinux process type, e.g. container_t
+}
+
+// NewConfinement returns the confinement for a mode ("apparmor", "selinux" or "" for none)
+// and an optional profile name or SELinux type overriding the default.
+func NewConfinement(mode, profile string) Confinement {
+	switch mode {
+	case ConfinementAppArmor:
+		if profile == "" {
+			profile = DefaultAppArmorProfile
+		}
+		return Confinement{AppArmorProfile: profile}
+	case ConfinementSELinux:
+		if profile == "" {
+			profile = DefaultSELinuxType
+		}
+		return Confinement{SELinuxType: profile}
+	default:
+		return Confinement{}
+	}
+}
+
+// Enabled reports whether a profile or label is applied
+func (c Confinement) Enabled() bool {
+	return c.AppArmorProfile != "" || c.SELinuxType != ""
+}
+
+// SecurityOpts returns the security_opt entries that apply the confinement
+func (c Confinement) SecurityOpts() []string {
+	var opts []string
+	if c.AppArmorProfile != "" {
+		opts = append(opts, "apparmor="+c.AppArmorProfile)
+	}
+	if c.SELinuxType != "" {
+		opts = append(opts, "label=type:"+c.SELinuxType)
+	}
+	return opts
+}
+
+// SetConfinement applies a profile or label to all containers started from now on
+func (s *Service) SetConfinement(c Confinement) {
+	s.confinement = c
+}
+
+// ContainerConfinement is the profile and label a running container is confined by
+type ContainerConfinement struct {
+	Name            string
+	Service         string
+	AppArmorProfile string // Empty or "unconfined" when AppArmor does not confine the container
+	SELinuxLabel    string // Process label, empty without SELinux
+}
+
+// Confined reports whether a profile or label restricts the container
+func (c ContainerConfinement) Confined() bool {
+	return (c.AppArmorProfile != "" && c.AppArmorProfile != "unconfined") ||
+		(c.SELinuxLabel != "" && !strings.Contains(c.SELinuxLabel, ":spc_t:") && !strings.Contains(c.SELinuxLabel, ":unconfined_t:"))
+}
+
+// Confinement returns the AppArmor profile and SELinux label of each container of a project
+func (s *Service) Confinement(ctx context.Context, opts Options) ([]ContainerConfinement, error) {
+	containers, err := s.PS(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if s.mock != nil || len(containers) == 0 {
+		return []ContainerConfinement{}, nil
+	}
+
+	args := []string{"inspect", "--format", "{{.Name}}\t{{.AppArmorProfile}}\t{{.ProcessLabel}}"}
+	services := make(map[string]string, len(containers))
+	for _, container := range containers {
+		args = append(args, container.ID)
+		services[container.Name] = container.Service
+	}
+
+	// #nosec G204 -- container IDs come from docker ps
+	output, err := exec.CommandContext(ctx, s.dockerBinary, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("docker inspect failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return parseConfinement(string(output), services), nil
+}
+
+func parseConfinement(output string, services map[string]string) []ContainerConfinement {
+	result := []ContainerConfinement{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[0], "/")
+		result = append(result, ContainerConfinement{
+			Name:            name,
+			Service:         services[name],
+			AppArmorProfile: fields[1],
+			SELinuxLabel:    fields[2],
+		})
+	}
+	return result
+}
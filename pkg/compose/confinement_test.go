@@ -0,0 +1,49 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewConfinement(t *testing.T) {
+	cases := []struct {
+		mode, profile string
+		want          []string
+	}{
+		{"", "", nil},
+		{"apparmor", "", []string{"apparmor=docker-default"}},
+		{"apparmor", "treeos-strict", []string{"apparmor=treeos-strict"}},
+		{"selinux", "", []string{"label=type:container_t"}},
+	}
+
+	for _, tc := range cases {
+		got := NewConfinement(tc.mode, tc.profile).SecurityOpts()
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("NewConfinement(%q, %q).SecurityOpts() = %v, want %v", tc.mode, tc.profile, got, tc.want)
+		}
+	}
+}
+
+func TestParseConfinement(t *testing.T) {
+	output := "/ontree-immich-server-1\tdocker-default\t\n" +
+		"/ontree-immich-db-1\tunconfined\t\n" +
+		"/ontree-immich-redis-1\t\tsystem_u:system_r:container_t:s0:c1,c2\n"
+	services := map[string]string{
+		"ontree-immich-server-1": "server",
+		"ontree-immich-db-1":     "db",
+	}
+
+	got := parseConfinement(output, services)
+	if len(got) != 3 {
+		t.Fatalf("parseConfinement() returned %d containers, want 3", len(got))
+	}
+	if got[0].Service != "server" || got[0].AppArmorProfile != "docker-default" || !got[0].Confined() {
+		t.Errorf("server = %+v", got[0])
+	}
+	if got[1].Confined() {
+		t.Errorf("unconfined db reported as confined: %+v", got[1])
+	}
+	if got[2].Name != "ontree-immich-redis-1" || !got[2].Confined() {
+		t.Errorf("redis = %+v", got[2])
+	}
+}
@@ -0,0 +1,161 @@
+package compose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ontree-co/treeos/internal/metrics"
+)
+
+// Images returns the images of a compose file's services, sorted and without duplicates.
+// ${VAR}, ${VAR:-default} and ${VAR-default} are resolved from env. Images that still depend
+// on an unset variable are skipped, they can't be pulled before the app is configured.
+func Images(content []byte, env map[string]string) ([]string, error) {
+	var project struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(content, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var images []string
+	for _, service := range project.Services {
+		image, resolved := ExpandVariables(service.Image, env)
+		if !resolved || image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// ExpandVariables resolves ${VAR}, ${VAR:-default} and ${VAR-default} in a value of a
+// compose file from env. It reports false if a variable is unset and has no default.
+func ExpandVariables(value string, env map[string]string) (string, bool) {
+	resolved := true
+	expanded := os.Expand(value, func(name string) string {
+		v, ok := expandVariable(name, env)
+		resolved = resolved && ok
+		return v
+	})
+	return expanded, resolved
+}
+
+// expandVariable resolves the content of ${...}, reporting false for unset variables
+// without a default
+func expandVariable(expr string, env map[string]string) (string, bool) {
+	if name, fallback, ok := strings.Cut(expr, ":-"); ok {
+		if value := env[name]; value != "" {
+			return value, true
+		}
+		return fallback, true
+	}
+	if name, fallback, ok := strings.Cut(expr, "-"); ok {
+		if value, set := env[name]; set {
+			return value, true
+		}
+		return fallback, true
+	}
+	value, ok := env[expr]
+	return value, ok
+}
+
+// ReadEnvFile reads the KEY=VALUE lines of an .env file. A missing file is empty.
+func ReadEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path) //nolint:gosec // Path of an app's own .env file
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck // Read-only file
+	return parseEnv(file)
+}
+
+// ParseEnv reads the KEY=VALUE lines of the content of an .env file
+func ParseEnv(content []byte) (map[string]string, error) {
+	return parseEnv(bytes.NewReader(content))
+}
+
+func parseEnv(r io.Reader) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[strings.TrimSpace(key)] = value
+	}
+	return env, scanner.Err()
+}
+
+// PullImage pulls an image so a later `up` of an app using it doesn't have to
+func (s *Service) PullImage(ctx context.Context, image string) (err error) {
+	start := time.Now()
+	defer func() { metrics.ComposeDuration.ObserveSince(start, "pull", metrics.Result(err)) }()
+	if s.mock != nil {
+		select {
+		case <-time.After(s.mock.Config().Latency):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// #nosec G204 -- image references come from compose files of templates and installed apps
+	cmd := exec.CommandContext(ctx, s.dockerBinary, "pull", "--quiet", image)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w (output: %s)", image, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ImageDigests returns the registry digests of a local image, e.g.
+// "postgres@sha256:...". It is empty for images that were built locally or aren't pulled.
+func (s *Service) ImageDigests(ctx context.Context, image string) ([]string, error) {
+	if s.mock != nil {
+		return nil, nil
+	}
+
+	// #nosec G204 -- image references come from compose files of installed apps
+	cmd := exec.CommandContext(ctx, s.dockerBinary, "image", "inspect", "--format", "{{json .RepoDigests}}", image)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(strings.ToLower(string(output)), "no such image") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to inspect %s: %w (output: %s)", image, err, strings.TrimSpace(string(output)))
+	}
+	var digests []string
+	if err := json.Unmarshal(output, &digests); err != nil {
+		return nil, fmt.Errorf("failed to parse digests of %s: %w", image, err)
+	}
+	return digests, nil
+}
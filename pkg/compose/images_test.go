@@ -0,0 +1,67 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImages(t *testing.T) {
+	content := []byte(`
+services:
+  app:
+    image: ${IMAGE_REPOSITORY:-ghcr.io/ontree-co/cookingcompanion}:${VERSION:-latest}
+  db:
+    image: postgres:16-alpine
+  cache:
+    image: redis:${REDIS_TAG}
+  worker:
+    image: postgres:16-alpine
+  build-only:
+    build: .
+`)
+
+	images, err := Images(content, nil)
+	if err != nil {
+		t.Fatalf("Images() error = %v", err)
+	}
+	want := []string{"ghcr.io/ontree-co/cookingcompanion:latest", "postgres:16-alpine"}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("Images() = %v, want %v", images, want)
+	}
+
+	images, err = Images(content, map[string]string{"VERSION": "1.2", "REDIS_TAG": "7-alpine"})
+	if err != nil {
+		t.Fatalf("Images() error = %v", err)
+	}
+	want = []string{"ghcr.io/ontree-co/cookingcompanion:1.2", "postgres:16-alpine", "redis:7-alpine"}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("Images() with env = %v, want %v", images, want)
+	}
+
+	if _, err := Images([]byte("services: ["), nil); err == nil {
+		t.Error("Images() accepted invalid YAML")
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	env, err := ReadEnvFile(path)
+	if err != nil || len(env) != 0 {
+		t.Fatalf("ReadEnvFile() of a missing file = %v, %v", env, err)
+	}
+
+	content := "# comment\nCOMPOSE_PROJECT_NAME=ontree-demo\nexport VERSION=\"1.2\"\nEMPTY=\nnot a variable\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env, err = ReadEnvFile(path)
+	if err != nil {
+		t.Fatalf("ReadEnvFile() error = %v", err)
+	}
+	want := map[string]string{"COMPOSE_PROJECT_NAME": "ontree-demo", "VERSION": "1.2", "EMPTY": ""}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("ReadEnvFile() = %v, want %v", env, want)
+	}
+}
@@ -0,0 +1,102 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/mockruntime"
+)
+
+// mockUp starts the project in the mock runtime, reporting compose-like progress lines
+func (s *Service) mockUp(ctx context.Context, opts Options, progressCallback ProgressCallback) error {
+	absPath, project, err := resolveProject(opts)
+	if err != nil {
+		return err
+	}
+	composeFile, err := locateComposeFile(absPath)
+	if err != nil {
+		return err
+	}
+	services, err := mockruntime.ReadServices(composeFile)
+	if err != nil {
+		return err
+	}
+
+	if progressCallback != nil {
+		for _, service := range services {
+			progressCallback(fmt.Sprintf(" Container %s-%s-1  Creating", project, service.Name))
+		}
+	}
+	// The simulation recreates all services, also when opts.Services names some of them
+	if err := s.mock.Up(ctx, project, services); err != nil {
+		return fmt.Errorf("failed to start containers: %w", err)
+	}
+	if progressCallback != nil {
+		for _, service := range services {
+			progressCallback(fmt.Sprintf(" Container %s-%s-1  Started", project, service.Name))
+		}
+	}
+	return nil
+}
+
+func (s *Service) mockDown(ctx context.Context, opts Options) error {
+	_, project, err := resolveProject(opts)
+	if err != nil {
+		return err
+	}
+	if err := s.mock.Down(ctx, project); err != nil {
+		return fmt.Errorf("failed to stop containers: %w", err)
+	}
+	return nil
+}
+
+// mockLogs writes the simulated log lines. With follow it blocks until ctx is done, like
+// docker compose logs --follow on a quiet container.
+func (s *Service) mockLogs(ctx context.Context, opts Options, services []string, follow bool, writer LogWriter) error {
+	_, project, err := resolveProject(opts)
+	if err != nil {
+		return err
+	}
+	if writer.Out == nil {
+		writer.Out = io.Discard
+	}
+	if lines := s.mock.Logs(project, services); len(lines) > 0 {
+		if _, err := io.WriteString(writer.Out, strings.Join(lines, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+	if follow {
+		<-ctx.Done()
+	}
+	return nil
+}
+
+func mockContainerSummaries(containers []mockruntime.Container) []ContainerSummary {
+	summaries := make([]ContainerSummary, 0, len(containers))
+	for _, c := range containers {
+		summary := ContainerSummary{
+			ID:      c.ID,
+			Name:    c.Name,
+			Service: c.Service,
+			State:   c.State,
+			Status:  c.Status,
+			Image:   c.Image,
+			Health:  c.Health,
+		}
+		for _, mapping := range c.Ports {
+			host, container, ok := strings.Cut(mapping, ":")
+			if !ok {
+				continue
+			}
+			containerPort, protocol, ok := strings.Cut(container, "/")
+			if !ok {
+				protocol = "tcp"
+			}
+			summary.Ports = append(summary.Ports, PortMapping{HostIP: "0.0.0.0", HostPort: host, ContainerPort: containerPort, Protocol: protocol})
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
@@ -0,0 +1,57 @@
+package compose
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontree-co/treeos/internal/mockruntime"
+)
+
+func TestMockService(t *testing.T) {
+	dir := t.TempDir()
+	compose := "services:\n  web:\n    image: nginx:1.27\n    ports:\n      - \"8080:80\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(compose), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("COMPOSE_PROJECT_NAME=ontree-blog\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{mock: mockruntime.New(mockruntime.Config{})}
+	opts := Options{WorkingDir: dir}
+	ctx := context.Background()
+
+	var progress []string
+	if err := s.UpWithProgress(ctx, opts, func(line string) { progress = append(progress, line) }); err != nil {
+		t.Fatalf("UpWithProgress() error = %v", err)
+	}
+	if len(progress) != 2 || !strings.Contains(progress[1], "ontree-blog-web-1  Started") {
+		t.Errorf("progress = %q", progress)
+	}
+
+	containers, err := s.PS(ctx, opts)
+	if err != nil {
+		t.Fatalf("PS() error = %v", err)
+	}
+	if len(containers) != 1 || containers[0].State != "running" || len(containers[0].Ports) != 1 || containers[0].Ports[0].HostPort != "8080" {
+		t.Errorf("containers = %+v", containers)
+	}
+
+	var logs bytes.Buffer
+	if err := s.Logs(ctx, opts, []string{"web"}, false, LogWriter{Out: &logs}); err != nil {
+		t.Fatalf("Logs() error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "ontree-blog-web-1  | Starting nginx:1.27") {
+		t.Errorf("logs = %q", logs.String())
+	}
+
+	if err := s.Down(ctx, opts, false); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if containers, _ := s.PS(ctx, opts); len(containers) != 0 {
+		t.Errorf("containers after down = %+v", containers)
+	}
+}
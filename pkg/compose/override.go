@@ -0,0 +1,172 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Per-app read-only root settings in the x-ontree metadata; empty follows SetReadOnlyRoot
+const (
+	ReadOnlyRootOn  = "on"
+	ReadOnlyRootOff = "off"
+)
+
+// defaultTmpfs are mounted into read-only services, most images write there at runtime
+var defaultTmpfs = []string{"/tmp", "/run"}
+
+// serviceOverride is what TreeOS merges over a service of an app's compose file
+type serviceOverride struct {
+	SecurityOpt []string `yaml:"security_opt,omitempty"`
+	ReadOnly    bool     `yaml:"read_only,omitempty"`
+	Tmpfs       []string `yaml:"tmpfs,omitempty"`
+}
+
+// overrideProject holds the parts of a compose file that decide the override. Templates mark
+// services that work with a read-only root filesystem in their x-ontree extension:
+//
+//	services:
+//	  web:
+//	    x-ontree:
+//	      read_only: true
+//	      tmpfs: [/var/cache/nginx]
+type overrideProject struct {
+	Services map[string]struct {
+		XOnTree struct {
+			ReadOnly bool     `yaml:"read_only"`
+			Tmpfs    []string `yaml:"tmpfs"`
+		} `yaml:"x-ontree"`
+	} `yaml:"services"`
+	XOnTree struct {
+		BypassSecurity bool   `yaml:"bypass_security"`
+		ReadOnlyRoot   string `yaml:"read_only_root"`
+	} `yaml:"x-ontree"`
+}
+
+// SetReadOnlyRoot sets whether services marked compatible get a read-only root filesystem
+// in apps without their own read_only_root setting
+func (s *Service) SetReadOnlyRoot(enabled bool) {
+	s.readOnlyRoot = enabled
+}
+
+// ReadOnlyRootApplies reports whether an app with the given read_only_root setting runs
+// with a read-only root filesystem
+func (s *Service) ReadOnlyRootApplies(setting string) bool {
+	return ReadOnlyRootEnabled(setting, s.readOnlyRoot)
+}
+
+// ReadOnlyRootEnabled resolves a read_only_root setting against the global default
+func ReadOnlyRootEnabled(setting string, defaultOn bool) bool {
+	switch setting {
+	case ReadOnlyRootOn:
+		return true
+	case ReadOnlyRootOff:
+		return false
+	default:
+		return defaultOn
+	}
+}
+
+// ReadOnlyServices returns the services of a compose file marked compatible with a
+// read-only root filesystem, sorted by name
+func ReadOnlyServices(content []byte) ([]string, error) {
+	var project overrideProject
+	if err := yaml.Unmarshal(content, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	names := []string{}
+	for name, service := range project.Services {
+		if service.XOnTree.ReadOnly {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// buildOverride returns a compose override hardening the services of a compose file, or
+// nil when there is nothing to apply. Apps with security validation bypassed are not
+// confined, they may need the access the profile denies.
+func buildOverride(content []byte, c Confinement, readOnlyRoot bool) ([]byte, error) {
+	var project overrideProject
+	if err := yaml.Unmarshal(content, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	confine := c.Enabled() && !project.XOnTree.BypassSecurity
+	readOnly := ReadOnlyRootEnabled(project.XOnTree.ReadOnlyRoot, readOnlyRoot)
+
+	names := make([]string, 0, len(project.Services))
+	for name := range project.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	services := map[string]serviceOverride{}
+	for _, name := range names {
+		var override serviceOverride
+		if confine {
+			override.SecurityOpt = c.SecurityOpts()
+		}
+		if hints := project.Services[name].XOnTree; readOnly && hints.ReadOnly {
+			override.ReadOnly = true
+			override.Tmpfs = mergeTmpfs(defaultTmpfs, hints.Tmpfs)
+		}
+		if override.SecurityOpt != nil || override.ReadOnly {
+			services[name] = override
+		}
+	}
+	if len(services) == 0 {
+		return nil, nil
+	}
+	return yaml.Marshal(map[string]interface{}{"services": services})
+}
+
+func mergeTmpfs(defaults, extra []string) []string {
+	seen := make(map[string]bool, len(defaults)+len(extra))
+	merged := make([]string, 0, len(defaults)+len(extra))
+	for _, path := range append(append([]string{}, defaults...), extra...) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			merged = append(merged, path)
+		}
+	}
+	return merged
+}
+
+// writeOverride writes the override for a compose file to a temporary file.
+// The returned cleanup removes it; path is empty when no override is needed.
+func (s *Service) writeOverride(composeFile string) (path string, cleanup func(), err error) {
+	content, err := os.ReadFile(composeFile) //nolint:gosec // Path from the app directory
+	if err != nil {
+		return "", func() {}, fmt.Errorf("failed to read compose file: %w", err)
+	}
+	return s.writeOverrideFor(content)
+}
+
+// writeOverrideFor writes the override for compose file content, see writeOverride
+func (s *Service) writeOverrideFor(content []byte) (path string, cleanup func(), err error) {
+	noop := func() {}
+	override, err := buildOverride(content, s.confinement, s.readOnlyRoot)
+	if err != nil || override == nil {
+		return "", noop, err
+	}
+
+	file, err := os.CreateTemp("", "treeos-override-*.yml")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create compose override: %w", err)
+	}
+	cleanup = func() { os.Remove(file.Name()) } //nolint:errcheck,gosec // Best effort cleanup
+	if _, err := file.Write(override); err != nil {
+		file.Close() //nolint:errcheck,gosec // Already failing
+		cleanup()
+		return "", noop, fmt.Errorf("failed to write compose override: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to write compose override: %w", err)
+	}
+	return file.Name(), cleanup, nil
+}
@@ -0,0 +1,97 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseOverride(t *testing.T, override []byte) map[string]serviceOverride {
+	t.Helper()
+	var parsed struct {
+		Services map[string]serviceOverride `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(override, &parsed); err != nil {
+		t.Fatalf("override is not valid YAML: %v\n%s", err, override)
+	}
+	return parsed.Services
+}
+
+func TestBuildOverride(t *testing.T) {
+	content := `services:
+  web:
+    image: miniflux/miniflux
+    x-ontree:
+      read_only: true
+      tmpfs: [/var/cache/miniflux, /tmp]
+  db:
+    image: postgres
+`
+	confinement := NewConfinement(ConfinementAppArmor, "")
+
+	t.Run("confinement only", func(t *testing.T) {
+		override, err := buildOverride([]byte(content), confinement, false)
+		if err != nil {
+			t.Fatalf("buildOverride() error = %v", err)
+		}
+		services := parseOverride(t, override)
+		if len(services) != 2 || !reflect.DeepEqual(services["db"].SecurityOpt, []string{"apparmor=docker-default"}) || services["web"].ReadOnly {
+			t.Errorf("override = %s", override)
+		}
+	})
+
+	t.Run("read-only root for compatible services", func(t *testing.T) {
+		override, err := buildOverride([]byte(content), Confinement{}, true)
+		if err != nil {
+			t.Fatalf("buildOverride() error = %v", err)
+		}
+		services := parseOverride(t, override)
+		web, ok := services["web"]
+		if len(services) != 1 || !ok || !web.ReadOnly {
+			t.Fatalf("override = %s, want only web read-only", override)
+		}
+		if want := []string{"/tmp", "/run", "/var/cache/miniflux"}; !reflect.DeepEqual(web.Tmpfs, want) {
+			t.Errorf("tmpfs = %v, want %v", web.Tmpfs, want)
+		}
+	})
+
+	t.Run("app setting overrides the default", func(t *testing.T) {
+		off := content + "x-ontree:\n  read_only_root: \"off\"\n"
+		if override, err := buildOverride([]byte(off), Confinement{}, true); err != nil || override != nil {
+			t.Errorf("buildOverride() with read_only_root off = %s, %v; want nothing", override, err)
+		}
+		on := content + "x-ontree:\n  read_only_root: \"on\"\n"
+		override, err := buildOverride([]byte(on), Confinement{}, false)
+		if err != nil || !parseOverride(t, override)["web"].ReadOnly {
+			t.Errorf("buildOverride() with read_only_root on = %s, %v", override, err)
+		}
+	})
+
+	t.Run("bypassed apps are not confined", func(t *testing.T) {
+		bypassed := content + "x-ontree:\n  bypass_security: true\n"
+		if override, err := buildOverride([]byte(bypassed), confinement, false); err != nil || override != nil {
+			t.Errorf("buildOverride() for bypassed app = %s, %v; want nothing", override, err)
+		}
+	})
+}
+
+func TestReadOnlyServices(t *testing.T) {
+	content := `services:
+  web:
+    x-ontree:
+      read_only: true
+  db:
+    image: postgres
+  cache:
+    x-ontree:
+      read_only: true
+`
+	services, err := ReadOnlyServices([]byte(content))
+	if err != nil {
+		t.Fatalf("ReadOnlyServices() error = %v", err)
+	}
+	if want := []string{"cache", "web"}; !reflect.DeepEqual(services, want) {
+		t.Errorf("ReadOnlyServices() = %v, want %v", services, want)
+	}
+}
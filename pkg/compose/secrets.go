@@ -0,0 +1,196 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ontree-co/treeos/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// SecretStore returns the stored material of an app's compose secrets by secret name. The app
+// is the name of the project directory.
+type SecretStore func(app string) (map[string]string, error)
+
+// FileSecret is a top-level compose secret read from a file in the app directory:
+//
+//	secrets:
+//	  db_password:
+//	    file: ./secrets/db_password
+type FileSecret struct {
+	Name string `json:"name"`
+	File string `json:"file"` // Relative to the app directory
+}
+
+// SetSecretStore sets where Up reads the material of file secrets from. Up writes the stored
+// values to the secret files and Down removes them again, so the material is only on disk
+// while the app runs.
+func (s *Service) SetSecretStore(store SecretStore) {
+	s.secretStore = store
+}
+
+// SetEnvSecretStore sets where Up reads the values of {{ secret NAME }} placeholders in the
+// .env file from. Up hands compose a copy of the .env with the values filled in, which is
+// removed once the command finished; the .env in the app directory keeps the placeholders.
+func (s *Service) SetEnvSecretStore(store SecretStore) {
+	s.envSecrets = store
+}
+
+// FileSecrets returns the file secrets of compose content sorted by name. Secrets from the
+// environment and files outside the app directory are skipped, TreeOS doesn't manage them.
+func FileSecrets(content []byte) ([]FileSecret, error) {
+	var project struct {
+		Secrets map[string]struct {
+			File string `yaml:"file"`
+		} `yaml:"secrets"`
+	}
+	if err := yaml.Unmarshal(content, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	secrets := []FileSecret{}
+	for name, secret := range project.Secrets {
+		file := filepath.Clean(secret.File)
+		if secret.File == "" || filepath.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+			continue
+		}
+		secrets = append(secrets, FileSecret{Name: name, File: file})
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+// storedSecrets returns the directory and file secrets of a project and the stored values
+// among them
+func (s *Service) storedSecrets(opts Options) (string, []FileSecret, map[string]string, error) {
+	if s.secretStore == nil {
+		return "", nil, nil, nil
+	}
+	absPath, _, err := resolveProject(opts)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	composeFile, err := locateComposeFile(absPath)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	content, err := os.ReadFile(composeFile) //nolint:gosec // Compose file of the project
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read compose file: %w", err)
+	}
+	secrets, err := FileSecrets(content)
+	if err != nil || len(secrets) == 0 {
+		return "", nil, nil, err
+	}
+	values, err := s.secretStore(filepath.Base(absPath))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	return absPath, secrets, values, nil
+}
+
+// materializeSecrets writes the stored secrets of a project to their files before Up. Missing
+// directories are created accessible to TreeOS only, the file is world-readable so that
+// containers with another user can read the bind mount. A secret without a stored value needs its file on
+// disk already, otherwise Up fails naming the secret.
+func (s *Service) materializeSecrets(opts Options) error {
+	absPath, secrets, values, err := s.storedSecrets(opts)
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		path := filepath.Join(absPath, secret.File)
+		value, ok := values[secret.Name]
+		if !ok {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("secret %s has no value, set it before starting the app", secret.Name)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return fmt.Errorf("failed to create directory of secret %s: %w", secret.Name, err)
+		}
+		// The previous file is read-only, replace it instead of writing into it
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to replace secret %s: %w", secret.Name, err)
+		}
+		if err := os.WriteFile(path, []byte(value), 0o444); err != nil { //nolint:gosec // Read through the bind mount by the container user
+			return fmt.Errorf("failed to write secret %s: %w", secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// removeSecrets deletes the files of stored secrets after Down. Files of secrets without a
+// stored value were put there by the user and stay.
+func (s *Service) removeSecrets(opts Options) error {
+	absPath, secrets, values, err := s.storedSecrets(opts)
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		if _, ok := values[secret.Name]; !ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(absPath, secret.File)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove secret %s: %w", secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeEnvSecrets writes the .env of a project with its secrets filled in to a temporary
+// file readable only by TreeOS. path is empty when the .env has no placeholders.
+func (s *Service) writeEnvSecrets(opts Options) (path string, cleanup func(), err error) {
+	noop := func() {}
+	if s.envSecrets == nil {
+		return "", noop, nil
+	}
+	absPath, _, err := resolveProject(opts)
+	if err != nil {
+		return "", noop, err
+	}
+	envFile := filepath.Join(absPath, ".env")
+	if filepath.IsAbs(opts.EnvFile) {
+		envFile = opts.EnvFile
+	} else if opts.EnvFile != "" {
+		envFile = filepath.Join(absPath, opts.EnvFile)
+	}
+	content, err := os.ReadFile(envFile) //nolint:gosec // Path from the app directory
+	if os.IsNotExist(err) {
+		return "", noop, nil
+	}
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to read .env: %w", err)
+	}
+	if len(secrets.Names(content)) == 0 {
+		return "", noop, nil
+	}
+
+	values, err := s.envSecrets(filepath.Base(absPath))
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	rendered, err := secrets.Render(content, values)
+	if err != nil {
+		return "", noop, err
+	}
+	file, err := os.CreateTemp("", "treeos-env-*")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create .env with secrets: %w", err)
+	}
+	cleanup = func() { os.Remove(file.Name()) } //nolint:errcheck,gosec // Best effort cleanup
+	if _, err := file.Write(rendered); err != nil {
+		file.Close() //nolint:errcheck,gosec // Already failing
+		cleanup()
+		return "", noop, fmt.Errorf("failed to write .env with secrets: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to write .env with secrets: %w", err)
+	}
+	return file.Name(), cleanup, nil
+}
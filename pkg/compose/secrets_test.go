@@ -0,0 +1,162 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileSecrets(t *testing.T) {
+	content := `services:
+  db:
+    image: postgres:16
+    secrets: [db_password, api_key]
+secrets:
+  db_password:
+    file: ./secrets/db_password
+  api_key:
+    environment: API_KEY
+  host_file:
+    file: /etc/shadow
+  escape:
+    file: ../other/secret
+`
+	secrets, err := FileSecrets([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileSecret{{Name: "db_password", File: "secrets/db_password"}}
+	if !reflect.DeepEqual(secrets, want) {
+		t.Errorf("FileSecrets() = %+v, want %+v", secrets, want)
+	}
+}
+
+func TestMaterializeSecrets(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "myapp")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	compose := `services:
+  db:
+    image: postgres:16
+secrets:
+  db_password:
+    file: ./secrets/db_password
+  own:
+    file: ./own_secret
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(compose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := map[string]string{"db_password": "s3cret"}
+	var gotApp string
+	s := &Service{}
+	s.SetSecretStore(func(app string) (map[string]string, error) {
+		gotApp = app
+		return stored, nil
+	})
+	opts := Options{WorkingDir: dir}
+
+	if err := s.materializeSecrets(opts); err == nil || !strings.Contains(err.Error(), "own") {
+		t.Fatalf("materializeSecrets() error = %v, want the secret without value or file", err)
+	}
+
+	ownPath := filepath.Join(dir, "own_secret")
+	if err := os.WriteFile(ownPath, []byte("mine"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ { // A second start replaces the read-only file
+		if err := s.materializeSecrets(opts); err != nil {
+			t.Fatalf("materializeSecrets() error = %v", err)
+		}
+	}
+	if gotApp != "myapp" {
+		t.Errorf("store called with %q, want myapp", gotApp)
+	}
+	secretPath := filepath.Join(dir, "secrets", "db_password")
+	data, err := os.ReadFile(secretPath)
+	if err != nil || string(data) != "s3cret" {
+		t.Fatalf("secret file = %q, %v", data, err)
+	}
+	if info, _ := os.Stat(secretPath); info.Mode().Perm() != 0o444 {
+		t.Errorf("secret file mode = %v, want 0444", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(filepath.Dir(secretPath)); info.Mode().Perm() != 0o700 {
+		t.Errorf("secrets directory mode = %v, want 0700", info.Mode().Perm())
+	}
+
+	if err := s.removeSecrets(opts); err != nil {
+		t.Fatalf("removeSecrets() error = %v", err)
+	}
+	if _, err := os.Stat(secretPath); !os.IsNotExist(err) {
+		t.Errorf("stored secret still on disk: %v", err)
+	}
+	if _, err := os.Stat(ownPath); err != nil {
+		t.Errorf("user's secret file was removed: %v", err)
+	}
+
+	s.SetSecretStore(func(string) (map[string]string, error) { return nil, errors.New("database closed") })
+	if err := s.materializeSecrets(opts); err == nil {
+		t.Error("materializeSecrets() succeeded with a failing store")
+	}
+}
+
+func TestWriteEnvSecrets(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "myapp")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	env := "COMPOSE_PROJECT_NAME=ontree-myapp\nDB_PASSWORD={{ secret DB_PASSWORD }}\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(env), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := map[string]string{"DB_PASSWORD": "two words"}
+	s := &Service{}
+	s.SetEnvSecretStore(func(app string) (map[string]string, error) {
+		if app != "myapp" {
+			t.Errorf("store asked for app %q", app)
+		}
+		return stored, nil
+	})
+
+	path, cleanup, err := s.writeEnvSecrets(Options{WorkingDir: dir})
+	if err != nil {
+		t.Fatalf("writeEnvSecrets() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "COMPOSE_PROJECT_NAME=ontree-myapp\nDB_PASSWORD='two words'\n"; string(content) != want {
+		t.Errorf("rendered .env = %q, want %q", content, want)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("rendered .env mode = %v, %v", info, err)
+	}
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("rendered .env left behind: %v", err)
+	}
+	if onDisk, _ := os.ReadFile(filepath.Join(dir, ".env")); string(onDisk) != env {
+		t.Errorf(".env in the app directory changed to %q", onDisk)
+	}
+
+	// A placeholder without a stored value fails the start, naming the secret
+	delete(stored, "DB_PASSWORD")
+	if _, _, err := s.writeEnvSecrets(Options{WorkingDir: dir}); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD") {
+		t.Errorf("writeEnvSecrets() without value error = %v", err)
+	}
+
+	// Without placeholders compose reads the .env of the app directory
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("PORT=8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if path, _, err := s.writeEnvSecrets(Options{WorkingDir: dir}); err != nil || path != "" {
+		t.Errorf("writeEnvSecrets() without placeholders = %q, %v", path, err)
+	}
+}
@@ -0,0 +1,129 @@
+// Handwritten part of the TreeOS client: the transport and the requests api/openapi.yaml
+// marks x-client: manual. The methods of the other operations are generated into index.ts.
+
+import type { AppActionResponse, Job, JobKind, LogLevel, ParsedLogs } from "./types";
+
+export class TreeOSError extends Error {
+  constructor(public readonly status: number, message: string) {
+    super(`treeos api error (${status}): ${message}`);
+  }
+}
+
+// LogQuery selects the lines of parsedAppLogs. Unset fields select everything.
+export interface LogQuery {
+  service?: string;
+  level?: LogLevel; // Minimum level
+  q?: string; // Case-insensitive text in the message
+  tail?: number; // Lines to parse, 1000 if unset
+  since?: string;
+  until?: string;
+}
+
+export class BaseClient {
+  private readonly baseURL: string;
+  private token = "";
+
+  constructor(baseURL: string, private readonly fetchFn: typeof fetch = fetch) {
+    this.baseURL = baseURL.replace(/\/+$/, "");
+  }
+
+  // setToken authenticates with the node's API token instead of a login.
+  setToken(token: string): void {
+    this.token = token;
+  }
+
+  // Login relies on the runtime keeping the session cookie (browsers do,
+  // for Node pass a cookie-aware fetch implementation).
+  async login(username: string, password: string): Promise<void> {
+    const res = await this.fetchFn(`${this.baseURL}/login`, {
+      method: "POST",
+      headers: { "Content-Type": "application/x-www-form-urlencoded" },
+      body: new URLSearchParams({ username, password }).toString(),
+      redirect: "manual",
+      credentials: "include",
+    });
+    const location = res.headers.get("Location") ?? "";
+    if (res.status !== 302 || location.startsWith("/login")) {
+      throw new TreeOSError(401, "invalid username or password");
+    }
+  }
+
+  // forceStopApp stops an app also while running apps depend on it; stopApp fails with 409 then.
+  forceStopApp(name: string): Promise<AppActionResponse> {
+    return this.request("POST", `${appPath(name, "stop")}?force=true`);
+  }
+
+  // With waitSeconds the server holds the request until the job is done or
+  // the time has passed.
+  job(kind: JobKind, name: string, waitSeconds = 0): Promise<Job> {
+    const query = waitSeconds > 0 ? `?wait=${waitSeconds}s` : "";
+    return this.request("GET", `/api/jobs/${kind}/${encodeURIComponent(name)}${query}`);
+  }
+
+  async waitForJob(kind: JobKind, name: string): Promise<Job> {
+    for (;;) {
+      const job = await this.job(kind, name, 30);
+      if (job.done) {
+        return job;
+      }
+    }
+  }
+
+  async appLogs(name: string, service = ""): Promise<string> {
+    const query = service ? `?service=${encodeURIComponent(service)}` : "";
+    const res = await this.send("GET", appPath(name, "logs") + query);
+    return res.text();
+  }
+
+  // parsedAppLogs returns the last log lines with detected time and level, and a timeline with error spikes.
+  parsedAppLogs(name: string, query: LogQuery = {}): Promise<ParsedLogs> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(query)) {
+      if (value !== undefined && value !== "") params.set(key, String(value));
+    }
+    const suffix = params.toString() ? `?${params}` : "";
+    return this.request("GET", appPath(name, "logs/parsed") + suffix);
+  }
+
+  protected async request<T>(method: string, path: string, body?: unknown): Promise<T> {
+    const res = await this.send(method, path, body);
+    return (await res.json()) as T;
+  }
+
+  protected async send(method: string, path: string, body?: unknown): Promise<Response> {
+    const headers: Record<string, string> = { Accept: "application/json" };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+    if (this.token) {
+      headers["Authorization"] = `Bearer ${this.token}`;
+    }
+    const res = await this.fetchFn(`${this.baseURL}${path}`, {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+      redirect: "manual",
+      credentials: "include",
+    });
+    if (res.ok) {
+      return res;
+    }
+    if (res.status === 302 || res.type === "opaqueredirect") {
+      throw new TreeOSError(401, "not authenticated");
+    }
+    const text = (await res.text()).trim();
+    let message = text || res.statusText;
+    try {
+      const payload = JSON.parse(text);
+      message = payload.error || payload.message || message;
+    } catch {
+      // Plain text error body
+    }
+    throw new TreeOSError(res.status, message);
+  }
+}
+
+function appPath(name: string, action = ""): string {
+  const path = `/api/apps/${encodeURIComponent(name)}`;
+  return action ? `${path}/${action}` : path;
+}
@@ -0,0 +1,80 @@
+// followAppStatus keeps the status badges of app containers current from the "app-status"
+// events of url, which the server sends when a container is created, starts, stops, dies or
+// changes its health. rootFor returns the element that shows an event's app, or null. In it,
+// elements with data-status and data-service hold the badges of a service, elements with
+// data-uptime and data-service its uptime. onUnknown is called for containers the page
+// doesn't show, e.g. ones created or removed since it was rendered.
+(function () {
+  'use strict';
+
+  // Like the health-badge template
+  const HEALTH_BADGES = {
+    healthy: '<span class="badge bg-success ms-1" title="Health check passes">healthy</span>',
+    unhealthy: '<span class="badge bg-danger ms-1" title="Health check fails">unhealthy</span>',
+    starting: '<span class="badge bg-info ms-1" title="Health check start period">starting</span>'
+  };
+
+  function escapeHTML(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+  }
+
+  // statusBadge returns the badge of a container state, as the pages render it
+  function statusBadge(state) {
+    switch (state) {
+      case 'running':
+        return '<span class="badge badge-running">Running</span>';
+      case 'exited':
+        return '<span class="badge badge-stopped">Stopped</span>';
+      default:
+        return '<span class="badge bg-warning">' + escapeHTML(state.charAt(0).toUpperCase() + state.slice(1)) + '</span>';
+    }
+  }
+
+  function update(root, data, onUnknown) {
+    const service = '[data-service="' + CSS.escape(data.service) + '"]';
+    const badges = root.querySelectorAll('[data-status]' + service);
+    if (badges.length === 0 || data.state === 'removed' || data.state === 'created') {
+      if (onUnknown) {
+        onUnknown(data);
+      }
+      return;
+    }
+    badges.forEach(function (el) {
+      let health = el.dataset.health || '';
+      if (data.action === 'health_status') {
+        health = data.health;
+      } else if (data.state !== 'running' || data.action === 'start') {
+        health = '';
+      }
+      el.dataset.health = health;
+      el.innerHTML = statusBadge(data.state) + (HEALTH_BADGES[health] || '');
+    });
+    if (data.action === 'health_status') {
+      return;
+    }
+    root.querySelectorAll('[data-uptime]' + service).forEach(function (el) {
+      el.innerHTML = data.state === 'running'
+        ? '<small class="text-muted">Up less than a minute</small>'
+        : '<span class="text-muted">-</span>';
+    });
+  }
+
+  window.followAppStatus = function (url, rootFor, onUnknown) {
+    const source = new TreeOSEventSource(url, { withCredentials: true });
+    source.addEventListener('app-status', function (e) {
+      let data;
+      try {
+        data = JSON.parse(e.data);
+      } catch (err) {
+        return;
+      }
+      const root = rootFor(data);
+      if (root) {
+        update(root, data, onUnknown);
+      }
+    });
+    return source;
+  };
+})();
@@ -0,0 +1,255 @@
+// TreeOSEventSource is an EventSource that falls back to long polling when a proxy drops or
+// buffers server-sent events. It tries the stream first; if the stream does not open within
+// OPEN_TIMEOUT it polls the same URL with ?transport=poll, which answers with the same event
+// framing. The fallback is remembered for the browser session.
+(function () {
+  'use strict';
+
+  const CONNECTING = 0;
+  const OPEN = 1;
+  const CLOSED = 2;
+
+  const OPEN_TIMEOUT = 10000;
+  const MAX_RETRY_DELAY = 30000;
+  const STORAGE_KEY = 'treeos-event-transport';
+
+  function pollPreferred() {
+    try {
+      return sessionStorage.getItem(STORAGE_KEY) === 'poll';
+    } catch (err) {
+      return false;
+    }
+  }
+
+  function rememberPoll() {
+    try {
+      sessionStorage.setItem(STORAGE_KEY, 'poll');
+    } catch (err) {
+      // Storage disabled, negotiate again on the next page
+    }
+  }
+
+  // parseEvents splits a response of the poll transport into events
+  function parseEvents(text) {
+    const events = [];
+    text.split(/\r?\n\r?\n/).forEach(function (block) {
+      let type = 'message';
+      const data = [];
+      block.split(/\r?\n/).forEach(function (line) {
+        if (line === '' || line.startsWith(':')) {
+          return;
+        }
+        const colon = line.indexOf(':');
+        const field = colon === -1 ? line : line.slice(0, colon);
+        let value = colon === -1 ? '' : line.slice(colon + 1);
+        if (value.startsWith(' ')) {
+          value = value.slice(1);
+        }
+        if (field === 'event') {
+          type = value;
+        } else if (field === 'data') {
+          data.push(value);
+        }
+      });
+      if (data.length > 0) {
+        events.push({ type: type, data: data.join('\n') });
+      }
+    });
+    return events;
+  }
+
+  function TreeOSEventSource(url, options) {
+    this.url = url;
+    this.withCredentials = Boolean(options && options.withCredentials);
+    this.readyState = CONNECTING;
+    this.transport = null;
+    this.onopen = null;
+    this.onmessage = null;
+    this.onerror = null;
+
+    this._listeners = {};
+    this._source = null;
+    this._session = null;
+    this._failures = 0;
+    this._abort = null;
+    this._timer = null;
+
+    if (!window.EventSource || pollPreferred()) {
+      this._poll();
+    } else {
+      this._stream();
+    }
+  }
+
+  TreeOSEventSource.CONNECTING = CONNECTING;
+  TreeOSEventSource.OPEN = OPEN;
+  TreeOSEventSource.CLOSED = CLOSED;
+
+  TreeOSEventSource.prototype.addEventListener = function (type, listener) {
+    if (!this._listeners[type]) {
+      this._listeners[type] = [];
+      this._forward(type);
+    }
+    this._listeners[type].push(listener);
+  };
+
+  TreeOSEventSource.prototype.removeEventListener = function (type, listener) {
+    const listeners = this._listeners[type];
+    if (listeners) {
+      this._listeners[type] = listeners.filter(function (l) { return l !== listener; });
+    }
+  };
+
+  TreeOSEventSource.prototype.close = function () {
+    this.readyState = CLOSED;
+    clearTimeout(this._timer);
+    if (this._source) {
+      this._source.close();
+      this._source = null;
+    }
+    if (this._abort) {
+      this._abort.abort();
+      this._abort = null;
+    }
+  };
+
+  TreeOSEventSource.prototype._dispatch = function (event) {
+    const handler = this['on' + event.type];
+    if (typeof handler === 'function') {
+      handler.call(this, event);
+    }
+    (this._listeners[event.type] || []).slice().forEach(function (listener) {
+      listener.call(this, event);
+    }, this);
+  };
+
+  TreeOSEventSource.prototype._message = function (type, data) {
+    this._dispatch(new MessageEvent(type, { data: data }));
+  };
+
+  TreeOSEventSource.prototype._opened = function () {
+    this.readyState = OPEN;
+    this._failures = 0;
+    this._dispatch(new Event('open'));
+  };
+
+  // _forward passes events of a type from the native stream on
+  TreeOSEventSource.prototype._forward = function (type) {
+    if (!this._source || type === 'message' || type === 'error' || type === 'open') {
+      return;
+    }
+    const self = this;
+    this._source.addEventListener(type, function (e) {
+      self._message(type, e.data);
+    });
+  };
+
+  TreeOSEventSource.prototype._stream = function () {
+    const self = this;
+    let opened = false;
+    const source = new EventSource(this.url, { withCredentials: this.withCredentials });
+    this._source = source;
+    this.transport = 'sse';
+    Object.keys(this._listeners).forEach(function (type) { self._forward(type); });
+
+    // A proxy that drops or buffers the stream never lets it open
+    this._timer = setTimeout(function () {
+      if (!opened) {
+        self._fallback();
+      }
+    }, OPEN_TIMEOUT);
+
+    source.onopen = function () {
+      opened = true;
+      clearTimeout(self._timer);
+      self._opened();
+    };
+    source.onmessage = function (e) {
+      self._message('message', e.data);
+    };
+    source.onerror = function (e) {
+      if (e.data !== undefined) {
+        // An "error" event sent by the server
+        self._message('error', e.data);
+        return;
+      }
+      if (!opened) {
+        self._fallback();
+        return;
+      }
+      self.readyState = source.readyState;
+      self._dispatch(new Event('error'));
+    };
+  };
+
+  TreeOSEventSource.prototype._fallback = function () {
+    if (this.readyState === CLOSED) {
+      return;
+    }
+    clearTimeout(this._timer);
+    if (this._source) {
+      this._source.close();
+      this._source = null;
+    }
+    rememberPoll();
+    this._poll();
+  };
+
+  TreeOSEventSource.prototype._poll = function () {
+    if (this.readyState === CLOSED) {
+      return;
+    }
+    const self = this;
+    this.transport = 'poll';
+    const params = new URLSearchParams({ transport: 'poll' });
+    if (this._session) {
+      params.set('session', this._session);
+    }
+    const url = this.url + (this.url.includes('?') ? '&' : '?') + params.toString();
+    this._abort = new AbortController();
+
+    fetch(url, {
+      credentials: this.withCredentials ? 'include' : 'same-origin',
+      headers: { 'Accept': 'text/event-stream' },
+      signal: this._abort.signal
+    }).then(function (response) {
+      if (!response.ok) {
+        const err = new Error('HTTP ' + response.status);
+        err.status = response.status;
+        throw err;
+      }
+      self._session = response.headers.get('X-Poll-Session') || self._session;
+      return response.text();
+    }).then(function (text) {
+      if (self.readyState === CLOSED) {
+        return;
+      }
+      if (self.readyState !== OPEN) {
+        self._opened();
+      }
+      parseEvents(text).forEach(function (event) {
+        if (self.readyState !== CLOSED) {
+          self._message(event.type, event.data);
+        }
+      });
+      self._poll();
+    }).catch(function (err) {
+      if (self.readyState === CLOSED) {
+        return;
+      }
+      // Like a stream, a poll that is refused is not retried
+      if (err.status && err.status < 500) {
+        self.readyState = CLOSED;
+        self._dispatch(new Event('error'));
+        return;
+      }
+      self.readyState = CONNECTING;
+      self._dispatch(new Event('error'));
+      const delay = Math.min(1000 * Math.pow(2, self._failures), MAX_RETRY_DELAY);
+      self._failures++;
+      self._timer = setTimeout(function () { self._poll(); }, delay);
+    });
+  };
+
+  window.TreeOSEventSource = TreeOSEventSource;
+})();
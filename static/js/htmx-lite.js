@@ -18,6 +18,12 @@
     return Number.isNaN(numeric) ? 0 : numeric;
   }
 
+  // Shortest polling interval the node allows, nodes with little memory set it on the body
+  function minPollInterval() {
+    const value = document.body && document.body.getAttribute('data-min-poll-interval');
+    return value ? parseInt(value, 10) || 0 : 0;
+  }
+
   function resolveTarget(element, targetSpec) {
     if (targetSpec === 'this') {
       return element;
@@ -61,28 +67,66 @@
     document.body.dispatchEvent(new CustomEvent(name, { detail }));
   }
 
+  // Swapping replaces the focused element, keyboard and screen reader users would be sent
+  // back to the top of the page. Focus moves to the new element with the same id, or to the
+  // swapped region when the focused element is gone.
+  function captureFocus(target) {
+    const active = document.activeElement;
+    if (!active || active === document.body || !target.contains(active)) {
+      return null;
+    }
+    return {
+      id: active.id,
+      targetId: target.id,
+      selectionStart: active.selectionStart,
+      selectionEnd: active.selectionEnd,
+    };
+  }
+
+  function restoreFocus(state, target) {
+    if (!state || (document.activeElement && document.activeElement !== document.body)) {
+      return;
+    }
+    let element = state.id ? document.getElementById(state.id) : null;
+    if (!element) {
+      element = target.isConnected ? target : state.targetId && document.getElementById(state.targetId);
+      if (!element) {
+        return;
+      }
+      if (!element.hasAttribute('tabindex')) {
+        element.setAttribute('tabindex', '-1');
+      }
+    }
+    element.focus({ preventScroll: true });
+    if (typeof state.selectionStart === 'number' && typeof element.setSelectionRange === 'function') {
+      try {
+        element.setSelectionRange(state.selectionStart, state.selectionEnd);
+      } catch (error) {
+        // Inputs like email and number have no selection
+      }
+    }
+  }
+
   function swapContent(target, swapStyle, html) {
     if (!target) {
       return;
     }
+    const focus = captureFocus(target);
     const mode = (swapStyle || 'innerHTML').toLowerCase();
     if (mode === 'outerhtml') {
       target.outerHTML = html;
       scanForHx(document);
-      return;
-    }
-    if (mode === 'beforeend') {
+    } else if (mode === 'beforeend') {
       target.insertAdjacentHTML('beforeend', html);
       scanForHx(target);
-      return;
-    }
-    if (mode === 'afterend') {
+    } else if (mode === 'afterend') {
       target.insertAdjacentHTML('afterend', html);
       scanForHx(document);
-      return;
+    } else {
+      target.innerHTML = html;
+      scanForHx(target);
     }
-    target.innerHTML = html;
-    scanForHx(target);
+    restoreFocus(focus, target);
   }
 
   function performFetch(contextEl, method, url, options = {}) {
@@ -113,6 +157,7 @@
     dispatchGlobalEvent('htmx:configRequest', detail);
     setIndicatorVisibility(indicator, true);
     dispatchGlobalEvent('htmx:beforeRequest', { element: contextEl, target, url });
+    target.setAttribute('aria-busy', 'true');
 
     const controller = new AbortController();
     const timeoutMs = typeof options.timeout === 'number' ? options.timeout : 15000;
@@ -159,6 +204,7 @@
           clearTimeout(timeoutId);
         }
         setIndicatorVisibility(indicator, false);
+        target.removeAttribute('aria-busy');
       });
   }
 
@@ -179,8 +225,9 @@
         setTimeout(() => handler(), delay);
       } else if (part.startsWith('every')) {
         const intervalMatch = part.match(/every\s+([0-9.]+(?:ms|s)?)/i);
-        const interval = intervalMatch ? toMilliseconds(intervalMatch[1]) : 0;
+        let interval = intervalMatch ? toMilliseconds(intervalMatch[1]) : 0;
         if (interval > 0) {
+          interval = Math.max(interval, minPollInterval());
           setInterval(() => handler(), interval);
         }
       } else {
@@ -1,6 +1,6 @@
 /**
  * TreeOS Theme Toggle
- * Manages light/dark mode switching with localStorage persistence
+ * Manages light/dark mode and high contrast switching with localStorage persistence
  */
 
 (function() {
@@ -9,6 +9,9 @@
   const STORAGE_KEY = 'treeos-theme';
   const THEME_LIGHT = 'light';
   const THEME_DARK = 'dark';
+  const CONTRAST_STORAGE_KEY = 'treeos-contrast';
+  const CONTRAST_NORMAL = 'normal';
+  const CONTRAST_HIGH = 'high';
 
   /**
    * Get the current theme from localStorage or default to light
@@ -53,12 +56,51 @@
     }));
   }
 
+  /**
+   * Get the contrast from localStorage, defaulting to the system preference
+   */
+  function getCurrentContrast() {
+    const saved = localStorage.getItem(CONTRAST_STORAGE_KEY);
+    if (saved) {
+      return saved;
+    }
+    const prefersMore = window.matchMedia && window.matchMedia('(prefers-contrast: more)').matches;
+    return prefersMore ? CONTRAST_HIGH : CONTRAST_NORMAL;
+  }
+
+  /**
+   * Apply contrast to the document. The choice is only stored when the user makes it,
+   * so the system preference keeps applying until then.
+   */
+  function applyContrast(contrast, persist) {
+    document.documentElement.setAttribute('data-contrast', contrast);
+    if (persist) {
+      localStorage.setItem(CONTRAST_STORAGE_KEY, contrast);
+    }
+    document.querySelectorAll('[data-contrast-toggle]').forEach(button => {
+      button.setAttribute('aria-pressed', contrast === CONTRAST_HIGH ? 'true' : 'false');
+    });
+  }
+
+  /**
+   * Toggle between normal and high contrast
+   */
+  function toggleContrast() {
+    const newContrast = getCurrentContrast() === CONTRAST_HIGH ? CONTRAST_NORMAL : CONTRAST_HIGH;
+    applyContrast(newContrast, true);
+
+    window.dispatchEvent(new CustomEvent('contrast-changed', {
+      detail: { contrast: newContrast }
+    }));
+  }
+
   /**
    * Initialize theme on page load
    */
   function initTheme() {
     const savedTheme = getCurrentTheme();
     applyTheme(savedTheme);
+    applyContrast(getCurrentContrast(), false);
   }
 
   /**
@@ -87,6 +129,14 @@
         }
       });
     });
+
+    document.querySelectorAll('[data-contrast-toggle]').forEach(button => {
+      button.addEventListener('click', function(event) {
+        event.preventDefault();
+        toggleContrast();
+      });
+    });
+    applyContrast(getCurrentContrast(), false);
   }
 
   // Initialize theme immediately (before page renders)
@@ -97,5 +147,6 @@
 
   // Expose toggle function globally for inline onclick handlers
   window.toggleTheme = toggleTheme;
+  window.toggleContrast = toggleContrast;
 
 })();